@@ -24,6 +24,7 @@ var (
 	analyzeCommand        commands.AnalyzeCommand
 	bucketValidateCommand commands.BucketValidationCommand
 	configCommand         commands.ConfigCommand
+	ingestCommand         commands.IngestCommand
 	loadgenCommand        commands.LoadgenCommand
 	logConfig             commands.LoggerConfig
 	pushGateway           commands.PushGatewayConfig
@@ -42,6 +43,7 @@ func main() {
 	analyzeCommand.Register(app, envVars)
 	bucketValidateCommand.Register(app, envVars)
 	configCommand.Register(app, envVars)
+	ingestCommand.Register(app, envVars)
 	loadgenCommand.Register(app, envVars, prometheus.DefaultRegisterer)
 	logConfig.Register(app, envVars)
 	pushGateway.Register(app, envVars)
@@ -121,6 +121,34 @@
             message: '%(product)s Compactor %(alert_instance_variable)s in %(alert_aggregation_variables)s has found and ignored blocks with out of order chunks.' % $._config,
           },
         },
+        {
+          // Alert if compactor is skipping the compaction of tenants because of repeated failures.
+          alert: $.alertName('CompactorSkippedTenantsWithRepeatedFailures'),
+          'for': '5m',
+          expr: |||
+            cortex_compactor_tenants_compaction_skipped > 0
+          |||,
+          labels: {
+            severity: 'warning',
+          },
+          annotations: {
+            message: '%(product)s Compactor %(alert_instance_variable)s in %(alert_aggregation_variables)s is skipping the compaction of tenants whose compaction repeatedly failed.' % $._config,
+          },
+        },
+        {
+          // Alert if compactor has quarantined blocks which repeatedly failed compaction.
+          alert: $.alertName('CompactorQuarantinedBlocksWithRepeatedFailures'),
+          'for': '1m',
+          expr: |||
+            increase(cortex_compactor_blocks_marked_for_no_compaction_total{reason="repeated-compaction-failures"}[5m]) > 0
+          |||,
+          labels: {
+            severity: 'warning',
+          },
+          annotations: {
+            message: '%(product)s Compactor %(alert_instance_variable)s in %(alert_aggregation_variables)s has quarantined blocks which repeatedly failed compaction.' % $._config,
+          },
+        },
       ],
     },
   ],
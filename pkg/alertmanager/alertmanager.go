@@ -87,6 +87,10 @@ type Config struct {
 	Replicator        Replicator
 	Store             alertstore.AlertStore
 	PersisterConfig   PersisterConfig
+
+	// SilencesFederator is nil if the silences federation is disabled.
+	SilencesFederationConfig SilencesFederationConfig
+	SilencesFederator        SilencesFederator
 }
 
 // An Alertmanager manages the alerts for one user.
@@ -96,6 +100,7 @@ type Alertmanager struct {
 	logger          log.Logger
 	state           *state
 	persister       *statePersister
+	federation      *silencesFederation
 	nflog           *nflog.Log
 	silences        *silence.Silences
 	marker          types.Marker
@@ -188,8 +193,14 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 	am.state = newReplicatedStates(cfg.UserID, cfg.ReplicationFactor, cfg.Replicator, cfg.Store, am.logger, am.registry)
 	am.persister = newStatePersister(cfg.PersisterConfig, cfg.UserID, am.state, cfg.Store, am.logger, am.registry)
 
+	// When the state is stored only in the object storage, no snapshot is written to the local disk.
+	var snapshotFile, silencesFile string
+	if !cfg.PersisterConfig.ExternalStateStorageEnabled {
+		snapshotFile = filepath.Join(cfg.TenantDataDir, notificationLogSnapshot)
+		silencesFile = filepath.Join(cfg.TenantDataDir, silencesSnapshot)
+	}
+
 	var err error
-	snapshotFile := filepath.Join(cfg.TenantDataDir, notificationLogSnapshot)
 	am.nflog, err = nflog.New(nflog.Options{
 		SnapshotFile: snapshotFile,
 		Retention:    cfg.Retention,
@@ -212,7 +223,6 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 
 	am.marker = types.NewMarker(am.registry)
 
-	silencesFile := filepath.Join(cfg.TenantDataDir, silencesSnapshot)
 	am.silences, err = silence.New(silence.Options{
 		SnapshotFile: silencesFile,
 		Retention:    cfg.Retention,
@@ -223,9 +233,21 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 		return nil, fmt.Errorf("failed to create silences: %v", err)
 	}
 
-	c = am.state.AddState("sil:"+cfg.UserID, am.silences, am.registry)
+	silencesKey := "sil:" + cfg.UserID
+	c = am.state.AddState(silencesKey, am.silences, am.registry)
 	am.silences.SetBroadcast(c.Broadcast)
 
+	// The silences updates are also replicated to the Alertmanagers of other Mimir clusters, if enabled.
+	// The silences broadcast both the local updates and the updates merged from the other replicas or
+	// the federation peers, which the federation tells apart.
+	if cfg.SilencesFederator != nil {
+		am.federation = newSilencesFederation(cfg.SilencesFederationConfig, cfg.UserID, silencesKey, am.state, am.silences, cfg.SilencesFederator, am.logger, am.registry)
+		am.silences.SetBroadcast(func(b []byte) {
+			c.Broadcast(b)
+			am.federation.broadcast(b)
+		})
+	}
+
 	// State replication needs to be started after the state keys are defined.
 	if err := am.state.StartAsync(context.Background()); err != nil {
 		return nil, errors.Wrap(err, "failed to start ring-based replication service")
@@ -235,6 +257,12 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 		return nil, errors.Wrap(err, "failed to start state persister service")
 	}
 
+	if am.federation != nil {
+		if err := am.federation.StartAsync(context.Background()); err != nil {
+			return nil, errors.Wrap(err, "failed to start silences federation service")
+		}
+	}
+
 	am.pipelineBuilder = notify.NewPipelineBuilder(am.registry)
 
 	// Run the silences maintenance in a dedicated goroutine.
@@ -417,6 +445,9 @@ func (am *Alertmanager) Stop() {
 
 	am.persister.StopAsync()
 	am.state.StopAsync()
+	if am.federation != nil {
+		am.federation.StopAsync()
+	}
 
 	am.alerts.Close()
 	close(am.maintenanceStop)
@@ -433,6 +464,12 @@ func (am *Alertmanager) StopAndWait() {
 		level.Warn(am.logger).Log("msg", "error while stopping ring-based replication service", "err", err)
 	}
 
+	if am.federation != nil {
+		if err := am.federation.AwaitTerminated(context.Background()); err != nil {
+			level.Warn(am.logger).Log("msg", "error while stopping silences federation service", "err", err)
+		}
+	}
+
 	am.wg.Wait()
 }
 
@@ -440,6 +477,14 @@ func (am *Alertmanager) mergePartialExternalState(part *clusterpb.Part) error {
 	return am.state.MergePartialState(part)
 }
 
+// mergeFederatedSilences merges a silences state received from the Alertmanagers of another Mimir cluster.
+func (am *Alertmanager) mergeFederatedSilences(b []byte) error {
+	if am.federation != nil {
+		return am.federation.mergeFromPeer(b)
+	}
+	return am.silences.Merge(b)
+}
+
 func (am *Alertmanager) getFullState() (*clusterpb.FullState, error) {
 	return am.state.GetFullState()
 }
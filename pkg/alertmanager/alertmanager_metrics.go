@@ -65,6 +65,10 @@ type alertmanagerMetrics struct {
 	persistTotal            *prometheus.Desc
 	persistFailed           *prometheus.Desc
 
+	// The silences federation.
+	silencesFederationTotal  *prometheus.Desc
+	silencesFederationFailed *prometheus.Desc
+
 	// exported metrics, gathered from Alertmanager Dispatcher
 	dispatcherAggrGroups                    *prometheus.Desc
 	dispatcherProcessingDuration            *prometheus.Desc
@@ -219,6 +223,14 @@ func newAlertmanagerMetrics() *alertmanagerMetrics {
 			"cortex_alertmanager_state_persist_failed_total",
 			"Number of times we have failed to persist the running state to storage.",
 			nil, nil),
+		silencesFederationTotal: prometheus.NewDesc(
+			"cortex_alertmanager_silences_federation_replication_total",
+			"Number of times we have tried to replicate the silences to the federation peers.",
+			nil, nil),
+		silencesFederationFailed: prometheus.NewDesc(
+			"cortex_alertmanager_silences_federation_replication_failed_total",
+			"Number of times we have failed to replicate the silences to the federation peers.",
+			nil, nil),
 		dispatcherAggrGroups: prometheus.NewDesc(
 			"cortex_alertmanager_dispatcher_aggregation_groups",
 			"Number of active aggregation groups",
@@ -296,6 +308,8 @@ func (m *alertmanagerMetrics) Describe(out chan<- *prometheus.Desc) {
 	out <- m.initialSyncDuration
 	out <- m.persistTotal
 	out <- m.persistFailed
+	out <- m.silencesFederationTotal
+	out <- m.silencesFederationFailed
 	out <- m.dispatcherAggrGroups
 	out <- m.dispatcherProcessingDuration
 	out <- m.dispatcherAggregationGroupsLimitReached
@@ -348,6 +362,8 @@ func (m *alertmanagerMetrics) Collect(out chan<- prometheus.Metric) {
 	data.SendSumOfHistograms(out, m.initialSyncDuration, "alertmanager_state_initial_sync_duration_seconds")
 	data.SendSumOfCounters(out, m.persistTotal, "alertmanager_state_persist_total")
 	data.SendSumOfCounters(out, m.persistFailed, "alertmanager_state_persist_failed_total")
+	data.SendSumOfCounters(out, m.silencesFederationTotal, "alertmanager_silences_federation_replication_total")
+	data.SendSumOfCounters(out, m.silencesFederationFailed, "alertmanager_silences_federation_replication_failed_total")
 
 	data.SendSumOfGauges(out, m.dispatcherAggrGroups, "alertmanager_dispatcher_aggregation_groups")
 	data.SendSumOfSummaries(out, m.dispatcherProcessingDuration, "alertmanager_dispatcher_alert_processing_duration_seconds")
@@ -280,6 +280,12 @@ func TestAlertmanagerMetricsStore(t *testing.T) {
 		# HELP cortex_alertmanager_state_persist_total Number of times we have tried to persist the running state to storage.
 		# TYPE cortex_alertmanager_state_persist_total counter
 		cortex_alertmanager_state_persist_total 0
+		# HELP cortex_alertmanager_silences_federation_replication_failed_total Number of times we have failed to replicate the silences to the federation peers.
+		# TYPE cortex_alertmanager_silences_federation_replication_failed_total counter
+		cortex_alertmanager_silences_federation_replication_failed_total 0
+		# HELP cortex_alertmanager_silences_federation_replication_total Number of times we have tried to replicate the silences to the federation peers.
+		# TYPE cortex_alertmanager_silences_federation_replication_total counter
+		cortex_alertmanager_silences_federation_replication_total 0
 
 		# HELP cortex_alertmanager_dispatcher_aggregation_group_limit_reached_total Number of times when dispatcher failed to create new aggregation group due to limit.
 		# TYPE cortex_alertmanager_dispatcher_aggregation_group_limit_reached_total counter
@@ -592,6 +598,12 @@ func TestAlertmanagerMetricsRemoval(t *testing.T) {
 						# HELP cortex_alertmanager_state_persist_total Number of times we have tried to persist the running state to storage.
 						# TYPE cortex_alertmanager_state_persist_total counter
 						cortex_alertmanager_state_persist_total 0
+						# HELP cortex_alertmanager_silences_federation_replication_failed_total Number of times we have failed to replicate the silences to the federation peers.
+						# TYPE cortex_alertmanager_silences_federation_replication_failed_total counter
+						cortex_alertmanager_silences_federation_replication_failed_total 0
+						# HELP cortex_alertmanager_silences_federation_replication_total Number of times we have tried to replicate the silences to the federation peers.
+						# TYPE cortex_alertmanager_silences_federation_replication_total counter
+						cortex_alertmanager_silences_federation_replication_total 0
 
 						# HELP cortex_alertmanager_dispatcher_aggregation_group_limit_reached_total Number of times when dispatcher failed to create new aggregation group due to limit.
 						# TYPE cortex_alertmanager_dispatcher_aggregation_group_limit_reached_total counter
@@ -853,6 +865,12 @@ func TestAlertmanagerMetricsRemoval(t *testing.T) {
 			# HELP cortex_alertmanager_state_persist_total Number of times we have tried to persist the running state to storage.
 			# TYPE cortex_alertmanager_state_persist_total counter
 			cortex_alertmanager_state_persist_total 0
+			# HELP cortex_alertmanager_silences_federation_replication_failed_total Number of times we have failed to replicate the silences to the federation peers.
+			# TYPE cortex_alertmanager_silences_federation_replication_failed_total counter
+			cortex_alertmanager_silences_federation_replication_failed_total 0
+			# HELP cortex_alertmanager_silences_federation_replication_total Number of times we have tried to replicate the silences to the federation peers.
+			# TYPE cortex_alertmanager_silences_federation_replication_total counter
+			cortex_alertmanager_silences_federation_replication_total 0
 
 			# HELP cortex_alertmanager_dispatcher_aggregation_group_limit_reached_total Number of times when dispatcher failed to create new aggregation group due to limit.
 			# TYPE cortex_alertmanager_dispatcher_aggregation_group_limit_reached_total counter
@@ -4,11 +4,15 @@ package alertmanager
 
 import (
 	"encoding/json"
+	"fmt"
 	tmplhtml "html/template"
 	"net/url"
 	tmpltext "text/template"
+	"text/template/parse"
 
 	"github.com/prometheus/alertmanager/template"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
 )
 
 type grafanaDatasource struct {
@@ -57,15 +61,113 @@ func grafanaExploreURL(grafanaURL, datasource, from, to, expr string) (string, e
 	return grafanaURL + "/explore?left=" + url.QueryEscape(string(res)), err
 }
 
-// withCustomFunctions returns template.Option which adds additional template functions
-// to the default ones.
-func withCustomFunctions(userID string) template.Option {
-	funcs := tmpltext.FuncMap{
+// customFunctions returns the template functions added to the default ones.
+func customFunctions(userID string) tmpltext.FuncMap {
+	return tmpltext.FuncMap{
 		"tenantID":          func() string { return userID },
 		"grafanaExploreURL": grafanaExploreURL,
 	}
+}
+
+// withCustomFunctions returns template.Option which adds additional template functions
+// to the default ones.
+func withCustomFunctions(userID string) template.Option {
+	funcs := customFunctions(userID)
 	return func(text *tmpltext.Template, html *tmplhtml.Template) {
 		text.Funcs(funcs)
 		html.Funcs(funcs)
 	}
 }
+
+// storedTemplateBuiltinFunctions are the text/template builtin functions which the templates stored in the
+// template store are allowed to call. The "call" builtin isn't allowed, because it calls arbitrary functions.
+var storedTemplateBuiltinFunctions = map[string]struct{}{
+	"and": {}, "or": {}, "not": {}, "len": {}, "index": {}, "slice": {}, "print": {}, "printf": {}, "println": {},
+	"html": {}, "js": {}, "urlquery": {}, "eq": {}, "ne": {}, "lt": {}, "le": {}, "gt": {}, "ge": {},
+}
+
+// validateStoredTemplateFunctions returns an error if the input template, stored in the template store, calls
+// any function other than the Alertmanager default functions, the custom ones and the allowed builtin functions.
+func validateStoredTemplateFunctions(userID string, tmpl alertspb.TemplateDesc) error {
+	allowed := tmpltext.FuncMap{}
+	for name, fn := range template.DefaultFuncs {
+		allowed[name] = fn
+	}
+	for name, fn := range customFunctions(userID) {
+		allowed[name] = fn
+	}
+
+	t, err := tmpltext.New(tmpl.Filename).Funcs(allowed).Parse(tmpl.Body)
+	if err != nil {
+		return err
+	}
+
+	for _, tt := range t.Templates() {
+		if tt.Tree == nil {
+			continue
+		}
+		if err := validateTemplateNodeFunctions(tt.Tree.Root, allowed); err != nil {
+			return fmt.Errorf("template %s: %w", tmpl.Filename, err)
+		}
+	}
+	return nil
+}
+
+func validateTemplateNodeFunctions(node parse.Node, allowed tmpltext.FuncMap) error {
+	switch n := node.(type) {
+	case *parse.IdentifierNode:
+		if _, ok := allowed[n.Ident]; ok {
+			return nil
+		}
+		if _, ok := storedTemplateBuiltinFunctions[n.Ident]; ok {
+			return nil
+		}
+		return fmt.Errorf("function %q is not allowed", n.Ident)
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := validateTemplateNodeFunctions(child, allowed); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return validateTemplateNodeFunctions(n.Pipe, allowed)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := validateTemplateNodeFunctions(cmd, allowed); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := validateTemplateNodeFunctions(arg, allowed); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return validateTemplateNodeFunctions(n.Node, allowed)
+	case *parse.IfNode:
+		return validateTemplateBranchFunctions(&n.BranchNode, allowed)
+	case *parse.RangeNode:
+		return validateTemplateBranchFunctions(&n.BranchNode, allowed)
+	case *parse.WithNode:
+		return validateTemplateBranchFunctions(&n.BranchNode, allowed)
+	case *parse.TemplateNode:
+		return validateTemplateNodeFunctions(n.Pipe, allowed)
+	}
+	return nil
+}
+
+func validateTemplateBranchFunctions(n *parse.BranchNode, allowed tmpltext.FuncMap) error {
+	for _, child := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := validateTemplateNodeFunctions(child, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/prometheus/alertmanager/template"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
 )
 
 func Test_withCustomFunctions(t *testing.T) {
@@ -49,3 +51,37 @@ func Test_withCustomFunctions(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateStoredTemplateFunctions(t *testing.T) {
+	tests := map[string]struct {
+		body        string
+		expectedErr string
+	}{
+		"default, custom and builtin functions": {
+			body: `{{ define "t" }}{{ toUpper "a" }} {{ tenantID }} {{ printf "%s" (len .Alerts) }}{{ end }}`,
+		},
+		"call builtin in an action": {
+			body:        `{{ call .Fn }}`,
+			expectedErr: `function "call" is not allowed`,
+		},
+		"call builtin nested in a branch": {
+			body:        `{{ define "t" }}{{ range .Alerts }}{{ if .Labels }}{{ else }}{{ with (call .Fn) }}{{ . }}{{ end }}{{ end }}{{ end }}{{ end }}`,
+			expectedErr: `function "call" is not allowed`,
+		},
+		"call builtin in the pipeline of a template invocation": {
+			body:        `{{ template "t" (call .Fn) }}`,
+			expectedErr: `function "call" is not allowed`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateStoredTemplateFunctions("user-1", alertspb.TemplateDesc{Filename: "test.tpl", Body: tc.body})
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -24,6 +26,8 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
 )
 
 func TestDispatcherGroupLimits(t *testing.T) {
@@ -197,6 +201,47 @@ route:
 	})
 }
 
+func TestAlertmanager_ExternalStateStorage(t *testing.T) {
+	for _, externalStateStorageEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("external state storage enabled: %t", externalStateStorageEnabled), func(t *testing.T) {
+			user := "test"
+			dataDir := t.TempDir()
+			store := prepareInMemoryAlertStore()
+
+			am, err := New(&Config{
+				UserID:            user,
+				Logger:            log.NewNopLogger(),
+				Limits:            &mockAlertManagerLimits{},
+				TenantDataDir:     dataDir,
+				ExternalURL:       &url.URL{Path: "/am"},
+				ShardingEnabled:   true,
+				Store:             store,
+				Replicator:        &stubReplicator{},
+				ReplicationFactor: 1,
+				PersisterConfig:   PersisterConfig{Interval: time.Hour, ExternalStateStorageEnabled: externalStateStorageEnabled},
+			}, prometheus.NewPedanticRegistry())
+			require.NoError(t, err)
+			require.NoError(t, am.persister.AwaitRunning(context.Background()))
+
+			am.StopAndWait()
+
+			// The state snapshots are written to the local disk on shutdown only if the external state storage is disabled.
+			_, err = os.Stat(filepath.Join(dataDir, notificationLogSnapshot))
+			assert.Equal(t, !externalStateStorageEnabled, err == nil)
+			_, err = os.Stat(filepath.Join(dataDir, silencesSnapshot))
+			assert.Equal(t, !externalStateStorageEnabled, err == nil)
+
+			// The state is persisted to the storage on shutdown only if the external state storage is enabled.
+			_, err = store.GetFullState(context.Background(), user)
+			if externalStateStorageEnabled {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, alertspb.ErrNotFound)
+			}
+		})
+	}
+}
+
 var (
 	alert1 = model.Alert{
 		Labels:       model.LabelSet{"alert": "first"},
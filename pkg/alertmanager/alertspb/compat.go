@@ -5,12 +5,22 @@
 
 package alertspb
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrNotFound = errors.New("alertmanager storage object not found")
 )
 
+// ConfigVersion describes a version of an Alertmanager configuration stored in the configuration history.
+type ConfigVersion struct {
+	Version   uint64    `yaml:"version"`
+	Timestamp time.Time `yaml:"timestamp"`
+	Author    string    `yaml:"author,omitempty"`
+}
+
 // ToProto transforms a yaml Alertmanager config and map of template files to an AlertConfigDesc
 func ToProto(cfg string, templates map[string]string, user string) AlertConfigDesc {
 	tmpls := []*TemplateDesc{}
@@ -8,11 +8,17 @@ package bucketclient
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/gogo/protobuf/proto"
 	"github.com/grafana/dskit/concurrency"
 	"github.com/grafana/dskit/runutil"
@@ -37,6 +43,19 @@ const (
 	// The name of alertmanager full state objects (notification log + silences).
 	fullStateName = "fullstate"
 
+	// The prefix under which the notification templates are stored. Each version of a template is stored
+	// in a separate object, following the pattern:
+	//     alertmanager/<user-id>/templates/<template-name>/<version>
+	templatesPrefix = "templates"
+
+	// How many versions of each template are retained.
+	templateVersionsRetained = 10
+
+	// The prefix under which the history of the Alertmanager configuration is stored. Each version
+	// of the configuration is stored in a separate object, following the pattern:
+	//     alertmanager/<user-id>/config-history/<version>
+	configHistoryPrefix = "config-history"
+
 	// How many users to load concurrently.
 	fetchConcurrency = 16
 )
@@ -177,6 +196,259 @@ func (s *BucketAlertStore) DeleteFullState(ctx context.Context, userID string) e
 	return err
 }
 
+// ListTemplates implements alertstore.TemplateStore.
+func (s *BucketAlertStore) ListTemplates(ctx context.Context, userID string) (map[string]uint64, error) {
+	bkt := s.getAlertmanagerUserBucket(userID)
+	templates := map[string]uint64{}
+
+	err := bkt.Iter(ctx, templatesPrefix, func(key string) error {
+		name, version, ok := parseTemplateObjectName(key)
+		if !ok {
+			return nil
+		}
+		if version > templates[name] {
+			templates[name] = version
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+
+	return templates, err
+}
+
+// GetTemplate implements alertstore.TemplateStore.
+func (s *BucketAlertStore) GetTemplate(ctx context.Context, userID, name string, version uint64) (alertspb.TemplateDesc, uint64, error) {
+	if version == 0 {
+		versions, err := s.listTemplateVersions(ctx, userID, name)
+		if err != nil {
+			return alertspb.TemplateDesc{}, 0, err
+		}
+		if len(versions) == 0 {
+			return alertspb.TemplateDesc{}, 0, alertspb.ErrNotFound
+		}
+		version = versions[len(versions)-1]
+	}
+
+	readCloser, err := s.getAlertmanagerUserBucket(userID).Get(ctx, templateObjectName(name, version))
+	if s.amBucket.IsObjNotFoundErr(err) {
+		return alertspb.TemplateDesc{}, 0, alertspb.ErrNotFound
+	} else if err != nil {
+		return alertspb.TemplateDesc{}, 0, err
+	}
+
+	defer runutil.CloseWithLogOnErr(s.logger, readCloser, "close bucket reader")
+
+	body, err := io.ReadAll(readCloser)
+	if err != nil {
+		return alertspb.TemplateDesc{}, 0, errors.Wrapf(err, "failed to read alertmanager template %s for user %s", name, userID)
+	}
+
+	return alertspb.TemplateDesc{Filename: name, Body: string(body)}, version, nil
+}
+
+// SetTemplate implements alertstore.TemplateStore.
+func (s *BucketAlertStore) SetTemplate(ctx context.Context, userID string, tmpl alertspb.TemplateDesc) (uint64, error) {
+	bkt := s.getAlertmanagerUserBucket(userID)
+
+	versions, err := s.listTemplateVersions(ctx, userID, tmpl.Filename)
+	if err != nil {
+		return 0, err
+	}
+
+	version := uint64(1)
+	if len(versions) > 0 {
+		version = versions[len(versions)-1] + 1
+	}
+
+	if err := bkt.Upload(ctx, templateObjectName(tmpl.Filename, version), strings.NewReader(tmpl.Body)); err != nil {
+		return 0, err
+	}
+
+	// Delete the versions which are no longer retained. The failure is not returned,
+	// because the new version has been successfully stored.
+	versions = append(versions, version)
+	for i := 0; i < len(versions)-templateVersionsRetained; i++ {
+		if err := bkt.Delete(ctx, templateObjectName(tmpl.Filename, versions[i])); err != nil && !bkt.IsObjNotFoundErr(err) {
+			level.Warn(s.logger).Log("msg", "failed to delete old alertmanager template version", "user", userID, "template", tmpl.Filename, "version", versions[i], "err", err)
+		}
+	}
+
+	return version, nil
+}
+
+// DeleteTemplate implements alertstore.TemplateStore.
+func (s *BucketAlertStore) DeleteTemplate(ctx context.Context, userID, name string) error {
+	bkt := s.getAlertmanagerUserBucket(userID)
+
+	versions, err := s.listTemplateVersions(ctx, userID, name)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if err := bkt.Delete(ctx, templateObjectName(name, version)); err != nil && !bkt.IsObjNotFoundErr(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listTemplateVersions returns the versions of a template, sorted in ascending order.
+func (s *BucketAlertStore) listTemplateVersions(ctx context.Context, userID, name string) ([]uint64, error) {
+	var versions []uint64
+
+	err := s.getAlertmanagerUserBucket(userID).Iter(ctx, path.Join(templatesPrefix, name)+objstore.DirDelim, func(key string) error {
+		if _, version, ok := parseTemplateObjectName(key); ok {
+			versions = append(versions, version)
+		}
+		return nil
+	})
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, err
+}
+
+func templateObjectName(name string, version uint64) string {
+	return path.Join(templatesPrefix, name, strconv.FormatUint(version, 10))
+}
+
+func parseTemplateObjectName(key string) (name string, version uint64, ok bool) {
+	parts := strings.Split(key, objstore.DirDelim)
+	if len(parts) != 3 || parts[0] != templatesPrefix {
+		return "", 0, false
+	}
+
+	version, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[1], version, true
+}
+
+// alertConfigVersion is the content of an Alertmanager configuration version object.
+type alertConfigVersion struct {
+	Timestamp time.Time `json:"timestamp"`
+	Author    string    `json:"author,omitempty"`
+	Config    []byte    `json:"config"`
+}
+
+// ListAlertConfigVersions implements alertstore.AlertConfigHistoryStore.
+func (s *BucketAlertStore) ListAlertConfigVersions(ctx context.Context, userID string) ([]alertspb.ConfigVersion, error) {
+	versions, err := s.listAlertConfigVersions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]alertspb.ConfigVersion, 0, len(versions))
+	for _, version := range versions {
+		v, err := s.getAlertConfigVersion(ctx, userID, version)
+		if errors.Is(err, alertspb.ErrNotFound) {
+			// The version has been deleted in the meanwhile.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, alertspb.ConfigVersion{Version: version, Timestamp: v.Timestamp, Author: v.Author})
+	}
+	return result, nil
+}
+
+// GetAlertConfigVersion implements alertstore.AlertConfigHistoryStore.
+func (s *BucketAlertStore) GetAlertConfigVersion(ctx context.Context, userID string, version uint64) (alertspb.AlertConfigDesc, error) {
+	v, err := s.getAlertConfigVersion(ctx, userID, version)
+	if err != nil {
+		return alertspb.AlertConfigDesc{}, err
+	}
+
+	cfg := alertspb.AlertConfigDesc{}
+	if err := cfg.Unmarshal(v.Config); err != nil {
+		return alertspb.AlertConfigDesc{}, errors.Wrapf(err, "failed to unmarshal alertmanager config version %d for user %s", version, userID)
+	}
+	return cfg, nil
+}
+
+// AddAlertConfigVersion implements alertstore.AlertConfigHistoryStore.
+func (s *BucketAlertStore) AddAlertConfigVersion(ctx context.Context, cfg alertspb.AlertConfigDesc, author string, retain int) (uint64, error) {
+	bkt := s.getAlertmanagerUserBucket(cfg.User)
+
+	versions, err := s.listAlertConfigVersions(ctx, cfg.User)
+	if err != nil {
+		return 0, err
+	}
+
+	// The version is time-based, so that the concurrent writers of the configuration can't assign the same version.
+	version := uint64(time.Now().UnixNano())
+	if len(versions) > 0 && version <= versions[len(versions)-1] {
+		version = versions[len(versions)-1] + 1
+	}
+
+	cfgBytes, err := cfg.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(alertConfigVersion{Timestamp: time.Now().UTC(), Author: author, Config: cfgBytes})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := bkt.Upload(ctx, configVersionObjectName(version), bytes.NewReader(data)); err != nil {
+		return 0, err
+	}
+
+	// Delete the versions which are no longer retained. The failure is not returned,
+	// because the new version has been successfully stored.
+	versions = append(versions, version)
+	for i := 0; i < len(versions)-retain; i++ {
+		if err := bkt.Delete(ctx, configVersionObjectName(versions[i])); err != nil && !bkt.IsObjNotFoundErr(err) {
+			level.Warn(s.logger).Log("msg", "failed to delete old alertmanager config version", "user", cfg.User, "version", versions[i], "err", err)
+		}
+	}
+
+	return version, nil
+}
+
+func (s *BucketAlertStore) getAlertConfigVersion(ctx context.Context, userID string, version uint64) (alertConfigVersion, error) {
+	readCloser, err := s.getAlertmanagerUserBucket(userID).Get(ctx, configVersionObjectName(version))
+	if s.amBucket.IsObjNotFoundErr(err) {
+		return alertConfigVersion{}, alertspb.ErrNotFound
+	} else if err != nil {
+		return alertConfigVersion{}, err
+	}
+
+	defer runutil.CloseWithLogOnErr(s.logger, readCloser, "close bucket reader")
+
+	body, err := io.ReadAll(readCloser)
+	if err != nil {
+		return alertConfigVersion{}, errors.Wrapf(err, "failed to read alertmanager config version %d for user %s", version, userID)
+	}
+
+	v := alertConfigVersion{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return alertConfigVersion{}, errors.Wrapf(err, "failed to unmarshal alertmanager config version %d for user %s", version, userID)
+	}
+	return v, nil
+}
+
+// listAlertConfigVersions returns the versions of the configuration of a user, sorted in ascending order.
+func (s *BucketAlertStore) listAlertConfigVersions(ctx context.Context, userID string) ([]uint64, error) {
+	var versions []uint64
+
+	err := s.getAlertmanagerUserBucket(userID).Iter(ctx, configHistoryPrefix+objstore.DirDelim, func(key string) error {
+		if version, err := strconv.ParseUint(path.Base(key), 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+		return nil
+	})
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, err
+}
+
+func configVersionObjectName(version uint64) string {
+	return path.Join(configHistoryPrefix, strconv.FormatUint(version, 10))
+}
+
 func (s *BucketAlertStore) getAlertConfig(ctx context.Context, userID string) (alertspb.AlertConfigDesc, error) {
 	config := alertspb.AlertConfigDesc{}
 	err := s.get(ctx, s.getUserBucket(userID), userID, &config)
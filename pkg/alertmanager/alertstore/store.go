@@ -52,6 +52,40 @@ type AlertStore interface {
 	DeleteFullState(ctx context.Context, user string) error
 }
 
+// TemplateStore stores the notification templates of the tenants, independently of their Alertmanager
+// configuration. Each update of a template stores a new version of it.
+type TemplateStore interface {
+	// ListTemplates returns the name and the latest version of all templates of the given user.
+	ListTemplates(ctx context.Context, user string) (map[string]uint64, error)
+
+	// GetTemplate loads and returns the given version of a template, along with the version.
+	// If the version is 0, the latest version of the template is returned.
+	GetTemplate(ctx context.Context, user, name string, version uint64) (alertspb.TemplateDesc, uint64, error)
+
+	// SetTemplate stores a new version of a template and returns the version.
+	SetTemplate(ctx context.Context, user string, tmpl alertspb.TemplateDesc) (uint64, error)
+
+	// DeleteTemplate deletes all versions of a template.
+	// If the template doesn't exist, no error is reported.
+	DeleteTemplate(ctx context.Context, user, name string) error
+}
+
+// AlertConfigHistoryStore keeps the history of the Alertmanager configurations of the tenants. Each stored
+// configuration is a new version of it, and only the latest versions are retained.
+type AlertConfigHistoryStore interface {
+	// ListAlertConfigVersions returns the retained versions of the configuration of the given user,
+	// sorted in ascending order.
+	ListAlertConfigVersions(ctx context.Context, user string) ([]alertspb.ConfigVersion, error)
+
+	// GetAlertConfigVersion loads and returns the given version of the configuration of the given user.
+	GetAlertConfigVersion(ctx context.Context, user string, version uint64) (alertspb.AlertConfigDesc, error)
+
+	// AddAlertConfigVersion stores the configuration as a new version, retaining at most the given number
+	// of versions, and returns the version. The versions are unique and monotonically increasing, but not
+	// consecutive.
+	AddAlertConfigVersion(ctx context.Context, cfg alertspb.AlertConfigDesc, author string, retain int) (uint64, error)
+}
+
 // NewAlertStore returns a alertmanager store backend client based on the provided cfg.
 func NewAlertStore(ctx context.Context, cfg Config, cfgProvider bucket.TenantConfigProvider, logger log.Logger, reg prometheus.Registerer) (AlertStore, error) {
 	if cfg.Backend == local.Name {
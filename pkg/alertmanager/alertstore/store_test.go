@@ -7,6 +7,7 @@ package alertstore
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/go-kit/log"
@@ -239,3 +240,137 @@ func TestBucketAlertStore_GetSetDeleteFullState(t *testing.T) {
 		require.NoError(t, store.DeleteFullState(ctx, "user-1"))
 	}
 }
+
+func TestBucketAlertStore_GetSetDeleteTemplate(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	store := bucketclient.NewBucketAlertStore(bucket, nil, log.NewNopLogger())
+
+	ctx := context.Background()
+
+	// The storage is empty.
+	{
+		_, _, err := store.GetTemplate(ctx, "user-1", "first.tpl", 0)
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		templates, err := store.ListTemplates(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, templates)
+	}
+
+	// The storage contains multiple versions of the templates.
+	{
+		for i := 1; i <= 12; i++ {
+			version, err := store.SetTemplate(ctx, "user-1", alertspb.TemplateDesc{Filename: "first.tpl", Body: fmt.Sprintf("content-%d", i)})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(i), version)
+		}
+
+		version, err := store.SetTemplate(ctx, "user-1", alertspb.TemplateDesc{Filename: "second.tpl", Body: "content"})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), version)
+
+		templates, err := store.ListTemplates(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]uint64{"first.tpl": 12, "second.tpl": 1}, templates)
+
+		// The latest version is returned by default.
+		tmpl, version, err := store.GetTemplate(ctx, "user-1", "first.tpl", 0)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(12), version)
+		assert.Equal(t, alertspb.TemplateDesc{Filename: "first.tpl", Body: "content-12"}, tmpl)
+
+		tmpl, version, err = store.GetTemplate(ctx, "user-1", "first.tpl", 3)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), version)
+		assert.Equal(t, alertspb.TemplateDesc{Filename: "first.tpl", Body: "content-3"}, tmpl)
+
+		// The oldest versions are no longer retained.
+		_, _, err = store.GetTemplate(ctx, "user-1", "first.tpl", 2)
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		// Ensure the template is stored at the expected location.
+		exists, err := bucket.Exists(ctx, "alertmanager/user-1/templates/first.tpl/12")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		// Templates are isolated between tenants.
+		templates, err = store.ListTemplates(ctx, "user-2")
+		require.NoError(t, err)
+		assert.Empty(t, templates)
+	}
+
+	// The storage has had a template deleted.
+	{
+		require.NoError(t, store.DeleteTemplate(ctx, "user-1", "first.tpl"))
+
+		_, _, err := store.GetTemplate(ctx, "user-1", "first.tpl", 0)
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		templates, err := store.ListTemplates(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]uint64{"second.tpl": 1}, templates)
+
+		// Delete again (should be idempotent).
+		require.NoError(t, store.DeleteTemplate(ctx, "user-1", "first.tpl"))
+	}
+}
+
+func TestBucketAlertStore_AlertConfigHistory(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	store := bucketclient.NewBucketAlertStore(bucket, nil, log.NewNopLogger())
+
+	ctx := context.Background()
+
+	// The storage is empty.
+	{
+		_, err := store.GetAlertConfigVersion(ctx, "user-1", 1)
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		versions, err := store.ListAlertConfigVersions(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	}
+
+	// The storage contains more versions than the retained ones.
+	{
+		var added []uint64
+		for i := 1; i <= 5; i++ {
+			cfg := alertspb.AlertConfigDesc{User: "user-1", RawConfig: fmt.Sprintf("config-%d", i)}
+			version, err := store.AddAlertConfigVersion(ctx, cfg, fmt.Sprintf("author-%d", i), 3)
+			require.NoError(t, err)
+
+			// The versions are monotonically increasing.
+			if len(added) > 0 {
+				assert.Greater(t, version, added[len(added)-1])
+			}
+			added = append(added, version)
+		}
+
+		versions, err := store.ListAlertConfigVersions(ctx, "user-1")
+		require.NoError(t, err)
+		require.Len(t, versions, 3)
+		for i, v := range versions {
+			assert.Equal(t, added[i+2], v.Version)
+			assert.Equal(t, fmt.Sprintf("author-%d", i+3), v.Author)
+			assert.False(t, v.Timestamp.IsZero())
+		}
+
+		cfg, err := store.GetAlertConfigVersion(ctx, "user-1", added[3])
+		require.NoError(t, err)
+		assert.Equal(t, alertspb.AlertConfigDesc{User: "user-1", RawConfig: "config-4"}, cfg)
+
+		// The oldest versions are no longer retained.
+		_, err = store.GetAlertConfigVersion(ctx, "user-1", added[1])
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		// Ensure the version is stored at the expected location.
+		exists, err := bucket.Exists(ctx, fmt.Sprintf("alertmanager/user-1/config-history/%d", added[4]))
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		// The history is isolated between tenants.
+		versions, err = store.ListAlertConfigVersions(ctx, "user-2")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	}
+}
@@ -13,9 +13,12 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
 	"github.com/grafana/dskit/concurrency"
 	"github.com/pkg/errors"
 	"github.com/prometheus/alertmanager/config"
@@ -41,6 +44,17 @@ const (
 	errConfigurationTooBig   = "Alertmanager configuration is too big, limit: %d bytes"
 	errTooManyTemplates      = "too many templates in the configuration: %d (limit: %d)"
 	errTemplateTooBig        = "template %s is too big: %d bytes (limit: %d bytes)"
+	errTemplateStoreDisabled = "the Alertmanager template store is not enabled"
+	errReadingTemplate       = "unable to read the template"
+	errStoringTemplate       = "unable to store the template"
+	errDeletingTemplate      = "unable to delete the template"
+	errListingTemplates      = "unable to list the templates"
+	errValidatingTemplate    = "error validating the template"
+	errInvalidTemplateVer    = "invalid template version"
+	errConfigHistoryDisabled = "the Alertmanager configuration history is not enabled"
+	errListingConfigVersions = "unable to list the Alertmanager config versions"
+	errReadingConfigVersion  = "unable to read the Alertmanager config version"
+	errInvalidConfigVer      = "invalid Alertmanager config version"
 
 	fetchConcurrency = 16
 )
@@ -113,6 +127,54 @@ func (am *MultitenantAlertmanager) SetUserConfig(w http.ResponseWriter, r *http.
 		return
 	}
 
+	cfgDesc, ok := am.readUserConfig(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	if err := validateUserConfig(logger, cfgDesc, am.limits, userID); err != nil {
+		level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = am.store.SetAlertConfig(r.Context(), cfgDesc)
+	if err != nil {
+		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	am.addAlertConfigVersion(r, logger, cfgDesc)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// ValidateUserConfig validates the Alertmanager config of the user, including the templates, without storing it.
+// The response always has status 200 if the config could be read, and lists the validation errors.
+func (am *MultitenantAlertmanager) ValidateUserConfig(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	cfgDesc, ok := am.readUserConfig(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	errs := validateUserConfigDetailed(logger, cfgDesc, am.limits, userID)
+	writeYAMLResponse(w, logger, http.StatusOK, ConfigValidationResult{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	})
+}
+
+// readUserConfig reads the Alertmanager config of the user from the request body. If the config can't be read,
+// the error response is written and false is returned.
+func (am *MultitenantAlertmanager) readUserConfig(w http.ResponseWriter, r *http.Request, logger log.Logger, userID string) (alertspb.AlertConfigDesc, bool) {
 	var input io.Reader
 	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
 	if maxConfigSize > 0 {
@@ -127,14 +189,14 @@ func (am *MultitenantAlertmanager) SetUserConfig(w http.ResponseWriter, r *http.
 	if err != nil {
 		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
 		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusBadRequest)
-		return
+		return alertspb.AlertConfigDesc{}, false
 	}
 
 	if maxConfigSize > 0 && len(payload) > maxConfigSize {
 		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
 		level.Warn(logger).Log("msg", msg)
 		http.Error(w, msg, http.StatusBadRequest)
-		return
+		return alertspb.AlertConfigDesc{}, false
 	}
 
 	cfg := &UserConfig{}
@@ -142,47 +204,390 @@ func (am *MultitenantAlertmanager) SetUserConfig(w http.ResponseWriter, r *http.
 	if err != nil {
 		level.Error(logger).Log("msg", errMarshallingYAML, "err", err.Error())
 		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusBadRequest)
+		return alertspb.AlertConfigDesc{}, false
+	}
+
+	return alertspb.ToProto(cfg.AlertmanagerConfig, cfg.TemplateFiles, userID), true
+}
+
+// DeleteUserConfig is exposed via user-visible API (if enabled, uses DELETE method), but also as an internal endpoint using POST method.
+// Note that if no config exists for a user, StatusOK is returned.
+func (am *MultitenantAlertmanager) DeleteUserConfig(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	err = am.store.DeleteAlertConfig(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errDeletingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errDeletingConfiguration, err.Error()), http.StatusInternalServerError)
 		return
 	}
 
-	cfgDesc := alertspb.ToProto(cfg.AlertmanagerConfig, cfg.TemplateFiles, userID)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListUserConfigVersions returns the versions of the user's Alertmanager configuration retained in the configuration history.
+func (am *MultitenantAlertmanager) ListUserConfigVersions(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkConfigHistoryRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	versions, err := am.configHistoryStore.ListAlertConfigVersions(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errListingConfigVersions, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errListingConfigVersions, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	writeYAMLResponse(w, logger, http.StatusOK, versions)
+}
+
+// GetUserConfigVersion returns a version of the user's Alertmanager configuration retained in the configuration history.
+func (am *MultitenantAlertmanager) GetUserConfigVersion(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkConfigHistoryRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	cfg, ok := am.getUserConfigVersion(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	writeYAMLResponse(w, logger, http.StatusOK, &UserConfig{
+		TemplateFiles:      alertspb.ParseTemplates(cfg),
+		AlertmanagerConfig: cfg.RawConfig,
+	})
+}
+
+// RollbackUserConfig replaces the user's Alertmanager configuration with a version retained in the configuration
+// history. The rollback is recorded in the configuration history as a new version.
+func (am *MultitenantAlertmanager) RollbackUserConfig(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkConfigHistoryRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	cfgDesc, ok := am.getUserConfigVersion(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	// The limits may have changed since the version has been stored, so it's validated again.
+	cfgDesc.User = userID
 	if err := validateUserConfig(logger, cfgDesc, am.limits, userID); err != nil {
 		level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
 		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	err = am.store.SetAlertConfig(r.Context(), cfgDesc)
-	if err != nil {
+	if err := am.store.SetAlertConfig(r.Context(), cfgDesc); err != nil {
 		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
 		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
 		return
 	}
 
+	am.addAlertConfigVersion(r, logger, cfgDesc)
 	w.WriteHeader(http.StatusCreated)
 }
 
-// DeleteUserConfig is exposed via user-visible API (if enabled, uses DELETE method), but also as an internal endpoint using POST method.
-// Note that if no config exists for a user, StatusOK is returned.
-func (am *MultitenantAlertmanager) DeleteUserConfig(w http.ResponseWriter, r *http.Request) {
-	logger := util_log.WithContext(r.Context(), am.logger)
+// checkConfigHistoryRequest returns the user ID of a configuration history API request. If the request can't be
+// served, the error response is written and false is returned.
+func (am *MultitenantAlertmanager) checkConfigHistoryRequest(w http.ResponseWriter, r *http.Request, logger log.Logger) (string, bool) {
 	userID, err := tenant.TenantID(r.Context())
 	if err != nil {
 		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
 		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return "", false
+	}
+
+	if am.configHistoryStore == nil {
+		http.Error(w, errConfigHistoryDisabled, http.StatusNotImplemented)
+		return "", false
+	}
+
+	return userID, true
+}
+
+// getUserConfigVersion loads the configuration version requested via the "version" path parameter. If the version
+// can't be loaded, the error response is written and false is returned.
+func (am *MultitenantAlertmanager) getUserConfigVersion(w http.ResponseWriter, r *http.Request, logger log.Logger, userID string) (alertspb.AlertConfigDesc, bool) {
+	v := mux.Vars(r)["version"]
+	version, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || version == 0 {
+		http.Error(w, fmt.Sprintf("%s: %s", errInvalidConfigVer, v), http.StatusBadRequest)
+		return alertspb.AlertConfigDesc{}, false
+	}
+
+	cfg, err := am.configHistoryStore.GetAlertConfigVersion(r.Context(), userID, version)
+	if err != nil {
+		if errors.Is(err, alertspb.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			level.Error(logger).Log("msg", errReadingConfigVersion, "err", err.Error())
+			http.Error(w, fmt.Sprintf("%s: %s", errReadingConfigVersion, err.Error()), http.StatusInternalServerError)
+		}
+		return alertspb.AlertConfigDesc{}, false
+	}
+
+	return cfg, true
+}
+
+// addAlertConfigVersion records the configuration, which has just been stored, in the configuration history.
+// The failure is not returned to the client, because the configuration has been successfully stored.
+func (am *MultitenantAlertmanager) addAlertConfigVersion(r *http.Request, logger log.Logger, cfg alertspb.AlertConfigDesc) {
+	if am.configHistoryStore == nil {
 		return
 	}
 
-	err = am.store.DeleteAlertConfig(r.Context(), userID)
+	author := r.Header.Get(util.ConfigAuthorHeader)
+	if _, err := am.configHistoryStore.AddAlertConfigVersion(r.Context(), cfg, author, am.cfg.ConfigHistorySize); err != nil {
+		level.Warn(logger).Log("msg", "unable to record the Alertmanager config in the configuration history", "err", err.Error(), "user", cfg.User)
+	}
+}
+
+// UserTemplate is used to communicate a user notification template stored in the template store.
+type UserTemplate struct {
+	Name    string `yaml:"name"`
+	Version uint64 `yaml:"version"`
+	Body    string `yaml:"body,omitempty"`
+}
+
+// ListUserTemplates returns the name and the latest version of all templates of the user stored in the template store.
+func (am *MultitenantAlertmanager) ListUserTemplates(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkTemplateStoreRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	versions, err := am.templateStore.ListTemplates(r.Context(), userID)
 	if err != nil {
-		level.Error(logger).Log("msg", errDeletingConfiguration, "err", err.Error())
-		http.Error(w, fmt.Sprintf("%s: %s", errDeletingConfiguration, err.Error()), http.StatusInternalServerError)
+		level.Error(logger).Log("msg", errListingTemplates, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errListingTemplates, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	templates := make([]UserTemplate, 0, len(versions))
+	for name, version := range versions {
+		templates = append(templates, UserTemplate{Name: name, Version: version})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	writeYAMLResponse(w, logger, http.StatusOK, templates)
+}
+
+// GetUserTemplate returns a template of the user stored in the template store. The latest version of the template
+// is returned, unless a specific version is requested via the "version" query parameter.
+func (am *MultitenantAlertmanager) GetUserTemplate(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkTemplateStoreRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	var version uint64
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.ParseUint(v, 10, 64); err != nil || version == 0 {
+			http.Error(w, fmt.Sprintf("%s: %s", errInvalidTemplateVer, v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tmpl, version, err := am.templateStore.GetTemplate(r.Context(), userID, mux.Vars(r)["name"], version)
+	if err != nil {
+		if errors.Is(err, alertspb.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeYAMLResponse(w, logger, http.StatusOK, UserTemplate{Name: tmpl.Filename, Version: version, Body: tmpl.Body})
+}
+
+// SetUserTemplate validates the template in the request body and stores it as a new version of the template.
+func (am *MultitenantAlertmanager) SetUserTemplate(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkTemplateStoreRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	tmpl, ok := am.readAndValidateUserTemplate(w, r, logger, userID)
+	if !ok {
+		return
+	}
+
+	version, err := am.templateStore.SetTemplate(r.Context(), userID, tmpl)
+	if err != nil {
+		level.Error(logger).Log("msg", errStoringTemplate, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errStoringTemplate, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	writeYAMLResponse(w, logger, http.StatusCreated, UserTemplate{Name: tmpl.Filename, Version: version})
+}
+
+// ValidateUserTemplate validates the template in the request body, without storing it.
+func (am *MultitenantAlertmanager) ValidateUserTemplate(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkTemplateStoreRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	if _, ok := am.readAndValidateUserTemplate(w, r, logger, userID); !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteUserTemplate deletes all versions of a template of the user from the template store.
+// Note that if the template doesn't exist, StatusOK is returned.
+func (am *MultitenantAlertmanager) DeleteUserTemplate(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, ok := am.checkTemplateStoreRequest(w, r, logger)
+	if !ok {
+		return
+	}
+
+	if err := am.templateStore.DeleteTemplate(r.Context(), userID, mux.Vars(r)["name"]); err != nil {
+		level.Error(logger).Log("msg", errDeletingTemplate, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errDeletingTemplate, err.Error()), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// checkTemplateStoreRequest returns the user ID of a template store API request. If the request can't be served,
+// the error response is written and false is returned.
+func (am *MultitenantAlertmanager) checkTemplateStoreRequest(w http.ResponseWriter, r *http.Request, logger log.Logger) (string, bool) {
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return "", false
+	}
+
+	if am.templateStore == nil {
+		http.Error(w, errTemplateStoreDisabled, http.StatusNotImplemented)
+		return "", false
+	}
+
+	return userID, true
+}
+
+// readAndValidateUserTemplate reads the template from the request body and validates it. If the template
+// is invalid, the error response is written and false is returned.
+func (am *MultitenantAlertmanager) readAndValidateUserTemplate(w http.ResponseWriter, r *http.Request, logger log.Logger, userID string) (alertspb.TemplateDesc, bool) {
+	tmpl := alertspb.TemplateDesc{Filename: mux.Vars(r)["name"]}
+
+	input := r.Body
+	maxSize := am.limits.AlertmanagerMaxTemplateSize(userID)
+	if maxSize > 0 {
+		// LimitReader will return EOF after reading specified number of bytes. To check if
+		// we have read too many bytes, allow one extra byte.
+		input = io.NopCloser(io.LimitReader(r.Body, int64(maxSize)+1))
+	}
+
+	body, err := io.ReadAll(input)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingTemplate, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingTemplate, err.Error()), http.StatusBadRequest)
+		return tmpl, false
+	}
+	tmpl.Body = string(body)
+
+	// The template store is checked to enforce the max number of templates only when a new template is added.
+	stored, err := am.templateStore.ListTemplates(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errListingTemplates, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errListingTemplates, err.Error()), http.StatusInternalServerError)
+		return tmpl, false
+	}
+	numTemplates := len(stored)
+	if _, ok := stored[tmpl.Filename]; !ok {
+		numTemplates++
+	}
+
+	if err := validateUserTemplate(logger, tmpl, numTemplates, am.limits, userID); err != nil {
+		level.Warn(logger).Log("msg", errValidatingTemplate, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingTemplate, err.Error()), http.StatusBadRequest)
+		return tmpl, false
+	}
+
+	return tmpl, true
+}
+
+// validateUserTemplate validates a template to be stored in the template store, given the number of templates
+// the user would have in the template store.
+func validateUserTemplate(logger log.Logger, tmpl alertspb.TemplateDesc, numTemplates int, limits Limits, user string) error {
+	if tmpl.Filename == "" || tmpl.Filename == "." || tmpl.Filename == ".." {
+		return fmt.Errorf("invalid template name %q", tmpl.Filename)
+	}
+	if err := validateTemplateFilename(tmpl.Filename); err != nil {
+		return err
+	}
+
+	if l := limits.AlertmanagerMaxTemplatesCount(user); l > 0 && numTemplates > l {
+		return fmt.Errorf(errTooManyTemplates, numTemplates, l)
+	}
+	if maxSize := limits.AlertmanagerMaxTemplateSize(user); maxSize > 0 && len(tmpl.Body) > maxSize {
+		return fmt.Errorf(errTemplateTooBig, tmpl.Filename, len(tmpl.Body), maxSize)
+	}
+
+	userTempDir, err := os.MkdirTemp("", "validate-template-"+user)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(userTempDir)
+
+	templateFilepath, err := safeTemplateFilepath(userTempDir, tmpl.Filename)
+	if err != nil {
+		return err
+	}
+
+	if _, err = storeTemplateFile(templateFilepath, tmpl.Body); err != nil {
+		level.Error(logger).Log("msg", "unable to store template file", "err", err, "user", user)
+		return fmt.Errorf("unable to store template file '%s'", tmpl.Filename)
+	}
+
+	if _, err = template.FromGlobs([]string{templateFilepath}, withCustomFunctions(user)); err != nil {
+		return err
+	}
+
+	return validateStoredTemplateFunctions(user, tmpl)
+}
+
+func writeYAMLResponse(w http.ResponseWriter, logger log.Logger, statusCode int, v interface{}) {
+	d, err := yaml.Marshal(v)
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(d); err != nil {
+		level.Warn(logger).Log("msg", "failed to write the response", "err", err)
+	}
+}
+
 // Partially copied from: https://github.com/prometheus/alertmanager/blob/8e861c646bf67599a1704fc843c6a94d519ce312/cli/check_config.go#L65-L96
 func validateUserConfig(logger log.Logger, cfg alertspb.AlertConfigDesc, limits Limits, user string) error {
 	// We don't have a valid use case for empty configurations. If a tenant does not have a
@@ -272,6 +677,101 @@ func validateUserConfig(logger log.Logger, cfg alertspb.AlertConfigDesc, limits
 	return nil
 }
 
+// ConfigValidationResult is the response of the Alertmanager config validation API.
+type ConfigValidationResult struct {
+	Valid  bool                    `yaml:"valid"`
+	Errors []ConfigValidationError `yaml:"errors,omitempty"`
+}
+
+// Kinds of the Alertmanager config validation errors.
+const (
+	ConfigValidationErrorKindConfig   = "config"
+	ConfigValidationErrorKindLimits   = "limits"
+	ConfigValidationErrorKindTemplate = "template"
+)
+
+// ConfigValidationError is an error found validating an Alertmanager config.
+type ConfigValidationError struct {
+	Kind     string `yaml:"kind"`
+	Template string `yaml:"template,omitempty"`
+	Message  string `yaml:"message"`
+}
+
+// validateUserConfigDetailed validates the input config like validateUserConfig, but returns all the errors
+// found, each one with the kind of error and the template it refers to, if any. The returned errors are
+// empty if and only if validateUserConfig succeeds.
+func validateUserConfigDetailed(logger log.Logger, cfg alertspb.AlertConfigDesc, limits Limits, user string) []ConfigValidationError {
+	var errs []ConfigValidationError
+	addErr := func(kind, tmpl string, err error) {
+		errs = append(errs, ConfigValidationError{Kind: kind, Template: tmpl, Message: err.Error()})
+	}
+
+	// All the templates are parsed if the config can't be loaded.
+	var templateGlobs []string
+	parseAllTemplates := true
+
+	if cfg.RawConfig == "" {
+		addErr(ConfigValidationErrorKindConfig, "", fmt.Errorf("configuration provided is empty"))
+	} else if amCfg, err := config.Load(cfg.RawConfig); err != nil {
+		addErr(ConfigValidationErrorKindConfig, "", err)
+	} else {
+		if err := validateAlertmanagerConfig(amCfg); err != nil {
+			addErr(ConfigValidationErrorKindConfig, "", err)
+		}
+		for _, name := range amCfg.Templates {
+			if err := validateTemplateFilename(name); err != nil {
+				addErr(ConfigValidationErrorKindConfig, "", err)
+			}
+		}
+		templateGlobs = amCfg.Templates
+		parseAllTemplates = false
+	}
+
+	if l := limits.AlertmanagerMaxTemplatesCount(user); l > 0 && len(cfg.Templates) > l {
+		addErr(ConfigValidationErrorKindLimits, "", fmt.Errorf(errTooManyTemplates, len(cfg.Templates), l))
+	}
+
+	maxSize := limits.AlertmanagerMaxTemplateSize(user)
+	for _, tmpl := range cfg.Templates {
+		if size := len(tmpl.GetBody()); maxSize > 0 && size > maxSize {
+			addErr(ConfigValidationErrorKindLimits, tmpl.Filename, fmt.Errorf(errTemplateTooBig, tmpl.Filename, size, maxSize))
+			continue
+		}
+		if err := validateTemplateFilename(tmpl.Filename); err != nil {
+			addErr(ConfigValidationErrorKindTemplate, tmpl.Filename, err)
+			continue
+		}
+
+		// The templates are parsed one by one to report the errors of each template. Like the Alertmanager,
+		// only the templates matching the templates referenced by the config are parsed.
+		if !parseAllTemplates && !matchesAnyTemplateGlob(tmpl.Filename, templateGlobs) {
+			continue
+		}
+		if err := validateUserTemplate(logger, *tmpl, 0, limits, user); err != nil {
+			addErr(ConfigValidationErrorKindTemplate, tmpl.Filename, err)
+		}
+	}
+
+	// Ensure the detailed validation never accepts a config which would be rejected when stored.
+	if len(errs) == 0 {
+		if err := validateUserConfig(logger, cfg, limits, user); err != nil {
+			addErr(ConfigValidationErrorKindConfig, "", err)
+		}
+	}
+
+	return errs
+}
+
+// matchesAnyTemplateGlob returns whether the template filename matches any of the input globs.
+func matchesAnyTemplateGlob(filename string, globs []string) bool {
+	for _, glob := range globs {
+		if ok, err := filepath.Match(glob, filename); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (am *MultitenantAlertmanager) ListAllConfigs(w http.ResponseWriter, r *http.Request) {
 	logger := util_log.WithContext(r.Context(), am.logger)
 	userIDs, err := am.store.ListAllUsers(r.Context())
@@ -12,6 +12,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/log"
@@ -23,9 +25,11 @@ import (
 	commoncfg "github.com/prometheus/common/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/thanos-io/objstore"
+	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
 	"github.com/grafana/mimir/pkg/alertmanager/alertstore/bucketclient"
+	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 
 	"github.com/stretchr/testify/require"
@@ -905,6 +909,291 @@ func TestMultitenantAlertmanager_DeleteUserConfig(t *testing.T) {
 	}
 }
 
+func TestMultitenantAlertmanager_ValidateUserConfig(t *testing.T) {
+	const validAlertmanagerConfig = `
+  templates:
+    - '*.tpl'
+  receivers:
+    - name: default-receiver
+  route:
+    receiver: default-receiver
+`
+
+	tests := map[string]struct {
+		cfg             string
+		maxTemplateSize int
+		expectedCode    int
+		expectedResult  ConfigValidationResult
+	}{
+		"valid config": {
+			cfg: `
+template_files:
+  first.tpl: '{{ define "first" }}first{{ end }}'
+alertmanager_config: |` + validAlertmanagerConfig,
+			expectedCode:   http.StatusOK,
+			expectedResult: ConfigValidationResult{Valid: true},
+		},
+		"empty config": {
+			cfg:          `alertmanager_config: ""`,
+			expectedCode: http.StatusOK,
+			expectedResult: ConfigValidationResult{Errors: []ConfigValidationError{
+				{Kind: ConfigValidationErrorKindConfig, Message: "configuration provided is empty"},
+			}},
+		},
+		"invalid config and template": {
+			cfg: `
+template_files:
+  invalid.tpl: '{{ invalid }}'
+alertmanager_config: |
+  templates:
+    - '*.tpl'
+  route:
+    receiver: default-receiver
+`,
+			expectedCode: http.StatusOK,
+			expectedResult: ConfigValidationResult{Errors: []ConfigValidationError{
+				{Kind: ConfigValidationErrorKindConfig, Message: `undefined receiver "default-receiver" used in route`},
+				{Kind: ConfigValidationErrorKindTemplate, Template: "invalid.tpl", Message: `template: invalid.tpl:1: function "invalid" not defined`},
+			}},
+		},
+		"templates not referenced by the config are not parsed": {
+			cfg: `
+template_files:
+  invalid.txt: '{{ invalid }}'
+alertmanager_config: |` + validAlertmanagerConfig,
+			expectedCode:   http.StatusOK,
+			expectedResult: ConfigValidationResult{Valid: true},
+		},
+		"template exceeding the limit": {
+			cfg: `
+template_files:
+  big.tpl: '{{ define "big" }}big{{ end }}'
+alertmanager_config: |` + validAlertmanagerConfig,
+			maxTemplateSize: 10,
+			expectedCode:    http.StatusOK,
+			expectedResult: ConfigValidationResult{Errors: []ConfigValidationError{
+				{Kind: ConfigValidationErrorKindLimits, Template: "big.tpl", Message: "template big.tpl is too big: 30 bytes (limit: 10 bytes)"},
+			}},
+		},
+		"malformed request": {
+			cfg:          `alertmanager_config: [`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			am := &MultitenantAlertmanager{
+				store:  prepareInMemoryAlertStore(),
+				logger: util_log.Logger,
+				limits: &mockAlertManagerLimits{maxSizeOfTemplate: testData.maxTemplateSize},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "http://alertmanager/api/v1/alerts/validate", bytes.NewReader([]byte(testData.cfg)))
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+			rec := httptest.NewRecorder()
+			am.ValidateUserConfig(rec, req)
+
+			require.Equal(t, testData.expectedCode, rec.Code)
+			if testData.expectedCode != http.StatusOK {
+				return
+			}
+
+			var result ConfigValidationResult
+			require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &result))
+			assert.Equal(t, testData.expectedResult, result)
+
+			// The config is never stored.
+			_, err := am.store.GetAlertConfig(context.Background(), "user-1")
+			assert.ErrorIs(t, err, alertspb.ErrNotFound)
+		})
+	}
+}
+
+func TestMultitenantAlertmanager_UserTemplatesAPI(t *testing.T) {
+	storage := objstore.NewInMemBucket()
+	alertStore := bucketclient.NewBucketAlertStore(storage, nil, log.NewNopLogger())
+	limits := &mockAlertManagerLimits{maxTemplatesCount: 2, maxSizeOfTemplate: 100}
+
+	am := &MultitenantAlertmanager{
+		store:         alertStore,
+		templateStore: alertStore,
+		logger:        util_log.Logger,
+		limits:        limits,
+	}
+
+	router := mux.NewRouter()
+	router.Path("/api/v1/alerts/templates").Methods(http.MethodGet).HandlerFunc(am.ListUserTemplates)
+	router.Path("/api/v1/alerts/templates/{name}").Methods(http.MethodGet).HandlerFunc(am.GetUserTemplate)
+	router.Path("/api/v1/alerts/templates/{name}").Methods(http.MethodPut).HandlerFunc(am.SetUserTemplate)
+	router.Path("/api/v1/alerts/templates/{name}").Methods(http.MethodDelete).HandlerFunc(am.DeleteUserTemplate)
+	router.Path("/api/v1/alerts/templates/{name}/validate").Methods(http.MethodPost).HandlerFunc(am.ValidateUserTemplate)
+
+	doRequest := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, "http://alertmanager"+path, bytes.NewReader([]byte(body)))
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	// Store two versions of a template.
+	code, body := doRequest(http.MethodPut, "/api/v1/alerts/templates/first.tpl", `{{ define "first" }}v1{{ end }}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "name: first.tpl\nversion: 1\n", body)
+
+	code, body = doRequest(http.MethodPut, "/api/v1/alerts/templates/first.tpl", `{{ define "first" }}v2{{ end }}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "name: first.tpl\nversion: 2\n", body)
+
+	// Get the latest and a specific version of the template.
+	code, body = doRequest(http.MethodGet, "/api/v1/alerts/templates/first.tpl", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "name: first.tpl\nversion: 2\nbody: '{{ define \"first\" }}v2{{ end }}'\n", body)
+
+	code, body = doRequest(http.MethodGet, "/api/v1/alerts/templates/first.tpl?version=1", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "name: first.tpl\nversion: 1\nbody: '{{ define \"first\" }}v1{{ end }}'\n", body)
+
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/templates/first.tpl?version=invalid", "")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/templates/missing.tpl", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	// Invalid templates are rejected, both by the validation and the store endpoints.
+	code, _ = doRequest(http.MethodPost, "/api/v1/alerts/templates/first.tpl/validate", `{{ define "first" }}v3{{ end }}`)
+	require.Equal(t, http.StatusOK, code)
+
+	code, body = doRequest(http.MethodPost, "/api/v1/alerts/templates/invalid.tpl/validate", `{{ invalid }}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, errValidatingTemplate)
+
+	code, body = doRequest(http.MethodPut, "/api/v1/alerts/templates/invalid.tpl", `{{ invalid }}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, errValidatingTemplate)
+
+	// Templates calling functions which aren't allowed are rejected.
+	code, body = doRequest(http.MethodPut, "/api/v1/alerts/templates/call.tpl", `{{ define "call" }}{{ call .Fn }}{{ end }}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, `function "call" is not allowed`)
+
+	code, body = doRequest(http.MethodPut, "/api/v1/alerts/templates/big.tpl", strings.Repeat("a", 101))
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "template big.tpl is too big")
+
+	// The max number of templates is enforced when adding a new template.
+	code, _ = doRequest(http.MethodPut, "/api/v1/alerts/templates/second.tpl", `{{ define "second" }}v1{{ end }}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	code, body = doRequest(http.MethodPut, "/api/v1/alerts/templates/third.tpl", `{{ define "third" }}v1{{ end }}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "too many templates")
+
+	code, body = doRequest(http.MethodGet, "/api/v1/alerts/templates", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "- name: first.tpl\n  version: 2\n- name: second.tpl\n  version: 1\n", body)
+
+	// Delete a template.
+	code, _ = doRequest(http.MethodDelete, "/api/v1/alerts/templates/first.tpl", "")
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/templates/first.tpl", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	// The API is not available when the template store is not enabled.
+	am.templateStore = nil
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/templates", "")
+	require.Equal(t, http.StatusNotImplemented, code)
+}
+
+func TestMultitenantAlertmanager_UserConfigHistoryAPI(t *testing.T) {
+	storage := objstore.NewInMemBucket()
+	alertStore := bucketclient.NewBucketAlertStore(storage, nil, log.NewNopLogger())
+
+	am := &MultitenantAlertmanager{
+		cfg:                &MultitenantAlertmanagerConfig{ConfigHistorySize: 2},
+		store:              alertStore,
+		configHistoryStore: alertStore,
+		logger:             util_log.Logger,
+		limits:             &mockAlertManagerLimits{},
+	}
+
+	router := mux.NewRouter()
+	router.Path("/api/v1/alerts").Methods(http.MethodGet).HandlerFunc(am.GetUserConfig)
+	router.Path("/api/v1/alerts").Methods(http.MethodPost).HandlerFunc(am.SetUserConfig)
+	router.Path("/api/v1/alerts/history").Methods(http.MethodGet).HandlerFunc(am.ListUserConfigVersions)
+	router.Path("/api/v1/alerts/history/{version}").Methods(http.MethodGet).HandlerFunc(am.GetUserConfigVersion)
+	router.Path("/api/v1/alerts/history/{version}/rollback").Methods(http.MethodPost).HandlerFunc(am.RollbackUserConfig)
+
+	doRequest := func(method, path, body, author string) (int, string) {
+		req := httptest.NewRequest(method, "http://alertmanager"+path, bytes.NewReader([]byte(body)))
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		if author != "" {
+			req.Header.Set(util.ConfigAuthorHeader, author)
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	userConfig := func(receiver string) string {
+		return fmt.Sprintf("template_files: {}\nalertmanager_config: |\n  route:\n    receiver: %s\n  receivers:\n    - name: %s\n", receiver, receiver)
+	}
+
+	// Store more versions than the retained ones.
+	for _, receiver := range []string{"first", "second", "third"} {
+		code, _ := doRequest(http.MethodPost, "/api/v1/alerts", userConfig(receiver), "author-"+receiver)
+		require.Equal(t, http.StatusCreated, code)
+	}
+
+	code, body := doRequest(http.MethodGet, "/api/v1/alerts/history", "", "")
+	require.Equal(t, http.StatusOK, code)
+
+	var versions []alertspb.ConfigVersion
+	require.NoError(t, yaml.Unmarshal([]byte(body), &versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, "author-second", versions[0].Author)
+	assert.Equal(t, "author-third", versions[1].Author)
+	assert.Less(t, versions[0].Version, versions[1].Version)
+	secondVersion, thirdVersion := strconv.FormatUint(versions[0].Version, 10), versions[1].Version
+
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/history/1", "", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/history/invalid", "", "")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, body = doRequest(http.MethodGet, "/api/v1/alerts/history/"+secondVersion, "", "")
+	require.Equal(t, http.StatusOK, code)
+	require.YAMLEq(t, userConfig("second"), body)
+
+	// Roll back to the second version.
+	code, _ = doRequest(http.MethodPost, "/api/v1/alerts/history/"+secondVersion+"/rollback", "", "")
+	require.Equal(t, http.StatusCreated, code)
+
+	code, body = doRequest(http.MethodGet, "/api/v1/alerts", "", "")
+	require.Equal(t, http.StatusOK, code)
+	require.YAMLEq(t, userConfig("second"), body)
+
+	// The rollback has been recorded as a new version.
+	code, body = doRequest(http.MethodGet, "/api/v1/alerts/history", "", "")
+	require.Equal(t, http.StatusOK, code)
+
+	versions = nil
+	require.NoError(t, yaml.Unmarshal([]byte(body), &versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, thirdVersion, versions[0].Version)
+	assert.Greater(t, versions[1].Version, thirdVersion)
+
+	// The API is not available when the configuration history is not enabled.
+	am.configHistoryStore = nil
+	code, _ = doRequest(http.MethodGet, "/api/v1/alerts/history", "", "")
+	require.Equal(t, http.StatusNotImplemented, code)
+}
+
 func TestAMConfigListUserConfig(t *testing.T) {
 	testCases := map[string]*UserConfig{
 		"user1": {
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +22,7 @@ import (
 	"github.com/grafana/dskit/concurrency"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/multierror"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/ring/client"
 	"github.com/grafana/dskit/services"
@@ -61,6 +63,8 @@ var (
 	errInvalidExternalURLMissingHostname   = errors.New("the configured external URL is invalid because it's missing the hostname")
 	errZoneAwarenessEnabledWithoutZoneInfo = errors.New("the configured alertmanager has zone awareness enabled but zone is not set")
 	errNotUploadingFallback                = errors.New("not uploading fallback configuration")
+	errTemplateStoreNotSupported           = errors.New("the Alertmanager template store requires an object storage backend")
+	errConfigHistoryNotSupported           = errors.New("the Alertmanager configuration history requires an object storage backend")
 )
 
 // MultitenantAlertmanagerConfig is the configuration for a multitenant Alertmanager.
@@ -80,6 +84,10 @@ type MultitenantAlertmanagerConfig struct {
 
 	EnableAPI bool `yaml:"enable_api" category:"advanced"`
 
+	TemplateStoreEnabled bool `yaml:"template_store_enabled" category:"experimental"`
+
+	ConfigHistorySize int `yaml:"config_history_size" category:"experimental"`
+
 	MaxConcurrentGetRequestsPerTenant int `yaml:"max_concurrent_get_requests_per_tenant" category:"advanced"`
 
 	// For distributor.
@@ -87,6 +95,8 @@ type MultitenantAlertmanagerConfig struct {
 
 	// For the state persister.
 	Persister PersisterConfig `yaml:",inline"`
+
+	SilencesFederation SilencesFederationConfig `yaml:"silences_federation"`
 }
 
 const (
@@ -106,10 +116,13 @@ func (cfg *MultitenantAlertmanagerConfig) RegisterFlags(f *flag.FlagSet, logger
 	f.DurationVar(&cfg.PollInterval, "alertmanager.configs.poll-interval", 15*time.Second, "How frequently to poll Alertmanager configs.")
 
 	f.BoolVar(&cfg.EnableAPI, "alertmanager.enable-api", true, "Enable the alertmanager config API.")
+	f.BoolVar(&cfg.TemplateStoreEnabled, "alertmanager.template-store-enabled", false, "Enable storing the notification templates of each tenant in the Alertmanager storage, independently of the Alertmanager configuration, and managing them via the templates API. The latest version of the stored templates is used alongside the templates of the Alertmanager configuration, which take precedence if they have the same name. Requires an object storage backend.")
+	f.IntVar(&cfg.ConfigHistorySize, "alertmanager.config-history-size", 0, "How many versions of the Alertmanager configuration of each tenant to retain in the configuration history, which allows to list and roll back to previous versions of the configuration through the configuration API. 0 to disable. Requires an object storage backend.")
 	f.IntVar(&cfg.MaxConcurrentGetRequestsPerTenant, "alertmanager.max-concurrent-get-requests-per-tenant", 0, "Maximum number of concurrent GET requests allowed per tenant. The zero value (and negative values) result in a limit of GOMAXPROCS or 8, whichever is larger. Status code 503 is served for GET requests that would exceed the concurrency limit.")
 
 	cfg.AlertmanagerClient.RegisterFlagsWithPrefix("alertmanager.alertmanager-client", f)
 	cfg.Persister.RegisterFlagsWithPrefix("alertmanager", f)
+	cfg.SilencesFederation.RegisterFlagsWithPrefix("alertmanager.silences-federation", f)
 	cfg.ShardingRing.RegisterFlags(f, logger)
 
 	f.DurationVar(&cfg.PeerTimeout, "alertmanager.peer-timeout", defaultPeerTimeout, "Time to wait between peers to send notifications.")
@@ -140,6 +153,10 @@ func (cfg *MultitenantAlertmanagerConfig) Validate() error {
 		return err
 	}
 
+	if err := cfg.SilencesFederation.Validate(); err != nil {
+		return err
+	}
+
 	if cfg.ShardingRing.ZoneAwarenessEnabled && cfg.ShardingRing.InstanceZone == "" {
 		return errZoneAwarenessEnabledWithoutZoneInfo
 	}
@@ -250,6 +267,17 @@ type MultitenantAlertmanager struct {
 
 	store alertstore.AlertStore
 
+	// Set only if the template store is enabled.
+	templateStore alertstore.TemplateStore
+
+	// The templates loaded from the template store, by user and template name. Template versions are
+	// immutable, so a template is fetched again only when its latest version changes.
+	storedTemplatesMtx sync.Mutex
+	storedTemplates    map[string]map[string]storedTemplate
+
+	// Set only if the configuration history is enabled.
+	configHistoryStore alertstore.AlertConfigHistoryStore
+
 	// The fallback config is stored as a string and parsed every time it's needed
 	// because we mutate the parsed results and don't want those changes to take
 	// effect here.
@@ -267,6 +295,10 @@ type MultitenantAlertmanager struct {
 
 	alertmanagerClientsPool ClientsPool
 
+	// Pool of clients of the Alertmanagers of other Mimir clusters, which the silences are replicated to.
+	// Nil if the silences federation is disabled.
+	federationClientsPool ClientsPool
+
 	limits Limits
 
 	registry          prometheus.Registerer
@@ -369,6 +401,22 @@ func createMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, fallbackC
 		}),
 	}
 
+	if cfg.TemplateStoreEnabled {
+		templateStore, ok := store.(alertstore.TemplateStore)
+		if !ok {
+			return nil, errTemplateStoreNotSupported
+		}
+		am.templateStore = templateStore
+	}
+
+	if cfg.ConfigHistorySize > 0 {
+		configHistoryStore, ok := store.(alertstore.AlertConfigHistoryStore)
+		if !ok {
+			return nil, errConfigHistoryNotSupported
+		}
+		am.configHistoryStore = configHistoryStore
+	}
+
 	// Initialize the top-level metrics.
 	for _, r := range []string{reasonInitial, reasonPeriodic, reasonRingChange} {
 		am.syncTotal.WithLabelValues(r)
@@ -404,6 +452,12 @@ func createMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, fallbackC
 		return nil, errors.Wrap(err, "create distributor")
 	}
 
+	if len(cfg.SilencesFederation.Peers) > 0 {
+		peers := cfg.SilencesFederation.Peers
+		// The client metrics are not registered, because they're already registered by the ring clients pool.
+		am.federationClientsPool = newAlertmanagerClientsPool(func() ([]string, error) { return peers, nil }, cfg.AlertmanagerClient, logger, nil)
+	}
+
 	if registerer != nil {
 		registerer.MustRegister(am.alertmanagerMetrics)
 	}
@@ -597,11 +651,126 @@ func (am *MultitenantAlertmanager) loadAlertmanagerConfigs(ctx context.Context)
 		return nil, nil, errors.Wrapf(err, "failed to load alertmanager configurations for owned users")
 	}
 
+	if am.templateStore != nil {
+		am.loadStoredTemplates(ctx, configs)
+	}
+
 	am.tenantsDiscovered.Set(float64(numUsersDiscovered))
 	am.tenantsOwned.Set(float64(numUsersOwned))
 	return allUserIDs, configs, nil
 }
 
+// storedTemplate is a version of a template loaded from the template store. The template is nil
+// if it calls functions which aren't allowed in stored templates.
+type storedTemplate struct {
+	version uint64
+	tmpl    *alertspb.TemplateDesc
+}
+
+// loadStoredTemplates adds the latest version of the templates stored in the template store to the input
+// configs. The templates of the Alertmanager configuration take precedence over the stored ones with the
+// same name. If the stored templates of a user can't be loaded, the user's config is left unchanged.
+func (am *MultitenantAlertmanager) loadStoredTemplates(ctx context.Context, configs map[string]alertspb.AlertConfigDesc) {
+	userIDs := make([]string, 0, len(configs))
+	for userID := range configs {
+		userIDs = append(userIDs, userID)
+	}
+
+	// Forget the templates of the users which are no longer owned.
+	am.storedTemplatesMtx.Lock()
+	for userID := range am.storedTemplates {
+		if _, ok := configs[userID]; !ok {
+			delete(am.storedTemplates, userID)
+		}
+	}
+	am.storedTemplatesMtx.Unlock()
+
+	var configsMx sync.Mutex
+
+	_ = concurrency.ForEachUser(ctx, userIDs, fetchConcurrency, func(ctx context.Context, userID string) error {
+		templates, err := am.getStoredTemplates(ctx, userID)
+		if err != nil {
+			level.Warn(am.logger).Log("msg", "failed to load stored templates, only the templates of the Alertmanager configuration will be used", "user", userID, "err", err)
+			return nil
+		}
+
+		configsMx.Lock()
+		defer configsMx.Unlock()
+
+		configs[userID] = mergeStoredTemplates(configs[userID], templates)
+		return nil
+	})
+}
+
+// getStoredTemplates returns the latest version of the templates of the user stored in the template store.
+// Only the templates whose latest version isn't already loaded are fetched from the template store.
+func (am *MultitenantAlertmanager) getStoredTemplates(ctx context.Context, userID string) ([]*alertspb.TemplateDesc, error) {
+	versions, err := am.templateStore.ListTemplates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	am.storedTemplatesMtx.Lock()
+	loaded := am.storedTemplates[userID]
+	am.storedTemplatesMtx.Unlock()
+
+	updated := make(map[string]storedTemplate, len(versions))
+	templates := make([]*alertspb.TemplateDesc, 0, len(versions))
+	for name, version := range versions {
+		st, ok := loaded[name]
+		if !ok || st.version != version {
+			tmpl, _, err := am.templateStore.GetTemplate(ctx, userID, name, version)
+			if errors.Is(err, alertspb.ErrNotFound) {
+				// The template has been deleted in the meanwhile.
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			st = storedTemplate{version: version, tmpl: &tmpl}
+			if err := validateStoredTemplateFunctions(userID, tmpl); err != nil {
+				level.Warn(am.logger).Log("msg", "ignoring stored template calling functions which aren't allowed", "user", userID, "template", name, "version", version, "err", err)
+				st.tmpl = nil
+			}
+		}
+
+		updated[name] = st
+		if st.tmpl != nil {
+			templates = append(templates, st.tmpl)
+		}
+	}
+
+	am.storedTemplatesMtx.Lock()
+	if am.storedTemplates == nil {
+		am.storedTemplates = map[string]map[string]storedTemplate{}
+	}
+	am.storedTemplates[userID] = updated
+	am.storedTemplatesMtx.Unlock()
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Filename < templates[j].Filename })
+	return templates, nil
+}
+
+// mergeStoredTemplates returns a copy of the input config including the stored templates
+// whose name doesn't clash with any template of the config.
+func mergeStoredTemplates(cfg alertspb.AlertConfigDesc, stored []*alertspb.TemplateDesc) alertspb.AlertConfigDesc {
+	names := make(map[string]struct{}, len(cfg.Templates))
+	for _, tmpl := range cfg.Templates {
+		names[tmpl.Filename] = struct{}{}
+	}
+
+	templates := make([]*alertspb.TemplateDesc, 0, len(cfg.Templates)+len(stored))
+	templates = append(templates, cfg.Templates...)
+	for _, tmpl := range stored {
+		if _, ok := names[tmpl.Filename]; !ok {
+			templates = append(templates, tmpl)
+		}
+	}
+
+	cfg.Templates = templates
+	return cfg
+}
+
 func (am *MultitenantAlertmanager) isUserOwned(userID string) bool {
 	alertmanagers, err := am.ring.Get(shardByUser(userID), SyncRingOp, nil, nil, nil)
 	if err != nil {
@@ -778,6 +947,8 @@ func (am *MultitenantAlertmanager) newAlertmanager(userID string, amConfig *amco
 		Store:                             am.store,
 		PersisterConfig:                   am.cfg.Persister,
 		Limits:                            am.limits,
+		SilencesFederationConfig:          am.cfg.SilencesFederation,
+		SilencesFederator:                 am.silencesFederator(),
 	}, reg)
 	if err != nil {
 		return nil, fmt.Errorf("unable to start Alertmanager for user %v: %v", userID, err)
@@ -941,6 +1112,63 @@ func (am *MultitenantAlertmanager) ReplicateStateForUser(ctx context.Context, us
 	return err
 }
 
+// silencesFederator returns the SilencesFederator of the tenants' Alertmanagers, or nil if the silences
+// federation is disabled.
+func (am *MultitenantAlertmanager) silencesFederator() SilencesFederator {
+	if am.federationClientsPool == nil {
+		return nil
+	}
+	return am
+}
+
+// FederateSilencesForUser writes the given silences state to the Alertmanagers of other Mimir clusters. The state
+// is sent to all the federation peers, and merged by the ones owning the user, which replicate it to the other
+// replicas of their cluster.
+func (am *MultitenantAlertmanager) FederateSilencesForUser(ctx context.Context, userID string, part *clusterpb.Part) error {
+	peers := am.cfg.SilencesFederation.Peers
+
+	ctx, cancel := context.WithTimeout(user.InjectOrgID(ctx, userID), am.cfg.AlertmanagerClient.RemoteTimeout)
+	defer cancel()
+
+	errs := multierror.New()
+	errsMtx := sync.Mutex{}
+
+	_ = concurrency.ForEachJob(ctx, len(peers), len(peers), func(ctx context.Context, idx int) error {
+		addr := peers[idx]
+
+		err := func() error {
+			c, err := am.federationClientsPool.GetClientFor(addr)
+			if err != nil {
+				return err
+			}
+
+			resp, err := c.UpdateState(ctx, part)
+			if err != nil {
+				return err
+			}
+
+			switch resp.Status {
+			case alertmanagerpb.MERGE_ERROR:
+				level.Error(am.logger).Log("msg", "silences federation failed", "user", userID, "peer", addr, "err", resp.Error)
+			case alertmanagerpb.USER_NOT_FOUND:
+				level.Debug(am.logger).Log("msg", "user not found while trying to federate silences", "user", userID, "peer", addr)
+			}
+			return nil
+		}()
+
+		if err != nil {
+			errsMtx.Lock()
+			errs.Add(errors.Wrapf(err, "failed to federate silences to %s", addr))
+			errsMtx.Unlock()
+		}
+
+		// Never fail, so that the silences are sent to all the peers.
+		return nil
+	})
+
+	return errs.Err()
+}
+
 // ReadFullStateForUser attempts to read the full state from each replica for user. Note that it will try to obtain and return
 // state from all replicas, but will consider it a success if state is obtained from at least one replica.
 func (am *MultitenantAlertmanager) ReadFullStateForUser(ctx context.Context, userID string) ([]*clusterpb.FullState, error) {
@@ -1031,7 +1259,12 @@ func (am *MultitenantAlertmanager) UpdateState(ctx context.Context, part *cluste
 		}, nil
 	}
 
-	if err = userAM.mergePartialExternalState(part); err != nil {
+	if strings.HasPrefix(part.Key, federatedSilencesKeyPrefix) {
+		err = userAM.mergeFederatedSilences(part.Data)
+	} else {
+		err = userAM.mergePartialExternalState(part)
+	}
+	if err != nil {
 		return &alertmanagerpb.UpdateStateResponse{
 			Status: alertmanagerpb.MERGE_ERROR,
 			Error:  err.Error(),
@@ -35,6 +35,7 @@ import (
 	"github.com/prometheus/alertmanager/cluster/clusterpb"
 	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/silence/silencepb"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -153,6 +154,12 @@ func TestMultitenantAlertmanagerConfig_Validate(t *testing.T) {
 			},
 			expected: errInvalidPersistInterval,
 		},
+		"should fail if silences federation sync interval is negative": {
+			setup: func(t *testing.T, cfg *MultitenantAlertmanagerConfig) {
+				cfg.SilencesFederation.SyncInterval = -1
+			},
+			expected: errInvalidSilencesFederationSyncInterval,
+		},
 		"should fail if external URL ends with /": {
 			setup: func(t *testing.T, cfg *MultitenantAlertmanagerConfig) {
 				require.NoError(t, cfg.ExternalURL.Set("http://localhost/prefix/"))
@@ -2031,6 +2038,106 @@ func TestAlertmanager_StateReplication_InitialSyncFromPeers(t *testing.T) {
 }
 
 // prepareInMemoryAlertStore builds and returns an in-memory alert store.
+func TestAlertmanager_SilencesFederation(t *testing.T) {
+	const userID = "user-1"
+	ctx := context.Background()
+
+	// Two Mimir clusters with a single Alertmanager replica each, replicating the silences to each other.
+	clientPool := newPassthroughAlertmanagerClientPool()
+	clusters := map[string]*MultitenantAlertmanager{}
+	registries := map[string]*prometheus.Registry{}
+
+	for name, peer := range map[string]string{"primary": "dr", "dr": "primary"} {
+		ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+		t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+		store := prepareInMemoryAlertStore()
+		require.NoError(t, store.SetAlertConfig(ctx, alertspb.AlertConfigDesc{
+			User:      userID,
+			RawConfig: simpleConfigOne,
+			Templates: []*alertspb.TemplateDesc{},
+		}))
+
+		cfg := mockAlertmanagerConfig(t)
+		cfg.SilencesFederation.Peers = []string{peer}
+
+		reg := prometheus.NewPedanticRegistry()
+		am, err := createMultitenantAlertmanager(cfg, nil, store, ringStore, nil, log.NewNopLogger(), reg)
+		require.NoError(t, err)
+		am.federationClientsPool = clientPool
+		clientPool.setServer(name, am)
+
+		require.NoError(t, services.StartAndAwaitRunning(ctx, am))
+		t.Cleanup(func() {
+			require.NoError(t, services.StopAndAwaitTerminated(ctx, am))
+		})
+
+		clusters[name] = am
+		registries[name] = reg
+	}
+
+	getSilences := func(name string) []*silencepb.Silence {
+		am := clusters[name]
+		am.alertmanagersMtx.Lock()
+		userAM := am.alertmanagers[userID]
+		am.alertmanagersMtx.Unlock()
+		require.NotNil(t, userAM)
+
+		silences, _, err := userAM.silences.Query()
+		require.NoError(t, err)
+		return silences
+	}
+
+	createSilence := func(name, comment string) string {
+		data, err := json.Marshal(types.Silence{
+			Matchers: labels.Matchers{{Name: "instance", Value: "prometheus-one"}},
+			Comment:  comment,
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/alertmanager/api/v2/silences", bytes.NewReader(data))
+		req.Header.Set("content-type", "application/json")
+		w := httptest.NewRecorder()
+		clusters[name].serveRequest(w, req.WithContext(user.InjectOrgID(req.Context(), userID)))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := struct {
+			SilenceID string `json:"silenceID"`
+		}{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp.SilenceID
+	}
+
+	hasSilence := func(name, id string) bool {
+		for _, s := range getSilences(name) {
+			if s.Id == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A silence created in a cluster is replicated to the other one, in both directions.
+	primaryID := createSilence("primary", "Created in the primary cluster.")
+	test.Poll(t, 5*time.Second, true, func() interface{} {
+		return hasSilence("dr", primaryID)
+	})
+
+	drID := createSilence("dr", "Created in the DR cluster.")
+	test.Poll(t, 5*time.Second, true, func() interface{} {
+		return hasSilence("primary", drID)
+	})
+
+	for name, reg := range registries {
+		metrics, err := dskit_metrics.NewMetricFamilyMapFromGatherer(reg)
+		require.NoError(t, err)
+		assert.Greater(t, metrics.SumCounters("cortex_alertmanager_silences_federation_replication_total"), float64(0), name)
+		assert.Equal(t, float64(0), metrics.SumCounters("cortex_alertmanager_silences_federation_replication_failed_total"), name)
+	}
+}
+
 func prepareInMemoryAlertStore() alertstore.AlertStore {
 	return bucketclient.NewBucketAlertStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
 }
@@ -2285,3 +2392,79 @@ func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsCount(_ string) int {
 func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsSizeBytes(_ string) int {
 	return m.maxAlertsSizeBytes
 }
+
+func TestMergeStoredTemplates(t *testing.T) {
+	cfg := alertspb.AlertConfigDesc{
+		User:      "user-1",
+		RawConfig: simpleConfigOne,
+		Templates: []*alertspb.TemplateDesc{{Filename: "first.tpl", Body: "from-config"}},
+	}
+
+	stored := []*alertspb.TemplateDesc{
+		{Filename: "first.tpl", Body: "from-store"},
+		{Filename: "second.tpl", Body: "from-store"},
+	}
+
+	merged := mergeStoredTemplates(cfg, stored)
+
+	// The templates in the config take precedence over the stored ones.
+	assert.Equal(t, []*alertspb.TemplateDesc{
+		{Filename: "first.tpl", Body: "from-config"},
+		{Filename: "second.tpl", Body: "from-store"},
+	}, merged.Templates)
+
+	// The input config is not modified.
+	assert.Len(t, cfg.Templates, 1)
+}
+
+type countingTemplateStore struct {
+	alertstore.TemplateStore
+	gets int
+}
+
+func (s *countingTemplateStore) GetTemplate(ctx context.Context, userID, name string, version uint64) (alertspb.TemplateDesc, uint64, error) {
+	s.gets++
+	return s.TemplateStore.GetTemplate(ctx, userID, name, version)
+}
+
+func TestMultitenantAlertmanager_GetStoredTemplates(t *testing.T) {
+	ctx := context.Background()
+	alertStore := bucketclient.NewBucketAlertStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+	store := &countingTemplateStore{TemplateStore: alertStore}
+
+	am := &MultitenantAlertmanager{
+		store:         alertStore,
+		templateStore: store,
+		logger:        log.NewNopLogger(),
+	}
+
+	_, err := alertStore.SetTemplate(ctx, "user-1", alertspb.TemplateDesc{Filename: "first.tpl", Body: "v1"})
+	require.NoError(t, err)
+	_, err = alertStore.SetTemplate(ctx, "user-1", alertspb.TemplateDesc{Filename: "second.tpl", Body: "v1"})
+	require.NoError(t, err)
+
+	templates, err := am.getStoredTemplates(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []*alertspb.TemplateDesc{{Filename: "first.tpl", Body: "v1"}, {Filename: "second.tpl", Body: "v1"}}, templates)
+	assert.Equal(t, 2, store.gets)
+
+	// The templates whose latest version is already loaded are not fetched again.
+	_, err = alertStore.SetTemplate(ctx, "user-1", alertspb.TemplateDesc{Filename: "second.tpl", Body: "v2"})
+	require.NoError(t, err)
+
+	templates, err = am.getStoredTemplates(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []*alertspb.TemplateDesc{{Filename: "first.tpl", Body: "v1"}, {Filename: "second.tpl", Body: "v2"}}, templates)
+	assert.Equal(t, 3, store.gets)
+
+	// The templates calling functions which aren't allowed are ignored, and not fetched again.
+	_, err = alertStore.SetTemplate(ctx, "user-1", alertspb.TemplateDesc{Filename: "call.tpl", Body: `{{ call .Fn }}`})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		templates, err = am.getStoredTemplates(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, []*alertspb.TemplateDesc{{Filename: "first.tpl", Body: "v1"}, {Filename: "second.tpl", Body: "v2"}}, templates)
+		assert.Equal(t, 4, store.gets)
+	}
+}
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/cluster/clusterpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// Max number of silences updates waiting to be replicated to the federation peers, for each tenant.
+	silencesFederationQueueSize = 100
+
+	// Prefix of the key of the silences state sent to the federation peers, to tell it from the state
+	// replicated by the other replicas of the tenant.
+	federatedSilencesKeyPrefix = "federated:"
+)
+
+var (
+	errInvalidSilencesFederationSyncInterval = errors.New("invalid alertmanager silences federation sync interval, must be greater than or equal to zero")
+)
+
+// SilencesFederationConfig configures the replication of the silences to the Alertmanagers of other Mimir clusters.
+type SilencesFederationConfig struct {
+	Peers        flagext.StringSliceCSV `yaml:"peers" category:"experimental"`
+	SyncInterval time.Duration          `yaml:"sync_interval" category:"experimental"`
+}
+
+func (cfg *SilencesFederationConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Var(&cfg.Peers, prefix+".peers", "Comma-separated list of gRPC addresses of the Alertmanager replicas of other Mimir clusters, for example in another region, to which the silences of each tenant are replicated. A silence created in this cluster then suppresses the same alerts in the other clusters. The silences are merged only by the replicas owning the tenant, so the list should include all the replicas of the other clusters. Empty to disable.")
+	f.DurationVar(&cfg.SyncInterval, prefix+".sync-interval", 5*time.Minute, "The interval between the replications of all the silences of each tenant to the federation peers, which replicates the silences created while a peer was unreachable. 0 to replicate only the silences updates.")
+}
+
+func (cfg *SilencesFederationConfig) Validate() error {
+	if cfg.SyncInterval < 0 {
+		return errInvalidSilencesFederationSyncInterval
+	}
+	return nil
+}
+
+// SilencesFederator is used to replicate the silences to the Alertmanagers of other Mimir clusters.
+type SilencesFederator interface {
+	// FederateSilencesForUser writes the given silences state to the federation peers.
+	FederateSilencesForUser(ctx context.Context, userID string, part *clusterpb.Part) error
+}
+
+// silencesFederation replicates the silences of a tenant to the federation peers, asynchronously.
+// The silences updates made in this cluster are replicated by the replica of the tenant at position
+// zero only, which receives the updates made on the other replicas through the state replication.
+// The silences merged from the federation peers are not replicated back to them.
+type silencesFederation struct {
+	services.Service
+
+	userID    string
+	key       string
+	state     State
+	silences  cluster.State
+	federator SilencesFederator
+	logger    log.Logger
+
+	syncInterval time.Duration
+	msgc         chan []byte
+
+	// The silences states being merged from the federation peers, by the address of their first byte:
+	// the silences broadcast the merged states as they are, so this tells them from the other updates.
+	peerMergesMtx sync.Mutex
+	peerMerges    map[*byte]struct{}
+
+	replicationTotal  prometheus.Counter
+	replicationFailed prometheus.Counter
+}
+
+// newSilencesFederation creates a new silencesFederation.
+func newSilencesFederation(cfg SilencesFederationConfig, userID, key string, state State, silences cluster.State, federator SilencesFederator, l log.Logger, r prometheus.Registerer) *silencesFederation {
+	s := &silencesFederation{
+		userID:       userID,
+		key:          key,
+		state:        state,
+		silences:     silences,
+		federator:    federator,
+		logger:       l,
+		syncInterval: cfg.SyncInterval,
+		msgc:         make(chan []byte, silencesFederationQueueSize),
+		peerMerges:   map[*byte]struct{}{},
+		replicationTotal: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_silences_federation_replication_total",
+			Help: "Number of times we have tried to replicate the silences to the federation peers.",
+		}),
+		replicationFailed: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_silences_federation_replication_failed_total",
+			Help: "Number of times we have failed to replicate the silences to the federation peers.",
+		}),
+	}
+
+	s.Service = services.NewBasicService(nil, s.running, nil)
+
+	return s
+}
+
+// broadcast enqueues a silences update to be replicated to the federation peers, unless it has been merged
+// from a federation peer, or this isn't the replica at position zero. The update is dropped if the queue is full,
+// and is replicated by the next periodic sync.
+func (s *silencesFederation) broadcast(b []byte) {
+	if s.isMergingFromPeer(b) || s.state.Position() != 0 {
+		return
+	}
+
+	select {
+	case s.msgc <- b:
+	default:
+		s.replicationTotal.Inc()
+		s.replicationFailed.Inc()
+		level.Warn(s.logger).Log("msg", "dropped silences update to replicate to the federation peers because the queue is full", "user", s.userID)
+	}
+}
+
+// mergeFromPeer merges a silences state received from a federation peer. The silences updated by the merge
+// are replicated to the other replicas of the tenant, but not back to the federation peers.
+func (s *silencesFederation) mergeFromPeer(b []byte) error {
+	if len(b) == 0 {
+		return s.silences.Merge(b)
+	}
+
+	s.peerMergesMtx.Lock()
+	s.peerMerges[&b[0]] = struct{}{}
+	s.peerMergesMtx.Unlock()
+
+	defer func() {
+		s.peerMergesMtx.Lock()
+		delete(s.peerMerges, &b[0])
+		s.peerMergesMtx.Unlock()
+	}()
+
+	return s.silences.Merge(b)
+}
+
+// isMergingFromPeer returns whether the input silences state is being merged from a federation peer.
+func (s *silencesFederation) isMergingFromPeer(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	s.peerMergesMtx.Lock()
+	defer s.peerMergesMtx.Unlock()
+	_, ok := s.peerMerges[&b[0]]
+	return ok
+}
+
+func (s *silencesFederation) running(ctx context.Context) error {
+	var syncC <-chan time.Time
+	if s.syncInterval > 0 {
+		ticker := time.NewTicker(s.syncInterval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+
+	for {
+		select {
+		case b := <-s.msgc:
+			s.replicate(ctx, b)
+		case <-syncC:
+			s.sync(ctx)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// sync replicates all the silences to the federation peers.
+func (s *silencesFederation) sync(ctx context.Context) {
+	// Only the replica at position zero replicates all the silences, because the replicas
+	// of the tenant share the same silences.
+	if s.state.Position() != 0 {
+		return
+	}
+
+	b, err := s.silences.MarshalBinary()
+	if err != nil {
+		level.Error(s.logger).Log("msg", "failed to encode the silences to replicate to the federation peers", "user", s.userID, "err", err)
+		return
+	}
+	s.replicate(ctx, b)
+}
+
+func (s *silencesFederation) replicate(ctx context.Context, b []byte) {
+	s.replicationTotal.Inc()
+	if err := s.federator.FederateSilencesForUser(ctx, s.userID, &clusterpb.Part{Key: federatedSilencesKeyPrefix + s.key, Data: b}); err != nil {
+		s.replicationFailed.Inc()
+		level.Error(s.logger).Log("msg", "failed to replicate the silences to the federation peers", "user", s.userID, "err", err)
+	}
+}
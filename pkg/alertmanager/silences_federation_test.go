@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/alertmanager/cluster/clusterpb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSilencesFederator struct {
+	mtx   sync.Mutex
+	parts []*clusterpb.Part
+	err   error
+}
+
+func (f *fakeSilencesFederator) FederateSilencesForUser(_ context.Context, _ string, part *clusterpb.Part) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.parts = append(f.parts, part)
+	return f.err
+}
+
+func (f *fakeSilencesFederator) getParts() []*clusterpb.Part {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]*clusterpb.Part(nil), f.parts...)
+}
+
+// fakeSilences returns a fixed full state, and broadcasts again the merged states, like the silences do.
+type fakeSilences struct {
+	fullState []byte
+	broadcast func([]byte)
+}
+
+func (f *fakeSilences) MarshalBinary() ([]byte, error) {
+	return f.fullState, nil
+}
+
+func (f *fakeSilences) Merge(b []byte) error {
+	if f.broadcast != nil {
+		f.broadcast(b)
+	}
+	return nil
+}
+
+func makeTestSilencesFederation(t *testing.T, cfg SilencesFederationConfig, position int, federator SilencesFederator) (*silencesFederation, *prometheus.Registry) {
+	state := newFakePersistableState()
+	state.position = position
+
+	reg := prometheus.NewPedanticRegistry()
+	silences := &fakeSilences{fullState: []byte("full-state")}
+	s := newSilencesFederation(cfg, "user-1", "sil:user-1", state, silences, federator, log.NewNopLogger(), reg)
+	silences.broadcast = s.broadcast
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), s))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), s))
+	})
+
+	return s, reg
+}
+
+func TestSilencesFederation_ShouldReplicateSilencesUpdates(t *testing.T) {
+	federator := &fakeSilencesFederator{}
+	s, reg := makeTestSilencesFederation(t, SilencesFederationConfig{}, 0, federator)
+
+	s.broadcast([]byte("update-1"))
+	s.broadcast([]byte("update-2"))
+
+	require.Eventually(t, func() bool {
+		return len(federator.getParts()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []*clusterpb.Part{
+		{Key: "federated:sil:user-1", Data: []byte("update-1")},
+		{Key: "federated:sil:user-1", Data: []byte("update-2")},
+	}, federator.getParts())
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP alertmanager_silences_federation_replication_failed_total Number of times we have failed to replicate the silences to the federation peers.
+		# TYPE alertmanager_silences_federation_replication_failed_total counter
+		alertmanager_silences_federation_replication_failed_total 0
+
+		# HELP alertmanager_silences_federation_replication_total Number of times we have tried to replicate the silences to the federation peers.
+		# TYPE alertmanager_silences_federation_replication_total counter
+		alertmanager_silences_federation_replication_total 2
+	`)))
+}
+
+func TestSilencesFederation_ShouldReplicateSilencesUpdatesOnlyFromTheReplicaAtPositionZero(t *testing.T) {
+	federator := &fakeSilencesFederator{}
+	s, _ := makeTestSilencesFederation(t, SilencesFederationConfig{}, 1, federator)
+
+	s.broadcast([]byte("update-1"))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, federator.getParts())
+	assert.Equal(t, float64(0), testutil.ToFloat64(s.replicationTotal))
+}
+
+func TestSilencesFederation_ShouldNotReplicateTheSilencesMergedFromPeers(t *testing.T) {
+	federator := &fakeSilencesFederator{}
+	s, _ := makeTestSilencesFederation(t, SilencesFederationConfig{}, 0, federator)
+
+	// The merged state is broadcasted again by the silences, but not replicated back to the peers.
+	require.NoError(t, s.mergeFromPeer([]byte("peer-update")))
+	s.broadcast([]byte("update-1"))
+
+	require.Eventually(t, func() bool {
+		return len(federator.getParts()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []*clusterpb.Part{{Key: "federated:sil:user-1", Data: []byte("update-1")}}, federator.getParts())
+	assert.Empty(t, s.peerMerges)
+}
+
+func TestSilencesFederation_ShouldTrackFailedReplications(t *testing.T) {
+	federator := &fakeSilencesFederator{err: fmt.Errorf("peer unreachable")}
+	s, _ := makeTestSilencesFederation(t, SilencesFederationConfig{}, 0, federator)
+
+	s.broadcast([]byte("update-1"))
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(s.replicationFailed) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.replicationTotal))
+}
+
+func TestSilencesFederation_PeriodicSync(t *testing.T) {
+	tests := map[string]struct {
+		position       int
+		expectedSynced bool
+	}{
+		"replica at position zero should replicate all the silences": {
+			position:       0,
+			expectedSynced: true,
+		},
+		"replica at other positions should not replicate all the silences": {
+			position:       1,
+			expectedSynced: false,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			federator := &fakeSilencesFederator{}
+			s, _ := makeTestSilencesFederation(t, SilencesFederationConfig{SyncInterval: 10 * time.Millisecond}, testData.position, federator)
+
+			if !testData.expectedSynced {
+				time.Sleep(100 * time.Millisecond)
+				assert.Empty(t, federator.getParts())
+				assert.Equal(t, float64(0), testutil.ToFloat64(s.replicationTotal))
+				return
+			}
+
+			require.Eventually(t, func() bool {
+				return len(federator.getParts()) > 0
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, &clusterpb.Part{Key: "federated:sil:user-1", Data: []byte("full-state")}, federator.getParts()[0])
+		})
+	}
+}
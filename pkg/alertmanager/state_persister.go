@@ -31,11 +31,13 @@ var (
 )
 
 type PersisterConfig struct {
-	Interval time.Duration `yaml:"persist_interval" category:"advanced"`
+	Interval                    time.Duration `yaml:"persist_interval" category:"advanced"`
+	ExternalStateStorageEnabled bool          `yaml:"external_state_storage_enabled" category:"experimental"`
 }
 
 func (cfg *PersisterConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.DurationVar(&cfg.Interval, prefix+".persist-interval", 15*time.Minute, "The interval between persisting the current alertmanager state (notification log and silences) to object storage. This is only used when sharding is enabled. This state is read when all replicas for a shard can not be contacted. In this scenario, having persisted the state more frequently will result in potentially fewer lost silences, and fewer duplicate notifications.")
+	f.BoolVar(&cfg.ExternalStateStorageEnabled, prefix+".external-state-storage-enabled", false, "If enabled, the alertmanager state (notification log and silences) is stored only in the object storage: snapshots are no longer written to the local disk, and the state is persisted to the object storage on shutdown too. This allows running alertmanager replicas without a persistent local disk. We recommend to reduce the persist interval when enabled.")
 }
 
 func (cfg *PersisterConfig) Validate() error {
@@ -59,7 +61,8 @@ type statePersister struct {
 	userID string
 	logger log.Logger
 
-	timeout time.Duration
+	timeout           time.Duration
+	persistOnShutdown bool
 
 	persistTotal  prometheus.Counter
 	persistFailed prometheus.Counter
@@ -69,11 +72,12 @@ type statePersister struct {
 func newStatePersister(cfg PersisterConfig, userID string, state PersistableState, store alertstore.AlertStore, l log.Logger, r prometheus.Registerer) *statePersister {
 
 	s := &statePersister{
-		state:   state,
-		store:   store,
-		userID:  userID,
-		logger:  l,
-		timeout: defaultPersistTimeout,
+		state:             state,
+		store:             store,
+		userID:            userID,
+		logger:            l,
+		timeout:           defaultPersistTimeout,
+		persistOnShutdown: cfg.ExternalStateStorageEnabled,
 		persistTotal: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "alertmanager_state_persist_total",
 			Help: "Number of times we have tried to persist the running state to remote storage.",
@@ -84,7 +88,7 @@ func newStatePersister(cfg PersisterConfig, userID string, state PersistableStat
 		}),
 	}
 
-	s.Service = services.NewTimerService(cfg.Interval, s.starting, s.iteration, nil)
+	s.Service = services.NewTimerService(cfg.Interval, s.starting, s.iteration, s.stopping)
 
 	return s
 }
@@ -102,12 +106,31 @@ func (s *statePersister) iteration(ctx context.Context) error {
 	return nil
 }
 
-func (s *statePersister) persist(ctx context.Context) (err error) {
+// stopping persists the state on shutdown, if enabled. The stopping function is called only if the
+// state was ready, so the state persisted in the storage is not overwritten with an empty state.
+func (s *statePersister) stopping(_ error) error {
+	if !s.persistOnShutdown {
+		return nil
+	}
+
+	// The state is persisted regardless of the replica position, because the replica at position zero
+	// may have already been stopped, like when all replicas are restarted.
+	if err := s.write(context.Background()); err != nil {
+		level.Error(s.logger).Log("msg", "failed to persist state on shutdown", "user", s.userID, "err", err)
+	}
+	return nil
+}
+
+func (s *statePersister) persist(ctx context.Context) error {
 	// Only the replica at position zero should write the state.
 	if s.state.Position() != 0 {
 		return nil
 	}
 
+	return s.write(ctx)
+}
+
+func (s *statePersister) write(ctx context.Context) (err error) {
 	s.persistTotal.Inc()
 	defer func() {
 		if err != nil {
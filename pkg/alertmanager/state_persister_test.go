@@ -7,6 +7,7 @@ package alertmanager
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -160,3 +161,30 @@ func TestStatePersister_Position1ShouldNotWrite(t *testing.T) {
 		assert.Equal(t, 0, len(store.getWrites()))
 	}
 }
+
+func TestStatePersister_ShouldWriteOnShutdownIfExternalStateStorageIsEnabled(t *testing.T) {
+	for _, externalStateStorageEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("external state storage enabled: %t", externalStateStorageEnabled), func(t *testing.T) {
+			state := newFakePersistableState()
+			state.position = 1
+			state.getResult = makeTestFullState()
+			close(state.readyc)
+
+			store := &fakeStore{}
+			cfg := PersisterConfig{Interval: time.Hour, ExternalStateStorageEnabled: externalStateStorageEnabled}
+
+			s := newStatePersister(cfg, "user-1", state, store, log.NewNopLogger(), nil)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), s))
+			require.NoError(t, services.StopAndAwaitTerminated(context.Background(), s))
+
+			// The state is written on shutdown regardless of the replica position.
+			if externalStateStorageEnabled {
+				require.Len(t, store.getWrites(), 1)
+				assert.Equal(t, "user-1", store.getWrites()[0].user)
+				assert.Equal(t, alertspb.FullStateDesc{State: makeTestFullState()}, store.getWrites()[0].desc)
+			} else {
+				assert.Empty(t, store.getWrites())
+			}
+		})
+	}
+}
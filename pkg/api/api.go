@@ -17,6 +17,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/storage"
@@ -36,6 +37,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/querier"
 	"github.com/grafana/mimir/pkg/ruler"
+	"github.com/grafana/mimir/pkg/sandbox"
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
 	"github.com/grafana/mimir/pkg/storegateway"
@@ -43,6 +45,7 @@ import (
 	"github.com/grafana/mimir/pkg/util/gziphandler"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/push"
+	"github.com/grafana/mimir/pkg/util/validation"
 	"github.com/grafana/mimir/pkg/util/validation/exporter"
 )
 
@@ -54,9 +57,12 @@ type Config struct {
 	AlertmanagerHTTPPrefix string `yaml:"alertmanager_http_prefix" category:"advanced"`
 	PrometheusHTTPPrefix   string `yaml:"prometheus_http_prefix" category:"advanced"`
 
+	StatusConfigComponents flagext.StringSliceCSV `yaml:"status_config_components" category:"experimental"`
+
 	// The following configs are injected by the upstream caller.
-	ServerPrefix       string               `yaml:"-"`
-	HTTPAuthMiddleware middleware.Interface `yaml:"-"`
+	ServerPrefix            string               `yaml:"-"`
+	HTTPAuthMiddleware      middleware.Interface `yaml:"-"`
+	TenantFederationEnabled bool                 `yaml:"-"`
 
 	// The CustomConfigHandler allows for providing a different handler for the
 	// `/config` endpoint. If this field is set _before_ the API module is
@@ -68,6 +74,7 @@ type Config struct {
 // RegisterFlags adds the flags required to config this to the given FlagSet.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&cfg.SkipLabelNameValidationHeader, "api.skip-label-name-validation-header-enabled", false, "Allows to skip label name validation via X-Mimir-SkipLabelNameValidation header on the http write path. Use with caution as it breaks PromQL. Allowing this for external clients allows any client to send invalid label names. After enabling it, requests with a specific HTTP header set to true will not have label names validated.")
+	f.Var(&cfg.StatusConfigComponents, "api.status-config-components", "Comma-separated list of the base URLs of the HTTP API of other Mimir components, whose configuration values that differ from the defaults are fetched from their /config?mode=diff endpoint and aggregated in the /api/v1/status/config response.")
 	cfg.RegisterFlagsWithPrefix("", f)
 }
 
@@ -194,6 +201,15 @@ func (a *API) RegisterAlertmanager(am *alertmanager.MultitenantAlertmanager, api
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.GetUserConfig), true, true, "GET")
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.SetUserConfig), true, true, "POST")
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.DeleteUserConfig), true, true, "DELETE")
+		a.RegisterRoute("/api/v1/alerts/validate", http.HandlerFunc(am.ValidateUserConfig), true, true, "POST")
+		a.RegisterRoute("/api/v1/alerts/history", http.HandlerFunc(am.ListUserConfigVersions), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/history/{version}", http.HandlerFunc(am.GetUserConfigVersion), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/history/{version}/rollback", http.HandlerFunc(am.RollbackUserConfig), true, true, "POST")
+		a.RegisterRoute("/api/v1/alerts/templates", http.HandlerFunc(am.ListUserTemplates), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}", http.HandlerFunc(am.GetUserTemplate), true, true, "GET")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}", http.HandlerFunc(am.SetUserTemplate), true, true, "PUT")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}", http.HandlerFunc(am.DeleteUserTemplate), true, true, "DELETE")
+		a.RegisterRoute("/api/v1/alerts/templates/{name}/validate", http.HandlerFunc(am.ValidateUserTemplate), true, true, "POST")
 	}
 }
 
@@ -209,10 +225,19 @@ func (a *API) RegisterAPI(httpPathPrefix string, actualCfg interface{}, defaultC
 	a.RegisterRoutesWithPrefix("/static/", http.StripPrefix(httpPathPrefix, http.FileServer(http.FS(staticFiles))), false, true, "GET")
 	a.RegisterRoute("/debug/fgprof", fgprof.Handler(), false, true, "GET")
 	a.RegisterRoute("/api/v1/status/buildinfo", buildInfoHandler, false, true, "GET")
-	a.RegisterRoute("/api/v1/status/config", a.cfg.statusConfigHandler(), false, true, "GET")
+	a.RegisterRoute("/api/v1/status/config", a.cfg.statusConfigHandler(actualCfg, defaultCfg), false, true, "GET")
 	a.RegisterRoute("/api/v1/status/flags", a.cfg.statusFlagsHandler(), false, true, "GET")
 }
 
+// RegisterGoRuntime registers the endpoint to inspect and change the Go runtime garbage collector settings.
+func (a *API) RegisterGoRuntime(tuner http.Handler) {
+	a.indexPage.AddLinks(defaultWeight, "Go runtime", []IndexPageLink{
+		{Desc: "Garbage collector settings and statistics", Path: "/debug/gc"},
+	})
+
+	a.RegisterRoute("/debug/gc", tuner, false, true, "GET", "POST")
+}
+
 // RegisterRuntimeConfig registers the endpoints associates with the runtime configuration
 func (a *API) RegisterRuntimeConfig(runtimeConfigHandler http.HandlerFunc, userLimitsHandler http.HandlerFunc) {
 	a.indexPage.AddLinks(runtimeConfigWeight, "Current runtime config", []IndexPageLink{
@@ -225,11 +250,11 @@ func (a *API) RegisterRuntimeConfig(runtimeConfigHandler http.HandlerFunc, userL
 }
 
 // RegisterDistributor registers the endpoints associated with the distributor.
-func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distributor.Config, reg prometheus.Registerer) {
+func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distributor.Config, reg prometheus.Registerer, limits *validation.Overrides) {
 	distributorpb.RegisterDistributorServer(a.server.GRPC, d)
 
 	a.RegisterRoute("/api/v1/push", push.Handler(pushConfig.MaxRecvMsgSize, a.sourceIPs, a.cfg.SkipLabelNameValidationHeader, d.PushWithMiddlewares), true, false, "POST")
-	a.RegisterRoute("/otlp/v1/metrics", push.OTLPHandler(pushConfig.MaxRecvMsgSize, a.sourceIPs, a.cfg.SkipLabelNameValidationHeader, reg, d.PushWithMiddlewares), true, false, "POST")
+	a.RegisterRoute("/otlp/v1/metrics", push.OTLPHandler(pushConfig.MaxRecvMsgSize, a.sourceIPs, a.cfg.SkipLabelNameValidationHeader, limits, reg, d.PushWithMiddlewares), true, false, "POST")
 
 	a.indexPage.AddLinks(defaultWeight, "Distributor", []IndexPageLink{
 		{Desc: "Ring status", Path: "/distributor/ring"},
@@ -240,6 +265,25 @@ func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distrib
 	a.RegisterRoute("/distributor/ring", d, false, true, "GET", "POST")
 	a.RegisterRoute("/distributor/all_user_stats", http.HandlerFunc(d.AllUserStatsHandler), false, true, "GET")
 	a.RegisterRoute("/distributor/ha_tracker", d.HATracker, false, true, "GET")
+	a.RegisterRoute("/distributor/ha_tracker/failover", http.HandlerFunc(d.HATracker.FailoverHandler), false, true, "POST")
+}
+
+// RegisterTenantsStats registers the endpoint listing all tenants known to the cluster, with their summary statistics.
+func (a *API) RegisterTenantsStats(h http.Handler) {
+	a.indexPage.AddLinks(defaultWeight, "Distributor", []IndexPageLink{
+		{Desc: "Tenants statistics", Path: "/distributor/tenants"},
+	})
+
+	a.RegisterRoute("/distributor/tenants", h, false, true, "GET")
+}
+
+// RegisterSandboxTenants registers the endpoints associated with the sandbox tenants.
+func (a *API) RegisterSandboxTenants(r *sandbox.Registry) {
+	a.indexPage.AddLinks(defaultWeight, "Distributor", []IndexPageLink{
+		{Desc: "Sandbox tenants", Path: "/distributor/sandbox_tenants"},
+	})
+
+	a.RegisterRoute("/distributor/sandbox_tenants", r, false, true, "GET", "POST", "DELETE")
 }
 
 // Ingester is defined as an interface to allow for alternative implementations
@@ -250,11 +294,13 @@ type Ingester interface {
 	ShutdownHandler(http.ResponseWriter, *http.Request)
 	PushWithCleanup(context.Context, *push.Request) (*mimirpb.WriteResponse, error)
 	UserRegistryHandler(http.ResponseWriter, *http.Request)
+	client.HandoffServer
 }
 
 // RegisterIngester registers the ingesters HTTP and GRPC service
 func (a *API) RegisterIngester(i Ingester, pushConfig distributor.Config) {
 	client.RegisterIngesterServer(a.server.GRPC, i)
+	client.RegisterHandoffServer(a.server.GRPC, i)
 
 	a.indexPage.AddLinks(dangerousWeight, "Dangerous", []IndexPageLink{
 		{Dangerous: true, Desc: "Trigger a flush of data from ingester to storage", Path: "/ingester/flush"},
@@ -300,6 +346,9 @@ func (a *API) RegisterRulerAPI(r *ruler.API, configAPIEnabled bool, buildInfoHan
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.CreateRuleGroup), true, true, "POST")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}"), http.HandlerFunc(r.DeleteRuleGroup), true, true, "DELETE")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.DeleteNamespace), true, true, "DELETE")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}/history"), http.HandlerFunc(r.ListRuleGroupVersions), true, true, "GET")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}/history/{version}"), http.HandlerFunc(r.GetRuleGroupVersion), true, true, "GET")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}/history/{version}/rollback"), http.HandlerFunc(r.RollbackRuleGroup), true, true, "POST")
 	}
 }
 
@@ -322,6 +371,8 @@ func (a *API) RegisterStoreGateway(s *storegateway.StoreGateway) {
 	a.RegisterRoute("/store-gateway/ring", http.HandlerFunc(s.RingHandler), false, true, "GET", "POST")
 	a.RegisterRoute("/store-gateway/tenants", http.HandlerFunc(s.TenantsHandler), false, true, "GET")
 	a.RegisterRoute("/store-gateway/tenant/{tenant}/blocks", http.HandlerFunc(s.BlocksHandler), false, true, "GET")
+	a.RegisterRoute("/store-gateway/invalidate_caches", http.HandlerFunc(s.InvalidateCachesHandler), true, true, "POST")
+	a.RegisterRoute("/store-gateway/ready-detail", http.HandlerFunc(s.ReadyDetailHandler), false, true, "GET")
 }
 
 // RegisterCompactor registers routes associated with the compactor.
@@ -330,12 +381,17 @@ func (a *API) RegisterCompactor(c *compactor.MultitenantCompactor) {
 		{Desc: "Ring status", Path: "/compactor/ring"},
 	})
 	a.RegisterRoute("/compactor/ring", http.HandlerFunc(c.RingHandler), false, true, "GET", "POST")
+	a.RegisterRoute("/compactor/ready-detail", http.HandlerFunc(c.ReadyDetailHandler), false, true, "GET")
 	a.RegisterRoute("/api/v1/upload/block/{block}/start", http.HandlerFunc(c.StartBlockUpload), true, false, http.MethodPost)
 	a.RegisterRoute("/api/v1/upload/block/{block}/files", http.HandlerFunc(c.UploadBlockFile), true, false, http.MethodPost)
 	a.RegisterRoute("/api/v1/upload/block/{block}/finish", http.HandlerFunc(c.FinishBlockUpload), true, false, http.MethodPost)
 	a.RegisterRoute("/api/v1/upload/block/{block}/check", http.HandlerFunc(c.GetBlockUploadStateHandler), true, false, http.MethodGet)
+	a.RegisterRoute("/api/v1/blocks", http.HandlerFunc(c.ListBlocksHandler), true, false, http.MethodGet)
+	a.RegisterRoute("/api/v1/blocks/{block}/files/{file}", http.HandlerFunc(c.BlockFileHandler), true, false, http.MethodGet)
 	a.RegisterRoute("/compactor/delete_tenant", http.HandlerFunc(c.DeleteTenant), true, true, "POST")
 	a.RegisterRoute("/compactor/delete_tenant_status", http.HandlerFunc(c.DeleteTenantStatus), true, true, "GET")
+	a.RegisterRoute("/compactor/retention_policies", http.HandlerFunc(c.RetentionPoliciesHandler), true, true, "GET", "POST", "DELETE")
+	a.RegisterRoute("/compactor/tenant_compaction_skip", http.HandlerFunc(c.TenantCompactionSkipHandler), true, true, "GET", "DELETE")
 }
 
 type Distributor interface {
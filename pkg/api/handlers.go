@@ -9,14 +9,17 @@ import (
 	"context"
 	"embed"
 	"html/template"
+	"io"
 	"net/http"
 	"path"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/concurrency"
 	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/grafana/regexp"
 	"github.com/pkg/errors"
@@ -30,6 +33,7 @@ import (
 	v1 "github.com/prometheus/prometheus/web/api/v1"
 	"github.com/weaveworks/common/instrument"
 	"github.com/weaveworks/common/middleware"
+	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/querier"
 	"github.com/grafana/mimir/pkg/querier/stats"
@@ -132,19 +136,7 @@ func DefaultConfigHandler(actualCfg interface{}, defaultCfg interface{}) http.Ha
 		var output interface{}
 		switch r.URL.Query().Get("mode") {
 		case "diff":
-			defaultCfgObj, err := util.YAMLMarshalUnmarshal(defaultCfg)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			actualCfgObj, err := util.YAMLMarshalUnmarshal(actualCfg)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			diff, err := util.DiffConfig(defaultCfgObj, actualCfgObj)
+			diff, err := configDiff(actualCfg, defaultCfg)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -161,21 +153,96 @@ func DefaultConfigHandler(actualCfg interface{}, defaultCfg interface{}) http.Ha
 	}
 }
 
+// configDiff returns the values of the actual config which differ from the default config.
+// Secrets are redacted, because they're marshalled to a placeholder.
+func configDiff(actualCfg interface{}, defaultCfg interface{}) (map[string]interface{}, error) {
+	defaultCfgObj, err := util.YAMLMarshalUnmarshal(defaultCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	actualCfgObj, err := util.YAMLMarshalUnmarshal(actualCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return util.DiffConfig(defaultCfgObj, actualCfgObj)
+}
+
+// statusConfigComponentTimeout is the timeout of the requests fetching the config of the other components.
+const statusConfigComponentTimeout = 10 * time.Second
+
 type configResponse struct {
 	Status string            `json:"status"`
 	Config map[string]string `json:"data"`
 }
 
-func (cfg *Config) statusConfigHandler() http.HandlerFunc {
+// statusConfigHandler returns the config values differing from the defaults, encoded as YAML
+// like the Prometheus /api/v1/status/config API does, to be used by config drift detection tools.
+// The values of the components configured via -api.status-config-components are fetched from their
+// own /config?mode=diff endpoint, and returned keyed by the component URL.
+func (cfg *Config) statusConfigHandler(actualCfg interface{}, defaultCfg interface{}) http.HandlerFunc {
+	client := &http.Client{Timeout: statusConfigComponentTimeout}
+
 	return func(w http.ResponseWriter, r *http.Request) {
+		diff, err := configDiff(actualCfg, defaultCfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := yaml.Marshal(diff)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		components := make([]string, len(cfg.StatusConfigComponents))
+		err = concurrency.ForEachJob(r.Context(), len(cfg.StatusConfigComponents), len(cfg.StatusConfigComponents), func(ctx context.Context, idx int) error {
+			var err error
+			components[idx], err = fetchComponentConfigDiff(ctx, client, cfg.StatusConfigComponents[idx])
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
 		response := configResponse{
 			Status: "success",
-			Config: map[string]string{},
+			Config: map[string]string{"yaml": string(data)},
+		}
+		for idx, component := range cfg.StatusConfigComponents {
+			response.Config[component] = components[idx]
 		}
 		util.WriteJSONResponse(w, response)
 	}
 }
 
+// fetchComponentConfigDiff returns the YAML encoded config values differing from the defaults of the
+// component exposing its HTTP API at the input base URL.
+func fetchComponentConfigDiff(ctx context.Context, client *http.Client, baseURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/config?mode=diff", nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to fetch the config of the component %s", baseURL)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to fetch the config of the component %s", baseURL)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the config of the component %s", baseURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to fetch the config of the component %s: unexpected status code %d", baseURL, resp.StatusCode)
+	}
+	return string(body), nil
+}
+
 type flagsResponse struct {
 	Status string            `json:"status"`
 	Flags  map[string]string `json:"data"`
@@ -275,6 +342,7 @@ func NewQuerierHandler(
 		InflightRequests: inflightRequests,
 	}
 	router.Use(instrumentMiddleware.Wrap)
+	router.Use(skipOutOfOrderMiddleware)
 
 	// Define the prefixes for all routes
 	prefix := path.Join(cfg.ServerPrefix, cfg.PrometheusHTTPPrefix)
@@ -302,13 +370,24 @@ func NewQuerierHandler(
 	router.Path(path.Join(prefix, "/api/v1/label/{name}/values")).Methods("GET").Handler(labelsQueryStats.Wrap(promRouter))
 	router.Path(path.Join(prefix, "/api/v1/series")).Methods("GET", "POST", "DELETE").Handler(seriesQueryStats.Wrap(promRouter))
 	router.Path(path.Join(prefix, "/api/v1/metadata")).Methods("GET").Handler(metadataQueryStats.Wrap(querier.NewMetadataHandler(metadataSupplier)))
-	router.Path(path.Join(prefix, "/api/v1/cardinality/label_names")).Methods("GET", "POST").Handler(cardinalityQueryStats.Wrap(querier.LabelNamesCardinalityHandler(distributor, limits)))
-	router.Path(path.Join(prefix, "/api/v1/cardinality/label_values")).Methods("GET", "POST").Handler(cardinalityQueryStats.Wrap(querier.LabelValuesCardinalityHandler(distributor, limits)))
+	router.Path(path.Join(prefix, "/api/v1/cardinality/label_names")).Methods("GET", "POST").Handler(cardinalityQueryStats.Wrap(querier.LabelNamesCardinalityHandler(distributor, queryable, limits)))
+	router.Path(path.Join(prefix, "/api/v1/cardinality/label_values")).Methods("GET", "POST").Handler(cardinalityQueryStats.Wrap(querier.LabelValuesCardinalityHandler(distributor, queryable, limits, cfg.TenantFederationEnabled)))
 
 	// Track execution time.
 	return stats.NewWallTimeMiddleware().Wrap(router)
 }
 
+// skipOutOfOrderMiddleware propagates the option to exclude the samples ingested out-of-order,
+// requested via HTTP header, to the ingesters and store-gateways queried by the request.
+func skipOutOfOrderMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if util.SkipOutOfOrderFromRequest(r) {
+			r = r.WithContext(util.AddSkipOutOfOrderToOutgoingContext(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 //go:embed memberlist_status.gohtml
 var memberlistStatusPageHTML string
 
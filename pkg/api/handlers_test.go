@@ -6,12 +6,14 @@
 package api
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/grafana/dskit/flagext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -67,10 +69,11 @@ func TestIndexPageContent(t *testing.T) {
 }
 
 type diffConfigMock struct {
-	MyInt          int          `yaml:"my_int"`
-	MyFloat        float64      `yaml:"my_float"`
-	MySlice        []string     `yaml:"my_slice"`
-	IgnoredField   func() error `yaml:"-"`
+	MyInt          int            `yaml:"my_int"`
+	MyFloat        float64        `yaml:"my_float"`
+	MySlice        []string       `yaml:"my_slice"`
+	MySecret       flagext.Secret `yaml:"my_secret"`
+	IgnoredField   func() error   `yaml:"-"`
 	MyNestedStruct struct {
 		MyString      string   `yaml:"my_string"`
 		MyBool        bool     `yaml:"my_bool"`
@@ -172,6 +175,59 @@ func TestConfigDiffHandler(t *testing.T) {
 	}
 }
 
+func TestStatusConfigHandler(t *testing.T) {
+	actualCfg := newDefaultDiffConfigMock()
+	actualCfg.MyInt = 42
+	require.NoError(t, actualCfg.MySecret.Set("password"))
+
+	req := httptest.NewRequest("GET", "http://localhost/api/v1/status/config", nil)
+	w := httptest.NewRecorder()
+
+	h := (&Config{}).statusConfigHandler(actualCfg, newDefaultDiffConfigMock())
+	h(w, req)
+	resp := w.Result()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status":"success","data":{"yaml":"my_int: 42\nmy_secret: '********'\n"}}`, string(body))
+}
+
+func TestStatusConfigHandler_Components(t *testing.T) {
+	component := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/prefix/config", r.URL.Path)
+		assert.Equal(t, "diff", r.URL.Query().Get("mode"))
+		_, _ = w.Write([]byte("my_int: 7\n"))
+	}))
+	t.Cleanup(component.Close)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+
+	t.Run("should aggregate the config of the components", func(t *testing.T) {
+		cfg := &Config{StatusConfigComponents: []string{component.URL + "/prefix/"}}
+
+		w := httptest.NewRecorder()
+		cfg.statusConfigHandler(newDefaultDiffConfigMock(), newDefaultDiffConfigMock())(w, httptest.NewRequest("GET", "http://localhost/api/v1/status/config", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := configResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]string{"yaml": "{}\n", component.URL + "/prefix/": "my_int: 7\n"}, resp.Config)
+	})
+
+	t.Run("should fail if the config of a component can't be fetched", func(t *testing.T) {
+		cfg := &Config{StatusConfigComponents: []string{component.URL + "/prefix", failing.URL}}
+
+		w := httptest.NewRecorder()
+		cfg.statusConfigHandler(newDefaultDiffConfigMock(), newDefaultDiffConfigMock())(w, httptest.NewRequest("GET", "http://localhost/api/v1/status/config", nil))
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.Contains(t, w.Body.String(), "failed to fetch the config of the component "+failing.URL)
+	})
+}
+
 func TestConfigOverrideHandler(t *testing.T) {
 	cfg := &Config{
 		CustomConfigHandler: func(_ interface{}, _ interface{}) http.HandlerFunc {
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"sync"
+
+	"github.com/oklog/ulid"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+// blockQuarantine tracks the number of consecutive compaction failures caused by each block of a tenant,
+// across compaction runs, to find out the blocks which should be marked for no-compaction because otherwise
+// they would block the compaction of the tenant forever. A nil blockQuarantine never quarantines blocks.
+type blockQuarantine struct {
+	threshold int
+
+	mtx      sync.Mutex
+	failures map[ulid.ULID]int
+}
+
+// newBlockQuarantine returns a blockQuarantine quarantining blocks after the given number of consecutive
+// compaction failures, or nil if the threshold is not positive.
+func newBlockQuarantine(threshold int) *blockQuarantine {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &blockQuarantine{
+		threshold: threshold,
+		failures:  map[ulid.ULID]int{},
+	}
+}
+
+// recordFailure tracks a compaction failure caused by the input block, and returns whether the block reached
+// the configured number of consecutive failures and should be quarantined.
+func (q *blockQuarantine) recordFailure(id ulid.ULID) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.failures[id]++
+	return q.failures[id] >= q.threshold
+}
+
+// recordSuccess resets the failures of the input blocks, because they've been successfully compacted.
+func (q *blockQuarantine) recordSuccess(ids []ulid.ULID) {
+	if q == nil {
+		return
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	for _, id := range ids {
+		delete(q.failures, id)
+	}
+}
+
+// forget stops tracking the failures of the input block, once it has been quarantined.
+func (q *blockQuarantine) forget(id ulid.ULID) {
+	if q == nil {
+		return
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	delete(q.failures, id)
+}
+
+// retain stops tracking the failures of the blocks which are not in the input metas, because they've been deleted.
+func (q *blockQuarantine) retain(metas map[ulid.ULID]*metadata.Meta) {
+	if q == nil {
+		return
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	for id := range q.failures {
+		if _, ok := metas[id]; !ok {
+			delete(q.failures, id)
+		}
+	}
+}
+
+// tenantBlockQuarantine returns the blockQuarantine of the input tenant, or nil if the quarantine is disabled.
+func (c *MultitenantCompactor) tenantBlockQuarantine(userID string) *blockQuarantine {
+	if q, ok := c.blockQuarantines[userID]; ok {
+		return q
+	}
+
+	q := newBlockQuarantine(c.compactorCfg.BlockQuarantineFailuresThreshold)
+	if q != nil {
+		c.blockQuarantines[userID] = q
+	}
+	return q
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+func TestBlockQuarantine(t *testing.T) {
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+
+	t.Run("disabled", func(t *testing.T) {
+		q := newBlockQuarantine(0)
+		require.Nil(t, q)
+
+		assert.False(t, q.recordFailure(block1))
+		q.recordSuccess([]ulid.ULID{block1})
+		q.forget(block1)
+		q.retain(nil)
+	})
+
+	t.Run("quarantines blocks after consecutive failures", func(t *testing.T) {
+		q := newBlockQuarantine(3)
+
+		assert.False(t, q.recordFailure(block1))
+		assert.False(t, q.recordFailure(block1))
+		assert.False(t, q.recordFailure(block2))
+		assert.True(t, q.recordFailure(block1))
+
+		// Once forgotten, the failures are counted from scratch.
+		q.forget(block1)
+		assert.False(t, q.recordFailure(block1))
+	})
+
+	t.Run("a successful compaction resets the failures", func(t *testing.T) {
+		q := newBlockQuarantine(2)
+
+		assert.False(t, q.recordFailure(block1))
+		assert.False(t, q.recordFailure(block2))
+		q.recordSuccess([]ulid.ULID{block1, block2})
+
+		assert.False(t, q.recordFailure(block1))
+		assert.True(t, q.recordFailure(block1))
+	})
+	t.Run("the failures of deleted blocks are not tracked anymore", func(t *testing.T) {
+		q := newBlockQuarantine(2)
+
+		assert.False(t, q.recordFailure(block1))
+		assert.False(t, q.recordFailure(block2))
+		q.retain(map[ulid.ULID]*metadata.Meta{block2: {}})
+		assert.Len(t, q.failures, 1)
+
+		assert.False(t, q.recordFailure(block1))
+		assert.True(t, q.recordFailure(block2))
+	})
+}
@@ -109,11 +109,11 @@ func (c *MultitenantCompactor) FinishBlockUpload(w http.ResponseWriter, r *http.
 			writeBlockUploadError(err, op, "while creating validation file", logger, w)
 			return
 		}
-		go c.validateAndCompleteBlockUpload(logger, userBkt, blockID, m, func(ctx context.Context) error {
+		go c.validateAndCompleteBlockUpload(logger, tenantID, userBkt, blockID, m, func(ctx context.Context) error {
 			return c.validateBlock(ctx, blockID, userBkt, tenantID)
 		})
 	} else {
-		if err := c.markBlockComplete(ctx, logger, userBkt, blockID, m); err != nil {
+		if err := c.markBlockComplete(ctx, logger, tenantID, userBkt, blockID, m); err != nil {
 			writeBlockUploadError(err, op, "uploading meta file", logger, w)
 			return
 		}
@@ -277,7 +277,7 @@ func (c *MultitenantCompactor) UploadBlockFile(w http.ResponseWriter, r *http.Re
 	w.WriteHeader(http.StatusOK)
 }
 
-func (c *MultitenantCompactor) validateAndCompleteBlockUpload(logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID, meta *metadata.Meta, validation func(context.Context) error) {
+func (c *MultitenantCompactor) validateAndCompleteBlockUpload(logger log.Logger, tenantID string, userBkt objstore.Bucket, blockID ulid.ULID, meta *metadata.Meta, validation func(context.Context) error) {
 	level.Debug(logger).Log("msg", "completing block upload", "files", len(meta.Thanos.Files))
 
 	{
@@ -308,7 +308,7 @@ func (c *MultitenantCompactor) validateAndCompleteBlockUpload(logger log.Logger,
 
 	ctx := context.Background()
 
-	if err := c.markBlockComplete(ctx, logger, userBkt, blockID, meta); err != nil {
+	if err := c.markBlockComplete(ctx, logger, tenantID, userBkt, blockID, meta); err != nil {
 		if err := c.uploadValidationWithError(ctx, blockID, userBkt, err.Error()); err != nil {
 			level.Error(logger).Log("msg", "error updating validation file after upload of metadata file failed", "err", err)
 		}
@@ -324,7 +324,7 @@ func (c *MultitenantCompactor) validateAndCompleteBlockUpload(logger log.Logger,
 	level.Debug(logger).Log("msg", "successfully completed block upload")
 }
 
-func (c *MultitenantCompactor) markBlockComplete(ctx context.Context, logger log.Logger, userBkt objstore.Bucket, blockID ulid.ULID, meta *metadata.Meta) error {
+func (c *MultitenantCompactor) markBlockComplete(ctx context.Context, logger log.Logger, tenantID string, userBkt objstore.Bucket, blockID ulid.ULID, meta *metadata.Meta) error {
 	if err := c.uploadMeta(ctx, logger, meta, blockID, block.MetaFilename, userBkt); err != nil {
 		level.Error(logger).Log("msg", "error uploading block metadata file", "err", err)
 		return err
@@ -335,9 +335,27 @@ func (c *MultitenantCompactor) markBlockComplete(ctx context.Context, logger log
 		level.Warn(logger).Log("msg", fmt.Sprintf("failed to delete %s from block in object storage", uploadingMetaFilename), "err", err)
 	}
 
+	c.addBlockToBucketIndex(logger, tenantID)
+
 	return nil
 }
 
+// addBlockToBucketIndex asynchronously triggers the blocks cleaner run of the tenant, which adds the uploaded
+// block to the tenant's bucket index, so that it can be queried without waiting for the next periodic run.
+// The bucket index is only written by the blocks cleaner, to not race with it: if this compactor doesn't own
+// the tenant, or the tenant cleanup is already in progress, the block is added by the next periodic run.
+func (c *MultitenantCompactor) addBlockToBucketIndex(logger log.Logger, tenantID string) {
+	if c.blocksCleaner == nil {
+		return
+	}
+
+	go func() {
+		if err := c.blocksCleaner.UpdateUserBucketIndex(context.Background(), tenantID); err != nil {
+			level.Warn(logger).Log("msg", "failed to add uploaded block to the bucket index", "err", err)
+		}
+	}()
+}
+
 // sanitizeMeta sanitizes and validates a metadata.Meta object. If a validation error occurs, an error
 // message gets returned, otherwise an empty string.
 func (c *MultitenantCompactor) sanitizeMeta(logger log.Logger, blockID ulid.ULID, meta *metadata.Meta) string {
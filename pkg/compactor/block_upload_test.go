@@ -35,6 +35,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 	"github.com/grafana/mimir/pkg/storegateway/testhelper"
 )
@@ -1276,6 +1277,63 @@ func TestMultitenantCompactor_FinishBlockUpload(t *testing.T) {
 	}
 }
 
+func TestMultitenantCompactor_MarkBlockCompleteUpdatesBucketIndex(t *testing.T) {
+	const tenantID = "test"
+	blockID := ulid.MustParse("01G3FZ0JWJYJC0ZM6Y9778P6KD")
+	meta := metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			Version: metadata.TSDBVersion1,
+			ULID:    blockID,
+			MinTime: 10,
+			MaxTime: 20,
+		},
+		Thanos: metadata.Thanos{
+			Labels: map[string]string{
+				mimir_tsdb.CompactorShardIDExternalLabel: "1_of_3",
+			},
+			Source: "upload",
+		},
+	}
+
+	for _, ownTenant := range []bool{false, true} {
+		t.Run(fmt.Sprintf("tenant owned by the blocks cleaner: %t", ownTenant), func(t *testing.T) {
+			ctx := context.Background()
+			bkt := objstore.NewInMemBucket()
+			cfgProvider := newMockConfigProvider()
+			ownUser := func(string) (bool, error) { return ownTenant, nil }
+			c := &MultitenantCompactor{
+				logger:        log.NewNopLogger(),
+				bucketClient:  bkt,
+				cfgProvider:   cfgProvider,
+				blocksCleaner: NewBlocksCleaner(BlocksCleanerConfig{CleanupConcurrency: 1, DeleteBlocksConcurrency: 1}, bkt, ownUser, cfgProvider, log.NewNopLogger(), nil),
+			}
+			userBkt := bucket.NewUserBucketClient(tenantID, bkt, cfgProvider)
+
+			require.NoError(t, c.markBlockComplete(ctx, log.NewNopLogger(), tenantID, userBkt, blockID, &meta))
+
+			if !ownTenant {
+				// The bucket index is left to the blocks cleaner owning the tenant.
+				require.Never(t, func() bool {
+					exists, err := bkt.Exists(ctx, path.Join(tenantID, bucketindex.IndexCompressedFilename))
+					return err != nil || exists
+				}, 100*time.Millisecond, 10*time.Millisecond)
+				return
+			}
+
+			var idx *bucketindex.Index
+			require.Eventually(t, func() bool {
+				var err error
+				idx, err = bucketindex.ReadIndex(ctx, bkt, tenantID, cfgProvider, log.NewNopLogger())
+				return err == nil
+			}, time.Second, 10*time.Millisecond)
+			require.Equal(t, []ulid.ULID{blockID}, idx.Blocks.GetULIDs())
+			require.Equal(t, int64(10), idx.Blocks[0].MinTime)
+			require.Equal(t, int64(20), idx.Blocks[0].MaxTime)
+			require.Equal(t, "1_of_3", idx.Blocks[0].CompactorShardID)
+		})
+	}
+}
+
 func TestMultitenantCompactor_ValidateAndComplete(t *testing.T) {
 	const tenantID = "test"
 	const blockID = "01G3FZ0JWJYJC0ZM6Y9778P6KD"
@@ -1385,7 +1443,7 @@ func TestMultitenantCompactor_ValidateAndComplete(t *testing.T) {
 			v := validationFile{}
 			marshalAndUploadJSON(t, bkt, validationPath, v)
 
-			c.validateAndCompleteBlockUpload(log.NewNopLogger(), userBkt, ulid.MustParse(blockID), &meta, tc.validation)
+			c.validateAndCompleteBlockUpload(log.NewNopLogger(), tenantID, userBkt, ulid.MustParse(blockID), &meta, tc.validation)
 
 			tempUploadingMetaExists, err := bkt.Exists(context.Background(), uploadingMetaPath)
 			require.NoError(t, err)
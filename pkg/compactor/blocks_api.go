@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/tenant"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// blockFilesAllowedForDownload are the files of a block the tenant can download. The index and
+// chunks are not allowed, given they can be very large.
+var blockFilesAllowedForDownload = map[string]struct{}{
+	block.MetaFilename:             {},
+	metadata.DeletionMarkFilename:  {},
+	metadata.NoCompactMarkFilename: {},
+}
+
+// ListBlocksHandler returns the blocks of the tenant, and their deletion marks. The blocks are read from the
+// bucket index if it exists, otherwise they're listed from the storage.
+func (c *MultitenantCompactor) ListBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	userLogger := util_log.WithUserID(userID, c.logger)
+
+	idx, err := bucketindex.ReadIndex(ctx, c.bucketClient, userID, c.cfgProvider, userLogger)
+	if errors.Is(err, bucketindex.ErrIndexNotFound) || errors.Is(err, bucketindex.ErrIndexCorrupted) {
+		idx, _, err = bucketindex.NewUpdater(c.bucketClient, userID, c.cfgProvider, userLogger).UpdateIndex(ctx, nil)
+	}
+	if err != nil {
+		level.Error(userLogger).Log("msg", "failed to list blocks", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	util.WriteJSONResponse(w, idx)
+}
+
+// BlockFileHandler returns a small file of a block of the tenant, like the meta.json or the deletion mark.
+func (c *MultitenantCompactor) BlockFileHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	blockID, err := ulid.Parse(mux.Vars(r)["block"])
+	if err != nil {
+		http.Error(w, "invalid block ID", http.StatusBadRequest)
+		return
+	}
+
+	file := mux.Vars(r)["file"]
+	if _, ok := blockFilesAllowedForDownload[file]; !ok {
+		http.Error(w, "file not allowed for download", http.StatusBadRequest)
+		return
+	}
+
+	userBkt := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
+	rc, err := userBkt.Get(ctx, blockID.String()+"/"+file)
+	if userBkt.IsObjNotFoundErr(err) {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		level.Error(util_log.WithUserID(userID, c.logger)).Log("msg", "failed to read block file", "block", blockID, "file", file, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := io.Copy(w, rc); err != nil {
+		level.Warn(util_log.WithUserID(userID, c.logger)).Log("msg", "failed to write block file", "block", blockID, "file", file, "err", err)
+	}
+}
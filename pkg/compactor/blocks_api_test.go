@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+)
+
+func TestMultitenantCompactor_ListBlocksHandler(t *testing.T) {
+	const userID = "user-1"
+
+	bkt := bucketindex.BucketWithGlobalMarkers(objstore.NewInMemBucket())
+	block1 := mimir_testutil.MockStorageBlock(t, bkt, userID, 10, 20)
+	block2 := mimir_testutil.MockStorageBlock(t, bkt, userID, 20, 30)
+	mimir_testutil.MockStorageDeletionMark(t, bkt, userID, block2)
+	mimir_testutil.MockStorageBlock(t, bkt, "user-2", 10, 20)
+
+	c := &MultitenantCompactor{
+		logger:       log.NewNopLogger(),
+		bucketClient: bkt,
+		cfgProvider:  newMockConfigProvider(),
+	}
+
+	listBlocks := func() *bucketindex.Index {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/blocks", nil)
+		r = r.WithContext(user.InjectOrgID(r.Context(), userID))
+
+		w := httptest.NewRecorder()
+		c.ListBlocksHandler(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		idx := &bucketindex.Index{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(idx))
+		return idx
+	}
+
+	t.Run("should fail if the tenant ID is missing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c.ListBlocksHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/blocks", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should list the blocks from the storage if the bucket index doesn't exist", func(t *testing.T) {
+		idx := listBlocks()
+		assert.ElementsMatch(t, []ulid.ULID{block1.ULID, block2.ULID}, idx.Blocks.GetULIDs())
+		assert.Equal(t, []ulid.ULID{block2.ULID}, idx.BlockDeletionMarks.GetULIDs())
+	})
+
+	t.Run("should read the blocks from the bucket index if it exists", func(t *testing.T) {
+		written := &bucketindex.Index{
+			Version: bucketindex.IndexVersion1,
+			Blocks:  bucketindex.Blocks{{ID: block1.ULID, MinTime: 10, MaxTime: 20}},
+		}
+		require.NoError(t, bucketindex.WriteIndex(context.Background(), bkt, userID, nil, written))
+
+		idx := listBlocks()
+		assert.Equal(t, []ulid.ULID{block1.ULID}, idx.Blocks.GetULIDs())
+		assert.Empty(t, idx.BlockDeletionMarks)
+	})
+}
+
+func TestMultitenantCompactor_BlockFileHandler(t *testing.T) {
+	const userID = "user-1"
+
+	bkt := objstore.NewInMemBucket()
+	blockMeta := mimir_testutil.MockStorageBlock(t, bkt, userID, 10, 20)
+	mimir_testutil.MockStorageDeletionMark(t, bkt, userID, blockMeta)
+	otherMeta := mimir_testutil.MockStorageBlock(t, bkt, "user-2", 10, 20)
+
+	c := &MultitenantCompactor{
+		logger:       log.NewNopLogger(),
+		bucketClient: bkt,
+		cfgProvider:  newMockConfigProvider(),
+	}
+
+	tests := map[string]struct {
+		blockID            string
+		file               string
+		expectedStatusCode int
+		expectedBody       func(t *testing.T, body []byte)
+	}{
+		"meta.json": {
+			blockID:            blockMeta.ULID.String(),
+			file:               block.MetaFilename,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: func(t *testing.T, body []byte) {
+				meta := metadata.Meta{}
+				require.NoError(t, json.Unmarshal(body, &meta))
+				assert.Equal(t, blockMeta.ULID, meta.ULID)
+			},
+		},
+		"deletion mark": {
+			blockID:            blockMeta.ULID.String(),
+			file:               metadata.DeletionMarkFilename,
+			expectedStatusCode: http.StatusOK,
+			expectedBody: func(t *testing.T, body []byte) {
+				mark := metadata.DeletionMark{}
+				require.NoError(t, json.Unmarshal(body, &mark))
+				assert.Equal(t, blockMeta.ULID, mark.ID)
+			},
+		},
+		"missing no-compact mark": {
+			blockID:            blockMeta.ULID.String(),
+			file:               metadata.NoCompactMarkFilename,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		"block of another tenant": {
+			blockID:            otherMeta.ULID.String(),
+			file:               block.MetaFilename,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		"file not allowed": {
+			blockID:            blockMeta.ULID.String(),
+			file:               block.IndexFilename,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"invalid block ID": {
+			blockID:            "invalid",
+			file:               block.MetaFilename,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/blocks/%s/files/%s", tc.blockID, tc.file), nil)
+			r = mux.SetURLVars(r, map[string]string{"block": tc.blockID, "file": tc.file})
+			r = r.WithContext(user.InjectOrgID(r.Context(), userID))
+
+			w := httptest.NewRecorder()
+			c.BlockFileHandler(w, r)
+			require.Equal(t, tc.expectedStatusCode, w.Code)
+
+			if tc.expectedBody != nil {
+				tc.expectedBody(t, w.Body.Bytes())
+			}
+		})
+	}
+}
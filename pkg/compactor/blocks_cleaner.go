@@ -8,6 +8,7 @@ package compactor
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +23,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
@@ -43,6 +45,8 @@ type BlocksCleanerConfig struct {
 	CleanupConcurrency      int
 	TenantCleanupDelay      time.Duration // Delay before removing tenant deletion mark and "debug".
 	DeleteBlocksConcurrency int
+	ColdStorageTieringAge   time.Duration // Age after which blocks are moved to the cold storage. 0 = disabled.
+	TenantConfigDeleters    map[string]TenantConfigDeleter
 }
 
 type BlocksCleaner struct {
@@ -59,6 +63,9 @@ type BlocksCleaner struct {
 	// Keep track of the last owned users.
 	lastOwnedUsers []string
 
+	// Time the last cleanup run, updating the bucket indexes, successfully completed.
+	lastSuccessfulRun atomic.Time
+
 	// Metrics.
 	runsStarted                    prometheus.Counter
 	runsCompleted                  prometheus.Counter
@@ -68,6 +75,8 @@ type BlocksCleaner struct {
 	blocksFailedTotal              prometheus.Counter
 	blocksMarkedForDeletion        prometheus.Counter
 	partialBlocksMarkedForDeletion prometheus.Counter
+	blocksMovedToColdStorage       prometheus.Counter
+	blocksMovedToColdStorageFailed prometheus.Counter
 	tenantBlocks                   *prometheus.GaugeVec
 	tenantMarkedBlocks             *prometheus.GaugeVec
 	tenantPartialBlocks            *prometheus.GaugeVec
@@ -117,6 +126,14 @@ func NewBlocksCleaner(cfg BlocksCleanerConfig, bucketClient objstore.Bucket, own
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "partial"},
 		}),
+		blocksMovedToColdStorage: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_moved_to_cold_storage_total",
+			Help: "Total number of blocks moved to the cold storage.",
+		}),
+		blocksMovedToColdStorageFailed: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_moved_to_cold_storage_failures_total",
+			Help: "Total number of blocks failed to be moved to the cold storage.",
+		}),
 
 		// The following metrics don't have the "cortex_compactor" prefix because not strictly related to
 		// the compactor. They're just tracked by the compactor because it's the most logical place where these
@@ -198,6 +215,7 @@ func (c *BlocksCleaner) instrumentFinishedCleanupRun(err error, logger log.Logge
 		level.Info(logger).Log("msg", "successfully completed blocks cleanup and maintenance")
 		c.runsCompleted.Inc()
 		c.runsLastSuccess.SetToCurrentTime()
+		c.lastSuccessfulRun.Store(time.Now())
 	} else if errors.Is(err, context.Canceled) {
 		level.Info(logger).Log("msg", "canceled blocks cleanup and maintenance", "err", err)
 		return
@@ -207,6 +225,12 @@ func (c *BlocksCleaner) instrumentFinishedCleanupRun(err error, logger log.Logge
 	}
 }
 
+// LastSuccessfulRun returns the time the last cleanup run successfully completed, or the zero time
+// if no cleanup run successfully completed yet.
+func (c *BlocksCleaner) LastSuccessfulRun() time.Time {
+	return c.lastSuccessfulRun.Load()
+}
+
 // refreshOwnedUsers is not required to be concurrency safe, but a single instance of this function
 // could run concurrently with the cleanup job for any tenant.
 func (c *BlocksCleaner) refreshOwnedUsers(ctx context.Context) ([]string, map[string]bool, error) {
@@ -250,6 +274,14 @@ func (c *BlocksCleaner) cleanUsers(ctx context.Context, allUsers []string, isDel
 	})
 }
 
+// UpdateUserBucketIndex runs the cleanup and maintenance of the input tenant, which updates its bucket index
+// with the blocks found in the storage, if the tenant is owned by this blocks cleaner. The run goes through the
+// same single flight as the periodic runs, so that the bucket index is never written concurrently by this
+// blocks cleaner: nothing is done if the tenant cleanup is already in progress.
+func (c *BlocksCleaner) UpdateUserBucketIndex(ctx context.Context, userID string) error {
+	return c.cleanUsers(ctx, []string{userID}, nil)
+}
+
 // deleteUserMarkedForDeletion removes blocks and remaining data for tenant marked for deletion.
 func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID string) error {
 	userLogger := util_log.WithUserID(userID, c.logger)
@@ -321,6 +353,16 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 		return fmt.Errorf("cannot find tenant deletion mark anymore")
 	}
 
+	// Delete the tenant configurations stored outside the blocks storage, unless they have already been deleted.
+	configsDeleted := false
+	if mark.ConfigsDeletedTime == 0 {
+		if err := c.deleteTenantConfigs(ctx, userID, userLogger); err != nil {
+			return err
+		}
+		mark.ConfigsDeletedTime = time.Now().Unix()
+		configsDeleted = true
+	}
+
 	// If we have just deleted some blocks, update "finished" time. Also update "finished" time if it wasn't set yet, but there are no blocks.
 	// Note: this UPDATES the tenant deletion mark. Components that use caching bucket will NOT SEE this update,
 	// but that is fine -- they only check whether tenant deletion marker exists or not.
@@ -330,6 +372,12 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 		return errors.Wrap(mimir_tsdb.WriteTenantDeletionMark(ctx, c.bucketClient, userID, c.cfgProvider, mark), "failed to update tenant deletion mark")
 	}
 
+	if configsDeleted {
+		if err := mimir_tsdb.WriteTenantDeletionMark(ctx, c.bucketClient, userID, c.cfgProvider, mark); err != nil {
+			return errors.Wrap(err, "failed to update tenant deletion mark")
+		}
+	}
+
 	if time.Since(time.Unix(mark.FinishedTime, 0)) < c.cfg.TenantCleanupDelay {
 		return nil
 	}
@@ -353,6 +401,24 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 	return nil
 }
 
+// deleteTenantConfigs deletes the tenant configurations stored outside the blocks storage. An error is
+// returned if any of the configurations couldn't be deleted, so that the deletion is retried at the next run.
+func (c *BlocksCleaner) deleteTenantConfigs(ctx context.Context, userID string, userLogger log.Logger) error {
+	names := make([]string, 0, len(c.cfg.TenantConfigDeleters))
+	for name := range c.cfg.TenantConfigDeleters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := c.cfg.TenantConfigDeleters[name](ctx, userID); err != nil {
+			return errors.Wrapf(err, "failed to delete %s for tenant marked for deletion", name)
+		}
+		level.Info(userLogger).Log("msg", "deleted "+name+" for tenant marked for deletion")
+	}
+	return nil
+}
+
 func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string) (returnErr error) {
 	userLogger := util_log.WithUserID(userID, c.logger)
 	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
@@ -394,6 +460,7 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string) (returnErr
 	}
 
 	c.deleteBlocksMarkedForDeletion(ctx, idx, userBucket, userLogger)
+	c.deleteStaleBlockReplacementMarks(ctx, userBucket, userLogger)
 
 	// Partial blocks with a deletion mark can be cleaned up. This is a best effort, so we don't return
 	// error if the cleanup of partial blocks fail.
@@ -409,6 +476,10 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string) (returnErr
 		c.cleanUserPartialBlocks(ctx, partials, idx, partialDeletionCutoffTime, userBucket, userLogger)
 	}
 
+	if c.cfg.ColdStorageTieringAge > 0 {
+		c.moveBlocksToColdStorage(ctx, idx, userID, userLogger)
+	}
+
 	// Upload the updated index to the storage.
 	if err := bucketindex.WriteIndex(ctx, c.bucketClient, userID, c.cfgProvider, idx); err != nil {
 		return err
@@ -457,6 +528,31 @@ func (c *BlocksCleaner) deleteBlocksMarkedForDeletion(ctx context.Context, idx *
 	})
 }
 
+// deleteStaleBlockReplacementMarks deletes the block replacement marks older than the deletion delay. Once
+// the deletion delay has elapsed the replaced blocks are deleted, so the marks are no longer useful.
+// This is a best effort, so errors are just logged.
+func (c *BlocksCleaner) deleteStaleBlockReplacementMarks(ctx context.Context, userBucket objstore.Bucket, userLogger log.Logger) {
+	ids, err := bucketindex.ListBlockReplacementMarks(ctx, userBucket)
+	if err != nil {
+		level.Warn(userLogger).Log("msg", "failed to list block replacement marks", "err", err)
+		return
+	}
+
+	for _, id := range ids {
+		// The block ID is generated when the compacted block is created, so it's a good
+		// approximation of the mark creation time and doesn't require to read the mark.
+		if time.Since(ulid.Time(id.Time())) <= c.cfg.DeletionDelay {
+			continue
+		}
+
+		if err := userBucket.Delete(ctx, bucketindex.BlockReplacementMarkFilepath(id)); err != nil && !userBucket.IsObjNotFoundErr(err) {
+			level.Warn(userLogger).Log("msg", "failed to delete stale block replacement mark", "block", id, "err", err)
+			continue
+		}
+		level.Info(userLogger).Log("msg", "deleted stale block replacement mark", "block", id)
+	}
+}
+
 // cleanUserPartialBlocks deletes partial blocks which are safe to be deleted. The provided index is updated accordingly.
 // partialDeletionCutoffTime, if not zero, is used to find blocks without deletion marker that were last modified before this time. Such blocks will be marked for deletion.
 func (c *BlocksCleaner) cleanUserPartialBlocks(ctx context.Context, partials map[ulid.ULID]error, idx *bucketindex.Index, partialDeletionCutoffTime time.Time, userBucket objstore.InstrumentedBucket, userLogger log.Logger) {
@@ -276,6 +276,71 @@ func TestBlocksCleaner_ShouldContinueOnBlockDeletionFailure(t *testing.T) {
 	assert.ElementsMatch(t, []ulid.ULID{block3}, idx.BlockDeletionMarks.GetULIDs())
 }
 
+func TestBlocksCleaner_ShouldDeleteTenantConfigsForTenantMarkedForDeletion(t *testing.T) {
+	const userID = "user-1"
+
+	bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	bucketClient = bucketindex.BucketWithGlobalMarkers(bucketClient)
+
+	ctx := context.Background()
+	block1 := createTSDBBlock(t, bucketClient, userID, 10, 20, 2, nil)
+	require.NoError(t, tsdb.WriteTenantDeletionMark(ctx, bucketClient, userID, nil, tsdb.NewTenantDeletionMark(time.Now())))
+
+	var (
+		deletedRuleGroups       []string
+		alertmanagerConfigFails = true
+	)
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		TenantCleanupDelay:      time.Hour,
+		DeleteBlocksConcurrency: 1,
+		TenantConfigDeleters: map[string]TenantConfigDeleter{
+			"rule groups": func(_ context.Context, userID string) error {
+				deletedRuleGroups = append(deletedRuleGroups, userID)
+				return nil
+			},
+			"alertmanager configuration": func(context.Context, string) error {
+				if alertmanagerConfigFails {
+					return errors.New("failed to delete alertmanager configuration")
+				}
+				return nil
+			},
+		},
+	}
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, newMockConfigProvider(), log.NewNopLogger(), nil)
+
+	// The blocks are deleted, but the tenant deletion isn't finished until the configs are deleted.
+	require.Error(t, cleaner.deleteUserMarkedForDeletion(ctx, userID))
+
+	exists, err := bucketClient.Exists(ctx, path.Join(userID, block1.String(), metadata.MetaFilename))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	mark, err := tsdb.ReadTenantDeletionMark(ctx, bucketClient, userID)
+	require.NoError(t, err)
+	assert.Zero(t, mark.ConfigsDeletedTime)
+	assert.Zero(t, mark.FinishedTime)
+	assert.Empty(t, deletedRuleGroups)
+
+	// The configs deletion is retried at the next run.
+	alertmanagerConfigFails = false
+	require.NoError(t, cleaner.deleteUserMarkedForDeletion(ctx, userID))
+
+	mark, err = tsdb.ReadTenantDeletionMark(ctx, bucketClient, userID)
+	require.NoError(t, err)
+	assert.NotZero(t, mark.ConfigsDeletedTime)
+	assert.NotZero(t, mark.FinishedTime)
+	assert.Equal(t, []string{userID}, deletedRuleGroups)
+
+	// The configs are not deleted again once the deletion has been recorded in the tenant deletion mark.
+	require.NoError(t, cleaner.deleteUserMarkedForDeletion(ctx, userID))
+	assert.Equal(t, []string{userID}, deletedRuleGroups)
+}
+
 func TestBlocksCleaner_ShouldRebuildBucketIndexOnCorruptedOne(t *testing.T) {
 	const userID = "user-1"
 
@@ -909,6 +974,34 @@ func TestBlocksCleaner_ShouldNotRemovePartialBlocksIfConfiguredDelayIsInvalid(t
 	))
 }
 
+func TestBlocksCleaner_ShouldRemoveStaleBlockReplacementMarks(t *testing.T) {
+	bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	bucketClient = bucketindex.BucketWithGlobalMarkers(bucketClient)
+
+	ctx := context.Background()
+	userBucket := bucket.NewUserBucketClient("user-1", bucketClient, nil)
+
+	createTSDBBlock(t, bucketClient, "user-1", 10, 20, 2, nil)
+	staleBlock := ulid.MustNew(ulid.Timestamp(time.Now().Add(-2*time.Hour)), rand.Reader)
+	recentBlock := ulid.MustNew(ulid.Now(), rand.Reader)
+	require.NoError(t, bucketindex.WriteBlockReplacementMark(ctx, userBucket, staleBlock, nil))
+	require.NoError(t, bucketindex.WriteBlockReplacementMark(ctx, userBucket, recentBlock, nil))
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		DeleteBlocksConcurrency: 1,
+	}
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, newMockConfigProvider(), test.NewTestingLogger(t), prometheus.NewPedanticRegistry())
+	require.NoError(t, cleaner.runCleanupWithErr(ctx))
+
+	ids, err := bucketindex.ListBlockReplacementMarks(ctx, userBucket)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{recentBlock}, ids)
+}
+
 func TestStalePartialBlockLastModifiedTime(t *testing.T) {
 	b, dir := mimir_testutil.PrepareFilesystemBucket(t)
 
@@ -968,6 +1061,7 @@ func (m *mockBucketFailure) Delete(ctx context.Context, name string) error {
 
 type mockConfigProvider struct {
 	userRetentionPeriods         map[string]time.Duration
+	blockRanges                  map[string]tsdb.DurationList
 	splitAndMergeShards          map[string]int
 	instancesShardSize           map[string]int
 	splitGroups                  map[string]int
@@ -981,6 +1075,7 @@ type mockConfigProvider struct {
 func newMockConfigProvider() *mockConfigProvider {
 	return &mockConfigProvider{
 		userRetentionPeriods:         make(map[string]time.Duration),
+		blockRanges:                  make(map[string]tsdb.DurationList),
 		splitAndMergeShards:          make(map[string]int),
 		splitGroups:                  make(map[string]int),
 		blockUploadEnabled:           make(map[string]bool),
@@ -998,6 +1093,10 @@ func (m *mockConfigProvider) CompactorBlocksRetentionPeriod(user string) time.Du
 	return 0
 }
 
+func (m *mockConfigProvider) CompactorBlockRanges(user string) tsdb.DurationList {
+	return m.blockRanges[user]
+}
+
 func (m *mockConfigProvider) CompactorSplitAndMergeShards(user string) int {
 	if result, ok := m.splitAndMergeShards[user]; ok {
 		return result
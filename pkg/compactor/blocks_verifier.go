@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	// Number of verification marks read concurrently when selecting the blocks to verify.
+	defaultReadVerificationMarksConcurrency = 16
+)
+
+type BlocksVerifierConfig struct {
+	VerificationInterval time.Duration
+	BlocksPerTenant      int
+	DataDir              string // Directory the blocks are downloaded to while verified.
+}
+
+// BlocksVerifier periodically verifies the chunks checksums and the index integrity of a sample of the blocks
+// of the owned tenants, in order to detect the blocks silently corrupted in the object storage before queries
+// fail. The result of the verification is stored in a verification mark uploaded next to the block.
+type BlocksVerifier struct {
+	services.Service
+
+	cfg          BlocksVerifierConfig
+	cfgProvider  ConfigProvider
+	logger       log.Logger
+	bucketClient objstore.Bucket
+	usersScanner *mimir_tsdb.UsersScanner
+
+	// Metrics.
+	blocksVerified       prometheus.Counter
+	corruptedBlocksFound prometheus.Counter
+	verificationFailures prometheus.Counter
+}
+
+func NewBlocksVerifier(cfg BlocksVerifierConfig, bucketClient objstore.Bucket, ownUser func(userID string) (bool, error), cfgProvider ConfigProvider, logger log.Logger, reg prometheus.Registerer) *BlocksVerifier {
+	v := &BlocksVerifier{
+		cfg:          cfg,
+		cfgProvider:  cfgProvider,
+		logger:       log.With(logger, "component", "verifier"),
+		bucketClient: bucketClient,
+		usersScanner: mimir_tsdb.NewUsersScanner(bucketClient, ownUser, logger),
+		blocksVerified: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_verified_total",
+			Help: "Total number of blocks whose chunks checksums and index integrity have been verified.",
+		}),
+		corruptedBlocksFound: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_corrupted_blocks_found_total",
+			Help: "Total number of verified blocks found with corrupted chunks or index.",
+		}),
+		verificationFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_block_verification_failures_total",
+			Help: "Total number of blocks failed to be verified, for example because they couldn't be downloaded.",
+		}),
+	}
+
+	v.Service = services.NewTimerService(cfg.VerificationInterval, nil, v.verifyUsers, nil)
+	return v
+}
+
+func (v *BlocksVerifier) verifyUsers(ctx context.Context) error {
+	users, _, err := v.usersScanner.ScanUsers(ctx)
+	if err != nil {
+		level.Warn(v.logger).Log("msg", "failed to discover users from bucket", "err", err)
+		return nil
+	}
+
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		userLogger := util_log.WithUserID(userID, v.logger)
+		if err := v.verifyUser(ctx, userID, userLogger); err != nil {
+			level.Warn(userLogger).Log("msg", "failed to verify blocks", "err", err)
+		}
+	}
+
+	// Failures are not returned, to keep verifying blocks at the next interval.
+	return nil
+}
+
+func (v *BlocksVerifier) verifyUser(ctx context.Context, userID string, userLogger log.Logger) error {
+	idx, err := bucketindex.ReadIndex(ctx, v.bucketClient, userID, v.cfgProvider, userLogger)
+	if errors.Is(err, bucketindex.ErrIndexNotFound) {
+		// The bucket index is created by the blocks cleaner, so it will be available at the next run.
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "read bucket index")
+	}
+
+	userBucket := bucket.NewUserBucketClient(userID, v.bucketClient, v.cfgProvider)
+
+	blocks, err := v.selectBlocksToVerify(ctx, userBucket, idx, userLogger)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range blocks {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := v.verifyBlock(ctx, userBucket, b, userLogger); err != nil {
+			v.verificationFailures.Inc()
+			level.Warn(userLogger).Log("msg", "failed to verify block", "block", b.ID, "err", err)
+		}
+	}
+	return nil
+}
+
+// selectBlocksToVerify returns the blocks to verify among the ones in the bucket index: the blocks never
+// verified come first, followed by the blocks verified the longest time ago.
+func (v *BlocksVerifier) selectBlocksToVerify(ctx context.Context, userBucket objstore.Bucket, idx *bucketindex.Index, userLogger log.Logger) ([]*bucketindex.Block, error) {
+	deleted := map[ulid.ULID]struct{}{}
+	for _, m := range idx.BlockDeletionMarks {
+		deleted[m.ID] = struct{}{}
+	}
+
+	// Blocks marked for deletion are skipped, as well as the blocks moved to the cold storage,
+	// which are stored in a different bucket.
+	var candidates []*bucketindex.Block
+	for _, b := range idx.Blocks {
+		if _, ok := deleted[b.ID]; ok || b.Tier == bucketindex.BlockTierCold {
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+
+	var (
+		mtx          sync.Mutex
+		verifiedTime = make(map[ulid.ULID]int64, len(candidates))
+	)
+
+	err := concurrency.ForEachJob(ctx, len(candidates), defaultReadVerificationMarksConcurrency, func(ctx context.Context, idx int) error {
+		id := candidates[idx].ID
+
+		mark := metadata.VerificationMark{}
+		if err := metadata.ReadMarker(ctx, userLogger, objstore.WithNoopInstr(userBucket), id.String(), &mark); err != nil {
+			if errors.Is(err, metadata.ErrorMarkerNotFound) {
+				return nil
+			}
+			// A corrupted or partially uploaded mark is overwritten once the block is verified again.
+			level.Warn(userLogger).Log("msg", "failed to read block verification mark", "block", id, "err", err)
+			return nil
+		}
+
+		mtx.Lock()
+		verifiedTime[id] = mark.VerificationTime
+		mtx.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ti, tj := verifiedTime[candidates[i].ID], verifiedTime[candidates[j].ID]
+		if ti != tj {
+			return ti < tj
+		}
+		return candidates[i].ID.Compare(candidates[j].ID) < 0
+	})
+
+	if len(candidates) > v.cfg.BlocksPerTenant {
+		candidates = candidates[:v.cfg.BlocksPerTenant]
+	}
+	return candidates, nil
+}
+
+// verifyBlock downloads the block, verifies its chunks checksums and index integrity, and uploads
+// the verification mark with the result. An error is returned if the block couldn't be verified.
+func (v *BlocksVerifier) verifyBlock(ctx context.Context, userBucket objstore.Bucket, b *bucketindex.Block, userLogger log.Logger) error {
+	blockDir := filepath.Join(v.cfg.DataDir, b.ID.String())
+	defer func() {
+		if err := os.RemoveAll(blockDir); err != nil {
+			level.Warn(userLogger).Log("msg", "failed to remove verified block directory", "dir", blockDir, "err", err)
+		}
+	}()
+
+	// Remove the leftovers of a previous verification of the block interrupted before completion,
+	// otherwise the files already downloaded wouldn't be downloaded again.
+	if err := os.RemoveAll(blockDir); err != nil {
+		return errors.Wrapf(err, "remove block directory %s", blockDir)
+	}
+
+	if err := block.Download(ctx, userLogger, userBucket, b.ID, blockDir); err != nil {
+		return errors.Wrapf(err, "download block %s", b.ID)
+	}
+
+	mark := metadata.VerificationMark{
+		ID:      b.ID,
+		Version: metadata.VerificationMarkVersion1,
+	}
+
+	if err := block.VerifyBlock(userLogger, blockDir, b.MinTime, b.MaxTime, true); err != nil {
+		mark.Corrupted = true
+		mark.Details = err.Error()
+	}
+	mark.VerificationTime = time.Now().Unix()
+
+	data, err := json.Marshal(mark)
+	if err != nil {
+		return errors.Wrap(err, "json encode verification mark")
+	}
+
+	markFile := path.Join(b.ID.String(), metadata.VerificationMarkFilename)
+	if err := userBucket.Upload(ctx, markFile, bytes.NewReader(data)); err != nil {
+		return errors.Wrapf(err, "upload file %s to bucket", markFile)
+	}
+
+	v.blocksVerified.Inc()
+	if mark.Corrupted {
+		v.corruptedBlocksFound.Inc()
+		level.Error(userLogger).Log("msg", "block verification found a corrupted block", "block", b.ID, "err", mark.Details)
+	} else {
+		level.Info(userLogger).Log("msg", "block verification completed", "block", b.ID)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+)
+
+func TestBlocksVerifier(t *testing.T) {
+	const userID = "user-1"
+
+	bucketClient, storageDir := mimir_testutil.PrepareFilesystemBucket(t)
+	bucketClient = bucketindex.BucketWithGlobalMarkers(bucketClient)
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	ids := []ulid.ULID{
+		createTSDBBlock(t, bucketClient, userID, 10, 20, 2, nil),
+		createTSDBBlock(t, bucketClient, userID, 20, 30, 2, nil),
+		createTSDBBlock(t, bucketClient, userID, 30, 40, 2, nil),
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+
+	// Corrupt the checksum of the last chunk of the first block.
+	segmentFile := filepath.Join(storageDir, userID, ids[0].String(), "chunks", "000001")
+	data, err := os.ReadFile(segmentFile)
+	require.NoError(t, err)
+	data[len(data)-1]++
+	require.NoError(t, os.WriteFile(segmentFile, data, 0600))
+
+	// Blocks marked for deletion are not verified.
+	deleted := createTSDBBlock(t, bucketClient, userID, 40, 50, 2, nil)
+	createDeletionMark(t, bucketClient, userID, deleted, time.Now())
+
+	idx, _, err := bucketindex.NewUpdater(bucketClient, userID, nil, logger).UpdateIndex(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, bucketindex.WriteIndex(ctx, bucketClient, userID, nil, idx))
+
+	cfg := BlocksVerifierConfig{
+		VerificationInterval: time.Hour,
+		BlocksPerTenant:      2,
+		DataDir:              t.TempDir(),
+	}
+	ownAllUsers := func(string) (bool, error) { return true, nil }
+	v := NewBlocksVerifier(cfg, bucketClient, ownAllUsers, nil, logger, prometheus.NewPedanticRegistry())
+
+	readMark := func(id ulid.ULID) *metadata.VerificationMark {
+		mark := &metadata.VerificationMark{}
+		err := metadata.ReadMarker(ctx, logger, objstore.WithNoopInstr(bucketClient), path.Join(userID, id.String()), mark)
+		if errors.Is(err, metadata.ErrorMarkerNotFound) {
+			return nil
+		}
+		require.NoError(t, err)
+		return mark
+	}
+
+	// The first run verifies the first two blocks.
+	require.NoError(t, v.verifyUsers(ctx))
+
+	corruptedMark := readMark(ids[0])
+	require.NotNil(t, corruptedMark)
+	assert.True(t, corruptedMark.Corrupted)
+	assert.Contains(t, corruptedMark.Details, "checksum mismatch")
+
+	healthyMark := readMark(ids[1])
+	require.NotNil(t, healthyMark)
+	assert.False(t, healthyMark.Corrupted)
+	assert.Empty(t, healthyMark.Details)
+
+	assert.Nil(t, readMark(ids[2]))
+
+	assert.Equal(t, float64(2), prom_testutil.ToFloat64(v.blocksVerified))
+	assert.Equal(t, float64(1), prom_testutil.ToFloat64(v.corruptedBlocksFound))
+	assert.Equal(t, float64(0), prom_testutil.ToFloat64(v.verificationFailures))
+
+	// The next run verifies the block never verified first.
+	require.NoError(t, v.verifyUsers(ctx))
+
+	mark := readMark(ids[2])
+	require.NotNil(t, mark)
+	assert.False(t, mark.Corrupted)
+
+	assert.Nil(t, readMark(deleted))
+	assert.Equal(t, float64(4), prom_testutil.ToFloat64(v.blocksVerified))
+
+	// The downloaded blocks have been removed.
+	entries, err := os.ReadDir(cfg.DataDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
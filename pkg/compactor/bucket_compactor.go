@@ -26,13 +26,16 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/providers/filesystem"
 	"go.uber.org/atomic"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
 )
 
 type DeduplicateFilter interface {
@@ -316,11 +319,11 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 		// Ensure all input blocks are valid.
 		stats, err := block.GatherBlockHealthStats(jobLogger, bdir, meta.MinTime, meta.MaxTime, false)
 		if err != nil {
-			return errors.Wrapf(err, "gather index issues for block %s", bdir)
+			return blockError(errors.Wrapf(err, "gather index issues for block %s", bdir), meta.ULID)
 		}
 
 		if err := stats.CriticalErr(); err != nil {
-			return errors.Wrapf(err, "block with not healthy index found %s; Compaction level %v; Labels: %v", bdir, meta.Compaction.Level, meta.Thanos.Labels)
+			return blockError(errors.Wrapf(err, "block with not healthy index found %s; Compaction level %v; Labels: %v", bdir, meta.Compaction.Level, meta.Thanos.Labels), meta.ULID)
 		}
 
 		if err := stats.OutOfOrderChunksErr(); err != nil {
@@ -332,7 +335,7 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 		}
 
 		if err := stats.OutOfOrderLabelsErr(); err != nil {
-			return errors.Wrapf(err, "block id %s", meta.ULID)
+			return blockError(errors.Wrapf(err, "block id %s", meta.ULID), meta.ULID)
 		}
 		return nil
 	})
@@ -345,6 +348,17 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 		blocksToCompactDirs[ix] = filepath.Join(subDir, meta.ULID.String())
 	}
 
+	// The exemplars of the source blocks, if any, are stored in the compacted blocks.
+	exemplarSets := make([][]mimirpb.TimeSeries, 0, len(blocksToCompactDirs))
+	for _, bdir := range blocksToCompactDirs {
+		series, err := block.ReadExemplarsFile(bdir)
+		if err != nil {
+			return false, nil, errors.Wrapf(err, "read exemplars of block %s", bdir)
+		}
+		exemplarSets = append(exemplarSets, series)
+	}
+	exemplars := block.MergeExemplars(exemplarSets...)
+
 	elapsed := time.Since(downloadBegin)
 	level.Info(jobLogger).Log("msg", "downloaded and verified blocks; compacting blocks", "blocks", len(blocksToCompactDirs), "plan", fmt.Sprintf("%v", blocksToCompactDirs), "duration", elapsed, "duration_ms", elapsed.Milliseconds())
 
@@ -414,7 +428,23 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 			return errors.Wrapf(err, "invalid result block %s", bdir)
 		}
 
+		blockExemplars := exemplars
+		if job.UseSplitting() {
+			blockExemplars = shardExemplars(exemplars, blockToUpload.shardIndex, job.SplittingShards())
+		}
+		if err := block.WriteExemplarsFile(bdir, blockExemplars); err != nil {
+			return errors.Wrapf(err, "failed to write the exemplars of the block %s", bdir)
+		}
+
 		begin := time.Now()
+
+		// The index-header is uploaded before the block, so that it's available once the block is.
+		if c.uploadIndexHeaders {
+			if err := uploadIndexHeader(ctx, jobLogger, c.bkt, subDir, blockToUpload.ulid); err != nil {
+				return errors.Wrapf(err, "upload of the index-header of %s failed", blockToUpload.ulid)
+			}
+		}
+
 		if err := block.Upload(ctx, jobLogger, c.bkt, bdir, nil); err != nil {
 			return errors.Wrapf(err, "upload of %s failed", blockToUpload.ulid)
 		}
@@ -430,6 +460,22 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 	elapsed = time.Since(uploadBegin)
 	level.Info(jobLogger).Log("msg", "uploaded all blocks", "blocks", uploadedBlocks, "duration", elapsed, "duration_ms", elapsed.Milliseconds())
 
+	// Let store-gateways know about the new blocks before the compacted ones are marked for deletion,
+	// so that they can build the index-header of the new blocks in advance.
+	if c.writeBlockReplacementMarks {
+		replaced := make([]ulid.ULID, 0, len(toCompact))
+		for _, meta := range toCompact {
+			replaced = append(replaced, meta.ULID)
+		}
+
+		for _, blockToUpload := range blocksToUpload {
+			if err := bucketindex.WriteBlockReplacementMark(ctx, c.bkt, blockToUpload.ulid, replaced); err != nil {
+				// The marks are an optimization, so we don't fail the compaction.
+				level.Warn(jobLogger).Log("msg", "failed to upload block replacement mark", "block", blockToUpload.ulid, "err", err)
+			}
+		}
+	}
+
 	// Mark for deletion the blocks we just compacted from the job and bucket so they do not get included
 	// into the next planning cycle.
 	// Eventually the block we just uploaded should get synced into the job again (including sync-delay).
@@ -442,6 +488,38 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 	return true, compIDs, nil
 }
 
+// shardExemplars returns the exemplars of the series belonging to the input shard, using the same
+// sharding function used to split the series across the compacted blocks.
+func shardExemplars(exemplars []mimirpb.TimeSeries, shardIndex int, shardCount uint32) []mimirpb.TimeSeries {
+	var result []mimirpb.TimeSeries
+	for _, series := range exemplars {
+		if labels.StableHash(mimirpb.FromLabelAdaptersToLabels(series.Labels))%uint64(shardCount) == uint64(shardIndex) {
+			result = append(result, series)
+		}
+	}
+	return result
+}
+
+// uploadIndexHeader builds the index-header of the block in the input directory, and uploads it next to the block.
+func uploadIndexHeader(ctx context.Context, logger log.Logger, bkt objstore.Bucket, dir string, id ulid.ULID) error {
+	localBkt, err := filesystem.NewBucket(dir)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(dir, id.String(), block.IndexHeaderFilename)
+	if err := indexheader.WriteBinary(ctx, localBkt, id, filename); err != nil {
+		return errors.Wrap(err, "build index-header")
+	}
+	defer func() {
+		if err := os.Remove(filename); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove the index-header", "path", filename, "err", err)
+		}
+	}()
+
+	return objstore.UploadFile(ctx, logger, bkt, filename, path.Join(id.String(), block.IndexHeaderFilename))
+}
+
 // convertCompactionResultToForEachJobs filters out empty ULIDs.
 // When handling result of split compactions, shard index is index in the slice returned by compaction.
 func convertCompactionResultToForEachJobs(compactedBlocks []ulid.ULID, splitJob bool, jobLogger log.Logger) []ulidWithShardIndex {
@@ -509,6 +587,33 @@ func IsOutOfOrderChunkError(err error) bool {
 	return ok
 }
 
+// BlockError is a type wrapper for errors caused by a block of a compaction job whose index is not healthy.
+type BlockError struct {
+	err error
+	id  ulid.ULID
+}
+
+func (e BlockError) Error() string {
+	return e.err.Error()
+}
+
+func (e BlockError) Unwrap() error {
+	return e.err
+}
+
+func blockError(err error, brokenBlock ulid.ULID) BlockError {
+	return BlockError{err: err, id: brokenBlock}
+}
+
+// blockIDFromError returns the ID of the block which caused the error, if the error was caused by a block
+// with an unhealthy index.
+func blockIDFromError(err error) (ulid.ULID, bool) {
+	if e, ok := errors.Cause(err).(BlockError); ok {
+		return e.id, true
+	}
+	return ulid.ULID{}, false
+}
+
 // RepairIssue347 repairs the https://github.com/prometheus/tsdb/issues/347 issue when having issue347Error.
 func RepairIssue347(ctx context.Context, logger log.Logger, bkt objstore.Bucket, blocksMarkedForDeletion prometheus.Counter, issue347Err error) error {
 	ie, ok := errors.Cause(issue347Err).(Issue347Error)
@@ -590,6 +695,7 @@ type BucketCompactorMetrics struct {
 	groupCompactions             prometheus.Counter
 	blocksMarkedForDeletion      prometheus.Counter
 	blocksMarkedForNoCompact     prometheus.Counter
+	blocksQuarantined            prometheus.Counter
 	blocksMaxTimeDelta           prometheus.Histogram
 }
 
@@ -618,6 +724,11 @@ func NewBucketCompactorMetrics(blocksMarkedForDeletion prometheus.Counter, reg p
 			Help:        "Total number of blocks that were marked for no-compaction.",
 			ConstLabels: prometheus.Labels{"reason": metadata.OutOfOrderChunksNoCompactReason},
 		}),
+		blocksQuarantined: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "cortex_compactor_blocks_marked_for_no_compaction_total",
+			Help:        "Total number of blocks that were marked for no-compaction.",
+			ConstLabels: prometheus.Labels{"reason": metadata.RepeatedCompactionFailuresNoCompactReason},
+		}),
 		blocksMaxTimeDelta: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
 			Name:    "cortex_compactor_block_max_time_delta_seconds",
 			Help:    "Difference between now and the max time of a block being compacted in seconds.",
@@ -644,10 +755,13 @@ type BucketCompactor struct {
 	bkt                            objstore.Bucket
 	concurrency                    int
 	skipBlocksWithOutOfOrderChunks bool
+	writeBlockReplacementMarks     bool
+	uploadIndexHeaders             bool
 	ownJob                         ownCompactionJobFunc
 	sortJobs                       JobsOrderFunc
 	waitPeriod                     time.Duration
 	blockSyncConcurrency           int
+	quarantine                     *blockQuarantine
 	metrics                        *BucketCompactorMetrics
 }
 
@@ -662,10 +776,13 @@ func NewBucketCompactor(
 	bkt objstore.Bucket,
 	concurrency int,
 	skipBlocksWithOutOfOrderChunks bool,
+	writeBlockReplacementMarks bool,
+	uploadIndexHeaders bool,
 	ownJob ownCompactionJobFunc,
 	sortJobs JobsOrderFunc,
 	waitPeriod time.Duration,
 	blockSyncConcurrency int,
+	quarantine *blockQuarantine,
 	metrics *BucketCompactorMetrics,
 ) (*BucketCompactor, error) {
 	if concurrency <= 0 {
@@ -681,10 +798,13 @@ func NewBucketCompactor(
 		bkt:                            bkt,
 		concurrency:                    concurrency,
 		skipBlocksWithOutOfOrderChunks: skipBlocksWithOutOfOrderChunks,
+		writeBlockReplacementMarks:     writeBlockReplacementMarks,
+		uploadIndexHeaders:             uploadIndexHeaders,
 		ownJob:                         ownJob,
 		sortJobs:                       sortJobs,
 		waitPeriod:                     waitPeriod,
 		blockSyncConcurrency:           blockSyncConcurrency,
+		quarantine:                     quarantine,
 		metrics:                        metrics,
 	}, nil
 }
@@ -747,6 +867,7 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 						if hasNonZeroULIDs(compactedBlockIDs) {
 							c.metrics.groupCompactions.Inc()
 						}
+						c.quarantine.recordSuccess(g.IDs())
 
 						if shouldRerunJob {
 							mtx.Lock()
@@ -784,6 +905,14 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 							continue
 						}
 					}
+					// If the same block caused the compaction to fail too many consecutive times,
+					// then we quarantine it marking it for no compaction, and proceed with the other blocks.
+					if c.quarantineBlock(ctx, workCtx, err) {
+						mtx.Lock()
+						finishedAllJobs = false
+						mtx.Unlock()
+						continue
+					}
 					errChan <- errors.Wrapf(err, "group %s", g.Key())
 					return
 				}
@@ -802,6 +931,9 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 			return errors.Wrap(err, "garbage")
 		}
 
+		// Stop tracking the failures of the blocks which have been deleted in the meanwhile.
+		c.quarantine.retain(c.sy.Metas())
+
 		jobs, err := c.grouper.Groups(c.sy.Metas())
 		if err != nil {
 			return errors.Wrap(err, "build compaction jobs")
@@ -880,6 +1012,31 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 	return nil
 }
 
+// quarantineBlock records the compaction failure of the block which caused the input error, if any, and marks the
+// block for no-compaction once it caused the configured number of consecutive failures. Returns true if the block
+// has been marked for no-compaction.
+func (c *BucketCompactor) quarantineBlock(ctx, workCtx context.Context, compactionErr error) bool {
+	// Failures caused by the compaction being interrupted are not a block issue.
+	if workCtx.Err() != nil {
+		return false
+	}
+
+	id, ok := blockIDFromError(compactionErr)
+	if !ok || !c.quarantine.recordFailure(id) {
+		return false
+	}
+
+	details := fmt.Sprintf("RepeatedCompactionFailures: marking block which failed %d consecutive compactions as no compact to unblock compaction, last error: %v", c.quarantine.threshold, compactionErr)
+	if err := block.MarkForNoCompact(ctx, c.logger, c.bkt, id, metadata.RepeatedCompactionFailuresNoCompactReason, details, c.metrics.blocksQuarantined); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to mark block which repeatedly failed compaction for no-compaction", "block", id, "err", err)
+		return false
+	}
+
+	level.Warn(c.logger).Log("msg", "marked block which repeatedly failed compaction for no-compaction", "block", id, "failures", c.quarantine.threshold, "err", compactionErr)
+	c.quarantine.forget(id)
+	return true
+}
+
 // blockMaxTimeDeltas returns a slice of the difference between now and the MaxTime of each
 // block that will be compacted as part of the provided jobs, in seconds.
 func (c *BucketCompactor) blockMaxTimeDeltas(now time.Time, jobs []*Job) []float64 {
@@ -7,6 +7,9 @@ package compactor
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -22,9 +25,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/objstore"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	"github.com/grafana/mimir/pkg/storegateway/testhelper"
 	"github.com/grafana/mimir/pkg/util/extprom"
 )
 
@@ -121,7 +127,7 @@ func TestBucketCompactor_FilterOwnJobs(t *testing.T) {
 	m := NewBucketCompactorMetrics(promauto.With(nil).NewCounter(prometheus.CounterOpts{}), nil)
 	for testName, testCase := range tests {
 		t.Run(testName, func(t *testing.T) {
-			bc, err := NewBucketCompactor(log.NewNopLogger(), nil, nil, nil, nil, "", nil, 2, false, testCase.ownJob, nil, 0, 4, m)
+			bc, err := NewBucketCompactor(log.NewNopLogger(), nil, nil, nil, nil, "", nil, 2, false, false, false, testCase.ownJob, nil, 0, 4, nil, m)
 			require.NoError(t, err)
 
 			res, err := bc.filterOwnJobs(jobsFn())
@@ -157,7 +163,7 @@ func TestBlockMaxTimeDeltas(t *testing.T) {
 
 	metrics := NewBucketCompactorMetrics(promauto.With(nil).NewCounter(prometheus.CounterOpts{}), nil)
 	now := time.UnixMilli(1500002900159)
-	bc, err := NewBucketCompactor(log.NewNopLogger(), nil, nil, nil, nil, "", nil, 2, false, nil, nil, 0, 4, metrics)
+	bc, err := NewBucketCompactor(log.NewNopLogger(), nil, nil, nil, nil, "", nil, 2, false, false, false, nil, nil, 0, 4, nil, metrics)
 	require.NoError(t, err)
 
 	deltas := bc.blockMaxTimeDeltas(now, []*Job{j1, j2})
@@ -282,3 +288,64 @@ func TestConvertCompactionResultToForEachJobs(t *testing.T) {
 	require.Equal(t, ulidWithShardIndex{ulid: ulid1, shardIndex: 1}, res[0])
 	require.Equal(t, ulidWithShardIndex{ulid: ulid2, shardIndex: 3}, res[1])
 }
+
+func TestShardExemplars(t *testing.T) {
+	const shardCount = 3
+
+	var exemplars []mimirpb.TimeSeries
+	for i := 0; i < 30; i++ {
+		exemplars = append(exemplars, mimirpb.TimeSeries{
+			Labels:    mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("series", fmt.Sprintf("%d", i))),
+			Exemplars: []mimirpb.Exemplar{{Value: float64(i), TimestampMs: int64(i)}},
+		})
+	}
+
+	total := 0
+	for shardIndex := 0; shardIndex < shardCount; shardIndex++ {
+		sharded := shardExemplars(exemplars, shardIndex, shardCount)
+		for _, series := range sharded {
+			lbls := mimirpb.FromLabelAdaptersToLabels(series.Labels)
+			assert.Equal(t, uint64(shardIndex), labels.StableHash(lbls)%shardCount)
+		}
+		total += len(sharded)
+	}
+
+	// Each series belongs to exactly one shard.
+	assert.Equal(t, len(exemplars), total)
+}
+
+func TestUploadIndexHeader(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	series := []labels.Labels{
+		labels.FromStrings("a", "1"),
+		labels.FromStrings("a", "2"),
+		labels.FromStrings("a", "3"),
+		labels.FromStrings("a", "4"),
+	}
+	id, err := testhelper.CreateBlock(ctx, dir, series, 10, 0, 1000, labels.EmptyLabels())
+	require.NoError(t, err)
+
+	bkt := objstore.NewInMemBucket()
+	require.NoError(t, uploadIndexHeader(ctx, log.NewNopLogger(), bkt, dir, id))
+
+	// The local index-header is removed once uploaded.
+	assert.NoFileExists(t, filepath.Join(dir, id.String(), block.IndexHeaderFilename))
+
+	// The uploaded index-header is the one the store-gateway would build.
+	require.NoError(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(dir, id.String()), nil))
+	expected := filepath.Join(t.TempDir(), "expected")
+	require.NoError(t, indexheader.WriteBinary(ctx, bkt, id, expected))
+
+	downloaded := filepath.Join(t.TempDir(), "downloaded")
+	found, err := indexheader.DownloadBinary(ctx, bkt, id, downloaded)
+	require.NoError(t, err)
+	require.True(t, found)
+
+	expectedContent, err := os.ReadFile(expected)
+	require.NoError(t, err)
+	downloadedContent, err := os.ReadFile(downloaded)
+	require.NoError(t, err)
+	assert.Equal(t, expectedContent, downloadedContent)
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+// moveBlocksToColdStorage moves the blocks older than the cold storage tiering age to the cold storage,
+// and updates the tier of the moved blocks in the provided index. This is a best effort, so errors are
+// just logged and the blocks failed to be moved will be retried at the next cleanup.
+func (c *BlocksCleaner) moveBlocksToColdStorage(ctx context.Context, idx *bucketindex.Index, userID string, userLogger log.Logger) {
+	tiered, ok := c.bucketClient.(*mimir_tsdb.TieredBucket)
+	if !ok {
+		level.Warn(userLogger).Log("msg", "skipped moving blocks to the cold storage because the cold storage is not enabled")
+		return
+	}
+
+	hotBucket := bucket.NewUserBucketClient(userID, tiered.Hot(), c.cfgProvider)
+	coldBucket := bucket.NewUserBucketClient(userID, tiered.Cold(), c.cfgProvider)
+
+	// There's no need to move the blocks which are going to be deleted.
+	marked := make(map[ulid.ULID]struct{}, len(idx.BlockDeletionMarks))
+	for _, d := range idx.BlockDeletionMarks {
+		marked[d.ID] = struct{}{}
+	}
+
+	threshold := time.Now().Add(-c.cfg.ColdStorageTieringAge)
+
+	for _, b := range idx.Blocks {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if b.Tier == bucketindex.BlockTierCold || !time.UnixMilli(b.MaxTime).Before(threshold) {
+			continue
+		}
+		if _, isMarked := marked[b.ID]; isMarked {
+			continue
+		}
+
+		if err := moveBlock(ctx, hotBucket, coldBucket, b.ID, userLogger); err != nil {
+			c.blocksMovedToColdStorageFailed.Inc()
+			level.Warn(userLogger).Log("msg", "failed to move block to the cold storage", "block", b.ID, "err", err)
+			continue
+		}
+
+		b.Tier = bucketindex.BlockTierCold
+		c.blocksMovedToColdStorage.Inc()
+		level.Info(userLogger).Log("msg", "moved block to the cold storage", "block", b.ID, "maxTime", b.MaxTime)
+	}
+}
+
+// moveBlock copies all the block files from the source to the destination bucket, and then deletes
+// them from the source bucket. The meta.json is copied last and deleted last, so that the block is
+// complete in the destination bucket before it's removed from the source bucket. The move is
+// idempotent, so a failed move can be safely retried.
+func moveBlock(ctx context.Context, src, dst objstore.Bucket, id ulid.ULID, logger log.Logger) error {
+	metaFile := path.Join(id.String(), block.MetaFilename)
+
+	var files []string
+	err := src.Iter(ctx, id.String(), func(name string) error {
+		if name != metaFile {
+			files = append(files, name)
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+	if err != nil {
+		return errors.Wrap(err, "list block files")
+	}
+
+	files = append(files, metaFile)
+
+	for _, file := range files {
+		if err := copyObject(ctx, src, dst, file, logger); err != nil {
+			return errors.Wrapf(err, "copy %s", file)
+		}
+	}
+
+	for _, file := range files {
+		if err := src.Delete(ctx, file); err != nil && !src.IsObjNotFoundErr(err) {
+			return errors.Wrapf(err, "delete %s", file)
+		}
+	}
+
+	return nil
+}
+
+func copyObject(ctx context.Context, src, dst objstore.Bucket, name string, logger log.Logger) error {
+	r, err := src.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer runutil.CloseWithLogOnErr(logger, r, "close object reader")
+
+	return dst.Upload(ctx, name, r)
+}
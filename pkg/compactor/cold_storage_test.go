@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+func TestBlocksCleaner_ShouldMoveOldBlocksToColdStorage(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	hot := objstore.NewInMemBucket()
+	cold := objstore.NewInMemBucket()
+	bucketClient := bucketindex.BucketWithGlobalMarkers(mimir_tsdb.NewTieredBucket(hot, cold))
+
+	ts := func(hours int) int64 {
+		return time.Now().Add(time.Duration(hours)*time.Hour).Unix() * 1000
+	}
+
+	oldBlock := createTSDBBlock(t, bucketClient, userID, ts(-50), ts(-48), 2, nil)
+	recentBlock := createTSDBBlock(t, bucketClient, userID, ts(-4), ts(-2), 2, nil)
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		DeleteBlocksConcurrency: 1,
+		ColdStorageTieringAge:   24 * time.Hour,
+	}
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, mimir_tsdb.AllUsers, newMockConfigProvider(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	assertBlockExists := func(bkt objstore.Bucket, block ulid.ULID, expectExists bool) {
+		exists, err := bkt.Exists(ctx, path.Join(userID, block.String(), metadata.MetaFilename))
+		require.NoError(t, err)
+		assert.Equal(t, expectExists, exists)
+	}
+
+	// Run the cleanup twice to check that moving the blocks is idempotent.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, cleaner.runCleanupWithErr(ctx))
+
+		assertBlockExists(hot, oldBlock, false)
+		assertBlockExists(cold, oldBlock, true)
+		assertBlockExists(hot, recentBlock, true)
+		assertBlockExists(cold, recentBlock, false)
+
+		idx, err := bucketindex.ReadIndex(ctx, hot, userID, nil, log.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, idx.Blocks, 2)
+		for _, b := range idx.Blocks {
+			if b.ID == oldBlock {
+				assert.Equal(t, bucketindex.BlockTierCold, b.Tier)
+			} else {
+				assert.Empty(t, b.Tier)
+			}
+		}
+	}
+
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(cleaner.blocksMovedToColdStorage))
+	assert.Equal(t, 0.0, prom_testutil.ToFloat64(cleaner.blocksMovedToColdStorageFailed))
+}
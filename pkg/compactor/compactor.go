@@ -15,6 +15,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -29,11 +30,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/thanos-io/objstore"
 
+	"github.com/grafana/mimir/pkg/sandbox"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/conditions"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
@@ -59,6 +62,9 @@ var (
 	errInvalidMaxOpeningBlocksConcurrency = fmt.Errorf("invalid max-opening-blocks-concurrency value, must be positive")
 	errInvalidMaxClosingBlocksConcurrency = fmt.Errorf("invalid max-closing-blocks-concurrency value, must be positive")
 	errInvalidSymbolFlushersConcurrency   = fmt.Errorf("invalid symbols-flushers-concurrency value, must be positive")
+	errColdStorageNotEnabled              = fmt.Errorf("the cold storage tiering requires the cold storage to be enabled")
+	errInvalidTenantSkipBackoff           = fmt.Errorf("invalid tenant-skip-backoff value, must be positive and not greater than tenant-skip-max-backoff")
+	errInvalidBlockVerificationBlocks     = fmt.Errorf("invalid block-verification-blocks-per-tenant value, must be positive when the block verification is enabled")
 	RingOp                                = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 )
 
@@ -110,6 +116,25 @@ type Config struct {
 
 	CompactionJobsOrder string `yaml:"compaction_jobs_order" category:"advanced"`
 
+	BlockReplacementMarksEnabled bool `yaml:"block_replacement_marks_enabled" category:"experimental"`
+
+	UploadIndexHeaders bool `yaml:"upload_index_headers" category:"experimental"`
+
+	RetentionPoliciesEnabled bool `yaml:"retention_policies_enabled" category:"experimental"`
+
+	DownsamplingEnabled bool `yaml:"downsampling_enabled" category:"experimental"`
+
+	ColdStorageTieringAge time.Duration `yaml:"cold_storage_tiering_age" category:"experimental"`
+
+	TenantSkipFailuresThreshold int           `yaml:"tenant_skip_failures_threshold" category:"experimental"`
+	TenantSkipBackoff           time.Duration `yaml:"tenant_skip_backoff" category:"experimental"`
+	TenantSkipMaxBackoff        time.Duration `yaml:"tenant_skip_max_backoff" category:"experimental"`
+
+	BlockQuarantineFailuresThreshold int `yaml:"block_quarantine_failures_threshold" category:"experimental"`
+
+	BlockVerificationInterval        time.Duration `yaml:"block_verification_interval" category:"experimental"`
+	BlockVerificationBlocksPerTenant int           `yaml:"block_verification_blocks_per_tenant" category:"experimental"`
+
 	// No need to add options to customize the retry backoff,
 	// given the defaults should be fine, but allow to override
 	// it in tests.
@@ -119,6 +144,14 @@ type Config struct {
 	// Allow downstream projects to customise the blocks compactor.
 	BlocksGrouperFactory   BlocksGrouperFactory   `yaml:"-"`
 	BlocksCompactorFactory BlocksCompactorFactory `yaml:"-"`
+
+	// This is dynamically injected because the sandbox tenants are shared with other components.
+	SandboxTenants *sandbox.Registry `yaml:"-"`
+
+	// Delete the tenant configurations stored outside the blocks storage when a tenant is deleted, by
+	// the name of the configuration. This is dynamically injected because the ruler and Alertmanager
+	// storages are configured outside the compactor.
+	TenantConfigDeleters map[string]TenantConfigDeleter `yaml:"-"`
 }
 
 // RegisterFlags registers the MultitenantCompactor flags.
@@ -151,6 +184,18 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.IntVar(&cfg.MaxClosingBlocksConcurrency, "compactor.max-closing-blocks-concurrency", 1, "Max number of blocks that can be closed concurrently during split compaction. Note that closing of newly compacted block uses a lot of memory for writing index.")
 	f.IntVar(&cfg.SymbolsFlushersConcurrency, "compactor.symbols-flushers-concurrency", 1, "Number of symbols flushers used when doing split compaction.")
 
+	f.BoolVar(&cfg.BlockReplacementMarksEnabled, "compactor.block-replacement-marks-enabled", false, "If enabled, the compactor uploads a replacement mark for each block produced by a compaction, before marking the compacted blocks for deletion. Store-gateways configured with -blocks-storage.bucket-store.index-header-warmup-interval use these marks to build the index-header of the new blocks in advance.")
+	f.BoolVar(&cfg.UploadIndexHeaders, "compactor.upload-index-headers", false, "If enabled, the compactor builds the index-header of each block produced by a compaction and uploads it next to the block, so that store-gateways configured with -blocks-storage.bucket-store.index-header.prebuilt-download-enabled download it instead of building it from the block index.")
+	f.DurationVar(&cfg.ColdStorageTieringAge, "compactor.cold-storage-tiering-age", 0, "Blocks older than this age are moved to the cold storage, and the bucket index is updated with the tier of the moved blocks. Requires -blocks-storage.cold-storage.enabled. 0 to disable.")
+	f.BoolVar(&cfg.RetentionPoliciesEnabled, "compactor.retention-policies-enabled", false, "If enabled, the compactor applies the retention policies configured by tenants through the retention policies API, rewriting the blocks older than a policy retention to remove the series matching the policy selector.")
+	f.BoolVar(&cfg.DownsamplingEnabled, "compactor.downsampling-enabled", false, "If enabled, the compactor downsamples the blocks compacted up to the largest compaction range to the 5m and 1h resolutions. The downsampled blocks are used by queriers configured with -querier.downsampled-blocks-enabled to run queries with a large step.")
+	f.IntVar(&cfg.TenantSkipFailuresThreshold, "compactor.tenant-skip-failures-threshold", 0, "Number of consecutive failed compaction runs of a tenant after which the compactor uploads a skip mark with the failure reason to the bucket, and skips the compaction of the tenant until the mark expires. 0 to disable.")
+	f.DurationVar(&cfg.TenantSkipBackoff, "compactor.tenant-skip-backoff", time.Hour, "How long the compaction of a tenant is skipped once the number of consecutive failures reaches -compactor.tenant-skip-failures-threshold. The backoff doubles every time the compaction of the tenant fails again.")
+	f.DurationVar(&cfg.TenantSkipMaxBackoff, "compactor.tenant-skip-max-backoff", 24*time.Hour, "Maximum time the compaction of a tenant is skipped because of consecutive failures.")
+	f.IntVar(&cfg.BlockQuarantineFailuresThreshold, "compactor.block-quarantine-failures-threshold", 0, "Number of consecutive failed compactions caused by the same block, because its index is corrupted or has out-of-order labels, after which the compactor quarantines the block marking it for no-compaction, and proceeds with the compaction of the remaining blocks. 0 to disable.")
+	f.DurationVar(&cfg.BlockVerificationInterval, "compactor.block-verification-interval", 0, "How frequently the compactor verifies the chunks checksums and the index integrity of a sample of the blocks of each owned tenant, uploading a verification mark with the result next to each verified block. 0 to disable.")
+	f.IntVar(&cfg.BlockVerificationBlocksPerTenant, "compactor.block-verification-blocks-per-tenant", 1, "Number of blocks of each tenant verified at every -compactor.block-verification-interval. The blocks never verified, or verified the longest time ago, are verified first.")
+
 	f.Var(&cfg.EnabledTenants, "compactor.enabled-tenants", "Comma separated list of tenants that can be compacted. If specified, only these tenants will be compacted by compactor, otherwise all tenants can be compacted. Subject to sharding.")
 	f.Var(&cfg.DisabledTenants, "compactor.disabled-tenants", "Comma separated list of tenants that cannot be compacted by this compactor. If specified, and compactor would normally pick given tenant for compaction (via -compactor.enabled-tenants or sharding), it will be ignored instead.")
 }
@@ -175,6 +220,12 @@ func (cfg *Config) Validate(logger log.Logger) error {
 	if !util.StringsContain(CompactionOrders, cfg.CompactionJobsOrder) {
 		return errInvalidCompactionOrder
 	}
+	if cfg.TenantSkipFailuresThreshold > 0 && (cfg.TenantSkipBackoff <= 0 || cfg.TenantSkipBackoff > cfg.TenantSkipMaxBackoff) {
+		return errInvalidTenantSkipBackoff
+	}
+	if cfg.BlockVerificationInterval > 0 && cfg.BlockVerificationBlocksPerTenant <= 0 {
+		return errInvalidBlockVerificationBlocks
+	}
 	if cfg.DeprecatedConsistencyDelay > 0 {
 		util.WarnDeprecatedConfig(consistencyDelayFlag, logger)
 	}
@@ -189,6 +240,10 @@ type ConfigProvider interface {
 	// CompactorBlocksRetentionPeriod returns the retention period for a given user.
 	CompactorBlocksRetentionPeriod(user string) time.Duration
 
+	// CompactorBlockRanges returns the compaction time ranges for a given user. An empty list
+	// means the ranges configured in the compactor config should be used.
+	CompactorBlockRanges(userID string) mimir_tsdb.DurationList
+
 	// CompactorSplitAndMergeShards returns the number of shards to use when splitting blocks.
 	CompactorSplitAndMergeShards(userID string) int
 
@@ -214,6 +269,15 @@ type ConfigProvider interface {
 	CompactorBlockUploadVerifyChunks(tenantID string) bool
 }
 
+// blockRangesForUser returns the compaction time ranges (in milliseconds) for the input user,
+// falling back to the ranges in the compactor config if the user has no overrides.
+func blockRangesForUser(cfg Config, cfgProvider ConfigProvider, userID string) []int64 {
+	if ranges := cfgProvider.CompactorBlockRanges(userID); len(ranges) > 0 {
+		return ranges.ToMilliseconds()
+	}
+	return cfg.BlockRanges.ToMilliseconds()
+}
+
 // MultitenantCompactor is a multi-tenant TSDB blocks compactor based on Thanos.
 type MultitenantCompactor struct {
 	services.Service
@@ -234,6 +298,9 @@ type MultitenantCompactor struct {
 	// Blocks cleaner is responsible to hard delete blocks marked for deletion.
 	blocksCleaner *BlocksCleaner
 
+	// Blocks verifier, nil if the block verification is disabled.
+	blocksVerifier *BlocksVerifier
+
 	// Underlying compactor and planner used to compact TSDB blocks.
 	blocksCompactor Compactor
 	blocksPlanner   Planner
@@ -250,6 +317,20 @@ type MultitenantCompactor struct {
 	shardingStrategy shardingStrategy
 	jobsOrder        JobsOrderFunc
 
+	// Blocks with no series matching the retention policies, keyed by user. Used to avoid reading
+	// the blocks retention policies checked marks at every compaction run.
+	retentionPoliciesCheckedMtx sync.Mutex
+	retentionPoliciesChecked    map[string]map[string]struct{}
+
+	// Number of consecutive failed compaction runs, keyed by user. Only accessed by the compaction loop.
+	tenantCompactionFailures map[string]int
+
+	// Consecutive compaction failures caused by each block, by tenant, used to quarantine the blocks repeatedly failing compaction.
+	blockQuarantines map[string]*blockQuarantine
+
+	// Status conditions exposed by the ready detail endpoint.
+	conditions *conditions.Set
+
 	// Metrics.
 	compactionRunsStarted          prometheus.Counter
 	compactionRunsCompleted        prometheus.Counter
@@ -263,6 +344,20 @@ type MultitenantCompactor struct {
 	compactionRunInterval          prometheus.Gauge
 	blocksMarkedForDeletion        prometheus.Counter
 
+	// Tenant compaction skip metrics.
+	tenantCompactionSkipMarksCreated prometheus.Counter
+	tenantsCompactionSkipped         prometheus.Gauge
+
+	// Sandbox tenants metrics.
+	sandboxTenantsMarkedForDeletion prometheus.Counter
+
+	// Retention policies metrics.
+	blocksRewrittenByRetentionPolicies         prometheus.Counter
+	blocksMarkedForDeletionByRetentionPolicies prometheus.Counter
+
+	// Downsampling metrics.
+	blocksDownsampled prometheus.Counter
+
 	// Metrics shared across all BucketCompactor instances.
 	bucketCompactorMetrics *BucketCompactorMetrics
 
@@ -272,8 +367,12 @@ type MultitenantCompactor struct {
 
 // NewMultitenantCompactor makes a new MultitenantCompactor.
 func NewMultitenantCompactor(compactorCfg Config, storageCfg mimir_tsdb.BlocksStorageConfig, cfgProvider ConfigProvider, logger log.Logger, registerer prometheus.Registerer) (*MultitenantCompactor, error) {
+	if compactorCfg.ColdStorageTieringAge > 0 && !storageCfg.ColdStorage.Enabled {
+		return nil, errColdStorageNotEnabled
+	}
+
 	bucketClientFactory := func(ctx context.Context) (objstore.Bucket, error) {
-		return bucket.NewClient(ctx, storageCfg.Bucket, "compactor", logger, registerer)
+		return mimir_tsdb.NewBucketClient(ctx, storageCfg, "compactor", logger, registerer)
 	}
 
 	// Configure the compactor and grouper factories.
@@ -316,6 +415,11 @@ func newMultitenantCompactor(
 		blocksGrouperFactory:   blocksGrouperFactory,
 		blocksCompactorFactory: blocksCompactorFactory,
 
+		retentionPoliciesChecked: map[string]map[string]struct{}{},
+		tenantCompactionFailures: map[string]int{},
+		blockQuarantines:         map[string]*blockQuarantine{},
+		conditions:               conditions.NewSet(conditionRingHealthy, conditionSynced, conditionCompacting, conditionLaggingBucketIndex),
+
 		compactionRunsStarted: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_compactor_runs_started_total",
 			Help: "Total number of compaction runs started.",
@@ -363,6 +467,31 @@ func newMultitenantCompactor(
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "compaction"},
 		}),
+		tenantCompactionSkipMarksCreated: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_tenant_compaction_skip_marks_created_total",
+			Help: "Total number of compaction skip marks created for tenants whose compaction repeatedly failed.",
+		}),
+		tenantsCompactionSkipped: promauto.With(registerer).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_compactor_tenants_compaction_skipped",
+			Help: "Number of tenants whose compaction has been skipped during the last compaction run because of a compaction skip mark.",
+		}),
+		sandboxTenantsMarkedForDeletion: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_sandbox_tenants_marked_for_deletion_total",
+			Help: "Total number of expired sandbox tenants marked for deletion.",
+		}),
+		blocksRewrittenByRetentionPolicies: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_retention_policies_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten to remove the series matching the tenants retention policies.",
+		}),
+		blocksMarkedForDeletionByRetentionPolicies: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name:        blocksMarkedForDeletionName,
+			Help:        blocksMarkedForDeletionHelp,
+			ConstLabels: prometheus.Labels{"reason": "retention-policy"},
+		}),
+		blocksDownsampled: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_downsampled_total",
+			Help: "Total number of downsampled blocks created by the compactor.",
+		}),
 	}
 
 	c.bucketCompactorMetrics = NewBucketCompactorMetrics(c.blocksMarkedForDeletion, registerer)
@@ -465,6 +594,8 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 		CleanupConcurrency:      c.compactorCfg.CleanupConcurrency,
 		TenantCleanupDelay:      c.compactorCfg.TenantCleanupDelay,
 		DeleteBlocksConcurrency: defaultDeleteBlocksConcurrency,
+		ColdStorageTieringAge:   c.compactorCfg.ColdStorageTieringAge,
+		TenantConfigDeleters:    c.compactorCfg.TenantConfigDeleters,
 	}, c.bucketClient, c.shardingStrategy.blocksCleanerOwnUser, c.cfgProvider, c.parentLogger, c.registerer)
 
 	// Start blocks cleaner asynchronously, don't wait until initial cleanup is finished.
@@ -473,6 +604,22 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 		return errors.Wrap(err, "failed to start the blocks cleaner")
 	}
 
+	// Create the blocks verifier (service), if enabled. The blocks of a tenant are verified by the same
+	// compactor running the blocks cleaner for the tenant.
+	if c.compactorCfg.BlockVerificationInterval > 0 {
+		c.blocksVerifier = NewBlocksVerifier(BlocksVerifierConfig{
+			VerificationInterval: util.DurationWithJitter(c.compactorCfg.BlockVerificationInterval, 0.1),
+			BlocksPerTenant:      c.compactorCfg.BlockVerificationBlocksPerTenant,
+			DataDir:              filepath.Join(c.compactorCfg.DataDir, "verify"),
+		}, c.bucketClient, c.shardingStrategy.blocksCleanerOwnUser, c.cfgProvider, c.parentLogger, c.registerer)
+
+		if err := c.blocksVerifier.StartAsync(ctx); err != nil {
+			services.StopAndAwaitTerminated(ctx, c.blocksCleaner) //nolint:errcheck
+			c.ringSubservices.StopAsync()
+			return errors.Wrap(err, "failed to start the blocks verifier")
+		}
+	}
+
 	return nil
 }
 
@@ -510,6 +657,9 @@ func (c *MultitenantCompactor) stopping(_ error) error {
 	ctx := context.Background()
 
 	services.StopAndAwaitTerminated(ctx, c.blocksCleaner) //nolint:errcheck
+	if c.blocksVerifier != nil {
+		services.StopAndAwaitTerminated(ctx, c.blocksVerifier) //nolint:errcheck
+	}
 	if c.ringSubservices != nil {
 		return services.StopManagerAndAwaitStopped(ctx, c.ringSubservices)
 	}
@@ -540,15 +690,21 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 	compactionErrorCount := 0
 
 	c.compactionRunsStarted.Inc()
+	c.conditions.Set(conditionCompacting, conditions.StatusTrue, "CompactionRunInProgress", "")
 
 	defer func() {
 		if succeeded && compactionErrorCount == 0 {
 			c.compactionRunsCompleted.Inc()
 			c.compactionRunsLastSuccess.SetToCurrentTime()
+			c.conditions.Set(conditionCompacting, conditions.StatusFalse, "CompactionRunSucceeded", "")
+			c.conditions.Set(conditionSynced, conditions.StatusTrue, "CompactionRunSucceeded", "")
 		} else if compactionErrorCount == 0 {
 			c.compactionRunsShutdown.Inc()
+			c.conditions.Set(conditionCompacting, conditions.StatusFalse, "CompactionRunInterrupted", "")
 		} else {
 			c.compactionRunsErred.Inc()
+			c.conditions.Set(conditionCompacting, conditions.StatusFalse, "CompactionRunFailed", "")
+			c.conditions.Set(conditionSynced, conditions.StatusFalse, "CompactionRunFailed", fmt.Sprintf("%d errors in the last compaction run", compactionErrorCount))
 		}
 
 		// Reset progress metrics once done.
@@ -558,6 +714,9 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 		c.compactionRunFailedTenants.Set(0)
 	}()
 
+	// Mark the expired sandbox tenants for deletion before discovering the users, so that they're skipped.
+	c.markExpiredSandboxTenantsForDeletion(ctx, time.Now())
+
 	level.Info(c.logger).Log("msg", "discovering users from bucket")
 	users, err := c.discoverUsersWithRetries(ctx)
 	if err != nil {
@@ -580,6 +739,7 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 
 	// Keep track of users owned by this shard, so that we can delete the local files for all other users.
 	ownedUsers := map[string]struct{}{}
+	skippedTenants := 0
 	for _, userID := range users {
 		// Ensure the context has not been canceled (ie. compactor shutdown has been triggered).
 		if ctx.Err() != nil {
@@ -610,6 +770,21 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 			continue
 		}
 
+		var skipMark *TenantCompactionSkipMark
+		if c.compactorCfg.TenantSkipFailuresThreshold > 0 {
+			if skipMark, err = ReadTenantCompactionSkipMark(ctx, c.bucketClient, userID); err != nil {
+				level.Warn(c.logger).Log("msg", "unable to check if user compaction is skipped", "user", userID, "err", err)
+			} else if skipMark != nil && !skipMark.Expired(time.Now()) {
+				skippedTenants++
+				c.compactionRunSkippedTenants.Inc()
+				level.Info(c.logger).Log("msg", "skipping user because its compaction repeatedly failed", "user", userID, "failures", skipMark.Failures, "until", time.Unix(skipMark.ExpirationTime, 0).UTC(), "reason", skipMark.Reason)
+				continue
+			} else if skipMark == nil && c.tenantCompactionFailures[userID] >= c.compactorCfg.TenantSkipFailuresThreshold {
+				// The skip mark has been manually deleted, so we start counting the failures again.
+				delete(c.tenantCompactionFailures, userID)
+			}
+		}
+
 		level.Info(c.logger).Log("msg", "starting compaction of user blocks", "user", userID)
 
 		if err = c.compactUserWithRetries(ctx, userID); err != nil {
@@ -622,14 +797,36 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 				c.compactionRunFailedTenants.Inc()
 				compactionErrorCount++
 				level.Error(c.logger).Log("msg", "failed to compact user blocks", "user", userID, "err", err)
+
+				if c.compactorCfg.TenantSkipFailuresThreshold > 0 {
+					c.recordTenantCompactionFailure(ctx, userID, skipMark, err, util_log.WithUserID(userID, c.logger))
+				}
 			}
 			continue
 		}
 
+		if c.compactorCfg.TenantSkipFailuresThreshold > 0 {
+			c.recordTenantCompactionSuccess(ctx, userID, skipMark, util_log.WithUserID(userID, c.logger))
+		}
+
 		c.compactionRunSucceededTenants.Inc()
 		level.Info(c.logger).Log("msg", "successfully compacted user blocks", "user", userID)
 	}
 
+	c.tenantsCompactionSkipped.Set(float64(skippedTenants))
+
+	// Forget the failures of the tenants not owned anymore.
+	for userID := range c.tenantCompactionFailures {
+		if _, owned := ownedUsers[userID]; !owned {
+			delete(c.tenantCompactionFailures, userID)
+		}
+	}
+	for userID := range c.blockQuarantines {
+		if _, owned := ownedUsers[userID]; !owned {
+			delete(c.blockQuarantines, userID)
+		}
+	}
+
 	// Delete local files for unowned tenants, if there are any. This cleans up
 	// leftover local files for tenants that belong to different compactors now,
 	// or have been deleted completely.
@@ -737,20 +934,30 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "failed to create syncer")
 	}
 
+	planner := c.blocksPlanner
+	if _, ok := planner.(*SplitAndMergePlanner); ok {
+		// The split-and-merge planner checks the blocks against the largest compaction range,
+		// which can be overridden on a per-tenant basis.
+		planner = NewSplitAndMergePlanner(blockRangesForUser(c.compactorCfg, c.cfgProvider, userID))
+	}
+
 	compactor, err := NewBucketCompactor(
 		userLogger,
 		syncer,
 		c.blocksGrouperFactory(ctx, c.compactorCfg, c.cfgProvider, userID, userLogger, reg),
-		c.blocksPlanner,
+		planner,
 		c.blocksCompactor,
 		path.Join(c.compactorCfg.DataDir, "compact"),
 		userBucket,
 		c.compactorCfg.CompactionConcurrency,
 		true, // Skip blocks without of order chunks, and mark them for no-compaction.
+		c.compactorCfg.BlockReplacementMarksEnabled,
+		c.compactorCfg.UploadIndexHeaders,
 		c.shardingStrategy.ownJob,
 		c.jobsOrder,
 		c.compactorCfg.CompactionWaitPeriod,
 		c.compactorCfg.BlockSyncConcurrency,
+		c.tenantBlockQuarantine(userID),
 		c.bucketCompactorMetrics,
 	)
 	if err != nil {
@@ -761,6 +968,18 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "compaction")
 	}
 
+	if c.compactorCfg.RetentionPoliciesEnabled {
+		if err := c.applyRetentionPolicies(ctx, userID, userBucket, fetcher, userLogger); err != nil {
+			return errors.Wrap(err, "retention policies")
+		}
+	}
+
+	if c.compactorCfg.DownsamplingEnabled {
+		if err := c.downsampleBlocks(ctx, userID, userBucket, fetcher, userLogger); err != nil {
+			return errors.Wrap(err, "downsampling")
+		}
+	}
+
 	return nil
 }
 
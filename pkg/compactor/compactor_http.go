@@ -7,15 +7,29 @@ package compactor
 
 import (
 	_ "embed" // Used to embed html template
+	"fmt"
 	"html/template"
 	"net/http"
+	"time"
 
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/services"
 
+	"github.com/grafana/mimir/pkg/util/conditions"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
+const (
+	conditionRingHealthy        = "RingHealthy"
+	conditionSynced             = "Synced"
+	conditionCompacting         = "Compacting"
+	conditionLaggingBucketIndex = "LaggingBucketIndex"
+
+	// The bucket index is considered lagging when no blocks cleanup run, updating it,
+	// successfully completed in the last laggingBucketIndexCleanupRuns cleanup intervals.
+	laggingBucketIndexCleanupRuns = 3
+)
+
 var (
 	//go:embed status.gohtml
 	statusPageHTML     string
@@ -45,3 +59,31 @@ func (c *MultitenantCompactor) RingHandler(w http.ResponseWriter, req *http.Requ
 
 	c.ring.ServeHTTP(w, req)
 }
+
+// ReadyDetailHandler returns the status conditions of the compactor, providing more detail than the
+// binary readiness: whether the instance is ACTIVE in the ring, whether the last compaction run succeeded,
+// whether a compaction run is in progress, and whether the bucket indexes are updated.
+func (c *MultitenantCompactor) ReadyDetailHandler(w http.ResponseWriter, _ *http.Request) {
+	// The ring and the blocks cleaner can't be read before the compactor is in the Running state.
+	if c.State() == services.Running {
+		c.conditions.UpdateRingHealthy(conditionRingHealthy, c.ring, c.ringLifecycler.GetInstanceID())
+		c.updateLaggingBucketIndexCondition(time.Now())
+	}
+
+	conditions.WriteResponse(w, c.conditions.List())
+}
+
+func (c *MultitenantCompactor) updateLaggingBucketIndexCondition(now time.Time) {
+	lastRun := c.blocksCleaner.LastSuccessfulRun()
+	maxAge := laggingBucketIndexCleanupRuns * c.compactorCfg.CleanupInterval
+
+	switch {
+	case lastRun.IsZero():
+		c.conditions.Set(conditionLaggingBucketIndex, conditions.StatusUnknown, "NoCleanupRunCompleted", "")
+	case now.Sub(lastRun) > maxAge:
+		c.conditions.Set(conditionLaggingBucketIndex, conditions.StatusTrue, "CleanupRunsNotCompleted",
+			fmt.Sprintf("the last successful blocks cleanup run completed at %s, more than %s ago", lastRun.UTC().Format(time.RFC3339), maxAge))
+	default:
+		c.conditions.Set(conditionLaggingBucketIndex, conditions.StatusFalse, "CleanupRunCompleted", "")
+	}
+}
@@ -7,7 +7,6 @@ package compactor
 
 import (
 	"flag"
-	"fmt"
 	"time"
 
 	"github.com/go-kit/log"
@@ -55,7 +54,7 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 }
 
 func (cfg *RingConfig) ToBasicLifecyclerConfig(logger log.Logger) (ring.BasicLifecyclerConfig, error) {
-	instanceAddr, err := ring.GetInstanceAddr(cfg.Common.InstanceAddr, cfg.Common.InstanceInterfaceNames, logger)
+	instanceAddr, err := util.GetInstanceAddr(cfg.Common.InstanceAddr, cfg.Common.InstanceInterfaceNames, cfg.Common.InstanceEnableIPv6)
 	if err != nil {
 		return ring.BasicLifecyclerConfig{}, err
 	}
@@ -64,7 +63,7 @@ func (cfg *RingConfig) ToBasicLifecyclerConfig(logger log.Logger) (ring.BasicLif
 
 	return ring.BasicLifecyclerConfig{
 		ID:                              cfg.Common.InstanceID,
-		Addr:                            fmt.Sprintf("%s:%d", instanceAddr, instancePort),
+		Addr:                            util.JoinHostPort(instanceAddr, instancePort),
 		HeartbeatPeriod:                 cfg.Common.HeartbeatPeriod,
 		HeartbeatTimeout:                cfg.Common.HeartbeatTimeout,
 		TokensObservePeriod:             cfg.ObservePeriod,
@@ -12,6 +12,8 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
@@ -50,6 +52,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 	"github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+	"github.com/grafana/mimir/pkg/util/conditions"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -134,6 +137,21 @@ func TestConfig_Validate(t *testing.T) {
 			setup:    func(cfg *Config) { cfg.SymbolsFlushersConcurrency = 0 },
 			expected: errInvalidSymbolFlushersConcurrency.Error(),
 		},
+		"should fail on tenant-skip-backoff greater than tenant-skip-max-backoff": {
+			setup: func(cfg *Config) {
+				cfg.TenantSkipFailuresThreshold = 3
+				cfg.TenantSkipBackoff = 2 * time.Hour
+				cfg.TenantSkipMaxBackoff = time.Hour
+			},
+			expected: errInvalidTenantSkipBackoff.Error(),
+		},
+		"should fail on no blocks verified per tenant when the block verification is enabled": {
+			setup: func(cfg *Config) {
+				cfg.BlockVerificationInterval = time.Hour
+				cfg.BlockVerificationBlocksPerTenant = 0
+			},
+			expected: errInvalidBlockVerificationBlocks.Error(),
+		},
 	}
 
 	for testName, testData := range tests {
@@ -151,6 +169,63 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestMultitenantCompactor_ReadyDetailHandler(t *testing.T) {
+	t.Parallel()
+
+	bucketClient := &bucket.ClientMock{}
+	bucketClient.MockIter("", []string{}, nil)
+	cfg := prepareConfig(t)
+	c, _, _, _, _ := prepare(t, cfg, bucketClient)
+
+	getConditions := func() map[string]conditions.Condition {
+		w := httptest.NewRecorder()
+		c.ReadyDetailHandler(w, httptest.NewRequest("GET", "/compactor/ready-detail", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Conditions []conditions.Condition `json:"conditions"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+		byType := map[string]conditions.Condition{}
+		for _, cond := range res.Conditions {
+			byType[cond.Type] = cond
+		}
+		return byType
+	}
+
+	// Before the compactor is running, nothing has been observed yet.
+	for _, cond := range getConditions() {
+		assert.Equal(t, conditions.StatusUnknown, cond.Status)
+	}
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+	})
+
+	// Wait until a compaction run and a cleanup run have completed.
+	test.Poll(t, time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+	})
+	test.Poll(t, time.Second, false, func() interface{} {
+		return c.blocksCleaner.LastSuccessfulRun().IsZero()
+	})
+
+	actual := getConditions()
+	assert.Equal(t, conditions.StatusTrue, actual[conditionRingHealthy].Status)
+	assert.Equal(t, conditions.StatusTrue, actual[conditionSynced].Status)
+	assert.Equal(t, conditions.StatusFalse, actual[conditionCompacting].Status)
+	assert.Equal(t, conditions.StatusFalse, actual[conditionLaggingBucketIndex].Status)
+
+	// The bucket index is lagging once no cleanup run completed for too long.
+	c.updateLaggingBucketIndexCondition(time.Now().Add(laggingBucketIndexCleanupRuns*cfg.CleanupInterval + time.Minute))
+
+	cond, _ := c.conditions.Get(conditionLaggingBucketIndex)
+	assert.Equal(t, conditions.StatusTrue, cond.Status)
+	assert.Equal(t, "CleanupRunsNotCompleted", cond.Reason)
+}
+
 func TestMultitenantCompactor_ShouldDoNothingOnNoUserBlocks(t *testing.T) {
 	t.Parallel()
 
@@ -255,6 +330,7 @@ func TestMultitenantCompactor_ShouldDoNothingOnNoUserBlocks(t *testing.T) {
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 
 		# TYPE cortex_compactor_block_cleanup_started_total counter
 		# HELP cortex_compactor_block_cleanup_started_total Total number of blocks cleanup runs started.
@@ -399,6 +475,7 @@ func TestMultitenantCompactor_ShouldRetryCompactionOnFailureWhileDiscoveringUser
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 
 		# TYPE cortex_compactor_block_cleanup_started_total counter
 		# HELP cortex_compactor_block_cleanup_started_total Total number of blocks cleanup runs started.
@@ -665,6 +742,7 @@ func TestMultitenantCompactor_ShouldIterateOverUsersAndRunCompaction(t *testing.
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 
 		# TYPE cortex_compactor_block_cleanup_started_total counter
 		# HELP cortex_compactor_block_cleanup_started_total Total number of blocks cleanup runs started.
@@ -851,6 +929,7 @@ func TestMultitenantCompactor_ShouldNotCompactBlocksMarkedForDeletion(t *testing
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 
 		# TYPE cortex_compactor_block_cleanup_started_total counter
 		# HELP cortex_compactor_block_cleanup_started_total Total number of blocks cleanup runs started.
@@ -1009,6 +1088,7 @@ func TestMultitenantCompactor_ShouldNotCompactBlocksForUsersMarkedForDeletion(t
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 
 		# TYPE cortex_compactor_block_cleanup_started_total counter
 		# HELP cortex_compactor_block_cleanup_started_total Total number of blocks cleanup runs started.
@@ -1141,6 +1221,7 @@ func TestMultitenantCompactor_ShouldCompactAllUsersOnShardingEnabledButOnlyOneIn
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 	`),
 		"cortex_compactor_runs_started_total",
 		"cortex_compactor_runs_completed_total",
@@ -1353,6 +1434,7 @@ func TestMultitenantCompactor_ShouldSkipCompactionForJobsNoMoreOwnedAfterPlannin
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 	`),
 		"cortex_compactor_runs_started_total",
 		"cortex_compactor_runs_completed_total",
@@ -1463,6 +1545,7 @@ func TestMultitenantCompactor_ShouldSkipCompactionForJobsWithFirstLevelCompactio
 		cortex_compactor_blocks_marked_for_deletion_total{reason="compaction"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 0
 		cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+		cortex_compactor_blocks_marked_for_deletion_total{reason="retention-policy"} 0
 	`),
 		"cortex_compactor_runs_started_total",
 		"cortex_compactor_runs_completed_total",
@@ -2158,6 +2241,73 @@ func TestMultitenantCompactor_OutOfOrderCompaction(t *testing.T) {
 		# HELP cortex_compactor_blocks_marked_for_no_compaction_total Total number of blocks that were marked for no-compaction.
 		# TYPE cortex_compactor_blocks_marked_for_no_compaction_total counter
 		cortex_compactor_blocks_marked_for_no_compaction_total{reason="block-index-out-of-order-chunk"} 1
+		cortex_compactor_blocks_marked_for_no_compaction_total{reason="repeated-compaction-failures"} 0
+	`),
+		"cortex_compactor_blocks_marked_for_no_compaction_total",
+	))
+}
+
+func TestMultitenantCompactor_QuarantineBlocksWithRepeatedFailures(t *testing.T) {
+	specs := []*testutil.BlockSeriesSpec{
+		{
+			Labels: labels.FromStrings("case", "corrupted"),
+			Chunks: []chunks.Meta{
+				tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(0, 0, nil, nil), newSample(2*time.Hour.Milliseconds()-1, 0, nil, nil)}),
+			},
+		},
+	}
+
+	const user = "user"
+
+	storageDir := t.TempDir()
+	// We need two blocks to start compaction.
+	meta1, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), specs)
+	require.NoError(t, err)
+	meta2, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), specs)
+	require.NoError(t, err)
+
+	// Corrupt the index of the first block, so that its verification fails.
+	require.NoError(t, os.WriteFile(filepath.Join(storageDir, user, meta1.ULID.String(), block.IndexFilename), []byte("corrupted"), 0600))
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	cfg := prepareConfig(t)
+	cfg.CompactionRetries = 3
+	cfg.BlockQuarantineFailuresThreshold = 2
+	c, _, tsdbPlanner, logs, registry := prepare(t, cfg, bkt)
+
+	tsdbPlanner.On("Plan", mock.Anything, mock.Anything).Return([]*metadata.Meta{meta1, meta2}, nil)
+
+	// Start the compactor
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+
+	// Wait until a compaction run has been completed. The first attempt fails, while the retry
+	// quarantines the corrupted block and proceeds with the remaining ones.
+	test.Poll(t, 10*time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+	})
+
+	// Stop the compactor.
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+
+	assert.Contains(t, logs.String(), fmt.Sprintf(`msg="marked block which repeatedly failed compaction for no-compaction" block=%s failures=2`, meta1.ULID.String()))
+
+	m := &metadata.NoCompactMark{}
+	require.NoError(t, metadata.ReadMarker(context.Background(), log.NewNopLogger(), objstore.WithNoopInstr(bkt), path.Join(user, meta1.ULID.String()), m))
+	require.Equal(t, meta1.ULID, m.ID)
+	require.Equal(t, metadata.NoCompactReason(metadata.RepeatedCompactionFailuresNoCompactReason), m.Reason)
+
+	// The healthy block has not been marked.
+	exists, err := bkt.Exists(context.Background(), path.Join(user, meta2.ULID.String(), metadata.NoCompactMarkFilename))
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	assert.NoError(t, prom_testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_compactor_blocks_marked_for_no_compaction_total Total number of blocks that were marked for no-compaction.
+		# TYPE cortex_compactor_blocks_marked_for_no_compaction_total counter
+		cortex_compactor_blocks_marked_for_no_compaction_total{reason="block-index-out-of-order-chunk"} 0
+		cortex_compactor_blocks_marked_for_no_compaction_total{reason="repeated-compaction-failures"} 1
 	`),
 		"cortex_compactor_blocks_marked_for_no_compaction_total",
 	))
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/downsample"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+// downsampleBlocks downsamples the raw blocks of the user which have been compacted up to the largest
+// compaction range, and haven't been downsampled yet to all the downsampling resolutions. A raw block
+// has been downsampled to a resolution if there's a block at that resolution with the same sources.
+func (c *MultitenantCompactor) downsampleBlocks(ctx context.Context, userID string, userBucket objstore.Bucket, fetcher *block.MetaFetcher, userLogger log.Logger) error {
+	metas, _, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetch blocks metadata")
+	}
+
+	downsampled := map[string]struct{}{}
+	for _, meta := range metas {
+		if res := meta.Thanos.Downsample.Resolution; res > 0 {
+			downsampled[downsampledBlockKey(meta, res)] = struct{}{}
+		}
+	}
+
+	// Sort the blocks to process them in a deterministic order.
+	ids := make([]ulid.ULID, 0, len(metas))
+	for id := range metas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Compare(ids[j]) < 0
+	})
+
+	ranges := blockRangesForUser(c.compactorCfg, c.cfgProvider, userID)
+	largestRange := ranges[len(ranges)-1]
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		meta := metas[id]
+		if meta.Thanos.Downsample.Resolution > 0 || meta.MaxTime-meta.MinTime < largestRange {
+			continue
+		}
+
+		var missing []int64
+		for _, res := range downsample.Resolutions {
+			if _, ok := downsampled[downsampledBlockKey(meta, res)]; !ok {
+				missing = append(missing, res)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		// Each block is downsampled by a single compactor.
+		if ok, err := c.shardingStrategy.ownJob(NewJob(userID, "downsample-"+id.String(), labels.FromMap(meta.Thanos.Labels), meta.Thanos.Downsample.Resolution, false, 0, id.String())); err != nil {
+			return errors.Wrapf(err, "check ownership of block %s", id)
+		} else if !ok {
+			continue
+		}
+
+		if err := c.downsampleBlock(ctx, userBucket, meta, missing, log.With(userLogger, "block", id)); err != nil {
+			return errors.Wrapf(err, "downsample block %s", id)
+		}
+	}
+
+	return nil
+}
+
+// downsampleBlock downloads the raw block and uploads its downsampled blocks at the input resolutions.
+func (c *MultitenantCompactor) downsampleBlock(ctx context.Context, userBucket objstore.Bucket, meta *metadata.Meta, resolutions []int64, logger log.Logger) (returnErr error) {
+	tmpDir := filepath.Join(c.compactorCfg.DataDir, "downsample", meta.ULID.String())
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return errors.Wrap(err, "clean up temporary directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove temporary directory", "dir", tmpDir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(tmpDir, meta.ULID.String())
+	if err := block.Download(ctx, logger, userBucket, meta.ULID, bdir); err != nil {
+		return errors.Wrap(err, "download block")
+	}
+
+	b, err := tsdb.OpenBlock(logger, bdir, nil)
+	if err != nil {
+		return errors.Wrap(err, "open block")
+	}
+	defer func() {
+		if err := b.Close(); err != nil && returnErr == nil {
+			returnErr = errors.Wrap(err, "close block")
+		}
+	}()
+
+	level.Info(logger).Log("msg", "downsampling block", "resolutions", formatResolutions(resolutions))
+
+	newMetas, err := downsample.Downsample(logger, meta, b, tmpDir, resolutions)
+	if err != nil {
+		return errors.Wrap(err, "downsample")
+	}
+
+	for _, newMeta := range newMetas {
+		newDir := filepath.Join(tmpDir, newMeta.ULID.String())
+
+		// Ensure the downsampled block is valid.
+		if err := block.VerifyBlock(logger, newDir, newMeta.MinTime, newMeta.MaxTime, false); err != nil {
+			return errors.Wrapf(err, "invalid downsampled block %s", newMeta.ULID)
+		}
+
+		if err := block.Upload(ctx, logger, userBucket, newDir, nil); err != nil {
+			return errors.Wrapf(err, "upload of %s failed", newMeta.ULID)
+		}
+
+		level.Info(logger).Log("msg", "uploaded downsampled block", "new_block", newMeta.ULID, "resolution", newMeta.Thanos.Downsample.Resolution)
+		c.blocksDownsampled.Inc()
+	}
+
+	return nil
+}
+
+// downsampledBlockKey returns the key identifying the block at the input resolution with the same sources
+// of the input block.
+func downsampledBlockKey(meta *metadata.Meta, resolution int64) string {
+	sources := make([]string, 0, len(meta.Compaction.Sources))
+	for _, id := range meta.Compaction.Sources {
+		sources = append(sources, id.String())
+	}
+	sort.Strings(sources)
+
+	return defaultGroupKey(resolution, labels.FromMap(meta.Thanos.Labels)) + "/" + strings.Join(sources, ",")
+}
+
+func formatResolutions(resolutions []int64) string {
+	formatted := make([]string, 0, len(resolutions))
+	for _, res := range resolutions {
+		formatted = append(formatted, (time.Duration(res) * time.Millisecond).String())
+	}
+	return strings.Join(formatted, ",")
+}
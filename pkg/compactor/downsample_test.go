@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/downsample"
+	"github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestMultitenantCompactor_ShouldDownsampleBlocks(t *testing.T) {
+	const user = "user"
+
+	ctx := context.Background()
+	storageDir := t.TempDir()
+
+	// A block spanning the whole largest compaction range, which should be downsampled.
+	fullChunk := tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(0, 1, nil, nil), newSample(2*time.Hour.Milliseconds()-1, 2, nil, nil)})
+	fullMeta, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), []*testutil.BlockSeriesSpec{
+		{Labels: labels.FromStrings("series", "1"), Chunks: []chunks.Meta{fullChunk}},
+	})
+	require.NoError(t, err)
+
+	// A block smaller than the largest compaction range, which shouldn't be downsampled.
+	partialChunk := tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(2*time.Hour.Milliseconds(), 1, nil, nil), newSample(2*time.Hour.Milliseconds()+10, 2, nil, nil)})
+	partialMeta, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), []*testutil.BlockSeriesSpec{
+		{Labels: labels.FromStrings("series", "1"), Chunks: []chunks.Meta{partialChunk}},
+	})
+	require.NoError(t, err)
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	cfg := prepareConfig(t)
+	cfg.DataDir = t.TempDir()
+	cfg.BlockRanges = mimir_tsdb.DurationList{2 * time.Hour}
+	cfg.DownsamplingEnabled = true
+
+	storageCfg := mimir_tsdb.BlocksStorageConfig{}
+	flagext.DefaultValues(&storageCfg)
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	bucketClientFactory := func(ctx context.Context) (objstore.Bucket, error) {
+		return bkt, nil
+	}
+
+	runCompactor := func() *MultitenantCompactor {
+		c, err := newMultitenantCompactor(cfg, storageCfg, overrides, log.NewNopLogger(), prometheus.NewRegistry(), bucketClientFactory, splitAndMergeGrouperFactory, splitAndMergeCompactorFactory)
+		require.NoError(t, err)
+
+		require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+
+		// Wait until a compaction run has been completed.
+		test.Poll(t, 10*time.Second, 1.0, func() interface{} {
+			return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+		})
+
+		require.NoError(t, services.StopAndAwaitTerminated(ctx, c))
+		return c
+	}
+
+	c := runCompactor()
+	assert.Equal(t, 2.0, prom_testutil.ToFloat64(c.blocksDownsampled))
+
+	// Find the downsampled blocks.
+	resolutions := map[int64]int{}
+	require.NoError(t, bkt.Iter(ctx, user+"/", func(name string) error {
+		id, ok := block.IsBlockDir(name)
+		if !ok || id == fullMeta.ULID || id == partialMeta.ULID {
+			return nil
+		}
+
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), bucket.NewUserBucketClient(user, bkt, nil), id)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, fullMeta.MinTime, meta.MinTime)
+		assert.Equal(t, fullMeta.MaxTime, meta.MaxTime)
+		assert.Equal(t, fullMeta.Compaction.Sources, meta.Compaction.Sources)
+		assert.Equal(t, uint64(6), meta.Stats.NumSeries)
+		resolutions[meta.Thanos.Downsample.Resolution]++
+		return nil
+	}))
+
+	assert.Equal(t, map[int64]int{downsample.ResLevel1: 1, downsample.ResLevel2: 1}, resolutions)
+
+	// The blocks which have already been downsampled shouldn't be downsampled again.
+	c = runCompactor()
+	assert.Equal(t, 0.0, prom_testutil.ToFloat64(c.blocksDownsampled))
+}
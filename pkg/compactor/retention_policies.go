@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// retentionPolicyHintPrefix is the prefix of the compaction hint added to the meta.json of the blocks
+// rewritten by a retention policy. The hint is followed by the retention policy hash.
+const retentionPolicyHintPrefix = "retention-policy-"
+
+func retentionPolicyHint(policy mimir_tsdb.RetentionPolicy) string {
+	return retentionPolicyHintPrefix + policy.Hash()
+}
+
+// applyRetentionPolicies rewrites the blocks of the user which are older than the retention of the user's
+// retention policies, removing the series matching the retention policies selectors.
+func (c *MultitenantCompactor) applyRetentionPolicies(ctx context.Context, userID string, userBucket objstore.Bucket, fetcher *block.MetaFetcher, userLogger log.Logger) error {
+	policies, err := mimir_tsdb.ReadRetentionPolicies(ctx, c.bucketClient, userID)
+	if err != nil {
+		return err
+	}
+
+	if len(policies.Policies) == 0 {
+		c.setRetentionPoliciesCheckedBlocks(userID, nil)
+		return nil
+	}
+
+	metas, _, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetch blocks metadata")
+	}
+
+	// Sort the blocks to process them in a deterministic order.
+	ids := make([]ulid.ULID, 0, len(metas))
+	for id := range metas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Compare(ids[j]) < 0
+	})
+
+	now := time.Now()
+	prevChecked := c.getRetentionPoliciesCheckedBlocks(userID)
+	checked := map[string]struct{}{}
+	defer func() {
+		c.setRetentionPoliciesCheckedBlocks(userID, checked)
+	}()
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		meta := metas[id]
+
+		// Find the policies which have to be applied to the block but haven't been applied yet.
+		var pending []mimir_tsdb.RetentionPolicy
+		for _, policy := range policies.Policies {
+			if meta.MaxTime > util.TimeToMillis(policy.Cutoff(now)) {
+				continue
+			}
+
+			hint := retentionPolicyHint(policy)
+			if util.StringsContain(meta.Compaction.Hints, hint) {
+				continue
+			}
+
+			key := id.String() + "/" + hint
+			if _, ok := prevChecked[key]; ok {
+				checked[key] = struct{}{}
+				continue
+			}
+
+			pending = append(pending, policy)
+		}
+
+		if len(pending) == 0 {
+			continue
+		}
+
+		// Each block is rewritten by a single compactor.
+		if ok, err := c.shardingStrategy.ownJob(NewJob(userID, "retention-policies-"+id.String(), labels.FromMap(meta.Thanos.Labels), meta.Thanos.Downsample.Resolution, false, 0, id.String())); err != nil {
+			return errors.Wrapf(err, "check ownership of block %s", id)
+		} else if !ok {
+			continue
+		}
+
+		// The policies found to not match any series of the block before the compactor restarted are
+		// tracked by the block's retention policies checked mark.
+		markedHints := c.readRetentionPoliciesCheckedMark(ctx, userBucket, id, userLogger)
+		unmarked := pending[:0]
+		for _, policy := range pending {
+			hint := retentionPolicyHint(policy)
+			if util.StringsContain(markedHints, hint) {
+				checked[id.String()+"/"+hint] = struct{}{}
+				continue
+			}
+			unmarked = append(unmarked, policy)
+		}
+		pending = unmarked
+
+		if len(pending) == 0 {
+			continue
+		}
+
+		rewritten, err := c.applyRetentionPoliciesToBlock(ctx, userBucket, meta, pending, log.With(userLogger, "block", id))
+		if err != nil {
+			return errors.Wrapf(err, "apply retention policies to block %s", id)
+		}
+
+		if !rewritten {
+			// The block has no series matching the policies. We keep track of it to not download it
+			// again at the next compaction runs, including after a restart.
+			for _, policy := range pending {
+				hint := retentionPolicyHint(policy)
+				markedHints = append(markedHints, hint)
+				checked[id.String()+"/"+hint] = struct{}{}
+			}
+
+			if err := writeRetentionPoliciesCheckedMark(ctx, userBucket, id, markedHints); err != nil {
+				return errors.Wrapf(err, "write retention policies checked mark of block %s", id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// readRetentionPoliciesCheckedMark returns the hints of the retention policies found to not match any series
+// of the block, or nil if the block has no retention policies checked mark.
+func (c *MultitenantCompactor) readRetentionPoliciesCheckedMark(ctx context.Context, userBucket objstore.Bucket, id ulid.ULID, userLogger log.Logger) []string {
+	mark := metadata.RetentionPoliciesCheckedMark{}
+	if err := metadata.ReadMarker(ctx, userLogger, objstore.WithNoopInstr(userBucket), id.String(), &mark); err != nil {
+		if !errors.Is(err, metadata.ErrorMarkerNotFound) {
+			// A corrupted or partially uploaded mark is overwritten once the block is checked again.
+			level.Warn(userLogger).Log("msg", "failed to read block retention policies checked mark", "block", id, "err", err)
+		}
+		return nil
+	}
+	return mark.Hints
+}
+
+// writeRetentionPoliciesCheckedMark uploads the retention policies checked mark of the block with the input hints.
+func writeRetentionPoliciesCheckedMark(ctx context.Context, userBucket objstore.Bucket, id ulid.ULID, hints []string) error {
+	data, err := json.Marshal(metadata.RetentionPoliciesCheckedMark{
+		ID:      id,
+		Version: metadata.RetentionPoliciesCheckedMarkVersion1,
+		Hints:   hints,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encode retention policies checked mark")
+	}
+
+	markFile := path.Join(id.String(), metadata.RetentionPoliciesCheckedMarkFilename)
+	return errors.Wrapf(userBucket.Upload(ctx, markFile, bytes.NewReader(data)), "upload file %s to bucket", markFile)
+}
+
+// applyRetentionPoliciesToBlock downloads the block and removes the series matching the selectors of the
+// input policies. If any series has been removed, the rewritten block is uploaded and the original block is
+// marked for deletion. Returns whether the block has been rewritten.
+func (c *MultitenantCompactor) applyRetentionPoliciesToBlock(ctx context.Context, userBucket objstore.Bucket, meta *metadata.Meta, policies []mimir_tsdb.RetentionPolicy, logger log.Logger) (_ bool, returnErr error) {
+	tmpDir := filepath.Join(c.compactorCfg.DataDir, "retention-policies", meta.ULID.String())
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return false, errors.Wrap(err, "clean up temporary directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove temporary directory", "dir", tmpDir, "err", err)
+		}
+	}()
+
+	bdir := filepath.Join(tmpDir, meta.ULID.String())
+	if err := block.Download(ctx, logger, userBucket, meta.ULID, bdir); err != nil {
+		return false, errors.Wrap(err, "download block")
+	}
+
+	b, err := tsdb.OpenBlock(logger, bdir, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "open block")
+	}
+	defer func() {
+		if err := b.Close(); err != nil && returnErr == nil {
+			returnErr = errors.Wrap(err, "close block")
+		}
+	}()
+
+	hints := append([]string(nil), meta.Compaction.Hints...)
+	for _, policy := range policies {
+		matchers, err := policy.Matchers()
+		if err != nil {
+			return false, errors.Wrapf(err, "parse selector of retention policy %s", policy.Name)
+		}
+
+		if err := b.Delete(math.MinInt64, math.MaxInt64, matchers...); err != nil {
+			return false, errors.Wrapf(err, "delete series matching retention policy %s", policy.Name)
+		}
+
+		hints = append(hints, retentionPolicyHint(policy))
+	}
+
+	if b.Meta().Stats.NumTombstones == 0 {
+		level.Debug(logger).Log("msg", "block has no series matching the retention policies")
+		return false, nil
+	}
+
+	level.Info(logger).Log("msg", "rewriting block to apply retention policies", "tombstones", b.Meta().Stats.NumTombstones)
+
+	newID, err := c.blocksCompactor.Compact(tmpDir, []string{bdir}, []*tsdb.Block{b})
+	if err != nil {
+		return false, errors.Wrap(err, "rewrite block")
+	}
+
+	if newID != (ulid.ULID{}) {
+		newDir := filepath.Join(tmpDir, newID.String())
+
+		newMeta, err := metadata.ReadFromDir(newDir)
+		if err != nil {
+			return false, errors.Wrap(err, "read rewritten block meta")
+		}
+
+		newMeta.Thanos = metadata.Thanos{
+			Labels:       meta.Thanos.Labels,
+			Downsample:   meta.Thanos.Downsample,
+			Source:       metadata.CompactorSource,
+			SegmentFiles: block.GetSegmentFiles(newDir),
+		}
+		newMeta.Compaction.Hints = hints
+
+		if err := newMeta.WriteToDir(logger, newDir); err != nil {
+			return false, errors.Wrap(err, "write rewritten block meta")
+		}
+
+		// Remove the empty tombstones file written by the TSDB compactor.
+		if err := os.Remove(filepath.Join(newDir, "tombstones")); err != nil {
+			return false, errors.Wrap(err, "remove tombstones")
+		}
+
+		// Ensure the rewritten block is valid.
+		if err := block.VerifyBlock(logger, newDir, newMeta.MinTime, newMeta.MaxTime, false); err != nil {
+			return false, errors.Wrapf(err, "invalid rewritten block %s", newID)
+		}
+
+		if err := block.Upload(ctx, logger, userBucket, newDir, nil); err != nil {
+			return false, errors.Wrapf(err, "upload of %s failed", newID)
+		}
+
+		level.Info(logger).Log("msg", "uploaded block rewritten by retention policies", "new_block", newID)
+	} else {
+		level.Info(logger).Log("msg", "all series of the block match the retention policies, the block will be deleted")
+	}
+
+	// Spawn a new context so we always mark a block for deletion in full on shutdown.
+	delCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := block.MarkForDeletion(delCtx, logger, userBucket, meta.ULID, "source of block rewritten by retention policies", c.blocksMarkedForDeletionByRetentionPolicies); err != nil {
+		return false, errors.Wrap(err, "mark block for deletion")
+	}
+
+	c.blocksRewrittenByRetentionPolicies.Inc()
+	return true, nil
+}
+
+func (c *MultitenantCompactor) getRetentionPoliciesCheckedBlocks(userID string) map[string]struct{} {
+	c.retentionPoliciesCheckedMtx.Lock()
+	defer c.retentionPoliciesCheckedMtx.Unlock()
+
+	return c.retentionPoliciesChecked[userID]
+}
+
+func (c *MultitenantCompactor) setRetentionPoliciesCheckedBlocks(userID string, checked map[string]struct{}) {
+	c.retentionPoliciesCheckedMtx.Lock()
+	defer c.retentionPoliciesCheckedMtx.Unlock()
+
+	if len(checked) == 0 {
+		delete(c.retentionPoliciesChecked, userID)
+		return
+	}
+
+	c.retentionPoliciesChecked[userID] = checked
+}
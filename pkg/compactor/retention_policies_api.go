@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// RetentionPoliciesHandler handles the retention policies API. GET lists the tenant's retention policies,
+// POST creates or replaces a retention policy, and DELETE removes the retention policy with the given name.
+func (c *MultitenantCompactor) RetentionPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		c.listRetentionPolicies(w, r)
+	case http.MethodPost:
+		c.setRetentionPolicy(w, r)
+	case http.MethodDelete:
+		c.deleteRetentionPolicy(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *MultitenantCompactor) listRetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	policies, err := mimir_tsdb.ReadRetentionPolicies(ctx, c.bucketClient, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if policies.Policies == nil {
+		policies.Policies = []mimir_tsdb.RetentionPolicy{}
+	}
+
+	util.WriteJSONResponse(w, policies)
+}
+
+func (c *MultitenantCompactor) setRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	policy := mimir_tsdb.RetentionPolicy{}
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policies, err := mimir_tsdb.ReadRetentionPolicies(ctx, c.bucketClient, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	policies.Set(policy)
+
+	if err := mimir_tsdb.WriteRetentionPolicies(ctx, c.bucketClient, userID, c.cfgProvider, policies); err != nil {
+		level.Error(c.logger).Log("msg", "failed to write retention policies", "user", userID, "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(c.logger).Log("msg", "retention policy set", "user", userID, "name", policy.Name, "selector", policy.Selector, "retention", policy.Retention)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *MultitenantCompactor) deleteRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		http.Error(w, "the retention policy name is required", http.StatusBadRequest)
+		return
+	}
+
+	policies, err := mimir_tsdb.ReadRetentionPolicies(ctx, c.bucketClient, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !policies.Delete(name) {
+		http.Error(w, "retention policy not found", http.StatusNotFound)
+		return
+	}
+
+	if err := mimir_tsdb.WriteRetentionPolicies(ctx, c.bucketClient, userID, c.cfgProvider, policies); err != nil {
+		level.Error(c.logger).Log("msg", "failed to write retention policies", "user", userID, "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(c.logger).Log("msg", "retention policy deleted", "user", userID, "name", name)
+
+	w.WriteHeader(http.StatusOK)
+}
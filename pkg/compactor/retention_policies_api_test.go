@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/user"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+func TestRetentionPoliciesHandler(t *testing.T) {
+	const userID = "user"
+
+	bkt := objstore.NewInMemBucket()
+	cfg := prepareConfig(t)
+	c, _, _, _, _ := prepare(t, cfg, bkt)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	doRequest := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+		resp := httptest.NewRecorder()
+		c.RetentionPoliciesHandler(resp, req)
+		return resp
+	}
+
+	listPolicies := func() []mimir_tsdb.RetentionPolicy {
+		resp := doRequest(http.MethodGet, "/compactor/retention_policies", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		policies := mimir_tsdb.RetentionPolicies{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&policies))
+		return policies.Policies
+	}
+
+	t.Run("should fail if the tenant ID is missing", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		c.RetentionPoliciesHandler(resp, httptest.NewRequest(http.MethodGet, "/compactor/retention_policies", nil))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("should return no policies if none have been created", func(t *testing.T) {
+		assert.Empty(t, listPolicies())
+	})
+
+	t.Run("should reject an invalid policy", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/compactor/retention_policies", `{"name":"dev","selector":"{env=~\".*\"}","retention":"7d"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Empty(t, listPolicies())
+	})
+
+	t.Run("should create, update and delete policies", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/compactor/retention_policies", `{"name":"dev","selector":"{env=\"dev\"}","retention":"7d"}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		resp = doRequest(http.MethodPost, "/compactor/retention_policies", `{"name":"test","selector":"{env=\"test\"}","retention":"1d"}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		resp = doRequest(http.MethodPost, "/compactor/retention_policies", `{"name":"dev","selector":"{env=\"dev\"}","retention":"14d"}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, []mimir_tsdb.RetentionPolicy{
+			{Name: "dev", Selector: `{env="dev"}`, Retention: model.Duration(14 * 24 * time.Hour)},
+			{Name: "test", Selector: `{env="test"}`, Retention: model.Duration(24 * time.Hour)},
+		}, listPolicies())
+
+		resp = doRequest(http.MethodDelete, "/compactor/retention_policies?name=unknown", "")
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = doRequest(http.MethodDelete, "/compactor/retention_policies?name=dev", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, []mimir_tsdb.RetentionPolicy{
+			{Name: "test", Selector: `{env="test"}`, Retention: model.Duration(24 * time.Hour)},
+		}, listPolicies())
+	})
+}
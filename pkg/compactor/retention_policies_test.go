@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestMultitenantCompactor_ShouldApplyRetentionPolicies(t *testing.T) {
+	const user = "user"
+
+	ctx := context.Background()
+	chunk := tsdbutil.ChunkFromSamples([]tsdbutil.Sample{newSample(10, 10, nil, nil), newSample(20, 20, nil, nil)})
+
+	storageDir := t.TempDir()
+	matchingMeta, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), []*testutil.BlockSeriesSpec{
+		{Labels: labels.FromStrings("env", "dev", "series", "1"), Chunks: []chunks.Meta{chunk}},
+		{Labels: labels.FromStrings("env", "prod", "series", "2"), Chunks: []chunks.Meta{chunk}},
+	})
+	require.NoError(t, err)
+	notMatchingMeta, err := testutil.GenerateBlockFromSpec(user, filepath.Join(storageDir, user), []*testutil.BlockSeriesSpec{
+		{Labels: labels.FromStrings("env", "prod", "series", "3"), Chunks: []chunks.Meta{chunk}},
+	})
+	require.NoError(t, err)
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	require.NoError(t, mimir_tsdb.WriteRetentionPolicies(ctx, bkt, user, nil, &mimir_tsdb.RetentionPolicies{Policies: []mimir_tsdb.RetentionPolicy{
+		{Name: "dev", Selector: `{env="dev"}`, Retention: model.Duration(time.Hour)},
+	}}))
+
+	cfg := prepareConfig(t)
+	cfg.DataDir = t.TempDir()
+	cfg.RetentionPoliciesEnabled = true
+
+	storageCfg := mimir_tsdb.BlocksStorageConfig{}
+	flagext.DefaultValues(&storageCfg)
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	bucketClientFactory := func(ctx context.Context) (objstore.Bucket, error) {
+		return bkt, nil
+	}
+
+	registry := prometheus.NewRegistry()
+	c, err := newMultitenantCompactor(cfg, storageCfg, overrides, log.NewNopLogger(), registry, bucketClientFactory, splitAndMergeGrouperFactory, splitAndMergeCompactorFactory)
+	require.NoError(t, err)
+
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+
+	// Wait until a compaction run has been completed.
+	test.Poll(t, 10*time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+	})
+
+	require.NoError(t, services.StopAndAwaitTerminated(ctx, c))
+
+	// The block containing series matching the policy should have been rewritten.
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.blocksRewrittenByRetentionPolicies))
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.blocksMarkedForDeletionByRetentionPolicies))
+
+	exists, err := bkt.Exists(ctx, path.Join(user, matchingMeta.ULID.String(), metadata.DeletionMarkFilename))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = bkt.Exists(ctx, path.Join(user, notMatchingMeta.ULID.String(), metadata.DeletionMarkFilename))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Find the rewritten block.
+	var rewrittenMeta *metadata.Meta
+	require.NoError(t, bkt.Iter(ctx, user+"/", func(name string) error {
+		id, ok := block.IsBlockDir(name)
+		if !ok || id == matchingMeta.ULID || id == notMatchingMeta.ULID {
+			return nil
+		}
+
+		meta, err := block.DownloadMeta(ctx, log.NewNopLogger(), bucket.NewUserBucketClient(user, bkt, nil), id)
+		rewrittenMeta = &meta
+		return err
+	}))
+
+	require.NotNil(t, rewrittenMeta)
+	require.Len(t, rewrittenMeta.Compaction.Parents, 1)
+	assert.Equal(t, matchingMeta.ULID, rewrittenMeta.Compaction.Parents[0].ULID)
+	assert.Equal(t, uint64(1), rewrittenMeta.Stats.NumSeries)
+	assert.Contains(t, rewrittenMeta.Compaction.Hints, retentionPolicyHint(mimir_tsdb.RetentionPolicy{Selector: `{env="dev"}`, Retention: model.Duration(time.Hour)}))
+
+	// The block not containing series matching the policy should have been checked only once.
+	assert.Len(t, c.getRetentionPoliciesCheckedBlocks(user), 1)
+
+	// The policies checked should have been persisted in the block not containing series matching the policy.
+	userBkt := bucket.NewUserBucketClient(user, bkt, nil)
+	mark := metadata.RetentionPoliciesCheckedMark{}
+	require.NoError(t, metadata.ReadMarker(ctx, log.NewNopLogger(), objstore.WithNoopInstr(userBkt), notMatchingMeta.ULID.String(), &mark))
+	assert.Equal(t, []string{retentionPolicyHint(mimir_tsdb.RetentionPolicy{Selector: `{env="dev"}`, Retention: model.Duration(time.Hour)})}, mark.Hints)
+
+	// Remove the index of the block not containing series matching the policy, so that the block
+	// can't be downloaded anymore, and restart the compactor.
+	require.NoError(t, userBkt.Delete(ctx, path.Join(notMatchingMeta.ULID.String(), block.IndexFilename)))
+
+	c, err = newMultitenantCompactor(cfg, storageCfg, overrides, log.NewNopLogger(), prometheus.NewRegistry(), bucketClientFactory, splitAndMergeGrouperFactory, splitAndMergeCompactorFactory)
+	require.NoError(t, err)
+
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	test.Poll(t, 10*time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.compactionRunsCompleted)
+	})
+	require.NoError(t, services.StopAndAwaitTerminated(ctx, c))
+
+	// The block shouldn't have been downloaded again after the restart.
+	assert.Equal(t, 0.0, prom_testutil.ToFloat64(c.compactionRunFailedTenants))
+	assert.Equal(t, 0.0, prom_testutil.ToFloat64(c.blocksRewrittenByRetentionPolicies))
+	assert.Len(t, c.getRetentionPoliciesCheckedBlocks(user), 1)
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+
+	"github.com/grafana/mimir/pkg/sandbox"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+// markExpiredSandboxTenantsForDeletion writes the tenant deletion mark of the expired sandbox tenants owned
+// by this compactor. Once marked, the blocks of the sandbox tenants are deleted by the blocks cleaner, and the
+// ingesters close their TSDB and delete their local data.
+func (c *MultitenantCompactor) markExpiredSandboxTenantsForDeletion(ctx context.Context, now time.Time) {
+	if c.compactorCfg.SandboxTenants == nil {
+		return
+	}
+
+	for _, t := range c.compactorCfg.SandboxTenants.ExpiredTenants(now) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Never delete a tenant which isn't a sandbox tenant, in case it has been registered
+		// as sandbox tenant before the sandbox tenant ID prefix was required.
+		if !sandbox.IsSandboxTenantID(t.ID) {
+			level.Warn(c.logger).Log("msg", "skipping expired sandbox tenant without the sandbox tenant ID prefix", "user", t.ID, "prefix", sandbox.TenantIDPrefix)
+			continue
+		}
+
+		// Only a single compactor writes the deletion mark of a given sandbox tenant.
+		if owned, err := c.shardingStrategy.blocksCleanerOwnUser(t.ID); err != nil {
+			level.Warn(c.logger).Log("msg", "unable to check if sandbox tenant is owned by this shard", "user", t.ID, "err", err)
+			continue
+		} else if !owned {
+			continue
+		}
+
+		markedForDeletion, err := mimir_tsdb.TenantDeletionMarkExists(ctx, c.bucketClient, t.ID)
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "unable to check if sandbox tenant is marked for deletion", "user", t.ID, "err", err)
+			continue
+		}
+
+		if !markedForDeletion {
+			if err := mimir_tsdb.WriteTenantDeletionMark(ctx, c.bucketClient, t.ID, c.cfgProvider, mimir_tsdb.NewTenantDeletionMark(now)); err != nil {
+				level.Error(c.logger).Log("msg", "failed to write expired sandbox tenant deletion mark", "user", t.ID, "err", err)
+				continue
+			}
+
+			c.sandboxTenantsMarkedForDeletion.Inc()
+			level.Info(c.logger).Log("msg", "expired sandbox tenant marked for deletion", "user", t.ID, "source", t.SourceID, "expired_at", t.ExpiresAt)
+		}
+
+		// The deletion mark is in place, so the expired sandbox tenant is not needed anymore.
+		if now.Sub(t.ExpiresAt) > sandbox.ExpiredTenantsRetention {
+			if err := c.compactorCfg.SandboxTenants.Remove(ctx, t.ID); err != nil {
+				level.Warn(c.logger).Log("msg", "failed to remove expired sandbox tenant", "user", t.ID, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/sandbox"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+func TestMultitenantCompactor_MarkExpiredSandboxTenantsForDeletion(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	var sandboxCfg sandbox.Config
+	flagext.DefaultValues(&sandboxCfg)
+	sandboxCfg.Enabled = true
+	kvStore, closer := consul.NewInMemoryClient(sandbox.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+	sandboxCfg.KVStore.Store = "consul"
+	sandboxCfg.KVStore.Mock = kvStore
+
+	registry, err := sandbox.NewRegistry(sandboxCfg, nil, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, registry))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, registry)) })
+
+	for id, createdAt := range map[string]time.Time{
+		sandbox.TenantIDPrefix + "active":    now,
+		sandbox.TenantIDPrefix + "expired":   now.Add(-sandbox.ExpiredTenantsRetention),
+		sandbox.TenantIDPrefix + "not-owned": now.Add(-sandbox.ExpiredTenantsRetention),
+		sandbox.TenantIDPrefix + "old":       now.Add(-2 * sandbox.ExpiredTenantsRetention),
+	} {
+		_, err := registry.Create(ctx, id, "user", 0.5, time.Hour, createdAt)
+		require.NoError(t, err)
+	}
+
+	// A tenant without the sandbox tenant ID prefix, registered before the prefix was required.
+	require.NoError(t, kvStore.CAS(ctx, "user-legacy", func(interface{}) (interface{}, bool, error) {
+		createdAt := now.Add(-sandbox.ExpiredTenantsRetention)
+		return &sandbox.Tenant{ID: "user-legacy", SourceID: "user", Fraction: 0.5, CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour), UpdatedAt: createdAt}, false, nil
+	}))
+	test.Poll(t, time.Second, true, func() interface{} {
+		_, ok := registry.Get("user-legacy")
+		return ok
+	})
+
+	bucketClient := objstore.NewInMemBucket()
+
+	cfg := prepareConfig(t)
+	cfg.SandboxTenants = registry
+	c, _, _, _, reg := prepare(t, cfg, bucketClient)
+	c.bucketClient = bucketClient
+	c.shardingStrategy = &sandboxShardingStrategyMock{notOwned: sandbox.TenantIDPrefix + "not-owned"}
+
+	c.markExpiredSandboxTenantsForDeletion(ctx, now)
+
+	for id, expected := range map[string]bool{
+		sandbox.TenantIDPrefix + "active":    false,
+		sandbox.TenantIDPrefix + "expired":   true,
+		sandbox.TenantIDPrefix + "not-owned": false,
+		sandbox.TenantIDPrefix + "old":       true,
+		"user-legacy":                        false,
+	} {
+		marked, err := mimir_tsdb.TenantDeletionMarkExists(ctx, bucketClient, id)
+		require.NoError(t, err)
+		assert.Equal(t, expected, marked, id)
+	}
+
+	// The sandbox tenants expired for longer than the retention are removed once marked for deletion.
+	_, ok := registry.Get(sandbox.TenantIDPrefix + "old")
+	assert.False(t, ok)
+	_, ok = registry.Get(sandbox.TenantIDPrefix + "expired")
+	assert.True(t, ok)
+
+	// The deletion mark is written only once.
+	c.markExpiredSandboxTenantsForDeletion(ctx, now)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_compactor_sandbox_tenants_marked_for_deletion_total Total number of expired sandbox tenants marked for deletion.
+		# TYPE cortex_compactor_sandbox_tenants_marked_for_deletion_total counter
+		cortex_compactor_sandbox_tenants_marked_for_deletion_total 2
+	`), "cortex_compactor_sandbox_tenants_marked_for_deletion_total"))
+}
+
+type sandboxShardingStrategyMock struct {
+	notOwned string
+}
+
+func (m *sandboxShardingStrategyMock) compactorOwnUser(userID string) (bool, error) {
+	return userID != m.notOwned, nil
+}
+
+func (m *sandboxShardingStrategyMock) blocksCleanerOwnUser(userID string) (bool, error) {
+	return userID != m.notOwned, nil
+}
+
+func (m *sandboxShardingStrategyMock) ownJob(*Job) (bool, error) {
+	return true, nil
+}
@@ -13,7 +13,7 @@ import (
 func splitAndMergeGrouperFactory(ctx context.Context, cfg Config, cfgProvider ConfigProvider, userID string, logger log.Logger, reg prometheus.Registerer) Grouper {
 	return NewSplitAndMergeGrouper(
 		userID,
-		cfg.BlockRanges.ToMilliseconds(),
+		blockRangesForUser(cfg, cfgProvider, userID),
 		uint32(cfgProvider.CompactorSplitAndMergeShards(userID)),
 		uint32(cfgProvider.CompactorSplitGroups(userID)),
 		logger)
@@ -870,3 +870,12 @@ func convertMetasMapToSlice(metas map[ulid.ULID]*metadata.Meta) []*metadata.Meta
 	}
 	return out
 }
+
+func TestBlockRangesForUser(t *testing.T) {
+	cfg := Config{BlockRanges: mimir_tsdb.DurationList{2 * time.Hour, 24 * time.Hour}}
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.blockRanges["user-2"] = mimir_tsdb.DurationList{2 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+	assert.Equal(t, []int64{2 * time.Hour.Milliseconds(), 24 * time.Hour.Milliseconds()}, blockRangesForUser(cfg, cfgProvider, "user-1"))
+	assert.Equal(t, []int64{2 * time.Hour.Milliseconds(), 24 * time.Hour.Milliseconds(), 7 * 24 * time.Hour.Milliseconds()}, blockRangesForUser(cfg, cfgProvider, "user-2"))
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// Relative to user-specific prefix.
+const TenantCompactionSkipMarkPath = "markers/tenant-compaction-skip-mark.json"
+
+// TenantCompactionSkipMark is uploaded to the bucket when the compaction of a tenant repeatedly fails,
+// and instructs the compactor to skip the tenant until the mark expires.
+type TenantCompactionSkipMark struct {
+	// The error of the last failed compaction.
+	Reason string `json:"reason"`
+
+	// Number of consecutive failed compaction runs.
+	Failures int `json:"failures"`
+
+	// Unix timestamp when the mark was created or last updated.
+	SkipTime int64 `json:"skip_time"`
+
+	// Unix timestamp until which the compaction of the tenant is skipped.
+	ExpirationTime int64 `json:"expiration_time"`
+}
+
+// Expired returns whether the compaction of the tenant should be attempted again.
+func (m *TenantCompactionSkipMark) Expired(now time.Time) bool {
+	return !now.Before(time.Unix(m.ExpirationTime, 0))
+}
+
+// ReadTenantCompactionSkipMark returns the compaction skip mark of the given user, if it exists.
+// If it doesn't exist, returns nil mark, and no error.
+func ReadTenantCompactionSkipMark(ctx context.Context, bkt objstore.BucketReader, userID string) (*TenantCompactionSkipMark, error) {
+	markerFile := path.Join(userID, TenantCompactionSkipMarkPath)
+
+	r, err := bkt.Get(ctx, markerFile)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to read compaction skip mark object: %s", markerFile)
+	}
+
+	mark := &TenantCompactionSkipMark{}
+	err = json.NewDecoder(r).Decode(mark)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode compaction skip mark object: %s", markerFile)
+	}
+
+	return mark, nil
+}
+
+// WriteTenantCompactionSkipMark uploads the compaction skip mark to the tenant location in the bucket.
+func WriteTenantCompactionSkipMark(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, mark *TenantCompactionSkipMark) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	data, err := json.Marshal(mark)
+	if err != nil {
+		return errors.Wrap(err, "serialize compaction skip mark")
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, TenantCompactionSkipMarkPath, bytes.NewReader(data)), "upload compaction skip mark")
+}
+
+// DeleteTenantCompactionSkipMark removes the compaction skip mark of the given user, if it exists.
+func DeleteTenantCompactionSkipMark(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	if err := bkt.Delete(ctx, TenantCompactionSkipMarkPath); err != nil && !bkt.IsObjNotFoundErr(err) {
+		return errors.Wrap(err, "delete compaction skip mark")
+	}
+	return nil
+}
+
+// tenantCompactionSkipBackoff returns for how long a tenant is skipped after the given number of
+// consecutive failed compaction runs. The backoff doubles at each failure after the threshold.
+func (c *MultitenantCompactor) tenantCompactionSkipBackoff(failures int) time.Duration {
+	backoff := c.compactorCfg.TenantSkipBackoff
+	for i := c.compactorCfg.TenantSkipFailuresThreshold; i < failures && backoff < c.compactorCfg.TenantSkipMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > c.compactorCfg.TenantSkipMaxBackoff {
+		return c.compactorCfg.TenantSkipMaxBackoff
+	}
+	return backoff
+}
+
+// recordTenantCompactionFailure tracks a failed compaction run of the tenant, and uploads the compaction skip mark
+// once the number of consecutive failures reaches the configured threshold. The skip mark read at the beginning
+// of the compaction run, if any, is used to keep track of the failures across compactor restarts.
+func (c *MultitenantCompactor) recordTenantCompactionFailure(ctx context.Context, userID string, mark *TenantCompactionSkipMark, compactionErr error, userLogger log.Logger) {
+	failures := c.tenantCompactionFailures[userID] + 1
+	if mark != nil && mark.Failures >= failures {
+		failures = mark.Failures + 1
+	}
+	c.tenantCompactionFailures[userID] = failures
+
+	if failures < c.compactorCfg.TenantSkipFailuresThreshold {
+		return
+	}
+
+	now := time.Now()
+	backoff := c.tenantCompactionSkipBackoff(failures)
+	newMark := &TenantCompactionSkipMark{
+		Reason:         compactionErr.Error(),
+		Failures:       failures,
+		SkipTime:       now.Unix(),
+		ExpirationTime: now.Add(backoff).Unix(),
+	}
+
+	if err := WriteTenantCompactionSkipMark(ctx, c.bucketClient, userID, c.cfgProvider, newMark); err != nil {
+		level.Warn(userLogger).Log("msg", "failed to write tenant compaction skip mark", "err", err)
+		return
+	}
+
+	c.tenantCompactionSkipMarksCreated.Inc()
+	level.Warn(userLogger).Log("msg", "skipping compaction of tenant because of consecutive failures", "failures", failures, "backoff", backoff, "reason", newMark.Reason)
+}
+
+// recordTenantCompactionSuccess resets the failures of the tenant, removing its compaction skip mark if any.
+func (c *MultitenantCompactor) recordTenantCompactionSuccess(ctx context.Context, userID string, mark *TenantCompactionSkipMark, userLogger log.Logger) {
+	delete(c.tenantCompactionFailures, userID)
+
+	if mark == nil {
+		return
+	}
+
+	if err := DeleteTenantCompactionSkipMark(ctx, c.bucketClient, userID, c.cfgProvider); err != nil {
+		level.Warn(userLogger).Log("msg", "failed to delete tenant compaction skip mark", "err", err)
+		return
+	}
+
+	level.Info(userLogger).Log("msg", "deleted tenant compaction skip mark after a successful compaction")
+}
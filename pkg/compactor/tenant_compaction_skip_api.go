@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type TenantCompactionSkipStatusResponse struct {
+	TenantID string                    `json:"tenant_id"`
+	Skipped  bool                      `json:"skipped"`
+	Mark     *TenantCompactionSkipMark `json:"mark,omitempty"`
+}
+
+// TenantCompactionSkipHandler handles the tenant compaction skip API. GET returns whether the compaction
+// of the tenant is skipped because of consecutive failures, and DELETE removes the compaction skip mark
+// so that the tenant is compacted again at the next compaction run.
+func (c *MultitenantCompactor) TenantCompactionSkipHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mark, err := ReadTenantCompactionSkipMark(ctx, c.bucketClient, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		util.WriteJSONResponse(w, TenantCompactionSkipStatusResponse{
+			TenantID: userID,
+			Skipped:  mark != nil && !mark.Expired(time.Now()),
+			Mark:     mark,
+		})
+
+	case http.MethodDelete:
+		if err := DeleteTenantCompactionSkipMark(ctx, c.bucketClient, userID, c.cfgProvider); err != nil {
+			level.Error(c.logger).Log("msg", "failed to delete tenant compaction skip mark", "user", userID, "err", err)
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		level.Info(c.logger).Log("msg", "tenant compaction skip mark deleted", "user", userID)
+
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+func TestMultitenantCompactor_ShouldSkipTenantAfterConsecutiveCompactionFailures(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	createTSDBBlock(t, bkt, userID, 10, 20, 2, nil)
+
+	// Fail the compaction of the tenant while listing its blocks.
+	injectedBkt := &bucket.ErrorInjectedBucketClient{
+		Bucket:   bkt,
+		Injector: bucket.InjectErrorOn(bucket.OpIter, userID+"/", errors.New("failed to list blocks")),
+	}
+
+	cfg := prepareConfig(t)
+	cfg.CompactionInterval = 100 * time.Millisecond
+	cfg.CompactionRetries = 1
+	cfg.TenantSkipFailuresThreshold = 2
+	cfg.TenantSkipBackoff = time.Hour
+	cfg.TenantSkipMaxBackoff = 4 * time.Hour
+
+	c, _, _, _, _ := prepare(t, cfg, injectedBkt)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(stopServiceFn(t, c))
+
+	// Wait until the tenant has been skipped by a compaction run following the failures.
+	test.Poll(t, 10*time.Second, 1.0, func() interface{} {
+		return prom_testutil.ToFloat64(c.tenantsCompactionSkipped)
+	})
+
+	assert.Equal(t, 1.0, prom_testutil.ToFloat64(c.tenantCompactionSkipMarksCreated))
+
+	mark, err := ReadTenantCompactionSkipMark(ctx, bkt, userID)
+	require.NoError(t, err)
+	require.NotNil(t, mark)
+	assert.Equal(t, 2, mark.Failures)
+	assert.Contains(t, mark.Reason, "failed to list blocks")
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), mark.ExpirationTime, 60)
+
+	doRequest := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/compactor/tenant_compaction_skip", nil).WithContext(user.InjectOrgID(ctx, userID))
+		resp := httptest.NewRecorder()
+		c.TenantCompactionSkipHandler(resp, req)
+		return resp
+	}
+
+	getStatus := func() TenantCompactionSkipStatusResponse {
+		resp := doRequest(http.MethodGet)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		status := TenantCompactionSkipStatusResponse{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		return status
+	}
+
+	status := getStatus()
+	assert.Equal(t, userID, status.TenantID)
+	assert.True(t, status.Skipped)
+	assert.Equal(t, mark, status.Mark)
+
+	// Deleting the mark resumes the compaction of the tenant.
+	require.Equal(t, http.StatusOK, doRequest(http.MethodDelete).Code)
+	assert.Equal(t, TenantCompactionSkipStatusResponse{TenantID: userID}, getStatus())
+}
+
+func TestMultitenantCompactor_TenantCompactionSkipBackoff(t *testing.T) {
+	cfg := prepareConfig(t)
+	cfg.TenantSkipFailuresThreshold = 3
+	cfg.TenantSkipBackoff = time.Hour
+	cfg.TenantSkipMaxBackoff = 6 * time.Hour
+
+	c := &MultitenantCompactor{compactorCfg: cfg}
+
+	for failures, expected := range map[int]time.Duration{
+		3: time.Hour,
+		4: 2 * time.Hour,
+		5: 4 * time.Hour,
+		6: 6 * time.Hour,
+		9: 6 * time.Hour,
+	} {
+		assert.Equal(t, expected, c.tenantCompactionSkipBackoff(failures), "failures: %d", failures)
+	}
+}
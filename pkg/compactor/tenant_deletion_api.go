@@ -45,9 +45,21 @@ func (c *MultitenantCompactor) DeleteTenant(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// TenantConfigDeleter deletes the configuration of a tenant stored outside the blocks storage, like
+// the ruler rule groups or the Alertmanager configuration, when the tenant is deleted.
+type TenantConfigDeleter func(ctx context.Context, userID string) error
+
 type DeleteTenantStatusResponse struct {
 	TenantID      string `json:"tenant_id"`
 	BlocksDeleted bool   `json:"blocks_deleted"`
+
+	// Progress of the tenant deletion, tracked in the tenant deletion mark.
+	MarkedForDeletion bool  `json:"marked_for_deletion"`
+	DeletionTime      int64 `json:"deletion_time,omitempty"`
+	RemainingBlocks   int   `json:"remaining_blocks"`
+	ConfigsDeleted    bool  `json:"configs_deleted"`
+	FinishedTime      int64 `json:"finished_time,omitempty"`
+	DeletionCompleted bool  `json:"deletion_completed"`
 }
 
 func (c *MultitenantCompactor) DeleteTenantStatus(w http.ResponseWriter, r *http.Request) {
@@ -58,9 +70,7 @@ func (c *MultitenantCompactor) DeleteTenantStatus(w http.ResponseWriter, r *http
 		return
 	}
 
-	result := DeleteTenantStatusResponse{}
-	result.TenantID = userID
-	result.BlocksDeleted, err = c.isBlocksForUserDeleted(ctx, userID)
+	result, err := c.deleteTenantStatus(ctx, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -69,31 +79,47 @@ func (c *MultitenantCompactor) DeleteTenantStatus(w http.ResponseWriter, r *http
 	util.WriteJSONResponse(w, result)
 }
 
-func (c *MultitenantCompactor) isBlocksForUserDeleted(ctx context.Context, userID string) (bool, error) {
-	var errBlockFound = errors.New("block found")
+func (c *MultitenantCompactor) deleteTenantStatus(ctx context.Context, userID string) (DeleteTenantStatusResponse, error) {
+	result := DeleteTenantStatusResponse{TenantID: userID}
+
+	remainingBlocks, err := c.countBlocksForUser(ctx, userID)
+	if err != nil {
+		return result, err
+	}
+	result.RemainingBlocks = remainingBlocks
+	result.BlocksDeleted = remainingBlocks == 0
+
+	mark, err := mimir_tsdb.ReadTenantDeletionMark(ctx, c.bucketClient, userID)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to read tenant deletion mark")
+	}
+	if mark == nil {
+		// Either the tenant hasn't been marked for deletion, or the deletion has been completed
+		// and the tenant deletion mark cleaned up.
+		return result, nil
+	}
+
+	result.MarkedForDeletion = true
+	result.DeletionTime = mark.DeletionTime
+	result.ConfigsDeleted = mark.ConfigsDeletedTime > 0
+	result.FinishedTime = mark.FinishedTime
+	result.DeletionCompleted = result.BlocksDeleted && result.ConfigsDeleted && mark.FinishedTime > 0
+	return result, nil
+}
+
+// countBlocksForUser returns the number of blocks of the tenant in the storage.
+func (c *MultitenantCompactor) countBlocksForUser(ctx context.Context, userID string) (int, error) {
+	count := 0
 
 	userBucket := bucket.NewUserBucketClient(userID, c.bucketClient, c.cfgProvider)
 	err := userBucket.Iter(ctx, "", func(s string) error {
 		s = strings.TrimSuffix(s, "/")
 
-		_, err := ulid.Parse(s)
-		if err != nil {
-			// not block, keep looking
-			return nil
+		if _, err := ulid.Parse(s); err == nil {
+			count++
 		}
-
-		// Used as shortcut to stop iteration.
-		return errBlockFound
+		return nil
 	})
 
-	if errors.Is(err, errBlockFound) {
-		return false, nil
-	}
-
-	if err != nil {
-		return false, err
-	}
-
-	// No blocks found, all good.
-	return true, nil
+	return count, err
 }
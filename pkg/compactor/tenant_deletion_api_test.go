@@ -52,33 +52,72 @@ func TestDeleteTenantStatus(t *testing.T) {
 	const username = "user"
 
 	for name, tc := range map[string]struct {
-		objects               map[string][]byte
-		expectedBlocksDeleted bool
+		objects          map[string][]byte
+		deletionMark     *tsdb.TenantDeletionMark
+		expectedResponse DeleteTenantStatusResponse
 	}{
 		"empty": {
-			objects:               nil,
-			expectedBlocksDeleted: true,
+			objects:          nil,
+			expectedResponse: DeleteTenantStatusResponse{TenantID: username, BlocksDeleted: true},
 		},
 
 		"no user objects": {
 			objects: map[string][]byte{
 				"different-user/01EQK4QKFHVSZYVJ908Y7HH9E0/meta.json": []byte("data"),
 			},
-			expectedBlocksDeleted: true,
+			expectedResponse: DeleteTenantStatusResponse{TenantID: username, BlocksDeleted: true},
 		},
 
 		"non-block files": {
 			objects: map[string][]byte{
 				"user/deletion-mark.json": []byte("data"),
 			},
-			expectedBlocksDeleted: true,
+			expectedResponse: DeleteTenantStatusResponse{TenantID: username, BlocksDeleted: true},
 		},
 
 		"block files": {
 			objects: map[string][]byte{
 				"user/01EQK4QKFHVSZYVJ908Y7HH9E0/meta.json": []byte("data"),
+				"user/01EQK4QKFHVSZYVJ908Y7HH9E1/meta.json": []byte("data"),
+			},
+			expectedResponse: DeleteTenantStatusResponse{TenantID: username, BlocksDeleted: false, RemainingBlocks: 2},
+		},
+
+		"marked for deletion, blocks deletion in progress": {
+			objects: map[string][]byte{
+				"user/01EQK4QKFHVSZYVJ908Y7HH9E0/meta.json": []byte("data"),
+			},
+			deletionMark: &tsdb.TenantDeletionMark{DeletionTime: 100},
+			expectedResponse: DeleteTenantStatusResponse{
+				TenantID:          username,
+				BlocksDeleted:     false,
+				MarkedForDeletion: true,
+				DeletionTime:      100,
+				RemainingBlocks:   1,
+			},
+		},
+
+		"marked for deletion, configs deletion in progress": {
+			deletionMark: &tsdb.TenantDeletionMark{DeletionTime: 100},
+			expectedResponse: DeleteTenantStatusResponse{
+				TenantID:          username,
+				BlocksDeleted:     true,
+				MarkedForDeletion: true,
+				DeletionTime:      100,
+			},
+		},
+
+		"marked for deletion, deletion completed": {
+			deletionMark: &tsdb.TenantDeletionMark{DeletionTime: 100, ConfigsDeletedTime: 150, FinishedTime: 200},
+			expectedResponse: DeleteTenantStatusResponse{
+				TenantID:          username,
+				BlocksDeleted:     true,
+				MarkedForDeletion: true,
+				DeletionTime:      100,
+				ConfigsDeleted:    true,
+				FinishedTime:      200,
+				DeletionCompleted: true,
 			},
-			expectedBlocksDeleted: false,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -87,15 +126,20 @@ func TestDeleteTenantStatus(t *testing.T) {
 			for objName, data := range tc.objects {
 				require.NoError(t, bkt.Upload(context.Background(), objName, bytes.NewReader(data)))
 			}
+			if tc.deletionMark != nil {
+				require.NoError(t, tsdb.WriteTenantDeletionMark(context.Background(), bkt, username, nil, tc.deletionMark))
+			}
 
+			// The tenant is disabled, otherwise the blocks cleaner could delete it concurrently.
 			cfg := prepareConfig(t)
+			cfg.DisabledTenants = []string{username}
 			c, _, _, _, _ := prepare(t, cfg, bkt)
 			require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
 			t.Cleanup(stopServiceFn(t, c))
 
-			res, err := c.isBlocksForUserDeleted(context.Background(), username)
+			res, err := c.deleteTenantStatus(context.Background(), username)
 			require.NoError(t, err)
-			require.Equal(t, tc.expectedBlocksDeleted, res)
+			require.Equal(t, tc.expectedResponse, res)
 		})
 	}
 }
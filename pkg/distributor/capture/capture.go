@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+const (
+	// Prefix is the prefix, relative to bucket.MimirInternalsPrefix, under which the captured requests are stored.
+	Prefix = "request-capture"
+
+	// Max number of captured requests waiting to be uploaded to the bucket.
+	uploadQueueSize = 100
+
+	// Timeout of the upload of a captured request.
+	uploadTimeout = time.Minute
+)
+
+var (
+	errInvalidSampleRatio = errors.New("invalid distributor request capture sample ratio, must be between 0 and 1")
+)
+
+// Config configures the capture of the push requests received by the distributor.
+type Config struct {
+	SampleRatio float64 `yaml:"sample_ratio" category:"experimental"`
+}
+
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.Float64Var(&c.SampleRatio, "distributor.request-capture.sample-ratio", 0, "Ratio of the push requests which are captured to the blocks storage bucket, under the "+bucket.MimirInternalsPrefix+"/"+Prefix+" prefix, to be replayed with mimirtool. The requests are captured as received, before the HA deduplication, relabeling and validation. The value must be between 0 and 1. 0 to disable.")
+}
+
+func (c *Config) Validate() error {
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errInvalidSampleRatio
+	}
+	return nil
+}
+
+// Enabled returns whether the capture of the push requests is enabled.
+func (c *Config) Enabled() bool {
+	return c.SampleRatio > 0
+}
+
+// Capturer captures a sample of the push requests to the bucket, asynchronously.
+type Capturer struct {
+	services.Service
+
+	cfg    Config
+	bucket objstore.Bucket
+	logger log.Logger
+
+	queue chan capturedRequest
+
+	capturedTotal prometheus.Counter
+	failedTotal   *prometheus.CounterVec
+}
+
+type capturedRequest struct {
+	name string
+	data []byte
+}
+
+// NewCapturer creates a new Capturer storing the captured requests to the input bucket. It returns nil if
+// the capture is disabled.
+func NewCapturer(cfg Config, bucketClient objstore.Bucket, reg prometheus.Registerer, logger log.Logger) *Capturer {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	c := &Capturer{
+		cfg:    cfg,
+		bucket: bucket.NewPrefixedBucketClient(bucketClient, bucket.MimirInternalsPrefix),
+		logger: logger,
+		queue:  make(chan capturedRequest, uploadQueueSize),
+		capturedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_distributor_request_capture_captured_total",
+			Help: "The total number of push requests captured to the bucket.",
+		}),
+		failedTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_request_capture_failed_total",
+			Help: "The total number of push requests sampled for capture which have not been stored to the bucket.",
+		}, []string{"reason"}),
+	}
+
+	// Initialise the failure reasons.
+	for _, reason := range []string{"queue_full", "encode", "upload"} {
+		c.failedTotal.WithLabelValues(reason)
+	}
+
+	c.Service = services.NewBasicService(nil, c.running, nil)
+	return c
+}
+
+// Capture captures the input request if it's sampled. The request is encoded synchronously, because it's
+// modified by the distributor once this function has returned, and uploaded to the bucket asynchronously.
+// The request is dropped if too many requests are waiting to be uploaded.
+func (c *Capturer) Capture(userID string, req *mimirpb.WriteRequest) {
+	if rand.Float64() >= c.cfg.SampleRatio {
+		return
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		c.failedTotal.WithLabelValues("encode").Inc()
+		level.Warn(c.logger).Log("msg", "failed to encode the captured push request", "user", userID, "err", err)
+		return
+	}
+
+	r := capturedRequest{
+		name: ObjectName(userID, time.Now(), rand.Uint64()),
+		data: snappy.Encode(nil, data),
+	}
+
+	select {
+	case c.queue <- r:
+	default:
+		c.failedTotal.WithLabelValues("queue_full").Inc()
+	}
+}
+
+func (c *Capturer) running(ctx context.Context) error {
+	for {
+		select {
+		case r := <-c.queue:
+			c.upload(ctx, r)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *Capturer) upload(ctx context.Context, r capturedRequest) {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	if err := c.bucket.Upload(ctx, r.name, bytes.NewReader(r.data)); err != nil {
+		c.failedTotal.WithLabelValues("upload").Inc()
+		level.Warn(c.logger).Log("msg", "failed to upload the captured push request", "object", r.name, "err", err)
+		return
+	}
+	c.capturedTotal.Inc()
+}
+
+// ObjectName returns the name, relative to bucket.MimirInternalsPrefix, of the object storing a request
+// of the input tenant captured at the input time. The timestamp is zero-padded, so that the objects of a
+// tenant are listed in the order they have been captured, and the id makes the name unique across distributors.
+func ObjectName(userID string, t time.Time, id uint64) string {
+	return fmt.Sprintf("%s/%s/%013d-%016x", Prefix, userID, t.UnixMilli(), id)
+}
+
+// ParseObjectName returns the tenant and the capture time of the request stored in the input object,
+// whose name is relative to bucket.MimirInternalsPrefix.
+func ParseObjectName(name string) (userID string, t time.Time, err error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != Prefix {
+		return "", time.Time{}, fmt.Errorf("invalid captured request object name: %s", name)
+	}
+
+	ts, _, ok := strings.Cut(parts[2], "-")
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("invalid captured request object name: %s", name)
+	}
+
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid captured request object name: %s", name)
+	}
+
+	return parts[1], time.UnixMilli(ms), nil
+}
+
+// DecodeRequest decodes a captured request.
+func DecodeRequest(data []byte) (*mimirpb.WriteRequest, error) {
+	decoded, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &mimirpb.WriteRequest{}
+	if err := req.Unmarshal(decoded); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
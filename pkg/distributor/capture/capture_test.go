@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package capture
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		sampleRatio float64
+		expectedErr error
+	}{
+		"disabled":               {sampleRatio: 0},
+		"capture some requests":  {sampleRatio: 0.5},
+		"capture all requests":   {sampleRatio: 1},
+		"negative sample ratio":  {sampleRatio: -0.1, expectedErr: errInvalidSampleRatio},
+		"sample ratio exceeds 1": {sampleRatio: 1.1, expectedErr: errInvalidSampleRatio},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := Config{SampleRatio: testData.sampleRatio}
+			assert.Equal(t, testData.expectedErr, cfg.Validate())
+		})
+	}
+}
+
+func TestNewCapturer_ShouldReturnNilIfDisabled(t *testing.T) {
+	assert.Nil(t, NewCapturer(Config{}, objstore.NewInMemBucket(), nil, log.NewNopLogger()))
+}
+
+func TestCapturer_Capture(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	reg := prometheus.NewPedanticRegistry()
+
+	c := NewCapturer(Config{SampleRatio: 1}, bkt, reg, log.NewNopLogger())
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), c))
+	})
+
+	req := &mimirpb.WriteRequest{
+		Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "series_1"}},
+			Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}},
+		}}},
+		Source: mimirpb.API,
+	}
+	c.Capture("user-1", req)
+
+	// The request can be modified once captured.
+	req.Timeseries[0].Samples[0].Value = 2
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(c.capturedTotal) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var names []string
+	require.NoError(t, bkt.Iter(context.Background(), bucket.MimirInternalsPrefix+"/"+Prefix+"/user-1/", func(name string) error {
+		names = append(names, name)
+		return nil
+	}))
+	require.Len(t, names, 1)
+
+	userID, _, err := ParseObjectName(strings.TrimPrefix(names[0], bucket.MimirInternalsPrefix+"/"))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	reader, err := bkt.Get(context.Background(), names[0])
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	captured, err := DecodeRequest(data)
+	require.NoError(t, err)
+	require.Len(t, captured.Timeseries, 1)
+	assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "series_1"}}, captured.Timeseries[0].Labels)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 1000, Value: 1}}, captured.Timeseries[0].Samples)
+	assert.Equal(t, mimirpb.API, captured.Source)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_request_capture_captured_total The total number of push requests captured to the bucket.
+		# TYPE cortex_distributor_request_capture_captured_total counter
+		cortex_distributor_request_capture_captured_total 1
+
+		# HELP cortex_distributor_request_capture_failed_total The total number of push requests sampled for capture which have not been stored to the bucket.
+		# TYPE cortex_distributor_request_capture_failed_total counter
+		cortex_distributor_request_capture_failed_total{reason="encode"} 0
+		cortex_distributor_request_capture_failed_total{reason="queue_full"} 0
+		cortex_distributor_request_capture_failed_total{reason="upload"} 0
+	`)))
+}
+
+func TestCapturer_ShouldSampleRequests(t *testing.T) {
+	c := NewCapturer(Config{SampleRatio: 0.5}, objstore.NewInMemBucket(), nil, log.NewNopLogger())
+
+	// The capturer is not running, so the sampled requests are queued.
+	for i := 0; i < uploadQueueSize; i++ {
+		c.Capture("user-1", &mimirpb.WriteRequest{})
+	}
+
+	assert.Greater(t, len(c.queue), 0)
+	assert.Less(t, len(c.queue), uploadQueueSize)
+}
+
+func TestObjectName(t *testing.T) {
+	now := time.UnixMilli(1680000000123)
+
+	name := ObjectName("user-1", now, 255)
+	assert.Equal(t, "request-capture/user-1/1680000000123-00000000000000ff", name)
+
+	userID, ts, err := ParseObjectName(name)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	assert.Equal(t, now, ts)
+
+	for _, invalid := range []string{"", "request-capture/user-1", "other/user-1/1680000000123-00000000000000ff", "request-capture/user-1/invalid"} {
+		_, _, err := ParseObjectName(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
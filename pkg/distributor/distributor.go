@@ -31,6 +31,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/relabel"
 	"github.com/prometheus/prometheus/scrape"
+	"github.com/thanos-io/objstore"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/instrument"
 	"github.com/weaveworks/common/mtime"
@@ -41,11 +42,15 @@ import (
 
 	"github.com/grafana/dskit/tenant"
 
+	"github.com/grafana/mimir/pkg/distributor/capture"
 	"github.com/grafana/mimir/pkg/distributor/forwarding"
 	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/sandbox"
+	"github.com/grafana/mimir/pkg/storage/ingest"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/httpgrpcutil"
+	"github.com/grafana/mimir/pkg/util/ingestaggregation"
 	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/push"
 	"github.com/grafana/mimir/pkg/util/validation"
@@ -53,7 +58,8 @@ import (
 
 var (
 	// Validation errors.
-	errInvalidTenantShardSize = errors.New("invalid tenant shard size, the value must be greater or equal to zero")
+	errInvalidTenantShardSize            = errors.New("invalid tenant shard size, the value must be greater or equal to zero")
+	errInvalidIngesterQueryHedgingBudget = errors.New("invalid ingester query hedging budget, the value must be between 0 and 1")
 )
 
 const (
@@ -73,12 +79,13 @@ const (
 type Distributor struct {
 	services.Service
 
-	cfg           Config
-	log           log.Logger
-	ingestersRing ring.ReadRing
-	ingesterPool  *ring_client.Pool
-	limits        *validation.Overrides
-	forwarder     forwarding.Forwarder
+	cfg             Config
+	log             log.Logger
+	ingestersRing   ring.ReadRing
+	ingesterPool    *ring_client.Pool
+	limits          *validation.Overrides
+	forwarder       forwarding.Forwarder
+	requestCapturer *capture.Capturer
 
 	// The global rate limiter requires a distributors ring to count
 	// the number of healthy instances
@@ -93,6 +100,9 @@ type Distributor struct {
 	requestRateLimiter   *limiter.RateLimiter
 	ingestionRateLimiter *limiter.RateLimiter
 
+	// Per-source rate limiter, applied to each source of requests within a tenant.
+	sourceRequestRateLimiter *sourceRateLimiter
+
 	// Manager for subservices (HA Tracker, distributor ring, forwarder and client pool)
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -104,10 +114,35 @@ type Distributor struct {
 	inflightPushRequests      atomic.Int64
 	inflightPushRequestsBytes atomic.Int64
 
+	// Budget for hedging the read requests to ingesters.
+	hedgingBudget *util.HedgingBudget
+
+	// Pressure reported by ingesters in the push responses.
+	ingestersPressure *ingesterPressureTracker
+
+	// Circuit breaker of the write requests to each ingester.
+	ingesterCircuitBreaker *ingesterCircuitBreaker
+
+	// Compiled ingest aggregation rules of each tenant.
+	ingestAggregationRules *ingestaggregation.RulesCache
+
+	// Slots of the push requests mirrored to sandbox tenants in flight.
+	sandboxMirroringInflight chan struct{}
+
+	// Writer of the series to the ingest storage, if enabled.
+	ingestStorageWriter *ingest.Writer
+
 	// Metrics
 	queryDuration                    *instrument.HistogramCollector
 	ingesterChunksDeduplicated       prometheus.Counter
 	ingesterChunksTotal              prometheus.Counter
+	ingesterHedgedRequests           prometheus.Counter
+	ingesterHedgingBudgetExhausted   prometheus.Counter
+	ingestersPressureRejected        prometheus.Counter
+	ingesterCircuitBreakerOpened     prometheus.Counter
+	ingesterCircuitBreakerRejected   prometheus.Counter
+	sandboxMirroringFailures         prometheus.Counter
+	sandboxMirroringDropped          prometheus.Counter
 	receivedRequests                 *prometheus.CounterVec
 	receivedSamples                  *prometheus.CounterVec
 	receivedExemplars                *prometheus.CounterVec
@@ -123,11 +158,12 @@ type Distributor struct {
 	replicationFactor                prometheus.Gauge
 	latestSeenSampleTimestampPerUser *prometheus.GaugeVec
 
-	discardedSamplesTooManyHaClusters *prometheus.CounterVec
-	discardedSamplesRateLimited       *prometheus.CounterVec
-	discardedRequestsRateLimited      *prometheus.CounterVec
-	discardedExemplarsRateLimited     *prometheus.CounterVec
-	discardedMetadataRateLimited      *prometheus.CounterVec
+	discardedSamplesTooManyHaClusters  *prometheus.CounterVec
+	discardedSamplesRateLimited        *prometheus.CounterVec
+	discardedRequestsRateLimited       *prometheus.CounterVec
+	discardedRequestsSourceRateLimited *prometheus.CounterVec
+	discardedExemplarsRateLimited      *prometheus.CounterVec
+	discardedMetadataRateLimited       *prometheus.CounterVec
 
 	sampleValidationMetrics   *validation.SampleValidationMetrics
 	exemplarValidationMetrics *validation.ExemplarValidationMetrics
@@ -159,6 +195,26 @@ type Config struct {
 	// This config is dynamically injected because it is defined in the querier config.
 	ShuffleShardingLookbackPeriod time.Duration `yaml:"-"`
 
+	// This config is dynamically injected because it is defined in the ingester config.
+	IngestersZoneAwarenessEnabled bool     `yaml:"-"`
+	IngestersWitnessZones         []string `yaml:"-"`
+
+	// This is dynamically injected because the sandbox tenants are shared with other components.
+	SandboxTenants *sandbox.Registry `yaml:"-"`
+
+	// This config is dynamically injected because it's shared with the ingesters.
+	IngestStorageConfig ingest.Config `yaml:"-"`
+
+	// Hedging of the read requests to ingesters.
+	IngesterQueryHedgingDelay  time.Duration `yaml:"ingester_query_hedging_delay" category:"experimental"`
+	IngesterQueryHedgingBudget float64       `yaml:"ingester_query_hedging_budget" category:"experimental"`
+
+	// Load shedding based on the pressure reported by ingesters.
+	IngesterPushPressureThreshold float64 `yaml:"ingester_push_pressure_threshold" category:"experimental"`
+
+	// Circuit breaker of the write requests to each ingester.
+	IngesterCircuitBreaker IngesterCircuitBreakerConfig `yaml:"ingester_circuit_breaker"`
+
 	// Limits for distributor
 	DefaultLimits    InstanceLimits         `yaml:"instance_limits"`
 	InstanceLimitsFn func() *InstanceLimits `yaml:"-"`
@@ -166,6 +222,12 @@ type Config struct {
 	// Configuration for forwarding of metrics to alternative ingestion endpoint.
 	Forwarding forwarding.Config
 
+	// Capture of the push requests to the blocks storage bucket.
+	RequestCapture capture.Config `yaml:"request_capture"`
+
+	// This is dynamically injected because the bucket is defined in the blocks storage config.
+	RequestCaptureBucket objstore.Bucket `yaml:"-"`
+
 	// This allows downstream projects to wrap the distributor push function
 	// and access the deserialized write requests before/after they are pushed.
 	// These functions will only receive samples that don't get forwarded to an
@@ -183,9 +245,14 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.HATrackerConfig.RegisterFlags(f)
 	cfg.DistributorRing.RegisterFlags(f, logger)
 	cfg.Forwarding.RegisterFlags(f)
+	cfg.RequestCapture.RegisterFlags(f)
 
 	f.IntVar(&cfg.MaxRecvMsgSize, "distributor.max-recv-msg-size", 100<<20, "Max message size in bytes that the distributors will accept for incoming push requests to the remote write API. If exceeded, the request will be rejected.")
 	f.DurationVar(&cfg.RemoteTimeout, "distributor.remote-timeout", 2*time.Second, "Timeout for downstream ingesters.")
+	f.DurationVar(&cfg.IngesterQueryHedgingDelay, "distributor.ingester-query-hedging-delay", 0, "When querying ingesters, the requests to the ingesters allowed to fail are delayed by this duration, and sent only if the other requests haven't completed in the meanwhile, in order to reduce the tail latency of queries. Hedging is not supported when zone-awareness is enabled. 0 to disable.")
+	f.Float64Var(&cfg.IngesterQueryHedgingBudget, "distributor.ingester-query-hedging-budget", 0.1, "Max ratio of read requests to ingesters which can be hedged when -distributor.ingester-query-hedging-delay is enabled. The value must be between 0 and 1.")
+	f.Float64Var(&cfg.IngesterPushPressureThreshold, ingesterPushPressureThresholdFlag, 0, "Ingesters report their pressure in the push responses, computed as the utilization of their most utilized in-flight push requests or ingestion rate instance limit. When the highest pressure reported by the ingesters a push request is sent to is above this threshold, the distributor rejects a share of the push requests proportional to how far the pressure is above the threshold, to reduce the load on ingesters gradually before they reach their instance limits. The value must be greater than or equal to 0 and lower than 1. 0 to disable.")
+	cfg.IngesterCircuitBreaker.RegisterFlags(f)
 
 	cfg.DefaultLimits.RegisterFlags(f)
 }
@@ -196,6 +263,22 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 		return errInvalidTenantShardSize
 	}
 
+	if cfg.IngesterQueryHedgingBudget < 0 || cfg.IngesterQueryHedgingBudget > 1 {
+		return errInvalidIngesterQueryHedgingBudget
+	}
+
+	if cfg.IngesterPushPressureThreshold < 0 || cfg.IngesterPushPressureThreshold >= 1 {
+		return errInvalidIngesterPushPressureThreshold
+	}
+
+	if err := cfg.IngesterCircuitBreaker.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.RequestCapture.Validate(); err != nil {
+		return err
+	}
+
 	err := cfg.HATrackerConfig.Validate()
 	if err != nil {
 		return err
@@ -229,14 +312,18 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	subservices = append(subservices, haTracker)
 
 	d := &Distributor{
-		cfg:                   cfg,
-		log:                   log,
-		ingestersRing:         ingestersRing,
-		ingesterPool:          NewPool(cfg.PoolConfig, ingestersRing, cfg.IngesterClientFactory, log),
-		healthyInstancesCount: atomic.NewUint32(0),
-		limits:                limits,
-		HATracker:             haTracker,
-		ingestionRate:         util_math.NewEWMARate(0.2, instanceIngestionRateTickInterval),
+		cfg:                    cfg,
+		log:                    log,
+		ingestersRing:          ingestersRing,
+		ingesterPool:           NewPool(cfg.PoolConfig, ingestersRing, cfg.IngesterClientFactory, log),
+		healthyInstancesCount:  atomic.NewUint32(0),
+		limits:                 limits,
+		HATracker:              haTracker,
+		ingestionRate:          util_math.NewEWMARate(0.2, instanceIngestionRateTickInterval),
+		hedgingBudget:          util.NewHedgingBudget(cfg.IngesterQueryHedgingBudget),
+		ingestersPressure:      newIngesterPressureTracker(cfg.IngesterPushPressureThreshold),
+		ingesterCircuitBreaker: newIngesterCircuitBreaker(cfg.IngesterCircuitBreaker),
+		ingestAggregationRules: ingestaggregation.NewRulesCache(),
 
 		queryDuration: instrument.NewHistogramCollector(promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: "cortex",
@@ -254,6 +341,42 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 			Name:      "distributor_query_ingester_chunks_total",
 			Help:      "Number of chunks transferred at query time from ingesters.",
 		}),
+		ingesterHedgedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_query_ingester_hedged_requests_total",
+			Help:      "Number of hedged read requests sent to ingesters.",
+		}),
+		ingesterHedgingBudgetExhausted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_query_ingester_hedging_budget_exhausted_total",
+			Help:      "Number of read requests to ingesters which have not been hedged because the hedging budget was exhausted.",
+		}),
+		ingestersPressureRejected: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_ingesters_pressure_rejected_requests_total",
+			Help:      "Number of push requests rejected because of the pressure reported by ingesters.",
+		}),
+		ingesterCircuitBreakerOpened: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_ingester_circuit_breaker_opened_total",
+			Help:      "Number of times the circuit breaker of the write requests to an ingester has been opened.",
+		}),
+		ingesterCircuitBreakerRejected: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_ingester_circuit_breaker_rejected_requests_total",
+			Help:      "Number of write requests to ingesters not sent because the circuit breaker of the ingester is open.",
+		}),
+		sandboxMirroringFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_sandbox_mirroring_failures_total",
+			Help:      "Number of push requests mirrored to sandbox tenants which failed.",
+		}),
+		sandboxMirroringDropped: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "distributor_sandbox_mirroring_dropped_total",
+			Help:      "Number of push requests to mirror to sandbox tenants which have been dropped because too many mirrored requests were in flight.",
+		}),
+		sandboxMirroringInflight: make(chan struct{}, maxInflightSandboxMirroringRequests),
 		receivedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Namespace: "cortex",
 			Name:      "distributor_received_requests_total",
@@ -339,11 +462,12 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 			Help: "Unix timestamp of latest received sample per user.",
 		}, []string{"user"}),
 
-		discardedSamplesTooManyHaClusters: validation.DiscardedSamplesCounter(reg, validation.ReasonTooManyHAClusters),
-		discardedSamplesRateLimited:       validation.DiscardedSamplesCounter(reg, validation.ReasonRateLimited),
-		discardedRequestsRateLimited:      validation.DiscardedRequestsCounter(reg, validation.ReasonRateLimited),
-		discardedExemplarsRateLimited:     validation.DiscardedExemplarsCounter(reg, validation.ReasonRateLimited),
-		discardedMetadataRateLimited:      validation.DiscardedMetadataCounter(reg, validation.ReasonRateLimited),
+		discardedSamplesTooManyHaClusters:  validation.DiscardedSamplesCounter(reg, validation.ReasonTooManyHAClusters),
+		discardedSamplesRateLimited:        validation.DiscardedSamplesCounter(reg, validation.ReasonRateLimited),
+		discardedRequestsRateLimited:       validation.DiscardedRequestsCounter(reg, validation.ReasonRateLimited),
+		discardedRequestsSourceRateLimited: validation.DiscardedRequestsCounter(reg, validation.ReasonSourceRateLimited),
+		discardedExemplarsRateLimited:      validation.DiscardedExemplarsCounter(reg, validation.ReasonRateLimited),
+		discardedMetadataRateLimited:       validation.DiscardedMetadataCounter(reg, validation.ReasonRateLimited),
 
 		sampleValidationMetrics:   validation.NewSampleValidationMetrics(reg),
 		exemplarValidationMetrics: validation.NewExemplarValidationMetrics(reg),
@@ -393,16 +517,29 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	}, func() float64 {
 		return d.ingestionRate.Rate()
 	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_distributor_request_rate_limited_sources",
+		Help: "Current number of sources of write requests tracked by the per-source request rate limit.",
+	}, func() float64 {
+		return float64(d.sourceRequestRateLimiter.sources())
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_distributor_ingester_circuit_breakers_open",
+		Help: "Current number of ingesters whose circuit breaker of the write requests is open or half-open.",
+	}, func() float64 {
+		return float64(d.ingesterCircuitBreaker.openCount())
+	})
 
 	// Create the configured ingestion rate limit strategy (local or global). In case
 	// it's an internal dependency and we can't join the distributors ring, we skip rate
 	// limiting.
-	var ingestionRateStrategy, requestRateStrategy limiter.RateLimiterStrategy
+	var ingestionRateStrategy, requestRateStrategy, sourceRequestRateStrategy limiter.RateLimiterStrategy
 	var distributorsLifecycler *ring.BasicLifecycler
 	var distributorsRing *ring.Ring
 
 	if !canJoinDistributorsRing {
 		requestRateStrategy = newInfiniteRateStrategy()
+		sourceRequestRateStrategy = newInfiniteRateStrategy()
 		ingestionRateStrategy = newInfiniteRateStrategy()
 	} else {
 		distributorsRing, distributorsLifecycler, err = newRingAndLifecycler(cfg.DistributorRing, d.healthyInstancesCount, log, reg)
@@ -412,11 +549,13 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 
 		subservices = append(subservices, distributorsLifecycler, distributorsRing)
 		requestRateStrategy = newGlobalRateStrategy(newRequestRateStrategy(limits), d)
+		sourceRequestRateStrategy = newGlobalRateStrategy(newSourceRequestRateStrategy(limits), d)
 		ingestionRateStrategy = newGlobalRateStrategy(newIngestionRateStrategy(limits), d)
 	}
 
 	d.requestRateLimiter = limiter.NewRateLimiter(requestRateStrategy, 10*time.Second)
 	d.ingestionRateLimiter = limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second)
+	d.sourceRequestRateLimiter = newSourceRateLimiter(sourceRequestRateStrategy)
 	d.distributorsLifecycler = distributorsLifecycler
 	d.distributorsRing = distributorsRing
 
@@ -430,6 +569,18 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		subservices = append(subservices, d.forwarder)
 	}
 
+	// The request capture is an optional feature, if it's disabled then d.requestCapturer will be nil.
+	if cfg.RequestCaptureBucket != nil {
+		d.requestCapturer = capture.NewCapturer(cfg.RequestCapture, cfg.RequestCaptureBucket, reg, log)
+	}
+	if d.requestCapturer != nil {
+		subservices = append(subservices, d.requestCapturer)
+	}
+
+	if cfg.IngestStorageConfig.Enabled {
+		d.ingestStorageWriter = ingest.NewWriter(cfg.IngestStorageConfig.KafkaConfig, log, reg)
+	}
+
 	d.PushWithMiddlewares = d.wrapPushWithMiddlewares(d.push)
 
 	subservices = append(subservices, d.ingesterPool, d.activeUsers)
@@ -498,6 +649,12 @@ func (d *Distributor) running(ctx context.Context) error {
 	ingestionRateTicker := time.NewTicker(instanceIngestionRateTickInterval)
 	defer ingestionRateTicker.Stop()
 
+	sourceRateLimiterPurgeTicker := time.NewTicker(sourceRateLimiterPurgePeriod)
+	defer sourceRateLimiterPurgeTicker.Stop()
+
+	ingesterCircuitBreakerPurgeTicker := time.NewTicker(ingesterCircuitBreakerPurgePeriod)
+	defer ingesterCircuitBreakerPurgeTicker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -506,6 +663,12 @@ func (d *Distributor) running(ctx context.Context) error {
 		case <-ingestionRateTicker.C:
 			d.ingestionRate.Tick()
 
+		case <-sourceRateLimiterPurgeTicker.C:
+			d.sourceRequestRateLimiter.purge(time.Now().Add(-sourceRateLimiterIdleTimeout))
+
+		case <-ingesterCircuitBreakerPurgeTicker.C:
+			d.ingesterCircuitBreaker.purge(time.Now().Add(-ingesterCircuitBreakerIdleTimeout))
+
 		case err := <-d.subservicesWatcher.Chan():
 			return errors.Wrap(err, "distributor subservice failed")
 		}
@@ -533,6 +696,7 @@ func (d *Distributor) cleanupInactiveUser(userID string) {
 	d.discardedSamplesTooManyHaClusters.DeletePartialMatch(filter)
 	d.discardedSamplesRateLimited.DeletePartialMatch(filter)
 	d.discardedRequestsRateLimited.DeleteLabelValues(userID)
+	d.discardedRequestsSourceRateLimited.DeleteLabelValues(userID)
 	d.discardedExemplarsRateLimited.DeleteLabelValues(userID)
 	d.discardedMetadataRateLimited.DeleteLabelValues(userID)
 
@@ -554,6 +718,9 @@ func (d *Distributor) RemoveGroupMetricsForUser(userID, group string) {
 
 // Called after distributor is asked to stop via StopAsync.
 func (d *Distributor) stopping(_ error) error {
+	if d.ingestStorageWriter != nil {
+		defer d.ingestStorageWriter.Close()
+	}
 	return services.StopManagerAndAwaitStopped(context.Background(), d.subservices)
 }
 
@@ -646,28 +813,60 @@ func (d *Distributor) validateSeries(nowt time.Time, ts mimirpb.PreallocTimeseri
 
 	now := model.TimeFromUnixNano(nowt.UnixNano())
 
-	for _, s := range ts.Samples {
+	var (
+		clampedSamples  []int
+		sampleAtNowSeen bool
+	)
+	for i := range ts.Samples {
+		s := &ts.Samples[i]
 
 		delta := now - model.Time(s.TimestampMs)
 		if delta > 0 {
 			d.sampleDelayHistogram.Observe(float64(delta) / 1000)
 		}
 
-		if err := validation.ValidateSample(d.sampleValidationMetrics, now, d.limits, userID, group, ts.Labels, s); err != nil {
+		if s.TimestampMs == int64(now) {
+			sampleAtNowSeen = true
+		} else if clamped := validation.ClampSampleTimestamp(d.sampleValidationMetrics, now, d.limits, userID, group, s.TimestampMs); clamped != s.TimestampMs {
+			s.TimestampMs = clamped
+			clampedSamples = append(clampedSamples, i)
+		}
+
+		if err := validation.ValidateSample(d.sampleValidationMetrics, now, d.limits, userID, group, ts.Labels, *s); err != nil {
 			return err
 		}
 	}
+	ts.Samples = removeConflictingClamped(ts.Samples, clampedSamples, sampleAtNowSeen)
+
+	var (
+		clampedHistograms  []int
+		histogramAtNowSeen bool
+	)
+	for i := range ts.Histograms {
+		h := &ts.Histograms[i]
 
-	for _, h := range ts.Histograms {
 		delta := now - model.Time(h.Timestamp)
 		if delta > 0 {
 			d.sampleDelayHistogram.Observe(float64(delta) / 1000)
 		}
 
-		if err := validation.ValidateSampleHistogram(d.sampleValidationMetrics, now, d.limits, userID, group, ts.Labels, h); err != nil {
+		if h.Timestamp == int64(now) {
+			histogramAtNowSeen = true
+		} else if clamped := validation.ClampSampleTimestamp(d.sampleValidationMetrics, now, d.limits, userID, group, h.Timestamp); clamped != h.Timestamp {
+			h.Timestamp = clamped
+			clampedHistograms = append(clampedHistograms, i)
+		}
+
+		if err := validation.ValidateSampleHistogram(d.sampleValidationMetrics, now, d.limits, userID, group, ts.Labels, *h); err != nil {
 			return err
 		}
 	}
+	ts.Histograms = removeConflictingClamped(ts.Histograms, clampedHistograms, histogramAtNowSeen)
+
+	// The created timestamp is ingested only if enabled for the tenant and if it precedes the series samples.
+	if ts.CreatedTimestampMs != 0 && (!d.limits.CreatedTimestampsIngestionEnabled(userID) || !createdTimestampPrecedesSamples(ts.TimeSeries)) {
+		ts.CreatedTimestampMs = 0
+	}
 
 	if d.limits.MaxGlobalExemplarsPerUser(userID) == 0 {
 		mimirpb.ClearExemplars(ts.TimeSeries)
@@ -697,6 +896,48 @@ func (d *Distributor) validateSeries(nowt time.Time, ts mimirpb.PreallocTimeseri
 	return nil
 }
 
+// createdTimestampPrecedesSamples returns whether the created timestamp of the series is valid and precedes
+// all its samples and histograms, which is required to ingest it as a zero sample.
+func createdTimestampPrecedesSamples(ts *mimirpb.TimeSeries) bool {
+	if ts.CreatedTimestampMs <= 0 || (len(ts.Samples) == 0 && len(ts.Histograms) == 0) {
+		return false
+	}
+	for _, s := range ts.Samples {
+		if s.TimestampMs <= ts.CreatedTimestampMs {
+			return false
+		}
+	}
+	for _, h := range ts.Histograms {
+		if h.Timestamp <= ts.CreatedTimestampMs {
+			return false
+		}
+	}
+	return true
+}
+
+// removeConflictingClamped removes in-place the items at the input sorted indexes, whose timestamp has been
+// clamped to the current time, which would conflict in the ingesters because they share the same timestamp.
+// If an item was received at the current time, all the clamped items are removed, so that the received one
+// is kept. Otherwise, only the last clamped item is kept.
+func removeConflictingClamped[T any](items []T, clamped []int, itemAtNowReceived bool) []T {
+	if !itemAtNowReceived && len(clamped) > 0 {
+		clamped = clamped[:len(clamped)-1]
+	}
+	if len(clamped) == 0 {
+		return items
+	}
+
+	out := items[:0]
+	for i, item := range items {
+		if len(clamped) > 0 && clamped[0] == i {
+			clamped = clamped[1:]
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
 // wrapPushWithMiddlewares returns push function wrapped in all Distributor's middlewares.
 // push wrappers will be applied to incoming requests in the order in which they are in the slice in the config struct.
 func (d *Distributor) wrapPushWithMiddlewares(next push.Func) push.Func {
@@ -707,8 +948,12 @@ func (d *Distributor) wrapPushWithMiddlewares(next push.Func) push.Func {
 	// result from previous call.
 	middlewares = append(middlewares, d.limitsMiddleware) // should run first because it checks limits before other middlewares need to read the request body
 	middlewares = append(middlewares, d.metricsMiddleware)
+	if d.requestCapturer != nil {
+		middlewares = append(middlewares, d.requestCaptureMiddleware)
+	}
 	middlewares = append(middlewares, d.prePushHaDedupeMiddleware)
 	middlewares = append(middlewares, d.prePushRelabelMiddleware)
+	middlewares = append(middlewares, d.prePushSandboxMiddleware)
 	middlewares = append(middlewares, d.prePushValidationMiddleware)
 	middlewares = append(middlewares, d.prePushForwardingMiddleware)
 	middlewares = append(middlewares, d.cfg.PushWrappers...)
@@ -916,6 +1161,13 @@ func (d *Distributor) prePushValidationMiddleware(next push.Func) push.Func {
 
 		var firstPartialErr error
 		var removeIndexes []int
+
+		// The series having both float and native histogram samples are handled before being validated, given
+		// the native histogram samples may be moved to a separate series, which has to be validated too.
+		if d.limits.HistogramFloatConflictPolicy(userID) != "" && d.limits.NativeHistogramsIngestionEnabled(userID) {
+			req.Timeseries, firstPartialErr = d.resolveHistogramFloatConflicts(req.Timeseries, userID, group)
+		}
+
 		for tsIdx, ts := range req.Timeseries {
 			if len(ts.Labels) == 0 {
 				removeIndexes = append(removeIndexes, tsIdx)
@@ -998,6 +1250,59 @@ func (d *Distributor) prePushValidationMiddleware(next push.Func) push.Func {
 	}
 }
 
+// resolveHistogramFloatConflicts applies the per-tenant policy to the series having both float and native histogram
+// samples. It returns the input series without the rejected ones, followed by the series the native histogram samples
+// have been moved to, if any, and the error of the first rejected series.
+func (d *Distributor) resolveHistogramFloatConflicts(timeseries []mimirpb.PreallocTimeseries, userID, group string) ([]mimirpb.PreallocTimeseries, error) {
+	var (
+		firstErr      error
+		removeIndexes []int
+		splitSeries   []mimirpb.PreallocTimeseries
+	)
+
+	for tsIdx, ts := range timeseries {
+		split, err := validation.ResolveHistogramFloatConflict(d.sampleValidationMetrics, d.limits, userID, group, ts.TimeSeries)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = httpgrpc.Errorf(http.StatusBadRequest, err.Error())
+			}
+			removeIndexes = append(removeIndexes, tsIdx)
+			continue
+		}
+		if split {
+			splitSeries = append(splitSeries, splitHistogramSeries(ts))
+		}
+	}
+
+	if len(removeIndexes) > 0 {
+		for _, removeIndex := range removeIndexes {
+			mimirpb.ReusePreallocTimeseries(&timeseries[removeIndex])
+		}
+		timeseries = util.RemoveSliceIndexes(timeseries, removeIndexes)
+	}
+
+	return append(timeseries, splitSeries...), firstErr
+}
+
+// splitHistogramSeries moves the native histogram samples of the input series to a new series, whose metric name
+// is suffixed with validation.HistogramFloatConflictSeriesSuffix. The new series keeps the created timestamp of the
+// input series, while the exemplars are kept in the input series only.
+func splitHistogramSeries(ts mimirpb.PreallocTimeseries) mimirpb.PreallocTimeseries {
+	split := mimirpb.PreallocTimeseries{TimeSeries: mimirpb.TimeseriesFromPool()}
+
+	for _, l := range ts.Labels {
+		if l.Name == labels.MetricName {
+			l.Value += validation.HistogramFloatConflictSeriesSuffix
+		}
+		split.Labels = append(split.Labels, l)
+	}
+	split.Histograms = append(split.Histograms, ts.Histograms...)
+	split.CreatedTimestampMs = ts.CreatedTimestampMs
+
+	ts.Histograms = ts.Histograms[:0]
+	return split
+}
+
 // prePushForwardingMiddleware is used as push.Func middleware in front of push method.
 // It forwards time series to configured remote_write endpoints if the forwarding rules say so.
 func (d *Distributor) prePushForwardingMiddleware(next push.Func) push.Func {
@@ -1079,6 +1384,33 @@ func (d *Distributor) metricsMiddleware(next push.Func) push.Func {
 	}
 }
 
+// requestCaptureMiddleware captures a sample of the push requests, as received, to the bucket.
+func (d *Distributor) requestCaptureMiddleware(next push.Func) push.Func {
+	return func(ctx context.Context, pushReq *push.Request) (*mimirpb.WriteResponse, error) {
+		cleanupInDefer := true
+		defer func() {
+			if cleanupInDefer {
+				pushReq.CleanUp()
+			}
+		}()
+
+		req, err := pushReq.WriteRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		d.requestCapturer.Capture(userID, req)
+
+		cleanupInDefer = false
+		return next(ctx, pushReq)
+	}
+}
+
 // limitsMiddleware checks for instance limits and rejects request if this instance cannot process it at the moment.
 func (d *Distributor) limitsMiddleware(next push.Func) push.Func {
 	return func(ctx context.Context, pushReq *push.Request) (*mimirpb.WriteResponse, error) {
@@ -1113,6 +1445,14 @@ func (d *Distributor) limitsMiddleware(next push.Func) push.Func {
 		}
 
 		now := mtime.Now()
+
+		// The per-source limit is checked first, so that the requests of a source exceeding
+		// its limit don't consume the rate limit of the whole tenant.
+		if source := d.requestSource(userID, pushReq); source != "" && !d.sourceRequestRateLimiter.AllowN(now, userID, source, 1) {
+			d.discardedRequestsSourceRateLimited.WithLabelValues(userID).Add(1)
+			return nil, httpgrpc.Errorf(http.StatusTooManyRequests, validation.NewSourceRequestRateLimitedError(d.limits.RequestRatePerSource(userID), d.limits.RequestBurstSizePerSource(userID)).Error())
+		}
+
 		if !d.requestRateLimiter.AllowN(now, userID, 1) {
 			d.discardedRequestsRateLimited.WithLabelValues(userID).Add(1)
 
@@ -1141,6 +1481,22 @@ func (d *Distributor) limitsMiddleware(next push.Func) push.Func {
 	}
 }
 
+// requestSource returns the source of the push request for the per-source request rate limit: the source
+// address of the request, along with the value of the configured request header if any. The header value
+// is scoped to the source address, so that a client can't consume the limit of the other clients by sending
+// their header value. It returns an empty string if the configured header is missing.
+func (d *Distributor) requestSource(userID string, pushReq *push.Request) string {
+	source := pushReq.SourceAddress()
+	if header := d.limits.RequestRateSourceHeader(userID); header != "" {
+		value := pushReq.Header(header)
+		if value == "" {
+			return ""
+		}
+		source += "/" + value
+	}
+	return source
+}
+
 func (d *Distributor) forwardSamples(ctx context.Context, userID string, ts []mimirpb.PreallocTimeseries) ([]mimirpb.PreallocTimeseries, <-chan error) {
 	forwardingErrCh := make(chan error)
 	forwardingRules := d.limits.ForwardingRules(userID)
@@ -1207,15 +1563,37 @@ func (d *Distributor) push(ctx context.Context, pushReq *push.Request) (*mimirpb
 		span.SetTag("organization", userID)
 	}
 
-	seriesKeys := d.getTokensForSeries(userID, req.Timeseries)
+	// The request is cleaned up asynchronously once pushed to the ingesters, so count what's written beforehand.
+	var writtenSamples, writtenHistograms, writtenExemplars int
+	for _, ts := range req.Timeseries {
+		writtenSamples += len(ts.Samples)
+		writtenHistograms += len(ts.Histograms)
+		writtenExemplars += len(ts.Exemplars)
+	}
+
+	timeseries, aggregationInputs, aggregationInputKeys := d.splitIngestAggregationInputs(userID, req.Timeseries)
+	seriesKeys := d.getTokensForSeries(userID, timeseries)
 	metadataKeys := make([]uint32, 0, len(req.Metadata))
 
 	for _, m := range req.Metadata {
 		metadataKeys = append(metadataKeys, d.tokenForMetadata(userID, m.MetricFamilyName))
 	}
 
+	if d.ingestStorageWriter != nil {
+		if err := d.pushToIngestStorage(ctx, userID, req, timeseries, aggregationInputs, seriesKeys, aggregationInputKeys, metadataKeys); err != nil {
+			return nil, err
+		}
+		pushReq.SetWritten(writtenSamples, writtenHistograms, writtenExemplars)
+		return &mimirpb.WriteResponse{}, nil
+	}
+
 	// Get a subring if tenant has shuffle shard size configured.
-	subRing := d.ingestersRing.ShuffleShard(userID, d.limits.IngestionTenantShardSize(userID))
+	subRing := d.tenantIngestersRing(userID).ShuffleShard(userID, d.limits.IngestionTenantShardSize(userID))
+
+	if d.ingestersPressure.shouldReject(subRing, time.Now()) {
+		d.ingestersPressureRejected.Inc()
+		return nil, errIngestersPressure
+	}
 
 	// Use a background context to make sure all ingesters get samples even if we return early
 	localCtx, cancel := context.WithTimeout(context.Background(), d.cfg.RemoteTimeout)
@@ -1227,10 +1605,12 @@ func (d *Distributor) push(ctx context.Context, pushReq *push.Request) (*mimirpb
 		localCtx = opentracing.ContextWithSpan(localCtx, sp)
 	}
 
-	// All tokens, stored in order: series, metadata.
-	keys := make([]uint32, len(seriesKeys)+len(metadataKeys))
-	initialMetadataIndex := len(seriesKeys)
+	// All tokens, stored in order: series, ingest aggregation inputs, metadata.
+	keys := make([]uint32, len(seriesKeys)+len(aggregationInputKeys)+len(metadataKeys))
+	initialAggregationInputIndex := len(seriesKeys)
+	initialMetadataIndex := initialAggregationInputIndex + len(aggregationInputKeys)
 	copy(keys, seriesKeys)
+	copy(keys[initialAggregationInputIndex:], aggregationInputKeys)
 	copy(keys[initialMetadataIndex:], metadataKeys)
 
 	// we must not re-use buffers now until all DoBatch goroutines have finished,
@@ -1238,27 +1618,32 @@ func (d *Distributor) push(ctx context.Context, pushReq *push.Request) (*mimirpb
 	cleanupInDefer = false
 
 	err = ring.DoBatch(ctx, ring.WriteNoExtend, subRing, keys, func(ingester ring.InstanceDesc, indexes []int) error {
-		var timeseriesCount, metadataCount int
+		var timeseriesCount, aggregationInputCount, metadataCount int
 		for _, i := range indexes {
 			if i >= initialMetadataIndex {
 				metadataCount++
+			} else if i >= initialAggregationInputIndex {
+				aggregationInputCount++
 			} else {
 				timeseriesCount++
 			}
 		}
 
-		timeseries := preallocSliceIfNeeded[mimirpb.PreallocTimeseries](timeseriesCount)
+		ingesterTimeseries := preallocSliceIfNeeded[mimirpb.PreallocTimeseries](timeseriesCount)
+		ingesterAggregationInputs := preallocSliceIfNeeded[mimirpb.PreallocTimeseries](aggregationInputCount)
 		metadata := preallocSliceIfNeeded[*mimirpb.MetricMetadata](metadataCount)
 
 		for _, i := range indexes {
 			if i >= initialMetadataIndex {
 				metadata = append(metadata, req.Metadata[i-initialMetadataIndex])
+			} else if i >= initialAggregationInputIndex {
+				ingesterAggregationInputs = append(ingesterAggregationInputs, aggregationInputs[i-initialAggregationInputIndex])
 			} else {
-				timeseries = append(timeseries, req.Timeseries[i])
+				ingesterTimeseries = append(ingesterTimeseries, timeseries[i])
 			}
 		}
 
-		err := d.send(localCtx, ingester, timeseries, metadata, req.Source)
+		err := d.send(localCtx, ingester, ingesterTimeseries, ingesterAggregationInputs, metadata, req.Source)
 		if errors.Is(err, context.DeadlineExceeded) {
 			return httpgrpc.Errorf(500, "exceeded configured distributor remote timeout: %s", err.Error())
 		}
@@ -1268,6 +1653,7 @@ func (d *Distributor) push(ctx context.Context, pushReq *push.Request) (*mimirpb
 	if err != nil {
 		return nil, err
 	}
+	pushReq.SetWritten(writtenSamples, writtenHistograms, writtenExemplars)
 	return &mimirpb.WriteResponse{}, nil
 }
 
@@ -1290,6 +1676,48 @@ func (d *Distributor) getTokensForSeries(userID string, series []mimirpb.Preallo
 	return result
 }
 
+// splitIngestAggregationInputs returns the series to ingest, and the series rolled up by the tenant's ingest
+// aggregation rules along with their tokens. The series to ingest exclude the series rolled up by the rules
+// dropping their input. The rolled up series are sharded by the labels of the aggregated series, so that all
+// the series aggregated together are sent to the same ingesters. Series with native histograms are not aggregated.
+func (d *Distributor) splitIngestAggregationInputs(userID string, series []mimirpb.PreallocTimeseries) (ingested, inputs []mimirpb.PreallocTimeseries, inputKeys []uint32) {
+	rules, err := d.ingestAggregationRules.Get(userID, d.limits.IngestAggregationRules(userID))
+	if err != nil {
+		level.Warn(d.log).Log("msg", "failed to compile ingest aggregation rules", "user", userID, "err", err)
+		return series, nil, nil
+	}
+	if len(rules) == 0 {
+		return series, nil, nil
+	}
+
+	var filtered []mimirpb.PreallocTimeseries
+	for idx, ts := range series {
+		rule := ingestaggregation.Match(rules, ts.Labels)
+		if rule == nil || len(ts.Histograms) > 0 {
+			if filtered != nil {
+				filtered = append(filtered, ts)
+			}
+			continue
+		}
+
+		inputs = append(inputs, ts)
+		inputKeys = append(inputKeys, d.tokenForLabels(userID, rule.OutputLabels(ts.Labels)))
+
+		if rule.DropInput() && filtered == nil {
+			// Lazily allocate the filtered series only when the first series is dropped.
+			filtered = make([]mimirpb.PreallocTimeseries, 0, len(series))
+			filtered = append(filtered, series[:idx]...)
+		} else if !rule.DropInput() && filtered != nil {
+			filtered = append(filtered, ts)
+		}
+	}
+
+	if filtered == nil {
+		return series, inputs, inputKeys
+	}
+	return filtered, inputs, inputKeys
+}
+
 func (d *Distributor) updateReceivedMetrics(req *mimirpb.WriteRequest, userID string) {
 	var receivedSamples, receivedExemplars, receivedMetadata int
 	for _, ts := range req.Timeseries {
@@ -1329,19 +1757,34 @@ func sortLabelsIfNeeded(labels []mimirpb.LabelAdapter) {
 	})
 }
 
-func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, timeseries []mimirpb.PreallocTimeseries, metadata []*mimirpb.MetricMetadata, source mimirpb.WriteRequest_SourceEnum) error {
+func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, timeseries, aggregationInputs []mimirpb.PreallocTimeseries, metadata []*mimirpb.MetricMetadata, source mimirpb.WriteRequest_SourceEnum) error {
 	h, err := d.ingesterPool.GetClientFor(ingester.Addr)
 	if err != nil {
 		return err
 	}
 	c := h.(ingester_client.IngesterClient)
 
+	// Fail fast if the ingester is degraded: the quorum is reached as long as the other replicas succeed.
+	if !d.ingesterCircuitBreaker.allow(ingester.Addr, time.Now()) {
+		d.ingesterCircuitBreakerRejected.Inc()
+		return errIngesterCircuitBreakerOpen
+	}
+
 	req := mimirpb.WriteRequest{
-		Timeseries: timeseries,
-		Metadata:   metadata,
-		Source:     source,
+		Timeseries:              timeseries,
+		Metadata:                metadata,
+		Source:                  source,
+		IngestAggregationInputs: aggregationInputs,
+	}
+	start := time.Now()
+	pushResp, err := c.Push(ctx, &req)
+	if err == nil {
+		d.ingestersPressure.observe(ingester.Addr, pushResp.GetPressure(), time.Now())
+	}
+	if d.ingesterCircuitBreaker.record(ingester.Addr, err, time.Since(start), time.Now()) {
+		d.ingesterCircuitBreakerOpened.Inc()
+		level.Warn(d.log).Log("msg", "opened the circuit breaker of the write requests to the ingester", "ingester", ingester.Addr)
 	}
-	_, err = c.Push(ctx, &req)
 	if resp, ok := httpgrpc.HTTPResponseFromError(err); ok {
 		// Wrap HTTP gRPC error with more explanatory message.
 		return httpgrpc.Errorf(int(resp.Code), "failed pushing to ingester: %s", resp.Body)
@@ -1351,7 +1794,7 @@ func (d *Distributor) send(ctx context.Context, ingester ring.InstanceDesc, time
 
 // forReplicationSet runs f, in parallel, for all ingesters in the input replication set.
 func (d *Distributor) forReplicationSet(ctx context.Context, replicationSet ring.ReplicationSet, f func(context.Context, ingester_client.IngesterClient) (interface{}, error)) ([]interface{}, error) {
-	return replicationSet.Do(ctx, 0, func(ctx context.Context, ing *ring.InstanceDesc) (interface{}, error) {
+	return d.doWithHedging(ctx, replicationSet, func(ctx context.Context, ing *ring.InstanceDesc) (interface{}, error) {
 		client, err := d.ingesterPool.GetClientFor(ing.Addr)
 		if err != nil {
 			return nil, err
@@ -1546,6 +1989,11 @@ func (d *Distributor) LabelValuesCardinality(ctx context.Context, labelNames []m
 // labelValuesCardinality queries ingesters for label values cardinality of a set of labelNames
 // Returns a LabelValuesCardinalityResponse where each item contains an exclusive label name and associated label values
 func (d *Distributor) labelValuesCardinality(ctx context.Context, labelNames []model.LabelName, matchers []*labels.Matcher) (*ingester_client.LabelValuesCardinalityResponse, error) {
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	replicationSet, err := d.GetIngesters(ctx)
 	if err != nil {
 		return nil, err
@@ -1576,7 +2024,7 @@ func (d *Distributor) labelValuesCardinality(ctx context.Context, labelNames []m
 	if err != nil {
 		return nil, err
 	}
-	return cardinalityConcurrentMap.toLabelValuesCardinalityResponse(d.ingestersRing.ReplicationFactor()), nil
+	return cardinalityConcurrentMap.toLabelValuesCardinalityResponse(d.tenantQueriedReplicationFactor(userID)), nil
 }
 
 func toLabelValuesCardinalityRequest(labelNames []model.LabelName, matchers []*labels.Matcher) (*ingester_client.LabelValuesCardinalityRequest, error) {
@@ -1773,6 +2221,11 @@ func (d *Distributor) MetricsMetadata(ctx context.Context) ([]scrape.MetricMetad
 
 // UserStats returns statistics about the current user.
 func (d *Distributor) UserStats(ctx context.Context) (*UserStats, error) {
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	replicationSet, err := d.GetIngesters(ctx)
 	if err != nil {
 		return nil, err
@@ -1799,8 +2252,9 @@ func (d *Distributor) UserStats(ctx context.Context) (*UserStats, error) {
 		totalStats.NumSeries += r.NumSeries
 	}
 
-	totalStats.IngestionRate /= float64(d.ingestersRing.ReplicationFactor())
-	totalStats.NumSeries /= uint64(d.ingestersRing.ReplicationFactor())
+	replicationFactor := d.tenantQueriedReplicationFactor(userID)
+	totalStats.IngestionRate /= float64(replicationFactor)
+	totalStats.NumSeries /= uint64(replicationFactor)
 
 	return totalStats, nil
 }
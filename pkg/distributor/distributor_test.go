@@ -36,6 +36,7 @@ import (
 	"github.com/prometheus/prometheus/model/relabel"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/common/user"
@@ -46,13 +47,17 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 
+	"github.com/grafana/mimir/pkg/distributor/capture"
 	"github.com/grafana/mimir/pkg/distributor/forwarding"
 	"github.com/grafana/mimir/pkg/ingester"
 	"github.com/grafana/mimir/pkg/ingester/client"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/sandbox"
+	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/chunk"
 	"github.com/grafana/mimir/pkg/util/chunkcompat"
 	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/ingestaggregation"
 	"github.com/grafana/mimir/pkg/util/limiter"
 	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/push"
@@ -331,6 +336,47 @@ func TestDistributor_Push(t *testing.T) {
 	}
 }
 
+func TestDistributor_Push_ShouldCaptureRequests(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	ds, _, regs := prepare(t, prepConfig{
+		numIngesters:         3,
+		happyIngesters:       3,
+		numDistributors:      1,
+		requestCaptureBucket: bkt,
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "user")
+	request := makeWriteRequest(123456789000, 2, 0, false, false)
+	_, err := ds[0].Push(ctx, request)
+	require.NoError(t, err)
+
+	test.Poll(t, 5*time.Second, 1, func() interface{} {
+		return len(bkt.Objects())
+	})
+
+	for name, data := range bkt.Objects() {
+		userID, _, err := capture.ParseObjectName(strings.TrimPrefix(name, bucket.MimirInternalsPrefix+"/"))
+		require.NoError(t, err)
+		assert.Equal(t, "user", userID)
+
+		captured, err := capture.DecodeRequest(data)
+		require.NoError(t, err)
+
+		expected := makeWriteRequest(123456789000, 2, 0, false, false)
+		require.Len(t, captured.Timeseries, len(expected.Timeseries))
+		for i, series := range expected.Timeseries {
+			assert.Equal(t, series.Labels, captured.Timeseries[i].Labels)
+			assert.Equal(t, series.Samples, captured.Timeseries[i].Samples)
+		}
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_distributor_request_capture_captured_total The total number of push requests captured to the bucket.
+		# TYPE cortex_distributor_request_capture_captured_total counter
+		cortex_distributor_request_capture_captured_total 1
+	`), "cortex_distributor_request_capture_captured_total"))
+}
+
 func TestDistributor_ContextCanceledRequest(t *testing.T) {
 	now := time.Now()
 	mtime.NowForce(now)
@@ -1105,6 +1151,107 @@ func TestDistributor_QueryStream_ShouldReturnErrorIfMaxChunksPerQueryLimitIsReac
 	assert.ErrorContains(t, err, "the query exceeded the maximum number of chunks")
 }
 
+func TestDistributor_IngestionReplicationFactor(t *testing.T) {
+	const numSeries = 10
+
+	allSeriesMatchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchRegexp, model.MetricNameLabel, ".+"),
+	}
+
+	tests := map[string]struct {
+		tenantReplicationFactor   int
+		happyIngesters            int
+		ingesterZones             []string
+		expectedReplicationFactor int
+		expectedQueryErr          bool
+	}{
+		"should use the ring replication factor by default": {
+			tenantReplicationFactor:   0,
+			happyIngesters:            3,
+			expectedReplicationFactor: 3,
+		},
+		"should use the ring replication factor if the tenant replication factor is greater": {
+			tenantReplicationFactor:   5,
+			happyIngesters:            3,
+			expectedReplicationFactor: 3,
+		},
+		"should write to fewer ingesters if the tenant replication factor is lower": {
+			tenantReplicationFactor:   1,
+			happyIngesters:            3,
+			expectedReplicationFactor: 1,
+		},
+		"should write to fewer ingesters if the tenant replication factor is lower and zone-awareness is enabled": {
+			tenantReplicationFactor:   2,
+			happyIngesters:            3,
+			ingesterZones:             []string{"zone-a", "zone-b", "zone-c"},
+			expectedReplicationFactor: 2,
+		},
+		"should not tolerate a failing ingester on the read path if the tenant replication factor is 1": {
+			tenantReplicationFactor: 1,
+			happyIngesters:          2,
+			expectedQueryErr:        true,
+		},
+		"should not tolerate a failing zone on the read path if the tenant replication factor is 1 and zone-awareness is enabled": {
+			tenantReplicationFactor: 1,
+			happyIngesters:          2,
+			ingesterZones:           []string{"zone-a", "zone-b", "zone-c"},
+			expectedQueryErr:        true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "user")
+
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.IngestionReplicationFactor = testData.tenantReplicationFactor
+
+			ds, ingesters, _ := prepare(t, prepConfig{
+				numIngesters:    3,
+				happyIngesters:  testData.happyIngesters,
+				ingesterZones:   testData.ingesterZones,
+				numDistributors: 1,
+				limits:          limits,
+			})
+
+			if testData.expectedQueryErr {
+				_, err := ds[0].QueryStream(ctx, math.MinInt32, math.MaxInt32, allSeriesMatchers...)
+				require.Error(t, err)
+				return
+			}
+
+			_, err := ds[0].Push(ctx, makeWriteRequest(0, numSeries, 0, false, false))
+			require.NoError(t, err)
+
+			// Each series is expected to be written to a number of ingesters equal to the replication factor.
+			// The push returns once the quorum is reached, so we wait until all writes have completed.
+			replicas := map[uint32]int{}
+			test.Poll(t, time.Second, numSeries*testData.expectedReplicationFactor, func() interface{} {
+				replicas = map[uint32]int{}
+				total := 0
+				for i := range ingesters {
+					for token := range ingesters[i].series() {
+						replicas[token]++
+						total++
+					}
+				}
+				return total
+			})
+
+			require.Len(t, replicas, numSeries)
+			for _, count := range replicas {
+				assert.Equal(t, testData.expectedReplicationFactor, count)
+			}
+
+			// All series are expected to be queried back.
+			res, err := ds[0].QueryStream(ctx, math.MinInt32, math.MaxInt32, allSeriesMatchers...)
+			require.NoError(t, err)
+			assert.Len(t, res.Chunkseries, numSeries)
+		})
+	}
+}
+
 func TestDistributor_QueryStream_ShouldReturnErrorIfMaxSeriesPerQueryLimitIsReached(t *testing.T) {
 	const maxSeriesLimit = 10
 
@@ -1223,6 +1370,49 @@ func TestDistributor_QueryStream_ShouldReturnErrorIfMaxChunkBytesPerQueryLimitIs
 	assert.ErrorContains(t, err, fmt.Sprintf(limiter.MaxChunkBytesHitMsgFormat, maxBytesLimit))
 }
 
+func TestDistributor_QueryStream_ShouldReturnErrorIfMaxEstimatedMemoryPerQueryLimitIsReached(t *testing.T) {
+	const seriesToAdd = 10
+
+	ctx := user.InjectOrgID(context.Background(), "user")
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+
+	// Use replication factor of 1 so that we always wait the response from all ingesters.
+	ds, _, _ := prepare(t, prepConfig{
+		numIngesters:      3,
+		happyIngesters:    3,
+		numDistributors:   1,
+		limits:            limits,
+		replicationFactor: 1,
+	})
+
+	allSeriesMatchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchRegexp, model.MetricNameLabel, ".+"),
+	}
+
+	writeReq := makeWriteRequest(0, seriesToAdd, 0, false, false)
+	writeRes, err := ds[0].Push(ctx, writeReq)
+	assert.Equal(t, &mimirpb.WriteResponse{}, writeRes)
+	assert.Nil(t, err)
+
+	// Measure the estimated memory of the query running on all series.
+	tracker := limiter.NewMemoryTracker(0)
+	queryRes, err := ds[0].QueryStream(limiter.AddMemoryTrackerToContext(ctx, tracker), math.MinInt32, math.MaxInt32, allSeriesMatchers...)
+	require.NoError(t, err)
+	assert.Len(t, queryRes.Chunkseries, seriesToAdd)
+	require.Greater(t, tracker.Bytes(), int64(0))
+
+	// Since the estimated memory is equal to the limit (but doesn't exceed it), we expect the query to succeed.
+	maxBytesLimit := int(tracker.Bytes())
+	_, err = ds[0].QueryStream(limiter.AddMemoryTrackerToContext(ctx, limiter.NewMemoryTracker(maxBytesLimit)), math.MinInt32, math.MaxInt32, allSeriesMatchers...)
+	require.NoError(t, err)
+
+	// Since the estimated memory is exceeding the limit, we expect the query to fail.
+	_, err = ds[0].QueryStream(limiter.AddMemoryTrackerToContext(ctx, limiter.NewMemoryTracker(maxBytesLimit-1)), math.MinInt32, math.MaxInt32, allSeriesMatchers...)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, fmt.Sprintf(limiter.MaxEstimatedMemoryPerQueryMsgFormat, maxBytesLimit-1))
+}
+
 func TestDistributor_Push_LabelRemoval(t *testing.T) {
 	ctx := user.InjectOrgID(context.Background(), "user")
 
@@ -1678,6 +1868,287 @@ func TestDistributor_ExemplarValidation(t *testing.T) {
 	}
 }
 
+func TestDistributor_FutureTimestampsClamping(t *testing.T) {
+	now := time.Now()
+	nowMs := now.UnixMilli()
+	seriesLabels := []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "test"}}
+
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+	limits.CreationGracePeriod = model.Duration(time.Minute)
+	limits.FutureTimestampsClampWindow = model.Duration(time.Hour)
+
+	ds, _, _ := prepare(t, prepConfig{
+		limits:          limits,
+		numDistributors: 1,
+	})
+
+	t.Run("should clamp the samples within the clamp window, keeping only the latest one", func(t *testing.T) {
+		ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels: seriesLabels,
+			Samples: []mimirpb.Sample{
+				{TimestampMs: nowMs - 1000, Value: 1},
+				{TimestampMs: nowMs + 10*time.Minute.Milliseconds(), Value: 2},
+				{TimestampMs: nowMs + 20*time.Minute.Milliseconds(), Value: 3},
+			},
+		}}
+
+		require.NoError(t, ds[0].validateSeries(now, ts, "user", "test-group", false, 0))
+		assert.Equal(t, []mimirpb.Sample{
+			{TimestampMs: nowMs - 1000, Value: 1},
+			{TimestampMs: nowMs, Value: 3},
+		}, ts.Samples)
+	})
+
+	t.Run("should keep the sample received at the current time over the clamped ones", func(t *testing.T) {
+		ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels: seriesLabels,
+			Samples: []mimirpb.Sample{
+				{TimestampMs: nowMs, Value: 1},
+				{TimestampMs: nowMs + 10*time.Minute.Milliseconds(), Value: 2},
+				{TimestampMs: nowMs + 20*time.Minute.Milliseconds(), Value: 3},
+			},
+		}}
+
+		require.NoError(t, ds[0].validateSeries(now, ts, "user", "test-group", false, 0))
+		assert.Equal(t, []mimirpb.Sample{{TimestampMs: nowMs, Value: 1}}, ts.Samples)
+	})
+
+	t.Run("should clamp the histograms within the clamp window", func(t *testing.T) {
+		ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:     seriesLabels,
+			Histograms: []mimirpb.Histogram{mimirpb.FromHistogramToHistogramProto(nowMs+10*time.Minute.Milliseconds(), generateTestHistogram(0))},
+		}}
+
+		require.NoError(t, ds[0].validateSeries(now, ts, "user", "test-group", false, 0))
+		require.Len(t, ts.Histograms, 1)
+		assert.Equal(t, nowMs, ts.Histograms[0].Timestamp)
+	})
+
+	t.Run("should reject the samples beyond the clamp window", func(t *testing.T) {
+		ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  seriesLabels,
+			Samples: []mimirpb.Sample{{TimestampMs: nowMs + 2*time.Hour.Milliseconds(), Value: 1}},
+		}}
+
+		err := ds[0].validateSeries(now, ts, "user", "test-group", false, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), string(globalerror.SampleTooFarInFuture))
+	})
+
+	t.Run("should not clamp the samples if the creation grace period is disabled", func(t *testing.T) {
+		limits := &validation.Limits{}
+		flagext.DefaultValues(limits)
+		limits.CreationGracePeriod = 0
+		limits.FutureTimestampsClampWindow = model.Duration(time.Hour)
+
+		ds, _, _ := prepare(t, prepConfig{
+			limits:          limits,
+			numDistributors: 1,
+		})
+
+		ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  seriesLabels,
+			Samples: []mimirpb.Sample{{TimestampMs: nowMs + 10*time.Minute.Milliseconds(), Value: 1}},
+		}}
+
+		err := ds[0].validateSeries(now, ts, "user", "test-group", false, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), string(globalerror.SampleTooFarInFuture))
+		assert.Equal(t, nowMs+10*time.Minute.Milliseconds(), ts.Samples[0].TimestampMs)
+	})
+}
+
+func TestDistributor_HistogramFloatConflicts(t *testing.T) {
+	nowMs := time.Now().UnixMilli()
+	histogram := mimirpb.FromHistogramToHistogramProto(nowMs, generateTestHistogram(0))
+
+	makeSeries := func() []mimirpb.PreallocTimeseries {
+		return []mimirpb.PreallocTimeseries{
+			{TimeSeries: &mimirpb.TimeSeries{
+				Labels:     []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "conflicting"}, {Name: "job", Value: "test"}},
+				Samples:    []mimirpb.Sample{{TimestampMs: nowMs - 1000, Value: 1}},
+				Histograms: []mimirpb.Histogram{histogram},
+			}},
+			{TimeSeries: &mimirpb.TimeSeries{
+				Labels:  []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "floats"}},
+				Samples: []mimirpb.Sample{{TimestampMs: nowMs, Value: 2}},
+			}},
+		}
+	}
+
+	tests := map[string]struct {
+		policy            string
+		expectedSeries    []mimirpb.PreallocTimeseries
+		expectedErr       bool
+		expectedDiscarded int
+	}{
+		"reject": {
+			policy: validation.HistogramFloatConflictPolicyReject,
+			expectedSeries: []mimirpb.PreallocTimeseries{
+				makeSeries()[1],
+			},
+			expectedErr:       true,
+			expectedDiscarded: 2,
+		},
+		"prefer-histogram": {
+			policy: validation.HistogramFloatConflictPolicyPreferHistogram,
+			expectedSeries: []mimirpb.PreallocTimeseries{
+				{TimeSeries: &mimirpb.TimeSeries{
+					Labels:     []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "conflicting"}, {Name: "job", Value: "test"}},
+					Samples:    []mimirpb.Sample{},
+					Histograms: []mimirpb.Histogram{histogram},
+				}},
+				makeSeries()[1],
+			},
+			expectedDiscarded: 1,
+		},
+		"prefer-float": {
+			policy: validation.HistogramFloatConflictPolicyPreferFloat,
+			expectedSeries: []mimirpb.PreallocTimeseries{
+				{TimeSeries: &mimirpb.TimeSeries{
+					Labels:     []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "conflicting"}, {Name: "job", Value: "test"}},
+					Samples:    []mimirpb.Sample{{TimestampMs: nowMs - 1000, Value: 1}},
+					Histograms: []mimirpb.Histogram{},
+				}},
+				makeSeries()[1],
+			},
+			expectedDiscarded: 1,
+		},
+		"split-series-with-suffix": {
+			policy: validation.HistogramFloatConflictPolicySplitSeries,
+			expectedSeries: []mimirpb.PreallocTimeseries{
+				{TimeSeries: &mimirpb.TimeSeries{
+					Labels:     []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "conflicting"}, {Name: "job", Value: "test"}},
+					Samples:    []mimirpb.Sample{{TimestampMs: nowMs - 1000, Value: 1}},
+					Histograms: []mimirpb.Histogram{},
+				}},
+				makeSeries()[1],
+				{TimeSeries: &mimirpb.TimeSeries{
+					Labels:     []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "conflicting_histogram"}, {Name: "job", Value: "test"}},
+					Samples:    []mimirpb.Sample{},
+					Histograms: []mimirpb.Histogram{histogram},
+				}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.NativeHistogramsIngestionEnabled = true
+			limits.HistogramFloatConflictPolicy = tc.policy
+
+			ds, _, regs := prepare(t, prepConfig{
+				limits:          limits,
+				numDistributors: 1,
+			})
+
+			series, err := ds[0].resolveHistogramFloatConflicts(makeSeries(), "user", "")
+			if tc.expectedErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), string(globalerror.SeriesHistogramFloatConflict))
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, series, len(tc.expectedSeries))
+			for i, expected := range tc.expectedSeries {
+				assert.Equal(t, expected.Labels, series[i].Labels)
+
+				// The series slices come from pools, so an empty slice may be either nil or not.
+				if len(expected.Samples) == 0 {
+					assert.Empty(t, series[i].Samples)
+				} else {
+					assert.Equal(t, expected.Samples, series[i].Samples)
+				}
+				if len(expected.Histograms) == 0 {
+					assert.Empty(t, series[i].Histograms)
+				} else {
+					assert.Equal(t, expected.Histograms, series[i].Histograms)
+				}
+			}
+
+			expectedMetrics := `
+				# HELP cortex_distributor_histogram_float_conflicting_series_total The total number of series received with both float and native histogram samples, handled according to the per-tenant policy.
+				# TYPE cortex_distributor_histogram_float_conflicting_series_total counter
+				cortex_distributor_histogram_float_conflicting_series_total{group="",user="user"} 1
+			`
+			if tc.expectedDiscarded > 0 {
+				expectedMetrics += fmt.Sprintf(`
+				# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+				# TYPE cortex_discarded_samples_total counter
+				cortex_discarded_samples_total{group="",reason="histogram_float_conflict",user="user"} %d
+			`, tc.expectedDiscarded)
+			}
+			assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(expectedMetrics), "cortex_distributor_histogram_float_conflicting_series_total", "cortex_discarded_samples_total"))
+		})
+	}
+}
+
+func TestDistributor_CreatedTimestampsSanitization(t *testing.T) {
+	now := time.Now()
+	nowMs := now.UnixMilli()
+	seriesLabels := []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "test"}}
+
+	tests := map[string]struct {
+		enabled                    bool
+		series                     *mimirpb.TimeSeries
+		expectedCreatedTimestampMs int64
+	}{
+		"should keep the created timestamp preceding the samples": {
+			enabled:                    true,
+			series:                     &mimirpb.TimeSeries{Samples: []mimirpb.Sample{{TimestampMs: nowMs, Value: 1}}, CreatedTimestampMs: nowMs - 1000},
+			expectedCreatedTimestampMs: nowMs - 1000,
+		},
+		"should keep the created timestamp preceding the histograms": {
+			enabled:                    true,
+			series:                     &mimirpb.TimeSeries{Histograms: []mimirpb.Histogram{mimirpb.FromHistogramToHistogramProto(nowMs, generateTestHistogram(0))}, CreatedTimestampMs: nowMs - 1000},
+			expectedCreatedTimestampMs: nowMs - 1000,
+		},
+		"should drop the created timestamp if disabled": {
+			enabled:                    false,
+			series:                     &mimirpb.TimeSeries{Samples: []mimirpb.Sample{{TimestampMs: nowMs, Value: 1}}, CreatedTimestampMs: nowMs - 1000},
+			expectedCreatedTimestampMs: 0,
+		},
+		"should drop the created timestamp not preceding all the samples": {
+			enabled:                    true,
+			series:                     &mimirpb.TimeSeries{Samples: []mimirpb.Sample{{TimestampMs: nowMs - 2000, Value: 1}, {TimestampMs: nowMs, Value: 2}}, CreatedTimestampMs: nowMs - 1000},
+			expectedCreatedTimestampMs: 0,
+		},
+		"should drop the created timestamp equal to the sample timestamp": {
+			enabled:                    true,
+			series:                     &mimirpb.TimeSeries{Samples: []mimirpb.Sample{{TimestampMs: nowMs, Value: 1}}, CreatedTimestampMs: nowMs},
+			expectedCreatedTimestampMs: 0,
+		},
+		"should drop a negative created timestamp": {
+			enabled:                    true,
+			series:                     &mimirpb.TimeSeries{Samples: []mimirpb.Sample{{TimestampMs: nowMs, Value: 1}}, CreatedTimestampMs: -1},
+			expectedCreatedTimestampMs: 0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.CreatedTimestampsIngestionEnabled = tc.enabled
+
+			ds, _, _ := prepare(t, prepConfig{
+				limits:          limits,
+				numDistributors: 1,
+			})
+
+			tc.series.Labels = seriesLabels
+			ts := mimirpb.PreallocTimeseries{TimeSeries: tc.series}
+
+			require.NoError(t, ds[0].validateSeries(now, ts, "user", "test-group", false, 0))
+			assert.Equal(t, tc.expectedCreatedTimestampMs, ts.CreatedTimestampMs)
+		})
+	}
+}
+
 func BenchmarkDistributor_Push(b *testing.B) {
 	const (
 		numSeriesPerRequest = 1000
@@ -3509,6 +3980,8 @@ type prepConfig struct {
 	labelNamesStreamZonesResponseDelay map[string]time.Duration
 	forwarding                         bool
 	getForwarder                       func() forwarding.Forwarder
+	requestCaptureBucket               objstore.Bucket
+	sandboxTenants                     *sandbox.Registry
 
 	timeOut bool
 }
@@ -3619,6 +4092,13 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, []*p
 		distributorCfg.DefaultLimits.MaxInflightPushRequestsBytes = cfg.maxInflightRequestsBytes
 		distributorCfg.DefaultLimits.MaxIngestionRate = cfg.maxIngestionRate
 		distributorCfg.ShuffleShardingLookbackPeriod = time.Hour
+		distributorCfg.IngestersZoneAwarenessEnabled = len(cfg.ingesterZones) > 0
+		distributorCfg.SandboxTenants = cfg.sandboxTenants
+
+		if cfg.requestCaptureBucket != nil {
+			distributorCfg.RequestCapture.SampleRatio = 1
+			distributorCfg.RequestCaptureBucket = cfg.requestCaptureBucket
+		}
 
 		if cfg.forwarding {
 			distributorCfg.Forwarding.Enabled = true
@@ -4698,6 +5178,46 @@ func TestShardByAllLabelsReturnsWrongResultsForUnsortedLabels(t *testing.T) {
 	assert.NotEqual(t, val1, val2)
 }
 
+func TestDistributor_SplitIngestAggregationInputs(t *testing.T) {
+	limits := validation.Limits{}
+	flagext.DefaultValues(&limits)
+	limits.IngestAggregationRules = []validation.IngestAggregationRule{
+		{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod"}},
+		{Selector: `{__name__="http_request_duration_seconds"}`, Without: []string{"pod"}, DropInput: true},
+	}
+
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	d := &Distributor{limits: overrides, log: log.NewNopLogger(), ingestAggregationRules: ingestaggregation.NewRulesCache()}
+
+	series := []mimirpb.PreallocTimeseries{
+		makeWriteRequestTimeseries(mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(model.MetricNameLabel, "http_requests_total", "job", "api", "pod", "api-1")), 1, 1),
+		makeWriteRequestTimeseries(mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(model.MetricNameLabel, "http_requests_total", "job", "api", "pod", "api-2")), 1, 1),
+		makeWriteRequestTimeseries(mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(model.MetricNameLabel, "http_request_duration_seconds", "job", "api", "pod", "api-1")), 1, 1),
+		makeWriteRequestTimeseries(mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(model.MetricNameLabel, "up", "job", "api", "pod", "api-1")), 1, 1),
+	}
+
+	ingested, inputs, inputKeys := d.splitIngestAggregationInputs("user", series)
+
+	// The series rolled up by the rules dropping their input are not ingested, while the other series
+	// are sharded by all their labels.
+	assert.Equal(t, []mimirpb.PreallocTimeseries{series[0], series[1], series[3]}, ingested)
+	assert.Equal(t, []uint32{
+		shardByAllLabels("user", series[0].Labels),
+		shardByAllLabels("user", series[1].Labels),
+		shardByAllLabels("user", series[3].Labels),
+	}, d.getTokensForSeries("user", ingested))
+
+	// The series rolled up are also sent to the ingesters owning the aggregated series.
+	assert.Equal(t, []mimirpb.PreallocTimeseries{series[0], series[1], series[2]}, inputs)
+	assert.Equal(t, []uint32{
+		shardByAllLabels("user", []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "http_requests_total"}, {Name: "job", Value: "api"}}),
+		shardByAllLabels("user", []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "http_requests_total"}, {Name: "job", Value: "api"}}),
+		shardByAllLabels("user", []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "http_request_duration_seconds"}, {Name: "job", Value: "api"}}),
+	}, inputKeys)
+}
+
 func TestSortLabels(t *testing.T) {
 	sorted := []mimirpb.LabelAdapter{
 		{Name: "__name__", Value: "foo"},
@@ -20,6 +20,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/kv/codec"
+	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/grafana/dskit/services"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -34,7 +35,11 @@ import (
 var (
 	errNegativeUpdateTimeoutJitterMax = errors.New("HA tracker max update timeout jitter shouldn't be negative")
 	errInvalidFailoverTimeout         = "HA Tracker failover timeout (%v) must be at least 1s greater than update timeout - max jitter (%v)"
-	errMemberlistUnsupported          = errors.New("memberlist is not supported by the HA tracker since gossip propagation is too slow for HA purposes")
+	errInvalidReplicaDescMergeable    = errors.New("HA tracker can only merge a replica descriptor with another replica descriptor")
+	errHATrackerDisabled              = errors.New("HA tracker is not enabled")
+	errUnknownHACluster               = errors.New("the HA cluster is not tracked")
+	errNoFailoverReplica              = errors.New("no replica to fail over to has been specified, and no other replica has been seen recently")
+	errFailoverToElectedReplica       = errors.New("the replica to fail over to is already the elected one")
 )
 
 type haTrackerLimits interface {
@@ -53,6 +58,78 @@ func NewReplicaDesc() *ReplicaDesc {
 	return &ReplicaDesc{}
 }
 
+// Merge implements memberlist.Mergeable. The replica descriptor with the most recent received
+// timestamp wins. On equal received timestamps, the descriptor marked for deletion wins, then the
+// removed tombstone, and the replica name is used as a last resort to make the merge deterministic.
+func (d *ReplicaDesc) Merge(mergeable memberlist.Mergeable, _ bool) (memberlist.Mergeable, error) {
+	if mergeable == nil {
+		return nil, nil
+	}
+
+	other, ok := mergeable.(*ReplicaDesc)
+	if !ok {
+		return nil, errInvalidReplicaDescMergeable
+	}
+	if other == nil || !other.supersedes(d) {
+		return nil, nil
+	}
+
+	*d = *other
+	return other.Clone(), nil
+}
+
+// supersedes returns whether d should replace other when merging them.
+func (d *ReplicaDesc) supersedes(other *ReplicaDesc) bool {
+	if d.ReceivedAt != other.ReceivedAt {
+		return d.ReceivedAt > other.ReceivedAt
+	}
+	if d.DeletedAt != other.DeletedAt {
+		return d.DeletedAt > other.DeletedAt
+	}
+	// The removed tombstones win, so that they're not restored by the tombstones still gossiped.
+	if d.isRemovedTombstone() != other.isRemovedTombstone() {
+		return d.isRemovedTombstone()
+	}
+	return d.Replica > other.Replica
+}
+
+// isRemovedTombstone returns whether the replica descriptor is a tombstone removed by RemoveTombstones.
+func (d *ReplicaDesc) isRemovedTombstone() bool {
+	return d.DeletedAt > 0 && d.Replica == ""
+}
+
+// MergeContent implements memberlist.Mergeable. The removed tombstones have no content, so that
+// they're no longer gossiped.
+func (d *ReplicaDesc) MergeContent() []string {
+	if d.isRemovedTombstone() {
+		return nil
+	}
+	return []string{d.Replica}
+}
+
+// RemoveTombstones implements memberlist.Mergeable. Memberlist doesn't support deleting keys, so
+// a replica marked for deletion before the limit is removed by clearing the replica, and keeping
+// the timestamps, so that it's no longer gossiped and still seen as deleted by the distributors.
+// A zero limit doesn't remove the tombstones, because the distributors rely on the deleted timestamp
+// to clean up their cache.
+func (d *ReplicaDesc) RemoveTombstones(limit time.Time) (total, removed int) {
+	if d.DeletedAt == 0 {
+		return 0, 0
+	}
+	if d.isRemovedTombstone() || limit.IsZero() || !timestamp.Time(d.DeletedAt).Before(limit) {
+		return 1, 0
+	}
+
+	d.Replica = ""
+	return 1, 1
+}
+
+// Clone implements memberlist.Mergeable.
+func (d *ReplicaDesc) Clone() memberlist.Mergeable {
+	clone := *d
+	return &clone
+}
+
 // HATrackerConfig contains the configuration require to
 // create a HA Tracker.
 type HATrackerConfig struct {
@@ -68,7 +145,7 @@ type HATrackerConfig struct {
 	// more than this duration
 	FailoverTimeout time.Duration `yaml:"ha_tracker_failover_timeout" category:"advanced"`
 
-	KVStore kv.Config `yaml:"kvstore" doc:"description=Backend storage to use for the ring. Please be aware that memberlist is not supported by the HA tracker since gossip propagation is too slow for HA purposes."`
+	KVStore kv.Config `yaml:"kvstore" doc:"description=Backend storage to use for the ring. When using memberlist, the elected replicas are propagated via gossiping, so the failover timeout should be configured higher than the time it takes for a change to propagate across the distributors."`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet.
@@ -96,10 +173,6 @@ func (cfg *HATrackerConfig) Validate() error {
 		return fmt.Errorf(errInvalidFailoverTimeout, cfg.FailoverTimeout, minFailureTimeout)
 	}
 
-	if cfg.KVStore.Store == "memberlist" {
-		return errMemberlistUnsupported
-	}
-
 	return nil
 }
 
@@ -356,7 +429,9 @@ func (h *haTracker) cleanupOldReplicas(ctx context.Context, deadline time.Time)
 		}
 
 		if desc.DeletedAt > 0 {
-			if timestamp.Time(desc.DeletedAt).After(deadline) {
+			// Memberlist doesn't support deleting keys, so the replicas marked for deletion are removed by
+			// memberlist itself via RemoveTombstones, once the -memberlist.left-ingesters-timeout has elapsed.
+			if timestamp.Time(desc.DeletedAt).After(deadline) || h.cfg.KVStore.Store == "memberlist" {
 				continue
 			}
 
@@ -505,6 +580,55 @@ func (h *haTracker) updateKVStore(ctx context.Context, userID, cluster, replica
 	return err
 }
 
+// failover forces the election of the input replica for the given user and cluster. If the
+// replica is empty, the last non-elected replica we have received samples from is elected.
+func (h *haTracker) failover(ctx context.Context, userID, cluster, replica string, now time.Time) error {
+	if !h.cfg.EnableHATracker {
+		return errHATrackerDisabled
+	}
+
+	h.electedLock.RLock()
+	entry := h.clusters[userID][cluster]
+	var elected string
+	if entry != nil {
+		elected = entry.elected.Replica
+		if replica == "" {
+			replica = entry.nonElectedLastSeenReplica
+		}
+	}
+	h.electedLock.RUnlock()
+
+	switch {
+	case entry == nil:
+		return errUnknownHACluster
+	case replica == "":
+		return errNoFailoverReplica
+	case replica == elected:
+		return errFailoverToElectedReplica
+	}
+
+	desc := &ReplicaDesc{
+		Replica:    replica,
+		ReceivedAt: timestamp.FromTime(now),
+	}
+	key := fmt.Sprintf("%s/%s", userID, cluster)
+	err := h.client.CAS(ctx, key, func(interface{}) (out interface{}, retry bool, err error) {
+		return desc, true, nil
+	})
+	h.kvCASCalls.WithLabelValues(userID, cluster).Inc()
+	if err != nil {
+		return err
+	}
+
+	// Update the cache right away, without waiting for the KV store to notify the change.
+	h.electedLock.Lock()
+	h.updateCache(userID, cluster, desc)
+	h.electedLock.Unlock()
+
+	level.Info(h.logger).Log("msg", "forced HA tracker failover", "user", userID, "cluster", cluster, "previous_replica", elected, "replica", replica)
+	return nil
+}
+
 type replicasNotMatchError struct {
 	replica, elected string
 }
@@ -7,6 +7,7 @@ package distributor
 
 import (
 	_ "embed" // Used to embed html template
+	"errors"
 	"html/template"
 	"net/http"
 	"sort"
@@ -27,12 +28,15 @@ type haTrackerStatusPageContents struct {
 }
 
 type haTrackerReplica struct {
-	UserID       string        `json:"userID"`
-	Cluster      string        `json:"cluster"`
-	Replica      string        `json:"replica"`
-	ElectedAt    time.Time     `json:"electedAt"`
-	UpdateTime   time.Duration `json:"updateDuration"`
-	FailoverTime time.Duration `json:"failoverDuration"`
+	UserID                   string        `json:"userID"`
+	Cluster                  string        `json:"cluster"`
+	Replica                  string        `json:"replica"`
+	ElectedAt                time.Time     `json:"electedAt"`
+	LastReceivedAt           time.Time     `json:"lastReceivedAt"`
+	NonElectedReplica        string        `json:"nonElectedReplica,omitempty"`
+	NonElectedLastReceivedAt time.Time     `json:"nonElectedLastReceivedAt"`
+	UpdateTime               time.Duration `json:"updateDuration"`
+	FailoverTime             time.Duration `json:"failoverDuration"`
 }
 
 func (h *haTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -42,14 +46,22 @@ func (h *haTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	for userID, clusters := range h.clusters {
 		for cluster, entry := range clusters {
 			desc := &entry.elected
-			electedReplicas = append(electedReplicas, haTrackerReplica{
-				UserID:       userID,
-				Cluster:      cluster,
-				Replica:      desc.Replica,
-				ElectedAt:    timestamp.Time(desc.ReceivedAt),
-				UpdateTime:   time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.UpdateTimeout)),
-				FailoverTime: time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.FailoverTimeout)),
-			})
+			replica := haTrackerReplica{
+				UserID:            userID,
+				Cluster:           cluster,
+				Replica:           desc.Replica,
+				ElectedAt:         timestamp.Time(desc.ReceivedAt),
+				NonElectedReplica: entry.nonElectedLastSeenReplica,
+				UpdateTime:        time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.UpdateTimeout)),
+				FailoverTime:      time.Until(timestamp.Time(desc.ReceivedAt).Add(h.cfg.FailoverTimeout)),
+			}
+			if entry.electedLastSeenTimestamp > 0 {
+				replica.LastReceivedAt = timestamp.Time(entry.electedLastSeenTimestamp)
+			}
+			if entry.nonElectedLastSeenTimestamp > 0 {
+				replica.NonElectedLastReceivedAt = timestamp.Time(entry.nonElectedLastSeenTimestamp)
+			}
+			electedReplicas = append(electedReplicas, replica)
 		}
 	}
 	h.electedLock.RUnlock()
@@ -69,3 +81,27 @@ func (h *haTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		Now:     time.Now(),
 	}, haTrackerStatusPageTemplate, req)
 }
+
+// FailoverHandler forces the HA tracker to elect another replica for a cluster. The user and
+// cluster are required. If the replica is not specified, the HA tracker fails over to the last
+// non-elected replica it has received samples from.
+func (h *haTracker) FailoverHandler(w http.ResponseWriter, req *http.Request) {
+	userID := req.FormValue("user")
+	cluster := req.FormValue("cluster")
+	if userID == "" || cluster == "" {
+		http.Error(w, "the user and cluster parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.failover(req.Context(), userID, cluster, req.FormValue("replica"), time.Now())
+	switch {
+	case errors.Is(err, errUnknownHACluster):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errHATrackerDisabled), errors.Is(err, errNoFailoverReplica), errors.Is(err, errFailoverToElectedReplica):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
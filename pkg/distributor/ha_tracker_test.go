@@ -8,14 +8,19 @@ package distributor
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/dns"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/kv/codec"
 	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
 	"github.com/grafana/dskit/test"
@@ -116,7 +121,7 @@ func TestHATrackerConfig_Validate(t *testing.T) {
 			}(),
 			expectedErr: nil,
 		},
-		"should fail if KV backend is set to memberlist": {
+		"should pass if KV backend is set to memberlist": {
 			cfg: func() HATrackerConfig {
 				cfg := HATrackerConfig{}
 				flagext.DefaultValues(&cfg)
@@ -124,7 +129,7 @@ func TestHATrackerConfig_Validate(t *testing.T) {
 
 				return cfg
 			}(),
-			expectedErr: errMemberlistUnsupported,
+			expectedErr: nil,
 		},
 	}
 
@@ -896,3 +901,252 @@ func getSumOfHistogramSampleCount(families []*dto.MetricFamily, metricName strin
 
 	return sum
 }
+
+func TestReplicaDesc_Merge(t *testing.T) {
+	tests := map[string]struct {
+		local, incoming *ReplicaDesc
+		expected        *ReplicaDesc
+		expectedChange  bool
+	}{
+		"incoming replica received more recently": {
+			local:          &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+			incoming:       &ReplicaDesc{Replica: "r2", ReceivedAt: 2000},
+			expected:       &ReplicaDesc{Replica: "r2", ReceivedAt: 2000},
+			expectedChange: true,
+		},
+		"incoming replica received less recently": {
+			local:    &ReplicaDesc{Replica: "r1", ReceivedAt: 2000},
+			incoming: &ReplicaDesc{Replica: "r2", ReceivedAt: 1000},
+			expected: &ReplicaDesc{Replica: "r1", ReceivedAt: 2000},
+		},
+		"incoming replica marked for deletion": {
+			local:          &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+			incoming:       &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			expected:       &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			expectedChange: true,
+		},
+		"incoming replica revived after being marked for deletion": {
+			local:          &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			incoming:       &ReplicaDesc{Replica: "r1", ReceivedAt: 4000},
+			expected:       &ReplicaDesc{Replica: "r1", ReceivedAt: 4000},
+			expectedChange: true,
+		},
+		"same received timestamp for different replicas": {
+			local:    &ReplicaDesc{Replica: "r2", ReceivedAt: 1000},
+			incoming: &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+			expected: &ReplicaDesc{Replica: "r2", ReceivedAt: 1000},
+		},
+		"same replica descriptor": {
+			local:    &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+			incoming: &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+			expected: &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+		},
+		"incoming tombstone already removed": {
+			local:    &ReplicaDesc{ReceivedAt: 1000, DeletedAt: 3000},
+			incoming: &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			expected: &ReplicaDesc{ReceivedAt: 1000, DeletedAt: 3000},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			// The merge must be commutative.
+			reversed := testData.incoming.Clone().(*ReplicaDesc)
+			_, err := reversed.Merge(testData.local.Clone(), false)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, reversed)
+
+			change, err := testData.local.Merge(testData.incoming, false)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, testData.local)
+
+			if testData.expectedChange {
+				assert.Equal(t, testData.expected, change)
+			} else {
+				assert.Nil(t, change)
+			}
+
+			// The merge must be idempotent.
+			change, err = testData.local.Merge(testData.incoming, false)
+			require.NoError(t, err)
+			assert.Nil(t, change)
+		})
+	}
+}
+
+func TestReplicaDesc_RemoveTombstones(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		desc            *ReplicaDesc
+		limit           time.Time
+		expected        *ReplicaDesc
+		expectedTotal   int
+		expectedRemoved int
+	}{
+		"replica not marked for deletion": {
+			desc:     &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+			limit:    now,
+			expected: &ReplicaDesc{Replica: "r1", ReceivedAt: 1000},
+		},
+		"replica marked for deletion after the limit": {
+			desc:          &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: timestamp.FromTime(now)},
+			limit:         now.Add(-time.Minute),
+			expected:      &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: timestamp.FromTime(now)},
+			expectedTotal: 1,
+		},
+		"replica marked for deletion before the limit": {
+			desc:            &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			limit:           now,
+			expected:        &ReplicaDesc{ReceivedAt: 1000, DeletedAt: 3000},
+			expectedTotal:   1,
+			expectedRemoved: 1,
+		},
+		"replica marked for deletion with zero limit": {
+			desc:          &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			expected:      &ReplicaDesc{Replica: "r1", ReceivedAt: 1000, DeletedAt: 3000},
+			expectedTotal: 1,
+		},
+		"tombstone already removed": {
+			desc:          &ReplicaDesc{ReceivedAt: 1000, DeletedAt: 3000},
+			limit:         now,
+			expected:      &ReplicaDesc{ReceivedAt: 1000, DeletedAt: 3000},
+			expectedTotal: 1,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			total, removed := testData.desc.RemoveTombstones(testData.limit)
+			assert.Equal(t, testData.expectedTotal, total)
+			assert.Equal(t, testData.expectedRemoved, removed)
+			assert.Equal(t, testData.expected, testData.desc)
+
+			// The removed tombstones are no longer gossiped.
+			assert.Equal(t, testData.desc.isRemovedTombstone(), len(testData.desc.MergeContent()) == 0)
+		})
+	}
+}
+
+func TestHATracker_Memberlist(t *testing.T) {
+	ctx := context.Background()
+
+	var mlCfg memberlist.KVConfig
+	flagext.DefaultValues(&mlCfg)
+	mlCfg.TCPTransport = memberlist.TCPTransportConfig{BindAddrs: []string{"localhost"}, BindPort: 0}
+	mlCfg.Codecs = []codec.Codec{GetReplicaDescCodec()}
+
+	mlKV := memberlist.NewKV(mlCfg, log.NewNopLogger(), dns.NewProvider(log.NewNopLogger(), nil, dns.GolangResolverType), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, mlKV))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, mlKV)) })
+
+	cfg := HATrackerConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.EnableHATracker = true
+	cfg.KVStore.Store = "memberlist"
+	cfg.KVStore.MemberlistKV = func() (*memberlist.KV, error) { return mlKV, nil }
+	require.NoError(t, cfg.Validate())
+
+	c, err := newHATracker(cfg, trackerLimits{maxClusters: 100}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, c)) })
+
+	now := time.Now()
+	require.NoError(t, c.checkReplica(ctx, "user", "cluster", "r1", now))
+	assert.ErrorIs(t, c.checkReplica(ctx, "user", "cluster", "r2", now), replicasNotMatchError{})
+	checkReplicaTimestamp(t, time.Second, c, "user", "cluster", "r1", now)
+
+	// Failover once the elected replica stops sending samples.
+	now = now.Add(cfg.FailoverTimeout + time.Second)
+	assert.ErrorIs(t, c.checkReplica(ctx, "user", "cluster", "r2", now), replicasNotMatchError{})
+	c.updateKVStoreAll(ctx, now)
+	checkReplicaTimestamp(t, time.Second, c, "user", "cluster", "r2", now)
+
+	// Replicas marked for deletion are kept in memberlist.
+	c.cleanupOldReplicas(ctx, now.Add(time.Second))
+	checkReplicaDeletionState(t, time.Second, c, "user", "cluster", false, true, true)
+	c.cleanupOldReplicas(ctx, time.Now().Add(time.Minute))
+	checkReplicaDeletionState(t, time.Second, c, "user", "cluster", false, true, true)
+}
+
+func TestHATracker_Failover(t *testing.T) {
+	ctx := context.Background()
+
+	kvStore, closer := consul.NewInMemoryClient(GetReplicaDescCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	c, err := newHATracker(HATrackerConfig{
+		EnableHATracker: true,
+		KVStore:         kv.Config{Mock: kv.PrefixClient(kvStore, "prefix")},
+		UpdateTimeout:   time.Minute,
+		FailoverTimeout: 2 * time.Minute,
+	}, trackerLimits{maxClusters: 100}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, c)) })
+
+	now := time.Now()
+	require.NoError(t, c.checkReplica(ctx, "user", "cluster", "r1", now))
+
+	// No other replica has been seen yet.
+	assert.ErrorIs(t, c.failover(ctx, "user", "cluster", "", now), errNoFailoverReplica)
+	assert.ErrorIs(t, c.failover(ctx, "user", "cluster", "r1", now), errFailoverToElectedReplica)
+	assert.ErrorIs(t, c.failover(ctx, "user", "unknown", "r2", now), errUnknownHACluster)
+
+	// Fail over to the last non-elected replica seen.
+	assert.ErrorIs(t, c.checkReplica(ctx, "user", "cluster", "r2", now), replicasNotMatchError{})
+	now = now.Add(time.Second)
+	require.NoError(t, c.failover(ctx, "user", "cluster", "", now))
+	require.NoError(t, c.checkReplica(ctx, "user", "cluster", "r2", now))
+	assert.ErrorIs(t, c.checkReplica(ctx, "user", "cluster", "r1", now), replicasNotMatchError{})
+	checkReplicaTimestamp(t, time.Second, c, "user", "cluster", "r2", now)
+
+	// Fail over to an explicit replica.
+	now = now.Add(time.Second)
+	require.NoError(t, c.failover(ctx, "user", "cluster", "r3", now))
+	checkReplicaTimestamp(t, time.Second, c, "user", "cluster", "r3", now)
+
+	val, err := kvStore.Get(ctx, "prefixuser/cluster")
+	require.NoError(t, err)
+	assert.Equal(t, "r3", val.(*ReplicaDesc).Replica)
+}
+
+func TestHATracker_FailoverHandler(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := newHATracker(HATrackerConfig{
+		EnableHATracker: true,
+		KVStore:         kv.Config{Store: "inmemory"},
+		UpdateTimeout:   time.Minute,
+		FailoverTimeout: 2 * time.Minute,
+	}, trackerLimits{maxClusters: 100}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, c)) })
+
+	require.NoError(t, c.checkReplica(ctx, "user", "cluster", "r1", time.Now()))
+
+	tests := map[string]struct {
+		query          string
+		expectedStatus int
+	}{
+		"missing cluster":     {query: "user=user", expectedStatus: http.StatusBadRequest},
+		"unknown cluster":     {query: "user=user&cluster=unknown&replica=r2", expectedStatus: http.StatusNotFound},
+		"no failover replica": {query: "user=user&cluster=cluster", expectedStatus: http.StatusBadRequest},
+		"failover":            {query: "user=user&cluster=cluster&replica=r2", expectedStatus: http.StatusNoContent},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/distributor/ha_tracker/failover?"+testData.query, nil)
+			rec := httptest.NewRecorder()
+			c.FailoverHandler(rec, req)
+			assert.Equal(t, testData.expectedStatus, rec.Code, rec.Body.String())
+		})
+	}
+
+	c.electedLock.RLock()
+	defer c.electedLock.RUnlock()
+	assert.Equal(t, "r2", c.clusters["user"]["cluster"].elected.Replica)
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/dskit/ring"
+)
+
+// doWithHedging runs f, in parallel, for the instances in the replication set like ReplicationSet.Do() does.
+// If hedging is enabled, the requests to the instances allowed to fail are sent only if the other requests
+// haven't completed within the hedging delay and the hedging budget allows it, or if any other request failed.
+// Hedging is not supported when the replication set is zone-aware.
+func (d *Distributor) doWithHedging(ctx context.Context, replicationSet ring.ReplicationSet, f func(context.Context, *ring.InstanceDesc) (interface{}, error)) ([]interface{}, error) {
+	delay := d.cfg.IngesterQueryHedgingDelay
+	if delay <= 0 || replicationSet.MaxErrors <= 0 || replicationSet.MaxUnavailableZones > 0 {
+		return replicationSet.Do(ctx, 0, f)
+	}
+
+	d.hedgingBudget.AddRequest()
+
+	// ReplicationSet.Do() delays the requests to the last MaxErrors instances.
+	hedged := make(map[string]struct{}, replicationSet.MaxErrors)
+	for _, instance := range replicationSet.Instances[len(replicationSet.Instances)-replicationSet.MaxErrors:] {
+		hedged[instance.Addr] = struct{}{}
+	}
+
+	var (
+		failed     = make(chan struct{})
+		failedOnce sync.Once
+	)
+
+	return replicationSet.Do(ctx, delay, func(ctx context.Context, instance *ring.InstanceDesc) (interface{}, error) {
+		if _, ok := hedged[instance.Addr]; ok {
+			select {
+			case <-failed:
+				// The request has been started to replace a failed one, so it's not a hedged request.
+			default:
+				if d.hedgingBudget.TryHedge() {
+					d.ingesterHedgedRequests.Inc()
+					break
+				}
+
+				// The budget is exhausted, so we send the request only if another one fails.
+				d.ingesterHedgingBudgetExhausted.Inc()
+				select {
+				case <-failed:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+
+		res, err := f(ctx, instance)
+		if err != nil {
+			failedOnce.Do(func() { close(failed) })
+		}
+		return res, err
+	})
+}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestDistributor_doWithHedging(t *testing.T) {
+	const hedgingDelay = 100 * time.Millisecond
+
+	replicationSet := ring.ReplicationSet{
+		Instances: []ring.InstanceDesc{{Addr: "ingester-1"}, {Addr: "ingester-2"}, {Addr: "ingester-3"}},
+		MaxErrors: 1,
+	}
+
+	tests := map[string]struct {
+		hedgingDelay           time.Duration
+		hedgingBudget          float64
+		slowInstance           string
+		failingInstance        string
+		expectedResults        []interface{}
+		expectedCalls          []string
+		expectedHedged         int
+		expectedBudgetExceeded int
+	}{
+		"should send requests to all instances if hedging is disabled": {
+			hedgingDelay:  0,
+			expectedCalls: []string{"ingester-1", "ingester-2", "ingester-3"},
+		},
+		"should not send the hedged request if the other requests complete within the hedging delay": {
+			hedgingDelay:    hedgingDelay,
+			hedgingBudget:   1,
+			expectedResults: []interface{}{"ingester-1", "ingester-2"},
+			expectedCalls:   []string{"ingester-1", "ingester-2"},
+		},
+		"should send the hedged request if a request is slow": {
+			hedgingDelay:    hedgingDelay,
+			hedgingBudget:   1,
+			slowInstance:    "ingester-1",
+			expectedResults: []interface{}{"ingester-2", "ingester-3"},
+			expectedCalls:   []string{"ingester-1", "ingester-2", "ingester-3"},
+			expectedHedged:  1,
+		},
+		"should not send the hedged request if a request is slow but the hedging budget is exhausted": {
+			hedgingDelay:           hedgingDelay,
+			hedgingBudget:          0,
+			slowInstance:           "ingester-1",
+			expectedResults:        []interface{}{"ingester-1", "ingester-2"},
+			expectedCalls:          []string{"ingester-1", "ingester-2"},
+			expectedBudgetExceeded: 1,
+		},
+		"should send the delayed request if a request fails, even if the hedging budget is exhausted": {
+			hedgingDelay:    hedgingDelay,
+			hedgingBudget:   0,
+			failingInstance: "ingester-1",
+			expectedResults: []interface{}{"ingester-2", "ingester-3"},
+			expectedCalls:   []string{"ingester-1", "ingester-2", "ingester-3"},
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			d := &Distributor{
+				cfg:                            Config{IngesterQueryHedgingDelay: testData.hedgingDelay},
+				hedgingBudget:                  util.NewHedgingBudget(testData.hedgingBudget),
+				ingesterHedgedRequests:         prometheus.NewCounter(prometheus.CounterOpts{}),
+				ingesterHedgingBudgetExhausted: prometheus.NewCounter(prometheus.CounterOpts{}),
+			}
+
+			var calls [3]atomic.Bool
+
+			results, err := d.doWithHedging(context.Background(), replicationSet, func(ctx context.Context, instance *ring.InstanceDesc) (interface{}, error) {
+				for i := range replicationSet.Instances {
+					if replicationSet.Instances[i].Addr == instance.Addr {
+						calls[i].Store(true)
+					}
+				}
+
+				switch instance.Addr {
+				case testData.failingInstance:
+					return nil, errors.New("request failed")
+				case testData.slowInstance:
+					select {
+					case <-ctx.Done():
+					case <-time.After(3 * testData.hedgingDelay):
+					}
+				}
+
+				return instance.Addr, nil
+			})
+			require.NoError(t, err)
+			if testData.expectedResults != nil {
+				assert.ElementsMatch(t, testData.expectedResults, results)
+			} else {
+				// Any quorum of the instances is fine.
+				assert.Len(t, results, 2)
+			}
+
+			// Wait until the requests still running in the background have completed.
+			time.Sleep(3 * hedgingDelay)
+
+			var actualCalls []string
+			for i := range calls {
+				if calls[i].Load() {
+					actualCalls = append(actualCalls, replicationSet.Instances[i].Addr)
+				}
+			}
+			assert.Equal(t, testData.expectedCalls, actualCalls)
+
+			assert.Equal(t, float64(testData.expectedHedged), testutil.ToFloat64(d.ingesterHedgedRequests))
+			assert.Equal(t, float64(testData.expectedBudgetExceeded), testutil.ToFloat64(d.ingesterHedgingBudgetExhausted))
+		})
+	}
+}
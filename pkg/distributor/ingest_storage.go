@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/httpgrpc"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+var errNoIngestStoragePartitions = errors.New("the topic of the ingest storage has no partitions")
+
+// pushToIngestStorage writes the series, ingest aggregation inputs and metadata to the partitions of the ingest
+// storage owning their tokens, and returns once all the partitions have acknowledged the write. The ingesters
+// consume the partitions asynchronously, so the write doesn't depend on the ingesters availability.
+func (d *Distributor) pushToIngestStorage(ctx context.Context, userID string, req *mimirpb.WriteRequest, timeseries, aggregationInputs []mimirpb.PreallocTimeseries, seriesKeys, aggregationInputKeys, metadataKeys []uint32) error {
+	partitionRing, err := d.ingestStorageWriter.PartitionRing(ctx)
+	if err != nil {
+		return err
+	}
+
+	var partitionIDs []int32
+	partitionRequests := map[int32]*mimirpb.WriteRequest{}
+	partitionRequest := func(token uint32) (*mimirpb.WriteRequest, error) {
+		partitionID, ok := partitionRing.PartitionForToken(token)
+		if !ok {
+			return nil, errNoIngestStoragePartitions
+		}
+
+		partitionReq := partitionRequests[partitionID]
+		if partitionReq == nil {
+			partitionReq = &mimirpb.WriteRequest{Source: req.Source}
+			partitionRequests[partitionID] = partitionReq
+			partitionIDs = append(partitionIDs, partitionID)
+		}
+		return partitionReq, nil
+	}
+
+	for i, ts := range timeseries {
+		partitionReq, err := partitionRequest(seriesKeys[i])
+		if err != nil {
+			return err
+		}
+		partitionReq.Timeseries = append(partitionReq.Timeseries, ts)
+	}
+	for i, ts := range aggregationInputs {
+		partitionReq, err := partitionRequest(aggregationInputKeys[i])
+		if err != nil {
+			return err
+		}
+		partitionReq.IngestAggregationInputs = append(partitionReq.IngestAggregationInputs, ts)
+	}
+	for i, m := range req.Metadata {
+		partitionReq, err := partitionRequest(metadataKeys[i])
+		if err != nil {
+			return err
+		}
+		partitionReq.Metadata = append(partitionReq.Metadata, m)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.RemoteTimeout)
+	defer cancel()
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, partitionID := range partitionIDs {
+		partitionID := partitionID
+		g.Go(func() error {
+			err := d.ingestStorageWriter.WriteSync(ctx, partitionID, userID, partitionRequests[partitionID])
+			if errors.Is(err, context.DeadlineExceeded) {
+				return httpgrpc.Errorf(500, "exceeded configured distributor remote timeout: %s", err.Error())
+			}
+			return err
+		})
+	}
+	return g.Wait()
+}
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+)
+
+const (
+	ingesterCircuitBreakerFlagPrefix = "distributor.ingester-circuit-breaker"
+
+	// ingesterCircuitBreakerIdleTimeout is how long the circuit breaker state of an ingester is kept after
+	// the last write request to it, so that the state of the ingesters removed from the ring is purged.
+	ingesterCircuitBreakerIdleTimeout = 10 * time.Minute
+	ingesterCircuitBreakerPurgePeriod = time.Minute
+)
+
+var (
+	errIngesterCircuitBreakerOpen = errors.New(globalerror.DistributorIngesterCircuitBreakerOpen.MessageWithPerInstanceLimitConfig("the write request has not been sent to the ingester because its circuit breaker is open", ingesterCircuitBreakerFlagPrefix+".enabled"))
+
+	errInvalidIngesterCircuitBreakerFailureThreshold = errors.New("invalid ingester circuit breaker failure threshold, the value must be greater than 0 and lower than or equal to 1")
+	errInvalidIngesterCircuitBreakerMinRequests      = errors.New("invalid ingester circuit breaker minimum number of requests, the value must be greater than 0")
+	errInvalidIngesterCircuitBreakerWindow           = errors.New("invalid ingester circuit breaker window, the value must be greater than 0")
+	errInvalidIngesterCircuitBreakerCooldown         = errors.New("invalid ingester circuit breaker cooldown, the value must be greater than 0")
+)
+
+// IngesterCircuitBreakerConfig configures the circuit breaker of the write requests to each ingester.
+type IngesterCircuitBreakerConfig struct {
+	Enabled          bool          `yaml:"enabled" category:"experimental"`
+	FailureThreshold float64       `yaml:"failure_threshold" category:"experimental"`
+	LatencyThreshold time.Duration `yaml:"latency_threshold" category:"experimental"`
+	MinRequests      int           `yaml:"min_requests" category:"experimental"`
+	Window           time.Duration `yaml:"window" category:"experimental"`
+	Cooldown         time.Duration `yaml:"cooldown" category:"experimental"`
+}
+
+func (cfg *IngesterCircuitBreakerConfig) RegisterFlags(f *flag.FlagSet) {
+	prefix := ingesterCircuitBreakerFlagPrefix
+	f.BoolVar(&cfg.Enabled, prefix+".enabled", false, "Enable the circuit breaker of the write requests to each ingester. When the share of failed write requests to an ingester exceeds the failure threshold, the distributor stops sending write requests to it for the cooldown period, and relies on the other replicas of the series instead.")
+	f.Float64Var(&cfg.FailureThreshold, prefix+".failure-threshold", 0.5, "Share of failed write requests to an ingester within the window, greater than 0 and lower than or equal to 1, above which the circuit breaker opens.")
+	f.DurationVar(&cfg.LatencyThreshold, prefix+".latency-threshold", 0, "Write requests to an ingester taking longer than this duration are counted as failed by the circuit breaker, even if they succeed. 0 to count only the requests that fail.")
+	f.IntVar(&cfg.MinRequests, prefix+".min-requests", 20, "Minimum number of write requests to an ingester within the window before the circuit breaker can open.")
+	f.DurationVar(&cfg.Window, prefix+".window", 10*time.Second, "Period over which the write requests to an ingester are counted to compute the share of failed requests.")
+	f.DurationVar(&cfg.Cooldown, prefix+".cooldown", 10*time.Second, "How long the circuit breaker stays open before sending a write request to the ingester again to probe whether it recovered.")
+}
+
+func (cfg *IngesterCircuitBreakerConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.FailureThreshold <= 0 || cfg.FailureThreshold > 1 {
+		return errInvalidIngesterCircuitBreakerFailureThreshold
+	}
+	if cfg.MinRequests <= 0 {
+		return errInvalidIngesterCircuitBreakerMinRequests
+	}
+	if cfg.Window <= 0 {
+		return errInvalidIngesterCircuitBreakerWindow
+	}
+	if cfg.Cooldown <= 0 {
+		return errInvalidIngesterCircuitBreakerCooldown
+	}
+	return nil
+}
+
+type circuitBreakerState int
+
+const (
+	circuitBreakerClosed circuitBreakerState = iota
+	circuitBreakerOpen
+	circuitBreakerHalfOpen
+)
+
+type ingesterCircuitBreakerEntry struct {
+	state       circuitBreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	lastSeen    time.Time
+
+	// probing is whether a write request is in flight to the ingester while the circuit breaker is half-open.
+	probing bool
+}
+
+// ingesterCircuitBreaker tracks the outcome of the write requests to each ingester, and stops sending
+// write requests to the ingesters which fail or are slow to respond, even if they're healthy in the ring.
+// It prevents the write requests from piling up in the distributors when some ingesters are degraded:
+// a failed request to an ingester is tolerated by the quorum as long as the other replicas succeed.
+type ingesterCircuitBreaker struct {
+	cfg IngesterCircuitBreakerConfig
+
+	mtx     sync.Mutex
+	entries map[string]*ingesterCircuitBreakerEntry
+}
+
+func newIngesterCircuitBreaker(cfg IngesterCircuitBreakerConfig) *ingesterCircuitBreaker {
+	return &ingesterCircuitBreaker{
+		cfg:     cfg,
+		entries: map[string]*ingesterCircuitBreakerEntry{},
+	}
+}
+
+// allow returns whether a write request can be sent to the ingester with the input address. Once the
+// cooldown period is elapsed, a single write request at a time is allowed to probe the ingester.
+func (cb *ingesterCircuitBreaker) allow(addr string, now time.Time) bool {
+	if !cb.cfg.Enabled {
+		return true
+	}
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	entry, ok := cb.entries[addr]
+	if !ok {
+		return true
+	}
+	entry.lastSeen = now
+
+	switch entry.state {
+	case circuitBreakerOpen:
+		if now.Sub(entry.openedAt) < cb.cfg.Cooldown {
+			return false
+		}
+		entry.state = circuitBreakerHalfOpen
+		entry.probing = true
+		return true
+	case circuitBreakerHalfOpen:
+		if entry.probing {
+			return false
+		}
+		entry.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record records the outcome of a write request to the ingester with the input address, and returns
+// whether the circuit breaker has been opened because of it.
+func (cb *ingesterCircuitBreaker) record(addr string, err error, duration time.Duration, now time.Time) bool {
+	if !cb.cfg.Enabled {
+		return false
+	}
+
+	failed, relevant := cb.isFailure(err, duration)
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	entry, ok := cb.entries[addr]
+	if !ok {
+		if !relevant {
+			return false
+		}
+		entry = &ingesterCircuitBreakerEntry{windowStart: now}
+		cb.entries[addr] = entry
+	}
+	entry.lastSeen = now
+
+	switch entry.state {
+	case circuitBreakerOpen:
+		// The request has been sent before the circuit breaker opened.
+		return false
+	case circuitBreakerHalfOpen:
+		// Allow another probe if the outcome of this one doesn't tell anything about the ingester health.
+		entry.probing = false
+		if !relevant {
+			return false
+		}
+		if failed {
+			entry.state = circuitBreakerOpen
+			entry.openedAt = now
+			return true
+		}
+		entry.state = circuitBreakerClosed
+		entry.windowStart, entry.requests, entry.failures = now, 0, 0
+		return false
+	}
+
+	if !relevant {
+		return false
+	}
+	if now.Sub(entry.windowStart) >= cb.cfg.Window {
+		entry.windowStart, entry.requests, entry.failures = now, 0, 0
+	}
+	entry.requests++
+	if failed {
+		entry.failures++
+	}
+
+	if entry.requests >= cb.cfg.MinRequests && float64(entry.failures)/float64(entry.requests) > cb.cfg.FailureThreshold {
+		entry.state = circuitBreakerOpen
+		entry.openedAt = now
+		return true
+	}
+	return false
+}
+
+// isFailure returns whether the outcome of a write request is a failure of the ingester. The second
+// returned value is false if the outcome doesn't tell anything about the ingester health, for example
+// because the request has been canceled, or rejected because of the data it contains.
+func (cb *ingesterCircuitBreaker) isFailure(err error, duration time.Duration) (failed bool, relevant bool) {
+	if err == nil {
+		return cb.cfg.LatencyThreshold > 0 && duration > cb.cfg.LatencyThreshold, true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false, false
+	}
+	if resp, isHTTPErr := httpgrpc.HTTPResponseFromError(err); isHTTPErr && resp.Code/100 == 4 && resp.Code != http.StatusTooManyRequests {
+		return false, false
+	}
+	return true, true
+}
+
+// purge removes the state of the circuit breakers of the ingesters whose last write request is older than idleBefore.
+func (cb *ingesterCircuitBreaker) purge(idleBefore time.Time) {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	for addr, entry := range cb.entries {
+		if entry.lastSeen.Before(idleBefore) {
+			delete(cb.entries, addr)
+		}
+	}
+}
+
+// openCount returns the number of ingesters whose circuit breaker is not closed.
+func (cb *ingesterCircuitBreaker) openCount() int {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	count := 0
+	for _, entry := range cb.entries {
+		if entry.state != circuitBreakerClosed {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+func TestIngesterCircuitBreakerConfig_Validate(t *testing.T) {
+	valid := IngesterCircuitBreakerConfig{Enabled: true, FailureThreshold: 0.5, MinRequests: 10, Window: time.Second, Cooldown: time.Second}
+
+	tests := map[string]struct {
+		cfg         func(cfg *IngesterCircuitBreakerConfig)
+		expectedErr error
+	}{
+		"should pass on valid config": {
+			cfg: func(*IngesterCircuitBreakerConfig) {},
+		},
+		"should pass on invalid config if disabled": {
+			cfg: func(cfg *IngesterCircuitBreakerConfig) {
+				cfg.Enabled = false
+				cfg.FailureThreshold = 0
+			},
+		},
+		"should fail on failure threshold equal to 0": {
+			cfg:         func(cfg *IngesterCircuitBreakerConfig) { cfg.FailureThreshold = 0 },
+			expectedErr: errInvalidIngesterCircuitBreakerFailureThreshold,
+		},
+		"should fail on failure threshold greater than 1": {
+			cfg:         func(cfg *IngesterCircuitBreakerConfig) { cfg.FailureThreshold = 1.1 },
+			expectedErr: errInvalidIngesterCircuitBreakerFailureThreshold,
+		},
+		"should fail on min requests equal to 0": {
+			cfg:         func(cfg *IngesterCircuitBreakerConfig) { cfg.MinRequests = 0 },
+			expectedErr: errInvalidIngesterCircuitBreakerMinRequests,
+		},
+		"should fail on window equal to 0": {
+			cfg:         func(cfg *IngesterCircuitBreakerConfig) { cfg.Window = 0 },
+			expectedErr: errInvalidIngesterCircuitBreakerWindow,
+		},
+		"should fail on cooldown equal to 0": {
+			cfg:         func(cfg *IngesterCircuitBreakerConfig) { cfg.Cooldown = 0 },
+			expectedErr: errInvalidIngesterCircuitBreakerCooldown,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := valid
+			tc.cfg(&cfg)
+			assert.Equal(t, tc.expectedErr, cfg.Validate())
+		})
+	}
+}
+
+func TestIngesterCircuitBreaker(t *testing.T) {
+	const addr = "ingester-1"
+
+	cfg := IngesterCircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 0.5,
+		LatencyThreshold: time.Second,
+		MinRequests:      4,
+		Window:           10 * time.Second,
+		Cooldown:         5 * time.Second,
+	}
+	errFailure := errors.New("failure")
+	now := time.Now()
+
+	t.Run("should always allow requests when disabled", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(IngesterCircuitBreakerConfig{})
+
+		for i := 0; i < 10; i++ {
+			assert.False(t, cb.record(addr, errFailure, 0, now))
+		}
+		assert.True(t, cb.allow(addr, now))
+		assert.Equal(t, 0, cb.openCount())
+	})
+
+	t.Run("should open once the share of failures exceeds the threshold after the min number of requests", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(cfg)
+
+		assert.False(t, cb.record(addr, errFailure, 0, now))
+		assert.False(t, cb.record(addr, errFailure, 0, now))
+		assert.False(t, cb.record(addr, errFailure, 0, now))
+		assert.True(t, cb.allow(addr, now))
+
+		assert.True(t, cb.record(addr, nil, 0, now))
+		assert.False(t, cb.allow(addr, now))
+		assert.True(t, cb.allow("ingester-2", now))
+		assert.Equal(t, 1, cb.openCount())
+	})
+
+	t.Run("should count the slow requests as failed", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(cfg)
+
+		assert.False(t, cb.record(addr, nil, 2*time.Second, now))
+		assert.False(t, cb.record(addr, nil, 2*time.Second, now))
+		assert.False(t, cb.record(addr, nil, 2*time.Second, now))
+		assert.True(t, cb.record(addr, nil, 2*time.Second, now))
+		assert.False(t, cb.allow(addr, now))
+	})
+
+	t.Run("should ignore the outcomes which don't tell anything about the ingester health", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(cfg)
+
+		for i := 0; i < 10; i++ {
+			assert.False(t, cb.record(addr, context.Canceled, 0, now))
+			assert.False(t, cb.record(addr, httpgrpc.Errorf(http.StatusBadRequest, "invalid series"), 0, now))
+		}
+		assert.True(t, cb.allow(addr, now))
+	})
+
+	t.Run("should reset the counts at the end of the window", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(cfg)
+
+		assert.False(t, cb.record(addr, errFailure, 0, now))
+		assert.False(t, cb.record(addr, errFailure, 0, now))
+		assert.False(t, cb.record(addr, errFailure, 0, now))
+		assert.False(t, cb.record(addr, errFailure, 0, now.Add(cfg.Window)))
+		assert.True(t, cb.allow(addr, now.Add(cfg.Window)))
+	})
+
+	t.Run("should probe the ingester with a single request after the cooldown", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(cfg)
+		for i := 0; i < cfg.MinRequests; i++ {
+			cb.record(addr, errFailure, 0, now)
+		}
+		assert.False(t, cb.allow(addr, now.Add(cfg.Cooldown-time.Millisecond)))
+
+		// The probe fails, so the circuit breaker opens again.
+		probeTime := now.Add(cfg.Cooldown)
+		assert.True(t, cb.allow(addr, probeTime))
+		assert.False(t, cb.allow(addr, probeTime))
+		assert.True(t, cb.record(addr, errFailure, 0, probeTime))
+		assert.False(t, cb.allow(addr, probeTime.Add(time.Millisecond)))
+
+		// The probe is canceled, so another probe is allowed.
+		probeTime = probeTime.Add(cfg.Cooldown)
+		assert.True(t, cb.allow(addr, probeTime))
+		assert.False(t, cb.record(addr, context.Canceled, 0, probeTime))
+		assert.True(t, cb.allow(addr, probeTime))
+
+		// The probe succeeds, so the circuit breaker closes.
+		assert.False(t, cb.record(addr, nil, 0, probeTime))
+		assert.True(t, cb.allow(addr, probeTime))
+		assert.True(t, cb.allow(addr, probeTime))
+		assert.Equal(t, 0, cb.openCount())
+	})
+
+	t.Run("should purge the state of the idle ingesters", func(t *testing.T) {
+		cb := newIngesterCircuitBreaker(cfg)
+		for i := 0; i < cfg.MinRequests; i++ {
+			cb.record(addr, errFailure, 0, now)
+		}
+		assert.Equal(t, 1, cb.openCount())
+
+		cb.purge(now)
+		assert.Equal(t, 1, cb.openCount())
+		cb.purge(now.Add(time.Millisecond))
+		assert.Equal(t, 0, cb.openCount())
+		assert.True(t, cb.allow(addr, now))
+	})
+}
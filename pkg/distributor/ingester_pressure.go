@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+)
+
+const (
+	ingesterPushPressureThresholdFlag = "distributor.ingester-push-pressure-threshold"
+
+	// ingesterPressureStaleness is the period after which the pressure reported by an ingester is ignored.
+	// It allows to accept push requests again once ingesters stopped reporting pressure, for example
+	// because all requests have been rejected, or because they have been scaled down.
+	ingesterPressureStaleness = 30 * time.Second
+
+	// ingesterPressureUpdateInterval is how often the highest pressure reported by the ingesters is recomputed.
+	ingesterPressureUpdateInterval = time.Second
+)
+
+var (
+	errIngestersPressure                    = errors.New(globalerror.DistributorIngestersPressure.MessageWithPerInstanceLimitConfig("the write request has been rejected because the ingesters are approaching their instance limits", ingesterPushPressureThresholdFlag))
+	errInvalidIngesterPushPressureThreshold = errors.New("invalid ingester push pressure threshold, the value must be greater than or equal to 0 and lower than 1")
+)
+
+type ingesterPressureSample struct {
+	pressure  float64
+	timestamp time.Time
+}
+
+// ingesterPressureTracker tracks the pressure reported by ingesters in the push responses, and
+// rejects a share of the push requests proportional to how far the highest pressure of the ingesters
+// they're sent to is above the threshold, so that the load on ingesters is reduced gradually before
+// they reach their limits.
+type ingesterPressureTracker struct {
+	threshold float64
+	random    func() float64
+
+	mtx         sync.Mutex
+	samples     map[string]ingesterPressureSample
+	maxPressure float64
+	lastUpdate  time.Time
+}
+
+func newIngesterPressureTracker(threshold float64) *ingesterPressureTracker {
+	return &ingesterPressureTracker{
+		threshold: threshold,
+		random:    rand.Float64,
+		samples:   map[string]ingesterPressureSample{},
+	}
+}
+
+// observe records the pressure reported by the ingester with the input address.
+func (t *ingesterPressureTracker) observe(addr string, pressure float64, now time.Time) {
+	if t.threshold <= 0 {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.samples[addr] = ingesterPressureSample{pressure: pressure, timestamp: now}
+}
+
+// pressure returns the highest pressure reported by the ingesters within the staleness period.
+func (t *ingesterPressureTracker) pressure(now time.Time) float64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if now.Sub(t.lastUpdate) < ingesterPressureUpdateInterval {
+		return t.maxPressure
+	}
+
+	t.maxPressure = 0
+	for addr, sample := range t.samples {
+		if now.Sub(sample.timestamp) > ingesterPressureStaleness {
+			delete(t.samples, addr)
+			continue
+		}
+		if sample.pressure > t.maxPressure {
+			t.maxPressure = sample.pressure
+		}
+	}
+	t.lastUpdate = now
+
+	return t.maxPressure
+}
+
+// instancesPressure returns the highest pressure reported by the input ingesters within the staleness period.
+func (t *ingesterPressureTracker) instancesPressure(instances []ring.InstanceDesc, now time.Time) float64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	maxPressure := 0.0
+	for _, instance := range instances {
+		sample, ok := t.samples[instance.Addr]
+		if !ok || now.Sub(sample.timestamp) > ingesterPressureStaleness {
+			continue
+		}
+		if sample.pressure > maxPressure {
+			maxPressure = sample.pressure
+		}
+	}
+
+	return maxPressure
+}
+
+// shouldReject returns whether a push request should be rejected because of the pressure of the ingesters
+// of the input ring, which the request would be sent to.
+func (t *ingesterPressureTracker) shouldReject(r ring.ReadRing, now time.Time) bool {
+	if t.threshold <= 0 {
+		return false
+	}
+
+	// Avoid looking up the ingesters of the ring if no ingester is under pressure.
+	if t.pressure(now) <= t.threshold {
+		return false
+	}
+
+	set, err := r.GetReplicationSetForOperation(ring.WriteNoExtend)
+	if err != nil {
+		// The request will fail anyway.
+		return false
+	}
+
+	pressure := t.instancesPressure(set.Instances, now)
+	if pressure <= t.threshold {
+		return false
+	}
+
+	return t.random() < (pressure-t.threshold)/(1-t.threshold)
+}
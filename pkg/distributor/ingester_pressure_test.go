@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngesterPressureTracker(t *testing.T) {
+	now := time.Now()
+	allIngesters := &replicationSetRingMock{set: ring.ReplicationSet{Instances: []ring.InstanceDesc{{Addr: "ingester-1"}, {Addr: "ingester-2"}}}}
+
+	t.Run("should never reject requests when disabled", func(t *testing.T) {
+		tracker := newIngesterPressureTracker(0)
+		tracker.random = func() float64 { return 0 }
+
+		tracker.observe("ingester-1", 1, now)
+		assert.False(t, tracker.shouldReject(allIngesters, now))
+	})
+
+	t.Run("should reject a share of requests proportional to the highest pressure above the threshold", func(t *testing.T) {
+		tracker := newIngesterPressureTracker(0.6)
+
+		tracker.observe("ingester-1", 0.5, now)
+		tracker.observe("ingester-2", 0.9, now)
+		assert.Equal(t, 0.9, tracker.pressure(now))
+
+		// The rejection probability is (0.9 - 0.6) / (1 - 0.6) = 0.75.
+		tracker.random = func() float64 { return 0.7 }
+		assert.True(t, tracker.shouldReject(allIngesters, now))
+		tracker.random = func() float64 { return 0.8 }
+		assert.False(t, tracker.shouldReject(allIngesters, now))
+	})
+
+	t.Run("should only take into account the pressure of the ingesters the request is sent to", func(t *testing.T) {
+		tracker := newIngesterPressureTracker(0.6)
+		tracker.random = func() float64 { return 0 }
+
+		tracker.observe("ingester-1", 0.5, now)
+		tracker.observe("ingester-2", 0.9, now)
+
+		shard := &replicationSetRingMock{set: ring.ReplicationSet{Instances: []ring.InstanceDesc{{Addr: "ingester-1"}, {Addr: "ingester-3"}}}}
+		assert.False(t, tracker.shouldReject(shard, now))
+		assert.True(t, tracker.shouldReject(allIngesters, now))
+	})
+
+	t.Run("should not reject requests when the pressure is below the threshold", func(t *testing.T) {
+		tracker := newIngesterPressureTracker(0.6)
+		tracker.random = func() float64 { return 0 }
+
+		tracker.observe("ingester-1", 0.6, now)
+		assert.False(t, tracker.shouldReject(allIngesters, now))
+	})
+
+	t.Run("should ignore the stale pressure of the ingesters the request is sent to", func(t *testing.T) {
+		tracker := newIngesterPressureTracker(0.6)
+		tracker.random = func() float64 { return 0 }
+
+		tracker.observe("ingester-1", 0.9, now)
+		tracker.observe("ingester-3", 0.9, now.Add(ingesterPressureStaleness))
+
+		// The highest pressure of all the ingesters is above the threshold, but the one of ingester-1 is stale.
+		assert.False(t, tracker.shouldReject(allIngesters, now.Add(ingesterPressureStaleness+time.Second)))
+	})
+
+	t.Run("should recompute the highest pressure once per update interval, ignoring stale pressures", func(t *testing.T) {
+		tracker := newIngesterPressureTracker(0.6)
+
+		tracker.observe("ingester-1", 0.9, now)
+		tracker.observe("ingester-2", 0.7, now.Add(ingesterPressureStaleness))
+		assert.Equal(t, 0.9, tracker.pressure(now))
+
+		// The pressure is not recomputed within the update interval.
+		tracker.observe("ingester-1", 0.8, now)
+		assert.Equal(t, 0.9, tracker.pressure(now.Add(ingesterPressureUpdateInterval/2)))
+
+		// The pressure reported by ingester-1 is stale.
+		assert.Equal(t, 0.7, tracker.pressure(now.Add(ingesterPressureStaleness+time.Second)))
+	})
+}
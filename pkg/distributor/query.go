@@ -92,11 +92,25 @@ func (d *Distributor) GetIngesters(ctx context.Context) (ring.ReplicationSet, er
 	shardSize := d.limits.IngestionTenantShardSize(userID)
 	lookbackPeriod := d.cfg.ShuffleShardingLookbackPeriod
 
+	ingestersRing := d.tenantIngestersRing(userID)
+
 	if shardSize > 0 && lookbackPeriod > 0 {
-		return d.ingestersRing.ShuffleShardWithLookback(userID, shardSize, lookbackPeriod, time.Now()).GetReplicationSetForOperation(ring.Read)
+		return ingestersRing.ShuffleShardWithLookback(userID, shardSize, lookbackPeriod, time.Now()).GetReplicationSetForOperation(ring.Read)
 	}
 
-	return d.ingestersRing.GetReplicationSetForOperation(ring.Read)
+	return ingestersRing.GetReplicationSetForOperation(ring.Read)
+}
+
+// tenantIngestersRing returns the ingesters ring honoring the tenant's replication factor
+// and the witness zones.
+func (d *Distributor) tenantIngestersRing(userID string) ring.ReadRing {
+	r := newReplicationFactorRing(d.ingestersRing, d.limits.IngestionReplicationFactor(userID), d.cfg.IngestersZoneAwarenessEnabled)
+	return newWitnessZonesRing(r, d.cfg.IngestersWitnessZones)
+}
+
+// tenantQueriedReplicationFactor returns the number of replicas of each tenant's series returned on the read path.
+func (d *Distributor) tenantQueriedReplicationFactor(userID string) int {
+	return queriedReplicationFactor(d.tenantIngestersRing(userID).ReplicationFactor(), d.cfg.IngestersWitnessZones)
 }
 
 // mergeExemplarSets merges and dedupes two sets of already sorted exemplar pairs.
@@ -128,7 +142,7 @@ func mergeExemplarSets(a, b []mimirpb.Exemplar) []mimirpb.Exemplar {
 func (d *Distributor) queryIngestersExemplars(ctx context.Context, replicationSet ring.ReplicationSet, req *ingester_client.ExemplarQueryRequest) (*ingester_client.ExemplarQueryResponse, error) {
 	// Fetch exemplars from multiple ingesters in parallel, using the replicationSet
 	// to deal with consistency.
-	results, err := replicationSet.Do(ctx, 0, func(ctx context.Context, ing *ring.InstanceDesc) (interface{}, error) {
+	results, err := d.doWithHedging(ctx, replicationSet, func(ctx context.Context, ing *ring.InstanceDesc) (interface{}, error) {
 		client, err := d.ingesterPool.GetClientFor(ing.Addr)
 		if err != nil {
 			return nil, err
@@ -178,12 +192,32 @@ func mergeExemplarQueryResponses(results []interface{}) *ingester_client.Exempla
 	return &ingester_client.ExemplarQueryResponse{Timeseries: result}
 }
 
+// trackQueryStreamResponseMemory accounts the estimated memory of the series and chunks in the
+// ingester response, returning an error if the query exceeds the limit.
+func trackQueryStreamResponseMemory(memoryTracker *limiter.MemoryTracker, resp *ingester_client.QueryStreamResponse) error {
+	for _, series := range resp.Chunkseries {
+		if err := memoryTracker.AddSeries(series.Labels); err != nil {
+			return err
+		}
+	}
+	for _, series := range resp.Timeseries {
+		if err := memoryTracker.AddSeries(series.Labels); err != nil {
+			return err
+		}
+		if err := memoryTracker.AddSamples(len(series.Samples)); err != nil {
+			return err
+		}
+	}
+	return memoryTracker.Add(resp.ChunksSize())
+}
+
 // queryIngesterStream queries the ingesters using the new streaming API.
 func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ring.ReplicationSet, req *ingester_client.QueryRequest) (*ingester_client.QueryStreamResponse, error) {
 	var (
-		queryLimiter = limiter.QueryLimiterFromContextWithFallback(ctx)
-		reqStats     = stats.FromContext(ctx)
-		results      = make(chan *ingester_client.QueryStreamResponse)
+		queryLimiter  = limiter.QueryLimiterFromContextWithFallback(ctx)
+		memoryTracker = limiter.MemoryTrackerFromContextWithFallback(ctx)
+		reqStats      = stats.FromContext(ctx)
+		results       = make(chan *ingester_client.QueryStreamResponse)
 		// Note we can't signal goroutines to stop by closing 'results', because it has multiple concurrent senders.
 		stop        = make(chan struct{}) // Signal all background goroutines to stop.
 		doneReading = make(chan struct{}) // Signal that the reader has stopped.
@@ -244,7 +278,7 @@ func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ri
 	}()
 
 	// Fetch samples from multiple ingesters, and send them to the results chan
-	_, err := replicationSet.Do(ctx, 0, func(ctx context.Context, ing *ring.InstanceDesc) (interface{}, error) {
+	_, err := d.doWithHedging(ctx, replicationSet, func(ctx context.Context, ing *ring.InstanceDesc) (interface{}, error) {
 		client, err := d.ingesterPool.GetClientFor(ing.Addr)
 		if err != nil {
 			return nil, err
@@ -285,7 +319,12 @@ func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSet ri
 				}
 			}
 
-			// This goroutine could be left running after replicationSet.Do() returns,
+			// Enforce the max estimated memory of the fetched series and chunks.
+			if memoryLimitErr := trackQueryStreamResponseMemory(memoryTracker, resp); memoryLimitErr != nil {
+				return nil, validation.LimitError(memoryLimitErr.Error())
+			}
+
+			// This goroutine could be left running after d.doWithHedging() returns,
 			// so check before writing to the results chan.
 			select {
 			case <-stop:
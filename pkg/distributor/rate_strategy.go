@@ -76,6 +76,34 @@ func (s *requestRateStrategy) Burst(tenantID string) int {
 	return math.MaxInt
 }
 
+type sourceRequestRateStrategy struct {
+	limits *validation.Overrides
+}
+
+func newSourceRequestRateStrategy(limits *validation.Overrides) limiter.RateLimiterStrategy {
+	return &sourceRequestRateStrategy{
+		limits: limits,
+	}
+}
+
+func (s *sourceRequestRateStrategy) Limit(tenantID string) float64 {
+	if lm := s.limits.RequestRatePerSource(tenantID); lm > 0 {
+		return lm
+	}
+	return float64(rate.Inf)
+}
+
+func (s *sourceRequestRateStrategy) Burst(tenantID string) int {
+	if s.limits.RequestRatePerSource(tenantID) <= 0 {
+		// Burst is ignored when limit = rate.Inf
+		return 0
+	}
+	if lm := s.limits.RequestBurstSizePerSource(tenantID); lm > 0 {
+		return lm
+	}
+	return math.MaxInt
+}
+
 type ingestionRateStrategy struct {
 	limits *validation.Overrides
 }
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+
+	util_math "github.com/grafana/mimir/pkg/util/math"
+)
+
+// replicationFactorRing is a ring.ReadRing used to write and read the series of a tenant whose
+// replication factor is lower than the ingesters ring one.
+//
+// On the write path, the series are written to the first replicationFactor healthy instances of the
+// replication set returned by the wrapped ring, with a quorum computed on the tenant's replication factor.
+// On the read path, all instances are still queried, but fewer failures are tolerated, given the series
+// are replicated to fewer instances.
+type replicationFactorRing struct {
+	ring.ReadRing
+
+	replicationFactor    int
+	zoneAwarenessEnabled bool
+}
+
+// newReplicationFactorRing returns a ring.ReadRing honoring the input replication factor. The input ring is
+// returned as is if the replication factor is 0 or not lower than the ring replication factor.
+func newReplicationFactorRing(r ring.ReadRing, replicationFactor int, zoneAwarenessEnabled bool) ring.ReadRing {
+	if replicationFactor <= 0 || replicationFactor >= r.ReplicationFactor() {
+		return r
+	}
+
+	return &replicationFactorRing{
+		ReadRing:             r,
+		replicationFactor:    replicationFactor,
+		zoneAwarenessEnabled: zoneAwarenessEnabled,
+	}
+}
+
+// Get implements ring.ReadRing.
+func (r *replicationFactorRing) Get(key uint32, op ring.Operation, bufDescs []ring.InstanceDesc, bufHosts, bufZones []string) (ring.ReplicationSet, error) {
+	set, err := r.ReadRing.Get(key, op, bufDescs, bufHosts, bufZones)
+	if err != nil {
+		return ring.ReplicationSet{}, err
+	}
+
+	// The instances are returned in ring order, and belong to different zones when zone-awareness
+	// is enabled, so keeping the first ones preserves the replicas placement.
+	minSuccess := (r.replicationFactor / 2) + 1
+	if len(set.Instances) < minSuccess {
+		return ring.ReplicationSet{}, fmt.Errorf("at least %d live replicas required, could only find %d", minSuccess, len(set.Instances))
+	}
+	if len(set.Instances) > r.replicationFactor {
+		set.Instances = set.Instances[:r.replicationFactor]
+	}
+
+	set.MaxErrors = len(set.Instances) - minSuccess
+	return set, nil
+}
+
+// GetReplicationSetForOperation implements ring.ReadRing.
+func (r *replicationFactorRing) GetReplicationSetForOperation(op ring.Operation) (ring.ReplicationSet, error) {
+	set, err := r.ReadRing.GetReplicationSetForOperation(op)
+	if err != nil {
+		return ring.ReplicationSet{}, err
+	}
+
+	ringReplicationFactor := r.ReadRing.ReplicationFactor()
+
+	if r.zoneAwarenessEnabled {
+		// The wrapped ring tolerates up to half of the replicated zones to be unavailable. We assume the
+		// ring spans at least as many zones as its replication factor, which is the conservative choice.
+		set.MaxUnavailableZones -= ringReplicationFactor/2 - r.replicationFactor/2
+		if set.MaxUnavailableZones < 0 {
+			return ring.ReplicationSet{}, ring.ErrTooManyUnhealthyInstances
+		}
+		return set, nil
+	}
+
+	numInstances := r.ReadRing.InstancesCount()
+	set.MaxErrors -= requiredInstances(numInstances, r.replicationFactor) - requiredInstances(numInstances, ringReplicationFactor)
+	if set.MaxErrors < 0 {
+		return ring.ReplicationSet{}, ring.ErrTooManyUnhealthyInstances
+	}
+	return set, nil
+}
+
+// ReplicationFactor implements ring.ReadRing.
+func (r *replicationFactorRing) ReplicationFactor() int {
+	return r.replicationFactor
+}
+
+// ShuffleShard implements ring.ReadRing.
+func (r *replicationFactorRing) ShuffleShard(identifier string, size int) ring.ReadRing {
+	return newReplicationFactorRing(r.ReadRing.ShuffleShard(identifier, size), r.replicationFactor, r.zoneAwarenessEnabled)
+}
+
+// ShuffleShardWithLookback implements ring.ReadRing.
+func (r *replicationFactorRing) ShuffleShardWithLookback(identifier string, size int, lookbackPeriod time.Duration, now time.Time) ring.ReadRing {
+	return newReplicationFactorRing(r.ReadRing.ShuffleShardWithLookback(identifier, size, lookbackPeriod, now), r.replicationFactor, r.zoneAwarenessEnabled)
+}
+
+// requiredInstances returns the number of instances required to successfully read from a ring with
+// numInstances instances and the input replication factor, when zone-awareness is disabled.
+func requiredInstances(numInstances, replicationFactor int) int {
+	return util_math.Max(numInstances, replicationFactor) - replicationFactor/2
+}
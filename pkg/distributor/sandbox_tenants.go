@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/sandbox"
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/push"
+)
+
+// Max number of push requests mirrored to sandbox tenants in flight. The requests exceeding the limit are dropped,
+// so that mirroring can't pile up goroutines and memory when the sandbox tenants are slow to ingest.
+const maxInflightSandboxMirroringRequests = 100
+
+var errSandboxTenantExpired = globalerror.SandboxTenantExpired.Message("the write request has been rejected because the tenant is an expired sandbox tenant")
+
+// prePushSandboxMiddleware is used as push.Func middleware in front of push method.
+// It mirrors a fraction of the series of the tenant to its sandbox tenants, if any, and rejects
+// the write requests of expired sandbox tenants.
+func (d *Distributor) prePushSandboxMiddleware(next push.Func) push.Func {
+	if d.cfg.SandboxTenants == nil {
+		// Sandbox tenants are disabled, no need to wrap "next".
+		return next
+	}
+
+	return func(ctx context.Context, pushReq *push.Request) (*mimirpb.WriteResponse, error) {
+		cleanupInDefer := true
+		defer func() {
+			if cleanupInDefer {
+				pushReq.CleanUp()
+			}
+		}()
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if t, ok := d.cfg.SandboxTenants.Get(userID); ok && t.Expired(now) {
+			return nil, httpgrpc.Errorf(http.StatusBadRequest, errSandboxTenantExpired)
+		}
+
+		if sandboxes := d.cfg.SandboxTenants.ActiveSandboxesOf(userID, now); len(sandboxes) > 0 {
+			req, err := pushReq.WriteRequest()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, t := range sandboxes {
+				d.mirrorToSandboxTenant(t, req)
+			}
+		}
+
+		cleanupInDefer = false
+		return next(ctx, pushReq)
+	}
+}
+
+// mirrorToSandboxTenant asynchronously pushes a copy of the series of the request selected for the sandbox tenant.
+// The series are selected by hashing their labels, so that the same series are always mirrored. The request is
+// dropped if too many mirrored requests are in flight.
+func (d *Distributor) mirrorToSandboxTenant(t sandbox.Tenant, req *mimirpb.WriteRequest) {
+	select {
+	case d.sandboxMirroringInflight <- struct{}{}:
+	default:
+		d.sandboxMirroringDropped.Inc()
+		return
+	}
+
+	mirrored := mimirpb.PreallocTimeseriesSliceFromPool()
+	for _, ts := range req.Timeseries {
+		if !isSandboxSeries(t, ts.Labels) {
+			continue
+		}
+
+		// The series are deep-copied because the request buffers are reused once the request is done.
+		dst := mimirpb.DeepCopyTimeseries(mimirpb.PreallocTimeseries{}, ts, true)
+		dst.Histograms = append(dst.Histograms, ts.Histograms...)
+		mirrored = append(mirrored, dst)
+	}
+
+	if len(mirrored) == 0 {
+		mimirpb.ReuseSlice(mirrored)
+		<-d.sandboxMirroringInflight
+		return
+	}
+
+	pushReq := push.NewParsedRequest(&mimirpb.WriteRequest{
+		Timeseries:              mirrored,
+		Source:                  req.Source,
+		SkipLabelNameValidation: req.SkipLabelNameValidation,
+	})
+	pushReq.AddCleanup(func() {
+		mimirpb.ReuseSlice(mirrored)
+	})
+
+	go func() {
+		defer func() { <-d.sandboxMirroringInflight }()
+
+		ctx := user.InjectOrgID(context.Background(), t.ID)
+		if _, err := d.PushWithMiddlewares(ctx, pushReq); err != nil {
+			d.sandboxMirroringFailures.Inc()
+			level.Debug(d.log).Log("msg", "failed to mirror series to sandbox tenant", "user", t.SourceID, "sandbox", t.ID, "err", err)
+		}
+	}()
+}
+
+// isSandboxSeries returns whether the series with the input labels is mirrored to the sandbox tenant.
+// The series are selected with the FNV-1a hash, rather than the FNV-1 hash used to shard series, because
+// the high bits of the FNV-1 hash barely change between series whose labels only differ in their last characters.
+func isSandboxSeries(t sandbox.Tenant, lbls []mimirpb.LabelAdapter) bool {
+	if t.Fraction >= 1 {
+		return true
+	}
+
+	h := ingester_client.HashAdd32a(ingester_client.HashNew32a(), t.ID)
+	for _, l := range lbls {
+		h = ingester_client.HashAdd32a(h, l.Name)
+		h = ingester_client.HashAdd32a(h, l.Value)
+	}
+	return h <= uint32(t.Fraction*math.MaxUint32)
+}
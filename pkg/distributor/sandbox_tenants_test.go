@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/sandbox"
+)
+
+func TestDistributor_SandboxTenants(t *testing.T) {
+	ctx := context.Background()
+
+	var sandboxCfg sandbox.Config
+	flagext.DefaultValues(&sandboxCfg)
+	sandboxCfg.Enabled = true
+
+	kvStore, closer := consul.NewInMemoryClient(sandbox.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+	sandboxCfg.KVStore.Mock = kvStore
+
+	registry, err := sandbox.NewRegistry(sandboxCfg, nil, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, registry))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, registry)) })
+
+	sandboxFull := sandbox.TenantIDPrefix + "full"
+	sandboxHalf := sandbox.TenantIDPrefix + "half"
+
+	now := time.Now()
+	_, err = registry.Create(ctx, sandboxFull, "user", 1, time.Hour, now)
+	require.NoError(t, err)
+	_, err = registry.Create(ctx, sandboxHalf, "user", 0.5, time.Hour, now)
+	require.NoError(t, err)
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+		sandboxTenants:  registry,
+	})
+
+	var series []labels.Labels
+	for i := 0; i < 20; i++ {
+		series = append(series, labels.FromStrings(labels.MetricName, fmt.Sprintf("series_%d", i)))
+	}
+
+	req := mimirpb.ToWriteRequest(series, make([]mimirpb.Sample, len(series)), nil, nil, mimirpb.API)
+	for i := range req.Timeseries {
+		req.Timeseries[i].Samples[0].TimestampMs = now.UnixMilli()
+	}
+	_, err = ds[0].Push(user.InjectOrgID(ctx, "user"), req)
+	require.NoError(t, err)
+
+	// All the series are mirrored to the first sandbox tenant, and only a subset
+	// selected by hashing the series labels to the second one.
+	half := sandbox.Tenant{ID: sandboxHalf, Fraction: 0.5}
+	var expectedHalf int
+	for _, s := range series {
+		lbls := mimirpb.FromLabelsToLabelAdapters(s)
+		assert.True(t, ingestersHaveSeries(ingesters, shardByAllLabels("user", lbls)))
+
+		test.Poll(t, time.Second, true, func() interface{} {
+			return ingestersHaveSeries(ingesters, shardByAllLabels(sandboxFull, lbls))
+		})
+
+		if isSandboxSeries(half, lbls) {
+			expectedHalf++
+			test.Poll(t, time.Second, true, func() interface{} {
+				return ingestersHaveSeries(ingesters, shardByAllLabels(sandboxHalf, lbls))
+			})
+		}
+	}
+	assert.Greater(t, expectedHalf, 0)
+	assert.Less(t, expectedHalf, len(series))
+
+	for _, s := range series {
+		lbls := mimirpb.FromLabelsToLabelAdapters(s)
+		if !isSandboxSeries(half, lbls) {
+			assert.False(t, ingestersHaveSeries(ingesters, shardByAllLabels(sandboxHalf, lbls)))
+		}
+	}
+
+	// Once expired, the write requests of the sandbox tenant are rejected.
+	require.NoError(t, registry.Expire(ctx, sandboxFull, time.Now()))
+
+	req = mimirpb.ToWriteRequest(series[:1], []mimirpb.Sample{{TimestampMs: now.UnixMilli()}}, nil, nil, mimirpb.API)
+	_, err = ds[0].Push(user.InjectOrgID(ctx, sandboxFull), req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "err-mimir-sandbox-tenant-expired")
+}
+
+func TestDistributor_SandboxTenants_ShouldDropMirroredRequestsWhenTooManyInflight(t *testing.T) {
+	ctx := context.Background()
+
+	var sandboxCfg sandbox.Config
+	flagext.DefaultValues(&sandboxCfg)
+	sandboxCfg.Enabled = true
+
+	kvStore, closer := consul.NewInMemoryClient(sandbox.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+	sandboxCfg.KVStore.Mock = kvStore
+
+	registry, err := sandbox.NewRegistry(sandboxCfg, nil, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, registry))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, registry)) })
+
+	sandboxID := sandbox.TenantIDPrefix + "full"
+	now := time.Now()
+	_, err = registry.Create(ctx, sandboxID, "user", 1, time.Hour, now)
+	require.NoError(t, err)
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+		sandboxTenants:  registry,
+	})
+
+	// Take all the slots of the mirrored requests in flight.
+	for i := 0; i < maxInflightSandboxMirroringRequests; i++ {
+		ds[0].sandboxMirroringInflight <- struct{}{}
+	}
+
+	series := labels.FromStrings(labels.MetricName, "series_1")
+	req := mimirpb.ToWriteRequest([]labels.Labels{series}, []mimirpb.Sample{{TimestampMs: now.UnixMilli()}}, nil, nil, mimirpb.API)
+	_, err = ds[0].Push(user.InjectOrgID(ctx, "user"), req)
+	require.NoError(t, err)
+
+	lbls := mimirpb.FromLabelsToLabelAdapters(series)
+	assert.True(t, ingestersHaveSeries(ingesters, shardByAllLabels("user", lbls)))
+	assert.False(t, ingestersHaveSeries(ingesters, shardByAllLabels(sandboxID, lbls)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ds[0].sandboxMirroringDropped))
+}
+
+func ingestersHaveSeries(ingesters []mockIngester, hash uint32) bool {
+	for i := range ingesters {
+		if _, ok := ingesters[i].series()[hash]; ok {
+			return true
+		}
+	}
+	return false
+}
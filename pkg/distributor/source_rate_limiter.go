@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/limiter"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// sourceRateLimiterIdleTimeout is how long the rate limiter of a source is kept after its last request.
+	sourceRateLimiterIdleTimeout = 10 * time.Minute
+	sourceRateLimiterPurgePeriod = time.Minute
+
+	// sourceRateLimiterMaxSourcesPerTenant is the max number of sources tracked for each tenant, so that the
+	// memory used by the limiters is bounded. The sources of a tenant exceeding it share the limiter of the
+	// overflowSource, until the limiters of the idle sources are purged.
+	sourceRateLimiterMaxSourcesPerTenant = 10000
+	overflowSource                       = ""
+)
+
+type sourceLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// sourceRateLimiter is a rate limiter of the requests of each source within a tenant, like an
+// agent identified by a request header or by its address. Contrary to the dskit rate limiter,
+// the limiters of the sources which don't send requests anymore are purged.
+type sourceRateLimiter struct {
+	strategy limiter.RateLimiterStrategy
+
+	maxSourcesPerTenant int
+
+	mtx      sync.Mutex
+	limiters map[string]map[string]*sourceLimiter // By tenant and source.
+}
+
+func newSourceRateLimiter(strategy limiter.RateLimiterStrategy) *sourceRateLimiter {
+	return &sourceRateLimiter{
+		strategy:            strategy,
+		maxSourcesPerTenant: sourceRateLimiterMaxSourcesPerTenant,
+		limiters:            map[string]map[string]*sourceLimiter{},
+	}
+}
+
+// AllowN reports whether n requests of the source of the tenant may happen at time now.
+func (l *sourceRateLimiter) AllowN(now time.Time, userID, source string, n int) bool {
+	limit := rate.Limit(l.strategy.Limit(userID))
+	if limit == rate.Inf {
+		return true
+	}
+	burst := l.strategy.Burst(userID)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	tenantLimiters := l.limiters[userID]
+	if tenantLimiters == nil {
+		tenantLimiters = map[string]*sourceLimiter{}
+		l.limiters[userID] = tenantLimiters
+	}
+
+	entry, ok := tenantLimiters[source]
+	if !ok && len(tenantLimiters) >= l.maxSourcesPerTenant {
+		source = overflowSource
+		entry, ok = tenantLimiters[source]
+	}
+	if !ok {
+		entry = &sourceLimiter{limiter: rate.NewLimiter(limit, burst)}
+		tenantLimiters[source] = entry
+	}
+	entry.lastSeen = now
+
+	// Ensure the limiter's limit and burst match the expected value, given they can change at runtime.
+	if entry.limiter.Limit() != limit {
+		entry.limiter.SetLimitAt(now, limit)
+	}
+	if entry.limiter.Burst() != burst {
+		entry.limiter.SetBurstAt(now, burst)
+	}
+
+	return entry.limiter.AllowN(now, n)
+}
+
+// purge removes the limiters of the sources whose last request is older than idleBefore.
+func (l *sourceRateLimiter) purge(idleBefore time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for userID, tenantLimiters := range l.limiters {
+		for source, entry := range tenantLimiters {
+			if entry.lastSeen.Before(idleBefore) {
+				delete(tenantLimiters, source)
+			}
+		}
+		if len(tenantLimiters) == 0 {
+			delete(l.limiters, userID)
+		}
+	}
+}
+
+// sources returns the number of sources currently tracked.
+func (l *sourceRateLimiter) sources() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	count := 0
+	for _, tenantLimiters := range l.limiters {
+		count += len(tenantLimiters)
+	}
+	return count
+}
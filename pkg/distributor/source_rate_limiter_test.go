@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/middleware"
+	"github.com/weaveworks/common/user"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/util/push"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestSourceRequestRateStrategy(t *testing.T) {
+	t.Run("rate limiter should share the limit across the number of distributors", func(t *testing.T) {
+		overrides, err := validation.NewOverrides(validation.Limits{
+			RequestRatePerSource:      float64(10),
+			RequestBurstSizePerSource: 5,
+		}, nil)
+		require.NoError(t, err)
+
+		mockRing := newReadLifecyclerMock()
+		mockRing.On("HealthyInstancesCount").Return(2)
+
+		strategy := newGlobalRateStrategy(newSourceRequestRateStrategy(overrides), mockRing)
+		assert.Equal(t, float64(5), strategy.Limit("test"))
+		assert.Equal(t, 5, strategy.Burst("test"))
+	})
+
+	t.Run("rate limiter should be unlimited if the limit is disabled", func(t *testing.T) {
+		overrides, err := validation.NewOverrides(validation.Limits{}, nil)
+		require.NoError(t, err)
+
+		strategy := newSourceRequestRateStrategy(overrides)
+		assert.Equal(t, float64(rate.Inf), strategy.Limit("test"))
+		assert.Equal(t, 0, strategy.Burst("test"))
+	})
+}
+
+func TestSourceRateLimiter(t *testing.T) {
+	overrides, err := validation.NewOverrides(validation.Limits{
+		RequestRatePerSource:      float64(1),
+		RequestBurstSizePerSource: 2,
+	}, nil)
+	require.NoError(t, err)
+
+	l := newSourceRateLimiter(newSourceRequestRateStrategy(overrides))
+	now := time.Now()
+
+	// Each source of each tenant has its own limit.
+	assert.True(t, l.AllowN(now, "user-1", "source-1", 1))
+	assert.True(t, l.AllowN(now, "user-1", "source-1", 1))
+	assert.False(t, l.AllowN(now, "user-1", "source-1", 1))
+	assert.True(t, l.AllowN(now, "user-1", "source-2", 1))
+	assert.True(t, l.AllowN(now, "user-2", "source-1", 1))
+	assert.Equal(t, 3, l.sources())
+
+	// The source is allowed again once the bucket is refilled.
+	assert.True(t, l.AllowN(now.Add(time.Second), "user-1", "source-1", 1))
+
+	// Only the limiters of the idle sources are purged.
+	l.purge(now.Add(time.Millisecond))
+	assert.Equal(t, 1, l.sources())
+	l.purge(now.Add(2 * time.Second))
+	assert.Equal(t, 0, l.sources())
+}
+
+func TestSourceRateLimiter_MaxSourcesPerTenant(t *testing.T) {
+	overrides, err := validation.NewOverrides(validation.Limits{
+		RequestRatePerSource:      float64(1),
+		RequestBurstSizePerSource: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	l := newSourceRateLimiter(newSourceRequestRateStrategy(overrides))
+	l.maxSourcesPerTenant = 2
+	now := time.Now()
+
+	assert.True(t, l.AllowN(now, "user-1", "source-1", 1))
+	assert.True(t, l.AllowN(now, "user-1", "source-2", 1))
+
+	// The sources exceeding the max number of sources of the tenant share the same limiter.
+	assert.True(t, l.AllowN(now, "user-1", "source-3", 1))
+	assert.False(t, l.AllowN(now, "user-1", "source-4", 1))
+	assert.Equal(t, 3, l.sources())
+
+	// The tracked sources keep their own limiter, and the other tenants aren't affected.
+	assert.False(t, l.AllowN(now, "user-1", "source-1", 1))
+	assert.True(t, l.AllowN(now, "user-2", "source-3", 1))
+	assert.Equal(t, 4, l.sources())
+
+	// Once the idle sources are purged, new sources get their own limiter again.
+	l.purge(now.Add(time.Millisecond))
+	assert.True(t, l.AllowN(now.Add(time.Second), "user-1", "source-4", 1))
+	assert.Equal(t, 1, l.sources())
+}
+
+func TestDistributor_PushSourceRequestRateLimiter(t *testing.T) {
+	const sourceHeader = "X-Agent-ID"
+
+	tests := map[string]struct {
+		sourceHeader        string
+		requests            []*http.Request
+		expectedStatusCodes []int
+	}{
+		"should limit the requests of each source identified by the header": {
+			sourceHeader: sourceHeader,
+			requests: []*http.Request{
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.1:5678"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-2"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, nil, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, nil, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, nil, "10.0.0.1:1234"),
+			},
+			// The requests without the header are not subject to the per-source limit.
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		"should not share the limit of a header value across source addresses": {
+			sourceHeader: sourceHeader,
+			requests: []*http.Request{
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.2:1234"),
+			},
+			// A client can't consume the limit of another client by sending its header value.
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		"should not use the spoofable forwarding headers as source address": {
+			requests: []*http.Request{
+				newSourceRateLimiterTestRequest(t, map[string]string{"X-Forwarded-For": "192.168.0.1"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{"X-Forwarded-For": "192.168.0.2"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{"X-Forwarded-For": "192.168.0.3"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{"X-Forwarded-For": "192.168.0.3"}, "10.0.0.2:1234"),
+			},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK},
+		},
+		"should limit the requests of each source address if the header is not configured": {
+			requests: []*http.Request{
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-2"}, "10.0.0.1:5678"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-3"}, "10.0.0.1:1234"),
+				newSourceRateLimiterTestRequest(t, map[string]string{sourceHeader: "agent-1"}, "10.0.0.2:1234"),
+			},
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK},
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.RequestRatePerSource = 1
+			limits.RequestBurstSizePerSource = 2
+			limits.RequestRateSourceHeader = testData.sourceHeader
+
+			distributors, _, regs := prepare(t, prepConfig{
+				numIngesters:    3,
+				happyIngesters:  3,
+				numDistributors: 1,
+				limits:          limits,
+			})
+
+			// The source IPs extraction is enabled, to check that the forwarding headers aren't trusted.
+			sourceIPs, err := middleware.NewSourceIPs("", "")
+			require.NoError(t, err)
+			handler := push.Handler(100000, sourceIPs, false, distributors[0].PushWithMiddlewares)
+
+			rateLimited := 0
+			for i, req := range testData.requests {
+				resp := httptest.NewRecorder()
+				handler.ServeHTTP(resp, req)
+				assert.Equal(t, testData.expectedStatusCodes[i], resp.Code, "request %d", i)
+
+				if resp.Code == http.StatusTooManyRequests {
+					rateLimited++
+				}
+			}
+
+			assert.Equal(t, float64(rateLimited), testutil.ToFloat64(distributors[0].discardedRequestsSourceRateLimited.WithLabelValues("user")))
+			assert.NoError(t, testutil.GatherAndCompare(regs[0], bytes.NewBufferString(`
+				# HELP cortex_distributor_request_rate_limited_sources Current number of sources of write requests tracked by the per-source request rate limit.
+				# TYPE cortex_distributor_request_rate_limited_sources gauge
+				cortex_distributor_request_rate_limited_sources 2
+			`), "cortex_distributor_request_rate_limited_sources"))
+		})
+	}
+}
+
+func newSourceRateLimiterTestRequest(t *testing.T, headers map[string]string, remoteAddr string) *http.Request {
+	data, err := makeWriteRequest(0, 1, 0, false, false).Marshal()
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/push", bytes.NewReader(snappy.Encode(nil, data)))
+	req = req.WithContext(user.InjectOrgID(context.Background(), "user"))
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
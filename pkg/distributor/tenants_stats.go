@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/grafana/dskit/concurrency"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// LimitsClassDefault is the limits class of tenants without per-tenant limits.
+	LimitsClassDefault = "default"
+
+	// LimitsClassOverrides is the limits class of tenants with per-tenant limits overriding the default ones.
+	LimitsClassOverrides = "overrides"
+
+	// How many bucket indexes to read concurrently.
+	tenantsStatsConcurrency = 16
+)
+
+// TenantStats summarizes a tenant known to the cluster.
+type TenantStats struct {
+	UserID string `json:"userID"`
+
+	// Number of in-memory series in the ingesters, divided by the replication factor.
+	ActiveSeries  uint64  `json:"activeSeries"`
+	IngestionRate float64 `json:"ingestionRate"`
+
+	// Blocks in the tenant's bucket index, excluding the ones marked for deletion. The time range
+	// of the blocks is not set if the tenant has no blocks, or no bucket index.
+	Blocks                int            `json:"blocks"`
+	OldestBlockTime       *time.Time     `json:"oldestBlockTime,omitempty"`
+	NewestBlockTime       *time.Time     `json:"newestBlockTime,omitempty"`
+	BucketIndexUpdatedAt  *time.Time     `json:"bucketIndexUpdatedAt,omitempty"`
+	MarkedForDeletion     bool           `json:"markedForDeletion,omitempty"`
+	BlocksRetentionPeriod model.Duration `json:"blocksRetentionPeriod"`
+
+	// Whether the tenant has per-tenant limits overriding the default ones.
+	LimitsClass string `json:"limitsClass"`
+}
+
+// TenantsStatsHandler returns an handler listing all tenants known to the cluster, with their summary
+// statistics. The tenants are discovered from both the ingesters and the blocks storage, and the blocks
+// statistics are read from the tenants' bucket index.
+func (d *Distributor) TenantsStatsHandler(bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats, err := d.TenantsStats(r.Context(), bkt, cfgProvider)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		util.WriteJSONResponse(w, stats)
+	})
+}
+
+// TenantsStats returns the summary statistics of all tenants known to the cluster, sorted by tenant ID.
+func (d *Distributor) TenantsStats(ctx context.Context, bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider) ([]TenantStats, error) {
+	ingesterStats, err := d.AllUserStats(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tenants stats from ingesters")
+	}
+
+	users, markedForDeletion, err := mimir_tsdb.NewUsersScanner(bkt, mimir_tsdb.AllUsers, d.log).ScanUsers(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tenants in the blocks storage")
+	}
+
+	tenants := map[string]*TenantStats{}
+	getTenant := func(userID string) *TenantStats {
+		t, ok := tenants[userID]
+		if !ok {
+			t = &TenantStats{UserID: userID}
+			tenants[userID] = t
+		}
+		return t
+	}
+
+	for _, s := range ingesterStats {
+		t := getTenant(s.UserID)
+		replicationFactor := d.tenantQueriedReplicationFactor(s.UserID)
+		t.ActiveSeries = s.NumSeries / uint64(replicationFactor)
+		t.IngestionRate = s.IngestionRate / float64(replicationFactor)
+	}
+	for _, userID := range users {
+		getTenant(userID)
+	}
+	for _, userID := range markedForDeletion {
+		getTenant(userID).MarkedForDeletion = true
+	}
+
+	userIDs := make([]string, 0, len(tenants))
+	for userID, t := range tenants {
+		userIDs = append(userIDs, userID)
+		t.BlocksRetentionPeriod = model.Duration(d.limits.CompactorBlocksRetentionPeriod(userID))
+		t.LimitsClass = LimitsClassDefault
+		if d.limits.HasTenantOverrides(userID) {
+			t.LimitsClass = LimitsClassOverrides
+		}
+	}
+	sort.Strings(userIDs)
+
+	// The map isn't modified anymore, and each tenant is updated by a single goroutine.
+	err = concurrency.ForEachUser(ctx, userIDs, tenantsStatsConcurrency, func(ctx context.Context, userID string) error {
+		idx, err := bucketindex.ReadIndex(ctx, bkt, userID, cfgProvider, d.log)
+		if errors.Is(err, bucketindex.ErrIndexNotFound) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read bucket index of tenant %s", userID)
+		}
+
+		setBlocksStats(tenants[userID], idx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TenantStats, 0, len(userIDs))
+	for _, userID := range userIDs {
+		result = append(result, *tenants[userID])
+	}
+	return result, nil
+}
+
+func setBlocksStats(t *TenantStats, idx *bucketindex.Index) {
+	updatedAt := idx.GetUpdatedAt().UTC()
+	t.BucketIndexUpdatedAt = &updatedAt
+
+	deleted := make(map[string]struct{}, len(idx.BlockDeletionMarks))
+	for _, m := range idx.BlockDeletionMarks {
+		deleted[m.ID.String()] = struct{}{}
+	}
+
+	var minTime, maxTime int64
+	for _, b := range idx.Blocks {
+		if _, ok := deleted[b.ID.String()]; ok {
+			continue
+		}
+
+		if t.Blocks == 0 || b.MinTime < minTime {
+			minTime = b.MinTime
+		}
+		if t.Blocks == 0 || b.MaxTime > maxTime {
+			maxTime = b.MaxTime
+		}
+		t.Blocks++
+	}
+
+	if t.Blocks > 0 {
+		oldest, newest := util.TimeFromMillis(minTime).UTC(), util.TimeFromMillis(maxTime).UTC()
+		t.OldestBlockTime, t.NewestBlockTime = &oldest, &newest
+	}
+}
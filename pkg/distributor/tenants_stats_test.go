@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestDistributor_TenantsStats(t *testing.T) {
+	ctx := context.Background()
+
+	ds, ingesters, _ := prepare(t, prepConfig{
+		numIngesters:      3,
+		happyIngesters:    3,
+		numDistributors:   1,
+		replicationFactor: 3,
+	})
+
+	// Each ingester has a replica of the series of user-1 and user-2.
+	for i := range ingesters {
+		ingesters[i].stats = client.UsersStatsResponse{Stats: []*client.UserIDStatsResponse{
+			{UserId: "user-1", Data: &client.UserStatsResponse{NumSeries: 10, IngestionRate: 3}},
+			{UserId: "user-2", Data: &client.UserStatsResponse{NumSeries: 20}},
+		}}
+	}
+
+	// user-2 has per-tenant limits.
+	defaults := validation.Limits{}
+	flagext.DefaultValues(&defaults)
+	defaults.CompactorBlocksRetentionPeriod = model.Duration(24 * time.Hour)
+	tenantLimits := defaults
+	tenantLimits.CompactorBlocksRetentionPeriod = model.Duration(48 * time.Hour)
+	overrides, err := validation.NewOverrides(defaults, validation.NewMockTenantLimits(map[string]*validation.Limits{"user-2": &tenantLimits}))
+	require.NoError(t, err)
+	ds[0].limits = overrides
+
+	// user-1 and user-3 have blocks in the storage, and user-4 is marked for deletion.
+	bkt := objstore.NewInMemBucket()
+	deletedBlock := ulid.MustNew(4, nil)
+	require.NoError(t, bucketindex.WriteIndex(ctx, bkt, "user-1", nil, &bucketindex.Index{
+		Version: bucketindex.IndexVersion1,
+		Blocks: bucketindex.Blocks{
+			{ID: ulid.MustNew(1, nil), MinTime: 2000, MaxTime: 3000},
+			{ID: ulid.MustNew(2, nil), MinTime: 1000, MaxTime: 2000},
+			{ID: deletedBlock, MinTime: 0, MaxTime: 4000},
+		},
+		BlockDeletionMarks: bucketindex.BlockDeletionMarks{{ID: deletedBlock}},
+		UpdatedAt:          100,
+	}))
+	require.NoError(t, bucketindex.WriteIndex(ctx, bkt, "user-3", nil, &bucketindex.Index{
+		Version:   bucketindex.IndexVersion1,
+		UpdatedAt: 200,
+	}))
+	require.NoError(t, mimir_tsdb.WriteTenantDeletionMark(ctx, bkt, "user-4", nil, mimir_tsdb.NewTenantDeletionMark(time.Now())))
+
+	stats, err := ds[0].TenantsStats(ctx, bkt, nil)
+	require.NoError(t, err)
+
+	timePtr := func(t time.Time) *time.Time { return &t }
+
+	assert.Equal(t, []TenantStats{
+		{
+			UserID:                "user-1",
+			ActiveSeries:          10,
+			IngestionRate:         3,
+			Blocks:                2,
+			OldestBlockTime:       timePtr(time.UnixMilli(1000).UTC()),
+			NewestBlockTime:       timePtr(time.UnixMilli(3000).UTC()),
+			BucketIndexUpdatedAt:  timePtr(time.Unix(100, 0).UTC()),
+			BlocksRetentionPeriod: model.Duration(24 * time.Hour),
+			LimitsClass:           LimitsClassDefault,
+		}, {
+			UserID:                "user-2",
+			ActiveSeries:          20,
+			BlocksRetentionPeriod: model.Duration(48 * time.Hour),
+			LimitsClass:           LimitsClassOverrides,
+		}, {
+			UserID:                "user-3",
+			BucketIndexUpdatedAt:  timePtr(time.Unix(200, 0).UTC()),
+			BlocksRetentionPeriod: model.Duration(24 * time.Hour),
+			LimitsClass:           LimitsClassDefault,
+		}, {
+			UserID:                "user-4",
+			MarkedForDeletion:     true,
+			BlocksRetentionPeriod: model.Duration(24 * time.Hour),
+			LimitsClass:           LimitsClassDefault,
+		},
+	}, stats)
+}
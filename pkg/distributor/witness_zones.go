@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"time"
+
+	"github.com/grafana/dskit/ring"
+
+	util_math "github.com/grafana/mimir/pkg/util/math"
+)
+
+// witnessZonesRing is a ring.ReadRing excluding the ingesters running in witness zones from the read path.
+//
+// Witness ingesters acknowledge the writes without holding any queryable state, so they're still returned
+// on the write path, but never queried. Given a write may have reached the quorum with the acknowledgement
+// of a witness, the read path tolerates one less unavailable zone for each witness zone.
+type witnessZonesRing struct {
+	ring.ReadRing
+
+	witnessZones map[string]struct{}
+}
+
+// newWitnessZonesRing returns a ring.ReadRing excluding the input witness zones from the read path.
+// The input ring is returned as is if there are no witness zones.
+func newWitnessZonesRing(r ring.ReadRing, witnessZones []string) ring.ReadRing {
+	if len(witnessZones) == 0 {
+		return r
+	}
+
+	zones := make(map[string]struct{}, len(witnessZones))
+	for _, zone := range witnessZones {
+		zones[zone] = struct{}{}
+	}
+
+	return &witnessZonesRing{
+		ReadRing:     r,
+		witnessZones: zones,
+	}
+}
+
+// GetReplicationSetForOperation implements ring.ReadRing.
+func (r *witnessZonesRing) GetReplicationSetForOperation(op ring.Operation) (ring.ReplicationSet, error) {
+	set, err := r.ReadRing.GetReplicationSetForOperation(op)
+	if err != nil {
+		return ring.ReplicationSet{}, err
+	}
+
+	// The zones which already failed have been removed from the set, and subtracted from the
+	// unavailable zones tolerated by the wrapped ring, so we only account for the remaining ones.
+	removedZones := map[string]struct{}{}
+	instances := make([]ring.InstanceDesc, 0, len(set.Instances))
+	for _, instance := range set.Instances {
+		if _, ok := r.witnessZones[instance.Zone]; ok {
+			removedZones[instance.Zone] = struct{}{}
+			continue
+		}
+		instances = append(instances, instance)
+	}
+
+	set.Instances = instances
+	set.MaxUnavailableZones -= len(removedZones)
+	if set.MaxUnavailableZones < 0 || len(set.Instances) == 0 {
+		return ring.ReplicationSet{}, ring.ErrTooManyUnhealthyInstances
+	}
+	return set, nil
+}
+
+// ShuffleShard implements ring.ReadRing.
+func (r *witnessZonesRing) ShuffleShard(identifier string, size int) ring.ReadRing {
+	return &witnessZonesRing{ReadRing: r.ReadRing.ShuffleShard(identifier, size), witnessZones: r.witnessZones}
+}
+
+// ShuffleShardWithLookback implements ring.ReadRing.
+func (r *witnessZonesRing) ShuffleShardWithLookback(identifier string, size int, lookbackPeriod time.Duration, now time.Time) ring.ReadRing {
+	return &witnessZonesRing{ReadRing: r.ReadRing.ShuffleShardWithLookback(identifier, size, lookbackPeriod, now), witnessZones: r.witnessZones}
+}
+
+// queriedReplicationFactor returns the number of replicas of each series returned on the read path,
+// given the input replication factor and witness zones.
+func queriedReplicationFactor(replicationFactor int, witnessZones []string) int {
+	return util_math.Max(replicationFactor-len(witnessZones), 1)
+}
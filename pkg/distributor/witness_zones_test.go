@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"testing"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWitnessZonesRing_GetReplicationSetForOperation(t *testing.T) {
+	instances := func(zones ...string) []ring.InstanceDesc {
+		out := make([]ring.InstanceDesc, 0, len(zones))
+		for _, zone := range zones {
+			out = append(out, ring.InstanceDesc{Addr: "ingester-" + zone, Zone: zone})
+		}
+		return out
+	}
+
+	tests := map[string]struct {
+		set           ring.ReplicationSet
+		witnessZones  []string
+		expectedSet   ring.ReplicationSet
+		expectedError error
+	}{
+		"should return the replication set as is if there are no witness zones": {
+			set:         ring.ReplicationSet{Instances: instances("a", "b", "c"), MaxUnavailableZones: 1},
+			expectedSet: ring.ReplicationSet{Instances: instances("a", "b", "c"), MaxUnavailableZones: 1},
+		},
+		"should exclude the witness zone and tolerate one less unavailable zone": {
+			set:          ring.ReplicationSet{Instances: instances("a", "b", "w"), MaxUnavailableZones: 1},
+			witnessZones: []string{"w"},
+			expectedSet:  ring.ReplicationSet{Instances: instances("a", "b"), MaxUnavailableZones: 0},
+		},
+		"should not change the tolerated unavailable zones if the witness zone already failed": {
+			set:          ring.ReplicationSet{Instances: instances("a", "b"), MaxUnavailableZones: 0},
+			witnessZones: []string{"w"},
+			expectedSet:  ring.ReplicationSet{Instances: instances("a", "b"), MaxUnavailableZones: 0},
+		},
+		"should fail if a zone holding the series failed and the witness zone is available": {
+			set:           ring.ReplicationSet{Instances: instances("a", "w"), MaxUnavailableZones: 0},
+			witnessZones:  []string{"w"},
+			expectedError: ring.ErrTooManyUnhealthyInstances,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			r := newWitnessZonesRing(&replicationSetRingMock{set: testData.set}, testData.witnessZones)
+
+			set, err := r.GetReplicationSetForOperation(ring.Read)
+			if testData.expectedError != nil {
+				require.ErrorIs(t, err, testData.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedSet, set)
+		})
+	}
+}
+
+func TestQueriedReplicationFactor(t *testing.T) {
+	assert.Equal(t, 3, queriedReplicationFactor(3, nil))
+	assert.Equal(t, 2, queriedReplicationFactor(3, []string{"w"}))
+	assert.Equal(t, 1, queriedReplicationFactor(1, []string{"w"}))
+}
+
+type replicationSetRingMock struct {
+	ring.ReadRing
+
+	set ring.ReplicationSet
+}
+
+func (m *replicationSetRingMock) GetReplicationSetForOperation(ring.Operation) (ring.ReplicationSet, error) {
+	return m.set, nil
+}
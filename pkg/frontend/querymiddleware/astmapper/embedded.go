@@ -29,6 +29,10 @@ const (
 
 	// EmbeddedQueriesMetricName is a reserved metric name denoting a special metric which contains embedded queries.
 	EmbeddedQueriesMetricName = "__embedded_queries__"
+
+	// EmbeddedQueriesSubqueryLabelName is a reserved label name denoting embedded queries evaluated within a subquery,
+	// which need to be run at the subquery resolution instead of the query one.
+	EmbeddedQueriesSubqueryLabelName = "__subquery__"
 )
 
 // EmbeddedQueries is a wrapper type for encoding queries
@@ -84,3 +88,40 @@ func vectorSquasher(exprs ...parser.Expr) (parser.Expr, error) {
 		LabelMatchers: []*labels.Matcher{embeddedQuery},
 	}, nil
 }
+
+// subqueryMarker is an ExprMapper which marks the embedded queries evaluated within a subquery.
+type subqueryMarker struct{}
+
+// newSubqueryMarker creates a subqueryMarker which marks the embedded queries evaluated within a subquery,
+// so that they can be run at the subquery resolution.
+func newSubqueryMarker() ASTMapper {
+	return NewASTExprMapper(&subqueryMarker{})
+}
+
+// MapExpr implements ExprMapper.
+func (m *subqueryMarker) MapExpr(expr parser.Expr) (mapped parser.Expr, finished bool, err error) {
+	subquery, ok := expr.(*parser.SubqueryExpr)
+	if !ok {
+		return expr, false, nil
+	}
+
+	visitNode(subquery.Expr, func(node parser.Node) {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok || selector.Name != EmbeddedQueriesMetricName || isSubqueryEmbeddedQuery(selector.LabelMatchers) {
+			return
+		}
+		selector.LabelMatchers = append(selector.LabelMatchers, labels.MustNewMatcher(labels.MatchEqual, EmbeddedQueriesSubqueryLabelName, "true"))
+	})
+
+	return expr, true, nil
+}
+
+// isSubqueryEmbeddedQuery returns whether the input matchers select embedded queries evaluated within a subquery.
+func isSubqueryEmbeddedQuery(matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if m.Name == EmbeddedQueriesSubqueryLabelName {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,8 @@ type instantSplitter struct {
 	ctx context.Context
 
 	interval time.Duration
+	// If set, the partial queries are aligned to the split interval, based on this query time.
+	alignTo *time.Time
 	// In case of outer vector aggregator expressions, this contains the expression that will be used on the
 	// downstream queries, i.e. the query that will be executed in parallel in each partial query.
 	// This is an optimization to send outer vector aggregator expressions to reduce the label sets returned
@@ -75,6 +77,26 @@ func NewInstantQuerySplitter(ctx context.Context, interval time.Duration, logger
 	)
 }
 
+// NewAlignedInstantQuerySplitter creates a new query range mapper whose partial queries, except the most recent one,
+// select time ranges aligned to the split interval. The partial queries are the same for queries executed at different
+// times, as long as their result does not depend on the query time.
+func NewAlignedInstantQuerySplitter(ctx context.Context, interval time.Duration, queryTime time.Time, logger log.Logger, stats *InstantSplitterStats) ASTMapper {
+	instantQueryMapper := NewASTExprMapper(
+		&instantSplitter{
+			ctx:      ctx,
+			interval: interval,
+			alignTo:  &queryTime,
+			logger:   logger,
+			stats:    stats,
+		},
+	)
+
+	return NewMultiMapper(
+		instantQueryMapper,
+		newSubtreeFolder(),
+	)
+}
+
 // MapExpr returns expr mapped as embedded queries
 func (i *instantSplitter) MapExpr(expr parser.Expr) (mapped parser.Expr, finished bool, err error) {
 	if err := i.ctx.Err(); err != nil {
@@ -357,20 +379,16 @@ func (i *instantSplitter) splitAndSquashCall(expr *parser.Call, rangeInterval ti
 	}
 
 	// Create a partial query for each split
-	embeddedQueries := make([]parser.Expr, 0, splitCount)
-	for split := 0; split < splitCount; split++ {
-		splitOffset := time.Duration(split) * i.interval
-		// The range interval of the last embedded query can be smaller than i.interval
-		splitRangeInterval := i.interval
-		if lastSplit := split == splitCount-1; cannotDoubleCountBoundaries[expr.Func.Name] && !lastSplit {
-			splitRangeInterval -= time.Millisecond
-		}
-		if splitOffset+splitRangeInterval > rangeInterval {
-			splitRangeInterval = rangeInterval - splitOffset
-		}
-		// The offset of the embedded queries is always the original offset + a multiple of i.interval
-		splitOffset += originalOffset
-		splitExpr, err := createSplitExpr(embeddedQuery, splitRangeInterval, splitOffset)
+	// The partial queries of extrapolating functions are never aligned, because an aligned split could select a range
+	// too small to be extrapolated correctly.
+	align := i.alignTo != nil && expr.Func.Name != increase && !hasAtModifier(expr)
+	splitRanges := i.splitRanges(rangeInterval, originalOffset, cannotDoubleCountBoundaries[expr.Func.Name], align)
+	if len(splitRanges) <= 1 {
+		return expr, false, nil
+	}
+	embeddedQueries := make([]parser.Expr, 0, len(splitRanges))
+	for _, split := range splitRanges {
+		splitExpr, err := createSplitExpr(embeddedQuery, split.rangeInterval, split.offset)
 		if err != nil {
 			return nil, false, err
 		}
@@ -385,11 +403,49 @@ func (i *instantSplitter) splitAndSquashCall(expr *parser.Call, rangeInterval ti
 	}
 
 	// Update stats
-	i.stats.AddSplitQueries(splitCount)
+	i.stats.AddSplitQueries(len(splitRanges))
 
 	return squashExpr, true, nil
 }
 
+// splitRange is the time range selected by a partial query, expressed as its offset from the query time
+// and its range interval.
+type splitRange struct {
+	offset        time.Duration
+	rangeInterval time.Duration
+}
+
+// splitRanges returns the ranges of the partial queries, ordered from the most recent to the oldest one.
+// If align is true, the partial queries (except the most recent one) select ranges aligned to the
+// split interval, so that their results don't depend on the query time and can be cached.
+func (i *instantSplitter) splitRanges(rangeInterval, originalOffset time.Duration, cannotDoubleCountBoundaries, align bool) []splitRange {
+	// The most recent partial query selects a full split interval, unless the splits are aligned.
+	headInterval := i.interval
+	if align {
+		end := i.alignTo.Add(-originalOffset).UnixMilli()
+		headInterval = time.Duration(((end%i.interval.Milliseconds())+i.interval.Milliseconds())%i.interval.Milliseconds()) * time.Millisecond
+		if headInterval <= time.Millisecond {
+			// Merge a (almost) empty head into the next split, to never select an empty range.
+			headInterval += i.interval
+		}
+	}
+
+	var splits []splitRange
+	for covered, splitInterval := time.Duration(0), headInterval; covered < rangeInterval; covered, splitInterval = covered+splitInterval, i.interval {
+		// The range interval of the last embedded query can be smaller than i.interval
+		if covered+splitInterval > rangeInterval {
+			splitInterval = rangeInterval - covered
+		}
+		splitRangeInterval := splitInterval
+		if lastSplit := covered+splitInterval == rangeInterval; cannotDoubleCountBoundaries && !lastSplit {
+			splitRangeInterval -= time.Millisecond
+		}
+		// The offset of the embedded queries is always the original offset + the range covered by the newer splits.
+		splits = append(splits, splitRange{offset: originalOffset + covered, rangeInterval: splitRangeInterval})
+	}
+	return splits
+}
+
 // assertSplittableRangeInterval returns the range interval specified in the input expr and whether it is greater than
 // the configured split interval.
 func (i *instantSplitter) assertSplittableRangeInterval(expr parser.Expr) (rangeInterval time.Duration, canSplit bool, err error) {
@@ -443,6 +499,23 @@ func (i *instantSplitter) assertOffset(expr parser.Expr) (offset time.Duration,
 	return offsets[0], nil
 }
 
+// hasAtModifier returns whether the input expr contains a @ modifier.
+func hasAtModifier(expr parser.Expr) bool {
+	found := false
+
+	// Ignore the error since we never return it.
+	visitNode(expr, func(entry parser.Node) {
+		switch e := entry.(type) {
+		case *parser.VectorSelector:
+			found = found || e.Timestamp != nil || e.StartOrEnd != 0
+		case *parser.SubqueryExpr:
+			found = found || e.Timestamp != nil || e.StartOrEnd != 0
+		}
+	})
+
+	return found
+}
+
 // getOffsets recursively visit the input expr and returns a slice containing all offsets found.
 func getOffsets(expr parser.Expr) []time.Duration {
 	// Due to how this function is used, we expect to always find at most 1 offset
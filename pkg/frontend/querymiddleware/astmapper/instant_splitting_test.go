@@ -442,6 +442,58 @@ func TestInstantSplitterUnevenRangeInterval(t *testing.T) {
 	}
 }
 
+func TestAlignedInstantSplitter(t *testing.T) {
+	splitInterval := 2 * time.Minute
+	queryTime := time.Unix(0, 0).Add(time.Hour + 30*time.Second)
+
+	for _, tt := range []struct {
+		in                   string
+		out                  string
+		expectedSplitQueries int
+	}{
+		{
+			in:                   `max_over_time({app="foo"}[5m])`,
+			out:                  `max without() (__embedded_queries__{__queries__="{\"Concat\":[\"max_over_time({app=\\\"foo\\\"}[30s] offset 4m30s)\",\"max_over_time({app=\\\"foo\\\"}[2m] offset 2m30s)\",\"max_over_time({app=\\\"foo\\\"}[2m] offset 30s)\",\"max_over_time({app=\\\"foo\\\"}[30s])\"]}"})`,
+			expectedSplitQueries: 4,
+		},
+		{
+			in:                   `sum_over_time({app="foo"}[5m] offset 30s)`,
+			out:                  `sum without() (__embedded_queries__{__queries__="{\"Concat\":[\"sum_over_time({app=\\\"foo\\\"}[1m] offset 4m30s)\",\"sum_over_time({app=\\\"foo\\\"}[1m59s999ms] offset 2m30s)\",\"sum_over_time({app=\\\"foo\\\"}[1m59s999ms] offset 30s)\"]}"})`,
+			expectedSplitQueries: 3,
+		},
+		// Should not align the partial queries of extrapolating functions.
+		{
+			in:                   `rate({app="foo"}[5m])`,
+			out:                  `sum without() (__embedded_queries__{__queries__="{\"Concat\":[\"increase({app=\\\"foo\\\"}[1m] offset 4m)\",\"increase({app=\\\"foo\\\"}[2m] offset 2m)\",\"increase({app=\\\"foo\\\"}[2m])\"]}"}) / 300`,
+			expectedSplitQueries: 3,
+		},
+		// Should not align the partial queries of expressions with the @ modifier.
+		{
+			in:                   `max_over_time({app="foo"}[5m] @ 3600)`,
+			out:                  `max without() (__embedded_queries__{__queries__="{\"Concat\":[\"max_over_time({app=\\\"foo\\\"}[1m] @ 3600.000 offset 4m)\",\"max_over_time({app=\\\"foo\\\"}[2m] @ 3600.000 offset 2m)\",\"max_over_time({app=\\\"foo\\\"}[2m] @ 3600.000)\"]}"})`,
+			expectedSplitQueries: 3,
+		},
+	} {
+		tt := tt
+
+		t.Run(tt.in, func(t *testing.T) {
+			stats := NewInstantSplitterStats()
+			mapper := NewAlignedInstantQuerySplitter(context.Background(), splitInterval, queryTime, log.NewNopLogger(), stats)
+
+			expr, err := parser.ParseExpr(tt.in)
+			require.NoError(t, err)
+			out, err := parser.ParseExpr(tt.out)
+			require.NoError(t, err)
+
+			mapped, err := mapper.Map(expr)
+			require.NoError(t, err)
+			require.Equal(t, out.String(), mapped.String())
+
+			assert.Equal(t, tt.expectedSplitQueries, stats.GetSplitQueries())
+		})
+	}
+}
+
 func TestInstantSplitterSkippedQueryReason(t *testing.T) {
 	splitInterval := 1 * time.Minute
 
@@ -61,11 +61,16 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 		}
 
 		// Ensure there are no nested aggregations
-		nestedAggrs, err := anyNode(e.Expr, isAggregateExpr)
-
-		return err == nil && !nestedAggrs && CanParallelize(e.Expr, logger)
+		return !containsShardedAggregateExpr(e.Expr) && CanParallelize(e.Expr, logger)
 
 	case *parser.BinaryExpr:
+		// Vector matching binary expressions can be parallelised when the output series only depend on a single
+		// series of one of the two legs: that leg is sharded, while the other one is fully evaluated by each shard.
+		// The sharded leg must not contain aggregations, for the same reason explained below.
+		if leg, ok := shardedVectorMatchingLeg(e); ok {
+			return CanParallelize(*leg, logger) && !containsShardedAggregateExpr(*leg)
+		}
+
 		// Binary expressions can be parallelised when:
 		// - It's not a bool expr: bool expression should yield only one result, but sharding would provide many.
 		// - One of the sides is a constant scalar value
@@ -78,10 +83,10 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 		//
 		// Since we don't care about the order in which binary op is written, we extract the condition into a lambda and check both ways.
 		parallelisable := func(a, b parser.Expr) bool {
-			return CanParallelize(a, logger) && noAggregates(a) && !isConstantScalar(a) && isConstantScalar(b)
+			return CanParallelize(a, logger) && !containsShardedAggregateExpr(a) && !isConstantScalar(a) && isConstantScalar(b)
 		}
-		// If e.VectorMatching is not nil, then both hands are vector operators, so none of them is a constant scalar, so we can't shard it.
-		// It is just a shortcut, but the other two operations should imply the same.
+		// If e.VectorMatching is not nil, then both hands are vector operators, so none of them is a constant scalar, so we can't shard it
+		// this way. It is just a shortcut, but the other two operations should imply the same.
 		return e.VectorMatching == nil && !e.ReturnBool && (parallelisable(e.LHS, e.RHS) || parallelisable(e.RHS, e.LHS))
 
 	case *parser.Call:
@@ -102,7 +107,7 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 	case *parser.SubqueryExpr:
 		// Subqueries are parallelizable if they are parallelizable themselves
 		// and they don't contain aggregations over series in children exprs.
-		return !containsAggregateExpr(e) && CanParallelize(e.Expr, logger)
+		return !containsShardedAggregateExpr(e) && CanParallelize(e.Expr, logger)
 
 	case *parser.ParenExpr:
 		return CanParallelize(e.Expr, logger)
@@ -120,10 +125,47 @@ func CanParallelize(expr parser.Expr, logger log.Logger) bool {
 	}
 }
 
-// containsAggregateExpr returns true if the given expr contains an aggregate expression within its children.
-func containsAggregateExpr(e parser.Expr) bool {
-	containsAggregate, _ := anyNode(e, isAggregateExpr)
-	return containsAggregate
+// containsShardedAggregateExpr returns true if the given expr contains an aggregate expression within its children,
+// excluding the legs of vector matching binary expressions which are fully evaluated by each shard.
+func containsShardedAggregateExpr(e parser.Node) bool {
+	switch n := e.(type) {
+	case *parser.AggregateExpr:
+		return true
+	case *parser.BinaryExpr:
+		if leg, ok := shardedVectorMatchingLeg(n); ok {
+			return containsShardedAggregateExpr(*leg)
+		}
+	}
+
+	for _, child := range parser.Children(e) {
+		if containsShardedAggregateExpr(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// shardedVectorMatchingLeg returns the leg of the vector matching binary expression which can be sharded, if any.
+// Each output series of the binary expression only depends on a single series of the returned leg, and on any
+// series of the other leg. For this reason, the binary expression can be sharded by only sharding the returned
+// leg and fully evaluating the other leg in each shard. This is the case of:
+// - The "many" side of many-to-one and one-to-many matching (group_left and group_right modifiers).
+// - The left-hand side of the "and" and "unless" set operators.
+func shardedVectorMatchingLeg(e *parser.BinaryExpr) (*parser.Expr, bool) {
+	if e.VectorMatching == nil {
+		return nil, false
+	}
+
+	switch {
+	case e.VectorMatching.Card == parser.CardManyToOne:
+		return &e.LHS, true
+	case e.VectorMatching.Card == parser.CardOneToMany:
+		return &e.RHS, true
+	case e.Op == parser.LAND || e.Op == parser.LUNLESS:
+		return &e.LHS, true
+	default:
+		return nil, false
+	}
 }
 
 // countVectorSelectors returns the number of vector selectors in the input expression.
@@ -139,11 +181,6 @@ func countVectorSelectors(e parser.Expr) int {
 	return count
 }
 
-func isAggregateExpr(n parser.Node) (bool, error) {
-	_, ok := n.(*parser.AggregateExpr)
-	return ok, nil
-}
-
 // ParallelizableFunc ensures that a promql function can be part of a parallel query.
 func ParallelizableFunc(f parser.Function) bool {
 	for _, v := range NonParallelFuncs {
@@ -166,11 +203,6 @@ func argsWithDefaults(call *parser.Call) parser.Expressions {
 	return call.Args
 }
 
-func noAggregates(n parser.Node) bool {
-	hasAggregates, _ := anyNode(n, isAggregateExpr)
-	return !hasAggregates
-}
-
 func isConstantScalar(n parser.Node) bool {
 	isNot, _ := anyNode(n, isNotConstantNumber)
 	return !isNot
@@ -7,7 +7,6 @@ package astmapper
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/go-kit/log"
 	"github.com/pkg/errors"
@@ -24,9 +23,11 @@ func NewSharding(ctx context.Context, shards int, logger log.Logger, stats *Mapp
 		return nil, err
 	}
 	subtreeFolder := newSubtreeFolder()
+	subqueryMarker := newSubqueryMarker()
 	return NewMultiMapper(
 		shardSummer,
 		subtreeFolder,
+		subqueryMarker,
 	), nil
 }
 
@@ -107,14 +108,10 @@ func (summer *shardSummer) MapExpr(expr parser.Expr) (mapped parser.Expr, finish
 		// only shard the most outer function call.
 		if summer.currentShard == nil {
 			// Only shards Subqueries, they are parallelizable if they are parallelizable themselves
-			// and they don't contain aggregations over series in children exprs.
-			if isSubqueryCall(e) {
-				if containsAggregateExpr(e) {
-					return e, true, nil
-				}
-				if !CanParallelize(e, summer.logger) {
-					return e, true, nil
-				}
+			// and they don't contain aggregations over series in children exprs. If they're not
+			// parallelizable, we keep mapping the subquery inner expression, which could still be
+			// sharded (e.g. an aggregation inside the subquery).
+			if isSubqueryCall(e) && CanParallelize(e, summer.logger) {
 				return summer.shardAndSquashFuncCall(e)
 			}
 			return e, false, nil
@@ -123,12 +120,26 @@ func (summer *shardSummer) MapExpr(expr parser.Expr) (mapped parser.Expr, finish
 
 	case *parser.BinaryExpr:
 		if summer.currentShard != nil {
+			// If the mapped expr is part of the sharded query, then it means we already checked whether it was
+			// safe to shard. The vector matching binary operations are sharded by only sharding one of the two
+			// legs, while the other leg is fully evaluated by each shard.
+			if leg, ok := shardedVectorMatchingLeg(e); ok {
+				mapped, err := NewASTExprMapper(summer).Map(*leg)
+				if err != nil {
+					return nil, true, err
+				}
+				*leg = mapped
+				return e, true, nil
+			}
 			return e, false, nil
 		}
 
 		// If we can parallelize the whole binary operation then just do it.
 		if CanParallelize(e, summer.logger) {
-			return summer.shardBinOp(e)
+			mapped, finished, err := summer.shardBinOp(e)
+			if err != nil || finished {
+				return mapped, finished, err
+			}
 		}
 
 		// We can't parallelize the whole binary operation but we could still parallelize
@@ -188,10 +199,11 @@ func (summer *shardSummer) MapExpr(expr parser.Expr) (mapped parser.Expr, finish
 			return e, false, nil
 		}
 
-		// If the mapper hits a subquery expression, it means it's a subquery whose sharding is currently
-		// not supported, so we terminate the mapping here. If the subquery was parallelizable we didn't reach
-		// this point, because the subquery was part of a parent shardable expr.
-		return e, true, nil
+		// If the mapper hits a subquery expression, it means the subquery can't be sharded as a whole, otherwise
+		// we didn't reach this point because the subquery was part of a parent shardable expr. However, the
+		// subquery inner expression could still be sharded, so we keep mapping it. The embedded queries
+		// within the subquery are run at the subquery resolution (see subqueryMarker).
+		return e, false, nil
 
 	default:
 		return e, false, nil
@@ -431,7 +443,9 @@ func (summer *shardSummer) shardBinOp(expr *parser.BinaryExpr) (mapped parser.Ex
 	case parser.GTR,
 		parser.GTE,
 		parser.LSS,
-		parser.LTE:
+		parser.LTE,
+		parser.LAND,
+		parser.LUNLESS:
 		mapped, err = summer.shardAndSquashBinOp(expr)
 		if err != nil {
 			return nil, false, err
@@ -444,32 +458,18 @@ func (summer *shardSummer) shardBinOp(expr *parser.BinaryExpr) (mapped parser.Ex
 
 // shardAndSquashBinOp returns a squashed CONCAT expression including N embedded
 // queries, where N is the number of shards and each sub-query queries a different shard
-// with the same binary operation.
+// with the same binary operation. If the binary operation is a vector matching one, only
+// one of the two legs is sharded (see shardedVectorMatchingLeg).
 func (summer *shardSummer) shardAndSquashBinOp(expr *parser.BinaryExpr) (parser.Expr, error) {
-	if expr.VectorMatching != nil {
-		// We shouldn't ever reach this point with a vector matching binary expression,
-		// but it's better to check twice than completely mess it up with the results.
-		return nil, fmt.Errorf("tried to shard a bin op with vector matching: %s", expr)
-	}
-
 	children := make([]parser.Expr, 0, summer.shards)
 	// Create sub-query for each shard.
 	for i := 0; i < summer.shards; i++ {
-		shardedLHS, err := cloneAndMap(NewASTExprMapper(summer.CopyWithCurShard(i)), expr.LHS)
-		if err != nil {
-			return nil, err
-		}
-		shardedRHS, err := cloneAndMap(NewASTExprMapper(summer.CopyWithCurShard(i)), expr.RHS)
+		sharded, err := cloneAndMap(NewASTExprMapper(summer.CopyWithCurShard(i)), expr)
 		if err != nil {
 			return nil, err
 		}
 
-		children = append(children, &parser.BinaryExpr{
-			LHS:        shardedLHS,
-			Op:         expr.Op,
-			RHS:        shardedRHS,
-			ReturnBool: expr.ReturnBool,
-		})
+		children = append(children, sharded)
 	}
 
 	// Update stats.
@@ -196,14 +196,12 @@ func TestShardSummer(t *testing.T) {
 					rate(metric_counter[5m])
 				)[10m:2m]
 			)`,
-			concat(
-				`min_over_time(
-					sum by(group_1) (
-						rate(metric_counter[5m])
-					)[10m:2m]
-				)`,
-			),
-			0,
+			`min_over_time(
+				sum by(group_1) (` +
+				subquery(concatShards(3, `sum by(group_1) (rate(metric_counter{__query_shard__="x_of_y"}[5m]))`)) +
+				`)[10m:2m]
+			)`,
+			3,
 		},
 		{
 			`max_over_time(
@@ -246,14 +244,12 @@ func TestShardSummer(t *testing.T) {
 					rate(metric_counter[5m])
 				)[10m:]
 			)`,
-			concat(
-				`rate(
-						sum by(group_1) (
-							rate(metric_counter[5m])
-						)[10m:]
-					)`,
-			),
-			0,
+			`rate(
+				sum by(group_1) (` +
+				subquery(concatShards(3, `sum by(group_1) (rate(metric_counter{__query_shard__="x_of_y"}[5m]))`)) +
+				`)[10m:]
+			)`,
+			3,
 		},
 		{
 			`absent_over_time(rate(metric_counter[5m])[10m:])`,
@@ -289,16 +285,13 @@ func TestShardSummer(t *testing.T) {
 					[5m:1m])
 				[2m:])
 			[10m:])`,
-			concat(
-				`max_over_time(
-					absent_over_time(
-						deriv(
-							rate(metric_counter[1m])
-						[5m:1m])
-					[2m:])
-				[10m:])`,
-			),
-			0,
+			// The inner subquery is sharded, while absent_over_time() is run on the whole results.
+			`max_over_time(
+				absent_over_time(` +
+				subquery(concatShards(3, `deriv(rate(metric_counter{__query_shard__="x_of_y"}[1m])[5m:1m])`)) +
+				`[2m:])
+			[10m:])`,
+			3,
 		},
 		{
 			`quantile_over_time(0.99, cortex_ingester_active_series[1w])`,
@@ -468,6 +461,54 @@ func TestShardSummer(t *testing.T) {
 			out:                    concat(`foo * on(a, b) group_left(c) avg by(a, b, c) (bar)`),
 			expectedShardedQueries: 0,
 		},
+		{
+			// The "many" leg "foo" is sharded, while the "one" leg is fully evaluated by each shard.
+			in:                     `sum by(c) (foo * on(a, b) group_left(c) avg by(a, b, c) (bar))`,
+			out:                    `sum by(c) (` + concatShards(3, `sum by(c) (foo{__query_shard__="x_of_y"} * on(a, b) group_left(c) avg by(a, b, c) (bar))`) + `)`,
+			expectedShardedQueries: 3,
+		},
+		{
+			in:                     `foo > on(a) group_left() bar`,
+			out:                    concatShards(3, `foo{__query_shard__="x_of_y"} > on(a) group_left() bar`),
+			expectedShardedQueries: 3,
+		},
+		{
+			in:                     `rate(foo[1m]) and on(a) bar`,
+			out:                    concatShards(3, `rate(foo{__query_shard__="x_of_y"}[1m]) and on(a) bar`),
+			expectedShardedQueries: 3,
+		},
+		{
+			in:                     `count(foo unless bar)`,
+			out:                    `sum(` + concatShards(3, `count(foo{__query_shard__="x_of_y"} unless bar)`) + `)`,
+			expectedShardedQueries: 3,
+		},
+		{
+			// This query is not parallelized because the output series of "or" depend on both legs.
+			in:                     `sum(foo or bar)`,
+			out:                    concat(`sum(foo or bar)`),
+			expectedShardedQueries: 0,
+		},
+		{
+			// This query is not parallelized because one-to-one matching requires to check both legs for duplicates.
+			in:                     `sum(foo * bar)`,
+			out:                    concat(`sum(foo * bar)`),
+			expectedShardedQueries: 0,
+		},
+		{
+			// This query is not parallelized because the "many" leg contains an aggregation, and the
+			// leg "bar" is not aggregated and could result in high cardinality results.
+			in:                     `sum(sum by(a) (foo) * on(a) group_left() bar)`,
+			out:                    concat(`sum(sum by(a) (foo) * on(a) group_left() bar)`),
+			expectedShardedQueries: 0,
+		},
+		{
+			in: `max_over_time((sum(rate(foo[1m])) / sum(rate(bar[1m])))[10m:1m])`,
+			out: `max_over_time((` +
+				`sum(` + subquery(concatShards(3, `sum(rate(foo{__query_shard__="x_of_y"}[1m]))`)) + `) / ` +
+				`sum(` + subquery(concatShards(3, `sum(rate(bar{__query_shard__="x_of_y"}[1m]))`)) + `)` +
+				`)[10m:1m])`,
+			expectedShardedQueries: 6,
+		},
 		{
 			in:                     `vector(1) > 0 and vector(1)`,
 			out:                    `vector(1) > 0 and vector(1)`,
@@ -479,19 +520,18 @@ func TestShardSummer(t *testing.T) {
 			expectedShardedQueries: 3,
 		},
 		{
-			// This query is not parallelized because the leg "pod:container_cpu_usage:sum" is not aggregated and
-			// could result in high cardinality results.
+			// The "many" leg "pod:container_cpu_usage:sum" is sharded, while the "one" leg is fully evaluated by each shard.
 			in: `max by(pod) (
                     max without(prometheus_replica, instance, node) (kube_pod_labels{namespace="test"})
                     *
                     on(cluster, pod, namespace) group_right() pod:container_cpu_usage:sum
             )`,
-			out: concat(`max by(pod) (
+			out: `max by(pod) (` + concatShards(3, `max by(pod) (
                     max without(prometheus_replica, instance, node) (kube_pod_labels{namespace="test"})
                     *
-                    on(cluster, pod, namespace) group_right() pod:container_cpu_usage:sum
-            )`),
-			expectedShardedQueries: 0,
+                    on(cluster, pod, namespace) group_right() pod:container_cpu_usage:sum{__query_shard__="x_of_y"}
+            )`) + `)`,
+			expectedShardedQueries: 3,
 		},
 		{
 			in:                     `sum(rate(metric[1m])) and max(metric) > 0`,
@@ -531,6 +571,11 @@ func concatShards(shards int, queryTemplate string) string {
 	return concat(queries...)
 }
 
+// subquery returns the input embedded queries marked as evaluated within a subquery.
+func subquery(embedded string) string {
+	return strings.TrimSuffix(embedded, "}") + "," + EmbeddedQueriesSubqueryLabelName + `="true"}`
+}
+
 func concat(queries ...string) string {
 	exprs := make([]parser.Expr, 0, len(queries))
 	for _, q := range queries {
@@ -18,8 +18,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	apierror "github.com/grafana/mimir/pkg/api/error"
 	"github.com/grafana/mimir/pkg/querier/stats"
+	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 const (
@@ -35,16 +38,18 @@ const (
 )
 
 // cardinalityEstimation is a Handler that caches estimates for a query's
-// cardinality based on similar queries seen previously.
+// cardinality based on similar queries seen previously. Queries whose estimated
+// memory exceeds the tenant's limit are rejected before being executed.
 type cardinalityEstimation struct {
 	cache  cache.Cache
+	limits Limits
 	next   Handler
 	logger log.Logger
 
 	estimationError prometheus.Histogram
 }
 
-func newCardinalityEstimationMiddleware(cache cache.Cache, logger log.Logger, registerer prometheus.Registerer) Middleware {
+func newCardinalityEstimationMiddleware(cache cache.Cache, limits Limits, logger log.Logger, registerer prometheus.Registerer) Middleware {
 	estimationError := promauto.With(registerer).NewHistogram(prometheus.HistogramOpts{
 		Name:    "cortex_query_frontend_cardinality_estimation_difference",
 		Help:    "Difference between estimated and actual query cardinality",
@@ -53,6 +58,7 @@ func newCardinalityEstimationMiddleware(cache cache.Cache, logger log.Logger, re
 	return MiddlewareFunc(func(next Handler) Handler {
 		return &cardinalityEstimation{
 			cache:  cache,
+			limits: limits,
 			next:   next,
 			logger: logger,
 
@@ -75,13 +81,20 @@ func (c *cardinalityEstimation) Do(ctx context.Context, request Request) (Respon
 	k := generateCardinalityEstimationCacheKey(tenant.JoinTenantIDs(tenants), request, cardinalityEstimateBucketSize)
 	spanLog.LogFields(otlog.String("cache key", k))
 
-	estimatedCardinality, estimateAvailable := c.lookupCardinalityForKey(ctx, k)
+	estimate, estimateAvailable := c.lookupCardinalityForKey(ctx, k)
+	estimatedCardinality := estimate.GetEstimatedSeriesCount()
 	if estimateAvailable {
 		request = request.WithEstimatedSeriesCountHint(estimatedCardinality)
 		spanLog.LogFields(
 			otlog.Bool("estimate available", true),
 			otlog.Uint64("estimated cardinality", estimatedCardinality),
+			otlog.Uint64("estimated chunk bytes", estimate.GetEstimatedChunkBytes()),
 		)
+
+		if err := c.checkEstimatedMemory(tenants, request, estimate); err != nil {
+			level.Debug(spanLog).Log("msg", "query rejected because its estimated memory exceeds the limit", "err", err)
+			return nil, err
+		}
 	} else {
 		spanLog.LogFields(otlog.Bool("estimate available", false))
 	}
@@ -93,10 +106,14 @@ func (c *cardinalityEstimation) Do(ctx context.Context, request Request) (Respon
 
 	statistics := stats.FromContext(ctx)
 	actualCardinality := statistics.GetFetchedSeriesCount()
-	spanLog.LogFields(otlog.Uint64("actual cardinality", actualCardinality))
-
-	if !estimateAvailable || !isCardinalitySimilar(actualCardinality, estimatedCardinality) {
-		c.storeCardinalityForKey(k, actualCardinality)
+	actualChunkBytes := statistics.GetFetchedChunkBytes()
+	spanLog.LogFields(
+		otlog.Uint64("actual cardinality", actualCardinality),
+		otlog.Uint64("actual chunk bytes", actualChunkBytes),
+	)
+
+	if !estimateAvailable || !isCardinalitySimilar(actualCardinality, estimatedCardinality) || !isCardinalitySimilar(actualChunkBytes, estimate.GetEstimatedChunkBytes()) {
+		c.storeCardinalityForKey(k, &QueryStatistics{EstimatedSeriesCount: actualCardinality, EstimatedChunkBytes: actualChunkBytes})
 		spanLog.LogFields(otlog.Bool("cache updated", true))
 	}
 
@@ -110,11 +127,41 @@ func (c *cardinalityEstimation) Do(ctx context.Context, request Request) (Respon
 	return res, nil
 }
 
+// checkEstimatedMemory returns an error if the estimated memory of the request exceeds the tenant's
+// max estimated memory per query, which is enforced by queriers on each request they receive.
+// The estimated memory is the size of the chunks fetched by previous executions of the request,
+// spread over the max number of shards the request may be split into, so that only the requests
+// which would exceed the limit in queriers anyway are rejected.
+func (c *cardinalityEstimation) checkEstimatedMemory(tenantIDs []string, request Request, estimate *QueryStatistics) error {
+	if c.limits == nil || estimate.GetEstimatedChunkBytes() == 0 {
+		return nil
+	}
+
+	maxBytes := validation.SmallestPositiveIntPerTenant(tenantIDs, c.limits.MaxEstimatedMemoryPerQuery)
+	if maxBytes <= 0 {
+		return nil
+	}
+
+	shards := 1
+	if !request.GetOptions().ShardingDisabled {
+		shards = util_math.Max(validation.SmallestPositiveIntPerTenant(tenantIDs, c.limits.QueryShardingTotalShards), 1)
+		if request.GetOptions().TotalShards > 0 {
+			shards = int(request.GetOptions().TotalShards)
+		}
+	}
+
+	estimatedBytes := int(estimate.GetEstimatedChunkBytes()) / shards
+	if estimatedBytes > maxBytes {
+		return apierror.New(apierror.TypeExec, validation.NewMaxEstimatedMemoryPerQueryError(estimatedBytes, maxBytes).Error())
+	}
+	return nil
+}
+
 // lookupCardinalityForKey fetches a cardinality estimate for the given key from
 // the results cache.
-func (c *cardinalityEstimation) lookupCardinalityForKey(ctx context.Context, key string) (uint64, bool) {
+func (c *cardinalityEstimation) lookupCardinalityForKey(ctx context.Context, key string) (*QueryStatistics, bool) {
 	if c.cache == nil {
-		return 0, false
+		return nil, false
 	}
 	res := c.cache.Fetch(ctx, []string{key})
 	if val, ok := res[key]; ok {
@@ -122,20 +169,19 @@ func (c *cardinalityEstimation) lookupCardinalityForKey(ctx context.Context, key
 		err := proto.Unmarshal(val, qs)
 		if err != nil {
 			level.Warn(c.logger).Log("msg", "failed to unmarshal cardinality estimate")
-			return 0, false
+			return nil, false
 		}
-		return qs.EstimatedSeriesCount, true
+		return qs, true
 	}
-	return 0, false
+	return nil, false
 }
 
 // storeCardinalityForKey stores a cardinality estimate for the given key in the
 // results cache.
-func (c *cardinalityEstimation) storeCardinalityForKey(key string, count uint64) {
+func (c *cardinalityEstimation) storeCardinalityForKey(key string, m *QueryStatistics) {
 	if c.cache == nil {
 		return
 	}
-	m := &QueryStatistics{EstimatedSeriesCount: count}
 	marshaled, err := proto.Marshal(m)
 	if err != nil {
 		level.Warn(c.logger).Log("msg", "failed to marshal cardinality estimate")
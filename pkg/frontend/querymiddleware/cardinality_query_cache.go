@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	cardinalityLabelNamesPathSuffix  = "/api/v1/cardinality/label_names"
+	cardinalityLabelValuesPathSuffix = "/api/v1/cardinality/label_values"
+)
+
+// cardinalityQueryCache is a http.RoundTripper caching the responses of the cardinality analysis API
+// in the results cache, for the TTL configured for the tenant.
+type cardinalityQueryCache struct {
+	next   http.RoundTripper
+	limits Limits
+	cache  cache.Cache
+	logger log.Logger
+
+	cacheRequests prometheus.Counter
+	cacheHits     prometheus.Counter
+	cacheSkipped  *prometheus.CounterVec
+}
+
+func newCardinalityQueryCacheRoundTripper(c cache.Cache, limits Limits, next http.RoundTripper, logger log.Logger, reg prometheus.Registerer) http.RoundTripper {
+	q := &cardinalityQueryCache{
+		next:   next,
+		limits: limits,
+		cache:  c,
+		logger: logger,
+		cacheRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_cardinality_query_cache_requests_total",
+			Help: "Total number of cardinality queries looked up in the results cache.",
+		}),
+		cacheHits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_cardinality_query_cache_hits_total",
+			Help: "Total number of cardinality queries whose response has been found in the results cache.",
+		}),
+		cacheSkipped: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_frontend_cardinality_query_cache_skipped_total",
+			Help: "Total number of cardinality queries whose response has not been stored in the results cache.",
+		}, []string{"reason"}),
+	}
+
+	// Initialize known label values.
+	q.cacheSkipped.WithLabelValues(notCachableReasonTooLarge)
+
+	return q
+}
+
+func (c *cardinalityQueryCache) RoundTrip(r *http.Request) (*http.Response, error) {
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return c.next.RoundTrip(r)
+	}
+
+	ttl := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, c.limits.ResultsCacheTTLForCardinalityQuery)
+	var opts Options
+	decodeOptions(r, &opts)
+	if ttl <= 0 || opts.CacheDisabled {
+		return c.next.RoundTrip(r)
+	}
+
+	key, err := c.generateCacheKey(tenant.JoinTenantIDs(tenantIDs), r)
+	if err != nil {
+		return c.next.RoundTrip(r)
+	}
+
+	spanLog, ctx := spanlogger.NewWithLogger(r.Context(), c.logger, "cardinalityQueryCache.RoundTrip")
+	defer spanLog.Finish()
+
+	hashed := cacheHashKey(key)
+	c.cacheRequests.Inc()
+	if found := c.cache.Fetch(ctx, []string{hashed}); len(found[hashed]) > 0 {
+		c.cacheHits.Inc()
+		spanLog.LogKV("cache hit", true)
+		return newCardinalityQueryCachedResponse(r, found[hashed]), nil
+	}
+	spanLog.LogKV("cache hit", false)
+
+	res, err := c.next.RoundTrip(r)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	if maxSize := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, c.limits.ResultsCacheMaxEntrySizeBytes); maxSize > 0 && len(body) > maxSize {
+		c.cacheSkipped.WithLabelValues(notCachableReasonTooLarge).Inc()
+		return res, nil
+	}
+	c.cache.StoreAsync(map[string][]byte{hashed: body}, ttl)
+
+	return res, nil
+}
+
+// generateCacheKey returns the cache key of the cardinality request, made of the tenant, the requested
+// endpoint and all the request params. The params are encoded sorted by key, so that the same request
+// always has the same key.
+func (c *cardinalityQueryCache) generateCacheKey(userID string, r *http.Request) (string, error) {
+	var endpoint string
+	switch {
+	case strings.HasSuffix(r.URL.Path, cardinalityLabelNamesPathSuffix):
+		endpoint = "label_names"
+	case strings.HasSuffix(r.URL.Path, cardinalityLabelValuesPathSuffix):
+		endpoint = "label_values"
+	default:
+		return "", fmt.Errorf("unsupported cardinality endpoint %s", r.URL.Path)
+	}
+
+	if err := parseCardinalityRequestForm(r); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to parse cardinality request params", "err", err)
+		return "", err
+	}
+
+	return fmt.Sprintf("cardinality:%s:%s:%s", userID, endpoint, r.Form.Encode()), nil
+}
+
+// parseCardinalityRequestForm parses the params of the input cardinality request. Parsing the form
+// consumes the body of POST requests, so it's restored afterwards.
+func parseCardinalityRequestForm(r *http.Request) error {
+	var body []byte
+	if r.Body != nil && r.Form == nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return err
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return nil
+}
+
+func newCardinalityQueryCachedResponse(r *http.Request, body []byte) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(http.StatusOK),
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
+
+func isCardinalityQuery(path string) bool {
+	return strings.HasSuffix(path, cardinalityLabelNamesPathSuffix) || strings.HasSuffix(path, cardinalityLabelValuesPathSuffix)
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+)
+
+func TestCardinalityQueryCache_RoundTrip(t *testing.T) {
+	const labelValuesPath = "/prometheus/api/v1/cardinality/label_values"
+
+	tests := map[string]struct {
+		cacheTTL             time.Duration
+		firstRequest         func() *http.Request
+		secondRequest        func() *http.Request
+		downstreamStatus     int
+		maxEntrySizeBytes    int
+		expectedCacheHits    int
+		expectedCacheSkipped int
+	}{
+		"cache hit for the same GET request": {
+			cacheTTL:          time.Minute,
+			firstRequest:      newCardinalityGetRequest(labelValuesPath + "?label_names[]=job&limit=10"),
+			secondRequest:     newCardinalityGetRequest(labelValuesPath + "?limit=10&label_names[]=job"),
+			downstreamStatus:  http.StatusOK,
+			expectedCacheHits: 1,
+		},
+		"cache hit for the same request sent as GET and POST": {
+			cacheTTL:          time.Minute,
+			firstRequest:      newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			secondRequest:     newCardinalityPostRequest(labelValuesPath, url.Values{"label_names[]": []string{"job"}}),
+			downstreamStatus:  http.StatusOK,
+			expectedCacheHits: 1,
+		},
+		"cache miss for different params": {
+			cacheTTL:          time.Minute,
+			firstRequest:      newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			secondRequest:     newCardinalityGetRequest(labelValuesPath + "?label_names[]=instance"),
+			downstreamStatus:  http.StatusOK,
+			expectedCacheHits: 0,
+		},
+		"cache miss for a different endpoint": {
+			cacheTTL:          time.Minute,
+			firstRequest:      newCardinalityGetRequest(labelValuesPath + "?limit=10"),
+			secondRequest:     newCardinalityGetRequest("/prometheus/api/v1/cardinality/label_names?limit=10"),
+			downstreamStatus:  http.StatusOK,
+			expectedCacheHits: 0,
+		},
+		"caching disabled for the tenant": {
+			cacheTTL:          0,
+			firstRequest:      newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			secondRequest:     newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			downstreamStatus:  http.StatusOK,
+			expectedCacheHits: 0,
+		},
+		"error responses are not cached": {
+			cacheTTL:          time.Minute,
+			firstRequest:      newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			secondRequest:     newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			downstreamStatus:  http.StatusBadRequest,
+			expectedCacheHits: 0,
+		},
+		"responses larger than the max entry size are not cached": {
+			cacheTTL:             time.Minute,
+			firstRequest:         newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			secondRequest:        newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			downstreamStatus:     http.StatusOK,
+			maxEntrySizeBytes:    1,
+			expectedCacheHits:    0,
+			expectedCacheSkipped: 2,
+		},
+		"cache disabled by the request": {
+			cacheTTL:     time.Minute,
+			firstRequest: newCardinalityGetRequest(labelValuesPath + "?label_names[]=job"),
+			secondRequest: func() *http.Request {
+				r := newCardinalityGetRequest(labelValuesPath + "?label_names[]=job")()
+				r.Header.Set(cacheControlHeader, noStoreValue)
+				return r
+			},
+			downstreamStatus:  http.StatusOK,
+			expectedCacheHits: 0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			const responseBody = `{"series_count_total":1}`
+
+			var downstreamCalls int
+			var downstreamBodies []string
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				downstreamCalls++
+
+				// The downstream must receive the request params, even if the request has been parsed.
+				require.NoError(t, r.ParseForm())
+				downstreamBodies = append(downstreamBodies, r.Form.Encode())
+
+				return &http.Response{
+					StatusCode: tc.downstreamStatus,
+					Body:       io.NopCloser(strings.NewReader(responseBody)),
+				}, nil
+			})
+
+			reg := prometheus.NewPedanticRegistry()
+			limits := mockLimits{resultsCacheTTLForCardinalityQuery: tc.cacheTTL, resultsCacheMaxEntrySizeBytes: tc.maxEntrySizeBytes}
+			rt := newCardinalityQueryCacheRoundTripper(cache.NewMockCache(), limits, downstream, log.NewNopLogger(), reg)
+
+			for _, req := range []*http.Request{tc.firstRequest(), tc.secondRequest()} {
+				res, err := rt.RoundTrip(req)
+				require.NoError(t, err)
+				assert.Equal(t, tc.downstreamStatus, res.StatusCode)
+
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.Equal(t, responseBody, string(body))
+			}
+
+			assert.Equal(t, 2-tc.expectedCacheHits, downstreamCalls)
+			for _, body := range downstreamBodies {
+				assert.NotEmpty(t, body)
+			}
+			assert.Equal(t, float64(tc.expectedCacheHits), testutil.ToFloat64(rt.(*cardinalityQueryCache).cacheHits))
+			assert.Equal(t, float64(tc.expectedCacheSkipped), testutil.ToFloat64(rt.(*cardinalityQueryCache).cacheSkipped.WithLabelValues(notCachableReasonTooLarge)))
+		})
+	}
+}
+
+func newCardinalityGetRequest(target string) func() *http.Request {
+	return func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		return r.WithContext(user.InjectOrgID(r.Context(), "user-1"))
+	}
+}
+
+func newCardinalityPostRequest(target string, form url.Values) func() *http.Request {
+	return func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r.WithContext(user.InjectOrgID(r.Context(), "user-1"))
+	}
+}
@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const labelNamesParam = "label_names[]"
+
+// cardinalityQuerySharding is a http.RoundTripper splitting the label values cardinality requests
+// into up to the tenant's query sharding total shards requests, each one querying a subset of the
+// requested label names. The partial requests are executed concurrently, up to the tenant's max query
+// parallelism, and their responses are merged. The partial responses can be merged exactly because
+// each label name is queried by only one partial request, and the series count total is the same for
+// all the partial requests.
+type cardinalityQuerySharding struct {
+	next   http.RoundTripper
+	limits Limits
+	logger log.Logger
+
+	shardedRequests prometheus.Counter
+	shardedQueries  prometheus.Counter
+}
+
+func newCardinalityQueryShardingRoundTripper(limits Limits, next http.RoundTripper, logger log.Logger, reg prometheus.Registerer) http.RoundTripper {
+	return &cardinalityQuerySharding{
+		next:   next,
+		limits: limits,
+		logger: logger,
+		shardedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_cardinality_query_sharding_requests_total",
+			Help: "Total number of label values cardinality requests split by label name.",
+		}),
+		shardedQueries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_cardinality_query_sharding_sharded_queries_total",
+			Help: "Total number of partial label values cardinality requests executed.",
+		}),
+	}
+}
+
+func (c *cardinalityQuerySharding) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(r.URL.Path, cardinalityLabelValuesPathSuffix) {
+		return c.next.RoundTrip(r)
+	}
+
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return c.next.RoundTrip(r)
+	}
+
+	// Malformed requests are forwarded to the queriers, which return the proper error.
+	if err := parseCardinalityRequestForm(r); err != nil {
+		return c.next.RoundTrip(r)
+	}
+
+	shards := validation.SmallestPositiveIntPerTenant(tenantIDs, c.limits.QueryShardingTotalShards)
+	batches := splitCardinalityLabelNames(r.Form[labelNamesParam], shards)
+	if len(batches) <= 1 {
+		return c.next.RoundTrip(r)
+	}
+
+	spanLog, ctx := spanlogger.NewWithLogger(r.Context(), c.logger, "cardinalityQuerySharding.RoundTrip")
+	defer spanLog.Finish()
+	spanLog.LogKV("label names", len(r.Form[labelNamesParam]), "sharded queries", len(batches))
+
+	c.shardedRequests.Inc()
+	c.shardedQueries.Add(float64(len(batches)))
+
+	parallelism := validation.SmallestPositiveIntPerTenant(tenantIDs, c.limits.MaxQueryParallelism)
+	if parallelism <= 0 {
+		parallelism = len(batches)
+	}
+
+	responses := make([]*labelValuesCardinalityResponse, len(batches))
+	err = concurrency.ForEachJob(ctx, len(batches), parallelism, func(ctx context.Context, idx int) error {
+		res, err := c.next.RoundTrip(newCardinalityShardRequest(ctx, r, batches[idx]))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = res.Body.Close() }()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode != http.StatusOK {
+			res.Body = io.NopCloser(bytes.NewReader(body))
+			return &cardinalityShardError{res: res}
+		}
+
+		responses[idx] = &labelValuesCardinalityResponse{}
+		return json.Unmarshal(body, responses[idx])
+	})
+	if err != nil {
+		var shardErr *cardinalityShardError
+		if errors.As(err, &shardErr) {
+			shardErr.res.Request = r
+			return shardErr.res, nil
+		}
+		return nil, err
+	}
+
+	body, err := json.Marshal(mergeLabelValuesCardinalityResponses(responses))
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(http.StatusOK),
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, nil
+}
+
+// cardinalityShardError is the error returned when a partial request has a non-successful response,
+// which is returned as is to the client.
+type cardinalityShardError struct {
+	res *http.Response
+}
+
+func (e *cardinalityShardError) Error() string {
+	return "cardinality partial request failed with status code " + strconv.Itoa(e.res.StatusCode)
+}
+
+// splitCardinalityLabelNames splits the input label names into up to shards batches of contiguous
+// label names, whose sizes differ by at most one.
+func splitCardinalityLabelNames(labelNames []string, shards int) [][]string {
+	if shards > len(labelNames) {
+		shards = len(labelNames)
+	}
+	if shards <= 1 {
+		return [][]string{labelNames}
+	}
+
+	batches := make([][]string, 0, shards)
+	for i := 0; i < shards; i++ {
+		start, end := i*len(labelNames)/shards, (i+1)*len(labelNames)/shards
+		batches = append(batches, labelNames[start:end])
+	}
+	return batches
+}
+
+// newCardinalityShardRequest returns a copy of the input label values cardinality request, querying
+// only the input label names. The request params are sent in the body, as a POST request, so that
+// they're not limited by the max URL length.
+func newCardinalityShardRequest(ctx context.Context, r *http.Request, labelNames []string) *http.Request {
+	form := make(url.Values, len(r.Form))
+	for name, values := range r.Form {
+		form[name] = values
+	}
+	form[labelNamesParam] = labelNames
+	body := form.Encode()
+
+	shardReq := r.Clone(ctx)
+	shardReq.Method = http.MethodPost
+	shardReq.URL.RawQuery = ""
+	shardReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	shardReq.Header.Del("Content-Length")
+	shardReq.Body = io.NopCloser(strings.NewReader(body))
+	shardReq.ContentLength = int64(len(body))
+	shardReq.Form = nil
+	shardReq.PostForm = nil
+	return shardReq
+}
+
+// mergeLabelValuesCardinalityResponses merges the responses of the partial requests, each one querying
+// a disjoint subset of the label names. The labels of each tenant are merged too, for federated requests.
+func mergeLabelValuesCardinalityResponses(responses []*labelValuesCardinalityResponse) *labelValuesCardinalityResponse {
+	merged := &labelValuesCardinalityResponse{
+		SeriesCountTotal: responses[0].SeriesCountTotal,
+		Labels:           []labelNamesCardinality{},
+	}
+
+	tenantIdxByID := map[string]int{}
+	for _, res := range responses {
+		merged.Labels = append(merged.Labels, res.Labels...)
+
+		for _, t := range res.Tenants {
+			idx, ok := tenantIdxByID[t.TenantID]
+			if !ok {
+				idx = len(merged.Tenants)
+				tenantIdxByID[t.TenantID] = idx
+				merged.Tenants = append(merged.Tenants, tenantLabelValuesCardinality{
+					TenantID:         t.TenantID,
+					SeriesCountTotal: t.SeriesCountTotal,
+					Labels:           []labelNamesCardinality{},
+				})
+			}
+			merged.Tenants[idx].Labels = append(merged.Tenants[idx].Labels, t.Labels...)
+		}
+	}
+
+	sortLabelNamesCardinality(merged.Labels)
+	for i := range merged.Tenants {
+		sortLabelNamesCardinality(merged.Tenants[i].Labels)
+	}
+	return merged
+}
+
+// sortLabelNamesCardinality sorts the labels in DESC order by series count and ASC order by label name,
+// like the queriers do.
+func sortLabelNamesCardinality(labels []labelNamesCardinality) {
+	sort.Slice(labels, func(i, j int) bool {
+		left, right := labels[i], labels[j]
+		return left.SeriesCount > right.SeriesCount || (left.SeriesCount == right.SeriesCount && left.LabelName < right.LabelName)
+	})
+}
+
+// labelValuesCardinalityResponse is the response of the label values cardinality API.
+type labelValuesCardinalityResponse struct {
+	SeriesCountTotal uint64                         `json:"series_count_total"`
+	Labels           []labelNamesCardinality        `json:"labels"`
+	Tenants          []tenantLabelValuesCardinality `json:"tenants,omitempty"`
+}
+
+type labelNamesCardinality struct {
+	LabelName        string                   `json:"label_name"`
+	LabelValuesCount uint64                   `json:"label_values_count"`
+	SeriesCount      uint64                   `json:"series_count"`
+	Cardinality      []labelValuesCardinality `json:"cardinality"`
+}
+
+type labelValuesCardinality struct {
+	LabelValue  string `json:"label_value"`
+	SeriesCount uint64 `json:"series_count"`
+}
+
+type tenantLabelValuesCardinality struct {
+	TenantID         string                  `json:"tenant_id"`
+	SeriesCountTotal uint64                  `json:"series_count_total"`
+	Labels           []labelNamesCardinality `json:"labels"`
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinalityQuerySharding_RoundTrip(t *testing.T) {
+	const labelValuesPath = "/prometheus/api/v1/cardinality/label_values"
+
+	// The series count of each label name, returned by the downstream.
+	seriesCounts := map[string]uint64{"job": 10, "instance": 30, "pod": 20, "namespace": 20}
+
+	tests := map[string]struct {
+		request                 func() *http.Request
+		totalShards             int
+		expectedDownstreamCalls int
+		expectedLabelNames      []string
+	}{
+		"should not split the request when sharding is disabled": {
+			request:                 newCardinalityGetRequest(labelValuesPath + "?label_names[]=job&label_names[]=instance&start=0&end=100"),
+			totalShards:             0,
+			expectedDownstreamCalls: 1,
+			expectedLabelNames:      []string{"instance", "job"},
+		},
+		"should not split the request with a single label name": {
+			request:                 newCardinalityGetRequest(labelValuesPath + "?label_names[]=job&start=0&end=100"),
+			totalShards:             4,
+			expectedDownstreamCalls: 1,
+			expectedLabelNames:      []string{"job"},
+		},
+		"should split a GET request by label name": {
+			request:                 newCardinalityGetRequest(labelValuesPath + "?label_names[]=job&label_names[]=instance&label_names[]=pod&label_names[]=namespace&start=0&end=100"),
+			totalShards:             2,
+			expectedDownstreamCalls: 2,
+			expectedLabelNames:      []string{"instance", "namespace", "pod", "job"},
+		},
+		"should split a POST request by label name, up to one request per label name": {
+			request: newCardinalityPostRequest(labelValuesPath, url.Values{
+				"label_names[]": []string{"job", "instance", "pod"},
+				"start":         []string{"0"},
+			}),
+			totalShards:             16,
+			expectedDownstreamCalls: 3,
+			expectedLabelNames:      []string{"instance", "pod", "job"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				mtx             sync.Mutex
+				downstreamCalls int
+			)
+			downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				require.NoError(t, r.ParseForm())
+				require.Equal(t, "0", r.Form.Get("start"))
+
+				mtx.Lock()
+				downstreamCalls++
+				mtx.Unlock()
+
+				var labels []string
+				for _, name := range r.Form["label_names[]"] {
+					labels = append(labels, fmt.Sprintf(`{"label_name":%q,"label_values_count":1,"series_count":%d,"cardinality":[{"label_value":"v","series_count":%d}]}`, name, seriesCounts[name], seriesCounts[name]))
+				}
+				body := fmt.Sprintf(`{"series_count_total":100,"labels":[%s]}`, strings.Join(labels, ","))
+
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			})
+
+			limits := mockLimits{totalShards: tc.totalShards, maxQueryParallelism: 2}
+			rt := newCardinalityQueryShardingRoundTripper(limits, downstream, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			res, err := rt.RoundTrip(tc.request())
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, tc.expectedDownstreamCalls, downstreamCalls)
+
+			response := labelValuesCardinalityResponse{}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+			assert.Equal(t, uint64(100), response.SeriesCountTotal)
+
+			var labelNames []string
+			for _, l := range response.Labels {
+				labelNames = append(labelNames, l.LabelName)
+				assert.Equal(t, seriesCounts[l.LabelName], l.SeriesCount)
+			}
+			if downstreamCalls == 1 {
+				// The response of a request which is not split is returned as is.
+				sort.Strings(labelNames)
+				sort.Strings(tc.expectedLabelNames)
+			}
+			assert.Equal(t, tc.expectedLabelNames, labelNames)
+		})
+	}
+}
+
+func TestCardinalityQuerySharding_RoundTripError(t *testing.T) {
+	downstream := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		require.NoError(t, r.ParseForm())
+		if r.Form.Get("label_names[]") == "instance" {
+			return &http.Response{StatusCode: http.StatusUnprocessableEntity, Body: io.NopCloser(strings.NewReader("limit exceeded"))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"series_count_total":1,"labels":[]}`))}, nil
+	})
+
+	rt := newCardinalityQueryShardingRoundTripper(mockLimits{totalShards: 2}, downstream, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	res, err := rt.RoundTrip(newCardinalityGetRequest("/prometheus/api/v1/cardinality/label_values?label_names[]=job&label_names[]=instance")())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "limit exceeded", string(body))
+}
+
+func TestMergeLabelValuesCardinalityResponses(t *testing.T) {
+	responses := []*labelValuesCardinalityResponse{
+		{
+			SeriesCountTotal: 30,
+			Labels:           []labelNamesCardinality{{LabelName: "job", LabelValuesCount: 1, SeriesCount: 10}},
+			Tenants: []tenantLabelValuesCardinality{
+				{TenantID: "team-a", SeriesCountTotal: 20, Labels: []labelNamesCardinality{{LabelName: "job", SeriesCount: 5}}},
+				{TenantID: "team-b", SeriesCountTotal: 10, Labels: []labelNamesCardinality{{LabelName: "job", SeriesCount: 5}}},
+			},
+		},
+		{
+			SeriesCountTotal: 30,
+			Labels:           []labelNamesCardinality{{LabelName: "instance", LabelValuesCount: 2, SeriesCount: 30}},
+			Tenants: []tenantLabelValuesCardinality{
+				{TenantID: "team-a", SeriesCountTotal: 20, Labels: []labelNamesCardinality{{LabelName: "instance", SeriesCount: 20}}},
+				{TenantID: "team-b", SeriesCountTotal: 10, Labels: []labelNamesCardinality{{LabelName: "instance", SeriesCount: 10}}},
+			},
+		},
+	}
+
+	assert.Equal(t, &labelValuesCardinalityResponse{
+		SeriesCountTotal: 30,
+		Labels: []labelNamesCardinality{
+			{LabelName: "instance", LabelValuesCount: 2, SeriesCount: 30},
+			{LabelName: "job", LabelValuesCount: 1, SeriesCount: 10},
+		},
+		Tenants: []tenantLabelValuesCardinality{
+			{TenantID: "team-a", SeriesCountTotal: 20, Labels: []labelNamesCardinality{{LabelName: "instance", SeriesCount: 20}, {LabelName: "job", SeriesCount: 5}}},
+			{TenantID: "team-b", SeriesCountTotal: 10, Labels: []labelNamesCardinality{{LabelName: "instance", SeriesCount: 10}, {LabelName: "job", SeriesCount: 5}}},
+		},
+	}, mergeLabelValuesCardinalityResponses(responses))
+}
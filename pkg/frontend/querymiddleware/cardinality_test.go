@@ -113,13 +113,13 @@ func Test_cardinalityEstimation_lookupCardinalityForKey(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ce := cardinalityEstimation{cache: tt.cache}
-			ce.storeCardinalityForKey(actualKey, actualValue)
+			ce.storeCardinalityForKey(actualKey, &QueryStatistics{EstimatedSeriesCount: actualValue})
 			estimate, ok := ce.lookupCardinalityForKey(ctx, tt.key)
 			if tt.cache != nil {
 				expectedFetchCount++
 			}
 			assert.Equal(t, expectedFetchCount, c.CountFetchCalls())
-			assert.Equal(t, tt.expectedCardinality, estimate)
+			assert.Equal(t, tt.expectedCardinality, estimate.GetEstimatedSeriesCount())
 			assert.Equal(t, tt.expectedPresent, ok)
 		})
 	}
@@ -219,7 +219,7 @@ func Test_cardinalityEstimation_Do(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := cache.NewInstrumentedMockCache()
-			mw := newCardinalityEstimationMiddleware(c, log.NewNopLogger(), nil)
+			mw := newCardinalityEstimationMiddleware(c, mockLimits{}, log.NewNopLogger(), nil)
 			handler := mw.Wrap(tt.downstreamHandler)
 			_, ctx := stats.ContextWithEmptyStats(context.Background())
 			if tt.tenantID != "" {
@@ -241,6 +241,86 @@ func Test_cardinalityEstimation_Do(t *testing.T) {
 
 }
 
+func Test_cardinalityEstimation_Do_estimatedMemoryLimit(t *testing.T) {
+	requestWithOptions := func(options Options) *PrometheusRangeQueryRequest {
+		return &PrometheusRangeQueryRequest{
+			Start:   parseTimeRFC3339(t, "2023-01-31T09:00:00Z").Unix() * 1000,
+			End:     parseTimeRFC3339(t, "2023-01-31T10:00:00Z").Unix() * 1000,
+			Query:   "up",
+			Options: options,
+		}
+	}
+	request := requestWithOptions(Options{})
+	marshaledEstimate, err := proto.Marshal(&QueryStatistics{EstimatedSeriesCount: 25, EstimatedChunkBytes: 1000})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		limits          mockLimits
+		request         Request
+		expectedErr     string
+		expectedQueries int
+	}{
+		"limit disabled": {
+			limits:          mockLimits{},
+			request:         request,
+			expectedQueries: 1,
+		},
+		"estimate below the limit": {
+			limits:          mockLimits{maxEstimatedMemoryPerQuery: 1000},
+			request:         request,
+			expectedQueries: 1,
+		},
+		"estimate above the limit": {
+			limits:      mockLimits{maxEstimatedMemoryPerQuery: 999},
+			request:     request,
+			expectedErr: "the query has been rejected before execution because its estimated memory exceeds the limit (estimated: 1000 bytes, limit: 999 bytes)",
+		},
+		"estimate spread over shards below the limit": {
+			limits:          mockLimits{maxEstimatedMemoryPerQuery: 500, totalShards: 2},
+			request:         request,
+			expectedQueries: 1,
+		},
+		"estimate spread over shards above the limit": {
+			limits:      mockLimits{maxEstimatedMemoryPerQuery: 300, totalShards: 2},
+			request:     request,
+			expectedErr: "(estimated: 500 bytes, limit: 300 bytes)",
+		},
+		"estimate spread over the shards requested in the query options": {
+			limits:          mockLimits{maxEstimatedMemoryPerQuery: 300, totalShards: 2},
+			request:         requestWithOptions(Options{TotalShards: 4}),
+			expectedQueries: 1,
+		},
+		"estimate not spread over shards when sharding is disabled for the query": {
+			limits:      mockLimits{maxEstimatedMemoryPerQuery: 500, totalShards: 2},
+			request:     requestWithOptions(Options{ShardingDisabled: true}),
+			expectedErr: "(estimated: 1000 bytes, limit: 500 bytes)",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := cache.NewInstrumentedMockCache()
+			c.StoreAsync(map[string][]byte{generateCardinalityEstimationCacheKey("1", request, cardinalityEstimateBucketSize): marshaledEstimate}, time.Minute)
+
+			queries := 0
+			handler := newCardinalityEstimationMiddleware(c, tt.limits, log.NewNopLogger(), nil).Wrap(HandlerFunc(func(ctx context.Context, _ Request) (Response, error) {
+				queries++
+				return &PrometheusResponse{}, nil
+			}))
+
+			_, ctx := stats.ContextWithEmptyStats(context.Background())
+			_, err := handler.Do(user.InjectOrgID(ctx, "1"), tt.request)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedQueries, queries)
+		})
+	}
+}
+
 func Test_cardinalityEstimateBucket_GenerateCacheKey_requestEquality(t *testing.T) {
 	rangeQuery := &PrometheusRangeQueryRequest{
 		Start: util.TimeToMillis(parseTimeRFC3339(t, "2023-01-31T09:00:00Z")),
@@ -352,7 +432,7 @@ func Test_cardinalityEstimateBucket_GenerateCacheKey_requestEquality(t *testing.
 func Test_newCardinalityEstimationMiddleware_canWrapMoreThanOnce(t *testing.T) {
 	req := &PrometheusRangeQueryRequest{}
 
-	mw := newCardinalityEstimationMiddleware(nil, log.NewNopLogger(), prometheus.NewRegistry())
+	mw := newCardinalityEstimationMiddleware(nil, mockLimits{}, log.NewNopLogger(), prometheus.NewRegistry())
 
 	require.NotPanics(t, func() {
 		_, err := mw.Wrap(mockHandlerWith(nil, nil)).Do(user.InjectOrgID(context.Background(), "test"), req)
@@ -37,6 +37,9 @@ import (
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 )
 
+// maxRangeQuerySteps is the max number of steps of a range query, enforced by the queriers too.
+const maxRangeQuerySteps = 11000
+
 var (
 	errEndBeforeStart = apierror.New(apierror.TypeBadData, `invalid parameter "end": end timestamp must not be before start time`)
 	errNegativeStep   = apierror.New(apierror.TypeBadData, `invalid parameter "step": zero or negative query resolution step widths are not accepted. Try a positive integer`)
@@ -203,9 +206,28 @@ func (prometheusCodec) MergeResponse(responses ...Response) (Response, error) {
 			ResultType: model.ValMatrix.String(),
 			Result:     matrixMerge(promResponses),
 		},
+		Warnings: warningsMerge(promResponses),
 	}, nil
 }
 
+// warningsMerge returns the unique warnings of the input responses, preserving their order.
+func warningsMerge(resps []*PrometheusResponse) []string {
+	var warnings []string
+	seen := map[string]struct{}{}
+
+	for _, resp := range resps {
+		for _, w := range resp.Warnings {
+			if _, ok := seen[w]; ok {
+				continue
+			}
+			seen[w] = struct{}{}
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
 func (c prometheusCodec) DecodeRequest(_ context.Context, r *http.Request) (Request, error) {
 	switch {
 	case isRangeQuery(r.URL.Path):
@@ -246,7 +268,7 @@ func (prometheusCodec) decodeRangeQueryRequest(r *http.Request) (Request, error)
 
 	// For safety, limit the number of returned points per timeseries.
 	// This is sufficient for 60s resolution for a week or 1h resolution for a year.
-	if (result.End-result.Start)/result.Step > 11000 {
+	if (result.End-result.Start)/result.Step > maxRangeQuerySteps {
 		return nil, errStepTooSmall
 	}
 
@@ -278,6 +300,11 @@ func decodeOptions(r *http.Request, opts *Options) {
 		}
 	}
 
+	// The results cache doesn't distinguish between queries including and excluding out-of-order samples.
+	if util.SkipOutOfOrderFromRequest(r) {
+		opts.CacheDisabled = true
+	}
+
 	for _, value := range r.Header.Values(totalShardsControlHeader) {
 		shards, err := strconv.ParseInt(value, 10, 32)
 		if err != nil {
@@ -335,6 +362,10 @@ func (c prometheusCodec) EncodeRequest(ctx context.Context, r Request) (*http.Re
 		Header:     http.Header{},
 	}
 
+	if util.SkipOutOfOrderFromOutgoingContext(ctx) {
+		req.Header.Set(util.SkipOutOfOrderHeader, "true")
+	}
+
 	switch c.preferredQueryResultResponseFormat {
 	case formatJSON:
 		req.Header.Set("Accept", jsonMimeType)
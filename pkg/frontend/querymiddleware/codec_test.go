@@ -30,6 +30,7 @@ import (
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
 )
 
 var (
@@ -128,6 +129,18 @@ func TestPrometheusCodec_EncodeRequest_AcceptHeader(t *testing.T) {
 	}
 }
 
+func TestPrometheusCodec_EncodeRequest_SkipOutOfOrderHeader(t *testing.T) {
+	codec := NewPrometheusCodec(prometheus.NewPedanticRegistry(), formatJSON)
+
+	encodedRequest, err := codec.EncodeRequest(context.Background(), &PrometheusRangeQueryRequest{})
+	require.NoError(t, err)
+	require.Empty(t, encodedRequest.Header.Get(util.SkipOutOfOrderHeader))
+
+	encodedRequest, err = codec.EncodeRequest(util.AddSkipOutOfOrderToOutgoingContext(context.Background()), &PrometheusRangeQueryRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "true", encodedRequest.Header.Get(util.SkipOutOfOrderHeader))
+}
+
 func TestPrometheusCodec_EncodeResponse_ContentNegotiation(t *testing.T) {
 	testResponse := &PrometheusResponse{
 		Status:    statusError,
@@ -402,6 +415,36 @@ func TestMergeAPIResponses(t *testing.T) {
 			},
 		},
 
+		{
+			name: "Warnings are merged and deduplicated.",
+			input: []Response{
+				&PrometheusResponse{
+					Status: statusSuccess,
+					Data: &PrometheusData{
+						ResultType: matrix,
+						Result:     []SampleStream{},
+					},
+					Warnings: []string{"warning 1", "warning 2"},
+				},
+				&PrometheusResponse{
+					Status: statusSuccess,
+					Data: &PrometheusData{
+						ResultType: matrix,
+						Result:     []SampleStream{},
+					},
+					Warnings: []string{"warning 2", "warning 3"},
+				},
+			},
+			expected: &PrometheusResponse{
+				Status: statusSuccess,
+				Data: &PrometheusData{
+					ResultType: matrix,
+					Result:     []SampleStream{},
+				},
+				Warnings: []string{"warning 1", "warning 2", "warning 3"},
+			},
+		},
+
 		{
 			name: "Basic merging of two responses.",
 			input: []Response{
@@ -835,6 +878,17 @@ func Test_DecodeOptions(t *testing.T) {
 				CacheDisabled: true,
 			},
 		},
+		{
+			name: "skip out-of-order samples",
+			input: &http.Request{
+				Header: http.Header{
+					util.SkipOutOfOrderHeader: []string{"true"},
+				},
+			},
+			expected: &Options{
+				CacheDisabled: true,
+			},
+		},
 		{
 			name: "custom sharding",
 			input: &http.Request{
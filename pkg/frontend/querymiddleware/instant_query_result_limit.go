@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/common/model"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// instantQueryResultSeriesLimitMiddleware is a Middleware that enforces the max number of series
+// returned in the result of an instant query. The result exceeding the limit is either rejected
+// or truncated to the series with the highest values, depending on the tenant configuration.
+type instantQueryResultSeriesLimitMiddleware struct {
+	next   Handler
+	limits Limits
+	logger log.Logger
+}
+
+// newInstantQueryResultSeriesLimitMiddleware creates a new Middleware that enforces the max number of series
+// returned in the result of an instant query.
+func newInstantQueryResultSeriesLimitMiddleware(limits Limits, logger log.Logger) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &instantQueryResultSeriesLimitMiddleware{
+			next:   next,
+			limits: limits,
+			logger: logger,
+		}
+	})
+}
+
+func (m *instantQueryResultSeriesLimitMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	maxSeries := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, m.limits.MaxInstantQueryResultSeries)
+	if maxSeries <= 0 {
+		return m.next.Do(ctx, req)
+	}
+
+	res, err := m.next.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	promRes, ok := res.(*PrometheusResponse)
+	if !ok || promRes.Data == nil || len(promRes.Data.Result) <= maxSeries {
+		return res, nil
+	}
+
+	// Scalar and string results are made of a single sample stream, so they can't exceed the limit.
+	resultType := promRes.Data.ResultType
+	if resultType != model.ValVector.String() && resultType != model.ValMatrix.String() {
+		return res, nil
+	}
+
+	numSeries := len(promRes.Data.Result)
+
+	// The result is truncated only if all tenants allow it.
+	for _, tenantID := range tenantIDs {
+		if !m.limits.InstantQueryResultSeriesTruncationEnabled(tenantID) {
+			return nil, apierror.New(apierror.TypeExec, validation.NewMaxInstantQueryResultSeriesError(numSeries, maxSeries).Error())
+		}
+	}
+
+	spanLog := spanlogger.FromContext(ctx, m.logger)
+	level.Debug(spanLog).Log("msg", "truncating instant query result", "series", numSeries, "limit", maxSeries)
+
+	promRes.Data.Result = truncateSampleStreams(promRes.Data.Result, maxSeries)
+	promRes.Warnings = append(promRes.Warnings, fmt.Sprintf(
+		"the query result has been truncated to the %d series with the highest values, out of %d series (limit set by -query-frontend.max-instant-query-result-series)",
+		maxSeries, numSeries))
+
+	return promRes, nil
+}
+
+// truncateSampleStreams returns the maxSeries input streams having the highest values, like topk() does.
+// The value of a stream is the value of its latest sample. Streams without a value, or with a NaN value,
+// are sorted last. The order of streams with the same value is preserved.
+func truncateSampleStreams(streams []SampleStream, maxSeries int) []SampleStream {
+	sort.SliceStable(streams, func(i, j int) bool {
+		vi, vj := sampleStreamValue(streams[i]), sampleStreamValue(streams[j])
+		if math.IsNaN(vj) {
+			return !math.IsNaN(vi)
+		}
+		return vi > vj
+	})
+
+	return streams[:maxSeries]
+}
+
+// sampleStreamValue returns the value of the latest sample in the input stream, or NaN if the stream has no samples.
+// The value of a histogram sample is its count.
+func sampleStreamValue(stream SampleStream) float64 {
+	var (
+		value = math.NaN()
+		ts    = int64(math.MinInt64)
+	)
+
+	if n := len(stream.Samples); n > 0 {
+		value, ts = stream.Samples[n-1].Value, stream.Samples[n-1].TimestampMs
+	}
+	if n := len(stream.Histograms); n > 0 && stream.Histograms[n-1].TimestampMs > ts {
+		value = stream.Histograms[n-1].Histogram.Count
+	}
+
+	return value
+}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestInstantQueryResultSeriesLimitMiddleware(t *testing.T) {
+	vectorResponse := func(values ...float64) *PrometheusResponse {
+		res := &PrometheusResponse{
+			Status: statusSuccess,
+			Data:   &PrometheusData{ResultType: "vector"},
+		}
+		for i, v := range values {
+			res.Data.Result = append(res.Data.Result, SampleStream{
+				Labels:  []mimirpb.LabelAdapter{{Name: "series", Value: string(rune('a' + i))}},
+				Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: v}},
+			})
+		}
+		return res
+	}
+
+	tests := map[string]struct {
+		limits           Limits
+		tenantIDs        []string
+		response         *PrometheusResponse
+		expectedResponse *PrometheusResponse
+		expectedErr      string
+	}{
+		"limit disabled": {
+			limits:           mockLimits{},
+			tenantIDs:        []string{"user-1"},
+			response:         vectorResponse(1, 2, 3),
+			expectedResponse: vectorResponse(1, 2, 3),
+		},
+		"result within the limit": {
+			limits:           mockLimits{maxInstantQueryResultSeries: 3},
+			tenantIDs:        []string{"user-1"},
+			response:         vectorResponse(1, 2, 3),
+			expectedResponse: vectorResponse(1, 2, 3),
+		},
+		"result exceeding the limit with truncation disabled": {
+			limits:      mockLimits{maxInstantQueryResultSeries: 2},
+			tenantIDs:   []string{"user-1"},
+			response:    vectorResponse(1, 2, 3),
+			expectedErr: "the number of series in the instant query result exceeds the limit (series: 3, limit: 2)",
+		},
+		"result exceeding the limit with truncation enabled": {
+			limits:    mockLimits{maxInstantQueryResultSeries: 2, instantQueryResultSeriesTruncationEnabled: true},
+			tenantIDs: []string{"user-1"},
+			response:  vectorResponse(1, math.NaN(), 3, 2),
+			expectedResponse: func() *PrometheusResponse {
+				res := vectorResponse(1, math.NaN(), 3, 2)
+				res.Data.Result = []SampleStream{res.Data.Result[2], res.Data.Result[3]}
+				res.Warnings = []string{"the query result has been truncated to the 2 series with the highest values, out of 4 series (limit set by -query-frontend.max-instant-query-result-series)"}
+				return res
+			}(),
+		},
+		"scalar result": {
+			limits:    mockLimits{maxInstantQueryResultSeries: 1},
+			tenantIDs: []string{"user-1"},
+			response: &PrometheusResponse{
+				Status: statusSuccess,
+				Data: &PrometheusData{
+					ResultType: "scalar",
+					Result:     []SampleStream{{Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}}}},
+				},
+			},
+			expectedResponse: &PrometheusResponse{
+				Status: statusSuccess,
+				Data: &PrometheusData{
+					ResultType: "scalar",
+					Result:     []SampleStream{{Samples: []mimirpb.Sample{{TimestampMs: 1000, Value: 1}}}},
+				},
+			},
+		},
+		"multiple tenants, the smallest limit is enforced and truncation is disabled if any tenant disables it": {
+			limits: multiTenantMockLimits{byTenant: map[string]mockLimits{
+				"user-1": {maxInstantQueryResultSeries: 5, instantQueryResultSeriesTruncationEnabled: true},
+				"user-2": {maxInstantQueryResultSeries: 2},
+			}},
+			tenantIDs:   []string{"user-1", "user-2"},
+			response:    vectorResponse(1, 2, 3),
+			expectedErr: "the number of series in the instant query result exceeds the limit (series: 3, limit: 2)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tenant.WithDefaultResolver(tenant.NewMultiResolver())
+			ctx := user.InjectOrgID(context.Background(), tenant.JoinTenantIDs(tc.tenantIDs))
+
+			inner := &mockHandler{}
+			inner.On("Do", mock.Anything, mock.Anything).Return(tc.response, nil)
+
+			middleware := newInstantQueryResultSeriesLimitMiddleware(tc.limits, log.NewNopLogger()).Wrap(inner)
+			res, err := middleware.Do(ctx, &PrometheusInstantQueryRequest{Query: "up"})
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				assert.True(t, apierror.IsAPIError(err))
+				return
+			}
+
+			require.NoError(t, err)
+
+			// Compare the JSON encoding, because NaN values are not equal to each other.
+			expected, err := json.Marshal(tc.expectedResponse)
+			require.NoError(t, err)
+			actual, err := json.Marshal(res)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
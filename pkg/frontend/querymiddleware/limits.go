@@ -43,6 +43,29 @@ type Limits interface {
 	// query may be. 0 means "unlimited".
 	MaxQueryExpressionSizeBytes(userID string) int
 
+	// MaxInstantQueryResultSeries returns the limit of the number of series returned in the result of
+	// an instant query. 0 means "unlimited".
+	MaxInstantQueryResultSeries(userID string) int
+
+	// InstantQueryResultSeriesTruncationEnabled returns whether the result of an instant query exceeding
+	// the max number of series should be truncated instead of failing the query.
+	InstantQueryResultSeriesTruncationEnabled(userID string) bool
+
+	// MaxRangeQueryResultSamples returns the limit of the number of samples returned in the result of
+	// a range query. 0 means "unlimited".
+	MaxRangeQueryResultSamples(userID string) int
+
+	// RangeQueryResultStepIncreaseEnabled returns whether the step of a range query whose result exceeds
+	// the max number of samples should be increased instead of failing the query.
+	RangeQueryResultStepIncreaseEnabled(userID string) bool
+
+	// MaxEstimatedMemoryPerQuery returns the limit of the estimated memory, in bytes, taken in the querier
+	// by the series and chunks fetched by a query. 0 means "unlimited".
+	MaxEstimatedMemoryPerQuery(userID string) int
+
+	// BlockedQueries returns the queries rejected by the query-frontend for the given tenant.
+	BlockedQueries(userID string) []validation.BlockedQuery
+
 	// MaxCacheFreshness returns the period after which results are cacheable,
 	// to prevent caching of very recent results.
 	MaxCacheFreshness(userID string) time.Duration
@@ -80,6 +103,12 @@ type Limits interface {
 
 	// ResultsCacheForOutOfOrderWindowTTL returns TTL for cached results for query that falls into out-of-order ingestion window.
 	ResultsCacheTTLForOutOfOrderTimeWindow(userID string) time.Duration
+
+	// ResultsCacheMaxEntrySizeBytes returns the maximum size of a results cache entry. Larger entries are not cached.
+	ResultsCacheMaxEntrySizeBytes(userID string) int
+
+	// ResultsCacheTTLForCardinalityQuery returns TTL for cached results for cardinality queries. 0 to disable the cache.
+	ResultsCacheTTLForCardinalityQuery(userID string) time.Duration
 }
 
 type limitsMiddleware struct {
@@ -232,6 +261,12 @@ func (rt limitedParallelismRoundTripper) RoundTrip(r *http.Request) (*http.Respo
 		return nil, err
 	}
 
+	// Keep track of the option to exclude out-of-order samples, so that it's propagated to the querier
+	// when the sub-requests are encoded.
+	if util.SkipOutOfOrderFromRequest(r) {
+		ctx = util.AddSkipOutOfOrderToOutgoingContext(ctx)
+	}
+
 	if span := opentracing.SpanFromContext(ctx); span != nil {
 		request.LogToSpan(span)
 	}
@@ -23,6 +23,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 func TestLimitsMiddleware_MaxQueryLookback(t *testing.T) {
@@ -371,6 +372,30 @@ func (m multiTenantMockLimits) MaxQueryExpressionSizeBytes(userID string) int {
 	return m.byTenant[userID].maxQueryExpressionSizeBytes
 }
 
+func (m multiTenantMockLimits) MaxInstantQueryResultSeries(userID string) int {
+	return m.byTenant[userID].maxInstantQueryResultSeries
+}
+
+func (m multiTenantMockLimits) InstantQueryResultSeriesTruncationEnabled(userID string) bool {
+	return m.byTenant[userID].instantQueryResultSeriesTruncationEnabled
+}
+
+func (m multiTenantMockLimits) MaxRangeQueryResultSamples(userID string) int {
+	return m.byTenant[userID].maxRangeQueryResultSamples
+}
+
+func (m multiTenantMockLimits) RangeQueryResultStepIncreaseEnabled(userID string) bool {
+	return m.byTenant[userID].rangeQueryResultStepIncreaseEnabled
+}
+
+func (m multiTenantMockLimits) MaxEstimatedMemoryPerQuery(userID string) int {
+	return m.byTenant[userID].maxEstimatedMemoryPerQuery
+}
+
+func (m multiTenantMockLimits) BlockedQueries(userID string) []validation.BlockedQuery {
+	return m.byTenant[userID].blockedQueries
+}
+
 func (m multiTenantMockLimits) MaxQueryParallelism(userID string) int {
 	return m.byTenant[userID].maxQueryParallelism
 }
@@ -411,6 +436,14 @@ func (m multiTenantMockLimits) ResultsCacheTTLForOutOfOrderTimeWindow(userID str
 	return m.byTenant[userID].resultsCacheOutOfOrderWindowTTL
 }
 
+func (m multiTenantMockLimits) ResultsCacheMaxEntrySizeBytes(userID string) int {
+	return m.byTenant[userID].resultsCacheMaxEntrySizeBytes
+}
+
+func (m multiTenantMockLimits) ResultsCacheTTLForCardinalityQuery(userID string) time.Duration {
+	return m.byTenant[userID].resultsCacheTTLForCardinalityQuery
+}
+
 func (m multiTenantMockLimits) CreationGracePeriod(userID string) time.Duration {
 	return m.byTenant[userID].creationGracePeriod
 }
@@ -420,22 +453,30 @@ func (m multiTenantMockLimits) NativeHistogramsIngestionEnabled(userID string) b
 }
 
 type mockLimits struct {
-	maxQueryLookback                 time.Duration
-	maxQueryLength                   time.Duration
-	maxTotalQueryLength              time.Duration
-	maxQueryExpressionSizeBytes      int
-	maxCacheFreshness                time.Duration
-	maxQueryParallelism              int
-	maxShardedQueries                int
-	splitInstantQueriesInterval      time.Duration
-	totalShards                      int
-	compactorShards                  int
-	compactorBlocksRetentionPeriod   time.Duration
-	outOfOrderTimeWindow             time.Duration
-	creationGracePeriod              time.Duration
-	nativeHistogramsIngestionEnabled bool
-	resultsCacheTTL                  time.Duration
-	resultsCacheOutOfOrderWindowTTL  time.Duration
+	maxQueryLookback                          time.Duration
+	maxQueryLength                            time.Duration
+	maxTotalQueryLength                       time.Duration
+	maxQueryExpressionSizeBytes               int
+	maxInstantQueryResultSeries               int
+	instantQueryResultSeriesTruncationEnabled bool
+	maxRangeQueryResultSamples                int
+	rangeQueryResultStepIncreaseEnabled       bool
+	maxEstimatedMemoryPerQuery                int
+	blockedQueries                            []validation.BlockedQuery
+	maxCacheFreshness                         time.Duration
+	maxQueryParallelism                       int
+	maxShardedQueries                         int
+	splitInstantQueriesInterval               time.Duration
+	totalShards                               int
+	compactorShards                           int
+	compactorBlocksRetentionPeriod            time.Duration
+	outOfOrderTimeWindow                      time.Duration
+	creationGracePeriod                       time.Duration
+	nativeHistogramsIngestionEnabled          bool
+	resultsCacheTTL                           time.Duration
+	resultsCacheOutOfOrderWindowTTL           time.Duration
+	resultsCacheMaxEntrySizeBytes             int
+	resultsCacheTTLForCardinalityQuery        time.Duration
 }
 
 func (m mockLimits) MaxQueryLookback(string) time.Duration {
@@ -457,6 +498,30 @@ func (m mockLimits) MaxQueryExpressionSizeBytes(string) int {
 	return m.maxQueryExpressionSizeBytes
 }
 
+func (m mockLimits) MaxInstantQueryResultSeries(string) int {
+	return m.maxInstantQueryResultSeries
+}
+
+func (m mockLimits) InstantQueryResultSeriesTruncationEnabled(string) bool {
+	return m.instantQueryResultSeriesTruncationEnabled
+}
+
+func (m mockLimits) MaxRangeQueryResultSamples(string) int {
+	return m.maxRangeQueryResultSamples
+}
+
+func (m mockLimits) RangeQueryResultStepIncreaseEnabled(string) bool {
+	return m.rangeQueryResultStepIncreaseEnabled
+}
+
+func (m mockLimits) MaxEstimatedMemoryPerQuery(string) int {
+	return m.maxEstimatedMemoryPerQuery
+}
+
+func (m mockLimits) BlockedQueries(string) []validation.BlockedQuery {
+	return m.blockedQueries
+}
+
 func (m mockLimits) MaxQueryParallelism(string) int {
 	if m.maxQueryParallelism == 0 {
 		return 14 // Flag default.
@@ -500,6 +565,14 @@ func (m mockLimits) ResultsCacheTTLForOutOfOrderTimeWindow(userID string) time.D
 	return m.resultsCacheOutOfOrderWindowTTL
 }
 
+func (m mockLimits) ResultsCacheMaxEntrySizeBytes(userID string) int {
+	return m.resultsCacheMaxEntrySizeBytes
+}
+
+func (m mockLimits) ResultsCacheTTLForCardinalityQuery(userID string) time.Duration {
+	return m.resultsCacheTTLForCardinalityQuery
+}
+
 func (m mockLimits) CreationGracePeriod(userID string) time.Duration {
 	return m.creationGracePeriod
 }
@@ -287,6 +287,7 @@ type PrometheusResponse struct {
 	ErrorType string                      `protobuf:"bytes,3,opt,name=ErrorType,proto3" json:"errorType,omitempty"`
 	Error     string                      `protobuf:"bytes,4,opt,name=Error,proto3" json:"error,omitempty"`
 	Headers   []*PrometheusResponseHeader `protobuf:"bytes,5,rep,name=Headers,proto3" json:"-"`
+	Warnings  []string                    `protobuf:"bytes,6,rep,name=Warnings,proto3" json:"warnings,omitempty"`
 }
 
 func (m *PrometheusResponse) Reset()      { *m = PrometheusResponse{} }
@@ -356,6 +357,13 @@ func (m *PrometheusResponse) GetHeaders() []*PrometheusResponseHeader {
 	return nil
 }
 
+func (m *PrometheusResponse) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
 type PrometheusData struct {
 	ResultType string         `protobuf:"bytes,1,opt,name=ResultType,proto3" json:"resultType"`
 	Result     []SampleStream `protobuf:"bytes,2,rep,name=Result,proto3" json:"result"`
@@ -752,6 +760,7 @@ func (*Hints) XXX_OneofWrappers() []interface{} {
 
 type QueryStatistics struct {
 	EstimatedSeriesCount uint64 `protobuf:"varint,1,opt,name=EstimatedSeriesCount,proto3" json:"EstimatedSeriesCount,omitempty"`
+	EstimatedChunkBytes  uint64 `protobuf:"varint,2,opt,name=EstimatedChunkBytes,proto3" json:"EstimatedChunkBytes,omitempty"`
 }
 
 func (m *QueryStatistics) Reset()      { *m = QueryStatistics{} }
@@ -793,6 +802,13 @@ func (m *QueryStatistics) GetEstimatedSeriesCount() uint64 {
 	return 0
 }
 
+func (m *QueryStatistics) GetEstimatedChunkBytes() uint64 {
+	if m != nil {
+		return m.EstimatedChunkBytes
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*PrometheusRangeQueryRequest)(nil), "queryrange.PrometheusRangeQueryRequest")
 	proto.RegisterType((*PrometheusInstantQueryRequest)(nil), "queryrange.PrometheusInstantQueryRequest")
@@ -810,77 +826,79 @@ func init() {
 func init() { proto.RegisterFile("model.proto", fileDescriptor_4c16552f9fdb66d8) }
 
 var fileDescriptor_4c16552f9fdb66d8 = []byte{
-	// 1107 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xdf, 0x6e, 0x1b, 0xc5,
-	0x17, 0xf6, 0x7a, 0xd7, 0x76, 0x72, 0xd2, 0x5f, 0x92, 0xdf, 0x24, 0x88, 0x4d, 0xa1, 0xbb, 0xd6,
-	0xaa, 0x17, 0x01, 0xb5, 0x0e, 0xa4, 0x70, 0x83, 0x04, 0xa2, 0x9b, 0x06, 0xa5, 0x88, 0x3f, 0x65,
-	0x12, 0x71, 0xc1, 0x4d, 0x35, 0xf6, 0x4e, 0xed, 0xa5, 0xfb, 0xaf, 0x33, 0xe3, 0x52, 0xdf, 0x21,
-	0x1e, 0x00, 0x71, 0xc9, 0x0b, 0x20, 0xf1, 0x04, 0x3c, 0x43, 0x2f, 0xcb, 0x5d, 0xd5, 0x0b, 0x43,
-	0x5d, 0x21, 0x21, 0x5f, 0xf5, 0x11, 0xd0, 0x9c, 0xd9, 0xb5, 0x37, 0x4d, 0x2a, 0xca, 0x4d, 0x32,
-	0x73, 0xce, 0x77, 0xbe, 0xf9, 0xce, 0x99, 0xf1, 0xb7, 0xb0, 0x96, 0xe6, 0x11, 0x4f, 0x7a, 0x85,
-	0xc8, 0x55, 0x4e, 0xe0, 0xde, 0x98, 0x8b, 0x89, 0x60, 0xd9, 0x90, 0x5f, 0xbc, 0x3a, 0x8c, 0xd5,
-	0x68, 0xdc, 0xef, 0x0d, 0xf2, 0x74, 0x6f, 0x98, 0x0f, 0xf3, 0x3d, 0x84, 0xf4, 0xc7, 0x77, 0x70,
-	0x87, 0x1b, 0x5c, 0x99, 0xd2, 0x8b, 0xde, 0x30, 0xcf, 0x87, 0x09, 0x5f, 0xa2, 0xa2, 0xb1, 0x60,
-	0x2a, 0xce, 0xb3, 0x32, 0xff, 0x4e, 0x9d, 0x4e, 0xb0, 0x3b, 0x2c, 0x63, 0x7b, 0x69, 0x9c, 0xc6,
-	0x62, 0xaf, 0xb8, 0x3b, 0x34, 0xab, 0xa2, 0x6f, 0xfe, 0x97, 0x15, 0x3b, 0x2f, 0x32, 0xb2, 0x6c,
-	0x62, 0x52, 0xc1, 0x6f, 0x4d, 0x78, 0xe3, 0x96, 0xc8, 0x53, 0xae, 0x46, 0x7c, 0x2c, 0xa9, 0xd6,
-	0xfb, 0x95, 0x56, 0x4e, 0xf9, 0xbd, 0x31, 0x97, 0x8a, 0x10, 0x70, 0x0a, 0xa6, 0x46, 0xae, 0xd5,
-	0xb5, 0x76, 0x57, 0x29, 0xae, 0xc9, 0x36, 0xb4, 0xa4, 0x62, 0x42, 0xb9, 0xcd, 0xae, 0xb5, 0x6b,
-	0x53, 0xb3, 0x21, 0x9b, 0x60, 0xf3, 0x2c, 0x72, 0x6d, 0x8c, 0xe9, 0xa5, 0xae, 0x95, 0x8a, 0x17,
-	0xae, 0x83, 0x21, 0x5c, 0x93, 0x0f, 0xa1, 0xa3, 0xe2, 0x94, 0xe7, 0x63, 0xe5, 0xb6, 0xba, 0xd6,
-	0xee, 0xda, 0xfe, 0x4e, 0xcf, 0x88, 0xeb, 0x55, 0xe2, 0x7a, 0x37, 0xca, 0x76, 0xc3, 0x95, 0x87,
-	0x53, 0xbf, 0xf1, 0xf3, 0x1f, 0xbe, 0x45, 0xab, 0x1a, 0x7d, 0x34, 0x0e, 0xd6, 0x6d, 0xa3, 0x1e,
-	0xb3, 0x21, 0xd7, 0xa0, 0x93, 0x17, 0xba, 0x44, 0xba, 0x1d, 0x24, 0xdd, 0xea, 0x2d, 0xc7, 0xdf,
-	0xfb, 0xd2, 0xa4, 0x42, 0x47, 0xd3, 0xd1, 0x0a, 0x49, 0xd6, 0xa1, 0x19, 0x47, 0xee, 0x0a, 0x6a,
-	0x6b, 0xc6, 0x11, 0xb9, 0x0a, 0xad, 0x51, 0x9c, 0x29, 0xe9, 0xae, 0x22, 0xc5, 0xff, 0xeb, 0x14,
-	0x47, 0x3a, 0x81, 0x04, 0x16, 0x35, 0xa8, 0xe0, 0x77, 0x0b, 0x2e, 0x2d, 0x07, 0x77, 0x33, 0x93,
-	0x8a, 0x65, 0xea, 0x5f, 0x47, 0x47, 0xc0, 0xd1, 0xad, 0x94, 0x93, 0xc3, 0xf5, 0xb2, 0x27, 0xfb,
-	0x25, 0x3d, 0x39, 0xff, 0xb1, 0xa7, 0xd6, 0xd9, 0x9e, 0xda, 0xaf, 0xd4, 0xd3, 0x09, 0xb8, 0xb5,
-	0xb7, 0xc0, 0x65, 0x91, 0x67, 0x92, 0x1f, 0x71, 0x16, 0x71, 0x41, 0x76, 0xc0, 0xf9, 0x82, 0xa5,
-	0xdc, 0x74, 0x13, 0xb6, 0xe6, 0x53, 0xdf, 0xba, 0x4a, 0x31, 0x44, 0x2e, 0x41, 0xfb, 0x6b, 0x96,
-	0x8c, 0xb9, 0x74, 0x9b, 0x5d, 0x7b, 0x99, 0x2c, 0x83, 0xc1, 0x2f, 0x4d, 0x20, 0x67, 0x69, 0x49,
-	0x00, 0xed, 0x63, 0xc5, 0xd4, 0x58, 0x96, 0x94, 0x30, 0x9f, 0xfa, 0x6d, 0x89, 0x11, 0x5a, 0x66,
-	0x48, 0x08, 0xce, 0x0d, 0xa6, 0x18, 0x8e, 0x6b, 0x6d, 0xff, 0x62, 0x5d, 0xfe, 0x92, 0x51, 0x23,
-	0x42, 0x32, 0x9f, 0xfa, 0xeb, 0x11, 0x53, 0xec, 0x4a, 0x9e, 0xc6, 0x8a, 0xa7, 0x85, 0x9a, 0x50,
-	0xac, 0x25, 0xef, 0xc3, 0xea, 0xa1, 0x10, 0xb9, 0x38, 0x99, 0x14, 0xdc, 0x8c, 0x38, 0x7c, 0x7d,
-	0x3e, 0xf5, 0xb7, 0x78, 0x15, 0xac, 0x55, 0x2c, 0x91, 0xe4, 0x2d, 0x68, 0xe1, 0x06, 0xa7, 0xbf,
-	0x1a, 0x6e, 0xcd, 0xa7, 0xfe, 0x06, 0x96, 0xd4, 0xe0, 0x06, 0x41, 0x0e, 0xa1, 0x63, 0x86, 0x24,
-	0xdd, 0x56, 0xd7, 0xde, 0x5d, 0xdb, 0xbf, 0x7c, 0xbe, 0xd0, 0xd3, 0x13, 0xad, 0xc6, 0x54, 0xd5,
-	0x06, 0x3f, 0x58, 0xb0, 0x7e, 0xba, 0x2b, 0xd2, 0x03, 0xa0, 0x5c, 0x8e, 0x13, 0x85, 0xe2, 0xcd,
-	0x9c, 0xd6, 0xe7, 0x53, 0x1f, 0xc4, 0x22, 0x4a, 0x6b, 0x08, 0xf2, 0x31, 0xb4, 0xcd, 0x0e, 0x6f,
-	0x62, 0x6d, 0xdf, 0xad, 0x0b, 0x39, 0x66, 0x69, 0x91, 0xf0, 0x63, 0x25, 0x38, 0x4b, 0xc3, 0x75,
-	0xfd, 0x70, 0xf4, 0xc4, 0x0d, 0x13, 0x2d, 0xeb, 0x82, 0x1f, 0x9b, 0x70, 0xa1, 0x0e, 0x24, 0x05,
-	0xb4, 0x13, 0xd6, 0xe7, 0x89, 0xbe, 0x26, 0x1b, 0x9f, 0xe1, 0x20, 0x17, 0x8a, 0x3f, 0x28, 0xfa,
-	0xbd, 0xcf, 0x74, 0xfc, 0x16, 0x8b, 0x45, 0x78, 0xa0, 0xd9, 0x9e, 0x4c, 0xfd, 0x77, 0x5f, 0xc5,
-	0x9a, 0x4c, 0xdd, 0xf5, 0x88, 0x15, 0x8a, 0x0b, 0x2d, 0x21, 0xe5, 0x4a, 0xc4, 0x03, 0x5a, 0x9e,
-	0x43, 0x3e, 0x80, 0x8e, 0x44, 0x05, 0xb2, 0xec, 0x62, 0x73, 0x79, 0xa4, 0x91, 0xb6, 0x54, 0x7f,
-	0x1f, 0x9f, 0x18, 0xad, 0x0a, 0xc8, 0x2d, 0x80, 0x51, 0x2c, 0x55, 0x3e, 0x14, 0x2c, 0x95, 0xae,
-	0x8d, 0xe5, 0x6f, 0x2e, 0xcb, 0x3f, 0x49, 0x72, 0xa6, 0x8e, 0x2a, 0x00, 0x4a, 0x27, 0x25, 0x55,
-	0xad, 0x8e, 0xd6, 0xd6, 0xc1, 0xb7, 0xb0, 0x7e, 0xc0, 0x06, 0x23, 0x1e, 0x2d, 0x1e, 0xee, 0x0e,
-	0xd8, 0x77, 0xf9, 0xa4, 0xbc, 0x8d, 0xce, 0x7c, 0xea, 0xeb, 0x2d, 0xd5, 0x7f, 0xb4, 0xbb, 0xf1,
-	0x07, 0x8a, 0xeb, 0x5f, 0x9c, 0x91, 0x4e, 0xea, 0x17, 0x70, 0x88, 0xa9, 0x70, 0xa3, 0x3c, 0xb1,
-	0x82, 0xd2, 0x6a, 0x11, 0x3c, 0xb1, 0xa0, 0x6d, 0x40, 0xc4, 0xaf, 0x3c, 0x56, 0x1f, 0x63, 0x87,
-	0xab, 0xf3, 0xa9, 0x6f, 0x02, 0x95, 0xdd, 0xee, 0x18, 0xbb, 0x45, 0x23, 0x31, 0x2a, 0x78, 0x16,
-	0x19, 0xdf, 0xed, 0xc2, 0x8a, 0x12, 0x6c, 0xc0, 0x6f, 0xc7, 0x51, 0xf9, 0x7a, 0xab, 0xa7, 0x86,
-	0xe1, 0x9b, 0x11, 0xf9, 0x08, 0x56, 0x44, 0xd9, 0x4e, 0x69, 0xc3, 0xdb, 0x67, 0x6c, 0xf8, 0x7a,
-	0x36, 0x09, 0x2f, 0xcc, 0xa7, 0xfe, 0x02, 0x49, 0x17, 0x2b, 0x72, 0x05, 0x08, 0xf6, 0x75, 0x5b,
-	0x1b, 0x98, 0x54, 0x2c, 0x2d, 0x6e, 0xa7, 0xc6, 0x64, 0x6c, 0xba, 0x89, 0x99, 0x93, 0x2a, 0xf1,
-	0xb9, 0xfc, 0xd4, 0x59, 0xb1, 0x37, 0x9d, 0xe0, 0x2f, 0x0b, 0x3a, 0xa5, 0x6d, 0x91, 0xcb, 0xf0,
-	0x3f, 0x1c, 0xea, 0x8d, 0x58, 0xb2, 0x7e, 0xc2, 0x23, 0xec, 0x72, 0x85, 0x9e, 0x0e, 0x92, 0xb7,
-	0x61, 0xf3, 0x78, 0xc4, 0x44, 0x14, 0x67, 0xc3, 0x05, 0xb0, 0x89, 0xc0, 0x33, 0x71, 0xd2, 0x85,
-	0xb5, 0x93, 0x5c, 0xb1, 0x04, 0x13, 0x12, 0x7f, 0xe7, 0x2d, 0x5a, 0x0f, 0x91, 0x7d, 0xd8, 0x2e,
-	0x5d, 0xfa, 0xb8, 0x48, 0x62, 0xb5, 0x60, 0x74, 0x90, 0xf1, 0xdc, 0xdc, 0x8b, 0x35, 0x37, 0x33,
-	0xc5, 0xc5, 0x7d, 0x96, 0x94, 0x0e, 0x7b, 0x6e, 0x2e, 0x78, 0x00, 0x2d, 0xb4, 0x56, 0x12, 0xc0,
-	0x05, 0x3c, 0x5f, 0x7f, 0x14, 0x62, 0x6e, 0x6c, 0xae, 0x45, 0x4f, 0xc5, 0xc8, 0x7b, 0xb0, 0x7d,
-	0x28, 0x55, 0x9c, 0x32, 0xc5, 0xa3, 0x63, 0x0c, 0x1d, 0xe4, 0xe3, 0xcc, 0x7c, 0x59, 0x9d, 0xa3,
-	0x06, 0x3d, 0x37, 0x1b, 0xbe, 0x06, 0x5b, 0x07, 0xd8, 0x3f, 0x4b, 0x62, 0x35, 0xa9, 0x20, 0xc1,
-	0x21, 0x6c, 0xe0, 0x07, 0x48, 0x9b, 0x67, 0x2c, 0x55, 0x3c, 0xc0, 0xa6, 0xcf, 0xe5, 0xd7, 0x5a,
-	0x9c, 0x97, 0xb0, 0x1f, 0x3e, 0x7a, 0xea, 0x35, 0x1e, 0x3f, 0xf5, 0x1a, 0xcf, 0x9f, 0x7a, 0xd6,
-	0xf7, 0x33, 0xcf, 0xfa, 0x75, 0xe6, 0x59, 0x0f, 0x67, 0x9e, 0xf5, 0x68, 0xe6, 0x59, 0x7f, 0xce,
-	0x3c, 0xeb, 0xef, 0x99, 0xd7, 0x78, 0x3e, 0xf3, 0xac, 0x9f, 0x9e, 0x79, 0x8d, 0x47, 0xcf, 0xbc,
-	0xc6, 0xe3, 0x67, 0x5e, 0xe3, 0x9b, 0x0d, 0xbc, 0xf6, 0x34, 0x8e, 0xa2, 0x84, 0x7f, 0xc7, 0x04,
-	0xef, 0xb7, 0xf1, 0x25, 0x5d, 0xfb, 0x27, 0x00, 0x00, 0xff, 0xff, 0x04, 0x35, 0xa1, 0x0d, 0x17,
-	0x09, 0x00, 0x00,
+	// 1142 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xcd, 0x6e, 0x1c, 0x45,
+	0x10, 0xde, 0xd9, 0x7f, 0x97, 0x83, 0x6d, 0xda, 0x06, 0xc6, 0x81, 0xcc, 0xac, 0x56, 0x39, 0x18,
+	0x94, 0xac, 0x83, 0x03, 0x17, 0x24, 0x10, 0x19, 0xc7, 0xc8, 0x41, 0xfc, 0x84, 0xb6, 0x05, 0x12,
+	0x97, 0xa8, 0xd7, 0xd3, 0xd9, 0x1d, 0x32, 0x7f, 0xe9, 0xee, 0x49, 0xb2, 0x37, 0xc4, 0x03, 0x20,
+	0x8e, 0x3c, 0x02, 0x4f, 0xc0, 0x33, 0xe4, 0x18, 0x6e, 0x21, 0x87, 0x81, 0x6c, 0x84, 0x84, 0xf6,
+	0x94, 0x47, 0x40, 0x5d, 0x3d, 0xb3, 0x3b, 0x8e, 0x17, 0x11, 0x2e, 0x76, 0x75, 0xd5, 0x57, 0xd5,
+	0x5f, 0x55, 0xd7, 0x7e, 0x03, 0xab, 0x51, 0xe2, 0xf3, 0x70, 0x90, 0x8a, 0x44, 0x25, 0x04, 0xee,
+	0x66, 0x5c, 0x4c, 0x04, 0x8b, 0x47, 0xfc, 0xfc, 0xe5, 0x51, 0xa0, 0xc6, 0xd9, 0x70, 0x70, 0x92,
+	0x44, 0xbb, 0xa3, 0x64, 0x94, 0xec, 0x22, 0x64, 0x98, 0xdd, 0xc6, 0x13, 0x1e, 0xd0, 0x32, 0xa9,
+	0xe7, 0x9d, 0x51, 0x92, 0x8c, 0x42, 0xbe, 0x40, 0xf9, 0x99, 0x60, 0x2a, 0x48, 0xe2, 0x22, 0x7e,
+	0xa5, 0x5a, 0x4e, 0xb0, 0xdb, 0x2c, 0x66, 0xbb, 0x51, 0x10, 0x05, 0x62, 0x37, 0xbd, 0x33, 0x32,
+	0x56, 0x3a, 0x34, 0xff, 0x8b, 0x8c, 0xed, 0x17, 0x2b, 0xb2, 0x78, 0x62, 0x42, 0xfd, 0x5f, 0xeb,
+	0xf0, 0xe6, 0x4d, 0x91, 0x44, 0x5c, 0x8d, 0x79, 0x26, 0xa9, 0xe6, 0xfb, 0x95, 0x66, 0x4e, 0xf9,
+	0xdd, 0x8c, 0x4b, 0x45, 0x08, 0x34, 0x53, 0xa6, 0xc6, 0xb6, 0xd5, 0xb3, 0x76, 0x56, 0x28, 0xda,
+	0x64, 0x0b, 0x5a, 0x52, 0x31, 0xa1, 0xec, 0x7a, 0xcf, 0xda, 0x69, 0x50, 0x73, 0x20, 0x1b, 0xd0,
+	0xe0, 0xb1, 0x6f, 0x37, 0xd0, 0xa7, 0x4d, 0x9d, 0x2b, 0x15, 0x4f, 0xed, 0x26, 0xba, 0xd0, 0x26,
+	0x1f, 0x42, 0x47, 0x05, 0x11, 0x4f, 0x32, 0x65, 0xb7, 0x7a, 0xd6, 0xce, 0xea, 0xde, 0xf6, 0xc0,
+	0x90, 0x1b, 0x94, 0xe4, 0x06, 0xd7, 0x8b, 0x76, 0xbd, 0xee, 0xc3, 0xdc, 0xad, 0xfd, 0xfc, 0x87,
+	0x6b, 0xd1, 0x32, 0x47, 0x5f, 0x8d, 0x83, 0xb5, 0xdb, 0xc8, 0xc7, 0x1c, 0xc8, 0x55, 0xe8, 0x24,
+	0xa9, 0x4e, 0x91, 0x76, 0x07, 0x8b, 0x6e, 0x0e, 0x16, 0xe3, 0x1f, 0x7c, 0x69, 0x42, 0x5e, 0x53,
+	0x97, 0xa3, 0x25, 0x92, 0xac, 0x41, 0x3d, 0xf0, 0xed, 0x2e, 0x72, 0xab, 0x07, 0x3e, 0xb9, 0x0c,
+	0xad, 0x71, 0x10, 0x2b, 0x69, 0xaf, 0x60, 0x89, 0x57, 0xab, 0x25, 0x0e, 0x75, 0x00, 0x0b, 0x58,
+	0xd4, 0xa0, 0xfa, 0xbf, 0x59, 0x70, 0x61, 0x31, 0xb8, 0x1b, 0xb1, 0x54, 0x2c, 0x56, 0xff, 0x39,
+	0x3a, 0x02, 0x4d, 0xdd, 0x4a, 0x31, 0x39, 0xb4, 0x17, 0x3d, 0x35, 0xfe, 0xa5, 0xa7, 0xe6, 0xff,
+	0xec, 0xa9, 0x75, 0xb6, 0xa7, 0xf6, 0x4b, 0xf5, 0x74, 0x0c, 0x76, 0x65, 0x17, 0xb8, 0x4c, 0x93,
+	0x58, 0xf2, 0x43, 0xce, 0x7c, 0x2e, 0xc8, 0x36, 0x34, 0xbf, 0x60, 0x11, 0x37, 0xdd, 0x78, 0xad,
+	0x59, 0xee, 0x5a, 0x97, 0x29, 0xba, 0xc8, 0x05, 0x68, 0x7f, 0xcd, 0xc2, 0x8c, 0x4b, 0xbb, 0xde,
+	0x6b, 0x2c, 0x82, 0x85, 0xb3, 0xff, 0x7b, 0x1d, 0xc8, 0xd9, 0xb2, 0xa4, 0x0f, 0xed, 0x23, 0xc5,
+	0x54, 0x26, 0x8b, 0x92, 0x30, 0xcb, 0xdd, 0xb6, 0x44, 0x0f, 0x2d, 0x22, 0xc4, 0x83, 0xe6, 0x75,
+	0xa6, 0x18, 0x8e, 0x6b, 0x75, 0xef, 0x7c, 0x95, 0xfe, 0xa2, 0xa2, 0x46, 0x78, 0x64, 0x96, 0xbb,
+	0x6b, 0x3e, 0x53, 0xec, 0x52, 0x12, 0x05, 0x8a, 0x47, 0xa9, 0x9a, 0x50, 0xcc, 0x25, 0xef, 0xc3,
+	0xca, 0x81, 0x10, 0x89, 0x38, 0x9e, 0xa4, 0xdc, 0x8c, 0xd8, 0x7b, 0x63, 0x96, 0xbb, 0x9b, 0xbc,
+	0x74, 0x56, 0x32, 0x16, 0x48, 0xf2, 0x36, 0xb4, 0xf0, 0x80, 0xd3, 0x5f, 0xf1, 0x36, 0x67, 0xb9,
+	0xbb, 0x8e, 0x29, 0x15, 0xb8, 0x41, 0x90, 0x03, 0xe8, 0x98, 0x21, 0x49, 0xbb, 0xd5, 0x6b, 0xec,
+	0xac, 0xee, 0x5d, 0x5c, 0x4e, 0xf4, 0xf4, 0x44, 0xcb, 0x31, 0x95, 0xb9, 0x64, 0x0f, 0xba, 0xdf,
+	0x30, 0x11, 0x07, 0xf1, 0x48, 0xbf, 0x97, 0x1e, 0xe4, 0xeb, 0xb3, 0xdc, 0x25, 0xf7, 0x0b, 0x5f,
+	0xe5, 0xde, 0x39, 0xae, 0xff, 0x83, 0x05, 0x6b, 0xa7, 0x27, 0x41, 0x06, 0x00, 0x94, 0xcb, 0x2c,
+	0x54, 0xd8, 0xb0, 0x99, 0xed, 0xda, 0x2c, 0x77, 0x41, 0xcc, 0xbd, 0xb4, 0x82, 0x20, 0x1f, 0x43,
+	0xdb, 0x9c, 0xf0, 0xf5, 0x56, 0xf7, 0xec, 0x2a, 0xf9, 0x23, 0x16, 0xa5, 0x21, 0x3f, 0x52, 0x82,
+	0xb3, 0xc8, 0x5b, 0xd3, 0xcb, 0xa6, 0x5f, 0xc9, 0x54, 0xa2, 0x45, 0x5e, 0xff, 0xc7, 0x3a, 0x9c,
+	0xab, 0x02, 0x49, 0x0a, 0xed, 0x90, 0x0d, 0x79, 0xa8, 0x9f, 0xb6, 0x81, 0xab, 0x7b, 0x92, 0x08,
+	0xc5, 0x1f, 0xa4, 0xc3, 0xc1, 0x67, 0xda, 0x7f, 0x93, 0x05, 0xc2, 0xdb, 0xd7, 0xd5, 0x9e, 0xe4,
+	0xee, 0xbb, 0x2f, 0x23, 0x67, 0x26, 0xef, 0x9a, 0xcf, 0x52, 0xc5, 0x85, 0xa6, 0x10, 0x71, 0x25,
+	0x82, 0x13, 0x5a, 0xdc, 0x43, 0x3e, 0x80, 0x8e, 0x44, 0x06, 0xb2, 0xe8, 0x62, 0x63, 0x71, 0xa5,
+	0xa1, 0xb6, 0x60, 0x7f, 0x0f, 0xd7, 0x92, 0x96, 0x09, 0xe4, 0x26, 0xc0, 0x38, 0x90, 0x2a, 0x19,
+	0x09, 0x16, 0x49, 0xbb, 0x81, 0xe9, 0x6f, 0x2d, 0xd2, 0x3f, 0x09, 0x13, 0xa6, 0x0e, 0x4b, 0x00,
+	0x52, 0x27, 0x45, 0xa9, 0x4a, 0x1e, 0xad, 0xd8, 0xfd, 0xef, 0x60, 0x6d, 0x9f, 0x9d, 0x8c, 0xb9,
+	0x3f, 0x5f, 0xf6, 0x6d, 0x68, 0xdc, 0xe1, 0x93, 0xe2, 0x35, 0x3a, 0xb3, 0xdc, 0xd5, 0x47, 0xaa,
+	0xff, 0x68, 0x45, 0xe4, 0x0f, 0x14, 0x8f, 0x55, 0x49, 0x9d, 0x54, 0x1f, 0xe0, 0x00, 0x43, 0xde,
+	0x7a, 0x71, 0x63, 0x09, 0xa5, 0xa5, 0xd1, 0x7f, 0x62, 0x41, 0xdb, 0x80, 0x88, 0x5b, 0xea, 0xb2,
+	0xbe, 0xa6, 0xe1, 0xad, 0xcc, 0x72, 0xd7, 0x38, 0x4a, 0x89, 0xde, 0x36, 0x12, 0x8d, 0xe2, 0x63,
+	0x58, 0xf0, 0xd8, 0x37, 0x5a, 0xdd, 0x83, 0xae, 0x12, 0xec, 0x84, 0xdf, 0x0a, 0xfc, 0x62, 0xe3,
+	0xcb, 0xf5, 0x44, 0xf7, 0x0d, 0x9f, 0x7c, 0x04, 0x5d, 0x51, 0xb4, 0x53, 0x48, 0xf7, 0xd6, 0x19,
+	0xe9, 0xbe, 0x16, 0x4f, 0xbc, 0x73, 0xb3, 0xdc, 0x9d, 0x23, 0xe9, 0xdc, 0x22, 0x97, 0x80, 0x60,
+	0x5f, 0xb7, 0xb4, 0xe8, 0x49, 0xc5, 0xa2, 0xf4, 0x56, 0x64, 0x84, 0xa9, 0x41, 0x37, 0x30, 0x72,
+	0x5c, 0x06, 0x3e, 0x97, 0x9f, 0x36, 0xbb, 0x8d, 0x8d, 0x66, 0xff, 0x2f, 0x0b, 0x3a, 0x85, 0xd4,
+	0x91, 0x8b, 0xf0, 0x0a, 0x0e, 0xf5, 0x7a, 0x20, 0xd9, 0x30, 0xe4, 0x3e, 0x76, 0xd9, 0xa5, 0xa7,
+	0x9d, 0xe4, 0x1d, 0xd8, 0x38, 0x1a, 0x33, 0xe1, 0x07, 0xf1, 0x68, 0x0e, 0xac, 0x23, 0xf0, 0x8c,
+	0x9f, 0xf4, 0x60, 0xf5, 0x38, 0x51, 0x2c, 0xc4, 0x80, 0x44, 0x6d, 0x68, 0xd1, 0xaa, 0x8b, 0xec,
+	0xc1, 0x56, 0xa1, 0xec, 0x47, 0x69, 0x18, 0xa8, 0x79, 0xc5, 0x26, 0x56, 0x5c, 0x1a, 0x7b, 0x31,
+	0xe7, 0x46, 0xac, 0xb8, 0xb8, 0xc7, 0xc2, 0x42, 0x95, 0x97, 0xc6, 0xfa, 0x0f, 0xa0, 0x85, 0x72,
+	0x4c, 0xfa, 0x70, 0x0e, 0xef, 0xd7, 0x1f, 0x92, 0x80, 0x1b, 0x69, 0x6c, 0xd1, 0x53, 0x3e, 0xf2,
+	0x1e, 0x6c, 0x1d, 0x48, 0x15, 0x44, 0x4c, 0x71, 0xff, 0x08, 0x5d, 0xfb, 0x49, 0x16, 0x9b, 0xaf,
+	0x71, 0xf3, 0xb0, 0x46, 0x97, 0x46, 0xbd, 0xd7, 0x60, 0x73, 0x1f, 0xfb, 0x67, 0x61, 0xa0, 0x26,
+	0x25, 0xa4, 0x7f, 0x1f, 0xd6, 0xf1, 0xa3, 0xa5, 0x05, 0x37, 0x90, 0x2a, 0x38, 0xc1, 0xa6, 0x97,
+	0xd6, 0xd7, 0x5c, 0x9a, 0xcb, 0xab, 0x93, 0x2b, 0xb0, 0x39, 0xf7, 0xef, 0x8f, 0xb3, 0xf8, 0x8e,
+	0x37, 0x51, 0xf8, 0x5b, 0xd4, 0x29, 0xcb, 0x42, 0xde, 0xc1, 0xa3, 0xa7, 0x4e, 0xed, 0xf1, 0x53,
+	0xa7, 0xf6, 0xfc, 0xa9, 0x63, 0x7d, 0x3f, 0x75, 0xac, 0x5f, 0xa6, 0x8e, 0xf5, 0x70, 0xea, 0x58,
+	0x8f, 0xa6, 0x8e, 0xf5, 0xe7, 0xd4, 0xb1, 0xfe, 0x9e, 0x3a, 0xb5, 0xe7, 0x53, 0xc7, 0xfa, 0xe9,
+	0x99, 0x53, 0x7b, 0xf4, 0xcc, 0xa9, 0x3d, 0x7e, 0xe6, 0xd4, 0xbe, 0x5d, 0xc7, 0x45, 0x89, 0x02,
+	0xdf, 0x0f, 0xf9, 0x7d, 0x26, 0xf8, 0xb0, 0x8d, 0xbb, 0x77, 0xf5, 0x9f, 0x01, 0x00, 0x5b, 0x97,
+	0x96, 0xae, 0x7d, 0x09, 0x00, 0x00,
 }
 
 func (this *PrometheusRangeQueryRequest) Equal(that interface{}) bool {
@@ -1041,6 +1059,14 @@ func (this *PrometheusResponse) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if len(this.Warnings) != len(that1.Warnings) {
+		return false
+	}
+	for i := range this.Warnings {
+		if this.Warnings[i] != that1.Warnings[i] {
+			return false
+		}
+	}
 	return true
 }
 func (this *PrometheusData) Equal(that interface{}) bool {
@@ -1303,6 +1329,9 @@ func (this *QueryStatistics) Equal(that interface{}) bool {
 	if this.EstimatedSeriesCount != that1.EstimatedSeriesCount {
 		return false
 	}
+	if this.EstimatedChunkBytes != that1.EstimatedChunkBytes {
+		return false
+	}
 	return true
 }
 func (this *PrometheusRangeQueryRequest) GoString() string {
@@ -1357,7 +1386,7 @@ func (this *PrometheusResponse) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 10)
 	s = append(s, "&querymiddleware.PrometheusResponse{")
 	s = append(s, "Status: "+fmt.Sprintf("%#v", this.Status)+",\n")
 	if this.Data != nil {
@@ -1368,6 +1397,7 @@ func (this *PrometheusResponse) GoString() string {
 	if this.Headers != nil {
 		s = append(s, "Headers: "+fmt.Sprintf("%#v", this.Headers)+",\n")
 	}
+	s = append(s, "Warnings: "+fmt.Sprintf("%#v", this.Warnings)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1484,9 +1514,10 @@ func (this *QueryStatistics) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 5)
+	s := make([]string, 0, 6)
 	s = append(s, "&querymiddleware.QueryStatistics{")
 	s = append(s, "EstimatedSeriesCount: "+fmt.Sprintf("%#v", this.EstimatedSeriesCount)+",\n")
+	s = append(s, "EstimatedChunkBytes: "+fmt.Sprintf("%#v", this.EstimatedChunkBytes)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1713,6 +1744,15 @@ func (m *PrometheusResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Warnings) > 0 {
+		for iNdEx := len(m.Warnings) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Warnings[iNdEx])
+			copy(dAtA[i:], m.Warnings[iNdEx])
+			i = encodeVarintModel(dAtA, i, uint64(len(m.Warnings[iNdEx])))
+			i--
+			dAtA[i] = 0x32
+		}
+	}
 	if len(m.Headers) > 0 {
 		for iNdEx := len(m.Headers) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -2104,6 +2144,11 @@ func (m *QueryStatistics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.EstimatedChunkBytes != 0 {
+		i = encodeVarintModel(dAtA, i, uint64(m.EstimatedChunkBytes))
+		i--
+		dAtA[i] = 0x10
+	}
 	if m.EstimatedSeriesCount != 0 {
 		i = encodeVarintModel(dAtA, i, uint64(m.EstimatedSeriesCount))
 		i--
@@ -2236,6 +2281,12 @@ func (m *PrometheusResponse) Size() (n int) {
 			n += 1 + l + sovModel(uint64(l))
 		}
 	}
+	if len(m.Warnings) > 0 {
+		for _, s := range m.Warnings {
+			l = len(s)
+			n += 1 + l + sovModel(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -2387,6 +2438,9 @@ func (m *QueryStatistics) Size() (n int) {
 	if m.EstimatedSeriesCount != 0 {
 		n += 1 + sovModel(uint64(m.EstimatedSeriesCount))
 	}
+	if m.EstimatedChunkBytes != 0 {
+		n += 1 + sovModel(uint64(m.EstimatedChunkBytes))
+	}
 	return n
 }
 
@@ -2455,6 +2509,7 @@ func (this *PrometheusResponse) String() string {
 		`ErrorType:` + fmt.Sprintf("%v", this.ErrorType) + `,`,
 		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
 		`Headers:` + repeatedStringForHeaders + `,`,
+		`Warnings:` + fmt.Sprintf("%v", this.Warnings) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2568,6 +2623,7 @@ func (this *QueryStatistics) String() string {
 	}
 	s := strings.Join([]string{`&QueryStatistics{`,
 		`EstimatedSeriesCount:` + fmt.Sprintf("%v", this.EstimatedSeriesCount) + `,`,
+		`EstimatedChunkBytes:` + fmt.Sprintf("%v", this.EstimatedChunkBytes) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -3411,6 +3467,38 @@ func (m *PrometheusResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Warnings", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowModel
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthModel
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthModel
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Warnings = append(m.Warnings, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipModel(dAtA[iNdEx:])
@@ -4297,6 +4385,25 @@ func (m *QueryStatistics) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EstimatedChunkBytes", wireType)
+			}
+			m.EstimatedChunkBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowModel
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.EstimatedChunkBytes |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipModel(dAtA[iNdEx:])
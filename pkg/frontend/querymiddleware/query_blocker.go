@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// queryBlockerMiddleware is a Middleware that rejects the queries matching the tenant's blocked queries.
+type queryBlockerMiddleware struct {
+	next   Handler
+	limits Limits
+	logger log.Logger
+	cache  *blockedQueriesCache
+
+	blockedQueries *prometheus.CounterVec
+}
+
+// newQueryBlockerMiddleware creates a new Middleware that rejects the queries blocked by the tenant's limits.
+func newQueryBlockerMiddleware(limits Limits, logger log.Logger, registerer prometheus.Registerer) Middleware {
+	blockedQueries := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_query_frontend_rejected_queries_total",
+		Help: "Number of queries rejected by the query-frontend.",
+	}, []string{"user", "reason"})
+	cache := newBlockedQueriesCache()
+
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &queryBlockerMiddleware{
+			next:           next,
+			limits:         limits,
+			logger:         logger,
+			cache:          cache,
+			blockedQueries: blockedQueries,
+		}
+	})
+}
+
+func (m *queryBlockerMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	spanLog := spanlogger.FromContext(ctx, m.logger)
+
+	// The query expression is parsed lazily, only if a blocked query has a selector.
+	var (
+		expr     parser.Expr
+		parseErr error
+		parsed   bool
+	)
+	getExpr := func() (parser.Expr, error) {
+		if !parsed {
+			expr, parseErr = parser.ParseExpr(req.GetQuery())
+			parsed = true
+		}
+		return expr, parseErr
+	}
+
+	for _, tenantID := range tenantIDs {
+		for _, blocked := range m.cache.get(tenantID, m.limits.BlockedQueries(tenantID)) {
+			if !isQueryBlocked(req.GetQuery(), blocked, getExpr) {
+				continue
+			}
+
+			level.Info(spanLog).Log("msg", "query blocked", "user", tenantID, "query", req.GetQuery(), "pattern", blocked.cfg.Pattern, "regex", blocked.cfg.Regex, "selector", blocked.cfg.Selector)
+			m.blockedQueries.WithLabelValues(tenantID, "blocked").Inc()
+
+			return nil, apierror.New(apierror.TypeBadData, globalerror.QueryBlocked.Message("the query has been blocked by the cluster administrator"))
+		}
+	}
+
+	return m.next.Do(ctx, req)
+}
+
+// compiledBlockedQuery is a blocked query with its regular expression or selector compiled.
+type compiledBlockedQuery struct {
+	cfg validation.BlockedQuery
+
+	// Set only if the blocked query is a valid regular expression.
+	re *regexp.Regexp
+
+	// Set only if the blocked query is a valid selector.
+	matchers []*labels.Matcher
+}
+
+func compileBlockedQueries(cfgs []validation.BlockedQuery) []compiledBlockedQuery {
+	compiled := make([]compiledBlockedQuery, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		c := compiledBlockedQuery{cfg: cfg}
+		if cfg.Pattern != "" && cfg.Regex {
+			c.re, _ = regexp.Compile(cfg.Pattern)
+		} else if cfg.Pattern == "" && cfg.Selector != "" {
+			c.matchers, _ = parser.ParseMetricSelector(cfg.Selector)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// blockedQueriesCache caches the blocked queries compiled from the tenants' limits, so that the blocked
+// queries of a tenant are compiled again only when its limits change.
+type blockedQueriesCache struct {
+	mtx     sync.RWMutex
+	tenants map[string][]compiledBlockedQuery
+}
+
+func newBlockedQueriesCache() *blockedQueriesCache {
+	return &blockedQueriesCache{tenants: map[string][]compiledBlockedQuery{}}
+}
+
+// get returns the blocked queries compiled from the input limits of the tenant, compiling them if the
+// limits changed since the previous call.
+func (c *blockedQueriesCache) get(userID string, cfgs []validation.BlockedQuery) []compiledBlockedQuery {
+	c.mtx.RLock()
+	cached, ok := c.tenants[userID]
+	c.mtx.RUnlock()
+
+	if ok && blockedQueriesEqual(cached, cfgs) {
+		return cached
+	}
+	if !ok && len(cfgs) == 0 {
+		return nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(cfgs) == 0 {
+		delete(c.tenants, userID)
+		return nil
+	}
+
+	compiled := compileBlockedQueries(cfgs)
+	c.tenants[userID] = compiled
+	return compiled
+}
+
+func blockedQueriesEqual(compiled []compiledBlockedQuery, cfgs []validation.BlockedQuery) bool {
+	if len(compiled) != len(cfgs) {
+		return false
+	}
+	for i := range cfgs {
+		if compiled[i].cfg != cfgs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isQueryBlocked returns whether the query matches the blocked query. Invalid blocked queries never match.
+func isQueryBlocked(query string, blocked compiledBlockedQuery, getExpr func() (parser.Expr, error)) bool {
+	if blocked.cfg.Pattern != "" {
+		if !blocked.cfg.Regex {
+			return strings.TrimSpace(query) == strings.TrimSpace(blocked.cfg.Pattern)
+		}
+
+		return blocked.re != nil && blocked.re.MatchString(query)
+	}
+
+	blockedMatchers := blocked.matchers
+	if len(blockedMatchers) == 0 {
+		return false
+	}
+
+	expr, err := getExpr()
+	if err != nil {
+		// Queries failing to parse are rejected downstream anyway.
+		return false
+	}
+
+	matched := false
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if vs, ok := node.(*parser.VectorSelector); ok && containsAllMatchers(vs.LabelMatchers, blockedMatchers) {
+			matched = true
+		}
+		return nil
+	})
+	return matched
+}
+
+// containsAllMatchers returns whether all the expected matchers are in the actual ones.
+func containsAllMatchers(actual, expected []*labels.Matcher) bool {
+	for _, e := range expected {
+		found := false
+		for _, a := range actual {
+			if a.Name == e.Name && a.Type == e.Type && a.Value == e.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
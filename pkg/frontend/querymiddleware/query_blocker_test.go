@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestQueryBlockerMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		limits          Limits
+		tenantIDs       []string
+		query           string
+		expectedBlocked bool
+	}{
+		"no blocked queries": {
+			limits:    mockLimits{},
+			tenantIDs: []string{"user-1"},
+			query:     "up",
+		},
+		"query equal to the pattern": {
+			limits:          mockLimits{blockedQueries: []validation.BlockedQuery{{Pattern: `sum(rate(http_requests_total[5m]))`}}},
+			tenantIDs:       []string{"user-1"},
+			query:           ` sum(rate(http_requests_total[5m])) `,
+			expectedBlocked: true,
+		},
+		"query not equal to the pattern": {
+			limits:    mockLimits{blockedQueries: []validation.BlockedQuery{{Pattern: `sum(rate(http_requests_total[5m]))`}}},
+			tenantIDs: []string{"user-1"},
+			query:     `sum(rate(http_requests_total[1m]))`,
+		},
+		"query matching the regex": {
+			limits:          mockLimits{blockedQueries: []validation.BlockedQuery{{Pattern: `rate\(http_requests_total\[\d+d\]\)`, Regex: true}}},
+			tenantIDs:       []string{"user-1"},
+			query:           `sum(rate(http_requests_total[30d]))`,
+			expectedBlocked: true,
+		},
+		"query not matching the regex": {
+			limits:    mockLimits{blockedQueries: []validation.BlockedQuery{{Pattern: `rate\(http_requests_total\[\d+d\]\)`, Regex: true}}},
+			tenantIDs: []string{"user-1"},
+			query:     `sum(rate(http_requests_total[30m]))`,
+		},
+		"query with a vector selector containing all the matchers of the selector": {
+			limits:          mockLimits{blockedQueries: []validation.BlockedQuery{{Selector: `{__name__="http_requests_total", job="dashboards"}`}}},
+			tenantIDs:       []string{"user-1"},
+			query:           `sum(rate(http_requests_total{job="dashboards", status="500"}[5m])) / sum(rate(http_requests_total[5m]))`,
+			expectedBlocked: true,
+		},
+		"query without a vector selector containing all the matchers of the selector": {
+			limits:    mockLimits{blockedQueries: []validation.BlockedQuery{{Selector: `{__name__="http_requests_total", job="dashboards"}`}}},
+			tenantIDs: []string{"user-1"},
+			query:     `sum(rate(http_requests_total{job=~"dashboards"}[5m])) / sum(rate(up{job="dashboards"}[5m]))`,
+		},
+		"invalid query and selector": {
+			limits:    mockLimits{blockedQueries: []validation.BlockedQuery{{Selector: `{job="dashboards"}`}}},
+			tenantIDs: []string{"user-1"},
+			query:     `sum(`,
+		},
+		"multiple tenants, the query is blocked if blocked by any tenant": {
+			limits: multiTenantMockLimits{byTenant: map[string]mockLimits{
+				"user-1": {},
+				"user-2": {blockedQueries: []validation.BlockedQuery{{Pattern: "up"}}},
+			}},
+			tenantIDs:       []string{"user-1", "user-2"},
+			query:           "up",
+			expectedBlocked: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tenant.WithDefaultResolver(tenant.NewMultiResolver())
+			ctx := user.InjectOrgID(context.Background(), tenant.JoinTenantIDs(tc.tenantIDs))
+
+			inner := &mockHandler{}
+			inner.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{Status: statusSuccess}, nil)
+
+			reg := prometheus.NewPedanticRegistry()
+			middleware := newQueryBlockerMiddleware(tc.limits, log.NewNopLogger(), reg).Wrap(inner)
+			res, err := middleware.Do(ctx, &PrometheusRangeQueryRequest{Query: tc.query})
+
+			if !tc.expectedBlocked {
+				require.NoError(t, err)
+				assert.Equal(t, &PrometheusResponse{Status: statusSuccess}, res)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "the query has been blocked by the cluster administrator (err-mimir-query-blocked)")
+			assert.True(t, apierror.IsAPIError(err))
+			inner.AssertNotCalled(t, "Do", mock.Anything, mock.Anything)
+
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+				# HELP cortex_query_frontend_rejected_queries_total Number of queries rejected by the query-frontend.
+				# TYPE cortex_query_frontend_rejected_queries_total counter
+				cortex_query_frontend_rejected_queries_total{reason="blocked",user="`+tc.tenantIDs[len(tc.tenantIDs)-1]+`"} 1
+			`)))
+		})
+	}
+}
+
+func TestBlockedQueriesCache(t *testing.T) {
+	cache := newBlockedQueriesCache()
+
+	assert.Empty(t, cache.get("user-1", nil))
+	assert.Empty(t, cache.tenants)
+
+	cfgs := []validation.BlockedQuery{{Pattern: `rate\(.*\[\d+d\]\)`, Regex: true}, {Pattern: `(`, Regex: true}, {Selector: `{job="dashboards"}`}}
+	first := cache.get("user-1", cfgs)
+	require.Len(t, first, 3)
+	assert.NotNil(t, first[0].re)
+	assert.Nil(t, first[1].re)
+	assert.Len(t, first[2].matchers, 1)
+
+	// The blocked queries are not compiled again if the limits didn't change.
+	second := cache.get("user-1", append([]validation.BlockedQuery(nil), cfgs...))
+	assert.Same(t, first[0].re, second[0].re)
+	assert.Same(t, first[2].matchers[0], second[2].matchers[0])
+
+	// The blocked queries are compiled again if the limits changed.
+	third := cache.get("user-1", cfgs[:1])
+	require.Len(t, third, 1)
+	assert.NotSame(t, first[0].re, third[0].re)
+
+	// The tenant is removed from the cache once it has no blocked queries.
+	assert.Empty(t, cache.get("user-1", nil))
+	assert.Empty(t, cache.tenants)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/promql"
@@ -40,6 +41,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/test"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -106,6 +108,43 @@ func approximatelyEquals(t *testing.T, a, b *PrometheusResponse) {
 				require.InEpsilonf(t, expected.Value, actual.Value, 1e-12, "sample value at position %d with timestamp %d for series %s", j, expected.TimestampMs, a.Labels)
 			}
 		}
+
+		require.Equal(t, len(a.Histograms), len(b.Histograms), "expected same number of histograms for series %s", a.Labels)
+
+		for j := 0; j < len(a.Histograms); j++ {
+			expected := a.Histograms[j]
+			actual := b.Histograms[j]
+			require.Equalf(t, expected.TimestampMs, actual.TimestampMs, "histogram timestamp at position %d for series %s", j, a.Labels)
+
+			msg := fmt.Sprintf("histogram at position %d with timestamp %d for series %s", j, expected.TimestampMs, a.Labels)
+			require.Equal(t, expected.Histogram.Schema, actual.Histogram.Schema, msg)
+			require.Equal(t, expected.Histogram.ZeroThreshold, actual.Histogram.ZeroThreshold, msg)
+			require.Equal(t, expected.Histogram.PositiveSpans, actual.Histogram.PositiveSpans, msg)
+			require.Equal(t, expected.Histogram.NegativeSpans, actual.Histogram.NegativeSpans, msg)
+			requireFloatApproximatelyEqual(t, expected.Histogram.Count, actual.Histogram.Count, msg)
+			requireFloatApproximatelyEqual(t, expected.Histogram.Sum, actual.Histogram.Sum, msg)
+			requireFloatApproximatelyEqual(t, expected.Histogram.ZeroCount, actual.Histogram.ZeroCount, msg)
+			require.Equal(t, len(expected.Histogram.PositiveBuckets), len(actual.Histogram.PositiveBuckets), msg)
+			for k := range expected.Histogram.PositiveBuckets {
+				requireFloatApproximatelyEqual(t, expected.Histogram.PositiveBuckets[k], actual.Histogram.PositiveBuckets[k], msg)
+			}
+			require.Equal(t, len(expected.Histogram.NegativeBuckets), len(actual.Histogram.NegativeBuckets), msg)
+			for k := range expected.Histogram.NegativeBuckets {
+				requireFloatApproximatelyEqual(t, expected.Histogram.NegativeBuckets[k], actual.Histogram.NegativeBuckets[k], msg)
+			}
+		}
+	}
+}
+
+// requireFloatApproximatelyEqual ensures two float values are approximately equal, with a relative error less than 1e-12.
+func requireFloatApproximatelyEqual(t *testing.T, expected, actual float64, msg string) {
+	switch {
+	case math.IsNaN(expected):
+		require.Truef(t, math.IsNaN(actual), "%s: expected NaN", msg)
+	case expected == 0:
+		require.Zero(t, actual, msg)
+	default:
+		require.InEpsilon(t, expected, actual, 1e-12, msg)
 	}
 }
 
@@ -116,6 +155,9 @@ func TestQueryShardingCorrectness(t *testing.T) {
 		numHistograms      = 1000
 		numStaleHistograms = 100
 		histogramBuckets   = []float64{1.0, 2.0, 4.0, 10.0, 100.0, math.Inf(1)}
+
+		numNativeHistograms      = 1000
+		numStaleNativeHistograms = 100
 	)
 
 	tests := map[string]struct {
@@ -395,27 +437,89 @@ func TestQueryShardingCorrectness(t *testing.T) {
 			query:                  `max by(unique) (max_over_time(metric_counter[5m])) > scalar(min(metric_counter))`,
 			expectedShardedQueries: 2,
 		},
-		//
-		// The following queries are not expected to be shardable.
-		//
 		"subquery min_over_time with aggr": {
 			query: `min_over_time(
 						sum by(group_1) (
 							rate(metric_counter[5m])
 						)[10m:]
 					)`,
-			expectedShardedQueries: 0,
+			expectedShardedQueries: 1,
+		},
+		"subquery with aggr and offset": {
+			query:                  `max_over_time(sum by(group_1) (rate(metric_counter[5m]))[10m:1m] offset 3m)`,
+			expectedShardedQueries: 1,
+		},
+		"subquery with aggr and @ modifier": {
+			query:                  `max_over_time(sum by(group_1) (rate(metric_counter[5m] @ start()))[10m:1m] @ end())`,
+			expectedShardedQueries: 1,
+		},
+		"subquery with binary operation between aggregations": {
+			query: `max_over_time((
+						sum by(group_1) (rate(metric_counter[1m]))
+						/
+						sum by(group_1) (rate(metric_counter[5m]))
+					)[10m:1m])`,
+			expectedShardedQueries: 2,
 		},
 		"outer subquery on top of sum": {
 			query:                  `sum(metric_counter) by (group_1)[5m:1m]`,
-			expectedShardedQueries: 0,
+			expectedShardedQueries: 1,
 			noRangeQuery:           true,
 		},
 		"outer subquery on top of avg": {
 			query:                  `avg(metric_counter) by (group_1)[5m:1m]`,
-			expectedShardedQueries: 0,
+			expectedShardedQueries: 2, // avg() is parallelized as sum()/count().
 			noRangeQuery:           true,
 		},
+		`subqueries with non parallelizable function in children`: {
+			query: `max_over_time(
+				absent_over_time(
+					deriv(
+						rate(metric_counter[1m])
+					[5m:1m])
+				[2m:1m])
+			[10m:1m] offset 25m)`,
+			expectedShardedQueries: 1,
+		},
+		"aggregation of group_left binary operation": {
+			query: `sum by(group_2) (
+						metric_counter
+						*
+						on(unique) group_left(group_2) max by(unique, group_2) (metric_counter)
+					)`,
+			expectedShardedQueries: 1,
+		},
+		"aggregation of group_right binary operation": {
+			query: `max by(group_1) (
+						max by(unique) (metric_counter)
+						-
+						on(unique) group_right() rate(metric_counter[1m])
+					)`,
+			expectedShardedQueries: 1,
+		},
+		"filtering binary operation with group_left": {
+			query:                  `rate(metric_counter[1m]) > on(group_1) group_left() avg by(group_1) (rate(metric_counter[1m]))`,
+			expectedShardedQueries: 1,
+		},
+		"and binary operation": {
+			query:                  `metric_counter and on(group_1) (sum by(group_1) (metric_counter) > 0)`,
+			expectedShardedQueries: 1,
+		},
+		"unless binary operation": {
+			query:                  `count(metric_counter unless on(group_1) metric_counter{group_1="0"})`,
+			expectedShardedQueries: 1,
+		},
+		//
+		// The following queries are not expected to be shardable.
+		//
+		"one-to-one binary operation": {
+			query:                  `sum(metric_counter * metric_counter)`,
+			expectedShardedQueries: 0,
+		},
+		"or binary operation": {
+			query:                  `sum(metric_counter or on(unique) metric_counter)`,
+			expectedShardedQueries: 0,
+		},
 		"stddev()": {
 			query:                  `stddev(metric_counter{const="fixed"})`,
 			expectedShardedQueries: 0,
@@ -452,16 +556,6 @@ func TestQueryShardingCorrectness(t *testing.T) {
 			query:                  `histogram_quantile(0.5, rate(metric_histogram_bucket{group_1="0"}[1m]))`,
 			expectedShardedQueries: 0,
 		},
-		`subqueries with non parallelizable function in children`: {
-			query: `max_over_time(
-				absent_over_time(
-					deriv(
-						rate(metric_counter[1m])
-					[5m:1m])
-				[2m:1m])
-			[10m:1m] offset 25m)`,
-			expectedShardedQueries: 0,
-		},
 		"string literal": {
 			query:                  `"test"`,
 			expectedShardedQueries: 0,
@@ -511,12 +605,54 @@ func TestQueryShardingCorrectness(t *testing.T) {
 			query:                  `scalar(sum(metric_counter)) < bool 1`,
 			expectedShardedQueries: 1,
 		},
-		`sum({__name__!=""})`: {
-			query:                  `sum({__name__!=""})`,
+		"sum() of native histograms": {
+			query:                  `sum(metric_native_histogram)`,
+			expectedShardedQueries: 1,
+		},
+		"sum(rate()) of native histograms no grouping": {
+			query:                  `sum(rate(metric_native_histogram[1m]))`,
+			expectedShardedQueries: 1,
+		},
+		"sum(rate()) of native histograms grouping 'by'": {
+			query:                  `sum by(group_1) (rate(metric_native_histogram[1m]))`,
+			expectedShardedQueries: 1,
+		},
+		"count() of native histograms": {
+			query:                  `count by(group_2) (metric_native_histogram)`,
+			expectedShardedQueries: 1,
+		},
+		"histogram_quantile() of sum(rate()) of native histograms": {
+			query:                  `histogram_quantile(0.5, sum(rate(metric_native_histogram[1m])))`,
+			expectedShardedQueries: 1,
+		},
+		"histogram_quantile() of sum(rate()) of native histograms grouping 'by'": {
+			query:                  `histogram_quantile(0.9, sum by(group_1) (rate(metric_native_histogram[1m])))`,
+			expectedShardedQueries: 1,
+		},
+		"histogram_sum() of sum(rate()) of native histograms": {
+			query:                  `histogram_sum(sum(rate(metric_native_histogram[1m])))`,
+			expectedShardedQueries: 1,
+		},
+		"histogram_count() of sum(rate()) of native histograms grouping 'without'": {
+			query:                  `histogram_count(sum without(unique) (rate(metric_native_histogram[1m])))`,
+			expectedShardedQueries: 1,
+		},
+		"histogram_fraction() of sum(rate()) of native histograms": {
+			query:                  `histogram_fraction(0, 4, sum(rate(metric_native_histogram[1m])))`,
 			expectedShardedQueries: 1,
 		},
-		`sum by (group_1) ({__name__!=""})`: {
-			query:                  `sum by (group_1) ({__name__!=""})`,
+		"sum(histogram_sum()) of native histograms": {
+			query:                  `sum(histogram_sum(rate(metric_native_histogram[1m])))`,
+			expectedShardedQueries: 1,
+		},
+		// The sum of float samples and native histograms has no result, so native histograms are excluded.
+		`sum({__name__!="", __name__!="metric_native_histogram"})`: {
+			query:                  `sum({__name__!="", __name__!="metric_native_histogram"})`,
+			expectedShardedQueries: 1,
+		},
+		// The sum of float samples and native histograms has no result, so native histograms are excluded.
+		`sum by (group_1) ({__name__!="", __name__!="metric_native_histogram"})`: {
+			query:                  `sum by (group_1) ({__name__!="", __name__!="metric_native_histogram"})`,
 			expectedShardedQueries: 1,
 		},
 		`sum by (group_1) (count_over_time({__name__!=""}[1m]))`: {
@@ -525,7 +661,7 @@ func TestQueryShardingCorrectness(t *testing.T) {
 		},
 	}
 
-	series := make([]*promql.StorageSeries, 0, numSeries+(numHistograms*len(histogramBuckets)))
+	series := make([]*promql.StorageSeries, 0, numSeries+(numHistograms*len(histogramBuckets))+numNativeHistograms)
 	seriesID := 0
 
 	// Add counter series.
@@ -575,6 +711,18 @@ func TestQueryShardingCorrectness(t *testing.T) {
 		seriesID++
 	}
 
+	// Add native histogram series.
+	for i := 0; i < numNativeHistograms; i++ {
+		gen := factor(float64(i) * 0.1)
+		if i >= numNativeHistograms-numStaleNativeHistograms {
+			// Wrap the generator to inject the staleness marker between minute 10 and 20.
+			gen = stale(start.Add(10*time.Minute), start.Add(20*time.Minute), gen)
+		}
+
+		series = append(series, newNativeHistogramSeries(newTestNativeHistogramLabels(seriesID), start.Add(-lookbackDelta), end, step, gen))
+		seriesID++
+	}
+
 	// Create a queryable on the fixtures.
 	queryable := storageSeriesQueryable(series)
 
@@ -681,8 +829,13 @@ func requireValidSamples(t *testing.T, result []SampleStream) {
 				return
 			}
 		}
+		for _, h := range stream.Histograms {
+			if !math.IsNaN(h.Histogram.Sum) {
+				return
+			}
+		}
 	}
-	t.Fatalf("Result should have some not-NaN samples")
+	t.Fatalf("Result should have some not-NaN samples or histograms")
 }
 
 type byLabels []SampleStream
@@ -868,6 +1021,10 @@ func TestQuerySharding_FunctionCorrectness(t *testing.T) {
 		{fn: "holt_winters", args: []string{"0.5", "0.7"}, rangeQuery: true},
 		{fn: "label_replace", args: []string{`"fuzz"`, `"$1"`, `"foo"`, `"b(.*)"`}},
 		{fn: "label_join", args: []string{`"fuzz"`, `","`, `"foo"`, `"bar"`}},
+		{fn: "histogram_count", tpl: `(<fn>(rate(bar2{}[1m])))`},
+		{fn: "histogram_sum", tpl: `(<fn>(rate(bar2{}[1m])))`},
+		{fn: "histogram_fraction", tpl: `(<fn>(0,4,rate(bar2{}[1m])))`},
+		{fn: "histogram_quantile", tpl: `(<fn>(0.5,rate(bar2{}[1m])))`},
 	}
 
 	for _, tc := range tests {
@@ -881,6 +1038,9 @@ func TestQuerySharding_FunctionCorrectness(t *testing.T) {
 					newSeries(labels.FromStrings("__name__", "bar1", "baz", "blip", "bar", "blap", "foo", "bozz"), start.Add(-lookbackDelta), end, step, factor(11)),
 					newSeries(labels.FromStrings("__name__", "bar1", "baz", "blip", "bar", "blop", "foo", "buzz"), start.Add(-lookbackDelta), end, step, factor(8)),
 					newSeries(labels.FromStrings("__name__", "bar1", "baz", "blip", "bar", "blap", "foo", "bazz"), start.Add(-lookbackDelta), end, step, arithmeticSequence(10)),
+					newNativeHistogramSeries(labels.FromStrings("__name__", "bar2", "baz", "blip", "bar", "blop", "foo", "barr"), start.Add(-lookbackDelta), end, step, factor(5)),
+					newNativeHistogramSeries(labels.FromStrings("__name__", "bar2", "baz", "blip", "bar", "blap", "foo", "buzz"), start.Add(-lookbackDelta), end, step, factor(12)),
+					newNativeHistogramSeries(labels.FromStrings("__name__", "bar2", "baz", "blip", "bar", "blop", "foo", "bazz"), start.Add(-lookbackDelta), end, step, arithmeticSequence(10)),
 				})
 
 				req := &PrometheusRangeQueryRequest{
@@ -934,10 +1094,6 @@ func TestQuerySharding_FunctionCorrectness(t *testing.T) {
 		"scalar": {},
 		"vector": {},
 		"pi":     {},
-		// Until support is added for querying histograms, these can be ignored:
-		"histogram_count":    {},
-		"histogram_sum":      {},
-		"histogram_fraction": {},
 	}
 
 	for expectedFn := range promql.FunctionCalls {
@@ -1184,6 +1340,61 @@ func TestQuerySharding_ShouldSupportMaxShardedQueries(t *testing.T) {
 	}
 }
 
+func TestQuerySharding_ShouldEvaluateTheNotShardedVectorMatchingLegInEachShard(t *testing.T) {
+	const totalShards = 4
+
+	tests := map[string]string{
+		"group_left binary operation":                 `sum by(group_1) (metric_a * on(unique) group_left(group_1) metric_b)`,
+		"group_right binary operation":                `sum by(group_1) (metric_b * on(unique) group_right(group_1) metric_a)`,
+		"filtering group_left binary operation":       `metric_a > on(unique) group_left() metric_b`,
+		"and binary operation":                        `metric_a and on(unique) metric_b`,
+		"group_left binary operation within subquery": `max_over_time(sum by(group_1) (metric_a * on(unique) group_left(group_1) metric_b)[10m:1m])`,
+	}
+
+	for testName, query := range tests {
+		t.Run(testName, func(t *testing.T) {
+			req := &PrometheusRangeQueryRequest{
+				Path:  "/query_range",
+				Start: util.TimeToMillis(start),
+				End:   util.TimeToMillis(end),
+				Step:  step.Milliseconds(),
+				Query: query,
+				Hints: &Hints{TotalQueries: 1},
+			}
+
+			limits := mockLimits{totalShards: totalShards, maxShardedQueries: 64}
+			shardingware := newQueryShardingMiddleware(log.NewNopLogger(), newEngine(), limits, 0, nil)
+
+			var (
+				downstreamMx      sync.Mutex
+				downstreamQueries []string
+			)
+			downstream := &mockHandler{}
+			downstream.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{
+				Status: statusSuccess, Data: &PrometheusData{
+					ResultType: string(parser.ValueTypeMatrix),
+				},
+			}, nil).Run(func(args mock.Arguments) {
+				downstreamMx.Lock()
+				downstreamQueries = append(downstreamQueries, args[1].(Request).GetQuery())
+				downstreamMx.Unlock()
+			})
+
+			res, err := shardingware.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "test"), req)
+			require.NoError(t, err)
+			assert.Equal(t, statusSuccess, res.(*PrometheusResponse).GetStatus())
+
+			// A downstream request is run for each shard, and each of them evaluates the whole not sharded leg.
+			require.Len(t, downstreamQueries, totalShards)
+			for _, q := range downstreamQueries {
+				assert.Regexp(t, `metric_a\{__query_shard__="[0-9]+_of_4"\}`, q)
+				assert.Contains(t, q, "metric_b")
+				assert.NotRegexp(t, `metric_b\{__query_shard__`, q)
+			}
+		})
+	}
+}
+
 func TestQuerySharding_ShouldReturnErrorOnDownstreamHandlerFailure(t *testing.T) {
 	req := &PrometheusRangeQueryRequest{
 		Path:  "/query_range",
@@ -1860,6 +2071,40 @@ func newSeries(metric labels.Labels, from, to time.Time, step time.Duration, gen
 	})
 }
 
+// newNativeHistogramSeries generates a native histogram series. The values returned by the generator are used
+// as the index of the generated test histogram, so the generator is expected to return increasing values.
+func newNativeHistogramSeries(metric labels.Labels, from, to time.Time, step time.Duration, gen generator) *promql.StorageSeries {
+	var (
+		points    []promql.Point
+		prevStale bool
+	)
+
+	for ts := from; ts.Unix() <= to.Unix(); ts = ts.Add(step) {
+		t := ts.Unix() * 1e3
+		v := gen(t)
+
+		// Like in newSeries(), we just keep the 1st one in a consecutive series of stale markers.
+		if value.IsStaleNaN(v) {
+			if !prevStale {
+				points = append(points, promql.Point{T: t, H: &histogram.FloatHistogram{Sum: v}})
+			}
+			prevStale = true
+			continue
+		}
+
+		prevStale = false
+		points = append(points, promql.Point{T: t, H: test.GenerateTestFloatHistogram(int(v))})
+	}
+
+	// Ensure series labels are sorted.
+	sort.Sort(metric)
+
+	return promql.NewStorageSeries(promql.Series{
+		Metric: metric,
+		Points: points,
+	})
+}
+
 // newTestCounterLabels generates series labels for a counter metric used in tests.
 func newTestCounterLabels(id int) labels.Labels {
 	return labels.FromStrings(
@@ -1883,6 +2128,17 @@ func newTestHistogramLabels(id int, bucketLe float64) labels.Labels {
 	)
 }
 
+// newTestNativeHistogramLabels generates series labels for a native histogram metric used in tests.
+func newTestNativeHistogramLabels(id int) labels.Labels {
+	return labels.FromStrings(
+		"__name__", "metric_native_histogram",
+		"const", "fixed", // A constant label.
+		"unique", strconv.Itoa(id), // A unique label.
+		"group_1", strconv.Itoa(id%10), // A first grouping label.
+		"group_2", strconv.Itoa(id%3), // A second grouping label.
+	)
+}
+
 // generator defined a function used to generate sample values in tests.
 type generator func(ts int64) float64
 
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/common/model"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// rangeQueryResultSamplesLimitMiddleware is a Middleware that enforces the max number of samples
+// returned in the result of a range query. The result exceeding the limit is either rejected or
+// downsampled by increasing the query step, depending on the tenant configuration.
+type rangeQueryResultSamplesLimitMiddleware struct {
+	next   Handler
+	limits Limits
+	logger log.Logger
+}
+
+// newRangeQueryResultSamplesLimitMiddleware creates a new Middleware that enforces the max number of samples
+// returned in the result of a range query.
+func newRangeQueryResultSamplesLimitMiddleware(limits Limits, logger log.Logger) Middleware {
+	return MiddlewareFunc(func(next Handler) Handler {
+		return &rangeQueryResultSamplesLimitMiddleware{
+			next:   next,
+			limits: limits,
+			logger: logger,
+		}
+	})
+}
+
+func (m *rangeQueryResultSamplesLimitMiddleware) Do(ctx context.Context, req Request) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	maxSamples := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, m.limits.MaxRangeQueryResultSamples)
+	if maxSamples <= 0 {
+		return m.next.Do(ctx, req)
+	}
+
+	res, err := m.next.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	promRes, ok := res.(*PrometheusResponse)
+	if !ok || promRes.Data == nil || promRes.Data.ResultType != model.ValMatrix.String() {
+		return res, nil
+	}
+
+	numSamples := countSampleStreamsSamples(promRes.Data.Result)
+	if numSamples <= maxSamples {
+		return res, nil
+	}
+
+	// The step is increased only if all tenants allow it.
+	for _, tenantID := range tenantIDs {
+		if !m.limits.RangeQueryResultStepIncreaseEnabled(tenantID) {
+			return nil, apierror.New(apierror.TypeExec, validation.NewMaxRangeQueryResultSamplesError(numSamples, maxSamples).Error())
+		}
+	}
+
+	step := req.GetStep()
+	if step <= 0 {
+		return nil, apierror.New(apierror.TypeExec, validation.NewMaxRangeQueryResultSamplesError(numSamples, maxSamples).Error())
+	}
+
+	// Look for the smallest multiple of the step keeping the result within the limit. The result of a query
+	// run with a step multiple of the requested one is a subset of the requested result, so there's no need
+	// to run the query again. The search starts from the average reduction required across all series.
+	factor := int64((numSamples + maxSamples - 1) / maxSamples)
+	for {
+		if factor*step > req.GetEnd()-req.GetStart() {
+			// Even the result made only of the samples at the query start exceeds the limit.
+			return nil, apierror.New(apierror.TypeExec, validation.NewMaxRangeQueryResultSamplesError(numSamples, maxSamples).Error())
+		}
+		if countDecimatedSampleStreamsSamples(promRes.Data.Result, req.GetStart(), factor*step) <= maxSamples {
+			break
+		}
+		factor++
+	}
+
+	newStep := factor * step
+
+	spanLog := spanlogger.FromContext(ctx, m.logger)
+	level.Debug(spanLog).Log("msg", "increasing the step of range query result", "samples", numSamples, "limit", maxSamples, "step", step, "new_step", newStep)
+
+	promRes.Data.Result = decimateSampleStreams(promRes.Data.Result, req.GetStart(), newStep)
+	promRes.Warnings = append(promRes.Warnings, fmt.Sprintf(
+		"the query result has been downsampled by increasing the step from %s to %s, because it contains %d samples exceeding the limit of %d samples (limit set by -query-frontend.max-range-query-result-samples)",
+		formatStep(step), formatStep(newStep), numSamples, maxSamples))
+
+	return promRes, nil
+}
+
+// countSampleStreamsSamples returns the number of float and histogram samples in the input streams.
+func countSampleStreamsSamples(streams []SampleStream) int {
+	count := 0
+	for _, stream := range streams {
+		count += len(stream.Samples) + len(stream.Histograms)
+	}
+	return count
+}
+
+// countDecimatedSampleStreamsSamples returns the number of samples decimateSampleStreams would keep.
+func countDecimatedSampleStreamsSamples(streams []SampleStream, start, step int64) int {
+	count := 0
+	for _, stream := range streams {
+		for _, s := range stream.Samples {
+			if isStepTimestamp(s.TimestampMs, start, step) {
+				count++
+			}
+		}
+		for _, h := range stream.Histograms {
+			if isStepTimestamp(h.TimestampMs, start, step) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// decimateSampleStreams keeps only the samples of the input streams evaluated at the timestamps of a range
+// query starting at start with the given step. Streams left without samples are removed. The input streams
+// are modified in place.
+func decimateSampleStreams(streams []SampleStream, start, step int64) []SampleStream {
+	out := streams[:0]
+
+	for _, stream := range streams {
+		samples := stream.Samples[:0]
+		for _, s := range stream.Samples {
+			if isStepTimestamp(s.TimestampMs, start, step) {
+				samples = append(samples, s)
+			}
+		}
+
+		histograms := stream.Histograms[:0]
+		for _, h := range stream.Histograms {
+			if isStepTimestamp(h.TimestampMs, start, step) {
+				histograms = append(histograms, h)
+			}
+		}
+
+		if len(samples) == 0 && len(histograms) == 0 {
+			continue
+		}
+
+		stream.Samples = nilIfEmptySamples(samples)
+		stream.Histograms = nilIfEmptyHistograms(histograms)
+		out = append(out, stream)
+	}
+
+	return out
+}
+
+func isStepTimestamp(ts, start, step int64) bool {
+	return (ts-start)%step == 0
+}
+
+func nilIfEmptySamples(samples []mimirpb.Sample) []mimirpb.Sample {
+	if len(samples) == 0 {
+		return nil
+	}
+	return samples
+}
+
+func nilIfEmptyHistograms(histograms []mimirpb.FloatHistogramPair) []mimirpb.FloatHistogramPair {
+	if len(histograms) == 0 {
+		return nil
+	}
+	return histograms
+}
+
+// formatStep formats the input step, in milliseconds, as a Prometheus duration.
+func formatStep(step int64) string {
+	return model.Duration(time.Duration(step) * time.Millisecond).String()
+}
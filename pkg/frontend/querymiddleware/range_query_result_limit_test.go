@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestRangeQueryResultSamplesLimitMiddleware(t *testing.T) {
+	// matrixResponse builds a response with a series for each input list of sample timestamps.
+	matrixResponse := func(series ...[]int64) *PrometheusResponse {
+		res := &PrometheusResponse{
+			Status: statusSuccess,
+			Data:   &PrometheusData{ResultType: "matrix"},
+		}
+		for i, timestamps := range series {
+			stream := SampleStream{Labels: []mimirpb.LabelAdapter{{Name: "series", Value: string(rune('a' + i))}}}
+			for _, ts := range timestamps {
+				stream.Samples = append(stream.Samples, mimirpb.Sample{TimestampMs: ts, Value: float64(ts)})
+			}
+			res.Data.Result = append(res.Data.Result, stream)
+		}
+		return res
+	}
+
+	req := &PrometheusRangeQueryRequest{Query: "up", Start: 0, End: 60_000, Step: 10_000}
+
+	tests := map[string]struct {
+		limits           Limits
+		tenantIDs        []string
+		response         *PrometheusResponse
+		expectedResponse *PrometheusResponse
+		expectedErr      string
+	}{
+		"limit disabled": {
+			limits:           mockLimits{},
+			tenantIDs:        []string{"user-1"},
+			response:         matrixResponse([]int64{0, 10_000, 20_000}),
+			expectedResponse: matrixResponse([]int64{0, 10_000, 20_000}),
+		},
+		"result within the limit": {
+			limits:           mockLimits{maxRangeQueryResultSamples: 3},
+			tenantIDs:        []string{"user-1"},
+			response:         matrixResponse([]int64{0, 10_000, 20_000}),
+			expectedResponse: matrixResponse([]int64{0, 10_000, 20_000}),
+		},
+		"result exceeding the limit with step increase disabled": {
+			limits:      mockLimits{maxRangeQueryResultSamples: 3},
+			tenantIDs:   []string{"user-1"},
+			response:    matrixResponse([]int64{0, 10_000, 20_000, 30_000}),
+			expectedErr: "the number of samples in the range query result exceeds the limit (samples: 4, limit: 3)",
+		},
+		"result exceeding the limit with step increase enabled": {
+			limits:    mockLimits{maxRangeQueryResultSamples: 4, rangeQueryResultStepIncreaseEnabled: true},
+			tenantIDs: []string{"user-1"},
+			response: matrixResponse(
+				[]int64{0, 10_000, 20_000, 30_000, 40_000, 50_000, 60_000},
+				[]int64{10_000, 30_000, 50_000},
+			),
+			expectedResponse: func() *PrometheusResponse {
+				res := matrixResponse([]int64{0, 30_000, 60_000}, []int64{30_000})
+				res.Warnings = []string{"the query result has been downsampled by increasing the step from 10s to 30s, because it contains 10 samples exceeding the limit of 4 samples (limit set by -query-frontend.max-range-query-result-samples)"}
+				return res
+			}(),
+		},
+		"step increased until the result is within the limit": {
+			limits:    mockLimits{maxRangeQueryResultSamples: 3, rangeQueryResultStepIncreaseEnabled: true},
+			tenantIDs: []string{"user-1"},
+			response: matrixResponse(
+				[]int64{0, 10_000, 20_000, 30_000, 40_000, 50_000, 60_000},
+				[]int64{0},
+			),
+			expectedResponse: func() *PrometheusResponse {
+				res := matrixResponse([]int64{0, 40_000}, []int64{0})
+				res.Warnings = []string{"the query result has been downsampled by increasing the step from 10s to 40s, because it contains 8 samples exceeding the limit of 3 samples (limit set by -query-frontend.max-range-query-result-samples)"}
+				return res
+			}(),
+		},
+		"series left without samples are removed from the downsampled result": {
+			limits:    mockLimits{maxRangeQueryResultSamples: 2, rangeQueryResultStepIncreaseEnabled: true},
+			tenantIDs: []string{"user-1"},
+			response: matrixResponse(
+				[]int64{0, 10_000, 20_000, 30_000},
+				[]int64{10_000},
+			),
+			expectedResponse: func() *PrometheusResponse {
+				res := matrixResponse([]int64{0, 30_000})
+				res.Warnings = []string{"the query result has been downsampled by increasing the step from 10s to 30s, because it contains 5 samples exceeding the limit of 2 samples (limit set by -query-frontend.max-range-query-result-samples)"}
+				return res
+			}(),
+		},
+		"result exceeding the limit even with a single sample per series": {
+			limits:      mockLimits{maxRangeQueryResultSamples: 1, rangeQueryResultStepIncreaseEnabled: true},
+			tenantIDs:   []string{"user-1"},
+			response:    matrixResponse([]int64{0, 10_000}, []int64{0, 10_000}),
+			expectedErr: "the number of samples in the range query result exceeds the limit (samples: 4, limit: 1)",
+		},
+		"multiple tenants, the smallest limit is enforced and the step increase is disabled if any tenant disables it": {
+			limits: multiTenantMockLimits{byTenant: map[string]mockLimits{
+				"user-1": {maxRangeQueryResultSamples: 10, rangeQueryResultStepIncreaseEnabled: true},
+				"user-2": {maxRangeQueryResultSamples: 2},
+			}},
+			tenantIDs:   []string{"user-1", "user-2"},
+			response:    matrixResponse([]int64{0, 10_000, 20_000}),
+			expectedErr: "the number of samples in the range query result exceeds the limit (samples: 3, limit: 2)",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tenant.WithDefaultResolver(tenant.NewMultiResolver())
+			ctx := user.InjectOrgID(context.Background(), tenant.JoinTenantIDs(tc.tenantIDs))
+
+			inner := &mockHandler{}
+			inner.On("Do", mock.Anything, mock.Anything).Return(tc.response, nil)
+
+			middleware := newRangeQueryResultSamplesLimitMiddleware(tc.limits, log.NewNopLogger()).Wrap(inner)
+			res, err := middleware.Do(ctx, req)
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				assert.True(t, apierror.IsAPIError(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResponse, res)
+		})
+	}
+}
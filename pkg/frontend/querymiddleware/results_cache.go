@@ -31,6 +31,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/cachemigration"
 	"github.com/grafana/mimir/pkg/util/math"
 )
 
@@ -53,8 +54,22 @@ var (
 
 // ResultsCacheConfig is the config for the results cache.
 type ResultsCacheConfig struct {
-	cache.BackendConfig `yaml:",inline"`
-	Compression         cache.CompressionConfig `yaml:",inline"`
+	cache.BackendConfig   `yaml:",inline"`
+	Compression           string                `yaml:"compression"`
+	IntegrityCheckEnabled bool                  `yaml:"integrity_check_enabled" category:"experimental"`
+	MigrationSource       cachemigration.Config `yaml:"migration_source"`
+}
+
+// maxItemSize returns the max size of the items stored in the configured cache backend, or 0 if unlimited.
+func (cfg *ResultsCacheConfig) maxItemSize() int {
+	switch cfg.Backend {
+	case cache.BackendMemcached:
+		return cfg.Memcached.MaxItemSize
+	case cache.BackendRedis:
+		return cfg.Redis.MaxItemSize
+	default:
+		return 0
+	}
 }
 
 // RegisterFlags registers flags.
@@ -62,7 +77,9 @@ func (cfg *ResultsCacheConfig) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.Backend, "query-frontend.results-cache.backend", "", fmt.Sprintf("Backend for query-frontend results cache, if not empty. Supported values: %s.", strings.Join(supportedResultsCacheBackends, ", ")))
 	cfg.Memcached.RegisterFlagsWithPrefix("query-frontend.results-cache.memcached.", f)
 	cfg.Redis.RegisterFlagsWithPrefix("query-frontend.results-cache.redis.", f)
-	cfg.Compression.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.")
+	f.StringVar(&cfg.Compression, "query-frontend.results-cache.compression", "", fmt.Sprintf("Enable cache compression, if not empty. Supported values are: %s.", strings.Join(supportedResultsCacheCompressions, ", ")))
+	f.BoolVar(&cfg.IntegrityCheckEnabled, "query-frontend.results-cache.integrity-check-enabled", false, "True to store a checksum along with each results cache entry, and verify it when the entry is fetched. Entries failing the verification are discarded, to not serve query results corrupted by the cache backend.")
+	cfg.MigrationSource.RegisterFlagsWithPrefix(f, "query-frontend.results-cache.migration-source.", "query-frontend.results-cache.backend")
 }
 
 func (cfg *ResultsCacheConfig) Validate() error {
@@ -82,7 +99,11 @@ func (cfg *ResultsCacheConfig) Validate() error {
 
 	}
 
-	if err := cfg.Compression.Validate(); err != nil {
+	if cfg.Compression != "" && !util.StringsContain(supportedResultsCacheCompressions, cfg.Compression) {
+		return errors.Wrap(errUnsupportedCompression, "query-frontend results cache")
+	}
+
+	if err := cfg.MigrationSource.Validate(); err != nil {
 		return errors.Wrap(err, "query-frontend results cache")
 	}
 
@@ -106,6 +127,11 @@ func newResultsCache(cfg ResultsCacheConfig, logger log.Logger, reg prometheus.R
 		return nil, errUnsupportedResultsCacheBackend(cfg.Backend)
 	}
 
+	client, err = cachemigration.WrapWithMigrationSource("frontend-cache", client, cfg.MigrationSource, logger, reg)
+	if err != nil {
+		return nil, err
+	}
+
 	return cache.NewVersioned(
 		cache.NewSpanlessTracingCache(client, logger, tenant.NewMultiResolver()),
 		resultsCacheVersion,
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	"github.com/klauspost/compress/zstd"
+
+	util_math "github.com/grafana/mimir/pkg/util/math"
+)
+
+const (
+	// compressionZstd is the value of the zstd compression.
+	compressionZstd = "zstd"
+
+	// defaultZstdMaxDecodedSize is the max size of the entries decoded by the zstd compression, used when
+	// the cache backend doesn't limit the size of the items.
+	defaultZstdMaxDecodedSize = 64 * 1024 * 1024
+)
+
+var (
+	supportedResultsCacheCompressions = []string{cache.CompressionSnappy, compressionZstd}
+	errUnsupportedCompression         = errors.New("unsupported compression")
+)
+
+// newResultsCacheCompression wraps the input cache with the configured compression, if any. maxItemSize is
+// the max size of the items stored in the cache, or 0 if unlimited.
+func newResultsCacheCompression(compression string, maxItemSize int, next cache.Cache, logger log.Logger) (cache.Cache, error) {
+	switch compression {
+	case cache.CompressionSnappy:
+		return cache.NewSnappy(next, logger), nil
+	case compressionZstd:
+		return newZstdCache(next, maxItemSize, logger)
+	default:
+		// No compression.
+		return next, nil
+	}
+}
+
+// zstdCache is a cache.Cache wrapper compressing the stored values with zstd. zstd trades some
+// CPU for a better compression ratio than snappy, which is worthwhile for large query results.
+//
+// The size of the decoded entries is limited, so that a corrupted cache entry can't make the decoder
+// allocate an unbounded amount of memory. The entries bigger than the limit are not stored.
+type zstdCache struct {
+	next           cache.Cache
+	logger         log.Logger
+	maxDecodedSize int
+	encoder        *zstd.Encoder
+	decoder        *zstd.Decoder
+}
+
+func newZstdCache(next cache.Cache, maxItemSize int, logger log.Logger) (cache.Cache, error) {
+	// The entries are stored uncompressed when not using compression, so the decoded entries
+	// are limited to the max size of the cache items.
+	maxDecodedSize := maxItemSize
+	if maxDecodedSize <= 0 {
+		maxDecodedSize = defaultZstdMaxDecodedSize
+	}
+	maxWindowSize := util_math.Max(maxDecodedSize, zstd.MinWindowSize)
+
+	// The encoder and decoder are safe for concurrent use when only EncodeAll() and DecodeAll() are called.
+	// The encoder window must be a power of 2, and not bigger than the max window accepted by the decoder.
+	encoder, err := zstd.NewWriter(nil, zstd.WithWindowSize(util_math.Min(1<<(bits.Len(uint(maxWindowSize))-1), zstd.MaxWindowSize)))
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(maxDecodedSize)), zstd.WithDecoderMaxWindow(uint64(maxWindowSize)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdCache{
+		next:           next,
+		logger:         logger,
+		maxDecodedSize: maxDecodedSize,
+		encoder:        encoder,
+		decoder:        decoder,
+	}, nil
+}
+
+// StoreAsync implements cache.Cache.
+func (c *zstdCache) StoreAsync(data map[string][]byte, ttl time.Duration) {
+	encoded := make(map[string][]byte, len(data))
+	for key, value := range data {
+		// The entries which couldn't be decoded are not stored.
+		if len(value) > c.maxDecodedSize {
+			continue
+		}
+		encoded[key] = c.encoder.EncodeAll(value, nil)
+	}
+
+	c.next.StoreAsync(encoded, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (c *zstdCache) Fetch(ctx context.Context, keys []string, opts ...cache.Option) map[string][]byte {
+	found := c.next.Fetch(ctx, keys, opts...)
+	decoded := make(map[string][]byte, len(found))
+
+	for key, encodedValue := range found {
+		decodedValue, err := c.decoder.DecodeAll(encodedValue, nil)
+		if err != nil {
+			level.Error(c.logger).Log("msg", "failed to decode cache entry", "err", err)
+			continue
+		}
+
+		decoded[key] = decodedValue
+	}
+
+	return decoded
+}
+
+// Name implements cache.Cache.
+func (c *zstdCache) Name() string {
+	return c.next.Name()
+}
+
+// Delete implements cache.Cache.
+func (c *zstdCache) Delete(ctx context.Context, key string) error {
+	return c.next.Delete(ctx, key)
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultsCacheCompression(t *testing.T) {
+	value := bytes.Repeat([]byte("compressible"), 100)
+
+	for _, compression := range []string{"", cache.CompressionSnappy, compressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ctx := context.Background()
+			backend := cache.NewMockCache()
+			c, err := newResultsCacheCompression(compression, 0, backend, log.NewNopLogger())
+			require.NoError(t, err)
+
+			c.StoreAsync(map[string][]byte{"key": value}, time.Minute)
+
+			stored := backend.GetItems()["key"].Data
+			if compression == "" {
+				assert.Equal(t, value, stored)
+			} else {
+				assert.Less(t, len(stored), len(value))
+			}
+
+			// Store an entry bypassing the compression, which can't be decoded.
+			backend.StoreAsync(map[string][]byte{"uncompressed": []byte("invalid")}, time.Minute)
+
+			found := c.Fetch(ctx, []string{"key", "uncompressed", "missing"})
+			assert.Equal(t, value, found["key"])
+			if compression != "" {
+				assert.NotContains(t, found, "uncompressed")
+			}
+			assert.NotContains(t, found, "missing")
+		})
+	}
+}
+
+func TestResultsCacheCompression_ZstdMaxItemSize(t *testing.T) {
+	const maxItemSize = 64 * 1024
+
+	ctx := context.Background()
+	backend := cache.NewMockCache()
+	c, err := newResultsCacheCompression(compressionZstd, maxItemSize, backend, log.NewNopLogger())
+	require.NoError(t, err)
+
+	small := bytes.Repeat([]byte("a"), maxItemSize)
+	large := bytes.Repeat([]byte("a"), maxItemSize+1)
+
+	// The entries bigger than the max item size are not stored.
+	c.StoreAsync(map[string][]byte{"small": small, "large": large}, time.Minute)
+	assert.Contains(t, backend.GetItems(), "small")
+	assert.NotContains(t, backend.GetItems(), "large")
+
+	// Store an entry decoding to more than the max item size, bypassing the size check.
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	backend.StoreAsync(map[string][]byte{"large": encoder.EncodeAll(large, nil)}, time.Minute)
+
+	found := c.Fetch(ctx, []string{"small", "large"})
+	assert.Equal(t, small, found["small"])
+	assert.NotContains(t, found, "large")
+}
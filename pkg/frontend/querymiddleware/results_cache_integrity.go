@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const integrityChecksumSize = crc32.Size
+
+var integrityCastagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// integrityCache is a cache.Cache wrapper prefixing each stored value with a checksum of both
+// the key and the value, which is verified when the value is fetched. The values failing the
+// verification are discarded, so that a misbehaving cache backend returning corrupted values,
+// or the value of another key, can't be used to serve query results.
+type integrityCache struct {
+	next   cache.Cache
+	logger log.Logger
+
+	checkedEntries prometheus.Counter
+	failedEntries  prometheus.Counter
+}
+
+func newIntegrityCache(next cache.Cache, logger log.Logger, reg prometheus.Registerer) cache.Cache {
+	return &integrityCache{
+		next:   next,
+		logger: logger,
+		checkedEntries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_query_result_cache_integrity_checks_total",
+			Help: "Total number of query result cache entries whose integrity has been verified.",
+		}),
+		failedEntries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_query_result_cache_integrity_check_failures_total",
+			Help: "Total number of query result cache entries discarded because their checksum doesn't match the fetched key and value. This includes the entries stored with the integrity check disabled.",
+		}),
+	}
+}
+
+// StoreAsync implements cache.Cache.
+func (c *integrityCache) StoreAsync(data map[string][]byte, ttl time.Duration) {
+	withChecksum := make(map[string][]byte, len(data))
+	for key, value := range data {
+		buf := make([]byte, integrityChecksumSize, integrityChecksumSize+len(value))
+		binary.BigEndian.PutUint32(buf, integrityChecksum(key, value))
+		withChecksum[key] = append(buf, value...)
+	}
+
+	c.next.StoreAsync(withChecksum, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (c *integrityCache) Fetch(ctx context.Context, keys []string, opts ...cache.Option) map[string][]byte {
+	found := c.next.Fetch(ctx, keys, opts...)
+	verified := make(map[string][]byte, len(found))
+
+	for key, buf := range found {
+		c.checkedEntries.Inc()
+
+		if len(buf) < integrityChecksumSize {
+			c.failedEntries.Inc()
+			level.Warn(c.logger).Log("msg", "discarded query result cache entry shorter than the checksum", "key", key)
+			continue
+		}
+
+		value := buf[integrityChecksumSize:]
+		if binary.BigEndian.Uint32(buf) != integrityChecksum(key, value) {
+			c.failedEntries.Inc()
+			level.Warn(c.logger).Log("msg", "discarded query result cache entry whose checksum doesn't match", "key", key)
+			continue
+		}
+
+		verified[key] = value
+	}
+
+	return verified
+}
+
+// Delete implements cache.Cache.
+func (c *integrityCache) Delete(ctx context.Context, key string) error {
+	return c.next.Delete(ctx, key)
+}
+
+// Name implements cache.Cache.
+func (c *integrityCache) Name() string {
+	return c.next.Name()
+}
+
+func integrityChecksum(key string, value []byte) uint32 {
+	checksum := crc32.Update(0, integrityCastagnoliTable, []byte(key))
+	return crc32.Update(checksum, integrityCastagnoliTable, value)
+}
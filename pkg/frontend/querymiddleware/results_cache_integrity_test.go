@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityCache(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewMockCache()
+	reg := prometheus.NewPedanticRegistry()
+	c := newIntegrityCache(backend, log.NewNopLogger(), reg)
+
+	c.StoreAsync(map[string][]byte{
+		"valid":     []byte("value-1"),
+		"corrupted": []byte("value-2"),
+		"swapped-1": []byte("value-3"),
+		"swapped-2": []byte("value-4"),
+	}, time.Minute)
+
+	// Corrupt the value of an entry, and swap the values of other two entries.
+	items := backend.GetItems()
+	corrupted := append([]byte(nil), items["corrupted"].Data...)
+	corrupted[len(corrupted)-1] = 'X'
+	backend.StoreAsync(map[string][]byte{
+		"corrupted": corrupted,
+		"swapped-1": items["swapped-2"].Data,
+		"swapped-2": items["swapped-1"].Data,
+	}, time.Minute)
+
+	// Store an entry bypassing the integrity cache, like entries stored with the integrity check disabled.
+	backend.StoreAsync(map[string][]byte{"no-checksum": []byte("v")}, time.Minute)
+
+	found := c.Fetch(ctx, []string{"valid", "corrupted", "swapped-1", "swapped-2", "no-checksum", "missing"})
+	assert.Equal(t, map[string][]byte{"valid": []byte("value-1")}, found)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_frontend_query_result_cache_integrity_checks_total Total number of query result cache entries whose integrity has been verified.
+		# TYPE cortex_frontend_query_result_cache_integrity_checks_total counter
+		cortex_frontend_query_result_cache_integrity_checks_total 5
+
+		# HELP cortex_frontend_query_result_cache_integrity_check_failures_total Total number of query result cache entries discarded because their checksum doesn't match the fetched key and value. This includes the entries stored with the integrity check disabled.
+		# TYPE cortex_frontend_query_result_cache_integrity_check_failures_total counter
+		cortex_frontend_query_result_cache_integrity_check_failures_total 4
+	`)))
+}
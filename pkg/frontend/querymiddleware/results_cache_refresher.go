@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// RefreshedQuery is a range query periodically re-executed by the query-frontend to keep its results cached.
+type RefreshedQuery struct {
+	Tenant string         `yaml:"tenant"`
+	Query  string         `yaml:"query"`
+	Range  model.Duration `yaml:"range"`
+	Step   model.Duration `yaml:"step"`
+}
+
+// Validate returns an error if the refreshed query is invalid.
+func (q RefreshedQuery) Validate() error {
+	if q.Tenant == "" {
+		return errors.New("the tenant of a refreshed query must be set")
+	}
+	if _, err := parser.ParseExpr(q.Query); err != nil {
+		return errors.Wrapf(err, "invalid refreshed query %q", q.Query)
+	}
+	if q.Range <= 0 || q.Step <= 0 {
+		return fmt.Errorf("the range and step of the refreshed query %q must be greater than 0", q.Query)
+	}
+	return nil
+}
+
+type resultsCacheRefreshCtxKey struct{}
+
+// contextWithResultsCacheRefresh returns a context marking the queries run with it as refreshed by the
+// results cache refresher. The cached extents of the refreshed queries are stored again even when the
+// queries are fully cached, so that their TTL is extended at every refresh.
+func contextWithResultsCacheRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resultsCacheRefreshCtxKey{}, true)
+}
+
+// isResultsCacheRefresh returns whether the input context is the context of a query refreshed by the
+// results cache refresher.
+func isResultsCacheRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(resultsCacheRefreshCtxKey{}).(bool)
+	return refresh
+}
+
+// resultsCacheRefresher periodically re-executes the configured range queries through the query-frontend
+// round tripper, so that the results missing from the cache, because they were never cached or because their
+// cache entries expired, are cached again before the queries are run by the users. The results already cached
+// are stored again, so that their TTL is extended and they don't expire as long as the queries are refreshed
+// more frequently than the results cache TTLs.
+type resultsCacheRefresher struct {
+	services.Service
+
+	queries      []RefreshedQuery
+	prefix       string
+	roundTripper http.RoundTripper
+	codec        Codec
+	logger       log.Logger
+
+	refreshedQueries       prometheus.Counter
+	refreshedQueriesFailed prometheus.Counter
+}
+
+// NewResultsCacheRefresher returns a service refreshing the results of the configured queries every refresh interval,
+// running them through the input round tripper. The Prometheus HTTP prefix is the path prefix of the refreshed queries.
+func NewResultsCacheRefresher(cfg Config, prometheusHTTPPrefix string, roundTripper http.RoundTripper, codec Codec, logger log.Logger, registerer prometheus.Registerer) services.Service {
+	r := &resultsCacheRefresher{
+		queries:      cfg.ResultsCacheRefreshQueries,
+		prefix:       prometheusHTTPPrefix,
+		roundTripper: roundTripper,
+		codec:        codec,
+		logger:       logger,
+		refreshedQueries: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_query_result_cache_refreshed_queries_total",
+			Help: "Total number of queries re-executed by the query-frontend to refresh their cached results.",
+		}),
+		refreshedQueriesFailed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_query_result_cache_refreshed_queries_failed_total",
+			Help: "Total number of queries re-executed by the query-frontend to refresh their cached results which failed.",
+		}),
+	}
+
+	r.Service = services.NewTimerService(cfg.ResultsCacheRefreshInterval, nil, r.iteration, nil).WithName("results cache refresher")
+	return r
+}
+
+func (r *resultsCacheRefresher) iteration(ctx context.Context) error {
+	now := time.Now()
+
+	for _, q := range r.queries {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		r.refreshedQueries.Inc()
+		if err := r.refresh(ctx, q, now); err != nil {
+			r.refreshedQueriesFailed.Inc()
+			level.Warn(r.logger).Log("msg", "failed to refresh the cached results of the query", "user", q.Tenant, "query", q.Query, "err", err)
+		}
+	}
+
+	// Failures are not returned, to keep refreshing the queries at the next interval.
+	return nil
+}
+
+// refresh runs the input query over the range ending at the input time, aligned to the query step
+// so that it's cached like the range queries run by dashboards aligning the time range to the step.
+func (r *resultsCacheRefresher) refresh(ctx context.Context, q RefreshedQuery, now time.Time) error {
+	step := time.Duration(q.Step).Milliseconds()
+	end := util.TimeToMillis(now) / step * step
+	start := end - time.Duration(q.Range).Milliseconds()
+
+	ctx = contextWithResultsCacheRefresh(user.InjectOrgID(ctx, q.Tenant))
+	req, err := r.codec.EncodeRequest(ctx, &PrometheusRangeQueryRequest{
+		Path:  path.Join(r.prefix, "/api/v1/query_range"),
+		Start: start,
+		End:   end,
+		Step:  step,
+		Query: q.Query,
+	})
+	if err != nil {
+		return err
+	}
+	if err := user.InjectOrgIDIntoHTTPRequest(ctx, req); err != nil {
+		return err
+	}
+
+	res, err := r.roundTripper.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// The response isn't used: running the query is enough to cache its results.
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response status code %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+)
+
+func TestResultsCacheRefresher(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		requests []*http.Request
+	)
+
+	roundTripper := RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mtx.Lock()
+		requests = append(requests, r)
+		mtx.Unlock()
+
+		status := http.StatusOK
+		if strings.Contains(r.URL.Query().Get("query"), "failing") {
+			status = http.StatusInternalServerError
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	})
+
+	cfg := Config{
+		ResultsCacheRefreshInterval: time.Minute,
+		ResultsCacheRefreshQueries: []RefreshedQuery{
+			{Tenant: "user-1", Query: "sum(rate(metric[1m]))", Range: model.Duration(time.Hour), Step: model.Duration(time.Minute)},
+			{Tenant: "user-2", Query: "failing", Range: model.Duration(6 * time.Hour), Step: model.Duration(5 * time.Minute)},
+		},
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	r := NewResultsCacheRefresher(cfg, "/prometheus", roundTripper, newTestPrometheusCodec(), log.NewNopLogger(), reg).(*resultsCacheRefresher)
+
+	now := time.Date(2023, 1, 1, 10, 7, 30, 0, time.UTC)
+	require.NoError(t, r.refresh(context.Background(), cfg.ResultsCacheRefreshQueries[0], now))
+	require.EqualError(t, r.refresh(context.Background(), cfg.ResultsCacheRefreshQueries[1], now), "unexpected response status code 500")
+
+	require.Len(t, requests, 2)
+
+	// The time range ends at the refresh time aligned to the step.
+	first := requests[0]
+	assert.Equal(t, "/prometheus/api/v1/query_range", first.URL.Path)
+	assert.Equal(t, "sum(rate(metric[1m]))", first.URL.Query().Get("query"))
+	assert.Equal(t, "1672564020", first.URL.Query().Get("start"))
+	assert.Equal(t, "1672567620", first.URL.Query().Get("end"))
+	assert.Equal(t, "60", first.URL.Query().Get("step"))
+
+	tenantID, err := user.ExtractOrgID(first.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", tenantID)
+	assert.Equal(t, "user-1", first.Header.Get(user.OrgIDHeaderName))
+	assert.True(t, isResultsCacheRefresh(first.Context()))
+
+	second := requests[1]
+	assert.Equal(t, "1672545900", second.URL.Query().Get("start"))
+	assert.Equal(t, "1672567500", second.URL.Query().Get("end"))
+	assert.Equal(t, "user-2", second.Header.Get(user.OrgIDHeaderName))
+
+	// Failures don't stop the refresh of the other queries.
+	require.NoError(t, r.iteration(context.Background()))
+	require.Len(t, requests, 4)
+	assert.Equal(t, float64(2), testutil.ToFloat64(r.refreshedQueries))
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.refreshedQueriesFailed))
+}
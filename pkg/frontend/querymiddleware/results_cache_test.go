@@ -19,6 +19,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/cachemigration"
 )
 
 func TestResultsCacheConfig_Validate(t *testing.T) {
@@ -64,6 +65,25 @@ func TestResultsCacheConfig_Validate(t *testing.T) {
 			},
 			expected: errUnsupportedBackend,
 		},
+		"should fail with invalid migration source memcached config": {
+			cfg: ResultsCacheConfig{
+				MigrationSource: cachemigration.Config{
+					Backend: cache.BackendMemcached,
+				},
+			},
+			expected: cache.ErrNoMemcachedAddresses,
+		},
+		"should pass with zstd compression": {
+			cfg: ResultsCacheConfig{
+				Compression: compressionZstd,
+			},
+		},
+		"should fail with unsupported compression": {
+			cfg: ResultsCacheConfig{
+				Compression: "unsupported",
+			},
+			expected: errUnsupportedCompression,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -48,6 +48,9 @@ type Config struct {
 	CacheSplitter CacheSplitter `yaml:"-"`
 
 	QueryResultResponseFormat string `yaml:"query_result_response_format"`
+
+	ResultsCacheRefreshInterval time.Duration    `yaml:"results_cache_refresh_interval" category:"experimental"`
+	ResultsCacheRefreshQueries  []RefreshedQuery `yaml:"results_cache_refresh_queries" doc:"nocli|description=List of range queries whose results are refreshed in the results cache every -query-frontend.results-cache-refresh-interval. Each entry sets the tenant, the query, and the range and step of the query, which ends at the refresh time aligned to the step." category:"experimental"`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet.
@@ -60,6 +63,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&cfg.CacheUnalignedRequests, "query-frontend.cache-unaligned-requests", false, "Cache requests that are not step-aligned.")
 	f.Uint64Var(&cfg.TargetSeriesPerShard, "query-frontend.query-sharding-target-series-per-shard", 0, "How many series a single sharded partial query should load at most. This is not a strict requirement guaranteed to be honoured by query sharding, but a hint given to the query sharding when the query execution is initially planned. 0 to disable cardinality-based hints.")
 	f.StringVar(&cfg.QueryResultResponseFormat, "query-frontend.query-result-response-format", formatProtobuf, fmt.Sprintf("Format to use when retrieving query results from queriers. Supported values: %s", strings.Join(allFormats, ", ")))
+	f.DurationVar(&cfg.ResultsCacheRefreshInterval, "query-frontend.results-cache-refresh-interval", 0, "How frequently the query-frontend re-executes the queries configured in results_cache_refresh_queries, to keep their results cached even when they're not run by the users. Should be lower than the results cache TTLs. 0 to disable.")
 	cfg.ResultsCacheConfig.RegisterFlags(f)
 }
 
@@ -77,6 +81,17 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.ResultsCacheRefreshEnabled() {
+		if !cfg.CacheResults {
+			return errors.New("-query-frontend.results-cache-refresh-interval may only be enabled in conjunction with -query-frontend.cache-results")
+		}
+		for _, q := range cfg.ResultsCacheRefreshQueries {
+			if err := q.Validate(); err != nil {
+				return errors.Wrap(err, "invalid query-frontend results cache refreshed query")
+			}
+		}
+	}
+
 	if !slices.Contains(allFormats, cfg.QueryResultResponseFormat) {
 		return fmt.Errorf("unknown query result response format '%s'. Supported values: %s", cfg.QueryResultResponseFormat, strings.Join(allFormats, ", "))
 	}
@@ -88,6 +103,11 @@ func (cfg *Config) cardinalityBasedShardingEnabled() bool {
 	return cfg.TargetSeriesPerShard > 0
 }
 
+// ResultsCacheRefreshEnabled returns whether the query-frontend periodically refreshes the results of the configured queries.
+func (cfg *Config) ResultsCacheRefreshEnabled() bool {
+	return cfg.ResultsCacheRefreshInterval > 0 && len(cfg.ResultsCacheRefreshQueries) > 0
+}
+
 // HandlerFunc is like http.HandlerFunc, but for Handler.
 type HandlerFunc func(context.Context, Request) (Response, error)
 
@@ -183,15 +203,22 @@ func newQueryTripperware(
 	// Metric used to keep track of each middleware execution duration.
 	metrics := newInstrumentMiddlewareMetrics(registerer)
 
+	queryBlockerMiddleware := newQueryBlockerMiddleware(limits, log, registerer)
+
 	queryRangeMiddleware := []Middleware{
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
 		newQueryStatsMiddleware(registerer),
+		queryBlockerMiddleware,
 		newLimitsMiddleware(limits, log),
 	}
 	if cfg.AlignQueriesWithStep {
 		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("step_align", metrics, log), newStepAlignMiddleware())
 	}
 
+	// Enforce the limit on the result samples after the step alignment, so that the result
+	// is downsampled relative to the aligned start of the query.
+	queryRangeMiddleware = append(queryRangeMiddleware, newRangeQueryResultSamplesLimitMiddleware(limits, log))
+
 	var c cache.Cache
 	if cfg.CacheResults || cfg.cardinalityBasedShardingEnabled() {
 		var err error
@@ -200,7 +227,13 @@ func newQueryTripperware(
 		if err != nil {
 			return nil, err
 		}
-		c = cache.NewCompression(cfg.ResultsCacheConfig.Compression, c, log)
+		c, err = newResultsCacheCompression(cfg.ResultsCacheConfig.Compression, cfg.ResultsCacheConfig.maxItemSize(), c, log)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.ResultsCacheConfig.IntegrityCheckEnabled {
+			c = newIntegrityCache(c, log, registerer)
+		}
 	}
 
 	// Inject the middleware to split requests by interval + results cache (if at least one of the two is enabled).
@@ -231,11 +264,15 @@ func newQueryTripperware(
 		))
 	}
 
-	queryInstantMiddleware := []Middleware{newLimitsMiddleware(limits, log)}
+	queryInstantMiddleware := []Middleware{
+		queryBlockerMiddleware,
+		newLimitsMiddleware(limits, log),
+		newInstantQueryResultSeriesLimitMiddleware(limits, log),
+	}
 
 	queryInstantMiddleware = append(
 		queryInstantMiddleware,
-		newSplitInstantQueryByIntervalMiddleware(limits, log, engine, registerer),
+		newSplitInstantQueryByIntervalMiddleware(limits, log, engine, cfg.CacheResults, c, registerer),
 	)
 
 	if cfg.ShardedQueries {
@@ -243,7 +280,7 @@ func newQueryTripperware(
 		// before query-sharding so that it can operate on the partial queries that are
 		// considered for sharding.
 		if cfg.cardinalityBasedShardingEnabled() {
-			cardinalityEstimationMiddleware := newCardinalityEstimationMiddleware(c, log, registerer)
+			cardinalityEstimationMiddleware := newCardinalityEstimationMiddleware(c, limits, log, registerer)
 			queryRangeMiddleware = append(
 				queryRangeMiddleware,
 				newInstrumentMiddleware("cardinality_estimation", metrics, log),
@@ -286,12 +323,23 @@ func newQueryTripperware(
 		instant := defaultInstantQueryParamsRoundTripper(
 			newLimitedParallelismRoundTripper(next, codec, limits, queryInstantMiddleware...),
 		)
+
+		cardinality := next
+		if cfg.ShardedQueries {
+			cardinality = newCardinalityQueryShardingRoundTripper(limits, cardinality, log, registerer)
+		}
+		if cfg.CacheResults {
+			cardinality = newCardinalityQueryCacheRoundTripper(c, limits, cardinality, log, registerer)
+		}
+
 		return RoundTripFunc(func(r *http.Request) (*http.Response, error) {
 			switch {
 			case isRangeQuery(r.URL.Path):
 				return queryrange.RoundTrip(r)
 			case isInstantQuery(r.URL.Path):
 				return instant.RoundTrip(r)
+			case isCardinalityQuery(r.URL.Path):
+				return cardinality.RoundTrip(r)
 			default:
 				return next.RoundTrip(r)
 			}
@@ -343,12 +343,34 @@ func TestConfig_Validate(t *testing.T) {
 			config:        Config{QueryResultResponseFormat: "something-else"},
 			expectedError: errors.New("unknown query result response format 'something-else'. Supported values: json, protobuf"),
 		},
+		"results cache refresh with results cache disabled": {
+			config: Config{
+				QueryResultResponseFormat:   formatJSON,
+				ResultsCacheRefreshInterval: time.Minute,
+				ResultsCacheRefreshQueries:  []RefreshedQuery{{Tenant: "user-1", Query: "up", Range: model.Duration(time.Hour), Step: model.Duration(time.Minute)}},
+			},
+			expectedError: errors.New("-query-frontend.results-cache-refresh-interval may only be enabled in conjunction with -query-frontend.cache-results"),
+		},
+		"results cache refresh with invalid refreshed query": {
+			config: Config{
+				QueryResultResponseFormat:   formatJSON,
+				CacheResults:                true,
+				SplitQueriesByInterval:      24 * time.Hour,
+				ResultsCacheRefreshInterval: time.Minute,
+				ResultsCacheRefreshQueries:  []RefreshedQuery{{Tenant: "user-1", Query: "up", Step: model.Duration(time.Minute)}},
+			},
+			expectedError: errors.New(`invalid query-frontend results cache refreshed query: the range and step of the refreshed query "up" must be greater than 0`),
+		},
 	}
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
 			err := test.config.Validate()
-			require.Equal(t, test.expectedError, err)
+			if test.expectedError == nil {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expectedError.Error())
+			}
 		})
 	}
 }
@@ -8,12 +8,15 @@ package querymiddleware
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"strings"
 	"sync"
 
 	"github.com/grafana/dskit/concurrency"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/promql/parser"
@@ -23,6 +26,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util"
+	util_math "github.com/grafana/mimir/pkg/util/math"
 )
 
 var (
@@ -76,7 +80,7 @@ type shardedQuerier struct {
 // The sorted bool is ignored because the series is always sorted.
 func (q *shardedQuerier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
 	var embeddedQuery string
-	var isEmbedded bool
+	var isEmbedded, isSubquery bool
 	for _, matcher := range matchers {
 		if matcher.Name == labels.MetricName && matcher.Value == astmapper.EmbeddedQueriesMetricName {
 			isEmbedded = true
@@ -85,6 +89,10 @@ func (q *shardedQuerier) Select(_ bool, hints *storage.SelectHints, matchers ...
 		if matcher.Name == astmapper.EmbeddedQueriesLabelName {
 			embeddedQuery = matcher.Value
 		}
+
+		if matcher.Name == astmapper.EmbeddedQueriesSubqueryLabelName {
+			isSubquery = true
+		}
 	}
 
 	if !isEmbedded {
@@ -100,17 +108,36 @@ func (q *shardedQuerier) Select(_ bool, hints *storage.SelectHints, matchers ...
 		return storage.ErrSeriesSet(err)
 	}
 
-	return q.handleEmbeddedQueries(queries, hints)
+	return q.handleEmbeddedQueries(queries, isSubquery, hints)
 }
 
 // handleEmbeddedQueries concurrently executes the provided queries through the downstream handler.
 // The returned storage.SeriesSet contains sorted series.
-func (q *shardedQuerier) handleEmbeddedQueries(queries []string, hints *storage.SelectHints) storage.SeriesSet {
-	streams := make([][]SampleStream, len(queries))
+func (q *shardedQuerier) handleEmbeddedQueries(queries []string, isSubquery bool, hints *storage.SelectHints) storage.SeriesSet {
+	// Each query may be split in multiple requests, run concurrently with the requests of the other queries.
+	type job struct {
+		queryIdx int
+		req      Request
+	}
+
+	var (
+		jobs       []job
+		jobStreams [][]SampleStream
+	)
+	for idx, query := range queries {
+		reqs, err := q.embeddedQueryRequests(query, isSubquery, hints)
+		if err != nil {
+			return storage.ErrSeriesSet(err)
+		}
+		for _, req := range reqs {
+			jobs = append(jobs, job{queryIdx: idx, req: req})
+		}
+	}
+	jobStreams = make([][]SampleStream, len(jobs))
 
-	// Concurrently run each query. It breaks and cancels each worker context on first error.
-	err := concurrency.ForEachJob(q.ctx, len(queries), len(queries), func(ctx context.Context, idx int) error {
-		resp, err := q.handler.Do(ctx, q.req.WithQuery(queries[idx]))
+	// Concurrently run each request. It breaks and cancels each worker context on first error.
+	err := concurrency.ForEachJob(q.ctx, len(jobs), len(jobs), func(ctx context.Context, idx int) error {
+		resp, err := q.handler.Do(ctx, jobs[idx].req)
 		if err != nil {
 			return err
 		}
@@ -119,7 +146,7 @@ func (q *shardedQuerier) handleEmbeddedQueries(queries []string, hints *storage.
 		if err != nil {
 			return err
 		}
-		streams[idx] = resStreams // No mutex is needed since each job writes its own index. This is like writing separate variables.
+		jobStreams[idx] = resStreams // No mutex is needed since each job writes its own index. This is like writing separate variables.
 
 		q.responseHeaders.mergeHeaders(resp.(*PrometheusResponse).Headers)
 		return nil
@@ -129,9 +156,91 @@ func (q *shardedQuerier) handleEmbeddedQueries(queries []string, hints *storage.
 		return storage.ErrSeriesSet(err)
 	}
 
+	// The requests of each query are sorted by time, so the results of a query are merged in order.
+	streams := make([][]SampleStream, len(queries))
+	for idx, j := range jobs {
+		if streams[j.queryIdx] == nil {
+			streams[j.queryIdx] = jobStreams[idx]
+			continue
+		}
+		streams[j.queryIdx] = appendSampleStreams(streams[j.queryIdx], jobStreams[idx])
+	}
+
 	return newSeriesSetFromEmbeddedQueriesResults(streams, hints)
 }
 
+// embeddedQueryRequests returns the requests to run the input embedded query. The embedded queries evaluated within
+// a subquery are run as range queries at the subquery resolution, over the time range selected by the PromQL engine.
+// That time range is split in multiple requests if it has more steps than allowed for a range query.
+func (q *shardedQuerier) embeddedQueryRequests(query string, isSubquery bool, hints *storage.SelectHints) ([]Request, error) {
+	if !isSubquery || hints == nil || hints.Step <= 0 {
+		return []Request{q.req.WithQuery(query)}, nil
+	}
+
+	// The start() and end() @ modifiers refer to the time range of the original query,
+	// which is different from the time range of the embedded query.
+	query, err := evaluateAtModifierFunction(query, q.req.GetStart(), q.req.GetEnd())
+	if err != nil {
+		return nil, err
+	}
+
+	// The subquery is evaluated at timestamps aligned to its step (same logic as the PromQL engine).
+	start := hints.Step * (hints.Start / hints.Step)
+	if start < hints.Start {
+		start += hints.Step
+	}
+
+	var reqs []Request
+	for ; start <= hints.End || len(reqs) == 0; start += (maxRangeQuerySteps + 1) * hints.Step {
+		end := util_math.Min(start+maxRangeQuerySteps*hints.Step, hints.End)
+
+		switch r := q.req.(type) {
+		case *PrometheusRangeQueryRequest:
+			req := *r
+			req.Query = query
+			req.Start = start
+			req.End = end
+			req.Step = hints.Step
+			reqs = append(reqs, &req)
+		case *PrometheusInstantQueryRequest:
+			reqs = append(reqs, &PrometheusRangeQueryRequest{
+				Path:    strings.TrimSuffix(r.Path, instantQueryPathSuffix) + queryRangePathSuffix,
+				Start:   start,
+				End:     end,
+				Step:    hints.Step,
+				Query:   query,
+				Options: r.Options,
+				Id:      r.Id,
+				Hints:   r.Hints,
+			})
+		default:
+			return nil, fmt.Errorf("unsupported request type %T", r)
+		}
+	}
+	return reqs, nil
+}
+
+// appendSampleStreams appends the samples of the src streams to the streams of dst with the same labels,
+// or appends the src streams to dst if there's no stream with the same labels. The samples of src are
+// expected to follow the samples of dst.
+func appendSampleStreams(dst, src []SampleStream) []SampleStream {
+	idxByLabels := make(map[string]int, len(dst))
+	for idx, stream := range dst {
+		idxByLabels[mimirpb.FromLabelAdaptersToLabels(stream.Labels).String()] = idx
+	}
+
+	for _, stream := range src {
+		idx, ok := idxByLabels[mimirpb.FromLabelAdaptersToLabels(stream.Labels).String()]
+		if !ok {
+			dst = append(dst, stream)
+			continue
+		}
+		dst[idx].Samples = append(dst[idx].Samples, stream.Samples...)
+		dst[idx].Histograms = append(dst[idx].Histograms, stream.Histograms...)
+	}
+	return dst
+}
+
 // LabelValues implements storage.LabelQuerier.
 func (q *shardedQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
 	return nil, nil, errNotImplemented
@@ -235,53 +344,47 @@ func newSeriesSetFromEmbeddedQueriesResults(results [][]SampleStream, hints *sto
 				})
 			}
 
+			// Same logic as samples above, but for native histograms. The stale marker of a native histogram
+			// is a histogram with the stale marker as sum.
+			histograms := make([]mimirpb.Histogram, 0, len(stream.Histograms)+10)
+
+			for idx, h := range stream.Histograms {
+				if step > 0 && idx > 0 && h.TimestampMs > stream.Histograms[idx-1].TimestampMs+step {
+					histograms = append(histograms, staleHistogram(stream.Histograms[idx-1].TimestampMs+step))
+				}
+
+				histograms = append(histograms, mimirpb.FromFloatHistogramToHistogramProto(h.TimestampMs, h.Histogram.ToPrometheusModel()))
+			}
+
 			// In case the embedded query processed series which all ended before the end of the query time range,
 			// we don't want the outer query to apply the lookback at the end of the embedded query results. To keep it
 			// simple, it's safe always to add an extra stale marker at the end of the query results.
 			//
 			// This could result in an extra sample (stale marker) after the end of the query time range, but that's
 			// not a problem when running the outer query because it will just be discarded.
-			if len(samples) > 0 && step > 0 {
+			//
+			// If the series contains both float samples and native histograms, the stale marker is added only after
+			// the last one, otherwise it could overlap with a sample of the other type.
+			if len(samples) > 0 && step > 0 && (len(histograms) == 0 || int64(samples[len(samples)-1].Timestamp) > histograms[len(histograms)-1].Timestamp) {
 				samples = append(samples, model.SamplePair{
 					Timestamp: samples[len(samples)-1].Timestamp + model.Time(step),
 					Value:     model.SampleValue(math.Float64frombits(value.StaleNaN)),
 				})
+			} else if len(histograms) > 0 && step > 0 {
+				histograms = append(histograms, staleHistogram(histograms[len(histograms)-1].Timestamp+step))
 			}
 
-			// same logic as samples above
-			histograms := make([]mimirpb.Histogram, 0) //, len(stream.Histograms)+10)
-			/* // for now we will disable query sharding for tenants who enable native histograms, as the changes to support query sharding with native histograms is non-trivial
-			for idx, histogram := range stream.Histograms {
-				if step > 0 && idx > 0 && histogram.Timestamp > stream.Histograms[idx-1].Timestamp+step {
-					histograms = append(histograms, model.SampleHistogramPair{
-						Timestamp: model.Time(stream.Histograms[idx-1].Timestamp + step),
-						Histogram: model.SampleHistogram{
-							Sum: model.FloatString(math.Float64frombits(value.StaleNaN)),
-						},
-					})
-				}
-
-				histograms = append(histograms, model.SampleHistogramPair{
-					Timestamp: model.Time(histogram.Timestamp),
-					Histogram: mimirpb.FromMimirSampleToPromCommonHistogram(*histogram.Histogram),
-				})
-			}
-
-			if len(histograms) > 0 && step > 0 {
-				histograms = append(histograms, model.SampleHistogramPair{
-					Timestamp: histograms[len(histograms)-1].Timestamp + model.Time(step),
-					Histogram: model.SampleHistogram{
-						Sum: model.FloatString(math.Float64frombits(value.StaleNaN)),
-					},
-				})
-			}*/
-
 			set = append(set, series.NewConcreteSeries(mimirpb.FromLabelAdaptersToLabels(stream.Labels), samples, histograms))
 		}
 	}
 	return series.NewConcreteSeriesSet(set)
 }
 
+// staleHistogram returns a native histogram stale marker at the given timestamp.
+func staleHistogram(ts int64) mimirpb.Histogram {
+	return mimirpb.FromFloatHistogramToHistogramProto(ts, &histogram.FloatHistogram{Sum: math.Float64frombits(value.StaleNaN)})
+}
+
 // responseToSamples is needed to map back from api response to the underlying series data
 func responseToSamples(resp Response) ([]SampleStream, error) {
 	promRes, ok := resp.(*PrometheusResponse)
@@ -23,6 +23,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware/astmapper"
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/test"
 )
 
 func TestShardedQuerier_Select(t *testing.T) {
@@ -249,6 +250,123 @@ func TestShardedQuerier_Select_ShouldConcurrentlyRunEmbeddedQueries(t *testing.T
 	require.Equal(t, len(embeddedQueries), actualSeries)
 }
 
+func TestShardedQuerier_EmbeddedQueryRequests(t *testing.T) {
+	const query = `sum(rate(metric{__query_shard__="0_of_3"}[1m] @ start()))`
+
+	tests := map[string]struct {
+		req        Request
+		isSubquery bool
+		hints      *storage.SelectHints
+		expected   []Request
+	}{
+		"should run the embedded query with the same range query request": {
+			req:      &PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 2000, End: 10000, Step: 1000},
+			hints:    &storage.SelectHints{Start: 1000, End: 10000, Step: 3000},
+			expected: []Request{&PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 2000, End: 10000, Step: 1000, Query: query}},
+		},
+		"should run the embedded query with the same instant query request": {
+			req:      &PrometheusInstantQueryRequest{Path: "/api/v1/query", Time: 10000},
+			hints:    &storage.SelectHints{Start: 1000, End: 10000, Step: 3000},
+			expected: []Request{&PrometheusInstantQueryRequest{Path: "/api/v1/query", Time: 10000, Query: query}},
+		},
+		"should run the embedded query within a subquery at the subquery resolution for range query request": {
+			req:        &PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 2000, End: 10000, Step: 1000, Options: Options{TotalShards: 3}},
+			isSubquery: true,
+			hints:      &storage.SelectHints{Start: 1000, End: 10000, Step: 3000},
+			expected: []Request{&PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 3000, End: 10000, Step: 3000, Options: Options{TotalShards: 3},
+				Query: `sum(rate(metric{__query_shard__="0_of_3"}[1m] @ 2.000))`}},
+		},
+		"should run the embedded query within a subquery as a range query for instant query request": {
+			req:        &PrometheusInstantQueryRequest{Path: "/api/v1/query", Time: 10000, Options: Options{TotalShards: 3}},
+			isSubquery: true,
+			hints:      &storage.SelectHints{Start: 1000, End: 10000, Step: 3000},
+			expected: []Request{&PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 3000, End: 10000, Step: 3000, Options: Options{TotalShards: 3},
+				Query: `sum(rate(metric{__query_shard__="0_of_3"}[1m] @ 10.000))`}},
+		},
+		"should split the embedded query within a subquery exceeding the max number of steps of a range query": {
+			req:        &PrometheusInstantQueryRequest{Path: "/api/v1/query", Time: 30000000, Options: Options{TotalShards: 3}},
+			isSubquery: true,
+			hints:      &storage.SelectHints{Start: 0, End: 30000000, Step: 1000},
+			expected: []Request{
+				&PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 0, End: 11000000, Step: 1000, Options: Options{TotalShards: 3},
+					Query: `sum(rate(metric{__query_shard__="0_of_3"}[1m] @ 30000.000))`},
+				&PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 11001000, End: 22001000, Step: 1000, Options: Options{TotalShards: 3},
+					Query: `sum(rate(metric{__query_shard__="0_of_3"}[1m] @ 30000.000))`},
+				&PrometheusRangeQueryRequest{Path: "/api/v1/query_range", Start: 22002000, End: 30000000, Step: 1000, Options: Options{TotalShards: 3},
+					Query: `sum(rate(metric{__query_shard__="0_of_3"}[1m] @ 30000.000))`},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			q := &shardedQuerier{ctx: context.Background(), req: testData.req, responseHeaders: newResponseHeadersTracker()}
+
+			actual, err := q.embeddedQueryRequests(query, testData.isSubquery, testData.hints)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, actual)
+
+			for _, req := range actual {
+				if r, ok := req.(*PrometheusRangeQueryRequest); ok {
+					assert.LessOrEqual(t, (r.End-r.Start)/r.Step, int64(maxRangeQuerySteps))
+				}
+			}
+		})
+	}
+}
+
+func TestShardedQuerier_SelectShouldMergeTheResultsOfTheSplitSubqueryRequests(t *testing.T) {
+	const step = 1000
+
+	querier := mkShardedQuerier(HandlerFunc(func(ctx context.Context, req Request) (Response, error) {
+		// Return a sample at each step of the request for the same series.
+		var samples []mimirpb.Sample
+		for ts := req.GetStart(); ts <= req.GetEnd(); ts += req.GetStep() {
+			samples = append(samples, mimirpb.Sample{TimestampMs: ts, Value: float64(ts)})
+		}
+
+		return &PrometheusResponse{
+			Data: &PrometheusData{
+				ResultType: string(parser.ValueTypeMatrix),
+				Result: []SampleStream{{
+					Labels:  []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+					Samples: samples,
+				}},
+			},
+		}, nil
+	}))
+	querier.req = &PrometheusInstantQueryRequest{Path: "/api/v1/query", Time: 2 * maxRangeQuerySteps * step}
+
+	encodedQueries, err := astmapper.JSONCodec.Encode([]string{`sum(metric{__query_shard__="1_of_2"})`})
+	require.NoError(t, err)
+
+	seriesSet := querier.Select(
+		false,
+		&storage.SelectHints{Start: 0, End: 2 * maxRangeQuerySteps * step, Step: step},
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", astmapper.EmbeddedQueriesMetricName),
+		labels.MustNewMatcher(labels.MatchEqual, astmapper.EmbeddedQueriesLabelName, encodedQueries),
+		labels.MustNewMatcher(labels.MatchEqual, astmapper.EmbeddedQueriesSubqueryLabelName, "true"),
+	)
+
+	// The results of the requests are merged in a single series, with a sample at each step.
+	require.True(t, seriesSet.Next())
+	it := seriesSet.At().Iterator(nil)
+	expectedTs := int64(0)
+	for it.Next() != chunkenc.ValNone {
+		ts, v := it.At()
+		if value.IsStaleNaN(v) {
+			// The stale marker added after the last sample.
+			assert.Equal(t, int64(2*maxRangeQuerySteps*step+step), ts)
+			continue
+		}
+		assert.Equal(t, expectedTs, ts)
+		expectedTs += step
+	}
+	assert.Equal(t, int64(2*maxRangeQuerySteps*step+step), expectedTs)
+	assert.False(t, seriesSet.Next())
+	require.NoError(t, seriesSet.Err())
+}
+
 func TestShardedQueryable_GetResponseHeaders(t *testing.T) {
 	queryable := newShardedQueryable(&PrometheusRangeQueryRequest{}, nil)
 	assert.Empty(t, queryable.getResponseHeaders())
@@ -324,6 +442,44 @@ func TestNewSeriesSetFromEmbeddedQueriesResults(t *testing.T) {
 				Samples: []mimirpb.Sample{{TimestampMs: 20, Value: 2}, {TimestampMs: 30, Value: 3}, {TimestampMs: 40, Value: math.Float64frombits(value.StaleNaN)}},
 			}},
 		},
+		"should add stale markers at the beginning of each gap and one at the end of native histogram series": {
+			input: []SampleStream{{
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 10, Histogram: *testFloatHistogram(1)}, {TimestampMs: 40, Histogram: *testFloatHistogram(4)}, {TimestampMs: 90, Histogram: *testFloatHistogram(9)}},
+			}, {
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "b"}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 20, Histogram: *testFloatHistogram(2)}, {TimestampMs: 30, Histogram: *testFloatHistogram(3)}},
+			}},
+			hints: &storage.SelectHints{Step: 10},
+			expected: []SampleStream{{
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 10, Histogram: *testFloatHistogram(1)}, {TimestampMs: 20, Histogram: staleFloatHistogram}, {TimestampMs: 40, Histogram: *testFloatHistogram(4)}, {TimestampMs: 50, Histogram: staleFloatHistogram}, {TimestampMs: 90, Histogram: *testFloatHistogram(9)}, {TimestampMs: 100, Histogram: staleFloatHistogram}},
+			}, {
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "b"}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 20, Histogram: *testFloatHistogram(2)}, {TimestampMs: 30, Histogram: *testFloatHistogram(3)}, {TimestampMs: 40, Histogram: staleFloatHistogram}},
+			}},
+		},
+		"should add the stale marker at the end of series with both float samples and native histograms only after the last one": {
+			input: []SampleStream{{
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+				Samples:    []mimirpb.Sample{{TimestampMs: 10, Value: 1}, {TimestampMs: 20, Value: 2}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 30, Histogram: *testFloatHistogram(3)}},
+			}, {
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "b"}},
+				Samples:    []mimirpb.Sample{{TimestampMs: 20, Value: 2}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 10, Histogram: *testFloatHistogram(1)}},
+			}},
+			hints: &storage.SelectHints{Step: 10},
+			expected: []SampleStream{{
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
+				Samples:    []mimirpb.Sample{{TimestampMs: 10, Value: 1}, {TimestampMs: 20, Value: 2}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 30, Histogram: *testFloatHistogram(3)}, {TimestampMs: 40, Histogram: staleFloatHistogram}},
+			}, {
+				Labels:     []mimirpb.LabelAdapter{{Name: "a", Value: "b"}},
+				Samples:    []mimirpb.Sample{{TimestampMs: 20, Value: 2}, {TimestampMs: 30, Value: math.Float64frombits(value.StaleNaN)}},
+				Histograms: []mimirpb.FloatHistogramPair{{TimestampMs: 10, Histogram: *testFloatHistogram(1)}},
+			}},
+		},
 		"should not add stale markers even if points have gaps if hints is not passed": {
 			input: []SampleStream{{
 				Labels:  []mimirpb.LabelAdapter{{Name: "a", Value: "1"}},
@@ -441,9 +597,29 @@ func assertEqualSampleStream(t *testing.T, expected, actual []SampleStream) {
 				assert.Equal(t, expectedSample.Value, actualSample.Value)
 			}
 		}
+
+		// Expect the same histograms (in this comparison, stale markers are equal if both have a StaleNaN sum).
+		require.Equal(t, len(expectedStream.Histograms), len(actualStream.Histograms))
+
+		for idx, expectedHistogram := range expectedStream.Histograms {
+			actualHistogram := actualStream.Histograms[idx]
+			require.Equal(t, expectedHistogram.TimestampMs, actualHistogram.TimestampMs)
+
+			if value.IsStaleNaN(expectedHistogram.Histogram.Sum) {
+				assert.True(t, value.IsStaleNaN(actualHistogram.Histogram.Sum))
+			} else {
+				assert.Equal(t, expectedHistogram.Histogram, actualHistogram.Histogram)
+			}
+		}
 	}
 }
 
+var staleFloatHistogram = mimirpb.FloatHistogram{Sum: math.Float64frombits(value.StaleNaN)}
+
+func testFloatHistogram(i int) *mimirpb.FloatHistogram {
+	return mimirpb.FloatHistogramFromPrometheusModel(test.GenerateTestFloatHistogram(i))
+}
+
 // seriesSetToSampleStreams iterate through the input storage.SeriesSet and returns it as a []SampleStream.
 func seriesSetToSampleStreams(set storage.SeriesSet) ([]SampleStream, error) {
 	var out []SampleStream
@@ -35,6 +35,7 @@ const (
 	notCachableReasonUnalignedTimeRange   = "unaligned-time-range"
 	notCachableReasonTooNew               = "too-new"
 	notCachableReasonModifiersNotCachable = "has-modifiers"
+	notCachableReasonTooLarge             = "too-large"
 )
 
 var (
@@ -67,7 +68,7 @@ func newSplitAndCacheMiddlewareMetrics(reg prometheus.Registerer) *splitAndCache
 
 	// Initialize known label values.
 	for _, reason := range []string{notCachableReasonUnalignedTimeRange, notCachableReasonTooNew,
-		notCachableReasonModifiersNotCachable} {
+		notCachableReasonModifiersNotCachable, notCachableReasonTooLarge} {
 		m.queryResultCacheSkippedCount.WithLabelValues(reason)
 	}
 
@@ -149,6 +150,10 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 	}
 
 	isCacheEnabled := s.cacheEnabled && (s.shouldCacheReq == nil || s.shouldCacheReq(req))
+
+	// The extents of the queries refreshed by the results cache refresher are stored again even when they're
+	// fully cached, so that their TTL is extended and they don't expire before the queries are run by the users.
+	isCacheRefresh := isResultsCacheRefresh(ctx)
 	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, s.limits.MaxCacheFreshness)
 	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
 
@@ -198,6 +203,9 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 				}
 
 				lookupReqs[lookupIdx].cachedResponses = []Response{response}
+				if isCacheRefresh {
+					lookupReqs[lookupIdx].cachedExtents = extents
+				}
 				continue
 			}
 
@@ -235,11 +243,11 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 	}
 
 	// Store the updated response in the results cache.
-	if isCacheEnabled && len(execReqs) > 0 {
+	if isCacheEnabled && (len(execReqs) > 0 || isCacheRefresh) {
 		for _, splitReq := range splitReqs {
 			// If there are no downstream requests it means the response was entirely picked up from the cache
-			// so there's no need to store it again in the cache (because nothing has changed).
-			if len(splitReq.downstreamRequests) == 0 {
+			// so there's no need to store it again in the cache (because nothing has changed), unless it's refreshed.
+			if len(splitReq.downstreamRequests) == 0 && (!isCacheRefresh || len(splitReq.cachedExtents) == 0) {
 				continue
 			}
 
@@ -265,8 +273,8 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req Request) (Response
 				updatedExtents = append(updatedExtents, extent)
 			}
 
-			// If extents haven't been updated, we can skip storing it in the cache again.
-			if len(splitReq.cachedExtents) == len(updatedExtents) {
+			// If extents haven't been updated, we can skip storing it in the cache again, unless it's refreshed.
+			if len(splitReq.cachedExtents) == len(updatedExtents) && !isCacheRefresh {
 				continue
 			}
 
@@ -430,6 +438,11 @@ func (s *splitAndCacheMiddleware) storeCacheExtents(key string, tenantIDs []stri
 		return
 	}
 
+	if maxSize := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.ResultsCacheMaxEntrySizeBytes); maxSize > 0 && len(buf) > maxSize {
+		s.metrics.queryResultCacheSkippedCount.WithLabelValues(notCachableReasonTooLarge).Inc()
+		return
+	}
+
 	s.cache.StoreAsync(map[string][]byte{cacheHashKey(key): buf}, usedTTL)
 }
 
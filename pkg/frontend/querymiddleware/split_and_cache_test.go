@@ -216,6 +216,7 @@ func TestSplitAndCacheMiddleware_SplitByInterval(t *testing.T) {
 		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 		# TYPE cortex_frontend_query_result_cache_skipped_total counter
 		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="too-large"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
 		# HELP cortex_frontend_split_queries_total Total number of underlying query requests after the split by interval is applied.
@@ -335,6 +336,71 @@ func TestSplitAndCacheMiddleware_ResultsCache(t *testing.T) {
 	assert.Equal(t, uint32(2), queryStats.LoadSplitQueries())
 }
 
+func TestSplitAndCacheMiddleware_ResultsCache_ShouldStoreFullyCachedExtentsAgainOnRefresh(t *testing.T) {
+	cacheBackend := cache.NewInstrumentedMockCache()
+
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		false,
+		mockLimits{maxCacheFreshness: 10 * time.Minute, resultsCacheTTL: resultsCacheTTL, resultsCacheOutOfOrderWindowTTL: resultsCacheLowerTTL},
+		newTestPrometheusCodec(),
+		cacheBackend,
+		ConstSplitter(day),
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		log.NewNopLogger(),
+		prometheus.NewPedanticRegistry(),
+	)
+
+	expectedResponse := &PrometheusResponse{
+		Status: "success",
+		Data: &PrometheusData{
+			ResultType: model.ValMatrix.String(),
+			Result: []SampleStream{
+				{
+					Labels:  []mimirpb.LabelAdapter{{Name: "foo", Value: "bar"}},
+					Samples: []mimirpb.Sample{{Value: 137, TimestampMs: 1634292000000}},
+				},
+			},
+		},
+	}
+
+	downstreamReqs := 0
+	rc := mw.Wrap(HandlerFunc(func(_ context.Context, req Request) (Response, error) {
+		downstreamReqs++
+		return expectedResponse, nil
+	}))
+
+	req := Request(&PrometheusRangeQueryRequest{
+		Path:  "/api/v1/query_range",
+		Start: parseTimeRFC3339(t, "2021-10-15T10:00:00Z").Unix() * 1000,
+		End:   parseTimeRFC3339(t, "2021-10-15T12:00:00Z").Unix() * 1000,
+		Step:  120 * 1000,
+		Query: `{__name__=~".+"}`,
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "1")
+	_, err := rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 1, downstreamReqs)
+	assert.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// A fully cached request isn't stored again.
+	_, err = rc.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 1, downstreamReqs)
+	assert.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// A fully cached request is stored again when it's refreshed, without querying the downstream.
+	resp, err := rc.Do(contextWithResultsCacheRefresh(ctx), req)
+	require.NoError(t, err)
+	require.Equal(t, expectedResponse, resp)
+	require.Equal(t, 1, downstreamReqs)
+	assert.Equal(t, 2, cacheBackend.CountStoreCalls())
+}
+
 func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAligned(t *testing.T) {
 	cacheBackend := cache.NewInstrumentedMockCache()
 	reg := prometheus.NewPedanticRegistry()
@@ -431,6 +497,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotLookupCacheIfStepIsNotAli
 		# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 		# TYPE cortex_frontend_query_result_cache_skipped_total counter
 		cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+		cortex_frontend_query_result_cache_skipped_total{reason="too-large"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 0
 		cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 1
 		# HELP cortex_frontend_split_queries_total Total number of underlying query requests after the split by interval is applied.
@@ -567,6 +634,7 @@ func TestSplitAndCacheMiddleware_ResultsCache_ShouldNotCacheRequestEarlierThanMa
 				# HELP cortex_frontend_query_result_cache_skipped_total Total number of times a query was not cacheable because of a reason. This metric is tracked for each partial query when time-splitting is enabled.
 				# TYPE cortex_frontend_query_result_cache_skipped_total counter
 				cortex_frontend_query_result_cache_skipped_total{reason="has-modifiers"} 0
+				cortex_frontend_query_result_cache_skipped_total{reason="too-large"} 0
 				cortex_frontend_query_result_cache_skipped_total{reason="too-new"} 2
 				cortex_frontend_query_result_cache_skipped_total{reason="unaligned-time-range"} 0
 				# HELP cortex_frontend_split_queries_total Total number of underlying query requests after the split by interval is applied.
@@ -1221,6 +1289,39 @@ func TestSplitAndCacheMiddleware_StoreAndFetchCacheExtents(t *testing.T) {
 	})
 }
 
+func TestSplitAndCacheMiddleware_StoreCacheExtents_ShouldNotCacheEntriesLargerThanMaxEntrySize(t *testing.T) {
+	cacheBackend := cache.NewMockCache()
+	reg := prometheus.NewPedanticRegistry()
+	mw := newSplitAndCacheMiddleware(
+		false,
+		true,
+		24*time.Hour,
+		false,
+		multiTenantMockLimits{byTenant: map[string]mockLimits{
+			"small":     {resultsCacheTTL: time.Hour, resultsCacheMaxEntrySizeBytes: 100},
+			"unlimited": {resultsCacheTTL: time.Hour},
+		}},
+		newTestPrometheusCodec(),
+		cacheBackend,
+		ConstSplitter(day),
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		log.NewNopLogger(),
+		reg,
+	).Wrap(nil).(*splitAndCacheMiddleware)
+
+	ctx := context.Background()
+	extents := []Extent{mkExtent(0, 100)}
+
+	mw.storeCacheExtents("key-small", []string{"small"}, extents)
+	mw.storeCacheExtents("key-unlimited", []string{"unlimited"}, extents)
+	mw.storeCacheExtents("key-both", []string{"small", "unlimited"}, extents)
+
+	actual := mw.fetchCacheExtents(ctx, time.Now(), []string{"unlimited"}, []string{"key-small", "key-unlimited", "key-both"})
+	assert.Equal(t, [][]Extent{nil, extents, nil}, actual)
+	assert.Equal(t, float64(2), testutil.ToFloat64(mw.metrics.queryResultCacheSkippedCount.WithLabelValues(notCachableReasonTooLarge)))
+}
+
 func TestSplitAndCacheMiddleware_WrapMultipleTimes(t *testing.T) {
 	m := newSplitAndCacheMiddleware(
 		false,
@@ -8,10 +8,12 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/tenant"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/promql/parser"
 
@@ -36,6 +38,10 @@ type splitInstantQueryByIntervalMiddleware struct {
 
 	engine *promql.Engine
 
+	// Results caching of the partial queries.
+	cacheEnabled bool
+	cache        cache.Cache
+
 	metrics instantQuerySplittingMetrics
 }
 
@@ -45,6 +51,10 @@ type instantQuerySplittingMetrics struct {
 	splittingSkipped     *prometheus.CounterVec
 	splitQueries         prometheus.Counter
 	splitQueriesPerQuery prometheus.Histogram
+
+	partialQueriesCacheAttempts prometheus.Counter
+	partialQueriesCacheHits     prometheus.Counter
+	partialQueriesCacheSkipped  *prometheus.CounterVec
 }
 
 func newInstantQuerySplittingMetrics(registerer prometheus.Registerer) instantQuerySplittingMetrics {
@@ -70,6 +80,18 @@ func newInstantQuerySplittingMetrics(registerer prometheus.Registerer) instantQu
 			Help:    "Number of split partial queries a single instant query has been rewritten to.",
 			Buckets: prometheus.ExponentialBuckets(2, 2, 10),
 		}),
+		partialQueriesCacheAttempts: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_split_queries_cache_attempted_total",
+			Help: "Total number of split partial queries that were attempted to be fetched from the results cache.",
+		}),
+		partialQueriesCacheHits: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_split_queries_cache_hits_total",
+			Help: "Total number of split partial queries whose result was fetched from the results cache.",
+		}),
+		partialQueriesCacheSkipped: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_frontend_instant_query_split_queries_cache_skipped_total",
+			Help: "Total number of split partial queries whose result was not stored in the results cache.",
+		}, []string{"reason"}),
 	}
 
 	// Initialize known label values.
@@ -77,6 +99,7 @@ func newInstantQuerySplittingMetrics(registerer prometheus.Registerer) instantQu
 		string(astmapper.SkippedReasonSmallInterval), string(astmapper.SkippedReasonSubquery), string(astmapper.SkippedReasonNonSplittable)} {
 		m.splittingSkipped.WithLabelValues(reason)
 	}
+	m.partialQueriesCacheSkipped.WithLabelValues(notCachableReasonTooLarge)
 
 	return m
 }
@@ -86,16 +109,20 @@ func newSplitInstantQueryByIntervalMiddleware(
 	limits Limits,
 	logger log.Logger,
 	engine *promql.Engine,
+	cacheEnabled bool,
+	cache cache.Cache,
 	registerer prometheus.Registerer) Middleware {
 	metrics := newInstantQuerySplittingMetrics(registerer)
 
 	return MiddlewareFunc(func(next Handler) Handler {
 		return &splitInstantQueryByIntervalMiddleware{
-			next:    next,
-			limits:  limits,
-			logger:  logger,
-			engine:  engine,
-			metrics: metrics,
+			next:         next,
+			limits:       limits,
+			logger:       logger,
+			engine:       engine,
+			cacheEnabled: cacheEnabled,
+			cache:        cache,
+			metrics:      metrics,
 		}
 	})
 }
@@ -124,7 +151,15 @@ func (s *splitInstantQueryByIntervalMiddleware) Do(ctx context.Context, req Requ
 	mapperStats := astmapper.NewInstantSplitterStats()
 	mapperCtx, cancel := context.WithTimeout(ctx, shardingTimeout)
 	defer cancel()
-	mapper := astmapper.NewInstantQuerySplitter(mapperCtx, splitInterval, s.logger, mapperStats)
+	// When the partial queries are cached, align them to the split interval so that they can be reused
+	// by the same query executed at a different time.
+	isCacheEnabled := s.cacheEnabled && !req.GetOptions().CacheDisabled
+	var mapper astmapper.ASTMapper
+	if isCacheEnabled {
+		mapper = astmapper.NewAlignedInstantQuerySplitter(mapperCtx, splitInterval, timestamp.Time(req.GetStart()), s.logger, mapperStats)
+	} else {
+		mapper = astmapper.NewInstantQuerySplitter(mapperCtx, splitInterval, s.logger, mapperStats)
+	}
 
 	expr, err := parser.ParseExpr(req.GetQuery())
 	if err != nil {
@@ -172,7 +207,18 @@ func (s *splitInstantQueryByIntervalMiddleware) Do(ctx context.Context, req Requ
 
 	// Send hint with number of embedded queries to the sharding middleware
 	req = req.WithQuery(instantSplitQuery.String()).WithTotalQueriesHint(int32(mapperStats.GetSplitQueries()))
-	shardedQueryable := newShardedQueryable(req, s.next)
+	next := s.next
+	if isCacheEnabled {
+		next = &splitInstantQueryCache{
+			next:        s.next,
+			limits:      s.limits,
+			cache:       s.cache,
+			logger:      logger,
+			metrics:     s.metrics,
+			currentTime: time.Now,
+		}
+	}
+	shardedQueryable := newShardedQueryable(req, next)
 
 	qry, err := newQuery(req, s.engine, lazyquery.NewLazyQueryable(shardedQueryable))
 	if err != nil {
@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// splitInstantQueryCache is a Handler running the partial queries of a split instant query
+// through the results cache.
+type splitInstantQueryCache struct {
+	next    Handler
+	limits  Limits
+	cache   cache.Cache
+	logger  log.Logger
+	metrics instantQuerySplittingMetrics
+
+	// Can be set from tests
+	currentTime func() time.Time
+}
+
+func (c *splitInstantQueryCache) Do(ctx context.Context, req Request) (Response, error) {
+	instantReq, ok := req.(*PrometheusInstantQueryRequest)
+	if !ok {
+		return c.next.Do(ctx, req)
+	}
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return c.next.Do(ctx, req)
+	}
+
+	// Only cache partial queries whose selected time range is older than the max cache freshness.
+	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, c.limits.MaxCacheFreshness)
+	maxCacheTime := c.currentTime().Add(-maxCacheFreshness).UnixMilli()
+
+	key, evalTime, ok := generateSplitInstantQueryCacheKey(tenant.JoinTenantIDs(tenantIDs), instantReq)
+	if !ok || evalTime > maxCacheTime {
+		return c.next.Do(ctx, req)
+	}
+
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, c.logger, "splitInstantQueryCache.Do")
+	defer spanLog.Finish()
+
+	c.metrics.partialQueriesCacheAttempts.Inc()
+	if res, ok := c.fetch(ctx, key); ok {
+		c.metrics.partialQueriesCacheHits.Inc()
+		spanLog.LogKV("cache hit", true)
+		return res, nil
+	}
+	spanLog.LogKV("cache hit", false)
+
+	res, err := c.next.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if promRes, ok := res.(*PrometheusResponse); ok && promRes.Status == statusSuccess && isResponseCachable(res, c.logger) {
+		c.store(ctx, key, evalTime, tenantIDs, res)
+	}
+
+	return res, nil
+}
+
+// fetch looks up the response for the given key in the results cache.
+func (c *splitInstantQueryCache) fetch(ctx context.Context, key string) (Response, bool) {
+	hashed := cacheHashKey(key)
+	found := c.cache.Fetch(ctx, []string{hashed})
+
+	buf, ok := found[hashed]
+	if !ok {
+		return nil, false
+	}
+
+	var cached CachedResponse
+	if err := proto.Unmarshal(buf, &cached); err != nil {
+		level.Warn(c.logger).Log("msg", "failed to unmarshal cached split instant query response", "err", err)
+		return nil, false
+	}
+
+	// Check the key to protect against hash collisions.
+	if cached.Key != key || len(cached.Extents) != 1 {
+		return nil, false
+	}
+
+	res, err := cached.Extents[0].toResponse()
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to decode cached split instant query response", "err", err)
+		return nil, false
+	}
+	return res, true
+}
+
+// store stores the response for the given key in the results cache.
+func (c *splitInstantQueryCache) store(ctx context.Context, key string, evalTime int64, tenantIDs []string, res Response) {
+	extent, err := toExtent(ctx, &PrometheusInstantQueryRequest{Time: evalTime}, PrometheusResponseExtractor{}.ResponseWithoutHeaders(res), c.currentTime())
+	if err != nil {
+		level.Warn(c.logger).Log("msg", "failed to encode split instant query response", "err", err)
+		return
+	}
+
+	buf, err := proto.Marshal(&CachedResponse{
+		Key:     key,
+		Extents: []Extent{extent},
+	})
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error marshalling cached split instant query response", "err", err)
+		return
+	}
+
+	if maxSize := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, c.limits.ResultsCacheMaxEntrySizeBytes); maxSize > 0 && len(buf) > maxSize {
+		c.metrics.partialQueriesCacheSkipped.WithLabelValues(notCachableReasonTooLarge).Inc()
+		return
+	}
+
+	ttl := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, c.limits.ResultsCacheTTL)
+	ttlInOOO := validation.SmallestPositiveNonZeroDurationPerTenant(tenantIDs, c.limits.ResultsCacheTTLForOutOfOrderTimeWindow)
+	oooWindow := validation.MaxDurationPerTenant(tenantIDs, c.limits.OutOfOrderTimeWindow)
+
+	c.cache.StoreAsync(map[string][]byte{cacheHashKey(key): buf}, getTTLForExtent(c.currentTime(), ttl, ttlInOOO, oooWindow, &extent))
+}
+
+// generateSplitInstantQueryCacheKey generates the key to cache the result of a partial query of a split instant query.
+// The offset of the selectors is replaced by the @ modifier pointing to the time they're evaluated at, so that the same
+// partial query run by split instant queries executed at different times gets the same key. Returns false if the
+// result of the partial query may depend on the query time in any other way.
+func generateSplitInstantQueryCacheKey(userID string, r *PrometheusInstantQueryRequest) (key string, evalTime int64, ok bool) {
+	expr, err := parser.ParseExpr(r.GetQuery())
+	if err != nil {
+		return "", 0, false
+	}
+
+	evalTimes := map[int64]struct{}{}
+	cachable := true
+	parser.Inspect(expr, func(n parser.Node, _ []parser.Node) error {
+		switch e := n.(type) {
+		case *parser.VectorSelector:
+			if e.Timestamp != nil || e.StartOrEnd != 0 || e.OriginalOffset < 0 {
+				cachable = false
+				return nil
+			}
+			ts := r.GetStart() - e.OriginalOffset.Milliseconds()
+			evalTimes[ts] = struct{}{}
+			e.Timestamp = &ts
+			e.OriginalOffset = 0
+		case *parser.Call:
+			// Functions without arguments (e.g. time()) depend on the query time.
+			if len(e.Args) == 0 {
+				cachable = false
+			}
+		case nil, *parser.AggregateExpr, *parser.MatrixSelector, *parser.ParenExpr, *parser.NumberLiteral, *parser.StringLiteral, parser.Expressions:
+		default:
+			cachable = false
+		}
+		return nil
+	})
+
+	if !cachable || len(evalTimes) != 1 {
+		return "", 0, false
+	}
+	for ts := range evalTimes {
+		evalTime = ts
+	}
+
+	// Prefix key with `IS` (short for "instant split").
+	return fmt.Sprintf("IS:%s:%s", userID, expr.String()), evalTime, true
+}
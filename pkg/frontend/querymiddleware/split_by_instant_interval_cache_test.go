@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSplitInstantQueryCacheKey(t *testing.T) {
+	queryTime := time.Unix(3600, 0)
+
+	for _, tt := range []struct {
+		query            string
+		expectedKey      string
+		expectedEvalTime int64
+		expectedOk       bool
+	}{
+		{
+			query:            `sum_over_time(metric[1h])`,
+			expectedKey:      `IS:user:sum_over_time(metric[1h] @ 3600.000)`,
+			expectedEvalTime: 3600 * 1000,
+			expectedOk:       true,
+		},
+		{
+			query:            `sum by(a) (max_over_time(metric[1h] offset 30m))`,
+			expectedKey:      `IS:user:sum by (a) (max_over_time(metric[1h] @ 1800.000))`,
+			expectedEvalTime: 1800 * 1000,
+			expectedOk:       true,
+		},
+		{
+			query:      `sum_over_time(metric[1h] @ 1800)`,
+			expectedOk: false,
+		},
+		{
+			query:      `sum_over_time(metric[1h] offset -30m)`,
+			expectedOk: false,
+		},
+		{
+			query:      `sum_over_time(metric[1h]) + time()`,
+			expectedOk: false,
+		},
+		{
+			query:      `sum_over_time(metric[1h]) / sum_over_time(metric[1h] offset 1h)`,
+			expectedOk: false,
+		},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			key, evalTime, ok := generateSplitInstantQueryCacheKey("user", &PrometheusInstantQueryRequest{
+				Time:  queryTime.UnixMilli(),
+				Query: tt.query,
+			})
+
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedKey, key)
+			assert.Equal(t, tt.expectedEvalTime, evalTime)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/promql"
@@ -511,7 +512,7 @@ func TestInstantQuerySplittingCorrectness(t *testing.T) {
 							require.NotEmpty(t, expectedPrometheusRes.Data.Result)
 							requireValidSamples(t, expectedPrometheusRes.Data.Result)
 
-							splittingware := newSplitInstantQueryByIntervalMiddleware(mockLimits{splitInstantQueriesInterval: 1 * time.Minute}, log.NewNopLogger(), engine, reg)
+							splittingware := newSplitInstantQueryByIntervalMiddleware(mockLimits{splitInstantQueriesInterval: 1 * time.Minute}, log.NewNopLogger(), engine, false, nil, reg)
 
 							// Run the query with splitting
 							splitRes, err := splittingware.Wrap(downstream).Do(user.InjectOrgID(ctx, "test"), req)
@@ -558,6 +559,19 @@ func TestInstantQuerySplittingCorrectness(t *testing.T) {
 							// Assert query stats from context
 							queryStats := stats.FromContext(ctx)
 							assert.Equal(t, uint32(testData.expectedSplitQueries), queryStats.LoadSplitQueries())
+
+							// Run the query with splitting and the results cache of the partial queries enabled, which aligns the
+							// partial queries to the split interval. The second run fetches the partial queries from the cache.
+							cachingware := newSplitInstantQueryByIntervalMiddleware(mockLimits{splitInstantQueriesInterval: 1 * time.Minute, resultsCacheTTL: time.Hour}, log.NewNopLogger(), engine, true, cache.NewMockCache(), nil)
+							for i := 0; i < 2; i++ {
+								cachedRes, err := cachingware.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "test"), req)
+								require.Nil(t, err)
+
+								cachedPrometheusRes := cachedRes.(*PrometheusResponse)
+								sort.Sort(byLabels(cachedPrometheusRes.Data.Result))
+
+								approximatelyEquals(t, expectedPrometheusRes, cachedPrometheusRes)
+							}
 						})
 					}
 				})
@@ -603,7 +617,7 @@ func TestInstantQuerySplittingHTTPOptions(t *testing.T) {
 			}
 
 			// Split by interval middleware with a limit configuration of split instant query interval of 1m
-			splittingware := newSplitInstantQueryByIntervalMiddleware(mockLimits{splitInstantQueriesInterval: 1 * time.Minute}, log.NewNopLogger(), newEngine(), nil)
+			splittingware := newSplitInstantQueryByIntervalMiddleware(mockLimits{splitInstantQueriesInterval: 1 * time.Minute}, log.NewNopLogger(), newEngine(), false, nil, nil)
 
 			downstream := &mockHandler{}
 			downstream.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{
@@ -619,3 +633,89 @@ func TestInstantQuerySplittingHTTPOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestInstantQuerySplittingResultsCache(t *testing.T) {
+	const query = "sum_over_time(metric_counter[3h])"
+
+	queryTime, err := time.Parse(time.RFC3339, "2020-01-01T10:30:00Z")
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name                    string
+		cacheEnabled            bool
+		httpOptions             Options
+		secondQueryTime         time.Time
+		maxEntrySizeBytes       int
+		expectedDownstreamCalls []int
+		expectedCacheSkipped    int
+	}{
+		{
+			name:            "should not cache the partial queries if the results cache is disabled",
+			secondQueryTime: queryTime,
+			// [3h] range interval with 1h split interval is split in 3 partial queries.
+			expectedDownstreamCalls: []int{3, 6},
+		},
+		{
+			name:                    "should not cache the partial queries if the results cache is disabled via HTTP option",
+			cacheEnabled:            true,
+			httpOptions:             Options{CacheDisabled: true},
+			secondQueryTime:         queryTime,
+			expectedDownstreamCalls: []int{3, 6},
+		},
+		{
+			name:            "should fetch all partial queries from the cache when the same query is executed again",
+			cacheEnabled:    true,
+			secondQueryTime: queryTime,
+			// The partial queries are aligned to the split interval: (10:00,10:30], (9:00,10:00], (8:00,9:00], (7:30,8:00].
+			expectedDownstreamCalls: []int{4, 4},
+		},
+		{
+			name:            "should fetch the aligned partial queries from the cache when the query is executed at a different time",
+			cacheEnabled:    true,
+			secondQueryTime: queryTime.Add(time.Hour),
+			// Only the (9:00,10:00] partial query is shared with the previous execution.
+			expectedDownstreamCalls: []int{4, 7},
+		},
+		{
+			name:                    "should not cache the partial queries larger than the max cache entry size",
+			cacheEnabled:            true,
+			secondQueryTime:         queryTime,
+			maxEntrySizeBytes:       1,
+			expectedDownstreamCalls: []int{4, 8},
+			expectedCacheSkipped:    8,
+		},
+	} {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			limits := mockLimits{splitInstantQueriesInterval: time.Hour, resultsCacheTTL: time.Hour, resultsCacheMaxEntrySizeBytes: tt.maxEntrySizeBytes}
+			splittingware := newSplitInstantQueryByIntervalMiddleware(limits, log.NewNopLogger(), newEngine(), tt.cacheEnabled, cache.NewMockCache(), reg)
+
+			downstream := &mockHandler{}
+			downstream.On("Do", mock.Anything, mock.Anything).Return(&PrometheusResponse{
+				Status: statusSuccess, Data: &PrometheusData{ResultType: string(parser.ValueTypeVector)},
+			}, nil)
+
+			for i, ts := range []time.Time{queryTime, tt.secondQueryTime} {
+				req := &PrometheusInstantQueryRequest{
+					Path:    "/query",
+					Time:    ts.UnixMilli(),
+					Query:   query,
+					Options: tt.httpOptions,
+				}
+
+				res, err := splittingware.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "test"), req)
+				require.NoError(t, err)
+				assert.Equal(t, statusSuccess, res.(*PrometheusResponse).GetStatus())
+				downstream.AssertNumberOfCalls(t, "Do", tt.expectedDownstreamCalls[i])
+			}
+
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+				# HELP cortex_frontend_instant_query_split_queries_cache_skipped_total Total number of split partial queries whose result was not stored in the results cache.
+				# TYPE cortex_frontend_instant_query_split_queries_cache_skipped_total counter
+				cortex_frontend_instant_query_split_queries_cache_skipped_total{reason="too-large"} %d
+			`, tt.expectedCacheSkipped)), "cortex_frontend_instant_query_split_queries_cache_skipped_total"))
+		})
+	}
+}
@@ -20,13 +20,11 @@ const (
 
 // ActiveSeries is keeping track of recently active series for a single tenant.
 type ActiveSeries struct {
-	mu                 sync.RWMutex
-	stripes            [numStripes]seriesStripe
-	matchers           *Matchers
-	lastMatchersUpdate time.Time
+	mu       sync.RWMutex
+	stripes  [numStripes]seriesStripe
+	matchers *Matchers
 
 	// The duration after which series become inactive.
-	// Also used to determine if enough time has passed since configuration reload for valid results.
 	timeout time.Duration
 }
 
@@ -69,15 +67,16 @@ func (c *ActiveSeries) CurrentMatcherNames() []string {
 	return c.matchers.MatcherNames()
 }
 
-func (c *ActiveSeries) ReloadMatchers(asm *Matchers, now time.Time) {
+// ReloadMatchers replaces the custom trackers matchers. The series already tracked are matched
+// against the new matchers, so that the Active results are accurate right after the reload.
+func (c *ActiveSeries) ReloadMatchers(asm *Matchers) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	for i := 0; i < numStripes; i++ {
-		c.stripes[i].reinitialize(asm)
+		c.stripes[i].reloadMatchers(asm)
 	}
 	c.matchers = asm
-	c.lastMatchersUpdate = now
 }
 
 func (c *ActiveSeries) CurrentConfig() CustomTrackersConfig {
@@ -103,17 +102,11 @@ func (c *ActiveSeries) purge(keepUntil time.Time) {
 
 // Active returns the total number of active series, as well as a slice of active series matching each one of the
 // custom trackers provided (in the same order as custom trackers are defined).
-// The result is correct only if the third return value is true, which shows if enough time has passed since last reload.
 // This should be called periodically to avoid unbounded memory growth.
-func (c *ActiveSeries) Active(now time.Time) (int, []int, bool) {
+func (c *ActiveSeries) Active(now time.Time) (int, []int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	purgeTime := now.Add(-c.timeout)
-	c.purge(purgeTime)
-
-	if c.lastMatchersUpdate.After(purgeTime) {
-		return 0, nil, false
-	}
+	c.purge(now.Add(-c.timeout))
 
 	total := 0
 	totalMatching := resizeAndClear(len(c.matchers.MatcherNames()), nil)
@@ -121,7 +114,7 @@ func (c *ActiveSeries) Active(now time.Time) (int, []int, bool) {
 		total += c.stripes[s].getTotalAndUpdateMatching(totalMatching)
 	}
 
-	return total, totalMatching, true
+	return total, totalMatching
 }
 
 // getTotalAndUpdateMatching will return the total active series in the stripe and also update the slice provided
@@ -234,6 +227,26 @@ func (s *seriesStripe) reinitialize(asm *Matchers) {
 	s.activeMatching = resizeAndClear(len(asm.MatcherNames()), s.activeMatching)
 }
 
+// reloadMatchers assigns new matchers, and matches the entries of the stripe against them.
+func (s *seriesStripe) reloadMatchers(asm *Matchers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.matchers = asm
+	s.activeMatching = resizeAndClear(len(asm.MatcherNames()), s.activeMatching)
+
+	for _, entries := range s.refs {
+		for i := range entries {
+			entries[i].matches = asm.matches(entries[i].lbs)
+
+			ml := entries[i].matches.len()
+			for j := 0; j < ml; j++ {
+				s.activeMatching[entries[i].matches.get(j)]++
+			}
+		}
+	}
+}
+
 func (s *seriesStripe) purge(keepUntil time.Time) {
 	keepUntilNanos := keepUntil.UnixNano()
 	if oldest := s.oldestEntryTs.Load(); oldest > 0 && keepUntilNanos <= oldest {
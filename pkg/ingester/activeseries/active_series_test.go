@@ -27,25 +27,21 @@ func TestActiveSeries_UpdateSeries_NoMatchers(t *testing.T) {
 	ls2 := labels.FromStrings("a", "2")
 
 	c := NewActiveSeries(&Matchers{}, DefaultTimeout)
-	allActive, activeMatching, valid := c.Active(time.Now())
+	allActive, activeMatching := c.Active(time.Now())
 	assert.Equal(t, 0, allActive)
 	assert.Nil(t, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, time.Now(), copyFn)
-	allActive, _, valid = c.Active(time.Now())
+	allActive, _ = c.Active(time.Now())
 	assert.Equal(t, 1, allActive)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, time.Now(), copyFn)
-	allActive, _, valid = c.Active(time.Now())
+	allActive, _ = c.Active(time.Now())
 	assert.Equal(t, 1, allActive)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls2, time.Now(), copyFn)
-	allActive, _, valid = c.Active(time.Now())
+	allActive, _ = c.Active(time.Now())
 	assert.Equal(t, 2, allActive)
-	assert.True(t, valid)
 }
 
 func TestActiveSeries_UpdateSeries_WithMatchers(t *testing.T) {
@@ -56,34 +52,29 @@ func TestActiveSeries_UpdateSeries_WithMatchers(t *testing.T) {
 	asm := NewMatchers(mustNewCustomTrackersConfigFromMap(t, map[string]string{"foo": `{a=~"2|3"}`}))
 
 	c := NewActiveSeries(asm, DefaultTimeout)
-	allActive, activeMatching, valid := c.Active(time.Now())
+	allActive, activeMatching := c.Active(time.Now())
 	assert.Equal(t, 0, allActive)
 	assert.Equal(t, []int{0}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, time.Now(), copyFn)
-	allActive, activeMatching, valid = c.Active(time.Now())
+	allActive, activeMatching = c.Active(time.Now())
 	assert.Equal(t, 1, allActive)
 	assert.Equal(t, []int{0}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls2, time.Now(), copyFn)
-	allActive, activeMatching, valid = c.Active(time.Now())
+	allActive, activeMatching = c.Active(time.Now())
 	assert.Equal(t, 2, allActive)
 	assert.Equal(t, []int{1}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls3, time.Now(), copyFn)
-	allActive, activeMatching, valid = c.Active(time.Now())
+	allActive, activeMatching = c.Active(time.Now())
 	assert.Equal(t, 3, allActive)
 	assert.Equal(t, []int{2}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls3, time.Now(), copyFn)
-	allActive, activeMatching, valid = c.Active(time.Now())
+	allActive, activeMatching = c.Active(time.Now())
 	assert.Equal(t, 3, allActive)
 	assert.Equal(t, []int{2}, activeMatching)
-	assert.True(t, valid)
 }
 
 func TestActiveSeries_ShouldCorrectlyHandleHashCollisions(t *testing.T) {
@@ -96,9 +87,8 @@ func TestActiveSeries_ShouldCorrectlyHandleHashCollisions(t *testing.T) {
 	c.UpdateSeries(ls1, time.Now(), copyFn)
 	c.UpdateSeries(ls2, time.Now(), copyFn)
 
-	allActive, _, valid := c.Active(time.Now())
+	allActive, _ := c.Active(time.Now())
 	assert.Equal(t, 2, allActive)
-	assert.True(t, valid)
 }
 
 func TestActiveSeries_Purge_NoMatchers(t *testing.T) {
@@ -126,10 +116,9 @@ func TestActiveSeries_Purge_NoMatchers(t *testing.T) {
 
 			exp := len(series) - (ttl)
 			// c.Active is not intended to purge
-			allActive, activeMatching, valid := c.Active(mockedTime)
+			allActive, activeMatching := c.Active(mockedTime)
 			assert.Equal(t, exp, allActive)
 			assert.Nil(t, activeMatching)
-			assert.True(t, valid)
 		})
 	}
 }
@@ -169,10 +158,9 @@ func TestActiveSeries_Purge_WithMatchers(t *testing.T) {
 			c.purge(time.Unix(int64(ttl), 0))
 
 			// c.Active is not intended to purge
-			allActive, activeMatching, valid := c.Active(mockedTime)
+			allActive, activeMatching := c.Active(mockedTime)
 			assert.Equal(t, exp, allActive)
 			assert.Equal(t, []int{expMatchingSeries}, activeMatching)
-			assert.True(t, valid)
 		})
 	}
 }
@@ -188,23 +176,20 @@ func TestActiveSeries_PurgeOpt(t *testing.T) {
 	c.UpdateSeries(ls1, currentTime.Add(-2*time.Minute), copyFn)
 	c.UpdateSeries(ls2, currentTime, copyFn)
 
-	allActive, _, valid := c.Active(currentTime)
+	allActive, _ := c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, currentTime.Add(-1*time.Minute), copyFn)
 	c.UpdateSeries(ls2, currentTime, copyFn)
 
-	allActive, _, valid = c.Active(currentTime)
+	allActive, _ = c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
-	assert.True(t, valid)
 
 	// This will *not* update the series, since there is already newer timestamp.
 	c.UpdateSeries(ls2, currentTime.Add(-1*time.Minute), copyFn)
 
-	allActive, _, valid = c.Active(currentTime)
+	allActive, _ = c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
-	assert.True(t, valid)
 }
 
 func TestActiveSeries_ReloadSeriesMatchers(t *testing.T) {
@@ -218,54 +203,48 @@ func TestActiveSeries_ReloadSeriesMatchers(t *testing.T) {
 	currentTime := time.Now()
 	c := NewActiveSeries(asm, DefaultTimeout)
 
-	allActive, activeMatching, valid := c.Active(currentTime)
+	allActive, activeMatching := c.Active(currentTime)
 	assert.Equal(t, 0, allActive)
 	assert.Equal(t, []int{0}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, currentTime, copyFn)
-	allActive, activeMatching, valid = c.Active(currentTime)
+	allActive, activeMatching = c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
 	assert.Equal(t, []int{1}, activeMatching)
-	assert.True(t, valid)
 
-	c.ReloadMatchers(asm, currentTime)
-	_, _, valid = c.Active(currentTime)
-	assert.False(t, valid)
-
-	// Adding timeout time to make Active results valid.
-	currentTime = currentTime.Add(DefaultTimeout)
-	c.UpdateSeries(ls1, currentTime, copyFn)
+	// Reloading the same matchers keeps the series tracked.
+	c.ReloadMatchers(asm)
 	c.UpdateSeries(ls2, currentTime, copyFn)
-	allActive, activeMatching, valid = c.Active(currentTime)
+	allActive, activeMatching = c.Active(currentTime)
 	assert.Equal(t, 2, allActive)
 	assert.Equal(t, []int{2}, activeMatching)
-	assert.True(t, valid)
 
 	asmWithLessMatchers := NewMatchers(mustNewCustomTrackersConfigFromMap(t, map[string]string{}))
-	c.ReloadMatchers(asmWithLessMatchers, currentTime)
+	c.ReloadMatchers(asmWithLessMatchers)
 
-	// Adding timeout time to make Active results valid.
-	currentTime = currentTime.Add(DefaultTimeout)
 	c.UpdateSeries(ls3, currentTime, copyFn)
-	allActive, activeMatching, valid = c.Active(currentTime)
-	assert.Equal(t, 1, allActive)
+	allActive, activeMatching = c.Active(currentTime)
+	assert.Equal(t, 3, allActive)
 	assert.Equal(t, []int(nil), activeMatching)
-	assert.True(t, valid)
 
 	asmWithMoreMatchers := NewMatchers(mustNewCustomTrackersConfigFromMap(t, map[string]string{
 		"a": `{a="3"}`,
 		"b": `{a="4"}`,
 	}))
-	c.ReloadMatchers(asmWithMoreMatchers, currentTime)
+	c.ReloadMatchers(asmWithMoreMatchers)
 
-	// Adding timeout time to make Active results valid.
-	currentTime = currentTime.Add(DefaultTimeout)
+	// The series tracked before the reload are matched against the new matchers.
 	c.UpdateSeries(ls4, currentTime, copyFn)
-	allActive, activeMatching, valid = c.Active(currentTime)
+	allActive, activeMatching = c.Active(currentTime)
+	assert.Equal(t, 4, allActive)
+	assert.Equal(t, []int{1, 1}, activeMatching)
+
+	// Series are purged after the reload as usual.
+	currentTime = currentTime.Add(DefaultTimeout + time.Second)
+	c.UpdateSeries(ls4, currentTime, copyFn)
+	allActive, activeMatching = c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
 	assert.Equal(t, []int{0, 1}, activeMatching)
-	assert.True(t, valid)
 }
 
 func TestActiveSeries_ReloadSeriesMatchers_LessMatchers(t *testing.T) {
@@ -278,29 +257,23 @@ func TestActiveSeries_ReloadSeriesMatchers_LessMatchers(t *testing.T) {
 
 	currentTime := time.Now()
 	c := NewActiveSeries(asm, DefaultTimeout)
-	allActive, activeMatching, valid := c.Active(currentTime)
+	allActive, activeMatching := c.Active(currentTime)
 	assert.Equal(t, 0, allActive)
 	assert.Equal(t, []int{0, 0}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, currentTime, copyFn)
-	allActive, activeMatching, valid = c.Active(currentTime)
+	allActive, activeMatching = c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
 	assert.Equal(t, []int{1, 1}, activeMatching)
-	assert.True(t, valid)
 
 	asm = NewMatchers(mustNewCustomTrackersConfigFromMap(t, map[string]string{
 		"foo": `{a=~.+}`,
 	}))
 
-	c.ReloadMatchers(asm, currentTime)
-	c.purge(time.Time{})
-	// Adding timeout time to make Active results valid.
-	currentTime = currentTime.Add(DefaultTimeout)
-	allActive, activeMatching, valid = c.Active(currentTime)
-	assert.Equal(t, 0, allActive)
-	assert.Equal(t, []int{0}, activeMatching)
-	assert.True(t, valid)
+	c.ReloadMatchers(asm)
+	allActive, activeMatching = c.Active(currentTime)
+	assert.Equal(t, 1, allActive)
+	assert.Equal(t, []int{1}, activeMatching)
 }
 
 func TestActiveSeries_ReloadSeriesMatchers_SameSizeNewLabels(t *testing.T) {
@@ -314,31 +287,25 @@ func TestActiveSeries_ReloadSeriesMatchers_SameSizeNewLabels(t *testing.T) {
 	currentTime := time.Now()
 
 	c := NewActiveSeries(asm, DefaultTimeout)
-	allActive, activeMatching, valid := c.Active(currentTime)
+	allActive, activeMatching := c.Active(currentTime)
 	assert.Equal(t, 0, allActive)
 	assert.Equal(t, []int{0, 0}, activeMatching)
-	assert.True(t, valid)
 
 	c.UpdateSeries(ls1, currentTime, copyFn)
-	allActive, activeMatching, valid = c.Active(currentTime)
+	allActive, activeMatching = c.Active(currentTime)
 	assert.Equal(t, 1, allActive)
 	assert.Equal(t, []int{1, 1}, activeMatching)
-	assert.True(t, valid)
 
 	asm = NewMatchers(mustNewCustomTrackersConfigFromMap(t, map[string]string{
 		"foo": `{b=~.+}`,
-		"bar": `{b=~.+}`,
+		"bar": `{a=~.+}`,
 	}))
 
-	c.ReloadMatchers(asm, currentTime)
-	c.purge(time.Time{})
-	// Adding timeout time to make Active results valid.
-	currentTime = currentTime.Add(DefaultTimeout)
-
-	allActive, activeMatching, valid = c.Active(currentTime)
-	assert.Equal(t, 0, allActive)
-	assert.Equal(t, []int{0, 0}, activeMatching)
-	assert.True(t, valid)
+	// Matchers are sorted by name: bar, foo.
+	c.ReloadMatchers(asm)
+	allActive, activeMatching = c.Active(currentTime)
+	assert.Equal(t, 1, allActive)
+	assert.Equal(t, []int{1, 0}, activeMatching)
 }
 
 var activeSeriesTestGoroutines = []int{50, 100, 500}
@@ -462,21 +429,18 @@ func benchmarkPurge(b *testing.B, twice bool) {
 			}
 		}
 
-		allActive, _, valid := c.Active(currentTime)
+		allActive, _ := c.Active(currentTime)
 		assert.Equal(b, numSeries, allActive)
-		assert.True(b, valid)
 		b.StartTimer()
 
 		// Active is going to purge everything
 		currentTime = currentTime.Add(DefaultTimeout)
-		allActive, _, valid = c.Active(currentTime)
+		allActive, _ = c.Active(currentTime)
 		assert.Equal(b, numSeries-numExpiresSeries, allActive)
-		assert.True(b, valid)
 
 		if twice {
-			allActive, _, valid = c.Active(currentTime)
+			allActive, _ = c.Active(currentTime)
 			assert.Equal(b, numSeries-numExpiresSeries, allActive)
-			assert.True(b, valid)
 		}
 	}
 }
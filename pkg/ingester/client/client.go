@@ -14,6 +14,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/grafana/mimir/pkg/util/faultinjection"
 )
 
 //lint:ignore faillint It's non-trivial to remove this global variable.
@@ -38,7 +40,8 @@ type closableHealthAndIngesterClient struct {
 
 // MakeIngesterClient makes a new IngesterClient
 func MakeIngesterClient(addr string, cfg Config) (HealthAndIngesterClient, error) {
-	dialOpts, err := cfg.GRPCClientConfig.DialOption(grpcclient.Instrument(ingesterClientRequestDuration))
+	unary, stream := cfg.FaultInjection.Interceptors(grpcclient.Instrument(ingesterClientRequestDuration))
+	dialOpts, err := cfg.GRPCClientConfig.DialOption(unary, stream)
 	if err != nil {
 		return nil, err
 	}
@@ -59,14 +62,19 @@ func (c *closableHealthAndIngesterClient) Close() error {
 
 // Config is the configuration struct for the ingester client
 type Config struct {
-	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config" doc:"description=Configures the gRPC client used to communicate between distributors and ingesters."`
+	GRPCClientConfig grpcclient.Config     `yaml:"grpc_client_config" doc:"description=Configures the gRPC client used to communicate between distributors and ingesters."`
+	FaultInjection   faultinjection.Config `yaml:"fault_injection" doc:"hidden"`
 }
 
 // RegisterFlags registers configuration settings used by the ingester client config.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("ingester.client", f)
+	cfg.FaultInjection.RegisterFlagsWithPrefix("ingester.client.fault-injection", f)
 }
 
 func (cfg *Config) Validate(log log.Logger) error {
+	if err := cfg.FaultInjection.Validate(); err != nil {
+		return err
+	}
 	return cfg.GRPCClientConfig.Validate(log)
 }
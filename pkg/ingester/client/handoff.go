@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+
+	"github.com/weaveworks/common/httpgrpc"
+	"google.golang.org/grpc"
+)
+
+// HandoffHandleMethod is the full name of the gRPC method used by a leaving ingester to hand off its TSDBs.
+const HandoffHandleMethod = "/ingester.Handoff/Handle"
+
+// HandoffServer is the server API for the Handoff service, used by a leaving ingester to hand off its TSDBs
+// to its successor. The handoff requests are HTTP requests wrapped in httpgrpc messages. The service is only
+// exposed over gRPC, and not through the HTTP API.
+type HandoffServer interface {
+	Handoff(context.Context, *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error)
+}
+
+// HandoffClient is the client API for the Handoff service.
+type HandoffClient interface {
+	Handoff(ctx context.Context, in *httpgrpc.HTTPRequest, opts ...grpc.CallOption) (*httpgrpc.HTTPResponse, error)
+}
+
+type handoffClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewHandoffClient returns a HandoffClient sending the requests over the input connection.
+func NewHandoffClient(cc *grpc.ClientConn) HandoffClient {
+	return &handoffClient{cc: cc}
+}
+
+func (c *handoffClient) Handoff(ctx context.Context, in *httpgrpc.HTTPRequest, opts ...grpc.CallOption) (*httpgrpc.HTTPResponse, error) {
+	out := new(httpgrpc.HTTPResponse)
+	if err := c.cc.Invoke(ctx, HandoffHandleMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterHandoffServer registers the Handoff service to the input gRPC server.
+func RegisterHandoffServer(s *grpc.Server, srv HandoffServer) {
+	s.RegisterService(&handoffServiceDesc, srv)
+}
+
+func handoffHandleHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(httpgrpc.HTTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandoffServer).Handoff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandoffHandleMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandoffServer).Handoff(ctx, req.(*httpgrpc.HTTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var handoffServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ingester.Handoff",
+	HandlerType: (*HandoffServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Handle",
+			Handler:    handoffHandleHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/ingester/client/handoff.go",
+}
@@ -9,9 +9,14 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
 )
 
+var errTenantMarkedForDeletion = errors.New(globalerror.TenantMarkedForDeletion.Message("the write request has been rejected because the tenant has been marked for deletion"))
+
 type validationError struct {
 	err    error // underlying error
 	code   int
@@ -0,0 +1,685 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/middleware"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+const (
+	// handoffDirname is the directory, in the TSDB directory, where a successor stages the
+	// TSDBs received from a leaving ingester until the handoff is completed.
+	handoffDirname = "handoff"
+
+	// handoffPathPrefix is the path prefix of the handoff requests. The requests are HTTP requests
+	// wrapped in httpgrpc messages, sent to the gRPC Handoff service of the successor.
+	handoffPathPrefix = "/ingester/handoff/"
+
+	// handoffChunkSize is the max size of the file chunks sent in each handoff request.
+	handoffChunkSize = 4 * 1024 * 1024
+
+	// handoffIdleTimeout is the time after which a handoff not receiving any request is considered
+	// aborted, and the successor accepts a handoff from another leaving ingester.
+	handoffIdleTimeout = time.Minute
+)
+
+var (
+	errNoHandoffSuccessor = errors.New("no PENDING ingester in the same zone to hand off the TSDBs to")
+	errHandoffTooLarge    = errors.New("handoff too large")
+)
+
+// handoffClient sends the handoff requests to the successor.
+type handoffClient interface {
+	Handoff(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error)
+	Close() error
+}
+
+type grpcHandoffClient struct {
+	client client.HandoffClient
+	conn   *grpc.ClientConn
+}
+
+func (c *grpcHandoffClient) Handoff(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error) {
+	return c.client.Handoff(ctx, req)
+}
+
+func (c *grpcHandoffClient) Close() error {
+	return c.conn.Close()
+}
+
+// dialHandoffClient returns a handoffClient sending the requests to the ingester at the input address,
+// using the same gRPC client configuration of the distributors. The tenant ID in the context of the requests
+// is propagated like for the requests of the distributors.
+func (i *Ingester) dialHandoffClient(addr string) (handoffClient, error) {
+	dialOpts, err := i.cfg.IngesterClientConfig.GRPCClientConfig.DialOption([]grpc.UnaryClientInterceptor{middleware.ClientUserHeaderInterceptor}, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.Dial(addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcHandoffClient{client: client.NewHandoffClient(conn), conn: conn}, nil
+}
+
+// incomingHandoff is the state of the handoff received by a successor.
+type incomingHandoff struct {
+	fromInstanceID string
+	lastUpdate     time.Time
+	receivedBytes  int64
+}
+
+// TransferOut implements ring.FlushTransferer. When the handoff is enabled, the TSDBs are handed off to
+// a PENDING ingester in the same zone, which takes over the tokens of this ingester. On failure, the
+// lifecycler falls back to flushing the TSDB blocks, if configured to.
+func (i *Ingester) TransferOut(ctx context.Context) error {
+	if !i.cfg.HandoffEnabled || i.cfg.IngesterRing.isWitness() {
+		return ring.ErrTransferDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, i.cfg.HandoffTimeout)
+	defer cancel()
+
+	if err := i.handoffOut(ctx); err != nil {
+		i.metrics.handoffs.WithLabelValues("leaving", "failure").Inc()
+		return err
+	}
+
+	i.metrics.handoffs.WithLabelValues("leaving", "success").Inc()
+	return nil
+}
+
+func (i *Ingester) handoffOut(ctx context.Context) error {
+	successors, err := i.findHandoffSuccessors(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The begin and complete requests are not specific to a tenant, while the requests handing off the
+	// files of a tenant are sent with the tenant ID.
+	handoffCtx := user.InjectOrgID(ctx, "1") // fake: the gRPC server insists on having an org ID
+
+	// Multiple ingesters may be leaving at the same time, so we hand off to the first
+	// successor accepting the handoff.
+	var c handoffClient
+	for _, successor := range successors {
+		c, err = i.handoffClientFactory(successor.Addr)
+		if err == nil {
+			err = i.sendHandoffRequest(handoffCtx, c, "begin", nil, nil)
+			if err == nil {
+				level.Info(i.logger).Log("msg", "handing off TSDBs", "successor", successor.id, "addr", successor.Addr)
+				break
+			}
+			_ = c.Close()
+			c = nil
+		}
+		level.Warn(i.logger).Log("msg", "failed to begin TSDBs handoff", "successor", successor.id, "addr", successor.Addr, "err", err)
+	}
+	if c == nil {
+		return errors.Wrap(err, "begin TSDBs handoff")
+	}
+	defer c.Close()
+
+	// The TSDB heads are not compacted: they're handed off as they are on disk, and the successor replays
+	// them like on restart. This ingester is LEAVING, so it's not expected to receive samples anymore, while
+	// it keeps serving queries until the handoff is completed.
+	for _, userID := range i.getTSDBUsers() {
+		db := i.getTSDB(userID)
+		if db == nil {
+			continue
+		}
+
+		// The snapshot of the in-memory data is only loaded by the successor if enabled, and
+		// saves the replay of the write-ahead log up to the snapshot.
+		if i.cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown {
+			if _, err := db.db.Head().ChunkSnapshot(); err != nil {
+				return errors.Wrapf(err, "snapshot the TSDB head of user %s", userID)
+			}
+		}
+
+		files, err := i.handoffFiles(userID)
+		if err != nil {
+			return errors.Wrapf(err, "list TSDB files of user %s", userID)
+		}
+		userCtx := user.InjectOrgID(ctx, userID)
+		for _, file := range files {
+			if err := i.sendHandoffFile(userCtx, c, file); err != nil {
+				return errors.Wrapf(err, "hand off file %s", file)
+			}
+		}
+	}
+
+	if err := i.sendHandoffRequest(handoffCtx, c, "complete", nil, nil); err != nil {
+		return err
+	}
+
+	level.Info(i.logger).Log("msg", "completed TSDBs handoff")
+	return nil
+}
+
+type handoffSuccessor struct {
+	ring.InstanceDesc
+	id string
+}
+
+// findHandoffSuccessors returns the healthy PENDING ingesters in the same zone of this ingester, sorted by ID.
+func (i *Ingester) findHandoffSuccessors(ctx context.Context) ([]handoffSuccessor, error) {
+	d, err := i.lifecycler.KVStore.Get(ctx, i.lifecycler.RingKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "get the ring")
+	}
+
+	var (
+		ringDesc   = ring.GetOrCreateRingDesc(d)
+		now        = time.Now()
+		successors []handoffSuccessor
+	)
+	for id, instance := range ringDesc.Ingesters {
+		if id == i.lifecycler.ID || instance.State != ring.PENDING || instance.Zone != i.lifecycler.Zone {
+			continue
+		}
+		if !instance.IsHeartbeatHealthy(i.cfg.IngesterRing.HeartbeatTimeout, now) {
+			continue
+		}
+		successors = append(successors, handoffSuccessor{InstanceDesc: instance, id: id})
+	}
+
+	if len(successors) == 0 {
+		return nil, errNoHandoffSuccessor
+	}
+
+	sort.Slice(successors, func(a, b int) bool {
+		return successors[a].id < successors[b].id
+	})
+	return successors, nil
+}
+
+// handoffFiles returns the files of the TSDB of the user to hand off, relative to the TSDB directory: the
+// TSDB blocks, the shipper meta files, so that the successor ships the blocks not shipped yet, and the files
+// of the TSDB head. The files are sorted by name, so the write-ahead logs are handed off last.
+func (i *Ingester) handoffFiles(userID string) ([]string, error) {
+	dir := i.cfg.BlocksStorageConfig.TSDB.BlocksDir(userID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() {
+			if name == shipperMetaFilename || name == thanosShipperMetaFilename {
+				files = append(files, filepath.Join(userID, name))
+			}
+			continue
+		}
+
+		if !isHandoffTSDBDir(name) {
+			continue
+		}
+
+		err := filepath.Walk(filepath.Join(dir, name), func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.Join(userID, rel))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// isHandoffTSDBDir returns whether the input directory of a TSDB is handed off: the blocks, the write-ahead
+// log, the write-behind log of the out-of-order samples, the memory-mapped head chunks and the complete
+// snapshots of the in-memory data.
+func isHandoffTSDBDir(name string) bool {
+	switch name {
+	case "wal", "wbl", "chunks_head":
+		return true
+	}
+	if strings.HasPrefix(name, "chunk_snapshot.") {
+		return !strings.HasSuffix(name, ".tmp")
+	}
+	_, err := ulid.ParseStrict(name)
+	return err == nil
+}
+
+// sendHandoffFile sends the file, relative to the TSDB directory, in chunks of handoffChunkSize.
+func (i *Ingester) sendHandoffFile(ctx context.Context, c handoffClient, file string) error {
+	f, err := os.Open(filepath.Join(i.cfg.BlocksStorageConfig.TSDB.Dir, file))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, handoffChunkSize)
+	for offset := 0; ; {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+
+		// Send the first chunk even if the file is empty, so that the file is created.
+		if n > 0 || offset == 0 {
+			params := url.Values{}
+			params.Set("path", filepath.ToSlash(file))
+			params.Set("offset", strconv.Itoa(offset))
+			if err := i.sendHandoffRequest(ctx, c, "file", params, buf[:n]); err != nil {
+				return err
+			}
+			offset += n
+		}
+
+		if n < len(buf) {
+			return nil
+		}
+	}
+}
+
+func (i *Ingester) sendHandoffRequest(ctx context.Context, c handoffClient, action string, params url.Values, body []byte) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("instance_id", i.lifecycler.ID)
+
+	resp, err := c.Handoff(ctx, &httpgrpc.HTTPRequest{
+		Method: http.MethodPost,
+		Url:    handoffPathPrefix + action + "?" + params.Encode(),
+		Body:   body,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "handoff %s request failed", action)
+	}
+	if resp.Code/100 != 2 {
+		return fmt.Errorf("handoff %s request failed with status code %d: %s", action, resp.Code, strings.TrimSpace(string(resp.Body)))
+	}
+	return nil
+}
+
+// Handoff implements client.HandoffServer. It receives the TSDBs handed off by a leaving ingester, when this
+// ingester is PENDING. The handoff begins with a "begin" request, continues with a "file" request for each chunk
+// of the files to hand off, and ends with a "complete" request, after which this ingester opens the TSDBs and
+// takes over the tokens of the leaving ingester. The requests go through the gRPC server authentication like
+// the other requests to the ingesters, and the files are only accepted in the directory of the tenant of the
+// request. The requests are also only accepted from an ingester LEAVING the ring in the same zone, sent from
+// the address the ingester is registered with in the ring.
+func (i *Ingester) Handoff(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error) {
+	if !i.cfg.HandoffEnabled || i.cfg.IngesterRing.isWitness() {
+		return handoffResponse(http.StatusNotFound, "handoff is disabled"), nil
+	}
+
+	u, err := url.Parse(req.Url)
+	if err != nil {
+		return handoffResponse(http.StatusBadRequest, "invalid URL"), nil
+	}
+	params := u.Query()
+
+	fromInstanceID := params.Get("instance_id")
+	if fromInstanceID == "" {
+		return handoffResponse(http.StatusBadRequest, "missing instance_id"), nil
+	}
+
+	if err := i.checkHandoffSender(ctx, fromInstanceID); err != nil {
+		level.Warn(i.logger).Log("msg", "rejected TSDBs handoff request", "from", fromInstanceID, "path", u.Path, "err", err)
+		return handoffResponse(http.StatusForbidden, err.Error()), nil
+	}
+
+	switch action := path.Base(u.Path); action {
+	case "begin":
+		err = i.beginHandoff(fromInstanceID)
+	case "file":
+		var offset int64
+		offset, err = strconv.ParseInt(params.Get("offset"), 10, 64)
+		if err != nil {
+			return handoffResponse(http.StatusBadRequest, "invalid offset"), nil
+		}
+		file := params.Get("path")
+		if err := checkHandoffFileTenant(ctx, file); err != nil {
+			level.Warn(i.logger).Log("msg", "rejected TSDBs handoff request", "from", fromInstanceID, "path", u.Path, "err", err)
+			return handoffResponse(http.StatusForbidden, err.Error()), nil
+		}
+		err = i.receiveHandoffFile(fromInstanceID, file, offset, req.Body)
+	case "complete":
+		err = i.completeHandoff(ctx, fromInstanceID)
+	default:
+		return handoffResponse(http.StatusNotFound, fmt.Sprintf("unknown handoff action %q", action)), nil
+	}
+
+	if err != nil {
+		level.Warn(i.logger).Log("msg", "TSDBs handoff request failed", "from", fromInstanceID, "path", u.Path, "err", err)
+		code := http.StatusInternalServerError
+		if errors.Is(err, errHandoffTooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		return handoffResponse(code, err.Error()), nil
+	}
+	return handoffResponse(http.StatusNoContent, ""), nil
+}
+
+// checkHandoffFileTenant returns an error if the input file, relative to the TSDB directory, is not in the
+// directory of the tenant of the request.
+func checkHandoffFileTenant(ctx context.Context, file string) error {
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return err
+	}
+	if fileUserID, _, _ := strings.Cut(path.Clean(file), "/"); fileUserID != userID {
+		return fmt.Errorf("file %q is not in the directory of tenant %s", file, userID)
+	}
+	return nil
+}
+
+func handoffResponse(code int, msg string) *httpgrpc.HTTPResponse {
+	return &httpgrpc.HTTPResponse{Code: int32(code), Body: []byte(msg)}
+}
+
+// checkHandoffSender returns an error if the input instance is not an ingester LEAVING the ring in the same zone
+// of this ingester, or if the request in the context hasn't been sent from the address of the instance in the ring.
+func (i *Ingester) checkHandoffSender(ctx context.Context, fromInstanceID string) error {
+	d, err := i.lifecycler.KVStore.Get(ctx, i.lifecycler.RingKey)
+	if err != nil {
+		return errors.Wrap(err, "get the ring")
+	}
+
+	instance, ok := ring.GetOrCreateRingDesc(d).Ingesters[fromInstanceID]
+	if !ok {
+		return fmt.Errorf("ingester %s not found in the ring", fromInstanceID)
+	}
+	if instance.State != ring.LEAVING {
+		return fmt.Errorf("ingester %s is not LEAVING: %s", fromInstanceID, instance.State)
+	}
+	if instance.Zone != i.lifecycler.Zone {
+		return fmt.Errorf("ingester %s is in zone %q, while this ingester is in zone %q", fromInstanceID, instance.Zone, i.lifecycler.Zone)
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return errors.New("unknown request sender address")
+	}
+	return checkHandoffSenderAddr(ctx, p.Addr.String(), instance.Addr)
+}
+
+// checkHandoffSenderAddr returns an error if the host of the input sender address doesn't match the host of
+// the input ring address, which may be a hostname.
+func checkHandoffSenderAddr(ctx context.Context, senderAddr, ringAddr string) error {
+	senderHost, _, err := net.SplitHostPort(senderAddr)
+	if err != nil {
+		return errors.Wrapf(err, "invalid request sender address %s", senderAddr)
+	}
+	senderIP := net.ParseIP(senderHost)
+
+	ringHost, _, err := net.SplitHostPort(ringAddr)
+	if err != nil {
+		return errors.Wrapf(err, "invalid ring address %s", ringAddr)
+	}
+
+	ringIPs := []net.IP{net.ParseIP(ringHost)}
+	if ringIPs[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, ringHost)
+		if err != nil {
+			return errors.Wrapf(err, "resolve ring address %s", ringAddr)
+		}
+		ringIPs = ringIPs[:0]
+		for _, addr := range addrs {
+			ringIPs = append(ringIPs, addr.IP)
+		}
+	}
+
+	for _, ip := range ringIPs {
+		if ip.Equal(senderIP) {
+			return nil
+		}
+	}
+	return fmt.Errorf("request sent from %s, while the ingester is registered in the ring with address %s", senderAddr, ringAddr)
+}
+
+func (i *Ingester) handoffDir() string {
+	return filepath.Join(i.cfg.BlocksStorageConfig.TSDB.Dir, handoffDirname)
+}
+
+func (i *Ingester) beginHandoff(fromInstanceID string) error {
+	if s := i.State(); s != services.Running {
+		return fmt.Errorf("ingester is not running: %s", s)
+	}
+	if s := i.lifecycler.GetState(); s != ring.PENDING {
+		return fmt.Errorf("ingester is not PENDING: %s", s)
+	}
+
+	i.handoffMtx.Lock()
+	defer i.handoffMtx.Unlock()
+
+	if h := i.handoff; h != nil && h.fromInstanceID != fromInstanceID && time.Since(h.lastUpdate) < handoffIdleTimeout {
+		return fmt.Errorf("handoff from %s in progress", h.fromInstanceID)
+	}
+
+	// Discard any data staged by a previous handoff.
+	if err := os.RemoveAll(i.handoffDir()); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(i.handoffDir(), os.ModePerm); err != nil {
+		return err
+	}
+
+	i.handoff = &incomingHandoff{fromInstanceID: fromInstanceID, lastUpdate: time.Now()}
+	level.Info(i.logger).Log("msg", "began receiving TSDBs handoff", "from", fromInstanceID)
+	return nil
+}
+
+func (i *Ingester) receiveHandoffFile(fromInstanceID, file string, offset int64, data []byte) error {
+	i.handoffMtx.Lock()
+	defer i.handoffMtx.Unlock()
+
+	if err := i.checkHandoffInProgress(fromInstanceID); err != nil {
+		return err
+	}
+	i.handoff.lastUpdate = time.Now()
+
+	if len(data) > handoffChunkSize {
+		return errors.Wrapf(errHandoffTooLarge, "file chunk of %d bytes exceeds the max size of %d bytes", len(data), handoffChunkSize)
+	}
+	if i.handoff.receivedBytes+int64(len(data)) > i.cfg.HandoffMaxSizeBytes {
+		return errors.Wrapf(errHandoffTooLarge, "the handed off TSDBs exceed the max size of %d bytes", i.cfg.HandoffMaxSizeBytes)
+	}
+
+	// The file is expected in the directory of a user.
+	file = filepath.Clean(filepath.FromSlash(file))
+	if filepath.IsAbs(file) || file == "." || file == ".." || strings.HasPrefix(file, ".."+string(filepath.Separator)) || !strings.Contains(file, string(filepath.Separator)) {
+		return fmt.Errorf("invalid file path %q", file)
+	}
+
+	p := filepath.Join(i.handoffDir(), file)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(p, flags, 0o666)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		if info.Size() != offset {
+			_ = f.Close()
+			return fmt.Errorf("unexpected offset %d for file %s of size %d", offset, file, info.Size())
+		}
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	i.handoff.receivedBytes += int64(len(data))
+	return f.Close()
+}
+
+// completeHandoff opens the TSDBs of the users received from the leaving ingester, and takes over its tokens.
+func (i *Ingester) completeHandoff(ctx context.Context, fromInstanceID string) (returnErr error) {
+	i.handoffMtx.Lock()
+	defer i.handoffMtx.Unlock()
+
+	if err := i.checkHandoffInProgress(fromInstanceID); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := os.RemoveAll(i.handoffDir()); err != nil {
+			level.Warn(i.logger).Log("msg", "failed to remove the TSDBs handoff directory", "err", err)
+		}
+		i.handoff = nil
+
+		if returnErr == nil {
+			i.metrics.handoffs.WithLabelValues("successor", "success").Inc()
+		} else {
+			i.metrics.handoffs.WithLabelValues("successor", "failure").Inc()
+		}
+	}()
+
+	// This ingester may have joined the ring with its own tokens in the meanwhile.
+	if s := i.lifecycler.GetState(); s != ring.PENDING {
+		return fmt.Errorf("ingester is not PENDING: %s", s)
+	}
+
+	entries, err := os.ReadDir(i.handoffDir())
+	if err != nil {
+		return err
+	}
+
+	if err := i.openHandedOffTSDBs(entries); err != nil {
+		return err
+	}
+	i.updateUsageStats()
+
+	if err := i.lifecycler.ClaimTokensFor(ctx, fromInstanceID); err != nil {
+		return errors.Wrapf(err, "claim the tokens of %s", fromInstanceID)
+	}
+	if s := i.lifecycler.GetState(); s == ring.PENDING {
+		if err := i.lifecycler.ChangeState(ctx, ring.ACTIVE); err != nil {
+			return errors.Wrap(err, "switch to ACTIVE state")
+		}
+	}
+
+	level.Info(i.logger).Log("msg", "completed receiving TSDBs handoff", "from", fromInstanceID, "users", len(entries))
+	return nil
+}
+
+// openHandedOffTSDBs moves the TSDBs of the users received from the leaving ingester to the TSDB directory,
+// and opens them. Either all the TSDBs are moved, or none of them if any user already has a TSDB.
+func (i *Ingester) openHandedOffTSDBs(entries []os.DirEntry) error {
+	i.tsdbsMtx.Lock()
+	defer i.tsdbsMtx.Unlock()
+
+	for _, entry := range entries {
+		userID := entry.Name()
+		if _, ok := i.tsdbs[userID]; ok {
+			return fmt.Errorf("the TSDB of user %s is already open", userID)
+		}
+		if err := os.Remove(i.cfg.BlocksStorageConfig.TSDB.BlocksDir(userID)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "the TSDB directory of user %s already exists", userID)
+		}
+	}
+
+	for _, entry := range entries {
+		userID := entry.Name()
+		if err := os.Rename(filepath.Join(i.handoffDir(), userID), i.cfg.BlocksStorageConfig.TSDB.BlocksDir(userID)); err != nil {
+			return errors.Wrapf(err, "move the TSDB of user %s", userID)
+		}
+
+		db, err := i.createTSDB(userID, 0)
+		if err != nil {
+			return errors.Wrapf(err, "open the TSDB of user %s", userID)
+		}
+		i.tsdbs[userID] = db
+		i.metrics.memUsers.Inc()
+	}
+	return nil
+}
+
+func (i *Ingester) checkHandoffInProgress(fromInstanceID string) error {
+	if i.handoff == nil || i.handoff.fromInstanceID != fromInstanceID {
+		return fmt.Errorf("no handoff from %s in progress", fromInstanceID)
+	}
+	return nil
+}
+
+// registerUnlessHandoffExpected registers this ingester in the ring with new tokens, like the lifecycler does
+// once -ingester.handoff-timeout has elapsed, if no ingester in the same zone is LEAVING the ring. This way, a
+// new ingester only waits in the PENDING state for a handoff when there's an ingester which may hand off its
+// TSDBs to it. The ingester is not registered if it's already in the ring, if it restores its tokens from the
+// tokens file, or if its tokens must be observed before joining the ring.
+func (i *Ingester) registerUnlessHandoffExpected(ctx context.Context) error {
+	ringCfg := i.cfg.IngesterRing
+	if ringCfg.ObservePeriod > 0 {
+		return nil
+	}
+	if ringCfg.TokensFilePath != "" {
+		if tokens, err := ring.LoadTokensFromFile(ringCfg.TokensFilePath); err == nil && len(tokens) > 0 {
+			return nil
+		}
+	}
+
+	now := time.Now()
+	return i.lifecycler.KVStore.CAS(ctx, i.lifecycler.RingKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc := ring.GetOrCreateRingDesc(in)
+		if _, ok := ringDesc.Ingesters[i.lifecycler.ID]; ok {
+			return nil, false, nil
+		}
+
+		for id, instance := range ringDesc.Ingesters {
+			if instance.State == ring.LEAVING && instance.Zone == i.lifecycler.Zone && instance.IsHeartbeatHealthy(ringCfg.HeartbeatTimeout, now) {
+				level.Info(i.logger).Log("msg", "waiting for a TSDBs handoff, because an ingester in the same zone is leaving the ring", "leaving", id, "timeout", i.cfg.HandoffTimeout)
+				return nil, false, nil
+			}
+		}
+
+		_, takenTokens := ringDesc.TokensFor(i.lifecycler.ID)
+		tokens := ring.Tokens(ring.GenerateTokens(ringCfg.NumTokens, takenTokens))
+		sort.Sort(tokens)
+
+		level.Info(i.logger).Log("msg", "joining the ring without waiting for a TSDBs handoff, because no ingester in the same zone is leaving the ring")
+		ringDesc.AddIngester(i.lifecycler.ID, i.lifecycler.Addr, i.lifecycler.Zone, tokens, ring.ACTIVE, now)
+		return ringDesc, true, nil
+	})
+}
@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"math"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc/peer"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+func TestIngester_Handoff(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.HandoffTimeout = time.Minute
+	limits := defaultLimitsTestConfig()
+
+	// The leaving ingester joins the ring right away, and hands off its TSDBs once enabled below.
+	leavingCfg := cfg
+	leavingCfg.IngesterRing.InstanceID = "ingester-1"
+	leaving, err := prepareIngesterWithBlocksStorageAndLimits(t, leavingCfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), leaving))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), leaving)
+	})
+
+	test.Poll(t, time.Second, ring.ACTIVE, func() interface{} {
+		return leaving.lifecycler.GetState()
+	})
+
+	// Push samples compacted to a block, and samples kept in the TSDB head.
+	ctx := user.InjectOrgID(context.Background(), userID)
+	for ts := int64(0); ts < 20; ts++ {
+		if ts == 10 {
+			leaving.compactBlocks(context.Background(), true, nil)
+		}
+
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), float64(ts), ts)
+		_, err := leaving.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	// A new ingester only waits for a handoff if an ingester in the same zone is leaving.
+	require.NoError(t, leaving.lifecycler.ChangeState(context.Background(), ring.LEAVING))
+
+	successorCfg := cfg
+	successorCfg.HandoffEnabled = true
+	successorCfg.IngesterRing.InstanceID = "ingester-2"
+	successor, err := prepareIngesterWithBlocksStorageAndLimits(t, successorCfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), successor))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), successor)
+	})
+
+	// The successor waits for the handoff in the PENDING state.
+	test.Poll(t, time.Second, ring.PENDING, func() interface{} {
+		return successor.lifecycler.GetState()
+	})
+
+	leavingTokens := ringTokens(t, cfg, "ingester-1")
+	require.Len(t, leavingTokens, 1)
+
+	leaving.cfg.HandoffEnabled = true
+	leaving.handoffClientFactory = func(string) (handoffClient, error) {
+		return newTestHandoffClient(successor, "127.0.0.1:12345"), nil
+	}
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), leaving))
+
+	// The successor has taken over the tokens and the data of the leaving ingester.
+	assert.Equal(t, ring.ACTIVE, successor.lifecycler.GetState())
+	assert.Equal(t, leavingTokens, ringTokens(t, cfg, "ingester-2"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(successor.metrics.handoffs.WithLabelValues("successor", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(leaving.metrics.handoffs.WithLabelValues("leaving", "success")))
+
+	db := successor.getTSDB(userID)
+	require.NotNil(t, db)
+	assert.Len(t, db.db.Blocks(), 1)
+	assert.Equal(t, uint64(1), db.Head().NumSeries())
+	assert.NoDirExists(t, successor.handoffDir())
+
+	q, err := db.Querier(ctx, math.MinInt64, math.MaxInt64)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = q.Close() })
+
+	set := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test"))
+	require.True(t, set.Next())
+	samples := 0
+	it := set.At().Iterator(nil)
+	for it.Next() != 0 {
+		samples++
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, 20, samples)
+	assert.False(t, set.Next())
+	require.NoError(t, set.Err())
+
+	// The successor ships the handed off blocks.
+	successor.shipBlocks(context.Background(), nil)
+	assert.Equal(t, float64(0), successor.getOldestUnshippedBlockMetric())
+}
+
+func TestIngester_HandoffShouldNotDelayJoiningTheRingIfNoIngesterIsLeaving(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.HandoffEnabled = true
+	cfg.HandoffTimeout = time.Hour
+
+	ing, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), ing)
+	})
+
+	assert.Equal(t, ring.ACTIVE, ing.lifecycler.GetState())
+	assert.Len(t, ringTokens(t, cfg, cfg.IngesterRing.InstanceID), cfg.IngesterRing.NumTokens)
+}
+
+func TestIngester_TransferOut(t *testing.T) {
+	t.Run("should return ErrTransferDisabled if the handoff is disabled", func(t *testing.T) {
+		ing, err := prepareIngesterWithBlocksStorage(t, defaultIngesterTestConfig(t), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, ring.ErrTransferDisabled, ing.TransferOut(context.Background()))
+	})
+
+	t.Run("should fail if there's no PENDING ingester in the same zone", func(t *testing.T) {
+		cfg := defaultIngesterTestConfig(t)
+		cfg.HandoffEnabled = true
+		cfg.HandoffTimeout = time.Second
+
+		ing, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, ing.TransferOut(context.Background()), errNoHandoffSuccessor)
+		assert.Equal(t, float64(1), testutil.ToFloat64(ing.metrics.handoffs.WithLabelValues("leaving", "failure")))
+	})
+}
+
+func TestIngester_HandoffServer(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.HandoffEnabled = true
+	cfg.HandoffTimeout = time.Minute
+	cfg.HandoffMaxSizeBytes = 10
+
+	// A leaving ingester in the ring keeps this ingester in the PENDING state.
+	addRingInstance(t, cfg, "ingester-1", "127.0.0.1:9095", ring.LEAVING)
+	addRingInstance(t, cfg, "ingester-2", "127.0.0.1:9095", ring.LEAVING)
+	addRingInstance(t, cfg, "ingester-3", "127.0.0.1:9095", ring.ACTIVE)
+
+	ing, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	t.Cleanup(func() {
+		_ = services.StopAndAwaitTerminated(context.Background(), ing)
+	})
+
+	sendWithContext := func(ctx context.Context, senderAddr, fromInstanceID, action string, params map[string]string) error {
+		other := &Ingester{lifecycler: &ring.Lifecycler{ID: fromInstanceID}}
+		values := map[string][]string{}
+		for k, v := range params {
+			values[k] = []string{v}
+		}
+		return other.sendHandoffRequest(ctx, newTestHandoffClient(ing, senderAddr), action, values, []byte("data"))
+	}
+	sendFrom := func(senderAddr, fromInstanceID, action string, params map[string]string) error {
+		return sendWithContext(user.InjectOrgID(context.Background(), "user-1"), senderAddr, fromInstanceID, action, params)
+	}
+	send := func(fromInstanceID, action string, params map[string]string) error {
+		return sendFrom("127.0.0.1:12345", fromInstanceID, action, params)
+	}
+
+	// The handoff is only accepted from a LEAVING ingester, sending the requests from its address in the ring.
+	require.ErrorContains(t, send("ingester-unknown", "begin", nil), "not found in the ring")
+	require.ErrorContains(t, send("ingester-3", "begin", nil), "is not LEAVING")
+	require.ErrorContains(t, sendFrom("127.0.0.2:12345", "ingester-1", "begin", nil), "registered in the ring with address")
+
+	require.NoError(t, send("ingester-1", "begin", nil))
+
+	// Another leaving ingester can't hand off while a handoff is in progress.
+	require.Error(t, send("ingester-2", "begin", nil))
+	require.Error(t, send("ingester-2", "file", map[string]string{"path": "user-1/file", "offset": "0"}))
+
+	for _, invalidPath := range []string{"file", "../file", "/user-1/file", "user-1/../../file"} {
+		require.Error(t, send("ingester-1", "file", map[string]string{"path": invalidPath, "offset": "0"}), invalidPath)
+	}
+
+	// The files are only accepted in the directory of the tenant of the request.
+	require.ErrorContains(t, sendWithContext(user.InjectOrgID(context.Background(), "user-2"), "127.0.0.1:12345", "ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "0"}), "status code 403")
+	require.ErrorContains(t, sendWithContext(context.Background(), "127.0.0.1:12345", "ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "0"}), "status code 403")
+
+	require.NoError(t, send("ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "0"}))
+	require.NoError(t, send("ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "4"}))
+	require.Error(t, send("ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "4"}), "unexpected offset")
+	assert.FileExists(t, filepath.Join(ing.handoffDir(), "user-1", "file"))
+
+	// The handed off TSDBs can't exceed the max size.
+	require.ErrorContains(t, send("ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "8"}), "status code 413")
+
+	// Beginning a new handoff discards the data previously received.
+	require.NoError(t, send("ingester-1", "begin", nil))
+	assert.NoFileExists(t, filepath.Join(ing.handoffDir(), "user-1", "file"))
+	require.NoError(t, send("ingester-1", "file", map[string]string{"path": "user-1/file", "offset": "0"}))
+}
+
+func TestCheckHandoffSenderAddr(t *testing.T) {
+	ctx := context.Background()
+
+	require.NoError(t, checkHandoffSenderAddr(ctx, "10.0.0.1:12345", "10.0.0.1:9095"))
+	require.NoError(t, checkHandoffSenderAddr(ctx, "127.0.0.1:12345", "localhost:9095"))
+	require.NoError(t, checkHandoffSenderAddr(ctx, "[::1]:12345", "[::1]:9095"))
+	require.Error(t, checkHandoffSenderAddr(ctx, "10.0.0.2:12345", "10.0.0.1:9095"))
+	require.Error(t, checkHandoffSenderAddr(ctx, "10.0.0.1", "10.0.0.1:9095"))
+}
+
+func ringTokens(t *testing.T, cfg Config, instanceID string) []uint32 {
+	d, err := cfg.IngesterRing.KVStore.Mock.Get(context.Background(), IngesterRingKey)
+	require.NoError(t, err)
+	return ring.GetOrCreateRingDesc(d).Ingesters[instanceID].Tokens
+}
+
+func addRingInstance(t *testing.T, cfg Config, instanceID, addr string, state ring.InstanceState) {
+	require.NoError(t, cfg.IngesterRing.KVStore.Mock.CAS(context.Background(), IngesterRingKey, func(in interface{}) (interface{}, bool, error) {
+		desc := ring.GetOrCreateRingDesc(in)
+		desc.AddIngester(instanceID, addr, "", ring.GenerateTokens(1, nil), state, time.Now())
+		return desc, true, nil
+	}))
+}
+
+// testHandoffClient sends the handoff requests to the server, as if they've been sent from the sender address.
+type testHandoffClient struct {
+	server     client.HandoffServer
+	senderAddr net.Addr
+}
+
+func newTestHandoffClient(server client.HandoffServer, senderAddr string) *testHandoffClient {
+	addr, err := net.ResolveTCPAddr("tcp", senderAddr)
+	if err != nil {
+		panic(err)
+	}
+	return &testHandoffClient{server: server, senderAddr: addr}
+}
+
+func (c *testHandoffClient) Handoff(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error) {
+	return c.server.Handoff(peer.NewContext(ctx, &peer.Peer{Addr: c.senderAddr}), req)
+}
+
+func (c *testHandoffClient) Close() error {
+	return nil
+}
@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/ingestaggregation"
+)
+
+const (
+	// How frequently the ingest aggregation rules are evaluated. The aggregated series are computed
+	// only when the interval of the rule has elapsed, so this is the max delay of the computation.
+	ingestAggregationEvaluationPeriod = 5 * time.Second
+
+	// The series rolled up by an ingest aggregation rule are no longer part of the aggregated series
+	// if they haven't received any sample for this period, like in the PromQL lookback delta.
+	ingestAggregationStalenessPeriod = 5 * time.Minute
+)
+
+// ingestAggregator keeps track of the increases of the series rolled up by the tenant's ingest
+// aggregation rules, in order to periodically compute the aggregated series. The state is in-memory
+// only, so it's lost when the ingester restarts: the aggregated series restart from zero, which is
+// handled like a counter reset by the PromQL functions like rate() and increase().
+type ingestAggregator struct {
+	mtx   sync.Mutex
+	rules map[string]*ingestAggregationRuleState
+}
+
+// ingestAggregationRuleState is the state of a single ingest aggregation rule.
+type ingestAggregationRuleState struct {
+	interval time.Duration
+
+	// Timestamp (in milliseconds) of the last time the aggregated series have been computed.
+	lastEvaluation int64
+
+	// Aggregated series, keyed by the hash of their labels.
+	outputs map[uint64]*ingestAggregatedSeries
+}
+
+type ingestAggregatedSeries struct {
+	labels labels.Labels
+
+	// Sum of the increases of the series rolled up into the aggregated series, since it has been created.
+	total float64
+
+	// Latest sample of each series rolled up into the aggregated series, keyed by the hash of their labels.
+	inputs map[uint64]mimirpb.Sample
+}
+
+type ingestAggregatedSample struct {
+	labels    labels.Labels
+	timestamp int64
+	value     float64
+}
+
+func newIngestAggregator() *ingestAggregator {
+	return &ingestAggregator{
+		rules: map[string]*ingestAggregationRuleState{},
+	}
+}
+
+// add tracks the samples of a series rolled up by the rule, adding their increases to the aggregated series.
+// The samples are expected to be sorted by timestamp. The input labels are not retained.
+func (a *ingestAggregator) add(rule *ingestaggregation.Rule, series []mimirpb.LabelAdapter, samples []mimirpb.Sample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	outputLabels := rule.OutputLabels(series)
+	outputHash := mimirpb.FromLabelAdaptersToLabels(outputLabels).Hash()
+	inputHash := mimirpb.FromLabelAdaptersToLabels(series).Hash()
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	state, ok := a.rules[rule.Key()]
+	if !ok {
+		state = &ingestAggregationRuleState{
+			interval: rule.Interval(),
+			outputs:  map[uint64]*ingestAggregatedSeries{},
+		}
+		a.rules[rule.Key()] = state
+	}
+
+	output, ok := state.outputs[outputHash]
+	if !ok {
+		// Copy the labels because the input ones are backed by the request buffer.
+		output = &ingestAggregatedSeries{
+			labels: mimirpb.FromLabelAdaptersToLabelsWithCopy(outputLabels),
+			inputs: map[uint64]mimirpb.Sample{},
+		}
+		state.outputs[outputHash] = output
+	}
+
+	prev, ok := output.inputs[inputHash]
+	for _, s := range samples {
+		// The first sample of a series is the baseline of its increases, because the series may have been
+		// rolled up before this ingester has started tracking it. Samples older than the latest one are ignored.
+		if !ok {
+			prev, ok = s, true
+			continue
+		}
+		if s.TimestampMs <= prev.TimestampMs {
+			continue
+		}
+
+		// Like in PromQL, a value lower than the previous one is a counter reset.
+		if s.Value >= prev.Value {
+			output.total += s.Value - prev.Value
+		} else {
+			output.total += s.Value
+		}
+		prev = s
+	}
+	output.inputs[inputHash] = prev
+}
+
+// evaluate computes the aggregated series of the rules whose interval has elapsed since their last
+// evaluation. Each aggregated sample is the sum of the increases of the series rolled up into it, so the
+// aggregated series is a counter which doesn't go down when a series is reset or stops being rolled up.
+// The timestamp of the aggregated samples is aligned to the interval of the rule.
+func (a *ingestAggregator) evaluate(now time.Time) []ingestAggregatedSample {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var result []ingestAggregatedSample
+	for key, state := range a.rules {
+		ts := now.Truncate(state.interval).UnixMilli()
+		if ts <= state.lastEvaluation {
+			continue
+		}
+		state.lastEvaluation = ts
+
+		staleDeadline := ts - ingestAggregationStalenessPeriod.Milliseconds()
+		for outputHash, output := range state.outputs {
+			// The increases of the stale series are kept in the aggregated series.
+			for inputHash, s := range output.inputs {
+				if s.TimestampMs <= staleDeadline {
+					delete(output.inputs, inputHash)
+				}
+			}
+
+			if len(output.inputs) == 0 {
+				delete(state.outputs, outputHash)
+				continue
+			}
+
+			result = append(result, ingestAggregatedSample{labels: output.labels, timestamp: ts, value: output.total})
+		}
+
+		if len(state.outputs) == 0 {
+			delete(a.rules, key)
+		}
+	}
+
+	return result
+}
+
+// aggregateSeries feeds the series rolled up by the tenant's ingest aggregation rules to the aggregator.
+// The input series are sent by the distributors to the ingesters owning the aggregated series, and are
+// not ingested. Series with native histograms are not aggregated.
+func (i *Ingester) aggregateSeries(userID string, db *userTSDB, timeseries []mimirpb.PreallocTimeseries) {
+	rules, err := i.ingestAggregationRules.Get(userID, i.limits.IngestAggregationRules(userID))
+	if err != nil {
+		level.Warn(i.logger).Log("msg", "failed to compile ingest aggregation rules", "user", userID, "err", err)
+		return
+	}
+
+	for _, ts := range timeseries {
+		// The rule may not match anymore if the rules have changed since the series has been sent.
+		rule := ingestaggregation.Match(rules, ts.Labels)
+		if rule == nil || len(ts.Histograms) > 0 {
+			continue
+		}
+
+		db.ingestAggregator.add(rule, ts.Labels, ts.Samples)
+		i.metrics.ingestAggregationInputSamples.Add(float64(len(ts.Samples)))
+	}
+}
+
+// evaluateIngestAggregations appends the aggregated series computed by the tenants' ingest aggregation rules.
+func (i *Ingester) evaluateIngestAggregations(now time.Time) {
+	for _, userID := range i.getTSDBUsers() {
+		userDB := i.getTSDB(userID)
+		if userDB == nil {
+			continue
+		}
+
+		samples := userDB.ingestAggregator.evaluate(now)
+		if len(samples) == 0 {
+			continue
+		}
+
+		if err := i.appendIngestAggregatedSamples(userDB, samples); err != nil {
+			level.Warn(i.logger).Log("msg", "failed to append ingest aggregated samples", "user", userID, "err", err)
+		}
+	}
+}
+
+func (i *Ingester) appendIngestAggregatedSamples(db *userTSDB, samples []ingestAggregatedSample) error {
+	if err := db.acquireAppendLock(); err != nil {
+		return err
+	}
+	defer db.releaseAppendLock()
+
+	var app storage.Appender = db.Appender(context.Background())
+	if i.sharedWAL != nil {
+		app = newSharedWALAppender(app.(extendedAppender), i.sharedWAL, db, mimirpb.API)
+	}
+
+	appended := 0
+	for _, s := range samples {
+		// The aggregated samples are best-effort: a sample failing to be appended (e.g. because the tenant
+		// reached the series limit) doesn't prevent the others from being appended.
+		if _, err := app.Append(0, s.labels, s.timestamp, s.value); err != nil {
+			level.Debug(i.logger).Log("msg", "failed to append ingest aggregated sample", "user", db.userID, "series", s.labels.String(), "err", err)
+			continue
+		}
+		appended++
+	}
+
+	if err := app.Commit(); err != nil {
+		return err
+	}
+
+	i.metrics.ingestAggregationOutputSamples.Add(float64(appended))
+	if appended > 0 {
+		db.setLastUpdate(time.Now())
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/ingestaggregation"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestIngestAggregator(t *testing.T) {
+	rules, err := ingestaggregation.Compile([]validation.IngestAggregationRule{
+		{Selector: `{job="api"}`, Without: []string{"pod"}, Interval: model.Duration(time.Minute)},
+	})
+	require.NoError(t, err)
+	rule := rules[0]
+
+	series := func(pod string) []mimirpb.LabelAdapter {
+		return mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api", "pod", pod))
+	}
+	output := labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api")
+
+	a := newIngestAggregator()
+	a.add(rule, series("api-1"), []mimirpb.Sample{{TimestampMs: 10000, Value: 1}, {TimestampMs: 20000, Value: 3}})
+	a.add(rule, series("api-2"), []mimirpb.Sample{{TimestampMs: 15000, Value: 5}})
+
+	// The aggregated sample is the sum of the increases, aligned to the interval. The first sample of
+	// each series is the baseline of its increases.
+	assert.Equal(t, []ingestAggregatedSample{{labels: output, timestamp: 60000, value: 2}}, a.evaluate(time.UnixMilli(70000)))
+
+	// The rule is not evaluated again until the interval has elapsed.
+	assert.Empty(t, a.evaluate(time.UnixMilli(110000)))
+
+	// Older samples are ignored, and a lower value is a counter reset.
+	a.add(rule, series("api-1"), []mimirpb.Sample{{TimestampMs: 5000, Value: 100}})
+	a.add(rule, series("api-2"), []mimirpb.Sample{{TimestampMs: 90000, Value: 9}, {TimestampMs: 100000, Value: 2}})
+	assert.Equal(t, []ingestAggregatedSample{{labels: output, timestamp: 120000, value: 8}}, a.evaluate(time.UnixMilli(120000)))
+
+	// The increases of the stale series are kept, so the aggregated series doesn't go down.
+	assert.Equal(t, []ingestAggregatedSample{{labels: output, timestamp: 360000, value: 8}}, a.evaluate(time.UnixMilli(360000)))
+
+	// Once all series are stale, the rule state is removed.
+	assert.Empty(t, a.evaluate(time.UnixMilli(420000)))
+	assert.Empty(t, a.rules)
+}
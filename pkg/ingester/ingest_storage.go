@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// ingestStorageConsumer ingests the write requests consumed from the partition of the ingest storage. The write
+// requests failing with a server error, like the ones refused by the instance limits, are consumed again.
+type ingestStorageConsumer struct {
+	ingester *Ingester
+}
+
+func (c ingestStorageConsumer) Consume(ctx context.Context, userID string, req *mimirpb.WriteRequest) error {
+	if c.ingester.isTenantMarkedForDeletion(userID) {
+		return httpgrpc.Errorf(http.StatusBadRequest, wrapWithUser(errTenantMarkedForDeletion, userID).Error())
+	}
+
+	_, err := c.ingester.pushWriteRequest(user.InjectOrgID(ctx, userID), userID, req)
+
+	// The samples rejected by the validation, like the out of bounds samples, are tracked by the discarded
+	// samples metrics, and the rest of the request has been ingested.
+	if resp, ok := httpgrpc.HTTPResponseFromError(err); ok && resp.Code/100 == http.StatusBadRequest/100 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestStorageConsumer(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	series := labels.FromStrings(labels.MetricName, "test")
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i)) })
+
+	consumer := ingestStorageConsumer{ingester: i}
+	for ts := int64(1); ts <= 2; ts++ {
+		req, _, _, _ := mockWriteRequest(t, series, float64(ts), ts*1000)
+		require.NoError(t, consumer.Consume(context.Background(), "user-1", req))
+	}
+
+	// The out-of-order sample is discarded, and not reported as a failure to consume the record.
+	req, _, _, _ := mockWriteRequest(t, series, 100, 500)
+	require.NoError(t, consumer.Consume(context.Background(), "user-1", req))
+
+	assert.Equal(t, map[string][]float64{series.String(): {1, 2}}, querySharedWALTestTSDB(t, i, "user-1"))
+}
+
+func TestIngester_IngestStorageRequiresSequenceNumberInInstanceID(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngestStorageConfig.Enabled = true
+	cfg.IngestStorageConfig.KafkaConfig.Address = "localhost:9092"
+	cfg.IngestStorageConfig.KafkaConfig.Topic = "test"
+
+	cfg.IngesterRing.InstanceID = "ingester"
+	_, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.Error(t, err)
+
+	cfg.IngesterRing.InstanceID = "ingester-zone-a-1"
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, i.ingestReader)
+}
@@ -14,6 +14,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -41,6 +42,7 @@ import (
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/hashcache"
+	"github.com/segmentio/fasthash/fnv1a"
 	"github.com/thanos-io/objstore"
 	"github.com/weaveworks/common/httpgrpc"
 	"go.uber.org/atomic"
@@ -54,12 +56,14 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/chunk"
+	"github.com/grafana/mimir/pkg/storage/ingest"
 	"github.com/grafana/mimir/pkg/storage/sharding"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/ingestaggregation"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/push"
@@ -67,10 +71,13 @@ import (
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
-const (
-	// Number of timeseries to return in each batch of a QueryStream.
-	queryStreamBatchSize = 128
+var (
+	errInvalidQueryStreamBatchSize = errors.New("invalid query stream batch size, the value must be greater than 0")
+	errInvalidHandoffTimeout       = errors.New("invalid handoff timeout, the value must be greater than 0 when the handoff is enabled")
+	errInvalidHandoffMaxSize       = errors.New("invalid handoff max size, the value must be greater than 0 when the handoff is enabled")
+)
 
+const (
 	// Discarded Metadata metric labels.
 	perUserMetadataLimit   = "per_user_metadata_limit"
 	perMetricMetadataLimit = "per_metric_metadata_limit"
@@ -98,6 +105,7 @@ const (
 	sampleOutOfBounds    = "sample-out-of-bounds"
 	perUserSeriesLimit   = "per_user_series_limit"
 	perMetricSeriesLimit = "per_metric_series_limit"
+	sampleTooFrequent    = "sample-too-frequent"
 
 	replicationFactorStatsName             = "ingester_replication_factor"
 	ringStoreStatsName                     = "ingester_ring_store"
@@ -153,11 +161,23 @@ type Config struct {
 	StreamChunksWhenUsingBlocks bool                           `yaml:"-" category:"advanced"`
 	// Runtime-override for type of streaming query to use (chunks or samples).
 	StreamTypeFn func() QueryStreamType `yaml:"-"`
+	// Max number of series to return in each batch of a QueryStream.
+	QueryStreamBatchSize int `yaml:"query_stream_batch_size" category:"experimental"`
 
 	DefaultLimits    InstanceLimits         `yaml:"instance_limits"`
 	InstanceLimitsFn func() *InstanceLimits `yaml:"-"`
 
 	IgnoreSeriesLimitForMetricNames string `yaml:"ignore_series_limit_for_metric_names" category:"advanced"`
+
+	HandoffEnabled      bool          `yaml:"handoff_enabled" category:"experimental"`
+	HandoffTimeout      time.Duration `yaml:"handoff_timeout" category:"experimental"`
+	HandoffMaxSizeBytes int64         `yaml:"handoff_max_size_bytes" category:"experimental"`
+
+	// Injected internally, used to hand off the TSDB blocks to the successor.
+	IngesterClientConfig client.Config `yaml:"-"`
+
+	// This config is dynamically injected because it's shared with the distributors.
+	IngestStorageConfig ingest.Config `yaml:"-"`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet
@@ -172,14 +192,30 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.ActiveSeriesMetricsIdleTimeout, "ingester.active-series-metrics-idle-timeout", 10*time.Minute, "After what time a series is considered to be inactive.")
 
 	f.BoolVar(&cfg.StreamChunksWhenUsingBlocks, "ingester.stream-chunks-when-using-blocks", true, "Stream chunks from ingesters to queriers.")
+	f.IntVar(&cfg.QueryStreamBatchSize, "ingester.query-stream-batch-size", 128, "Max number of series streamed from ingesters to queriers in each message. Each message is also limited to about 1MiB. Lower values reduce the memory used by ingesters to build each message, while queriers still receive all the series of a query before evaluating it. The batch size must be greater than 0.")
 	f.DurationVar(&cfg.TSDBConfigUpdatePeriod, "ingester.tsdb-config-update-period", 15*time.Second, "Period with which to update the per-tenant TSDB configuration.")
 
 	cfg.DefaultLimits.RegisterFlags(f)
 
 	f.StringVar(&cfg.IgnoreSeriesLimitForMetricNames, "ingester.ignore-series-limit-for-metric-names", "", "Comma-separated list of metric names, for which the -ingester.max-global-series-per-metric limit will be ignored. Does not affect the -ingester.max-global-series-per-user limit.")
+
+	f.BoolVar(&cfg.HandoffEnabled, "ingester.handoff-enabled", false, "When enabled, an ingester leaving the ring hands off its TSDBs, including the TSDB heads, to a new ingester in the PENDING state in the same zone, which takes over its tokens and starts with its data. The handoff is sent over the gRPC server with the tenant ID of the data handed off, like the requests of the distributors, and is only accepted from the address of an ingester leaving the ring in the same zone. A new ingester starting while an ingester in the same zone is leaving waits in the PENDING state for a handoff up to -ingester.handoff-timeout before joining the ring with its own tokens.")
+	f.DurationVar(&cfg.HandoffTimeout, "ingester.handoff-timeout", time.Minute, "Maximum time a new ingester waits for a handoff before joining the ring, and maximum time a leaving ingester spends handing off its TSDBs before falling back to the regular shutdown.")
+	f.Int64Var(&cfg.HandoffMaxSizeBytes, "ingester.handoff-max-size-bytes", 100<<30, "Maximum total size of the TSDBs a new ingester receives in a handoff. The handoff fails if the TSDBs of the leaving ingester are larger.")
 }
 
 func (cfg *Config) Validate(logger log.Logger) error {
+	if cfg.QueryStreamBatchSize <= 0 {
+		return errInvalidQueryStreamBatchSize
+	}
+
+	if cfg.HandoffEnabled && cfg.HandoffTimeout <= 0 {
+		return errInvalidHandoffTimeout
+	}
+	if cfg.HandoffEnabled && cfg.HandoffMaxSizeBytes <= 0 {
+		return errInvalidHandoffMaxSize
+	}
+
 	return cfg.IngesterRing.Validate(logger)
 }
 
@@ -223,11 +259,38 @@ type Ingester struct {
 	tsdbsMtx sync.RWMutex
 	tsdbs    map[string]*userTSDB // tsdb sharded by userID
 
+	// Tenants whose deletion mark has been found in the storage, by the time the mark has been found.
+	// Writes for these tenants are rejected.
+	tenantsMarkedForDeletionMtx sync.RWMutex
+	tenantsMarkedForDeletion    map[string]time.Time
+
 	bucket objstore.Bucket
 
 	// Value used by shipper as external label.
 	shipperIngesterID string
 
+	// Write-ahead log storing the pushed series when running in a witness zone. When set,
+	// the ingester doesn't create any TSDB.
+	witness        *witnessWAL
+	witnessMetrics *witnessMetrics
+
+	// Write-ahead log shared by all tenants, written in addition to the write-ahead log of each TSDB when set.
+	sharedWAL        *sharedWAL
+	sharedWALMetrics *sharedWALMetrics
+
+	// Compiled ingest aggregation rules of each tenant.
+	ingestAggregationRules *ingestaggregation.RulesCache
+
+	// Handoff of the TSDB blocks received from a leaving ingester, when this ingester is PENDING.
+	handoffMtx sync.Mutex
+	handoff    *incomingHandoff
+
+	// Creates the client used to hand off the TSDB blocks to the successor, when this ingester is leaving.
+	handoffClientFactory func(addr string) (handoffClient, error)
+
+	// Reader of the partition of the ingest storage consumed by the ingester, if enabled.
+	ingestReader *ingest.PartitionReader
+
 	subservices  *services.Manager
 	activeGroups *util.ActiveGroupsCleanupService
 
@@ -277,11 +340,18 @@ func newIngester(cfg Config, limits *validation.Overrides, registerer prometheus
 	usagestats.GetInt(replicationFactorStatsName).Set(int64(cfg.IngesterRing.ReplicationFactor))
 	usagestats.GetString(ringStoreStatsName).Set(cfg.IngesterRing.KVStore.Store)
 
+	var sharedWALMetrics *sharedWALMetrics
+	if cfg.BlocksStorageConfig.TSDB.SharedWALEnabled {
+		sharedWALMetrics = newSharedWALMetrics(registerer)
+	}
+
 	return &Ingester{
 		cfg:    cfg,
 		limits: limits,
 		logger: logger,
 
+		sharedWALMetrics: sharedWALMetrics,
+
 		tsdbs:               make(map[string]*userTSDB),
 		usersMetadata:       make(map[string]*userMetricsMetadata),
 		bucket:              bucketClient,
@@ -290,6 +360,9 @@ func newIngester(cfg Config, limits *validation.Overrides, registerer prometheus
 		shipTrigger:         make(chan requestWithUsersAndCallback),
 		seriesHashCache:     hashcache.NewSeriesHashCache(cfg.BlocksStorageConfig.TSDB.SeriesHashCacheMaxBytes),
 
+		tenantsMarkedForDeletion: make(map[string]time.Time),
+		ingestAggregationRules:   ingestaggregation.NewRulesCache(),
+
 		memorySeriesStats:                  usagestats.GetAndResetInt(memorySeriesStatsName),
 		memoryTenantsStats:                 usagestats.GetAndResetInt(memoryTenantsStatsName),
 		appendedSamplesStats:               usagestats.GetAndResetCounter(appendedSamplesStatsName),
@@ -317,13 +390,35 @@ func New(cfg Config, limits *validation.Overrides, activeGroupsCleanupService *u
 		}, i.getOldestUnshippedBlockMetric)
 	}
 
-	i.lifecycler, err = ring.NewLifecycler(cfg.IngesterRing.ToLifecyclerConfig(), i, "ingester", IngesterRingKey, cfg.BlocksStorageConfig.TSDB.FlushBlocksOnShutdown, logger, prometheus.WrapRegistererWithPrefix("cortex_", registerer))
+	lifecyclerCfg := cfg.IngesterRing.ToLifecyclerConfig()
+	if cfg.HandoffEnabled && !cfg.IngesterRing.isWitness() {
+		// Wait for a leaving ingester to hand off its TSDBs before joining the ring. The ingester joins the
+		// ring right away if no ingester is leaving when it starts (see registerUnlessHandoffExpected()).
+		lifecyclerCfg.JoinAfter = cfg.HandoffTimeout
+	}
+
+	i.lifecycler, err = ring.NewLifecycler(lifecyclerCfg, i, "ingester", IngesterRingKey, cfg.BlocksStorageConfig.TSDB.FlushBlocksOnShutdown, logger, prometheus.WrapRegistererWithPrefix("cortex_", registerer))
 	if err != nil {
 		return nil, err
 	}
 	i.subservicesWatcher = services.NewFailureWatcher()
 	i.subservicesWatcher.WatchService(i.lifecycler)
 
+	if cfg.IngesterRing.isWitness() {
+		i.witnessMetrics = newWitnessMetrics(registerer)
+	}
+
+	// The ingesters in a witness zone don't consume the ingest storage, which already stores the series durably.
+	if cfg.IngestStorageConfig.Enabled && !cfg.IngesterRing.isWitness() {
+		partitionID, err := ingest.IngesterPartitionID(cfg.IngesterRing.InstanceID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compute the partition of the ingest storage consumed by the ingester")
+		}
+
+		i.ingestReader = ingest.NewPartitionReader(cfg.IngestStorageConfig.KafkaConfig, partitionID, cfg.BlocksStorageConfig.TSDB.Dir, ingestStorageConsumer{ingester: i}, logger, registerer)
+		i.subservicesWatcher.WatchService(i.ingestReader)
+	}
+
 	// Init the limter and instantiate the user states which depend on it
 	i.limiter = NewLimiter(
 		limits,
@@ -332,6 +427,7 @@ func New(cfg Config, limits *validation.Overrides, activeGroupsCleanupService *u
 		cfg.IngesterRing.ZoneAwarenessEnabled)
 
 	i.shipperIngesterID = i.lifecycler.ID
+	i.handoffClientFactory = i.dialHandoffClient
 
 	// Apply positive jitter only to ensure that the minimum timeout is adhered to.
 	i.compactionIdleTimeout = util.DurationWithPositiveJitter(i.cfg.BlocksStorageConfig.TSDB.HeadCompactionIdleTimeout, compactionIdleTimeoutJitter)
@@ -367,11 +463,19 @@ func (i *Ingester) startingForFlusher(ctx context.Context) error {
 		return errors.Wrap(err, "opening existing TSDBs")
 	}
 
+	if err := i.openSharedWALIfEnabled(ctx); err != nil {
+		return err
+	}
+
 	// Don't start any sub-services (lifecycler, compaction, shipper) at all.
 	return nil
 }
 
 func (i *Ingester) starting(ctx context.Context) error {
+	if i.cfg.IngesterRing.isWitness() {
+		return i.startingWitness(ctx)
+	}
+
 	if err := i.openExistingTSDB(ctx); err != nil {
 		// Try to rollback and close opened TSDBs before halting the ingester.
 		i.closeAllTSDB()
@@ -379,6 +483,13 @@ func (i *Ingester) starting(ctx context.Context) error {
 		return errors.Wrap(err, "opening existing TSDBs")
 	}
 
+	if i.cfg.HandoffEnabled {
+		if err := i.registerUnlessHandoffExpected(ctx); err != nil {
+			i.closeAllTSDB()
+			return errors.Wrap(err, "failed to register the ingester in the ring")
+		}
+	}
+
 	// Important: we want to keep lifecycler running until we ask it to stop, so we need to give it independent context
 	if err := i.lifecycler.StartAsync(context.Background()); err != nil {
 		return errors.Wrap(err, "failed to start lifecycler")
@@ -387,12 +498,31 @@ func (i *Ingester) starting(ctx context.Context) error {
 		return errors.Wrap(err, "failed to start lifecycler")
 	}
 
+	// The shared write-ahead log and the partition of the ingest storage are replayed once the ingester is
+	// registered in the ring, so that the limiter can convert the global series limits to local limits while
+	// replaying. The ingester doesn't accept write requests until it's running.
+	if i.cfg.BlocksStorageConfig.TSDB.SharedWALEnabled || i.ingestReader != nil {
+		err := i.waitLifecyclerRegistered(ctx)
+		if err == nil {
+			err = i.openSharedWALIfEnabled(ctx)
+		}
+		if err != nil {
+			_ = services.StopAndAwaitTerminated(context.Background(), i.lifecycler)
+			return err
+		}
+	}
+
 	// let's start the rest of subservices via manager
 	servs := []services.Service(nil)
 
 	compactionService := services.NewBasicService(nil, i.compactionLoop, nil)
 	servs = append(servs, compactionService)
 
+	// The partition reader is running once it has replayed the partition up to its end.
+	if i.ingestReader != nil {
+		servs = append(servs, i.ingestReader)
+	}
+
 	if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
 		shippingService := services.NewBasicService(nil, i.shipBlocksLoop, nil)
 		servs = append(servs, shippingService)
@@ -416,6 +546,8 @@ func (i *Ingester) starting(ctx context.Context) error {
 }
 
 func (i *Ingester) stoppingForFlusher(_ error) error {
+	i.closeSharedWAL()
+
 	if !i.cfg.BlocksStorageConfig.TSDB.KeepUserTSDBOpenOnShutdown {
 		i.closeAllTSDB()
 	}
@@ -436,6 +568,14 @@ func (i *Ingester) stopping(_ error) error {
 		level.Warn(i.logger).Log("msg", "failed to stop ingester lifecycler", "err", err)
 	}
 
+	if i.witness != nil {
+		if err := i.witness.close(); err != nil {
+			level.Warn(i.logger).Log("msg", "failed to close witness write-ahead log", "err", err)
+		}
+	}
+
+	i.closeSharedWAL()
+
 	if !i.cfg.BlocksStorageConfig.TSDB.KeepUserTSDBOpenOnShutdown {
 		i.closeAllTSDB()
 	}
@@ -466,10 +606,14 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 	usageStatsUpdateTicker := time.NewTicker(usageStatsUpdateInterval)
 	defer usageStatsUpdateTicker.Stop()
 
+	ingestAggregationTicker := time.NewTicker(ingestAggregationEvaluationPeriod)
+	defer ingestAggregationTicker.Stop()
+
 	for {
 		select {
 		case <-metadataPurgeTicker.C:
 			i.purgeUserMetricsMetadata()
+			i.purgeSampleIntervals(time.Now())
 		case <-ingestionRateTicker.C:
 			i.ingestionRate.Tick()
 		case <-rateUpdateTicker.C:
@@ -489,6 +633,9 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 		case <-usageStatsUpdateTicker.C:
 			i.updateUsageStats()
 
+		case <-ingestAggregationTicker.C:
+			i.evaluateIngestAggregations(time.Now())
+
 		case <-ctx.Done():
 			return nil
 		case err := <-i.subservicesWatcher.Chan():
@@ -497,9 +644,9 @@ func (i *Ingester) updateLoop(ctx context.Context) error {
 	}
 }
 
-func (i *Ingester) replaceMatchers(asm *activeseries.Matchers, userDB *userTSDB, now time.Time) {
+func (i *Ingester) replaceMatchers(asm *activeseries.Matchers, userDB *userTSDB) {
 	i.metrics.deletePerUserCustomTrackerMetrics(userDB.userID, userDB.activeSeries.CurrentMatcherNames())
-	userDB.activeSeries.ReloadMatchers(asm, now)
+	userDB.activeSeries.ReloadMatchers(asm)
 }
 
 func (i *Ingester) updateActiveSeries(now time.Time) {
@@ -511,27 +658,21 @@ func (i *Ingester) updateActiveSeries(now time.Time) {
 
 		newMatchersConfig := i.limits.ActiveSeriesCustomTrackersConfig(userID)
 		if newMatchersConfig.String() != userDB.activeSeries.CurrentConfig().String() {
-			i.replaceMatchers(activeseries.NewMatchers(newMatchersConfig), userDB, now)
+			i.replaceMatchers(activeseries.NewMatchers(newMatchersConfig), userDB)
 		}
-		allActive, activeMatching, valid := userDB.activeSeries.Active(now)
-		if !valid {
-			// Active series config has been reloaded, exposing loading metric until MetricsIdleTimeout passes.
-			i.metrics.activeSeriesLoading.WithLabelValues(userID).Set(1)
+		allActive, activeMatching := userDB.activeSeries.Active(now)
+		if allActive > 0 {
+			i.metrics.activeSeriesPerUser.WithLabelValues(userID).Set(float64(allActive))
 		} else {
-			i.metrics.activeSeriesLoading.DeleteLabelValues(userID)
-			if allActive > 0 {
-				i.metrics.activeSeriesPerUser.WithLabelValues(userID).Set(float64(allActive))
-			} else {
-				i.metrics.activeSeriesPerUser.DeleteLabelValues(userID)
-			}
+			i.metrics.activeSeriesPerUser.DeleteLabelValues(userID)
+		}
 
-			for idx, name := range userDB.activeSeries.CurrentMatcherNames() {
-				// We only set the metrics for matchers that actually exist, to avoid increasing cardinality with zero valued metrics.
-				if activeMatching[idx] > 0 {
-					i.metrics.activeSeriesCustomTrackersPerUser.WithLabelValues(userID, name).Set(float64(activeMatching[idx]))
-				} else {
-					i.metrics.activeSeriesCustomTrackersPerUser.DeleteLabelValues(userID, name)
-				}
+		for idx, name := range userDB.activeSeries.CurrentMatcherNames() {
+			// We only set the metrics for matchers that actually exist, to avoid increasing cardinality with zero valued metrics.
+			if activeMatching[idx] > 0 {
+				i.metrics.activeSeriesCustomTrackersPerUser.WithLabelValues(userID, name).Set(float64(activeMatching[idx]))
+			} else {
+				i.metrics.activeSeriesCustomTrackersPerUser.DeleteLabelValues(userID, name)
 			}
 		}
 	}
@@ -582,13 +723,25 @@ func (i *Ingester) updateUsageStats() {
 	i.maxOutOfOrderTimeWindowSecondsStat.Set(int64(maxOutOfOrderTimeWindow.Seconds()))
 }
 
+// maxExemplars returns the max number of exemplars stored by the TSDB of the tenant. The local limit can't be
+// computed while the ring is empty, like when the TSDBs are opened on startup, before the ingester joins the ring:
+// in this case the global limit is used instead, so that the exemplars replayed from the write-ahead log and the
+// memory snapshot are not dropped. The exemplars storage is shrunk to the local limit by applyTSDBSettings.
+// A local limit of 0, like when the global limit is lower than the number of ingesters, disables the exemplars.
+func (i *Ingester) maxExemplars(userID string) int {
+	globalLimit := i.limits.MaxGlobalExemplarsPerUser(userID)
+	if localLimit, ok := i.limiter.tryConvertGlobalToLocalLimit(userID, globalLimit); ok {
+		return util_math.Max(localLimit, 0)
+	}
+	return util_math.Max(globalLimit, 0)
+}
+
 // applyTSDBSettings goes through all tenants and applies
 // * The current max-exemplars setting. If it changed, tsdb will resize the buffer; if it didn't change tsdb will return quickly.
 // * The current out-of-order time window. If it changes from 0 to >0, then a new Write-Behind-Log gets created for that tenant.
 func (i *Ingester) applyTSDBSettings() {
 	for _, userID := range i.getTSDBUsers() {
-		globalValue := i.limits.MaxGlobalExemplarsPerUser(userID)
-		localValue := i.limiter.convertGlobalToLocalLimit(userID, globalValue)
+		localValue := i.maxExemplars(userID)
 
 		oooTW := i.limits.OutOfOrderTimeWindow(userID)
 		if oooTW < 0 {
@@ -642,6 +795,7 @@ type pushStats struct {
 	newValueForTimestampCount int
 	perUserSeriesLimitCount   int
 	perMetricSeriesLimitCount int
+	sampleTooFrequentCount    int
 }
 
 // PushWithCleanup is the Push() implementation for blocks storage and takes a WriteRequest and adds it to the TSDB head.
@@ -670,6 +824,10 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, pushReq *push.Request) (
 		return nil, err
 	}
 
+	if i.isTenantMarkedForDeletion(userID) {
+		return nil, httpgrpc.Errorf(http.StatusBadRequest, wrapWithUser(errTenantMarkedForDeletion, userID).Error())
+	}
+
 	if il != nil && il.MaxIngestionRate > 0 {
 		if rate := i.ingestionRate.Rate(); rate >= il.MaxIngestionRate {
 			return nil, errMaxIngestionRateReached
@@ -681,6 +839,16 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, pushReq *push.Request) (
 		return nil, err
 	}
 
+	return i.pushWriteRequest(ctx, userID, req)
+}
+
+// pushWriteRequest adds the write request of the tenant to the TSDB head, or to the witness write-ahead log.
+// It doesn't check whether the ingester is running, nor the instance limits: the callers are in charge of it.
+func (i *Ingester) pushWriteRequest(ctx context.Context, userID string, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
+	if i.witness != nil {
+		return i.pushWitness(userID, req)
+	}
+
 	// Given metadata is a best-effort approach, and we don't halt on errors
 	// process it before samples. Otherwise, we risk returning an error before ingestion.
 	if ingestedMetadata := i.pushMetadata(ctx, userID, req.GetMetadata()); ingestedMetadata > 0 {
@@ -688,6 +856,15 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, pushReq *push.Request) (
 		i.ingestionRate.Add(int64(ingestedMetadata))
 	}
 
+	// The series rolled up by the ingest aggregation rules are only fed to the aggregation.
+	if len(req.IngestAggregationInputs) > 0 {
+		db, err := i.getOrCreateTSDB(userID, false)
+		if err != nil {
+			return nil, wrapWithUser(err, userID)
+		}
+		i.aggregateSeries(userID, db, req.IngestAggregationInputs)
+	}
+
 	// Early exit if no timeseries in request - don't create a TSDB or an appender.
 	if len(req.Timeseries) == 0 {
 		return &mimirpb.WriteResponse{}, nil
@@ -724,6 +901,13 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, pushReq *push.Request) (
 
 	// Walk the samples, appending them to the users database
 	app := db.Appender(ctx).(extendedAppender)
+	if i.sharedWAL != nil {
+		// Log only the samples accepted by the TSDB and by the wrapping appenders.
+		app = newSharedWALAppender(app, i.sharedWAL, db, req.Source)
+	}
+	if minInterval := i.limits.MinSampleInterval(userID); minInterval > 0 {
+		app = newMinSampleIntervalAppender(app, db.sampleIntervals, minInterval)
+	}
 	level.Debug(spanlog).Log("event", "got appender for timeseries", "series", len(req.Timeseries))
 
 	var activeSeries *activeseries.ActiveSeries
@@ -812,6 +996,9 @@ func (i *Ingester) updateMetricsFromPushStats(userID string, group string, stats
 	if stats.perMetricSeriesLimitCount > 0 {
 		discarded.perMetricSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perMetricSeriesLimitCount))
 	}
+	if stats.sampleTooFrequentCount > 0 {
+		discarded.sampleTooFrequent.WithLabelValues(userID, group).Add(float64(stats.sampleTooFrequentCount))
+	}
 	if stats.succeededSamplesCount > 0 {
 		i.ingestionRate.Add(int64(stats.succeededSamplesCount))
 
@@ -865,6 +1052,13 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 			})
 			return true
 
+		case errSampleTooFrequent:
+			stats.sampleTooFrequentCount++
+			updateFirstPartial(func() error {
+				return newIngestErrSampleTooFrequent(model.Time(timestamp), labels, i.limits.MinSampleInterval(userID))
+			})
+			return true
+
 		case errMaxSeriesPerUserLimitExceeded:
 			stats.perUserSeriesLimitCount++
 			updateFirstPartial(func() error {
@@ -936,6 +1130,25 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 		// and NOT the stable hashing because we use the stable hashing in ingesters only for query sharding.
 		ref, copiedLabels := app.GetRef(mimirpb.FromLabelAdaptersToLabels(ts.Labels), mimirpb.FromLabelAdaptersToLabels(ts.Labels).Hash())
 
+		// Ingest the created timestamp, if any, as a zero sample preceding the series samples, so that
+		// rate() and increase() account for the increase since the counter has been (re)created.
+		if ts.CreatedTimestampMs > 0 {
+			if ref == 0 {
+				// Copy the label set because both TSDB and the active series tracker may retain it.
+				copiedLabels = mimirpb.FromLabelAdaptersToLabelsWithCopy(ts.Labels)
+			}
+			createdRef, err := appendCreatedTimestampZeroSample(app, ref, copiedLabels, ts.TimeSeries, nativeHistogramsIngestionEnabled)
+			switch {
+			case err == nil:
+				if createdRef != 0 {
+					ref = createdRef
+				}
+			case !isExpectedCreatedTimestampZeroSampleErr(err):
+				i.metrics.createdTimestampZeroSamplesFail.Inc()
+				level.Debug(i.logger).Log("msg", "failed to append the zero sample at the created timestamp", "user", userID, "series", mimirpb.FromLabelAdaptersToLabels(ts.Labels), "created_timestamp", ts.CreatedTimestampMs, "err", err)
+			}
+		}
+
 		// To find out if any sample was added to this series, we keep old value.
 		oldSucceededSamplesCount := stats.succeededSamplesCount
 
@@ -1053,6 +1266,48 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 	return nil
 }
 
+// appendCreatedTimestampZeroSample appends a zero sample at the created timestamp of the series, of the same
+// type of the series samples. The returned reference is 0 if the zero sample hasn't been appended. The zero
+// sample isn't subject to the min sample interval, which applies to the series samples only: it always precedes
+// them, and it would otherwise cause the first sample of a new series to be rejected.
+func appendCreatedTimestampZeroSample(app extendedAppender, ref storage.SeriesRef, lbls labels.Labels, ts *mimirpb.TimeSeries, nativeHistogramsIngestionEnabled bool) (storage.SeriesRef, error) {
+	var (
+		createdRef storage.SeriesRef
+		err        error
+	)
+
+	if intervalApp, ok := app.(*minSampleIntervalAppender); ok {
+		app = intervalApp.extendedAppender
+	}
+
+	switch {
+	case len(ts.Samples) > 0:
+		createdRef, err = app.Append(ref, lbls, ts.CreatedTimestampMs, 0)
+	case len(ts.Histograms) > 0 && nativeHistogramsIngestionEnabled:
+		h := &ts.Histograms[0]
+		if h.IsFloatHistogram() {
+			createdRef, err = app.AppendHistogram(ref, lbls, ts.CreatedTimestampMs, nil, &histogram.FloatHistogram{Schema: h.Schema, ZeroThreshold: h.ZeroThreshold})
+		} else {
+			createdRef, err = app.AppendHistogram(ref, lbls, ts.CreatedTimestampMs, &histogram.Histogram{Schema: h.Schema, ZeroThreshold: h.ZeroThreshold}, nil)
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+	return createdRef, nil
+}
+
+// isExpectedCreatedTimestampZeroSampleErr returns whether the input error, returned when appending the zero
+// sample at the created timestamp of a series, is expected. Because the created timestamp is sent along with
+// every sample of the series, the zero sample is out-of-order, too old or duplicated once it has been ingested.
+func isExpectedCreatedTimestampZeroSampleErr(err error) bool {
+	return errors.Is(err, storage.ErrOutOfOrderSample) ||
+		errors.Is(err, storage.ErrTooOldSample) ||
+		errors.Is(err, storage.ErrOutOfBounds) ||
+		errors.Is(err, storage.ErrDuplicateSampleForTimestamp)
+}
+
 func (i *Ingester) QueryExemplars(ctx context.Context, req *client.ExemplarQueryRequest) (*client.ExemplarQueryResponse, error) {
 	if err := i.checkRunning(); err != nil {
 		return nil, err
@@ -1436,7 +1691,13 @@ func (i *Ingester) QueryStream(req *client.QueryRequest, stream client.Ingester_
 }
 
 func (i *Ingester) queryStreamSamples(ctx context.Context, db *userTSDB, from, through int64, matchers []*labels.Matcher, shard *sharding.ShardSelector, stream client.Ingester_QueryStreamServer) (numSeries, numSamples int, _ error) {
-	q, err := db.Querier(ctx, from, through)
+	var q storage.Querier
+	var err error
+	if util.SkipOutOfOrderFromIncomingContext(ctx) {
+		q, err = db.InOrderQuerier(from, through)
+	} else {
+		q, err = db.Querier(ctx, from, through)
+	}
 	if err != nil {
 		return 0, 0, err
 	}
@@ -1453,7 +1714,7 @@ func (i *Ingester) queryStreamSamples(ctx context.Context, db *userTSDB, from, t
 		return 0, 0, ss.Err()
 	}
 
-	timeseries := make([]mimirpb.TimeSeries, 0, queryStreamBatchSize)
+	timeseries := make([]mimirpb.TimeSeries, 0, i.cfg.QueryStreamBatchSize)
 	batchSizeBytes := 0
 	var it chunkenc.Iterator
 	for ss.Next() {
@@ -1484,7 +1745,7 @@ func (i *Ingester) queryStreamSamples(ctx context.Context, db *userTSDB, from, t
 		numSeries++
 		tsSize := ts.Size()
 
-		if (batchSizeBytes > 0 && batchSizeBytes+tsSize > queryStreamBatchMessageSize) || len(timeseries) >= queryStreamBatchSize {
+		if (batchSizeBytes > 0 && batchSizeBytes+tsSize > queryStreamBatchMessageSize) || len(timeseries) >= i.cfg.QueryStreamBatchSize {
 			// Adding this series to the batch would make it too big,
 			// flush the data and add it to new batch instead.
 			err = client.SendQueryStream(stream, &client.QueryStreamResponse{
@@ -1524,7 +1785,9 @@ func (i *Ingester) queryStreamSamples(ctx context.Context, db *userTSDB, from, t
 func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, through int64, matchers []*labels.Matcher, shard *sharding.ShardSelector, stream client.Ingester_QueryStreamServer) (numSeries, numSamples int, _ error) {
 	var q storage.ChunkQuerier
 	var err error
-	if i.limits.OutOfOrderTimeWindow(db.userID) > 0 {
+	if util.SkipOutOfOrderFromIncomingContext(ctx) {
+		q, err = db.InOrderChunkQuerier(from, through)
+	} else if i.limits.OutOfOrderTimeWindow(db.userID) > 0 {
 		q, err = db.UnorderedChunkQuerier(ctx, from, through)
 	} else {
 		q, err = db.ChunkQuerier(ctx, from, through)
@@ -1546,7 +1809,7 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 		return 0, 0, ss.Err()
 	}
 
-	chunkSeries := make([]client.TimeSeriesChunk, 0, queryStreamBatchSize)
+	chunkSeries := make([]client.TimeSeriesChunk, 0, i.cfg.QueryStreamBatchSize)
 	batchSizeBytes := 0
 	var it chunks.Iterator
 	for ss.Next() {
@@ -1568,6 +1831,13 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 				return 0, 0, errors.Errorf("unfilled chunk returned from TSDB chunk querier")
 			}
 
+			// The TSDB chunk querier is expected to only return chunks overlapping the queried time range,
+			// but we never want to stream chunks fully outside of it, so we guarantee it here.
+			if meta.MaxTime < from || meta.MinTime > through {
+				i.metrics.queriedChunksPruned.Inc()
+				continue
+			}
+
 			ch := client.Chunk{
 				StartTimestampMs: meta.MinTime,
 				EndTimestampMs:   meta.MaxTime,
@@ -1588,10 +1858,13 @@ func (i *Ingester) queryStreamChunks(ctx context.Context, db *userTSDB, from, th
 			ts.Chunks = append(ts.Chunks, ch)
 			numSamples += meta.Chunk.NumSamples()
 		}
+		if len(ts.Chunks) == 0 {
+			continue
+		}
 		numSeries++
 		tsSize := ts.Size()
 
-		if (batchSizeBytes > 0 && batchSizeBytes+tsSize > queryStreamBatchMessageSize) || len(chunkSeries) >= queryStreamBatchSize {
+		if (batchSizeBytes > 0 && batchSizeBytes+tsSize > queryStreamBatchMessageSize) || len(chunkSeries) >= i.cfg.QueryStreamBatchSize {
 			// Adding this series to the batch would make it too big,
 			// flush the data and add it to new batch instead.
 			err = client.SendQueryStream(stream, &client.QueryStreamResponse{
@@ -1634,6 +1907,39 @@ func (i *Ingester) getTSDB(userID string) *userTSDB {
 	return db
 }
 
+// setTenantMarkedForDeletion records the deletion mark of the tenant has been found, so that the following
+// writes for the tenant are rejected.
+func (i *Ingester) setTenantMarkedForDeletion(userID string) {
+	i.tenantsMarkedForDeletionMtx.Lock()
+	defer i.tenantsMarkedForDeletionMtx.Unlock()
+	i.tenantsMarkedForDeletion[userID] = time.Now()
+}
+
+// isTenantMarkedForDeletion returns whether the deletion mark of the tenant has been found. The deletion mark
+// is refreshed while shipping the blocks as long as the TSDB of the tenant is open. Once the TSDB has been closed,
+// the deletion mark is trusted for mimir_tsdb.DeletionMarkCheckInterval, then writes are accepted again until the
+// deletion mark is checked once again while shipping the blocks, in case the tenant deletion has been completed
+// or reverted.
+func (i *Ingester) isTenantMarkedForDeletion(userID string) bool {
+	i.tenantsMarkedForDeletionMtx.RLock()
+	foundTime, ok := i.tenantsMarkedForDeletion[userID]
+	i.tenantsMarkedForDeletionMtx.RUnlock()
+
+	if !ok {
+		return false
+	}
+	if time.Since(foundTime) <= mimir_tsdb.DeletionMarkCheckInterval {
+		return true
+	}
+
+	i.tenantsMarkedForDeletionMtx.Lock()
+	if i.tenantsMarkedForDeletion[userID] == foundTime {
+		delete(i.tenantsMarkedForDeletion, userID)
+	}
+	i.tenantsMarkedForDeletionMtx.Unlock()
+	return false
+}
+
 // List all users for which we have a TSDB. We do it here in order
 // to keep the mutex locked for the shortest time possible.
 func (i *Ingester) getTSDBUsers() []string {
@@ -1712,10 +2018,18 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 		instanceLimitsFn:    i.getInstanceLimits,
 		instanceSeriesCount: &i.seriesCount,
 		blockMinRetention:   i.cfg.BlocksStorageConfig.TSDB.Retention,
+		sampleIntervals:     newSampleIntervalTracker(),
+		ingestAggregator:    newIngestAggregator(),
 	}
 
-	maxExemplars := i.limiter.convertGlobalToLocalLimit(userID, i.limits.MaxGlobalExemplarsPerUser(userID))
+	maxExemplars := i.maxExemplars(userID)
 	oooTW := i.limits.OutOfOrderTimeWindow(userID)
+
+	// The write-ahead log of the TSDB is disabled when replaced by the write-ahead log shared by all tenants.
+	walSegmentSize := i.cfg.BlocksStorageConfig.TSDB.WALSegmentSizeBytes
+	if i.cfg.BlocksStorageConfig.TSDB.SharedWALEnabled {
+		walSegmentSize = -1
+	}
 	// Create a new user database
 	db, err := tsdb.Open(udir, userLogger, tsdbPromReg, &tsdb.Options{
 		RetentionDuration:                  i.cfg.BlocksStorageConfig.TSDB.Retention.Milliseconds(),
@@ -1726,7 +2040,7 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 		HeadChunksWriteBufferSize:          i.cfg.BlocksStorageConfig.TSDB.HeadChunksWriteBufferSize,
 		HeadChunksEndTimeVariance:          i.cfg.BlocksStorageConfig.TSDB.HeadChunksEndTimeVariance,
 		WALCompression:                     i.cfg.BlocksStorageConfig.TSDB.WALCompressionEnabled,
-		WALSegmentSize:                     i.cfg.BlocksStorageConfig.TSDB.WALSegmentSizeBytes,
+		WALSegmentSize:                     walSegmentSize,
 		WALReplayConcurrency:               walReplayConcurrency,
 		SeriesLifecycleCallback:            userDB,
 		BlocksToDelete:                     userDB.blocksToDelete,
@@ -1777,6 +2091,11 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 
 	// Create a new shipper for this database
 	if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
+		var exemplars storage.ExemplarQueryable
+		if i.cfg.BlocksStorageConfig.TSDB.BlockExemplarsEnabled {
+			exemplars = userDB
+		}
+
 		userDB.shipper = NewShipper(
 			userLogger,
 			i.limits,
@@ -1785,6 +2104,7 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 			udir,
 			bucket.NewUserBucketClient(userID, i.bucket, i.limits),
 			metadata.ReceiveSource,
+			exemplars,
 		)
 
 		// Initialise the shipper blocks cache.
@@ -1839,7 +2159,6 @@ func (i *Ingester) openExistingTSDB(ctx context.Context) error {
 	level.Info(i.logger).Log("msg", "opening existing TSDBs")
 	startTime := time.Now()
 
-	queue := make(chan string)
 	group, groupCtx := errgroup.WithContext(ctx)
 
 	userIDs, err := i.findUserIDsWithTSDBOnFilesystem()
@@ -1853,12 +2172,19 @@ func (i *Ingester) openExistingTSDB(ctx context.Context) error {
 	}
 
 	tsdbOpenConcurrency, tsdbWALReplayConcurrency := getOpenTSDBsConcurrencyConfig(i.cfg.BlocksStorageConfig.TSDB, len(userIDs))
+	scheduler := i.newWALReplayScheduler(userIDs)
 
 	// Create a pool of workers which will open existing TSDBs.
 	for n := 0; n < tsdbOpenConcurrency; n++ {
 		group.Go(func() error {
-			for userID := range queue {
+			for {
+				userID, done, ok := scheduler.next(groupCtx)
+				if !ok {
+					return nil
+				}
+
 				db, err := i.createTSDB(userID, tsdbWALReplayConcurrency)
+				done()
 				if err != nil {
 					level.Error(i.logger).Log("msg", "unable to open TSDB", "err", err, "user", userID)
 					return errors.Wrapf(err, "unable to open TSDB for user %s", userID)
@@ -1870,28 +2196,9 @@ func (i *Ingester) openExistingTSDB(ctx context.Context) error {
 				i.tsdbsMtx.Unlock()
 				i.metrics.memUsers.Inc()
 			}
-
-			return nil
 		})
 	}
 
-	// Spawn a goroutine to place all users with a TSDB found on the filesystem in the queue.
-	group.Go(func() error {
-		defer close(queue)
-
-		for _, userID := range userIDs {
-			// Enqueue the user to be processed.
-			select {
-			case queue <- userID:
-				// Nothing to do.
-			case <-groupCtx.Done():
-				// Interrupt in case a failure occurred in another goroutine.
-				return nil
-			}
-		}
-		return nil
-	})
-
 	// Wait for all workers to complete.
 	err = group.Wait()
 	if err != nil {
@@ -1907,6 +2214,31 @@ func (i *Ingester) openExistingTSDB(ctx context.Context) error {
 	return nil
 }
 
+// newWALReplayScheduler creates the scheduler used to open the existing TSDBs of the input tenants on startup.
+func (i *Ingester) newWALReplayScheduler(userIDs []string) *walReplayScheduler {
+	tsdbConfig := i.cfg.BlocksStorageConfig.TSDB
+	if tsdbConfig.WALReplayLargeTenantThresholdBytes == 0 {
+		return newWALReplayScheduler(userIDs, nil, 0, 0)
+	}
+
+	walSizes := make(map[string]uint64, len(userIDs))
+	for _, userID := range userIDs {
+		size, err := getWALSize(tsdbConfig.BlocksDir(userID))
+		if err != nil {
+			// The tenant is considered small.
+			level.Warn(i.logger).Log("msg", "unable to get the TSDB WAL size", "user", userID, "err", err)
+			continue
+		}
+
+		walSizes[userID] = size
+		if size > tsdbConfig.WALReplayLargeTenantThresholdBytes {
+			level.Info(i.logger).Log("msg", "TSDB of large tenant will be opened with bounded concurrency", "user", userID, "wal_size_bytes", size)
+		}
+	}
+
+	return newWALReplayScheduler(userIDs, walSizes, tsdbConfig.WALReplayLargeTenantThresholdBytes, tsdbConfig.WALReplayLargeTenantsConcurrency)
+}
+
 func getOpenTSDBsConcurrencyConfig(tsdbConfig mimir_tsdb.TSDBConfig, userCount int) (tsdbOpenConcurrency, tsdbWALReplayConcurrency int) {
 	tsdbOpenConcurrency = tsdbConfig.DeprecatedMaxTSDBOpeningConcurrencyOnStartup
 	tsdbWALReplayConcurrency = 0
@@ -1946,8 +2278,14 @@ func (i *Ingester) findUserIDsWithTSDBOnFilesystem() ([]string, error) {
 			return nil
 		}
 
-		// Top level directories are assumed to be user TSDBs
+		// Top level directories are assumed to be user TSDBs, except the write-ahead log shared by all tenants.
 		userID := info.Name()
+		if userID == sharedWALDirname && i.cfg.BlocksStorageConfig.TSDB.SharedWALEnabled {
+			return filepath.SkipDir
+		}
+		if userID == handoffDirname && i.cfg.HandoffEnabled {
+			return filepath.SkipDir
+		}
 		f, err := os.Open(path)
 		if err != nil {
 			level.Error(i.logger).Log("msg", "unable to open TSDB dir", "err", err, "user", userID, "path", path)
@@ -2040,6 +2378,8 @@ func (i *Ingester) shipBlocks(ctx context.Context, allowed *util.AllowedTenants)
 		}
 
 		if userDB.deletionMarkFound.Load() {
+			// Keep rejecting the writes for the tenant until its TSDB is closed.
+			i.setTenantMarkedForDeletion(userID)
 			return nil
 		}
 
@@ -2054,6 +2394,7 @@ func (i *Ingester) shipBlocks(ctx context.Context, allowed *util.AllowedTenants)
 				level.Warn(i.logger).Log("msg", "failed to check for tenant deletion mark before shipping blocks", "user", userID, "err", err)
 			} else if deletionMarkExists {
 				userDB.deletionMarkFound.Store(true)
+				i.setTenantMarkedForDeletion(userID)
 
 				level.Info(i.logger).Log("msg", "tenant deletion mark exists, not shipping blocks", "user", userID)
 				return nil
@@ -2103,6 +2444,7 @@ func (i *Ingester) compactionLoop(ctx context.Context) error {
 		select {
 		case <-ticker.C:
 			i.compactBlocks(ctx, false, nil)
+			i.truncateSharedWAL()
 
 			// Run it at a regular (configured) interval after the fist compaction.
 			if !tickerRunOnce {
@@ -2112,6 +2454,7 @@ func (i *Ingester) compactionLoop(ctx context.Context) error {
 
 		case req := <-i.forceCompactTrigger:
 			i.compactBlocks(ctx, true, req.users)
+			i.truncateSharedWAL()
 			close(req.callback) // Notify back.
 
 		case <-ctx.Done():
@@ -2148,6 +2491,14 @@ func (i *Ingester) compactBlocks(ctx context.Context, force bool, allowed *util.
 			return nil
 		}
 
+		now := time.Now()
+		idle := !force && i.compactionIdleTimeout > 0 && userDB.isIdle(now, i.compactionIdleTimeout)
+
+		// Regular compactions are delayed until the tenant's head compaction slot, if enabled.
+		if !force && !idle && !headCompactionSlotReached(now, i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0], i.cfg.BlocksStorageConfig.TSDB.HeadCompactionSlotsWindow, i.cfg.IngesterRing.InstanceID, userID) {
+			return nil
+		}
+
 		var err error
 
 		i.metrics.compactionsTriggered.Inc()
@@ -2158,7 +2509,7 @@ func (i *Ingester) compactBlocks(ctx context.Context, force bool, allowed *util.
 			reason = "forced"
 			err = userDB.compactHead(i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0].Milliseconds())
 
-		case i.compactionIdleTimeout > 0 && userDB.isIdle(time.Now(), i.compactionIdleTimeout):
+		case idle:
 			reason = "idle"
 			level.Info(i.logger).Log("msg", "TSDB is idle, forcing compaction", "user", userID)
 			err = userDB.compactHead(i.cfg.BlocksStorageConfig.TSDB.BlockRanges[0].Milliseconds())
@@ -2179,6 +2530,25 @@ func (i *Ingester) compactBlocks(ctx context.Context, force bool, allowed *util.
 	})
 }
 
+// headCompactionSlotReached returns whether the regular head compaction of the tenant is allowed at the given time.
+// The TSDB head becomes compactable once it spans 1.5x the block range, which happens for all tenants at the same
+// wall-clock time (e.g. at every odd hour with 2h blocks). When the slots window is enabled, each tenant gets
+// a deterministic slot within the window following that time, based on the hash of the ingester and tenant IDs,
+// and its head is compacted only once the slot is reached.
+func headCompactionSlotReached(now time.Time, blockRange, slotsWindow time.Duration, instanceID, userID string) bool {
+	if slotsWindow <= 0 || blockRange <= 0 {
+		return true
+	}
+
+	slot := time.Duration(fnv1a.HashString64(instanceID+"/"+userID) % uint64(slotsWindow))
+	sinceCompactable := time.Duration((now.UnixNano() - int64(blockRange/2)) % int64(blockRange))
+	if sinceCompactable < 0 {
+		sinceCompactable += blockRange
+	}
+
+	return sinceCompactable >= slot
+}
+
 func (i *Ingester) closeAndDeleteIdleUserTSDBs(ctx context.Context) error {
 	for _, userID := range i.getTSDBUsers() {
 		if ctx.Err() != nil {
@@ -2278,11 +2648,6 @@ func (i *Ingester) RemoveGroupMetricsForUser(userID, group string) {
 	i.metrics.deletePerGroupMetricsForUser(userID, group)
 }
 
-// TransferOut implements ring.FlushTransferer.
-func (i *Ingester) TransferOut(_ context.Context) error {
-	return ring.ErrTransferDisabled
-}
-
 // This method will flush all data. It is called as part of Lifecycler's shutdown (if flush on shutdown is configured), or from the flusher.
 //
 // When called as during Lifecycler shutdown, this happens as part of normal Ingester shutdown (see stopping method).
@@ -2402,6 +2767,10 @@ func newIngestErrSampleDuplicateTimestamp(timestamp model.Time, labels []mimirpb
 	return newIngestErr(globalerror.SampleDuplicateTimestamp, "the sample has been rejected because another sample with the same timestamp, but a different value, has already been ingested", timestamp, labels)
 }
 
+func newIngestErrSampleTooFrequent(timestamp model.Time, labels []mimirpb.LabelAdapter, minInterval time.Duration) error {
+	return newIngestErr(globalerror.SampleTooFrequent, fmt.Sprintf("the sample has been rejected because another sample of the same series has been ingested less than the minimum sample interval of %s before", model.Duration(minInterval).String()), timestamp, labels)
+}
+
 func newIngestErrExemplarMissingSeries(timestamp model.Time, seriesLabels, exemplarLabels []mimirpb.LabelAdapter) error {
 	return fmt.Errorf("%v. The affected exemplar is %s with timestamp %s for series %s",
 		globalerror.ExemplarSeriesMissing.Message("the exemplar has been rejected because the related series has not been ingested yet"),
@@ -2440,6 +2809,27 @@ func (i *Ingester) getInstanceLimits() *InstanceLimits {
 	return l
 }
 
+// getPushPressure returns the utilization of the most utilized in-flight push requests or ingestion rate
+// instance limit, from 0 to 1, reported to distributors in the push responses. The in-memory series limit
+// is not taken into account, because rejecting push requests doesn't reduce the number of in-memory series,
+// so it would keep the requests rejected until the series are compacted. The pressure is 0 if none of these
+// instance limits is configured.
+func (i *Ingester) getPushPressure() float64 {
+	il := i.getInstanceLimits()
+	if il == nil {
+		return 0
+	}
+
+	pressure := 0.0
+	if il.MaxInflightPushRequests > 0 {
+		pressure = math.Max(pressure, float64(i.inflightPushRequests.Load())/float64(il.MaxInflightPushRequests))
+	}
+	if il.MaxIngestionRate > 0 {
+		pressure = math.Max(pressure, i.ingestionRate.Rate()/il.MaxIngestionRate)
+	}
+	return math.Min(pressure, 1)
+}
+
 // ShutdownHandler triggers the following set of operations in order:
 //   - Change the state of ring to stop accepting writes.
 //   - Flush all the chunks.
@@ -2475,8 +2865,15 @@ func (i *Ingester) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirp
 	pushReq := push.NewParsedRequest(req)
 	pushReq.AddCleanup(func() {
 		mimirpb.ReuseSlice(req.Timeseries)
+		mimirpb.ReuseSlice(req.IngestAggregationInputs)
 	})
-	return i.PushWithCleanup(ctx, pushReq)
+
+	resp, err := i.PushWithCleanup(ctx, pushReq)
+	if err != nil {
+		return nil, err
+	}
+	resp.Pressure = i.getPushPressure()
+	return resp, nil
 }
 
 // pushMetadata returns number of ingested metadata.
@@ -2577,6 +2974,20 @@ func (i *Ingester) purgeUserMetricsMetadata() {
 	}
 }
 
+// purgeSampleIntervals removes the timestamps of the last samples which are no longer needed
+// to enforce the per-tenant minimum sample interval.
+func (i *Ingester) purgeSampleIntervals(now time.Time) {
+	for _, userID := range i.getTSDBUsers() {
+		userDB := i.getTSDB(userID)
+		if userDB == nil {
+			continue
+		}
+
+		deadline := now.Add(-i.limits.MinSampleInterval(userID))
+		userDB.sampleIntervals.purge(deadline.UnixMilli())
+	}
+}
+
 // MetricsMetadata returns all the metric metadata of a user.
 func (i *Ingester) MetricsMetadata(ctx context.Context, req *client.MetricsMetadataRequest) (*client.MetricsMetadataResponse, error) {
 	if err := i.checkRunning(); err != nil {
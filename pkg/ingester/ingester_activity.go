@@ -10,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/grafana/dskit/tenant"
+	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/tracing"
 
 	"github.com/grafana/mimir/pkg/ingester/client"
@@ -158,6 +159,15 @@ func (i *ActivityTrackerWrapper) UserRegistryHandler(writer http.ResponseWriter,
 	i.ing.UserRegistryHandler(writer, request)
 }
 
+func (i *ActivityTrackerWrapper) Handoff(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error) {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(ctx, "Ingester/Handoff", nil)
+	})
+	defer i.tracker.Delete(ix)
+
+	return i.ing.Handoff(ctx, req)
+}
+
 func requestActivity(ctx context.Context, name string, req interface{}) string {
 	userID, _ := tenant.TenantID(ctx)
 	traceID, _ := tracing.ExtractSampledTraceID(ctx)
@@ -3,7 +3,9 @@
 package ingester
 
 import (
+	"errors"
 	"flag"
+	"net"
 	"os"
 	"time"
 
@@ -24,6 +26,8 @@ const (
 	readinessCheckRingHealthFlag = "ingester.ring.readiness-check-ring-health"
 )
 
+var errWitnessZonesRequireZoneAwareness = errors.New("witness zones require zone-awareness to be enabled")
+
 type RingConfig struct {
 	KVStore              kv.Config              `yaml:"kvstore" doc:"description=The key-value store used to share the hash ring across multiple instances. This option needs be set on ingesters, distributors, queriers and rulers when running in microservices mode."`
 	HeartbeatPeriod      time.Duration          `yaml:"heartbeat_period" category:"advanced"`
@@ -31,6 +35,7 @@ type RingConfig struct {
 	ReplicationFactor    int                    `yaml:"replication_factor"`
 	ZoneAwarenessEnabled bool                   `yaml:"zone_awareness_enabled"`
 	ExcludedZones        flagext.StringSliceCSV `yaml:"excluded_zones" category:"advanced"`
+	WitnessZones         flagext.StringSliceCSV `yaml:"witness_zones" category:"experimental"`
 
 	// Tokens
 	TokensFilePath string `yaml:"tokens_file_path"`
@@ -41,6 +46,7 @@ type RingConfig struct {
 	InstanceInterfaceNames []string `yaml:"instance_interface_names" category:"advanced" doc:"default=[<private network interfaces>]"`
 	InstancePort           int      `yaml:"instance_port" category:"advanced"`
 	InstanceAddr           string   `yaml:"instance_addr" category:"advanced"`
+	InstanceEnableIPv6     bool     `yaml:"instance_enable_ipv6" category:"experimental"`
 	InstanceZone           string   `yaml:"instance_availability_zone" category:"advanced"`
 
 	UnregisterOnShutdown bool `yaml:"unregister_on_shutdown" category:"advanced"`
@@ -77,6 +83,7 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.IntVar(&cfg.ReplicationFactor, prefix+"replication-factor", 3, "Number of ingesters that each time series is replicated to."+sharedOptionWithRingClient)
 	f.BoolVar(&cfg.ZoneAwarenessEnabled, prefix+"zone-awareness-enabled", false, "True to enable the zone-awareness and replicate ingested samples across different availability zones."+sharedOptionWithRingClient)
 	f.Var(&cfg.ExcludedZones, prefix+"excluded-zones", "Comma-separated list of zones to exclude from the ring. Instances in excluded zones will be filtered out from the ring."+sharedOptionWithRingClient)
+	f.Var(&cfg.WitnessZones, prefix+"witness-zones", "Comma-separated list of zones whose ingesters run as witnesses. Witness ingesters acknowledge writes once persisted to their write-ahead log, without holding any queryable state, and are excluded from the read path. Requires zone-awareness to be enabled."+sharedOptionWithRingClient)
 
 	f.StringVar(&cfg.TokensFilePath, prefix+"tokens-file-path", "", "File path where tokens are stored. If empty, tokens are not stored at shutdown and restored at startup.")
 	f.IntVar(&cfg.NumTokens, prefix+"num-tokens", 128, "Number of tokens for each ingester.")
@@ -87,6 +94,7 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.Var((*flagext.StringSlice)(&cfg.InstanceInterfaceNames), prefix+"instance-interface-names", "List of network interface names to look up when finding the instance IP address.")
 	f.IntVar(&cfg.InstancePort, prefix+"instance-port", 0, "Port to advertise in the ring (defaults to -server.grpc-listen-port).")
 	f.StringVar(&cfg.InstanceAddr, prefix+"instance-addr", "", "IP address to advertise in the ring. Default is auto-detected.")
+	f.BoolVar(&cfg.InstanceEnableIPv6, prefix+"instance-enable-ipv6", false, "Enable using an IPv6 instance address, when no IPv4 address is found on the network interfaces.")
 	f.StringVar(&cfg.InstanceZone, prefix+"instance-availability-zone", "", "The availability zone where this instance is running.")
 
 	f.BoolVar(&cfg.UnregisterOnShutdown, prefix+"unregister-on-shutdown", true, "Unregister from the ring upon clean shutdown. It can be useful to disable for rolling restarts with consistent naming.")
@@ -141,6 +149,17 @@ func (cfg *RingConfig) ToLifecyclerConfig() ring.LifecyclerConfig {
 	lc.UnregisterOnShutdown = cfg.UnregisterOnShutdown
 	lc.ReadinessCheckRingHealth = cfg.DeprecatedReadinessCheckRingHealth
 	lc.Addr = cfg.InstanceAddr
+	if lc.Addr == "" && cfg.InstanceEnableIPv6 {
+		// The lifecycler only looks up IPv4 addresses, so we look up the address here. If no address
+		// is found, we let the lifecycler fail with the lookup error.
+		if addr, err := util.GetFirstAddressOf(cfg.InstanceInterfaceNames, true); err == nil {
+			lc.Addr = addr
+		}
+	}
+	if ip := net.ParseIP(lc.Addr); ip != nil && ip.To4() == nil {
+		// The lifecycler joins the address and the port without enclosing IPv6 addresses in square brackets.
+		lc.Addr = "[" + lc.Addr + "]"
+	}
 	lc.Port = cfg.InstancePort
 	lc.ID = cfg.InstanceID
 	lc.ListenPort = cfg.ListenPort
@@ -152,5 +171,8 @@ func (cfg *RingConfig) Validate(logger log.Logger) error {
 	if cfg.DeprecatedReadinessCheckRingHealth {
 		util.WarnDeprecatedConfig(readinessCheckRingHealthFlag, logger)
 	}
+	if len(cfg.WitnessZones) > 0 && !cfg.ZoneAwarenessEnabled {
+		return errWitnessZonesRequireZoneAwareness
+	}
 	return nil
 }
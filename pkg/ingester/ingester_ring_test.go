@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/ring"
 	"github.com/stretchr/testify/assert"
@@ -88,3 +89,25 @@ func TestRingConfig_CustomConfigToLifecyclerConfig(t *testing.T) {
 
 	assert.Equal(t, expected, cfg.ToLifecyclerConfig())
 }
+
+func TestRingConfig_ToLifecyclerConfig_IPv6InstanceAddr(t *testing.T) {
+	cfg := RingConfig{}
+	flagext.DefaultValues(&cfg)
+
+	cfg.InstanceAddr = "2001:db8::1"
+	assert.Equal(t, "[2001:db8::1]", cfg.ToLifecyclerConfig().Addr)
+
+	cfg.InstanceAddr = "1.2.3.4"
+	assert.Equal(t, "1.2.3.4", cfg.ToLifecyclerConfig().Addr)
+}
+
+func TestRingConfig_Validate_WitnessZones(t *testing.T) {
+	cfg := RingConfig{}
+	flagext.DefaultValues(&cfg)
+
+	cfg.WitnessZones = []string{"zone-c"}
+	assert.ErrorIs(t, cfg.Validate(log.NewNopLogger()), errWitnessZonesRequireZoneAwareness)
+
+	cfg.ZoneAwarenessEnabled = true
+	assert.NoError(t, cfg.Validate(log.NewNopLogger()))
+}
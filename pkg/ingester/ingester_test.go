@@ -52,6 +52,7 @@ import (
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	grpc_metadata "google.golang.org/grpc/metadata"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
 	"github.com/grafana/mimir/pkg/ingester/client"
@@ -64,6 +65,7 @@ import (
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/chunkcompat"
+	"github.com/grafana/mimir/pkg/util/globalerror"
 	util_math "github.com/grafana/mimir/pkg/util/math"
 	"github.com/grafana/mimir/pkg/util/push"
 	util_test "github.com/grafana/mimir/pkg/util/test"
@@ -1005,6 +1007,112 @@ func TestIngester_Push(t *testing.T) {
 	}
 }
 
+func TestIngester_ExemplarsAreReplayedOnRestart(t *testing.T) {
+	metricLabels := labels.FromStrings(labels.MetricName, "test")
+	// The exemplar is built for each use because the pushed request is reused once ingested.
+	newExemplar := func() *mimirpb.Exemplar {
+		return &mimirpb.Exemplar{Labels: []mimirpb.LabelAdapter{{Name: "traceID", Value: "123"}}, TimestampMs: 1000, Value: 1000}
+	}
+
+	tests := map[string]func(cfg *Config){
+		"TSDB write-ahead log": func(*Config) {},
+		"memory snapshot on shutdown": func(cfg *Config) {
+			cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown = true
+		},
+		"shared write-ahead log": func(cfg *Config) {
+			cfg.BlocksStorageConfig.TSDB.SharedWALEnabled = true
+		},
+	}
+
+	for name, configure := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := defaultIngesterTestConfig(t)
+			configure(&cfg)
+			dataDir := t.TempDir()
+
+			limits := defaultLimitsTestConfig()
+			limits.MaxGlobalExemplarsPerUser = 10
+
+			ctx := user.InjectOrgID(context.Background(), userID)
+
+			i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+
+			// Wait until it's healthy, so that the local exemplars limit is computed.
+			test.Poll(t, 1*time.Second, 1, func() interface{} {
+				return i.lifecycler.HealthyInstancesCount()
+			})
+
+			req := mimirpb.ToWriteRequest([]labels.Labels{metricLabels}, []mimirpb.Sample{{Value: 1, TimestampMs: 1000}}, []*mimirpb.Exemplar{newExemplar()}, nil, mimirpb.API)
+			_, err = i.Push(ctx, req)
+			require.NoError(t, err)
+			require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+			// Restart the ingester: the exemplars are replayed.
+			i, err = prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+			t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i)) })
+
+			res, err := i.QueryExemplars(ctx, &client.ExemplarQueryRequest{
+				StartTimestampMs: math.MinInt64,
+				EndTimestampMs:   math.MaxInt64,
+				Matchers: []*client.LabelMatchers{
+					{Matchers: []*client.LabelMatcher{{Type: client.REGEX_MATCH, Name: labels.MetricName, Value: ".*"}}},
+				},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []mimirpb.TimeSeries{{Labels: mimirpb.FromLabelsToLabelAdapters(metricLabels), Exemplars: []mimirpb.Exemplar{*newExemplar()}}}, res.Timeseries)
+		})
+	}
+}
+
+func TestIngester_maxExemplars(t *testing.T) {
+	tests := map[string]struct {
+		maxGlobalExemplarsPerUser int
+		ringIngesterCount         int
+		expected                  int
+	}{
+		"exemplars are disabled": {
+			maxGlobalExemplarsPerUser: 0,
+			ringIngesterCount:         1,
+			expected:                  0,
+		},
+		"local limit is used": {
+			maxGlobalExemplarsPerUser: 100,
+			ringIngesterCount:         10,
+			expected:                  10,
+		},
+		"local limit of 0 disables the exemplars": {
+			maxGlobalExemplarsPerUser: 5,
+			ringIngesterCount:         10,
+			expected:                  0,
+		},
+		"global limit is used while the ring is empty": {
+			maxGlobalExemplarsPerUser: 100,
+			ringIngesterCount:         0,
+			expected:                  100,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			ring := &ringCountMock{}
+			ring.On("InstancesCount").Return(testData.ringIngesterCount)
+			ring.On("ZonesCount").Return(1)
+
+			limits, err := validation.NewOverrides(validation.Limits{MaxGlobalExemplarsPerUser: testData.maxGlobalExemplarsPerUser}, nil)
+			require.NoError(t, err)
+
+			i := &Ingester{limits: limits, limiter: NewLimiter(limits, ring, 1, false)}
+			assert.Equal(t, testData.expected, i.maxExemplars(userID))
+		})
+	}
+}
+
 func TestIngester_Push_ShouldCorrectlyTrackMetricsInMultiTenantScenario(t *testing.T) {
 	metricLabelAdapters := []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "test"}}
 	metricLabels := mimirpb.FromLabelAdaptersToLabels(metricLabelAdapters)
@@ -2152,6 +2260,83 @@ func TestIngester_LabelNames_ShouldNotCreateTSDBIfDoesNotExists(t *testing.T) {
 	assert.False(t, tsdbCreated)
 }
 
+func TestIngester_Push_ShouldIngestCreatedTimestampAsZeroSample(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy.
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	lbls := labels.FromStrings(labels.MetricName, "counter")
+
+	// The created timestamp is sent along with every sample of the counter, and the zero sample
+	// is expected to be out-of-order once the counter samples have been ingested.
+	for _, sample := range []mimirpb.Sample{{TimestampMs: 2000, Value: 5}, {TimestampMs: 3000, Value: 7}} {
+		req, _, _, _ := mockWriteRequest(t, lbls, sample.Value, sample.TimestampMs)
+		req.Timeseries[0].CreatedTimestampMs = 1000
+
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	res, _, err := runTestQuery(ctx, t, i, labels.MatchEqual, labels.MetricName, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{{
+		Metric: model.Metric{labels.MetricName: "counter"},
+		Values: []model.SamplePair{{Timestamp: 1000, Value: 0}, {Timestamp: 2000, Value: 5}, {Timestamp: 3000, Value: 7}},
+	}}, res)
+}
+
+func TestIngester_Push_ShouldIngestCreatedTimestampZeroSampleRegardlessOfTheMinSampleInterval(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.MinSampleInterval = model.Duration(30 * time.Second)
+
+	registry := prometheus.NewRegistry()
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy.
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	lbls := labels.FromStrings(labels.MetricName, "counter")
+
+	// The zero sample precedes the first sample of the counter by less than the min sample interval,
+	// and the samples are received exactly at the min sample interval.
+	for _, sample := range []mimirpb.Sample{{TimestampMs: 2000, Value: 5}, {TimestampMs: 32000, Value: 7}} {
+		req, _, _, _ := mockWriteRequest(t, lbls, sample.Value, sample.TimestampMs)
+		req.Timeseries[0].CreatedTimestampMs = 1000
+
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	res, _, err := runTestQuery(ctx, t, i, labels.MatchEqual, labels.MetricName, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{{
+		Metric: model.Metric{labels.MetricName: "counter"},
+		Values: []model.SamplePair{{Timestamp: 1000, Value: 0}, {Timestamp: 2000, Value: 5}, {Timestamp: 32000, Value: 7}},
+	}}, res)
+
+	// The zero samples out-of-order on the second push are not tracked as failures.
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_created_timestamp_zero_samples_failures_total The total number of zero samples, ingested at the created timestamps of the series, that errored on ingestion for reasons other than being out-of-order or duplicated.
+		# TYPE cortex_ingester_created_timestamp_zero_samples_failures_total counter
+		cortex_ingester_created_timestamp_zero_samples_failures_total 0
+	`), "cortex_ingester_created_timestamp_zero_samples_failures_total"))
+}
+
 func TestIngester_Push_ShouldNotCreateTSDBIfNotInActiveState(t *testing.T) {
 	// Configure the lifecycler to not immediately join the ring, to make sure
 	// the ingester will NOT be in the ACTIVE state when we'll push samples.
@@ -2766,6 +2951,141 @@ func TestIngester_QueryStream(t *testing.T) {
 	}
 }
 
+func TestIngester_QueryStream_ShouldHonorBatchSize(t *testing.T) {
+	const (
+		numSeries = 25
+		batchSize = 10
+	)
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.QueryStreamBatchSize = batchSize
+
+	var streamType QueryStreamType
+	cfg.StreamTypeFn = func() QueryStreamType {
+		return streamType
+	}
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy.
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	for seriesID := 0; seriesID < numSeries; seriesID++ {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "foo", "series_id", strconv.Itoa(seriesID)), float64(seriesID), int64(seriesID))
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	// Create a GRPC server used to query back the data.
+	serv := grpc.NewServer(grpc.StreamInterceptor(middleware.StreamServerUserHeaderInterceptor))
+	defer serv.GracefulStop()
+	client.RegisterIngesterServer(serv, i)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go func() {
+		require.NoError(t, serv.Serve(listener))
+	}()
+
+	c, err := client.MakeIngesterClient(listener.Addr().String(), defaultClientTestConfig())
+	require.NoError(t, err)
+	defer c.Close()
+
+	for testName, queryStreamType := range map[string]QueryStreamType{"samples": QueryStreamSamples, "chunks": QueryStreamChunks} {
+		t.Run(testName, func(t *testing.T) {
+			streamType = queryStreamType
+
+			s, err := c.QueryStream(ctx, &client.QueryRequest{
+				StartTimestampMs: math.MinInt64,
+				EndTimestampMs:   math.MaxInt64,
+				Matchers:         []*client.LabelMatcher{{Type: client.EQUAL, Name: model.MetricNameLabel, Value: "foo"}},
+			})
+			require.NoError(t, err)
+
+			var batchSizes []int
+			for {
+				resp, err := s.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+
+				batchSizes = append(batchSizes, len(resp.Timeseries)+len(resp.Chunkseries))
+			}
+
+			assert.Equal(t, []int{10, 10, 5}, batchSizes)
+		})
+	}
+}
+
+func TestIngester_QueryStream_ShouldNotReturnChunksOutsideQueriedTimeRange(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.StreamTypeFn = func() QueryStreamType {
+		return QueryStreamChunks
+	}
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy.
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	// Push a series with samples only before some of the queried time ranges, and a series with
+	// samples overlapping them.
+	for ts := int64(0); ts < 100; ts += 10 {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "foo", "series", "outside"), 1, ts)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+	for ts := int64(0); ts < 300; ts += 10 {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "foo", "series", "inside"), 1, ts)
+		_, err = i.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	for _, queryRange := range [][2]int64{{200, 300}, {100, 150}, {400, 500}} {
+		t.Run(fmt.Sprintf("range %d-%d", queryRange[0], queryRange[1]), func(t *testing.T) {
+			s := stream{ctx: ctx}
+			err = i.QueryStream(&client.QueryRequest{
+				StartTimestampMs: queryRange[0],
+				EndTimestampMs:   queryRange[1],
+				Matchers:         []*client.LabelMatcher{{Type: client.EQUAL, Name: model.MetricNameLabel, Value: "foo"}},
+			}, &s)
+			require.NoError(t, err)
+
+			var returnedSeries []string
+			for _, resp := range s.responses {
+				for _, series := range resp.Chunkseries {
+					returnedSeries = append(returnedSeries, mimirpb.FromLabelAdaptersToLabels(series.Labels).Get("series"))
+					for _, c := range series.Chunks {
+						assert.True(t, c.StartTimestampMs <= queryRange[1] && c.EndTimestampMs >= queryRange[0], "chunk %d-%d is outside of the queried time range", c.StartTimestampMs, c.EndTimestampMs)
+					}
+				}
+			}
+
+			if queryRange[0] > 300 {
+				assert.Empty(t, returnedSeries)
+			} else {
+				assert.Equal(t, []string{"inside"}, returnedSeries)
+			}
+		})
+	}
+}
+
 func TestIngester_QueryStreamManySamples(t *testing.T) {
 	// Create ingester.
 	cfg := defaultIngesterTestConfig(t)
@@ -3297,6 +3617,7 @@ func TestIngester_OpenExistingTSDBOnStartup(t *testing.T) {
 	tests := map[string]struct {
 		walReplayConcurrency                         int
 		deprecatedMaxTSDBOpeningConcurrencyOnStartup int
+		walReplayLargeTenantThresholdBytes           uint64
 		setup                                        func(*testing.T, string)
 		check                                        func(*testing.T, *Ingester)
 		expectedErr                                  string
@@ -3431,6 +3752,28 @@ func TestIngester_OpenExistingTSDBOnStartup(t *testing.T) {
 			},
 			expectedErr: "unable to open TSDB for user user2",
 		},
+		"should load all TSDBs when the TSDBs of large tenants are opened with bounded concurrency": {
+			walReplayConcurrency:               2,
+			walReplayLargeTenantThresholdBytes: 10,
+			setup: func(t *testing.T, dir string) {
+				for _, userID := range []string{"user0", "user1", "user2", "user3", "user4"} {
+					require.NoError(t, os.MkdirAll(filepath.Join(dir, userID, "dummy"), 0700))
+				}
+
+				// Create a fake WAL segment larger than the threshold for some tenants. An empty segment
+				// is not valid, so it's just padded with zeros which are read as the end of the segment.
+				for _, userID := range []string{"user1", "user3"} {
+					require.NoError(t, os.MkdirAll(filepath.Join(dir, userID, "wal"), 0700))
+					require.NoError(t, os.WriteFile(filepath.Join(dir, userID, "wal", "00000000"), make([]byte, 100), 0700))
+				}
+			},
+			check: func(t *testing.T, i *Ingester) {
+				require.Equal(t, 5, len(i.tsdbs))
+				for _, userID := range []string{"user0", "user1", "user2", "user3", "user4"} {
+					require.NotNil(t, i.getTSDB(userID))
+				}
+			},
+		},
 		"should load all TSDBs and honor DeprecatedMaxTSDBOpeningConcurrencyOnStartup when walReplayConcurrency = 0": {
 			walReplayConcurrency:                         0,
 			deprecatedMaxTSDBOpeningConcurrencyOnStartup: 2,
@@ -3465,6 +3808,7 @@ func TestIngester_OpenExistingTSDBOnStartup(t *testing.T) {
 			ingesterCfg := defaultIngesterTestConfig(t)
 			ingesterCfg.BlocksStorageConfig.TSDB.Dir = tempDir
 			ingesterCfg.BlocksStorageConfig.TSDB.WALReplayConcurrency = testData.walReplayConcurrency
+			ingesterCfg.BlocksStorageConfig.TSDB.WALReplayLargeTenantThresholdBytes = testData.walReplayLargeTenantThresholdBytes
 			ingesterCfg.BlocksStorageConfig.Bucket.Backend = "s3"
 			ingesterCfg.BlocksStorageConfig.Bucket.S3.Endpoint = "localhost"
 
@@ -3622,6 +3966,67 @@ func TestIngester_seriesCountIsCorrectAfterClosingTSDBForDeletedTenant(t *testin
 	require.Equal(t, int64(0), i.seriesCount.Load())
 }
 
+func TestIngester_rejectWritesWhenTenantDeletionMarkerIsPresent(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+
+	// Create ingester
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+
+	// Use in-memory bucket.
+	bucket := objstore.NewInMemBucket()
+
+	i.bucket = bucket
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	// Writes are accepted until the tenant deletion mark is found.
+	pushSingleSampleWithMetadata(t, i)
+	require.NoError(t, mimir_tsdb.WriteTenantDeletionMark(context.Background(), bucket, userID, nil, mimir_tsdb.NewTenantDeletionMark(time.Now())))
+	pushSingleSampleWithMetadata(t, i)
+
+	// We call shipBlocks to check for deletion marker (it happens inside this method).
+	i.shipBlocks(context.Background(), nil)
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	// The request is built for each push, because the pushed request is reused once ingested.
+	push := func() error {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 0, util.TimeToMillis(time.Now()))
+		_, err := i.Push(ctx, req)
+		return err
+	}
+
+	err = push()
+	require.Error(t, err)
+	assert.Equal(t, httpgrpc.Errorf(http.StatusBadRequest, wrapWithUser(errTenantMarkedForDeletion, userID).Error()), err)
+
+	// Writes are still rejected after the check interval, as long as the TSDB of the tenant is open.
+	i.tenantsMarkedForDeletionMtx.Lock()
+	i.tenantsMarkedForDeletion[userID] = time.Now().Add(-2 * mimir_tsdb.DeletionMarkCheckInterval)
+	i.tenantsMarkedForDeletionMtx.Unlock()
+	i.shipBlocks(context.Background(), nil)
+	err = push()
+	require.Error(t, err)
+	assert.Equal(t, httpgrpc.Errorf(http.StatusBadRequest, wrapWithUser(errTenantMarkedForDeletion, userID).Error()), err)
+
+	// Writes are still rejected once the TSDB of the tenant has been closed.
+	require.Equal(t, tsdbTenantMarkedForDeletion, i.closeAndDeleteUserTSDBIfIdle(userID))
+	err = push()
+	require.Error(t, err)
+	assert.Nil(t, i.getTSDB(userID))
+
+	// Writes are accepted again once the deletion mark has been trusted for the check interval.
+	i.tenantsMarkedForDeletionMtx.Lock()
+	i.tenantsMarkedForDeletion[userID] = time.Now().Add(-2 * mimir_tsdb.DeletionMarkCheckInterval)
+	i.tenantsMarkedForDeletionMtx.Unlock()
+	pushSingleSampleWithMetadata(t, i)
+}
+
 func TestIngester_closeAndDeleteUserTSDBIfIdle_shouldNotCloseTSDBIfShippingIsInProgress(t *testing.T) {
 	ctx := context.Background()
 	cfg := defaultIngesterTestConfig(t)
@@ -4200,6 +4605,47 @@ func Test_Ingester_AllUserStats(t *testing.T) {
 	assert.ElementsMatch(t, expect, res.Stats)
 }
 
+func TestHeadCompactionSlotReached(t *testing.T) {
+	const (
+		blockRange  = 2 * time.Hour
+		slotsWindow = 30 * time.Minute
+	)
+
+	// The head becomes compactable at odd hours with 2h blocks.
+	compactableAt := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	t.Run("should always be reached if the slots window is disabled", func(t *testing.T) {
+		assert.True(t, headCompactionSlotReached(compactableAt, blockRange, 0, "ingester-1", "user-1"))
+	})
+
+	t.Run("should be reached within the window and until the head becomes compactable again", func(t *testing.T) {
+		for _, userID := range []string{"user-1", "user-2", "user-3"} {
+			assert.True(t, headCompactionSlotReached(compactableAt.Add(slotsWindow), blockRange, slotsWindow, "ingester-1", userID))
+			assert.True(t, headCompactionSlotReached(compactableAt.Add(blockRange-time.Nanosecond), blockRange, slotsWindow, "ingester-1", userID))
+		}
+	})
+
+	t.Run("should spread the slots of different tenants over the window", func(t *testing.T) {
+		slots := map[time.Duration]struct{}{}
+		for u := 0; u < 100; u++ {
+			userID := fmt.Sprintf("user-%d", u)
+
+			// Find the first minute since the head became compactable at which the slot is reached.
+			for offset := time.Duration(0); offset <= slotsWindow; offset += time.Minute {
+				if headCompactionSlotReached(compactableAt.Add(offset), blockRange, slotsWindow, "ingester-1", userID) {
+					slots[offset] = struct{}{}
+
+					// The slot is deterministic.
+					assert.True(t, headCompactionSlotReached(compactableAt.Add(offset), blockRange, slotsWindow, "ingester-1", userID))
+					break
+				}
+			}
+		}
+
+		assert.Greater(t, len(slots), 10)
+	})
+}
+
 func TestIngesterCompactIdleBlock(t *testing.T) {
 	cfg := defaultIngesterTestConfig(t)
 	cfg.BlocksStorageConfig.TSDB.ShipConcurrency = 1
@@ -5071,6 +5517,43 @@ func TestIngester_instanceLimitsMetrics(t *testing.T) {
 	`), "cortex_ingester_instance_limits"))
 }
 
+func TestIngester_PushPressure(t *testing.T) {
+	limits := InstanceLimits{MaxInflightPushRequests: 4, MaxInMemorySeries: 4}
+
+	cfg := defaultIngesterTestConfig(t)
+	cfg.InstanceLimitsFn = func() *InstanceLimits { return &limits }
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is healthy
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+
+	// The pressure is the utilization of the most utilized in-flight push requests or ingestion rate instance
+	// limit, while the in-memory series limit is not taken into account.
+	for inflight, expected := range []float64{0, 0.25, 0.5} {
+		i.inflightPushRequests.Store(int64(inflight))
+
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, fmt.Sprintf("test_%d", inflight)), 1, 100000)
+		res, err := i.Push(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, expected, res.Pressure)
+	}
+
+	// The pressure is 0 when no instance limit is configured.
+	limits = InstanceLimits{}
+	req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test_3"), 1, 100000)
+	res, err := i.Push(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, res.Pressure)
+}
+
 func TestIngester_inflightPushRequests(t *testing.T) {
 	limits := InstanceLimits{MaxInflightPushRequests: 1}
 
@@ -5457,6 +5940,173 @@ func TestIngester_Push_SeriesWithBlankLabel(t *testing.T) {
 	assert.Equal(t, expected, res)
 }
 
+func TestIngester_Push_MinSampleInterval(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.MinSampleInterval = model.Duration(30 * time.Second)
+
+	registry := prometheus.NewRegistry()
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	// Wait until the ingester is healthy
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	series := labels.FromStrings(labels.MetricName, "testmetric", "foo", "bar")
+
+	push := func(samples ...mimirpb.Sample) error {
+		lbls := make([]labels.Labels, 0, len(samples))
+		for range samples {
+			lbls = append(lbls, series)
+		}
+		_, err := ing.Push(ctx, mimirpb.ToWriteRequest(lbls, samples, nil, nil, mimirpb.API))
+		return err
+	}
+
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 0, Value: 1}))
+
+	// A sample received before the min interval elapsed is rejected.
+	err = push(mimirpb.Sample{TimestampMs: 10000, Value: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), string(globalerror.SampleTooFrequent))
+
+	// Samples are accepted once the min interval elapsed, and the interval is enforced within the same request too.
+	err = push(mimirpb.Sample{TimestampMs: 30000, Value: 3}, mimirpb.Sample{TimestampMs: 40000, Value: 4}, mimirpb.Sample{TimestampMs: 60000, Value: 5})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), string(globalerror.SampleTooFrequent))
+
+	res, _, err := runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "testmetric")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{
+		{
+			Metric: model.Metric{labels.MetricName: "testmetric", "foo": "bar"},
+			Values: []model.SamplePair{
+				{Timestamp: 0, Value: 1},
+				{Timestamp: 30000, Value: 3},
+				{Timestamp: 60000, Value: 5},
+			},
+		},
+	}, res)
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="sample-too-frequent",user="1"} 2
+	`), "cortex_discarded_samples_total"))
+
+	// Once the last sample timestamps are purged, the next sample is accepted regardless of the interval.
+	ing.purgeSampleIntervals(time.UnixMilli(100000))
+	require.NoError(t, push(mimirpb.Sample{TimestampMs: 70000, Value: 6}))
+}
+
+func TestIngester_Push_IngestAggregationRules(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.IngestAggregationRules = []validation.IngestAggregationRule{
+		{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod"}, DropInput: true},
+		{Selector: `{__name__="cpu_seconds_total"}`, Without: []string{"pod"}},
+	}
+
+	registry := prometheus.NewRegistry()
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	// Wait until the ingester is healthy
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	// The distributors send the series of the rules not dropping their input to be ingested, and all the series
+	// rolled up by the rules to be fed to the aggregation.
+	req := mimirpb.ToWriteRequest(
+		[]labels.Labels{
+			labels.FromStrings(labels.MetricName, "cpu_seconds_total", "job", "api", "pod", "api-1"),
+			labels.FromStrings(labels.MetricName, "cpu_seconds_total", "job", "api", "pod", "api-2"),
+		},
+		[]mimirpb.Sample{{TimestampMs: 1000, Value: 1}, {TimestampMs: 2000, Value: 2}},
+		nil, nil, mimirpb.API,
+	)
+	req.IngestAggregationInputs = mimirpb.ToWriteRequest(
+		[]labels.Labels{
+			labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api", "pod", "api-1"),
+			labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api", "pod", "api-2"),
+			labels.FromStrings(labels.MetricName, "cpu_seconds_total", "job", "api", "pod", "api-1"),
+			labels.FromStrings(labels.MetricName, "cpu_seconds_total", "job", "api", "pod", "api-2"),
+			labels.FromStrings(labels.MetricName, "not_aggregated", "job", "api", "pod", "api-1"),
+		},
+		[]mimirpb.Sample{{TimestampMs: 1000, Value: 10}, {TimestampMs: 2000, Value: 20}, {TimestampMs: 1000, Value: 1}, {TimestampMs: 2000, Value: 2}, {TimestampMs: 1000, Value: 5}},
+		nil, nil, mimirpb.API,
+	).Timeseries
+	_, err = ing.Push(ctx, req)
+	require.NoError(t, err)
+
+	// The aggregated series are the sum of the increases of the series rolled up into them.
+	req = &mimirpb.WriteRequest{IngestAggregationInputs: mimirpb.ToWriteRequest(
+		[]labels.Labels{
+			labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api", "pod", "api-1"),
+			labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api", "pod", "api-2"),
+			labels.FromStrings(labels.MetricName, "cpu_seconds_total", "job", "api", "pod", "api-1"),
+			labels.FromStrings(labels.MetricName, "cpu_seconds_total", "job", "api", "pod", "api-2"),
+		},
+		[]mimirpb.Sample{{TimestampMs: 3000, Value: 25}, {TimestampMs: 4000, Value: 35}, {TimestampMs: 3000, Value: 2}, {TimestampMs: 4000, Value: 4}},
+		nil, nil, mimirpb.API,
+	).Timeseries}
+	_, err = ing.Push(ctx, req)
+	require.NoError(t, err)
+
+	ing.evaluateIngestAggregations(time.UnixMilli(60000))
+
+	// The series fed to the aggregation are not ingested.
+	res, _, err := runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "not_aggregated")
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	// The series of the rule dropping its input are replaced by the aggregated series.
+	res, _, err = runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "http_requests_total")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{
+		{
+			Metric: model.Metric{labels.MetricName: "http_requests_total", "job": "api"},
+			Values: []model.SamplePair{{Timestamp: 60000, Value: 30}},
+		},
+	}, res)
+
+	// The series of the rule not dropping its input are ingested along with the aggregated series.
+	res, _, err = runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "cpu_seconds_total")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{
+		{
+			Metric: model.Metric{labels.MetricName: "cpu_seconds_total", "job": "api"},
+			Values: []model.SamplePair{{Timestamp: 60000, Value: 3}},
+		},
+		{
+			Metric: model.Metric{labels.MetricName: "cpu_seconds_total", "job": "api", "pod": "api-1"},
+			Values: []model.SamplePair{{Timestamp: 1000, Value: 1}},
+		},
+		{
+			Metric: model.Metric{labels.MetricName: "cpu_seconds_total", "job": "api", "pod": "api-2"},
+			Values: []model.SamplePair{{Timestamp: 2000, Value: 2}},
+		},
+	}, res)
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_ingest_aggregation_input_samples_total The total number of samples received for series rolled up by ingest aggregation rules.
+		# TYPE cortex_ingester_ingest_aggregation_input_samples_total counter
+		cortex_ingester_ingest_aggregation_input_samples_total 8
+
+		# HELP cortex_ingester_ingest_aggregation_output_samples_total The total number of samples appended for series computed by ingest aggregation rules.
+		# TYPE cortex_ingester_ingest_aggregation_output_samples_total counter
+		cortex_ingester_ingest_aggregation_output_samples_total 2
+	`), "cortex_ingester_ingest_aggregation_input_samples_total", "cortex_ingester_ingest_aggregation_output_samples_total"))
+}
+
 func TestIngesterUserLimitExceeded(t *testing.T) {
 	limits := defaultLimitsTestConfig()
 	limits.MaxGlobalSeriesPerUser = 1
@@ -5933,7 +6583,6 @@ func TestIngesterActiveSeriesConfigChanges(t *testing.T) {
 	}
 
 	metricNames := []string{
-		"cortex_ingester_active_series_loading",
 		"cortex_ingester_active_series",
 		"cortex_ingester_active_series_custom_tracker",
 	}
@@ -5998,19 +6647,19 @@ func TestIngesterActiveSeriesConfigChanges(t *testing.T) {
 				require.NoError(t, err)
 				ingester.limits = override
 				currentTime = time.Now()
-				// First update reloads the config
+				// First update reloads the config, matching the series already tracked against the new matchers.
 				ingester.updateActiveSeries(currentTime)
 				expectedMetrics = `
 					# HELP cortex_ingester_active_series Number of currently active series per user.
 					# TYPE cortex_ingester_active_series gauge
 					cortex_ingester_active_series{user="other_test_user"} 4
+					cortex_ingester_active_series{user="test_user"} 4
 					# HELP cortex_ingester_active_series_custom_tracker Number of currently active series matching a pre-configured label matchers per user.
 					# TYPE cortex_ingester_active_series_custom_tracker gauge
 					cortex_ingester_active_series_custom_tracker{name="bool_is_false_flagbased",user="other_test_user"} 2
 					cortex_ingester_active_series_custom_tracker{name="bool_is_true_flagbased",user="other_test_user"} 2
-					# HELP cortex_ingester_active_series_loading Indicates that active series configuration is being reloaded, and waiting to become stable. While this metric is non zero, values from active series metrics shouldn't be considered.
-					# TYPE cortex_ingester_active_series_loading gauge
-					cortex_ingester_active_series_loading{user="test_user"} 1
+					cortex_ingester_active_series_custom_tracker{name="team_a",user="test_user"} 2
+					cortex_ingester_active_series_custom_tracker{name="team_b",user="test_user"} 2
 				`
 				require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(expectedMetrics), metricNames...))
 
@@ -6074,13 +6723,13 @@ func TestIngesterActiveSeriesConfigChanges(t *testing.T) {
 					# HELP cortex_ingester_active_series Number of currently active series per user.
 					# TYPE cortex_ingester_active_series gauge
 					cortex_ingester_active_series{user="other_test_user"} 4
+					cortex_ingester_active_series{user="test_user"} 4
 					# HELP cortex_ingester_active_series_custom_tracker Number of currently active series matching a pre-configured label matchers per user.
 					# TYPE cortex_ingester_active_series_custom_tracker gauge
+					cortex_ingester_active_series_custom_tracker{name="bool_is_false_flagbased",user="other_test_user"} 2
 					cortex_ingester_active_series_custom_tracker{name="bool_is_true_flagbased",user="other_test_user"} 2
-            	    cortex_ingester_active_series_custom_tracker{name="bool_is_false_flagbased",user="other_test_user"} 2
-					# HELP cortex_ingester_active_series_loading Indicates that active series configuration is being reloaded, and waiting to become stable. While this metric is non zero, values from active series metrics shouldn't be considered.
-					# TYPE cortex_ingester_active_series_loading gauge
-					cortex_ingester_active_series_loading{user="test_user"} 1
+					cortex_ingester_active_series_custom_tracker{name="bool_is_false_flagbased",user="test_user"} 2
+					cortex_ingester_active_series_custom_tracker{name="bool_is_true_flagbased",user="test_user"} 2
 				`
 				require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(expectedMetrics), metricNames...))
 
@@ -6143,9 +6792,15 @@ func TestIngesterActiveSeriesConfigChanges(t *testing.T) {
 				ingester.limits = override
 				ingester.updateActiveSeries(currentTime)
 				expectedMetrics = `
-					# HELP cortex_ingester_active_series_loading Indicates that active series configuration is being reloaded, and waiting to become stable. While this metric is non zero, values from active series metrics shouldn't be considered.
-					# TYPE cortex_ingester_active_series_loading gauge
-					cortex_ingester_active_series_loading{user="test_user"} 1
+					# HELP cortex_ingester_active_series Number of currently active series per user.
+					# TYPE cortex_ingester_active_series gauge
+					cortex_ingester_active_series{user="test_user"} 4
+					# HELP cortex_ingester_active_series_custom_tracker Number of currently active series matching a pre-configured label matchers per user.
+					# TYPE cortex_ingester_active_series_custom_tracker gauge
+					cortex_ingester_active_series_custom_tracker{name="team_a",user="test_user"} 2
+					cortex_ingester_active_series_custom_tracker{name="team_b",user="test_user"} 2
+					cortex_ingester_active_series_custom_tracker{name="team_c",user="test_user"} 2
+					cortex_ingester_active_series_custom_tracker{name="team_d",user="test_user"} 2
 				`
 				require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(expectedMetrics), metricNames...))
 
@@ -6169,7 +6824,7 @@ func TestIngesterActiveSeriesConfigChanges(t *testing.T) {
 				require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(expectedMetrics), metricNames...))
 			},
 		},
-		"should cleanup loading metric at close": {
+		"should cleanup metrics at close": {
 			activeSeriesConfig: activeSeriesDefaultConfig,
 			tenantLimits:       defaultActiveSeriesTenantOverride,
 			test: func(t *testing.T, ingester *Ingester, gatherer prometheus.Gatherer) {
@@ -6203,10 +6858,10 @@ func TestIngesterActiveSeriesConfigChanges(t *testing.T) {
 				ingester.limits = override
 				ingester.updateActiveSeries(currentTime)
 				expectedMetrics = `
-					# HELP cortex_ingester_active_series_loading Indicates that active series configuration is being reloaded, and waiting to become stable. While this metric is non zero, values from active series metrics shouldn't be considered.
-					# TYPE cortex_ingester_active_series_loading gauge
-					cortex_ingester_active_series_loading{user="test_user"} 1
-					cortex_ingester_active_series_loading{user="other_test_user"} 1
+					# HELP cortex_ingester_active_series Number of currently active series per user.
+					# TYPE cortex_ingester_active_series gauge
+					cortex_ingester_active_series{user="other_test_user"} 4
+					cortex_ingester_active_series{user="test_user"} 4
 				`
 				require.NoError(t, testutil.GatherAndCompare(gatherer, strings.NewReader(expectedMetrics), metricNames...))
 				ingester.closeAllTSDB()
@@ -6420,6 +7075,72 @@ func Test_Ingester_OutOfOrder(t *testing.T) {
 	assert.Equal(t, int64(30*60), usagestats.GetInt(maxOutOfOrderTimeWindowSecondsStatName).Value())
 }
 
+func Test_Ingester_OutOfOrder_SkipOutOfOrder(t *testing.T) {
+	for _, streamChunks := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream chunks=%t", streamChunks), func(t *testing.T) {
+			cfg := defaultIngesterTestConfig(t)
+			cfg.StreamChunksWhenUsingBlocks = streamChunks
+
+			l := defaultLimitsTestConfig()
+			l.OutOfOrderTimeWindow = model.Duration(30 * time.Minute)
+			override, err := validation.NewOverrides(l, nil)
+			require.NoError(t, err)
+
+			i, err := prepareIngesterWithBlockStorageAndOverrides(t, cfg, override, "", "", nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+			defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+			// Wait until it's healthy
+			test.Poll(t, 1*time.Second, 1, func() interface{} {
+				return i.lifecycler.HealthyInstancesCount()
+			})
+
+			ctx := user.InjectOrgID(context.Background(), "test")
+			series := labels.FromStrings(labels.MetricName, "test_1", "status", "200")
+
+			// Push an in-order sample at minute 100, and then out-of-order samples at minutes 90 and 95.
+			for _, minute := range []int64{100, 90, 95} {
+				ts := minute * time.Minute.Milliseconds()
+				_, err := i.Push(ctx, mimirpb.ToWriteRequest([]labels.Labels{series}, []mimirpb.Sample{{TimestampMs: ts, Value: float64(ts)}}, nil, nil, mimirpb.API))
+				require.NoError(t, err)
+			}
+
+			query := func(ctx context.Context) []model.Time {
+				req := &client.QueryRequest{
+					StartTimestampMs: math.MinInt64,
+					EndTimestampMs:   math.MaxInt64,
+					Matchers: []*client.LabelMatcher{
+						{Type: client.EQUAL, Name: model.MetricNameLabel, Value: "test_1"},
+					},
+				}
+
+				s := stream{ctx: ctx}
+				require.NoError(t, i.QueryStream(req, &s))
+
+				res, err := chunkcompat.StreamsToMatrix(model.Earliest, model.Latest, s.responses)
+				require.NoError(t, err)
+				require.Len(t, res, 1)
+
+				var timestamps []model.Time
+				for _, p := range res[0].Values {
+					timestamps = append(timestamps, p.Timestamp)
+				}
+				return timestamps
+			}
+
+			minute := func(m int64) model.Time {
+				return model.Time(m * time.Minute.Milliseconds())
+			}
+
+			assert.Equal(t, []model.Time{minute(90), minute(95), minute(100)}, query(ctx))
+
+			skipCtx := grpc_metadata.NewIncomingContext(ctx, grpc_metadata.Pairs("skip-out-of-order", "true"))
+			assert.Equal(t, []model.Time{minute(100)}, query(skipCtx))
+		})
+	}
+}
+
 // Test_Ingester_OutOfOrder_CompactHead tests that the OOO head is compacted
 // when the compaction is forced or when the TSDB is idle.
 func Test_Ingester_OutOfOrder_CompactHead(t *testing.T) {
@@ -181,8 +181,15 @@ func (l *Limiter) convertGlobalToLocalLimitOrUnlimited(userID string, globalLimi
 }
 
 func (l *Limiter) convertGlobalToLocalLimit(userID string, globalLimit int) int {
+	localLimit, _ := l.tryConvertGlobalToLocalLimit(userID, globalLimit)
+	return localLimit
+}
+
+// tryConvertGlobalToLocalLimit returns the local limit, and false if it can't be computed because there are
+// no ingesters in the ring. In the latter case, the returned local limit is 0.
+func (l *Limiter) tryConvertGlobalToLocalLimit(userID string, globalLimit int) (int, bool) {
 	if globalLimit == 0 {
-		return 0
+		return 0, true
 	}
 
 	zonesCount := l.getZonesCount()
@@ -208,13 +215,22 @@ func (l *Limiter) convertGlobalToLocalLimit(userID string, globalLimit int) int
 	// when zone-aware replication is enabled but ingesters in a zone have been scaled down.
 	// In those cases we ignore the global limit.
 	if ingestersInZoneCount == 0 {
-		return 0
+		return 0, false
 	}
 
 	// Global limit is equally distributed among all the active zones.
 	// The portion of global limit related to each zone is then equally distributed
 	// among all the ingesters belonging to that zone.
-	return int((float64(globalLimit*l.replicationFactor) / float64(zonesCount)) / float64(ingestersInZoneCount))
+	return int((float64(globalLimit*l.getReplicationFactor(userID)) / float64(zonesCount)) / float64(ingestersInZoneCount)), true
+}
+
+// getReplicationFactor returns the replication factor of the series of the input tenant, which can be
+// lower than the ingesters ring one.
+func (l *Limiter) getReplicationFactor(userID string) int {
+	if replicationFactor := l.limits.IngestionReplicationFactor(userID); replicationFactor > 0 && replicationFactor < l.replicationFactor {
+		return replicationFactor
+	}
+	return l.replicationFactor
 }
 
 func (l *Limiter) getShardSize(userID string) int {
@@ -78,6 +78,7 @@ func runLimiterMaxFunctionTest(
 		ringZonesCount           int
 		ingestersInZoneCount     int
 		shardSize                int
+		tenantReplicationFactor  int
 		expectedValue            int
 	}{
 		"zone-awareness disabled, limit is disabled": {
@@ -121,6 +122,20 @@ func runLimiterMaxFunctionTest(
 			shardSize:             20,  // Greater than number of ingesters.
 			expectedValue:         300, // (1000 / 10 ingesters) * 3 replication factor
 		},
+		"zone-awareness disabled, limit is enabled with replication-factor=3 and tenant replication-factor=1": {
+			globalLimit:             1000,
+			ringReplicationFactor:   3,
+			ringIngesterCount:       10,
+			tenantReplicationFactor: 1,
+			expectedValue:           100, // (1000 / 10 ingesters) * 1 tenant replication factor
+		},
+		"zone-awareness disabled, limit is enabled with replication-factor=3 and tenant replication-factor > replication-factor": {
+			globalLimit:             1000,
+			ringReplicationFactor:   3,
+			ringIngesterCount:       10,
+			tenantReplicationFactor: 5,   // Ignored because greater than the ring replication factor.
+			expectedValue:           300, // (1000 / 10 ingesters) * 3 replication factor
+		},
 
 		"zone-awareness enabled, limit is disabled": {
 			globalLimit:              0,
@@ -281,6 +296,17 @@ func runLimiterMaxFunctionTest(
 			shardSize:                20,  // Greater than number of ingesters.
 			expectedValue:            300, // (900 / 3 zones / 3 ingesters per zone) * 3 replication factor
 		},
+		"zone-awareness enabled, limit is enabled with replication-factor=3, tenant replication-factor=2, all ingesters up and running, and shard size 0": {
+			globalLimit:              900,
+			ringReplicationFactor:    3,
+			ringIngesterCount:        9,
+			ringZoneAwarenessEnabled: true,
+			ringZonesCount:           3,
+			ingestersInZoneCount:     3, // 9 ingesters / 3 zones
+			shardSize:                0,
+			tenantReplicationFactor:  2,
+			expectedValue:            200, // (900 / 3 zones / 3 ingesters per zone) * 2 tenant replication factor
+		},
 	}
 
 	for testName, testData := range tests {
@@ -294,7 +320,7 @@ func runLimiterMaxFunctionTest(
 			ring.On("ZonesCount").Return(testData.ringZonesCount)
 
 			// Mock limits
-			limits := validation.Limits{IngestionTenantShardSize: testData.shardSize}
+			limits := validation.Limits{IngestionTenantShardSize: testData.shardSize, IngestionReplicationFactor: testData.tenantReplicationFactor}
 			applyLimits(&limits, testData.globalLimit)
 
 			overrides, err := validation.NewOverrides(limits, nil)
@@ -522,6 +548,60 @@ func TestLimiter_AssertMaxMetricsWithMetadataPerUser(t *testing.T) {
 	}
 }
 
+func TestLimiter_tryConvertGlobalToLocalLimit(t *testing.T) {
+	tests := map[string]struct {
+		globalLimit        int
+		ringIngesterCount  int
+		expectedLocalLimit int
+		expectedOK         bool
+	}{
+		"limit is disabled": {
+			globalLimit:        0,
+			ringIngesterCount:  0,
+			expectedLocalLimit: 0,
+			expectedOK:         true,
+		},
+		"ring is empty": {
+			globalLimit:        1000,
+			ringIngesterCount:  0,
+			expectedLocalLimit: 0,
+			expectedOK:         false,
+		},
+		"local limit is rounded down to 0": {
+			globalLimit:        1,
+			ringIngesterCount:  10,
+			expectedLocalLimit: 0,
+			expectedOK:         true,
+		},
+		"local limit is computed": {
+			globalLimit:        1000,
+			ringIngesterCount:  10,
+			expectedLocalLimit: 300,
+			expectedOK:         true,
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			// Mock the ring
+			ring := &ringCountMock{}
+			ring.On("InstancesCount").Return(testData.ringIngesterCount)
+			ring.On("ZonesCount").Return(1)
+
+			limits, err := validation.NewOverrides(validation.Limits{}, nil)
+			require.NoError(t, err)
+
+			limiter := NewLimiter(limits, ring, 3, false)
+			localLimit, ok := limiter.tryConvertGlobalToLocalLimit("test", testData.globalLimit)
+
+			assert.Equal(t, testData.expectedLocalLimit, localLimit)
+			assert.Equal(t, testData.expectedOK, ok)
+		})
+	}
+}
+
 func TestLimiter_FormatError(t *testing.T) {
 	// Mock the ring
 	ring := &ringCountMock{}
@@ -24,17 +24,23 @@ type ingesterMetrics struct {
 	ingestedExemplarsFail prometheus.Counter
 	ingestedMetadataFail  prometheus.Counter
 
+	createdTimestampZeroSamplesFail prometheus.Counter
+
 	queries          prometheus.Counter
 	queriedSamples   prometheus.Histogram
 	queriedExemplars prometheus.Histogram
 	queriedSeries    prometheus.Histogram
 
+	queriedChunksPruned prometheus.Counter
+
+	ingestAggregationInputSamples  prometheus.Counter
+	ingestAggregationOutputSamples prometheus.Counter
+
 	memMetadata             prometheus.Gauge
 	memUsers                prometheus.Gauge
 	memMetadataCreatedTotal *prometheus.CounterVec
 	memMetadataRemovedTotal *prometheus.CounterVec
 
-	activeSeriesLoading               *prometheus.GaugeVec
 	activeSeriesPerUser               *prometheus.GaugeVec
 	activeSeriesCustomTrackersPerUser *prometheus.GaugeVec
 
@@ -49,6 +55,7 @@ type ingesterMetrics struct {
 	// Head compactions metrics.
 	compactionsTriggered   prometheus.Counter
 	compactionsFailed      prometheus.Counter
+	handoffs               *prometheus.CounterVec
 	appenderAddDuration    prometheus.Histogram
 	appenderCommitDuration prometheus.Histogram
 	idleTsdbChecks         *prometheus.CounterVec
@@ -123,6 +130,10 @@ func newIngesterMetrics(
 			Name: "cortex_ingester_ingested_metadata_failures_total",
 			Help: "The total number of metadata that errored on ingestion.",
 		}),
+		createdTimestampZeroSamplesFail: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_created_timestamp_zero_samples_failures_total",
+			Help: "The total number of zero samples, ingested at the created timestamps of the series, that errored on ingestion for reasons other than being out-of-order or duplicated.",
+		}),
 		queries: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_ingester_queries_total",
 			Help: "The total number of queries the ingester has handled.",
@@ -145,6 +156,18 @@ func newIngesterMetrics(
 			// A reasonable upper bound is around 100k - 10*(8^(6-1)) = 327k.
 			Buckets: prometheus.ExponentialBuckets(10, 8, 6),
 		}),
+		queriedChunksPruned: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_queried_chunks_pruned_total",
+			Help: "The total number of chunks not returned from queries because fully outside of the queried time range.",
+		}),
+		ingestAggregationInputSamples: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_ingest_aggregation_input_samples_total",
+			Help: "The total number of samples received for series rolled up by ingest aggregation rules.",
+		}),
+		ingestAggregationOutputSamples: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_ingest_aggregation_output_samples_total",
+			Help: "The total number of samples appended for series computed by ingest aggregation rules.",
+		}),
 		memMetadata: promauto.With(r).NewGauge(prometheus.GaugeOpts{
 			Name: "cortex_ingester_memory_metadata",
 			Help: "The current number of metadata in memory.",
@@ -226,12 +249,6 @@ func newIngesterMetrics(
 			return 0
 		}),
 
-		// Not registered automatically, but only if activeSeriesEnabled is true.
-		activeSeriesLoading: promauto.With(activeSeriesReg).NewGaugeVec(prometheus.GaugeOpts{
-			Name: "cortex_ingester_active_series_loading",
-			Help: "Indicates that active series configuration is being reloaded, and waiting to become stable. While this metric is non zero, values from active series metrics shouldn't be considered.",
-		}, []string{"user"}),
-
 		// Not registered automatically, but only if activeSeriesEnabled is true.
 		activeSeriesPerUser: promauto.With(activeSeriesReg).NewGaugeVec(prometheus.GaugeOpts{
 			Name: "cortex_ingester_active_series",
@@ -253,6 +270,10 @@ func newIngesterMetrics(
 			Name: "cortex_ingester_tsdb_compactions_failed_total",
 			Help: "Total number of compactions that failed.",
 		}),
+		handoffs: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_handoffs_total",
+			Help: "Total number of TSDB blocks handoffs, by role of the ingester in the handoff and outcome.",
+		}, []string{"role", "outcome"}),
 		appenderAddDuration: promauto.With(r).NewHistogram(prometheus.HistogramOpts{
 			Name:    "cortex_ingester_tsdb_appender_add_duration_seconds",
 			Help:    "The total time it takes for a push request to add samples to the TSDB appender.",
@@ -298,7 +319,6 @@ func (m *ingesterMetrics) deletePerGroupMetricsForUser(userID, group string) {
 }
 
 func (m *ingesterMetrics) deletePerUserCustomTrackerMetrics(userID string, customTrackerMetrics []string) {
-	m.activeSeriesLoading.DeleteLabelValues(userID)
 	m.activeSeriesPerUser.DeleteLabelValues(userID)
 	for _, name := range customTrackerMetrics {
 		m.activeSeriesCustomTrackersPerUser.DeleteLabelValues(userID, name)
@@ -312,6 +332,7 @@ type discardedMetrics struct {
 	newValueForTimestamp *prometheus.CounterVec
 	perUserSeriesLimit   *prometheus.CounterVec
 	perMetricSeriesLimit *prometheus.CounterVec
+	sampleTooFrequent    *prometheus.CounterVec
 }
 
 func newDiscardedMetrics(r prometheus.Registerer) *discardedMetrics {
@@ -322,6 +343,7 @@ func newDiscardedMetrics(r prometheus.Registerer) *discardedMetrics {
 		newValueForTimestamp: validation.DiscardedSamplesCounter(r, newValueForTimestamp),
 		perUserSeriesLimit:   validation.DiscardedSamplesCounter(r, perUserSeriesLimit),
 		perMetricSeriesLimit: validation.DiscardedSamplesCounter(r, perMetricSeriesLimit),
+		sampleTooFrequent:    validation.DiscardedSamplesCounter(r, sampleTooFrequent),
 	}
 }
 
@@ -332,6 +354,7 @@ func (m *discardedMetrics) DeletePartialMatch(filter prometheus.Labels) {
 	m.newValueForTimestamp.DeletePartialMatch(filter)
 	m.perUserSeriesLimit.DeletePartialMatch(filter)
 	m.perMetricSeriesLimit.DeletePartialMatch(filter)
+	m.sampleTooFrequent.DeletePartialMatch(filter)
 }
 
 func (m *discardedMetrics) DeleteLabelValues(userID string, group string) {
@@ -341,6 +364,7 @@ func (m *discardedMetrics) DeleteLabelValues(userID string, group string) {
 	m.newValueForTimestamp.DeleteLabelValues(userID, group)
 	m.perUserSeriesLimit.DeleteLabelValues(userID, group)
 	m.perMetricSeriesLimit.DeleteLabelValues(userID, group)
+	m.sampleTooFrequent.DeleteLabelValues(userID, group)
 }
 
 // TSDB metrics collector. Each tenant has its own registry, that TSDB code uses.
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+var errSampleTooFrequent = errors.New("sample received more frequently than the min sample interval")
+
+// sampleIntervalTracker keeps track of the timestamp of the last sample appended to each series
+// of a tenant, in order to enforce the per-tenant min sample interval.
+type sampleIntervalTracker struct {
+	mtx  sync.Mutex
+	last map[storage.SeriesRef]int64
+}
+
+func newSampleIntervalTracker() *sampleIntervalTracker {
+	return &sampleIntervalTracker{
+		last: map[storage.SeriesRef]int64{},
+	}
+}
+
+func (t *sampleIntervalTracker) get(ref storage.SeriesRef) (int64, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	ts, ok := t.last[ref]
+	return ts, ok
+}
+
+func (t *sampleIntervalTracker) update(timestamps map[storage.SeriesRef]int64) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for ref, ts := range timestamps {
+		if last, ok := t.last[ref]; !ok || ts > last {
+			t.last[ref] = ts
+		}
+	}
+}
+
+// purge removes all series whose last sample timestamp is older than the deadline (in milliseconds).
+func (t *sampleIntervalTracker) purge(deadline int64) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for ref, ts := range t.last {
+		if ts < deadline {
+			delete(t.last, ref)
+		}
+	}
+}
+
+// minSampleIntervalAppender is an extendedAppender rejecting the samples appended to a series
+// less than minInterval after the last sample of the same series. The last samples timestamps
+// are tracked only once the appender is successfully committed.
+type minSampleIntervalAppender struct {
+	extendedAppender
+
+	tracker     *sampleIntervalTracker
+	minInterval int64
+	pending     map[storage.SeriesRef]int64
+}
+
+func newMinSampleIntervalAppender(app extendedAppender, tracker *sampleIntervalTracker, minInterval time.Duration) *minSampleIntervalAppender {
+	return &minSampleIntervalAppender{
+		extendedAppender: app,
+		tracker:          tracker,
+		minInterval:      minInterval.Milliseconds(),
+		pending:          map[storage.SeriesRef]int64{},
+	}
+}
+
+func (a *minSampleIntervalAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	if a.tooFrequent(ref, t) {
+		return 0, errSampleTooFrequent
+	}
+
+	ref, err := a.extendedAppender.Append(ref, l, t, v)
+	if err == nil {
+		a.track(ref, t)
+	}
+	return ref, err
+}
+
+func (a *minSampleIntervalAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	if a.tooFrequent(ref, t) {
+		return 0, errSampleTooFrequent
+	}
+
+	ref, err := a.extendedAppender.AppendHistogram(ref, l, t, h, fh)
+	if err == nil {
+		a.track(ref, t)
+	}
+	return ref, err
+}
+
+func (a *minSampleIntervalAppender) Commit() error {
+	if err := a.extendedAppender.Commit(); err != nil {
+		return err
+	}
+
+	a.tracker.update(a.pending)
+	return nil
+}
+
+// tooFrequent returns whether the sample at timestamp t is less than minInterval after the last sample
+// of the series. Samples older than or equal to the last one are left to the TSDB, which handles them
+// as out-of-order or duplicated samples.
+func (a *minSampleIntervalAppender) tooFrequent(ref storage.SeriesRef, t int64) bool {
+	if ref == 0 {
+		return false
+	}
+
+	last, ok := a.pending[ref]
+	if !ok {
+		last, ok = a.tracker.get(ref)
+	}
+	return ok && t > last && t-last < a.minInterval
+}
+
+func (a *minSampleIntervalAppender) track(ref storage.SeriesRef, t int64) {
+	if last, ok := a.pending[ref]; !ok || t > last {
+		a.pending[ref] = t
+	}
+}
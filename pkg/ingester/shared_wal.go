@@ -0,0 +1,422 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const sharedWALDirname = "shared-wal"
+
+// sharedWAL is a write-ahead log shared by all the tenants of the ingester. On ingesters hosting many
+// small tenants, it replaces the write-ahead log of each TSDB, which is disabled, reducing the number of
+// open files and the number of fsyncs, which otherwise grow with the number of tenants. The TSDB heads
+// are recovered on startup replaying the shared write-ahead log only.
+//
+// Each record has the same format of the witness write-ahead log records: the uvarint length-prefixed
+// tenant ID, followed by the marshalled mimirpb.WriteRequest of the samples, histograms and exemplars
+// committed to the tenant's TSDB. The records of a tenant are logged in commit order.
+type sharedWAL struct {
+	wal     *wlog.WL
+	metrics *sharedWALMetrics
+
+	// The last segment of the write-ahead log at the time of each truncation, used to find the
+	// segments only containing samples already compacted into blocks.
+	checkpointsMx sync.Mutex
+	checkpoints   []witnessWALCheckpoint
+}
+
+func openSharedWAL(dir string, segmentSize int, compress bool, metrics *sharedWALMetrics, logger log.Logger) (*sharedWAL, error) {
+	wal, err := wlog.NewSize(logger, nil, dir, segmentSize, compress)
+	if err != nil {
+		return nil, errors.Wrap(err, "open shared write-ahead log")
+	}
+
+	return &sharedWAL{
+		wal:     wal,
+		metrics: metrics,
+	}, nil
+}
+
+// log appends the series committed to the TSDB of the tenant to the write-ahead log.
+func (w *sharedWAL) log(userID string, timeseries []mimirpb.PreallocTimeseries, source mimirpb.WriteRequest_SourceEnum) error {
+	rec, err := w.encode(userID, timeseries, source)
+	if err != nil {
+		return err
+	}
+	return w.logRecord(rec)
+}
+
+// encode returns the write-ahead log record of the series of the tenant.
+func (w *sharedWAL) encode(userID string, timeseries []mimirpb.PreallocTimeseries, source mimirpb.WriteRequest_SourceEnum) ([]byte, error) {
+	data, err := (&mimirpb.WriteRequest{Timeseries: timeseries, Source: source}).Marshal()
+	if err != nil {
+		w.metrics.failedRecords.Inc()
+		return nil, errors.Wrap(err, "marshal write request")
+	}
+	return encodeTenantRecord(userID, data), nil
+}
+
+// logRecord appends a record returned by encode to the write-ahead log.
+func (w *sharedWAL) logRecord(rec []byte) error {
+	if err := w.wal.Log(rec); err != nil {
+		w.metrics.failedRecords.Inc()
+		return errors.Wrap(err, "append to shared write-ahead log")
+	}
+
+	w.metrics.appendedRecords.Inc()
+	return nil
+}
+
+// replay reads all the records of the write-ahead log, calling fn for each of them. If the write-ahead
+// log is corrupted, the records after the corruption are discarded.
+func (w *sharedWAL) replay(fn func(userID string, req *mimirpb.WriteRequest) error) error {
+	sr, err := wlog.NewSegmentsReader(w.wal.Dir())
+	if err != nil {
+		return errors.Wrap(err, "open shared write-ahead log segments")
+	}
+	defer sr.Close()
+
+	r := wlog.NewReader(sr)
+	for r.Next() {
+		userID, data, err := decodeTenantRecord(r.Record())
+		if err != nil {
+			return err
+		}
+
+		req := &mimirpb.WriteRequest{}
+		if err := req.Unmarshal(data); err != nil {
+			return errors.Wrap(err, "unmarshal shared write-ahead log record")
+		}
+
+		if err := fn(userID, req); err != nil {
+			return err
+		}
+		w.metrics.replayedRecords.Inc()
+	}
+
+	if err := r.Err(); err != nil {
+		var cerr *wlog.CorruptionErr
+		if !errors.As(err, &cerr) {
+			return errors.Wrap(err, "read shared write-ahead log")
+		}
+		if err := w.wal.Repair(err); err != nil {
+			return errors.Wrap(err, "repair corrupted shared write-ahead log")
+		}
+	}
+	return nil
+}
+
+// truncate removes the segments of the write-ahead log only containing samples older than minTime,
+// which is the min time of the samples not compacted into blocks yet, among all tenants.
+func (w *sharedWAL) truncate(now time.Time, minTime int64) error {
+	_, last, err := wlog.Segments(w.wal.Dir())
+	if err != nil {
+		return errors.Wrap(err, "list shared write-ahead log segments")
+	}
+
+	w.checkpointsMx.Lock()
+	defer w.checkpointsMx.Unlock()
+
+	w.checkpoints = append(w.checkpoints, witnessWALCheckpoint{time: now, segment: last})
+
+	// Find the most recent checkpoint recorded before minTime: all the segments before
+	// the one being written at the time of the checkpoint only contain samples ingested
+	// before minTime, so they've been compacted into blocks.
+	idx := -1
+	for i, cp := range w.checkpoints {
+		if cp.time.UnixMilli() < minTime {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+
+	segment := w.checkpoints[idx].segment
+	w.checkpoints = w.checkpoints[idx:]
+
+	if err := w.wal.Truncate(segment); err != nil {
+		return errors.Wrap(err, "truncate shared write-ahead log")
+	}
+	return nil
+}
+
+func (w *sharedWAL) close() error {
+	return w.wal.Close()
+}
+
+// encodeTenantRecord returns a write-ahead log record made of the uvarint length-prefixed tenant ID, followed by data.
+func encodeTenantRecord(userID string, data []byte) []byte {
+	rec := make([]byte, 0, binary.MaxVarintLen64+len(userID)+len(data))
+	rec = binary.AppendUvarint(rec, uint64(len(userID)))
+	rec = append(rec, userID...)
+	return append(rec, data...)
+}
+
+func decodeTenantRecord(rec []byte) (userID string, data []byte, err error) {
+	n, size := binary.Uvarint(rec)
+	if size <= 0 || uint64(len(rec)-size) < n {
+		return "", nil, errors.New("invalid tenant ID in write-ahead log record")
+	}
+	return string(rec[size : size+int(n)]), rec[size+int(n):], nil
+}
+
+type sharedWALMetrics struct {
+	appendedRecords prometheus.Counter
+	failedRecords   prometheus.Counter
+	replayedRecords prometheus.Counter
+}
+
+func newSharedWALMetrics(reg prometheus.Registerer) *sharedWALMetrics {
+	return &sharedWALMetrics{
+		appendedRecords: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_shared_wal_appended_records_total",
+			Help: "The total number of records appended to the write-ahead log shared by all tenants.",
+		}),
+		failedRecords: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_shared_wal_append_failures_total",
+			Help: "The total number of records the ingester failed to append to the write-ahead log shared by all tenants.",
+		}),
+		replayedRecords: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_shared_wal_replayed_records_total",
+			Help: "The total number of records replayed from the write-ahead log shared by all tenants on startup.",
+		}),
+	}
+}
+
+// openSharedWALIfEnabled opens the write-ahead log shared by all tenants if enabled. On failure, the TSDBs
+// opened on startup are closed.
+func (i *Ingester) openSharedWALIfEnabled(ctx context.Context) error {
+	if !i.cfg.BlocksStorageConfig.TSDB.SharedWALEnabled {
+		return nil
+	}
+
+	if err := i.openAndReplaySharedWAL(ctx); err != nil {
+		i.closeAllTSDB()
+		return errors.Wrap(err, "opening shared write-ahead log")
+	}
+	return nil
+}
+
+// waitLifecyclerRegistered waits until the lifecycler has registered the ingester in the ring, and so the
+// number of instances in the ring is known.
+func (i *Ingester) waitLifecyclerRegistered(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i.lifecycler.InstancesCount() == 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for the ingester to be registered in the ring")
+		}
+	}
+	return nil
+}
+
+func (i *Ingester) closeSharedWAL() {
+	if i.sharedWAL == nil {
+		return
+	}
+	if err := i.sharedWAL.close(); err != nil {
+		level.Warn(i.logger).Log("msg", "failed to close shared write-ahead log", "err", err)
+	}
+}
+
+// openAndReplaySharedWAL opens the write-ahead log shared by all tenants, and replays its records into
+// the TSDBs opened on startup. The records of the tenants whose TSDB doesn't exist anymore are skipped,
+// given the TSDB has been closed and deleted after shipping all its blocks.
+func (i *Ingester) openAndReplaySharedWAL(ctx context.Context) error {
+	tsdbCfg := i.cfg.BlocksStorageConfig.TSDB
+
+	wal, err := openSharedWAL(filepath.Join(tsdbCfg.Dir, sharedWALDirname), tsdbCfg.WALSegmentSizeBytes, tsdbCfg.WALCompressionEnabled, i.sharedWALMetrics, i.logger)
+	if err != nil {
+		return err
+	}
+
+	level.Info(i.logger).Log("msg", "replaying shared write-ahead log")
+	startTime := time.Now()
+
+	err = wal.replay(func(userID string, req *mimirpb.WriteRequest) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		db := i.getTSDB(userID)
+		if db == nil {
+			return nil
+		}
+		return i.replaySharedWALRecord(ctx, userID, db, req)
+	})
+	if err != nil {
+		_ = wal.close()
+		return errors.Wrap(err, "replay shared write-ahead log")
+	}
+
+	level.Info(i.logger).Log("msg", "shared write-ahead log replayed", "duration", time.Since(startTime))
+	i.sharedWAL = wal
+	return nil
+}
+
+// replaySharedWALRecord appends the series of a shared write-ahead log record to the TSDB of the tenant.
+// The series limits are applied, like on push. The samples which can't be appended, like the ones already
+// compacted into blocks, are skipped.
+func (i *Ingester) replaySharedWALRecord(ctx context.Context, userID string, db *userTSDB, req *mimirpb.WriteRequest) error {
+	var stats pushStats
+
+	app := db.Appender(ctx).(extendedAppender)
+	minAppendTime, minAppendTimeAvailable := db.Head().AppendableMinValidTime()
+	err := i.pushSamplesToAppender(userID, req.Timeseries, app, time.Now(), &stats, func(func() error) {}, nil, i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime)
+	if err != nil {
+		_ = app.Rollback()
+		return err
+	}
+	return app.Commit()
+}
+
+// sharedWALAppender is an extendedAppender logging the samples, histograms and exemplars successfully
+// appended to the TSDB to the shared write-ahead log once committed. The samples refused by the TSDB
+// or by the wrapping appenders are not logged.
+type sharedWALAppender struct {
+	extendedAppender
+
+	wal    *sharedWAL
+	db     *userTSDB
+	source mimirpb.WriteRequest_SourceEnum
+
+	// Series appended so far, in append order. The series are tracked by reference, so that the samples
+	// of the same series appended one after another are logged in the same mimirpb.TimeSeries.
+	series  []mimirpb.PreallocTimeseries
+	lastRef storage.SeriesRef
+}
+
+func newSharedWALAppender(app extendedAppender, wal *sharedWAL, db *userTSDB, source mimirpb.WriteRequest_SourceEnum) *sharedWALAppender {
+	return &sharedWALAppender{
+		extendedAppender: app,
+		wal:              wal,
+		db:               db,
+		source:           source,
+	}
+}
+
+func (a *sharedWALAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := a.extendedAppender.Append(ref, l, t, v)
+	if err == nil {
+		ts := a.seriesFor(ref, l)
+		ts.Samples = append(ts.Samples, mimirpb.Sample{TimestampMs: t, Value: v})
+	}
+	return ref, err
+}
+
+func (a *sharedWALAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	ref, err := a.extendedAppender.AppendHistogram(ref, l, t, h, fh)
+	if err == nil {
+		ts := a.seriesFor(ref, l)
+		if h != nil {
+			ts.Histograms = append(ts.Histograms, mimirpb.FromHistogramToHistogramProto(t, h))
+		} else {
+			ts.Histograms = append(ts.Histograms, mimirpb.FromFloatHistogramToHistogramProto(t, fh))
+		}
+	}
+	return ref, err
+}
+
+func (a *sharedWALAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	ref, err := a.extendedAppender.AppendExemplar(ref, l, e)
+	if err == nil {
+		ts := a.seriesFor(ref, l)
+		ts.Exemplars = append(ts.Exemplars, mimirpb.Exemplar{Labels: mimirpb.FromLabelsToLabelAdapters(e.Labels), Value: e.Value, TimestampMs: e.Ts})
+	}
+	return ref, err
+}
+
+// Commit commits the appender and logs the committed series to the shared write-ahead log. The commits of the
+// tenant are serialised while logging, so that the records are logged in the same order the samples are committed,
+// and replaying them accepts the same samples. The commits of different tenants are not serialised. The record is
+// encoded before, to keep the commits serialised for as short as possible. The write request fails if logging fails,
+// so the client retries it.
+func (a *sharedWALAppender) Commit() error {
+	if len(a.series) == 0 {
+		return a.extendedAppender.Commit()
+	}
+
+	rec, err := a.wal.encode(a.db.userID, a.series, a.source)
+	if err != nil {
+		_ = a.extendedAppender.Rollback()
+		return err
+	}
+
+	a.db.sharedWALMtx.Lock()
+	defer a.db.sharedWALMtx.Unlock()
+
+	if err := a.extendedAppender.Commit(); err != nil {
+		return err
+	}
+	return a.wal.logRecord(rec)
+}
+
+func (a *sharedWALAppender) Rollback() error {
+	a.series = nil
+	return a.extendedAppender.Rollback()
+}
+
+// seriesFor returns the series to log the sample with the input reference to. The labels are retained,
+// so they must not be modified by the caller once appended.
+func (a *sharedWALAppender) seriesFor(ref storage.SeriesRef, l labels.Labels) *mimirpb.TimeSeries {
+	if len(a.series) == 0 || ref != a.lastRef {
+		a.series = append(a.series, mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{Labels: mimirpb.FromLabelsToLabelAdapters(l)}})
+		a.lastRef = ref
+	}
+	return a.series[len(a.series)-1].TimeSeries
+}
+
+// truncateSharedWAL truncates the write-ahead log shared by all tenants, keeping the segments which
+// may contain samples not compacted into blocks yet, given it's the only write-ahead log of the TSDBs.
+func (i *Ingester) truncateSharedWAL() {
+	if i.sharedWAL == nil {
+		return
+	}
+
+	minTime := int64(math.MaxInt64)
+	for _, userID := range i.getTSDBUsers() {
+		db := i.getTSDB(userID)
+		if db == nil {
+			continue
+		}
+
+		head := db.Head()
+		if head.NumSeries() == 0 {
+			continue
+		}
+
+		// The out-of-order samples not compacted yet can be older than the head min time, and the segments are
+		// truncated by wall clock time, while the samples may be in the future up to the creation grace period.
+		mint := head.MinTime() - i.limits.OutOfOrderTimeWindow(db.userID).Milliseconds() - i.limits.CreationGracePeriod(db.userID).Milliseconds()
+		if mint < minTime {
+			minTime = mint
+		}
+	}
+
+	if err := i.sharedWAL.truncate(time.Now(), minTime); err != nil {
+		level.Warn(i.logger).Log("msg", "failed to truncate shared write-ahead log", "err", err)
+	}
+}
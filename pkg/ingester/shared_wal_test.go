@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestIngester_SharedWAL(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.BlocksStorageConfig.TSDB.SharedWALEnabled = true
+	dataDir := t.TempDir()
+
+	limits := defaultLimitsTestConfig()
+	series := labels.FromStrings(labels.MetricName, "test")
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+
+	for _, userID := range []string{"user-1", "user-2"} {
+		for ts := int64(1); ts <= 3; ts++ {
+			req, _, _, _ := mockWriteRequest(t, series, float64(ts), ts*1000)
+			_, err = i.Push(user.InjectOrgID(context.Background(), userID), req)
+			require.NoError(t, err)
+		}
+
+		// The out-of-order sample is refused, so it's not logged.
+		req, _, _, _ := mockWriteRequest(t, series, 100, 500)
+		_, err = i.Push(user.InjectOrgID(context.Background(), userID), req)
+		require.Error(t, err)
+	}
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+	// The write-ahead log of the TSDBs is disabled, so the samples can only be replayed from the shared write-ahead log.
+	for _, userID := range []string{"user-1", "user-2"} {
+		assert.NoDirExists(t, filepath.Join(dataDir, userID, "wal"))
+	}
+
+	// Restart the ingester: the samples are replayed from the shared write-ahead log.
+	reg := prometheus.NewPedanticRegistry()
+	i, err = prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, reg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i)) })
+
+	for _, userID := range []string{"user-1", "user-2"} {
+		assert.Equal(t, map[string][]float64{series.String(): {1, 2, 3}}, querySharedWALTestTSDB(t, i, userID))
+	}
+	assert.Equal(t, float64(6), testutil.ToFloat64(i.sharedWALMetrics.replayedRecords))
+}
+
+func TestIngester_SharedWAL_ReplayShouldApplyLimits(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.BlocksStorageConfig.TSDB.SharedWALEnabled = true
+	// Set RF=1, so that the global series limit is the local limit.
+	cfg.IngesterRing.ReplicationFactor = 1
+	dataDir := t.TempDir()
+
+	limits := defaultLimitsTestConfig()
+	limits.MaxGlobalSeriesPerUser = 1
+	series1 := labels.FromStrings(labels.MetricName, "series_1")
+	series2 := labels.FromStrings(labels.MetricName, "series_2")
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+
+	req, _, _, _ := mockWriteRequest(t, series1, 1, 1000)
+	_, err = i.Push(user.InjectOrgID(context.Background(), "user-1"), req)
+	require.NoError(t, err)
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+	// Log a series exceeding the series limit, like if the limit has been lowered before the restart.
+	w, err := openSharedWAL(filepath.Join(dataDir, sharedWALDirname), wlog.DefaultSegmentSize, false, newSharedWALMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+	req, _, _, _ = mockWriteRequest(t, series2, 2, 2000)
+	require.NoError(t, w.log("user-1", req.Timeseries, req.Source))
+	require.NoError(t, w.close())
+
+	i, err = prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i)) })
+
+	assert.Equal(t, map[string][]float64{series1.String(): {1}}, querySharedWALTestTSDB(t, i, "user-1"))
+}
+
+func TestSharedWAL_Replay(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openSharedWAL(dir, wlog.DefaultSegmentSize, false, newSharedWALMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+
+	req := mimirpb.ToWriteRequest([]labels.Labels{labels.FromStrings(labels.MetricName, "test")}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API)
+	require.NoError(t, w.log("user-1", req.Timeseries, req.Source))
+	require.NoError(t, w.log("user-2", req.Timeseries, req.Source))
+	require.NoError(t, w.close())
+
+	w, err = openSharedWAL(dir, wlog.DefaultSegmentSize, false, newSharedWALMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, w.close()) })
+
+	var userIDs []string
+	require.NoError(t, w.replay(func(userID string, actual *mimirpb.WriteRequest) error {
+		userIDs = append(userIDs, userID)
+		assert.Equal(t, req.Timeseries, actual.Timeseries)
+		return nil
+	}))
+	assert.Equal(t, []string{"user-1", "user-2"}, userIDs)
+}
+
+func TestSharedWAL_Truncate(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openSharedWAL(dir, wlog.DefaultSegmentSize, false, newSharedWALMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, w.close()) })
+
+	req := mimirpb.ToWriteRequest([]labels.Labels{labels.FromStrings(labels.MetricName, "test")}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API)
+	now := time.Now()
+
+	// Write a record to each of the first 3 segments, truncating after each one while
+	// the heads still contain samples older than the first truncation.
+	for n := 0; n < 3; n++ {
+		require.NoError(t, w.log("test", req.Timeseries, req.Source))
+		require.NoError(t, w.truncate(now.Add(time.Duration(n)*time.Hour), now.UnixMilli()))
+		_, err := w.wal.NextSegment()
+		require.NoError(t, err)
+	}
+
+	first, last, err := wlog.Segments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, first)
+	assert.Equal(t, 3, last)
+
+	// Once the heads only contain samples more recent than the second truncation, the segment 0
+	// is removed, while the segment 1 may still contain samples not compacted yet.
+	require.NoError(t, w.truncate(now.Add(3*time.Hour), now.Add(time.Hour).UnixMilli()+1))
+
+	first, last, err = wlog.Segments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 3, last)
+
+	// Once all the heads are empty, all the segments before the current one are removed.
+	require.NoError(t, w.truncate(now.Add(4*time.Hour), math.MaxInt64))
+
+	first, last, err = wlog.Segments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, first)
+	assert.Equal(t, 3, last)
+}
+
+func querySharedWALTestTSDB(t *testing.T, i *Ingester, userID string) map[string][]float64 {
+	db := i.getTSDB(userID)
+	require.NotNil(t, db)
+
+	q, err := db.Querier(context.Background(), math.MinInt64, math.MaxInt64)
+	require.NoError(t, err)
+	defer q.Close()
+
+	res := map[string][]float64{}
+	ss := q.Select(false, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+	for ss.Next() {
+		it := ss.At().Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			_, v := it.At()
+			res[ss.At().Labels().String()] = append(res[ss.At().Labels().String()], v)
+		}
+		require.NoError(t, it.Err())
+	}
+	require.NoError(t, ss.Err())
+	return res
+}
@@ -22,9 +22,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb/fileutil"
 	"github.com/thanos-io/objstore"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
@@ -80,11 +83,15 @@ type Shipper struct {
 	metrics     *metrics
 	bucket      objstore.Bucket
 	source      metadata.SourceType
+
+	// exemplars is the optional source of the exemplars stored in the uploaded blocks.
+	exemplars storage.ExemplarQueryable
 }
 
 // NewShipper creates a new uploader that detects new TSDB blocks in dir and uploads them to
 // remote if necessary. It attaches the Thanos metadata section in each meta JSON file.
 // If uploadCompacted is enabled, it also uploads compacted blocks which are already in filesystem.
+// If exemplars is not nil, the exemplars in the time range of each uploaded block are stored in the block.
 func NewShipper(
 	logger log.Logger,
 	cfgProvider ShipperConfigProvider,
@@ -93,6 +100,7 @@ func NewShipper(
 	dir string,
 	bucket objstore.Bucket,
 	source metadata.SourceType,
+	exemplars storage.ExemplarQueryable,
 ) *Shipper {
 	if logger == nil {
 		logger = log.NewNopLogger()
@@ -106,6 +114,7 @@ func NewShipper(
 		bucket:      bucket,
 		metrics:     newMetrics(r),
 		source:      source,
+		exemplars:   exemplars,
 	}
 }
 
@@ -209,10 +218,41 @@ func (s *Shipper) upload(ctx context.Context, meta *metadata.Meta) error {
 		meta.Thanos.Labels[mimir_tsdb.OutOfOrderExternalLabel] = mimir_tsdb.OutOfOrderExternalLabelValue
 	}
 
+	// The exemplars of out-of-order blocks are already stored in the in-order blocks covering the same
+	// time range, if any. Failing to store the exemplars shouldn't block the upload.
+	if s.exemplars != nil && !meta.Compaction.FromOutOfOrder() {
+		if err := s.writeExemplars(ctx, blockDir, meta); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to store exemplars in the block", "id", meta.ULID, "err", err)
+		}
+	}
+
 	// Upload block with custom metadata.
 	return block.Upload(ctx, s.logger, s.bucket, blockDir, meta)
 }
 
+// writeExemplars writes the exemplars in the block time range to the block exemplars file.
+func (s *Shipper) writeExemplars(ctx context.Context, blockDir string, meta *metadata.Meta) error {
+	q, err := s.exemplars.ExemplarQuerier(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The block max time is exclusive, while the exemplars selection end is inclusive.
+	res, err := q.Select(meta.MinTime, meta.MaxTime-1, []*labels.Matcher{})
+	if err != nil {
+		return err
+	}
+
+	series := make([]mimirpb.TimeSeries, 0, len(res))
+	for _, r := range res {
+		series = append(series, mimirpb.TimeSeries{
+			Labels:    mimirpb.FromLabelsToLabelAdapters(r.SeriesLabels),
+			Exemplars: mimirpb.FromExemplarsToExemplarProtos(r.Exemplars),
+		})
+	}
+	return block.WriteExemplarsFile(blockDir, series)
+}
+
 // blockMetasFromOldest returns the block meta of each block found in dir
 // sorted by minTime asc.
 func (s *Shipper) blockMetasFromOldest() (metas []*metadata.Meta, _ error) {
@@ -20,10 +20,13 @@ import (
 	"github.com/grafana/dskit/concurrency"
 	"github.com/oklog/ulid"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/objstore"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
@@ -52,7 +55,7 @@ func TestShipper(t *testing.T) {
 	logger := log.NewLogfmtLogger(logs)
 	overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), nil)
 	require.NoError(t, err)
-	s := NewShipper(logger, overrides, "", nil, blocksDir, bkt, metadata.TestSource)
+	s := NewShipper(logger, overrides, "", nil, blocksDir, bkt, metadata.TestSource, nil)
 
 	t.Run("no shipper file yet", func(t *testing.T) {
 		// No shipper file = nothing is reported as shipped.
@@ -194,7 +197,7 @@ func TestShipper_DeceivingUploadErrors(t *testing.T) {
 	logger := log.NewLogfmtLogger(os.Stderr)
 	overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), nil)
 	require.NoError(t, err)
-	s := NewShipper(logger, overrides, "", nil, blocksDir, bkt, metadata.TestSource)
+	s := NewShipper(logger, overrides, "", nil, blocksDir, bkt, metadata.TestSource, nil)
 
 	// Create and upload a block
 	id1 := ulid.MustNew(1, nil)
@@ -260,7 +263,7 @@ func TestIterBlockMetas(t *testing.T) {
 	}.WriteToDir(log.NewNopLogger(), path.Join(dir, id3.String())))
 	overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), nil)
 	require.NoError(t, err)
-	shipper := NewShipper(nil, overrides, "", nil, dir, nil, metadata.TestSource)
+	shipper := NewShipper(nil, overrides, "", nil, dir, nil, metadata.TestSource, nil)
 	metas, err := shipper.blockMetasFromOldest()
 	require.NoError(t, err)
 	require.Equal(t, sort.SliceIsSorted(metas, func(i, j int) bool {
@@ -274,7 +277,7 @@ func TestShipperAddsSegmentFiles(t *testing.T) {
 	inmemory := objstore.NewInMemBucket()
 	overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), nil)
 	require.NoError(t, err)
-	s := NewShipper(nil, overrides, "", nil, dir, inmemory, metadata.TestSource)
+	s := NewShipper(nil, overrides, "", nil, dir, inmemory, metadata.TestSource, nil)
 
 	id := ulid.MustNew(1, nil)
 	blockDir := path.Join(dir, id.String())
@@ -307,6 +310,65 @@ func TestShipperAddsSegmentFiles(t *testing.T) {
 	require.Equal(t, []string{segmentFile}, meta.Thanos.SegmentFiles)
 }
 
+func TestShipperStoresExemplars(t *testing.T) {
+	dir := t.TempDir()
+
+	exemplars, err := tsdb.NewCircularExemplarStorage(10, tsdb.NewExemplarMetrics(nil))
+	require.NoError(t, err)
+
+	series := labels.FromStrings("__name__", "series_1")
+	for _, ts := range []int64{500, 1000, 1500, 2000} {
+		require.NoError(t, exemplars.AddExemplar(series, exemplar.Exemplar{
+			Labels: labels.FromStrings("trace_id", fmt.Sprintf("%d", ts)),
+			Value:  float64(ts),
+			Ts:     ts,
+			HasTs:  true,
+		}))
+	}
+
+	inmemory := objstore.NewInMemBucket()
+	overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), nil)
+	require.NoError(t, err)
+	s := NewShipper(nil, overrides, "", nil, dir, inmemory, metadata.TestSource, exemplars)
+
+	id := ulid.MustNew(1, nil)
+	createBlock(t, dir, id, metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id,
+			MaxTime: 2000,
+			MinTime: 1000,
+			Version: 1,
+			Stats: tsdb.BlockStats{
+				NumSamples: 100,
+			},
+		},
+	})
+
+	uploaded, err := s.Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, uploaded)
+
+	meta, err := block.DownloadMeta(context.Background(), log.NewNopLogger(), inmemory, id)
+	require.NoError(t, err)
+	require.True(t, block.HasExemplars(&meta))
+
+	r, err := inmemory.Get(context.Background(), path.Join(id.String(), block.ExemplarsFilename))
+	require.NoError(t, err)
+	defer r.Close()
+
+	stored, err := block.ReadExemplars(r, 0)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	require.Equal(t, series, mimirpb.FromLabelAdaptersToLabels(stored[0].Labels))
+
+	// Only the exemplars within the block time range are stored.
+	var timestamps []int64
+	for _, e := range stored[0].Exemplars {
+		timestamps = append(timestamps, e.TimestampMs)
+	}
+	require.Equal(t, []int64{1000, 1500}, timestamps)
+}
+
 func TestReadThanosMetaFile(t *testing.T) {
 	t.Run("Missing meta file", func(t *testing.T) {
 		// Create TSDB directory without meta file
@@ -448,7 +510,7 @@ func TestShipper_AddOOOLabel(t *testing.T) {
 			}
 			overrides, err := validation.NewOverrides(defaultLimitsTestConfig(), validation.NewMockTenantLimits(tenantLimits))
 			require.NoError(t, err)
-			s := NewShipper(logger, overrides, "", nil, blocksDir, bkt, metadata.TestSource)
+			s := NewShipper(logger, overrides, "", nil, blocksDir, bkt, metadata.TestSource, nil)
 
 			createBlock(t, blocksDir, tc.meta.ULID, tc.meta)
 
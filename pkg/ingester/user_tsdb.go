@@ -105,6 +105,16 @@ type userTSDB struct {
 	// Block min retention
 	blockMinRetention time.Duration
 
+	// Serialises the commits logged to the write-ahead log shared by all tenants, so that the
+	// records are logged in commit order.
+	sharedWALMtx sync.Mutex
+
+	// Timestamps of the last samples appended, used to enforce the min sample interval.
+	sampleIntervals *sampleIntervalTracker
+
+	// Latest samples of the series rolled up by the ingest aggregation rules.
+	ingestAggregator *ingestAggregator
+
 	// Cached shipped blocks.
 	shippedBlocksMtx sync.Mutex
 	shippedBlocks    map[ulid.ULID]time.Time
@@ -129,6 +139,82 @@ func (u *userTSDB) UnorderedChunkQuerier(ctx context.Context, mint, maxt int64)
 	return u.db.UnorderedChunkQuerier(ctx, mint, maxt)
 }
 
+// InOrderQuerier returns a querier over the in-order data only, excluding the out-of-order head
+// and the blocks compacted from it.
+func (u *userTSDB) InOrderQuerier(mint, maxt int64) (storage.Querier, error) {
+	queriers, err := inOrderQueriers(u, mint, maxt, tsdb.NewBlockQuerier)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewMergeQuerier(queriers, nil, storage.ChainedSeriesMerge), nil
+}
+
+// InOrderChunkQuerier is like InOrderQuerier, but returns a chunk querier.
+func (u *userTSDB) InOrderChunkQuerier(mint, maxt int64) (storage.ChunkQuerier, error) {
+	queriers, err := inOrderQueriers(u, mint, maxt, tsdb.NewBlockChunkQuerier)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewMergeChunkQuerier(queriers, nil, storage.NewCompactingChunkSeriesMerger(storage.ChainedSeriesMerge)), nil
+}
+
+// inOrderQueriers returns the queriers, built with newQuerier, over the blocks not compacted from the
+// out-of-order head and the in-order head. This mirrors what tsdb.DB does when building its queriers.
+func inOrderQueriers[Q interface{ Close() error }](u *userTSDB, mint, maxt int64, newQuerier func(tsdb.BlockReader, int64, int64) (Q, error)) (_ []Q, returnErr error) {
+	var queriers []Q
+	defer func() {
+		if returnErr != nil {
+			// If we fail, all previously opened queriers must be closed.
+			for _, q := range queriers {
+				_ = q.Close()
+			}
+		}
+	}()
+
+	for _, b := range u.db.Blocks() {
+		meta := b.Meta()
+		if meta.Compaction.FromOutOfOrder() || !b.OverlapsClosedInterval(mint, maxt) {
+			continue
+		}
+
+		q, err := newQuerier(b, mint, maxt)
+		if err != nil {
+			return nil, errors.Wrapf(err, "open querier for block %s", b)
+		}
+		queriers = append(queriers, q)
+	}
+
+	head := u.db.Head()
+	if maxt < head.MinTime() {
+		return queriers, nil
+	}
+
+	q, err := newQuerier(tsdb.NewRangeHead(head, mint, maxt), mint, maxt)
+	if err != nil {
+		return nil, errors.Wrap(err, "open querier for head")
+	}
+
+	// Opening the querier registers it in the queue the head truncation waits on, so we check
+	// for a collision with an in-progress truncation only after it has been opened.
+	shouldClose, getNew, newMint := head.IsQuerierCollidingWithTruncation(mint, maxt)
+	if shouldClose {
+		if err := q.Close(); err != nil {
+			return nil, errors.Wrap(err, "close querier for head")
+		}
+	}
+	if getNew {
+		q, err = newQuerier(tsdb.NewRangeHead(head, newMint, maxt), newMint, maxt)
+		if err != nil {
+			return nil, errors.Wrap(err, "open querier for head")
+		}
+	}
+	if !shouldClose || getNew {
+		queriers = append(queriers, q)
+	}
+
+	return queriers, nil
+}
+
 func (u *userTSDB) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
 	return u.db.ExemplarQuerier(ctx)
 }
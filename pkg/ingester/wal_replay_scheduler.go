@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// walReplayScheduler schedules the opening of the existing TSDBs on startup, weighting each tenant
+// by the size of its WAL. Tenants with a WAL larger than a threshold are considered large: they're
+// scheduled first, from the largest one, but no more than maxLargeInFlight of them are opened
+// concurrently. The remaining capacity is used to open the small tenants, from the smallest one,
+// so that a few large tenants can't delay the opening of all other tenants.
+type walReplayScheduler struct {
+	maxLargeInFlight int
+
+	mtx           sync.Mutex
+	small         []string
+	large         []string
+	largeInFlight int
+
+	// released is closed and replaced each time a large tenant completes, in order to wake
+	// up the callers waiting for a large tenant slot.
+	released chan struct{}
+}
+
+// newWALReplayScheduler creates a walReplayScheduler for the input tenants. If largeThresholdBytes is 0,
+// all tenants are considered small and they're scheduled in the input order.
+func newWALReplayScheduler(userIDs []string, walSizes map[string]uint64, largeThresholdBytes uint64, maxLargeInFlight int) *walReplayScheduler {
+	s := &walReplayScheduler{
+		maxLargeInFlight: maxLargeInFlight,
+		released:         make(chan struct{}),
+	}
+
+	if largeThresholdBytes == 0 {
+		s.small = append([]string(nil), userIDs...)
+		return s
+	}
+
+	for _, userID := range userIDs {
+		if walSizes[userID] > largeThresholdBytes {
+			s.large = append(s.large, userID)
+		} else {
+			s.small = append(s.small, userID)
+		}
+	}
+
+	sort.SliceStable(s.small, func(i, j int) bool {
+		return walSizes[s.small[i]] < walSizes[s.small[j]]
+	})
+	sort.SliceStable(s.large, func(i, j int) bool {
+		return walSizes[s.large[i]] > walSizes[s.large[j]]
+	})
+
+	return s
+}
+
+// next returns the next tenant to open, and a function which must be called once the tenant has been
+// opened. If only large tenants are left and no large tenant slot is available, next waits until one
+// is released. It returns false once there are no more tenants to open, or the context is canceled.
+func (s *walReplayScheduler) next(ctx context.Context) (string, func(), bool) {
+	for {
+		s.mtx.Lock()
+
+		if len(s.large) > 0 && s.largeInFlight < s.maxLargeInFlight {
+			userID := s.large[0]
+			s.large = s.large[1:]
+			s.largeInFlight++
+			s.mtx.Unlock()
+
+			return userID, s.releaseLarge, true
+		}
+
+		if len(s.small) > 0 {
+			userID := s.small[0]
+			s.small = s.small[1:]
+			s.mtx.Unlock()
+
+			return userID, func() {}, true
+		}
+
+		if len(s.large) == 0 {
+			s.mtx.Unlock()
+			return "", nil, false
+		}
+
+		// Only large tenants are left, but all slots are in use.
+		released := s.released
+		s.mtx.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return "", nil, false
+		}
+	}
+}
+
+func (s *walReplayScheduler) releaseLarge() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.largeInFlight--
+	close(s.released)
+	s.released = make(chan struct{})
+}
+
+// getWALSize returns the size, in bytes, of the WAL in the input TSDB directory, including checkpoints.
+// It returns 0 if the WAL doesn't exist.
+func getWALSize(tsdbDir string) (uint64, error) {
+	var size uint64
+
+	err := filepath.WalkDir(filepath.Join(tsdbDir, "wal"), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	return size, err
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALReplayScheduler(t *testing.T) {
+	walSizes := map[string]uint64{
+		"small-1": 10,
+		"small-2": 30,
+		"small-3": 20,
+		"large-1": 1000,
+		"large-2": 2000,
+	}
+	userIDs := []string{"small-1", "large-1", "small-2", "small-3", "large-2"}
+
+	t.Run("should schedule tenants in the input order if the large tenant threshold is disabled", func(t *testing.T) {
+		s := newWALReplayScheduler(userIDs, walSizes, 0, 0)
+
+		var actual []string
+		for {
+			userID, done, ok := s.next(context.Background())
+			if !ok {
+				break
+			}
+			done()
+			actual = append(actual, userID)
+		}
+
+		assert.Equal(t, userIDs, actual)
+	})
+
+	t.Run("should schedule large tenants first, with bounded concurrency, and then small tenants in ascending order of WAL size", func(t *testing.T) {
+		s := newWALReplayScheduler(userIDs, walSizes, 100, 1)
+
+		// The largest tenant is scheduled first.
+		userID, doneLarge, ok := s.next(context.Background())
+		require.True(t, ok)
+		assert.Equal(t, "large-2", userID)
+
+		// The other large tenant has to wait, so small tenants are scheduled in the meanwhile.
+		var actual []string
+		for i := 0; i < 3; i++ {
+			userID, done, ok := s.next(context.Background())
+			require.True(t, ok)
+			done()
+			actual = append(actual, userID)
+		}
+		assert.Equal(t, []string{"small-1", "small-3", "small-2"}, actual)
+
+		// Only a large tenant is left, so the scheduler waits until a large tenant slot is released.
+		scheduled := make(chan string)
+		go func() {
+			userID, done, ok := s.next(context.Background())
+			if ok {
+				done()
+			}
+			scheduled <- userID
+		}()
+
+		select {
+		case <-scheduled:
+			require.FailNow(t, "large tenant has been scheduled while the slot is in use")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		doneLarge()
+		assert.Equal(t, "large-1", <-scheduled)
+
+		_, _, ok = s.next(context.Background())
+		assert.False(t, ok)
+	})
+
+	t.Run("should stop waiting for a large tenant slot when the context is canceled", func(t *testing.T) {
+		s := newWALReplayScheduler([]string{"large-1", "large-2"}, walSizes, 100, 1)
+
+		_, _, ok := s.next(context.Background())
+		require.True(t, ok)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, ok = s.next(ctx)
+		assert.False(t, ok)
+	})
+}
+
+func TestGetWALSize(t *testing.T) {
+	dir := t.TempDir()
+
+	// The WAL doesn't exist.
+	size, err := getWALSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), size)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "wal", "checkpoint.00000001"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wal", "00000002"), make([]byte, 100), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wal", "checkpoint.00000001", "00000000"), make([]byte, 20), 0700))
+
+	// Files outside the WAL are not counted.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "chunks_head"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "chunks_head", "00000001"), make([]byte, 1000), 0700))
+
+	size, err = getWALSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(120), size)
+}
@@ -0,0 +1,414 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/extract"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const witnessWALDirname = "witness-wal"
+
+// witnessWAL is the storage of an ingester running in a witness zone. The witness acknowledges
+// the writes once persisted to its write-ahead log, without building any queryable state, so that
+// it can take part in the write quorum of deployments with only two zones holding the series.
+//
+// Each record of the write-ahead log is the uvarint length-prefixed tenant ID, followed by the
+// marshalled mimirpb.WriteRequest.
+//
+// The series appended within the retention are tracked by tenant, in order to enforce the per-tenant
+// series limits like the ingesters holding the series do.
+type witnessWAL struct {
+	wal       *wlog.WL
+	retention time.Duration
+	metrics   *witnessMetrics
+
+	limiter        *Limiter
+	ignoredMetrics map[string]struct{}
+
+	usersMx sync.Mutex
+	users   map[string]*witnessUserSeries
+
+	// The last segment of the write-ahead log at the time of each truncation, used to find the
+	// segments only containing records older than the retention.
+	checkpointsMx sync.Mutex
+	checkpoints   []witnessWALCheckpoint
+}
+
+type witnessWALCheckpoint struct {
+	time    time.Time
+	segment int
+}
+
+// witnessUserSeries holds the series of a tenant appended to the witness write-ahead log.
+type witnessUserSeries struct {
+	mtx            sync.Mutex
+	series         map[uint64]witnessSeries // By labels hash.
+	seriesInMetric *metricCounter
+}
+
+type witnessSeries struct {
+	metricName string
+	lastSeen   time.Time
+}
+
+// openWitnessWAL opens the witness write-ahead log. The series limits aren't enforced if limiter is nil.
+func openWitnessWAL(dir string, retention time.Duration, segmentSize int, compress bool, limiter *Limiter, ignoredMetrics map[string]struct{}, metrics *witnessMetrics, logger log.Logger) (*witnessWAL, error) {
+	wal, err := wlog.NewSize(logger, nil, dir, segmentSize, compress)
+	if err != nil {
+		return nil, errors.Wrap(err, "open witness write-ahead log")
+	}
+
+	return &witnessWAL{
+		wal:            wal,
+		retention:      retention,
+		metrics:        metrics,
+		limiter:        limiter,
+		ignoredMetrics: ignoredMetrics,
+		users:          map[string]*witnessUserSeries{},
+	}, nil
+}
+
+// replay reads all the records of the write-ahead log, tracking their series as last seen at now.
+// If the write-ahead log is corrupted, the records after the corruption are discarded.
+func (w *witnessWAL) replay(now time.Time) error {
+	sr, err := wlog.NewSegmentsReader(w.wal.Dir())
+	if err != nil {
+		return errors.Wrap(err, "open witness write-ahead log segments")
+	}
+	defer sr.Close()
+
+	r := wlog.NewReader(sr)
+	for r.Next() {
+		userID, data, err := decodeTenantRecord(r.Record())
+		if err != nil {
+			return err
+		}
+
+		req := &mimirpb.WriteRequest{}
+		if err := req.Unmarshal(data); err != nil {
+			return errors.Wrap(err, "unmarshal witness write-ahead log record")
+		}
+
+		// The series have already been accepted when appended, so the limits aren't enforced again.
+		u := w.getOrCreateUser(userID)
+		u.mtx.Lock()
+		for _, ts := range req.Timeseries {
+			u.addSeries(ts.Labels, now)
+		}
+		u.mtx.Unlock()
+
+		w.metrics.replayedRequests.Inc()
+	}
+
+	if err := r.Err(); err != nil {
+		var cerr *wlog.CorruptionErr
+		if !errors.As(err, &cerr) {
+			return errors.Wrap(err, "read witness write-ahead log")
+		}
+		if err := w.wal.Repair(err); err != nil {
+			return errors.Wrap(err, "repair corrupted witness write-ahead log")
+		}
+	}
+	return nil
+}
+
+// push appends the series of the write request to the write-ahead log. The new series exceeding the
+// per-tenant series limits are discarded, and counted in stats: the other series are still appended,
+// and the first limit error is returned as a validationError.
+func (w *witnessWAL) push(userID string, req *mimirpb.WriteRequest, stats *pushStats) error {
+	accepted, firstPartialErr := w.applyLimits(userID, req.Timeseries, time.Now(), stats)
+
+	if len(accepted) > 0 {
+		// Metadata is not persisted, because it's never queried from witnesses.
+		data, err := (&mimirpb.WriteRequest{Timeseries: accepted, Source: req.Source}).Marshal()
+		if err != nil {
+			w.metrics.failedRequests.Inc()
+			return errors.Wrap(err, "marshal write request")
+		}
+
+		if err := w.wal.Log(encodeTenantRecord(userID, data)); err != nil {
+			w.metrics.failedRequests.Inc()
+			return errors.Wrap(err, "append to witness write-ahead log")
+		}
+
+		w.metrics.appendedRequests.Inc()
+		w.metrics.appendedSeries.Add(float64(len(accepted)))
+	}
+
+	return firstPartialErr
+}
+
+// applyLimits tracks the series of the tenant, and returns the ones not exceeding the series limits.
+// The series are tracked before being appended to the write-ahead log: if the append fails, they're
+// counted towards the limits until they're purged.
+func (w *witnessWAL) applyLimits(userID string, timeseries []mimirpb.PreallocTimeseries, now time.Time, stats *pushStats) ([]mimirpb.PreallocTimeseries, error) {
+	u := w.getOrCreateUser(userID)
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	var (
+		accepted        = timeseries[:0:0]
+		firstPartialErr error
+	)
+
+	for _, ts := range timeseries {
+		hash := mimirpb.FromLabelAdaptersToLabels(ts.Labels).Hash()
+		if s, ok := u.series[hash]; ok {
+			s.lastSeen = now
+			u.series[hash] = s
+			accepted = append(accepted, ts)
+			continue
+		}
+
+		if w.limiter != nil {
+			if err := w.canAddSeries(u, userID, ts.Labels); err != nil {
+				//nolint:errorlint // We don't expect wrapped errors.
+				switch err {
+				case errMaxSeriesPerUserLimitExceeded:
+					stats.perUserSeriesLimitCount++
+					if firstPartialErr == nil {
+						firstPartialErr = makeLimitError(w.limiter.FormatError(userID, err))
+					}
+				case errMaxSeriesPerMetricLimitExceeded:
+					stats.perMetricSeriesLimitCount++
+					if firstPartialErr == nil {
+						firstPartialErr = makeMetricLimitError(mimirpb.FromLabelAdaptersToLabelsWithCopy(ts.Labels), w.limiter.FormatError(userID, err))
+					}
+				default:
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		u.addSeries(ts.Labels, now)
+		accepted = append(accepted, ts)
+	}
+
+	return accepted, firstPartialErr
+}
+
+func (w *witnessWAL) canAddSeries(u *witnessUserSeries, userID string, lbls []mimirpb.LabelAdapter) error {
+	if err := w.limiter.AssertMaxSeriesPerUser(userID, len(u.series)); err != nil {
+		return err
+	}
+
+	metricName, err := extract.UnsafeMetricNameFromLabelAdapters(lbls)
+	if err != nil {
+		return err
+	}
+	return u.seriesInMetric.canAddSeriesFor(userID, metricName)
+}
+
+func (w *witnessWAL) getOrCreateUser(userID string) *witnessUserSeries {
+	w.usersMx.Lock()
+	defer w.usersMx.Unlock()
+
+	u := w.users[userID]
+	if u == nil {
+		u = &witnessUserSeries{
+			series:         map[uint64]witnessSeries{},
+			seriesInMetric: newMetricCounter(w.limiter, w.ignoredMetrics),
+		}
+		w.users[userID] = u
+	}
+	return u
+}
+
+// addSeries tracks the series as last seen at now. The caller must hold the mutex.
+func (u *witnessUserSeries) addSeries(lbls []mimirpb.LabelAdapter, now time.Time) {
+	hash := mimirpb.FromLabelAdaptersToLabels(lbls).Hash()
+	if s, ok := u.series[hash]; ok {
+		s.lastSeen = now
+		u.series[hash] = s
+		return
+	}
+
+	// The metric name is copied, because the labels reference the write request buffer.
+	metricName, _ := extract.UnsafeMetricNameFromLabelAdapters(lbls)
+	metricName = strings.Clone(metricName)
+
+	u.series[hash] = witnessSeries{metricName: metricName, lastSeen: now}
+	u.seriesInMetric.increaseSeriesForMetric(metricName)
+}
+
+// purgeSeries stops tracking the series not seen since the retention, and the tenants without series.
+func (w *witnessWAL) purgeSeries(now time.Time) {
+	w.usersMx.Lock()
+	defer w.usersMx.Unlock()
+
+	for userID, u := range w.users {
+		u.mtx.Lock()
+		for hash, s := range u.series {
+			if now.Sub(s.lastSeen) >= w.retention {
+				delete(u.series, hash)
+				u.seriesInMetric.decreaseSeriesForMetric(s.metricName)
+			}
+		}
+		if len(u.series) == 0 {
+			delete(w.users, userID)
+		}
+		u.mtx.Unlock()
+	}
+}
+
+// truncate removes the segments of the write-ahead log only containing records older than the retention,
+// and stops tracking the series not seen since the retention.
+func (w *witnessWAL) truncate(now time.Time) error {
+	w.purgeSeries(now)
+
+	_, last, err := wlog.Segments(w.wal.Dir())
+	if err != nil {
+		return errors.Wrap(err, "list witness write-ahead log segments")
+	}
+
+	w.checkpointsMx.Lock()
+	defer w.checkpointsMx.Unlock()
+
+	w.checkpoints = append(w.checkpoints, witnessWALCheckpoint{time: now, segment: last})
+
+	// Find the most recent checkpoint older than the retention: all segments before
+	// the one being written at the time of the checkpoint can be removed.
+	idx := -1
+	for i, cp := range w.checkpoints {
+		if now.Sub(cp.time) >= w.retention {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+
+	segment := w.checkpoints[idx].segment
+	w.checkpoints = w.checkpoints[idx:]
+
+	if err := w.wal.Truncate(segment); err != nil {
+		return errors.Wrap(err, "truncate witness write-ahead log")
+	}
+	return nil
+}
+
+func (w *witnessWAL) close() error {
+	return w.wal.Close()
+}
+
+type witnessMetrics struct {
+	appendedRequests prometheus.Counter
+	appendedSeries   prometheus.Counter
+	failedRequests   prometheus.Counter
+	replayedRequests prometheus.Counter
+}
+
+func newWitnessMetrics(reg prometheus.Registerer) *witnessMetrics {
+	return &witnessMetrics{
+		appendedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_witness_appended_requests_total",
+			Help: "The total number of write requests appended to the write-ahead log by a witness ingester.",
+		}),
+		appendedSeries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_witness_appended_series_total",
+			Help: "The total number of series appended to the write-ahead log by a witness ingester.",
+		}),
+		failedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_witness_append_failures_total",
+			Help: "The total number of write requests a witness ingester failed to append to the write-ahead log.",
+		}),
+		replayedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_witness_replayed_requests_total",
+			Help: "The total number of write requests replayed from the write-ahead log by a witness ingester on startup.",
+		}),
+	}
+}
+
+// startingWitness opens and replays the witness write-ahead log in place of the TSDBs, and starts the
+// lifecycler and the service periodically truncating the write-ahead log.
+func (i *Ingester) startingWitness(ctx context.Context) error {
+	tsdbCfg := i.cfg.BlocksStorageConfig.TSDB
+
+	var err error
+	i.witness, err = openWitnessWAL(filepath.Join(tsdbCfg.Dir, witnessWALDirname), tsdbCfg.BlockRanges[0], tsdbCfg.WALSegmentSizeBytes, tsdbCfg.WALCompressionEnabled, i.limiter, i.cfg.getIgnoreSeriesLimitForMetricNamesMap(), i.witnessMetrics, i.logger)
+	if err != nil {
+		return err
+	}
+
+	level.Info(i.logger).Log("msg", "replaying witness write-ahead log")
+	startTime := time.Now()
+	if err := i.witness.replay(startTime); err != nil {
+		return errors.Wrap(err, "failed to replay witness write-ahead log")
+	}
+	level.Info(i.logger).Log("msg", "witness write-ahead log replayed", "duration", time.Since(startTime))
+
+	// Important: we want to keep lifecycler running until we ask it to stop, so we need to give it independent context
+	if err := i.lifecycler.StartAsync(context.Background()); err != nil {
+		return errors.Wrap(err, "failed to start lifecycler")
+	}
+	if err := i.lifecycler.AwaitRunning(ctx); err != nil {
+		return errors.Wrap(err, "failed to start lifecycler")
+	}
+
+	i.subservices, err = services.NewManager(services.NewTimerService(tsdbCfg.HeadCompactionInterval, nil, i.truncateWitnessWAL, nil))
+	if err == nil {
+		err = services.StartManagerAndAwaitHealthy(ctx, i.subservices)
+	}
+	return errors.Wrap(err, "failed to start ingester components")
+}
+
+// pushWitness appends the write request of the tenant to the witness write-ahead log.
+func (i *Ingester) pushWitness(userID string, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
+	var stats pushStats
+	err := i.witness.push(userID, req, &stats)
+
+	if stats.perUserSeriesLimitCount > 0 || stats.perMetricSeriesLimitCount > 0 {
+		group := i.activeGroups.UpdateActiveGroupTimestamp(userID, validation.GroupLabel(i.limits, userID, req.Timeseries), time.Now())
+		if stats.perUserSeriesLimitCount > 0 {
+			i.metrics.discarded.perUserSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perUserSeriesLimitCount))
+		}
+		if stats.perMetricSeriesLimitCount > 0 {
+			i.metrics.discarded.perMetricSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perMetricSeriesLimitCount))
+		}
+	}
+
+	var ve *validationError
+	if errors.As(err, &ve) {
+		return &mimirpb.WriteResponse{}, httpgrpc.Errorf(ve.code, wrapWithUser(err, userID).Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mimirpb.WriteResponse{}, nil
+}
+
+// truncateWitnessWAL is a timer service iteration truncating the witness write-ahead log.
+func (i *Ingester) truncateWitnessWAL(_ context.Context) error {
+	if err := i.witness.truncate(time.Now()); err != nil {
+		level.Warn(i.logger).Log("msg", "failed to truncate witness write-ahead log", "err", err)
+	}
+	return nil
+}
+
+// isWitness returns whether the ingester runs in a witness zone.
+func (cfg *RingConfig) isWitness() bool {
+	for _, zone := range cfg.WitnessZones {
+		if zone == cfg.InstanceZone {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestIngester_Witness(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ZoneAwarenessEnabled = true
+	cfg.IngesterRing.InstanceZone = "zone-c"
+	cfg.IngesterRing.WitnessZones = []string{"zone-c"}
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 1, 100000)
+	_, err = i.Push(ctx, req)
+	require.NoError(t, err)
+
+	// The witness doesn't create any TSDB, so the series can't be queried.
+	assert.Nil(t, i.getTSDB("test"))
+
+	res, err := i.LabelNames(ctx, &client.LabelNamesRequest{EndTimestampMs: 200000})
+	require.NoError(t, err)
+	assert.Empty(t, res.LabelNames)
+
+	// The series have been persisted to the witness write-ahead log.
+	userIDs, requests := readWitnessWAL(t, filepath.Join(i.cfg.BlocksStorageConfig.TSDB.Dir, witnessWALDirname))
+	assert.Equal(t, []string{"test"}, userIDs)
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0].Timeseries, 1)
+	assert.Equal(t, labels.FromStrings(labels.MetricName, "test"), mimirpb.FromLabelAdaptersToLabels(requests[0].Timeseries[0].Labels))
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 100000, Value: 1}}, requests[0].Timeseries[0].Samples)
+}
+
+func TestIngester_WitnessSeriesLimits(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.IngesterRing.ZoneAwarenessEnabled = true
+	cfg.IngesterRing.InstanceZone = "zone-c"
+	cfg.IngesterRing.WitnessZones = []string{"zone-c"}
+	cfg.IngesterRing.ReplicationFactor = 1
+
+	limits := defaultLimitsTestConfig()
+	limits.MaxGlobalSeriesPerUser = 1
+
+	dataDir := t.TempDir()
+	ctx := user.InjectOrgID(context.Background(), "test")
+	series1 := labels.FromStrings(labels.MetricName, "test", "series", "1")
+	series2 := labels.FromStrings(labels.MetricName, "test", "series", "2")
+
+	push := func(i *Ingester, series labels.Labels) error {
+		req, _, _, _ := mockWriteRequest(t, series, 1, 100000)
+		_, err := i.Push(ctx, req)
+		return err
+	}
+
+	startIngester := func() *Ingester {
+		i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, dataDir, prometheus.NewPedanticRegistry())
+		require.NoError(t, err)
+		require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+
+		test.Poll(t, 1*time.Second, 1, func() interface{} {
+			return i.lifecycler.HealthyInstancesCount()
+		})
+		return i
+	}
+
+	assertSeriesLimitError := func(err error) {
+		require.Error(t, err)
+		res, ok := httpgrpc.HTTPResponseFromError(err)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, int(res.Code))
+	}
+
+	i := startIngester()
+
+	err := push(i, series1)
+	require.NoError(t, err)
+
+	// The known series are accepted, while the new ones exceeding the limit are rejected.
+	err = push(i, series1)
+	require.NoError(t, err)
+	err = push(i, series2)
+	assertSeriesLimitError(err)
+
+	// The rejected series haven't been appended to the write-ahead log.
+	_, requests := readWitnessWAL(t, filepath.Join(dataDir, witnessWALDirname))
+	require.Len(t, requests, 2)
+	for _, req := range requests {
+		require.Len(t, req.Timeseries, 1)
+		assert.Equal(t, series1, mimirpb.FromLabelAdaptersToLabels(req.Timeseries[0].Labels))
+	}
+
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+
+	// The series are tracked again after replaying the write-ahead log on restart.
+	i = startIngester()
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	err = push(i, series2)
+	assertSeriesLimitError(err)
+	err = push(i, series1)
+	require.NoError(t, err)
+}
+
+func TestWitnessWAL_Truncate(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWitnessWAL(dir, time.Hour, wlog.DefaultSegmentSize, false, nil, nil, newWitnessMetrics(nil), log.NewNopLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, w.close()) })
+
+	req := mimirpb.ToWriteRequest([]labels.Labels{labels.FromStrings(labels.MetricName, "test")}, []mimirpb.Sample{{TimestampMs: 1, Value: 1}}, nil, nil, mimirpb.API)
+	now := time.Now()
+
+	// Write a record to each of the first 3 segments, truncating after each one.
+	for n := 0; n < 3; n++ {
+		require.NoError(t, w.push("test", req, &pushStats{}))
+		require.NoError(t, w.truncate(now.Add(time.Duration(n)*30*time.Minute)))
+		_, err := w.wal.NextSegment()
+		require.NoError(t, err)
+	}
+
+	// The first truncation has been recorded while writing to segment 0, and its retention
+	// expired at the third truncation: segment 0 has been kept, given it may contain recent records.
+	first, last, err := wlog.Segments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, first)
+	assert.Equal(t, 3, last)
+
+	// Once the second truncation retention expires, the segment 0 is removed.
+	require.NoError(t, w.truncate(now.Add(90*time.Minute)))
+
+	first, last, err = wlog.Segments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 3, last)
+
+	// The series not seen since the retention are no longer tracked.
+	assert.Empty(t, w.users)
+}
+
+func readWitnessWAL(t *testing.T, dir string) ([]string, []mimirpb.WriteRequest) {
+	sr, err := wlog.NewSegmentsReader(dir)
+	require.NoError(t, err)
+	defer sr.Close()
+
+	var (
+		userIDs  []string
+		requests []mimirpb.WriteRequest
+		r        = wlog.NewReader(sr)
+	)
+
+	for r.Next() {
+		rec := r.Record()
+
+		n, size := binary.Uvarint(rec)
+		require.Greater(t, size, 0)
+		userIDs = append(userIDs, string(rec[size:size+int(n)]))
+
+		req := mimirpb.WriteRequest{}
+		require.NoError(t, req.Unmarshal(rec[size+int(n):]))
+		requests = append(requests, req)
+	}
+	require.NoError(t, r.Err())
+
+	return userIDs, requests
+}
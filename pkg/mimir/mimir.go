@@ -60,13 +60,16 @@ import (
 	"github.com/grafana/mimir/pkg/ruler/rulestore"
 	rulebucketclient "github.com/grafana/mimir/pkg/ruler/rulestore/bucketclient"
 	rulestorelocal "github.com/grafana/mimir/pkg/ruler/rulestore/local"
+	"github.com/grafana/mimir/pkg/sandbox"
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/ingest"
 	"github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
+	"github.com/grafana/mimir/pkg/util/goruntime"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/noauth"
 	"github.com/grafana/mimir/pkg/util/process"
@@ -115,6 +118,7 @@ type Config struct {
 	Worker           querier_worker.Config           `yaml:"frontend_worker"`
 	Frontend         frontend.CombinedFrontendConfig `yaml:"frontend"`
 	BlocksStorage    tsdb.BlocksStorageConfig        `yaml:"blocks_storage"`
+	IngestStorage    ingest.Config                   `yaml:"ingest_storage"`
 	Compactor        compactor.Config                `yaml:"compactor"`
 	StoreGateway     storegateway.Config             `yaml:"store_gateway"`
 	TenantFederation tenantfederation.Config         `yaml:"tenant_federation"`
@@ -129,7 +133,9 @@ type Config struct {
 	MemberlistKV        memberlist.KVConfig                        `yaml:"memberlist"`
 	QueryScheduler      scheduler.Config                           `yaml:"query_scheduler"`
 	UsageStats          usagestats.Config                          `yaml:"usage_stats"`
+	SandboxTenants      sandbox.Config                             `yaml:"sandbox_tenants"`
 	OverridesExporter   exporter.Config                            `yaml:"overrides_exporter"`
+	GoRuntime           goruntime.Config                           `yaml:"go_runtime"`
 
 	Common CommonConfig `yaml:"common"`
 }
@@ -165,6 +171,7 @@ func (c *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	c.Worker.RegisterFlags(f)
 	c.Frontend.RegisterFlags(f, logger)
 	c.BlocksStorage.RegisterFlags(f, logger)
+	c.IngestStorage.RegisterFlags(f)
 	c.Compactor.RegisterFlags(f, logger)
 	c.StoreGateway.RegisterFlags(f, logger)
 	c.TenantFederation.RegisterFlags(f)
@@ -179,7 +186,9 @@ func (c *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	c.ActivityTracker.RegisterFlags(f)
 	c.QueryScheduler.RegisterFlags(f, logger)
 	c.UsageStats.RegisterFlags(f)
+	c.SandboxTenants.RegisterFlags(f)
 	c.OverridesExporter.RegisterFlags(f, logger)
+	c.GoRuntime.RegisterFlags(f)
 
 	c.Common.RegisterFlags(f, logger)
 }
@@ -255,6 +264,9 @@ func (c *Config) Validate(log log.Logger) error {
 	if err := c.Compactor.Validate(log); err != nil {
 		return errors.Wrap(err, "invalid compactor config")
 	}
+	if err := c.LimitsConfig.ValidateCompactorBlockRanges(c.BlocksStorage.TSDB.BlockRanges[0]); err != nil {
+		return errors.Wrap(err, "invalid limits config")
+	}
 	if err := c.AlertmanagerStorage.Validate(); err != nil {
 		return errors.Wrap(err, "invalid alertmanager storage config")
 	}
@@ -264,6 +276,18 @@ func (c *Config) Validate(log log.Logger) error {
 	if err := c.UsageStats.Validate(); err != nil {
 		return errors.Wrap(err, "invalid usage stats config")
 	}
+	if err := c.IngestStorage.Validate(); err != nil {
+		return errors.Wrap(err, "invalid ingest storage config")
+	}
+	if c.Querier.IngestStorageFallbackEnabled && !c.IngestStorage.Enabled {
+		return errors.New("-querier.ingest-storage-fallback-enabled=true requires -ingest-storage.enabled=true")
+	}
+	if err := c.SandboxTenants.Validate(); err != nil {
+		return errors.Wrap(err, "invalid sandbox tenants config")
+	}
+	if err := c.GoRuntime.Validate(); err != nil {
+		return errors.Wrap(err, "invalid go runtime config")
+	}
 	if err := c.Vault.Validate(); err != nil {
 		return errors.Wrap(err, "invalid vault config")
 	}
@@ -292,6 +316,20 @@ func (c *Config) isAnyModuleEnabled(modules ...string) bool {
 	return false
 }
 
+// isIPv6InstanceAddrEnabled returns whether IPv6 instance addresses are enabled for any of the hash rings
+// or for the query-frontend.
+func (c *Config) isIPv6InstanceAddrEnabled() bool {
+	return c.Distributor.DistributorRing.Common.InstanceEnableIPv6 ||
+		c.Ingester.IngesterRing.InstanceEnableIPv6 ||
+		c.StoreGateway.ShardingRing.InstanceEnableIPv6 ||
+		c.Compactor.ShardingRing.Common.InstanceEnableIPv6 ||
+		c.Ruler.Ring.Common.InstanceEnableIPv6 ||
+		c.Alertmanager.ShardingRing.Common.InstanceEnableIPv6 ||
+		c.QueryScheduler.ServiceDiscovery.SchedulerRing.InstanceEnableIPv6 ||
+		c.OverridesExporter.Ring.Common.InstanceEnableIPv6 ||
+		c.Frontend.FrontendV2.EnableIPv6
+}
+
 func (c *Config) validateBucketConfigs() error {
 	errs := multierror.New()
 
@@ -677,10 +715,17 @@ type Mimir struct {
 	ActivityTracker          *activitytracker.ActivityTracker
 	Vault                    *vault.Vault
 	UsageStatsReporter       *usagestats.Reporter
+	SandboxTenants           *sandbox.Registry
 	BuildInfoHandler         http.Handler
 
 	// Queryables that the querier should use to query the long term storage.
 	StoreQueryables []querier.QueryableWithFilter
+
+	// Queryable that the querier should use to query the exemplars stored in the long term storage, if any.
+	StoreExemplarQueryable prom_storage.ExemplarQueryable
+
+	// Reader of the series of the ingest storage, used by the queriers when querying the ingesters fails, if enabled.
+	IngestStorageLogQuerier *ingest.LogQuerier
 }
 
 // New makes a new Mimir.
@@ -404,6 +404,16 @@ func TestConfigValidation(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name: "should fail if the first compactor block range override is not a multiple of the TSDB block range",
+			getTestConfig: func() *Config {
+				cfg := newDefaultConfig()
+				cfg.LimitsConfig.CompactorBlockRanges = []time.Duration{3 * time.Hour, 24 * time.Hour}
+
+				return cfg
+			},
+			expectAnyError: true,
+		},
 		{
 			name: "should fail if querier timeout is bigger than http server timeout",
 			getTestConfig: func() *Config {
@@ -600,6 +610,18 @@ func TestConfig_validateFilesystemPaths(t *testing.T) {
 	}
 }
 
+func TestConfig_isIPv6InstanceAddrEnabled(t *testing.T) {
+	cfg := newDefaultConfig()
+	assert.False(t, cfg.isIPv6InstanceAddrEnabled())
+
+	cfg.Ingester.IngesterRing.InstanceEnableIPv6 = true
+	assert.True(t, cfg.isIPv6InstanceAddrEnabled())
+
+	cfg = newDefaultConfig()
+	cfg.Frontend.FrontendV2.EnableIPv6 = true
+	assert.True(t, cfg.isIPv6InstanceAddrEnabled())
+}
+
 func TestIsAbsPathOverlapping(t *testing.T) {
 	tests := []struct {
 		first    string
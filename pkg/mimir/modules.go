@@ -9,6 +9,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/grafana/dskit/dns"
 	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/grafana/dskit/modules"
+	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/runtimeconfig"
 	"github.com/grafana/dskit/services"
@@ -34,6 +36,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/alertmanager"
 	"github.com/grafana/mimir/pkg/alertmanager/alertstore"
+	alertstorelocal "github.com/grafana/mimir/pkg/alertmanager/alertstore/local"
 	"github.com/grafana/mimir/pkg/api"
 	"github.com/grafana/mimir/pkg/compactor"
 	"github.com/grafana/mimir/pkg/distributor"
@@ -47,12 +50,17 @@ import (
 	"github.com/grafana/mimir/pkg/querier/tenantfederation"
 	querier_worker "github.com/grafana/mimir/pkg/querier/worker"
 	"github.com/grafana/mimir/pkg/ruler"
+	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	rulestorelocal "github.com/grafana/mimir/pkg/ruler/rulestore/local"
+	"github.com/grafana/mimir/pkg/sandbox"
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/ingest"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
+	"github.com/grafana/mimir/pkg/util/goruntime"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/validation"
 	"github.com/grafana/mimir/pkg/util/validation/exporter"
@@ -91,6 +99,7 @@ const (
 	Vault                      string = "vault"
 	TenantFederation           string = "tenant-federation"
 	UsageStats                 string = "usage-stats"
+	SandboxTenants             string = "sandbox-tenants"
 	All                        string = "all"
 
 	// Write Read and Backend are the targets used when using the read-write deployment mode.
@@ -108,6 +117,7 @@ func newDefaultConfig() *Config {
 
 func (t *Mimir) initAPI() (services.Service, error) {
 	t.Cfg.API.ServerPrefix = t.Cfg.Server.PathPrefix
+	t.Cfg.API.TenantFederationEnabled = t.Cfg.TenantFederation.Enabled
 
 	a, err := api.New(t.Cfg.API, t.Cfg.Server, t.Server, util_log.Logger)
 	if err != nil {
@@ -125,6 +135,7 @@ func (t *Mimir) initAPI() (services.Service, error) {
 
 	t.API = a
 	t.API.RegisterAPI(t.Cfg.Server.PathPrefix, t.Cfg, newDefaultConfig(), t.BuildInfoHandler)
+	t.API.RegisterGoRuntime(goruntime.NewTuner(t.Cfg.GoRuntime, util_log.Logger))
 
 	return nil, nil
 }
@@ -183,6 +194,7 @@ func (t *Mimir) initVault() (services.Service, error) {
 	// Update Configs - KVStore
 	t.Cfg.MemberlistKV.TCPTransport.TLS.Reader = t.Vault
 	t.Cfg.Distributor.HATrackerConfig.KVStore.StoreConfig.Etcd.TLS.Reader = t.Vault
+	t.Cfg.SandboxTenants.KVStore.StoreConfig.Etcd.TLS.Reader = t.Vault
 	t.Cfg.Alertmanager.ShardingRing.Common.KVStore.StoreConfig.Etcd.TLS.Reader = t.Vault
 	t.Cfg.Compactor.ShardingRing.Common.KVStore.StoreConfig.Etcd.TLS.Reader = t.Vault
 	t.Cfg.Distributor.DistributorRing.Common.KVStore.StoreConfig.Etcd.TLS.Reader = t.Vault
@@ -265,7 +277,7 @@ func (t *Mimir) initRuntimeConfig() (services.Service, error) {
 		// no need to initialize module if load path is empty
 		return nil, nil
 	}
-	t.Cfg.RuntimeConfig.Loader = loadRuntimeConfig
+	t.Cfg.RuntimeConfig.Loader = newRuntimeConfigLoader(t.Cfg.BlocksStorage.TSDB.BlockRanges[0])
 
 	// make sure to set default limits before we start loading configuration into memory
 	validation.SetDefaultLimitsForYAMLUnmarshalling(t.Cfg.LimitsConfig)
@@ -332,6 +344,10 @@ func (t *Mimir) initOverridesExporter() (services.Service, error) {
 func (t *Mimir) initDistributorService() (serv services.Service, err error) {
 	t.Cfg.Distributor.DistributorRing.Common.ListenPort = t.Cfg.Server.GRPCListenPort
 	t.Cfg.Distributor.InstanceLimitsFn = distributorInstanceLimits(t.RuntimeConfig)
+	t.Cfg.Distributor.IngestersZoneAwarenessEnabled = t.Cfg.Ingester.IngesterRing.ZoneAwarenessEnabled
+	t.Cfg.Distributor.IngestersWitnessZones = t.Cfg.Ingester.IngesterRing.WitnessZones
+	t.Cfg.Distributor.SandboxTenants = t.SandboxTenants
+	t.Cfg.Distributor.IngestStorageConfig = t.Cfg.IngestStorage
 
 	// Only enable shuffle sharding on the read path when `query-ingesters-within`
 	// is non-zero since otherwise we can't determine if an ingester should be part
@@ -346,6 +362,14 @@ func (t *Mimir) initDistributorService() (serv services.Service, err error) {
 	// ruler's dependency)
 	canJoinDistributorsRing := t.Cfg.isAnyModuleEnabled(Distributor, Write, All)
 
+	// The captured push requests are stored to the blocks storage bucket.
+	if t.Cfg.Distributor.RequestCapture.Enabled() && canJoinDistributorsRing {
+		t.Cfg.Distributor.RequestCaptureBucket, err = bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "distributor-request-capture", util_log.Logger, t.Registerer)
+		if err != nil {
+			return nil, errors.Wrap(err, "create the distributor request capture bucket client")
+		}
+	}
+
 	t.Distributor, err = distributor.New(t.Cfg.Distributor, t.Cfg.IngesterClient, t.Overrides, t.ActiveGroupsCleanup, t.Ring, canJoinDistributorsRing, t.Registerer, util_log.Logger)
 	if err != nil {
 		return
@@ -359,7 +383,19 @@ func (t *Mimir) initDistributorService() (serv services.Service, err error) {
 }
 
 func (t *Mimir) initDistributor() (serv services.Service, err error) {
-	t.API.RegisterDistributor(t.Distributor, t.Cfg.Distributor, t.Registerer)
+	t.API.RegisterDistributor(t.Distributor, t.Cfg.Distributor, t.Registerer, t.Overrides)
+
+	// The tenants stats API reads the tenants' bucket index, but distributors don't otherwise need to access
+	// the blocks storage, so a failure to create the bucket client only disables the API.
+	if bucketClient, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "distributor", util_log.Logger, t.Registerer); err != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to create the blocks storage bucket client, the tenants stats API is disabled", "err", err)
+	} else {
+		t.API.RegisterTenantsStats(t.Distributor.TenantsStatsHandler(bucketClient, t.Overrides))
+	}
+
+	if t.SandboxTenants != nil {
+		t.API.RegisterSandboxTenants(t.SandboxTenants)
+	}
 
 	return nil, nil
 }
@@ -370,7 +406,10 @@ func (t *Mimir) initQueryable() (serv services.Service, err error) {
 	querierRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "querier"}, t.Registerer)
 
 	// Create a querier queryable and PromQL engine
-	t.QuerierQueryable, t.ExemplarQueryable, t.QuerierEngine = querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, querierRegisterer, util_log.Logger, t.ActivityTracker)
+	t.QuerierQueryable, t.ExemplarQueryable, t.QuerierEngine = querier.New(t.Cfg.Querier, t.Overrides, t.querierDistributor(querierRegisterer), t.StoreQueryables, querierRegisterer, util_log.Logger, t.ActivityTracker)
+	if t.StoreExemplarQueryable != nil {
+		t.ExemplarQueryable = querier.NewMergeExemplarQueryable(t.Cfg.Querier, t.ExemplarQueryable, t.StoreExemplarQueryable)
+	}
 
 	// Use the distributor to return metric metadata by default
 	t.MetadataSupplier = t.Distributor
@@ -381,6 +420,19 @@ func (t *Mimir) initQueryable() (serv services.Service, err error) {
 	return nil, nil
 }
 
+// querierDistributor returns the distributor used by the queriers to query the series of the ingesters, reading
+// them from the ingest storage when querying the ingesters fails, if enabled.
+func (t *Mimir) querierDistributor(reg prometheus.Registerer) querier.Distributor {
+	if !t.Cfg.Querier.IngestStorageFallbackEnabled {
+		return t.Distributor
+	}
+
+	if t.IngestStorageLogQuerier == nil {
+		t.IngestStorageLogQuerier = ingest.NewLogQuerier(t.Cfg.IngestStorage.KafkaConfig, util_log.Logger, t.Registerer)
+	}
+	return querier.NewIngestStorageFallbackDistributor(t.Distributor, t.IngestStorageLogQuerier, t.Overrides, util_log.Logger, reg)
+}
+
 // Enable merge querier if multi tenant query federation is enabled
 func (t *Mimir) initTenantFederation() (serv services.Service, err error) {
 	if t.Cfg.TenantFederation.Enabled {
@@ -509,6 +561,9 @@ func (t *Mimir) initStoreQueryables() (services.Service, error) {
 		return nil, fmt.Errorf("failed to initialize querier: %v", err)
 	} else {
 		t.StoreQueryables = append(t.StoreQueryables, querier.UseAlwaysQueryable(q))
+		if t.Cfg.Querier.BlockExemplarsEnabled {
+			t.StoreExemplarQueryable = q
+		}
 		servs = append(servs, q)
 	}
 
@@ -539,6 +594,8 @@ func (t *Mimir) initIngesterService() (serv services.Service, err error) {
 	t.Cfg.Ingester.IngesterRing.ListenPort = t.Cfg.Server.GRPCListenPort
 	t.Cfg.Ingester.StreamTypeFn = ingesterChunkStreaming(t.RuntimeConfig)
 	t.Cfg.Ingester.InstanceLimitsFn = ingesterInstanceLimits(t.RuntimeConfig)
+	t.Cfg.Ingester.IngesterClientConfig = t.Cfg.IngesterClient
+	t.Cfg.Ingester.IngestStorageConfig = t.Cfg.IngestStorage
 	t.tsdbIngesterConfig()
 
 	t.Ingester, err = ingester.New(t.Cfg.Ingester, t.Overrides, t.ActiveGroupsCleanup, t.Registerer, util_log.Logger)
@@ -618,6 +675,12 @@ func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 	handler := transport.NewHandler(t.Cfg.Frontend.Handler, roundTripper, util_log.Logger, t.Registerer, t.ActivityTracker)
 	t.API.RegisterQueryFrontendHandler(handler, t.BuildInfoHandler)
 
+	// The cached results are refreshed through the whole query-frontend round tripper, like the queries run by the users.
+	var refresherSvc services.Service
+	if t.Cfg.Frontend.QueryMiddleware.ResultsCacheRefreshEnabled() {
+		refresherSvc = querymiddleware.NewResultsCacheRefresher(t.Cfg.Frontend.QueryMiddleware, t.Cfg.API.PrometheusHTTPPrefix, roundTripper, t.QueryFrontendCodec, util_log.Logger, t.Registerer)
+	}
+
 	var frontendSvc services.Service
 	if frontendV1 != nil {
 		t.API.RegisterQueryFrontend1(frontendV1)
@@ -634,7 +697,13 @@ func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 			w.WatchService(frontendSvc)
 			// Note that we pass an independent context to the service, since we want to
 			// delay stopping it until in-flight requests are waited on.
-			return services.StartAndAwaitRunning(context.Background(), frontendSvc)
+			if err := services.StartAndAwaitRunning(context.Background(), frontendSvc); err != nil {
+				return err
+			}
+		}
+		if refresherSvc != nil {
+			w.WatchService(refresherSvc)
+			return services.StartAndAwaitRunning(context.Background(), refresherSvc)
 		}
 		return nil
 	}, func(serviceContext context.Context) error {
@@ -645,6 +714,10 @@ func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 			return err
 		}
 	}, func(_ error) error {
+		if refresherSvc != nil {
+			_ = services.StopAndAwaitTerminated(context.Background(), refresherSvc)
+		}
+
 		handler.Stop()
 
 		if frontendSvc != nil {
@@ -673,61 +746,70 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 
 	t.Cfg.Ruler.Ring.Common.ListenPort = t.Cfg.Server.GRPCListenPort
 
-	var embeddedQueryable prom_storage.Queryable
-	var queryFunc rules.QueryFunc
+	var queryable, embeddedQueryable prom_storage.Queryable
+	var embeddedQueryFunc rules.QueryFunc
+
+	// TODO: Consider wrapping logger to differentiate from querier module logger
+	rulerRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "ruler"}, t.Registerer)
+
+	queryable, _, eng := querier.New(t.Cfg.Querier, t.Overrides, t.querierDistributor(rulerRegisterer), t.StoreQueryables, rulerRegisterer, util_log.Logger, t.ActivityTracker)
+	queryable = querier.NewErrorTranslateQueryableWithFn(queryable, ruler.WrapQueryableErrors)
+
+	if t.Cfg.Ruler.TenantFederation.Enabled {
+		if !t.Cfg.TenantFederation.Enabled {
+			return nil, errors.New("-" + ruler.TenantFederationFlag + "=true requires -tenant-federation.enabled=true")
+		}
+		// Setting bypassForSingleQuerier=false forces `tenantfederation.NewQueryable` to add
+		// the `__tenant_id__` label on all metrics regardless if they're for a single tenant or multiple tenants.
+		// This makes this label more consistent and hopefully less confusing to users.
+		const bypassForSingleQuerier = false
+
+		federatedQueryable := tenantfederation.NewQueryable(queryable, bypassForSingleQuerier, util_log.Logger)
+
+		regularQueryFunc := rules.EngineQueryFunc(eng, queryable)
+		federatedQueryFunc := rules.EngineQueryFunc(eng, federatedQueryable)
+
+		embeddedQueryable = federatedQueryable
+		embeddedQueryFunc = ruler.TenantFederationQueryFunc(regularQueryFunc, federatedQueryFunc)
+
+	} else {
+		embeddedQueryable = queryable
+		embeddedQueryFunc = rules.EngineQueryFunc(eng, queryable)
+	}
+
+	// The rule expressions of the tenants with the remote evaluation enabled are evaluated against the query-frontend.
+	var remoteQueryable prom_storage.Queryable
+	var remoteQueryFunc rules.QueryFunc
 
 	if t.Cfg.Ruler.QueryFrontend.Address != "" {
 		queryFrontendClient, err := ruler.DialQueryFrontend(t.Cfg.Ruler.QueryFrontend)
 		if err != nil {
 			return nil, err
 		}
-		remoteQuerier := ruler.NewRemoteQuerier(queryFrontendClient, t.Cfg.Querier.EngineConfig.Timeout, t.Cfg.Ruler.QueryFrontend.QueryResultResponseFormat, t.Cfg.API.PrometheusHTTPPrefix, util_log.Logger, ruler.WithOrgIDMiddleware)
 
-		embeddedQueryable = prom_remote.NewSampleAndChunkQueryableClient(
+		timeout := t.Cfg.Ruler.QueryFrontend.Timeout
+		if timeout == 0 {
+			timeout = t.Cfg.Querier.EngineConfig.Timeout
+		}
+		remoteQuerier := ruler.NewRemoteQuerier(queryFrontendClient, timeout, t.Cfg.Ruler.QueryFrontend.RetryConfig(), t.Cfg.Ruler.QueryFrontend.QueryResultResponseFormat, t.Cfg.API.PrometheusHTTPPrefix, util_log.Logger, ruler.WithOrgIDMiddleware)
+
+		remoteQueryable = prom_remote.NewSampleAndChunkQueryableClient(
 			remoteQuerier,
 			labels.Labels{},
 			nil,
 			true,
 			func() (int64, error) { return 0, nil },
 		)
-		queryFunc = remoteQuerier.Query
-
-	} else {
-		var queryable, federatedQueryable prom_storage.Queryable
-
-		// TODO: Consider wrapping logger to differentiate from querier module logger
-		rulerRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{"engine": "ruler"}, t.Registerer)
-
-		queryable, _, eng := querier.New(t.Cfg.Querier, t.Overrides, t.Distributor, t.StoreQueryables, rulerRegisterer, util_log.Logger, t.ActivityTracker)
-		queryable = querier.NewErrorTranslateQueryableWithFn(queryable, ruler.WrapQueryableErrors)
-
-		if t.Cfg.Ruler.TenantFederation.Enabled {
-			if !t.Cfg.TenantFederation.Enabled {
-				return nil, errors.New("-" + ruler.TenantFederationFlag + "=true requires -tenant-federation.enabled=true")
-			}
-			// Setting bypassForSingleQuerier=false forces `tenantfederation.NewQueryable` to add
-			// the `__tenant_id__` label on all metrics regardless if they're for a single tenant or multiple tenants.
-			// This makes this label more consistent and hopefully less confusing to users.
-			const bypassForSingleQuerier = false
-
-			federatedQueryable = tenantfederation.NewQueryable(queryable, bypassForSingleQuerier, util_log.Logger)
-
-			regularQueryFunc := rules.EngineQueryFunc(eng, queryable)
-			federatedQueryFunc := rules.EngineQueryFunc(eng, federatedQueryable)
-
-			embeddedQueryable = federatedQueryable
-			queryFunc = ruler.TenantFederationQueryFunc(regularQueryFunc, federatedQueryFunc)
-
-		} else {
-			embeddedQueryable = queryable
-			queryFunc = rules.EngineQueryFunc(eng, queryable)
-		}
+		remoteQueryFunc = remoteQuerier.Query
 	}
+
 	managerFactory := ruler.DefaultTenantManagerFactory(
 		t.Cfg.Ruler,
 		t.Distributor,
 		embeddedQueryable,
-		queryFunc,
+		embeddedQueryFunc,
+		remoteQueryable,
+		remoteQueryFunc,
 		t.Overrides,
 		t.Registerer,
 	)
@@ -789,6 +871,12 @@ func (t *Mimir) initAlertManager() (serv services.Service, err error) {
 
 func (t *Mimir) initCompactor() (serv services.Service, err error) {
 	t.Cfg.Compactor.ShardingRing.Common.ListenPort = t.Cfg.Server.GRPCListenPort
+	t.Cfg.Compactor.SandboxTenants = t.SandboxTenants
+
+	t.Cfg.Compactor.TenantConfigDeleters, err = t.tenantConfigDeleters()
+	if err != nil {
+		return
+	}
 
 	t.Compactor, err = compactor.NewMultitenantCompactor(t.Cfg.Compactor, t.Cfg.BlocksStorage, t.Overrides, util_log.Logger, t.Registerer)
 	if err != nil {
@@ -800,6 +888,39 @@ func (t *Mimir) initCompactor() (serv services.Service, err error) {
 	return t.Compactor, nil
 }
 
+// tenantConfigDeleters returns the functions used by the compactor to delete the rule groups and the Alertmanager
+// configuration of the tenants marked for deletion. The stores are created with no registerer, otherwise their
+// bucket metrics would clash with the ones of the ruler and Alertmanager running in the same process.
+func (t *Mimir) tenantConfigDeleters() (map[string]compactor.TenantConfigDeleter, error) {
+	deleters := map[string]compactor.TenantConfigDeleter{}
+
+	if !t.Cfg.RulerStorage.IsDefaults() && t.Cfg.RulerStorage.Backend != rulestorelocal.Name {
+		ruleStore, err := ruler.NewRuleStore(context.Background(), t.Cfg.RulerStorage, t.Overrides, rules.FileLoader{}, util_log.Logger, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "ruler storage")
+		}
+
+		deleters["rule groups"] = func(ctx context.Context, userID string) error {
+			// Empty namespace = delete all rule groups.
+			if err := ruleStore.DeleteNamespace(ctx, userID, ""); err != nil && !errors.Is(err, rulestore.ErrGroupNamespaceNotFound) {
+				return err
+			}
+			return nil
+		}
+	}
+
+	if t.Cfg.AlertmanagerStorage.Backend != alertstorelocal.Name {
+		alertStore, err := alertstore.NewAlertStore(context.Background(), t.Cfg.AlertmanagerStorage, t.Overrides, util_log.Logger, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "alertmanager storage")
+		}
+
+		deleters["alertmanager configuration"] = alertStore.DeleteAlertConfig
+	}
+
+	return deleters, nil
+}
+
 func (t *Mimir) initStoreGateway() (serv services.Service, err error) {
 	t.Cfg.StoreGateway.ShardingRing.ListenPort = t.Cfg.Server.GRPCListenPort
 
@@ -819,7 +940,21 @@ func (t *Mimir) initMemberlistKV() (services.Service, error) {
 	t.Cfg.MemberlistKV.MetricsRegisterer = reg
 
 	// Append to the list of codecs instead of overwriting the value to allow third parties to inject their own codecs.
-	t.Cfg.MemberlistKV.Codecs = append(t.Cfg.MemberlistKV.Codecs, ring.GetCodec())
+	t.Cfg.MemberlistKV.Codecs = append(t.Cfg.MemberlistKV.Codecs, ring.GetCodec(), distributor.GetReplicaDescCodec(), sandbox.GetCodec())
+
+	// When memberlist listens on the IPv6 unspecified address (e.g. "::", which accepts both IPv4 and IPv6
+	// connections on dual-stack hosts) it would advertise the unspecified address itself, so we look up
+	// the address to advertise from the private network interfaces. This is only done when IPv6 instance
+	// addresses are enabled, to not change the advertise address of the existing deployments.
+	if t.Cfg.MemberlistKV.AdvertiseAddr == "" && len(t.Cfg.MemberlistKV.TCPTransport.BindAddrs) > 0 && t.Cfg.isIPv6InstanceAddrEnabled() {
+		if ip := net.ParseIP(t.Cfg.MemberlistKV.TCPTransport.BindAddrs[0]); ip != nil && ip.IsUnspecified() && ip.To4() == nil {
+			addr, err := util.GetFirstAddressOf(netutil.PrivateNetworkInterfacesWithFallback([]string{"eth0", "en0"}, util_log.Logger), true)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to look up the memberlist advertise address")
+			}
+			t.Cfg.MemberlistKV.AdvertiseAddr = addr
+		}
+	}
 
 	dnsProviderReg := prometheus.WrapRegistererWithPrefix(
 		"cortex_",
@@ -834,6 +969,8 @@ func (t *Mimir) initMemberlistKV() (services.Service, error) {
 
 	// Update the config.
 	t.Cfg.Distributor.DistributorRing.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
+	t.Cfg.Distributor.HATrackerConfig.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
+	t.Cfg.SandboxTenants.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
 	t.Cfg.Ingester.IngesterRing.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
 	t.Cfg.StoreGateway.ShardingRing.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
 	t.Cfg.Compactor.ShardingRing.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
@@ -857,6 +994,26 @@ func (t *Mimir) initQueryScheduler() (services.Service, error) {
 	return s, nil
 }
 
+func (t *Mimir) initSandboxTenants() (services.Service, error) {
+	if !t.Cfg.SandboxTenants.Enabled {
+		return nil, nil
+	}
+
+	// The bucket is used to check that the sandbox tenants have no data in the blocks storage when created,
+	// because their blocks are deleted once they expire.
+	bucketClient, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "sandbox-tenants", util_log.Logger, t.Registerer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the sandbox tenants bucket client")
+	}
+
+	t.SandboxTenants, err = sandbox.NewRegistry(t.Cfg.SandboxTenants, bucketClient, t.Registerer, util_log.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.SandboxTenants, nil
+}
+
 func (t *Mimir) initUsageStats() (services.Service, error) {
 	if !t.Cfg.UsageStats.Enabled {
 		return nil, nil
@@ -914,6 +1071,7 @@ func (t *Mimir) setupModuleManager() error {
 	mm.RegisterModule(QueryScheduler, t.initQueryScheduler)
 	mm.RegisterModule(TenantFederation, t.initTenantFederation, modules.UserInvisibleModule)
 	mm.RegisterModule(UsageStats, t.initUsageStats, modules.UserInvisibleModule)
+	mm.RegisterModule(SandboxTenants, t.initSandboxTenants, modules.UserInvisibleModule)
 	mm.RegisterModule(Vault, t.initVault, modules.UserInvisibleModule)
 	mm.RegisterModule(Write, nil)
 	mm.RegisterModule(Read, nil)
@@ -927,10 +1085,11 @@ func (t *Mimir) setupModuleManager() error {
 		MemberlistKV:             {API, Vault},
 		RuntimeConfig:            {API},
 		Ring:                     {API, RuntimeConfig, MemberlistKV, Vault},
+		SandboxTenants:           {API, MemberlistKV, Vault},
 		Overrides:                {RuntimeConfig},
 		OverridesExporter:        {Overrides, MemberlistKV, Vault},
 		Distributor:              {DistributorService, API, ActiveGroupsCleanupService, Vault},
-		DistributorService:       {Ring, Overrides, Vault},
+		DistributorService:       {Ring, Overrides, MemberlistKV, SandboxTenants, Vault},
 		Ingester:                 {IngesterService, API, ActiveGroupsCleanupService, Vault},
 		IngesterService:          {Overrides, RuntimeConfig, MemberlistKV},
 		Flusher:                  {Overrides, API},
@@ -943,7 +1102,7 @@ func (t *Mimir) setupModuleManager() error {
 		Ruler:                    {DistributorService, StoreQueryable, RulerStorage, Vault},
 		RulerStorage:             {Overrides},
 		AlertManager:             {API, MemberlistKV, Overrides, Vault},
-		Compactor:                {API, MemberlistKV, Overrides, Vault},
+		Compactor:                {API, MemberlistKV, Overrides, SandboxTenants, Vault},
 		StoreGateway:             {API, Overrides, MemberlistKV, Vault},
 		TenantFederation:         {Queryable},
 		Write:                    {Distributor, Ingester},
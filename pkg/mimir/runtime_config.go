@@ -7,8 +7,10 @@ package mimir
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/runtimeconfig"
@@ -84,6 +86,27 @@ func loadRuntimeConfig(r io.Reader) (interface{}, error) {
 	return overrides, nil
 }
 
+// newRuntimeConfigLoader returns a loader of the runtime config, which additionally validates the tenant
+// limits overrides against the TSDB block range.
+func newRuntimeConfigLoader(tsdbBlockRange time.Duration) runtimeconfig.Loader {
+	return func(r io.Reader) (interface{}, error) {
+		cfg, err := loadRuntimeConfig(r)
+		if err != nil {
+			return nil, err
+		}
+
+		for userID, limits := range cfg.(*runtimeConfigValues).TenantLimits {
+			if limits == nil {
+				continue
+			}
+			if err := limits.ValidateCompactorBlockRanges(tsdbBlockRange); err != nil {
+				return nil, fmt.Errorf("invalid overrides for tenant %s: %w", userID, err)
+			}
+		}
+		return cfg, nil
+	}
+}
+
 func multiClientRuntimeConfigChannel(manager *runtimeconfig.Manager) func() <-chan kv.MultiRuntimeConfig {
 	if manager == nil {
 		return nil
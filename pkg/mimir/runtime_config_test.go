@@ -8,6 +8,7 @@ package mimir
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -113,3 +114,23 @@ overrides:
 		assert.Nil(t, actual)
 	}
 }
+
+func TestRuntimeConfigLoader_ShouldValidateCompactorBlockRanges(t *testing.T) {
+	validation.SetDefaultLimitsForYAMLUnmarshalling(validation.Limits{})
+	loader := newRuntimeConfigLoader(2 * time.Hour)
+
+	_, err := loader(strings.NewReader(`
+overrides:
+  '1234':
+    compactor_block_ranges: [4h, 24h]
+`))
+	require.NoError(t, err)
+
+	actual, err := loader(strings.NewReader(`
+overrides:
+  '1234':
+    compactor_block_ranges: [3h, 24h]
+`))
+	require.EqualError(t, err, "invalid overrides for tenant 1234: invalid compactor_block_ranges: 3h0m0s is not a multiple of the TSDB block range 2h0m0s")
+	assert.Nil(t, actual)
+}
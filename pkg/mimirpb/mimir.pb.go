@@ -187,6 +187,9 @@ type WriteRequest struct {
 	Metadata   []*MetricMetadata       `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty"`
 	// Skip validation of label names.
 	SkipLabelNameValidation bool `protobuf:"varint,1000,opt,name=skip_label_name_validation,json=skipLabelNameValidation,proto3" json:"skip_label_name_validation,omitempty"`
+	// Series rolled up by the tenant's ingest aggregation rules, sent to the ingesters owning the aggregated
+	// series. They're only fed to the ingest aggregation, and not ingested.
+	IngestAggregationInputs []PreallocTimeseries `protobuf:"bytes,1001,rep,name=ingest_aggregation_inputs,json=ingestAggregationInputs,proto3,customtype=PreallocTimeseries" json:"ingest_aggregation_inputs"`
 }
 
 func (m *WriteRequest) Reset()      { *m = WriteRequest{} }
@@ -243,6 +246,9 @@ func (m *WriteRequest) GetSkipLabelNameValidation() bool {
 }
 
 type WriteResponse struct {
+	// Pressure of the ingester which handled the request, from 0 (idle) to 1 (at its instance limits).
+	// Not set by the distributors.
+	Pressure float64 `protobuf:"fixed64,1,opt,name=pressure,proto3" json:"pressure,omitempty"`
 }
 
 func (m *WriteResponse) Reset()      { *m = WriteResponse{} }
@@ -277,12 +283,21 @@ func (m *WriteResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_WriteResponse proto.InternalMessageInfo
 
+func (m *WriteResponse) GetPressure() float64 {
+	if m != nil {
+		return m.Pressure
+	}
+	return 0
+}
+
 type TimeSeries struct {
 	Labels []LabelAdapter `protobuf:"bytes,1,rep,name=labels,proto3,customtype=LabelAdapter" json:"labels"`
 	// Sorted by time, oldest sample first.
 	Samples    []Sample    `protobuf:"bytes,2,rep,name=samples,proto3" json:"samples"`
 	Exemplars  []Exemplar  `protobuf:"bytes,3,rep,name=exemplars,proto3" json:"exemplars"`
 	Histograms []Histogram `protobuf:"bytes,4,rep,name=histograms,proto3" json:"histograms"`
+	// Timestamp at which the counter or histogram series has been created (its start timestamp), 0 if unknown.
+	CreatedTimestampMs int64 `protobuf:"varint,1000,opt,name=created_timestamp_ms,json=createdTimestampMs,proto3" json:"created_timestamp_ms,omitempty"`
 }
 
 func (m *TimeSeries) Reset()      { *m = TimeSeries{} }
@@ -338,6 +353,13 @@ func (m *TimeSeries) GetHistograms() []Histogram {
 	return nil
 }
 
+func (m *TimeSeries) GetCreatedTimestampMs() int64 {
+	if m != nil {
+		return m.CreatedTimestampMs
+	}
+	return 0
+}
+
 type LabelPair struct {
 	Name  []byte `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -1786,117 +1808,119 @@ func init() {
 func init() { proto.RegisterFile("mimir.proto", fileDescriptor_86d4d7485f544059) }
 
 var fileDescriptor_86d4d7485f544059 = []byte{
-	// 1754 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xcf, 0x6f, 0x23, 0x49,
-	0x15, 0x76, 0xd9, 0x1d, 0xdb, 0xfd, 0x62, 0x3b, 0xbd, 0xb5, 0xa3, 0xc1, 0x3b, 0xda, 0x71, 0x32,
-	0x8d, 0x58, 0x02, 0x02, 0x0f, 0x9a, 0x85, 0x59, 0xed, 0x6a, 0x10, 0xb4, 0x9d, 0x9e, 0x49, 0xb2,
-	0x89, 0x1d, 0xca, 0xf6, 0x2c, 0xcb, 0xc5, 0xea, 0x38, 0x95, 0xb8, 0xb5, 0xdd, 0xee, 0xa6, 0x7f,
-	0x0c, 0x13, 0x4e, 0x5c, 0x40, 0x88, 0x13, 0x17, 0x2e, 0x88, 0x1b, 0x07, 0xf8, 0x0b, 0xf8, 0x1b,
-	0x46, 0x42, 0x48, 0x73, 0x5c, 0x71, 0x18, 0x31, 0x99, 0xcb, 0x1e, 0xf7, 0xc0, 0x89, 0x13, 0xaa,
-	0x57, 0xfd, 0xc3, 0xed, 0x24, 0xb0, 0xb0, 0x73, 0xeb, 0x7a, 0xf5, 0xbd, 0x57, 0x5f, 0xbd, 0xfa,
-	0xea, 0xf9, 0x95, 0x61, 0xdd, 0xb5, 0x5d, 0x3b, 0xe8, 0xfa, 0x81, 0x17, 0x79, 0xb4, 0x3e, 0xf3,
-	0x82, 0x88, 0x3f, 0xf5, 0x8f, 0x6f, 0x7d, 0xfb, 0xcc, 0x8e, 0xe6, 0xf1, 0x71, 0x77, 0xe6, 0xb9,
-	0x77, 0xcf, 0xbc, 0x33, 0xef, 0x2e, 0x02, 0x8e, 0xe3, 0x53, 0x1c, 0xe1, 0x00, 0xbf, 0xa4, 0xa3,
-	0xfe, 0x97, 0x32, 0x34, 0x3e, 0x0a, 0xec, 0x88, 0x33, 0xfe, 0xd3, 0x98, 0x87, 0x11, 0x3d, 0x02,
-	0x88, 0x6c, 0x97, 0x87, 0x3c, 0xb0, 0x79, 0xd8, 0x26, 0x5b, 0x95, 0xed, 0xf5, 0x7b, 0x37, 0xba,
-	0x69, 0xf8, 0xee, 0xd8, 0x76, 0xf9, 0x08, 0xe7, 0x7a, 0xb7, 0x9e, 0xbd, 0xd8, 0x2c, 0xfd, 0xfd,
-	0xc5, 0x26, 0x3d, 0x0a, 0xb8, 0xe5, 0x38, 0xde, 0x6c, 0x9c, 0xf9, 0xb1, 0xa5, 0x18, 0xf4, 0x7d,
-	0xa8, 0x8e, 0xbc, 0x38, 0x98, 0xf1, 0x76, 0x79, 0x8b, 0x6c, 0xb7, 0xee, 0xdd, 0xc9, 0xa3, 0x2d,
-	0xaf, 0xdc, 0x95, 0x20, 0x73, 0x11, 0xbb, 0x2c, 0x71, 0xa0, 0x1f, 0x40, 0xdd, 0xe5, 0x91, 0x75,
-	0x62, 0x45, 0x56, 0xbb, 0x82, 0x54, 0xda, 0xb9, 0xf3, 0x21, 0x8f, 0x02, 0x7b, 0x76, 0x98, 0xcc,
-	0xf7, 0x94, 0x67, 0x2f, 0x36, 0x09, 0xcb, 0xf0, 0xf4, 0x01, 0xdc, 0x0a, 0x3f, 0xb1, 0xfd, 0xa9,
-	0x63, 0x1d, 0x73, 0x67, 0xba, 0xb0, 0x5c, 0x3e, 0x7d, 0x62, 0x39, 0xf6, 0x89, 0x15, 0xd9, 0xde,
-	0xa2, 0xfd, 0x59, 0x6d, 0x8b, 0x6c, 0xd7, 0xd9, 0x57, 0x04, 0xe4, 0x40, 0x20, 0x06, 0x96, 0xcb,
-	0x1f, 0x67, 0xf3, 0xfa, 0x26, 0x40, 0xce, 0x87, 0xd6, 0xa0, 0x62, 0x1c, 0xed, 0x69, 0x25, 0x5a,
-	0x07, 0x85, 0x4d, 0x0e, 0x4c, 0x8d, 0xe8, 0x1b, 0xd0, 0x4c, 0xd8, 0x87, 0xbe, 0xb7, 0x08, 0xb9,
-	0xfe, 0x4f, 0x02, 0x90, 0x67, 0x87, 0x1a, 0x50, 0xc5, 0x95, 0xd3, 0x1c, 0xbe, 0x99, 0x13, 0xc7,
-	0xf5, 0x8e, 0x2c, 0x3b, 0xe8, 0xdd, 0x48, 0x52, 0xd8, 0x40, 0x93, 0x71, 0x62, 0xf9, 0x11, 0x0f,
-	0x58, 0xe2, 0x48, 0xbf, 0x03, 0xb5, 0xd0, 0x72, 0x7d, 0x87, 0x87, 0xed, 0x32, 0xc6, 0xd0, 0xf2,
-	0x18, 0x23, 0x9c, 0xc0, 0x4d, 0x97, 0x58, 0x0a, 0xa3, 0xf7, 0x41, 0xe5, 0x4f, 0xb9, 0xeb, 0x3b,
-	0x56, 0x10, 0x26, 0x09, 0xa3, 0xb9, 0x8f, 0x99, 0x4c, 0x25, 0x5e, 0x39, 0x94, 0xbe, 0x0f, 0x30,
-	0xb7, 0xc3, 0xc8, 0x3b, 0x0b, 0x2c, 0x37, 0x6c, 0x2b, 0xab, 0x84, 0x77, 0xd3, 0xb9, 0xc4, 0x73,
-	0x09, 0xac, 0x7f, 0x0f, 0xd4, 0x6c, 0x3f, 0x94, 0x82, 0x22, 0x12, 0xdd, 0x26, 0x5b, 0x64, 0xbb,
-	0xc1, 0xf0, 0x9b, 0xde, 0x80, 0xb5, 0x27, 0x96, 0x13, 0xcb, 0xd3, 0x6f, 0x30, 0x39, 0xd0, 0x0d,
-	0xa8, 0xca, 0x2d, 0xd0, 0x3b, 0xd0, 0x40, 0xb1, 0x44, 0x96, 0xeb, 0x4f, 0xdd, 0x10, 0x61, 0x15,
-	0xb6, 0x9e, 0xd9, 0x0e, 0xc3, 0x3c, 0x84, 0x88, 0x4b, 0xd2, 0x10, 0xbf, 0x2f, 0x43, 0xab, 0xa8,
-	0x01, 0xfa, 0x1e, 0x28, 0xd1, 0xb9, 0x2f, 0x71, 0xad, 0x7b, 0x5f, 0xbd, 0x4e, 0x2b, 0xc9, 0x70,
-	0x7c, 0xee, 0x73, 0x86, 0x0e, 0xf4, 0x5b, 0x40, 0x5d, 0xb4, 0x4d, 0x4f, 0x2d, 0xd7, 0x76, 0xce,
-	0x51, 0x2f, 0x48, 0x45, 0x65, 0x9a, 0x9c, 0x79, 0x88, 0x13, 0x42, 0x26, 0x62, 0x9b, 0x73, 0xee,
-	0xf8, 0x6d, 0x05, 0xe7, 0xf1, 0x5b, 0xd8, 0xe2, 0x85, 0x1d, 0xb5, 0xd7, 0xa4, 0x4d, 0x7c, 0xeb,
-	0xe7, 0x00, 0xf9, 0x4a, 0x74, 0x1d, 0x6a, 0x93, 0xc1, 0x87, 0x83, 0xe1, 0x47, 0x03, 0xad, 0x24,
-	0x06, 0xfd, 0xe1, 0x64, 0x30, 0x36, 0x99, 0x46, 0xa8, 0x0a, 0x6b, 0x8f, 0x8c, 0xc9, 0x23, 0x53,
-	0x2b, 0xd3, 0x26, 0xa8, 0xbb, 0x7b, 0xa3, 0xf1, 0xf0, 0x11, 0x33, 0x0e, 0xb5, 0x0a, 0xa5, 0xd0,
-	0xc2, 0x99, 0xdc, 0xa6, 0x08, 0xd7, 0xd1, 0xe4, 0xf0, 0xd0, 0x60, 0x1f, 0x6b, 0x6b, 0x42, 0x90,
-	0x7b, 0x83, 0x87, 0x43, 0xad, 0x4a, 0x1b, 0x50, 0x1f, 0x8d, 0x8d, 0xb1, 0x39, 0x32, 0xc7, 0x5a,
-	0x4d, 0xff, 0x10, 0xaa, 0x72, 0xe9, 0xd7, 0x20, 0x44, 0xfd, 0x57, 0x04, 0xea, 0xa9, 0x78, 0x5e,
-	0x87, 0xb0, 0x0b, 0x92, 0x48, 0xcf, 0xf3, 0x92, 0x10, 0x2a, 0x97, 0x84, 0xa0, 0xff, 0x75, 0x0d,
-	0xd4, 0x4c, 0x8c, 0xf4, 0x36, 0xa8, 0x33, 0x2f, 0x5e, 0x44, 0x53, 0x7b, 0x11, 0xe1, 0x91, 0x2b,
-	0xbb, 0x25, 0x56, 0x47, 0xd3, 0xde, 0x22, 0xa2, 0x77, 0x60, 0x5d, 0x4e, 0x9f, 0x3a, 0x9e, 0x15,
-	0xc9, 0xb5, 0x76, 0x4b, 0x0c, 0xd0, 0xf8, 0x50, 0xd8, 0xa8, 0x06, 0x95, 0x30, 0x76, 0x71, 0x25,
-	0xc2, 0xc4, 0x27, 0xbd, 0x09, 0xd5, 0x70, 0x36, 0xe7, 0xae, 0x85, 0x87, 0xfb, 0x06, 0x4b, 0x46,
-	0xf4, 0x6b, 0xd0, 0xfa, 0x39, 0x0f, 0xbc, 0x69, 0x34, 0x0f, 0x78, 0x38, 0xf7, 0x9c, 0x13, 0x3c,
-	0x68, 0xc2, 0x9a, 0xc2, 0x3a, 0x4e, 0x8d, 0xf4, 0x9d, 0x04, 0x96, 0xf3, 0xaa, 0x22, 0x2f, 0xc2,
-	0x1a, 0xc2, 0xde, 0x4f, 0xb9, 0x7d, 0x13, 0xb4, 0x25, 0x9c, 0x24, 0x58, 0x43, 0x82, 0x84, 0xb5,
-	0x32, 0xa4, 0x24, 0x69, 0x40, 0x6b, 0xc1, 0xcf, 0xac, 0xc8, 0x7e, 0xc2, 0xa7, 0xa1, 0x6f, 0x2d,
-	0xc2, 0x76, 0x7d, 0xb5, 0x2a, 0xf7, 0xe2, 0xd9, 0x27, 0x3c, 0x1a, 0xf9, 0xd6, 0x22, 0xb9, 0xa1,
-	0xcd, 0xd4, 0x43, 0xd8, 0x42, 0xfa, 0x75, 0xd8, 0xc8, 0x42, 0x9c, 0x70, 0x27, 0xb2, 0xc2, 0xb6,
-	0xba, 0x55, 0xd9, 0xa6, 0x2c, 0x8b, 0xbc, 0x83, 0xd6, 0x02, 0x10, 0xb9, 0x85, 0x6d, 0xd8, 0xaa,
-	0x6c, 0x93, 0x1c, 0x88, 0xc4, 0x44, 0x79, 0x6b, 0xf9, 0x5e, 0x68, 0x2f, 0x91, 0x5a, 0xff, 0xef,
-	0xa4, 0x52, 0x8f, 0x8c, 0x54, 0x16, 0x22, 0x21, 0xd5, 0x90, 0xa4, 0x52, 0x73, 0x4e, 0x2a, 0x03,
-	0x26, 0xa4, 0x9a, 0x92, 0x54, 0x6a, 0x4e, 0x48, 0x3d, 0x00, 0x08, 0x78, 0xc8, 0xa3, 0xe9, 0x5c,
-	0x64, 0xbe, 0x85, 0x45, 0xe0, 0xf6, 0x15, 0x65, 0xac, 0xcb, 0x04, 0x6a, 0xd7, 0x5e, 0x44, 0x4c,
-	0x0d, 0xd2, 0x4f, 0xfa, 0x36, 0xa8, 0x99, 0xd6, 0xda, 0x1b, 0x28, 0xbe, 0xdc, 0xa0, 0x7f, 0x00,
-	0x6a, 0xe6, 0x55, 0xbc, 0xca, 0x35, 0xa8, 0x7c, 0x6c, 0x8e, 0x34, 0x42, 0xab, 0x50, 0x1e, 0x0c,
-	0xb5, 0x72, 0x7e, 0x9d, 0x2b, 0xb7, 0x94, 0x5f, 0xff, 0xb1, 0x43, 0x7a, 0x35, 0x58, 0x43, 0xde,
-	0xbd, 0x06, 0x40, 0x7e, 0xec, 0xfa, 0xdf, 0x14, 0x68, 0xe1, 0x11, 0xe7, 0x92, 0x0e, 0x81, 0xe2,
-	0x1c, 0x0f, 0xa6, 0x2b, 0x3b, 0x69, 0xf6, 0xcc, 0x7f, 0xbd, 0xd8, 0x34, 0x96, 0x7e, 0xdd, 0xfd,
-	0xc0, 0x73, 0x79, 0x34, 0xe7, 0x71, 0xb8, 0xfc, 0xe9, 0x7a, 0x27, 0xdc, 0xb9, 0x9b, 0x15, 0xe8,
-	0x6e, 0x5f, 0x86, 0xcb, 0x77, 0xac, 0xcd, 0x56, 0x2c, 0x5f, 0x56, 0xf3, 0xb7, 0x97, 0x37, 0x25,
-	0x55, 0xcc, 0xd4, 0x4c, 0xc3, 0xe2, 0xb2, 0xcb, 0x99, 0xe4, 0xb2, 0xe3, 0xe0, 0x8a, 0x9b, 0xf7,
-	0x1a, 0x14, 0xf5, 0x1a, 0x6e, 0xca, 0x37, 0x40, 0xcb, 0x58, 0x1c, 0x23, 0x36, 0x15, 0x5b, 0xa6,
-	0x41, 0x19, 0x02, 0xa1, 0xd9, 0x6a, 0x29, 0x54, 0x5e, 0x96, 0xec, 0x0e, 0x25, 0xd0, 0x7d, 0xa5,
-	0x4e, 0xb4, 0xf2, 0xbe, 0x52, 0xaf, 0x6a, 0xb5, 0x7d, 0xa5, 0xae, 0x6a, 0xb0, 0xaf, 0xd4, 0x1b,
-	0x5a, 0x73, 0x5f, 0xa9, 0x6f, 0x68, 0x1a, 0xcb, 0xab, 0x18, 0x5b, 0xa9, 0x1e, 0x6c, 0xf5, 0xda,
-	0xb2, 0xd5, 0x2b, 0xb3, 0x2c, 0xd1, 0x07, 0x00, 0xf9, 0xf6, 0xc4, 0xa9, 0x7a, 0xa7, 0xa7, 0x21,
-	0x97, 0xa5, 0xf1, 0x0d, 0x96, 0x8c, 0x84, 0xdd, 0xe1, 0x8b, 0xb3, 0x68, 0x8e, 0x07, 0xd2, 0x64,
-	0xc9, 0x48, 0x8f, 0x81, 0x16, 0xc5, 0x88, 0xbf, 0xe8, 0x0f, 0x40, 0xcd, 0xb4, 0x84, 0x81, 0x0a,
-	0x2d, 0x58, 0xd1, 0x21, 0xed, 0x2b, 0x32, 0x87, 0x2f, 0xf0, 0xdb, 0xae, 0x2f, 0x60, 0x43, 0x36,
-	0x02, 0xf9, 0x25, 0xc8, 0x14, 0x43, 0xae, 0x50, 0x4c, 0x39, 0x57, 0xcc, 0xbb, 0x50, 0x4b, 0xf3,
-	0x2e, 0x7b, 0x9d, 0xb7, 0xae, 0x6a, 0x59, 0x10, 0xc1, 0x52, 0xa4, 0x1e, 0xc2, 0xc6, 0xca, 0x1c,
-	0xed, 0x00, 0x1c, 0x7b, 0xf1, 0xe2, 0xc4, 0x4a, 0x5a, 0x5e, 0xb2, 0xbd, 0xc6, 0x96, 0x2c, 0x82,
-	0x8f, 0xe3, 0xfd, 0x8c, 0x07, 0xa9, 0x82, 0x71, 0x20, 0xac, 0xb1, 0xef, 0xf3, 0x20, 0xd1, 0xb0,
-	0x1c, 0xe4, 0xdc, 0x95, 0x25, 0xee, 0xba, 0x03, 0x6f, 0xae, 0x6c, 0x12, 0x93, 0x5b, 0xa8, 0x38,
-	0xe5, 0x95, 0x8a, 0x43, 0xdf, 0xbb, 0x9c, 0xfa, 0xb7, 0x56, 0x1b, 0xc0, 0x2c, 0xde, 0x52, 0xd6,
-	0xf5, 0x3f, 0x29, 0xd0, 0xfc, 0x51, 0xcc, 0x83, 0xf3, 0xb4, 0x37, 0xa5, 0xf7, 0xa1, 0x1a, 0x46,
-	0x56, 0x14, 0x87, 0x49, 0x67, 0xd4, 0xc9, 0xe3, 0x14, 0x80, 0xdd, 0x11, 0xa2, 0x58, 0x82, 0xa6,
-	0x3f, 0x04, 0xe0, 0x41, 0xe0, 0x05, 0x53, 0xec, 0xaa, 0x2e, 0xb5, 0xef, 0x45, 0x5f, 0x53, 0x20,
-	0xb1, 0xa7, 0x52, 0x79, 0xfa, 0x29, 0xf2, 0x81, 0x03, 0xcc, 0x92, 0xca, 0xe4, 0x80, 0x76, 0x05,
-	0x9f, 0xc0, 0x5e, 0x9c, 0x61, 0x9a, 0x0a, 0x17, 0x74, 0x84, 0xf6, 0x1d, 0x2b, 0xb2, 0x76, 0x4b,
-	0x2c, 0x41, 0x09, 0xfc, 0x13, 0x3e, 0x8b, 0xbc, 0x00, 0x2b, 0x50, 0x01, 0xff, 0x18, 0xed, 0x29,
-	0x5e, 0xa2, 0x30, 0xfe, 0xcc, 0x72, 0xac, 0x00, 0x7f, 0x7e, 0x8b, 0xf1, 0xd1, 0x9e, 0xc5, 0xc7,
-	0x91, 0xc0, 0xbb, 0x56, 0x14, 0xd8, 0x4f, 0xb1, 0x7c, 0x15, 0xf0, 0x87, 0x68, 0x4f, 0xf1, 0x12,
-	0xa5, 0xbf, 0x03, 0x55, 0x99, 0x29, 0x51, 0xeb, 0x4d, 0xc6, 0x86, 0x4c, 0xb6, 0x74, 0xa3, 0x49,
-	0xbf, 0x6f, 0x8e, 0x46, 0x1a, 0x91, 0x85, 0x5f, 0xff, 0x1d, 0x01, 0x35, 0x4b, 0x8b, 0xe8, 0xd5,
-	0x06, 0xc3, 0x81, 0x29, 0xa1, 0xe3, 0xbd, 0x43, 0x73, 0x38, 0x19, 0x6b, 0x44, 0x34, 0x6e, 0x7d,
-	0x63, 0xd0, 0x37, 0x0f, 0xcc, 0x1d, 0xd9, 0x00, 0x9a, 0x3f, 0x36, 0xfb, 0x93, 0xf1, 0xde, 0x70,
-	0xa0, 0x55, 0xc4, 0x64, 0xcf, 0xd8, 0x99, 0xee, 0x18, 0x63, 0x43, 0x53, 0xc4, 0x68, 0x4f, 0xf4,
-	0x8c, 0x03, 0xe3, 0x40, 0x5b, 0xa3, 0x1b, 0xb0, 0x3e, 0x19, 0x18, 0x8f, 0x8d, 0xbd, 0x03, 0xa3,
-	0x77, 0x60, 0x6a, 0x55, 0xe1, 0x3b, 0x18, 0x8e, 0xa7, 0x0f, 0x87, 0x93, 0xc1, 0x8e, 0x56, 0x13,
-	0xcd, 0xa3, 0x18, 0x1a, 0xfd, 0xbe, 0x79, 0x34, 0x46, 0x48, 0x3d, 0xf9, 0x41, 0xaa, 0x82, 0x22,
-	0xfa, 0x60, 0xdd, 0x04, 0xc8, 0xf3, 0x5d, 0x6c, 0xb3, 0xd5, 0xeb, 0xda, 0xb2, 0x2b, 0xee, 0xf0,
-	0x2f, 0x09, 0x40, 0x7e, 0x0e, 0xf4, 0x7e, 0xfe, 0x6e, 0x91, 0x2d, 0xe2, 0xcd, 0xd5, 0xe3, 0xba,
-	0xfa, 0xf5, 0xf2, 0x83, 0xc2, 0x2b, 0xa4, 0xbc, 0x7a, 0xa5, 0xa5, 0xeb, 0x7f, 0x7a, 0x8b, 0x4c,
-	0xa1, 0xb1, 0x1c, 0x5f, 0x94, 0x3a, 0xd9, 0xbb, 0x23, 0x0f, 0x95, 0x25, 0xa3, 0xff, 0xbf, 0xff,
-	0xfc, 0x0d, 0x81, 0x8d, 0x15, 0x1a, 0xd7, 0x2e, 0x52, 0xa8, 0x9c, 0xe5, 0x2f, 0x5b, 0x39, 0xaf,
-	0x20, 0x23, 0x0e, 0x2f, 0x13, 0xf3, 0xd5, 0x6f, 0xa4, 0x2f, 0x72, 0x78, 0x3d, 0x80, 0x5c, 0xe3,
-	0xf4, 0xbb, 0x50, 0x2d, 0x3c, 0xfd, 0x6f, 0xae, 0xde, 0x84, 0xe4, 0xf1, 0x2f, 0x09, 0x27, 0x58,
-	0xfd, 0x0f, 0x04, 0x1a, 0xcb, 0xd3, 0xd7, 0x26, 0xe5, 0x7f, 0x7f, 0xd2, 0xf6, 0x0a, 0xa2, 0x90,
-	0x75, 0xfe, 0xed, 0xeb, 0xf2, 0x88, 0x6f, 0x8f, 0x4b, 0xba, 0xe8, 0x7d, 0xff, 0xf9, 0xcb, 0x4e,
-	0xe9, 0xd3, 0x97, 0x9d, 0xd2, 0xe7, 0x2f, 0x3b, 0xe4, 0x17, 0x17, 0x1d, 0xf2, 0xe7, 0x8b, 0x0e,
-	0x79, 0x76, 0xd1, 0x21, 0xcf, 0x2f, 0x3a, 0xe4, 0x1f, 0x17, 0x1d, 0xf2, 0xd9, 0x45, 0xa7, 0xf4,
-	0xf9, 0x45, 0x87, 0xfc, 0xf6, 0x55, 0xa7, 0xf4, 0xfc, 0x55, 0xa7, 0xf4, 0xe9, 0xab, 0x4e, 0xe9,
-	0x27, 0x35, 0xfc, 0x83, 0xc5, 0x3f, 0x3e, 0xae, 0xe2, 0x5f, 0x25, 0xef, 0xfe, 0x3b, 0x00, 0x00,
-	0xff, 0xff, 0xec, 0xdb, 0xa5, 0x25, 0x72, 0x11, 0x00, 0x00,
+	// 1785 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xcd, 0x73, 0xe3, 0x48,
+	0x15, 0x77, 0xdb, 0xf2, 0x87, 0x5e, 0x6c, 0x47, 0xdb, 0x3b, 0x35, 0x78, 0x53, 0x3b, 0x4e, 0x46,
+	0x14, 0x4b, 0xf8, 0xf2, 0xc0, 0x2c, 0xcc, 0xd6, 0x6e, 0x0d, 0x05, 0xb2, 0xa3, 0x99, 0x24, 0x9b,
+	0xd8, 0xa1, 0x6d, 0xcf, 0xb2, 0x5c, 0x5c, 0x8a, 0xd3, 0x89, 0x55, 0x2b, 0x59, 0x42, 0x6a, 0x0d,
+	0x13, 0x4e, 0x5c, 0xa0, 0x28, 0x4e, 0x5c, 0xb8, 0x50, 0xdc, 0x38, 0x2c, 0x7f, 0x01, 0x7f, 0xc3,
+	0x54, 0x51, 0x54, 0xcd, 0x71, 0x8b, 0xc3, 0x14, 0x93, 0xb9, 0xec, 0x71, 0xcf, 0x9c, 0xa8, 0xee,
+	0xd6, 0x87, 0xa5, 0x24, 0xb0, 0x30, 0x73, 0xd3, 0x7b, 0xef, 0xf7, 0x5e, 0xff, 0xf4, 0xfa, 0xd7,
+	0xed, 0x27, 0xc3, 0x9a, 0x6b, 0xbb, 0x76, 0xd0, 0xf3, 0x03, 0x8f, 0x79, 0xb8, 0x31, 0xf7, 0x02,
+	0x46, 0x9f, 0xf8, 0xc7, 0x1b, 0xdf, 0x39, 0xb3, 0xd9, 0x22, 0x3a, 0xee, 0xcd, 0x3d, 0xf7, 0xce,
+	0x99, 0x77, 0xe6, 0xdd, 0x11, 0x80, 0xe3, 0xe8, 0x54, 0x58, 0xc2, 0x10, 0x4f, 0x32, 0x51, 0xff,
+	0x6b, 0x19, 0x9a, 0x1f, 0x05, 0x36, 0xa3, 0x84, 0xfe, 0x3c, 0xa2, 0x21, 0xc3, 0x47, 0x00, 0xcc,
+	0x76, 0x69, 0x48, 0x03, 0x9b, 0x86, 0x1d, 0xb4, 0x55, 0xd9, 0x5e, 0xbb, 0x7b, 0xa3, 0x97, 0x94,
+	0xef, 0x4d, 0x6c, 0x97, 0x8e, 0x45, 0xac, 0xbf, 0xf1, 0xf4, 0xf9, 0x66, 0xe9, 0x1f, 0xcf, 0x37,
+	0xf1, 0x51, 0x40, 0x2d, 0xc7, 0xf1, 0xe6, 0x93, 0x34, 0x8f, 0xac, 0xd4, 0xc0, 0xef, 0x43, 0x6d,
+	0xec, 0x45, 0xc1, 0x9c, 0x76, 0xca, 0x5b, 0x68, 0xbb, 0x7d, 0xf7, 0x76, 0x56, 0x6d, 0x75, 0xe5,
+	0x9e, 0x04, 0x99, 0xcb, 0xc8, 0x25, 0x71, 0x02, 0xfe, 0x00, 0x1a, 0x2e, 0x65, 0xd6, 0x89, 0xc5,
+	0xac, 0x4e, 0x45, 0x50, 0xe9, 0x64, 0xc9, 0x87, 0x94, 0x05, 0xf6, 0xfc, 0x30, 0x8e, 0xf7, 0x95,
+	0xa7, 0xcf, 0x37, 0x11, 0x49, 0xf1, 0xf8, 0x3e, 0x6c, 0x84, 0x9f, 0xd8, 0xfe, 0xcc, 0xb1, 0x8e,
+	0xa9, 0x33, 0x5b, 0x5a, 0x2e, 0x9d, 0x3d, 0xb6, 0x1c, 0xfb, 0xc4, 0x62, 0xb6, 0xb7, 0xec, 0x7c,
+	0x5e, 0xdf, 0x42, 0xdb, 0x0d, 0xf2, 0x15, 0x0e, 0x39, 0xe0, 0x88, 0xa1, 0xe5, 0xd2, 0x47, 0x69,
+	0x5c, 0xdf, 0x04, 0xc8, 0xf8, 0xe0, 0x3a, 0x54, 0x8c, 0xa3, 0x3d, 0xad, 0x84, 0x1b, 0xa0, 0x90,
+	0xe9, 0x81, 0xa9, 0x21, 0xfd, 0x5b, 0xd0, 0x8a, 0xd9, 0x87, 0xbe, 0xb7, 0x0c, 0x29, 0xde, 0x80,
+	0x86, 0x1f, 0xd0, 0x30, 0x8c, 0x02, 0xda, 0x41, 0x5b, 0x68, 0x1b, 0x91, 0xd4, 0xd6, 0x3f, 0x2d,
+	0x03, 0x64, 0x9d, 0xc3, 0x06, 0xd4, 0x04, 0xab, 0xa4, 0xbf, 0x6f, 0x66, 0x2f, 0x25, 0xb8, 0x1c,
+	0x59, 0x76, 0xd0, 0xbf, 0x11, 0xb7, 0xb7, 0x29, 0x5c, 0xc6, 0x89, 0xe5, 0x33, 0x1a, 0x90, 0x38,
+	0x11, 0x7f, 0x17, 0xea, 0xa1, 0xe5, 0xfa, 0x0e, 0x0d, 0x3b, 0x65, 0x51, 0x43, 0xcb, 0x6a, 0x8c,
+	0x45, 0x40, 0x34, 0xa4, 0x44, 0x12, 0x18, 0xbe, 0x07, 0x2a, 0x7d, 0x42, 0x5d, 0xdf, 0xb1, 0x82,
+	0x30, 0x6e, 0x26, 0xce, 0x72, 0xcc, 0x38, 0x14, 0x67, 0x65, 0x50, 0xfc, 0x3e, 0xc0, 0xc2, 0x0e,
+	0x99, 0x77, 0x16, 0x58, 0x6e, 0xd8, 0x51, 0x8a, 0x84, 0x77, 0x93, 0x58, 0x9c, 0xb9, 0x02, 0xc6,
+	0xdf, 0x83, 0x1b, 0xf3, 0x80, 0x5a, 0x8c, 0x9e, 0xcc, 0x84, 0x1e, 0x98, 0xe5, 0xfa, 0x33, 0x37,
+	0x94, 0xcd, 0xaf, 0x10, 0x1c, 0x07, 0x27, 0x49, 0xec, 0x30, 0xd4, 0x7f, 0x00, 0x6a, 0xda, 0x02,
+	0x8c, 0x41, 0xe1, 0xfb, 0x26, 0xda, 0xd9, 0x24, 0xe2, 0x19, 0xdf, 0x80, 0xea, 0x63, 0xcb, 0x89,
+	0xa4, 0x98, 0x9a, 0x44, 0x1a, 0xba, 0x01, 0x35, 0xf9, 0xd6, 0xf8, 0x36, 0x34, 0x73, 0x6b, 0x95,
+	0xc5, 0x52, 0x6b, 0x2c, 0x5b, 0x23, 0x2b, 0x21, 0xb7, 0x29, 0x2e, 0xf1, 0xc7, 0x32, 0xb4, 0xf3,
+	0x92, 0xc2, 0xef, 0x81, 0xc2, 0xce, 0x7d, 0x89, 0x6b, 0xdf, 0xfd, 0xea, 0x75, 0xd2, 0x8b, 0xcd,
+	0xc9, 0xb9, 0x4f, 0x89, 0x48, 0xc0, 0xdf, 0x06, 0xec, 0x0a, 0xdf, 0xec, 0xd4, 0x72, 0x6d, 0xe7,
+	0x5c, 0xc8, 0x4f, 0x50, 0x51, 0x89, 0x26, 0x23, 0x0f, 0x44, 0x80, 0xab, 0x8e, 0xbf, 0xe6, 0x82,
+	0x3a, 0x7e, 0x47, 0x11, 0x71, 0xf1, 0xcc, 0x7d, 0xd1, 0xd2, 0x66, 0x9d, 0xaa, 0xf4, 0xf1, 0x67,
+	0xfd, 0x1c, 0x20, 0x5b, 0x09, 0xaf, 0x41, 0x7d, 0x3a, 0xfc, 0x70, 0x38, 0xfa, 0x68, 0xa8, 0x95,
+	0xb8, 0x31, 0x18, 0x4d, 0x87, 0x13, 0x93, 0x68, 0x08, 0xab, 0x50, 0x7d, 0x68, 0x4c, 0x1f, 0x9a,
+	0x5a, 0x19, 0xb7, 0x40, 0xdd, 0xdd, 0x1b, 0x4f, 0x46, 0x0f, 0x89, 0x71, 0xa8, 0x55, 0x30, 0x86,
+	0xb6, 0x88, 0x64, 0x3e, 0x85, 0xa7, 0x8e, 0xa7, 0x87, 0x87, 0x06, 0xf9, 0x58, 0xab, 0x72, 0x7d,
+	0xef, 0x0d, 0x1f, 0x8c, 0xb4, 0x1a, 0x6e, 0x42, 0x63, 0x3c, 0x31, 0x26, 0xe6, 0xd8, 0x9c, 0x68,
+	0x75, 0xfd, 0x43, 0xa8, 0xc9, 0xa5, 0x5f, 0x83, 0x76, 0xf5, 0xdf, 0x20, 0x68, 0x24, 0x7a, 0x7b,
+	0x1d, 0x67, 0x21, 0x27, 0x89, 0x64, 0x3f, 0x2f, 0x09, 0xa1, 0x72, 0x49, 0x08, 0xfa, 0xdf, 0xaa,
+	0xa0, 0xa6, 0xfa, 0xc5, 0xb7, 0x40, 0x9d, 0x7b, 0xd1, 0x92, 0xcd, 0xec, 0x25, 0x13, 0x5b, 0xae,
+	0xec, 0x96, 0x48, 0x43, 0xb8, 0xf6, 0x96, 0x0c, 0xdf, 0x86, 0x35, 0x19, 0x3e, 0x75, 0x3c, 0x8b,
+	0xc9, 0xb5, 0x76, 0x4b, 0x04, 0x84, 0xf3, 0x01, 0xf7, 0x61, 0x0d, 0x2a, 0x61, 0xe4, 0x8a, 0x95,
+	0x10, 0xe1, 0x8f, 0xf8, 0x26, 0xd4, 0xc2, 0xf9, 0x82, 0xba, 0x96, 0xd8, 0xdc, 0x37, 0x48, 0x6c,
+	0xe1, 0xaf, 0x41, 0xfb, 0x97, 0x34, 0xf0, 0x66, 0x6c, 0x11, 0xd0, 0x70, 0xe1, 0x39, 0x27, 0x62,
+	0xa3, 0x11, 0x69, 0x71, 0xef, 0x24, 0x71, 0xe2, 0x77, 0x62, 0x58, 0xc6, 0xab, 0x26, 0x78, 0x21,
+	0xd2, 0xe4, 0xfe, 0x41, 0xc2, 0xed, 0x9b, 0xa0, 0xad, 0xe0, 0x24, 0xc1, 0xba, 0x20, 0x88, 0x48,
+	0x3b, 0x45, 0x4a, 0x92, 0x06, 0xb4, 0x97, 0xf4, 0xcc, 0x62, 0xf6, 0x63, 0x3a, 0x0b, 0x7d, 0x6b,
+	0x19, 0x76, 0x1a, 0xc5, 0x4b, 0xbe, 0x1f, 0xcd, 0x3f, 0xa1, 0x6c, 0xec, 0x5b, 0xcb, 0xf8, 0x50,
+	0xb7, 0x92, 0x0c, 0xee, 0x0b, 0xf1, 0xd7, 0x61, 0x3d, 0x2d, 0x71, 0x42, 0x1d, 0x66, 0x85, 0x1d,
+	0x75, 0xab, 0xb2, 0x8d, 0x49, 0x5a, 0x79, 0x47, 0x78, 0x73, 0x40, 0xc1, 0x2d, 0xec, 0xc0, 0x56,
+	0x65, 0x1b, 0x65, 0x40, 0x41, 0x8c, 0xdf, 0x88, 0x6d, 0xdf, 0x0b, 0xed, 0x15, 0x52, 0x6b, 0xff,
+	0x9d, 0x54, 0x92, 0x91, 0x92, 0x4a, 0x4b, 0xc4, 0xa4, 0x9a, 0x92, 0x54, 0xe2, 0xce, 0x48, 0xa5,
+	0xc0, 0x98, 0x54, 0x4b, 0x92, 0x4a, 0xdc, 0x31, 0xa9, 0xfb, 0x00, 0x01, 0x0d, 0x29, 0x9b, 0x2d,
+	0x78, 0xe7, 0xdb, 0xe2, 0x12, 0xb8, 0x75, 0xc5, 0xcd, 0xd7, 0x23, 0x1c, 0xb5, 0x6b, 0x2f, 0x19,
+	0x51, 0x83, 0xe4, 0x11, 0xbf, 0x0d, 0x6a, 0xaa, 0xb5, 0xce, 0xba, 0x10, 0x5f, 0xe6, 0xd0, 0x3f,
+	0x00, 0x35, 0xcd, 0xca, 0x1f, 0xe5, 0x3a, 0x54, 0x3e, 0x36, 0xc7, 0x1a, 0xc2, 0x35, 0x28, 0x0f,
+	0x47, 0x5a, 0x39, 0x3b, 0xce, 0x95, 0x0d, 0xe5, 0xb7, 0x7f, 0xee, 0xa2, 0x7e, 0x1d, 0xaa, 0x82,
+	0x77, 0xbf, 0x09, 0x90, 0x6d, 0xbb, 0xfe, 0x77, 0x05, 0xda, 0x62, 0x8b, 0x33, 0x49, 0x87, 0x80,
+	0x45, 0x8c, 0x06, 0xb3, 0xc2, 0x9b, 0xb4, 0xfa, 0xe6, 0xbf, 0x9e, 0x6f, 0x1a, 0x2b, 0xc3, 0x82,
+	0x1f, 0x78, 0x2e, 0x65, 0x0b, 0x1a, 0x85, 0xab, 0x8f, 0xae, 0x77, 0x42, 0x9d, 0x3b, 0xe9, 0x9d,
+	0xde, 0x1b, 0xc8, 0x72, 0xd9, 0x1b, 0x6b, 0xf3, 0x82, 0xe7, 0x55, 0x35, 0x7f, 0x6b, 0xf5, 0xa5,
+	0xa4, 0x8a, 0x89, 0x9a, 0x6a, 0x98, 0x1f, 0x76, 0x19, 0x89, 0x0f, 0xbb, 0x30, 0xae, 0x38, 0x79,
+	0xaf, 0x41, 0x51, 0xaf, 0xe1, 0xa4, 0x7c, 0x03, 0xb4, 0x94, 0xc5, 0xb1, 0xc0, 0x26, 0x62, 0x4b,
+	0x35, 0x28, 0x4b, 0x08, 0x68, 0xba, 0x5a, 0x02, 0x95, 0x87, 0x25, 0x3d, 0x43, 0x31, 0x74, 0x5f,
+	0x69, 0x20, 0xad, 0xbc, 0xaf, 0x34, 0x6a, 0x5a, 0x7d, 0x5f, 0x69, 0xa8, 0x1a, 0xec, 0x2b, 0x8d,
+	0xa6, 0xd6, 0xda, 0x57, 0x1a, 0xeb, 0x9a, 0x46, 0xb2, 0x5b, 0x8c, 0x14, 0x6e, 0x0f, 0x52, 0x3c,
+	0xb6, 0xa4, 0x78, 0x64, 0x56, 0x25, 0x7a, 0x1f, 0x20, 0x7b, 0x3d, 0xbe, 0xab, 0xde, 0xe9, 0x69,
+	0x48, 0xe5, 0xd5, 0xf8, 0x06, 0x89, 0x2d, 0xee, 0x77, 0xe8, 0xf2, 0x8c, 0x2d, 0xc4, 0x86, 0xb4,
+	0x48, 0x6c, 0xe9, 0x11, 0xe0, 0xbc, 0x18, 0xc5, 0x2f, 0xfa, 0x7d, 0x50, 0x53, 0x2d, 0x89, 0x42,
+	0xb9, 0x89, 0x2e, 0x9f, 0x90, 0x8c, 0x22, 0x69, 0xc2, 0x97, 0xf8, 0x6d, 0xd7, 0x97, 0xb0, 0x2e,
+	0x07, 0x81, 0xec, 0x10, 0xa4, 0x8a, 0x41, 0x57, 0x28, 0xa6, 0x9c, 0x29, 0xe6, 0x5d, 0xa8, 0x27,
+	0x7d, 0x97, 0xe3, 0xd1, 0x5b, 0x57, 0x4d, 0x39, 0x02, 0x41, 0x12, 0xa4, 0x1e, 0xc2, 0x7a, 0x21,
+	0x86, 0xbb, 0x00, 0xc7, 0x5e, 0xb4, 0x3c, 0xb1, 0xe2, 0x09, 0x1a, 0x6d, 0x57, 0xc9, 0x8a, 0x87,
+	0xf3, 0x71, 0xbc, 0x5f, 0xd0, 0x20, 0x51, 0xb0, 0x30, 0xb8, 0x37, 0xf2, 0x7d, 0x1a, 0xc4, 0x1a,
+	0x96, 0x46, 0xc6, 0x5d, 0x59, 0xe1, 0xae, 0x3b, 0xf0, 0x66, 0xe1, 0x25, 0x45, 0x73, 0x73, 0x37,
+	0x4e, 0xb9, 0x70, 0xe3, 0xe0, 0xf7, 0x2e, 0xb7, 0xfe, 0xad, 0xe2, 0xcc, 0x98, 0xd6, 0x5b, 0xe9,
+	0xba, 0xfe, 0xa9, 0x02, 0xad, 0x9f, 0x44, 0x34, 0x38, 0x4f, 0x47, 0xdd, 0x7b, 0x50, 0x0b, 0x99,
+	0xc5, 0xa2, 0x30, 0x9e, 0x8c, 0xba, 0x59, 0x9d, 0x1c, 0xb0, 0x37, 0x16, 0x28, 0x12, 0xa3, 0xf1,
+	0x8f, 0x01, 0x68, 0x10, 0x78, 0xc1, 0x4c, 0x4c, 0x55, 0x97, 0xbe, 0x06, 0xf2, 0xb9, 0x26, 0x47,
+	0x8a, 0x99, 0x4a, 0xa5, 0xc9, 0x23, 0xef, 0x87, 0x30, 0x44, 0x97, 0x54, 0x22, 0x0d, 0xdc, 0xe3,
+	0x7c, 0x02, 0x7b, 0x79, 0x26, 0xda, 0x94, 0x3b, 0xa0, 0x63, 0xe1, 0xdf, 0xb1, 0x98, 0xb5, 0x5b,
+	0x22, 0x31, 0x8a, 0xe3, 0x1f, 0xd3, 0x39, 0xf3, 0x82, 0x4e, 0xb5, 0x88, 0x7f, 0x24, 0xfc, 0x09,
+	0x5e, 0xa2, 0x44, 0xfd, 0xb9, 0xe5, 0x58, 0x41, 0xa7, 0x56, 0xc4, 0x8f, 0x85, 0x3f, 0xad, 0x2f,
+	0x2c, 0x8e, 0x77, 0x2d, 0x16, 0xd8, 0x4f, 0x3a, 0xf5, 0x22, 0xfe, 0x50, 0xf8, 0x13, 0xbc, 0x44,
+	0xe9, 0xef, 0x40, 0x4d, 0x76, 0x8a, 0xdf, 0xf5, 0x26, 0x21, 0x23, 0x22, 0x47, 0xba, 0xf1, 0x74,
+	0x30, 0x30, 0xc7, 0x63, 0x0d, 0xc9, 0x8b, 0x5f, 0xff, 0x03, 0x02, 0x35, 0x6d, 0x0b, 0x9f, 0xd5,
+	0x86, 0xa3, 0xa1, 0x29, 0xa1, 0x93, 0xbd, 0x43, 0x73, 0x34, 0x9d, 0x68, 0x88, 0x0f, 0x6e, 0x03,
+	0x63, 0x38, 0x30, 0x0f, 0xcc, 0x1d, 0x39, 0x00, 0x9a, 0x3f, 0x35, 0x07, 0xd3, 0xc9, 0xde, 0x68,
+	0xa8, 0x55, 0x78, 0xb0, 0x6f, 0xec, 0xcc, 0x76, 0x8c, 0x89, 0xa1, 0x29, 0xdc, 0xda, 0xe3, 0x33,
+	0xe3, 0xd0, 0x38, 0xd0, 0xaa, 0x78, 0x1d, 0xd6, 0xa6, 0x43, 0xe3, 0x91, 0xb1, 0x77, 0x60, 0xf4,
+	0x0f, 0x4c, 0xad, 0xc6, 0x73, 0x87, 0xa3, 0xc9, 0xec, 0xc1, 0x68, 0x3a, 0xdc, 0xd1, 0xea, 0x7c,
+	0x78, 0xe4, 0xa6, 0x31, 0x18, 0x98, 0x47, 0x13, 0x01, 0x69, 0xc4, 0x3f, 0x48, 0x35, 0x50, 0xf8,
+	0x1c, 0xac, 0x9b, 0x00, 0x59, 0xbf, 0xf3, 0x63, 0xb6, 0x7a, 0xdd, 0x58, 0x76, 0xc5, 0x19, 0xfe,
+	0x35, 0x02, 0xc8, 0xf6, 0x01, 0xdf, 0xcb, 0x3e, 0x75, 0xe4, 0x88, 0x78, 0xb3, 0xb8, 0x5d, 0x57,
+	0x7f, 0xf0, 0xfc, 0x28, 0xf7, 0xe1, 0x52, 0x2e, 0x1e, 0x69, 0x99, 0xfa, 0x1f, 0x3e, 0x5f, 0xf4,
+	0x19, 0x34, 0x57, 0xeb, 0xf3, 0xab, 0x4e, 0xce, 0xee, 0x82, 0x87, 0x4a, 0x62, 0xeb, 0xff, 0x9f,
+	0x3f, 0x7f, 0x87, 0x60, 0xbd, 0x40, 0xe3, 0xda, 0x45, 0x72, 0x37, 0x67, 0xf9, 0x55, 0x6f, 0xce,
+	0x2b, 0xc8, 0xf0, 0xcd, 0x4b, 0xc5, 0x7c, 0xf5, 0x37, 0xd2, 0x97, 0xd9, 0xbc, 0x3e, 0x40, 0xa6,
+	0x71, 0xfc, 0x7d, 0xa8, 0xe5, 0xfe, 0x49, 0xb8, 0x59, 0x3c, 0x09, 0xf1, 0x7f, 0x09, 0x92, 0x70,
+	0x8c, 0xd5, 0xff, 0x84, 0xa0, 0xb9, 0x1a, 0xbe, 0xb6, 0x29, 0xff, 0xfb, 0x57, 0x70, 0x3f, 0x27,
+	0x0a, 0x79, 0xcf, 0xbf, 0x7d, 0x5d, 0x1f, 0xc5, 0xb7, 0xc7, 0x25, 0x5d, 0xf4, 0x7f, 0xf8, 0xec,
+	0x45, 0xb7, 0xf4, 0xd9, 0x8b, 0x6e, 0xe9, 0x8b, 0x17, 0x5d, 0xf4, 0xab, 0x8b, 0x2e, 0xfa, 0xcb,
+	0x45, 0x17, 0x3d, 0xbd, 0xe8, 0xa2, 0x67, 0x17, 0x5d, 0xf4, 0xcf, 0x8b, 0x2e, 0xfa, 0xfc, 0xa2,
+	0x5b, 0xfa, 0xe2, 0xa2, 0x8b, 0x7e, 0xff, 0xb2, 0x5b, 0x7a, 0xf6, 0xb2, 0x5b, 0xfa, 0xec, 0x65,
+	0xb7, 0xf4, 0xb3, 0xba, 0xf8, 0xbf, 0xc6, 0x3f, 0x3e, 0xae, 0x89, 0x7f, 0x5e, 0xde, 0xfd, 0xf7,
+	0x00, 0x06, 0xc3, 0x43, 0xea, 0xc1, 0x11, 0x00, 0x00,
 }
 
 func (x WriteRequest_SourceEnum) String() string {
@@ -1975,6 +1999,14 @@ func (this *WriteRequest) Equal(that interface{}) bool {
 	if this.SkipLabelNameValidation != that1.SkipLabelNameValidation {
 		return false
 	}
+	if len(this.IngestAggregationInputs) != len(that1.IngestAggregationInputs) {
+		return false
+	}
+	for i := range this.IngestAggregationInputs {
+		if !this.IngestAggregationInputs[i].Equal(that1.IngestAggregationInputs[i]) {
+			return false
+		}
+	}
 	return true
 }
 func (this *WriteResponse) Equal(that interface{}) bool {
@@ -1996,6 +2028,9 @@ func (this *WriteResponse) Equal(that interface{}) bool {
 	} else if this == nil {
 		return false
 	}
+	if this.Pressure != that1.Pressure {
+		return false
+	}
 	return true
 }
 func (this *TimeSeries) Equal(that interface{}) bool {
@@ -2049,6 +2084,9 @@ func (this *TimeSeries) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if this.CreatedTimestampMs != that1.CreatedTimestampMs {
+		return false
+	}
 	return true
 }
 func (this *LabelPair) Equal(that interface{}) bool {
@@ -2994,7 +3032,7 @@ func (this *WriteRequest) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 9)
 	s = append(s, "&mimirpb.WriteRequest{")
 	s = append(s, "Timeseries: "+fmt.Sprintf("%#v", this.Timeseries)+",\n")
 	s = append(s, "Source: "+fmt.Sprintf("%#v", this.Source)+",\n")
@@ -3002,6 +3040,7 @@ func (this *WriteRequest) GoString() string {
 		s = append(s, "Metadata: "+fmt.Sprintf("%#v", this.Metadata)+",\n")
 	}
 	s = append(s, "SkipLabelNameValidation: "+fmt.Sprintf("%#v", this.SkipLabelNameValidation)+",\n")
+	s = append(s, "IngestAggregationInputs: "+fmt.Sprintf("%#v", this.IngestAggregationInputs)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -3009,8 +3048,9 @@ func (this *WriteResponse) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 4)
+	s := make([]string, 0, 5)
 	s = append(s, "&mimirpb.WriteResponse{")
+	s = append(s, "Pressure: "+fmt.Sprintf("%#v", this.Pressure)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -3018,7 +3058,7 @@ func (this *TimeSeries) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 9)
 	s = append(s, "&mimirpb.TimeSeries{")
 	s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
 	if this.Samples != nil {
@@ -3042,6 +3082,7 @@ func (this *TimeSeries) GoString() string {
 		}
 		s = append(s, "Histograms: "+fmt.Sprintf("%#v", vs)+",\n")
 	}
+	s = append(s, "CreatedTimestampMs: "+fmt.Sprintf("%#v", this.CreatedTimestampMs)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -3449,6 +3490,22 @@ func (m *WriteRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.IngestAggregationInputs) > 0 {
+		for iNdEx := len(m.IngestAggregationInputs) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size := m.IngestAggregationInputs[iNdEx].Size()
+				i -= size
+				if _, err := m.IngestAggregationInputs[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+					return 0, err
+				}
+				i = encodeVarintMimir(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x3e
+			i--
+			dAtA[i] = 0xca
+		}
+	}
 	if m.SkipLabelNameValidation {
 		i--
 		if m.SkipLabelNameValidation {
@@ -3517,6 +3574,12 @@ func (m *WriteResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Pressure != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Pressure))))
+		i--
+		dAtA[i] = 0x9
+	}
 	return len(dAtA) - i, nil
 }
 
@@ -3540,6 +3603,13 @@ func (m *TimeSeries) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.CreatedTimestampMs != 0 {
+		i = encodeVarintMimir(dAtA, i, uint64(m.CreatedTimestampMs))
+		i--
+		dAtA[i] = 0x3e
+		i--
+		dAtA[i] = 0xc0
+	}
 	if len(m.Histograms) > 0 {
 		for iNdEx := len(m.Histograms) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -4786,6 +4856,12 @@ func (m *WriteRequest) Size() (n int) {
 	if m.SkipLabelNameValidation {
 		n += 3
 	}
+	if len(m.IngestAggregationInputs) > 0 {
+		for _, e := range m.IngestAggregationInputs {
+			l = e.Size()
+			n += 2 + l + sovMimir(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -4795,6 +4871,9 @@ func (m *WriteResponse) Size() (n int) {
 	}
 	var l int
 	_ = l
+	if m.Pressure != 0 {
+		n += 9
+	}
 	return n
 }
 
@@ -4828,6 +4907,9 @@ func (m *TimeSeries) Size() (n int) {
 			n += 1 + l + sovMimir(uint64(l))
 		}
 	}
+	if m.CreatedTimestampMs != 0 {
+		n += 2 + sovMimir(uint64(m.CreatedTimestampMs))
+	}
 	return n
 }
 
@@ -5378,6 +5460,7 @@ func (this *WriteRequest) String() string {
 		`Source:` + fmt.Sprintf("%v", this.Source) + `,`,
 		`Metadata:` + repeatedStringForMetadata + `,`,
 		`SkipLabelNameValidation:` + fmt.Sprintf("%v", this.SkipLabelNameValidation) + `,`,
+		`IngestAggregationInputs:` + fmt.Sprintf("%v", this.IngestAggregationInputs) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -5387,6 +5470,7 @@ func (this *WriteResponse) String() string {
 		return "nil"
 	}
 	s := strings.Join([]string{`&WriteResponse{`,
+		`Pressure:` + fmt.Sprintf("%v", this.Pressure) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -5415,6 +5499,7 @@ func (this *TimeSeries) String() string {
 		`Samples:` + repeatedStringForSamples + `,`,
 		`Exemplars:` + repeatedStringForExemplars + `,`,
 		`Histograms:` + repeatedStringForHistograms + `,`,
+		`CreatedTimestampMs:` + fmt.Sprintf("%v", this.CreatedTimestampMs) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -5941,6 +6026,40 @@ func (m *WriteRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.SkipLabelNameValidation = bool(v != 0)
+		case 1001:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IngestAggregationInputs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMimir
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthMimir
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.IngestAggregationInputs = append(m.IngestAggregationInputs, PreallocTimeseries{})
+			if err := m.IngestAggregationInputs[len(m.IngestAggregationInputs)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMimir(dAtA[iNdEx:])
@@ -5994,6 +6113,17 @@ func (m *WriteResponse) Unmarshal(dAtA []byte) error {
 			return fmt.Errorf("proto: WriteResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pressure", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Pressure = float64(math.Float64frombits(v))
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMimir(dAtA[iNdEx:])
@@ -6183,6 +6313,25 @@ func (m *TimeSeries) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 1000:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreatedTimestampMs", wireType)
+			}
+			m.CreatedTimestampMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMimir
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreatedTimestampMs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMimir(dAtA[iNdEx:])
@@ -316,6 +316,7 @@ func ReuseTimeseries(ts *TimeSeries) {
 	ts.Labels = ts.Labels[:0]
 	ts.Samples = ts.Samples[:0]
 	ts.Histograms = ts.Histograms[:0]
+	ts.CreatedTimestampMs = 0
 
 	ClearExemplars(ts)
 	timeSeriesPool.Put(ts)
@@ -381,6 +382,7 @@ func DeepCopyTimeseries(dst, src PreallocTimeseries, keepExemplars bool) Preallo
 		dstTs.Samples = dstTs.Samples[:len(srcTs.Samples)]
 	}
 	copy(dstTs.Samples, srcTs.Samples)
+	dstTs.CreatedTimestampMs = srcTs.CreatedTimestampMs
 
 	// Prepare the slice of exemplars.
 	if keepExemplars {
@@ -64,11 +64,13 @@ func TestTimeseriesFromPool(t *testing.T) {
 		ts := TimeseriesFromPool()
 		ts.Labels = []LabelAdapter{{Name: "foo", Value: "bar"}}
 		ts.Samples = []Sample{{Value: 1, TimestampMs: 2}}
+		ts.CreatedTimestampMs = 1
 		ReuseTimeseries(ts)
 
 		reused := TimeseriesFromPool()
 		assert.Len(t, reused.Labels, 0)
 		assert.Len(t, reused.Samples, 0)
+		assert.Zero(t, reused.CreatedTimestampMs)
 	})
 }
 
@@ -120,6 +122,7 @@ func TestDeepCopyTimeseries(t *testing.T) {
 					{Name: "exemplarLabel2", Value: "exemplarValue2"},
 				},
 			}},
+			CreatedTimestampMs: 1,
 		},
 	}
 	dst := PreallocTimeseries{}
@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package analyze
+
+// MetricsUsage cross-references the metrics used in dashboards and rules with the series ingested by a tenant.
+type MetricsUsage struct {
+	TotalSeries uint64 `json:"total_series"`
+	UsedSeries  uint64 `json:"used_series"`
+
+	// UnusedCandidatesSeries is the number of series of the metrics which are neither used in dashboards nor rules,
+	// which is the estimated saving if these metrics were dropped.
+	UnusedCandidatesSeries uint64  `json:"unused_candidates_series"`
+	EstimatedSavingsRatio  float64 `json:"estimated_savings_ratio"`
+
+	UsedMetrics            []MetricUsage `json:"used_metrics"`
+	UnusedMetricCandidates []MetricUsage `json:"unused_metric_candidates"`
+
+	// MissingMetrics are the metrics used in dashboards or rules without any series ingested.
+	MissingMetrics []string `json:"missing_metrics"`
+
+	Errors []string `json:"errors"`
+}
+
+type MetricUsage struct {
+	Metric      string   `json:"metric"`
+	SeriesCount uint64   `json:"series_count"`
+	Dashboards  []string `json:"dashboards,omitempty"`
+	RuleGroups  []string `json:"rule_groups,omitempty"`
+}
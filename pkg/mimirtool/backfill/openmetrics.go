@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package backfill
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
+)
+
+// maxSamplesInAppender is the number of samples appended to a block before committing them.
+const maxSamplesInAppender = 5000
+
+// openMetricsIterator iterates over the samples of an OpenMetrics text dump.
+// The external labels are added to the labels of each series.
+type openMetricsIterator struct {
+	parser         textparse.Parser
+	externalLabels labels.Labels
+	builder        *labels.Builder
+
+	ts     int64
+	v      float64
+	labels labels.Labels
+}
+
+func newOpenMetricsIterator(data []byte, externalLabels labels.Labels) *openMetricsIterator {
+	return &openMetricsIterator{
+		parser:         textparse.NewOpenMetricsParser(data),
+		externalLabels: externalLabels,
+		builder:        labels.NewBuilder(nil),
+	}
+}
+
+func (i *openMetricsIterator) Next() error {
+	for {
+		entry, err := i.parser.Next()
+		if err != nil {
+			// The parser returns io.EOF once the whole input has been parsed.
+			return err
+		}
+		if entry != textparse.EntrySeries {
+			continue
+		}
+
+		_, ts, v := i.parser.Series()
+
+		var l labels.Labels
+		i.parser.Metric(&l)
+		if ts == nil {
+			return fmt.Errorf("expected timestamp for series %s, got none", l)
+		}
+
+		i.builder.Reset(l)
+		for _, el := range i.externalLabels {
+			i.builder.Set(el.Name, el.Value)
+		}
+
+		i.ts, i.v, i.labels = *ts, v, i.builder.Labels(nil)
+		return nil
+	}
+}
+
+func (i *openMetricsIterator) Sample() (ts int64, v float64) {
+	return i.ts, i.v
+}
+
+func (i *openMetricsIterator) Labels() (l labels.Labels) {
+	return i.labels
+}
+
+// CreateBlocksFromOpenMetrics converts the input OpenMetrics text dump into TSDB blocks written to outputDir.
+// Every sample of the dump must have a timestamp. The external labels are added to each series.
+func CreateBlocksFromOpenMetrics(data []byte, externalLabels labels.Labels, outputDir string, humanReadable bool, output io.Writer) error {
+	mint, maxt, err := openMetricsTimeRange(data)
+	if err != nil {
+		return err
+	}
+
+	input := func() Iterator {
+		return newOpenMetricsIterator(data, externalLabels)
+	}
+	return CreateBlocks(input, mint, maxt, maxSamplesInAppender, outputDir, humanReadable, output)
+}
+
+// openMetricsTimeRange returns the min and max timestamps of the samples in the input OpenMetrics text dump.
+func openMetricsTimeRange(data []byte) (mint, maxt int64, err error) {
+	mint, maxt = math.MaxInt64, math.MinInt64
+
+	i := newOpenMetricsIterator(data, nil)
+	for {
+		err := i.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "parse OpenMetrics input")
+		}
+
+		ts, _ := i.Sample()
+		if ts < mint {
+			mint = ts
+		}
+		if ts > maxt {
+			maxt = ts
+		}
+	}
+
+	if mint > maxt {
+		return 0, 0, errors.New("no samples found in the OpenMetrics input")
+	}
+	return mint, maxt, nil
+}
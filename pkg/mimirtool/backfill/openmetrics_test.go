@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package backfill
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBlocksFromOpenMetrics(t *testing.T) {
+	type sample struct {
+		ts int64
+		v  float64
+	}
+
+	const input = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200"} 1 1565133713.989
+http_requests_total{code="200"} 2 1565133773.989
+http_requests_total{code="400"} 3 1565166113.989
+# EOF
+`
+
+	outputDir := t.TempDir()
+	externalLabels := labels.FromStrings("cluster", "backfill")
+	require.NoError(t, CreateBlocksFromOpenMetrics([]byte(input), externalLabels, outputDir, false, &bytes.Buffer{}))
+
+	db, err := tsdb.OpenDBReadOnly(outputDir, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	// The samples span multiple block ranges, so multiple blocks have been created.
+	blocks, err := db.Blocks()
+	require.NoError(t, err)
+	assert.Len(t, blocks, 2)
+	for _, b := range blocks {
+		assert.LessOrEqual(t, b.Meta().MaxTime-b.Meta().MinTime, (2 * time.Hour).Milliseconds())
+	}
+
+	actual := map[string][]sample{}
+	for _, b := range blocks {
+		q, err := tsdb.NewBlockQuerier(b, math.MinInt64, math.MaxInt64)
+		require.NoError(t, err)
+
+		ss := q.Select(true, nil, labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+"))
+		for ss.Next() {
+			series := ss.At()
+			it := series.Iterator(nil)
+			for it.Next() != chunkenc.ValNone {
+				ts, v := it.At()
+				actual[series.Labels().String()] = append(actual[series.Labels().String()], sample{ts: ts, v: v})
+			}
+			require.NoError(t, it.Err())
+		}
+		require.NoError(t, ss.Err())
+		require.NoError(t, q.Close())
+	}
+
+	assert.Equal(t, map[string][]sample{
+		`{__name__="http_requests_total", cluster="backfill", code="200"}`: {{ts: 1565133713989, v: 1}, {ts: 1565133773989, v: 2}},
+		`{__name__="http_requests_total", cluster="backfill", code="400"}`: {{ts: 1565166113989, v: 3}},
+	}, actual)
+}
+
+func TestCreateBlocksFromOpenMetrics_Errors(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expectedErr string
+	}{
+		"sample without timestamp": {
+			input:       "up 1\n# EOF\n",
+			expectedErr: "expected timestamp for series",
+		},
+		"no samples": {
+			input:       "# EOF\n",
+			expectedErr: "no samples found",
+		},
+		"invalid input": {
+			input:       "up{ 1 1\n# EOF\n",
+			expectedErr: "parse OpenMetrics input",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := CreateBlocksFromOpenMetrics([]byte(tc.input), nil, t.TempDir(), false, &bytes.Buffer{})
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
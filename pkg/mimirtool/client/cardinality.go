@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const labelValuesCardinalityAPIPath = "/prometheus/api/v1/cardinality/label_values"
+
+// LabelValuesCardinality is the response of the label values cardinality API.
+type LabelValuesCardinality struct {
+	SeriesCountTotal uint64                       `json:"series_count_total"`
+	Labels           []LabelNameValuesCardinality `json:"labels"`
+}
+
+type LabelNameValuesCardinality struct {
+	LabelName        string                  `json:"label_name"`
+	LabelValuesCount uint64                  `json:"label_values_count"`
+	SeriesCount      uint64                  `json:"series_count"`
+	Cardinality      []LabelValueCardinality `json:"cardinality"`
+}
+
+type LabelValueCardinality struct {
+	LabelValue  string `json:"label_value"`
+	SeriesCount uint64 `json:"series_count"`
+}
+
+// LabelValuesCardinality returns the number of series of the tenant for the values of the input label,
+// sorted by series count, up to limit values. The series can be filtered by an optional selector.
+func (r *MimirClient) LabelValuesCardinality(ctx context.Context, labelName, selector string, limit int) (*LabelValuesCardinality, error) {
+	params := url.Values{}
+	params.Set("label_names[]", labelName)
+	params.Set("limit", strconv.Itoa(limit))
+	if selector != "" {
+		params.Set("selector", selector)
+	}
+
+	res, err := r.doRequest(ctx, labelValuesCardinalityAPIPath+"?"+params.Encode(), "GET", nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result := &LabelValuesCardinality{}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return nil, errors.Wrap(err, "unable to decode the label values cardinality response")
+	}
+	return result, nil
+}
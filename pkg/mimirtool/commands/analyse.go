@@ -101,4 +101,37 @@ func (cmd *AnalyzeCommand) Register(app *kingpin.Application, envVars EnvVarName
 	ruleFileAnalyzeCmd.Flag("output", "The path for the output file").
 		Default("metrics-in-ruler.json").
 		StringVar(&rfCmd.outputFile)
+
+	umCmd := &UnusedMetricsAnalyzeCommand{}
+	unusedMetricsAnalyzeCmd := analyzeCmd.Command("unused-metrics", "Cross-reference the metrics used in Grafana dashboards and rules with the series ingested by a Grafana Mimir tenant, and report the unused metrics.").Action(umCmd.run)
+	unusedMetricsAnalyzeCmd.Flag("address", "Address of the Grafana Mimir instance; alternatively, set "+envVars.Address+".").
+		Envar(envVars.Address).
+		Required().
+		StringVar(&umCmd.ClientConfig.Address)
+	unusedMetricsAnalyzeCmd.Flag("id", "Grafana Mimir tenant ID; alternatively, set "+envVars.TenantID+".").
+		Envar(envVars.TenantID).
+		Default("").
+		StringVar(&umCmd.ClientConfig.ID)
+	unusedMetricsAnalyzeCmd.Flag("key", "API key to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").
+		Envar(envVars.APIKey).
+		Default("").
+		StringVar(&umCmd.ClientConfig.Key)
+	unusedMetricsAnalyzeCmd.Flag("read-timeout", "timeout for read requests").
+		Default("30s").
+		DurationVar(&umCmd.readTimeout)
+	unusedMetricsAnalyzeCmd.Flag("limit", "Max number of metrics, with the highest number of series, to consider as unused candidates. It can't be greater than 500.").
+		Default("500").
+		IntVar(&umCmd.limit)
+	unusedMetricsAnalyzeCmd.Flag("grafana-metrics-file", "The path for the input file containing the metrics from grafana-analyze command").
+		Default("metrics-in-grafana.json").
+		StringVar(&umCmd.grafanaMetricsFile)
+	unusedMetricsAnalyzeCmd.Flag("ruler-metrics-file", "The path for the input file containing the metrics from ruler-analyze command").
+		Default("metrics-in-ruler.json").
+		StringVar(&umCmd.rulerMetricsFile)
+	unusedMetricsAnalyzeCmd.Flag("concurrency", "Concurrency (Default: runtime.NumCPU())").
+		Default(strconv.Itoa(runtime.NumCPU())).
+		IntVar(&umCmd.concurrency)
+	unusedMetricsAnalyzeCmd.Flag("output", "The path for the output file").
+		Default("unused-metrics.json").
+		StringVar(&umCmd.outputFile)
 }
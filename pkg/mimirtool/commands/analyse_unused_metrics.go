@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/concurrency"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/grafana/mimir/pkg/mimirtool/analyze"
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+)
+
+// UnusedMetricsAnalyzeCommand cross-references the metrics used in dashboards and rules with the
+// series ingested by a tenant, through the cardinality API.
+type UnusedMetricsAnalyzeCommand struct {
+	ClientConfig client.Config
+	readTimeout  time.Duration
+	limit        int
+	concurrency  int
+
+	grafanaMetricsFile string
+	rulerMetricsFile   string
+	outputFile         string
+}
+
+func (cmd *UnusedMetricsAnalyzeCommand) run(k *kingpin.ParseContext) error {
+	used, err := cmd.parseUsedMetrics()
+	if err != nil {
+		return err
+	}
+
+	cli, err := client.New(cmd.ClientConfig)
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.analyze(context.Background(), cli, used)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("%d series are being used in dashboards or rules", output.UsedSeries)
+	log.Infof("%d series of %d metrics are NOT being used in dashboards or rules (%.1f%% of the series)", output.UnusedCandidatesSeries, len(output.UnusedMetricCandidates), output.EstimatedSavingsRatio*100)
+	log.Infof("%d metrics used in dashboards or rules have no series", len(output.MissingMetrics))
+
+	return cmd.write(output)
+}
+
+// parseUsedMetrics returns the metrics used in dashboards and rules, along with where they're used.
+func (cmd *UnusedMetricsAnalyzeCommand) parseUsedMetrics() (map[string]*analyze.MetricUsage, error) {
+	var (
+		grafanaMetrics = &analyze.MetricsInGrafana{}
+		rulerMetrics   = &analyze.MetricsInRuler{}
+		used           = map[string]*analyze.MetricUsage{}
+	)
+
+	if err := parseMetricFileIfExist(cmd.grafanaMetricsFile, grafanaMetrics); err != nil {
+		return nil, err
+	}
+	if err := parseMetricFileIfExist(cmd.rulerMetricsFile, rulerMetrics); err != nil {
+		return nil, err
+	}
+
+	usage := func(metric string) *analyze.MetricUsage {
+		u, ok := used[metric]
+		if !ok {
+			u = &analyze.MetricUsage{Metric: metric}
+			used[metric] = u
+		}
+		return u
+	}
+
+	for _, metric := range grafanaMetrics.MetricsUsed {
+		usage(string(metric))
+	}
+	for _, dashboard := range grafanaMetrics.Dashboards {
+		for _, metric := range dashboard.Metrics {
+			u := usage(metric)
+			u.Dashboards = append(u.Dashboards, dashboard.Title)
+		}
+	}
+	for _, metric := range rulerMetrics.MetricsUsed {
+		usage(string(metric))
+	}
+	for _, group := range rulerMetrics.RuleGroups {
+		for _, metric := range group.Metrics {
+			u := usage(metric)
+			u.RuleGroups = append(u.RuleGroups, group.Namespace+"/"+group.GroupName)
+		}
+	}
+
+	if len(used) == 0 {
+		return nil, errors.New("no Grafana or Ruler metrics files")
+	}
+	return used, nil
+}
+
+func (cmd *UnusedMetricsAnalyzeCommand) analyze(ctx context.Context, cli *client.MimirClient, used map[string]*analyze.MetricUsage) (analyze.MetricsUsage, error) {
+	output := analyze.MetricsUsage{}
+
+	// Get the metrics with the highest number of series. The other metrics are not reported
+	// as unused candidates, given the cardinality API limits the number of returned values.
+	topMetrics, err := cmd.metricsCardinality(ctx, cli, "")
+	if err != nil {
+		return output, errors.Wrap(err, "error querying the metrics cardinality")
+	}
+	output.TotalSeries = topMetrics.SeriesCountTotal
+
+	seriesCounts := map[string]uint64{}
+	for _, label := range topMetrics.Labels {
+		for _, value := range label.Cardinality {
+			seriesCounts[value.LabelValue] = value.SeriesCount
+
+			if _, ok := used[value.LabelValue]; !ok {
+				output.UnusedMetricCandidates = append(output.UnusedMetricCandidates, analyze.MetricUsage{Metric: value.LabelValue, SeriesCount: value.SeriesCount})
+				output.UnusedCandidatesSeries += value.SeriesCount
+			}
+		}
+	}
+
+	// Get the series count of the used metrics which are not among the top ones.
+	var missing []string
+	for metric := range used {
+		if _, ok := seriesCounts[metric]; !ok {
+			missing = append(missing, metric)
+		}
+	}
+
+	var mtx sync.Mutex
+	err = concurrency.ForEachJob(ctx, len(missing), cmd.concurrency, func(ctx context.Context, idx int) error {
+		metric := missing[idx]
+
+		result, err := cmd.metricsCardinality(ctx, cli, fmt.Sprintf("{%s=%q}", labels.MetricName, metric))
+
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if err != nil {
+			errStr := fmt.Sprintf("skipped %s analysis because failed to query its cardinality: %s", metric, err.Error())
+			log.Warnln(errStr)
+			output.Errors = append(output.Errors, errStr)
+			return nil
+		}
+		seriesCounts[metric] = result.SeriesCountTotal
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	for metric, u := range used {
+		count, ok := seriesCounts[metric]
+		if !ok {
+			// The cardinality query failed.
+			continue
+		}
+		if count == 0 {
+			output.MissingMetrics = append(output.MissingMetrics, metric)
+			continue
+		}
+
+		u.SeriesCount = count
+		output.UsedMetrics = append(output.UsedMetrics, *u)
+		output.UsedSeries += count
+	}
+
+	if output.TotalSeries > 0 {
+		output.EstimatedSavingsRatio = float64(output.UnusedCandidatesSeries) / float64(output.TotalSeries)
+	}
+
+	sortMetricUsages(output.UsedMetrics)
+	sortMetricUsages(output.UnusedMetricCandidates)
+	sort.Strings(output.MissingMetrics)
+	sort.Strings(output.Errors)
+
+	return output, nil
+}
+
+func (cmd *UnusedMetricsAnalyzeCommand) metricsCardinality(ctx context.Context, cli *client.MimirClient, selector string) (*client.LabelValuesCardinality, error) {
+	ctx, cancel := context.WithTimeout(ctx, cmd.readTimeout)
+	defer cancel()
+
+	var result *client.LabelValuesCardinality
+	err := withBackoff(ctx, func() error {
+		var err error
+		result, err = cli.LabelValuesCardinality(ctx, labels.MetricName, selector, cmd.limit)
+		return err
+	})
+	return result, err
+}
+
+func (cmd *UnusedMetricsAnalyzeCommand) write(output analyze.MetricsUsage) error {
+	buf, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cmd.outputFile, buf, os.FileMode(0o666))
+}
+
+// sortMetricUsages sorts the metrics by decreasing number of series.
+func sortMetricUsages(usages []analyze.MetricUsage) {
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].SeriesCount != usages[j].SeriesCount {
+			return usages[i].SeriesCount > usages[j].SeriesCount
+		}
+		return usages[i].Metric < usages[j].Metric
+	})
+}
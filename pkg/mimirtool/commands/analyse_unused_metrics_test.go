@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirtool/analyze"
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+)
+
+func TestUnusedMetricsAnalyzeCommand(t *testing.T) {
+	// The cardinality API returns the top metrics, while the used metrics not among them are queried one by one.
+	responses := map[string]client.LabelValuesCardinality{
+		"": {
+			SeriesCountTotal: 1000,
+			Labels: []client.LabelNameValuesCardinality{{
+				LabelName: "__name__",
+				Cardinality: []client.LabelValueCardinality{
+					{LabelValue: "metric_unused_1", SeriesCount: 500},
+					{LabelValue: "metric_dashboard", SeriesCount: 200},
+					{LabelValue: "metric_unused_2", SeriesCount: 100},
+				},
+			}},
+		},
+		`{__name__="metric_rule"}`: {
+			SeriesCountTotal: 50,
+			Labels: []client.LabelNameValuesCardinality{{
+				LabelName:   "__name__",
+				SeriesCount: 50,
+				Cardinality: []client.LabelValueCardinality{{LabelValue: "metric_rule", SeriesCount: 50}},
+			}},
+		},
+		`{__name__="metric_missing"}`: {},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/prometheus/api/v1/cardinality/label_values", r.URL.Path)
+		assert.Equal(t, "user-1", r.Header.Get("X-Scope-OrgID"))
+		assert.Equal(t, "__name__", r.URL.Query().Get("label_names[]"))
+
+		res, ok := responses[r.URL.Query().Get("selector")]
+		if !ok {
+			http.Error(w, "unexpected selector", http.StatusBadRequest)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	writeJSON := func(name string, v any) string {
+		buf, err := json.Marshal(v)
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, buf, 0o666))
+		return path
+	}
+
+	cmd := &UnusedMetricsAnalyzeCommand{
+		ClientConfig: client.Config{Address: server.URL, ID: "user-1"},
+		readTimeout:  time.Second,
+		limit:        500,
+		concurrency:  2,
+		grafanaMetricsFile: writeJSON("grafana.json", analyze.MetricsInGrafana{
+			MetricsUsed: []model.LabelValue{"metric_dashboard", "metric_missing"},
+			Dashboards: []analyze.DashboardMetrics{
+				{Title: "Dashboard", Metrics: []string{"metric_dashboard", "metric_missing"}},
+			},
+		}),
+		rulerMetricsFile: writeJSON("ruler.json", analyze.MetricsInRuler{
+			MetricsUsed: []model.LabelValue{"metric_dashboard", "metric_rule"},
+			RuleGroups: []analyze.RuleGroupMetrics{
+				{Namespace: "ns", GroupName: "group", Metrics: []string{"metric_dashboard", "metric_rule"}},
+			},
+		}),
+		outputFile: filepath.Join(dir, "output.json"),
+	}
+
+	used, err := cmd.parseUsedMetrics()
+	require.NoError(t, err)
+
+	cli, err := client.New(cmd.ClientConfig)
+	require.NoError(t, err)
+
+	output, err := cmd.analyze(context.Background(), cli, used)
+	require.NoError(t, err)
+
+	assert.Equal(t, analyze.MetricsUsage{
+		TotalSeries:            1000,
+		UsedSeries:             250,
+		UnusedCandidatesSeries: 600,
+		EstimatedSavingsRatio:  0.6,
+		UsedMetrics: []analyze.MetricUsage{
+			{Metric: "metric_dashboard", SeriesCount: 200, Dashboards: []string{"Dashboard"}, RuleGroups: []string{"ns/group"}},
+			{Metric: "metric_rule", SeriesCount: 50, RuleGroups: []string{"ns/group"}},
+		},
+		UnusedMetricCandidates: []analyze.MetricUsage{
+			{Metric: "metric_unused_1", SeriesCount: 500},
+			{Metric: "metric_unused_2", SeriesCount: 100},
+		},
+		MissingMetrics: []string{"metric_missing"},
+	}, output)
+}
@@ -3,22 +3,32 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/grafana/mimir/pkg/mimirtool/backfill"
 	"github.com/grafana/mimir/pkg/mimirtool/client"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 )
 
 type BackfillCommand struct {
-	clientConfig client.Config
-	blocks       blockList
-	sleepTime    time.Duration
+	clientConfig    client.Config
+	blocks          blockList
+	sleepTime       time.Duration
+	openMetricsFile string
+	externalLabels  map[string]string
+	outputDir       string
 }
 
 type blockList []string
@@ -31,10 +41,46 @@ func (l *blockList) Set(value string) error {
 	if !st.IsDir() {
 		return fmt.Errorf("%q must be a directory", value)
 	}
-	*l = append(*l, value)
+
+	// The directory is either a block, or a Prometheus TSDB directory containing blocks.
+	if isBlockDir(value) {
+		*l = append(*l, value)
+		return nil
+	}
+
+	blocks, err := listBlockDirs(value)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("%q is neither a block nor a directory containing blocks", value)
+	}
+	*l = append(*l, blocks...)
 	return nil
 }
 
+func isBlockDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, block.MetaFilename))
+	return err == nil
+}
+
+// listBlockDirs returns the blocks found in the input directory, like the data directory of Prometheus.
+func listBlockDirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []string
+	for _, e := range entries {
+		blockDir := filepath.Join(dir, e.Name())
+		if e.IsDir() && isBlockDir(blockDir) {
+			blocks = append(blocks, blockDir)
+		}
+	}
+	return blocks, nil
+}
+
 func (l blockList) String() string {
 	return strings.Join(l, ",")
 }
@@ -46,7 +92,7 @@ func (l blockList) IsCumulative() bool {
 func (c *BackfillCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
 	cmd := app.Command("backfill", "Upload Prometheus TSDB blocks to Grafana Mimir compactor.")
 	cmd.Action(c.backfill)
-	cmd.Arg("block-dir", "block to upload").Required().SetValue(&c.blocks)
+	cmd.Arg("block-dir", "block to upload, or Prometheus TSDB directory whose blocks are uploaded").SetValue(&c.blocks)
 
 	cmd.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").
 		Envar(envVars.Address).
@@ -92,9 +138,43 @@ func (c *BackfillCommand) Register(app *kingpin.Application, envVars EnvVarNames
 	cmd.Flag("sleep-time", "How long to sleep between checking state of block upload after uploading all files for the block.").
 		Default("20s").
 		DurationVar(&c.sleepTime)
+
+	cmd.Flag("from-openmetrics", "OpenMetrics text file to convert into blocks before uploading them. Every sample in the file must have a timestamp.").
+		Default("").
+		StringVar(&c.openMetricsFile)
+
+	cmd.Flag("external-label", "Label to add to every series converted from the OpenMetrics file, in the form name=value. Can be repeated.").
+		StringMapVar(&c.externalLabels)
+
+	cmd.Flag("output-dir", "Directory where the blocks converted from the OpenMetrics file are written. If empty, a temporary directory is used and removed afterwards.").
+		Default("").
+		StringVar(&c.outputDir)
 }
 
 func (c *BackfillCommand) backfill(k *kingpin.ParseContext) error {
+	if c.openMetricsFile != "" {
+		outputDir := c.outputDir
+		if outputDir == "" {
+			var err error
+			if outputDir, err = os.MkdirTemp("", "mimirtool-backfill"); err != nil {
+				return err
+			}
+			defer os.RemoveAll(outputDir)
+		}
+
+		blocks, err := c.convertOpenMetrics(outputDir)
+		if err != nil {
+			return err
+		}
+		c.blocks = append(c.blocks, blocks...)
+	} else if len(c.externalLabels) > 0 {
+		return errors.New("--external-label can only be used together with --from-openmetrics")
+	}
+
+	if len(c.blocks) == 0 {
+		return errors.New("no blocks to upload: either a block directory or --from-openmetrics must be provided")
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"blocks": c.blocks.String(),
 		"user":   c.clientConfig.ID,
@@ -107,3 +187,57 @@ func (c *BackfillCommand) backfill(k *kingpin.ParseContext) error {
 
 	return cli.Backfill(context.Background(), c.blocks, c.sleepTime)
 }
+
+// convertOpenMetrics converts the OpenMetrics file into blocks written to outputDir, and returns their directories.
+func (c *BackfillCommand) convertOpenMetrics(outputDir string) ([]string, error) {
+	data, err := os.ReadFile(c.openMetricsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "read OpenMetrics file")
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Only upload the blocks created by the conversion, even if the output directory already contains blocks.
+	existing, err := listBlockDirs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeR, pipeW := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(pipeR)
+		for scanner.Scan() {
+			logrus.Info(scanner.Text())
+		}
+	}()
+	defer pipeW.Close()
+
+	logrus.WithFields(logrus.Fields{"file": c.openMetricsFile, "output_dir": outputDir}).Println("Converting OpenMetrics file into blocks")
+	if err := backfill.CreateBlocksFromOpenMetrics(data, labels.FromMap(c.externalLabels), outputDir, true, pipeW); err != nil {
+		return nil, errors.Wrap(err, "convert OpenMetrics file into blocks")
+	}
+
+	all, err := listBlockDirs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	return newBlockDirs(existing, all), nil
+}
+
+// newBlockDirs returns the block directories in all which are not in existing.
+func newBlockDirs(existing, all []string) []string {
+	seen := make(map[string]struct{}, len(existing))
+	for _, b := range existing {
+		seen[b] = struct{}{}
+	}
+
+	var blocks []string
+	for _, b := range all {
+		if _, ok := seen[b]; !ok {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
@@ -27,6 +27,9 @@ type ConfigCommand struct {
 	outFlagsFile   string
 	outNoticesFile string
 
+	fromVersion string
+	toVersion   string
+
 	updateDefaults  bool
 	includeDefaults bool
 
@@ -51,6 +54,21 @@ func (c *ConfigCommand) Register(app *kingpin.Application, _ EnvVarNames) {
 	convertCmd.Flag("include-defaults", "If you set this flag, all default values are included in the output YAML, regardless of whether you explicitly set the values in the input files.").BoolVar(&c.includeDefaults)
 	convertCmd.Flag("verbose", "If you set this flag, the CLI flags and YAML paths from the old configuration that do not exist in the new configuration are printed to stderr. This flag also prints default values that have changed between the old and the new configuration.").Short('v').BoolVar(&c.verbose)
 	convertCmd.Flag("gem", "If you set this flag, the tool will convert from Grafana Metrics Enterprise (GEM) v1.7.x to v2.0.0.").BoolVar(&c.gem)
+
+	migrateCmd := configCmd.
+		Command("migrate", "Migrate configuration parameters (YAML and CLI flags) from a Grafana Mimir version to another one, and report the removed parameters, the changed defaults and the parameters whose category changed (for example deprecated parameters) relevant to the configuration.").
+		Action(c.migrateConfig)
+
+	versionHelp := fmt.Sprintf("One of %s, or the path to the configuration descriptor JSON file of the version (cmd/mimir/config-descriptor.json in the Grafana Mimir repository at the version's tag).", strings.Join(config.SupportedMimirVersions(), ", "))
+	migrateCmd.Flag("from", "The Grafana Mimir version to migrate the configuration from. "+versionHelp).Required().StringVar(&c.fromVersion)
+	migrateCmd.Flag("to", "The Grafana Mimir version to migrate the configuration to. "+versionHelp).Required().StringVar(&c.toVersion)
+	migrateCmd.Flag("yaml-file", "The YAML configuration file to migrate.").StringVar(&c.yamlFile)
+	migrateCmd.Flag("flags-file", "Newline-delimited list of CLI flags to migrate.").StringVar(&c.flagsFile)
+	migrateCmd.Flag("yaml-out", "The file to output the migrated YAML configuration to. If not set, output to stdout.").StringVar(&c.outYAMLFile)
+	migrateCmd.Flag("flags-out", "The file to output the list of migrated CLI flags to. If not set, output to stdout.").StringVar(&c.outFlagsFile)
+	migrateCmd.Flag("report-out", "The file to output the migration report to. If not set, output to stderr.").StringVar(&c.outNoticesFile)
+	migrateCmd.Flag("update-defaults", "If you set this flag and you set a configuration parameter to a default value that has changed in the target version, the parameter updates to the new default value.").BoolVar(&c.updateDefaults)
+	migrateCmd.Flag("include-defaults", "If you set this flag, all default values are included in the output YAML, regardless of whether you explicitly set the values in the input files.").BoolVar(&c.includeDefaults)
 }
 
 func (c *ConfigCommand) convertConfig(_ *kingpin.ParseContext) error {
@@ -69,7 +87,32 @@ func (c *ConfigCommand) convertConfig(_ *kingpin.ParseContext) error {
 		return errors.Wrap(err, "could not convert configuration")
 	}
 
-	return c.output(convertedYAML, flagsFlags, notices)
+	return c.output(convertedYAML, flagsFlags, config.MigrationNotices{ConversionNotices: notices})
+}
+
+func (c *ConfigCommand) migrateConfig(_ *kingpin.ParseContext) error {
+	yamlContents, flagsFlags, err := c.prepareInputs()
+	if err != nil {
+		return err
+	}
+
+	sourceFactory, err := config.MimirConfigForVersion(c.fromVersion)
+	if err != nil {
+		return err
+	}
+	targetFactory, err := config.MimirConfigForVersion(c.toVersion)
+	if err != nil {
+		return err
+	}
+
+	migratedYAML, flagsFlags, notices, err := config.Migrate(yamlContents, flagsFlags, sourceFactory, targetFactory, c.updateDefaults, c.includeDefaults)
+	if err != nil {
+		return errors.Wrap(err, "could not migrate configuration")
+	}
+
+	// The migration report is always printed.
+	c.verbose = true
+	return c.output(migratedYAML, flagsFlags, notices)
 }
 
 func (c *ConfigCommand) prepareInputs() ([]byte, []string, error) {
@@ -102,7 +145,7 @@ func (c *ConfigCommand) prepareInputs() ([]byte, []string, error) {
 	return yamlContents, flags, nil
 }
 
-func (c *ConfigCommand) output(yamlContents []byte, flags []string, notices config.ConversionNotices) error {
+func (c *ConfigCommand) output(yamlContents []byte, flags []string, notices config.MigrationNotices) error {
 	openFile := func(path string, defaultWriter io.Writer) (io.Writer, func(), error) {
 		if path == "" {
 			return defaultWriter, func() {}, nil
@@ -147,7 +190,7 @@ func (c *ConfigCommand) output(yamlContents []byte, flags []string, notices conf
 	return multierror.New(err, err2, err3).Err()
 }
 
-func (c *ConfigCommand) writeNotices(notices config.ConversionNotices, w io.Writer) error {
+func (c *ConfigCommand) writeNotices(notices config.MigrationNotices, w io.Writer) error {
 	if !c.verbose {
 		return nil
 	}
@@ -169,6 +212,21 @@ func (c *ConfigCommand) writeNotices(notices config.ConversionNotices, w io.Writ
 	for _, d := range notices.PrunedDefaults {
 		_, _ = noticesOut.WriteString(fmt.Sprintf("removed default value %s: %s\n", d.Path, placeholderIfEmpty(d.Value)))
 	}
+	for _, d := range notices.ChangedCategories {
+		name := d.Path
+		if d.Flag != "" {
+			name = fmt.Sprintf("%s (-%s)", d.Path, d.Flag)
+		}
+
+		switch {
+		case d.NewCategory == "deprecated":
+			_, _ = noticesOut.WriteString(fmt.Sprintf("parameter is deprecated and will be removed in a future release: %s\n", name))
+		case d.NewCategory == "experimental":
+			_, _ = noticesOut.WriteString(fmt.Sprintf("parameter is experimental: %s\n", name))
+		case d.OldCategory == "experimental":
+			_, _ = noticesOut.WriteString(fmt.Sprintf("parameter is no longer experimental: %s\n", name))
+		}
+	}
 
 	_, err := noticesOut.WriteTo(w)
 	return err
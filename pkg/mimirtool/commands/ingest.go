@@ -0,0 +1,316 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/thanos-io/objstore"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/grafana/mimir/pkg/distributor/capture"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// IngestCommand is the kingpin command to replay the push requests captured by the distributors.
+type IngestCommand struct {
+	address        string
+	writePath      string
+	tenantID       string
+	apiKey         string
+	writeTimeout   time.Duration
+	captureTenants []string
+
+	speed           float64
+	shiftTimestamps bool
+
+	bucketConfig     string
+	bucketConfigHelp bool
+	cfg              bucket.Config
+
+	logger log.Logger
+
+	// Write clients, by tenant.
+	writeClients map[string]remote.WriteClient
+}
+
+// capturedRequestObject is an object storing a push request captured by the distributors.
+type capturedRequestObject struct {
+	name       string
+	userID     string
+	capturedAt time.Time
+}
+
+// Register is used to register the command to a parent command.
+func (c *IngestCommand) Register(app *kingpin.Application, envVars EnvVarNames) {
+	ingestCmd := app.Command("ingest", "Work with the push requests ingested by Grafana Mimir.")
+	replayCmd := ingestCmd.Command("replay", "Replay the push requests captured by the distributors to the blocks storage bucket, with -distributor.request-capture.sample-ratio, against a Grafana Mimir cluster.").Action(c.replay)
+
+	replayCmd.Flag("address", "Address of the Grafana Mimir cluster to which the requests are replayed; alternatively, set "+envVars.Address+".").
+		Envar(envVars.Address).
+		Required().
+		StringVar(&c.address)
+	replayCmd.Flag("write-path", "Path of the remote write endpoint.").
+		Default("/api/v1/push").
+		StringVar(&c.writePath)
+	replayCmd.Flag("id", "Grafana Mimir tenant ID to which all the requests are replayed. If empty, each request is replayed to the tenant it has been captured for; alternatively, set "+envVars.TenantID+".").
+		Envar(envVars.TenantID).
+		Default("").
+		StringVar(&c.tenantID)
+	replayCmd.Flag("key", "API key to use when contacting Grafana Mimir; alternatively, set "+envVars.APIKey+".").
+		Envar(envVars.APIKey).
+		Default("").
+		StringVar(&c.apiKey)
+	replayCmd.Flag("write-timeout", "Timeout for write requests.").
+		Default("30s").
+		DurationVar(&c.writeTimeout)
+	replayCmd.Flag("capture-tenant", "Tenant whose captured requests are replayed. Can be specified multiple times. If not set, the requests captured for all the tenants are replayed.").
+		StringsVar(&c.captureTenants)
+	replayCmd.Flag("speed", "Speed at which the requests are replayed, relative to the speed at which they have been captured. For example, 2 replays the requests twice as fast. 0 to replay the requests as fast as possible.").
+		Default("1").
+		Float64Var(&c.speed)
+	replayCmd.Flag("shift-timestamps", "Shift the timestamps of the samples, histograms and exemplars of each request by the time elapsed between the capture and the replay of the request, so that they're accepted by the cluster.").
+		Default("true").
+		BoolVar(&c.shiftTimestamps)
+	replayCmd.Flag("bucket-config", "The CLI args to configure the blocks storage bucket where the requests have been captured.").
+		StringVar(&c.bucketConfig)
+	replayCmd.Flag("bucket-config-help", "Help text explaining how to use the -bucket-config parameter.").
+		BoolVar(&c.bucketConfigHelp)
+}
+
+func (c *IngestCommand) replay(_ *kingpin.ParseContext) error {
+	c.logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	if c.bucketConfigHelp {
+		c.printBucketConfigHelp()
+		return nil
+	}
+
+	if c.speed < 0 {
+		return errors.New("the speed must be greater than or equal to 0")
+	}
+
+	if err := c.parseBucketConfig(); err != nil {
+		return errors.Wrap(err, "error when parsing bucket config")
+	}
+
+	ctx := context.Background()
+	bucketClient, err := bucket.NewClient(ctx, c.cfg, "mimirtool", c.logger, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the bucket client")
+	}
+
+	return c.replayRequests(ctx, bucket.NewPrefixedBucketClient(bucketClient, bucket.MimirInternalsPrefix))
+}
+
+func (c *IngestCommand) printBucketConfigHelp() {
+	fs := flag.NewFlagSet("bucket-config", flag.ContinueOnError)
+	c.cfg.RegisterFlags(fs, c.logger)
+
+	fmt.Fprintf(fs.Output(), `
+The following help text describes the arguments
+which may be specified in the string that gets
+passed to "-bucket-config".
+
+Example:
+mimirtool ingest replay --address=http://localhost:8080 --bucket-config='-backend=s3 -s3.endpoint=localhost:9000 -s3.bucket-name=blocks'
+
+`)
+	fs.Usage()
+}
+
+func (c *IngestCommand) parseBucketConfig() error {
+	fs := flag.NewFlagSet("bucket-config", flag.ContinueOnError)
+	c.cfg.RegisterFlags(fs, c.logger)
+	if err := fs.Parse(strings.Fields(c.bucketConfig)); err != nil {
+		return err
+	}
+
+	return c.cfg.Validate()
+}
+
+// replayRequests replays the requests captured in the input bucket, whose root is bucket.MimirInternalsPrefix,
+// in the order they have been captured.
+func (c *IngestCommand) replayRequests(ctx context.Context, bkt objstore.Bucket) error {
+	objects, err := c.listCapturedRequests(ctx, bkt)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the captured requests")
+	}
+	if len(objects) == 0 {
+		level.Info(c.logger).Log("msg", "no captured requests found")
+		return nil
+	}
+
+	level.Info(c.logger).Log("msg", "replaying the captured requests", "requests", len(objects), "from", objects[0].capturedAt, "to", objects[len(objects)-1].capturedAt)
+
+	replayStart := time.Now()
+	replayed, failed := 0, 0
+	for _, obj := range objects {
+		// Wait until the time the request should be replayed at, given the speed.
+		if c.speed > 0 {
+			delay := time.Duration(float64(obj.capturedAt.Sub(objects[0].capturedAt)) / c.speed)
+			if wait := time.Until(replayStart.Add(delay)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		if err := c.replayRequest(ctx, bkt, obj); err != nil {
+			failed++
+			level.Warn(c.logger).Log("msg", "failed to replay the captured request", "object", obj.name, "err", err)
+			continue
+		}
+		replayed++
+	}
+
+	level.Info(c.logger).Log("msg", "replayed the captured requests", "replayed", replayed, "failed", failed, "duration", time.Since(replayStart))
+	if failed > 0 {
+		return fmt.Errorf("failed to replay %d of %d captured requests", failed, len(objects))
+	}
+	return nil
+}
+
+// listCapturedRequests returns the captured requests of the selected tenants, sorted by capture time.
+func (c *IngestCommand) listCapturedRequests(ctx context.Context, bkt objstore.Bucket) ([]capturedRequestObject, error) {
+	tenants := c.captureTenants
+	if len(tenants) == 0 {
+		err := bkt.Iter(ctx, capture.Prefix+"/", func(name string) error {
+			tenants = append(tenants, path.Base(name))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []capturedRequestObject
+	for _, tenantID := range tenants {
+		err := bkt.Iter(ctx, path.Join(capture.Prefix, tenantID)+"/", func(name string) error {
+			userID, capturedAt, err := capture.ParseObjectName(name)
+			if err != nil {
+				level.Warn(c.logger).Log("msg", "skipping object which is not a captured request", "object", name)
+				return nil
+			}
+
+			objects = append(objects, capturedRequestObject{name: name, userID: userID, capturedAt: capturedAt})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].capturedAt.Before(objects[j].capturedAt)
+	})
+	return objects, nil
+}
+
+func (c *IngestCommand) replayRequest(ctx context.Context, bkt objstore.Bucket, obj capturedRequestObject) error {
+	reader, err := bkt.Get(ctx, obj.name)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return err
+	}
+
+	if c.shiftTimestamps {
+		if data, err = shiftCapturedRequestTimestamps(data, time.Since(obj.capturedAt)); err != nil {
+			return errors.Wrap(err, "failed to decode the captured request")
+		}
+	}
+
+	userID := obj.userID
+	if c.tenantID != "" {
+		userID = c.tenantID
+	}
+
+	client, err := c.writeClient(userID)
+	if err != nil {
+		return err
+	}
+	return client.Store(ctx, data)
+}
+
+// shiftCapturedRequestTimestamps shifts the timestamps of the samples, histograms and exemplars of the
+// input captured request by the input offset.
+func shiftCapturedRequestTimestamps(data []byte, offset time.Duration) ([]byte, error) {
+	req, err := capture.DecodeRequest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetMs := offset.Milliseconds()
+	for _, series := range req.Timeseries {
+		for i := range series.Samples {
+			series.Samples[i].TimestampMs += offsetMs
+		}
+		for i := range series.Histograms {
+			series.Histograms[i].Timestamp += offsetMs
+		}
+		for i := range series.Exemplars {
+			series.Exemplars[i].TimestampMs += offsetMs
+		}
+	}
+
+	encoded, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, encoded), nil
+}
+
+// writeClient returns the remote write client for the input tenant.
+func (c *IngestCommand) writeClient(userID string) (remote.WriteClient, error) {
+	if client, ok := c.writeClients[userID]; ok {
+		return client, nil
+	}
+
+	addressURL, err := url.Parse(c.address)
+	if err != nil {
+		return nil, err
+	}
+	addressURL.Path = path.Join(addressURL.Path, c.writePath)
+
+	client, err := remote.NewWriteClient("mimirtool-ingest-replay", &remote.ClientConfig{
+		URL:     &config_util.URL{URL: addressURL},
+		Timeout: model.Duration(c.writeTimeout),
+		HTTPClientConfig: config_util.HTTPClientConfig{
+			BasicAuth: &config_util.BasicAuth{
+				Username: userID,
+				Password: config_util.Secret(c.apiKey),
+			},
+		},
+		Headers: map[string]string{"X-Scope-OrgID": userID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.writeClients == nil {
+		c.writeClients = map[string]remote.WriteClient{}
+	}
+	c.writeClients[userID] = client
+	return client, nil
+}
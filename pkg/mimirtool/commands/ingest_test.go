@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/distributor/capture"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+type replayedRequest struct {
+	tenantID string
+	value    float64
+	ts       int64
+}
+
+func TestIngestCommand_ReplayRequests(t *testing.T) {
+	captureStart := time.UnixMilli(1680000000000)
+
+	// The captured requests of the two tenants are interleaved.
+	bkt := objstore.NewInMemBucket()
+	uploadCapturedRequest(t, bkt, "user-2", captureStart.Add(2*time.Second), 3)
+	uploadCapturedRequest(t, bkt, "user-1", captureStart, 1)
+	uploadCapturedRequest(t, bkt, "user-2", captureStart.Add(time.Second), 2)
+	uploadCapturedRequest(t, bkt, "user-1", captureStart.Add(3*time.Second), 4)
+
+	tests := map[string]struct {
+		tenantID       string
+		captureTenants []string
+		expected       []replayedRequest
+	}{
+		"should replay the requests of all the tenants in the order they have been captured": {
+			expected: []replayedRequest{
+				{tenantID: "user-1", value: 1, ts: captureStart.UnixMilli()},
+				{tenantID: "user-2", value: 2, ts: captureStart.Add(time.Second).UnixMilli()},
+				{tenantID: "user-2", value: 3, ts: captureStart.Add(2 * time.Second).UnixMilli()},
+				{tenantID: "user-1", value: 4, ts: captureStart.Add(3 * time.Second).UnixMilli()},
+			},
+		},
+		"should replay the requests of the selected tenants": {
+			captureTenants: []string{"user-2"},
+			expected: []replayedRequest{
+				{tenantID: "user-2", value: 2, ts: captureStart.Add(time.Second).UnixMilli()},
+				{tenantID: "user-2", value: 3, ts: captureStart.Add(2 * time.Second).UnixMilli()},
+			},
+		},
+		"should replay the requests to the configured tenant": {
+			tenantID:       "replay",
+			captureTenants: []string{"user-1"},
+			expected: []replayedRequest{
+				{tenantID: "replay", value: 1, ts: captureStart.UnixMilli()},
+				{tenantID: "replay", value: 4, ts: captureStart.Add(3 * time.Second).UnixMilli()},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var (
+				mtx      sync.Mutex
+				replayed []replayedRequest
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				req := decodeReplayedRequest(t, r.Body)
+
+				mtx.Lock()
+				defer mtx.Unlock()
+				for _, series := range req.Timeseries {
+					for _, s := range series.Samples {
+						replayed = append(replayed, replayedRequest{tenantID: r.Header.Get("X-Scope-OrgID"), value: s.Value, ts: s.TimestampMs})
+					}
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			c := &IngestCommand{
+				address:        server.URL,
+				writePath:      "/api/v1/push",
+				tenantID:       testData.tenantID,
+				captureTenants: testData.captureTenants,
+				writeTimeout:   time.Second,
+				speed:          0,
+				logger:         log.NewNopLogger(),
+			}
+			require.NoError(t, c.replayRequests(context.Background(), bkt))
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			assert.Equal(t, testData.expected, replayed)
+		})
+	}
+}
+
+func TestShiftCapturedRequestTimestamps(t *testing.T) {
+	req := &mimirpb.WriteRequest{
+		Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:     []mimirpb.LabelAdapter{{Name: "__name__", Value: "series_1"}},
+			Samples:    []mimirpb.Sample{{TimestampMs: 1000, Value: 1}, {TimestampMs: 2000, Value: 2}},
+			Exemplars:  []mimirpb.Exemplar{{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "1"}}, TimestampMs: 1500, Value: 1}},
+			Histograms: []mimirpb.Histogram{{Timestamp: 3000}},
+		}}},
+	}
+	data, err := req.Marshal()
+	require.NoError(t, err)
+
+	shifted, err := shiftCapturedRequestTimestamps(snappy.Encode(nil, data), time.Minute)
+	require.NoError(t, err)
+
+	actual, err := capture.DecodeRequest(shifted)
+	require.NoError(t, err)
+	require.Len(t, actual.Timeseries, 1)
+	assert.Equal(t, []mimirpb.Sample{{TimestampMs: 61000, Value: 1}, {TimestampMs: 62000, Value: 2}}, actual.Timeseries[0].Samples)
+	assert.Equal(t, int64(61500), actual.Timeseries[0].Exemplars[0].TimestampMs)
+	assert.Equal(t, int64(63000), actual.Timeseries[0].Histograms[0].Timestamp)
+}
+
+func uploadCapturedRequest(t *testing.T, bkt objstore.Bucket, userID string, capturedAt time.Time, value float64) {
+	req := &mimirpb.WriteRequest{
+		Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  []mimirpb.LabelAdapter{{Name: "__name__", Value: "series_1"}},
+			Samples: []mimirpb.Sample{{TimestampMs: capturedAt.UnixMilli(), Value: value}},
+		}}},
+	}
+	data, err := req.Marshal()
+	require.NoError(t, err)
+
+	name := capture.ObjectName(userID, capturedAt, 0)
+	require.NoError(t, bkt.Upload(context.Background(), name, bytes.NewReader(snappy.Encode(nil, data))))
+}
+
+func decodeReplayedRequest(t *testing.T, body io.Reader) *mimirpb.WriteRequest {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	req, err := capture.DecodeRequest(data)
+	require.NoError(t, err)
+	return req
+}
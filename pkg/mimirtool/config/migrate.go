@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
+)
+
+// embeddedMimirConfigs are the configuration descriptors of the Mimir versions embedded in mimirtool.
+var embeddedMimirConfigs = map[string]InspectedEntryFactory{
+	"2.6.0": DefaultMimirConfig,
+}
+
+// SupportedMimirVersions returns the Mimir versions whose configuration descriptor is embedded in mimirtool.
+func SupportedMimirVersions() []string {
+	versions := maps.Keys(embeddedMimirConfigs)
+	sort.Strings(versions)
+	return versions
+}
+
+// MimirConfigForVersion returns the InspectedEntryFactory for the given Mimir version. The version is either one
+// of the SupportedMimirVersions or the path to a configuration descriptor JSON file, as generated by
+// tools/config-inspector (e.g. cmd/mimir/config-descriptor.json of the Mimir repository at the version's tag).
+func MimirConfigForVersion(version string) (InspectedEntryFactory, error) {
+	if factory, ok := embeddedMimirConfigs[version]; ok {
+		return factory, nil
+	}
+
+	contents, err := os.ReadFile(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unsupported Mimir version %q (supported versions: %v) and could not read it as a configuration descriptor file", version, SupportedMimirVersions())
+	}
+
+	cfg := &InspectedEntry{}
+	if err := json.Unmarshal(contents, cfg); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal configuration descriptor file %s", version)
+	}
+
+	// The descriptor generated by tools/config-inspector doesn't include the flags not available in YAML,
+	// so we use the ones from the latest embedded version.
+	if _, err := cfg.find(notInYaml); err != nil {
+		cfgFlagsOnly := &InspectedEntry{}
+		if err := json.Unmarshal(mimirConfigFlagsOnly, cfgFlagsOnly); err != nil {
+			return nil, err
+		}
+
+		cfg.BlockEntries = append(cfg.BlockEntries, &InspectedEntry{
+			Kind:         KindBlock,
+			Name:         notInYaml,
+			Required:     false,
+			Desc:         "Flags not available in YAML file.",
+			BlockEntries: cfgFlagsOnly.BlockEntries,
+		})
+	}
+
+	return cfg.Clone, nil
+}
+
+type MigrationNotices struct {
+	ConversionNotices
+	ChangedCategories []ChangedCategory
+}
+
+// ChangedCategory is a configuration parameter, set in the migrated configuration, whose category (e.g. experimental
+// or deprecated) is different between the source and the target version. An empty category means the parameter is basic.
+type ChangedCategory struct {
+	Path                     string
+	Flag                     string
+	OldCategory, NewCategory string
+}
+
+// Migrate migrates the passed YAML contents and CLI flags from a Mimir version to another one. The input configuration
+// is validated against the source version, and any parameter in the input which is not known to the source version
+// causes an error. Along with the migrated configuration, Migrate reports the parameters set in the input that have been
+// removed from the target version, the defaults which have changed between the two versions and the parameters set in the
+// input whose category has changed (for example, parameters which have been deprecated in the target version).
+func Migrate(
+	contents []byte,
+	flags []string,
+	sourceFactory, targetFactory InspectedEntryFactory,
+	useNewDefaults, showDefaults bool,
+) (migratedContents []byte, migratedFlags []string, _ MigrationNotices, _ error) {
+	source, target := sourceFactory(), targetFactory()
+
+	if unknown := unknownYAMLParameters(contents, source); len(unknown) > 0 {
+		return nil, nil, MigrationNotices{}, fmt.Errorf("the configuration contains parameters unknown to the source version: %v", unknown)
+	}
+
+	err := yaml.Unmarshal(contents, &source)
+	if err != nil {
+		return nil, nil, MigrationNotices{}, errors.Wrap(err, "could not unmarshal configuration file")
+	}
+
+	err = addFlags(source, flags)
+	if err != nil {
+		return nil, nil, MigrationNotices{}, errors.Wrap(err, "could not parse provided flags")
+	}
+
+	notices := MigrationNotices{}
+	providedFlags := parseFlagNames(flags)
+	err = source.Walk(func(path string, value Value) error {
+		if value.IsUnset() {
+			return nil
+		}
+
+		sourceEntry, err := source.find(path)
+		if err != nil {
+			return err
+		}
+
+		targetEntry, err := target.find(path)
+		if errors.Is(err, ErrParameterNotFound) {
+			if sourceEntry.FieldFlag != "" && providedFlags[sourceEntry.FieldFlag] {
+				notices.RemovedCLIFlags = append(notices.RemovedCLIFlags, sourceEntry.FieldFlag)
+			} else {
+				notices.RemovedParameters = append(notices.RemovedParameters, path)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if sourceEntry.FieldCategory != targetEntry.FieldCategory {
+			notices.ChangedCategories = append(notices.ChangedCategories, ChangedCategory{
+				Path:        path,
+				Flag:        targetEntry.FieldFlag,
+				OldCategory: sourceEntry.FieldCategory,
+				NewCategory: targetEntry.FieldCategory,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, MigrationNotices{}, err
+	}
+
+	migratedContents, migratedFlags, conversionNotices, err := Convert(contents, flags, BestEffortDirectMapper{}, sourceFactory, targetFactory, useNewDefaults, showDefaults)
+	if err != nil {
+		return nil, nil, MigrationNotices{}, err
+	}
+
+	notices.ChangedDefaults = conversionNotices.ChangedDefaults
+	notices.SkippedChangedDefaults = conversionNotices.SkippedChangedDefaults
+	notices.PrunedDefaults = conversionNotices.PrunedDefaults
+
+	return migratedContents, migratedFlags, notices, nil
+}
+
+// unknownYAMLParameters returns the paths of the parameters in the YAML contents which don't exist in the configuration.
+func unknownYAMLParameters(contents []byte, cfg *InspectedEntry) []string {
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil || len(root.Content) == 0 {
+		// Unmarshalling errors are reported when unmarshalling into the configuration.
+		return nil
+	}
+
+	var unknown []string
+	var walk func(node *yaml.Node, entry *InspectedEntry, path string)
+	walk = func(node *yaml.Node, entry *InspectedEntry, path string) {
+		if entry.Kind != KindBlock || node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			name := node.Content[idx].Value
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+
+			child, err := entry.find(name)
+			if err != nil {
+				unknown = append(unknown, childPath)
+				continue
+			}
+			walk(node.Content[idx+1], child, childPath)
+		}
+	}
+	walk(root.Content[0], cfg, "")
+
+	return unknown
+}
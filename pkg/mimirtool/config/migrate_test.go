@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// currentMimirConfigDescriptor is the configuration descriptor of the Mimir version in this repository.
+const currentMimirConfigDescriptor = "../../../cmd/mimir/config-descriptor.json"
+
+func TestMimirConfigForVersion(t *testing.T) {
+	t.Run("embedded version", func(t *testing.T) {
+		factory, err := MimirConfigForVersion("2.6.0")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultMimirConfig(), factory())
+	})
+
+	t.Run("configuration descriptor file", func(t *testing.T) {
+		factory, err := MimirConfigForVersion(currentMimirConfigDescriptor)
+		require.NoError(t, err)
+
+		cfg := factory()
+		flag, err := cfg.GetFlag("blocks_storage.tsdb.retention_period")
+		require.NoError(t, err)
+		assert.Equal(t, "blocks-storage.tsdb.retention-period", flag)
+
+		// The flags not available in YAML are added from the embedded descriptor.
+		flag, err = cfg.GetFlag(notInYaml + ".config-file")
+		require.NoError(t, err)
+		assert.Equal(t, "config.file", flag)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		_, err := MimirConfigForVersion("1.0.0")
+		require.Error(t, err)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	sourceFactory, err := MimirConfigForVersion("2.6.0")
+	require.NoError(t, err)
+	targetFactory, err := MimirConfigForVersion(currentMimirConfigDescriptor)
+	require.NoError(t, err)
+
+	t.Run("should report removed parameters, changed defaults and changed categories", func(t *testing.T) {
+		inYAML := []byte(`
+blocks_storage:
+  bucket_store:
+    chunks_cache:
+      subrange_size: 1000
+    consistency_delay: 1h
+  tsdb:
+    dir: /data/tsdb
+`)
+		inFlags := []string{"-blocks-storage.bucket-store.index-header.map-populate-enabled=true", "-ruler.evaluation-delay-duration=0s"}
+
+		outYAML, outFlags, notices, err := Migrate(inYAML, inFlags, sourceFactory, targetFactory, false, false)
+		require.NoError(t, err)
+
+		assert.YAMLEq(t, `
+blocks_storage:
+  bucket_store:
+    consistency_delay: 1h0m0s
+  tsdb:
+    dir: /data/tsdb
+`, string(outYAML))
+		assert.Equal(t, []string{"-ruler.evaluation-delay-duration=0s"}, outFlags)
+
+		assert.Equal(t, []string{"blocks_storage.bucket_store.chunks_cache.subrange_size"}, notices.RemovedParameters)
+		assert.Equal(t, []string{"blocks-storage.bucket-store.index-header.map-populate-enabled"}, notices.RemovedCLIFlags)
+		assert.Contains(t, notices.ChangedDefaults, ChangedDefault{Path: "blocks_storage.tsdb.retention_period", OldDefault: "24h0m0s", NewDefault: "13h0m0s"})
+		assert.Contains(t, notices.SkippedChangedDefaults, ChangedDefault{Path: "limits.ruler_evaluation_delay_duration", OldDefault: "0s", NewDefault: "1m0s"})
+		assert.Equal(t, []ChangedCategory{{
+			Path:        "blocks_storage.bucket_store.consistency_delay",
+			Flag:        "blocks-storage.bucket-store.consistency-delay",
+			OldCategory: "advanced",
+			NewCategory: "deprecated",
+		}}, notices.ChangedCategories)
+	})
+
+	t.Run("should fail on parameters unknown to the source version", func(t *testing.T) {
+		inYAML := []byte(`
+blocks_storage:
+  bucket_store:
+    unknown_parameter: true
+`)
+
+		_, _, _, err := Migrate(inYAML, nil, sourceFactory, targetFactory, false, false)
+		require.ErrorContains(t, err, "blocks_storage.bucket_store.unknown_parameter")
+	})
+
+	t.Run("should fail on flags unknown to the source version", func(t *testing.T) {
+		_, _, _, err := Migrate(nil, []string{"-unknown.flag=true"}, sourceFactory, targetFactory, false, false)
+		require.Error(t, err)
+	})
+}
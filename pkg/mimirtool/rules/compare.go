@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/mitchellh/colorstring"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	yaml "gopkg.in/yaml.v3"
 
@@ -24,6 +25,8 @@ var (
 	errDiffRuleLen       = errors.New("rule groups have a different number of rules")
 	errDiffRWConfigs     = errors.New("rule groups have different remote write configs")
 	errDiffSourceTenants = errors.New("rule groups have different source tenants")
+	errDiffEvalDelay     = errors.New("rule groups have different evaluation delays")
+	errDiffEvalAlignment = errors.New("rule groups have different alignment of the evaluation time on interval")
 )
 
 // NamespaceState is used to denote the difference between the staged namespace
@@ -142,6 +145,14 @@ func CompareGroups(groupOne, groupTwo rwrulefmt.RuleGroup) error {
 		return errDiffSourceTenants
 	}
 
+	if durationValue(groupOne.EvaluationDelay) != durationValue(groupTwo.EvaluationDelay) {
+		return errDiffEvalDelay
+	}
+
+	if groupOne.AlignEvaluationTimeOnInterval != groupTwo.AlignEvaluationTimeOnInterval {
+		return errDiffEvalAlignment
+	}
+
 	for i := range groupOne.Rules {
 		eq := rulesEqual(&groupOne.Rules[i], &groupTwo.Rules[i])
 		if !eq {
@@ -152,6 +163,14 @@ func CompareGroups(groupOne, groupTwo rwrulefmt.RuleGroup) error {
 	return nil
 }
 
+// durationValue returns the value of the input optional duration, or zero if not set.
+func durationValue(d *model.Duration) model.Duration {
+	if d == nil {
+		return 0
+	}
+	return *d
+}
+
 // stringSlicesElementsMatch returns true if the two slices have completely overlapping elements.
 // For example, `stringSlicesElementsMatch([a, b], [a, b]) == true`
 // and `stringSlicesElementsMatch([a, b], [a, b, b]) == true`
@@ -7,7 +7,9 @@ package rules
 
 import (
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
@@ -460,6 +462,100 @@ func TestCompareGroups(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "unset and zero evaluation delay (should still be equivalent)",
+			groupOne: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record:      yaml.Node{Value: "one"},
+							Expr:        yaml.Node{Value: "up"},
+							Annotations: map[string]string{"a": "b", "c": "d"},
+							Labels:      nil,
+						},
+					},
+				},
+			},
+			groupTwo: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name:            "example_group",
+					EvaluationDelay: durationPtr(0),
+					Rules: []rulefmt.RuleNode{
+						{
+							Record:      yaml.Node{Value: "one"},
+							Expr:        yaml.Node{Value: "up"},
+							Annotations: map[string]string{"a": "b", "c": "d"},
+							Labels:      nil,
+						},
+					},
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "different evaluation delay",
+			groupOne: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name:            "example_group",
+					EvaluationDelay: durationPtr(model.Duration(time.Minute)),
+					Rules: []rulefmt.RuleNode{
+						{
+							Record:      yaml.Node{Value: "one"},
+							Expr:        yaml.Node{Value: "up"},
+							Annotations: map[string]string{"a": "b", "c": "d"},
+							Labels:      nil,
+						},
+					},
+				},
+			},
+			groupTwo: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name:            "example_group",
+					EvaluationDelay: durationPtr(model.Duration(2 * time.Minute)),
+					Rules: []rulefmt.RuleNode{
+						{
+							Record:      yaml.Node{Value: "one"},
+							Expr:        yaml.Node{Value: "up"},
+							Annotations: map[string]string{"a": "b", "c": "d"},
+							Labels:      nil,
+						},
+					},
+				},
+			},
+			expectedErr: errDiffEvalDelay,
+		},
+		{
+			name: "different alignment of the evaluation time on interval",
+			groupOne: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name: "example_group",
+					Rules: []rulefmt.RuleNode{
+						{
+							Record:      yaml.Node{Value: "one"},
+							Expr:        yaml.Node{Value: "up"},
+							Annotations: map[string]string{"a": "b", "c": "d"},
+							Labels:      nil,
+						},
+					},
+				},
+			},
+			groupTwo: rwrulefmt.RuleGroup{
+				RuleGroup: rulefmt.RuleGroup{
+					Name:                          "example_group",
+					AlignEvaluationTimeOnInterval: true,
+					Rules: []rulefmt.RuleNode{
+						{
+							Record:      yaml.Node{Value: "one"},
+							Expr:        yaml.Node{Value: "up"},
+							Annotations: map[string]string{"a": "b", "c": "d"},
+							Labels:      nil,
+						},
+					},
+				},
+			},
+			expectedErr: errDiffEvalAlignment,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -468,3 +564,7 @@ func TestCompareGroups(t *testing.T) {
 		})
 	}
 }
+
+func durationPtr(d model.Duration) *model.Duration {
+	return &d
+}
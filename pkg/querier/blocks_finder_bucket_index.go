@@ -19,6 +19,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/util/globalerror"
 )
 
@@ -30,6 +31,10 @@ type BucketIndexBlocksFinderConfig struct {
 	IndexLoader              bucketindex.LoaderConfig
 	MaxStalePeriod           time.Duration
 	IgnoreDeletionMarksDelay time.Duration
+
+	// Predicates of the blocks metadata filters configured in the store-gateway. The blocks not
+	// matching them are not loaded by any store-gateway, so they must not be queried.
+	BlockPredicates []storegateway.BlockPredicate
 }
 
 // BucketIndexBlocksFinder implements BlocksFinder interface and find blocks in the bucket
@@ -86,6 +91,9 @@ func (f *BucketIndexBlocksFinder) GetBlocks(ctx context.Context, userID string,
 		if !block.Within(minT, maxT) {
 			continue
 		}
+		if len(f.cfg.BlockPredicates) > 0 && !storegateway.MatchesBlockPredicates(f.cfg.BlockPredicates, block.ThanosMeta()) {
+			continue
+		}
 
 		matchingBlocks[block.ID] = block
 	}
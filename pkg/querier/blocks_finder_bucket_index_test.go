@@ -13,14 +13,17 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/services"
 	"github.com/oklog/ulid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/objstore"
 
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+	"github.com/grafana/mimir/pkg/storegateway"
 )
 
 func TestBucketIndexBlocksFinder_GetBlocks(t *testing.T) {
@@ -204,7 +207,37 @@ func TestBucketIndexBlocksFinder_GetBlocks_BucketIndexIsTooOld(t *testing.T) {
 	require.EqualError(t, err, newBucketIndexTooOldError(idx.GetUpdatedAt(), finder.cfg.MaxStalePeriod).Error())
 }
 
-func prepareBucketIndexBlocksFinder(t testing.TB, bkt objstore.Bucket) *BucketIndexBlocksFinder {
+func TestBucketIndexBlocksFinder_GetBlocks_ShouldApplyBlockPredicates(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+
+	block1 := &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 10, MaxTime: 20, Labels: map[string]string{"region": "eu"}}
+	block2 := &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 10, MaxTime: 20, Labels: map[string]string{"region": "us"}}
+	block3 := &bucketindex.Block{ID: ulid.MustNew(3, nil), MinTime: 10, MaxTime: 20}
+
+	require.NoError(t, bucketindex.WriteIndex(ctx, bkt, userID, nil, &bucketindex.Index{
+		Version:   bucketindex.IndexVersion1,
+		Blocks:    bucketindex.Blocks{block1, block2, block3},
+		UpdatedAt: time.Now().Unix(),
+	}))
+
+	// The store-gateways only load the blocks with the region=eu external label.
+	predicates, err := storegateway.CreateBlockPredicates(mimir_tsdb.BucketStoreConfig{
+		MetadataFilters:      flagext.StringSliceCSV{storegateway.ExternalLabelsMetadataFilter},
+		ExternalLabelsFilter: flagext.StringSliceCSV{"region=eu"},
+	})
+	require.NoError(t, err)
+
+	finder := prepareBucketIndexBlocksFinder(t, bkt, predicates...)
+
+	blocks, _, err := finder.GetBlocks(ctx, userID, 0, 30)
+	require.NoError(t, err)
+	require.Equal(t, bucketindex.Blocks{block1}, blocks)
+}
+
+func prepareBucketIndexBlocksFinder(t testing.TB, bkt objstore.Bucket, predicates ...storegateway.BlockPredicate) *BucketIndexBlocksFinder {
 	ctx := context.Background()
 	cfg := BucketIndexBlocksFinderConfig{
 		IndexLoader: bucketindex.LoaderConfig{
@@ -215,6 +248,7 @@ func prepareBucketIndexBlocksFinder(t testing.TB, bkt objstore.Bucket) *BucketIn
 		},
 		MaxStalePeriod:           time.Hour,
 		IgnoreDeletionMarksDelay: time.Hour,
+		BlockPredicates:          predicates,
 	}
 
 	finder := NewBucketIndexBlocksFinder(cfg, bkt, nil, log.NewNopLogger(), nil)
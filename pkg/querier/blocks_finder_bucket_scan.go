@@ -47,6 +47,10 @@ type BucketScanBlocksFinderConfig struct {
 	CacheDir                 string
 	ConsistencyDelay         time.Duration
 	IgnoreDeletionMarksDelay time.Duration
+
+	// Predicates of the blocks metadata filters configured in the store-gateway. The blocks not
+	// matching them are not loaded by any store-gateway, so they must not be queried.
+	BlockPredicates []storegateway.BlockPredicate
 }
 
 // BucketScanBlocksFinder is a BlocksFinder implementation periodically scanning the bucket to discover blocks.
@@ -382,6 +386,9 @@ func (d *BucketScanBlocksFinder) createMetaFetcher(userID string) (block.Metadat
 	//   discover and load the compacted ones.
 	deletionMarkFilter := block.NewIgnoreDeletionMarkFilter(userLogger, userBucket, d.cfg.IgnoreDeletionMarksDelay, d.cfg.MetasConcurrency)
 	filters := []block.MetadataFilter{deletionMarkFilter}
+	for _, predicate := range d.cfg.BlockPredicates {
+		filters = append(filters, storegateway.NewBlockPredicateMetaFilter(predicate))
+	}
 
 	f, err := block.NewMetaFetcher(
 		userLogger,
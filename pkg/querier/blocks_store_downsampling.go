@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/downsample"
+)
+
+// The step and the range of the queries run on the downsampled blocks must be at least this number
+// of times the resolution of the downsampled blocks.
+const minSamplesPerStep = 5
+
+// queryResolution returns the highest downsampling resolution which can be used to run the query, or 0 if
+// the query must run on raw blocks. The downsampled blocks are only used for the range vector functions
+// which have a matching aggregate in the downsampled blocks (see downsample.AggregateForFunc), with a step
+// and a range large enough to not be affected by the alignment of the resolution windows. The result of
+// these functions is approximated only at the edges of the range, where the windows partially overlap it.
+func queryResolution(sp *storage.SelectHints) int64 {
+	if sp == nil || sp.Range == 0 {
+		return 0
+	}
+	if _, ok := downsample.AggregateForFunc(sp.Func); !ok {
+		return 0
+	}
+
+	for i := len(downsample.Resolutions) - 1; i >= 0; i-- {
+		res := downsample.Resolutions[i]
+
+		if sp.Step >= minSamplesPerStep*res && sp.Range >= minSamplesPerStep*res {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// downsampledAggregate returns the aggregate to query in the downsampled blocks. The downsampled blocks
+// are only queried if queryResolution() returned a resolution greater than 0, in which case the function
+// has a matching aggregate.
+func downsampledAggregate(sp *storage.SelectHints) string {
+	if sp == nil {
+		return ""
+	}
+	aggr, _ := downsample.AggregateForFunc(sp.Func)
+	return aggr
+}
+
+// selectBlocksForResolution replaces each raw block with its downsampled block at the input resolution,
+// or the highest lower resolution available. A downsampled block replaces a raw block if it has the same
+// time range and compactor shard. The downsampled blocks not replacing any raw block are removed, so all
+// the downsampled blocks are removed if the input resolution is 0. The input order of the blocks is preserved.
+func selectBlocksForResolution(blocks bucketindex.Blocks, resolution int64) bucketindex.Blocks {
+	type blockKey struct {
+		minT, maxT int64
+		shardID    string
+		resolution int64
+	}
+
+	downsampled := map[blockKey]*bucketindex.Block{}
+	for _, b := range blocks {
+		if b.Resolution == 0 || b.Resolution > resolution {
+			continue
+		}
+
+		// A raw block can be downsampled again after being compacted with late blocks. The previous
+		// downsampled block is deleted by the compactor, and in the meanwhile we pick the newest one.
+		key := blockKey{minT: b.MinTime, maxT: b.MaxTime, shardID: b.CompactorShardID, resolution: b.Resolution}
+		if prev, ok := downsampled[key]; !ok || b.ID.Compare(prev.ID) > 0 {
+			downsampled[key] = b
+		}
+	}
+
+	result := make(bucketindex.Blocks, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Resolution > 0 {
+			continue
+		}
+
+		selected := b
+		for i := len(downsample.Resolutions) - 1; i >= 0; i-- {
+			key := blockKey{minT: b.MinTime, maxT: b.MaxTime, shardID: b.CompactorShardID, resolution: downsample.Resolutions[i]}
+			if d, ok := downsampled[key]; ok {
+				selected = d
+				break
+			}
+		}
+
+		result = append(result, selected)
+	}
+
+	return result
+}
+
+// removeAggregateLabel removes the aggregate label from the labels of a series fetched from downsampled blocks.
+func removeAggregateLabel(lbls []mimirpb.LabelAdapter) []mimirpb.LabelAdapter {
+	for i, l := range lbls {
+		if l.Name == downsample.AggregateLabel {
+			return append(lbls[:i], lbls[i+1:]...)
+		}
+	}
+	return lbls
+}
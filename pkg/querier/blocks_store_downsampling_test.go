@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/downsample"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+func TestQueryResolution(t *testing.T) {
+	tests := map[string]struct {
+		hints    *storage.SelectHints
+		expected int64
+	}{
+		"no hints": {
+			hints:    nil,
+			expected: 0,
+		},
+		"instant query": {
+			hints:    &storage.SelectHints{},
+			expected: 0,
+		},
+		"series query": {
+			hints:    &storage.SelectHints{Func: "series", Step: time.Hour.Milliseconds()},
+			expected: 0,
+		},
+		"small step": {
+			hints:    &storage.SelectHints{Step: time.Minute.Milliseconds()},
+			expected: 0,
+		},
+		"instant selector with a large step": {
+			hints:    &storage.SelectHints{Step: 10 * time.Hour.Milliseconds()},
+			expected: 0,
+		},
+		"aggregation of an instant selector with a large step": {
+			hints:    &storage.SelectHints{Func: "sum", Step: 10 * time.Hour.Milliseconds()},
+			expected: 0,
+		},
+		"unsupported range selector function with a large step and a large range": {
+			hints:    &storage.SelectHints{Func: "count_over_time", Step: 10 * time.Hour.Milliseconds(), Range: 6 * time.Hour.Milliseconds()},
+			expected: 0,
+		},
+		"supported range selector function with a large step and a large range": {
+			hints:    &storage.SelectHints{Func: "max_over_time", Step: 10 * time.Hour.Milliseconds(), Range: 6 * time.Hour.Milliseconds()},
+			expected: downsample.ResLevel2,
+		},
+		"range selector with a large step and a small range": {
+			hints:    &storage.SelectHints{Func: "rate", Step: 10 * time.Hour.Milliseconds(), Range: 10 * time.Minute.Milliseconds()},
+			expected: 0,
+		},
+		"range selector with a large step and a medium range": {
+			hints:    &storage.SelectHints{Func: "rate", Step: 10 * time.Hour.Milliseconds(), Range: time.Hour.Milliseconds()},
+			expected: downsample.ResLevel1,
+		},
+		"range selector with a large step and a large range": {
+			hints:    &storage.SelectHints{Func: "rate", Step: 10 * time.Hour.Milliseconds(), Range: 6 * time.Hour.Milliseconds()},
+			expected: downsample.ResLevel2,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, queryResolution(testData.hints))
+		})
+	}
+}
+
+func TestSelectBlocksForResolution(t *testing.T) {
+	var (
+		raw1      = &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 10, CompactorShardID: "1_of_2"}
+		raw2      = &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: 0, MaxTime: 10, CompactorShardID: "2_of_2"}
+		raw3      = &bucketindex.Block{ID: ulid.MustNew(3, nil), MinTime: 10, MaxTime: 12}
+		raw1Res1  = &bucketindex.Block{ID: ulid.MustNew(4, nil), MinTime: 0, MaxTime: 10, CompactorShardID: "1_of_2", Resolution: downsample.ResLevel1}
+		raw1Res2  = &bucketindex.Block{ID: ulid.MustNew(5, nil), MinTime: 0, MaxTime: 10, CompactorShardID: "1_of_2", Resolution: downsample.ResLevel2}
+		raw2Res1  = &bucketindex.Block{ID: ulid.MustNew(6, nil), MinTime: 0, MaxTime: 10, CompactorShardID: "2_of_2", Resolution: downsample.ResLevel1}
+		raw2Res1b = &bucketindex.Block{ID: ulid.MustNew(7, nil), MinTime: 0, MaxTime: 10, CompactorShardID: "2_of_2", Resolution: downsample.ResLevel1}
+	)
+
+	blocks := bucketindex.Blocks{raw1, raw2, raw3, raw1Res1, raw1Res2, raw2Res1, raw2Res1b}
+
+	assert.Equal(t, bucketindex.Blocks{raw1, raw2, raw3}, selectBlocksForResolution(blocks, 0))
+	assert.Equal(t, bucketindex.Blocks{raw1Res1, raw2Res1b, raw3}, selectBlocksForResolution(blocks, downsample.ResLevel1))
+	assert.Equal(t, bucketindex.Blocks{raw1Res2, raw2Res1b, raw3}, selectBlocksForResolution(blocks, downsample.ResLevel2))
+}
+
+func TestBlocksStoreQuerier_SelectOnDownsampledBlocks(t *testing.T) {
+	const (
+		metricName = "test_metric"
+		minT       = int64(0)
+		maxT       = int64(24 * time.Hour / time.Millisecond)
+	)
+
+	var (
+		rawBlock         = ulid.MustNew(1, nil)
+		downsampledBlock = ulid.MustNew(2, nil)
+		metricLabels     = labels.FromStrings(labels.MetricName, metricName)
+	)
+
+	tests := map[string]struct {
+		downsampledBlocksEnabled bool
+		hints                    *storage.SelectHints
+		expectedBlock            ulid.ULID
+		responseLabels           labels.Labels
+	}{
+		"downsampled blocks disabled": {
+			hints:          &storage.SelectHints{Start: minT, End: maxT, Func: "rate", Step: time.Hour.Milliseconds(), Range: time.Hour.Milliseconds()},
+			expectedBlock:  rawBlock,
+			responseLabels: metricLabels,
+		},
+		"downsampled blocks enabled but query step too small": {
+			downsampledBlocksEnabled: true,
+			hints:                    &storage.SelectHints{Start: minT, End: maxT, Func: "rate", Step: time.Minute.Milliseconds(), Range: time.Hour.Milliseconds()},
+			expectedBlock:            rawBlock,
+			responseLabels:           metricLabels,
+		},
+		"downsampled blocks enabled and large query step": {
+			downsampledBlocksEnabled: true,
+			hints:                    &storage.SelectHints{Start: minT, End: maxT, Func: "rate", Step: time.Hour.Milliseconds(), Range: time.Hour.Milliseconds()},
+			expectedBlock:            downsampledBlock,
+			responseLabels:           labels.FromStrings(downsample.AggregateLabel, downsample.AggregateCounter, labels.MetricName, metricName),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := limiter.AddQueryLimiterToContext(context.Background(), limiter.NewQueryLimiter(0, 0, 0))
+
+			client := &storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+				mockSeriesResponse(testData.responseLabels, minT+1, 1),
+				mockHintsResponse(testData.expectedBlock),
+			}}
+
+			stores := &blocksStoreSetMock{mockedResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{client: {testData.expectedBlock}},
+			}}
+
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{
+				{ID: rawBlock, MinTime: minT, MaxTime: maxT},
+				{ID: downsampledBlock, MinTime: minT, MaxTime: maxT, Resolution: downsample.ResLevel1},
+			}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+			q := &blocksStoreQuerier{
+				ctx:                      ctx,
+				minT:                     minT,
+				maxT:                     maxT,
+				userID:                   "user-1",
+				finder:                   finder,
+				stores:                   stores,
+				consistency:              NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:                   log.NewNopLogger(),
+				metrics:                  newBlocksStoreQueryableMetrics(prometheus.NewPedanticRegistry()),
+				limits:                   &blocksStoreLimitsMock{},
+				downsampledBlocksEnabled: testData.downsampledBlocksEnabled,
+			}
+
+			set := q.Select(true, testData.hints, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, metricName))
+			require.True(t, set.Next())
+			assert.Equal(t, metricLabels, set.At().Labels())
+			assert.False(t, set.Next())
+			require.NoError(t, set.Err())
+		})
+	}
+}
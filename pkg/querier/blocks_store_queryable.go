@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,14 +27,17 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/thanos-io/objstore"
 	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
 	grpc_metadata "google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/querier/stats"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/series"
@@ -41,6 +45,7 @@ import (
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/downsample"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/storegateway/hintspb"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
@@ -151,6 +156,9 @@ type BlocksStoreQueryable struct {
 	metrics         *blocksStoreQueryableMetrics
 	limits          BlocksStoreLimits
 
+	// Whether queries with a large step run on the downsampled blocks.
+	downsampledBlocksEnabled bool
+
 	// Subservices manager.
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -162,6 +170,7 @@ func NewBlocksStoreQueryable(
 	consistency *BlocksConsistencyChecker,
 	limits BlocksStoreLimits,
 	queryStoreAfter time.Duration,
+	downsampledBlocksEnabled bool,
 	logger log.Logger,
 	reg prometheus.Registerer,
 ) (*BlocksStoreQueryable, error) {
@@ -180,6 +189,8 @@ func NewBlocksStoreQueryable(
 		subservicesWatcher: services.NewFailureWatcher(),
 		metrics:            newBlocksStoreQueryableMetrics(reg),
 		limits:             limits,
+
+		downsampledBlocksEnabled: downsampledBlocksEnabled,
 	}
 
 	q.Service = services.NewBasicService(q.starting, q.running, q.stopping)
@@ -193,7 +204,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		bucketClient objstore.Bucket
 	)
 
-	bucketClient, err := bucket.NewClient(context.Background(), storageCfg.Bucket, "querier", logger, reg)
+	bucketClient, err := mimir_tsdb.NewBucketClient(context.Background(), storageCfg, "querier", logger, reg)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create bucket client")
 	}
@@ -203,8 +214,20 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 	if err != nil {
 		return nil, errors.Wrap(err, "create caching bucket")
 	}
+
+	if storageCfg.BucketStore.MetadataCache.Backend != "" {
+		// Version the cache keys with the tenants cache epoch, so that the cache invalidations are applied by the
+		// querier too. The cache epochs are re-read at the same interval at which the store-gateways read them.
+		cachingBucket = newCacheEpochBucket(cachingBucket, bucketClient, storageCfg.BucketStore.SyncInterval, logger)
+	}
 	bucketClient = cachingBucket
 
+	// The querier must not expect the blocks excluded by the store-gateway metadata filters to be loaded.
+	blockPredicates, err := storegateway.CreateBlockPredicates(storageCfg.BucketStore)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the blocks metadata filters")
+	}
+
 	// Create the blocks finder.
 	var finder BlocksFinder
 	if storageCfg.BucketStore.BucketIndex.Enabled {
@@ -217,6 +240,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 			},
 			MaxStalePeriod:           storageCfg.BucketStore.BucketIndex.MaxStalePeriod,
 			IgnoreDeletionMarksDelay: storageCfg.BucketStore.IgnoreDeletionMarksDelay,
+			BlockPredicates:          blockPredicates,
 		}, bucketClient, limits, logger, reg)
 	} else {
 		finder = NewBucketScanBlocksFinder(BucketScanBlocksFinderConfig{
@@ -225,6 +249,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 			MetasConcurrency:         storageCfg.BucketStore.MetaSyncConcurrency,
 			CacheDir:                 storageCfg.BucketStore.SyncDir,
 			IgnoreDeletionMarksDelay: storageCfg.BucketStore.IgnoreDeletionMarksDelay,
+			BlockPredicates:          blockPredicates,
 		}, bucketClient, limits, logger, reg)
 	}
 
@@ -261,7 +286,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		reg,
 	)
 
-	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, logger, reg)
+	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, querierCfg.DownsampledBlocksEnabled, logger, reg)
 }
 
 func (q *BlocksStoreQueryable) starting(ctx context.Context) error {
@@ -295,6 +320,24 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		return nil, errors.Errorf("BlocksStoreQueryable is not running: %v", s)
 	}
 
+	return q.newQuerier(ctx, mint, maxt)
+}
+
+// ExemplarQuerier returns a new ExemplarQuerier on the exemplars stored in the blocks.
+func (q *BlocksStoreQueryable) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	if s := q.State(); s != services.Running {
+		return nil, errors.Errorf("BlocksStoreQueryable is not running: %v", s)
+	}
+
+	// The time range is set on each Select() call.
+	querier, err := q.newQuerier(ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &blocksStoreExemplarQuerier{querier: querier}, nil
+}
+
+func (q *BlocksStoreQueryable) newQuerier(ctx context.Context, mint, maxt int64) (*blocksStoreQuerier, error) {
 	userID, err := tenant.TenantID(ctx)
 	if err != nil {
 		return nil, err
@@ -312,6 +355,8 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		consistency:     q.consistency,
 		logger:          q.logger,
 		queryStoreAfter: q.queryStoreAfter,
+
+		downsampledBlocksEnabled: q.downsampledBlocksEnabled,
 	}, nil
 }
 
@@ -329,6 +374,9 @@ type blocksStoreQuerier struct {
 	// If set, the querier manipulates the max time to not be greater than
 	// "now - queryStoreAfter" so that most recent blocks are not queried.
 	queryStoreAfter time.Duration
+
+	// Whether queries with a large step run on the downsampled blocks.
+	downsampledBlocksEnabled bool
 }
 
 // Select implements storage.Querier interface.
@@ -359,7 +407,7 @@ func (q *blocksStoreQuerier) LabelNames(matchers ...*labels.Matcher) ([]string,
 		convertedMatchers = convertMatchersToLabelMatcher(matchers)
 	)
 
-	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, _ map[ulid.ULID]struct{}, minT, maxT int64) ([]ulid.ULID, error) {
 		nameSets, warnings, queriedBlocks, err := q.fetchLabelNamesFromStore(spanCtx, clients, minT, maxT, convertedMatchers)
 		if err != nil {
 			return nil, err
@@ -371,7 +419,7 @@ func (q *blocksStoreQuerier) LabelNames(matchers ...*labels.Matcher) ([]string,
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
+	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, 0, queryFunc)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -400,7 +448,7 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		resWarnings  = storage.Warnings(nil)
 	)
 
-	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, _ map[ulid.ULID]struct{}, minT, maxT int64) ([]ulid.ULID, error) {
 		valueSets, warnings, queriedBlocks, err := q.fetchLabelValuesFromStore(spanCtx, name, clients, minT, maxT, matchers...)
 		if err != nil {
 			return nil, err
@@ -412,7 +460,7 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, queryFunc)
+	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, nil, 0, queryFunc)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -424,6 +472,52 @@ func (q *blocksStoreQuerier) Close() error {
 	return nil
 }
 
+type blocksStoreExemplarQuerier struct {
+	querier *blocksStoreQuerier
+}
+
+// Select implements storage.ExemplarQuerier interface.
+func (q *blocksStoreExemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	spanLog, spanCtx := spanlogger.NewWithLogger(q.querier.ctx, q.querier.logger, "blocksStoreExemplarQuerier.Select")
+	defer spanLog.Span.Finish()
+
+	level.Debug(spanLog).Log("start", util.TimeFromMillis(start).UTC().String(), "end", util.TimeFromMillis(end).UTC().String())
+
+	convertedMatchers := make([]storegatewaypb.ExemplarsMatchers, 0, len(matchers))
+	for _, m := range matchers {
+		convertedMatchers = append(convertedMatchers, storegatewaypb.ExemplarsMatchers{Matchers: convertMatchersToLabelMatcher(m)})
+	}
+
+	var resSets [][]mimirpb.TimeSeries
+
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, _ map[ulid.ULID]struct{}, minT, maxT int64) ([]ulid.ULID, error) {
+		sets, queriedBlocks, err := q.querier.fetchExemplarsFromStores(spanCtx, clients, minT, maxT, convertedMatchers)
+		if err != nil {
+			return nil, err
+		}
+
+		resSets = append(resSets, sets...)
+		return queriedBlocks, nil
+	}
+
+	err := q.querier.queryWithConsistencyCheck(spanCtx, spanLog, start, end, nil, 0, queryFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := block.MergeExemplars(resSets...)
+	result := make([]exemplar.QueryResult, 0, len(merged))
+	for _, ts := range merged {
+		result = append(result, exemplar.QueryResult{
+			SeriesLabels: mimirpb.FromLabelAdaptersToLabels(ts.Labels),
+			Exemplars:    mimirpb.FromExemplarProtosToExemplars(ts.Exemplars),
+		})
+	}
+
+	level.Debug(spanLog).Log("numSeries", len(result))
+	return result, nil
+}
+
 func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
 	spanLog, spanCtx := spanlogger.NewWithLogger(q.ctx, q.logger, "blocksStoreQuerier.selectSorted")
 	defer spanLog.Span.Finish()
@@ -441,8 +535,14 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return storage.ErrSeriesSet(err)
 	}
 
-	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
-		seriesSets, queriedBlocks, warnings, err := q.fetchSeriesFromStores(spanCtx, sp, clients, minT, maxT, convertedMatchers)
+	// Queries with a large step can run on the downsampled blocks, if any.
+	resolution := int64(0)
+	if q.downsampledBlocksEnabled {
+		resolution = queryResolution(sp)
+	}
+
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, downsampledBlocks map[ulid.ULID]struct{}, minT, maxT int64) ([]ulid.ULID, error) {
+		seriesSets, queriedBlocks, warnings, err := q.fetchSeriesFromStores(spanCtx, sp, clients, downsampledBlocks, minT, maxT, convertedMatchers)
 		if err != nil {
 			return nil, err
 		}
@@ -453,7 +553,7 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return queriedBlocks, nil
 	}
 
-	err = q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, queryFunc)
+	err = q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, shard, resolution, queryFunc)
 	if err != nil {
 		return storage.ErrSeriesSet(err)
 	}
@@ -467,8 +567,11 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		resWarnings)
 }
 
-func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector,
-	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) error {
+// queryWithConsistencyCheck runs queryFunc on the blocks within the time range, until all of them have been queried.
+// The raw blocks are replaced by their downsampled blocks at the input resolution (or a lower one), if any.
+// queryFunc gets the IDs of the downsampled blocks to query, which is empty if resolution is 0.
+func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64, shard *sharding.ShardSelector, resolution int64,
+	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, downsampledBlocks map[ulid.ULID]struct{}, minT, maxT int64) ([]ulid.ULID, error)) error {
 	// If queryStoreAfter is enabled, we do manipulate the query maxt to query samples up until
 	// now - queryStoreAfter, because the most recent time range is covered by ingesters. This
 	// optimization is particularly important for the blocks storage because can be used to skip
@@ -514,6 +617,24 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		knownBlocks = result
 	}
 
+	if util.SkipOutOfOrderFromOutgoingContext(ctx) {
+		result := filterOutOfOrderBlocks(knownBlocks)
+		level.Debug(logger).Log("msg", "filtered out-of-order blocks", "before", len(knownBlocks), "after", len(result))
+		knownBlocks = result
+	}
+
+	knownBlocks = selectBlocksForResolution(knownBlocks, resolution)
+
+	downsampledBlocks := map[ulid.ULID]struct{}{}
+	for _, b := range knownBlocks {
+		if b.Resolution > 0 {
+			downsampledBlocks[b.ID] = struct{}{}
+		}
+	}
+	if len(downsampledBlocks) > 0 {
+		level.Debug(logger).Log("msg", "querying downsampled blocks", "resolution", resolution, "downsampled", len(downsampledBlocks))
+	}
+
 	q.metrics.blocksQueried.Add(float64(len(knownBlocks)))
 
 	level.Debug(logger).Log("msg", "found blocks to query", "expected", knownBlocks.String())
@@ -545,7 +666,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 
 		// Fetch series from stores. If an error occur we do not retry because retries
 		// are only meant to cover missing blocks.
-		queriedBlocks, err := queryFunc(clients, minT, maxT)
+		queriedBlocks, err := queryFunc(clients, downsampledBlocks, minT, maxT)
 		if err != nil {
 			return err
 		}
@@ -626,6 +747,17 @@ func filterBlocksByShard(blocks bucketindex.Blocks, queryShardIndex, queryShardC
 	return blocks, incompatibleBlocks
 }
 
+// filterOutOfOrderBlocks returns the input blocks, excluding the ones containing out-of-order data.
+func filterOutOfOrderBlocks(blocks bucketindex.Blocks) bucketindex.Blocks {
+	result := make(bucketindex.Blocks, 0, len(blocks))
+	for _, b := range blocks {
+		if !b.OutOfOrder {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
 // canBlockWithCompactorShardIndexContainQueryShard returns false if block with given compactor shard ID can *definitely NOT*
 // contain series for given query shard. Returns true otherwise (we don't know if block *does* contain such series,
 // but we cannot rule it out).
@@ -669,7 +801,7 @@ func canBlockWithCompactorShardIndexContainQueryShard(queryShardIndex, queryShar
 // In case of a serious error during any of the concurrent executions, the error is returned. Errors while creating storepb.SeriesRequest,
 // context cancellation, and unprocessable requests to the store-gateways (e.g., if a chunk or series limit is hit) are
 // considered serious errors. All other errors are not returned, but they give rise to fetch retrials.
-func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *storage.SelectHints, clients map[BlocksStoreClient][]ulid.ULID, minT int64, maxT int64, convertedMatchers []storepb.LabelMatcher) ([]storage.SeriesSet, []ulid.ULID, storage.Warnings, error) {
+func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *storage.SelectHints, clients map[BlocksStoreClient][]ulid.ULID, downsampledBlocks map[ulid.ULID]struct{}, minT int64, maxT int64, convertedMatchers []storepb.LabelMatcher) ([]storage.SeriesSet, []ulid.ULID, storage.Warnings, error) {
 	var (
 		reqCtx        = grpc_metadata.AppendToOutgoingContext(ctx, storegateway.GrpcContextMetadataTenantID, q.userID)
 		g, gCtx       = errgroup.WithContext(reqCtx)
@@ -679,23 +811,33 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 		queriedBlocks = []ulid.ULID(nil)
 		spanLog       = spanlogger.FromContext(ctx, q.logger)
 		queryLimiter  = limiter.QueryLimiterFromContextWithFallback(ctx)
+		memoryTracker = limiter.MemoryTrackerFromContextWithFallback(ctx)
 		reqStats      = stats.FromContext(ctx)
 	)
 
-	// Concurrently fetch series from all clients.
-	for c, blockIDs := range clients {
-		// Change variables scope since it will be used in a goroutine.
-		c := c
-		blockIDs := blockIDs
+	// The series of the downsampled blocks are fetched with a separate request, selecting only the series
+	// of the aggregate needed by the query. The series of the raw series copied as-is in the downsampled
+	// blocks (eg. native histograms) are selected too.
+	downsampledMatchers := append(slices.Clone(convertedMatchers), storepb.LabelMatcher{
+		Type:  storepb.LabelMatcher_RE,
+		Name:  downsample.AggregateLabel,
+		Value: downsampledAggregate(sp) + "|" + downsample.AggregateRaw,
+	})
+
+	fetch := func(c BlocksStoreClient, blockIDs []ulid.ULID, downsampled bool) func() error {
+		matchers := convertedMatchers
+		if downsampled {
+			matchers = downsampledMatchers
+		}
 
-		g.Go(func() error {
+		return func() error {
 			// See: https://github.com/prometheus/prometheus/pull/8050
 			// TODO(goutham): we should ideally be passing the hints down to the storage layer
 			// and let the TSDB return us data with no chunks as in prometheus#8050.
 			// But this is an acceptable workaround for now.
 			skipChunks := sp != nil && sp.Func == "series"
 
-			req, err := createSeriesRequest(minT, maxT, convertedMatchers, skipChunks, blockIDs)
+			req, err := createSeriesRequest(minT, maxT, matchers, skipChunks, blockIDs)
 			if err != nil {
 				return errors.Wrapf(err, "failed to create series request")
 			}
@@ -737,6 +879,9 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 
 				// Response may either contain series, warning or hints.
 				if s := resp.GetSeries(); s != nil {
+					if downsampled {
+						s.Labels = removeAggregateLabel(s.Labels)
+					}
 					mySeries = append(mySeries, s)
 
 					// Add series fingerprint to query limiter; will return error if we are over the limit
@@ -752,6 +897,14 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 					if chunkLimitErr := queryLimiter.AddChunks(chunksCount); chunkLimitErr != nil {
 						return validation.LimitError(chunkLimitErr.Error())
 					}
+
+					// Enforce the max estimated memory of the fetched series and chunks.
+					if memoryLimitErr := memoryTracker.AddSeries(s.Labels); memoryLimitErr != nil {
+						return validation.LimitError(memoryLimitErr.Error())
+					}
+					if memoryLimitErr := memoryTracker.Add(chunksSize); memoryLimitErr != nil {
+						return validation.LimitError(memoryLimitErr.Error())
+					}
 				}
 
 				if w := resp.GetWarning(); w != "" {
@@ -794,6 +947,13 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
 				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
 
+			// Removing the aggregate label may change the ordering of the series.
+			if downsampled {
+				sort.SliceStable(mySeries, func(i, j int) bool {
+					return labels.Compare(mimirpb.FromLabelAdaptersToLabels(mySeries[i].Labels), mimirpb.FromLabelAdaptersToLabels(mySeries[j].Labels)) < 0
+				})
+			}
+
 			// Store the result.
 			mtx.Lock()
 			seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: mySeries})
@@ -802,7 +962,26 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 			mtx.Unlock()
 
 			return nil
-		})
+		}
+	}
+
+	// Concurrently fetch series from all clients.
+	for c, blockIDs := range clients {
+		var rawBlockIDs, downsampledBlockIDs []ulid.ULID
+		for _, id := range blockIDs {
+			if _, ok := downsampledBlocks[id]; ok {
+				downsampledBlockIDs = append(downsampledBlockIDs, id)
+			} else {
+				rawBlockIDs = append(rawBlockIDs, id)
+			}
+		}
+
+		if len(rawBlockIDs) > 0 {
+			g.Go(fetch(c, rawBlockIDs, false))
+		}
+		if len(downsampledBlockIDs) > 0 {
+			g.Go(fetch(c, downsampledBlockIDs, true))
+		}
 	}
 
 	// Wait until all client requests complete.
@@ -827,6 +1006,60 @@ func shouldStopQueryFunc(err error) bool {
 	return false
 }
 
+// fetchLabelNamesFromStoreGateway fetches the label names from the input store-gateway, which streams them
+// as soon as they're read from each block. It falls back to the non-streaming LabelNames if the store-gateway
+// doesn't support streaming them, for example during a rollout.
+func fetchLabelNamesFromStoreGateway(ctx context.Context, c BlocksStoreClient, req *storepb.LabelNamesRequest) ([]*storepb.LabelNamesResponse, error) {
+	var resps []*storepb.LabelNamesResponse
+
+	stream, err := c.LabelNamesStream(ctx, req)
+	for err == nil {
+		var resp *storepb.LabelNamesResponse
+		if resp, err = stream.Recv(); err == nil {
+			resps = append(resps, resp)
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return resps, nil
+	}
+	if status.Code(err) != codes.Unimplemented {
+		return nil, err
+	}
+
+	resp, err := c.LabelNames(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return []*storepb.LabelNamesResponse{resp}, nil
+}
+
+// fetchLabelValuesFromStoreGateway fetches the label values from the input store-gateway, which streams them
+// as soon as they're read from each block. It falls back to the non-streaming LabelValues if the store-gateway
+// doesn't support streaming them, for example during a rollout.
+func fetchLabelValuesFromStoreGateway(ctx context.Context, c BlocksStoreClient, req *storepb.LabelValuesRequest) ([]*storepb.LabelValuesResponse, error) {
+	var resps []*storepb.LabelValuesResponse
+
+	stream, err := c.LabelValuesStream(ctx, req)
+	for err == nil {
+		var resp *storepb.LabelValuesResponse
+		if resp, err = stream.Recv(); err == nil {
+			resps = append(resps, resp)
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return resps, nil
+	}
+	if status.Code(err) != codes.Unimplemented {
+		return nil, err
+	}
+
+	resp, err := c.LabelValues(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return []*storepb.LabelValuesResponse{resp}, nil
+}
+
 func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 	ctx context.Context,
 	clients map[BlocksStoreClient][]ulid.ULID,
@@ -856,9 +1089,9 @@ func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 				return errors.Wrapf(err, "failed to create label names request")
 			}
 
-			namesResp, err := c.LabelNames(gCtx, req)
+			namesResps, err := fetchLabelNamesFromStoreGateway(gCtx, c, req)
 			if err != nil {
-				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				if shouldStopQueryFunc(err) {
 					return err
 				}
 
@@ -866,33 +1099,45 @@ func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 				return nil
 			}
 
+			myNames := []string(nil)
+			myWarnings := storage.Warnings(nil)
 			myQueriedBlocks := []ulid.ULID(nil)
-			if namesResp.Hints != nil {
-				hints := hintspb.LabelNamesResponseHints{}
-				if err := types.UnmarshalAny(namesResp.Hints, &hints); err != nil {
-					return errors.Wrapf(err, "failed to unmarshal label names hints from %s", c.RemoteAddress())
-				}
 
-				ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
-				if err != nil {
-					return errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+			// Label names may be split across multiple messages.
+			for _, namesResp := range namesResps {
+				myNames = append(myNames, namesResp.Names...)
+				for _, w := range namesResp.Warnings {
+					myWarnings = append(myWarnings, errors.New(w))
 				}
 
-				myQueriedBlocks = ids
+				if namesResp.Hints != nil {
+					hints := hintspb.LabelNamesResponseHints{}
+					if err := types.UnmarshalAny(namesResp.Hints, &hints); err != nil {
+						return errors.Wrapf(err, "failed to unmarshal label names hints from %s", c.RemoteAddress())
+					}
+
+					ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
+					if err != nil {
+						return errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+					}
+
+					myQueriedBlocks = append(myQueriedBlocks, ids...)
+				}
 			}
 
 			level.Debug(spanLog).Log("msg", "received label names from store-gateway",
 				"instance", c,
-				"num labels", len(namesResp.Names),
+				"num labels", len(myNames),
 				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
 				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
 
+			// Label names are sorted within each message, but not across messages, so we need to sort them to merge.
+			slices.Sort(myNames)
+
 			// Store the result.
 			mtx.Lock()
-			nameSets = append(nameSets, namesResp.Names)
-			for _, w := range namesResp.Warnings {
-				warnings = append(warnings, errors.New(w))
-			}
+			nameSets = append(nameSets, myNames)
+			warnings = append(warnings, myWarnings...)
 			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
 			mtx.Unlock()
 
@@ -908,6 +1153,85 @@ func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 	return nameSets, warnings, queriedBlocks, nil
 }
 
+func (q *blocksStoreQuerier) fetchExemplarsFromStores(
+	ctx context.Context,
+	clients map[BlocksStoreClient][]ulid.ULID,
+	minT int64,
+	maxT int64,
+	matchers []storegatewaypb.ExemplarsMatchers,
+) ([][]mimirpb.TimeSeries, []ulid.ULID, error) {
+	var (
+		reqCtx        = grpc_metadata.AppendToOutgoingContext(ctx, storegateway.GrpcContextMetadataTenantID, q.userID)
+		g, gCtx       = errgroup.WithContext(reqCtx)
+		mtx           = sync.Mutex{}
+		sets          = [][]mimirpb.TimeSeries{}
+		queriedBlocks = []ulid.ULID(nil)
+		spanLog       = spanlogger.FromContext(ctx, q.logger)
+	)
+
+	// Concurrently fetch exemplars from all clients.
+	for c, blockIDs := range clients {
+		// Change variables scope since it will be used in a goroutine.
+		c := c
+		blockIDs := blockIDs
+
+		g.Go(func() error {
+			req := &storegatewaypb.ExemplarsRequest{
+				MinTime:  minT,
+				MaxTime:  maxT,
+				Matchers: matchers,
+				BlockIds: convertULIDsToString(blockIDs),
+			}
+
+			resp, err := c.Exemplars(gCtx, req)
+			if status.Code(err) == codes.Unimplemented {
+				// The store-gateway doesn't serve the exemplars yet (e.g. during a rollout), so it has no
+				// exemplars to return: consider the requested blocks as queried.
+				level.Debug(spanLog).Log("msg", "store-gateway doesn't support exemplars, considering the requested blocks as queried", "instance", c)
+				resp, err = &storegatewaypb.ExemplarsResponse{QueriedBlocks: req.BlockIds}, nil
+			}
+			if err != nil {
+				if shouldStopQueryFunc(err) {
+					return err
+				}
+
+				level.Warn(spanLog).Log("msg", "failed to fetch exemplars", "remote", c.RemoteAddress(), "err", err)
+				return nil
+			}
+
+			myQueriedBlocks := make([]ulid.ULID, 0, len(resp.QueriedBlocks))
+			for _, id := range resp.QueriedBlocks {
+				blockID, err := ulid.Parse(id)
+				if err != nil {
+					return errors.Wrapf(err, "failed to parse queried block IDs received from %s", c.RemoteAddress())
+				}
+				myQueriedBlocks = append(myQueriedBlocks, blockID)
+			}
+
+			level.Debug(spanLog).Log("msg", "received exemplars from store-gateway",
+				"instance", c,
+				"num series", len(resp.Timeseries),
+				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
+				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
+
+			// Store the result.
+			mtx.Lock()
+			sets = append(sets, resp.Timeseries)
+			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
+			mtx.Unlock()
+
+			return nil
+		})
+	}
+
+	// Wait until all client requests complete.
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return sets, queriedBlocks, nil
+}
+
 func (q *blocksStoreQuerier) fetchLabelValuesFromStore(
 	ctx context.Context,
 	name string,
@@ -938,45 +1262,55 @@ func (q *blocksStoreQuerier) fetchLabelValuesFromStore(
 				return errors.Wrapf(err, "failed to create label values request")
 			}
 
-			valuesResp, err := c.LabelValues(gCtx, req)
+			valuesResps, err := fetchLabelValuesFromStoreGateway(gCtx, c, req)
 			if err != nil {
-				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				if shouldStopQueryFunc(err) {
 					return err
 				}
+
 				level.Warn(spanLog).Log("msg", "failed to fetch label values", "remote", c.RemoteAddress(), "err", err)
 				return nil
 			}
 
+			myValues := []string(nil)
+			myWarnings := storage.Warnings(nil)
 			myQueriedBlocks := []ulid.ULID(nil)
-			if valuesResp.Hints != nil {
-				hints := hintspb.LabelValuesResponseHints{}
-				if err := types.UnmarshalAny(valuesResp.Hints, &hints); err != nil {
-					return errors.Wrapf(err, "failed to unmarshal label values hints from %s", c.RemoteAddress())
-				}
 
-				ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
-				if err != nil {
-					return errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+			// Label values may be split across multiple messages.
+			for _, valuesResp := range valuesResps {
+				myValues = append(myValues, valuesResp.Values...)
+				for _, w := range valuesResp.Warnings {
+					myWarnings = append(myWarnings, errors.New(w))
 				}
 
-				myQueriedBlocks = ids
+				if valuesResp.Hints != nil {
+					hints := hintspb.LabelValuesResponseHints{}
+					if err := types.UnmarshalAny(valuesResp.Hints, &hints); err != nil {
+						return errors.Wrapf(err, "failed to unmarshal label values hints from %s", c.RemoteAddress())
+					}
+
+					ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
+					if err != nil {
+						return errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+					}
+
+					myQueriedBlocks = append(myQueriedBlocks, ids...)
+				}
 			}
 
 			level.Debug(spanLog).Log("msg", "received label values from store-gateway",
 				"instance", c.RemoteAddress(),
-				"num values", len(valuesResp.Values),
+				"num values", len(myValues),
 				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
 				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
 
 			// Values returned need not be sorted, but we need them to be sorted so we can merge.
-			slices.Sort(valuesResp.Values)
+			slices.Sort(myValues)
 
 			// Store the result.
 			mtx.Lock()
-			valueSets = append(valueSets, valuesResp.Values)
-			for _, w := range valuesResp.Warnings {
-				warnings = append(warnings, errors.New(w))
-			}
+			valueSets = append(valueSets, myValues)
+			warnings = append(warnings, myWarnings...)
 			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
 			mtx.Unlock()
 
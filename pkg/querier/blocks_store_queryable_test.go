@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
@@ -32,6 +33,8 @@ import (
 	"github.com/weaveworks/common/user"
 	"golang.org/x/exp/slices"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/storage/sharding"
@@ -78,6 +81,7 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 		storeSetResponses []interface{}
 		limits            BlocksStoreLimits
 		queryLimiter      *limiter.QueryLimiter
+		memoryTracker     *limiter.MemoryTracker
 		expectedSeries    []seriesResult
 		expectedErr       error
 		expectedMetrics   string
@@ -558,6 +562,25 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 			queryLimiter: limiter.NewQueryLimiter(0, 8, 0),
 			expectedErr:  validation.LimitError(fmt.Sprintf(limiter.MaxChunkBytesHitMsgFormat, 8)),
 		},
+		"max estimated memory per query limit hit while fetching series": {
+			finderResult: bucketindex.Blocks{
+				{ID: block1},
+				{ID: block2},
+			},
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponse(series1Label, minT, 1),
+						mockSeriesResponse(series2Label, minT+1, 2),
+						mockHintsResponse(block1, block2),
+					}}: {block1, block2},
+				},
+			},
+			limits:        &blocksStoreLimitsMock{},
+			queryLimiter:  noOpQueryLimiter,
+			memoryTracker: limiter.NewMemoryTracker(32),
+			expectedErr:   validation.LimitError(fmt.Sprintf(limiter.MaxEstimatedMemoryPerQueryMsgFormat, 32)),
+		},
 		"blocks with non-matching shard are filtered out": {
 			finderResult: bucketindex.Blocks{
 				{ID: block1, CompactorShardID: "1_of_4"},
@@ -764,6 +787,9 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
 			ctx := limiter.AddQueryLimiterToContext(context.Background(), testData.queryLimiter)
+			if testData.memoryTracker != nil {
+				ctx = limiter.AddMemoryTrackerToContext(ctx, testData.memoryTracker)
+			}
 			reg := prometheus.NewPedanticRegistry()
 			stores := &blocksStoreSetMock{mockedResponses: testData.storeSetResponses}
 			finder := &blocksFinderMock{}
@@ -980,6 +1006,32 @@ func TestBlocksStoreQuerier_Labels(t *testing.T) {
 			expectedLabelNames:  namesFromSeries(series1, series2),
 			expectedLabelValues: valuesFromSeries(labels.MetricName, series1, series2),
 		},
+		"a single store-gateway instance holds the required blocks but doesn't support streaming label names and values": {
+			finderResult: bucketindex.Blocks{
+				{ID: block1},
+				{ID: block2},
+			},
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{
+						remoteAddr:                    "1.1.1.1",
+						mockedLabelsStreamUnsupported: true,
+						mockedLabelNamesResponse: &storepb.LabelNamesResponse{
+							Names:    namesFromSeries(series1, series2),
+							Warnings: []string{},
+							Hints:    mockNamesHints(block1, block2),
+						},
+						mockedLabelValuesResponse: &storepb.LabelValuesResponse{
+							Values:   valuesFromSeries(labels.MetricName, series1, series2),
+							Warnings: []string{},
+							Hints:    mockValuesHints(block1, block2),
+						},
+					}: {block1, block2},
+				},
+			},
+			expectedLabelNames:  namesFromSeries(series1, series2),
+			expectedLabelValues: valuesFromSeries(labels.MetricName, series1, series2),
+		},
 		"multiple store-gateway instances holds the required blocks without overlapping series": {
 			finderResult: bucketindex.Blocks{
 				{ID: block1},
@@ -1482,6 +1534,138 @@ func TestBlocksStoreQuerier_Labels(t *testing.T) {
 	})
 }
 
+func TestBlocksStoreQuerier_Exemplars(t *testing.T) {
+	const (
+		minT = int64(10)
+		maxT = int64(20)
+	)
+
+	var (
+		block1  = ulid.MustNew(1, nil)
+		block2  = ulid.MustNew(2, nil)
+		series1 = labels.FromStrings(labels.MetricName, "series_1")
+		series2 = labels.FromStrings(labels.MetricName, "series_2")
+	)
+
+	exemplarsSeries := func(lbls labels.Labels, timestamps ...int64) mimirpb.TimeSeries {
+		ts := mimirpb.TimeSeries{Labels: mimirpb.FromLabelsToLabelAdapters(lbls)}
+		for _, t := range timestamps {
+			ts.Exemplars = append(ts.Exemplars, mimirpb.Exemplar{Labels: []mimirpb.LabelAdapter{{Name: "trace_id", Value: "abc"}}, Value: float64(t), TimestampMs: t})
+		}
+		return ts
+	}
+
+	tests := map[string]struct {
+		storeSetResponses []interface{}
+		expectedSeries    []mimirpb.TimeSeries
+		expectedErr       string
+	}{
+		"a single store-gateway instance holds the required blocks": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{exemplarsSeries(series1, 10, 15), exemplarsSeries(series2, 20)},
+						QueriedBlocks: []string{block1.String(), block2.String()},
+					}}: {block1, block2},
+				},
+			},
+			expectedSeries: []mimirpb.TimeSeries{exemplarsSeries(series1, 10, 15), exemplarsSeries(series2, 20)},
+		},
+		"multiple store-gateway instances holds the required blocks with overlapping series": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{exemplarsSeries(series1, 10, 15)},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{exemplarsSeries(series1, 15, 18), exemplarsSeries(series2, 20)},
+						QueriedBlocks: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			expectedSeries: []mimirpb.TimeSeries{exemplarsSeries(series1, 10, 15, 18), exemplarsSeries(series2, 20)},
+		},
+		"a block is missing on the first attempt and found on the second one": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{exemplarsSeries(series1, 10)},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1, block2},
+				},
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{exemplarsSeries(series2, 20)},
+						QueriedBlocks: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			expectedSeries: []mimirpb.TimeSeries{exemplarsSeries(series1, 10), exemplarsSeries(series2, 20)},
+		},
+		"a store-gateway instance doesn't support exemplars": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Timeseries:    []mimirpb.TimeSeries{exemplarsSeries(series1, 10)},
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedExemplarsErr: status.Error(codes.Unimplemented, "unknown method Exemplars")}: {block2},
+				},
+			},
+			expectedSeries: []mimirpb.TimeSeries{exemplarsSeries(series1, 10)},
+		},
+		"a block is missing in all attempts": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						QueriedBlocks: []string{block1.String()},
+					}}: {block1, block2},
+				},
+				errors.New("no store-gateway remaining after exclude"),
+			},
+			expectedErr: newStoreConsistencyCheckFailedError([]ulid.ULID{block2}).Error(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}, {ID: block2}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+			q := &blocksStoreExemplarQuerier{querier: &blocksStoreQuerier{
+				ctx:         ctx,
+				userID:      "user-1",
+				finder:      finder,
+				stores:      &blocksStoreSetMock{mockedResponses: testData.storeSetResponses},
+				consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(prometheus.NewPedanticRegistry()),
+				limits:      &blocksStoreLimitsMock{},
+			}}
+
+			res, err := q.Select(minT, maxT, []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "series_.*")})
+			if testData.expectedErr != "" {
+				require.EqualError(t, err, testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			expected := make([]exemplar.QueryResult, 0, len(testData.expectedSeries))
+			for _, ts := range testData.expectedSeries {
+				expected = append(expected, exemplar.QueryResult{
+					SeriesLabels: mimirpb.FromLabelAdaptersToLabels(ts.Labels),
+					Exemplars:    mimirpb.FromExemplarProtosToExemplars(ts.Exemplars),
+				})
+			}
+			assert.Equal(t, expected, res)
+		})
+	}
+}
+
 func TestBlocksStoreQuerier_SelectSortedShouldHonorQueryStoreAfter(t *testing.T) {
 	now := time.Now()
 
@@ -1732,7 +1916,7 @@ func TestBlocksStoreQuerier_PromQLExecution(t *testing.T) {
 
 			// Instantiate the querier that will be executed to run the query.
 			logger := log.NewNopLogger()
-			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, logger, nil)
+			queryable, err := NewBlocksStoreQueryable(stores, finder, NewBlocksConsistencyChecker(0, 0, logger, nil), &blocksStoreLimitsMock{}, 0, false, logger, nil)
 			require.NoError(t, err)
 			require.NoError(t, services.StartAndAwaitRunning(context.Background(), queryable))
 			defer services.StopAndAwaitTerminated(context.Background(), queryable) // nolint:errcheck
@@ -1788,6 +1972,15 @@ func TestCanBlockWithCompactorShardIdContainQueryShard(t *testing.T) {
 	}
 }
 
+func TestFilterOutOfOrderBlocks(t *testing.T) {
+	block1 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 0, MaxTime: 100}
+	block2 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 0, MaxTime: 100, OutOfOrder: true}
+	block3 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 100, MaxTime: 200}
+
+	assert.Equal(t, bucketindex.Blocks{block1, block3}, filterOutOfOrderBlocks(bucketindex.Blocks{block1, block2, block3}))
+	assert.Empty(t, filterOutOfOrderBlocks(bucketindex.Blocks{block2}))
+}
+
 func TestFilterBlocksByShard(t *testing.T) {
 	block1 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 0, MaxTime: 100, CompactorShardID: "1_of_4"}
 	block2 := &bucketindex.Block{ID: ulid.MustNew(ulid.Now(), crand.Reader), MinTime: 0, MaxTime: 100, CompactorShardID: "2_of_4"}
@@ -1902,6 +2095,11 @@ type storeGatewayClientMock struct {
 	mockedLabelNamesErr       error
 	mockedLabelValuesResponse *storepb.LabelValuesResponse
 	mockedLabelValuesErr      error
+	mockedExemplarsResponse   *storegatewaypb.ExemplarsResponse
+
+	// mockedLabelsStreamUnsupported simulates a store-gateway which doesn't support streaming label names and values.
+	mockedLabelsStreamUnsupported bool
+	mockedExemplarsErr            error
 }
 
 func (m *storeGatewayClientMock) Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
@@ -1916,10 +2114,39 @@ func (m *storeGatewayClientMock) LabelNames(context.Context, *storepb.LabelNames
 	return m.mockedLabelNamesResponse, m.mockedLabelNamesErr
 }
 
+func (m *storeGatewayClientMock) LabelNamesStream(context.Context, *storepb.LabelNamesRequest, ...grpc.CallOption) (storegatewaypb.StoreGateway_LabelNamesStreamClient, error) {
+	namesClient := &storeGatewayLabelNamesClientMock{}
+	if m.mockedLabelsStreamUnsupported {
+		namesClient.mockedErr = status.Error(codes.Unimplemented, "unknown method LabelNamesStream")
+	} else if m.mockedLabelNamesResponse != nil {
+		namesClient.mockedResponses = []*storepb.LabelNamesResponse{m.mockedLabelNamesResponse}
+	}
+
+	return namesClient, m.mockedLabelNamesErr
+}
+
 func (m *storeGatewayClientMock) LabelValues(context.Context, *storepb.LabelValuesRequest, ...grpc.CallOption) (*storepb.LabelValuesResponse, error) {
 	return m.mockedLabelValuesResponse, m.mockedLabelValuesErr
 }
 
+func (m *storeGatewayClientMock) LabelValuesStream(context.Context, *storepb.LabelValuesRequest, ...grpc.CallOption) (storegatewaypb.StoreGateway_LabelValuesStreamClient, error) {
+	valuesClient := &storeGatewayLabelValuesClientMock{}
+	if m.mockedLabelsStreamUnsupported {
+		valuesClient.mockedErr = status.Error(codes.Unimplemented, "unknown method LabelValuesStream")
+	} else if m.mockedLabelValuesResponse != nil {
+		valuesClient.mockedResponses = []*storepb.LabelValuesResponse{m.mockedLabelValuesResponse}
+	}
+
+	return valuesClient, m.mockedLabelValuesErr
+}
+
+func (m *storeGatewayClientMock) Exemplars(context.Context, *storegatewaypb.ExemplarsRequest, ...grpc.CallOption) (*storegatewaypb.ExemplarsResponse, error) {
+	if m.mockedExemplarsResponse == nil {
+		return &storegatewaypb.ExemplarsResponse{}, m.mockedExemplarsErr
+	}
+	return m.mockedExemplarsResponse, m.mockedExemplarsErr
+}
+
 func (m *storeGatewayClientMock) RemoteAddress() string {
 	return m.remoteAddr
 }
@@ -1943,6 +2170,46 @@ func (m *storeGatewaySeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
 	return res, nil
 }
 
+type storeGatewayLabelNamesClientMock struct {
+	grpc.ClientStream
+
+	mockedResponses []*storepb.LabelNamesResponse
+	mockedErr       error
+}
+
+func (m *storeGatewayLabelNamesClientMock) Recv() (*storepb.LabelNamesResponse, error) {
+	if m.mockedErr != nil {
+		return nil, m.mockedErr
+	}
+	if len(m.mockedResponses) == 0 {
+		return nil, io.EOF
+	}
+
+	res := m.mockedResponses[0]
+	m.mockedResponses = m.mockedResponses[1:]
+	return res, nil
+}
+
+type storeGatewayLabelValuesClientMock struct {
+	grpc.ClientStream
+
+	mockedResponses []*storepb.LabelValuesResponse
+	mockedErr       error
+}
+
+func (m *storeGatewayLabelValuesClientMock) Recv() (*storepb.LabelValuesResponse, error) {
+	if m.mockedErr != nil {
+		return nil, m.mockedErr
+	}
+	if len(m.mockedResponses) == 0 {
+		return nil, io.EOF
+	}
+
+	res := m.mockedResponses[0]
+	m.mockedResponses = m.mockedResponses[1:]
+	return res, nil
+}
+
 type cancelerStoreGatewaySeriesClientMock struct {
 	storeGatewaySeriesClientMock
 	ctx    context.Context
@@ -1977,11 +2244,26 @@ func (m *cancelerStoreGatewayClientMock) LabelNames(ctx context.Context, _ *stor
 	return nil, ctx.Err()
 }
 
+func (m *cancelerStoreGatewayClientMock) LabelNamesStream(ctx context.Context, _ *storepb.LabelNamesRequest, _ ...grpc.CallOption) (storegatewaypb.StoreGateway_LabelNamesStreamClient, error) {
+	m.cancel()
+	return nil, ctx.Err()
+}
+
 func (m *cancelerStoreGatewayClientMock) LabelValues(ctx context.Context, _ *storepb.LabelValuesRequest, _ ...grpc.CallOption) (*storepb.LabelValuesResponse, error) {
 	m.cancel()
 	return nil, ctx.Err()
 }
 
+func (m *cancelerStoreGatewayClientMock) LabelValuesStream(ctx context.Context, _ *storepb.LabelValuesRequest, _ ...grpc.CallOption) (storegatewaypb.StoreGateway_LabelValuesStreamClient, error) {
+	m.cancel()
+	return nil, ctx.Err()
+}
+
+func (m *cancelerStoreGatewayClientMock) Exemplars(ctx context.Context, _ *storegatewaypb.ExemplarsRequest, _ ...grpc.CallOption) (*storegatewaypb.ExemplarsResponse, error) {
+	m.cancel()
+	return nil, ctx.Err()
+}
+
 func (m *cancelerStoreGatewayClientMock) RemoteAddress() string {
 	return m.remoteAddr
 }
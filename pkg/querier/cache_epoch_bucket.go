@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
+)
+
+// cacheEpochBucket is a bucket client which versions the cache keys of the tenants' objects with the tenant
+// cache epoch, so that the metadata cache entries invalidated with the store-gateway caches invalidation API
+// are no longer used. It expects the object names to be prefixed by the tenant ID.
+//
+// The cache epoch of a tenant is read from the bucket the first time the tenant's objects are accessed,
+// and read again once older than the refresh interval.
+type cacheEpochBucket struct {
+	objstore.Bucket
+
+	epochs *tenantCacheEpochs
+}
+
+// newCacheEpochBucket returns a cacheEpochBucket wrapping the caching bucket cachingBkt. The cache
+// epochs are read from bkt, which is expected to not be cached.
+func newCacheEpochBucket(cachingBkt objstore.Bucket, bkt objstore.BucketReader, refreshInterval time.Duration, logger log.Logger) objstore.InstrumentedBucket {
+	return &cacheEpochBucket{
+		Bucket: cachingBkt,
+		epochs: &tenantCacheEpochs{
+			bkt:             bkt,
+			refreshInterval: refreshInterval,
+			logger:          logger,
+			epochs:          map[string]*tenantCacheEpoch{},
+		},
+	}
+}
+
+func (b *cacheEpochBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	return b.Bucket.Iter(b.withCacheKeyEpoch(ctx, dir), dir, f, options...)
+}
+
+func (b *cacheEpochBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.Bucket.Get(b.withCacheKeyEpoch(ctx, name), name)
+}
+
+func (b *cacheEpochBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	return b.Bucket.GetRange(b.withCacheKeyEpoch(ctx, name), name, off, length)
+}
+
+func (b *cacheEpochBucket) Exists(ctx context.Context, name string) (bool, error) {
+	return b.Bucket.Exists(b.withCacheKeyEpoch(ctx, name), name)
+}
+
+func (b *cacheEpochBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	return b.Bucket.Attributes(b.withCacheKeyEpoch(ctx, name), name)
+}
+
+func (b *cacheEpochBucket) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+func (b *cacheEpochBucket) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	if ib, ok := b.Bucket.(objstore.InstrumentedBucket); ok {
+		return &cacheEpochBucket{Bucket: ib.WithExpectedErrs(fn), epochs: b.epochs}
+	}
+	return b
+}
+
+// withCacheKeyEpoch returns a new context versioning the cache keys of the object with the given name
+// with the cache epoch of its tenant. The objects not belonging to a tenant are not versioned.
+func (b *cacheEpochBucket) withCacheKeyEpoch(ctx context.Context, name string) context.Context {
+	userID, objectName, ok := strings.Cut(name, "/")
+	if !ok || userID == "" {
+		return ctx
+	}
+
+	epoch := b.epochs.get(ctx, userID)
+	return bucketcache.WithCacheKeyEpoch(ctx, epoch.ObjectKeyEpoch(objectName))
+}
+
+// tenantCacheEpochs holds the cache epochs read from the bucket, by tenant.
+type tenantCacheEpochs struct {
+	bkt             objstore.BucketReader
+	refreshInterval time.Duration
+	logger          log.Logger
+
+	mtx    sync.Mutex
+	epochs map[string]*tenantCacheEpoch
+}
+
+type tenantCacheEpoch struct {
+	mtx    sync.Mutex
+	epoch  *mimir_tsdb.CacheEpoch
+	readAt time.Time
+}
+
+// get returns the cache epoch of the given tenant, reading it from the bucket if it has never been read
+// or it's older than the refresh interval. If the cache epoch can't be read, the previous one is returned.
+func (e *tenantCacheEpochs) get(ctx context.Context, userID string) *mimir_tsdb.CacheEpoch {
+	e.mtx.Lock()
+	entry := e.epochs[userID]
+	if entry == nil {
+		entry = &tenantCacheEpoch{epoch: &mimir_tsdb.CacheEpoch{}}
+		e.epochs[userID] = entry
+	}
+	e.mtx.Unlock()
+
+	entry.mtx.Lock()
+	defer entry.mtx.Unlock()
+
+	if !entry.readAt.IsZero() && time.Since(entry.readAt) < e.refreshInterval {
+		return entry.epoch
+	}
+
+	// The read time is updated even if the read fails, so that the read isn't retried on every request.
+	entry.readAt = time.Now()
+
+	epoch, err := mimir_tsdb.ReadCacheEpoch(ctx, e.bkt, userID)
+	if err != nil {
+		level.Warn(e.logger).Log("msg", "failed to read the cache epoch", "user", userID, "err", err)
+		return entry.epoch
+	}
+
+	entry.epoch = epoch
+	return entry.epoch
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
+)
+
+func TestCacheEpochBucket(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	blockID := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0")
+	metaFile := userID + "/" + blockID.String() + "/meta.json"
+	indexFile := userID + "/bucket-index.json.gz"
+
+	bkt := objstore.NewInMemBucket()
+	require.NoError(t, bkt.Upload(ctx, metaFile, bytes.NewReader([]byte("meta v1"))))
+	require.NoError(t, bkt.Upload(ctx, indexFile, bytes.NewReader([]byte("index v1"))))
+
+	cfg := bucketcache.NewCachingBucketConfig()
+	cfg.CacheGet("metafile", cache.NewMockCache(), func(string) bool { return true }, 1024, time.Hour, time.Hour, time.Hour)
+	cachingBkt, err := bucketcache.NewCachingBucket(bkt, cfg, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	const refreshInterval = 100 * time.Millisecond
+	epochBkt := newCacheEpochBucket(cachingBkt, bkt, refreshInterval, log.NewNopLogger())
+
+	// Populate the cache.
+	assertGet(t, epochBkt, metaFile, "meta v1")
+	assertGet(t, epochBkt, indexFile, "index v1")
+
+	require.NoError(t, bkt.Upload(ctx, metaFile, bytes.NewReader([]byte("meta v2"))))
+	require.NoError(t, bkt.Upload(ctx, indexFile, bytes.NewReader([]byte("index v2"))))
+
+	// The cached entries are used until the caches are invalidated.
+	assertGet(t, epochBkt, metaFile, "meta v1")
+	assertGet(t, epochBkt, indexFile, "index v1")
+
+	// Invalidating the caches of the block doesn't invalidate the tenant objects not belonging to the block.
+	_, err = mimir_tsdb.BumpCacheEpoch(ctx, bkt, userID, nil, &blockID)
+	require.NoError(t, err)
+	time.Sleep(refreshInterval)
+	assertGet(t, epochBkt, metaFile, "meta v2")
+	assertGet(t, epochBkt, indexFile, "index v1")
+
+	// Invalidating the caches of the tenant invalidates all the tenant objects.
+	_, err = mimir_tsdb.BumpCacheEpoch(ctx, bkt, userID, nil, nil)
+	require.NoError(t, err)
+	time.Sleep(refreshInterval)
+	assertGet(t, epochBkt, indexFile, "index v2")
+}
+
+func assertGet(t *testing.T, bkt objstore.BucketReader, name, expected string) {
+	r, err := bkt.Get(context.Background(), name)
+	require.NoError(t, err)
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
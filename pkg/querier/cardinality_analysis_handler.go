@@ -3,17 +3,22 @@
 package querier
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
 
+	"github.com/grafana/dskit/concurrency"
 	"github.com/grafana/dskit/tenant"
 
 	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
@@ -26,105 +31,327 @@ const (
 	minLimit     = 0
 	maxLimit     = 500
 	defaultLimit = 20
+
+	// maxFederatedCardinalityConcurrency is the max number of tenants queried concurrently
+	// by a federated cardinality analysis request.
+	maxFederatedCardinalityConcurrency = 16
+
+	sortBySeriesCount      = "series_count"
+	sortByLabelValuesCount = "label_values_count"
+	sortByLabelName        = "label_name"
+	sortByLabelValue       = "label_value"
+
+	sortOrderAsc  = "asc"
+	sortOrderDesc = "desc"
+)
+
+var (
+	errCardinalityFederationDisabled              = errors.New("the cardinality of multiple tenants can't be queried because the tenant federation is disabled")
+	errLabelNamesCardinalityFederationUnsupported = errors.New("the label names cardinality of multiple tenants can't be queried, query a single tenant")
 )
 
+// cardinalityPage is the page of the sorted cardinality items returned in the response.
+type cardinalityPage struct {
+	offset int
+	limit  int
+
+	// sortBy is the field the items are sorted by. Items with the same value
+	// of the field are sorted by name in ASC order.
+	sortBy    string
+	sortOrder string
+}
+
+// paginate returns the items of the input page. The input items must be already sorted.
+func paginate[T any](items []T, page cardinalityPage) []T {
+	if page.offset >= len(items) {
+		return items[:0]
+	}
+	items = items[page.offset:]
+	if len(items) > page.limit {
+		items = items[:page.limit]
+	}
+	return items
+}
+
 // LabelNamesCardinalityHandler creates handler for label names cardinality endpoint.
-func LabelNamesCardinalityHandler(d Distributor, limits *validation.Overrides) http.Handler {
+// When the request has a time range, the cardinality is computed from the series queried through
+// the queryable, otherwise from the series in the ingesters. Requests federated across multiple
+// tenants are rejected.
+func LabelNamesCardinalityHandler(d Distributor, queryable storage.Queryable, limits *validation.Overrides) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		tenantID, err := tenant.TenantID(ctx)
+		tenantIDs, err := tenant.TenantIDs(ctx)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if len(tenantIDs) > 1 {
+			http.Error(w, errLabelNamesCardinalityFederationUnsupported.Error(), http.StatusBadRequest)
+			return
+		}
+		tenantID := tenantIDs[0]
 		if !limits.CardinalityAnalysisEnabled(tenantID) {
 			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
 			return
 		}
-		matchers, limit, err := extractLabelNamesRequestParams(r)
+		matchers, page, tr, err := extractLabelNamesRequestParams(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		response, err := d.LabelNamesAndValues(ctx, matchers)
+
+		var response *ingester_client.LabelNamesAndValuesResponse
+		if tr != nil {
+			response, err = queryableLabelNamesAndValues(ctx, queryable, tr, matchers)
+		} else {
+			response, err = d.LabelNamesAndValues(ctx, matchers)
+		}
 		if err != nil {
 			respondFromError(err, w)
 			return
 		}
-		cardinalityResponse := toLabelNamesCardinalityResponse(response, limit)
+
+		// The series count of each label name is expensive to compute, so it's computed only when required to sort the label names.
+		var seriesCounts map[string]uint64
+		if page.sortBy == sortBySeriesCount {
+			batchSize := limits.LabelValuesMaxCardinalityLabelNamesPerRequest(tenantID)
+			seriesCounts, err = labelNamesSeriesCount(ctx, newLabelValuesCardinalityFunc(d, queryable, limits, tr), response.Items, matchers, batchSize)
+			if err != nil {
+				respondFromError(err, w)
+				return
+			}
+		}
+
+		cardinalityResponse := toLabelNamesCardinalityResponse(response, seriesCounts, page)
 		util.WriteJSONResponse(w, cardinalityResponse)
 	})
 }
 
 // LabelValuesCardinalityHandler creates handler for label values cardinality endpoint.
-func LabelValuesCardinalityHandler(distributor Distributor, limits *validation.Overrides) http.Handler {
+// When the request is federated across multiple tenants, which requires the tenant federation to be enabled,
+// the cardinality of each tenant is merged in the response, and a per-tenant breakdown is returned too.
+// When the request has a time range, the cardinality is computed from the series queried through the queryable,
+// otherwise from the series in the ingesters.
+func LabelValuesCardinalityHandler(distributor Distributor, queryable storage.Queryable, limits *validation.Overrides, tenantFederationEnabled bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		// Guarantee request's context is for a single tenant id
-		tenantID, err := tenant.TenantID(ctx)
+		tenantIDs, err := tenant.TenantIDs(ctx)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if !limits.CardinalityAnalysisEnabled(tenantID) {
-			http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
+		if len(tenantIDs) > 1 && !tenantFederationEnabled {
+			http.Error(w, errCardinalityFederationDisabled.Error(), http.StatusBadRequest)
 			return
 		}
+		for _, tenantID := range tenantIDs {
+			if !limits.CardinalityAnalysisEnabled(tenantID) {
+				http.Error(w, fmt.Sprintf("cardinality analysis is disabled for the tenant: %v", tenantID), http.StatusBadRequest)
+				return
+			}
+		}
 
-		labelNames, matchers, limit, err := extractLabelValuesRequestParams(r)
+		labelNames, matchers, page, tr, err := extractLabelValuesRequestParams(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		seriesCountTotal, cardinalityResponse, err := distributor.LabelValuesCardinality(ctx, labelNames, matchers)
+		cardinality := newLabelValuesCardinalityFunc(distributor, queryable, limits, tr)
+
+		if len(tenantIDs) > 1 {
+			response, err := federatedLabelValuesCardinality(ctx, cardinality, tenantIDs, labelNames, matchers, page)
+			if err != nil {
+				respondFromError(err, w)
+				return
+			}
+
+			util.WriteJSONResponse(w, response)
+			return
+		}
+
+		seriesCountTotal, cardinalityResponse, err := cardinality(ctx, labelNames, matchers)
 		if err != nil {
 			respondFromError(err, w)
 			return
 		}
 
-		util.WriteJSONResponse(w, toLabelValuesCardinalityResponse(seriesCountTotal, cardinalityResponse, limit))
+		util.WriteJSONResponse(w, toLabelValuesCardinalityResponse(seriesCountTotal, cardinalityResponse, page))
 	})
 }
 
-func extractLabelNamesRequestParams(r *http.Request) ([]*labels.Matcher, int, error) {
+// labelValuesCardinalityFunc returns the total number of series, and the number of series for each
+// value of the input label names.
+type labelValuesCardinalityFunc func(ctx context.Context, labelNames []model.LabelName, matchers []*labels.Matcher) (uint64, *ingester_client.LabelValuesCardinalityResponse, error)
+
+// newLabelValuesCardinalityFunc returns a labelValuesCardinalityFunc computing the cardinality from the series
+// queried through the queryable within the input time range if set, otherwise from the series in the ingesters.
+// The queryable is queried enforcing the tenant's limits on the label names per request and on the series
+// fetched per query, which are otherwise enforced by the distributor and the ingesters.
+func newLabelValuesCardinalityFunc(d Distributor, queryable storage.Queryable, limits *validation.Overrides, tr *cardinalityTimeRange) labelValuesCardinalityFunc {
+	if tr == nil {
+		return d.LabelValuesCardinality
+	}
+	return func(ctx context.Context, labelNames []model.LabelName, matchers []*labels.Matcher) (uint64, *ingester_client.LabelValuesCardinalityResponse, error) {
+		tenantID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if lbNamesLimit := limits.LabelValuesMaxCardinalityLabelNamesPerRequest(tenantID); len(labelNames) > lbNamesLimit {
+			return 0, nil, httpgrpc.Errorf(http.StatusBadRequest, "label values cardinality request label names limit (limit: %d actual: %d) exceeded", lbNamesLimit, len(labelNames))
+		}
+		return queryableLabelValuesCardinality(ctx, queryable, tr, labelNames, matchers, limits.MaxFetchedSeriesPerQuery(tenantID))
+	}
+}
+
+// labelNamesSeriesCount returns the number of series having each of the input label names.
+// Since a series has at most one value for each label name, it's the sum of the series count of the label values.
+// The label names are queried in batches of at most batchSize names (0 for no limit), to not exceed the limit
+// of label names per label values cardinality request.
+func labelNamesSeriesCount(ctx context.Context, cardinality labelValuesCardinalityFunc, labelsWithValues []*ingester_client.LabelValues, matchers []*labels.Matcher, batchSize int) (map[string]uint64, error) {
+	if len(labelsWithValues) == 0 {
+		return nil, nil
+	}
+	if batchSize <= 0 {
+		batchSize = len(labelsWithValues)
+	}
+
+	seriesCounts := make(map[string]uint64, len(labelsWithValues))
+	for start := 0; start < len(labelsWithValues); start += batchSize {
+		labelNames := make([]model.LabelName, 0, batchSize)
+		for _, item := range labelsWithValues[start:util_math.Min(start+batchSize, len(labelsWithValues))] {
+			labelNames = append(labelNames, model.LabelName(item.LabelName))
+		}
+
+		_, cardinalityResponse, err := cardinality(ctx, labelNames, matchers)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range cardinalityResponse.Items {
+			for _, seriesCount := range item.LabelValueSeries {
+				seriesCounts[item.LabelName] += seriesCount
+			}
+		}
+	}
+	return seriesCounts, nil
+}
+
+// federatedLabelValuesCardinality queries the label values cardinality of each input tenant and
+// merges the results. Tenants have disjoint series, so the series counts are summed up.
+func federatedLabelValuesCardinality(ctx context.Context, cardinality labelValuesCardinalityFunc, tenantIDs []string, labelNames []model.LabelName, matchers []*labels.Matcher, page cardinalityPage) (*labelValuesCardinalityResponse, error) {
+	seriesCountTotals := make([]uint64, len(tenantIDs))
+	cardinalityResponses := make([]*ingester_client.LabelValuesCardinalityResponse, len(tenantIDs))
+
+	err := concurrency.ForEachJob(ctx, len(tenantIDs), maxFederatedCardinalityConcurrency, func(ctx context.Context, idx int) error {
+		seriesCountTotal, cardinalityResponse, err := cardinality(user.InjectOrgID(ctx, tenantIDs[idx]), labelNames, matchers)
+		if err != nil {
+			return err
+		}
+
+		seriesCountTotals[idx] = seriesCountTotal
+		cardinalityResponses[idx] = cardinalityResponse
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mergedSeriesCountTotal uint64
+		tenants                = make([]tenantLabelValuesCardinality, 0, len(tenantIDs))
+	)
+	for idx, tenantID := range tenantIDs {
+		mergedSeriesCountTotal += seriesCountTotals[idx]
+
+		tenantResponse := toLabelValuesCardinalityResponse(seriesCountTotals[idx], cardinalityResponses[idx], page)
+		tenants = append(tenants, tenantLabelValuesCardinality{
+			TenantID:         tenantID,
+			SeriesCountTotal: tenantResponse.SeriesCountTotal,
+			Labels:           tenantResponse.Labels,
+		})
+	}
+
+	response := toLabelValuesCardinalityResponse(mergedSeriesCountTotal, mergeLabelValuesCardinalityResponses(cardinalityResponses), page)
+	response.Tenants = tenants
+	return response, nil
+}
+
+// mergeLabelValuesCardinalityResponses merges the input responses, summing up the series count
+// of the same label value across responses.
+func mergeLabelValuesCardinalityResponses(responses []*ingester_client.LabelValuesCardinalityResponse) *ingester_client.LabelValuesCardinalityResponse {
+	merged := &ingester_client.LabelValuesCardinalityResponse{}
+	itemsByLabelName := map[string]*ingester_client.LabelValueSeriesCount{}
+
+	for _, response := range responses {
+		for _, item := range response.Items {
+			mergedItem, ok := itemsByLabelName[item.LabelName]
+			if !ok {
+				mergedItem = &ingester_client.LabelValueSeriesCount{
+					LabelName:        item.LabelName,
+					LabelValueSeries: make(map[string]uint64, len(item.LabelValueSeries)),
+				}
+				itemsByLabelName[item.LabelName] = mergedItem
+				merged.Items = append(merged.Items, mergedItem)
+			}
+
+			for labelValue, seriesCount := range item.LabelValueSeries {
+				mergedItem.LabelValueSeries[labelValue] += seriesCount
+			}
+		}
+	}
+
+	return merged
+}
+
+func extractLabelNamesRequestParams(r *http.Request) ([]*labels.Matcher, cardinalityPage, *cardinalityTimeRange, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return nil, 0, err
+		return nil, cardinalityPage{}, nil, err
 	}
 	matchers, err := extractSelector(r)
 	if err != nil {
-		return nil, 0, err
+		return nil, cardinalityPage{}, nil, err
 	}
-	limit, err := extractLimit(r)
+	page, err := extractPage(r, sortByLabelValuesCount, sortByLabelValuesCount, sortBySeriesCount, sortByLabelName)
 	if err != nil {
-		return nil, 0, err
+		return nil, cardinalityPage{}, nil, err
 	}
-	return matchers, limit, nil
+	tr, err := extractTimeRange(r)
+	if err != nil {
+		return nil, cardinalityPage{}, nil, err
+	}
+	return matchers, page, tr, nil
 }
 
 // extractLabelValuesRequestParams parses query params from GET requests and parses request body from POST requests
-func extractLabelValuesRequestParams(r *http.Request) (labelNames []model.LabelName, matchers []*labels.Matcher, limit int, err error) {
+func extractLabelValuesRequestParams(r *http.Request) (labelNames []model.LabelName, matchers []*labels.Matcher, page cardinalityPage, tr *cardinalityTimeRange, err error) {
 	if err := r.ParseForm(); err != nil {
-		return nil, nil, 0, err
+		return nil, nil, cardinalityPage{}, nil, err
 	}
 
 	labelNames, err = extractLabelNames(r)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, nil, cardinalityPage{}, nil, err
 	}
 
 	matchers, err = extractSelector(r)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, nil, cardinalityPage{}, nil, err
+	}
+
+	page, err = extractPage(r, sortBySeriesCount, sortBySeriesCount, sortByLabelValue)
+	if err != nil {
+		return nil, nil, cardinalityPage{}, nil, err
 	}
 
-	limit, err = extractLimit(r)
+	tr, err = extractTimeRange(r)
 	if err != nil {
-		return nil, nil, 0, err
+		return nil, nil, cardinalityPage{}, nil, err
 	}
 
-	return labelNames, matchers, limit, nil
+	return labelNames, matchers, page, tr, nil
 }
 
 // extractSelector parses and gets selector query parameter containing a single matcher
@@ -161,6 +388,71 @@ func extractLimit(r *http.Request) (limit int, err error) {
 	return limit, nil
 }
 
+// extractPage parses and validates the request params `limit`, `offset`, `sort_by` and `sort_order`.
+// The items are sorted by defaultSortBy when `sort_by` is not set. The default sort order is DESC
+// when sorting by a count, and ASC when sorting by a name.
+func extractPage(r *http.Request, defaultSortBy string, allowedSortBy ...string) (cardinalityPage, error) {
+	limit, err := extractLimit(r)
+	if err != nil {
+		return cardinalityPage{}, err
+	}
+
+	offset, err := extractOffset(r)
+	if err != nil {
+		return cardinalityPage{}, err
+	}
+
+	page := cardinalityPage{offset: offset, limit: limit, sortBy: defaultSortBy}
+
+	sortByParams := r.Form["sort_by"]
+	if len(sortByParams) > 1 {
+		return cardinalityPage{}, fmt.Errorf("multiple 'sort_by' params are not allowed")
+	}
+	if len(sortByParams) == 1 {
+		if !util.StringsContain(allowedSortBy, sortByParams[0]) {
+			return cardinalityPage{}, fmt.Errorf("invalid 'sort_by' param '%v', supported values are: %s", sortByParams[0], strings.Join(allowedSortBy, ", "))
+		}
+		page.sortBy = sortByParams[0]
+	}
+
+	page.sortOrder = sortOrderDesc
+	if page.sortBy == sortByLabelName || page.sortBy == sortByLabelValue {
+		page.sortOrder = sortOrderAsc
+	}
+
+	sortOrderParams := r.Form["sort_order"]
+	if len(sortOrderParams) > 1 {
+		return cardinalityPage{}, fmt.Errorf("multiple 'sort_order' params are not allowed")
+	}
+	if len(sortOrderParams) == 1 {
+		if sortOrderParams[0] != sortOrderAsc && sortOrderParams[0] != sortOrderDesc {
+			return cardinalityPage{}, fmt.Errorf("invalid 'sort_order' param '%v', supported values are: %s, %s", sortOrderParams[0], sortOrderAsc, sortOrderDesc)
+		}
+		page.sortOrder = sortOrderParams[0]
+	}
+
+	return page, nil
+}
+
+// extractOffset parses and validates request param `offset` if it's defined, otherwise returns 0.
+func extractOffset(r *http.Request) (int, error) {
+	offsetParams := r.Form["offset"]
+	if len(offsetParams) == 0 {
+		return 0, nil
+	}
+	if len(offsetParams) > 1 {
+		return 0, fmt.Errorf("multiple 'offset' params are not allowed")
+	}
+	offset, err := strconv.Atoi(offsetParams[0])
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("'offset' param cannot be less than '0'")
+	}
+	return offset, nil
+}
+
 // extractLabelNames parses and gets label_names query parameter containing an array of label values
 func extractLabelNames(r *http.Request) ([]model.LabelName, error) {
 	labelNamesParams := r.Form["label_names[]"]
@@ -190,28 +482,59 @@ func respondFromError(err error, w http.ResponseWriter) {
 	w.Write(httpResp.Body) //nolint
 }
 
-// toLabelNamesCardinalityResponse converts ingester's response to LabelNamesCardinalityResponse
-func toLabelNamesCardinalityResponse(response *ingester_client.LabelNamesAndValuesResponse, limit int) *LabelNamesCardinalityResponse {
-	labelsWithValues := response.Items
-	sortByValuesCountAndName(labelsWithValues)
-	valuesCountTotal := getValuesCountTotal(labelsWithValues)
-	items := make([]*LabelNamesCardinalityItem, util_math.Min(len(labelsWithValues), limit))
-	for i := 0; i < len(items); i++ {
-		items[i] = &LabelNamesCardinalityItem{LabelName: labelsWithValues[i].LabelName, LabelValuesCount: len(labelsWithValues[i].Values)}
+// toLabelNamesCardinalityResponse converts ingester's response to LabelNamesCardinalityResponse. The input
+// series counts are set only when sorting by series count.
+func toLabelNamesCardinalityResponse(response *ingester_client.LabelNamesAndValuesResponse, seriesCounts map[string]uint64, page cardinalityPage) *LabelNamesCardinalityResponse {
+	items := make([]*LabelNamesCardinalityItem, 0, len(response.Items))
+	for _, item := range response.Items {
+		items = append(items, &LabelNamesCardinalityItem{
+			LabelName:        item.LabelName,
+			LabelValuesCount: len(item.Values),
+			SeriesCount:      seriesCounts[item.LabelName],
+		})
 	}
+
 	return &LabelNamesCardinalityResponse{
-		LabelValuesCountTotal: valuesCountTotal,
+		LabelValuesCountTotal: getValuesCountTotal(response.Items),
 		LabelNamesCount:       len(response.Items),
-		Cardinality:           items,
+		Cardinality:           paginate(sortLabelNamesCardinalityItems(items, page), page),
 	}
 }
 
-func sortByValuesCountAndName(labelsWithValues []*ingester_client.LabelValues) {
-	sort.Slice(labelsWithValues, func(i, j int) bool {
-		left := labelsWithValues[i]
-		right := labelsWithValues[j]
-		return len(left.Values) > len(right.Values) || (len(left.Values) == len(right.Values) && left.LabelName < right.LabelName)
+// sortLabelNamesCardinalityItems sorts the items by the page sort field and order, and by LabelName in ASC order.
+func sortLabelNamesCardinalityItems(items []*LabelNamesCardinalityItem, page cardinalityPage) []*LabelNamesCardinalityItem {
+	sort.Slice(items, func(i, j int) bool {
+		left, right := items[i], items[j]
+		switch page.sortBy {
+		case sortByLabelValuesCount:
+			return lessByCountAndName(uint64(left.LabelValuesCount), uint64(right.LabelValuesCount), left.LabelName, right.LabelName, page.sortOrder)
+		case sortBySeriesCount:
+			return lessByCountAndName(left.SeriesCount, right.SeriesCount, left.LabelName, right.LabelName, page.sortOrder)
+		default:
+			return lessByName(left.LabelName, right.LabelName, page.sortOrder)
+		}
 	})
+	return items
+}
+
+// lessByCountAndName returns whether the left item sorts before the right one, by count in the input
+// sort order and by name in ASC order.
+func lessByCountAndName(leftCount, rightCount uint64, leftName, rightName, sortOrder string) bool {
+	if leftCount != rightCount {
+		if sortOrder == sortOrderAsc {
+			return leftCount < rightCount
+		}
+		return leftCount > rightCount
+	}
+	return leftName < rightName
+}
+
+// lessByName returns whether the left item sorts before the right one, by name in the input sort order.
+func lessByName(leftName, rightName, sortOrder string) bool {
+	if sortOrder == sortOrderDesc {
+		return leftName > rightName
+	}
+	return leftName < rightName
 }
 
 func getValuesCountTotal(labelsWithValues []*ingester_client.LabelValues) int {
@@ -231,9 +554,12 @@ type LabelNamesCardinalityResponse struct {
 type LabelNamesCardinalityItem struct {
 	LabelName        string `json:"label_name"`
 	LabelValuesCount int    `json:"label_values_count"`
+
+	// SeriesCount is the number of series having the label name. It's set only when sorting by series count.
+	SeriesCount uint64 `json:"series_count,omitempty"`
 }
 
-func toLabelValuesCardinalityResponse(seriesCountTotal uint64, cardinalityResponse *ingester_client.LabelValuesCardinalityResponse, limit int) *labelValuesCardinalityResponse {
+func toLabelValuesCardinalityResponse(seriesCountTotal uint64, cardinalityResponse *ingester_client.LabelValuesCardinalityResponse, page cardinalityPage) *labelValuesCardinalityResponse {
 	labels := make([]labelNamesCardinality, 0, len(cardinalityResponse.Items))
 
 	for _, cardinalityItem := range cardinalityResponse.Items {
@@ -252,7 +578,7 @@ func toLabelValuesCardinalityResponse(seriesCountTotal uint64, cardinalityRespon
 			LabelName:        cardinalityItem.LabelName,
 			LabelValuesCount: uint64(len(cardinalityItem.LabelValueSeries)),
 			SeriesCount:      labelValuesSeriesCountTotal,
-			Cardinality:      limitLabelValuesCardinality(sortBySeriesCountAndLabelValue(cardinality), limit),
+			Cardinality:      paginate(sortLabelValuesCardinality(cardinality, page), page),
 		})
 	}
 
@@ -273,24 +599,20 @@ func sortByLabelValuesSeriesCountAndLabelName(labelNamesCardinality []labelNames
 	return labelNamesCardinality
 }
 
-// sortBySeriesCountAndLabelValue sorts labelValuesCardinality array in DESC order by SeriesCount and
-// ASC order by LabelValue
-func sortBySeriesCountAndLabelValue(labelValuesCardinality []labelValuesCardinality) []labelValuesCardinality {
+// sortLabelValuesCardinality sorts labelValuesCardinality array by the page sort field and order, and
+// by LabelValue in ASC order.
+func sortLabelValuesCardinality(labelValuesCardinality []labelValuesCardinality, page cardinalityPage) []labelValuesCardinality {
 	sort.Slice(labelValuesCardinality, func(l, r int) bool {
 		left := labelValuesCardinality[l]
 		right := labelValuesCardinality[r]
-		return left.SeriesCount > right.SeriesCount || (left.SeriesCount == right.SeriesCount && left.LabelValue < right.LabelValue)
+		if page.sortBy == sortByLabelValue {
+			return lessByName(left.LabelValue, right.LabelValue, page.sortOrder)
+		}
+		return lessByCountAndName(left.SeriesCount, right.SeriesCount, left.LabelValue, right.LabelValue, page.sortOrder)
 	})
 	return labelValuesCardinality
 }
 
-func limitLabelValuesCardinality(labelValuesCardinality []labelValuesCardinality, limit int) []labelValuesCardinality {
-	if len(labelValuesCardinality) <= limit {
-		return labelValuesCardinality
-	}
-	return labelValuesCardinality[:limit]
-}
-
 type labelValuesCardinality struct {
 	LabelValue  string `json:"label_value"`
 	SeriesCount uint64 `json:"series_count"`
@@ -306,4 +628,13 @@ type labelNamesCardinality struct {
 type labelValuesCardinalityResponse struct {
 	SeriesCountTotal uint64                  `json:"series_count_total"`
 	Labels           []labelNamesCardinality `json:"labels"`
+
+	// Tenants is the per-tenant breakdown of a federated request.
+	Tenants []tenantLabelValuesCardinality `json:"tenants,omitempty"`
+}
+
+type tenantLabelValuesCardinality struct {
+	TenantID         string                  `json:"tenant_id"`
+	SeriesCountTotal uint64                  `json:"series_count_total"`
+	Labels           []labelNamesCardinality `json:"labels"`
 }
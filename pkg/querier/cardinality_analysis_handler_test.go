@@ -14,8 +14,10 @@ import (
 	"testing"
 
 	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/tenant"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/httpgrpc"
@@ -178,6 +180,147 @@ func TestLabelNamesCardinalityHandler_LimitTest(t *testing.T) {
 	}
 }
 
+func TestLabelNamesCardinalityHandler_PaginationAndSorting(t *testing.T) {
+	items := []*client.LabelValues{
+		{LabelName: "label-c", Values: []string{"0c"}},
+		{LabelName: "label-b", Values: []string{"0b", "1b"}},
+		{LabelName: "label-a", Values: []string{"0a", "1a"}},
+		{LabelName: "label-z", Values: []string{"0z", "1z", "2z"}},
+	}
+	labelValuesCardinality := &client.LabelValuesCardinalityResponse{
+		Items: []*client.LabelValueSeriesCount{
+			{LabelName: "label-c", LabelValueSeries: map[string]uint64{"0c": 50}},
+			{LabelName: "label-b", LabelValueSeries: map[string]uint64{"0b": 10, "1b": 30}},
+			{LabelName: "label-a", LabelValueSeries: map[string]uint64{"0a": 1, "1a": 1}},
+			{LabelName: "label-z", LabelValueSeries: map[string]uint64{"0z": 10, "1z": 20, "2z": 10}},
+		},
+	}
+
+	tests := map[string]struct {
+		params        string
+		expectedItems []*LabelNamesCardinalityItem
+	}{
+		"offset": {
+			params: "offset=1&limit=2",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-a", LabelValuesCount: 2},
+				{LabelName: "label-b", LabelValuesCount: 2},
+			},
+		},
+		"offset greater than the number of items": {
+			params:        "offset=10",
+			expectedItems: []*LabelNamesCardinalityItem{},
+		},
+		"sort by label values count in ASC order": {
+			params: "sort_order=asc",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-c", LabelValuesCount: 1},
+				{LabelName: "label-a", LabelValuesCount: 2},
+				{LabelName: "label-b", LabelValuesCount: 2},
+				{LabelName: "label-z", LabelValuesCount: 3},
+			},
+		},
+		"sort by label name": {
+			params: "sort_by=label_name",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-a", LabelValuesCount: 2},
+				{LabelName: "label-b", LabelValuesCount: 2},
+				{LabelName: "label-c", LabelValuesCount: 1},
+				{LabelName: "label-z", LabelValuesCount: 3},
+			},
+		},
+		"sort by label name in DESC order": {
+			params: "sort_by=label_name&sort_order=desc&limit=2",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-z", LabelValuesCount: 3},
+				{LabelName: "label-c", LabelValuesCount: 1},
+			},
+		},
+		"sort by series count": {
+			params: "sort_by=series_count",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-c", LabelValuesCount: 1, SeriesCount: 50},
+				{LabelName: "label-b", LabelValuesCount: 2, SeriesCount: 40},
+				{LabelName: "label-z", LabelValuesCount: 3, SeriesCount: 40},
+				{LabelName: "label-a", LabelValuesCount: 2, SeriesCount: 2},
+			},
+		},
+		"sort by series count with offset": {
+			params: "sort_by=series_count&offset=3",
+			expectedItems: []*LabelNamesCardinalityItem{
+				{LabelName: "label-a", LabelValuesCount: 2, SeriesCount: 2},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			distributor := mockDistributorLabelNamesAndValues(items, nil)
+			distributor.On("LabelValuesCardinality", mock.Anything, []model.LabelName{"label-c", "label-b", "label-a", "label-z"}, []*labels.Matcher(nil)).Return(uint64(100), labelValuesCardinality, nil)
+			handler := createEnabledHandler(t, LabelNamesCardinalityHandler, distributor)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest("/ignored-url?"+testData.params, "team-a"))
+			require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+			responseBody := LabelNamesCardinalityResponse{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responseBody))
+			require.Equal(t, 4, responseBody.LabelNamesCount)
+			require.Equal(t, 8, responseBody.LabelValuesCountTotal)
+			require.Equal(t, testData.expectedItems, responseBody.Cardinality)
+		})
+	}
+}
+
+func TestLabelNamesCardinalityHandler_SortBySeriesCountShouldHonorTheLabelNamesPerRequestLimit(t *testing.T) {
+	items := []*client.LabelValues{
+		{LabelName: "label-c", Values: []string{"0c"}},
+		{LabelName: "label-b", Values: []string{"0b", "1b"}},
+		{LabelName: "label-a", Values: []string{"0a", "1a"}},
+		{LabelName: "label-z", Values: []string{"0z", "1z", "2z"}},
+		{LabelName: "label-y", Values: []string{"0y"}},
+	}
+
+	// The label names exceed the limit, so they're queried in batches.
+	distributor := mockDistributorLabelNamesAndValues(items, nil)
+	distributor.On("LabelValuesCardinality", mock.Anything, []model.LabelName{"label-c", "label-b"}, []*labels.Matcher(nil)).Return(uint64(100), &client.LabelValuesCardinalityResponse{
+		Items: []*client.LabelValueSeriesCount{
+			{LabelName: "label-c", LabelValueSeries: map[string]uint64{"0c": 50}},
+			{LabelName: "label-b", LabelValueSeries: map[string]uint64{"0b": 10, "1b": 30}},
+		},
+	}, nil)
+	distributor.On("LabelValuesCardinality", mock.Anything, []model.LabelName{"label-a", "label-z"}, []*labels.Matcher(nil)).Return(uint64(100), &client.LabelValuesCardinalityResponse{
+		Items: []*client.LabelValueSeriesCount{
+			{LabelName: "label-a", LabelValueSeries: map[string]uint64{"0a": 1, "1a": 1}},
+			{LabelName: "label-z", LabelValueSeries: map[string]uint64{"0z": 10, "1z": 20, "2z": 10}},
+		},
+	}, nil)
+	distributor.On("LabelValuesCardinality", mock.Anything, []model.LabelName{"label-y"}, []*labels.Matcher(nil)).Return(uint64(100), &client.LabelValuesCardinalityResponse{
+		Items: []*client.LabelValueSeriesCount{
+			{LabelName: "label-y", LabelValueSeries: map[string]uint64{"0y": 5}},
+		},
+	}, nil)
+
+	overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: true, LabelValuesMaxCardinalityLabelNamesPerRequest: 2}, nil)
+	require.NoError(t, err)
+	handler := LabelNamesCardinalityHandler(distributor, nil, overrides)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/ignored-url?sort_by=series_count", "team-a"))
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	responseBody := LabelNamesCardinalityResponse{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responseBody))
+	require.Equal(t, []*LabelNamesCardinalityItem{
+		{LabelName: "label-c", LabelValuesCount: 1, SeriesCount: 50},
+		{LabelName: "label-b", LabelValuesCount: 2, SeriesCount: 40},
+		{LabelName: "label-z", LabelValuesCount: 3, SeriesCount: 40},
+		{LabelName: "label-y", LabelValuesCount: 1, SeriesCount: 5},
+		{LabelName: "label-a", LabelValuesCount: 2, SeriesCount: 2},
+	}, responseBody.Cardinality)
+	distributor.AssertNumberOfCalls(t, "LabelValuesCardinality", 3)
+}
+
 func TestLabelNamesCardinalityHandler_DistributorError(t *testing.T) {
 	const labelNamesURL = "/label_names"
 
@@ -209,7 +352,7 @@ func TestLabelNamesCardinalityHandler_DistributorError(t *testing.T) {
 			limits.CardinalityAnalysisEnabled = true
 			overrides, err := validation.NewOverrides(limits, nil)
 			require.NoError(t, err)
-			handler := LabelNamesCardinalityHandler(distributor, overrides)
+			handler := LabelNamesCardinalityHandler(distributor, nil, overrides)
 			ctx := user.InjectOrgID(context.Background(), "test")
 
 			request, err := http.NewRequestWithContext(ctx, "GET", labelNamesURL, http.NoBody)
@@ -258,6 +401,21 @@ func TestLabelNamesCardinalityHandler_NegativeTests(t *testing.T) {
 			request:              createRequest("/ignored-url?limit=10&limit=20", "team-a"),
 			expectedErrorMessage: "multiple 'limit' params are not allowed",
 		},
+		{
+			name:                 "expected error if `offset` param is negative",
+			request:              createRequest("/ignored-url?offset=-1", "team-a"),
+			expectedErrorMessage: "'offset' param cannot be less than '0'",
+		},
+		{
+			name:                 "expected error if `sort_by` param is not supported",
+			request:              createRequest("/ignored-url?sort_by=label_value", "team-a"),
+			expectedErrorMessage: "invalid 'sort_by' param 'label_value', supported values are: label_values_count, series_count, label_name",
+		},
+		{
+			name:                 "expected error if `sort_order` param is not supported",
+			request:              createRequest("/ignored-url?sort_order=up", "team-a"),
+			expectedErrorMessage: "invalid 'sort_order' param 'up', supported values are: asc, desc",
+		},
 		{
 			name:                        "expected error that cardinality analysis feature is disabled",
 			request:                     createRequest("/ignored-url", "team-a"),
@@ -274,7 +432,7 @@ func TestLabelNamesCardinalityHandler_NegativeTests(t *testing.T) {
 			}
 			overrides, err := validation.NewOverrides(limits, nil)
 			require.NoError(t, err)
-			handler := LabelNamesCardinalityHandler(mockDistributorLabelNamesAndValues([]*client.LabelValues{}, nil), overrides)
+			handler := LabelNamesCardinalityHandler(mockDistributorLabelNamesAndValues([]*client.LabelValues{}, nil), nil, overrides)
 
 			recorder := httptest.NewRecorder()
 
@@ -354,6 +512,36 @@ func TestLabelValuesCardinalityHandler_Success(t *testing.T) {
 				}},
 			},
 		},
+		"should return the page of label values sorted by label value": {
+			getRequestParams: "?label_names[]=__name__&sort_by=label_value&sort_order=desc&offset=1&limit=2",
+			postRequestForm: url.Values{
+				"label_names[]": []string{"__name__"},
+				"sort_by":       []string{"label_value"},
+				"sort_order":    []string{"desc"},
+				"offset":        []string{"1"},
+				"limit":         []string{"2"},
+			},
+			labelNames: []model.LabelName{"__name__"},
+			matcher:    []*labels.Matcher(nil),
+			labelValuesCardinality: &client.LabelValuesCardinalityResponse{
+				Items: []*client.LabelValueSeriesCount{{
+					LabelName:        labels.MetricName,
+					LabelValueSeries: map[string]uint64{"test_1": 10, "test_2": 20, "test_3": 30, "test_4": 40},
+				}},
+			},
+			expectedResponse: labelValuesCardinalityResponse{
+				SeriesCountTotal: seriesCountTotal,
+				Labels: []labelNamesCardinality{{
+					LabelName:        "__name__",
+					LabelValuesCount: 4,
+					SeriesCount:      100,
+					Cardinality: []labelValuesCardinality{
+						{LabelValue: "test_3", SeriesCount: 30},
+						{LabelValue: "test_2", SeriesCount: 20},
+					},
+				}},
+			},
+		},
 		"should return the label values cardinality for the specified label names in descending order": {
 			getRequestParams: "?label_names[]=foo&label_names[]=bar",
 			postRequestForm: url.Values{
@@ -552,7 +740,7 @@ func TestLabelValuesCardinalityHandler_Success(t *testing.T) {
 			seriesCountTotal,
 			testData.labelValuesCardinality,
 			nil)
-		handler := createEnabledHandler(t, LabelValuesCardinalityHandler, distributor)
+		handler := createEnabledHandler(t, labelValuesCardinalityHandlerWithFederation, distributor)
 		ctx := user.InjectOrgID(context.Background(), "test")
 
 		t.Run("GET request "+testName, func(t *testing.T) {
@@ -631,7 +819,7 @@ func TestLabelValuesCardinalityHandler_FeatureFlag(t *testing.T) {
 			limits := validation.Limits{CardinalityAnalysisEnabled: testData.cardinalityAnalysisEnabled}
 			overrides, err := validation.NewOverrides(limits, nil)
 			require.NoError(t, err)
-			handler := LabelValuesCardinalityHandler(distributor, overrides)
+			handler := LabelValuesCardinalityHandler(distributor, nil, overrides, true)
 
 			recorder := httptest.NewRecorder()
 			handler.ServeHTTP(recorder, testData.request)
@@ -657,7 +845,7 @@ func TestLabelValuesCardinalityHandler_ParseError(t *testing.T) {
 		uint64(0),
 		&client.LabelValuesCardinalityResponse{Items: []*client.LabelValueSeriesCount{}},
 		nil)
-	handler := createEnabledHandler(t, LabelValuesCardinalityHandler, distributor)
+	handler := createEnabledHandler(t, labelValuesCardinalityHandlerWithFederation, distributor)
 	ctx := user.InjectOrgID(context.Background(), "test")
 
 	t.Run("should return bad request if no tenant id is provided", func(t *testing.T) {
@@ -697,6 +885,18 @@ func TestLabelValuesCardinalityHandler_ParseError(t *testing.T) {
 				url:                  "/label_values?label_names[]=hello&limit=501",
 				expectedErrorMessage: "'limit' param cannot be greater than '500'",
 			},
+			"offset param is a negative number": {
+				url:                  "/label_values?label_names[]=hello&offset=-1",
+				expectedErrorMessage: "'offset' param cannot be less than '0'",
+			},
+			"multiple offset params are provided": {
+				url:                  "/label_values?label_names[]=hello&offset=1&offset=2",
+				expectedErrorMessage: "multiple 'offset' params are not allowed",
+			},
+			"sort_by param is not supported": {
+				url:                  "/label_values?label_names[]=hello&sort_by=label_values_count",
+				expectedErrorMessage: "invalid 'sort_by' param 'label_values_count', supported values are: series_count, label_value",
+			},
 		}
 		for testName, testData := range tests {
 			t.Run(testName, func(t *testing.T) {
@@ -750,7 +950,7 @@ func TestLabelValuesCardinalityHandler_DistributorError(t *testing.T) {
 				uint64(0),
 				&client.LabelValuesCardinalityResponse{Items: []*client.LabelValueSeriesCount{}},
 				testData.distributorError)
-			handler := createEnabledHandler(t, LabelValuesCardinalityHandler, distributor)
+			handler := createEnabledHandler(t, labelValuesCardinalityHandlerWithFederation, distributor)
 			ctx := user.InjectOrgID(context.Background(), "test")
 
 			request, err := http.NewRequestWithContext(ctx, "GET", labelValuesURL, http.NoBody)
@@ -772,13 +972,187 @@ func TestLabelValuesCardinalityHandler_DistributorError(t *testing.T) {
 	}
 }
 
+func TestLabelValuesCardinalityHandler_FederatedRequest(t *testing.T) {
+	tenant.WithDefaultResolver(tenant.NewMultiResolver())
+	t.Cleanup(func() { tenant.WithDefaultResolver(tenant.NewSingleResolver()) })
+
+	const labelValuesURL = "/label_values?label_names[]=__name__&label_names[]=job"
+
+	responsesByTenant := map[string]*client.LabelValuesCardinalityResponse{
+		"team-a": {
+			Items: []*client.LabelValueSeriesCount{
+				{LabelName: labels.MetricName, LabelValueSeries: map[string]uint64{"metric_1": 10, "metric_2": 5}},
+				{LabelName: "job", LabelValueSeries: map[string]uint64{"job_1": 15}},
+			},
+		},
+		"team-b": {
+			Items: []*client.LabelValueSeriesCount{
+				{LabelName: labels.MetricName, LabelValueSeries: map[string]uint64{"metric_2": 20}},
+			},
+		},
+	}
+
+	distributor := &mockDistributor{}
+	for tenantID, response := range responsesByTenant {
+		tenantID := tenantID
+		distributor.On("LabelValuesCardinality", mock.MatchedBy(func(ctx context.Context) bool {
+			actual, err := tenant.TenantID(ctx)
+			return err == nil && actual == tenantID
+		}), []model.LabelName{labels.MetricName, "job"}, []*labels.Matcher(nil)).Return(uint64(len(tenantID)*10), response, nil)
+	}
+
+	handler := createEnabledHandler(t, labelValuesCardinalityHandlerWithFederation, distributor)
+	ctx := user.InjectOrgID(context.Background(), "team-a|team-b")
+
+	request, err := http.NewRequestWithContext(ctx, "GET", labelValuesURL, http.NoBody)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	responseBody := labelValuesCardinalityResponse{}
+	require.NoError(t, json.NewDecoder(recorder.Result().Body).Decode(&responseBody))
+
+	require.Equal(t, labelValuesCardinalityResponse{
+		SeriesCountTotal: 120,
+		Labels: []labelNamesCardinality{
+			{
+				LabelName:        labels.MetricName,
+				LabelValuesCount: 2,
+				SeriesCount:      35,
+				Cardinality: []labelValuesCardinality{
+					{LabelValue: "metric_2", SeriesCount: 25},
+					{LabelValue: "metric_1", SeriesCount: 10},
+				},
+			},
+			{
+				LabelName:        "job",
+				LabelValuesCount: 1,
+				SeriesCount:      15,
+				Cardinality: []labelValuesCardinality{
+					{LabelValue: "job_1", SeriesCount: 15},
+				},
+			},
+		},
+		Tenants: []tenantLabelValuesCardinality{
+			{
+				TenantID:         "team-a",
+				SeriesCountTotal: 60,
+				Labels: []labelNamesCardinality{
+					{
+						LabelName:        labels.MetricName,
+						LabelValuesCount: 2,
+						SeriesCount:      15,
+						Cardinality: []labelValuesCardinality{
+							{LabelValue: "metric_1", SeriesCount: 10},
+							{LabelValue: "metric_2", SeriesCount: 5},
+						},
+					},
+					{
+						LabelName:        "job",
+						LabelValuesCount: 1,
+						SeriesCount:      15,
+						Cardinality: []labelValuesCardinality{
+							{LabelValue: "job_1", SeriesCount: 15},
+						},
+					},
+				},
+			},
+			{
+				TenantID:         "team-b",
+				SeriesCountTotal: 60,
+				Labels: []labelNamesCardinality{
+					{
+						LabelName:        labels.MetricName,
+						LabelValuesCount: 1,
+						SeriesCount:      20,
+						Cardinality: []labelValuesCardinality{
+							{LabelValue: "metric_2", SeriesCount: 20},
+						},
+					},
+				},
+			},
+		},
+	}, responseBody)
+}
+
+func TestLabelValuesCardinalityHandler_FederatedRequestWithDisabledTenant(t *testing.T) {
+	tenant.WithDefaultResolver(tenant.NewMultiResolver())
+	t.Cleanup(func() { tenant.WithDefaultResolver(tenant.NewSingleResolver()) })
+
+	tenantLimits := map[string]*validation.Limits{
+		"team-a": {CardinalityAnalysisEnabled: true},
+		"team-b": {CardinalityAnalysisEnabled: false},
+	}
+	overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: true}, validation.NewMockTenantLimits(tenantLimits))
+	require.NoError(t, err)
+
+	handler := LabelValuesCardinalityHandler(&mockDistributor{}, nil, overrides, true)
+	ctx := user.InjectOrgID(context.Background(), "team-a|team-b")
+
+	request, err := http.NewRequestWithContext(ctx, "GET", "/label_values?label_names[]=__name__", http.NoBody)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	require.Equal(t, "cardinality analysis is disabled for the tenant: team-b\n", string(body))
+}
+
+func TestLabelValuesCardinalityHandler_FederatedRequestWithFederationDisabled(t *testing.T) {
+	tenant.WithDefaultResolver(tenant.NewMultiResolver())
+	t.Cleanup(func() { tenant.WithDefaultResolver(tenant.NewSingleResolver()) })
+
+	overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: true}, nil)
+	require.NoError(t, err)
+
+	handler := LabelValuesCardinalityHandler(&mockDistributor{}, nil, overrides, false)
+	ctx := user.InjectOrgID(context.Background(), "team-a|team-b")
+
+	request, err := http.NewRequestWithContext(ctx, "GET", "/label_values?label_names[]=__name__", http.NoBody)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	require.Equal(t, errCardinalityFederationDisabled.Error()+"\n", string(body))
+}
+
+func TestLabelNamesCardinalityHandler_FederatedRequest(t *testing.T) {
+	tenant.WithDefaultResolver(tenant.NewMultiResolver())
+	t.Cleanup(func() { tenant.WithDefaultResolver(tenant.NewSingleResolver()) })
+
+	handler := createEnabledHandler(t, LabelNamesCardinalityHandler, &mockDistributor{})
+	ctx := user.InjectOrgID(context.Background(), "team-a|team-b")
+
+	request, err := http.NewRequestWithContext(ctx, "GET", "/label_names", http.NoBody)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	require.Equal(t, errLabelNamesCardinalityFederationUnsupported.Error()+"\n", string(body))
+}
+
+// labelValuesCardinalityHandlerWithFederation creates a LabelValuesCardinalityHandler with the tenant federation enabled.
+func labelValuesCardinalityHandlerWithFederation(d Distributor, queryable storage.Queryable, limits *validation.Overrides) http.Handler {
+	return LabelValuesCardinalityHandler(d, queryable, limits, true)
+}
+
 // createEnabledHandler creates a cardinalityHandler that can be either a LabelNamesCardinalityHandler or a LabelValuesCardinalityHandler
-func createEnabledHandler(t *testing.T, cardinalityHandler func(Distributor, *validation.Overrides) http.Handler, distributor *mockDistributor) http.Handler {
+func createEnabledHandler(t *testing.T, cardinalityHandler func(Distributor, storage.Queryable, *validation.Overrides) http.Handler, distributor *mockDistributor) http.Handler {
 	limits := validation.Limits{CardinalityAnalysisEnabled: true}
 	overrides, err := validation.NewOverrides(limits, nil)
 	require.NoError(t, err)
 
-	handler := cardinalityHandler(distributor, overrides)
+	handler := cardinalityHandler(distributor, nil, overrides)
 	return handler
 }
 
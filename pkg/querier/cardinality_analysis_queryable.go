@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/dskit/concurrency"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/weaveworks/common/httpgrpc"
+
+	ingester_client "github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+// maxLabelValuesConcurrency is the max number of label names whose values are queried concurrently
+// by a cardinality analysis request with a time range.
+const maxLabelValuesConcurrency = 16
+
+// cardinalityTimeRange is the time range of a cardinality analysis request. When set, the cardinality
+// is computed from the series queried from both the ingesters and the store-gateways, so that it
+// includes the historical blocks and not only the series in the ingesters' in-memory heads.
+type cardinalityTimeRange struct {
+	start, end int64
+}
+
+// extractTimeRange parses the optional start and end request params. The returned time range
+// is nil if start is not set. When start is set, end defaults to now.
+func extractTimeRange(r *http.Request) (*cardinalityTimeRange, error) {
+	startParams, endParams := r.Form["start"], r.Form["end"]
+	if len(startParams) == 0 {
+		if len(endParams) > 0 {
+			return nil, fmt.Errorf("'end' param requires the 'start' param")
+		}
+		return nil, nil
+	}
+	if len(startParams) > 1 {
+		return nil, fmt.Errorf("multiple 'start' params are not allowed")
+	}
+	if len(endParams) > 1 {
+		return nil, fmt.Errorf("multiple 'end' params are not allowed")
+	}
+
+	start, err := util.ParseTime(startParams[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'start' param: %w", err)
+	}
+
+	end := util.TimeToMillis(time.Now())
+	if len(endParams) > 0 {
+		if end, err = util.ParseTime(endParams[0]); err != nil {
+			return nil, fmt.Errorf("invalid 'end' param: %w", err)
+		}
+	}
+	if end < start {
+		return nil, fmt.Errorf("'end' param must not be before 'start' param")
+	}
+
+	return &cardinalityTimeRange{start: start, end: end}, nil
+}
+
+// queryableLabelNamesAndValues returns the label names and values of the series matching the input
+// matchers within the input time range.
+func queryableLabelNamesAndValues(ctx context.Context, queryable storage.Queryable, tr *cardinalityTimeRange, matchers []*labels.Matcher) (*ingester_client.LabelNamesAndValuesResponse, error) {
+	q, err := queryable.Querier(ctx, tr.start, tr.end)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	names, _, err := q.LabelNames(matchers...)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*ingester_client.LabelValues, len(names))
+	err = concurrency.ForEachJob(ctx, len(names), maxLabelValuesConcurrency, func(ctx context.Context, idx int) error {
+		values, _, err := q.LabelValues(names[idx], matchers...)
+		if err != nil {
+			return err
+		}
+		items[idx] = &ingester_client.LabelValues{LabelName: names[idx], Values: values}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ingester_client.LabelNamesAndValuesResponse{Items: items}, nil
+}
+
+// queryableLabelValuesCardinality returns the total number of series matching the input matchers
+// within the input time range, and the number of series for each value of the input label names.
+// The request fails once more than maxSeries series have been counted (0 for no limit).
+func queryableLabelValuesCardinality(ctx context.Context, queryable storage.Queryable, tr *cardinalityTimeRange, labelNames []model.LabelName, matchers []*labels.Matcher, maxSeries int) (uint64, *ingester_client.LabelValuesCardinalityResponse, error) {
+	q, err := queryable.Querier(ctx, tr.start, tr.end)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer q.Close()
+
+	selector := matchers
+	if len(selector) == 0 {
+		selector = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, model.MetricNameLabel, ".+")}
+	}
+
+	// The series are sorted so that the series returned by both the ingesters and the store-gateways
+	// are merged, and counted only once.
+	set := q.Select(true, &storage.SelectHints{Start: tr.start, End: tr.end, Func: "series"}, selector...)
+
+	var (
+		seriesCountTotal uint64
+		counts           = make([]map[string]uint64, len(labelNames))
+	)
+	for i := range counts {
+		counts[i] = map[string]uint64{}
+	}
+
+	for set.Next() {
+		seriesCountTotal++
+		if maxSeries > 0 && seriesCountTotal > uint64(maxSeries) {
+			return 0, nil, httpgrpc.Errorf(http.StatusUnprocessableEntity, limiter.MaxSeriesHitMsgFormat, maxSeries)
+		}
+
+		series := set.At().Labels()
+		for i, name := range labelNames {
+			if value := series.Get(string(name)); value != "" {
+				counts[i][value]++
+			}
+		}
+	}
+	if err := set.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	response := &ingester_client.LabelValuesCardinalityResponse{
+		Items: make([]*ingester_client.LabelValueSeriesCount, 0, len(labelNames)),
+	}
+	for i, name := range labelNames {
+		if len(counts[i]) == 0 {
+			continue
+		}
+		response.Items = append(response.Items, &ingester_client.LabelValueSeriesCount{
+			LabelName:        string(name),
+			LabelValueSeries: counts[i],
+		})
+	}
+	return seriesCountTotal, response, nil
+}
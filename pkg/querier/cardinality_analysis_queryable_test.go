@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestExtractTimeRange(t *testing.T) {
+	tests := map[string]struct {
+		params      string
+		expected    *cardinalityTimeRange
+		expectedErr string
+	}{
+		"no time range": {
+			params: "",
+		},
+		"start and end": {
+			params:   "start=10&end=20",
+			expected: &cardinalityTimeRange{start: 10000, end: 20000},
+		},
+		"end without start": {
+			params:      "end=20",
+			expectedErr: "'end' param requires the 'start' param",
+		},
+		"end before start": {
+			params:      "start=20&end=10",
+			expectedErr: "'end' param must not be before 'start' param",
+		},
+		"multiple start": {
+			params:      "start=10&start=20",
+			expectedErr: "multiple 'start' params are not allowed",
+		},
+		"invalid start": {
+			params:      "start=foo",
+			expectedErr: "invalid 'start' param",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ignored-url?"+tc.params, nil)
+			require.NoError(t, r.ParseForm())
+
+			tr, err := extractTimeRange(r)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, tr)
+		})
+	}
+
+	t.Run("start without end", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/ignored-url?start=10", nil)
+		require.NoError(t, r.ParseForm())
+
+		tr, err := extractTimeRange(r)
+		require.NoError(t, err)
+		require.Equal(t, int64(10000), tr.start)
+		require.Greater(t, tr.end, tr.start)
+	})
+}
+
+func TestLabelNamesCardinalityHandler_TimeRange(t *testing.T) {
+	queryable := mockCardinalityQueryable(t)
+
+	handler := createEnabledQueryableHandler(t, LabelNamesCardinalityHandler, queryable)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/ignored-url?start=0&end=100&selector={job=\"api\"}", "team-a"))
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	response := LabelNamesCardinalityResponse{}
+	require.NoError(t, json.NewDecoder(recorder.Result().Body).Decode(&response))
+	require.Equal(t, LabelNamesCardinalityResponse{
+		LabelValuesCountTotal: 5,
+		LabelNamesCount:       3,
+		Cardinality: []*LabelNamesCardinalityItem{
+			{LabelName: "__name__", LabelValuesCount: 2},
+			{LabelName: "instance", LabelValuesCount: 2},
+			{LabelName: "job", LabelValuesCount: 1},
+		},
+	}, response)
+}
+
+func TestLabelValuesCardinalityHandler_TimeRange(t *testing.T) {
+	queryable := mockCardinalityQueryable(t)
+
+	handler := createEnabledQueryableHandler(t, labelValuesCardinalityHandlerWithFederation, queryable)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, createRequest("/ignored-url?start=0&end=100&label_names[]=job&label_names[]=instance&label_names[]=missing", "team-a"))
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	response := labelValuesCardinalityResponse{}
+	require.NoError(t, json.NewDecoder(recorder.Result().Body).Decode(&response))
+	require.Equal(t, labelValuesCardinalityResponse{
+		SeriesCountTotal: 4,
+		Labels: []labelNamesCardinality{
+			{
+				LabelName:        "job",
+				LabelValuesCount: 2,
+				SeriesCount:      4,
+				Cardinality: []labelValuesCardinality{
+					{LabelValue: "api", SeriesCount: 3},
+					{LabelValue: "db", SeriesCount: 1},
+				},
+			},
+			{
+				LabelName:        "instance",
+				LabelValuesCount: 2,
+				SeriesCount:      3,
+				Cardinality: []labelValuesCardinality{
+					{LabelValue: "a", SeriesCount: 2},
+					{LabelValue: "b", SeriesCount: 1},
+				},
+			},
+		},
+	}, response)
+}
+
+func TestLabelValuesCardinalityHandler_TimeRangeLimits(t *testing.T) {
+	tests := map[string]struct {
+		limits         validation.Limits
+		params         string
+		expectedStatus int
+		expectedErr    string
+	}{
+		"should fail when the label names limit is exceeded": {
+			limits:         validation.Limits{CardinalityAnalysisEnabled: true, LabelValuesMaxCardinalityLabelNamesPerRequest: 1},
+			params:         "start=0&end=100&label_names[]=job&label_names[]=instance",
+			expectedStatus: http.StatusBadRequest,
+			expectedErr:    "label values cardinality request label names limit (limit: 1 actual: 2) exceeded",
+		},
+		"should fail when the max fetched series limit is exceeded": {
+			limits:         validation.Limits{CardinalityAnalysisEnabled: true, LabelValuesMaxCardinalityLabelNamesPerRequest: 100, MaxFetchedSeriesPerQuery: 3},
+			params:         "start=0&end=100&label_names[]=job",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedErr:    "the query exceeded the maximum number of series (limit: 3 series)",
+		},
+		"should succeed when the series are within the max fetched series limit": {
+			limits:         validation.Limits{CardinalityAnalysisEnabled: true, LabelValuesMaxCardinalityLabelNamesPerRequest: 100, MaxFetchedSeriesPerQuery: 4},
+			params:         "start=0&end=100&label_names[]=job",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			overrides, err := validation.NewOverrides(tc.limits, nil)
+			require.NoError(t, err)
+
+			handler := LabelValuesCardinalityHandler(&mockDistributor{}, mockCardinalityQueryable(t), overrides, false)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, createRequest("/ignored-url?"+tc.params, "team-a"))
+			require.Equal(t, tc.expectedStatus, recorder.Result().StatusCode)
+
+			body, err := io.ReadAll(recorder.Result().Body)
+			require.NoError(t, err)
+			require.Contains(t, string(body), tc.expectedErr)
+		})
+	}
+}
+
+func createEnabledQueryableHandler(t *testing.T, cardinalityHandler func(Distributor, storage.Queryable, *validation.Overrides) http.Handler, queryable storage.Queryable) http.Handler {
+	overrides, err := validation.NewOverrides(validation.Limits{CardinalityAnalysisEnabled: true, LabelValuesMaxCardinalityLabelNamesPerRequest: 100}, nil)
+	require.NoError(t, err)
+
+	// The distributor is not expected to be called when the request has a time range.
+	return cardinalityHandler(&mockDistributor{}, queryable, overrides)
+}
+
+func mockCardinalityQueryable(t *testing.T) storage.Queryable {
+	opts := tsdb.DefaultHeadOptions()
+	opts.ChunkDirRoot = t.TempDir()
+	head, err := tsdb.NewHead(nil, nil, nil, nil, opts, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = head.Close()
+	})
+
+	app := head.Appender(user.InjectOrgID(context.Background(), "team-a"))
+	for _, series := range []labels.Labels{
+		labels.FromStrings("__name__", "up", "job", "api", "instance", "a"),
+		labels.FromStrings("__name__", "up", "job", "api", "instance", "b"),
+		labels.FromStrings("__name__", "requests_total", "job", "api", "instance", "a"),
+		labels.FromStrings("__name__", "up", "job", "db"),
+	} {
+		_, err := app.Append(0, series, 10_000, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		return tsdb.NewBlockQuerier(head, mint, maxt)
+	})
+}
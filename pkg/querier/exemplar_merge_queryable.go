@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// NewMergeExemplarQueryable returns a storage.ExemplarQueryable querying the exemplars from both the
+// ingesters and the long-term storage, honoring the -querier.query-ingesters-within and
+// -querier.query-store-after options, and merging the results.
+func NewMergeExemplarQueryable(cfg Config, ingesters, store storage.ExemplarQueryable) storage.ExemplarQueryable {
+	return &mergeExemplarQueryable{
+		ingesters:            ingesters,
+		store:                store,
+		queryIngestersWithin: cfg.QueryIngestersWithin,
+		queryStoreAfter:      cfg.QueryStoreAfter,
+	}
+}
+
+type mergeExemplarQueryable struct {
+	ingesters            storage.ExemplarQueryable
+	store                storage.ExemplarQueryable
+	queryIngestersWithin time.Duration
+	queryStoreAfter      time.Duration
+}
+
+func (m *mergeExemplarQueryable) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	ingesters, err := m.ingesters.ExemplarQuerier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := m.store.ExemplarQuerier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mergeExemplarQuerier{
+		ingesters:            ingesters,
+		store:                store,
+		queryIngestersWithin: m.queryIngestersWithin,
+		queryStoreAfter:      m.queryStoreAfter,
+	}, nil
+}
+
+type mergeExemplarQuerier struct {
+	ingesters            storage.ExemplarQuerier
+	store                storage.ExemplarQuerier
+	queryIngestersWithin time.Duration
+	queryStoreAfter      time.Duration
+}
+
+// Select implements storage.ExemplarQuerier.
+func (m *mergeExemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	now := time.Now()
+
+	var queriers []storage.ExemplarQuerier
+	if m.queryIngestersWithin == 0 || end >= util.TimeToMillis(now.Add(-m.queryIngestersWithin)) {
+		queriers = append(queriers, m.ingesters)
+	}
+	if m.queryStoreAfter == 0 || start <= util.TimeToMillis(now.Add(-m.queryStoreAfter)) {
+		queriers = append(queriers, m.store)
+	}
+
+	if len(queriers) == 1 {
+		return queriers[0].Select(start, end, matchers...)
+	}
+
+	var (
+		g       errgroup.Group
+		mtx     sync.Mutex
+		results [][]exemplar.QueryResult
+	)
+
+	for _, q := range queriers {
+		q := q
+		g.Go(func() error {
+			res, err := q.Select(start, end, matchers...)
+			if err != nil {
+				return err
+			}
+
+			mtx.Lock()
+			results = append(results, res)
+			mtx.Unlock()
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return mergeExemplarQueryResults(results...), nil
+}
+
+// mergeExemplarQueryResults merges the exemplars of the same series, removing the duplicated exemplars.
+// The returned series are sorted by labels, and their exemplars by timestamp.
+func mergeExemplarQueryResults(results ...[]exemplar.QueryResult) []exemplar.QueryResult {
+	bySeries := map[string]*exemplar.QueryResult{}
+
+	for _, res := range results {
+		for _, r := range res {
+			key := r.SeriesLabels.String()
+
+			merged, ok := bySeries[key]
+			if !ok {
+				merged = &exemplar.QueryResult{SeriesLabels: r.SeriesLabels}
+				bySeries[key] = merged
+			}
+			merged.Exemplars = append(merged.Exemplars, r.Exemplars...)
+		}
+	}
+
+	out := make([]exemplar.QueryResult, 0, len(bySeries))
+	for _, r := range bySeries {
+		sort.SliceStable(r.Exemplars, func(i, j int) bool {
+			return r.Exemplars[i].Ts < r.Exemplars[j].Ts
+		})
+
+		deduped := r.Exemplars[:0]
+		for _, e := range r.Exemplars {
+			if len(deduped) > 0 && deduped[len(deduped)-1].Equals(e) {
+				continue
+			}
+			deduped = append(deduped, e)
+		}
+		r.Exemplars = deduped
+
+		out = append(out, *r)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return labels.Compare(out[i].SeriesLabels, out[j].SeriesLabels) < 0
+	})
+	return out
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+func TestMergeExemplarQueryable(t *testing.T) {
+	var (
+		now     = time.Now()
+		series1 = labels.FromStrings(labels.MetricName, "series_1")
+		series2 = labels.FromStrings(labels.MetricName, "series_2")
+	)
+
+	exemplars := func(timestamps ...int64) []exemplar.Exemplar {
+		var out []exemplar.Exemplar
+		for _, ts := range timestamps {
+			out = append(out, exemplar.Exemplar{Labels: labels.FromStrings("trace_id", "abc"), Value: float64(ts), Ts: ts, HasTs: true})
+		}
+		return out
+	}
+
+	ingestersResult := []exemplar.QueryResult{
+		{SeriesLabels: series2, Exemplars: exemplars(30)},
+		{SeriesLabels: series1, Exemplars: exemplars(20, 30)},
+	}
+	storeResult := []exemplar.QueryResult{
+		{SeriesLabels: series1, Exemplars: exemplars(10, 20)},
+	}
+
+	cfg := Config{
+		QueryIngestersWithin: 13 * time.Hour,
+		QueryStoreAfter:      12 * time.Hour,
+	}
+
+	tests := map[string]struct {
+		start, end       time.Time
+		expectedQueriers []string
+		expected         []exemplar.QueryResult
+	}{
+		"should query only the ingesters for a recent time range": {
+			start:            now.Add(-time.Hour),
+			end:              now,
+			expectedQueriers: []string{"ingesters"},
+			expected:         ingestersResult,
+		},
+		"should query only the store for an old time range": {
+			start:            now.Add(-48 * time.Hour),
+			end:              now.Add(-24 * time.Hour),
+			expectedQueriers: []string{"store"},
+			expected:         storeResult,
+		},
+		"should query both the ingesters and the store, and merge the results, for a time range spanning both": {
+			start:            now.Add(-24 * time.Hour),
+			end:              now,
+			expectedQueriers: []string{"ingesters", "store"},
+			expected: []exemplar.QueryResult{
+				{SeriesLabels: series1, Exemplars: exemplars(10, 20, 30)},
+				{SeriesLabels: series2, Exemplars: exemplars(30)},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ingesters := &exemplarQueryableMock{result: ingestersResult}
+			store := &exemplarQueryableMock{result: storeResult}
+
+			q, err := NewMergeExemplarQueryable(cfg, ingesters, store).ExemplarQuerier(context.Background())
+			require.NoError(t, err)
+
+			res, err := q.Select(util.TimeToMillis(testData.start), util.TimeToMillis(testData.end))
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, res)
+
+			var queried []string
+			if ingesters.called {
+				queried = append(queried, "ingesters")
+			}
+			if store.called {
+				queried = append(queried, "store")
+			}
+			assert.Equal(t, testData.expectedQueriers, queried)
+		})
+	}
+}
+
+type exemplarQueryableMock struct {
+	result []exemplar.QueryResult
+	called bool
+}
+
+func (m *exemplarQueryableMock) ExemplarQuerier(context.Context) (storage.ExemplarQuerier, error) {
+	return m, nil
+}
+
+func (m *exemplarQueryableMock) Select(_, _ int64, _ ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	m.called = true
+
+	// Return a copy, given the results may be manipulated by the caller.
+	out := make([]exemplar.QueryResult, 0, len(m.result))
+	for _, r := range m.result {
+		out = append(out, exemplar.QueryResult{SeriesLabels: r.SeriesLabels, Exemplars: append([]exemplar.Exemplar(nil), r.Exemplars...)})
+	}
+	return out, nil
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// IngestStorageSeriesReader reads the series of a tenant recently written to the ingest storage.
+type IngestStorageSeriesReader interface {
+	// QuerySeries returns the series of the tenant matching the matchers, with their samples in the [from, to]
+	// time range, reading the records written since writtenSince.
+	QuerySeries(ctx context.Context, userID string, writtenSince, from, to int64, matchers []*labels.Matcher) ([]mimirpb.TimeSeries, error)
+}
+
+// NewIngestStorageFallbackDistributor returns a Distributor reading the series from the ingest storage when
+// querying the series of the ingesters fails, like when the ingesters are unavailable. The queries failing
+// because of the query limits are not read from the ingest storage.
+func NewIngestStorageFallbackDistributor(next Distributor, reader IngestStorageSeriesReader, limits *validation.Overrides, logger log.Logger, reg prometheus.Registerer) Distributor {
+	return &ingestStorageFallbackDistributor{
+		Distributor: next,
+		reader:      reader,
+		limits:      limits,
+		logger:      logger,
+		fallbacks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_ingest_storage_fallbacks_total",
+			Help: "Total number of series queries read from the ingest storage because querying the ingesters failed.",
+		}),
+		failedFallbacks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_ingest_storage_fallback_failures_total",
+			Help: "Total number of series queries which failed to be read from the ingest storage.",
+		}),
+	}
+}
+
+type ingestStorageFallbackDistributor struct {
+	Distributor
+
+	reader IngestStorageSeriesReader
+	limits *validation.Overrides
+	logger log.Logger
+
+	fallbacks       prometheus.Counter
+	failedFallbacks prometheus.Counter
+}
+
+func (d *ingestStorageFallbackDistributor) QueryStream(ctx context.Context, from, to model.Time, matchers ...*labels.Matcher) (*client.QueryStreamResponse, error) {
+	res, err := d.Distributor.QueryStream(ctx, from, to, matchers...)
+	if err == nil || !shouldFallbackToIngestStorage(ctx, err) {
+		return res, err
+	}
+
+	userID, tenantErr := tenant.TenantID(ctx)
+	if tenantErr != nil {
+		return nil, err
+	}
+
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, d.logger, "ingestStorageFallbackDistributor.QueryStream")
+	defer spanLog.Finish()
+	level.Warn(spanLog).Log("msg", "failed to query the ingesters; reading the series from the ingest storage", "err", err)
+	d.fallbacks.Inc()
+
+	// The samples are written at most the creation grace period before their timestamp.
+	writtenSince := int64(from) - d.limits.CreationGracePeriod(userID).Milliseconds()
+
+	series, fallbackErr := d.reader.QuerySeries(ctx, userID, writtenSince, int64(from), int64(to), matchers)
+	if fallbackErr != nil {
+		d.failedFallbacks.Inc()
+		level.Warn(spanLog).Log("msg", "failed to read the series from the ingest storage", "err", fallbackErr)
+		return nil, err
+	}
+	return &client.QueryStreamResponse{Timeseries: series}, nil
+}
+
+// shouldFallbackToIngestStorage returns whether the series should be read from the ingest storage, given the error
+// returned by the ingesters. The canceled queries, and the ones exceeding the query limits, are not.
+func shouldFallbackToIngestStorage(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var limitErr validation.LimitError
+	return !errors.As(err, &limitErr)
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestIngestStorageFallbackDistributor_QueryStream(t *testing.T) {
+	matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "series_1")}
+	logSeries := []mimirpb.TimeSeries{{
+		Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(labels.MetricName, "series_1")),
+		Samples: []mimirpb.Sample{{TimestampMs: 20000, Value: 1}},
+	}}
+	ingestersRes := &client.QueryStreamResponse{Timeseries: []mimirpb.TimeSeries{{
+		Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(labels.MetricName, "series_1")),
+		Samples: []mimirpb.Sample{{TimestampMs: 20000, Value: 2}},
+	}}}
+
+	tests := map[string]struct {
+		ingestersErr error
+		readerErr    error
+		expected     *client.QueryStreamResponse
+		expectedErr  error
+		expectedRead bool
+	}{
+		"the ingesters are queried successfully": {
+			expected: ingestersRes,
+		},
+		"querying the ingesters fails": {
+			ingestersErr: errors.New("too many unhealthy instances in the ring"),
+			expected:     &client.QueryStreamResponse{Timeseries: logSeries},
+			expectedRead: true,
+		},
+		"querying the ingesters fails because of the query limits": {
+			ingestersErr: validation.LimitError("the query exceeded the maximum number of series"),
+			expectedErr:  validation.LimitError("the query exceeded the maximum number of series"),
+		},
+		"querying the ingesters and reading the ingest storage fail": {
+			ingestersErr: errors.New("too many unhealthy instances in the ring"),
+			readerErr:    errors.New("failed to fetch the records"),
+			expectedErr:  errors.New("too many unhealthy instances in the ring"),
+			expectedRead: true,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+
+			limits := defaultLimitsConfig()
+			limits.CreationGracePeriod = model.Duration(5 * time.Second)
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			distributor := &mockDistributor{}
+			if testData.ingestersErr != nil {
+				distributor.On("QueryStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return((*client.QueryStreamResponse)(nil), testData.ingestersErr)
+			} else {
+				distributor.On("QueryStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(ingestersRes, nil)
+			}
+
+			reader := &ingestStorageSeriesReaderMock{series: logSeries, err: testData.readerErr}
+			d := NewIngestStorageFallbackDistributor(distributor, reader, overrides, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			res, err := d.QueryStream(ctx, 10000, 30000, matchers...)
+			if testData.expectedErr != nil {
+				require.EqualError(t, err, testData.expectedErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, testData.expected, res)
+
+			if testData.expectedRead {
+				// The records written since the creation grace period before the start of the query are read.
+				assert.Equal(t, []ingestStorageSeriesReaderCall{{userID: "user-1", writtenSince: 5000, from: 10000, to: 30000}}, reader.calls)
+			} else {
+				assert.Empty(t, reader.calls)
+			}
+		})
+	}
+}
+
+type ingestStorageSeriesReaderCall struct {
+	userID                 string
+	writtenSince, from, to int64
+}
+
+type ingestStorageSeriesReaderMock struct {
+	series []mimirpb.TimeSeries
+	err    error
+	calls  []ingestStorageSeriesReaderCall
+}
+
+func (m *ingestStorageSeriesReaderMock) QuerySeries(_ context.Context, userID string, writtenSince, from, to int64, _ []*labels.Matcher) ([]mimirpb.TimeSeries, error) {
+	m.calls = append(m.calls, ingestStorageSeriesReaderCall{userID: userID, writtenSince: writtenSince, from: from, to: to})
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.series, nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql"
@@ -50,6 +51,14 @@ type Config struct {
 
 	ShuffleShardingIngestersEnabled bool `yaml:"shuffle_sharding_ingesters_enabled" category:"advanced"`
 
+	DownsampledBlocksEnabled bool `yaml:"downsampled_blocks_enabled" category:"experimental"`
+
+	BlockExemplarsEnabled bool `yaml:"block_exemplars_enabled" category:"experimental"`
+
+	DeduplicateRepeatedSelectors bool `yaml:"deduplicate_repeated_selectors" category:"experimental"`
+
+	IngestStorageFallbackEnabled bool `yaml:"ingest_storage_fallback_enabled" category:"experimental"`
+
 	// PromQL engine config.
 	EngineConfig engine.Config `yaml:",inline"`
 }
@@ -74,6 +83,11 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.QueryStoreAfter, queryStoreAfterFlag, 12*time.Hour, "The time after which a metric should be queried from storage and not just ingesters. 0 means all queries are sent to store. If this option is enabled, the time range of the query sent to the store-gateway will be manipulated to ensure the query end is not more recent than 'now - query-store-after'.")
 	f.BoolVar(&cfg.ShuffleShardingIngestersEnabled, "querier.shuffle-sharding-ingesters-enabled", true, fmt.Sprintf("Fetch in-memory series from the minimum set of required ingesters, selecting only ingesters which may have received series since -%s. If this setting is false or -%s is '0', queriers always query all ingesters (ingesters shuffle sharding on read path is disabled).", queryIngestersWithinFlag, queryIngestersWithinFlag))
 
+	f.BoolVar(&cfg.DownsampledBlocksEnabled, "querier.downsampled-blocks-enabled", false, "If enabled, the rate(), increase(), min_over_time(), max_over_time() and sum_over_time() functions with a step and a range of at least 5 times a downsampling resolution run on the blocks downsampled by the compactor at that resolution, when available. Requires -compactor.downsampling-enabled.")
+	f.BoolVar(&cfg.BlockExemplarsEnabled, "querier.block-exemplars-enabled", false, "True to query the exemplars stored in the blocks from the store-gateways, in addition to the in-memory exemplars of the ingesters. Enable it once the ingesters store the exemplars in the blocks with -blocks-storage.tsdb.block-exemplars-enabled, and the store-gateways are able to serve them.")
+	f.BoolVar(&cfg.DeduplicateRepeatedSelectors, "querier.deduplicate-repeated-selectors", false, "If enabled, the series of identical selectors repeated within a query, like in `a / (a + b)`, are fetched once and shared across the sub-expressions.")
+	f.BoolVar(&cfg.IngestStorageFallbackEnabled, "querier.ingest-storage-fallback-enabled", false, "If enabled, when querying the series of the ingesters fails, the series are read directly from the partitions of the ingest storage instead. All the records written to the partitions since the start of the queried time range, clamped to -"+queryIngestersWithinFlag+", are fetched and decoded, which is much slower than querying the ingesters. The exemplars, and the series computed by the ingest aggregation rules, are not read from the partitions. Requires -ingest-storage.enabled.")
+
 	cfg.EngineConfig.RegisterFlags(f)
 }
 
@@ -86,6 +100,10 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if err := cfg.StoreGatewayClient.FaultInjection.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -112,6 +130,12 @@ func New(cfg Config, limits *validation.Overrides, distributor Distributor, stor
 		}
 	}
 	queryable := NewQueryable(distributorQueryable, ns, iteratorFunc, cfg, limits, logger)
+	if cfg.DeduplicateRepeatedSelectors {
+		queryable = newRepeatedSelectorsQueryable(queryable, promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_deduplicated_selects_total",
+			Help: "Number of selects whose series have been shared with an identical select of the same query.",
+		}))
+	}
 	exemplarQueryable := newDistributorExemplarQueryable(distributor, logger)
 
 	lazyQueryable := storage.QueryableFunc(func(ctx context.Context, mint int64, maxt int64) (storage.Querier, error) {
@@ -171,6 +195,7 @@ func NewQueryable(distributor QueryableWithFilter, stores []QueryableWithFilter,
 		}
 
 		ctx = limiter.AddQueryLimiterToContext(ctx, limiter.NewQueryLimiter(limits.MaxFetchedSeriesPerQuery(userID), limits.MaxFetchedChunkBytesPerQuery(userID), limits.MaxChunksPerQuery(userID)))
+		ctx = limiter.AddMemoryTrackerToContext(ctx, limiter.NewMemoryTracker(limits.MaxEstimatedMemoryPerQuery(userID)))
 
 		mint, maxt, err = validateQueryTimeRange(ctx, userID, mint, maxt, limits, cfg.MaxQueryIntoFuture, logger)
 		if errors.Is(err, errEmptyTimeRange) {
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// newRepeatedSelectorsQueryable returns a storage.Queryable whose queriers fetch the series of identical
+// selectors only once. The PromQL engine creates a querier per query, and calls Select() for each selector
+// of the query, so selectors repeated within a query, like in `a / (a + b)`, are fetched once and their
+// series are shared across the sub-expressions.
+func newRepeatedSelectorsQueryable(next storage.Queryable, deduplicated prometheus.Counter) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		q, err := next.Querier(ctx, mint, maxt)
+		if err != nil {
+			return nil, err
+		}
+
+		return &repeatedSelectorsQuerier{
+			Querier:      q,
+			deduplicated: deduplicated,
+			selects:      map[string]*sharedSelect{},
+		}, nil
+	})
+}
+
+type repeatedSelectorsQuerier struct {
+	storage.Querier
+
+	deduplicated prometheus.Counter
+
+	mtx     sync.Mutex
+	selects map[string]*sharedSelect
+}
+
+// sharedSelect holds the result of a Select(), which is fetched once and shared by the identical selectors.
+type sharedSelect struct {
+	done     chan struct{}
+	series   []storage.Series
+	warnings storage.Warnings
+	err      error
+}
+
+// Select implements storage.Querier.
+func (q *repeatedSelectorsQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	key := selectKey(sortSeries, hints, matchers)
+
+	q.mtx.Lock()
+	shared, found := q.selects[key]
+	if !found {
+		shared = &sharedSelect{done: make(chan struct{})}
+		q.selects[key] = shared
+	}
+	q.mtx.Unlock()
+
+	if found {
+		q.deduplicated.Inc()
+		// The Select() may be running concurrently, for example if the querier is lazy.
+		<-shared.done
+	} else {
+		set := q.Querier.Select(sortSeries, hints, matchers...)
+		for set.Next() {
+			shared.series = append(shared.series, set.At())
+		}
+		shared.warnings = set.Warnings()
+		shared.err = set.Err()
+		close(shared.done)
+	}
+
+	if shared.err != nil {
+		return storage.ErrSeriesSet(shared.err)
+	}
+	return &sharedSeriesSet{series: shared.series, warnings: shared.warnings, cur: -1}
+}
+
+// selectKey returns a key identifying the input Select() parameters. The querier uses the hint describing the
+// surrounding function to detect the series-only requests and to pick the aggregate queried in the downsampled
+// blocks, so the function is part of the key through them only: the selectors differing only in the surrounding
+// aggregation, like in `sum(a) / count(a)`, share the same key, while `max_over_time(a[1h]) - min_over_time(a[1h])`
+// selects different series from the downsampled blocks and doesn't.
+func selectKey(sortSeries bool, hints *storage.SelectHints, matchers []*labels.Matcher) string {
+	b := strings.Builder{}
+	b.WriteString(strconv.FormatBool(sortSeries))
+
+	if hints != nil {
+		for _, v := range []int64{hints.Start, hints.End, hints.Step, hints.Range} {
+			b.WriteByte(',')
+			b.WriteString(strconv.FormatInt(v, 10))
+		}
+		for _, v := range []uint64{hints.ShardIndex, hints.ShardCount} {
+			b.WriteByte(',')
+			b.WriteString(strconv.FormatUint(v, 10))
+		}
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatBool(hints.Func == "series"))
+		b.WriteByte(',')
+		b.WriteString(downsampledAggregate(hints))
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatBool(hints.DisableTrimming))
+	}
+
+	// The matchers order doesn't change the selected series.
+	matcherStrings := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		matcherStrings = append(matcherStrings, m.String())
+	}
+	sort.Strings(matcherStrings)
+
+	for _, m := range matcherStrings {
+		b.WriteByte(0)
+		b.WriteString(m)
+	}
+	return b.String()
+}
+
+// sharedSeriesSet is a storage.SeriesSet over series shared with other series sets. Contrary to
+// series.ConcreteSeriesSet, it never modifies the input series slice.
+type sharedSeriesSet struct {
+	series   []storage.Series
+	warnings storage.Warnings
+	cur      int
+}
+
+func (s *sharedSeriesSet) Next() bool {
+	s.cur++
+	return s.cur < len(s.series)
+}
+
+func (s *sharedSeriesSet) At() storage.Series {
+	return s.series[s.cur]
+}
+
+func (s *sharedSeriesSet) Err() error {
+	return nil
+}
+
+func (s *sharedSeriesSet) Warnings() storage.Warnings {
+	return s.warnings
+}
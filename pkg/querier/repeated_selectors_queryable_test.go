@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestRepeatedSelectorsQueryable(t *testing.T) {
+	test, err := promql.NewTest(t, `
+		load 1m
+			a{job="1"} 1+1x30
+			a{job="2"} 2+2x30
+			b{job="1"} 3+3x30
+	`)
+	require.NoError(t, err)
+	t.Cleanup(test.Close)
+	require.NoError(t, test.Run())
+
+	engine := promql.NewEngine(promql.EngineOpts{MaxSamples: 1e6, Timeout: time.Minute})
+	start, end, step := time.Unix(0, 0), time.Unix(0, 0).Add(30*time.Minute), time.Minute
+
+	tests := map[string]struct {
+		query                string
+		expectedSelects      int64
+		expectedDeduplicated float64
+	}{
+		"should fetch the repeated selectors once": {
+			query:                `a / (a + b)`,
+			expectedSelects:      2,
+			expectedDeduplicated: 1,
+		},
+		"should fetch the repeated selectors once regardless of the surrounding aggregations": {
+			query:                `sum(a) / count(a)`,
+			expectedSelects:      1,
+			expectedDeduplicated: 1,
+		},
+		"should fetch the repeated selectors once regardless of the matchers order": {
+			query:                `{job="1",__name__="a"} + {__name__="a",job="1"}`,
+			expectedSelects:      1,
+			expectedDeduplicated: 1,
+		},
+		"should fetch the selectors with different time ranges separately": {
+			query:           `a - a offset 5m`,
+			expectedSelects: 2,
+		},
+		"should fetch the selectors with different ranges separately": {
+			query:           `rate(a[5m]) + rate(a[10m])`,
+			expectedSelects: 2,
+		},
+		"should fetch the selectors of functions querying different downsampled aggregates separately": {
+			query:           `max_over_time(a[5m]) - min_over_time(a[5m])`,
+			expectedSelects: 2,
+		},
+		"should fetch the repeated selectors once if the functions query the same downsampled aggregate": {
+			query:                `rate(a[5m]) / increase(a[5m])`,
+			expectedSelects:      1,
+			expectedDeduplicated: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			selects := atomic.NewInt64(0)
+			counting := storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+				q, err := test.Storage().Querier(ctx, mint, maxt)
+				if err != nil {
+					return nil, err
+				}
+				return &countingQuerier{Querier: q, selects: selects}, nil
+			})
+
+			deduplicated := prometheus.NewCounter(prometheus.CounterOpts{Name: "test"})
+			queryable := newRepeatedSelectorsQueryable(counting, deduplicated)
+
+			expected := runRangeQuery(t, engine, test.Storage(), tc.query, start, end, step)
+			actual := runRangeQuery(t, engine, queryable, tc.query, start, end, step)
+
+			assert.Equal(t, expected, actual)
+			assert.Equal(t, tc.expectedSelects, selects.Load())
+			assert.Equal(t, tc.expectedDeduplicated, testutil.ToFloat64(deduplicated))
+		})
+	}
+}
+
+func TestRepeatedSelectorsQuerier_ShouldShareErrors(t *testing.T) {
+	selects := atomic.NewInt64(0)
+	next := storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		return &countingQuerier{Querier: storage.NoopQuerier(), selects: selects, err: assert.AnError}, nil
+	})
+
+	q, err := newRepeatedSelectorsQueryable(next, prometheus.NewCounter(prometheus.CounterOpts{Name: "test"})).Querier(context.Background(), 0, 10)
+	require.NoError(t, err)
+
+	matcher := labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "a")
+	for i := 0; i < 2; i++ {
+		set := q.Select(true, nil, matcher)
+		assert.False(t, set.Next())
+		assert.ErrorIs(t, set.Err(), assert.AnError)
+	}
+	assert.Equal(t, int64(1), selects.Load())
+}
+
+// runRangeQuery returns the result of the query as a string, given the result can't be used once the query is closed.
+func runRangeQuery(t *testing.T, engine *promql.Engine, queryable storage.Queryable, query string, start, end time.Time, step time.Duration) string {
+	q, err := engine.NewRangeQuery(queryable, nil, query, start, end, step)
+	require.NoError(t, err)
+	defer q.Close()
+
+	res := q.Exec(context.Background())
+	require.NoError(t, res.Err)
+
+	m, err := res.Matrix()
+	require.NoError(t, err)
+	require.NotEmpty(t, m)
+	return m.String()
+}
+
+type countingQuerier struct {
+	storage.Querier
+
+	selects *atomic.Int64
+	err     error
+}
+
+func (q *countingQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	q.selects.Inc()
+	if q.err != nil {
+		return storage.ErrSeriesSet(q.err)
+	}
+	return q.Querier.Select(sortSeries, hints, matchers...)
+}
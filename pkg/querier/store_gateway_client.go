@@ -20,9 +20,10 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/util/faultinjection"
 )
 
-func newStoreGatewayClientFactory(clientCfg grpcclient.Config, reg prometheus.Registerer) client.PoolFactory {
+func newStoreGatewayClientFactory(clientCfg grpcclient.Config, faultInjectionCfg faultinjection.Config, reg prometheus.Registerer) client.PoolFactory {
 	requestDuration := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
 		Namespace:   "cortex",
 		Name:        "storegateway_client_request_duration_seconds",
@@ -32,12 +33,12 @@ func newStoreGatewayClientFactory(clientCfg grpcclient.Config, reg prometheus.Re
 	}, []string{"operation", "status_code"})
 
 	return func(addr string) (client.PoolClient, error) {
-		return dialStoreGatewayClient(clientCfg, addr, requestDuration)
+		return dialStoreGatewayClient(clientCfg, faultInjectionCfg, addr, requestDuration)
 	}
 }
 
-func dialStoreGatewayClient(clientCfg grpcclient.Config, addr string, requestDuration *prometheus.HistogramVec) (*storeGatewayClient, error) {
-	opts, err := clientCfg.DialOption(grpcclient.Instrument(requestDuration))
+func dialStoreGatewayClient(clientCfg grpcclient.Config, faultInjectionCfg faultinjection.Config, addr string, requestDuration *prometheus.HistogramVec) (*storeGatewayClient, error) {
+	opts, err := clientCfg.DialOption(faultInjectionCfg.Interceptors(grpcclient.Instrument(requestDuration)))
 	if err != nil {
 		return nil, err
 	}
@@ -97,15 +98,17 @@ func newStoreGatewayClientPool(discovery client.PoolServiceDiscovery, clientConf
 		ConstLabels: map[string]string{"client": "querier"},
 	})
 
-	return client.NewPool("store-gateway", poolCfg, discovery, newStoreGatewayClientFactory(clientCfg, reg), clientsCount, logger)
+	return client.NewPool("store-gateway", poolCfg, discovery, newStoreGatewayClientFactory(clientCfg, clientConfig.FaultInjection, reg), clientsCount, logger)
 }
 
 type ClientConfig struct {
-	TLSEnabled bool             `yaml:"tls_enabled" category:"advanced"`
-	TLS        tls.ClientConfig `yaml:",inline"`
+	TLSEnabled     bool                  `yaml:"tls_enabled" category:"advanced"`
+	TLS            tls.ClientConfig      `yaml:",inline"`
+	FaultInjection faultinjection.Config `yaml:"fault_injection" doc:"hidden"`
 }
 
 func (cfg *ClientConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.BoolVar(&cfg.TLSEnabled, prefix+".tls-enabled", cfg.TLSEnabled, "Enable TLS for gRPC client connecting to store-gateway.")
 	cfg.TLS.RegisterFlagsWithPrefix(prefix, f)
+	cfg.FaultInjection.RegisterFlagsWithPrefix(prefix+".fault-injection", f)
 }
@@ -21,6 +21,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
 	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util/faultinjection"
 )
 
 func Test_newStoreGatewayClientFactory(t *testing.T) {
@@ -44,7 +45,7 @@ func Test_newStoreGatewayClientFactory(t *testing.T) {
 	flagext.DefaultValues(&cfg)
 
 	reg := prometheus.NewPedanticRegistry()
-	factory := newStoreGatewayClientFactory(cfg, reg)
+	factory := newStoreGatewayClientFactory(cfg, faultinjection.Config{}, reg)
 
 	for i := 0; i < 2; i++ {
 		client, err := factory(listener.Addr().String())
@@ -82,6 +83,18 @@ func (m *mockStoreGatewayServer) LabelNames(context.Context, *storepb.LabelNames
 	return nil, nil
 }
 
+func (m *mockStoreGatewayServer) LabelNamesStream(*storepb.LabelNamesRequest, storegatewaypb.StoreGateway_LabelNamesStreamServer) error {
+	return nil
+}
+
 func (m *mockStoreGatewayServer) LabelValues(context.Context, *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
 	return nil, nil
 }
+
+func (m *mockStoreGatewayServer) LabelValuesStream(*storepb.LabelValuesRequest, storegatewaypb.StoreGateway_LabelValuesStreamServer) error {
+	return nil
+}
+
+func (m *mockStoreGatewayServer) Exemplars(context.Context, *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	return &storegatewaypb.ExemplarsResponse{}, nil
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/weaveworks/common/user"
@@ -30,6 +31,7 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	"github.com/grafana/mimir/pkg/util"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 )
 
@@ -287,8 +289,38 @@ var (
 	ErrNoRuleGroups = errors.New("no rule groups found")
 	// ErrBadRuleGroup is returned when the provided rule group can not be unmarshalled
 	ErrBadRuleGroup = errors.New("unable to decode rule group")
+	// ErrRuleGroupsHistoryDisabled is returned when the rule groups history is requested but it's not enabled
+	ErrRuleGroupsHistoryDisabled = errors.New("the rule groups history is disabled or not supported by the configured rule storage")
+	// ErrBadRuleGroupVersion is returned when the rule group version url parameter can not be parsed
+	ErrBadRuleGroupVersion = errors.New("invalid rule group version")
+	// ErrConflictingRuleGroupQueryOffset is returned when the rule group query offset and evaluation delay are set to different values
+	ErrConflictingRuleGroupQueryOffset = errors.New("invalid rules configuration: the rule group query_offset and evaluation_delay must not be set to different values")
 )
 
+// ruleGroupQueryOffset holds the query offset of a rule group, which is an alias of the rule group
+// evaluation delay using the Prometheus naming.
+type ruleGroupQueryOffset struct {
+	QueryOffset *model.Duration `yaml:"query_offset"`
+}
+
+// applyRuleGroupQueryOffset sets the evaluation delay of the input rule group to the query offset
+// defined in the payload, if any. The query offset is stored as the evaluation delay of the rule group.
+func applyRuleGroupQueryOffset(payload []byte, rg *rulefmt.RuleGroup) error {
+	offset := ruleGroupQueryOffset{}
+	if err := yaml.Unmarshal(payload, &offset); err != nil {
+		return err
+	}
+	if offset.QueryOffset == nil {
+		return nil
+	}
+
+	if rg.EvaluationDelay != nil && *rg.EvaluationDelay != *offset.QueryOffset {
+		return ErrConflictingRuleGroupQueryOffset
+	}
+	rg.EvaluationDelay = offset.QueryOffset
+	return nil
+}
+
 func marshalAndSend(output interface{}, w http.ResponseWriter, logger log.Logger) {
 	d, err := yaml.Marshal(&output)
 	if err != nil {
@@ -464,6 +496,12 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := applyRuleGroupQueryOffset(payload, &rg); err != nil {
+		level.Error(logger).Log("msg", "unable to apply rule group query offset", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	errs := a.ruler.manager.ValidateRuleGroup(rg)
 	if len(errs) > 0 {
 		e := []string{}
@@ -505,6 +543,7 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	a.addRuleGroupVersion(req, logger, userID, namespace, rgProto)
 	respondAccepted(w, logger)
 }
 
@@ -552,6 +591,173 @@ func (a *API) DeleteRuleGroup(w http.ResponseWriter, req *http.Request) {
 	respondAccepted(w, logger)
 }
 
+// ListRuleGroupVersions lists the versions of a rule group retained in the rule groups history.
+func (a *API) ListRuleGroupVersions(w http.ResponseWriter, req *http.Request) {
+	logger := util_log.WithContext(req.Context(), a.logger)
+
+	userID, namespace, groupName, err := parseRequest(req, true, true)
+	if err != nil {
+		respondError(logger, w, err.Error())
+		return
+	}
+
+	history := a.historyStore()
+	if history == nil {
+		http.Error(w, ErrRuleGroupsHistoryDisabled.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	versions, err := history.ListRuleGroupVersions(req.Context(), userID, namespace, groupName)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to list rule group versions", "err", err.Error(), "user", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(versions) == 0 {
+		http.Error(w, rulestore.ErrGroupNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	marshalAndSend(versions, w, logger)
+}
+
+// GetRuleGroupVersion returns a version of a rule group retained in the rule groups history.
+func (a *API) GetRuleGroupVersion(w http.ResponseWriter, req *http.Request) {
+	logger := util_log.WithContext(req.Context(), a.logger)
+
+	rg, ok := a.getRuleGroupVersion(w, req, logger)
+	if !ok {
+		return
+	}
+
+	marshalAndSend(rulespb.FromProto(rg), w, logger)
+}
+
+// RollbackRuleGroup replaces a rule group with a version retained in the rule groups history.
+// The rollback is recorded in the history as a new version.
+func (a *API) RollbackRuleGroup(w http.ResponseWriter, req *http.Request) {
+	logger := util_log.WithContext(req.Context(), a.logger)
+
+	rg, ok := a.getRuleGroupVersion(w, req, logger)
+	if !ok {
+		return
+	}
+
+	userID, namespace := rg.User, rg.Namespace
+	formatted := rulespb.FromProto(rg)
+
+	// The limits may have changed since the version has been stored, so it's validated again.
+	if errs := a.ruler.manager.ValidateRuleGroup(formatted); len(errs) > 0 {
+		e := []string{}
+		for _, err := range errs {
+			level.Error(logger).Log("msg", "unable to validate rule group version", "err", err.Error())
+			e = append(e, err.Error())
+		}
+
+		http.Error(w, strings.Join(e, ", "), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.ruler.AssertMaxRulesPerRuleGroup(userID, len(formatted.Rules)); err != nil {
+		level.Error(logger).Log("msg", "limit validation failure", "err", err.Error(), "user", userID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Rolling back a deleted rule group creates it again.
+	if _, err := a.store.GetRuleGroup(req.Context(), userID, namespace, rg.Name); errors.Is(err, rulestore.ErrGroupNotFound) {
+		rgs, err := a.store.ListRuleGroupsForUserAndNamespace(req.Context(), userID, "")
+		if err != nil {
+			level.Error(logger).Log("msg", "unable to fetch current rule groups for validation", "err", err.Error(), "user", userID)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := a.ruler.AssertMaxRuleGroups(userID, len(rgs)+1); err != nil {
+			level.Error(logger).Log("msg", "limit validation failure", "err", err.Error(), "user", userID)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err != nil {
+		level.Error(logger).Log("msg", "unable to fetch current rule group", "err", err.Error(), "user", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Debug(logger).Log("msg", "attempting to roll back rulegroup", "userID", userID, "group", rg.String())
+	if err := a.store.SetRuleGroup(req.Context(), userID, namespace, rg); err != nil {
+		level.Error(logger).Log("msg", "unable to store rule group", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a.addRuleGroupVersion(req, logger, userID, namespace, rg)
+	respondAccepted(w, logger)
+}
+
+// getRuleGroupVersion loads the rule group version requested by the client. If the version can't be loaded,
+// it writes the error response and returns false.
+func (a *API) getRuleGroupVersion(w http.ResponseWriter, req *http.Request, logger log.Logger) (*rulespb.RuleGroupDesc, bool) {
+	userID, namespace, groupName, err := parseRequest(req, true, true)
+	if err != nil {
+		respondError(logger, w, err.Error())
+		return nil, false
+	}
+
+	version, err := strconv.ParseUint(mux.Vars(req)["version"], 10, 64)
+	if err != nil {
+		http.Error(w, ErrBadRuleGroupVersion.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	history := a.historyStore()
+	if history == nil {
+		http.Error(w, ErrRuleGroupsHistoryDisabled.Error(), http.StatusNotImplemented)
+		return nil, false
+	}
+
+	rg, err := history.GetRuleGroupVersion(req.Context(), userID, namespace, groupName, version)
+	if err != nil {
+		if errors.Is(err, rulestore.ErrGroupVersionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return nil, false
+		}
+		level.Error(logger).Log("msg", "unable to get rule group version", "err", err.Error(), "user", userID)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	// Ensure the version belongs to the requested rule group.
+	rg.User, rg.Namespace, rg.Name = userID, namespace, groupName
+	return rg, true
+}
+
+// addRuleGroupVersion records the rule group, which has just been stored, in the rule groups history.
+// The failure is not returned to the client, because the rule group has been successfully stored.
+func (a *API) addRuleGroupVersion(req *http.Request, logger log.Logger, userID, namespace string, rg *rulespb.RuleGroupDesc) {
+	history := a.historyStore()
+	if history == nil {
+		return
+	}
+
+	author := req.Header.Get(util.ConfigAuthorHeader)
+	if _, err := history.AddRuleGroupVersion(req.Context(), userID, namespace, rg, author, a.ruler.cfg.RuleGroupsHistorySize); err != nil {
+		level.Warn(logger).Log("msg", "unable to record rule group in the rule groups history", "err", err.Error(), "user", userID)
+	}
+}
+
+// historyStore returns the store of the rule groups history, or nil if the history is disabled
+// or not supported by the configured rule storage.
+func (a *API) historyStore() rulestore.RuleGroupHistoryStore {
+	if a.ruler == nil || a.ruler.cfg.RuleGroupsHistorySize <= 0 {
+		return nil
+	}
+
+	history, _ := a.store.(rulestore.RuleGroupHistoryStore)
+	return history
+}
+
 // alertStateDescToPrometheusAlert converts AlertStateDesc to Alert. The returned data structure is suitable
 // to be exported by the user-facing API.
 func alertStateDescToPrometheusAlert(d *AlertStateDesc) *Alert {
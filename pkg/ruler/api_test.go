@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -22,9 +23,14 @@ import (
 	"github.com/grafana/dskit/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
 	"github.com/weaveworks/common/user"
+	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	"github.com/grafana/mimir/pkg/ruler/rulestore/bucketclient"
+	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -461,6 +467,65 @@ rules:
 `,
 			output: "name: test\ninterval: 15s\nsource_tenants: [t1, t2]\nrules:\n    - record: up_rule\n      expr: up{}\n    - alert: up_alert\n      expr: sum(up{}) > 1\n      for: 30s\n      labels:\n        test: test\n      annotations:\n        test: test\n",
 		},
+		{
+			name:   "with evaluation delay and alignment of the evaluation time on interval",
+			cfg:    defaultCfg,
+			status: 202,
+			input: `
+name: test
+interval: 1m
+evaluation_delay: 2m
+align_evaluation_time_on_interval: true
+rules:
+- alert: up_alert
+  expr: absent(up{})
+`,
+			output: "name: test\ninterval: 1m\nevaluation_delay: 2m\nalign_evaluation_time_on_interval: true\nrules:\n    - alert: up_alert\n      expr: absent(up{})\n",
+		},
+		{
+			name:   "with query offset",
+			cfg:    defaultCfg,
+			status: 202,
+			input: `
+name: test
+interval: 1m
+query_offset: 2m
+rules:
+- alert: up_alert
+  expr: absent(up{})
+`,
+			output: "name: test\ninterval: 1m\nevaluation_delay: 2m\nrules:\n    - alert: up_alert\n      expr: absent(up{})\n",
+		},
+		{
+			name:   "with query offset equal to the evaluation delay",
+			cfg:    defaultCfg,
+			status: 202,
+			input: `
+name: test
+interval: 1m
+query_offset: 2m
+evaluation_delay: 2m
+rules:
+- alert: up_alert
+  expr: absent(up{})
+`,
+			output: "name: test\ninterval: 1m\nevaluation_delay: 2m\nrules:\n    - alert: up_alert\n      expr: absent(up{})\n",
+		},
+		{
+			name:   "with query offset different than the evaluation delay",
+			cfg:    defaultCfg,
+			status: 400,
+			input: `
+name: test
+interval: 1m
+query_offset: 2m
+evaluation_delay: 1m
+rules:
+- alert: up_alert
+  expr: absent(up{})
+`,
+			err: ErrConflictingRuleGroupQueryOffset,
+		},
 	}
 
 	for _, tt := range tc {
@@ -549,6 +614,97 @@ func TestRuler_DeleteNamespace(t *testing.T) {
 	require.Equal(t, "{\"status\":\"error\",\"data\":null,\"errorType\":\"server_error\",\"error\":\"unable to delete rg\"}", w.Body.String())
 }
 
+func TestRuler_RuleGroupHistory(t *testing.T) {
+	createGroup := func(interval string) string {
+		return "name: test\ninterval: " + interval + "\nrules:\n    - record: up_rule\n      expr: up{}\n"
+	}
+
+	t.Run("should return 501 when the history is disabled", func(t *testing.T) {
+		cfg := defaultRulerConfig(t)
+		r := prepareRuler(t, cfg, bucketclient.NewBucketRuleStore(objstore.NewInMemBucket(), nil, log.NewNopLogger()), withStart())
+		a := NewAPI(r, r.store, log.NewNopLogger())
+
+		router := mux.NewRouter()
+		router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}/history").Methods(http.MethodGet).HandlerFunc(a.ListRuleGroupVersions)
+
+		req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/config/v1/rules/namespace/test/history", nil, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	t.Run("should list and roll back to previous versions of a rule group", func(t *testing.T) {
+		cfg := defaultRulerConfig(t)
+		cfg.RuleGroupsHistorySize = 2
+		r := prepareRuler(t, cfg, bucketclient.NewBucketRuleStore(objstore.NewInMemBucket(), nil, log.NewNopLogger()), withStart())
+		a := NewAPI(r, r.store, log.NewNopLogger())
+
+		router := mux.NewRouter()
+		router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodPost).HandlerFunc(a.CreateRuleGroup)
+		router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}").Methods(http.MethodGet).HandlerFunc(a.GetRuleGroup)
+		router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}/history").Methods(http.MethodGet).HandlerFunc(a.ListRuleGroupVersions)
+		router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}/history/{version}").Methods(http.MethodGet).HandlerFunc(a.GetRuleGroupVersion)
+		router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}/history/{version}/rollback").Methods(http.MethodPost).HandlerFunc(a.RollbackRuleGroup)
+
+		for _, interval := range []string{"15s", "30s", "45s"} {
+			req := requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/namespace", strings.NewReader(createGroup(interval)), "user1")
+			req.Header.Set(util.ConfigAuthorHeader, "author-"+interval)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusAccepted, w.Code)
+		}
+
+		// Only the latest 2 versions are retained.
+		req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/config/v1/rules/namespace/test/history", nil, "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var versions []rulestore.RuleGroupVersion
+		require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &versions))
+		require.Len(t, versions, 2)
+		assert.Equal(t, "author-30s", versions[0].Author)
+		assert.Equal(t, "author-45s", versions[1].Author)
+		assert.Less(t, versions[0].Version, versions[1].Version)
+		secondVersion, thirdVersion := strconv.FormatUint(versions[0].Version, 10), versions[1].Version
+
+		req = requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/config/v1/rules/namespace/test/history/1", nil, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		req = requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/config/v1/rules/namespace/test/history/"+secondVersion, nil, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.YAMLEq(t, createGroup("30s"), w.Body.String())
+
+		// Roll back to the second version.
+		req = requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/namespace/test/history/"+secondVersion+"/rollback", nil, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		req = requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/config/v1/rules/namespace/test", nil, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.YAMLEq(t, createGroup("30s"), w.Body.String())
+
+		// The rollback has been recorded as a new version.
+		req = requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/config/v1/rules/namespace/test/history", nil, "user1")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		versions = nil
+		require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &versions))
+		require.Len(t, versions, 2)
+		assert.Equal(t, thirdVersion, versions[0].Version)
+		assert.Greater(t, versions[1].Version, thirdVersion)
+	})
+}
+
 func TestRuler_LimitsPerGroup(t *testing.T) {
 	cfg := defaultRulerConfig(t)
 
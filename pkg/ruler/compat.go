@@ -30,6 +30,7 @@ import (
 	"github.com/grafana/mimir/pkg/querier"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	util_math "github.com/grafana/mimir/pkg/util/math"
 )
 
 // Pusher is an ingester server that accepts pushes.
@@ -144,6 +145,9 @@ type RulesLimits interface {
 	RulerMaxRulesPerRuleGroup(userID string) int
 	RulerRecordingRulesEvaluationEnabled(userID string) bool
 	RulerAlertingRulesEvaluationEnabled(userID string) bool
+	RulerRemoteEvaluationEnabled(userID string) bool
+	RulerMaxIndependentRuleConcurrency(userID string) int
+	RulerForStateRestoreLookback(userID string) time.Duration
 }
 
 func MetricsQueryFunc(qf rules.QueryFunc, queries, failedQueries prometheus.Counter) rules.QueryFunc {
@@ -227,6 +231,43 @@ func RecordAndReportRuleQueryMetrics(qf rules.QueryFunc, queryTime prometheus.Co
 	}
 }
 
+// RemoteEvaluationQueryable returns a storage.Queryable querying remoteQueryable if the remote evaluation is
+// enabled for the user, and embeddedQueryable otherwise. The limit is checked at each query, so that it can be
+// changed at runtime without recreating the user's rules manager.
+func RemoteEvaluationQueryable(userID string, limits RulesLimits, embeddedQueryable, remoteQueryable storage.Queryable) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		if limits.RulerRemoteEvaluationEnabled(userID) {
+			return remoteQueryable.Querier(ctx, mint, maxt)
+		}
+		return embeddedQueryable.Querier(ctx, mint, maxt)
+	})
+}
+
+// ForStateRestoreQueryable returns a storage.Queryable extending the time range of the queries to the user's
+// for-state restore lookback, if longer. The rules manager queries the ALERTS_FOR_STATE series between the
+// evaluation time and -ruler.for-outage-tolerance before, so a longer lookback allows to restore the "for"
+// state of the alerts after longer outages, reading the series from the store-gateways when the time range
+// goes beyond the ingesters retention.
+func ForStateRestoreQueryable(userID string, limits RulesLimits, queryable storage.Queryable) storage.Queryable {
+	return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+		if lookback := limits.RulerForStateRestoreLookback(userID); lookback > 0 {
+			mint = util_math.Min(mint, maxt-lookback.Milliseconds())
+		}
+		return queryable.Querier(ctx, mint, maxt)
+	})
+}
+
+// RemoteEvaluationQueryFunc returns a rules.QueryFunc evaluating the rule expressions through remoteQueryFunc if
+// the remote evaluation is enabled for the user, and through embeddedQueryFunc otherwise.
+func RemoteEvaluationQueryFunc(userID string, limits RulesLimits, embeddedQueryFunc, remoteQueryFunc rules.QueryFunc) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		if limits.RulerRemoteEvaluationEnabled(userID) {
+			return remoteQueryFunc(ctx, qs, t)
+		}
+		return embeddedQueryFunc(ctx, qs, t)
+	}
+}
+
 // RulesManager mimics rules.Manager API. Interface is used to simplify tests.
 type RulesManager interface {
 	// Run starts the rules manager. Blocks until Stop is called.
@@ -245,11 +286,16 @@ type RulesManager interface {
 // ManagerFactory is a function that creates new RulesManager for given user and notifier.Manager.
 type ManagerFactory func(ctx context.Context, userID string, notifier *notifier.Manager, logger log.Logger, reg prometheus.Registerer) RulesManager
 
+// DefaultTenantManagerFactory returns a ManagerFactory evaluating the rules through embeddedQueryable and
+// embeddedQueryFunc. If remoteQueryable and remoteQueryFunc are not nil, they're used instead for the users
+// with the remote evaluation enabled.
 func DefaultTenantManagerFactory(
 	cfg Config,
 	p Pusher,
 	embeddedQueryable storage.Queryable,
-	queryFunc rules.QueryFunc,
+	embeddedQueryFunc rules.QueryFunc,
+	remoteQueryable storage.Queryable,
+	remoteQueryFunc rules.QueryFunc,
 	overrides RulesLimits,
 	reg prometheus.Registerer,
 ) ManagerFactory {
@@ -270,6 +316,10 @@ func DefaultTenantManagerFactory(
 		Name: "cortex_ruler_queries_failed_total",
 		Help: "Number of failed queries by ruler.",
 	})
+	concurrentQueries := promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_ruler_independent_rule_concurrent_queries_total",
+		Help: "Number of queries of independent rules evaluated concurrently to the other rules of their rule group.",
+	})
 	var rulerQuerySeconds *prometheus.CounterVec
 	if cfg.EnableQueryStats {
 		rulerQuerySeconds = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
@@ -282,17 +332,27 @@ func DefaultTenantManagerFactory(
 		if rulerQuerySeconds != nil {
 			queryTime = rulerQuerySeconds.WithLabelValues(userID)
 		}
+		queryable, queryFunc := embeddedQueryable, embeddedQueryFunc
+		if remoteQueryable != nil && remoteQueryFunc != nil {
+			queryable = RemoteEvaluationQueryable(userID, overrides, embeddedQueryable, remoteQueryable)
+			queryFunc = RemoteEvaluationQueryFunc(userID, overrides, embeddedQueryFunc, remoteQueryFunc)
+		}
+
+		// The rules manager only uses the queryable to restore the "for" state of the alerts.
+		queryable = ForStateRestoreQueryable(userID, overrides, queryable)
+
 		var wrappedQueryFunc rules.QueryFunc
 
 		wrappedQueryFunc = MetricsQueryFunc(queryFunc, totalQueries, failedQueries)
 		wrappedQueryFunc = RecordAndReportRuleQueryMetrics(wrappedQueryFunc, queryTime, logger)
+		wrappedQueryFunc = concurrentIndependentRulesQueryFunc(wrappedQueryFunc, newTenantConcurrencyController(userID, overrides, concurrentQueries))
 
 		return rules.NewManager(&rules.ManagerOptions{
 			Appendable:                 NewPusherAppendable(p, userID, overrides, totalWrites, failedWrites),
-			Queryable:                  embeddedQueryable,
+			Queryable:                  queryable,
 			QueryFunc:                  wrappedQueryFunc,
 			Context:                    user.InjectOrgID(ctx, userID),
-			GroupEvaluationContextFunc: FederatedGroupContextFunc,
+			GroupEvaluationContextFunc: groupEvaluationContextFunc,
 			ExternalURL:                cfg.ExternalURL.URL,
 			NotifyFunc:                 rules.SendAlerts(notifier, cfg.ExternalURL.String()),
 			Logger:                     log.With(logger, "user", userID),
@@ -310,6 +370,12 @@ func DefaultTenantManagerFactory(
 	}
 }
 
+// groupEvaluationContextFunc prepares the context for the evaluation of the rule group.
+func groupEvaluationContextFunc(ctx context.Context, g *rules.Group) context.Context {
+	ctx = FederatedGroupContextFunc(ctx, g)
+	return independentRulesGroupContextFunc(ctx, g)
+}
+
 type QueryableError struct {
 	err error
 }
@@ -8,6 +8,7 @@ package ruler
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"net/http"
 	"testing"
@@ -27,6 +28,7 @@ import (
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/rules"
 	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/teststorage"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/httpgrpc"
 
@@ -433,7 +435,7 @@ func TestManagerFactory_CorrectQueryableUsed(t *testing.T) {
 			// create and use manager factory
 			pusher := newPusherMock()
 			pusher.MockPush(&mimirpb.WriteResponse{}, nil)
-			managerFactory := DefaultTenantManagerFactory(cfg, pusher, federatedQueryable, queryFunc, options.limits, nil)
+			managerFactory := DefaultTenantManagerFactory(cfg, pusher, federatedQueryable, queryFunc, nil, nil, options.limits, nil)
 
 			manager := managerFactory(context.Background(), userID, notifierManager, options.logger, nil)
 
@@ -454,6 +456,169 @@ func TestManagerFactory_CorrectQueryableUsed(t *testing.T) {
 	}
 }
 
+// TestManagerFactory_RemoteEvaluation ensures that the remote queryable is called for the tenants with the remote
+// evaluation enabled, and the embedded queryable is called otherwise.
+func TestManagerFactory_RemoteEvaluation(t *testing.T) {
+	const userID = "tenant-1"
+
+	ruleGroup := rulespb.RuleGroupDesc{
+		Name:  "group",
+		Rules: []*rulespb.RuleDesc{mockRecordingRuleDesc("sum:up", "sum(up)")},
+	}
+
+	for _, remoteEvaluationEnabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("remote evaluation enabled: %t", remoteEvaluationEnabled), func(t *testing.T) {
+			cfg := defaultRulerConfig(t)
+			options := applyPrepareOptions()
+			notifierManager := notifier.NewManager(&notifier.Options{Do: func(_ context.Context, _ *http.Client, _ *http.Request) (*http.Response, error) { return nil, nil }}, options.logger)
+			ruleFiles := writeRuleGroupToFiles(t, cfg.RulePath, options.logger, userID, ruleGroup)
+			embeddedQueryable, remoteQueryable := newMockQueryable(), newMockQueryable()
+
+			limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+				defaults.RulerEvaluationDelay = 0
+				defaults.RulerRemoteEvaluationEnabled = remoteEvaluationEnabled
+			})
+
+			tracker := promql.NewActiveQueryTracker(t.TempDir(), 20, log.NewNopLogger())
+			eng := promql.NewEngine(promql.EngineOpts{
+				MaxSamples:         1e6,
+				ActiveQueryTracker: tracker,
+				Timeout:            2 * time.Minute,
+			})
+
+			pusher := newPusherMock()
+			pusher.MockPush(&mimirpb.WriteResponse{}, nil)
+			managerFactory := DefaultTenantManagerFactory(cfg, pusher, embeddedQueryable, rules.EngineQueryFunc(eng, embeddedQueryable), remoteQueryable, rules.EngineQueryFunc(eng, remoteQueryable), limits, nil)
+
+			manager := managerFactory(context.Background(), userID, notifierManager, options.logger, nil)
+
+			require.NoError(t, manager.Update(time.Millisecond, ruleFiles, nil, "", nil))
+			go manager.Run()
+
+			select {
+			case <-embeddedQueryable.called:
+				require.False(t, remoteEvaluationEnabled, "unexpected call to embedded queryable")
+			case <-remoteQueryable.called:
+				require.True(t, remoteEvaluationEnabled, "unexpected call to remote queryable")
+			case <-time.NewTimer(time.Second).C:
+				require.Fail(t, "neither of the queryables was called within the timeout")
+			}
+			manager.Stop()
+		})
+	}
+}
+
+func TestForStateRestoreQueryable(t *testing.T) {
+	const userID = "tenant-1"
+	maxt := time.Now().UnixMilli()
+	mint := maxt - time.Hour.Milliseconds()
+
+	tests := map[string]struct {
+		lookback     time.Duration
+		expectedMint int64
+	}{
+		"lookback disabled": {
+			lookback:     0,
+			expectedMint: mint,
+		},
+		"lookback shorter than the queried time range": {
+			lookback:     30 * time.Minute,
+			expectedMint: mint,
+		},
+		"lookback longer than the queried time range": {
+			lookback:     24 * time.Hour,
+			expectedMint: maxt - (24 * time.Hour).Milliseconds(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+				defaults.RulerForStateRestoreLookback = model.Duration(testData.lookback)
+			})
+
+			var actualMint, actualMaxt int64
+			queryable := storage.QueryableFunc(func(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+				actualMint, actualMaxt = mint, maxt
+				return storage.NoopQuerier(), nil
+			})
+
+			_, err := ForStateRestoreQueryable(userID, limits, queryable).Querier(context.Background(), mint, maxt)
+			require.NoError(t, err)
+			require.Equal(t, testData.expectedMint, actualMint)
+			require.Equal(t, maxt, actualMaxt)
+		})
+	}
+}
+
+func TestForStateRestoreQueryable_ShouldRestoreAlertsAfterOutagesLongerThanOutageTolerance(t *testing.T) {
+	const userID = "tenant-1"
+
+	// The alert has been pending for 1h before an outage of the ruler lasting 3h.
+	now := time.Now().Truncate(time.Second)
+	activeAt := now.Add(-4 * time.Hour)
+	downAt := now.Add(-3 * time.Hour)
+
+	tests := map[string]struct {
+		lookback         time.Duration
+		expectedActiveAt time.Time
+	}{
+		"lookback disabled": {
+			lookback:         0,
+			expectedActiveAt: now,
+		},
+		"lookback longer than the outage": {
+			lookback:         12 * time.Hour,
+			expectedActiveAt: activeAt.Add(now.Sub(downAt)),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			store := teststorage.New(t)
+			t.Cleanup(func() { require.NoError(t, store.Close()) })
+
+			app := store.Appender(context.Background())
+			_, err := app.Append(0, labels.FromStrings(labels.MetricName, alertForStateMetricName, labels.AlertName, "HighLatency", "job", "api"), downAt.UnixMilli(), float64(activeAt.Unix()))
+			require.NoError(t, err)
+			require.NoError(t, app.Commit())
+
+			limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+				defaults.RulerForStateRestoreLookback = model.Duration(testData.lookback)
+			})
+
+			expr, err := parser.ParseExpr(`latency_seconds > 1`)
+			require.NoError(t, err)
+			rule := rules.NewAlertingRule("HighLatency", expr, 6*time.Hour, 0, nil, nil, nil, "", true, log.NewNopLogger())
+
+			group := rules.NewGroup(rules.GroupOptions{
+				Name:     "group",
+				Interval: time.Minute,
+				Rules:    []rules.Rule{rule},
+				Opts: &rules.ManagerOptions{
+					Queryable:       ForStateRestoreQueryable(userID, limits, store),
+					Context:         context.Background(),
+					Logger:          log.NewNopLogger(),
+					OutageTolerance: time.Hour,
+					ForGracePeriod:  10 * time.Minute,
+				},
+			})
+
+			queryFunc := func(context.Context, string, time.Time) (promql.Vector, error) {
+				return promql.Vector{{Metric: labels.FromStrings("job", "api"), Point: promql.Point{T: now.UnixMilli(), V: 2}}}, nil
+			}
+			_, err = rule.Eval(context.Background(), 0, now, queryFunc, nil, 0)
+			require.NoError(t, err)
+
+			group.RestoreForState(now)
+
+			alerts := rule.ActiveAlerts()
+			require.Len(t, alerts, 1)
+			require.Equal(t, testData.expectedActiveAt.UTC(), alerts[0].ActiveAt.UTC())
+		})
+	}
+}
+
 func writeRuleGroupToFiles(t *testing.T, path string, logger log.Logger, userID string, ruleGroup rulespb.RuleGroupDesc) []string {
 	_, files, err := newMapper(path, logger).MapRules(userID, map[string][]rulefmt.RuleGroup{
 		"namespace": {rulespb.FromProto(&ruleGroup)},
@@ -44,8 +44,6 @@ const (
 
 	statusError = "error"
 
-	maxRequestRetries = 3
-
 	formatJSON     = "json"
 	formatProtobuf = "protobuf"
 )
@@ -62,6 +60,12 @@ type QueryFrontendConfig struct {
 	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config" doc:"description=Configures the gRPC client used to communicate between the rulers and query-frontends."`
 
 	QueryResultResponseFormat string `yaml:"query_result_response_format" category:"experimental"`
+
+	// Timeout and retries of the requests to the query-frontend.
+	Timeout    time.Duration `yaml:"timeout" category:"experimental"`
+	MaxRetries int           `yaml:"max_retries" category:"experimental"`
+	MinBackoff time.Duration `yaml:"min_backoff" category:"experimental"`
+	MaxBackoff time.Duration `yaml:"max_backoff" category:"experimental"`
 }
 
 func (c *QueryFrontendConfig) RegisterFlags(f *flag.FlagSet) {
@@ -74,16 +78,39 @@ func (c *QueryFrontendConfig) RegisterFlags(f *flag.FlagSet) {
 	c.GRPCClientConfig.RegisterFlagsWithPrefix("ruler.query-frontend.grpc-client-config", f)
 
 	f.StringVar(&c.QueryResultResponseFormat, "ruler.query-frontend.query-result-response-format", formatJSON, fmt.Sprintf("Format to use when retrieving query results from query-frontends. Supported values: %s", strings.Join(allFormats, ", ")))
+
+	f.DurationVar(&c.Timeout, "ruler.query-frontend.timeout", 0, "Timeout of a rule expression evaluated against the query-frontend, including the retries. 0 to use the querier query timeout (-querier.timeout).")
+	f.IntVar(&c.MaxRetries, "ruler.query-frontend.max-retries", 3, "Maximum number of times a failed request to the query-frontend is retried. 0 to disable retries.")
+	f.DurationVar(&c.MinBackoff, "ruler.query-frontend.min-backoff", 100*time.Millisecond, "Minimum delay before retrying a failed request to the query-frontend.")
+	f.DurationVar(&c.MaxBackoff, "ruler.query-frontend.max-backoff", 2*time.Second, "Maximum delay before retrying a failed request to the query-frontend.")
 }
 
 func (c *QueryFrontendConfig) Validate() error {
 	if !slices.Contains(allFormats, c.QueryResultResponseFormat) {
 		return fmt.Errorf("unknown query result response format '%s'. Supported values: %s", c.QueryResultResponseFormat, strings.Join(allFormats, ", "))
 	}
+	if c.Timeout < 0 {
+		return errors.New("the query-frontend timeout must be greater than or equal to 0")
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("the query-frontend max retries must be greater than or equal to 0")
+	}
+	if c.MinBackoff <= 0 || c.MaxBackoff < c.MinBackoff {
+		return errors.New("the query-frontend min backoff must be greater than 0 and lower than or equal to the max backoff")
+	}
 
 	return nil
 }
 
+// RetryConfig returns the backoff configuration of the retries of the failed requests to the query-frontend.
+func (c *QueryFrontendConfig) RetryConfig() backoff.Config {
+	return backoff.Config{
+		MinBackoff: c.MinBackoff,
+		MaxBackoff: c.MaxBackoff,
+		MaxRetries: c.MaxRetries,
+	}
+}
+
 // DialQueryFrontend creates and initializes a new httpgrpc.HTTPClient taking a QueryFrontendConfig configuration.
 func DialQueryFrontend(cfg QueryFrontendConfig) (httpgrpc.HTTPClient, error) {
 	opts, err := cfg.GRPCClientConfig.DialOption([]grpc.UnaryClientInterceptor{
@@ -109,6 +136,7 @@ type Middleware func(ctx context.Context, req *httpgrpc.HTTPRequest) error
 type RemoteQuerier struct {
 	client                             httpgrpc.HTTPClient
 	timeout                            time.Duration
+	retryConfig                        backoff.Config
 	middlewares                        []Middleware
 	promHTTPPrefix                     string
 	logger                             log.Logger
@@ -123,6 +151,7 @@ var protobufDecoderInstance = protobufDecoder{}
 func NewRemoteQuerier(
 	client httpgrpc.HTTPClient,
 	timeout time.Duration,
+	retryConfig backoff.Config,
 	preferredQueryResultResponseFormat string,
 	prometheusHTTPPrefix string,
 	logger log.Logger,
@@ -131,6 +160,7 @@ func NewRemoteQuerier(
 	return &RemoteQuerier{
 		client:                             client,
 		timeout:                            timeout,
+		retryConfig:                        retryConfig,
 		middlewares:                        middlewares,
 		promHTTPPrefix:                     prometheusHTTPPrefix,
 		logger:                             logger,
@@ -283,14 +313,14 @@ func (q *RemoteQuerier) createRequest(ctx context.Context, query string, ts time
 }
 
 func (q *RemoteQuerier) sendRequest(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, error) {
+	// A backoff with 0 max retries would retry forever.
+	if q.retryConfig.MaxRetries == 0 {
+		return q.client.Handle(ctx, req)
+	}
+
 	// Ongoing request may be cancelled during evaluation due to some transient error or server shutdown,
 	// so we'll keep retrying until we get a successful response or backoff is terminated.
-	retryConfig := backoff.Config{
-		MinBackoff: 100 * time.Millisecond,
-		MaxBackoff: 2 * time.Second,
-		MaxRetries: maxRequestRetries,
-	}
-	retry := backoff.New(ctx, retryConfig)
+	retry := backoff.New(ctx, q.retryConfig)
 
 	for {
 		resp, err := q.client.Handle(ctx, req)
@@ -15,6 +15,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/status"
 	"github.com/golang/snappy"
+	"github.com/grafana/dskit/backoff"
 	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/prompb"
@@ -27,6 +28,12 @@ import (
 	"github.com/grafana/mimir/pkg/mimirpb"
 )
 
+var testRetryConfig = backoff.Config{
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+	MaxRetries: 3,
+}
+
 type mockHTTPGRPCClient func(ctx context.Context, req *httpgrpc.HTTPRequest, _ ...grpc.CallOption) (*httpgrpc.HTTPResponse, error)
 
 func (c mockHTTPGRPCClient) Handle(ctx context.Context, req *httpgrpc.HTTPRequest, opts ...grpc.CallOption) (*httpgrpc.HTTPResponse, error) {
@@ -53,7 +60,7 @@ func TestRemoteQuerier_ReadReq(t *testing.T) {
 			Body: snappy.Encode(nil, b),
 		}, nil
 	}
-	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, formatJSON, "/prometheus", log.NewNopLogger())
+	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, testRetryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 	_, err := q.Read(context.Background(), &prompb.Query{})
 	require.NoError(t, err)
@@ -69,7 +76,7 @@ func TestRemoteQuerier_ReadReqTimeout(t *testing.T) {
 		<-ctx.Done()
 		return nil, ctx.Err()
 	}
-	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Second, formatJSON, "/prometheus", log.NewNopLogger())
+	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Second, testRetryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 	_, err := q.Read(context.Background(), &prompb.Query{})
 	require.Error(t, err)
@@ -91,7 +98,7 @@ func TestRemoteQuerier_QueryReq(t *testing.T) {
 					}`),
 				}, nil
 			}
-			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, format, "/prometheus", log.NewNopLogger())
+			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, testRetryConfig, format, "/prometheus", log.NewNopLogger())
 
 			tm := time.Unix(1649092025, 515834)
 			_, err := q.Query(context.Background(), "qs", tm)
@@ -218,7 +225,7 @@ func TestRemoteQuerier_QueryJSONDecoding(t *testing.T) {
 					Body: []byte(scenario.body),
 				}, nil
 			}
-			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, formatJSON, "/prometheus", log.NewNopLogger())
+			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, testRetryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 			tm := time.Unix(1649092025, 515834)
 			actual, err := q.Query(context.Background(), "qs", tm)
@@ -483,7 +490,7 @@ func TestRemoteQuerier_QueryProtobufDecoding(t *testing.T) {
 					Body: b,
 				}, nil
 			}
-			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, formatProtobuf, "/prometheus", log.NewNopLogger())
+			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, testRetryConfig, formatProtobuf, "/prometheus", log.NewNopLogger())
 
 			tm := time.Unix(1649092025, 515834)
 			actual, err := q.Query(context.Background(), "qs", tm)
@@ -506,7 +513,7 @@ func TestRemoteQuerier_QueryUnknownResponseContentType(t *testing.T) {
 			Body: []byte("some body content"),
 		}, nil
 	}
-	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, formatJSON, "/prometheus", log.NewNopLogger())
+	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, testRetryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 	tm := time.Unix(1649092025, 515834)
 	_, err := q.Query(context.Background(), "qs", tm)
@@ -518,7 +525,7 @@ func TestRemoteQuerier_QueryReqTimeout(t *testing.T) {
 		<-ctx.Done()
 		return nil, ctx.Err()
 	}
-	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Second, formatJSON, "/prometheus", log.NewNopLogger())
+	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Second, testRetryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 	tm := time.Unix(1649092025, 515834)
 	_, err := q.Query(context.Background(), "qs", tm)
@@ -528,14 +535,15 @@ func TestRemoteQuerier_QueryReqTimeout(t *testing.T) {
 func TestRemoteQuerier_BackoffRetry(t *testing.T) {
 	tcs := map[string]struct {
 		failedRequests  int
+		disableRetries  bool
 		expectedError   string
 		requestDeadline time.Duration
 	}{
 		"succeed on failed requests <= max retries": {
-			failedRequests: maxRequestRetries,
+			failedRequests: testRetryConfig.MaxRetries,
 		},
 		"fail on failed requests > max retries": {
-			failedRequests: maxRequestRetries + 1,
+			failedRequests: testRetryConfig.MaxRetries + 1,
 			expectedError:  "failed request: 4",
 		},
 		"return last known error on context cancellation": {
@@ -543,6 +551,11 @@ func TestRemoteQuerier_BackoffRetry(t *testing.T) {
 			requestDeadline: 50 * time.Millisecond, // force context cancellation while waiting for retry
 			expectedError:   "context deadline exceeded while retrying request, last err was: failed request: 1",
 		},
+		"fail on first failed request when retries are disabled": {
+			failedRequests: 1,
+			disableRetries: true,
+			expectedError:  "failed request: 1",
+		},
 	}
 	for tn, tc := range tcs {
 		t.Run(tn, func(t *testing.T) {
@@ -562,7 +575,11 @@ func TestRemoteQuerier_BackoffRetry(t *testing.T) {
 					}`),
 				}, nil
 			}
-			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, formatJSON, "/prometheus", log.NewNopLogger())
+			retryConfig := testRetryConfig
+			if tc.disableRetries {
+				retryConfig.MaxRetries = 0
+			}
+			q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, retryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 			ctx := context.Background()
 			if tc.requestDeadline > 0 {
@@ -594,7 +611,7 @@ func TestRemoteQuerier_StatusErrorResponses(t *testing.T) {
 			}`),
 		}, nil
 	}
-	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, formatJSON, "/prometheus", log.NewNopLogger())
+	q := NewRemoteQuerier(mockHTTPGRPCClient(mockClientFn), time.Minute, testRetryConfig, formatJSON, "/prometheus", log.NewNopLogger())
 
 	tm := time.Unix(1649092025, 515834)
 
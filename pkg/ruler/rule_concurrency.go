@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"go.uber.org/atomic"
+)
+
+const independentRuleQueriesKey contextKey = 2
+
+// Names of the series generated by the alerting rules.
+const (
+	alertMetricName         = "ALERTS"
+	alertForStateMetricName = "ALERTS_FOR_STATE"
+)
+
+// tenantConcurrencyController limits the number of queries of independent rules evaluated concurrently,
+// across all the rule groups of a tenant, to the tenant's limit.
+type tenantConcurrencyController struct {
+	userID string
+	limits RulesLimits
+
+	inflight          atomic.Int64
+	concurrentQueries prometheus.Counter
+}
+
+func newTenantConcurrencyController(userID string, limits RulesLimits, concurrentQueries prometheus.Counter) *tenantConcurrencyController {
+	return &tenantConcurrencyController{
+		userID:            userID,
+		limits:            limits,
+		concurrentQueries: concurrentQueries,
+	}
+}
+
+// tryAcquire returns whether a query can be evaluated concurrently. If true, release must be called once
+// the query has been evaluated.
+func (c *tenantConcurrencyController) tryAcquire() bool {
+	// The limit is read at each call, so that it can be changed at runtime.
+	limit := int64(c.limits.RulerMaxIndependentRuleConcurrency(c.userID))
+	if limit <= 0 {
+		return false
+	}
+
+	if c.inflight.Inc() > limit {
+		c.inflight.Dec()
+		return false
+	}
+
+	c.concurrentQueries.Inc()
+	return true
+}
+
+func (c *tenantConcurrencyController) release() {
+	c.inflight.Dec()
+}
+
+// independentRuleQueries holds the queries of the independent rules of a rule group, and the results of
+// the queries evaluated concurrently for the ongoing evaluation of the rule group.
+type independentRuleQueries struct {
+	queries []string
+
+	mtx     sync.Mutex
+	ts      time.Time
+	results map[string][]*independentRuleQueryResult
+}
+
+type independentRuleQueryResult struct {
+	done   chan struct{}
+	vector promql.Vector
+	err    error
+}
+
+// independentRulesGroupContextFunc injects in the context the queries of the independent rules of the group,
+// used by concurrentIndependentRulesQueryFunc.
+func independentRulesGroupContextFunc(ctx context.Context, g *rules.Group) context.Context {
+	queries := findIndependentRuleQueries(g.Rules())
+	if len(queries) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, independentRuleQueriesKey, &independentRuleQueries{queries: queries})
+}
+
+// findIndependentRuleQueries returns the queries of the rules which don't read the series written by
+// the rules of the group. The query of a rule selecting series without an equality matcher on the
+// metric name can read any series, so the rule is never independent.
+func findIndependentRuleQueries(groupRules []rules.Rule) []string {
+	if len(groupRules) < 2 {
+		return nil
+	}
+
+	written := map[string]struct{}{}
+	for _, r := range groupRules {
+		switch r.(type) {
+		case *rules.RecordingRule:
+			written[r.Name()] = struct{}{}
+		case *rules.AlertingRule:
+			written[alertMetricName] = struct{}{}
+			written[alertForStateMetricName] = struct{}{}
+		}
+	}
+
+	var queries []string
+	for _, r := range groupRules {
+		independent := true
+		parser.Inspect(r.Query(), func(node parser.Node, _ []parser.Node) error {
+			vs, ok := node.(*parser.VectorSelector)
+			if !ok {
+				return nil
+			}
+
+			name := ""
+			for _, m := range vs.LabelMatchers {
+				if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+					name = m.Value
+				}
+			}
+			if _, ok := written[name]; ok || name == "" {
+				independent = false
+			}
+			return nil
+		})
+
+		if independent {
+			queries = append(queries, r.Query().String())
+		}
+	}
+
+	// A single independent rule is evaluated in order anyway.
+	if len(queries) < 2 {
+		return nil
+	}
+	return queries
+}
+
+// concurrentIndependentRulesQueryFunc returns a rules.QueryFunc which, at the beginning of each evaluation
+// of a rule group, starts evaluating concurrently with qf the queries of the independent rules of the group,
+// up to the tenant's concurrency limit. The independent rules then get the result of their query, while the
+// other rules are evaluated with qf in the order of the group. The results are always written in the order
+// of the group, so the rules reading the series written by other rules of the group see them.
+func concurrentIndependentRulesQueryFunc(qf rules.QueryFunc, ctrl *tenantConcurrencyController) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		queries, ok := ctx.Value(independentRuleQueriesKey).(*independentRuleQueries)
+		if !ok {
+			return qf(ctx, qs, t)
+		}
+
+		res := queries.result(ctx, qf, ctrl, qs, t)
+		if res == nil {
+			return qf(ctx, qs, t)
+		}
+
+		select {
+		case <-res.done:
+			return res.vector, res.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// result returns the result of the input query evaluated concurrently, or nil if the query has not been
+// evaluated concurrently. The concurrent evaluation of the queries starts when the first query of an
+// evaluation of the rule group is received, which is identified by the evaluation timestamp.
+func (q *independentRuleQueries) result(ctx context.Context, qf rules.QueryFunc, ctrl *tenantConcurrencyController, qs string, t time.Time) *independentRuleQueryResult {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if !t.Equal(q.ts) {
+		q.ts = t
+		q.results = make(map[string][]*independentRuleQueryResult, len(q.queries))
+
+		for _, query := range q.queries {
+			if !ctrl.tryAcquire() {
+				break
+			}
+
+			res := &independentRuleQueryResult{done: make(chan struct{})}
+			q.results[query] = append(q.results[query], res)
+
+			go func(query string) {
+				// The concurrency is released before the result is returned, so that it's available
+				// to the next evaluation of the rule group.
+				defer close(res.done)
+				defer ctrl.release()
+
+				res.vector, res.err = qf(ctx, query, t)
+			}(query)
+		}
+	}
+
+	results := q.results[qs]
+	if len(results) == 0 {
+		return nil
+	}
+	q.results[qs] = results[1:]
+	return results[0]
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestFindIndependentRuleQueries(t *testing.T) {
+	parseExpr := func(expr string) parser.Expr {
+		parsed, err := parser.ParseExpr(expr)
+		require.NoError(t, err)
+		return parsed
+	}
+	recordingRule := func(name, expr string) rules.Rule {
+		return rules.NewRecordingRule(name, parseExpr(expr), nil)
+	}
+	alertingRule := func(name, expr string) rules.Rule {
+		return rules.NewAlertingRule(name, parseExpr(expr), time.Minute, 0, nil, nil, nil, "", true, log.NewNopLogger())
+	}
+
+	tests := map[string]struct {
+		rules    []rules.Rule
+		expected []string
+	}{
+		"single rule": {
+			rules: []rules.Rule{
+				recordingRule("job:up:sum", `sum by(job) (up)`),
+			},
+		},
+		"all rules independent": {
+			rules: []rules.Rule{
+				recordingRule("job:up:sum", `sum by(job) (up)`),
+				alertingRule("HighErrorRate", `rate(http_errors_total[5m]) > 1`),
+				recordingRule("job:requests:rate5m", `sum by(job) (rate(http_requests_total[5m]))`),
+			},
+			expected: []string{
+				`sum by (job) (up)`,
+				`rate(http_errors_total[5m]) > 1`,
+				`sum by (job) (rate(http_requests_total[5m]))`,
+			},
+		},
+		"rules reading the series recorded by other rules": {
+			rules: []rules.Rule{
+				recordingRule("job:up:sum", `sum by(job) (up)`),
+				recordingRule("job:requests:rate5m", `sum by(job) (rate(http_requests_total[5m]))`),
+				recordingRule("job:requests:ratio", `job:requests:rate5m / on(job) job:up:sum`),
+				alertingRule("JobDown", `job:up:sum == 0`),
+				recordingRule("instance:cpu:rate5m", `rate(cpu_seconds_total[5m])`),
+			},
+			expected: []string{
+				`sum by (job) (up)`,
+				`sum by (job) (rate(http_requests_total[5m]))`,
+				`rate(cpu_seconds_total[5m])`,
+			},
+		},
+		"rules reading the series generated by the alerting rules": {
+			rules: []rules.Rule{
+				alertingRule("JobDown", `up == 0`),
+				recordingRule("alerts:count", `count(ALERTS)`),
+				recordingRule("instance:cpu:rate5m", `rate(cpu_seconds_total[5m])`),
+				recordingRule("job:up:sum", `sum by(job) (up)`),
+			},
+			expected: []string{
+				`up == 0`,
+				`rate(cpu_seconds_total[5m])`,
+				`sum by (job) (up)`,
+			},
+		},
+		"rules selecting series without the metric name": {
+			rules: []rules.Rule{
+				recordingRule("job:series:count", `count by(job) ({job="api"})`),
+				recordingRule("job:up:regexp", `sum by(job) ({__name__=~"up|down"})`),
+				recordingRule("job:up:sum", `sum by(job) (up)`),
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, findIndependentRuleQueries(testData.rules))
+		})
+	}
+}
+
+func TestConcurrentIndependentRulesQueryFunc(t *testing.T) {
+	const userID = "user-1"
+
+	tests := map[string]struct {
+		concurrency        int
+		expectedConcurrent int
+	}{
+		"concurrency disabled": {
+			concurrency:        0,
+			expectedConcurrent: 0,
+		},
+		"concurrency lower than the number of independent rules": {
+			concurrency:        2,
+			expectedConcurrent: 2,
+		},
+		"concurrency higher than the number of independent rules": {
+			concurrency:        10,
+			expectedConcurrent: 3,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+				defaults.RulerMaxIndependentRuleConcurrency = testData.concurrency
+			})
+			concurrentQueries := prometheus.NewCounter(prometheus.CounterOpts{Name: "test"})
+			ctrl := newTenantConcurrencyController(userID, limits, concurrentQueries)
+
+			var (
+				mtx     sync.Mutex
+				queried []string
+			)
+			qf := func(_ context.Context, qs string, _ time.Time) (promql.Vector, error) {
+				mtx.Lock()
+				queried = append(queried, qs)
+				mtx.Unlock()
+				return promql.Vector{{Point: promql.Point{V: float64(len(qs))}}}, nil
+			}
+
+			independentQueries := []string{"a", "bb", "ccc"}
+			ctx := context.WithValue(context.Background(), independentRuleQueriesKey, &independentRuleQueries{queries: independentQueries})
+			wrapped := concurrentIndependentRulesQueryFunc(qf, ctrl)
+
+			// Simulate two evaluations of the rule group, where the independent rules are interleaved with a dependent one.
+			for _, ts := range []time.Time{time.Unix(10, 0), time.Unix(20, 0)} {
+				for _, qs := range []string{"a", "dddd", "bb", "ccc"} {
+					res, err := wrapped(ctx, qs, ts)
+					require.NoError(t, err)
+					require.Equal(t, promql.Vector{{Point: promql.Point{V: float64(len(qs))}}}, res)
+				}
+			}
+
+			// Each query has been evaluated once per evaluation of the rule group.
+			assert.ElementsMatch(t, []string{"a", "dddd", "bb", "ccc", "a", "dddd", "bb", "ccc"}, queried)
+			assert.Equal(t, float64(2*testData.expectedConcurrent), testutil.ToFloat64(concurrentQueries))
+			assert.Equal(t, int64(0), ctrl.inflight.Load())
+		})
+	}
+}
+
+func TestConcurrentIndependentRulesQueryFunc_ShouldEvaluateIndependentRulesConcurrently(t *testing.T) {
+	limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+		defaults.RulerMaxIndependentRuleConcurrency = 2
+	})
+	ctrl := newTenantConcurrencyController("user-1", limits, prometheus.NewCounter(prometheus.CounterOpts{Name: "test"}))
+
+	// The query of the first rule doesn't complete until the query of the second rule has started.
+	secondStarted := make(chan struct{})
+	qf := func(_ context.Context, qs string, _ time.Time) (promql.Vector, error) {
+		switch qs {
+		case "first":
+			select {
+			case <-secondStarted:
+			case <-time.After(5 * time.Second):
+				return nil, context.DeadlineExceeded
+			}
+		case "second":
+			close(secondStarted)
+		}
+		return promql.Vector{}, nil
+	}
+
+	ctx := context.WithValue(context.Background(), independentRuleQueriesKey, &independentRuleQueries{queries: []string{"first", "second"}})
+	wrapped := concurrentIndependentRulesQueryFunc(qf, ctrl)
+
+	ts := time.Now()
+	_, err := wrapped(ctx, "first", ts)
+	require.NoError(t, err)
+	_, err = wrapped(ctx, "second", ts)
+	require.NoError(t, err)
+}
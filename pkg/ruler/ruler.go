@@ -104,6 +104,8 @@ type Config struct {
 
 	EnableAPI bool `yaml:"enable_api"`
 
+	RuleGroupsHistorySize int `yaml:"rule_groups_history_size" category:"experimental"`
+
 	EnabledTenants  flagext.StringSliceCSV `yaml:"enabled_tenants" category:"advanced"`
 	DisabledTenants flagext.StringSliceCSV `yaml:"disabled_tenants" category:"advanced"`
 
@@ -153,6 +155,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.StringVar(&cfg.RulePath, "ruler.rule-path", "./data-ruler/", "Directory to store temporary rule files loaded by the Prometheus rule managers. This directory is not required to be persisted between restarts.")
 	f.BoolVar(&cfg.EnableAPI, "ruler.enable-api", true, "Enable the ruler config API.")
+	f.IntVar(&cfg.RuleGroupsHistorySize, "ruler.rule-groups-history-size", 0, "How many versions of each rule group to retain in the rule groups history, which allows to list and roll back to previous versions of a rule group through the ruler config API. 0 to disable. The history is supported only when the rules are stored in object storage.")
 	f.DurationVar(&cfg.OutageTolerance, "ruler.for-outage-tolerance", time.Hour, `Max time to tolerate outage for restoring "for" state of alert.`)
 	f.DurationVar(&cfg.ForGracePeriod, "ruler.for-grace-period", 2*time.Minute, `This grace period controls which alerts the ruler restores after a restart. `+
 		`Alerts with "for" duration lower than this grace period are not restored after a ruler restart. `+
@@ -905,6 +908,14 @@ func (r *Ruler) DeleteTenantConfiguration(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
+	// The history is deleted even if disabled, because it may have been stored while it was enabled.
+	if history, ok := r.store.(rulestore.RuleGroupHistoryStore); ok {
+		if err := history.DeleteRuleGroupsHistory(req.Context(), userID); err != nil {
+			respondError(logger, w, err.Error())
+			return
+		}
+	}
+
 	level.Info(logger).Log("msg", "deleted all tenant rule groups", "user", userID)
 	w.WriteHeader(http.StatusOK)
 }
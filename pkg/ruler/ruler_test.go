@@ -203,7 +203,7 @@ func prepareRulerManager(t *testing.T, cfg Config, opts ...prepareOption) *Defau
 	pusher := newPusherMock()
 	pusher.MockPush(&mimirpb.WriteResponse{}, nil)
 
-	managerFactory := DefaultTenantManagerFactory(cfg, pusher, noopQueryable, noopQueryFunc, options.limits, options.registerer)
+	managerFactory := DefaultTenantManagerFactory(cfg, pusher, noopQueryable, noopQueryFunc, nil, nil, options.limits, options.registerer)
 	manager, err := NewDefaultMultiTenantManager(cfg, managerFactory, prometheus.NewRegistry(), options.logger, nil)
 	require.NoError(t, err)
 
@@ -938,13 +938,15 @@ func TestDeleteTenantRuleGroups(t *testing.T) {
 	obj := objstore.NewInMemBucket()
 	rs := bucketclient.NewBucketRuleStore(obj, nil, log.NewNopLogger())
 
-	// "upload" rule groups
+	// "upload" rule groups, along with their history
 	for _, key := range ruleGroups {
 		desc := rulespb.ToProto(key.user, key.namespace, rulefmt.RuleGroup{Name: key.group})
 		require.NoError(t, rs.SetRuleGroup(context.Background(), key.user, key.namespace, desc))
+		_, err := rs.AddRuleGroupVersion(context.Background(), key.user, key.namespace, desc, "", 1)
+		require.NoError(t, err)
 	}
 
-	require.Len(t, obj.Objects(), 3)
+	require.Len(t, obj.Objects(), 6)
 
 	cfg := defaultRulerConfig(t)
 	api, err := NewRuler(cfg, nil, nil, log.NewNopLogger(), rs, nil)
@@ -960,7 +962,7 @@ func TestDeleteTenantRuleGroups(t *testing.T) {
 
 	{
 		callDeleteTenantAPI(t, api, "user-with-no-rule-groups")
-		require.Len(t, obj.Objects(), 3)
+		require.Len(t, obj.Objects(), 6)
 
 		verifyExpectedDeletedRuleGroupsForUser(t, api, "user-with-no-rule-groups", true) // Has no rule groups
 		verifyExpectedDeletedRuleGroupsForUser(t, api, "userA", false)
@@ -969,7 +971,7 @@ func TestDeleteTenantRuleGroups(t *testing.T) {
 
 	{
 		callDeleteTenantAPI(t, api, "userA")
-		require.Len(t, obj.Objects(), 2)
+		require.Len(t, obj.Objects(), 4)
 
 		verifyExpectedDeletedRuleGroupsForUser(t, api, "user-with-no-rule-groups", true) // Has no rule groups
 		verifyExpectedDeletedRuleGroupsForUser(t, api, "userA", true)                    // Just deleted.
@@ -979,7 +981,7 @@ func TestDeleteTenantRuleGroups(t *testing.T) {
 	// Deleting same user again works fine and reports no problems.
 	{
 		callDeleteTenantAPI(t, api, "userA")
-		require.Len(t, obj.Objects(), 2)
+		require.Len(t, obj.Objects(), 4)
 
 		verifyExpectedDeletedRuleGroupsForUser(t, api, "user-with-no-rule-groups", true) // Has no rule groups
 		verifyExpectedDeletedRuleGroupsForUser(t, api, "userA", true)                    // Already deleted before.
@@ -9,9 +9,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -29,6 +33,11 @@ const (
 	// RulesPrefix is the bucket prefix under which all tenants rule groups are stored.
 	RulesPrefix = "rules"
 
+	// RulesHistoryPrefix is the bucket prefix under which the history of all tenants rule groups is stored.
+	// Each version of a rule group is stored in a separate object, following the pattern:
+	//     rules-history/<user-id>/<namespace>/<rules group>/<version>
+	RulesHistoryPrefix = "rules-history"
+
 	loadConcurrency = 10
 )
 
@@ -42,16 +51,18 @@ var (
 // BucketRuleStore is used to support the RuleStore interface against an object storage backend. It is implemented
 // using the Thanos objstore.Bucket interface
 type BucketRuleStore struct {
-	bucket      objstore.Bucket
-	cfgProvider bucket.TenantConfigProvider
-	logger      log.Logger
+	bucket        objstore.Bucket
+	historyBucket objstore.Bucket
+	cfgProvider   bucket.TenantConfigProvider
+	logger        log.Logger
 }
 
 func NewBucketRuleStore(bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider, logger log.Logger) *BucketRuleStore {
 	return &BucketRuleStore{
-		bucket:      bucket.NewPrefixedBucketClient(bkt, RulesPrefix),
-		cfgProvider: cfgProvider,
-		logger:      logger,
+		bucket:        bucket.NewPrefixedBucketClient(bkt, RulesPrefix),
+		historyBucket: bucket.NewPrefixedBucketClient(bkt, RulesHistoryPrefix),
+		cfgProvider:   cfgProvider,
+		logger:        logger,
 	}
 }
 
@@ -243,6 +254,144 @@ func (b *BucketRuleStore) DeleteNamespace(ctx context.Context, userID string, na
 	return nil
 }
 
+// ruleGroupVersion is the content of a rule group version object.
+type ruleGroupVersion struct {
+	Timestamp time.Time `json:"timestamp"`
+	Author    string    `json:"author,omitempty"`
+	RuleGroup []byte    `json:"rule_group"`
+}
+
+// ListRuleGroupVersions implements rulestore.RuleGroupHistoryStore.
+func (b *BucketRuleStore) ListRuleGroupVersions(ctx context.Context, userID, namespace, group string) ([]rulestore.RuleGroupVersion, error) {
+	versions, err := b.listRuleGroupVersions(ctx, userID, namespace, group)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]rulestore.RuleGroupVersion, 0, len(versions))
+	for _, version := range versions {
+		v, err := b.getRuleGroupVersion(ctx, userID, namespace, group, version)
+		if errors.Is(err, rulestore.ErrGroupVersionNotFound) {
+			// The version has been deleted in the meanwhile.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, rulestore.RuleGroupVersion{Version: version, Timestamp: v.Timestamp, Author: v.Author})
+	}
+	return result, nil
+}
+
+// GetRuleGroupVersion implements rulestore.RuleGroupHistoryStore.
+func (b *BucketRuleStore) GetRuleGroupVersion(ctx context.Context, userID, namespace, group string, version uint64) (*rulespb.RuleGroupDesc, error) {
+	v, err := b.getRuleGroupVersion(ctx, userID, namespace, group, version)
+	if err != nil {
+		return nil, err
+	}
+
+	rg := &rulespb.RuleGroupDesc{}
+	if err := proto.Unmarshal(v.RuleGroup, rg); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal rule group %s version %d", getRuleGroupObjectKey(namespace, group), version)
+	}
+	return rg, nil
+}
+
+// AddRuleGroupVersion implements rulestore.RuleGroupHistoryStore.
+func (b *BucketRuleStore) AddRuleGroupVersion(ctx context.Context, userID, namespace string, group *rulespb.RuleGroupDesc, author string, retain int) (uint64, error) {
+	userBucket := bucket.NewUserBucketClient(userID, b.historyBucket, b.cfgProvider)
+
+	versions, err := b.listRuleGroupVersions(ctx, userID, namespace, group.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	// The version is time-based, so that the concurrent writers of the rule group can't assign the same version.
+	version := uint64(time.Now().UnixNano())
+	if len(versions) > 0 && version <= versions[len(versions)-1] {
+		version = versions[len(versions)-1] + 1
+	}
+
+	rg, err := proto.Marshal(group)
+	if err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(ruleGroupVersion{Timestamp: time.Now().UTC(), Author: author, RuleGroup: rg})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := userBucket.Upload(ctx, getRuleGroupVersionObjectKey(namespace, group.Name, version), bytes.NewReader(data)); err != nil {
+		return 0, err
+	}
+
+	// Delete the versions which are no longer retained. The failure is not returned,
+	// because the new version has been successfully stored.
+	versions = append(versions, version)
+	for i := 0; i < len(versions)-retain; i++ {
+		if err := userBucket.Delete(ctx, getRuleGroupVersionObjectKey(namespace, group.Name, versions[i])); err != nil && !userBucket.IsObjNotFoundErr(err) {
+			level.Warn(b.logger).Log("msg", "failed to delete old rule group version", "user", userID, "namespace", namespace, "group", group.Name, "version", versions[i], "err", err)
+		}
+	}
+
+	return version, nil
+}
+
+// DeleteRuleGroupsHistory implements rulestore.RuleGroupHistoryStore.
+func (b *BucketRuleStore) DeleteRuleGroupsHistory(ctx context.Context, userID string) error {
+	userBucket := bucket.NewUserBucketClient(userID, b.historyBucket, b.cfgProvider)
+
+	return userBucket.Iter(ctx, "", func(key string) error {
+		if err := userBucket.Delete(ctx, key); err != nil && !userBucket.IsObjNotFoundErr(err) {
+			return errors.Wrapf(err, "failed to delete rule group version %s", key)
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+}
+
+func (b *BucketRuleStore) getRuleGroupVersion(ctx context.Context, userID, namespace, group string, version uint64) (ruleGroupVersion, error) {
+	userBucket := bucket.NewUserBucketClient(userID, b.historyBucket, b.cfgProvider)
+	objectKey := getRuleGroupVersionObjectKey(namespace, group, version)
+
+	reader, err := userBucket.Get(ctx, objectKey)
+	if userBucket.IsObjNotFoundErr(err) {
+		return ruleGroupVersion{}, rulestore.ErrGroupVersionNotFound
+	}
+	if err != nil {
+		return ruleGroupVersion{}, errors.Wrapf(err, "failed to get rule group version %s", objectKey)
+	}
+	defer func() { _ = reader.Close() }()
+
+	buf, err := io.ReadAll(reader)
+	if err != nil {
+		return ruleGroupVersion{}, errors.Wrapf(err, "failed to read rule group version %s", objectKey)
+	}
+
+	v := ruleGroupVersion{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return ruleGroupVersion{}, errors.Wrapf(err, "failed to unmarshal rule group version %s", objectKey)
+	}
+	return v, nil
+}
+
+// listRuleGroupVersions returns the versions of a rule group, sorted in ascending order.
+func (b *BucketRuleStore) listRuleGroupVersions(ctx context.Context, userID, namespace, group string) ([]uint64, error) {
+	userBucket := bucket.NewUserBucketClient(userID, b.historyBucket, b.cfgProvider)
+
+	var versions []uint64
+	err := userBucket.Iter(ctx, getRuleGroupObjectKey(namespace, group)+objstore.DirDelim, func(key string) error {
+		parts := strings.Split(key, objstore.DirDelim)
+		if version, err := strconv.ParseUint(parts[len(parts)-1], 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+		return nil
+	})
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, err
+}
+
 func getNamespacePrefix(namespace string) string {
 	return base64.URLEncoding.EncodeToString([]byte(namespace)) + objstore.DirDelim
 }
@@ -251,6 +400,10 @@ func getRuleGroupObjectKey(namespace, group string) string {
 	return getNamespacePrefix(namespace) + base64.URLEncoding.EncodeToString([]byte(group))
 }
 
+func getRuleGroupVersionObjectKey(namespace, group string, version uint64) string {
+	return getRuleGroupObjectKey(namespace, group) + objstore.DirDelim + strconv.FormatUint(version, 10)
+}
+
 // parseRuleGroupObjectKeyWithUser parses a bucket object key in the format "<user>/<namespace>/<rules group>".
 func parseRuleGroupObjectKeyWithUser(key string) (user, namespace, group string, err error) {
 	parts := strings.SplitN(key, objstore.DirDelim, 2)
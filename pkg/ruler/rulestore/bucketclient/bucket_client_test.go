@@ -231,6 +231,75 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestRuleGroupHistory(t *testing.T) {
+	bucketClient := objstore.NewInMemBucket()
+	rs := NewBucketRuleStore(bucketClient, nil, log.NewNopLogger())
+	ctx := context.Background()
+
+	versions, err := rs.ListRuleGroupVersions(ctx, "user1", "A", "1")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	_, err = rs.GetRuleGroupVersion(ctx, "user1", "A", "1", 1)
+	require.Equal(t, rulestore.ErrGroupVersionNotFound, err)
+
+	// Store more versions than the retained ones.
+	var added []uint64
+	for i := 1; i <= 4; i++ {
+		desc := rulespb.ToProto("user1", "A", rulefmt.RuleGroup{Name: "1", Interval: model.Duration(time.Duration(i) * time.Minute)})
+		version, err := rs.AddRuleGroupVersion(ctx, "user1", "A", desc, fmt.Sprintf("author-%d", i), 3)
+		require.NoError(t, err)
+
+		// The versions are monotonically increasing.
+		if len(added) > 0 {
+			require.Greater(t, version, added[len(added)-1])
+		}
+		added = append(added, version)
+	}
+
+	versions, err = rs.ListRuleGroupVersions(ctx, "user1", "A", "1")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	for i, v := range versions {
+		assert.Equal(t, added[i+1], v.Version)
+		assert.Equal(t, fmt.Sprintf("author-%d", i+2), v.Author)
+		assert.False(t, v.Timestamp.IsZero())
+	}
+
+	_, err = rs.GetRuleGroupVersion(ctx, "user1", "A", "1", added[0])
+	require.Equal(t, rulestore.ErrGroupVersionNotFound, err)
+
+	rg, err := rs.GetRuleGroupVersion(ctx, "user1", "A", "1", added[1])
+	require.NoError(t, err)
+	assert.Equal(t, "1", rg.Name)
+	assert.Equal(t, 2*time.Minute, rg.Interval)
+
+	// The history isn't listed as rule groups or users.
+	users, err := rs.ListAllUsers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	require.Equal(t, []string{
+		fmt.Sprintf("rules-history/user1/%s/%d", getRuleGroupObjectKey("A", "1"), added[1]),
+		fmt.Sprintf("rules-history/user1/%s/%d", getRuleGroupObjectKey("A", "1"), added[2]),
+		fmt.Sprintf("rules-history/user1/%s/%d", getRuleGroupObjectKey("A", "1"), added[3]),
+	}, getSortedObjectKeys(bucketClient))
+
+	// Deleting the history of the user deletes the versions of all its rule groups.
+	desc := rulespb.ToProto("user2", "A", rulefmt.RuleGroup{Name: "1"})
+	_, err = rs.AddRuleGroupVersion(ctx, "user2", "A", desc, "", 3)
+	require.NoError(t, err)
+
+	require.NoError(t, rs.DeleteRuleGroupsHistory(ctx, "user1"))
+	versions, err = rs.ListRuleGroupVersions(ctx, "user1", "A", "1")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	versions, err = rs.ListRuleGroupVersions(ctx, "user2", "A", "1")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
+
 func getSortedObjectKeys(bucketClient interface{}) []string {
 	if typed, ok := bucketClient.(*objstore.InMemBucket); ok {
 		var keys []string
@@ -8,6 +8,7 @@ package rulestore
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 )
@@ -19,6 +20,8 @@ var (
 	ErrGroupNamespaceNotFound = errors.New("group namespace does not exist")
 	// ErrUserNotFound is returned if the user does not currently exist
 	ErrUserNotFound = errors.New("no rule groups found for user")
+	// ErrGroupVersionNotFound is returned if a version of a rule group does not exist in the rule groups history
+	ErrGroupVersionNotFound = errors.New("group version does not exist")
 )
 
 // RuleStore is used to store and retrieve rules.
@@ -51,3 +54,29 @@ type RuleStore interface {
 	// If namespace is empty, deletes all rule groups for user.
 	DeleteNamespace(ctx context.Context, userID, namespace string) error
 }
+
+// RuleGroupVersion describes a version of a rule group stored in the rule groups history.
+type RuleGroupVersion struct {
+	Version   uint64    `yaml:"version"`
+	Timestamp time.Time `yaml:"timestamp"`
+	Author    string    `yaml:"author,omitempty"`
+}
+
+// RuleGroupHistoryStore keeps the history of the rule groups of the tenants. Each stored rule group
+// is a new version of it, and only the latest versions are retained.
+type RuleGroupHistoryStore interface {
+	// ListRuleGroupVersions returns the retained versions of a rule group, sorted in ascending order.
+	// The versions of a deleted rule group are retained too.
+	ListRuleGroupVersions(ctx context.Context, userID, namespace, group string) ([]RuleGroupVersion, error)
+
+	// GetRuleGroupVersion loads and returns the given version of a rule group.
+	GetRuleGroupVersion(ctx context.Context, userID, namespace, group string, version uint64) (*rulespb.RuleGroupDesc, error)
+
+	// AddRuleGroupVersion stores the rule group as a new version, retaining at most the given number
+	// of versions of the rule group, and returns the version. The versions are unique and monotonically
+	// increasing, but not consecutive.
+	AddRuleGroupVersion(ctx context.Context, userID, namespace string, group *rulespb.RuleGroupDesc, author string, retain int) (uint64, error)
+
+	// DeleteRuleGroupsHistory deletes the versions of all the rule groups of the user.
+	DeleteRuleGroupsHistory(ctx context.Context, userID string) error
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sandbox
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type tenantsResponse struct {
+	Tenants []Tenant  `json:"tenants"`
+	Now     time.Time `json:"now"`
+}
+
+// ServeHTTP lists the sandbox tenants on GET, creates a sandbox tenant on POST and expires a sandbox tenant on DELETE.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	now := time.Now()
+
+	switch req.Method {
+	case http.MethodPost:
+		fraction, err := strconv.ParseFloat(req.FormValue("fraction"), 64)
+		if err != nil {
+			http.Error(w, "invalid fraction: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ttl, err := time.ParseDuration(req.FormValue("ttl"))
+		if err != nil {
+			http.Error(w, "invalid ttl: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		t, err := r.Create(req.Context(), req.FormValue("sandbox"), req.FormValue("source"), fraction, ttl, now)
+		if err != nil {
+			http.Error(w, err.Error(), httpStatusCode(err))
+			return
+		}
+		util.WriteJSONResponse(w, t)
+
+	case http.MethodDelete:
+		if err := r.Expire(req.Context(), req.FormValue("sandbox"), now); err != nil {
+			http.Error(w, err.Error(), httpStatusCode(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		util.WriteJSONResponse(w, tenantsResponse{
+			Tenants: r.Tenants(),
+			Now:     now,
+		})
+	}
+}
+
+func httpStatusCode(err error) int {
+	switch {
+	case errors.Is(err, errTenantNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errTenantAlreadyExists), errors.Is(err, errTenantAlreadyExpired), errors.Is(err, errTenantHasData):
+		return http.StatusConflict
+	case errors.Is(err, errMissingTenantID), errors.Is(err, errSameTenantID), errors.Is(err, errInvalidTenantID), errors.Is(err, errMissingTenantPrefix), errors.Is(err, errInvalidFraction),
+		errors.Is(err, errSourceIsSandbox), errors.Is(err, errSandboxIsSource), errors.Is(err, errInvalidTTL):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
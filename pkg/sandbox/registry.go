@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/objstore"
+)
+
+var (
+	errMissingTenantID      = errors.New("the sandbox and source tenant IDs are required")
+	errSameTenantID         = errors.New("the sandbox tenant ID must be different than the source tenant ID")
+	errInvalidTenantID      = errors.New("invalid sandbox tenant ID")
+	errInvalidFraction      = errors.New("the fraction of series to mirror must be greater than 0 and lower than or equal to 1")
+	errInvalidTTL           = errors.New("the TTL must be greater than 0 and lower than or equal to the max TTL")
+	errSourceIsSandbox      = errors.New("the source tenant is a sandbox tenant")
+	errSandboxIsSource      = errors.New("the sandbox tenant is the source of other sandbox tenants")
+	errTenantAlreadyExists  = errors.New("the sandbox tenant already exists")
+	errTenantNotFound       = errors.New("the sandbox tenant doesn't exist")
+	errTenantAlreadyExpired = errors.New("the sandbox tenant is already expired")
+	errMissingTenantPrefix  = errors.New("the sandbox tenant ID must start with the " + TenantIDPrefix + " prefix")
+	errTenantHasData        = errors.New("the sandbox tenant ID already has data in the object storage")
+)
+
+// Registry keeps track of the sandbox tenants stored in the KV store.
+type Registry struct {
+	services.Service
+
+	cfg    Config
+	client kv.Client
+	bucket objstore.Bucket
+	logger log.Logger
+
+	mtx      sync.RWMutex
+	tenants  map[string]*Tenant   // Sandbox tenants by ID.
+	bySource map[string][]*Tenant // Sandbox tenants by source tenant ID.
+}
+
+// NewRegistry returns a new sandbox tenants registry. The registry must be started via StartAsync().
+// The bucket is used to check that the sandbox tenants don't already have data in the blocks storage.
+func NewRegistry(cfg Config, bucketClient objstore.Bucket, reg prometheus.Registerer, logger log.Logger) (*Registry, error) {
+	client, err := kv.NewClient(
+		cfg.KVStore,
+		GetCodec(),
+		kv.RegistererWithKVName(prometheus.WrapRegistererWithPrefix("cortex_", reg), "sandbox-tenants"),
+		logger,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize sandbox tenants KV store")
+	}
+
+	r := &Registry{
+		cfg:      cfg,
+		client:   client,
+		bucket:   bucketClient,
+		logger:   logger,
+		tenants:  map[string]*Tenant{},
+		bySource: map[string][]*Tenant{},
+	}
+
+	r.Service = services.NewBasicService(nil, r.running, nil)
+	return r, nil
+}
+
+func (r *Registry) running(ctx context.Context) error {
+	// The KV store client is prefixed, so we can watch all the keys.
+	r.client.WatchPrefix(ctx, "", func(key string, value interface{}) bool {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		if t, ok := value.(*Tenant); ok && t != nil {
+			r.tenants[key] = t
+		} else {
+			delete(r.tenants, key)
+		}
+		r.rebuildBySource()
+		return true
+	})
+
+	return nil
+}
+
+// rebuildBySource must be called with the lock held.
+func (r *Registry) rebuildBySource() {
+	r.bySource = make(map[string][]*Tenant, len(r.tenants))
+	for _, t := range r.tenants {
+		r.bySource[t.SourceID] = append(r.bySource[t.SourceID], t)
+	}
+}
+
+// Create creates a new sandbox tenant mirroring a fraction of the series of the source tenant until the TTL expires.
+// The sandbox tenant ID must have the reserved sandbox prefix and no data in the blocks storage, because the blocks of
+// the sandbox tenant are deleted once it expires.
+func (r *Registry) Create(ctx context.Context, sandboxID, sourceID string, fraction float64, ttl time.Duration, now time.Time) (*Tenant, error) {
+	if sandboxID == "" || sourceID == "" {
+		return nil, errMissingTenantID
+	}
+	if sandboxID == sourceID {
+		return nil, errSameTenantID
+	}
+	if err := tenant.ValidTenantID(sandboxID); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidTenantID, err)
+	}
+	if !IsSandboxTenantID(sandboxID) {
+		return nil, errMissingTenantPrefix
+	}
+	if fraction <= 0 || fraction > 1 {
+		return nil, errInvalidFraction
+	}
+	if ttl <= 0 || ttl > r.cfg.MaxTTL {
+		return nil, fmt.Errorf("%w (max TTL: %s)", errInvalidTTL, r.cfg.MaxTTL)
+	}
+
+	r.mtx.RLock()
+	_, sourceIsSandbox := r.tenants[sourceID]
+	sandboxIsSource := len(r.bySource[sandboxID]) > 0
+	r.mtx.RUnlock()
+
+	if sourceIsSandbox {
+		return nil, errSourceIsSandbox
+	}
+	if sandboxIsSource {
+		return nil, errSandboxIsSource
+	}
+
+	if hasData, err := r.tenantHasData(ctx, sandboxID); err != nil {
+		return nil, errors.Wrap(err, "failed to check if the sandbox tenant has data in the object storage")
+	} else if hasData {
+		return nil, errTenantHasData
+	}
+
+	t := &Tenant{
+		ID:        sandboxID,
+		SourceID:  sourceID,
+		Fraction:  fraction,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		UpdatedAt: now,
+	}
+
+	err := r.client.CAS(ctx, sandboxID, func(in interface{}) (out interface{}, retry bool, err error) {
+		if existing, ok := in.(*Tenant); ok && existing != nil {
+			return nil, false, errTenantAlreadyExists
+		}
+		return t, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(t)
+	level.Info(r.logger).Log("msg", "created sandbox tenant", "sandbox", sandboxID, "source", sourceID, "fraction", fraction, "expires_at", t.ExpiresAt)
+	return t, nil
+}
+
+// tenantHasData returns whether the input tenant has any object in the blocks storage.
+func (r *Registry) tenantHasData(ctx context.Context, userID string) (bool, error) {
+	if r.bucket == nil {
+		return false, nil
+	}
+
+	errFound := errors.New("found")
+	err := r.bucket.Iter(ctx, userID+"/", func(string) error {
+		return errFound
+	})
+	if errors.Is(err, errFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+// Expire expires the sandbox tenant before its TTL.
+func (r *Registry) Expire(ctx context.Context, sandboxID string, now time.Time) error {
+	var updated *Tenant
+
+	err := r.client.CAS(ctx, sandboxID, func(in interface{}) (out interface{}, retry bool, err error) {
+		existing, ok := in.(*Tenant)
+		if !ok || existing == nil {
+			return nil, false, errTenantNotFound
+		}
+		if existing.Expired(now) {
+			return nil, false, errTenantAlreadyExpired
+		}
+
+		updated = existing.Clone().(*Tenant)
+		updated.ExpiresAt = now
+		updated.UpdatedAt = now
+		return updated, true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.store(updated)
+	level.Info(r.logger).Log("msg", "expired sandbox tenant", "sandbox", sandboxID)
+	return nil
+}
+
+// Remove removes an expired sandbox tenant from the KV store.
+func (r *Registry) Remove(ctx context.Context, sandboxID string) error {
+	// Memberlist doesn't support deleting keys, so the expired sandbox tenants are kept.
+	if r.cfg.KVStore.Store == "memberlist" {
+		return nil
+	}
+
+	if err := r.client.Delete(ctx, sandboxID); err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	delete(r.tenants, sandboxID)
+	r.rebuildBySource()
+	r.mtx.Unlock()
+	return nil
+}
+
+// store updates the local state without waiting for the KV store to notify the change.
+func (r *Registry) store(t *Tenant) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if existing := r.tenants[t.ID]; existing != nil && !t.supersedes(existing) {
+		return
+	}
+	r.tenants[t.ID] = t
+	r.rebuildBySource()
+}
+
+// Get returns the sandbox tenant with the input ID, including expired ones.
+func (r *Registry) Get(sandboxID string) (Tenant, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if t, ok := r.tenants[sandboxID]; ok {
+		return *t, true
+	}
+	return Tenant{}, false
+}
+
+// ActiveSandboxesOf returns the sandbox tenants of the input source tenant which are not expired yet.
+func (r *Registry) ActiveSandboxesOf(sourceID string, now time.Time) []Tenant {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var active []Tenant
+	for _, t := range r.bySource[sourceID] {
+		if !t.Expired(now) {
+			active = append(active, *t)
+		}
+	}
+	return active
+}
+
+// ExpiredTenants returns the sandbox tenants which are expired.
+func (r *Registry) ExpiredTenants(now time.Time) []Tenant {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var expired []Tenant
+	for _, t := range r.tenants {
+		if t.Expired(now) {
+			expired = append(expired, *t)
+		}
+	}
+	sortTenants(expired)
+	return expired
+}
+
+// Tenants returns all the sandbox tenants.
+func (r *Registry) Tenants() []Tenant {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	all := make([]Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		all = append(all, *t)
+	}
+	sortTenants(all)
+	return all
+}
+
+func sortTenants(tenants []Tenant) {
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].ID < tenants[j].ID
+	})
+}
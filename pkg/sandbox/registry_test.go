@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func prepareRegistry(t *testing.T, bucketClient objstore.Bucket) *Registry {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	cfg.Enabled = true
+
+	kvStore, closer := consul.NewInMemoryClient(GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+	cfg.KVStore.Store = "consul"
+	cfg.KVStore.Mock = kvStore
+
+	r, err := NewRegistry(cfg, bucketClient, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), r))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(context.Background(), r)) })
+
+	return r
+}
+
+func TestRegistry_Create(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	bucketClient := objstore.NewInMemBucket()
+	require.NoError(t, bucketClient.Upload(ctx, TenantIDPrefix+"with-data/bucket-index.json.gz", strings.NewReader("{}")))
+	r := prepareRegistry(t, bucketClient)
+
+	_, err := r.Create(ctx, TenantIDPrefix+"sandbox", "user", 0.5, time.Hour, now)
+	require.NoError(t, err)
+	_, err = r.Create(ctx, TenantIDPrefix+"nested", TenantIDPrefix+"source", 0.5, time.Hour, now)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		sandboxID, sourceID string
+		fraction            float64
+		ttl                 time.Duration
+		expectedErr         error
+	}{
+		"missing sandbox tenant ID":        {sourceID: "user", fraction: 0.5, ttl: time.Hour, expectedErr: errMissingTenantID},
+		"same tenant IDs":                  {sandboxID: "user", sourceID: "user", fraction: 0.5, ttl: time.Hour, expectedErr: errSameTenantID},
+		"invalid sandbox tenant ID":        {sandboxID: TenantIDPrefix + "sandbox/1", sourceID: "user", fraction: 0.5, ttl: time.Hour, expectedErr: errInvalidTenantID},
+		"missing sandbox tenant ID prefix": {sandboxID: "other", sourceID: "user", fraction: 0.5, ttl: time.Hour, expectedErr: errMissingTenantPrefix},
+		"only sandbox tenant ID prefix":    {sandboxID: TenantIDPrefix, sourceID: "user", fraction: 0.5, ttl: time.Hour, expectedErr: errMissingTenantPrefix},
+		"invalid fraction":                 {sandboxID: TenantIDPrefix + "other", sourceID: "user", fraction: 1.5, ttl: time.Hour, expectedErr: errInvalidFraction},
+		"TTL above the max TTL":            {sandboxID: TenantIDPrefix + "other", sourceID: "user", fraction: 0.5, ttl: 48 * time.Hour, expectedErr: errInvalidTTL},
+		"source is a sandbox":              {sandboxID: TenantIDPrefix + "other", sourceID: TenantIDPrefix + "sandbox", fraction: 0.5, ttl: time.Hour, expectedErr: errSourceIsSandbox},
+		"sandbox is a source":              {sandboxID: TenantIDPrefix + "source", sourceID: "other", fraction: 0.5, ttl: time.Hour, expectedErr: errSandboxIsSource},
+		"sandbox already exists":           {sandboxID: TenantIDPrefix + "sandbox", sourceID: "other", fraction: 0.5, ttl: time.Hour, expectedErr: errTenantAlreadyExists},
+		"sandbox has data in the bucket":   {sandboxID: TenantIDPrefix + "with-data", sourceID: "user", fraction: 0.5, ttl: time.Hour, expectedErr: errTenantHasData},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := r.Create(ctx, testData.sandboxID, testData.sourceID, testData.fraction, testData.ttl, now)
+			assert.ErrorIs(t, err, testData.expectedErr)
+		})
+	}
+}
+
+func TestRegistry_Expire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	r := prepareRegistry(t, objstore.NewInMemBucket())
+
+	_, err := r.Create(ctx, TenantIDPrefix+"1", "user", 0.5, time.Hour, now)
+	require.NoError(t, err)
+	_, err = r.Create(ctx, TenantIDPrefix+"2", "user", 0.5, time.Hour, now)
+	require.NoError(t, err)
+
+	assert.Len(t, r.ActiveSandboxesOf("user", now), 2)
+	assert.Empty(t, r.ExpiredTenants(now))
+
+	require.NoError(t, r.Expire(ctx, TenantIDPrefix+"1", now))
+	assert.ErrorIs(t, r.Expire(ctx, TenantIDPrefix+"1", now), errTenantAlreadyExpired)
+	assert.ErrorIs(t, r.Expire(ctx, "unknown", now), errTenantNotFound)
+
+	active := r.ActiveSandboxesOf("user", now)
+	require.Len(t, active, 1)
+	assert.Equal(t, TenantIDPrefix+"2", active[0].ID)
+
+	expired := r.ExpiredTenants(now)
+	require.Len(t, expired, 1)
+	assert.Equal(t, TenantIDPrefix+"1", expired[0].ID)
+
+	// Once the TTL is over, all sandbox tenants are expired.
+	assert.Empty(t, r.ActiveSandboxesOf("user", now.Add(time.Hour)))
+	assert.Len(t, r.ExpiredTenants(now.Add(time.Hour)), 2)
+
+	require.NoError(t, r.Remove(ctx, TenantIDPrefix+"1"))
+	_, ok := r.Get(TenantIDPrefix + "1")
+	assert.False(t, ok)
+	assert.Len(t, r.Tenants(), 1)
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := prepareRegistry(t, objstore.NewInMemBucket())
+
+	tests := []struct {
+		method         string
+		query          string
+		expectedStatus int
+	}{
+		{method: http.MethodPost, query: "sandbox=" + TenantIDPrefix + "sandbox&source=user&fraction=0.5&ttl=1h", expectedStatus: http.StatusOK},
+		{method: http.MethodPost, query: "sandbox=" + TenantIDPrefix + "sandbox&source=user&fraction=0.5&ttl=1h", expectedStatus: http.StatusConflict},
+		{method: http.MethodPost, query: "sandbox=" + TenantIDPrefix + "other&source=user&fraction=invalid&ttl=1h", expectedStatus: http.StatusBadRequest},
+		{method: http.MethodPost, query: "sandbox=" + TenantIDPrefix + "other&source=user&fraction=0.5", expectedStatus: http.StatusBadRequest},
+		{method: http.MethodPost, query: "sandbox=other&source=user&fraction=0.5&ttl=1h", expectedStatus: http.StatusBadRequest},
+		{method: http.MethodDelete, query: "sandbox=unknown", expectedStatus: http.StatusNotFound},
+		{method: http.MethodDelete, query: "sandbox=" + TenantIDPrefix + "sandbox", expectedStatus: http.StatusNoContent},
+	}
+
+	for _, testData := range tests {
+		req := httptest.NewRequest(testData.method, "/distributor/sandbox_tenants?"+testData.query, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, testData.expectedStatus, rec.Code, "%s %s: %s", testData.method, testData.query, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/distributor/sandbox_tenants", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var res tenantsResponse
+	require.NoError(t, json.NewDecoder(strings.NewReader(rec.Body.String())).Decode(&res))
+	require.Len(t, res.Tenants, 1)
+	assert.Equal(t, TenantIDPrefix+"sandbox", res.Tenants[0].ID)
+	assert.Equal(t, "user", res.Tenants[0].SourceID)
+	assert.True(t, res.Tenants[0].Expired(res.Now))
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sandbox
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/kv/memberlist"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ExpiredTenantsRetention is how long an expired sandbox tenant is kept in the KV store,
+	// giving time to the compactor to mark its blocks for deletion.
+	ExpiredTenantsRetention = 24 * time.Hour
+
+	// TenantIDPrefix is the reserved prefix of the sandbox tenant IDs. The prefix guarantees that the deletion
+	// of an expired sandbox tenant never deletes the data of a regular tenant.
+	TenantIDPrefix = "__sandbox__"
+
+	codecID = "sandboxTenant"
+)
+
+var (
+	errInvalidMaxTTL           = errors.New("the sandbox tenants max TTL must be greater than 0")
+	errInvalidTenantMergeable  = errors.New("sandbox tenant can only be merged with another sandbox tenant")
+	errInvalidTenantCodecValue = errors.New("sandbox tenant codec can only encode sandbox tenants")
+)
+
+// Config holds the configuration of the sandbox tenants.
+type Config struct {
+	Enabled bool          `yaml:"enabled" category:"experimental"`
+	MaxTTL  time.Duration `yaml:"max_ttl" category:"experimental"`
+
+	KVStore kv.Config `yaml:"kvstore" doc:"description=Backend storage to use for the sandbox tenants."`
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "sandbox-tenants.enabled", false, "Enable the API to create short-lived sandbox tenants, which receive a copy of a fraction of the series pushed by a source tenant. Once expired, the sandbox tenants are marked for deletion by the compactor.")
+	f.DurationVar(&cfg.MaxTTL, "sandbox-tenants.max-ttl", 24*time.Hour, "Maximum TTL of a sandbox tenant.")
+
+	cfg.KVStore.Store = "memberlist"
+	cfg.KVStore.RegisterFlagsWithPrefix("sandbox-tenants.", "sandbox-tenants/", f)
+}
+
+// Validate the config and returns an error on failure.
+func (cfg *Config) Validate() error {
+	if cfg.Enabled && cfg.MaxTTL <= 0 {
+		return errInvalidMaxTTL
+	}
+	return nil
+}
+
+// IsSandboxTenantID returns whether the input tenant ID has the reserved sandbox tenant prefix.
+func IsSandboxTenantID(userID string) bool {
+	return strings.HasPrefix(userID, TenantIDPrefix) && len(userID) > len(TenantIDPrefix)
+}
+
+// Tenant is a sandbox tenant, receiving a copy of a fraction of the series pushed by the source tenant until it expires.
+type Tenant struct {
+	ID        string    `json:"id"`
+	SourceID  string    `json:"source_id"`
+	Fraction  float64   `json:"fraction"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Expired returns whether the sandbox tenant is expired at the input time.
+func (t *Tenant) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// Merge implements memberlist.Mergeable. The most recently updated sandbox tenant wins. On equal
+// update timestamps, the sandbox tenant expiring first wins.
+func (t *Tenant) Merge(mergeable memberlist.Mergeable, _ bool) (memberlist.Mergeable, error) {
+	if mergeable == nil {
+		return nil, nil
+	}
+
+	other, ok := mergeable.(*Tenant)
+	if !ok {
+		return nil, errInvalidTenantMergeable
+	}
+	if other == nil || !other.supersedes(t) {
+		return nil, nil
+	}
+
+	*t = *other
+	return other.Clone(), nil
+}
+
+// supersedes returns whether t should replace other when merging them.
+func (t *Tenant) supersedes(other *Tenant) bool {
+	if !t.UpdatedAt.Equal(other.UpdatedAt) {
+		return t.UpdatedAt.After(other.UpdatedAt)
+	}
+	if !t.ExpiresAt.Equal(other.ExpiresAt) {
+		return t.ExpiresAt.Before(other.ExpiresAt)
+	}
+	return false
+}
+
+// MergeContent implements memberlist.Mergeable.
+func (t *Tenant) MergeContent() []string {
+	return []string{t.ID}
+}
+
+// RemoveTombstones implements memberlist.Mergeable. Expired sandbox tenants are not removed,
+// because they're used to mark the sandbox tenants for deletion.
+func (t *Tenant) RemoveTombstones(_ time.Time) (total, removed int) {
+	return 0, 0
+}
+
+// Clone implements memberlist.Mergeable.
+func (t *Tenant) Clone() memberlist.Mergeable {
+	clone := *t
+	return &clone
+}
+
+// Codec is the KV store codec for the sandbox tenants.
+type Codec struct{}
+
+// GetCodec returns the codec used to store the sandbox tenants in the KV store.
+func GetCodec() Codec {
+	return Codec{}
+}
+
+// CodecID implements codec.Codec.
+func (Codec) CodecID() string {
+	return codecID
+}
+
+// Decode implements codec.Codec.
+func (Codec) Decode(data []byte) (interface{}, error) {
+	t := &Tenant{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Encode implements codec.Codec.
+func (Codec) Encode(v interface{}) ([]byte, error) {
+	t, ok := v.(*Tenant)
+	if !ok {
+		return nil, errInvalidTenantCodecValue
+	}
+	return json.Marshal(t)
+}
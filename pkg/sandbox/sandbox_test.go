@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sandbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Enabled = true
+	assert.NoError(t, cfg.Validate())
+
+	cfg.MaxTTL = 0
+	assert.ErrorIs(t, cfg.Validate(), errInvalidMaxTTL)
+}
+
+func TestTenant_Merge(t *testing.T) {
+	now := time.Now().UTC()
+	created := Tenant{ID: "sandbox", SourceID: "user", Fraction: 0.1, CreatedAt: now, ExpiresAt: now.Add(time.Hour), UpdatedAt: now}
+
+	expired := created
+	expired.ExpiresAt = now.Add(time.Minute)
+	expired.UpdatedAt = now.Add(time.Minute)
+
+	tests := map[string]struct {
+		local, incoming Tenant
+		expected        Tenant
+		expectedChange  bool
+	}{
+		"incoming tenant updated more recently": {
+			local:          created,
+			incoming:       expired,
+			expected:       expired,
+			expectedChange: true,
+		},
+		"incoming tenant updated less recently": {
+			local:    expired,
+			incoming: created,
+			expected: expired,
+		},
+		"same tenant": {
+			local:    created,
+			incoming: created,
+			expected: created,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			local := testData.local
+			incoming := testData.incoming
+
+			change, err := local.Merge(&incoming, false)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, local)
+
+			if testData.expectedChange {
+				assert.Equal(t, &testData.expected, change)
+			} else {
+				assert.Nil(t, change)
+			}
+
+			// The merge must be idempotent.
+			change, err = local.Merge(&incoming, false)
+			require.NoError(t, err)
+			assert.Nil(t, change)
+		})
+	}
+}
+
+func TestCodec(t *testing.T) {
+	now := time.Now().UTC()
+	tenant := &Tenant{ID: "sandbox", SourceID: "user", Fraction: 0.1, CreatedAt: now, ExpiresAt: now.Add(time.Hour), UpdatedAt: now}
+
+	data, err := GetCodec().Encode(tenant)
+	require.NoError(t, err)
+
+	decoded, err := GetCodec().Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, tenant, decoded)
+
+	_, err = GetCodec().Encode("invalid")
+	assert.ErrorIs(t, err, errInvalidTenantCodecValue)
+}
@@ -14,6 +14,9 @@ import (
 )
 
 func NewBucketClient(cfg Config, name string, logger log.Logger) (objstore.Bucket, error) {
+	if cfg.FederatedTokenFile != "" {
+		return newWorkloadIdentityBucketClient(cfg, name, logger)
+	}
 	return newBucketClient(cfg, name, logger, azure.NewBucket)
 }
 
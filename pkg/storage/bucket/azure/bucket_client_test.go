@@ -62,3 +62,31 @@ func fakeFactory(t *testing.T, cfg Config) func(log.Logger, []byte, string) (*az
 		return &azure.Bucket{}, nil
 	}
 }
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg      Config
+		expected error
+	}{
+		"should pass with account key": {
+			cfg: Config{StorageAccountName: "test", StorageAccountKey: flagext.SecretWithValue("test")},
+		},
+		"should pass with federated token file, tenant ID and client ID": {
+			cfg: Config{StorageAccountName: "test", FederatedTokenFile: "/var/run/secrets/token", TenantID: "tenant", ClientID: "client"},
+		},
+		"should fail with federated token file and account key": {
+			cfg:      Config{StorageAccountName: "test", StorageAccountKey: flagext.SecretWithValue("test"), FederatedTokenFile: "/var/run/secrets/token", TenantID: "tenant", ClientID: "client"},
+			expected: errWorkloadIdentityWithAccountKey,
+		},
+		"should fail with federated token file and no client ID": {
+			cfg:      Config{StorageAccountName: "test", FederatedTokenFile: "/var/run/secrets/token", TenantID: "tenant"},
+			expected: errWorkloadIdentityMissingTenantClient,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testData.expected, testData.cfg.Validate())
+		})
+	}
+}
@@ -6,12 +6,18 @@
 package azure
 
 import (
+	"errors"
 	"flag"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/dskit/flagext"
 )
 
+var (
+	errWorkloadIdentityWithAccountKey      = errors.New("the Azure storage account key cannot be set when using the federated token file authentication")
+	errWorkloadIdentityMissingTenantClient = errors.New("the Azure tenant ID and client ID are required when using the federated token file authentication")
+)
+
 // Config holds the config options for an Azure backend
 type Config struct {
 	StorageAccountName string         `yaml:"account_name"`
@@ -21,6 +27,9 @@ type Config struct {
 	MaxRetries         int            `yaml:"max_retries" category:"advanced"`
 	MSIResource        string         `yaml:"msi_resource" category:"advanced" doc:"hidden"` // TODO Remove in Mimir 2.7.
 	UserAssignedID     string         `yaml:"user_assigned_id" category:"advanced"`
+	FederatedTokenFile string         `yaml:"federated_token_file" category:"experimental"`
+	TenantID           string         `yaml:"tenant_id" category:"experimental"`
+	ClientID           string         `yaml:"client_id" category:"experimental"`
 }
 
 // RegisterFlags registers the flags for Azure storage
@@ -37,4 +46,21 @@ func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet, logge
 	f.IntVar(&cfg.MaxRetries, prefix+"azure.max-retries", 20, "Number of retries for recoverable errors")
 	flagext.DeprecatedFlag(f, prefix+"azure.msi-resource", "Deprecated: this setting was used for obtaining ServicePrincipalToken from MSI. The Azure SDK now chooses the address.", logger)
 	f.StringVar(&cfg.UserAssignedID, prefix+"azure.user-assigned-id", "", "User assigned identity. If empty, then System assigned identity is used.")
+	f.StringVar(&cfg.FederatedTokenFile, prefix+"azure.federated-token-file", "", "Path to the federated token file used to authenticate with Azure workload identity. If set, the token is exchanged for an access token of the application identified by the configured tenant ID and client ID. The file is read again every time the access token is refreshed, to pick up the rotated tokens.")
+	f.StringVar(&cfg.TenantID, prefix+"azure.tenant-id", "", "Azure Active Directory tenant ID, used to authenticate with the federated token file.")
+	f.StringVar(&cfg.ClientID, prefix+"azure.client-id", "", "Client ID of the application or user assigned identity, used to authenticate with the federated token file.")
+}
+
+// Validate config and returns error on failure
+func (cfg *Config) Validate() error {
+	if cfg.FederatedTokenFile == "" {
+		return nil
+	}
+	if cfg.StorageAccountKey.String() != "" {
+		return errWorkloadIdentityWithAccountKey
+	}
+	if cfg.TenantID == "" || cfg.ClientID == "" {
+		return errWorkloadIdentityMissingTenantClient
+	}
+	return nil
 }
@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/thanos-io/objstore/blob/main/providers/azure/azure.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Thanos Authors.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/exthttp"
+	"github.com/thanos-io/objstore/providers/azure"
+)
+
+// workloadIdentityBucket is an Azure bucket client authenticating with Azure workload identity,
+// which isn't supported by the Thanos Azure client. It's a port of the Thanos one, with a different
+// credential.
+type workloadIdentityBucket struct {
+	logger           log.Logger
+	containerClient  *container.Client
+	containerName    string
+	readerMaxRetries int
+}
+
+func newWorkloadIdentityBucketClient(cfg Config, name string, logger log.Logger) (objstore.Bucket, error) {
+	level.Debug(logger).Log("msg", "creating new Azure bucket connection with workload identity", "component", name)
+
+	conf := azure.DefaultConfig
+	if cfg.Endpoint != "" {
+		conf.Endpoint = cfg.Endpoint
+	}
+	if cfg.MaxRetries > 0 {
+		conf.PipelineConfig.MaxTries = int32(cfg.MaxRetries)
+		conf.ReaderConfig.MaxRetryRequests = cfg.MaxRetries
+	}
+
+	dt, err := exthttp.DefaultTransport(conf.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	clientOpts := azcore.ClientOptions{
+		Retry: policy.RetryOptions{
+			MaxRetries:    conf.PipelineConfig.MaxTries,
+			TryTimeout:    time.Duration(conf.PipelineConfig.TryTimeout),
+			RetryDelay:    time.Duration(conf.PipelineConfig.RetryDelay),
+			MaxRetryDelay: time.Duration(conf.PipelineConfig.MaxRetryDelay),
+		},
+		Telemetry: policy.TelemetryOptions{
+			ApplicationID: "Mimir",
+		},
+		Transport: &http.Client{Transport: dt},
+	}
+
+	// The token in the file is periodically rotated, so we read it every time a new access token is requested.
+	// The access token is cached and refreshed before it expires by the credential.
+	cred, err := azidentity.NewClientAssertionCredential(cfg.TenantID, cfg.ClientID, func(context.Context) (string, error) {
+		token, err := os.ReadFile(cfg.FederatedTokenFile)
+		if err != nil {
+			return "", errors.Wrap(err, "read Azure federated token file")
+		}
+		return strings.TrimSpace(string(token)), nil
+	}, &azidentity.ClientAssertionCredentialOptions{ClientOptions: clientOpts})
+	if err != nil {
+		return nil, err
+	}
+
+	containerURL := fmt.Sprintf("https://%s.%s/%s", cfg.StorageAccountName, conf.Endpoint, cfg.ContainerName)
+	containerClient, err := container.NewClient(containerURL, cred, &container.ClientOptions{ClientOptions: clientOpts})
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if storage account container already exists, and create one if it does not.
+	ctx := context.Background()
+	if _, err := containerClient.GetProperties(ctx, &container.GetPropertiesOptions{}); err != nil {
+		if !bloberror.HasCode(err, bloberror.ContainerNotFound) {
+			return nil, err
+		}
+		if _, err := containerClient.Create(ctx, nil); err != nil {
+			return nil, errors.Wrapf(err, "error creating Azure blob container: %s", cfg.ContainerName)
+		}
+		level.Info(logger).Log("msg", "Azure blob container successfully created", "address", cfg.ContainerName)
+	}
+
+	return &workloadIdentityBucket{
+		logger:           logger,
+		containerClient:  containerClient,
+		containerName:    cfg.ContainerName,
+		readerMaxRetries: conf.ReaderConfig.MaxRetryRequests,
+	}, nil
+}
+
+// Iter calls f for each entry in the given directory. The argument to f is the full
+// object name including the prefix of the inspected directory.
+func (b *workloadIdentityBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	prefix := dir
+	if prefix != "" && !strings.HasSuffix(prefix, azure.DirDelim) {
+		prefix += azure.DirDelim
+	}
+
+	params := objstore.ApplyIterOptions(options...)
+	if params.Recursive {
+		pager := b.containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+		for pager.More() {
+			resp, err := pager.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, blobItem := range resp.Segment.BlobItems {
+				if err := f(*blobItem.Name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	pager := b.containerClient.NewListBlobsHierarchyPager(azure.DirDelim, &container.ListBlobsHierarchyOptions{Prefix: &prefix})
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, blobItem := range resp.Segment.BlobItems {
+			if err := f(*blobItem.Name); err != nil {
+				return err
+			}
+		}
+		for _, blobPrefix := range resp.Segment.BlobPrefixes {
+			if err := f(*blobPrefix.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
+func (b *workloadIdentityBucket) IsObjNotFoundErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	return bloberror.HasCode(err, bloberror.BlobNotFound) || bloberror.HasCode(err, bloberror.InvalidURI)
+}
+
+func (b *workloadIdentityBucket) getBlobReader(ctx context.Context, name string, httpRange blob.HTTPRange) (io.ReadCloser, error) {
+	level.Debug(b.logger).Log("msg", "getting blob", "blob", name, "offset", httpRange.Offset, "length", httpRange.Count)
+	if name == "" {
+		return nil, errors.New("blob name cannot be empty")
+	}
+	blobClient := b.containerClient.NewBlobClient(name)
+	resp, err := blobClient.DownloadStream(ctx, &blob.DownloadStreamOptions{Range: httpRange})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot download blob, address: %s", blobClient.URL())
+	}
+	return resp.NewRetryReader(ctx, &azblob.RetryReaderOptions{MaxRetries: int32(b.readerMaxRetries)}), nil
+}
+
+// Get returns a reader for the given object name.
+func (b *workloadIdentityBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.getBlobReader(ctx, name, blob.HTTPRange{})
+}
+
+// GetRange returns a new range reader for the given object name and range.
+func (b *workloadIdentityBucket) GetRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	return b.getBlobReader(ctx, name, blob.HTTPRange{Offset: offset, Count: length})
+}
+
+// Attributes returns information about the specified object.
+func (b *workloadIdentityBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	level.Debug(b.logger).Log("msg", "getting blob attributes", "blob", name)
+	resp, err := b.containerClient.NewBlobClient(name).GetProperties(ctx, nil)
+	if err != nil {
+		return objstore.ObjectAttributes{}, err
+	}
+	return objstore.ObjectAttributes{
+		Size:         *resp.ContentLength,
+		LastModified: *resp.LastModified,
+	}, nil
+}
+
+// Exists checks if the given object exists.
+func (b *workloadIdentityBucket) Exists(ctx context.Context, name string) (bool, error) {
+	level.Debug(b.logger).Log("msg", "checking if blob exists", "blob", name)
+	if _, err := b.containerClient.NewBlobClient(name).GetProperties(ctx, nil); err != nil {
+		if b.IsObjNotFoundErr(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "cannot get properties for Azure blob, address: %s", name)
+	}
+	return true, nil
+}
+
+// Upload the contents of the reader as an object into the bucket.
+func (b *workloadIdentityBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	level.Debug(b.logger).Log("msg", "uploading blob", "blob", name)
+	opts := &blockblob.UploadStreamOptions{
+		BlockSize:   3 * 1024 * 1024,
+		Concurrency: 4,
+	}
+	if _, err := b.containerClient.NewBlockBlobClient(name).UploadStream(ctx, r, opts); err != nil {
+		return errors.Wrapf(err, "cannot upload Azure blob, address: %s", name)
+	}
+	return nil
+}
+
+// Delete removes the object with the given name.
+func (b *workloadIdentityBucket) Delete(ctx context.Context, name string) error {
+	level.Debug(b.logger).Log("msg", "deleting blob", "blob", name)
+	opts := &blob.DeleteOptions{
+		DeleteSnapshots: to.Ptr(blob.DeleteSnapshotsOptionTypeInclude),
+	}
+	if _, err := b.containerClient.NewBlobClient(name).Delete(ctx, opts); err != nil {
+		return errors.Wrapf(err, "error deleting blob, address: %s", name)
+	}
+	return nil
+}
+
+// Name returns Azure container name.
+func (b *workloadIdentityBucket) Name() string {
+	return b.containerName
+}
+
+// Close bucket.
+func (b *workloadIdentityBucket) Close() error {
+	return nil
+}
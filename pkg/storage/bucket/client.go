@@ -106,10 +106,19 @@ func (cfg *StorageBackendConfig) Validate() error {
 		return ErrUnsupportedStorageBackend
 	}
 
-	if cfg.Backend == S3 {
+	switch cfg.Backend {
+	case S3:
 		if err := cfg.S3.Validate(); err != nil {
 			return err
 		}
+	case GCS:
+		if err := cfg.GCS.Validate(); err != nil {
+			return err
+		}
+	case Azure:
+		if err := cfg.Azure.Validate(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -7,8 +7,10 @@ package gcs
 
 import (
 	"context"
+	"os"
 
 	"github.com/go-kit/log"
+	"github.com/pkg/errors"
 	"github.com/thanos-io/objstore"
 	"github.com/thanos-io/objstore/providers/gcs"
 	yaml "gopkg.in/yaml.v3"
@@ -21,6 +23,16 @@ func NewBucketClient(ctx context.Context, cfg Config, name string, logger log.Lo
 		ServiceAccount: cfg.ServiceAccount.String(),
 	}
 
+	// The credentials file only holds the configuration to get the access tokens (e.g. where to read the
+	// workload identity federation credentials from), so it's read once and the tokens are refreshed by the client.
+	if cfg.CredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read GCS credentials file")
+		}
+		bucketConfig.ServiceAccount = string(credentials)
+	}
+
 	// Thanos currently doesn't support passing the config as is, but expects a YAML,
 	// so we're going to serialize it.
 	serialized, err := yaml.Marshal(bucketConfig)
@@ -6,15 +6,19 @@
 package gcs
 
 import (
+	"errors"
 	"flag"
 
 	"github.com/grafana/dskit/flagext"
 )
 
+var errServiceAccountAndCredentialsFile = errors.New("the GCS service account and credentials file cannot be both set")
+
 // Config holds the config options for GCS backend
 type Config struct {
-	BucketName     string         `yaml:"bucket_name"`
-	ServiceAccount flagext.Secret `yaml:"service_account" doc:"description_method=GCSServiceAccountLongDescription"`
+	BucketName      string         `yaml:"bucket_name"`
+	ServiceAccount  flagext.Secret `yaml:"service_account" doc:"description_method=GCSServiceAccountLongDescription"`
+	CredentialsFile string         `yaml:"credentials_file" category:"experimental"`
 }
 
 // RegisterFlags registers the flags for GCS storage
@@ -26,6 +30,15 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.StringVar(&cfg.BucketName, prefix+"gcs.bucket-name", "", "GCS bucket name")
 	f.Var(&cfg.ServiceAccount, prefix+"gcs.service-account", cfg.GCSServiceAccountShortDescription())
+	f.StringVar(&cfg.CredentialsFile, prefix+"gcs.credentials-file", "", "Path to a JSON credentials file, used in place of the service account. The file can contain a service account key or a workload identity federation configuration, for example to authenticate with AWS or OIDC credentials. Access tokens are refreshed automatically.")
+}
+
+// Validate config and returns error on failure
+func (cfg *Config) Validate() error {
+	if cfg.ServiceAccount.String() != "" && cfg.CredentialsFile != "" {
+		return errServiceAccountAndCredentialsFile
+	}
+	return nil
 }
 
 func (cfg *Config) GCSServiceAccountShortDescription() string {
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package gcs
+
+import (
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg      Config
+		expected error
+	}{
+		"should pass with default config": {
+			cfg: Config{BucketName: "test"},
+		},
+		"should pass with service account": {
+			cfg: Config{BucketName: "test", ServiceAccount: flagext.SecretWithValue("{}")},
+		},
+		"should pass with credentials file": {
+			cfg: Config{BucketName: "test", CredentialsFile: "/var/run/secrets/credentials.json"},
+		},
+		"should fail with both service account and credentials file": {
+			cfg:      Config{BucketName: "test", ServiceAccount: flagext.SecretWithValue("{}"), CredentialsFile: "/var/run/secrets/credentials.json"},
+			expected: errServiceAccountAndCredentialsFile,
+		},
+	}
+
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testData.expected, testData.cfg.Validate())
+		})
+	}
+}
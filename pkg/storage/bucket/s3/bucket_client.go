@@ -51,7 +51,7 @@ func newS3Config(cfg Config) (s3.Config, error) {
 
 	return s3.Config{
 		Bucket:          cfg.BucketName,
-		Endpoint:        cfg.Endpoint,
+		Endpoint:        cfg.resolvedEndpoint(),
 		Region:          cfg.Region,
 		AccessKey:       cfg.AccessKeyID,
 		SecretKey:       cfg.SecretAccessKey.String(),
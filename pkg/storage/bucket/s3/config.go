@@ -45,6 +45,8 @@ var (
 	errUnsupportedStorageClass     = fmt.Errorf("unsupported S3 storage class (supported values: %s)", strings.Join(supportedStorageClasses, ", "))
 	errInvalidSSEContext           = errors.New("invalid S3 SSE encryption context")
 	errInvalidEndpointPrefix       = errors.New("the endpoint must not prefixed with the bucket name")
+	errEndpointWithDualstackOrFIPS = errors.New("the S3 endpoint must not be set when dual-stack or FIPS endpoints are enabled, because it's derived from the region")
+	errMissingRegion               = errors.New("the S3 region is required when dual-stack or FIPS endpoints are enabled")
 )
 
 // HTTPConfig stores the http.Transport configuration for the s3 minio client.
@@ -84,6 +86,8 @@ type Config struct {
 	Insecure         bool           `yaml:"insecure" category:"advanced"`
 	SignatureVersion string         `yaml:"signature_version" category:"advanced"`
 	StorageClass     string         `yaml:"storage_class"`
+	DualstackEnabled bool           `yaml:"dualstack_enabled" category:"experimental"`
+	FIPSEnabled      bool           `yaml:"fips_enabled" category:"experimental"`
 
 	SSE  SSEConfig  `yaml:"sse"`
 	HTTP HTTPConfig `yaml:"http"`
@@ -104,6 +108,8 @@ func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.BoolVar(&cfg.Insecure, prefix+"s3.insecure", false, "If enabled, use http:// for the S3 endpoint instead of https://. This could be useful in local dev/test environments while using an S3-compatible backend storage, like Minio.")
 	f.StringVar(&cfg.SignatureVersion, prefix+"s3.signature-version", SignatureVersionV4, fmt.Sprintf("The signature version to use for authenticating against S3. Supported values are: %s.", strings.Join(supportedSignatureVersions, ", ")))
 	f.StringVar(&cfg.StorageClass, prefix+"s3.storage-class", "", "The S3 storage class to use. Details can be found at https://aws.amazon.com/s3/storage-classes/. Supported values are: "+strings.Join(supportedStorageClasses, ", "))
+	f.BoolVar(&cfg.DualstackEnabled, prefix+"s3.dualstack-enabled", false, "If enabled, use the AWS S3 dual-stack (IPv4 and IPv6) endpoint of the configured region. The endpoint must not be set, because it's derived from the region.")
+	f.BoolVar(&cfg.FIPSEnabled, prefix+"s3.fips-enabled", false, "If enabled, use the AWS S3 FIPS endpoint of the configured region. The endpoint must not be set, because it's derived from the region.")
 	cfg.SSE.RegisterFlagsWithPrefix(prefix+"s3.sse.", f)
 	cfg.HTTP.RegisterFlagsWithPrefix(prefix, f)
 }
@@ -119,6 +125,14 @@ func (cfg *Config) Validate() error {
 			return errInvalidEndpointPrefix
 		}
 	}
+	if cfg.DualstackEnabled || cfg.FIPSEnabled {
+		if cfg.Endpoint != "" {
+			return errEndpointWithDualstackOrFIPS
+		}
+		if cfg.Region == "" {
+			return errMissingRegion
+		}
+	}
 	if !util.StringsContain(supportedStorageClasses, cfg.StorageClass) && cfg.StorageClass != "" {
 		return errUnsupportedStorageClass
 	}
@@ -129,6 +143,28 @@ func (cfg *Config) Validate() error {
 	return nil
 }
 
+// resolvedEndpoint returns the endpoint to connect to. When dual-stack or FIPS endpoints are enabled,
+// the AWS endpoint is derived from the region, otherwise the configured endpoint is returned.
+func (cfg *Config) resolvedEndpoint() string {
+	if !cfg.DualstackEnabled && !cfg.FIPSEnabled {
+		return cfg.Endpoint
+	}
+
+	host := "s3"
+	if cfg.FIPSEnabled {
+		host = "s3-fips"
+	}
+	if cfg.DualstackEnabled {
+		host += ".dualstack"
+	}
+
+	domain := "amazonaws.com"
+	if strings.HasPrefix(cfg.Region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return fmt.Sprintf("%s.%s.%s", host, cfg.Region, domain)
+}
+
 // SSEConfig configures S3 server side encryption
 // struct that is going to receive user input (through config file or CLI)
 type SSEConfig struct {
@@ -110,6 +110,36 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expected: errInvalidEndpointPrefix,
 		},
+		"should pass with dual-stack and FIPS endpoints enabled": {
+			setup: func() *Config {
+				return &Config{
+					Region:           "us-east-1",
+					DualstackEnabled: true,
+					FIPSEnabled:      true,
+					SignatureVersion: SignatureVersionV4,
+				}
+			},
+		},
+		"should fail with dual-stack endpoint enabled and endpoint set": {
+			setup: func() *Config {
+				return &Config{
+					Endpoint:         "s3.us-east-1.amazonaws.com",
+					Region:           "us-east-1",
+					DualstackEnabled: true,
+					SignatureVersion: SignatureVersionV4,
+				}
+			},
+			expected: errEndpointWithDualstackOrFIPS,
+		},
+		"should fail with FIPS endpoint enabled and no region": {
+			setup: func() *Config {
+				return &Config{
+					FIPSEnabled:      true,
+					SignatureVersion: SignatureVersionV4,
+				}
+			},
+			expected: errMissingRegion,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -119,6 +149,40 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestConfig_ResolvedEndpoint(t *testing.T) {
+	tests := map[string]struct {
+		cfg      Config
+		expected string
+	}{
+		"dual-stack and FIPS disabled": {
+			cfg:      Config{Endpoint: "localhost:9000", Region: "us-east-1"},
+			expected: "localhost:9000",
+		},
+		"dual-stack enabled": {
+			cfg:      Config{Region: "eu-central-1", DualstackEnabled: true},
+			expected: "s3.dualstack.eu-central-1.amazonaws.com",
+		},
+		"FIPS enabled": {
+			cfg:      Config{Region: "us-gov-west-1", FIPSEnabled: true},
+			expected: "s3-fips.us-gov-west-1.amazonaws.com",
+		},
+		"dual-stack and FIPS enabled": {
+			cfg:      Config{Region: "us-east-2", DualstackEnabled: true, FIPSEnabled: true},
+			expected: "s3-fips.dualstack.us-east-2.amazonaws.com",
+		},
+		"dual-stack enabled in a China region": {
+			cfg:      Config{Region: "cn-north-1", DualstackEnabled: true},
+			expected: "s3.dualstack.cn-north-1.amazonaws.com.cn",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, testData.cfg.resolvedEndpoint())
+		})
+	}
+}
+
 func TestSSEConfig_BuildMinioConfig(t *testing.T) {
 	tests := map[string]struct {
 		cfg             *SSEConfig
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"flag"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errMissingKafkaAddress = errors.New("the Kafka address has not been configured")
+	errMissingKafkaTopic   = errors.New("the Kafka topic has not been configured")
+	errInvalidDialTimeout  = errors.New("the Kafka dial timeout must be greater than 0")
+	errInvalidWriteTimeout = errors.New("the Kafka write timeout must be greater than 0")
+)
+
+// Config is the config of the ingest storage, where the distributors write the series to a partitioned
+// Kafka-compatible log, and the ingesters consume them from the log.
+type Config struct {
+	Enabled     bool        `yaml:"enabled" category:"experimental"`
+	KafkaConfig KafkaConfig `yaml:"kafka"`
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "ingest-storage.enabled", false, "True to enable the ingest storage architecture, where the distributors write the series to the partitions of a Kafka-compatible log, and each ingester consumes the partition whose ID is the sequence number at the end of its instance ID, for example 3 for ingester-zone-a-3. The partitions are the partitions of the Kafka topic. An ingester replays its partition from the last consumed offset on startup, before accepting queries.")
+
+	cfg.KafkaConfig.RegisterFlagsWithPrefix("ingest-storage.kafka.", f)
+}
+
+// Validate the config and returns an error on failure.
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	return cfg.KafkaConfig.Validate()
+}
+
+// KafkaConfig holds the config of the Kafka-compatible backend of the ingest storage.
+type KafkaConfig struct {
+	Address      string        `yaml:"address" category:"experimental"`
+	Topic        string        `yaml:"topic" category:"experimental"`
+	ClientID     string        `yaml:"client_id" category:"experimental"`
+	DialTimeout  time.Duration `yaml:"dial_timeout" category:"experimental"`
+	WriteTimeout time.Duration `yaml:"write_timeout" category:"experimental"`
+}
+
+func (cfg *KafkaConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Address, prefix+"address", "", "Comma-separated list of the addresses of the Kafka brokers used to bootstrap the connection. The brokers leading the partitions are discovered from the topic metadata. The connections to the brokers are plaintext: TLS and SASL authentication are not supported.")
+	f.StringVar(&cfg.Topic, prefix+"topic", "", "The Kafka topic storing the series. The topic must exist, and have as many partitions as the ingesters of each zone.")
+	f.StringVar(&cfg.ClientID, prefix+"client-id", "mimir", "The client ID sent to the Kafka brokers.")
+	f.DurationVar(&cfg.DialTimeout, prefix+"dial-timeout", 2*time.Second, "The maximum time allowed to open a connection to a Kafka broker.")
+	f.DurationVar(&cfg.WriteTimeout, prefix+"write-timeout", 10*time.Second, "The maximum time allowed to write a request to a Kafka broker and read its response, not including the time waiting for new records when fetching.")
+}
+
+// Validate the config and returns an error on failure.
+func (cfg *KafkaConfig) Validate() error {
+	if cfg.Address == "" {
+		return errMissingKafkaAddress
+	}
+	if cfg.Topic == "" {
+		return errMissingKafkaTopic
+	}
+	if cfg.DialTimeout <= 0 {
+		return errInvalidDialTimeout
+	}
+	if cfg.WriteTimeout <= 0 {
+		return errInvalidWriteTimeout
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup       func(*Config)
+		expectedErr error
+	}{
+		"should pass with the default config": {
+			setup: func(*Config) {},
+		},
+		"should pass with the Kafka address and topic configured": {
+			setup: func(cfg *Config) {
+				cfg.Enabled = true
+				cfg.KafkaConfig.Address = "localhost:9092"
+				cfg.KafkaConfig.Topic = "mimir"
+			},
+		},
+		"should fail if the Kafka address is missing": {
+			setup: func(cfg *Config) {
+				cfg.Enabled = true
+				cfg.KafkaConfig.Topic = "mimir"
+			},
+			expectedErr: errMissingKafkaAddress,
+		},
+		"should fail if the Kafka topic is missing": {
+			setup: func(cfg *Config) {
+				cfg.Enabled = true
+				cfg.KafkaConfig.Address = "localhost:9092"
+			},
+			expectedErr: errMissingKafkaTopic,
+		},
+		"should fail if the Kafka write timeout is not positive": {
+			setup: func(cfg *Config) {
+				cfg.Enabled = true
+				cfg.KafkaConfig.Address = "localhost:9092"
+				cfg.KafkaConfig.Topic = "mimir"
+				cfg.KafkaConfig.WriteTimeout = 0
+			},
+			expectedErr: errInvalidWriteTimeout,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{}
+			cfg.RegisterFlags(flag.NewFlagSet("", flag.PanicOnError))
+			tc.setup(&cfg)
+
+			assert.Equal(t, tc.expectedErr, cfg.Validate())
+		})
+	}
+}
@@ -0,0 +1,392 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	// metadataMaxAge is the maximum age of the topic metadata, after which it's refreshed.
+	metadataMaxAge = 10 * time.Second
+
+	// maxResponseSize is the maximum size of a response read from a broker.
+	maxResponseSize = 256 << 20
+)
+
+// kafkaClient is a minimal client of the Kafka protocol, supporting the requests needed by the ingest
+// storage: the topic metadata, produce, fetch and list offsets. The requests of a partition are sent to
+// the broker leading it, which is discovered from the topic metadata. Each broker connection serves
+// a request at a time, and idle connections are pooled to be reused by the subsequent requests.
+type kafkaClient struct {
+	cfg    KafkaConfig
+	logger log.Logger
+
+	mtx               sync.Mutex
+	idleConns         map[string][]*kafkaConn // By broker address.
+	brokers           map[int32]string        // Address by broker node ID.
+	leaders           map[int32]int32         // Leader node ID by partition.
+	partitions        []int32
+	metadataUpdatedAt time.Time
+	correlationID     int32
+}
+
+func newKafkaClient(cfg KafkaConfig, logger log.Logger) *kafkaClient {
+	return &kafkaClient{
+		cfg:       cfg,
+		logger:    logger,
+		idleConns: map[string][]*kafkaConn{},
+		brokers:   map[int32]string{},
+		leaders:   map[int32]int32{},
+	}
+}
+
+// partitionIDs returns the sorted IDs of the partitions of the topic.
+func (c *kafkaClient) partitionIDs(ctx context.Context) ([]int32, error) {
+	c.mtx.Lock()
+	partitions, updatedAt := c.partitions, c.metadataUpdatedAt
+	c.mtx.Unlock()
+
+	if len(partitions) > 0 && time.Since(updatedAt) < metadataMaxAge {
+		return partitions, nil
+	}
+
+	if err := c.refreshMetadata(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.partitions, nil
+}
+
+// refreshMetadata fetches the topic metadata from the first broker responding among the known brokers
+// and the bootstrap addresses.
+func (c *kafkaClient) refreshMetadata(ctx context.Context) error {
+	c.mtx.Lock()
+	addrs := make([]string, 0, len(c.brokers))
+	for _, addr := range c.brokers {
+		addrs = append(addrs, addr)
+	}
+	c.mtx.Unlock()
+
+	for _, addr := range strings.Split(c.cfg.Address, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		e := kafkaEncoder{}
+		encodeMetadataRequest(&e, c.cfg.Topic)
+
+		b, err := c.roundTrip(ctx, addr, apiKeyMetadata, metadataVersion, e.buf, 0)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		res, err := decodeMetadataResponse(b)
+		if err != nil {
+			lastErr = errors.Wrap(err, "decode metadata response")
+			continue
+		}
+
+		return c.updateMetadata(res)
+	}
+
+	return errors.Wrap(lastErr, "failed to fetch the Kafka topic metadata")
+}
+
+func (c *kafkaClient) updateMetadata(res metadataResponse) error {
+	var topic *metadataTopic
+	for i := range res.topics {
+		if res.topics[i].name == c.cfg.Topic {
+			topic = &res.topics[i]
+		}
+	}
+	if topic == nil {
+		return fmt.Errorf("the metadata of the Kafka topic %s is missing", c.cfg.Topic)
+	}
+	if err := kafkaErrorFromCode(topic.errorCode); err != nil {
+		return errors.Wrapf(err, "failed to fetch the metadata of the Kafka topic %s", c.cfg.Topic)
+	}
+
+	brokers := make(map[int32]string, len(res.brokers))
+	for _, b := range res.brokers {
+		brokers[b.nodeID] = net.JoinHostPort(b.host, strconv.Itoa(int(b.port)))
+	}
+
+	leaders := make(map[int32]int32, len(topic.partitions))
+	partitions := make([]int32, 0, len(topic.partitions))
+	for _, p := range topic.partitions {
+		partitions = append(partitions, p.id)
+		if p.errorCode == 0 && p.leader >= 0 {
+			leaders[p.id] = p.leader
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.brokers = brokers
+	c.leaders = leaders
+	c.partitions = partitions
+	c.metadataUpdatedAt = time.Now()
+	return nil
+}
+
+// leaderAddr returns the address of the broker leading the input partition.
+func (c *kafkaClient) leaderAddr(ctx context.Context, partition int32) (string, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		c.mtx.Lock()
+		leader, ok := c.leaders[partition]
+		addr := c.brokers[leader]
+		c.mtx.Unlock()
+
+		if ok && addr != "" {
+			return addr, nil
+		}
+		if attempt == 0 {
+			if err := c.refreshMetadata(ctx); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return "", fmt.Errorf("the leader of the partition %d of the Kafka topic %s is not available", partition, c.cfg.Topic)
+}
+
+// partitionRoundTrip sends the request to the broker leading the input partition, and decodes its response
+// with decode. The request is retried once after refreshing the topic metadata if the partition leader
+// has changed, or the broker connection failed.
+func (c *kafkaClient) partitionRoundTrip(ctx context.Context, partition int32, apiKey, apiVersion int16, body []byte, extraTimeout time.Duration, decode func([]byte) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 {
+			if err := c.refreshMetadata(ctx); err != nil {
+				return err
+			}
+		}
+
+		var addr string
+		addr, err = c.leaderAddr(ctx, partition)
+		if err != nil {
+			return err
+		}
+
+		var b []byte
+		b, err = c.roundTrip(ctx, addr, apiKey, apiVersion, body, extraTimeout)
+		if err == nil {
+			err = decode(b)
+		}
+
+		var kerr kafkaError
+		var netErr net.Error
+		if !(errors.As(err, &kerr) && kerr.staleMetadata()) && !errors.As(err, &netErr) && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		level.Debug(c.logger).Log("msg", "retrying Kafka request after refreshing the topic metadata", "partition", partition, "err", err)
+	}
+	return err
+}
+
+// produce writes the input records to the partition, and returns the offset of the first record once
+// all the in-sync replicas have acknowledged them.
+func (c *kafkaClient) produce(ctx context.Context, partition int32, records []record) (int64, error) {
+	e := kafkaEncoder{}
+	encodeProduceRequest(&e, c.cfg.Topic, partition, int32(c.cfg.WriteTimeout.Milliseconds()), encodeRecordBatch(records))
+
+	var baseOffset int64
+	err := c.partitionRoundTrip(ctx, partition, apiKeyProduce, produceVersion, e.buf, 0, func(b []byte) error {
+		res, err := decodeProduceResponse(b, c.cfg.Topic, partition)
+		if err != nil {
+			return errors.Wrap(err, "decode produce response")
+		}
+		baseOffset = res.baseOffset
+		return kafkaErrorFromCode(res.errorCode)
+	})
+	return baseOffset, err
+}
+
+// fetch reads the records of the partition starting from the input offset, waiting up to maxWait for
+// new records if there are none. It returns the records, and the offset to fetch next, which follows
+// the fetched records, including the skipped control records.
+func (c *kafkaClient) fetch(ctx context.Context, partition int32, offset int64, maxWait time.Duration, maxBytes int32) ([]record, int64, error) {
+	e := kafkaEncoder{}
+	encodeFetchRequest(&e, c.cfg.Topic, partition, offset, int32(maxWait.Milliseconds()), maxBytes)
+
+	var (
+		records    []record
+		nextOffset int64
+	)
+	err := c.partitionRoundTrip(ctx, partition, apiKeyFetch, fetchVersion, e.buf, maxWait, func(b []byte) error {
+		res, err := decodeFetchResponse(b, c.cfg.Topic, partition)
+		if err != nil {
+			return errors.Wrap(err, "decode fetch response")
+		}
+		if err := kafkaErrorFromCode(res.errorCode); err != nil {
+			return err
+		}
+
+		records, nextOffset, err = decodeRecordBatches(res.records, offset)
+		return errors.Wrap(err, "decode fetched records")
+	})
+	return records, nextOffset, err
+}
+
+// listOffset returns the offset of the partition for the input timestamp, either listOffsetsLatest,
+// listOffsetsEarliest, or a timestamp in milliseconds, in which case the offset is the one of the first
+// record whose timestamp is greater than or equal to it, or -1 if there's none.
+func (c *kafkaClient) listOffset(ctx context.Context, partition int32, timestamp int64) (int64, error) {
+	e := kafkaEncoder{}
+	encodeListOffsetsRequest(&e, c.cfg.Topic, partition, timestamp)
+
+	var offset int64
+	err := c.partitionRoundTrip(ctx, partition, apiKeyListOffsets, listOffsetsVersion, e.buf, 0, func(b []byte) error {
+		res, err := decodeListOffsetsResponse(b, c.cfg.Topic, partition)
+		if err != nil {
+			return errors.Wrap(err, "decode list offsets response")
+		}
+		offset = res.offset
+		return kafkaErrorFromCode(res.errorCode)
+	})
+	return offset, err
+}
+
+// roundTrip sends the request to the broker at the input address, and returns the response body.
+// The extra timeout is added to the write timeout, to account for the time the broker can wait
+// before responding.
+func (c *kafkaClient) roundTrip(ctx context.Context, addr string, apiKey, apiVersion int16, body []byte, extraTimeout time.Duration) ([]byte, error) {
+	conn, err := c.getConn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	c.correlationID++
+	correlationID := c.correlationID
+	c.mtx.Unlock()
+
+	deadline := time.Now().Add(c.cfg.WriteTimeout + extraTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	res, err := conn.roundTrip(deadline, apiKey, apiVersion, correlationID, c.cfg.ClientID, body)
+	if err != nil {
+		// The other idle connections to the broker are likely broken too, for example if it restarted.
+		_ = conn.Close()
+		c.closeIdleConns(addr)
+		return nil, errors.Wrapf(err, "request to Kafka broker %s", addr)
+	}
+
+	c.putConn(conn)
+	return res, nil
+}
+
+func (c *kafkaClient) getConn(ctx context.Context, addr string) (*kafkaConn, error) {
+	c.mtx.Lock()
+	if idle := c.idleConns[addr]; len(idle) > 0 {
+		conn := idle[len(idle)-1]
+		c.idleConns[addr] = idle[:len(idle)-1]
+		c.mtx.Unlock()
+		return conn, nil
+	}
+	c.mtx.Unlock()
+
+	dialer := net.Dialer{Timeout: c.cfg.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial Kafka broker %s", addr)
+	}
+	return &kafkaConn{Conn: conn, addr: addr}, nil
+}
+
+func (c *kafkaClient) putConn(conn *kafkaConn) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.idleConns[conn.addr] = append(c.idleConns[conn.addr], conn)
+}
+
+func (c *kafkaClient) closeIdleConns(addr string) {
+	c.mtx.Lock()
+	conns := c.idleConns[addr]
+	delete(c.idleConns, addr)
+	c.mtx.Unlock()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+// close closes the idle connections to the brokers.
+func (c *kafkaClient) close() {
+	c.mtx.Lock()
+	addrs := make([]string, 0, len(c.idleConns))
+	for addr := range c.idleConns {
+		addrs = append(addrs, addr)
+	}
+	c.mtx.Unlock()
+
+	for _, addr := range addrs {
+		c.closeIdleConns(addr)
+	}
+}
+
+// kafkaConn is a connection to a broker.
+type kafkaConn struct {
+	net.Conn
+	addr string
+}
+
+func (c *kafkaConn) roundTrip(deadline time.Time, apiKey, apiVersion int16, correlationID int32, clientID string, body []byte) ([]byte, error) {
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	e := kafkaEncoder{buf: make([]byte, 4, 4+32+len(body))}
+	encodeRequestHeader(&e, apiKey, apiVersion, correlationID, clientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf[:4], uint32(len(e.buf)-4))
+
+	if _, err := c.Write(e.buf); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(c, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > maxResponseSize {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != correlationID {
+		return nil, fmt.Errorf("unexpected response correlation ID %d, expected %d", id, correlationID)
+	}
+
+	res := make([]byte, size-4)
+	if _, err := io.ReadFull(c, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,328 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafka is an in-memory Kafka broker, leading all the partitions of a single topic, and supporting
+// the requests sent by the kafkaClient.
+type fakeKafka struct {
+	t        *testing.T
+	listener net.Listener
+	topic    string
+
+	mtx     sync.Mutex
+	cond    *sync.Cond
+	logs    map[int32][][]byte // Record batches by partition.
+	offsets map[int32][]int64  // Base offset of each record batch by partition.
+	ends    map[int32]int64    // Offset of the next record by partition.
+	starts  map[int32]int64    // Offset of the first record by partition.
+
+	// produceErr, if set, is returned by the produce requests.
+	produceErr kafkaError
+}
+
+func newFakeKafka(t *testing.T, topic string, partitions int32) *fakeKafka {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	k := &fakeKafka{
+		t:        t,
+		listener: listener,
+		topic:    topic,
+		logs:     map[int32][][]byte{},
+		offsets:  map[int32][]int64{},
+		ends:     map[int32]int64{},
+		starts:   map[int32]int64{},
+	}
+	k.cond = sync.NewCond(&k.mtx)
+	for p := int32(0); p < partitions; p++ {
+		k.ends[p] = 0
+		k.starts[p] = 0
+	}
+
+	go k.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+		k.mtx.Lock()
+		k.cond.Broadcast()
+		k.mtx.Unlock()
+	})
+	return k
+}
+
+func (k *fakeKafka) addr() string {
+	return k.listener.Addr().String()
+}
+
+func (k *fakeKafka) config() KafkaConfig {
+	return KafkaConfig{
+		Address:      k.addr(),
+		Topic:        k.topic,
+		ClientID:     "test",
+		DialTimeout:  time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+}
+
+func (k *fakeKafka) setProduceErr(err kafkaError) {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	k.produceErr = err
+}
+
+// deleteRecords deletes the records of the partition before the input offset, as the retention does.
+func (k *fakeKafka) deleteRecords(partition int32, before int64) {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	for len(k.offsets[partition]) > 0 && k.offsets[partition][0] < before {
+		k.offsets[partition] = k.offsets[partition][1:]
+		k.logs[partition] = k.logs[partition][1:]
+	}
+	k.starts[partition] = before
+}
+
+func (k *fakeKafka) serve() {
+	for {
+		conn, err := k.listener.Accept()
+		if err != nil {
+			return
+		}
+		go k.serveConn(conn)
+	}
+}
+
+func (k *fakeKafka) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		d := kafkaDecoder{buf: req}
+		apiKey, apiVersion, correlationID := d.int16(), d.int16(), d.int32()
+		_ = d.string() // Client ID.
+
+		e := kafkaEncoder{}
+		e.putInt32(0) // Size, set once the response is encoded.
+		e.putInt32(correlationID)
+
+		switch {
+		case apiKey == apiKeyMetadata && apiVersion == metadataVersion:
+			k.handleMetadata(&e)
+		case apiKey == apiKeyProduce && apiVersion == produceVersion:
+			k.handleProduce(&d, &e)
+		case apiKey == apiKeyFetch && apiVersion == fetchVersion:
+			k.handleFetch(&d, &e)
+		case apiKey == apiKeyListOffsets && apiVersion == listOffsetsVersion:
+			k.handleListOffsets(&d, &e)
+		default:
+			k.t.Errorf("unexpected request with API key %d and version %d", apiKey, apiVersion)
+			return
+		}
+		if err := d.err(); err != nil {
+			k.t.Errorf("failed to decode the request with API key %d: %s", apiKey, err)
+			return
+		}
+
+		binary.BigEndian.PutUint32(e.buf[:4], uint32(len(e.buf)-4))
+		if _, err := conn.Write(e.buf); err != nil {
+			return
+		}
+	}
+}
+
+func (k *fakeKafka) handleMetadata(e *kafkaEncoder) {
+	host, port, _ := net.SplitHostPort(k.addr())
+	portNum, _ := strconv.Atoi(port)
+
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	e.putArrayLen(1)
+	e.putInt32(1) // Node ID.
+	e.putString(host)
+	e.putInt32(int32(portNum))
+	e.putNullString() // Rack.
+	e.putInt32(1)     // Controller ID.
+
+	e.putArrayLen(1)
+	e.putInt16(0)
+	e.putString(k.topic)
+	e.putInt8(0) // Is internal.
+	e.putArrayLen(len(k.ends))
+	for p := int32(0); p < int32(len(k.ends)); p++ {
+		e.putInt16(0)
+		e.putInt32(p)
+		e.putInt32(1) // Leader.
+		e.putArrayLen(1)
+		e.putInt32(1) // Replicas.
+		e.putArrayLen(1)
+		e.putInt32(1) // In-sync replicas.
+	}
+}
+
+func (k *fakeKafka) handleProduce(d *kafkaDecoder, e *kafkaEncoder) {
+	_ = d.string() // Transactional ID.
+	_ = d.int16()  // Acks.
+	_ = d.int32()  // Timeout.
+	_ = d.arrayLen()
+	topic := d.string()
+	_ = d.arrayLen()
+	partition := d.int32()
+	batch := append([]byte(nil), d.bytes()...)
+
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	errCode, baseOffset := int16(0), int64(-1)
+	end, ok := k.ends[partition]
+	switch {
+	case topic != k.topic || !ok:
+		errCode = int16(errUnknownTopicOrPartition)
+	case k.produceErr != 0:
+		errCode = int16(k.produceErr)
+	default:
+		// The base offset isn't covered by the batch CRC, so it's assigned without re-computing it.
+		baseOffset = end
+		binary.BigEndian.PutUint64(batch[:8], uint64(baseOffset))
+		lastOffsetDelta := int32(binary.BigEndian.Uint32(batch[23:27]))
+
+		k.logs[partition] = append(k.logs[partition], batch)
+		k.offsets[partition] = append(k.offsets[partition], baseOffset)
+		k.ends[partition] = baseOffset + int64(lastOffsetDelta) + 1
+		k.cond.Broadcast()
+	}
+
+	e.putArrayLen(1)
+	e.putString(topic)
+	e.putArrayLen(1)
+	e.putInt32(partition)
+	e.putInt16(errCode)
+	e.putInt64(baseOffset)
+	e.putInt64(-1) // Log append time.
+	e.putInt32(0)  // Throttle time.
+}
+
+func (k *fakeKafka) handleFetch(d *kafkaDecoder, e *kafkaEncoder) {
+	_ = d.int32() // Replica ID.
+	maxWait := time.Duration(d.int32()) * time.Millisecond
+	_ = d.int32() // Min bytes.
+	_ = d.int32() // Max bytes.
+	_ = d.int8()  // Isolation level.
+	_ = d.arrayLen()
+	topic := d.string()
+	_ = d.arrayLen()
+	partition := d.int32()
+	offset := d.int64()
+	_ = d.int32() // Partition max bytes.
+
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	deadline := time.Now().Add(maxWait)
+	if maxWait > 0 {
+		// Wake up the waiting fetch once the max wait has elapsed.
+		timer := time.AfterFunc(maxWait, func() {
+			k.mtx.Lock()
+			k.cond.Broadcast()
+			k.mtx.Unlock()
+		})
+		defer timer.Stop()
+	}
+	for k.ends[partition] <= offset && time.Now().Before(deadline) {
+		k.cond.Wait()
+	}
+
+	errCode := int16(0)
+	var records []byte
+	end, ok := k.ends[partition]
+	switch {
+	case topic != k.topic || !ok:
+		errCode = int16(errUnknownTopicOrPartition)
+	case offset < k.starts[partition] || offset > end:
+		errCode = int16(errOffsetOutOfRange)
+	default:
+		for i, batch := range k.logs[partition] {
+			lastOffsetDelta := int64(int32(binary.BigEndian.Uint32(batch[23:27])))
+			if k.offsets[partition][i]+lastOffsetDelta >= offset {
+				records = append(records, batch...)
+			}
+		}
+	}
+
+	e.putInt32(0) // Throttle time.
+	e.putArrayLen(1)
+	e.putString(topic)
+	e.putArrayLen(1)
+	e.putInt32(partition)
+	e.putInt16(errCode)
+	e.putInt64(end)
+	e.putInt64(end) // Last stable offset.
+	e.putArrayLen(0)
+	e.putBytes(records)
+}
+
+func (k *fakeKafka) handleListOffsets(d *kafkaDecoder, e *kafkaEncoder) {
+	_ = d.int32() // Replica ID.
+	_ = d.arrayLen()
+	topic := d.string()
+	_ = d.arrayLen()
+	partition := d.int32()
+	timestamp := d.int64()
+
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	errCode, offset := int16(0), int64(-1)
+	if _, ok := k.ends[partition]; topic != k.topic || !ok {
+		errCode = int16(errUnknownTopicOrPartition)
+	} else if timestamp == listOffsetsEarliest {
+		offset = k.starts[partition]
+	} else if timestamp == listOffsetsLatest {
+		offset = k.ends[partition]
+	} else {
+		offset = k.offsetAt(partition, timestamp)
+	}
+
+	e.putArrayLen(1)
+	e.putString(topic)
+	e.putArrayLen(1)
+	e.putInt32(partition)
+	e.putInt16(errCode)
+	e.putInt64(-1) // Timestamp.
+	e.putInt64(offset)
+}
+
+// offsetAt returns the offset of the first record of the partition whose timestamp is greater than or equal
+// to the input timestamp, or -1 if there's none. It must be called with the lock held.
+func (k *fakeKafka) offsetAt(partition int32, timestamp int64) int64 {
+	for i, batch := range k.logs[partition] {
+		records, _, err := decodeRecordBatches(batch, k.offsets[partition][i])
+		require.NoError(k.t, err)
+
+		for _, rec := range records {
+			if rec.timestamp >= timestamp {
+				return rec.offset
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,465 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The Kafka APIs used by the ingest storage, along with the version of each API. The versions are the
+// lowest versions supporting the record batches (magic 2), and have no tagged fields, so they're
+// supported by all the Kafka-compatible brokers.
+const (
+	apiKeyProduce     int16 = 0
+	apiKeyFetch       int16 = 1
+	apiKeyListOffsets int16 = 2
+	apiKeyMetadata    int16 = 3
+
+	produceVersion     int16 = 3
+	fetchVersion       int16 = 4
+	listOffsetsVersion int16 = 1
+	metadataVersion    int16 = 1
+)
+
+// The special timestamps of the list offsets request.
+const (
+	listOffsetsLatest   int64 = -1
+	listOffsetsEarliest int64 = -2
+)
+
+var errTruncatedMessage = errors.New("truncated Kafka message")
+
+// kafkaError is an error code returned by a Kafka broker.
+type kafkaError int16
+
+const (
+	errOffsetOutOfRange          kafkaError = 1
+	errCorruptMessage            kafkaError = 2
+	errUnknownTopicOrPartition   kafkaError = 3
+	errLeaderNotAvailable        kafkaError = 5
+	errNotLeaderOrFollower       kafkaError = 6
+	errRequestTimedOut           kafkaError = 7
+	errMessageTooLarge           kafkaError = 10
+	errNetworkException          kafkaError = 13
+	errNotEnoughReplicas         kafkaError = 19
+	errNotEnoughReplicasAfterAdd kafkaError = 20
+	errKafkaStorageError         kafkaError = 56
+)
+
+var kafkaErrorNames = map[kafkaError]string{
+	errOffsetOutOfRange:          "OFFSET_OUT_OF_RANGE",
+	errCorruptMessage:            "CORRUPT_MESSAGE",
+	errUnknownTopicOrPartition:   "UNKNOWN_TOPIC_OR_PARTITION",
+	errLeaderNotAvailable:        "LEADER_NOT_AVAILABLE",
+	errNotLeaderOrFollower:       "NOT_LEADER_OR_FOLLOWER",
+	errRequestTimedOut:           "REQUEST_TIMED_OUT",
+	errMessageTooLarge:           "MESSAGE_TOO_LARGE",
+	errNetworkException:          "NETWORK_EXCEPTION",
+	errNotEnoughReplicas:         "NOT_ENOUGH_REPLICAS",
+	errNotEnoughReplicasAfterAdd: "NOT_ENOUGH_REPLICAS_AFTER_APPEND",
+	errKafkaStorageError:         "KAFKA_STORAGE_ERROR",
+}
+
+func (e kafkaError) Error() string {
+	if name, ok := kafkaErrorNames[e]; ok {
+		return fmt.Sprintf("Kafka error %s (%d)", name, int16(e))
+	}
+	return fmt.Sprintf("Kafka error %d", int16(e))
+}
+
+// staleMetadata returns whether the error is caused by the partition leader having changed, in which case
+// the request can be retried once the topic metadata has been refreshed.
+func (e kafkaError) staleMetadata() bool {
+	switch e {
+	case errUnknownTopicOrPartition, errLeaderNotAvailable, errNotLeaderOrFollower, errNetworkException, errKafkaStorageError:
+		return true
+	default:
+		return false
+	}
+}
+
+func kafkaErrorFromCode(code int16) error {
+	if code == 0 {
+		return nil
+	}
+	return kafkaError(code)
+}
+
+// kafkaEncoder encodes the primitive types of the Kafka protocol. Integers are big-endian.
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) putInt8(v int8) {
+	e.buf = append(e.buf, byte(v))
+}
+
+func (e *kafkaEncoder) putInt16(v int16) {
+	e.buf = append(e.buf, 0, 0)
+	binary.BigEndian.PutUint16(e.buf[len(e.buf)-2:], uint16(v))
+}
+
+func (e *kafkaEncoder) putInt32(v int32) {
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(e.buf[len(e.buf)-4:], uint32(v))
+}
+
+func (e *kafkaEncoder) putInt64(v int64) {
+	e.buf = append(e.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(e.buf[len(e.buf)-8:], uint64(v))
+}
+
+func (e *kafkaEncoder) putString(s string) {
+	e.putInt16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *kafkaEncoder) putNullString() {
+	e.putInt16(-1)
+}
+
+func (e *kafkaEncoder) putBytes(b []byte) {
+	if b == nil {
+		e.putInt32(-1)
+		return
+	}
+	e.putInt32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *kafkaEncoder) putArrayLen(n int) {
+	e.putInt32(int32(n))
+}
+
+func (e *kafkaEncoder) putVarint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	e.buf = append(e.buf, tmp[:n]...)
+}
+
+func (e *kafkaEncoder) putVarintBytes(b []byte) {
+	if b == nil {
+		e.putVarint(-1)
+		return
+	}
+	e.putVarint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// kafkaDecoder decodes the primitive types of the Kafka protocol. Once an error occurs, all the subsequent
+// reads return zero values, and the error is returned by err().
+type kafkaDecoder struct {
+	buf []byte
+	e   error
+}
+
+func (d *kafkaDecoder) err() error {
+	return d.e
+}
+
+func (d *kafkaDecoder) remaining() int {
+	return len(d.buf)
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.e != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.e = errTruncatedMessage
+		return nil
+	}
+
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// string decodes a string, returning an empty string if null.
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+// bytes decodes a byte slice, returning nil if null. The returned slice references the decoded buffer.
+func (d *kafkaDecoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// arrayLen decodes the length of an array, returning 0 if null.
+func (d *kafkaDecoder) arrayLen() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	// Each array item is at least 1 byte long.
+	if int(n) > len(d.buf) {
+		d.e = errTruncatedMessage
+		return 0
+	}
+	return int(n)
+}
+
+func (d *kafkaDecoder) varint() int64 {
+	if d.e != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.e = errTruncatedMessage
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *kafkaDecoder) varintBytes() []byte {
+	n := d.varint()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// encodeRequestHeader encodes the header of a request, version 1.
+func encodeRequestHeader(e *kafkaEncoder, apiKey, apiVersion int16, correlationID int32, clientID string) {
+	e.putInt16(apiKey)
+	e.putInt16(apiVersion)
+	e.putInt32(correlationID)
+	e.putString(clientID)
+}
+
+// metadataResponse is the subset of the metadata response used by the client.
+type metadataResponse struct {
+	brokers []metadataBroker
+	topics  []metadataTopic
+}
+
+type metadataBroker struct {
+	nodeID int32
+	host   string
+	port   int32
+}
+
+type metadataTopic struct {
+	errorCode  int16
+	name       string
+	partitions []metadataPartition
+}
+
+type metadataPartition struct {
+	errorCode int16
+	id        int32
+	leader    int32
+}
+
+func encodeMetadataRequest(e *kafkaEncoder, topic string) {
+	e.putArrayLen(1)
+	e.putString(topic)
+}
+
+func decodeMetadataResponse(b []byte) (metadataResponse, error) {
+	d := kafkaDecoder{buf: b}
+	res := metadataResponse{}
+
+	res.brokers = make([]metadataBroker, d.arrayLen())
+	for i := range res.brokers {
+		res.brokers[i].nodeID = d.int32()
+		res.brokers[i].host = d.string()
+		res.brokers[i].port = d.int32()
+		_ = d.string() // Rack.
+	}
+	_ = d.int32() // Controller ID.
+
+	res.topics = make([]metadataTopic, d.arrayLen())
+	for i := range res.topics {
+		topic := &res.topics[i]
+		topic.errorCode = d.int16()
+		topic.name = d.string()
+		_ = d.int8() // Is internal.
+
+		topic.partitions = make([]metadataPartition, d.arrayLen())
+		for j := range topic.partitions {
+			partition := &topic.partitions[j]
+			partition.errorCode = d.int16()
+			partition.id = d.int32()
+			partition.leader = d.int32()
+			for k, n := 0, d.arrayLen(); k < n; k++ {
+				_ = d.int32() // Replica nodes.
+			}
+			for k, n := 0, d.arrayLen(); k < n; k++ {
+				_ = d.int32() // In-sync replica nodes.
+			}
+		}
+	}
+
+	return res, d.err()
+}
+
+// produceResponse is the response of a produce request of a single partition.
+type produceResponse struct {
+	errorCode  int16
+	baseOffset int64
+}
+
+func encodeProduceRequest(e *kafkaEncoder, topic string, partition int32, timeout int32, records []byte) {
+	e.putNullString() // Transactional ID.
+	e.putInt16(-1)    // Acks from all the in-sync replicas.
+	e.putInt32(timeout)
+	e.putArrayLen(1)
+	e.putString(topic)
+	e.putArrayLen(1)
+	e.putInt32(partition)
+	e.putBytes(records)
+}
+
+func decodeProduceResponse(b []byte, topic string, partition int32) (produceResponse, error) {
+	d := kafkaDecoder{buf: b}
+
+	for i, topics := 0, d.arrayLen(); i < topics; i++ {
+		name := d.string()
+		for j, partitions := 0, d.arrayLen(); j < partitions; j++ {
+			id := d.int32()
+			res := produceResponse{errorCode: d.int16(), baseOffset: d.int64()}
+			_ = d.int64() // Log append time.
+
+			if d.err() == nil && name == topic && id == partition {
+				return res, nil
+			}
+		}
+	}
+
+	if err := d.err(); err != nil {
+		return produceResponse{}, err
+	}
+	return produceResponse{}, fmt.Errorf("the produce response doesn't include the partition %d", partition)
+}
+
+// fetchResponse is the response of a fetch request of a single partition.
+type fetchResponse struct {
+	errorCode     int16
+	highWatermark int64
+	records       []byte
+}
+
+func encodeFetchRequest(e *kafkaEncoder, topic string, partition int32, offset int64, maxWait, maxBytes int32) {
+	e.putInt32(-1) // Replica ID, -1 for consumers.
+	e.putInt32(maxWait)
+	e.putInt32(1) // Min bytes.
+	e.putInt32(maxBytes)
+	e.putInt8(1) // Isolation level: read committed.
+	e.putArrayLen(1)
+	e.putString(topic)
+	e.putArrayLen(1)
+	e.putInt32(partition)
+	e.putInt64(offset)
+	e.putInt32(maxBytes)
+}
+
+func decodeFetchResponse(b []byte, topic string, partition int32) (fetchResponse, error) {
+	d := kafkaDecoder{buf: b}
+	_ = d.int32() // Throttle time.
+
+	for i, topics := 0, d.arrayLen(); i < topics; i++ {
+		name := d.string()
+		for j, partitions := 0, d.arrayLen(); j < partitions; j++ {
+			id := d.int32()
+			res := fetchResponse{errorCode: d.int16(), highWatermark: d.int64()}
+			_ = d.int64() // Last stable offset.
+			for k, n := 0, d.arrayLen(); k < n; k++ {
+				_ = d.int64() // Aborted transaction producer ID.
+				_ = d.int64() // Aborted transaction first offset.
+			}
+			res.records = d.bytes()
+
+			if d.err() == nil && name == topic && id == partition {
+				return res, nil
+			}
+		}
+	}
+
+	if err := d.err(); err != nil {
+		return fetchResponse{}, err
+	}
+	return fetchResponse{}, fmt.Errorf("the fetch response doesn't include the partition %d", partition)
+}
+
+// listOffsetsResponse is the response of a list offsets request of a single partition.
+type listOffsetsResponse struct {
+	errorCode int16
+	offset    int64
+}
+
+func encodeListOffsetsRequest(e *kafkaEncoder, topic string, partition int32, timestamp int64) {
+	e.putInt32(-1) // Replica ID, -1 for consumers.
+	e.putArrayLen(1)
+	e.putString(topic)
+	e.putArrayLen(1)
+	e.putInt32(partition)
+	e.putInt64(timestamp)
+}
+
+func decodeListOffsetsResponse(b []byte, topic string, partition int32) (listOffsetsResponse, error) {
+	d := kafkaDecoder{buf: b}
+
+	for i, topics := 0, d.arrayLen(); i < topics; i++ {
+		name := d.string()
+		for j, partitions := 0, d.arrayLen(); j < partitions; j++ {
+			id := d.int32()
+			res := listOffsetsResponse{errorCode: d.int16()}
+			_ = d.int64() // Timestamp.
+			res.offset = d.int64()
+
+			if d.err() == nil && name == topic && id == partition {
+				return res, nil
+			}
+		}
+	}
+
+	if err := d.err(); err != nil {
+		return listOffsetsResponse{}, err
+	}
+	return listOffsetsResponse{}, fmt.Errorf("the list offsets response doesn't include the partition %d", partition)
+}
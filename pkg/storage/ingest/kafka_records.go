@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	recordBatchMagic = 2
+
+	// recordBatchHeaderLen is the length of the record batch header, from the base offset to the records count.
+	recordBatchHeaderLen = 61
+
+	// recordBatchCRCOffset is the offset of the CRC in the record batch header. The CRC covers the bytes
+	// following it, from the attributes to the end of the batch.
+	recordBatchCRCOffset = 17
+
+	recordBatchCompressionMask = 0x07
+	recordBatchControlFlag     = 0x20
+)
+
+var (
+	castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errRecordBatchChecksum = errors.New("record batch checksum mismatch")
+)
+
+// record is a Kafka record. The ingest storage records have the tenant ID as key, and the write request as value.
+type record struct {
+	offset    int64
+	timestamp int64
+	key       []byte
+	value     []byte
+}
+
+// encodeRecordBatch encodes the input records as an uncompressed record batch (magic 2). The offsets
+// of the records are assigned by the broker.
+func encodeRecordBatch(records []record) []byte {
+	e := kafkaEncoder{}
+	e.putInt64(0)  // Base offset, assigned by the broker.
+	e.putInt32(0)  // Batch length, set once the batch is encoded.
+	e.putInt32(-1) // Partition leader epoch.
+	e.putInt8(recordBatchMagic)
+	e.putInt32(0) // CRC, set once the batch is encoded.
+	e.putInt16(0) // Attributes: no compression, create time.
+	e.putInt32(int32(len(records) - 1))
+
+	firstTimestamp, maxTimestamp := int64(0), int64(0)
+	if len(records) > 0 {
+		firstTimestamp = records[0].timestamp
+	}
+	for _, r := range records {
+		if r.timestamp > maxTimestamp {
+			maxTimestamp = r.timestamp
+		}
+	}
+	e.putInt64(firstTimestamp)
+	e.putInt64(maxTimestamp)
+
+	e.putInt64(-1) // Producer ID.
+	e.putInt16(-1) // Producer epoch.
+	e.putInt32(-1) // Base sequence.
+	e.putArrayLen(len(records))
+
+	rec := kafkaEncoder{}
+	for i, r := range records {
+		rec.buf = rec.buf[:0]
+		rec.putInt8(0) // Attributes.
+		rec.putVarint(r.timestamp - firstTimestamp)
+		rec.putVarint(int64(i))
+		rec.putVarintBytes(r.key)
+		rec.putVarintBytes(r.value)
+		rec.putVarint(0) // Headers.
+
+		e.putVarint(int64(len(rec.buf)))
+		e.buf = append(e.buf, rec.buf...)
+	}
+
+	binary.BigEndian.PutUint32(e.buf[8:12], uint32(len(e.buf)-12))
+	binary.BigEndian.PutUint32(e.buf[recordBatchCRCOffset:recordBatchCRCOffset+4], crc32.Checksum(e.buf[recordBatchCRCOffset+4:], castagnoliTable))
+	return e.buf
+}
+
+// decodeRecordBatches decodes the records of the input record batches, skipping the records whose offset
+// is lower than minOffset and the control batches. A partial batch at the end of the input, which the
+// brokers return when the fetched batches exceed the fetch max bytes, is ignored. It returns the records,
+// which reference the input buffer, and the offset following the last decoded batch, or minOffset if none.
+func decodeRecordBatches(b []byte, minOffset int64) ([]record, int64, error) {
+	var records []record
+	nextOffset := minOffset
+
+	for len(b) >= 12 {
+		batchLen := int(int32(binary.BigEndian.Uint32(b[8:12])))
+		if batchLen < recordBatchHeaderLen-12 {
+			return nil, 0, errTruncatedMessage
+		}
+		if 12+batchLen > len(b) {
+			break
+		}
+		batch := b[:12+batchLen]
+		b = b[12+batchLen:]
+
+		if magic := int8(batch[16]); magic != recordBatchMagic {
+			return nil, 0, fmt.Errorf("unsupported record batch magic %d", magic)
+		}
+		if crc32.Checksum(batch[recordBatchCRCOffset+4:], castagnoliTable) != binary.BigEndian.Uint32(batch[recordBatchCRCOffset:recordBatchCRCOffset+4]) {
+			return nil, 0, errRecordBatchChecksum
+		}
+
+		d := kafkaDecoder{buf: batch}
+		baseOffset := d.int64()
+		_ = d.int32() // Batch length.
+		_ = d.int32() // Partition leader epoch.
+		_ = d.int8()  // Magic.
+		_ = d.int32() // CRC.
+		attributes := d.int16()
+		lastOffsetDelta := d.int32()
+		firstTimestamp := d.int64()
+		_ = d.int64() // Max timestamp.
+		_ = d.int64() // Producer ID.
+		_ = d.int16() // Producer epoch.
+		_ = d.int32() // Base sequence.
+		count := d.int32()
+
+		if end := baseOffset + int64(lastOffsetDelta) + 1; end > nextOffset {
+			nextOffset = end
+		}
+		if attributes&recordBatchControlFlag != 0 {
+			continue
+		}
+		if codec := attributes & recordBatchCompressionMask; codec != 0 {
+			return nil, 0, fmt.Errorf("unsupported record batch compression codec %d", codec)
+		}
+
+		for i := int32(0); i < count; i++ {
+			recordLen := d.varint()
+			rd := kafkaDecoder{buf: d.next(int(recordLen))}
+			if err := d.err(); err != nil {
+				return nil, 0, err
+			}
+
+			_ = rd.int8() // Attributes.
+			r := record{timestamp: firstTimestamp + rd.varint()}
+			r.offset = baseOffset + rd.varint()
+			r.key = rd.varintBytes()
+			r.value = rd.varintBytes()
+			for h, headers := int64(0), rd.varint(); h < headers; h++ {
+				_ = rd.varintBytes() // Header key.
+				_ = rd.varintBytes() // Header value.
+			}
+			if err := rd.err(); err != nil {
+				return nil, 0, err
+			}
+
+			if r.offset >= minOffset {
+				records = append(records, r)
+			}
+		}
+	}
+
+	return records, nextOffset, nil
+}
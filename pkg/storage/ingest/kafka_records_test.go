@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordBatch_EncodeDecode(t *testing.T) {
+	input := []record{
+		{timestamp: 1000, key: []byte("user-1"), value: []byte("first")},
+		{timestamp: 1010, key: []byte("user-2"), value: []byte("second")},
+		{timestamp: 1005, key: nil, value: []byte{}},
+	}
+
+	first := encodeRecordBatch(input)
+	second := encodeRecordBatch(input[:1])
+	// The base offset is assigned by the broker, and isn't covered by the checksum.
+	binary.BigEndian.PutUint64(first[:8], 10)
+	binary.BigEndian.PutUint64(second[:8], 13)
+	batches := append(append([]byte(nil), first...), second...)
+
+	t.Run("all the records", func(t *testing.T) {
+		records, nextOffset, err := decodeRecordBatches(batches, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(14), nextOffset)
+		require.Len(t, records, 4)
+
+		for i, expected := range append(append([]record(nil), input...), input[0]) {
+			assert.Equal(t, int64(10+i), records[i].offset)
+			assert.Equal(t, expected.timestamp, records[i].timestamp)
+			assert.Equal(t, expected.key, records[i].key)
+			assert.Equal(t, string(expected.value), string(records[i].value))
+		}
+	})
+
+	t.Run("records before the min offset are skipped", func(t *testing.T) {
+		records, nextOffset, err := decodeRecordBatches(batches, 12)
+		require.NoError(t, err)
+		assert.Equal(t, int64(14), nextOffset)
+		require.Len(t, records, 2)
+		assert.Equal(t, int64(12), records[0].offset)
+		assert.Equal(t, int64(13), records[1].offset)
+	})
+
+	t.Run("a partial batch at the end is ignored", func(t *testing.T) {
+		records, nextOffset, err := decodeRecordBatches(batches[:len(batches)-1], 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(13), nextOffset)
+		require.Len(t, records, 3)
+	})
+
+	t.Run("control batches are skipped", func(t *testing.T) {
+		control := append([]byte(nil), second...)
+		binary.BigEndian.PutUint16(control[21:23], recordBatchControlFlag)
+		binary.BigEndian.PutUint32(control[recordBatchCRCOffset:], crc32.Checksum(control[recordBatchCRCOffset+4:], castagnoliTable))
+
+		records, nextOffset, err := decodeRecordBatches(append(append([]byte(nil), first...), control...), 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(14), nextOffset)
+		require.Len(t, records, 3)
+	})
+
+	t.Run("corrupted batch", func(t *testing.T) {
+		corrupted := append([]byte(nil), batches...)
+		corrupted[len(first)-1] ^= 0xff
+
+		_, _, err := decodeRecordBatches(corrupted, 10)
+		require.ErrorIs(t, err, errRecordBatchChecksum)
+	})
+
+	t.Run("no batches", func(t *testing.T) {
+		records, nextOffset, err := decodeRecordBatches(nil, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), nextOffset)
+		assert.Empty(t, records)
+	})
+}
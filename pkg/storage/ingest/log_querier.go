@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// LogQuerier reads the series of a tenant recently written to the ingest storage directly from the partitions,
+// without going through the ingesters. It's meant to be used when the ingesters are unavailable: it's much
+// slower than querying the ingesters, given all the records written to the partitions since the start of the
+// queried time range are fetched and decoded.
+type LogQuerier struct {
+	client *kafkaClient
+	logger log.Logger
+
+	readRecords prometheus.Counter
+	readBytes   prometheus.Counter
+}
+
+// NewLogQuerier returns a LogQuerier reading the partitions of the topic.
+func NewLogQuerier(cfg KafkaConfig, logger log.Logger, reg prometheus.Registerer) *LogQuerier {
+	return &LogQuerier{
+		client: newKafkaClient(cfg, logger),
+		logger: logger,
+		readRecords: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_log_querier_records_total",
+			Help: "Total number of records read from the partitions to query the series of the ingest storage.",
+		}),
+		readBytes: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_log_querier_read_bytes_total",
+			Help: "Total number of bytes of the records read from the partitions to query the series of the ingest storage.",
+		}),
+	}
+}
+
+// QuerySeries returns the series of the tenant matching the matchers, with their samples and histograms in the
+// [from, to] time range, reading the records written to the partitions since writtenSince, up to the end of the
+// partitions. The series are sorted by labels, and their samples and histograms by timestamp. The exemplars and
+// the series computed by the ingest aggregation rules, which are not written to the partitions, are not returned.
+func (q *LogQuerier) QuerySeries(ctx context.Context, userID string, writtenSince, from, to int64, matchers []*labels.Matcher) ([]mimirpb.TimeSeries, error) {
+	partitionIDs, err := q.client.partitionIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mtx    sync.Mutex
+		series = map[string]*mimirpb.TimeSeries{}
+	)
+
+	err = concurrency.ForEachJob(ctx, len(partitionIDs), len(partitionIDs), func(ctx context.Context, idx int) error {
+		return q.readPartition(ctx, partitionIDs[idx], userID, writtenSince, func(req *mimirpb.WriteRequest) {
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			for _, ts := range req.Timeseries {
+				addMatchingSeries(series, ts.TimeSeries, from, to, matchers)
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]mimirpb.TimeSeries, 0, len(series))
+	for _, ts := range series {
+		sortAndDedupeSamples(ts)
+		result = append(result, *ts)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return labels.Compare(mimirpb.FromLabelAdaptersToLabels(result[i].Labels), mimirpb.FromLabelAdaptersToLabels(result[j].Labels)) < 0
+	})
+	return result, nil
+}
+
+// readPartition calls fn for each write request of the tenant written to the partition since writtenSince, up to
+// the end of the partition at the time it's called. The write request isn't retained once fn returns.
+func (q *LogQuerier) readPartition(ctx context.Context, partitionID int32, userID string, writtenSince int64, fn func(req *mimirpb.WriteRequest)) error {
+	// The offset of the first record written since writtenSince is -1 if there's none.
+	offset, err := q.client.listOffset(ctx, partitionID, writtenSince)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch the offset of partition %d at %d", partitionID, writtenSince)
+	}
+	if offset < 0 {
+		return nil
+	}
+
+	end, err := q.client.listOffset(ctx, partitionID, listOffsetsLatest)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch the end offset of partition %d", partitionID)
+	}
+
+	for offset < end {
+		records, nextOffset, err := q.client.fetch(ctx, partitionID, offset, 0, readerFetchMaxBytes)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch the records of partition %d", partitionID)
+		}
+
+		for _, rec := range records {
+			if rec.offset >= end {
+				break
+			}
+
+			q.readRecords.Inc()
+			q.readBytes.Add(float64(len(rec.value)))
+			if string(rec.key) != userID {
+				continue
+			}
+
+			req := mimirpb.WriteRequest{}
+			if err := req.Unmarshal(rec.value); err != nil {
+				return errors.Wrapf(err, "failed to decode the record at offset %d of partition %d", rec.offset, partitionID)
+			}
+			fn(&req)
+		}
+
+		if nextOffset <= offset {
+			break
+		}
+		offset = nextOffset
+	}
+	return nil
+}
+
+// addMatchingSeries adds the samples and histograms of the series in the [from, to] time range to the series
+// with the same labels, if the series matches the matchers.
+func addMatchingSeries(series map[string]*mimirpb.TimeSeries, ts *mimirpb.TimeSeries, from, to int64, matchers []*labels.Matcher) {
+	lbls := mimirpb.FromLabelAdaptersToLabels(ts.Labels)
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return
+		}
+	}
+
+	var (
+		key = lbls.String()
+		out = series[key]
+	)
+	for _, s := range ts.Samples {
+		if s.TimestampMs < from || s.TimestampMs > to {
+			continue
+		}
+		if out == nil {
+			out = newLogQuerierSeries(lbls, series, key)
+		}
+		out.Samples = append(out.Samples, s)
+	}
+	for _, h := range ts.Histograms {
+		if h.Timestamp < from || h.Timestamp > to {
+			continue
+		}
+		if out == nil {
+			out = newLogQuerierSeries(lbls, series, key)
+		}
+		out.Histograms = append(out.Histograms, h)
+	}
+}
+
+// newLogQuerierSeries adds a series with the input labels to series. The labels are copied, because they
+// reference the buffer of the fetched records.
+func newLogQuerierSeries(lbls labels.Labels, series map[string]*mimirpb.TimeSeries, key string) *mimirpb.TimeSeries {
+	ts := &mimirpb.TimeSeries{Labels: mimirpb.FromLabelsToLabelAdapters(mimirpb.CopyLabels(lbls))}
+	series[key] = ts
+	return ts
+}
+
+// sortAndDedupeSamples sorts the samples and histograms of the series by timestamp. If the same timestamp has
+// been read more than once, like when a write request is retried, the last one read is kept.
+func sortAndDedupeSamples(ts *mimirpb.TimeSeries) {
+	sort.SliceStable(ts.Samples, func(i, j int) bool { return ts.Samples[i].TimestampMs < ts.Samples[j].TimestampMs })
+	samples := ts.Samples[:0]
+	for i, s := range ts.Samples {
+		if i+1 < len(ts.Samples) && ts.Samples[i+1].TimestampMs == s.TimestampMs {
+			continue
+		}
+		samples = append(samples, s)
+	}
+	ts.Samples = samples
+
+	sort.SliceStable(ts.Histograms, func(i, j int) bool { return ts.Histograms[i].Timestamp < ts.Histograms[j].Timestamp })
+	histograms := ts.Histograms[:0]
+	for i, h := range ts.Histograms {
+		if i+1 < len(ts.Histograms) && ts.Histograms[i+1].Timestamp == h.Timestamp {
+			continue
+		}
+		histograms = append(histograms, h)
+	}
+	ts.Histograms = histograms
+}
+
+// Close closes the connections to the brokers.
+func (q *LogQuerier) Close() {
+	q.client.close()
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestLogQuerier_QuerySeries(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 2)
+
+	client := newKafkaClient(kafka.config(), log.NewNopLogger())
+	t.Cleanup(client.close)
+
+	produce := func(partition int32, writtenAt int64, userID string, series labels.Labels, samples ...mimirpb.Sample) {
+		req := &mimirpb.WriteRequest{Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  mimirpb.FromLabelsToLabelAdapters(series),
+			Samples: samples,
+		}}}}
+		value, err := req.Marshal()
+		require.NoError(t, err)
+
+		_, err = client.produce(ctx, partition, []record{{timestamp: writtenAt, key: []byte(userID), value: value}})
+		require.NoError(t, err)
+	}
+
+	series1 := labels.FromStrings(labels.MetricName, "series_1")
+	series2 := labels.FromStrings(labels.MetricName, "series_2")
+	other := labels.FromStrings(labels.MetricName, "other")
+
+	produce(0, 1000, "user-1", series1, mimirpb.Sample{TimestampMs: 500, Value: 1}, mimirpb.Sample{TimestampMs: 1500, Value: 2})
+	produce(0, 1000, "user-2", series1, mimirpb.Sample{TimestampMs: 1500, Value: 100})
+	produce(1, 2000, "user-1", series2, mimirpb.Sample{TimestampMs: 2000, Value: 3})
+	produce(1, 2000, "user-1", series1, mimirpb.Sample{TimestampMs: 2500, Value: 4})
+	produce(1, 2000, "user-1", other, mimirpb.Sample{TimestampMs: 2000, Value: 5})
+	// The sample written again, like when a write request is retried, is returned once.
+	produce(0, 3000, "user-1", series1, mimirpb.Sample{TimestampMs: 1500, Value: 2}, mimirpb.Sample{TimestampMs: 5000, Value: 6})
+
+	q := NewLogQuerier(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(q.Close)
+	matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "series_.+")}
+
+	t.Run("all the records written since the start of the partitions", func(t *testing.T) {
+		res, err := q.QuerySeries(ctx, "user-1", 0, 1000, 4000, matchers)
+		require.NoError(t, err)
+		assert.Equal(t, []mimirpb.TimeSeries{
+			{Labels: mimirpb.FromLabelsToLabelAdapters(series1), Samples: []mimirpb.Sample{{TimestampMs: 1500, Value: 2}, {TimestampMs: 2500, Value: 4}}},
+			{Labels: mimirpb.FromLabelsToLabelAdapters(series2), Samples: []mimirpb.Sample{{TimestampMs: 2000, Value: 3}}},
+		}, res)
+	})
+
+	t.Run("the records written since a timestamp", func(t *testing.T) {
+		res, err := q.QuerySeries(ctx, "user-1", 1500, 1000, 4000, matchers)
+		require.NoError(t, err)
+		assert.Equal(t, []mimirpb.TimeSeries{
+			{Labels: mimirpb.FromLabelsToLabelAdapters(series1), Samples: []mimirpb.Sample{{TimestampMs: 1500, Value: 2}, {TimestampMs: 2500, Value: 4}}},
+			{Labels: mimirpb.FromLabelsToLabelAdapters(series2), Samples: []mimirpb.Sample{{TimestampMs: 2000, Value: 3}}},
+		}, res)
+
+		res, err = q.QuerySeries(ctx, "user-1", 2500, 1000, 4000, matchers)
+		require.NoError(t, err)
+		assert.Equal(t, []mimirpb.TimeSeries{
+			{Labels: mimirpb.FromLabelsToLabelAdapters(series1), Samples: []mimirpb.Sample{{TimestampMs: 1500, Value: 2}}},
+		}, res)
+	})
+
+	t.Run("no records written since a timestamp", func(t *testing.T) {
+		res, err := q.QuerySeries(ctx, "user-1", 4000, 1000, 4000, matchers)
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	})
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// partitionRingTokensPerPartition is the number of tokens owned by each partition in the partition ring.
+const partitionRingTokensPerPartition = 128
+
+// PartitionRing is a consistent hashing ring of the partitions of the ingest storage, mapping the series
+// tokens to the partitions. The tokens of each partition are generated from the partition ID, so all the
+// distributors build the same ring from the same partitions, and adding a partition only moves the
+// series from the other partitions to the new one.
+type PartitionRing struct {
+	partitionIDs []int32
+	tokens       []uint32
+	owners       []int32 // Partition owning each token.
+}
+
+// NewPartitionRing returns the ring of the input partitions.
+func NewPartitionRing(partitionIDs []int32) *PartitionRing {
+	ids := append([]int32(nil), partitionIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	type tokenOwner struct {
+		token uint32
+		owner int32
+	}
+	owned := make([]tokenOwner, 0, len(ids)*partitionRingTokensPerPartition)
+	seen := make(map[uint32]struct{}, len(ids)*partitionRingTokensPerPartition)
+
+	for _, id := range ids {
+		r := rand.New(rand.NewSource(int64(id)))
+		for n := 0; n < partitionRingTokensPerPartition; {
+			token := r.Uint32()
+			if _, ok := seen[token]; ok {
+				continue
+			}
+			seen[token] = struct{}{}
+			owned = append(owned, tokenOwner{token: token, owner: id})
+			n++
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].token < owned[j].token })
+
+	r := &PartitionRing{
+		partitionIDs: ids,
+		tokens:       make([]uint32, len(owned)),
+		owners:       make([]int32, len(owned)),
+	}
+	for i, o := range owned {
+		r.tokens[i] = o.token
+		r.owners[i] = o.owner
+	}
+	return r
+}
+
+// PartitionIDs returns the sorted IDs of the partitions in the ring.
+func (r *PartitionRing) PartitionIDs() []int32 {
+	return r.partitionIDs
+}
+
+// PartitionForToken returns the partition owning the input token, which is the partition owning the
+// first ring token greater than or equal to it. It returns false if the ring has no partitions.
+func (r *PartitionRing) PartitionForToken(token uint32) (int32, bool) {
+	if len(r.tokens) == 0 {
+		return 0, false
+	}
+
+	i := sort.Search(len(r.tokens), func(i int) bool { return r.tokens[i] >= token })
+	if i == len(r.tokens) {
+		i = 0
+	}
+	return r.owners[i], true
+}
+
+// IngesterPartitionID returns the ID of the partition consumed by the ingester, which is the sequence
+// number at the end of its instance ID, for example 3 for ingester-zone-a-3. The ingesters of each zone
+// with the same sequence number consume the same partition.
+func IngesterPartitionID(ingesterID string) (int32, error) {
+	idx := strings.LastIndex(ingesterID, "-")
+	if idx < 0 {
+		return 0, fmt.Errorf("the ingester ID %s doesn't end with a sequence number, like ingester-zone-a-3", ingesterID)
+	}
+
+	id, err := strconv.ParseInt(ingesterID[idx+1:], 10, 32)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("the ingester ID %s doesn't end with a sequence number, like ingester-zone-a-3", ingesterID)
+	}
+	return int32(id), nil
+}
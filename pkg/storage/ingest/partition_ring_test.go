@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionRing(t *testing.T) {
+	t.Run("no partitions", func(t *testing.T) {
+		_, ok := NewPartitionRing(nil).PartitionForToken(1)
+		assert.False(t, ok)
+	})
+
+	t.Run("the ring doesn't depend on the partitions order", func(t *testing.T) {
+		a := NewPartitionRing([]int32{0, 1, 2})
+		b := NewPartitionRing([]int32{2, 0, 1})
+		assert.Equal(t, []int32{0, 1, 2}, b.PartitionIDs())
+
+		for i := 0; i < 1000; i++ {
+			token := rand.Uint32()
+			expected, _ := a.PartitionForToken(token)
+			actual, _ := b.PartitionForToken(token)
+			require.Equal(t, expected, actual)
+		}
+	})
+
+	t.Run("adding a partition only moves tokens to the new partition", func(t *testing.T) {
+		before := NewPartitionRing([]int32{0, 1, 2})
+		after := NewPartitionRing([]int32{0, 1, 2, 3})
+
+		owned := map[int32]int{}
+		for i := 0; i < 10000; i++ {
+			token := rand.Uint32()
+			prev, _ := before.PartitionForToken(token)
+			curr, _ := after.PartitionForToken(token)
+			if curr != 3 {
+				require.Equal(t, prev, curr)
+			}
+			owned[curr]++
+		}
+
+		// Each partition owns a fair share of the tokens.
+		for id := int32(0); id < 4; id++ {
+			assert.InDelta(t, 2500, owned[id], 1000, "partition %d", id)
+		}
+	})
+}
+
+func TestIngesterPartitionID(t *testing.T) {
+	tests := map[string]struct {
+		ingesterID  string
+		expectedID  int32
+		expectedErr bool
+	}{
+		"with zone":          {ingesterID: "ingester-zone-a-3", expectedID: 3},
+		"without zone":       {ingesterID: "ingester-12", expectedID: 12},
+		"no sequence number": {ingesterID: "ingester", expectedErr: true},
+		"not a number":       {ingesterID: "ingester-zone-a", expectedErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			id, err := IngesterPartitionID(tc.ingesterID)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
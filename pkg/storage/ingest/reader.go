@@ -0,0 +1,334 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// OffsetFilename is the name of the file storing the offset of the next record to consume from the partition.
+	OffsetFilename = "ingest_storage_offset.json"
+
+	// readerFetchMaxWait is the maximum time a fetch request waits for new records.
+	readerFetchMaxWait = time.Second
+
+	// readerFetchMaxBytes is the maximum size of the records returned by a fetch request. A record batch larger
+	// than it is still returned, if it's the first one.
+	readerFetchMaxBytes = 16 << 20
+
+	// readerCommitInterval is the interval at which the offset of the consumed records is persisted.
+	readerCommitInterval = time.Second
+)
+
+var readerBackoffConfig = backoff.Config{
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 10 * time.Second,
+}
+
+// RecordConsumer consumes the write requests read from a partition.
+type RecordConsumer interface {
+	// Consume ingests the write request of the tenant. The write request isn't retained once Consume
+	// returns. If a client error is returned, which is an httpgrpc error with a 4xx status code, the write
+	// request is skipped. Otherwise, the write request is consumed again, until it succeeds.
+	Consume(ctx context.Context, userID string, req *mimirpb.WriteRequest) error
+}
+
+// PartitionReader is a service consuming the records of a partition. When starting, it replays the records
+// written to the partition since the last consumed offset, which is persisted to disk, up to the end of the
+// partition, and is running once the replay has completed. Once running, it consumes the new records.
+type PartitionReader struct {
+	services.Service
+
+	client      *kafkaClient
+	partitionID int32
+	offsetFile  string
+	consumer    RecordConsumer
+	logger      log.Logger
+
+	// Only accessed by the service goroutines.
+	nextOffset      int64
+	committedOffset int64
+	committed       bool
+	lastCommit      time.Time
+
+	consumedRecords    prometheus.Counter
+	failedRecords      prometheus.Counter
+	consumeFailures    prometheus.Counter
+	fetchFailures      prometheus.Counter
+	lastConsumedOffset prometheus.Gauge
+}
+
+// NewPartitionReader returns a PartitionReader consuming the partition with the input consumer. The offset
+// of the consumed records is persisted to OffsetFilename in dataDir.
+func NewPartitionReader(cfg KafkaConfig, partitionID int32, dataDir string, consumer RecordConsumer, logger log.Logger, reg prometheus.Registerer) *PartitionReader {
+	logger = log.With(logger, "partition", partitionID)
+
+	r := &PartitionReader{
+		client:      newKafkaClient(cfg, logger),
+		partitionID: partitionID,
+		offsetFile:  filepath.Join(dataDir, OffsetFilename),
+		consumer:    consumer,
+		logger:      logger,
+		consumedRecords: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_reader_records_total",
+			Help: "Total number of records consumed from the partition.",
+		}),
+		failedRecords: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_reader_records_failed_total",
+			Help: "Total number of records consumed from the partition which failed to be ingested, and have been skipped.",
+		}),
+		consumeFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_reader_consume_failures_total",
+			Help: "Total number of failed attempts to ingest the records consumed from the partition, which are retried.",
+		}),
+		fetchFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_reader_fetch_failures_total",
+			Help: "Total number of failed fetch requests sent to the Kafka brokers.",
+		}),
+		lastConsumedOffset: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingest_storage_reader_last_consumed_offset",
+			Help: "The offset of the last record consumed from the partition.",
+		}),
+	}
+	r.lastConsumedOffset.Set(-1)
+
+	r.Service = services.NewBasicService(r.starting, r.running, r.stopping)
+	return r
+}
+
+func (r *PartitionReader) starting(ctx context.Context) error {
+	offset, ok, err := r.readOffsetFile()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Nothing has been consumed yet, so the partition is consumed from the start.
+		if offset, err = r.client.listOffset(ctx, r.partitionID, listOffsetsEarliest); err != nil {
+			return errors.Wrap(err, "failed to fetch the start offset of the partition")
+		}
+	}
+	r.nextOffset = offset
+	r.committedOffset = offset
+	r.committed = ok
+
+	end, err := r.client.listOffset(ctx, r.partitionID, listOffsetsLatest)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch the end offset of the partition")
+	}
+
+	level.Info(r.logger).Log("msg", "replaying partition", "from_offset", r.nextOffset, "to_offset", end)
+	startTime := time.Now()
+
+	boff := backoff.New(ctx, readerBackoffConfig)
+	for r.nextOffset < end {
+		if err := r.fetchAndConsume(ctx, 0); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			level.Warn(r.logger).Log("msg", "failed to fetch the records of the partition while replaying it", "offset", r.nextOffset, "err", err)
+			boff.Wait()
+			continue
+		}
+		boff.Reset()
+	}
+
+	level.Info(r.logger).Log("msg", "partition replayed", "offset", r.nextOffset, "duration", time.Since(startTime))
+	return r.commitOffset()
+}
+
+func (r *PartitionReader) running(ctx context.Context) error {
+	boff := backoff.New(ctx, readerBackoffConfig)
+
+	for ctx.Err() == nil {
+		if err := r.fetchAndConsume(ctx, readerFetchMaxWait); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+
+			level.Warn(r.logger).Log("msg", "failed to fetch the records of the partition", "offset", r.nextOffset, "err", err)
+			boff.Wait()
+			continue
+		}
+		boff.Reset()
+
+		if time.Since(r.lastCommit) >= readerCommitInterval {
+			if err := r.commitOffset(); err != nil {
+				level.Warn(r.logger).Log("msg", "failed to persist the offset of the consumed records", "err", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *PartitionReader) stopping(_ error) error {
+	defer r.client.close()
+
+	return r.commitOffset()
+}
+
+// fetchAndConsume fetches the records of the partition from the next offset to consume, and consumes them.
+// If the next offset to consume is out of the range of the partition, because the records have been deleted
+// by the retention or the topic has been recreated, the partition is consumed from its earliest offset.
+func (r *PartitionReader) fetchAndConsume(ctx context.Context, maxWait time.Duration) error {
+	records, nextOffset, err := r.client.fetch(ctx, r.partitionID, r.nextOffset, maxWait, readerFetchMaxBytes)
+
+	var kerr kafkaError
+	if errors.As(err, &kerr) && kerr == errOffsetOutOfRange {
+		earliest, err := r.client.listOffset(ctx, r.partitionID, listOffsetsEarliest)
+		if err != nil {
+			return err
+		}
+
+		level.Warn(r.logger).Log("msg", "the offset to consume is out of the range of the partition; consuming the partition from its earliest offset", "offset", r.nextOffset, "earliest_offset", earliest)
+		r.nextOffset = earliest
+		return nil
+	}
+	if err != nil {
+		r.fetchFailures.Inc()
+		return err
+	}
+
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The offset doesn't advance until the record has been ingested or skipped, so the records
+		// are not lost if the reader is stopped while retrying.
+		if err := r.consume(ctx, rec); err != nil {
+			return err
+		}
+		r.nextOffset = rec.offset + 1
+		r.lastConsumedOffset.Set(float64(rec.offset))
+	}
+
+	// Skip the control records following the consumed records, if any.
+	if nextOffset > r.nextOffset {
+		r.nextOffset = nextOffset
+	}
+	return nil
+}
+
+// consume ingests the record. The records which can't be decoded, and the ones failing with a client error,
+// are skipped. Otherwise, the record is consumed again with backoff until it succeeds, and an error is only
+// returned if the context is canceled while retrying.
+func (r *PartitionReader) consume(ctx context.Context, rec record) error {
+	r.consumedRecords.Inc()
+
+	userID := string(rec.key)
+	boff := backoff.New(ctx, readerBackoffConfig)
+	for {
+		// The record is decoded for each attempt, given the write request isn't retained by the consumer.
+		req := mimirpb.WriteRequest{}
+		if err := req.Unmarshal(rec.value); err != nil {
+			r.failedRecords.Inc()
+			level.Warn(r.logger).Log("msg", "failed to decode the record consumed from the partition", "offset", rec.offset, "err", err)
+			return nil
+		}
+
+		err := r.consumer.Consume(ctx, userID, &req)
+		mimirpb.ReuseSlice(req.Timeseries)
+		mimirpb.ReuseSlice(req.IngestAggregationInputs)
+
+		if err == nil {
+			return nil
+		}
+		if isClientError(err) {
+			r.failedRecords.Inc()
+			level.Warn(r.logger).Log("msg", "failed to ingest the record consumed from the partition; skipping it", "offset", rec.offset, "user", userID, "err", err)
+			return nil
+		}
+
+		r.consumeFailures.Inc()
+		level.Warn(r.logger).Log("msg", "failed to ingest the record consumed from the partition; retrying", "offset", rec.offset, "user", userID, "err", err)
+		boff.Wait()
+		if !boff.Ongoing() {
+			return boff.Err()
+		}
+	}
+}
+
+// isClientError returns whether the error is caused by the write request, so consuming it again would fail again.
+func isClientError(err error) bool {
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	return ok && resp.Code/100 == http.StatusBadRequest/100
+}
+
+// offsetFileContent is the content of the offset file.
+type offsetFileContent struct {
+	Partition int32 `json:"partition"`
+
+	// The offset of the next record to consume.
+	Offset int64 `json:"offset"`
+}
+
+// readOffsetFile returns the offset of the next record to consume from the offset file, and whether it's found.
+// The offset is not found if the offset file doesn't exist, or stores the offset of another partition.
+func (r *PartitionReader) readOffsetFile() (int64, bool, error) {
+	b, err := os.ReadFile(r.offsetFile)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to read the offset file")
+	}
+
+	content := offsetFileContent{}
+	if err := json.Unmarshal(b, &content); err != nil {
+		return 0, false, errors.Wrapf(err, "failed to decode the offset file %s", r.offsetFile)
+	}
+	if content.Partition != r.partitionID {
+		level.Warn(r.logger).Log("msg", "ignoring the offset of another partition", "offset_partition", content.Partition)
+		return 0, false, nil
+	}
+	return content.Offset, true, nil
+}
+
+// commitOffset persists the offset of the next record to consume to the offset file, if changed.
+func (r *PartitionReader) commitOffset() error {
+	r.lastCommit = time.Now()
+	if r.committed && r.nextOffset == r.committedOffset {
+		return nil
+	}
+
+	b, err := json.Marshal(offsetFileContent{Partition: r.partitionID, Offset: r.nextOffset})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.offsetFile), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := r.offsetFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return errors.Wrap(err, "failed to write the offset file")
+	}
+	if err := fileutil.Replace(tmp, r.offsetFile); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrap(err, "failed to rename the offset file")
+	}
+
+	r.committedOffset = r.nextOffset
+	r.committed = true
+	return nil
+}
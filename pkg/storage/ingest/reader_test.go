@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestPartitionReader(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 2)
+	dataDir := t.TempDir()
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	require.NoError(t, w.WriteSync(ctx, 1, "user-1", testWriteRequest("series_1", 1)))
+	require.NoError(t, w.WriteSync(ctx, 0, "user-1", testWriteRequest("series_0", 0)))
+	require.NoError(t, w.WriteSync(ctx, 1, "user-2", testWriteRequest("series_2", 2)))
+
+	// The records written before the reader starts are replayed before it's running.
+	consumer := &consumerMock{}
+	reader := NewPartitionReader(kafka.config(), 1, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+	assert.Equal(t, []string{"user-1/series_1", "user-2/series_2"}, consumer.consumed())
+	assertOffsetFile(t, dataDir, 1, 2)
+
+	// The records written once running are consumed.
+	require.NoError(t, w.WriteSync(ctx, 1, "user-1", testWriteRequest("series_3", 3)))
+	test.Poll(t, 5*time.Second, 3, func() interface{} {
+		return len(consumer.consumed())
+	})
+	require.NoError(t, services.StopAndAwaitTerminated(ctx, reader))
+	assertOffsetFile(t, dataDir, 1, 3)
+
+	// A restarted reader replays the records from the last consumed offset.
+	require.NoError(t, w.WriteSync(ctx, 1, "user-2", testWriteRequest("series_4", 4)))
+
+	consumer = &consumerMock{}
+	reader = NewPartitionReader(kafka.config(), 1, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+	assert.Equal(t, []string{"user-2/series_4"}, consumer.consumed())
+	require.NoError(t, services.StopAndAwaitTerminated(ctx, reader))
+	assertOffsetFile(t, dataDir, 1, 4)
+}
+
+func TestPartitionReader_ConsumerClientErrorsAreSkipped(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 1)
+	dataDir := t.TempDir()
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	require.NoError(t, w.WriteSync(ctx, 0, "user-1", testWriteRequest("series_1", 1)))
+	require.NoError(t, w.WriteSync(ctx, 0, "user-2", testWriteRequest("series_2", 2)))
+
+	consumer := &consumerMock{clientErrorUser: "user-1"}
+	reader := NewPartitionReader(kafka.config(), 0, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(ctx, reader)) })
+
+	assert.Equal(t, []string{"user-2/series_2"}, consumer.consumed())
+	assert.Equal(t, 1, consumer.attemptsOf("user-1"))
+	assertOffsetFile(t, dataDir, 0, 2)
+}
+
+func TestPartitionReader_ConsumerServerErrorsAreRetried(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 1)
+	dataDir := t.TempDir()
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	require.NoError(t, w.WriteSync(ctx, 0, "user-1", testWriteRequest("series_1", 1)))
+	require.NoError(t, w.WriteSync(ctx, 0, "user-2", testWriteRequest("series_2", 2)))
+
+	// The record is consumed again until it succeeds, before the next records.
+	consumer := &consumerMock{serverErrorUser: "user-1", serverErrors: 2}
+	reader := NewPartitionReader(kafka.config(), 0, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(ctx, reader)) })
+
+	assert.Equal(t, []string{"user-1/series_1", "user-2/series_2"}, consumer.consumed())
+	assert.Equal(t, 3, consumer.attemptsOf("user-1"))
+	assertOffsetFile(t, dataDir, 0, 2)
+}
+
+func TestPartitionReader_ShouldNotAdvanceTheOffsetWhileRetrying(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 1)
+	dataDir := t.TempDir()
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	consumer := &consumerMock{serverErrorUser: "user-1", serverErrors: math.MaxInt}
+	reader := NewPartitionReader(kafka.config(), 0, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+
+	require.NoError(t, w.WriteSync(ctx, 0, "user-2", testWriteRequest("series_0", 0)))
+	require.NoError(t, w.WriteSync(ctx, 0, "user-1", testWriteRequest("series_1", 1)))
+	require.NoError(t, w.WriteSync(ctx, 0, "user-2", testWriteRequest("series_2", 2)))
+	test.Poll(t, 5*time.Second, true, func() interface{} {
+		return consumer.attemptsOf("user-1") > 1
+	})
+
+	// The reader is stopped while retrying: the offset of the failing record is persisted, so it's consumed
+	// again once restarted.
+	require.NoError(t, services.StopAndAwaitTerminated(ctx, reader))
+	assert.Equal(t, []string{"user-2/series_0"}, consumer.consumed())
+	assertOffsetFile(t, dataDir, 0, 1)
+}
+
+func TestPartitionReader_OffsetOutOfRange(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 1)
+	dataDir := t.TempDir()
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	for _, name := range []string{"series_0", "series_1", "series_2"} {
+		require.NoError(t, w.WriteSync(ctx, 0, "user-1", testWriteRequest(name, 1)))
+	}
+
+	// The records consumed last have been deleted by the retention.
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, OffsetFilename), []byte(`{"partition":0,"offset":1}`), 0o644))
+	kafka.deleteRecords(0, 2)
+
+	consumer := &consumerMock{}
+	reader := NewPartitionReader(kafka.config(), 0, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(ctx, reader)) })
+
+	assert.Equal(t, []string{"user-1/series_2"}, consumer.consumed())
+}
+
+func TestPartitionReader_OffsetOfAnotherPartitionIsIgnored(t *testing.T) {
+	ctx := context.Background()
+	kafka := newFakeKafka(t, "test", 2)
+	dataDir := t.TempDir()
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	require.NoError(t, w.WriteSync(ctx, 1, "user-1", testWriteRequest("series_1", 1)))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, OffsetFilename), []byte(`{"partition":0,"offset":1}`), 0o644))
+
+	consumer := &consumerMock{}
+	reader := NewPartitionReader(kafka.config(), 1, dataDir, consumer, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, services.StartAndAwaitRunning(ctx, reader))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(ctx, reader)) })
+
+	assert.Equal(t, []string{"user-1/series_1"}, consumer.consumed())
+	assertOffsetFile(t, dataDir, 1, 1)
+}
+
+func assertOffsetFile(t *testing.T, dataDir string, expectedPartition int32, expectedOffset int64) {
+	t.Helper()
+
+	r := &PartitionReader{offsetFile: filepath.Join(dataDir, OffsetFilename), partitionID: expectedPartition, logger: log.NewNopLogger()}
+	offset, ok, err := r.readOffsetFile()
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, expectedOffset, offset)
+}
+
+// consumerMock records the series consumed, as <user>/<metric name>. The write requests of clientErrorUser
+// fail with a client error, while the first serverErrors write requests of serverErrorUser fail with a server error.
+type consumerMock struct {
+	clientErrorUser string
+	serverErrorUser string
+	serverErrors    int
+
+	mtx      sync.Mutex
+	series   []string
+	attempts map[string]int
+}
+
+func (c *consumerMock) Consume(_ context.Context, userID string, req *mimirpb.WriteRequest) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.attempts == nil {
+		c.attempts = map[string]int{}
+	}
+	c.attempts[userID]++
+
+	if userID == c.clientErrorUser {
+		return httpgrpc.Errorf(http.StatusBadRequest, "consume failed")
+	}
+	if userID == c.serverErrorUser && c.attempts[userID] <= c.serverErrors {
+		return errors.New("consume failed")
+	}
+
+	for _, ts := range req.Timeseries {
+		c.series = append(c.series, userID+"/"+mimirpb.FromLabelAdaptersToLabels(ts.Labels).Get("__name__"))
+	}
+	return nil
+}
+
+func (c *consumerMock) attemptsOf(userID string) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.attempts[userID]
+}
+
+func (c *consumerMock) consumed() []string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]string(nil), c.series...)
+}
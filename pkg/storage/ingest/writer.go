@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// maxRecordSizeBytes is the maximum size of the write request encoded in a record. Larger write requests
+// are split into multiple records, to not exceed the max message size of the brokers, which is 1MB by default.
+const maxRecordSizeBytes = 1000 * 1000
+
+// Writer writes the series pushed to the distributors to the partitions of the ingest storage.
+type Writer struct {
+	client *kafkaClient
+	logger log.Logger
+
+	ringMtx sync.Mutex
+	ring    *PartitionRing
+
+	writeRequests prometheus.Counter
+	writeFailures prometheus.Counter
+	writeLatency  prometheus.Histogram
+	writeBytes    prometheus.Counter
+}
+
+// NewWriter returns a Writer writing to the partitions of the topic of the input config.
+func NewWriter(cfg KafkaConfig, logger log.Logger, reg prometheus.Registerer) *Writer {
+	return &Writer{
+		client: newKafkaClient(cfg, logger),
+		logger: logger,
+		writeRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_writer_produce_requests_total",
+			Help: "Total number of produce requests sent to the Kafka brokers.",
+		}),
+		writeFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_writer_produce_failures_total",
+			Help: "Total number of failed produce requests sent to the Kafka brokers.",
+		}),
+		writeLatency: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "cortex_ingest_storage_writer_latency_seconds",
+			Help:    "Latency of the produce requests sent to the Kafka brokers.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		writeBytes: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingest_storage_writer_sent_bytes_total",
+			Help: "Total number of bytes of the records sent to the Kafka brokers.",
+		}),
+	}
+}
+
+// PartitionRing returns the ring of the partitions of the topic. The partitions are discovered from the
+// topic metadata, which is periodically refreshed.
+func (w *Writer) PartitionRing(ctx context.Context) (*PartitionRing, error) {
+	ids, err := w.client.partitionIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.ringMtx.Lock()
+	defer w.ringMtx.Unlock()
+
+	if w.ring == nil || !equalPartitionIDs(w.ring.PartitionIDs(), ids) {
+		w.ring = NewPartitionRing(ids)
+	}
+	return w.ring, nil
+}
+
+// WriteSync writes the request of the tenant to the partition, and returns once the records have been
+// acknowledged by all the in-sync replicas of the partition. The request isn't retained once WriteSync returns.
+func (w *Writer) WriteSync(ctx context.Context, partitionID int32, userID string, req *mimirpb.WriteRequest) error {
+	for _, partial := range splitWriteRequest(req, maxRecordSizeBytes) {
+		value, err := partial.Marshal()
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal the write request")
+		}
+
+		start := time.Now()
+		_, err = w.client.produce(ctx, partitionID, []record{{timestamp: start.UnixMilli(), key: []byte(userID), value: value}})
+		w.writeRequests.Inc()
+		w.writeLatency.Observe(time.Since(start).Seconds())
+		if err != nil {
+			w.writeFailures.Inc()
+			return errors.Wrapf(err, "failed to write to partition %d", partitionID)
+		}
+		w.writeBytes.Add(float64(len(value)))
+	}
+	return nil
+}
+
+// Close closes the connections to the brokers.
+func (w *Writer) Close() {
+	w.client.close()
+}
+
+// splitWriteRequest splits the input request into requests whose size is up to maxSize. A single series
+// or metadata larger than maxSize is written in a request of its own.
+func splitWriteRequest(req *mimirpb.WriteRequest, maxSize int) []*mimirpb.WriteRequest {
+	if req.Size() <= maxSize {
+		return []*mimirpb.WriteRequest{req}
+	}
+
+	newRequest := func() *mimirpb.WriteRequest {
+		return &mimirpb.WriteRequest{Source: req.Source, SkipLabelNameValidation: req.SkipLabelNameValidation}
+	}
+
+	var (
+		reqs     []*mimirpb.WriteRequest
+		curr     = newRequest()
+		currSize int
+	)
+	// reserve starts a new request if the current one can't fit the input size.
+	reserve := func(size int) {
+		// Account for the field tag, up to 2 bytes, and the length prefix of the item.
+		size += 2 + 5
+		if currSize > 0 && currSize+size > maxSize {
+			reqs = append(reqs, curr)
+			curr = newRequest()
+			currSize = 0
+		}
+		currSize += size
+	}
+
+	for _, ts := range req.Timeseries {
+		reserve(ts.Size())
+		curr.Timeseries = append(curr.Timeseries, ts)
+	}
+	for _, ts := range req.IngestAggregationInputs {
+		reserve(ts.Size())
+		curr.IngestAggregationInputs = append(curr.IngestAggregationInputs, ts)
+	}
+	for _, m := range req.Metadata {
+		reserve(m.Size())
+		curr.Metadata = append(curr.Metadata, m)
+	}
+
+	return append(reqs, curr)
+}
+
+func equalPartitionIDs(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
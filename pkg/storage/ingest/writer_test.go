@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestWriter_PartitionRing(t *testing.T) {
+	kafka := newFakeKafka(t, "test", 3)
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	ring, err := w.PartitionRing(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int32{0, 1, 2}, ring.PartitionIDs())
+
+	// The ring is reused while the partitions don't change.
+	again, err := w.PartitionRing(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, ring, again)
+}
+
+func TestWriter_WriteSync(t *testing.T) {
+	kafka := newFakeKafka(t, "test", 2)
+
+	w := NewWriter(kafka.config(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	t.Cleanup(w.Close)
+
+	require.NoError(t, w.WriteSync(context.Background(), 1, "user-1", testWriteRequest("series_1", 1)))
+	require.NoError(t, w.WriteSync(context.Background(), 1, "user-2", testWriteRequest("series_2", 2)))
+
+	records, nextOffset, err := w.client.fetch(context.Background(), 1, 0, 0, readerFetchMaxBytes)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), nextOffset)
+	require.Len(t, records, 2)
+	assert.Equal(t, "user-1", string(records[0].key))
+	assert.Equal(t, "user-2", string(records[1].key))
+
+	req := mimirpb.WriteRequest{}
+	require.NoError(t, req.Unmarshal(records[1].value))
+	require.Len(t, req.Timeseries, 1)
+	assert.Equal(t, `{__name__="series_2"}`, mimirpb.FromLabelAdaptersToLabels(req.Timeseries[0].Labels).String())
+
+	// Nothing has been written to the other partition.
+	records, _, err = w.client.fetch(context.Background(), 0, 0, 0, readerFetchMaxBytes)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	t.Run("the broker errors are returned", func(t *testing.T) {
+		kafka.setProduceErr(errNotEnoughReplicas)
+		t.Cleanup(func() { kafka.setProduceErr(0) })
+
+		err := w.WriteSync(context.Background(), 0, "user-1", testWriteRequest("series_1", 1))
+		require.ErrorIs(t, err, errNotEnoughReplicas)
+	})
+
+	t.Run("unknown partition", func(t *testing.T) {
+		require.Error(t, w.WriteSync(context.Background(), 5, "user-1", testWriteRequest("series_1", 1)))
+	})
+}
+
+func TestSplitWriteRequest(t *testing.T) {
+	req := &mimirpb.WriteRequest{Source: mimirpb.RULE, SkipLabelNameValidation: true}
+	for i := 0; i < 100; i++ {
+		req.Timeseries = append(req.Timeseries, testWriteRequest(fmt.Sprintf("series_%d", i), float64(i)).Timeseries...)
+	}
+	req.Metadata = append(req.Metadata, &mimirpb.MetricMetadata{MetricFamilyName: "series_0", Help: "help"})
+
+	t.Run("a request smaller than the max size isn't split", func(t *testing.T) {
+		reqs := splitWriteRequest(req, req.Size())
+		require.Len(t, reqs, 1)
+		assert.Same(t, req, reqs[0])
+	})
+
+	t.Run("a request larger than the max size is split", func(t *testing.T) {
+		maxSize := req.Size() / 4
+		reqs := splitWriteRequest(req, maxSize)
+		require.Greater(t, len(reqs), 4)
+
+		var series, metadata int
+		for _, r := range reqs {
+			assert.LessOrEqual(t, r.Size(), maxSize)
+			assert.Equal(t, req.Source, r.Source)
+			assert.Equal(t, req.SkipLabelNameValidation, r.SkipLabelNameValidation)
+			series += len(r.Timeseries)
+			metadata += len(r.Metadata)
+		}
+		assert.Equal(t, len(req.Timeseries), series)
+		assert.Equal(t, len(req.Metadata), metadata)
+	})
+
+	t.Run("a series larger than the max size is written in a request of its own", func(t *testing.T) {
+		reqs := splitWriteRequest(req, 1)
+		assert.Len(t, reqs, len(req.Timeseries)+len(req.Metadata))
+	})
+}
+
+func testWriteRequest(metricName string, value float64) *mimirpb.WriteRequest {
+	return mimirpb.ToWriteRequest(
+		[]labels.Labels{labels.FromStrings(labels.MetricName, metricName)},
+		[]mimirpb.Sample{{TimestampMs: 1000, Value: value}},
+		nil, nil, mimirpb.API,
+	)
+}
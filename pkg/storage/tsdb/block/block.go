@@ -54,7 +54,8 @@ func Download(ctx context.Context, logger log.Logger, bucket objstore.Bucket, id
 		return err
 	}
 
-	ignoredPaths := []string{MetaFilename}
+	// The index-header, uploaded by the compactor for the store-gateways, is not part of the block.
+	ignoredPaths := []string{MetaFilename, IndexHeaderFilename}
 	if err := objstore.DownloadDir(ctx, logger, bucket, id.String(), id.String(), dst, append(options, objstore.WithDownloadIgnoredPaths(ignoredPaths...))...); err != nil {
 		return err
 	}
@@ -122,6 +123,12 @@ func Upload(ctx context.Context, logger log.Logger, bkt objstore.Bucket, blockDi
 		return cleanUp(logger, bkt, id, errors.Wrap(err, "upload index"))
 	}
 
+	if HasExemplars(meta) {
+		if err := objstore.UploadFile(ctx, logger, bkt, filepath.Join(blockDir, ExemplarsFilename), path.Join(id.String(), ExemplarsFilename)); err != nil {
+			return cleanUp(logger, bkt, id, errors.Wrap(err, "upload exemplars"))
+		}
+	}
+
 	// Meta.json always need to be uploaded as a last item. This will allow to assume block directories without meta file to be pending uploads.
 	if err := bkt.Upload(ctx, path.Join(id.String(), MetaFilename), strings.NewReader(metaEncoded.String())); err != nil {
 		// Don't call cleanUp here. Despite getting error, meta.json may have been uploaded in certain cases,
@@ -314,6 +321,17 @@ func GatherFileStats(blockDir string) (res []metadata.File, _ error) {
 	}
 	res = append(res, mf)
 
+	// The exemplars file is optional.
+	exemplarsFile, err := os.Stat(filepath.Join(blockDir, ExemplarsFilename))
+	if err == nil {
+		res = append(res, metadata.File{
+			RelPath:   exemplarsFile.Name(),
+			SizeBytes: exemplarsFile.Size(),
+		})
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "stat %v", filepath.Join(blockDir, ExemplarsFilename))
+	}
+
 	metaFile, err := os.Stat(filepath.Join(blockDir, MetaFilename))
 	if err != nil {
 		return nil, errors.Wrapf(err, "stat %v", filepath.Join(blockDir, MetaFilename))
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+// ExemplarsFilename is the known file storing the exemplars of a block. The file is optional,
+// and its content is a gzip-compressed sequence of uvarint length-prefixed mimirpb.TimeSeries,
+// each one holding the labels and the exemplars of a series.
+const ExemplarsFilename = "exemplars"
+
+// ErrExemplarsTooLarge is returned when reading an exemplars file whose uncompressed content exceeds the max size.
+var ErrExemplarsTooLarge = errors.New("the exemplars file exceeds the max size")
+
+// HasExemplars returns whether the block has an exemplars file, according to the files listed in the meta.
+func HasExemplars(meta *metadata.Meta) bool {
+	for _, f := range meta.Thanos.Files {
+		if f.RelPath == ExemplarsFilename {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteExemplarsFile atomically writes the exemplars file to the block directory. The file is not
+// written if there are no exemplars.
+func WriteExemplarsFile(blockDir string, series []mimirpb.TimeSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+
+	filename := filepath.Join(blockDir, ExemplarsFilename)
+	tmp := filename + ".tmp"
+
+	if err := writeExemplars(tmp, series); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := fileutil.Replace(tmp, filename); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrap(err, "rename exemplars file")
+	}
+	return nil
+}
+
+func writeExemplars(filename string, series []mimirpb.TimeSeries) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "create exemplars file")
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	for i := range series {
+		data, err := series[i].Marshal()
+		if err != nil {
+			return errors.Wrap(err, "marshal exemplars")
+		}
+
+		n := binary.PutUvarint(buf, uint64(len(data)))
+		if _, err := gw.Write(buf[:n]); err != nil {
+			return errors.Wrap(err, "write exemplars file")
+		}
+		if _, err := gw.Write(data); err != nil {
+			return errors.Wrap(err, "write exemplars file")
+		}
+	}
+
+	if err := gw.Close(); err != nil {
+		return errors.Wrap(err, "close exemplars file")
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrap(err, "sync exemplars file")
+	}
+	return f.Close()
+}
+
+// ReadExemplarsFile reads the exemplars file from the block directory. It returns no exemplars if the
+// block has no exemplars file.
+func ReadExemplarsFile(blockDir string) ([]mimirpb.TimeSeries, error) {
+	f, err := os.Open(filepath.Join(blockDir, ExemplarsFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "open exemplars file")
+	}
+	defer f.Close()
+
+	return ReadExemplars(f, 0)
+}
+
+// ReadExemplars reads the content of an exemplars file. It returns ErrExemplarsTooLarge if the uncompressed
+// content exceeds maxSizeBytes, unless it's 0.
+func ReadExemplars(r io.Reader, maxSizeBytes int64) ([]mimirpb.TimeSeries, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "read exemplars")
+	}
+	defer gr.Close()
+
+	br := bufio.NewReader(gr)
+
+	var (
+		series    []mimirpb.TimeSeries
+		sizeBytes int64
+	)
+	for {
+		size, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			return series, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read exemplars")
+		}
+
+		sizeBytes += int64(size)
+		if maxSizeBytes > 0 && (size > uint64(maxSizeBytes) || sizeBytes > maxSizeBytes) {
+			return nil, ErrExemplarsTooLarge
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, errors.Wrap(err, "read exemplars")
+		}
+
+		var ts mimirpb.TimeSeries
+		if err := ts.Unmarshal(data); err != nil {
+			return nil, errors.Wrap(err, "unmarshal exemplars")
+		}
+		series = append(series, ts)
+	}
+}
+
+// MergeExemplars merges the exemplars of the same series across the input sets, removing the duplicated
+// exemplars. The returned series are sorted by labels, and their exemplars by timestamp.
+func MergeExemplars(sets ...[]mimirpb.TimeSeries) []mimirpb.TimeSeries {
+	bySeries := map[string]*mimirpb.TimeSeries{}
+	var keys []string
+
+	for _, set := range sets {
+		for _, ts := range set {
+			key := mimirpb.FromLabelAdaptersToLabels(ts.Labels).String()
+
+			merged, ok := bySeries[key]
+			if !ok {
+				merged = &mimirpb.TimeSeries{Labels: ts.Labels}
+				bySeries[key] = merged
+				keys = append(keys, key)
+			}
+			merged.Exemplars = append(merged.Exemplars, ts.Exemplars...)
+		}
+	}
+
+	result := make([]mimirpb.TimeSeries, 0, len(keys))
+	for _, key := range keys {
+		ts := bySeries[key]
+		ts.Exemplars = dedupeExemplars(ts.Exemplars)
+		result = append(result, *ts)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return labels.Compare(mimirpb.FromLabelAdaptersToLabels(result[i].Labels), mimirpb.FromLabelAdaptersToLabels(result[j].Labels)) < 0
+	})
+	return result
+}
+
+func dedupeExemplars(exemplars []mimirpb.Exemplar) []mimirpb.Exemplar {
+	sort.SliceStable(exemplars, func(i, j int) bool {
+		return exemplars[i].TimestampMs < exemplars[j].TimestampMs
+	})
+
+	out := exemplars[:0]
+	for _, e := range exemplars {
+		if len(out) > 0 {
+			last := out[len(out)-1]
+			if last.TimestampMs == e.TimestampMs && last.Value == e.Value && labels.Equal(mimirpb.FromLabelAdaptersToLabels(last.Labels), mimirpb.FromLabelAdaptersToLabels(e.Labels)) {
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
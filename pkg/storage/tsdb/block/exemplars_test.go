@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/storegateway/testhelper"
+)
+
+func TestWriteReadExemplarsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("should not write the file if there are no exemplars", func(t *testing.T) {
+		require.NoError(t, WriteExemplarsFile(dir, nil))
+
+		_, err := os.Stat(filepath.Join(dir, ExemplarsFilename))
+		assert.True(t, os.IsNotExist(err))
+
+		series, err := ReadExemplarsFile(dir)
+		require.NoError(t, err)
+		assert.Empty(t, series)
+	})
+
+	t.Run("should write and read back the exemplars", func(t *testing.T) {
+		input := []mimirpb.TimeSeries{
+			exemplarsSeries(labels.FromStrings("a", "1"), 10, 20),
+			exemplarsSeries(labels.FromStrings("a", "2"), 30),
+		}
+
+		require.NoError(t, WriteExemplarsFile(dir, input))
+
+		series, err := ReadExemplarsFile(dir)
+		require.NoError(t, err)
+		assert.Equal(t, input, series)
+	})
+
+	t.Run("should fail reading the exemplars if they exceed the max size", func(t *testing.T) {
+		input := []mimirpb.TimeSeries{
+			exemplarsSeries(labels.FromStrings("a", "1"), 10, 20),
+			exemplarsSeries(labels.FromStrings("a", "2"), 30),
+		}
+		sizeBytes := int64(input[0].Size() + input[1].Size())
+
+		require.NoError(t, WriteExemplarsFile(dir, input))
+
+		data, err := os.ReadFile(filepath.Join(dir, ExemplarsFilename))
+		require.NoError(t, err)
+
+		series, err := ReadExemplars(bytes.NewReader(data), sizeBytes)
+		require.NoError(t, err)
+		assert.Equal(t, input, series)
+
+		_, err = ReadExemplars(bytes.NewReader(data), sizeBytes-1)
+		assert.ErrorIs(t, err, ErrExemplarsTooLarge)
+	})
+}
+
+func TestUpload_WithExemplars(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	bkt := objstore.NewInMemBucket()
+
+	id, err := testhelper.CreateBlock(ctx, tmpDir, fiveLabels, 100, 0, 1000, labels.FromStrings("ext1", "val1"))
+	require.NoError(t, err)
+
+	blockDir := path.Join(tmpDir, id.String())
+	require.NoError(t, WriteExemplarsFile(blockDir, []mimirpb.TimeSeries{exemplarsSeries(labels.FromStrings("a", "1"), 10)}))
+
+	meta, err := metadata.ReadFromDir(blockDir)
+	require.NoError(t, err)
+	require.NoError(t, Upload(ctx, log.NewNopLogger(), bkt, blockDir, meta))
+
+	exists, err := bkt.Exists(ctx, path.Join(id.String(), ExemplarsFilename))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.True(t, HasExemplars(meta))
+}
+
+func TestMergeExemplars(t *testing.T) {
+	series1 := labels.FromStrings("a", "1")
+	series2 := labels.FromStrings("a", "2")
+
+	merged := MergeExemplars(
+		[]mimirpb.TimeSeries{exemplarsSeries(series2, 30), exemplarsSeries(series1, 20, 10)},
+		[]mimirpb.TimeSeries{exemplarsSeries(series1, 20, 40)},
+	)
+
+	assert.Equal(t, []mimirpb.TimeSeries{
+		exemplarsSeries(series1, 10, 20, 40),
+		exemplarsSeries(series2, 30),
+	}, merged)
+}
+
+func exemplarsSeries(lbls labels.Labels, timestamps ...int64) mimirpb.TimeSeries {
+	ts := mimirpb.TimeSeries{Labels: mimirpb.FromLabelsToLabelAdapters(lbls)}
+	for _, t := range timestamps {
+		ts.Exemplars = append(ts.Exemplars, mimirpb.Exemplar{
+			Labels:      []mimirpb.LabelAdapter{{Name: "trace_id", Value: "abc"}},
+			Value:       float64(t),
+			TimestampMs: t,
+		})
+	}
+	return ts
+}
@@ -69,7 +69,7 @@ const (
 	FailedMeta    = "failed"
 
 	// Synced label values.
-	labelExcludedMeta = "label-excluded"
+	LabelExcludedMeta = "label-excluded"
 	timeExcludedMeta  = "time-excluded"
 	tooFreshMeta      = "too-fresh"
 	duplicateMeta     = "duplicate"
@@ -117,7 +117,7 @@ func NewFetcherMetrics(reg prometheus.Registerer, syncedExtraLabels, modifiedExt
 			{LoadedMeta},
 			{tooFreshMeta},
 			{FailedMeta},
-			{labelExcludedMeta},
+			{LabelExcludedMeta},
 			{timeExcludedMeta},
 			{duplicateMeta},
 			{MarkedForDeletionMeta},
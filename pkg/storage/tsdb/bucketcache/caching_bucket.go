@@ -34,7 +34,11 @@ const (
 	originCache  = "cache"
 	originBucket = "bucket"
 
-	memoryPoolContextKey contextKey = 0
+	memoryPoolContextKey      contextKey = 0
+	subrangeTTLContextKey     contextKey = 1
+	subrangeCachingContextKey contextKey = 2
+	cacheKeyEpochContextKey   contextKey = 3
+	cacheLookupContextKey     contextKey = 4
 )
 
 var errObjNotFound = errors.Errorf("object not found")
@@ -60,6 +64,57 @@ func getMemoryPool(ctx context.Context) *pool.SafeSlabPool[byte] {
 	return slabs
 }
 
+// WithSubrangeTTL returns a new context overriding the TTL of the object subranges
+// stored to the cache by the GetRange calls run with the returned context.
+func WithSubrangeTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, subrangeTTLContextKey, ttl)
+}
+
+func getSubrangeTTL(ctx context.Context, defaultTTL time.Duration) time.Duration {
+	if ttl, ok := ctx.Value(subrangeTTLContextKey).(time.Duration); ok && ttl > 0 {
+		return ttl
+	}
+	return defaultTTL
+}
+
+// WithSubrangeCachingDisabled returns a new context for which the GetRange calls bypass
+// the cache, both when reading and storing the object subranges.
+func WithSubrangeCachingDisabled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, subrangeCachingContextKey, false)
+}
+
+func isSubrangeCachingEnabled(ctx context.Context) bool {
+	enabled, ok := ctx.Value(subrangeCachingContextKey).(bool)
+	return !ok || enabled
+}
+
+// WithCacheLookupDisabled returns a new context for which the Get calls don't look up the
+// cache, and read the object from the bucket. The object content is still stored to the cache,
+// replacing the cached one, so it can be used to refresh a stale cache entry.
+func WithCacheLookupDisabled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheLookupContextKey, false)
+}
+
+func isCacheLookupEnabled(ctx context.Context) bool {
+	enabled, ok := ctx.Value(cacheLookupContextKey).(bool)
+	return !ok || enabled
+}
+
+// WithCacheKeyEpoch returns a new context for which the cache keys of the objects accessed
+// with the returned context include the given epoch. Changing the epoch invalidates the
+// entries previously cached for the objects. An empty epoch leaves the cache keys unchanged.
+func WithCacheKeyEpoch(ctx context.Context, epoch string) context.Context {
+	return context.WithValue(ctx, cacheKeyEpochContextKey, epoch)
+}
+
+// cachingKeyName returns the name of the object to use in its cache keys.
+func cachingKeyName(ctx context.Context, name string) string {
+	if epoch, ok := ctx.Value(cacheKeyEpochContextKey).(string); ok && epoch != "" {
+		return name + "@" + epoch
+	}
+	return name
+}
+
 func getCacheOptions(slabs *pool.SafeSlabPool[byte]) []cache.Option {
 	var opts []cache.Option
 
@@ -168,7 +223,7 @@ func (cb *CachingBucket) Iter(ctx context.Context, dir string, f func(string) er
 
 	cb.operationRequests.WithLabelValues(objstore.OpIter, cfgName).Inc()
 
-	key := cachingKeyIter(dir)
+	key := cachingKeyIter(cachingKeyName(ctx, dir))
 	data := cfg.cache.Fetch(ctx, []string{key})
 	if data[key] != nil {
 		list, err := cfg.codec.Decode(data[key])
@@ -213,7 +268,7 @@ func (cb *CachingBucket) Exists(ctx context.Context, name string) (bool, error)
 
 	cb.operationRequests.WithLabelValues(objstore.OpExists, cfgName).Inc()
 
-	key := cachingKeyExists(name)
+	key := cachingKeyExists(cachingKeyName(ctx, name))
 	hits := cfg.cache.Fetch(ctx, []string{key})
 
 	if ex := hits[key]; ex != nil {
@@ -255,8 +310,8 @@ func (cb *CachingBucket) Get(ctx context.Context, name string) (io.ReadCloser, e
 
 	cb.operationRequests.WithLabelValues(objstore.OpGet, cfgName).Inc()
 
-	contentKey := cachingKeyContent(name)
-	existsKey := cachingKeyExists(name)
+	contentKey := cachingKeyContent(cachingKeyName(ctx, name))
+	existsKey := cachingKeyExists(cachingKeyName(ctx, name))
 	slabs := getMemoryPool(ctx)
 	cacheOpts := getCacheOptions(slabs)
 	releaseSlabs := true
@@ -272,7 +327,10 @@ func (cb *CachingBucket) Get(ctx context.Context, name string) (io.ReadCloser, e
 		}()
 	}
 
-	hits := cfg.cache.Fetch(ctx, []string{contentKey, existsKey}, cacheOpts...)
+	var hits map[string][]byte
+	if isCacheLookupEnabled(ctx) {
+		hits = cfg.cache.Fetch(ctx, []string{contentKey, existsKey}, cacheOpts...)
+	}
 	if hits[contentKey] != nil {
 		cb.operationHits.WithLabelValues(objstore.OpGet, cfgName).Inc()
 
@@ -316,7 +374,7 @@ func (cb *CachingBucket) IsObjNotFoundErr(err error) bool {
 }
 
 func (cb *CachingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
-	if off < 0 || length <= 0 {
+	if off < 0 || length <= 0 || !isSubrangeCachingEnabled(ctx) {
 		return cb.Bucket.GetRange(ctx, name, off, length)
 	}
 
@@ -338,7 +396,7 @@ func (cb *CachingBucket) Attributes(ctx context.Context, name string) (objstore.
 }
 
 func (cb *CachingBucket) cachedAttributes(ctx context.Context, name, cfgName string, cache cache.Cache, ttl time.Duration) (objstore.ObjectAttributes, error) {
-	key := cachingKeyAttributes(name)
+	key := cachingKeyAttributes(cachingKeyName(ctx, name))
 
 	cb.operationRequests.WithLabelValues(objstore.OpAttributes, cfgName).Inc()
 
@@ -410,7 +468,7 @@ func (cb *CachingBucket) cachedGetRange(ctx context.Context, name string, offset
 		}
 		totalRequestedBytes += (end - off)
 
-		k := cachingKeyObjectSubrange(name, off, end)
+		k := cachingKeyObjectSubrange(cachingKeyName(ctx, name), off, end)
 		keys = append(keys, k)
 		offsetKeys[off] = k
 	}
@@ -476,6 +534,7 @@ func (cb *CachingBucket) fetchMissingSubranges(ctx context.Context, name string,
 	}
 
 	var hitsMutex sync.Mutex
+	subrangeTTL := getSubrangeTTL(ctx, cfg.subrangeTTL)
 
 	// Run parallel queries for each missing range. Fetched data is stored into 'hits' map, protected by hitsMutex.
 	g, gctx := errgroup.WithContext(ctx)
@@ -518,7 +577,7 @@ func (cb *CachingBucket) fetchMissingSubranges(ctx context.Context, name string,
 
 				if storeToCache {
 					cb.fetchedGetRangeBytes.WithLabelValues(originBucket, cfgName).Add(float64(len(subrangeData)))
-					cfg.cache.StoreAsync(map[string][]byte{key: subrangeData}, cfg.subrangeTTL)
+					cfg.cache.StoreAsync(map[string][]byte{key: subrangeData}, subrangeTTL)
 				} else {
 					cb.refetchedGetRangeBytes.WithLabelValues(originCache, cfgName).Add(float64(len(subrangeData)))
 				}
@@ -244,6 +244,56 @@ func TestChunksCaching(t *testing.T) {
 	}
 }
 
+func TestChunksCachingWithContextOverrides(t *testing.T) {
+	const subrangeSize = int64(16000)
+
+	data := make([]byte, 4*subrangeSize)
+	name := "/test/chunks/000001"
+
+	inmem := objstore.NewInMemBucket()
+	require.NoError(t, inmem.Upload(context.Background(), name, bytes.NewReader(data)))
+
+	readRange := func(t *testing.T, ctx context.Context, cachingBucket *CachingBucket) {
+		r, err := cachingBucket.GetRange(ctx, name, 0, subrangeSize)
+		require.NoError(t, err)
+		defer r.Close()
+
+		read, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, subrangeSize, int64(len(read)))
+	}
+
+	newCachingBucket := func(t *testing.T) (*CachingBucket, *cache.MockCache) {
+		c := cache.NewMockCache()
+		cfg := NewCachingBucketConfig()
+		cfg.CacheGetRange("chunks", c, isTSDBChunkFile, subrangeSize, c, time.Hour, time.Hour, 3)
+
+		cachingBucket, err := NewCachingBucket(inmem, cfg, nil, nil)
+		require.NoError(t, err)
+		return cachingBucket, c
+	}
+
+	t.Run("should store the subranges with the TTL overridden in the context", func(t *testing.T) {
+		cachingBucket, c := newCachingBucket(t)
+		readRange(t, WithSubrangeTTL(context.Background(), 10*time.Hour), cachingBucket)
+
+		item, ok := c.GetItems()[cachingKeyObjectSubrange(name, 0, subrangeSize)]
+		require.True(t, ok)
+		assert.Greater(t, time.Until(item.ExpiresAt), 9*time.Hour)
+	})
+
+	t.Run("should bypass the cache if subrange caching is disabled in the context", func(t *testing.T) {
+		cachingBucket, c := newCachingBucket(t)
+		ctx := WithSubrangeCachingDisabled(context.Background())
+
+		readRange(t, ctx, cachingBucket)
+		readRange(t, ctx, cachingBucket)
+
+		assert.Empty(t, c.GetItems())
+		assert.Equal(t, float64(0), promtest.ToFloat64(cachingBucket.operationRequests.WithLabelValues(objstore.OpGetRange, "chunks")))
+	})
+}
+
 func verifyGetRange(t *testing.T, cachingBucket *CachingBucket, name string, offset, length, expectedLength int64) {
 	r, err := cachingBucket.GetRange(context.Background(), name, offset, length)
 	assert.NoError(t, err)
@@ -492,6 +542,57 @@ func TestGet(t *testing.T) {
 	verifyExists(t, cb, testFilename, true, true, cfgName)
 }
 
+func TestGetWithCacheKeyEpoch(t *testing.T) {
+	inmem := objstore.NewInMemBucket()
+	cache := cache.NewMockCache()
+
+	cfg := NewCachingBucketConfig()
+	const cfgName = "metafile"
+	cfg.CacheGet(cfgName, cache, matchAll, 1024, 10*time.Minute, 10*time.Minute, 2*time.Minute)
+
+	cb, err := NewCachingBucket(inmem, cfg, nil, nil)
+	assert.NoError(t, err)
+
+	oldData := []byte("hello world")
+	assert.NoError(t, inmem.Upload(context.Background(), testFilename, bytes.NewBuffer(oldData)))
+	verifyGet(t, cb, testFilename, oldData, false, cfgName)
+
+	newData := []byte("hello again")
+	assert.NoError(t, inmem.Upload(context.Background(), testFilename, bytes.NewBuffer(newData)))
+
+	// An empty epoch doesn't change the cache keys, so old data is served from cache.
+	verifyGetWithContext(t, WithCacheKeyEpoch(context.Background(), ""), cb, testFilename, oldData, true, cfgName)
+
+	// A new epoch invalidates the cached data.
+	ctx := WithCacheKeyEpoch(context.Background(), "1")
+	verifyGetWithContext(t, ctx, cb, testFilename, newData, false, cfgName)
+	verifyGetWithContext(t, ctx, cb, testFilename, newData, true, cfgName)
+}
+
+func TestGetWithCacheLookupDisabled(t *testing.T) {
+	inmem := objstore.NewInMemBucket()
+	cache := cache.NewMockCache()
+
+	cfg := NewCachingBucketConfig()
+	const cfgName = "metafile"
+	cfg.CacheGet(cfgName, cache, matchAll, 1024, 10*time.Minute, 10*time.Minute, 2*time.Minute)
+
+	cb, err := NewCachingBucket(inmem, cfg, nil, nil)
+	assert.NoError(t, err)
+
+	oldData := []byte("hello world")
+	assert.NoError(t, inmem.Upload(context.Background(), testFilename, bytes.NewBuffer(oldData)))
+	verifyGet(t, cb, testFilename, oldData, false, cfgName)
+
+	newData := []byte("hello again")
+	assert.NoError(t, inmem.Upload(context.Background(), testFilename, bytes.NewBuffer(newData)))
+	verifyGet(t, cb, testFilename, oldData, true, cfgName)
+
+	// The cache lookup is skipped, and the cached data is refreshed.
+	verifyGetWithContext(t, WithCacheLookupDisabled(context.Background()), cb, testFilename, newData, false, cfgName)
+	verifyGet(t, cb, testFilename, newData, true, cfgName)
+}
+
 func TestGetTooBigObject(t *testing.T) {
 	inmem := objstore.NewInMemBucket()
 
@@ -546,9 +647,13 @@ func TestGetPartialRead(t *testing.T) {
 }
 
 func verifyGet(t *testing.T, cb *CachingBucket, file string, expectedData []byte, cacheUsed bool, cfgName string) {
+	verifyGetWithContext(t, context.Background(), cb, file, expectedData, cacheUsed, cfgName)
+}
+
+func verifyGetWithContext(t *testing.T, ctx context.Context, cb *CachingBucket, file string, expectedData []byte, cacheUsed bool, cfgName string) {
 	hitsBefore := int(promtest.ToFloat64(cb.operationHits.WithLabelValues(objstore.OpGet, cfgName)))
 
-	r, err := cb.Get(context.Background(), file)
+	r, err := cb.Get(ctx, file)
 	if expectedData == nil {
 		assert.True(t, cb.IsObjNotFoundErr(err))
 
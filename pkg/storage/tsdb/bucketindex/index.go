@@ -30,6 +30,10 @@ const (
 	// SegmentsFormat1Based6Digits defined segments numbered with 6 digits numbers in a sequence starting from number 1
 	// eg. (000001, 000002, 000003).
 	SegmentsFormat1Based6Digits = "1b6d"
+
+	// BlockTierCold is the tier of the blocks stored in the cold storage. The blocks stored
+	// in the (default) hot storage have no tier.
+	BlockTierCold = "cold"
 )
 
 // Index contains all known blocks and markers of a tenant.
@@ -91,6 +95,19 @@ type Block struct {
 
 	// Block's compactor shard ID, copied from tsdb.CompactorShardIDExternalLabel label.
 	CompactorShardID string `json:"compactor_shard_id,omitempty"`
+
+	// Tier is the storage tier the block is stored in. Empty if the block is stored in the hot storage.
+	Tier string `json:"tier,omitempty"`
+
+	// Resolution is the downsampling resolution of the block, in milliseconds. 0 if the block is not downsampled.
+	Resolution int64 `json:"resolution,omitempty"`
+
+	// OutOfOrder is true if the block contains out-of-order data, based on the tsdb.OutOfOrderExternalLabel label.
+	OutOfOrder bool `json:"out_of_order,omitempty"`
+
+	// Labels holds the block's external labels, except the ones already stored in other fields
+	// and the deprecated ones, in order to keep the index small.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // Within returns whether the block contains samples within the provided range.
@@ -117,11 +134,31 @@ func (m *Block) ThanosMeta() *metadata.Meta {
 		},
 		Thanos: metadata.Thanos{
 			Version:      metadata.ThanosVersion1,
+			Downsample:   metadata.ThanosDownsample{Resolution: m.Resolution},
 			SegmentFiles: m.thanosMetaSegmentFiles(),
+			Labels:       m.thanosMetaLabels(),
 		},
 	}
 }
 
+func (m *Block) thanosMetaLabels() map[string]string {
+	if len(m.Labels) == 0 && m.CompactorShardID == "" && !m.OutOfOrder {
+		return nil
+	}
+
+	labels := make(map[string]string, len(m.Labels)+2)
+	for name, value := range m.Labels {
+		labels[name] = value
+	}
+	if m.CompactorShardID != "" {
+		labels[mimir_tsdb.CompactorShardIDExternalLabel] = m.CompactorShardID
+	}
+	if m.OutOfOrder {
+		labels[mimir_tsdb.OutOfOrderExternalLabel] = mimir_tsdb.OutOfOrderExternalLabelValue
+	}
+	return labels
+}
+
 func (m *Block) thanosMetaSegmentFiles() (files []string) {
 	if m.SegmentsFormat == SegmentsFormat1Based6Digits {
 		for i := 1; i <= m.SegmentsNum; i++ {
@@ -154,7 +191,26 @@ func BlockFromThanosMeta(meta metadata.Meta) *Block {
 		SegmentsFormat:   segmentsFormat,
 		SegmentsNum:      segmentsNum,
 		CompactorShardID: meta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+		Resolution:       meta.Thanos.Downsample.Resolution,
+		OutOfOrder:       meta.Thanos.Labels[mimir_tsdb.OutOfOrderExternalLabel] == mimir_tsdb.OutOfOrderExternalLabelValue,
+		Labels:           blockLabelsFromThanosMeta(meta),
+	}
+}
+
+func blockLabelsFromThanosMeta(meta metadata.Meta) map[string]string {
+	var labels map[string]string
+	for name, value := range meta.Thanos.Labels {
+		switch name {
+		case mimir_tsdb.CompactorShardIDExternalLabel, mimir_tsdb.OutOfOrderExternalLabel,
+			mimir_tsdb.DeprecatedTenantIDExternalLabel, mimir_tsdb.DeprecatedIngesterIDExternalLabel, mimir_tsdb.DeprecatedShardIDExternalLabel:
+			continue
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[name] = value
 	}
+	return labels
 }
 
 func detectBlockSegmentsFormat(meta metadata.Meta) (string, int) {
@@ -226,6 +226,7 @@ func TestBlockFromThanosMeta(t *testing.T) {
 				ID:      blockID,
 				MinTime: 10,
 				MaxTime: 20,
+				Labels:  map[string]string{"a": "b", "c": "d"},
 			},
 		},
 		"meta.json with external labels, with compactor shard ID": {
@@ -248,6 +249,45 @@ func TestBlockFromThanosMeta(t *testing.T) {
 				MinTime:          10,
 				MaxTime:          20,
 				CompactorShardID: "10_of_20",
+				Labels:           map[string]string{"a": "b", "c": "d"},
+			},
+		},
+		"meta.json of a downsampled block": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+				},
+				Thanos: metadata.Thanos{
+					Downsample: metadata.ThanosDownsample{Resolution: 300000},
+				},
+			},
+			expected: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				Resolution: 300000,
+			},
+		},
+		"meta.json of an out-of-order block": {
+			meta: metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+				},
+				Thanos: metadata.Thanos{
+					Labels: map[string]string{
+						mimir_tsdb.OutOfOrderExternalLabel: mimir_tsdb.OutOfOrderExternalLabelValue,
+					},
+				},
+			},
+			expected: Block{
+				ID:         blockID,
+				MinTime:    10,
+				MaxTime:    20,
+				OutOfOrder: true,
 			},
 		},
 		"meta.json with external labels, with invalid shard ID": {
@@ -270,6 +310,7 @@ func TestBlockFromThanosMeta(t *testing.T) {
 				MinTime:          10,
 				MaxTime:          20,
 				CompactorShardID: "some weird value",
+				Labels:           map[string]string{"a": "b", "c": "d"},
 			},
 		},
 	}
@@ -378,6 +419,32 @@ func TestBlock_ThanosMeta(t *testing.T) {
 				},
 			},
 		},
+		"block with external labels": {
+			block: Block{
+				ID:               blockID,
+				MinTime:          10,
+				MaxTime:          20,
+				CompactorShardID: "1_of_4",
+				OutOfOrder:       true,
+				Labels:           map[string]string{"a": "b"},
+			},
+			expected: &metadata.Meta{
+				BlockMeta: tsdb.BlockMeta{
+					ULID:    blockID,
+					MinTime: 10,
+					MaxTime: 20,
+					Version: metadata.TSDBVersion1,
+				},
+				Thanos: metadata.Thanos{
+					Version: metadata.ThanosVersion1,
+					Labels: map[string]string{
+						"a":                                      "b",
+						mimir_tsdb.CompactorShardIDExternalLabel: "1_of_4",
+						mimir_tsdb.OutOfOrderExternalLabel:       mimir_tsdb.OutOfOrderExternalLabelValue,
+					},
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
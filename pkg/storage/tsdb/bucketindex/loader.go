@@ -20,6 +20,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
 	"github.com/grafana/mimir/pkg/util"
 )
 
@@ -38,7 +39,8 @@ type LoaderConfig struct {
 
 // Loader is responsible to lazy load bucket indexes and, once loaded for the first time,
 // keep them updated in background. Loaded indexes are automatically offloaded once the
-// idle timeout expires.
+// idle timeout expires. A loaded index is never replaced by an older one, based on its
+// updated_at, when it's kept updated.
 type Loader struct {
 	services.Service
 
@@ -198,15 +200,32 @@ func (l *Loader) updateCachedIndex(ctx context.Context, userID string) {
 	readCtx, cancel := context.WithTimeout(ctx, readIndexTimeout)
 	defer cancel()
 
+	l.indexesMx.RLock()
+	loaded := l.indexes[userID].index
+	l.indexesMx.RUnlock()
+
 	l.loadAttempts.Inc()
 	startTime := time.Now()
 	idx, err := ReadIndex(readCtx, l.bkt, userID, l.cfgProvider, l.logger)
+
+	// The bucket index may be read through the caching bucket, which can return an index older than
+	// the loaded one, cached before the loaded one was read. In such case, the index is read again
+	// bypassing the cache lookup, which also refreshes the cached index.
+	if err == nil && loaded != nil && idx.UpdatedAt < loaded.UpdatedAt {
+		level.Debug(l.logger).Log("msg", "read bucket index older than the loaded one, reading it again bypassing the cache", "user", userID, "read_updated_at", idx.UpdatedAt, "loaded_updated_at", loaded.UpdatedAt)
+		idx, err = ReadIndex(bucketcache.WithCacheLookupDisabled(readCtx), l.bkt, userID, l.cfgProvider, l.logger)
+	}
 	if err != nil && !errors.Is(err, ErrIndexNotFound) {
 		l.loadFailures.Inc()
 		level.Warn(l.logger).Log("msg", "unable to update bucket index", "user", userID, "err", err)
 		return
 	}
 
+	// Never go back to an index older than the loaded one.
+	if idx != nil && loaded != nil && idx.UpdatedAt < loaded.UpdatedAt {
+		idx = loaded
+	}
+
 	l.loadDuration.Observe(time.Since(startTime).Seconds())
 
 	// We cache it either it was successfully refreshed or wasn't found. An use case for caching the ErrIndexNotFound
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/services"
 	"github.com/grafana/dskit/test"
 	"github.com/oklog/ulid"
@@ -22,6 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
 	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
 )
 
@@ -577,6 +579,66 @@ func TestLoader_ShouldOffloadIndexIfIdleTimeoutIsReachedDuringBackgroundUpdates(
 	))
 }
 
+func TestLoader_ShouldNotGoBackToAnOlderIndexOnBackgroundUpdates(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+
+	// Read the bucket index through the caching bucket.
+	indexCache := cache.NewMockCache()
+	cachingCfg := bucketcache.NewCachingBucketConfig()
+	cachingCfg.CacheGet("bucket-index", indexCache, func(name string) bool { return strings.HasSuffix(name, IndexCompressedFilename) }, 1024*1024, time.Hour, 0, 0)
+	cachingBkt, err := bucketcache.NewCachingBucket(bkt, cachingCfg, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	newIndex := func(updatedAt time.Time) *Index {
+		return &Index{
+			Version:   IndexVersion1,
+			Blocks:    Blocks{{ID: ulid.MustNew(uint64(updatedAt.UnixMilli()), nil), MinTime: 10, MaxTime: 20}},
+			UpdatedAt: updatedAt.Unix(),
+		}
+	}
+
+	now := time.Now()
+	oldIdx := newIndex(now.Add(-time.Hour))
+	newIdx := newIndex(now)
+
+	loader := NewLoader(prepareLoaderConfig(), cachingBkt, nil, log.NewNopLogger(), nil)
+
+	require.NoError(t, WriteIndex(ctx, bkt, userID, nil, newIdx))
+	actualIdx, err := loader.GetIndex(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, newIdx, actualIdx)
+
+	// Store the old index in the cache, like another querier could have done before the index was updated.
+	require.NoError(t, WriteIndex(ctx, bkt, userID, nil, oldIdx))
+	_, err = ReadIndex(bucketcache.WithCacheLookupDisabled(ctx), cachingBkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, WriteIndex(ctx, bkt, userID, nil, newIdx))
+
+	// The old cached index is ignored, and refreshed in the cache.
+	loader.updateCachedIndex(ctx, userID)
+
+	actualIdx, err = loader.GetIndex(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, newIdx, actualIdx)
+
+	cachedIdx, err := ReadIndex(ctx, cachingBkt, userID, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, newIdx, cachedIdx)
+
+	// The loaded index is kept even if the bucket index is older.
+	require.NoError(t, WriteIndex(ctx, bkt, userID, nil, oldIdx))
+	indexCache.Flush()
+
+	loader.updateCachedIndex(ctx, userID)
+
+	actualIdx, err = loader.GetIndex(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, newIdx, actualIdx)
+}
+
 func prepareLoaderConfig() LoaderConfig {
 	return LoaderConfig{
 		CheckInterval:         time.Minute,
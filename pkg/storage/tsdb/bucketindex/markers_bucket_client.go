@@ -15,6 +15,7 @@ import (
 	"github.com/oklog/ulid"
 	"github.com/thanos-io/objstore"
 
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 )
@@ -28,6 +29,11 @@ type globalMarkersBucket struct {
 // BucketWithGlobalMarkers wraps the input bucket into a bucket which also keeps track of markers
 // in the global markers location.
 func BucketWithGlobalMarkers(b objstore.Bucket) objstore.Bucket {
+	// Markers are always uploaded to the hot storage, so there's no need to wrap the cold one.
+	if tiered, ok := b.(*mimir_tsdb.TieredBucket); ok {
+		return mimir_tsdb.NewTieredBucket(BucketWithGlobalMarkers(tiered.Hot()), tiered.Cold())
+	}
+
 	return &globalMarkersBucket{
 		parent: b,
 	}
@@ -57,3 +57,23 @@ func TestIsNoCompactMarkFilename(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, expected, actual)
 }
+
+func TestBlockReplacementMarkFilepath(t *testing.T) {
+	id := ulid.MustNew(1, nil)
+
+	assert.Equal(t, "markers/"+id.String()+"-replacement-mark.json", BlockReplacementMarkFilepath(id))
+}
+
+func TestIsBlockReplacementMarkFilename(t *testing.T) {
+	expected := ulid.MustNew(1, nil)
+
+	_, ok := IsBlockReplacementMarkFilename("xxx-replacement-mark.json")
+	assert.False(t, ok)
+
+	_, ok = IsBlockReplacementMarkFilename(expected.String() + "-deletion-mark.json")
+	assert.False(t, ok)
+
+	actual, ok := IsBlockReplacementMarkFilename(expected.String() + "-replacement-mark.json")
+	assert.True(t, ok)
+	assert.Equal(t, expected, actual)
+}
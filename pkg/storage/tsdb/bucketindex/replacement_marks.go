@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucketindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+)
+
+const (
+	// BlockReplacementMarkFilename is the name of the mark uploaded by the compactor, in the bucket markers
+	// location, for each block produced by a compaction. The mark is used by store-gateways to find out
+	// about new blocks before the bucket index is updated.
+	BlockReplacementMarkFilename = "replacement-mark.json"
+
+	// BlockReplacementMarkVersion1 is the current supported version of the replacement mark file.
+	BlockReplacementMarkVersion1 = 1
+)
+
+// BlockReplacementMark stores the ID of a block produced by a compaction and the blocks it replaces.
+type BlockReplacementMark struct {
+	// ID of the new block.
+	ID ulid.ULID `json:"id"`
+	// Version of the file.
+	Version int `json:"version"`
+	// ReplacedBlocks are the IDs of the source blocks the new block has been compacted from.
+	ReplacedBlocks []ulid.ULID `json:"replaced_blocks"`
+
+	// CreationTime is a unix timestamp of when the mark was created.
+	CreationTime int64 `json:"creation_time"`
+}
+
+// BlockReplacementMarkFilepath returns the path, relative to the tenant's bucket location,
+// of a block replacement mark in the bucket markers location.
+func BlockReplacementMarkFilepath(blockID ulid.ULID) string {
+	return markFilepath(blockID, BlockReplacementMarkFilename)
+}
+
+// IsBlockReplacementMarkFilename returns whether the input filename matches the expected pattern
+// of block replacement marks stored in the markers location.
+func IsBlockReplacementMarkFilename(name string) (ulid.ULID, bool) {
+	return isMarkFilename(name, BlockReplacementMarkFilename)
+}
+
+// WriteBlockReplacementMark uploads a replacement mark for the input block to the user bucket.
+func WriteBlockReplacementMark(ctx context.Context, userBkt objstore.Bucket, blockID ulid.ULID, replaced []ulid.ULID) error {
+	data, err := json.Marshal(BlockReplacementMark{
+		ID:             blockID,
+		Version:        BlockReplacementMarkVersion1,
+		ReplacedBlocks: replaced,
+		CreationTime:   time.Now().Unix(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encode replacement mark")
+	}
+
+	return errors.Wrap(userBkt.Upload(ctx, BlockReplacementMarkFilepath(blockID), bytes.NewReader(data)), "upload replacement mark")
+}
+
+// ListBlockReplacementMarks returns the IDs of the blocks having a replacement mark in the user bucket.
+// The content of the marks is not read.
+func ListBlockReplacementMarks(ctx context.Context, userBkt objstore.BucketReader) ([]ulid.ULID, error) {
+	var ids []ulid.ULID
+
+	err := userBkt.Iter(ctx, MarkersPathname+"/", func(name string) error {
+		if id, ok := IsBlockReplacementMarkFilename(path.Base(name)); ok {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+
+	return ids, errors.Wrap(err, "list block replacement marks")
+}
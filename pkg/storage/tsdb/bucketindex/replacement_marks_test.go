@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucketindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestWriteAndListBlockReplacementMarks(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	newBlock := ulid.MustNew(3, nil)
+	replaced := []ulid.ULID{ulid.MustNew(1, nil), ulid.MustNew(2, nil)}
+
+	// Other markers must be ignored.
+	require.NoError(t, bkt.Upload(ctx, BlockDeletionMarkFilepath(replaced[0]), bytes.NewReader([]byte("{}"))))
+	require.NoError(t, WriteBlockReplacementMark(ctx, bkt, newBlock, replaced))
+
+	ids, err := ListBlockReplacementMarks(ctx, bkt)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{newBlock}, ids)
+
+	r, err := bkt.Get(ctx, BlockReplacementMarkFilepath(newBlock))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	mark := BlockReplacementMark{}
+	require.NoError(t, json.Unmarshal(data, &mark))
+	assert.Equal(t, newBlock, mark.ID)
+	assert.Equal(t, BlockReplacementMarkVersion1, mark.Version)
+	assert.Equal(t, replaced, mark.ReplacedBlocks)
+	assert.NotZero(t, mark.CreationTime)
+}
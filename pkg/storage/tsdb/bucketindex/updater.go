@@ -20,6 +20,7 @@ import (
 	"github.com/thanos-io/objstore"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 	util_log "github.com/grafana/mimir/pkg/util/log"
@@ -36,13 +37,24 @@ var (
 type Updater struct {
 	bkt    objstore.InstrumentedBucket
 	logger log.Logger
+
+	// The hot and cold storage clients, set only if the input bucket is a tiered bucket.
+	hotBkt  objstore.InstrumentedBucket
+	coldBkt objstore.InstrumentedBucket
 }
 
 func NewUpdater(bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) *Updater {
-	return &Updater{
+	w := &Updater{
 		bkt:    bucket.NewUserBucketClient(userID, bkt, cfgProvider),
 		logger: util_log.WithUserID(userID, logger),
 	}
+
+	if tiered, ok := bkt.(*mimir_tsdb.TieredBucket); ok {
+		w.hotBkt = bucket.NewUserBucketClient(userID, tiered.Hot(), cfgProvider)
+		w.coldBkt = bucket.NewUserBucketClient(userID, tiered.Cold(), cfgProvider)
+	}
+
+	return w
 }
 
 // UpdateIndex generates the bucket index and returns it, without storing it to the storage.
@@ -90,9 +102,21 @@ func (w *Updater) updateBlocks(ctx context.Context, old []*Block) (blocks []*Blo
 		return nil, nil, errors.Wrap(err, "list blocks")
 	}
 
+	coldBlocks, err := w.listColdBlocks(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Since blocks are immutable, all blocks already existing in the index can just be copied.
+	// The only exception is the tier, which changes when a block is moved to the cold storage.
 	for _, b := range old {
 		if _, ok := discovered[b.ID]; ok {
+			if tier := blockTier(b.ID, coldBlocks); b.Tier != tier {
+				updated := *b
+				updated.Tier = tier
+				b = &updated
+			}
+
 			blocks = append(blocks, b)
 			delete(discovered, b.ID)
 		}
@@ -104,6 +128,7 @@ func (w *Updater) updateBlocks(ctx context.Context, old []*Block) (blocks []*Blo
 	for id := range discovered {
 		b, err := w.updateBlockIndexEntry(ctx, id)
 		if err == nil {
+			b.Tier = blockTier(id, coldBlocks)
 			blocks = append(blocks, b)
 			continue
 		}
@@ -124,6 +149,60 @@ func (w *Updater) updateBlocks(ctx context.Context, old []*Block) (blocks []*Blo
 	return blocks, partials, nil
 }
 
+// listColdBlocks returns the blocks stored in the cold storage, or nil if the cold storage is not used.
+func (w *Updater) listColdBlocks(ctx context.Context) (map[ulid.ULID]struct{}, error) {
+	if w.coldBkt == nil {
+		return nil, nil
+	}
+
+	listBlocks := func(bkt objstore.Bucket) (map[ulid.ULID]struct{}, error) {
+		ids := map[ulid.ULID]struct{}{}
+		err := bkt.Iter(ctx, "", func(name string) error {
+			if id, ok := block.IsBlockDir(name); ok {
+				ids[id] = struct{}{}
+			}
+			return nil
+		})
+		return ids, err
+	}
+
+	coldBlocks, err := listBlocks(w.coldBkt)
+	if err != nil {
+		return nil, errors.Wrap(err, "list blocks in the cold storage")
+	}
+
+	hotBlocks, err := listBlocks(w.hotBkt)
+	if err != nil {
+		return nil, errors.Wrap(err, "list blocks in the hot storage")
+	}
+
+	// A block can be found in both storages while it's being moved, or because markers
+	// have been uploaded to the hot storage after the block has been moved. The block is
+	// stored in the cold storage once its meta.json has been removed from the hot storage.
+	for id := range coldBlocks {
+		if _, ok := hotBlocks[id]; !ok {
+			continue
+		}
+
+		exists, err := w.hotBkt.Exists(ctx, path.Join(id.String(), block.MetaFilename))
+		if err != nil {
+			return nil, errors.Wrapf(err, "check block meta file in the hot storage: %s", id)
+		}
+		if exists {
+			delete(coldBlocks, id)
+		}
+	}
+
+	return coldBlocks, nil
+}
+
+func blockTier(id ulid.ULID, coldBlocks map[ulid.ULID]struct{}) string {
+	if _, ok := coldBlocks[id]; ok {
+		return BlockTierCold
+	}
+	return ""
+}
+
 func (w *Updater) updateBlockIndexEntry(ctx context.Context, id ulid.ULID) (*Block, error) {
 	metaFile := path.Join(id.String(), block.MetaFilename)
 
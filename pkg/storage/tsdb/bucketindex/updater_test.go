@@ -229,6 +229,63 @@ func TestUpdater_UpdateIndexFromVersion1ToVersion2(t *testing.T) {
 		[]*metadata.DeletionMark{})
 }
 
+func TestUpdater_UpdateIndex_ShouldSetTheTierOfBlocksInTheColdStorage(t *testing.T) {
+	const userID = "user-1"
+
+	hotBkt, _ := testutil.PrepareFilesystemBucket(t)
+	coldBkt, _ := testutil.PrepareFilesystemBucket(t)
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	bkt := BucketWithGlobalMarkers(mimir_tsdb.NewTieredBucket(hotBkt, coldBkt))
+	block1 := testutil.MockStorageBlockWithExtLabels(t, hotBkt, userID, 10, 20, nil)
+	block2 := testutil.MockStorageBlockWithExtLabels(t, coldBkt, userID, 20, 30, nil)
+	block3 := testutil.MockStorageBlockWithExtLabels(t, hotBkt, userID, 30, 40, nil)
+
+	// Add a deletion mark to the block in the cold storage. The mark is uploaded to the hot storage.
+	block2Mark := testutil.MockStorageDeletionMark(t, bkt, userID, block2.BlockMeta)
+
+	w := NewUpdater(bkt, userID, nil, logger)
+	idx, _, err := w.UpdateIndex(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, idx.BlockDeletionMarks, 1)
+	assert.Equal(t, block2Mark.ID, idx.BlockDeletionMarks[0].ID)
+
+	assert.Equal(t, map[ulid.ULID]string{
+		block1.ULID: "",
+		block2.ULID: BlockTierCold,
+		block3.ULID: "",
+	}, blocksTier(idx))
+
+	// Move block3 to the cold storage and update the index.
+	for _, file := range []string{"index", block.MetaFilename} {
+		name := path.Join(userID, block3.ULID.String(), file)
+
+		r, err := hotBkt.Get(ctx, name)
+		require.NoError(t, err)
+		require.NoError(t, coldBkt.Upload(ctx, name, r))
+		require.NoError(t, r.Close())
+		require.NoError(t, hotBkt.Delete(ctx, name))
+	}
+
+	idx, _, err = w.UpdateIndex(ctx, idx)
+	require.NoError(t, err)
+	assert.Equal(t, map[ulid.ULID]string{
+		block1.ULID: "",
+		block2.ULID: BlockTierCold,
+		block3.ULID: BlockTierCold,
+	}, blocksTier(idx))
+}
+
+func blocksTier(idx *Index) map[ulid.ULID]string {
+	tiers := map[ulid.ULID]string{}
+	for _, b := range idx.Blocks {
+		tiers[b.ID] = b.Tier
+	}
+	return tiers
+}
+
 func getBlockUploadedAt(t testing.TB, bkt objstore.Bucket, userID string, blockID ulid.ULID) int64 {
 	metaFile := path.Join(userID, blockID.String(), block.MetaFilename)
 
@@ -251,6 +308,7 @@ func assertBucketIndexEqual(t testing.TB, idx *Index, bkt objstore.Bucket, userI
 			MaxTime:          b.MaxTime,
 			UploadedAt:       getBlockUploadedAt(t, bkt, userID, b.ULID),
 			CompactorShardID: b.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+			Labels:           blockLabelsFromThanosMeta(b),
 		})
 	}
 
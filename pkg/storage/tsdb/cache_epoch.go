@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// CacheEpochsDir is the directory storing the cache epochs, relative to the user-specific prefix. Each cache epoch
+// is stored as an empty object named "<scope>-<epoch>", where the scope is either CacheEpochTenantScope or a block ID.
+const CacheEpochsDir = "markers/cache-epochs"
+
+// CacheEpochTenantScope is the scope of the epoch of the cache keys of all the tenant's blocks.
+const CacheEpochTenantScope = "tenant"
+
+// CacheEpoch holds the epochs of the cache keys of a tenant. The store-gateway and the querier include
+// the epochs in the keys of the chunks, index and metadata caches, so bumping an epoch invalidates the
+// entries cached for the whole tenant or for a single block.
+type CacheEpoch struct {
+	// Epoch of the cache keys of all the tenant's blocks.
+	Tenant uint64 `json:"tenant,omitempty"`
+
+	// Epochs of the cache keys of single blocks.
+	Blocks map[ulid.ULID]uint64 `json:"blocks,omitempty"`
+}
+
+// TenantKeyEpoch returns the epoch to include in the cache keys of the tenant's objects not belonging
+// to a block, or an empty string if the tenant caches have never been invalidated.
+func (e *CacheEpoch) TenantKeyEpoch() string {
+	if e == nil || e.Tenant == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", e.Tenant)
+}
+
+// BlockKeyEpoch returns the epoch to include in the cache keys of the given block, or an empty string
+// if the caches of the block have never been invalidated.
+func (e *CacheEpoch) BlockKeyEpoch(blockID ulid.ULID) string {
+	if e == nil {
+		return ""
+	}
+
+	blockEpoch := e.Blocks[blockID]
+	if e.Tenant == 0 && blockEpoch == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", e.Tenant, blockEpoch)
+}
+
+// ObjectKeyEpoch returns the epoch to include in the cache keys of the object with the given name, relative
+// to the tenant location in the bucket.
+func (e *CacheEpoch) ObjectKeyEpoch(name string) string {
+	dir := name
+	if idx := strings.Index(name, "/"); idx >= 0 {
+		dir = name[:idx]
+	}
+	if blockID, err := ulid.Parse(dir); err == nil {
+		return e.BlockKeyEpoch(blockID)
+	}
+	return e.TenantKeyEpoch()
+}
+
+// Returns the cache epoch of given user. If it doesn't exist, returns an empty epoch, and no error.
+func ReadCacheEpoch(ctx context.Context, bkt objstore.BucketReader, userID string) (*CacheEpoch, error) {
+	epoch := &CacheEpoch{}
+	epochsDir := path.Join(userID, CacheEpochsDir)
+
+	err := bkt.Iter(ctx, epochsDir, func(name string) error {
+		scope, value, ok := parseCacheEpochObject(path.Base(name))
+		if !ok {
+			level.Warn(util_log.Logger).Log("msg", "ignoring invalid cache epoch object", "object", name)
+			return nil
+		}
+
+		if scope == CacheEpochTenantScope {
+			if value > epoch.Tenant {
+				epoch.Tenant = value
+			}
+			return nil
+		}
+
+		blockID, err := ulid.Parse(scope)
+		if err != nil {
+			level.Warn(util_log.Logger).Log("msg", "ignoring invalid cache epoch object", "object", name)
+			return nil
+		}
+		if epoch.Blocks == nil {
+			epoch.Blocks = map[ulid.ULID]uint64{}
+		}
+		if value > epoch.Blocks[blockID] {
+			epoch.Blocks[blockID] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list cache epoch objects: %s", epochsDir)
+	}
+
+	return epoch, nil
+}
+
+// BumpCacheEpoch bumps the cache epoch of the given block, or of the whole tenant if blockID is nil,
+// and returns the updated cache epoch of the tenant.
+//
+// Each bump uploads a new object, named after a new epoch, instead of updating the previous one, so that
+// concurrent bumps can't override each other. The epochs are time-based, and greater than the previous epoch
+// of the scope, so they're unique and monotonically increasing. The objects of the previous epochs of the
+// scope are then deleted, on a best-effort basis.
+func BumpCacheEpoch(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, blockID *ulid.ULID) (*CacheEpoch, error) {
+	epoch, err := ReadCacheEpoch(ctx, bkt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, previous := CacheEpochTenantScope, epoch.Tenant
+	if blockID != nil {
+		scope, previous = blockID.String(), epoch.Blocks[*blockID]
+	}
+
+	value := uint64(time.Now().UnixNano())
+	if value <= previous {
+		value = previous + 1
+	}
+
+	userBkt := bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+	if err := userBkt.Upload(ctx, cacheEpochObject(scope, value), bytes.NewReader(nil)); err != nil {
+		return nil, errors.Wrap(err, "upload cache epoch")
+	}
+
+	// Delete the objects of the previous epochs of the scope, which are no longer used.
+	err = userBkt.Iter(ctx, CacheEpochsDir, func(name string) error {
+		if s, v, ok := parseCacheEpochObject(path.Base(name)); ok && s == scope && v < value {
+			return userBkt.Delete(ctx, name)
+		}
+		return nil
+	})
+	if err != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to delete the previous cache epochs", "user", userID, "scope", scope, "err", err)
+	}
+
+	if blockID == nil {
+		epoch.Tenant = value
+	} else {
+		if epoch.Blocks == nil {
+			epoch.Blocks = map[ulid.ULID]uint64{}
+		}
+		epoch.Blocks[*blockID] = value
+	}
+	return epoch, nil
+}
+
+// cacheEpochObject returns the name of the object storing the epoch of the given scope, relative to the
+// user-specific prefix.
+func cacheEpochObject(scope string, epoch uint64) string {
+	return path.Join(CacheEpochsDir, fmt.Sprintf("%s-%d", scope, epoch))
+}
+
+// parseCacheEpochObject returns the scope and epoch of the cache epoch object with the given base name.
+func parseCacheEpochObject(name string) (scope string, epoch uint64, ok bool) {
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return "", 0, false
+	}
+
+	epoch, err := strconv.ParseUint(name[idx+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return name[:idx], epoch, true
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestCacheEpoch(t *testing.T) {
+	const userID = "user"
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	block1 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0")
+	block2 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E1")
+
+	// The cache keys are not versioned if the tenant caches have never been invalidated.
+	epoch, err := ReadCacheEpoch(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "", epoch.TenantKeyEpoch())
+	assert.Equal(t, "", epoch.BlockKeyEpoch(block1))
+
+	epoch, err = BumpCacheEpoch(ctx, bkt, userID, nil, &block1)
+	require.NoError(t, err)
+	block1Epoch := epoch.Blocks[block1]
+	assert.NotZero(t, block1Epoch)
+	assert.Equal(t, "", epoch.TenantKeyEpoch())
+	assert.Equal(t, "", epoch.BlockKeyEpoch(block2))
+
+	epoch, err = BumpCacheEpoch(ctx, bkt, userID, nil, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, "", epoch.TenantKeyEpoch())
+	assert.Equal(t, block1Epoch, epoch.Blocks[block1])
+	assert.NotEqual(t, epoch.BlockKeyEpoch(block1), epoch.BlockKeyEpoch(block2))
+
+	// The bumped epoch is read back from the bucket.
+	read, err := ReadCacheEpoch(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.Equal(t, epoch, read)
+
+	// Bumping the epoch of a scope again increases it, and deletes the object of the previous epoch.
+	epoch, err = BumpCacheEpoch(ctx, bkt, userID, nil, &block1)
+	require.NoError(t, err)
+	assert.Greater(t, epoch.Blocks[block1], block1Epoch)
+	assert.Equal(t, 2, countObjects(t, bkt, path.Join(userID, CacheEpochsDir)))
+
+	// The invalid cache epoch objects are ignored.
+	require.NoError(t, bkt.Upload(ctx, path.Join(userID, CacheEpochsDir, "invalid"), bytes.NewReader(nil)))
+	read, err = ReadCacheEpoch(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.Equal(t, epoch, read)
+}
+
+func TestCacheEpoch_ConcurrentBumps(t *testing.T) {
+	const userID = "user"
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	blocks := []ulid.ULID{
+		ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0"),
+		ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E1"),
+		ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E2"),
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range blocks {
+		blockID := blocks[i]
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := BumpCacheEpoch(ctx, bkt, userID, nil, &blockID)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := BumpCacheEpoch(ctx, bkt, userID, nil, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// None of the concurrent bumps is lost.
+	epoch, err := ReadCacheEpoch(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.NotZero(t, epoch.Tenant)
+	for _, blockID := range blocks {
+		assert.NotZero(t, epoch.Blocks[blockID])
+	}
+}
+
+func TestCacheEpoch_ObjectKeyEpoch(t *testing.T) {
+	block1 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0")
+	block2 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E1")
+
+	epoch := &CacheEpoch{}
+	assert.Equal(t, "", epoch.ObjectKeyEpoch(block1.String()+"/meta.json"))
+	assert.Equal(t, "", epoch.ObjectKeyEpoch("bucket-index.json.gz"))
+
+	epoch = &CacheEpoch{Tenant: 2, Blocks: map[ulid.ULID]uint64{block1: 1}}
+	assert.Equal(t, "2.1", epoch.ObjectKeyEpoch(block1.String()+"/chunks/000001"))
+	assert.Equal(t, "2.0", epoch.ObjectKeyEpoch(block2.String()+"/index"))
+	assert.Equal(t, "2", epoch.ObjectKeyEpoch("bucket-index.json.gz"))
+}
+
+func countObjects(t *testing.T, bkt objstore.BucketReader, dir string) int {
+	count := 0
+	require.NoError(t, bkt.Iter(context.Background(), dir, func(string) error {
+		count++
+		return nil
+	}))
+	return count
+}
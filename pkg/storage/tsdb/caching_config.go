@@ -76,6 +76,8 @@ type MetadataCacheConfig struct {
 	BlockIndexAttributesTTL time.Duration `yaml:"block_index_attributes_ttl" category:"advanced"`
 	BucketIndexContentTTL   time.Duration `yaml:"bucket_index_content_ttl" category:"advanced"`
 	BucketIndexMaxSize      int           `yaml:"bucket_index_max_size_bytes" category:"advanced"`
+	ExemplarsContentTTL     time.Duration `yaml:"exemplars_content_ttl" category:"experimental"`
+	ExemplarsMaxSize        int           `yaml:"exemplars_max_size_bytes" category:"experimental"`
 }
 
 func (cfg *MetadataCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
@@ -95,6 +97,8 @@ func (cfg *MetadataCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix
 	f.DurationVar(&cfg.BlockIndexAttributesTTL, prefix+"block-index-attributes-ttl", 168*time.Hour, "How long to cache attributes of the block index.")
 	f.DurationVar(&cfg.BucketIndexContentTTL, prefix+"bucket-index-content-ttl", 5*time.Minute, "How long to cache content of the bucket index.")
 	f.IntVar(&cfg.BucketIndexMaxSize, prefix+"bucket-index-max-size-bytes", 1*1024*1024, "Maximum size of bucket index content to cache in bytes. Caching will be skipped if the content exceeds this size. This is useful to avoid network round trip for large content if the configured caching backend has an hard limit on cached items size (in this case, you should set this limit to the same limit in the caching backend).")
+	f.DurationVar(&cfg.ExemplarsContentTTL, prefix+"exemplars-content-ttl", 24*time.Hour, "How long to cache content of the block exemplars file.")
+	f.IntVar(&cfg.ExemplarsMaxSize, prefix+"exemplars-max-size-bytes", 1*1024*1024, "Maximum size of block exemplars file content to cache in bytes. Caching will be skipped if the content exceeds this size. This is useful to avoid network round trip for large content if the configured caching backend has an hard limit on cached items size (in this case, you should set this limit to the same limit in the caching backend).")
 }
 
 func (cfg *MetadataCacheConfig) Validate() error {
@@ -118,6 +122,7 @@ func CreateCachingBucket(chunksCache cache.Cache, chunksConfig ChunksCacheConfig
 		cfg.CacheAttributes("metafile", metadataCache, isMetaFile, metadataConfig.MetafileAttributesTTL)
 		cfg.CacheAttributes("block-index", metadataCache, isBlockIndexFile, metadataConfig.BlockIndexAttributesTTL)
 		cfg.CacheGet("bucket-index", metadataCache, isBucketIndexFile, metadataConfig.BucketIndexMaxSize, metadataConfig.BucketIndexContentTTL /* do not cache exist / not exist: */, 0, 0)
+		cfg.CacheGet("exemplars", metadataCache, isBlockExemplarsFile, metadataConfig.ExemplarsMaxSize, metadataConfig.ExemplarsContentTTL /* do not cache exist / not exist: */, 0, 0)
 
 		codec := snappyIterCodec{bucketcache.JSONIterCodec{}}
 		cfg.CacheIter("tenants-iter", metadataCache, isTenantsDir, metadataConfig.TenantsListTTL, codec)
@@ -174,6 +179,16 @@ func isBlockIndexFile(name string) bool {
 	return err == nil
 }
 
+func isBlockExemplarsFile(name string) bool {
+	// Ensure the path ends with "<block id>/<exemplars filename>".
+	if !strings.HasSuffix(name, "/"+block.ExemplarsFilename) {
+		return false
+	}
+
+	_, err := ulid.Parse(filepath.Base(filepath.Dir(name)))
+	return err == nil
+}
+
 func isBucketIndexFile(name string) bool {
 	// TODO can't reference bucketindex because of a circular dependency. To be fixed.
 	return strings.HasSuffix(name, "/bucket-index.json.gz")
@@ -13,6 +13,7 @@ import (
 
 	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
 	"github.com/pkg/errors"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/wlog"
@@ -82,28 +83,60 @@ const (
 	consistencyDelayFlag                      = "blocks-storage.bucket-store.consistency-delay"
 	maxTSDBOpeningConcurrencyOnStartupFlag    = "blocks-storage.tsdb.max-tsdb-opening-concurrency-on-startup"
 	defaultMaxTSDBOpeningConcurrencyOnStartup = 10
+	walReplayLargeTenantThresholdBytesFlag    = "blocks-storage.tsdb.wal-replay-large-tenant-threshold-bytes"
 )
 
+// DefaultMetadataFilters is the default list of filters applied by the store-gateway to the blocks metadata.
+var DefaultMetadataFilters = []string{"consistency-delay", "ignore-blocks-within", "ignore-deletion-marks"}
+
 // Validation errors
 var (
-	errInvalidShipConcurrency       = errors.New("invalid TSDB ship concurrency")
-	errInvalidOpeningConcurrency    = errors.New("invalid TSDB opening concurrency")
-	errInvalidCompactionInterval    = errors.New("invalid TSDB compaction interval")
-	errInvalidCompactionConcurrency = errors.New("invalid TSDB compaction concurrency")
-	errInvalidWALSegmentSizeBytes   = errors.New("invalid TSDB WAL segment size bytes")
-	errInvalidWALReplayConcurrency  = errors.New("invalid TSDB WAL replay concurrency")
-	errInvalidStripeSize            = errors.New("invalid TSDB stripe size")
-	errInvalidStreamingBatchSize    = errors.New("invalid store-gateway streaming batch size")
-	errEmptyBlockranges             = errors.New("empty block ranges for TSDB")
+	errInvalidShipConcurrency               = errors.New("invalid TSDB ship concurrency")
+	errInvalidOpeningConcurrency            = errors.New("invalid TSDB opening concurrency")
+	errInvalidCompactionInterval            = errors.New("invalid TSDB compaction interval")
+	errInvalidCompactionConcurrency         = errors.New("invalid TSDB compaction concurrency")
+	errInvalidWALSegmentSizeBytes           = errors.New("invalid TSDB WAL segment size bytes")
+	errInvalidWALReplayConcurrency          = errors.New("invalid TSDB WAL replay concurrency")
+	errInvalidWALReplayLargeTenants         = errors.New("invalid TSDB WAL replay large tenants concurrency")
+	errSharedWALMemorySnapshot              = errors.New("the TSDB memory snapshot on shutdown can't be enabled when the shared WAL is enabled")
+	errInvalidStripeSize                    = errors.New("invalid TSDB stripe size")
+	errInvalidStreamingBatchSize            = errors.New("invalid store-gateway streaming batch size")
+	errInvalidExternalLabelsFilter          = errors.New("invalid store-gateway external labels filter, expected name=value")
+	errInvalidHedgedRequestsBudget          = errors.New("invalid store-gateway hedged requests budget, the value must be between 0 and 1")
+	errInvalidMaxConcurrentMemoryLimitRatio = errors.New("invalid store-gateway max concurrent memory limit ratio, the value must be between 0 and 1")
+	errEmptyBlockranges                     = errors.New("empty block ranges for TSDB")
+	errInvalidCompactionSlotsWindow         = errors.New("invalid TSDB head compaction slots window: must be between 0 and half of the smallest block range")
 )
 
 // BlocksStorageConfig holds the config information for the blocks storage.
 type BlocksStorageConfig struct {
 	Bucket      bucket.Config     `yaml:",inline"`
+	ColdStorage ColdStorageConfig `yaml:"cold_storage" doc:"description=This configures the cold storage, where the compactor moves the blocks older than -compactor.cold-storage-tiering-age."`
 	BucketStore BucketStoreConfig `yaml:"bucket_store" doc:"description=This configures how the querier and store-gateway discover and synchronize blocks stored in the bucket."`
 	TSDB        TSDBConfig        `yaml:"tsdb"`
 }
 
+// ColdStorageConfig holds the config of the cold storage, a second bucket storing the old blocks.
+type ColdStorageConfig struct {
+	Enabled bool          `yaml:"enabled" category:"experimental"`
+	Bucket  bucket.Config `yaml:",inline"`
+}
+
+// RegisterFlags registers the cold storage flags.
+func (cfg *ColdStorageConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
+	f.BoolVar(&cfg.Enabled, "blocks-storage.cold-storage.enabled", false, "If enabled, the blocks are read from both the blocks storage and the cold storage. The compactor moves the blocks older than -compactor.cold-storage-tiering-age to the cold storage.")
+	cfg.Bucket.RegisterFlagsWithPrefixAndDefaultDirectory("blocks-storage.cold-storage.", "cold-blocks", f, logger)
+}
+
+// Validate the config.
+func (cfg *ColdStorageConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	return errors.Wrap(cfg.Bucket.Validate(), "cold storage")
+}
+
 // DurationList is the block ranges for a tsdb
 type DurationList []time.Duration
 
@@ -144,6 +177,7 @@ func (d *DurationList) ToMilliseconds() []int64 {
 // RegisterFlags registers the TSDB flags
 func (cfg *BlocksStorageConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.Bucket.RegisterFlagsWithPrefixAndDefaultDirectory("blocks-storage.", "blocks", f, logger)
+	cfg.ColdStorage.RegisterFlags(f, logger)
 	cfg.BucketStore.RegisterFlags(f, logger)
 	cfg.TSDB.RegisterFlags(f)
 }
@@ -154,6 +188,10 @@ func (cfg *BlocksStorageConfig) Validate(logger log.Logger) error {
 		return err
 	}
 
+	if err := cfg.ColdStorage.Validate(); err != nil {
+		return err
+	}
+
 	if err := cfg.TSDB.Validate(logger); err != nil {
 		return err
 	}
@@ -173,6 +211,7 @@ type TSDBConfig struct {
 	HeadCompactionInterval    time.Duration `yaml:"head_compaction_interval" category:"advanced"`
 	HeadCompactionConcurrency int           `yaml:"head_compaction_concurrency" category:"advanced"`
 	HeadCompactionIdleTimeout time.Duration `yaml:"head_compaction_idle_timeout" category:"advanced"`
+	HeadCompactionSlotsWindow time.Duration `yaml:"head_compaction_slots_window" category:"experimental"`
 	HeadChunksWriteBufferSize int           `yaml:"head_chunks_write_buffer_size_bytes" category:"advanced"`
 	HeadChunksEndTimeVariance float64       `yaml:"head_chunks_end_time_variance" category:"experimental"`
 	StripeSize                int           `yaml:"stripe_size" category:"advanced"`
@@ -187,6 +226,13 @@ type TSDBConfig struct {
 	// Series hash cache.
 	SeriesHashCacheMaxBytes uint64 `yaml:"series_hash_cache_max_size_bytes" category:"advanced"`
 
+	// WAL replay of large tenants on startup.
+	WALReplayLargeTenantThresholdBytes uint64 `yaml:"wal_replay_large_tenant_threshold_bytes" category:"experimental"`
+	WALReplayLargeTenantsConcurrency   int    `yaml:"wal_replay_large_tenants_concurrency" category:"experimental"`
+
+	// Write-ahead log shared by all tenants.
+	SharedWALEnabled bool `yaml:"shared_wal_enabled" category:"experimental"`
+
 	// DeprecatedMaxTSDBOpeningConcurrencyOnStartup limits the number of concurrently opening TSDB's during startup.
 	DeprecatedMaxTSDBOpeningConcurrencyOnStartup int `yaml:"max_tsdb_opening_concurrency_on_startup" category:"deprecated"` // Deprecated. Remove in Mimir 2.10.
 
@@ -222,6 +268,9 @@ type TSDBConfig struct {
 	// BlockPostingsForMatchersCacheForce forces the usage of postings for matchers cache for all calls compacted blocks
 	// regardless of the `concurrent` param.
 	BlockPostingsForMatchersCacheForce bool `yaml:"block_postings_for_matchers_cache_force" category:"experimental"`
+
+	// BlockExemplarsEnabled controls whether the exemplars are stored in the blocks shipped to the storage.
+	BlockExemplarsEnabled bool `yaml:"block_exemplars_enabled" category:"experimental"`
 }
 
 // RegisterFlags registers the TSDBConfig flags.
@@ -240,12 +289,16 @@ func (cfg *TSDBConfig) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.HeadCompactionInterval, "blocks-storage.tsdb.head-compaction-interval", 1*time.Minute, "How frequently the ingester checks whether the TSDB head should be compacted and, if so, triggers the compaction. Mimir applies a jitter to the first check, while subsequent checks will happen at the configured interval. Block is only created if data covers smallest block range. The configured interval must be between 0 and 15 minutes.")
 	f.IntVar(&cfg.HeadCompactionConcurrency, "blocks-storage.tsdb.head-compaction-concurrency", 1, "Maximum number of tenants concurrently compacting TSDB head into a new block")
 	f.DurationVar(&cfg.HeadCompactionIdleTimeout, "blocks-storage.tsdb.head-compaction-idle-timeout", 1*time.Hour, "If TSDB head is idle for this duration, it is compacted. Note that up to 25% jitter is added to the value to avoid ingesters compacting concurrently. 0 means disabled.")
+	f.DurationVar(&cfg.HeadCompactionSlotsWindow, "blocks-storage.tsdb.head-compaction-slots-window", 0, "If greater than 0, the regular head compaction of each tenant is delayed to a deterministic wall-clock slot, computed by hashing the tenant and ingester IDs, within this window after the head becomes compactable. This spreads the head compactions of all tenants over the window instead of running them at the same time. The head of a tenant keeps the additional in-memory samples until its slot is reached. The window must not be greater than half of the smallest block range. 0 means disabled.")
 	f.IntVar(&cfg.HeadChunksWriteBufferSize, "blocks-storage.tsdb.head-chunks-write-buffer-size-bytes", chunks.DefaultWriteBufferSize, headChunkWriterBufferSizeHelp)
 	f.Float64Var(&cfg.HeadChunksEndTimeVariance, "blocks-storage.tsdb.head-chunks-end-time-variance", 0, headChunksEndTimeVarianceHelp)
 	f.IntVar(&cfg.StripeSize, "blocks-storage.tsdb.stripe-size", 16384, headStripeSizeHelp)
 	f.BoolVar(&cfg.WALCompressionEnabled, "blocks-storage.tsdb.wal-compression-enabled", false, "True to enable TSDB WAL compression.")
 	f.IntVar(&cfg.WALSegmentSizeBytes, "blocks-storage.tsdb.wal-segment-size-bytes", wlog.DefaultSegmentSize, "TSDB WAL segments files max size (bytes).")
 	f.IntVar(&cfg.WALReplayConcurrency, "blocks-storage.tsdb.wal-replay-concurrency", 0, "Maximum number of CPUs that can simultaneously processes WAL replay. If it is set to 0, then each TSDB is replayed with a concurrency equal to the number of CPU cores available on the machine. If set to a positive value it overrides the deprecated -"+maxTSDBOpeningConcurrencyOnStartupFlag+" option.")
+	f.Uint64Var(&cfg.WALReplayLargeTenantThresholdBytes, walReplayLargeTenantThresholdBytesFlag, 0, "TSDBs with a WAL larger than this size (in bytes) are considered large when opened on startup. The ingester opens the TSDBs of small tenants first, in ascending order of WAL size, while the TSDBs of large tenants are opened with a concurrency bounded by -blocks-storage.tsdb.wal-replay-large-tenants-concurrency. 0 to disable.")
+	f.IntVar(&cfg.WALReplayLargeTenantsConcurrency, "blocks-storage.tsdb.wal-replay-large-tenants-concurrency", 1, "Maximum number of TSDBs with a WAL larger than -"+walReplayLargeTenantThresholdBytesFlag+" concurrently opened on startup.")
+	f.BoolVar(&cfg.SharedWALEnabled, "blocks-storage.tsdb.shared-wal-enabled", false, "True to log the samples committed to the TSDBs to a single write-ahead log shared by all tenants, replacing the write-ahead log of each tenant's TSDB, which is disabled. This reduces the number of open files and fsyncs on ingesters hosting many small tenants. On startup, the TSDB heads are recovered replaying the shared write-ahead log only, so enabling or disabling this option discards the samples not compacted into blocks yet, unless the ingester has been shut down with -blocks-storage.tsdb.flush-blocks-on-shutdown enabled. Exemplars are persisted, while metadata is not. This option can't be enabled together with -blocks-storage.tsdb.memory-snapshot-on-shutdown.")
 	f.BoolVar(&cfg.FlushBlocksOnShutdown, "blocks-storage.tsdb.flush-blocks-on-shutdown", false, "True to flush blocks to storage on shutdown. If false, incomplete blocks will be reused after restart.")
 	f.DurationVar(&cfg.CloseIdleTSDBTimeout, "blocks-storage.tsdb.close-idle-tsdb-timeout", 13*time.Hour, "If TSDB has not received any data for this duration, and all blocks from TSDB have been shipped, TSDB is closed and deleted from local disk. If set to positive value, this value should be equal or higher than -querier.query-ingesters-within flag to make sure that TSDB is not closed prematurely, which could cause partial query results. 0 or negative value disables closing of idle TSDB.")
 	f.BoolVar(&cfg.MemorySnapshotOnShutdown, "blocks-storage.tsdb.memory-snapshot-on-shutdown", false, "True to enable snapshotting of in-memory TSDB data on disk when shutting down.")
@@ -257,6 +310,7 @@ func (cfg *TSDBConfig) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.BlockPostingsForMatchersCacheTTL, "blocks-storage.tsdb.block-postings-for-matchers-cache-ttl", 10*time.Second, "How long to cache postings for matchers in each compacted block queried from the ingester. 0 disables the cache and just deduplicates the in-flight calls.")
 	f.IntVar(&cfg.BlockPostingsForMatchersCacheSize, "blocks-storage.tsdb.block-postings-for-matchers-cache-size", 100, "Maximum number of entries in the cache for postings for matchers in each compacted block when TTL is greater than 0.")
 	f.BoolVar(&cfg.BlockPostingsForMatchersCacheForce, "blocks-storage.tsdb.block-postings-for-matchers-cache-force", false, "Force the cache to be used for postings for matchers in compacted blocks, even if it's not a concurrent (query-sharding) call.")
+	f.BoolVar(&cfg.BlockExemplarsEnabled, "blocks-storage.tsdb.block-exemplars-enabled", false, "True to store the in-memory exemplars in the blocks shipped to the storage. The exemplars are kept by the compactor, and are queried from the store-gateways for the whole blocks retention when -querier.block-exemplars-enabled is enabled.")
 }
 
 // Validate the config.
@@ -292,6 +346,10 @@ func (cfg *TSDBConfig) Validate(logger log.Logger) error {
 		return errEmptyBlockranges
 	}
 
+	if cfg.HeadCompactionSlotsWindow < 0 || cfg.HeadCompactionSlotsWindow > cfg.BlockRanges[0]/2 {
+		return errInvalidCompactionSlotsWindow
+	}
+
 	if cfg.WALSegmentSizeBytes <= 0 {
 		return errInvalidWALSegmentSizeBytes
 	}
@@ -300,6 +358,14 @@ func (cfg *TSDBConfig) Validate(logger log.Logger) error {
 		return errInvalidWALReplayConcurrency
 	}
 
+	if cfg.WALReplayLargeTenantThresholdBytes > 0 && cfg.WALReplayLargeTenantsConcurrency <= 0 {
+		return errInvalidWALReplayLargeTenants
+	}
+
+	if cfg.SharedWALEnabled && cfg.MemorySnapshotOnShutdown {
+		return errSharedWALMemorySnapshot
+	}
+
 	return nil
 }
 
@@ -316,19 +382,25 @@ func (cfg *TSDBConfig) IsBlocksShippingEnabled() bool {
 
 // BucketStoreConfig holds the config information for Bucket Stores used by the querier and store-gateway.
 type BucketStoreConfig struct {
-	SyncDir                    string              `yaml:"sync_dir"`
-	SyncInterval               time.Duration       `yaml:"sync_interval" category:"advanced"`
-	MaxConcurrent              int                 `yaml:"max_concurrent" category:"advanced"`
-	TenantSyncConcurrency      int                 `yaml:"tenant_sync_concurrency" category:"advanced"`
-	BlockSyncConcurrency       int                 `yaml:"block_sync_concurrency" category:"advanced"`
-	MetaSyncConcurrency        int                 `yaml:"meta_sync_concurrency" category:"advanced"`
-	DeprecatedConsistencyDelay time.Duration       `yaml:"consistency_delay" category:"deprecated"` // Deprecated. Remove in Mimir 2.9.
-	IndexCache                 IndexCacheConfig    `yaml:"index_cache"`
-	ChunksCache                ChunksCacheConfig   `yaml:"chunks_cache"`
-	MetadataCache              MetadataCacheConfig `yaml:"metadata_cache"`
-	IgnoreDeletionMarksDelay   time.Duration       `yaml:"ignore_deletion_mark_delay" category:"advanced"`
-	BucketIndex                BucketIndexConfig   `yaml:"bucket_index"`
-	IgnoreBlocksWithin         time.Duration       `yaml:"ignore_blocks_within" category:"advanced"`
+	SyncDir                       string              `yaml:"sync_dir"`
+	SyncInterval                  time.Duration       `yaml:"sync_interval" category:"advanced"`
+	MaxConcurrent                 int                 `yaml:"max_concurrent" category:"advanced"`
+	MaxConcurrentMemoryThreshold  uint64              `yaml:"max_concurrent_memory_threshold_bytes" category:"experimental"`
+	MaxConcurrentMemoryLimitRatio float64             `yaml:"max_concurrent_memory_limit_ratio" category:"experimental"`
+	TenantSyncConcurrency         int                 `yaml:"tenant_sync_concurrency" category:"advanced"`
+	BlockSyncConcurrency          int                 `yaml:"block_sync_concurrency" category:"advanced"`
+	MetaSyncConcurrency           int                 `yaml:"meta_sync_concurrency" category:"advanced"`
+	DeprecatedConsistencyDelay    time.Duration       `yaml:"consistency_delay" category:"deprecated"` // Deprecated. Remove in Mimir 2.9.
+	IndexCache                    IndexCacheConfig    `yaml:"index_cache"`
+	ChunksCache                   ChunksCacheConfig   `yaml:"chunks_cache"`
+	MetadataCache                 MetadataCacheConfig `yaml:"metadata_cache"`
+	IgnoreDeletionMarksDelay      time.Duration       `yaml:"ignore_deletion_mark_delay" category:"advanced"`
+	BucketIndex                   BucketIndexConfig   `yaml:"bucket_index"`
+	IgnoreBlocksWithin            time.Duration       `yaml:"ignore_blocks_within" category:"advanced"`
+
+	// Controls which blocks are loaded by the store-gateway.
+	MetadataFilters      flagext.StringSliceCSV `yaml:"metadata_filters" category:"experimental"`
+	ExternalLabelsFilter flagext.StringSliceCSV `yaml:"external_labels_filter" category:"experimental"`
 
 	// Chunk pool.
 	MaxChunkPoolBytes           uint64 `yaml:"max_chunk_pool_bytes" category:"advanced"`
@@ -342,9 +414,23 @@ type BucketStoreConfig struct {
 	IndexHeaderLazyLoadingEnabled     bool          `yaml:"index_header_lazy_loading_enabled" category:"advanced"`
 	IndexHeaderLazyLoadingIdleTimeout time.Duration `yaml:"index_header_lazy_loading_idle_timeout" category:"advanced"`
 
+	// Controls how frequently the index-header of blocks replacing compacted ones is built in advance.
+	IndexHeaderWarmupInterval time.Duration `yaml:"index_header_warmup_interval" category:"experimental"`
+
+	// Controls the hedging of GET object API requests.
+	HedgedRequestsDelay  time.Duration `yaml:"hedged_requests_delay" category:"experimental"`
+	HedgedRequestsBudget float64       `yaml:"hedged_requests_budget" category:"experimental"`
+
 	// Controls the partitioner, used to aggregate multiple GET object API requests.
 	PartitionerMaxGapBytes uint64 `yaml:"partitioner_max_gap_bytes" category:"advanced"`
 
+	// Controls the object storage budget of each Series() request.
+	SeriesMaxBucketGetOperations int    `yaml:"series_max_bucket_get_operations" category:"experimental"`
+	SeriesMaxBucketFetchedBytes  uint64 `yaml:"series_max_bucket_fetched_bytes" category:"experimental"`
+
+	// Controls the max size of the exemplars file of each block read by an Exemplars() request.
+	ExemplarsMaxFileSizeBytes int64 `yaml:"exemplars_max_file_size_bytes" category:"experimental"`
+
 	// Controls what is the ratio of postings offsets store will hold in memory.
 	// Larger value will keep less offsets, which will increase CPU cycles needed for query touching those postings.
 	// It's meant for setups that want low baseline memory pressure and where less traffic is expected.
@@ -357,6 +443,8 @@ type BucketStoreConfig struct {
 
 	StreamingBatchSize   int `yaml:"streaming_series_batch_size" category:"advanced"`
 	ChunkRangesPerSeries int `yaml:"fine_grained_chunks_caching_ranges_per_series" category:"experimental"`
+
+	StrictChunksTimeRangePruningEnabled bool `yaml:"strict_chunks_time_range_pruning_enabled" category:"experimental"`
 }
 
 // RegisterFlags registers the BucketStore flags
@@ -374,6 +462,8 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger)
 	f.IntVar(&cfg.ChunkPoolMaxBucketSizeBytes, "blocks-storage.bucket-store.chunk-pool-max-bucket-size-bytes", ChunkPoolDefaultMaxBucketSize, "Size - in bytes - of the largest chunks pool bucket.")
 	f.Uint64Var(&cfg.SeriesHashCacheMaxBytes, "blocks-storage.bucket-store.series-hash-cache-max-size-bytes", uint64(1*units.Gibibyte), "Max size - in bytes - of the in-memory series hash cache. The cache is shared across all tenants and it's used only when query sharding is enabled.")
 	f.IntVar(&cfg.MaxConcurrent, "blocks-storage.bucket-store.max-concurrent", 100, "Max number of concurrent queries to execute against the long-term storage. The limit is shared across all tenants.")
+	f.Uint64Var(&cfg.MaxConcurrentMemoryThreshold, "blocks-storage.bucket-store.max-concurrent-memory-threshold-bytes", 0, "Heap memory in-use - in bytes - at which the store-gateway admits only 1 concurrent query against the long-term storage. When the heap memory in-use exceeds 80% of this threshold, the max number of concurrent queries is linearly reduced, and new queries wait until the memory pressure decreases. The memory-mapped index-headers are not accounted in the heap memory in-use. 0 to disable.")
+	f.Float64Var(&cfg.MaxConcurrentMemoryLimitRatio, "blocks-storage.bucket-store.max-concurrent-memory-limit-ratio", 0, "Ratio of the Go runtime soft memory limit (GOMEMLIMIT) used as heap memory in-use threshold at which the store-gateway admits only 1 concurrent query against the long-term storage. The threshold follows the memory limit when it changes at runtime, and takes precedence over -blocks-storage.bucket-store.max-concurrent-memory-threshold-bytes when the memory limit is set. The value must be between 0 and 1. 0 to disable.")
 	f.IntVar(&cfg.TenantSyncConcurrency, "blocks-storage.bucket-store.tenant-sync-concurrency", 10, "Maximum number of concurrent tenants synching blocks.")
 	f.IntVar(&cfg.BlockSyncConcurrency, "blocks-storage.bucket-store.block-sync-concurrency", 20, "Maximum number of concurrent blocks synching per tenant.")
 	f.IntVar(&cfg.MetaSyncConcurrency, "blocks-storage.bucket-store.meta-sync-concurrency", 20, "Number of Go routines to use when syncing block meta files from object storage per tenant.")
@@ -381,12 +471,22 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger)
 	f.DurationVar(&cfg.IgnoreDeletionMarksDelay, "blocks-storage.bucket-store.ignore-deletion-marks-delay", time.Hour*1, "Duration after which the blocks marked for deletion will be filtered out while fetching blocks. "+
 		"The idea of ignore-deletion-marks-delay is to ignore blocks that are marked for deletion with some delay. This ensures store can still serve blocks that are meant to be deleted but do not have a replacement yet.")
 	f.DurationVar(&cfg.IgnoreBlocksWithin, "blocks-storage.bucket-store.ignore-blocks-within", 10*time.Hour, "Blocks with minimum time within this duration are ignored, and not loaded by store-gateway. Useful when used together with -querier.query-store-after to prevent loading young blocks, because there are usually many of them (depending on number of ingesters) and they are not yet compacted. Negative values or 0 disable the filter.")
+	cfg.MetadataFilters = append([]string(nil), DefaultMetadataFilters...)
+	f.Var(&cfg.MetadataFilters, "blocks-storage.bucket-store.metadata-filters", "Comma-separated list of filters applied, in order, to the blocks metadata fetched by the store-gateway. Only the blocks passing all filters are loaded. Supported built-in filters: consistency-delay, ignore-blocks-within, ignore-deletion-marks, external-labels. Blocks sharding is always applied before the configured filters. The querier applies the external-labels filter too, so that it doesn't query the blocks excluded by the filter.")
+	f.Var(&cfg.ExternalLabelsFilter, "blocks-storage.bucket-store.external-labels-filter", "Comma-separated list of name=value external labels a block must have to be loaded by the store-gateway. Applies only when the external-labels filter is enabled in -blocks-storage.bucket-store.metadata-filters.")
 	f.IntVar(&cfg.PostingOffsetsInMemSampling, "blocks-storage.bucket-store.posting-offsets-in-mem-sampling", DefaultPostingOffsetInMemorySampling, "Controls what is the ratio of postings offsets that the store will hold in memory.")
 	f.BoolVar(&cfg.IndexHeaderLazyLoadingEnabled, "blocks-storage.bucket-store.index-header-lazy-loading-enabled", true, "If enabled, store-gateway will lazy load an index-header only once required by a query.")
 	f.DurationVar(&cfg.IndexHeaderLazyLoadingIdleTimeout, "blocks-storage.bucket-store.index-header-lazy-loading-idle-timeout", 60*time.Minute, "If index-header lazy loading is enabled and this setting is > 0, the store-gateway will offload unused index-headers after 'idle timeout' inactivity.")
+	f.DurationVar(&cfg.IndexHeaderWarmupInterval, "blocks-storage.bucket-store.index-header-warmup-interval", 0, "How frequently the store-gateway checks for block replacement marks uploaded by the compactor (enabled with -compactor.block-replacement-marks-enabled), and builds the index-header of the new blocks it owns before they're loaded by the periodic sync. 0 to disable.")
+	f.DurationVar(&cfg.HedgedRequestsDelay, "blocks-storage.bucket-store.hedged-requests-delay", 0, "If a GET object API request to the object storage hasn't returned within this duration, a second identical request is sent, and the first one to return is used, in order to reduce the tail latency. 0 to disable.")
+	f.Float64Var(&cfg.HedgedRequestsBudget, "blocks-storage.bucket-store.hedged-requests-budget", 0.1, "Max ratio of GET object API requests which can be hedged when -blocks-storage.bucket-store.hedged-requests-delay is enabled. The value must be between 0 and 1.")
 	f.Uint64Var(&cfg.PartitionerMaxGapBytes, "blocks-storage.bucket-store.partitioner-max-gap-bytes", DefaultPartitionerMaxGapSize, "Max size - in bytes - of a gap for which the partitioner aggregates together two bucket GET object requests.")
+	f.IntVar(&cfg.SeriesMaxBucketGetOperations, "blocks-storage.bucket-store.series-max-bucket-get-operations", 0, "Maximum number of GET operations a single Series() request can run against the object storage. Operations served by the caches are not counted. When exceeded, the request fails. 0 to disable.")
+	f.Uint64Var(&cfg.SeriesMaxBucketFetchedBytes, "blocks-storage.bucket-store.series-max-bucket-fetched-bytes", 0, "Maximum number of bytes a single Series() request can fetch from the object storage. Bytes served by the caches are not counted. When exceeded, the request fails. 0 to disable.")
+	f.Int64Var(&cfg.ExemplarsMaxFileSizeBytes, "blocks-storage.bucket-store.exemplars-max-file-size-bytes", int64(64*units.Mebibyte), "Maximum uncompressed size - in bytes - of the exemplars file of a block read by a single Exemplars() request. When exceeded, the request fails. 0 to disable.")
 	f.IntVar(&cfg.StreamingBatchSize, "blocks-storage.bucket-store.batch-series-size", 5000, "This option controls how many series to fetch per batch. The batch size must be greater than 0.")
 	f.IntVar(&cfg.ChunkRangesPerSeries, "blocks-storage.bucket-store.fine-grained-chunks-caching-ranges-per-series", 1, "This option controls into how many ranges the chunks of each series from each block are split. This value is effectively the number of chunks cache items per series per block when -blocks-storage.bucket-store.chunks-cache.fine-grained-chunks-caching-enabled is enabled.")
+	f.BoolVar(&cfg.StrictChunksTimeRangePruningEnabled, "blocks-storage.bucket-store.strict-chunks-time-range-pruning-enabled", false, "True to never fetch the chunks fully outside of the queried time range. When -blocks-storage.bucket-store.chunks-cache.fine-grained-chunks-caching-enabled is enabled, the store-gateway otherwise fetches whole ranges of chunks of each series, including the chunks outside of the queried time range, in order to store the complete ranges in the chunks cache. When enabled, ranges of chunks which aren't fully within the queried time range are not stored in the chunks cache.")
 }
 
 // Validate the config.
@@ -403,12 +503,39 @@ func (cfg *BucketStoreConfig) Validate(logger log.Logger) error {
 	if err := cfg.MetadataCache.Validate(); err != nil {
 		return errors.Wrap(err, "metadata-cache configuration")
 	}
+	if cfg.HedgedRequestsBudget < 0 || cfg.HedgedRequestsBudget > 1 {
+		return errInvalidHedgedRequestsBudget
+	}
+	if cfg.MaxConcurrentMemoryLimitRatio < 0 || cfg.MaxConcurrentMemoryLimitRatio > 1 {
+		return errInvalidMaxConcurrentMemoryLimitRatio
+	}
+	if _, err := cfg.ParseExternalLabelsFilter(); err != nil {
+		return err
+	}
 	if cfg.DeprecatedConsistencyDelay > 0 {
 		util.WarnDeprecatedConfig(consistencyDelayFlag, logger)
 	}
 	return nil
 }
 
+// AdaptiveMaxConcurrentEnabled returns whether the max number of concurrent queries is reduced under memory pressure.
+func (cfg *BucketStoreConfig) AdaptiveMaxConcurrentEnabled() bool {
+	return cfg.MaxConcurrentMemoryThreshold > 0 || cfg.MaxConcurrentMemoryLimitRatio > 0
+}
+
+// ParseExternalLabelsFilter parses the configured external labels filter into a map of label name to value.
+func (cfg *BucketStoreConfig) ParseExternalLabelsFilter() (map[string]string, error) {
+	parsed := make(map[string]string, len(cfg.ExternalLabelsFilter))
+	for _, pair := range cfg.ExternalLabelsFilter {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, errInvalidExternalLabelsFilter
+		}
+		parsed[name] = value
+	}
+	return parsed, nil
+}
+
 type BucketIndexConfig struct {
 	Enabled               bool          `yaml:"enabled"`
 	UpdateOnErrorInterval time.Duration `yaml:"update_on_error_interval" category:"advanced"`
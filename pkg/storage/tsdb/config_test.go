@@ -115,18 +115,81 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expectedErr: errEmptyBlockranges,
 		},
+		"should pass on head compaction slots window equal to half of the smallest block range": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.HeadCompactionSlotsWindow = time.Hour
+			},
+			expectedErr: nil,
+		},
+		"should fail on head compaction slots window greater than half of the smallest block range": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.HeadCompactionSlotsWindow = time.Hour + time.Minute
+			},
+			expectedErr: errInvalidCompactionSlotsWindow,
+		},
+		"should fail on negative head compaction slots window": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.HeadCompactionSlotsWindow = -time.Minute
+			},
+			expectedErr: errInvalidCompactionSlotsWindow,
+		},
 		"should fail on invalid TSDB WAL segment size": {
 			setup: func(cfg *BlocksStorageConfig) {
 				cfg.TSDB.WALSegmentSizeBytes = 0
 			},
 			expectedErr: errInvalidWALSegmentSizeBytes,
 		},
+		"should fail on invalid TSDB WAL replay large tenants concurrency": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.WALReplayLargeTenantThresholdBytes = 1024
+				cfg.TSDB.WALReplayLargeTenantsConcurrency = 0
+			},
+			expectedErr: errInvalidWALReplayLargeTenants,
+		},
+		"should pass on invalid TSDB WAL replay large tenants concurrency if the large tenant threshold is disabled": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.WALReplayLargeTenantThresholdBytes = 0
+				cfg.TSDB.WALReplayLargeTenantsConcurrency = 0
+			},
+			expectedErr: nil,
+		},
+		"should fail if the shared WAL and the memory snapshot on shutdown are both enabled": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.TSDB.SharedWALEnabled = true
+				cfg.TSDB.MemorySnapshotOnShutdown = true
+			},
+			expectedErr: errSharedWALMemorySnapshot,
+		},
 		"should fail on invalid store-gateway streaming batch size": {
 			setup: func(cfg *BlocksStorageConfig) {
 				cfg.BucketStore.StreamingBatchSize = 0
 			},
 			expectedErr: errInvalidStreamingBatchSize,
 		},
+		"should fail on invalid store-gateway hedged requests budget": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.BucketStore.HedgedRequestsBudget = 1.5
+			},
+			expectedErr: errInvalidHedgedRequestsBudget,
+		},
+		"should fail on invalid store-gateway max concurrent memory limit ratio": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.BucketStore.MaxConcurrentMemoryLimitRatio = 1.5
+			},
+			expectedErr: errInvalidMaxConcurrentMemoryLimitRatio,
+		},
+		"should pass on valid store-gateway external labels filter": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.BucketStore.ExternalLabelsFilter = flagext.StringSliceCSV{"region=eu", "env="}
+			},
+			expectedErr: nil,
+		},
+		"should fail on invalid store-gateway external labels filter": {
+			setup: func(cfg *BlocksStorageConfig) {
+				cfg.BucketStore.ExternalLabelsFilter = flagext.StringSliceCSV{"region"}
+			},
+			expectedErr: errInvalidExternalLabelsFilter,
+		},
 	}
 
 	for testName, testData := range tests {
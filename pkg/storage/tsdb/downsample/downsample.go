@@ -0,0 +1,470 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package downsample implements the downsampling of TSDB blocks.
+//
+// A downsampled block is a regular TSDB block whose meta.json has the downsampling resolution set.
+// Each float series of the source block is replaced by one series for each aggregate, identified by
+// the AggregateLabel label, holding one sample for each resolution window. The series with native
+// histograms are not downsampled: they're copied as-is, with the AggregateRaw aggregate.
+package downsample
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+const (
+	// ResLevel1 is the 5 minutes downsampling resolution, in milliseconds.
+	ResLevel1 = int64(5 * time.Minute / time.Millisecond)
+
+	// ResLevel2 is the 1 hour downsampling resolution, in milliseconds.
+	ResLevel2 = int64(time.Hour / time.Millisecond)
+
+	// AggregateLabel is the name of the label added to the series of the downsampled blocks.
+	AggregateLabel = "__aggregate__"
+
+	// Aggregates of the downsampled series. The value of the AggregateCounter aggregate is the
+	// last value of the counter in the resolution window, adjusted for the counter resets since
+	// the beginning of the block, so that the resets within a window are not lost.
+	AggregateAvg     = "avg"
+	AggregateCount   = "count"
+	AggregateCounter = "counter"
+	AggregateMax     = "max"
+	AggregateMin     = "min"
+	AggregateSum     = "sum"
+	AggregateRaw     = "raw"
+
+	// Max number of samples in each chunk of the downsampled series.
+	samplesPerChunk = 120
+)
+
+// Resolutions are the supported downsampling resolutions, from the lowest to the highest.
+var Resolutions = []int64{ResLevel1, ResLevel2}
+
+// aggregates are the aggregates of the downsampled float series, sorted by name.
+var aggregates = []string{AggregateAvg, AggregateCount, AggregateCounter, AggregateMax, AggregateMin, AggregateSum}
+
+// AggregateForFunc returns the aggregate to query in the downsampled blocks to run the input PromQL
+// function (as passed in the storage.SelectHints) on the downsampled series, and false if the function
+// can't run on the downsampled series without changing its result, in which case the raw blocks must
+// be queried.
+func AggregateForFunc(fn string) (string, bool) {
+	switch fn {
+	case "min_over_time":
+		return AggregateMin, true
+	case "max_over_time":
+		return AggregateMax, true
+	case "sum_over_time":
+		return AggregateSum, true
+	case "rate", "increase":
+		return AggregateCounter, true
+	default:
+		return "", false
+	}
+}
+
+// Downsample writes into dir one downsampled block of the input raw block for each input resolution.
+// Returns the metas of the written blocks, in the same order as the input resolutions.
+func Downsample(logger log.Logger, meta *metadata.Meta, b tsdb.BlockReader, dir string, resolutions []int64) (_ []*metadata.Meta, returnErr error) {
+	if meta.Thanos.Downsample.Resolution > 0 {
+		return nil, errors.New("cannot downsample an already downsampled block")
+	}
+
+	indexr, err := b.Index()
+	if err != nil {
+		return nil, errors.Wrap(err, "open index")
+	}
+	defer func() {
+		if err := indexr.Close(); err != nil && returnErr == nil {
+			returnErr = errors.Wrap(err, "close index")
+		}
+	}()
+
+	chunkr, err := b.Chunks()
+	if err != nil {
+		return nil, errors.Wrap(err, "open chunks")
+	}
+	defer func() {
+		if err := chunkr.Close(); err != nil && returnErr == nil {
+			returnErr = errors.Wrap(err, "close chunks")
+		}
+	}()
+
+	entropy := rand.New(rand.NewSource(time.Now().UnixNano()))
+	writers := make([]*blockWriter, 0, len(resolutions))
+	defer func() {
+		for _, w := range writers {
+			w.close()
+		}
+	}()
+
+	for _, res := range resolutions {
+		w, err := newBlockWriter(meta, res, ulid.MustNew(ulid.Now(), entropy), dir)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, w)
+	}
+
+	postings, err := indexr.Postings(index.AllPostingsKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "postings")
+	}
+	postings = indexr.SortedPostings(postings)
+
+	var (
+		builder labels.ScratchBuilder
+		chks    []chunks.Meta
+	)
+
+	for postings.Next() {
+		if err := indexr.Series(postings.At(), &builder, &chks); err != nil {
+			return nil, errors.Wrap(err, "read series")
+		}
+		builder.Sort()
+		lset := builder.Labels()
+
+		hasHistograms := false
+		for i := range chks {
+			chks[i].Chunk, err = chunkr.Chunk(chks[i])
+			if err != nil {
+				return nil, errors.Wrap(err, "read chunk")
+			}
+			if chks[i].Chunk.Encoding() != chunkenc.EncXOR {
+				hasHistograms = true
+			}
+		}
+
+		for _, w := range writers {
+			if hasHistograms {
+				err = w.copySeries(lset, chks)
+			} else {
+				err = w.downsampleSeries(lset, chks)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "downsample series %s", lset.String())
+			}
+		}
+	}
+	if err := postings.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterate series")
+	}
+
+	metas := make([]*metadata.Meta, 0, len(writers))
+	for _, w := range writers {
+		m, err := w.finish(logger, indexr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "write downsampled block at resolution %d", w.meta.Thanos.Downsample.Resolution)
+		}
+		metas = append(metas, m)
+	}
+
+	return metas, nil
+}
+
+// blockWriter writes a downsampled block. The chunks are written as soon as the series are downsampled,
+// while the series are added to the index only when all the series have been downsampled, because the
+// aggregate label changes the ordering of the series.
+type blockWriter struct {
+	dir    string
+	meta   *metadata.Meta
+	chunkw *chunks.Writer
+	series []downsampledSeries
+}
+
+type downsampledSeries struct {
+	lset labels.Labels
+	chks []chunks.Meta
+}
+
+func newBlockWriter(src *metadata.Meta, resolution int64, id ulid.ULID, dir string) (*blockWriter, error) {
+	bdir := filepath.Join(dir, id.String())
+	chunkw, err := chunks.NewWriter(filepath.Join(bdir, block.ChunksDirname))
+	if err != nil {
+		return nil, errors.Wrap(err, "open chunk writer")
+	}
+
+	meta := &metadata.Meta{
+		BlockMeta: tsdb.BlockMeta{
+			ULID:    id,
+			MinTime: src.MinTime,
+			MaxTime: src.MaxTime,
+			Version: metadata.TSDBVersion1,
+			Compaction: tsdb.BlockMetaCompaction{
+				Level:   src.Compaction.Level,
+				Sources: append([]ulid.ULID(nil), src.Compaction.Sources...),
+				Parents: []tsdb.BlockDesc{{ULID: src.ULID, MinTime: src.MinTime, MaxTime: src.MaxTime}},
+			},
+		},
+		Thanos: metadata.Thanos{
+			Labels:     src.Thanos.Labels,
+			Downsample: metadata.ThanosDownsample{Resolution: resolution},
+			Source:     metadata.CompactorSource,
+		},
+	}
+
+	return &blockWriter{dir: bdir, meta: meta, chunkw: chunkw}, nil
+}
+
+// downsampleSeries computes the aggregates of the input float series for each resolution window.
+func (w *blockWriter) downsampleSeries(lset labels.Labels, chks []chunks.Meta) error {
+	var (
+		resolution = w.meta.Thanos.Downsample.Resolution
+		outputs    = make([]chunksBuilder, len(aggregates))
+		curr       window
+		lastT      = int64(-1 << 63)
+		lastV      float64
+		resets     float64
+		it         chunkenc.Iterator
+	)
+
+	flush := func() {
+		if curr.count == 0 {
+			return
+		}
+		for i, aggr := range aggregates {
+			outputs[i].add(curr.timestamp, curr.value(aggr))
+		}
+	}
+
+	for _, chk := range chks {
+		it = chk.Chunk.Iterator(it)
+		for it.Next() == chunkenc.ValFloat {
+			t, v := it.At()
+			if t <= lastT || value.IsStaleNaN(v) {
+				continue
+			}
+			lastT = t
+
+			// Adjust the counter value for the resets, like the PromQL rate() does.
+			if v < lastV {
+				resets += lastV
+			}
+			lastV = v
+
+			ts := windowTimestamp(t, resolution, w.meta.MaxTime)
+			if curr.count > 0 && ts != curr.timestamp {
+				flush()
+				curr = window{}
+			}
+			curr.add(ts, v, v+resets)
+		}
+		if err := it.Err(); err != nil {
+			return errors.Wrap(err, "iterate chunk")
+		}
+	}
+	flush()
+
+	for i, aggr := range aggregates {
+		if len(outputs[i].chks) == 0 {
+			continue
+		}
+		if err := w.addSeries(labels.NewBuilder(lset).Set(AggregateLabel, aggr).Labels(nil), outputs[i].chks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copySeries copies the input series chunks as-is.
+func (w *blockWriter) copySeries(lset labels.Labels, chks []chunks.Meta) error {
+	cp := make([]chunks.Meta, len(chks))
+	for i, c := range chks {
+		cp[i] = chunks.Meta{Chunk: c.Chunk, MinTime: c.MinTime, MaxTime: c.MaxTime}
+	}
+	return w.addSeries(labels.NewBuilder(lset).Set(AggregateLabel, AggregateRaw).Labels(nil), cp)
+}
+
+func (w *blockWriter) addSeries(lset labels.Labels, chks []chunks.Meta) error {
+	if err := w.chunkw.WriteChunks(chks...); err != nil {
+		return errors.Wrap(err, "write chunks")
+	}
+
+	for i := range chks {
+		w.meta.Stats.NumSamples += uint64(chks[i].Chunk.NumSamples())
+		// The chunk data has been written, so we don't need to keep it in memory.
+		chks[i].Chunk = nil
+	}
+	w.meta.Stats.NumChunks += uint64(len(chks))
+	w.meta.Stats.NumSeries++
+
+	w.series = append(w.series, downsampledSeries{lset: lset, chks: chks})
+	return nil
+}
+
+// finish writes the index and the meta.json of the block.
+func (w *blockWriter) finish(logger log.Logger, srcIndex tsdb.IndexReader) (*metadata.Meta, error) {
+	if err := w.chunkw.Close(); err != nil {
+		return nil, errors.Wrap(err, "close chunk writer")
+	}
+	w.chunkw = nil
+
+	indexw, err := index.NewWriter(context.Background(), filepath.Join(w.dir, block.IndexFilename))
+	if err != nil {
+		return nil, errors.Wrap(err, "open index writer")
+	}
+
+	if err := addSymbols(indexw, srcIndex.Symbols()); err != nil {
+		_ = indexw.Close()
+		return nil, err
+	}
+
+	sort.Slice(w.series, func(i, j int) bool {
+		return labels.Compare(w.series[i].lset, w.series[j].lset) < 0
+	})
+
+	for i, s := range w.series {
+		if err := indexw.AddSeries(storage.SeriesRef(i), s.lset, s.chks...); err != nil {
+			_ = indexw.Close()
+			return nil, errors.Wrap(err, "add series")
+		}
+	}
+	w.series = nil
+
+	if err := indexw.Close(); err != nil {
+		return nil, errors.Wrap(err, "close index writer")
+	}
+
+	w.meta.Thanos.SegmentFiles = block.GetSegmentFiles(w.dir)
+	if err := w.meta.WriteToDir(logger, w.dir); err != nil {
+		return nil, errors.Wrap(err, "write meta")
+	}
+
+	return w.meta, nil
+}
+
+// close releases the resources of a block writer which hasn't been finished.
+func (w *blockWriter) close() {
+	if w.chunkw != nil {
+		_ = w.chunkw.Close()
+		_ = os.RemoveAll(w.dir)
+	}
+}
+
+// addSymbols adds the symbols of the source block, merged with the symbols of the aggregate label.
+func addSymbols(indexw *index.Writer, symbols index.StringIter) error {
+	extra := append([]string{AggregateLabel, AggregateRaw}, aggregates...)
+	sort.Strings(extra)
+
+	for symbols.Next() {
+		sym := symbols.At()
+		for len(extra) > 0 && extra[0] <= sym {
+			if extra[0] < sym {
+				if err := indexw.AddSymbol(extra[0]); err != nil {
+					return errors.Wrap(err, "add symbol")
+				}
+			}
+			extra = extra[1:]
+		}
+
+		if err := indexw.AddSymbol(sym); err != nil {
+			return errors.Wrap(err, "add symbol")
+		}
+	}
+	if err := symbols.Err(); err != nil {
+		return errors.Wrap(err, "iterate symbols")
+	}
+
+	for _, sym := range extra {
+		if err := indexw.AddSymbol(sym); err != nil {
+			return errors.Wrap(err, "add symbol")
+		}
+	}
+	return nil
+}
+
+// windowTimestamp returns the timestamp of the aggregated sample of the resolution window the input
+// timestamp belongs to, which is the end of the window, capped to the max time of the block.
+func windowTimestamp(t, resolution, blockMaxTime int64) int64 {
+	start := t - t%resolution
+	if t < 0 && t%resolution != 0 {
+		start -= resolution
+	}
+
+	ts := start + resolution - 1
+	if ts >= blockMaxTime {
+		ts = blockMaxTime - 1
+	}
+	return ts
+}
+
+// window holds the aggregates of the samples in a resolution window.
+type window struct {
+	timestamp int64
+	count     int
+	sum       float64
+	min       float64
+	max       float64
+	counter   float64
+}
+
+func (w *window) add(ts int64, v, counter float64) {
+	if w.count == 0 {
+		w.timestamp = ts
+		w.min, w.max = v, v
+	}
+	w.count++
+	w.sum += v
+	w.counter = counter
+	if v < w.min {
+		w.min = v
+	}
+	if v > w.max {
+		w.max = v
+	}
+}
+
+func (w *window) value(aggr string) float64 {
+	switch aggr {
+	case AggregateAvg:
+		return w.sum / float64(w.count)
+	case AggregateCount:
+		return float64(w.count)
+	case AggregateCounter:
+		return w.counter
+	case AggregateMax:
+		return w.max
+	case AggregateMin:
+		return w.min
+	case AggregateSum:
+		return w.sum
+	default:
+		panic("unknown aggregate " + aggr)
+	}
+}
+
+// chunksBuilder builds the XOR chunks of a downsampled series.
+type chunksBuilder struct {
+	chks []chunks.Meta
+	app  chunkenc.Appender
+}
+
+func (b *chunksBuilder) add(t int64, v float64) {
+	if len(b.chks) == 0 || b.chks[len(b.chks)-1].Chunk.NumSamples() >= samplesPerChunk {
+		chk := chunkenc.NewXORChunk()
+		// The XOR chunk appender never fails.
+		b.app, _ = chk.Appender()
+		b.chks = append(b.chks, chunks.Meta{Chunk: chk, MinTime: t})
+	}
+
+	b.app.Append(t, v)
+	b.chks[len(b.chks)-1].MaxTime = t
+}
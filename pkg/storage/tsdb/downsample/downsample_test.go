@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package downsample
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+func TestDownsample(t *testing.T) {
+	const (
+		minT = int64(0)
+		maxT = 2 * ResLevel2
+	)
+
+	dir := t.TempDir()
+	logger := log.NewNopLogger()
+
+	// A counter with a sample every minute and a reset at the beginning of the second hour.
+	var counterSamples []tsdbutil.Sample
+	for ts := minT; ts < maxT; ts += time.Minute.Milliseconds() {
+		v := float64(ts / time.Minute.Milliseconds())
+		if ts >= ResLevel2 {
+			v -= 60
+		}
+		counterSamples = append(counterSamples, sample{t: ts, v: v})
+	}
+
+	histogramSamples := []tsdbutil.Sample{
+		sample{t: 0, h: tsdbutil.GenerateTestHistogram(1)},
+		sample{t: ResLevel2, h: tsdbutil.GenerateTestHistogram(2)},
+	}
+
+	srcDir, err := tsdb.CreateBlock([]storage.Series{
+		storage.NewListSeries(labels.FromStrings(labels.MetricName, "counter"), counterSamples),
+		storage.NewListSeries(labels.FromStrings(labels.MetricName, "histogram"), histogramSamples),
+	}, dir, 0, logger)
+	require.NoError(t, err)
+
+	srcMeta, err := metadata.ReadFromDir(srcDir)
+	require.NoError(t, err)
+	srcMeta.MinTime, srcMeta.MaxTime = minT, maxT
+	srcMeta.Thanos.Labels = map[string]string{"__compactor_shard_id__": "1_of_2"}
+
+	src, err := tsdb.OpenBlock(logger, srcDir, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, src.Close()) })
+
+	outDir := t.TempDir()
+	metas, err := Downsample(logger, srcMeta, src, outDir, Resolutions)
+	require.NoError(t, err)
+	require.Len(t, metas, 2)
+
+	for i, res := range Resolutions {
+		meta := metas[i]
+		assert.Equal(t, res, meta.Thanos.Downsample.Resolution)
+		assert.Equal(t, srcMeta.MinTime, meta.MinTime)
+		assert.Equal(t, srcMeta.MaxTime, meta.MaxTime)
+		assert.Equal(t, srcMeta.Thanos.Labels, meta.Thanos.Labels)
+		assert.Equal(t, srcMeta.Compaction.Sources, meta.Compaction.Sources)
+		assert.Equal(t, uint64(len(aggregates)+1), meta.Stats.NumSeries)
+
+		bdir := filepath.Join(outDir, meta.ULID.String())
+		require.NoError(t, block.VerifyBlock(logger, bdir, meta.MinTime, meta.MaxTime, true))
+
+		readMeta, err := metadata.ReadFromDir(bdir)
+		require.NoError(t, err)
+		assert.Equal(t, res, readMeta.Thanos.Downsample.Resolution)
+	}
+
+	// Check the 1h resolution block, which has a single sample per aggregate for each hour.
+	b, err := tsdb.OpenBlock(logger, filepath.Join(outDir, metas[1].ULID.String()), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, b.Close()) })
+
+	q, err := tsdb.NewBlockQuerier(b, math.MinInt64, math.MaxInt64)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, q.Close()) })
+
+	expected := map[string][]float64{
+		AggregateAvg:     {29.5, 29.5},
+		AggregateCount:   {60, 60},
+		AggregateCounter: {59, 118},
+		AggregateMax:     {59, 59},
+		AggregateMin:     {0, 0},
+		AggregateSum:     {1770, 1770},
+	}
+
+	for aggr, values := range expected {
+		ss := q.Select(true, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "counter"), labels.MustNewMatcher(labels.MatchEqual, AggregateLabel, aggr))
+		require.True(t, ss.Next(), aggr)
+
+		var (
+			actualTimestamps []int64
+			actualValues     []float64
+		)
+		it := ss.At().Iterator(nil)
+		for it.Next() == chunkenc.ValFloat {
+			ts, v := it.At()
+			actualTimestamps = append(actualTimestamps, ts)
+			actualValues = append(actualValues, v)
+		}
+		require.NoError(t, it.Err())
+
+		assert.Equal(t, []int64{ResLevel2 - 1, 2*ResLevel2 - 1}, actualTimestamps, aggr)
+		assert.Equal(t, values, actualValues, aggr)
+		assert.False(t, ss.Next(), aggr)
+	}
+
+	// The series with native histograms are copied as-is.
+	ss := q.Select(true, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "histogram"))
+	require.True(t, ss.Next())
+	assert.Equal(t, labels.FromStrings(AggregateLabel, AggregateRaw, labels.MetricName, "histogram"), ss.At().Labels())
+
+	var actualTimestamps []int64
+	it := ss.At().Iterator(nil)
+	for it.Next() == chunkenc.ValHistogram {
+		ts, _ := it.AtHistogram()
+		actualTimestamps = append(actualTimestamps, ts)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []int64{0, ResLevel2}, actualTimestamps)
+	assert.False(t, ss.Next())
+}
+
+func TestDownsample_ShouldFailOnDownsampledBlock(t *testing.T) {
+	meta := &metadata.Meta{Thanos: metadata.Thanos{Downsample: metadata.ThanosDownsample{Resolution: ResLevel1}}}
+
+	_, err := Downsample(log.NewNopLogger(), meta, nil, t.TempDir(), []int64{ResLevel2})
+	require.Error(t, err)
+}
+
+func TestAggregateForFunc(t *testing.T) {
+	tests := map[string]struct {
+		expectedAggr string
+		expectedOK   bool
+	}{
+		"rate":          {expectedAggr: AggregateCounter, expectedOK: true},
+		"increase":      {expectedAggr: AggregateCounter, expectedOK: true},
+		"min_over_time": {expectedAggr: AggregateMin, expectedOK: true},
+		"max_over_time": {expectedAggr: AggregateMax, expectedOK: true},
+		"sum_over_time": {expectedAggr: AggregateSum, expectedOK: true},
+
+		// The functions which can't run on the downsampled series.
+		"count_over_time": {},
+		"avg_over_time":   {},
+		"irate":           {},
+		"resets":          {},
+		"sum":             {},
+		"":                {},
+	}
+
+	for fn, testData := range tests {
+		t.Run(fn, func(t *testing.T) {
+			aggr, ok := AggregateForFunc(fn)
+			assert.Equal(t, testData.expectedAggr, aggr)
+			assert.Equal(t, testData.expectedOK, ok)
+		})
+	}
+}
+
+func TestWindowTimestamp(t *testing.T) {
+	assert.Equal(t, ResLevel1-1, windowTimestamp(0, ResLevel1, ResLevel2))
+	assert.Equal(t, ResLevel1-1, windowTimestamp(ResLevel1-1, ResLevel1, ResLevel2))
+	assert.Equal(t, 2*ResLevel1-1, windowTimestamp(ResLevel1, ResLevel1, ResLevel2))
+	assert.Equal(t, int64(-1), windowTimestamp(-10, ResLevel1, ResLevel2))
+
+	// The timestamp is capped to the block max time.
+	assert.Equal(t, int64(99), windowTimestamp(50, ResLevel1, 100))
+}
+
+type sample struct {
+	t int64
+	v float64
+	h *histogram.Histogram
+}
+
+func (s sample) T() int64                      { return s.t }
+func (s sample) V() float64                    { return s.v }
+func (s sample) H() *histogram.Histogram       { return s.h }
+func (s sample) FH() *histogram.FloatHistogram { return nil }
+
+func (s sample) Type() chunkenc.ValueType {
+	if s.h != nil {
+		return chunkenc.ValHistogram
+	}
+	return chunkenc.ValFloat
+}
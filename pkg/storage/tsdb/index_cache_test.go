@@ -71,6 +71,30 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 				},
 			},
 		},
+		"redis cluster addresses should pass": {
+			cfg: IndexCacheConfig{
+				BackendConfig: cache.BackendConfig{
+					Backend: IndexCacheBackendRedis,
+					Redis: cache.RedisClientConfig{
+						Endpoint:            []string{"redis-1:6379", "redis-2:6379", "redis-3:6379"},
+						MaxAsyncConcurrency: 1,
+						TLSEnabled:          true,
+					},
+				},
+			},
+		},
+		"redis sentinel addresses should pass": {
+			cfg: IndexCacheConfig{
+				BackendConfig: cache.BackendConfig{
+					Backend: IndexCacheBackendRedis,
+					Redis: cache.RedisClientConfig{
+						Endpoint:            []string{"sentinel-1:26379", "sentinel-2:26379"},
+						MasterName:          "mymaster",
+						MaxAsyncConcurrency: 1,
+					},
+				},
+			},
+		},
 		"inmemory should pass": {
 			cfg: IndexCacheConfig{
 				BackendConfig: cache.BackendConfig{
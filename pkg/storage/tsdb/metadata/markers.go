@@ -25,11 +25,21 @@ const (
 	// NoCompactMarkFilename is the known json filename for optional file storing details about why block has to be excluded from compaction.
 	// If such file is present in block dir, it means the block has to excluded from compaction (both vertical and horizontal) or rewrite (e.g deletions).
 	NoCompactMarkFilename = "no-compact-mark.json"
+	// VerificationMarkFilename is the known json filename for optional file storing the result of the last verification
+	// of the block chunks checksums and index integrity, run in background by the compactor.
+	VerificationMarkFilename = "verification-mark.json"
+	// RetentionPoliciesCheckedMarkFilename is the known json filename for optional file storing the retention policies
+	// which have been applied to the block by the compactor without finding any series to delete.
+	RetentionPoliciesCheckedMarkFilename = "retention-policies-checked-mark.json"
 
 	// DeletionMarkVersion1 is the version of deletion-mark file supported by Thanos.
 	DeletionMarkVersion1 = 1
 	// NoCompactMarkVersion1 is the version of no-compact-mark file supported by Thanos.
 	NoCompactMarkVersion1 = 1
+	// VerificationMarkVersion1 is the version of verification-mark file supported by Mimir.
+	VerificationMarkVersion1 = 1
+	// RetentionPoliciesCheckedMarkVersion1 is the version of retention-policies-checked-mark file supported by Mimir.
+	RetentionPoliciesCheckedMarkVersion1 = 1
 )
 
 var (
@@ -71,6 +81,9 @@ const (
 	IndexSizeExceedingNoCompactReason = "index-size-exceeding"
 	// OutOfOrderChunksNoCompactReason is a reason of to no compact block with index contains out of order chunk so that the compaction is not blocked.
 	OutOfOrderChunksNoCompactReason = "block-index-out-of-order-chunk"
+	// RepeatedCompactionFailuresNoCompactReason is a reason to not compact a block which caused the compaction to fail
+	// several consecutive times, so that the compaction of the other blocks is not blocked.
+	RepeatedCompactionFailuresNoCompactReason = "repeated-compaction-failures"
 )
 
 // NoCompactMark marker stores reason of block being excluded from compaction if needed.
@@ -89,6 +102,39 @@ type NoCompactMark struct {
 
 func (n *NoCompactMark) markerFilename() string { return NoCompactMarkFilename }
 
+// VerificationMark stores the result of the last verification of a block.
+type VerificationMark struct {
+	// ID of the tsdb block.
+	ID ulid.ULID `json:"id"`
+	// Version of the file.
+	Version int `json:"version"`
+	// Details is a human readable string giving details of the corruption found, if any.
+	Details string `json:"details,omitempty"`
+
+	// VerificationTime is a unix timestamp of when the block was verified.
+	VerificationTime int64 `json:"verification_time"`
+	// Corrupted is true if the verification found the block chunks or index corrupted.
+	Corrupted bool `json:"corrupted"`
+}
+
+func (m *VerificationMark) markerFilename() string { return VerificationMarkFilename }
+
+// RetentionPoliciesCheckedMark stores the retention policies which have been applied to a block without finding
+// any series to delete, so that the block isn't downloaded again to apply the same policies.
+type RetentionPoliciesCheckedMark struct {
+	// ID of the tsdb block.
+	ID ulid.ULID `json:"id"`
+	// Version of the file.
+	Version int `json:"version"`
+
+	// Hints are the compaction hints of the retention policies checked.
+	Hints []string `json:"hints"`
+}
+
+func (m *RetentionPoliciesCheckedMark) markerFilename() string {
+	return RetentionPoliciesCheckedMarkFilename
+}
+
 // ReadMarker reads the given mark file from <dir>/<marker filename>.json in bucket.
 func ReadMarker(ctx context.Context, logger log.Logger, bkt objstore.InstrumentedBucketReader, dir string, marker Marker) error {
 	markerFile := path.Join(dir, marker.markerFilename())
@@ -118,6 +164,14 @@ func ReadMarker(ctx context.Context, logger log.Logger, bkt objstore.Instrumente
 		if version := marker.(*DeletionMark).Version; version != DeletionMarkVersion1 {
 			return errors.Errorf("unexpected deletion-mark file version %d, expected %d", version, DeletionMarkVersion1)
 		}
+	case VerificationMarkFilename:
+		if version := marker.(*VerificationMark).Version; version != VerificationMarkVersion1 {
+			return errors.Errorf("unexpected verification-mark file version %d, expected %d", version, VerificationMarkVersion1)
+		}
+	case RetentionPoliciesCheckedMarkFilename:
+		if version := marker.(*RetentionPoliciesCheckedMark).Version; version != RetentionPoliciesCheckedMarkVersion1 {
+			return errors.Errorf("unexpected retention-policies-checked-mark file version %d, expected %d", version, RetentionPoliciesCheckedMarkVersion1)
+		}
 	}
 	return nil
 }
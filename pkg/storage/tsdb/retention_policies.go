@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// Relative to user-specific prefix.
+const RetentionPoliciesPath = "retention-policies.json"
+
+// RetentionPolicy defines the retention of the series matching a selector. The series matching
+// the selector are deleted from the blocks once they're older than the retention period.
+type RetentionPolicy struct {
+	Name      string         `json:"name"`
+	Selector  string         `json:"selector"`
+	Retention model.Duration `json:"retention"`
+}
+
+// Validate returns an error if the retention policy is invalid.
+func (p RetentionPolicy) Validate() error {
+	if p.Name == "" {
+		return errors.New("the retention policy name is required")
+	}
+	if p.Retention <= 0 {
+		return errors.New("the retention policy retention must be greater than 0")
+	}
+	matchers, err := p.Matchers()
+	if err != nil {
+		return errors.Wrap(err, "invalid retention policy selector")
+	}
+
+	// Like in PromQL, a selector must have at least one matcher not matching the empty string.
+	for _, m := range matchers {
+		if !m.Matches("") {
+			return nil
+		}
+	}
+	return errors.New("the retention policy selector must contain at least one label matcher not matching the empty string")
+}
+
+// Matchers returns the label matchers of the retention policy selector.
+func (p RetentionPolicy) Matchers() ([]*labels.Matcher, error) {
+	return parser.ParseMetricSelector(p.Selector)
+}
+
+// Cutoff returns the time before which the series matching the selector should be deleted.
+func (p RetentionPolicy) Cutoff(now time.Time) time.Time {
+	return now.Add(-time.Duration(p.Retention))
+}
+
+// Hash returns a hash of the retention policy selector and retention, which can be used to know whether
+// a block has already been rewritten by the policy. The name is not part of the hash.
+func (p RetentionPolicy) Hash() string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d", p.Selector, p.Retention)))
+	return hex.EncodeToString(h[:8])
+}
+
+type RetentionPolicies struct {
+	Policies []RetentionPolicy `json:"policies"`
+}
+
+// Set adds the input policy, replacing the existing policy with the same name if any.
+func (p *RetentionPolicies) Set(policy RetentionPolicy) {
+	for idx := range p.Policies {
+		if p.Policies[idx].Name == policy.Name {
+			p.Policies[idx] = policy
+			return
+		}
+	}
+
+	p.Policies = append(p.Policies, policy)
+}
+
+// Delete removes the policy with the input name. Returns false if the policy doesn't exist.
+func (p *RetentionPolicies) Delete(name string) bool {
+	for idx := range p.Policies {
+		if p.Policies[idx].Name == name {
+			p.Policies = append(p.Policies[:idx], p.Policies[idx+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadRetentionPolicies returns the retention policies of the given user. If the user has no
+// retention policies, returns empty policies and no error.
+func ReadRetentionPolicies(ctx context.Context, bkt objstore.BucketReader, userID string) (*RetentionPolicies, error) {
+	policiesFile := path.Join(userID, RetentionPoliciesPath)
+
+	r, err := bkt.Get(ctx, policiesFile)
+	if err != nil {
+		if bkt.IsObjNotFoundErr(err) {
+			return &RetentionPolicies{}, nil
+		}
+
+		return nil, errors.Wrapf(err, "failed to read retention policies object: %s", policiesFile)
+	}
+
+	policies := &RetentionPolicies{}
+	err = json.NewDecoder(r).Decode(policies)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode retention policies object: %s", policiesFile)
+	}
+
+	return policies, nil
+}
+
+// WriteRetentionPolicies uploads the retention policies of the given user. If there are no policies,
+// the retention policies object is deleted.
+func WriteRetentionPolicies(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, policies *RetentionPolicies) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	if len(policies.Policies) == 0 {
+		err := bkt.Delete(ctx, RetentionPoliciesPath)
+		if err != nil && !bkt.IsObjNotFoundErr(err) {
+			return errors.Wrap(err, "delete retention policies")
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(policies)
+	if err != nil {
+		return errors.Wrap(err, "serialize retention policies")
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, RetentionPoliciesPath, bytes.NewReader(data)), "upload retention policies")
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestRetentionPolicy_Validate(t *testing.T) {
+	tests := map[string]struct {
+		policy      RetentionPolicy
+		expectedErr bool
+	}{
+		"valid policy": {
+			policy: RetentionPolicy{Name: "dev", Selector: `{env="dev"}`, Retention: model.Duration(7 * 24 * time.Hour)},
+		},
+		"missing name": {
+			policy:      RetentionPolicy{Selector: `{env="dev"}`, Retention: model.Duration(time.Hour)},
+			expectedErr: true,
+		},
+		"missing retention": {
+			policy:      RetentionPolicy{Name: "dev", Selector: `{env="dev"}`},
+			expectedErr: true,
+		},
+		"invalid selector": {
+			policy:      RetentionPolicy{Name: "dev", Selector: `{env="dev"`, Retention: model.Duration(time.Hour)},
+			expectedErr: true,
+		},
+		"selector matching everything": {
+			policy:      RetentionPolicy{Name: "dev", Selector: `{env=~".*"}`, Retention: model.Duration(time.Hour)},
+			expectedErr: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := testData.policy.Validate()
+			if testData.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicy_Hash(t *testing.T) {
+	policy := RetentionPolicy{Name: "dev", Selector: `{env="dev"}`, Retention: model.Duration(time.Hour)}
+
+	renamed := policy
+	renamed.Name = "renamed"
+	assert.Equal(t, policy.Hash(), renamed.Hash())
+
+	changedSelector := policy
+	changedSelector.Selector = `{env="test"}`
+	assert.NotEqual(t, policy.Hash(), changedSelector.Hash())
+
+	changedRetention := policy
+	changedRetention.Retention = model.Duration(2 * time.Hour)
+	assert.NotEqual(t, policy.Hash(), changedRetention.Hash())
+}
+
+func TestRetentionPolicies_SetAndDelete(t *testing.T) {
+	policies := &RetentionPolicies{}
+
+	policies.Set(RetentionPolicy{Name: "first", Selector: `{env="dev"}`, Retention: model.Duration(time.Hour)})
+	policies.Set(RetentionPolicy{Name: "second", Selector: `{env="test"}`, Retention: model.Duration(time.Hour)})
+	policies.Set(RetentionPolicy{Name: "first", Selector: `{env="dev"}`, Retention: model.Duration(2 * time.Hour)})
+
+	assert.Equal(t, []RetentionPolicy{
+		{Name: "first", Selector: `{env="dev"}`, Retention: model.Duration(2 * time.Hour)},
+		{Name: "second", Selector: `{env="test"}`, Retention: model.Duration(time.Hour)},
+	}, policies.Policies)
+
+	assert.False(t, policies.Delete("unknown"))
+	assert.True(t, policies.Delete("first"))
+	assert.Equal(t, []RetentionPolicy{
+		{Name: "second", Selector: `{env="test"}`, Retention: model.Duration(time.Hour)},
+	}, policies.Policies)
+}
+
+func TestReadAndWriteRetentionPolicies(t *testing.T) {
+	const userID = "user"
+
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	// The user has no retention policies.
+	policies, err := ReadRetentionPolicies(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.Empty(t, policies.Policies)
+
+	expected := &RetentionPolicies{Policies: []RetentionPolicy{
+		{Name: "dev", Selector: `{env="dev"}`, Retention: model.Duration(7 * 24 * time.Hour)},
+	}}
+	require.NoError(t, WriteRetentionPolicies(ctx, bkt, userID, nil, expected))
+
+	exists, err := bkt.Exists(ctx, userID+"/"+RetentionPoliciesPath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	policies, err = ReadRetentionPolicies(ctx, bkt, userID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, policies)
+
+	// Writing empty policies deletes the object.
+	require.NoError(t, WriteRetentionPolicies(ctx, bkt, userID, nil, &RetentionPolicies{}))
+
+	exists, err = bkt.Exists(ctx, userID+"/"+RetentionPoliciesPath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -29,6 +29,10 @@ type TenantDeletionMark struct {
 
 	// Unix timestamp when cleanup was finished.
 	FinishedTime int64 `json:"finished_time,omitempty"`
+
+	// Unix timestamp when the tenant configurations stored outside the blocks storage
+	// (eg. ruler rule groups and Alertmanager configuration) have been deleted.
+	ConfigsDeletedTime int64 `json:"configs_deleted_time,omitempty"`
 }
 
 func NewTenantDeletionMark(deletionTime time.Time) *TenantDeletionMark {
@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/multierror"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+// NewBucketClient creates the bucket client for the blocks storage. If the cold storage is enabled,
+// the returned client is a TieredBucket reading the blocks from both the hot and cold storage.
+func NewBucketClient(ctx context.Context, cfg BlocksStorageConfig, name string, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
+	hot, err := bucket.NewClient(ctx, cfg.Bucket, name, logger, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.ColdStorage.Enabled {
+		return hot, nil
+	}
+
+	cold, err := bucket.NewClient(ctx, cfg.ColdStorage.Bucket, name+"-cold-storage", logger, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create cold storage bucket client")
+	}
+
+	return NewTieredBucket(hot, cold), nil
+}
+
+// TieredBucket is a bucket client for blocks stored either in the hot or in the cold storage.
+// The blocks files are read from the storage the block is known to be stored in, falling back
+// to the other storage if the object is not found. All other objects are read from the hot storage.
+// Uploads always go to the hot storage, while deletions are applied to both storages.
+type TieredBucket struct {
+	hot  objstore.Bucket
+	cold objstore.Bucket
+
+	// Blocks known to be stored in the cold storage, by tenant.
+	coldBlocksMx sync.RWMutex
+	coldBlocks   map[string]map[ulid.ULID]struct{}
+}
+
+func NewTieredBucket(hot, cold objstore.Bucket) *TieredBucket {
+	return &TieredBucket{
+		hot:        hot,
+		cold:       cold,
+		coldBlocks: map[string]map[ulid.ULID]struct{}{},
+	}
+}
+
+// Hot returns the client for the hot storage.
+func (b *TieredBucket) Hot() objstore.Bucket {
+	return b.hot
+}
+
+// Cold returns the client for the cold storage.
+func (b *TieredBucket) Cold() objstore.Bucket {
+	return b.cold
+}
+
+// SetColdBlocks replaces the blocks of the tenant known to be stored in the cold storage.
+func (b *TieredBucket) SetColdBlocks(userID string, ids []ulid.ULID) {
+	blocks := make(map[ulid.ULID]struct{}, len(ids))
+	for _, id := range ids {
+		blocks[id] = struct{}{}
+	}
+
+	b.coldBlocksMx.Lock()
+	defer b.coldBlocksMx.Unlock()
+
+	if len(blocks) == 0 {
+		delete(b.coldBlocks, userID)
+		return
+	}
+	b.coldBlocks[userID] = blocks
+}
+
+// readers returns the buckets to read the object from, in order.
+func (b *TieredBucket) readers(name string) []objstore.Bucket {
+	// Blocks objects are stored at <tenant>/<block>/...
+	parts := strings.SplitN(name, objstore.DirDelim, 3)
+	if len(parts) < 3 {
+		return []objstore.Bucket{b.hot}
+	}
+
+	id, err := ulid.Parse(parts[1])
+	if err != nil {
+		return []objstore.Bucket{b.hot}
+	}
+
+	b.coldBlocksMx.RLock()
+	_, cold := b.coldBlocks[parts[0]][id]
+	b.coldBlocksMx.RUnlock()
+
+	if cold {
+		return []objstore.Bucket{b.cold, b.hot}
+	}
+	return []objstore.Bucket{b.hot, b.cold}
+}
+
+// Iter calls f for each entry in the given directory of both the hot and cold storage.
+func (b *TieredBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	entries := map[string]struct{}{}
+	for _, bkt := range []objstore.Bucket{b.hot, b.cold} {
+		err := bkt.Iter(ctx, dir, func(name string) error {
+			entries[name] = struct{}{}
+			return nil
+		}, options...)
+		if err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := f(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a reader for the given object name.
+func (b *TieredBucket) Get(ctx context.Context, name string) (r io.ReadCloser, err error) {
+	for _, bkt := range b.readers(name) {
+		r, err = bkt.Get(ctx, name)
+		if err == nil || !bkt.IsObjNotFoundErr(err) {
+			return r, err
+		}
+	}
+	return nil, err
+}
+
+// GetRange returns a new range reader for the given object name and range.
+func (b *TieredBucket) GetRange(ctx context.Context, name string, off, length int64) (r io.ReadCloser, err error) {
+	for _, bkt := range b.readers(name) {
+		r, err = bkt.GetRange(ctx, name, off, length)
+		if err == nil || !bkt.IsObjNotFoundErr(err) {
+			return r, err
+		}
+	}
+	return nil, err
+}
+
+// Exists checks if the given object exists in the bucket.
+func (b *TieredBucket) Exists(ctx context.Context, name string) (bool, error) {
+	for _, bkt := range b.readers(name) {
+		if exists, err := bkt.Exists(ctx, name); err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// Attributes returns information about the specified object.
+func (b *TieredBucket) Attributes(ctx context.Context, name string) (attrs objstore.ObjectAttributes, err error) {
+	for _, bkt := range b.readers(name) {
+		attrs, err = bkt.Attributes(ctx, name)
+		if err == nil || !bkt.IsObjNotFoundErr(err) {
+			return attrs, err
+		}
+	}
+	return objstore.ObjectAttributes{}, err
+}
+
+// IsObjNotFoundErr returns true if the error means that the object is not found.
+func (b *TieredBucket) IsObjNotFoundErr(err error) bool {
+	return b.hot.IsObjNotFoundErr(err) || b.cold.IsObjNotFoundErr(err)
+}
+
+// Upload the contents of the reader as an object into the hot storage.
+func (b *TieredBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	return b.hot.Upload(ctx, name, r)
+}
+
+// Delete removes the object from both the hot and cold storage. Returns a not found error
+// only if the object doesn't exist in any storage.
+func (b *TieredBucket) Delete(ctx context.Context, name string) error {
+	hotErr := b.hot.Delete(ctx, name)
+	coldErr := b.cold.Delete(ctx, name)
+
+	hotNotFound := hotErr != nil && b.hot.IsObjNotFoundErr(hotErr)
+	coldNotFound := coldErr != nil && b.cold.IsObjNotFoundErr(coldErr)
+
+	switch {
+	case hotNotFound && coldNotFound:
+		return hotErr
+	case hotNotFound:
+		return coldErr
+	case coldNotFound:
+		return hotErr
+	default:
+		return multierror.New(hotErr, coldErr).Err()
+	}
+}
+
+// Name returns the bucket name of the hot storage.
+func (b *TieredBucket) Name() string {
+	return b.hot.Name()
+}
+
+// Close closes both the hot and cold storage clients.
+func (b *TieredBucket) Close() error {
+	return multierror.New(b.hot.Close(), b.cold.Close()).Err()
+}
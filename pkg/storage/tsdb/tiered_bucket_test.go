@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestTieredBucket(t *testing.T) {
+	const userID = "user"
+
+	var (
+		ctx      = context.Background()
+		hotID    = ulid.MustNew(1, nil)
+		coldID   = ulid.MustNew(2, nil)
+		hotFile  = userID + "/" + hotID.String() + "/meta.json"
+		coldFile = userID + "/" + coldID.String() + "/meta.json"
+	)
+
+	hot := objstore.NewInMemBucket()
+	cold := objstore.NewInMemBucket()
+	require.NoError(t, hot.Upload(ctx, hotFile, bytes.NewReader([]byte("hot"))))
+	require.NoError(t, cold.Upload(ctx, coldFile, bytes.NewReader([]byte("cold"))))
+	require.NoError(t, hot.Upload(ctx, userID+"/bucket-index.json.gz", bytes.NewReader([]byte("index"))))
+
+	bkt := NewTieredBucket(hot, cold)
+
+	readObject := func(t *testing.T, name string) string {
+		r, err := bkt.Get(ctx, name)
+		require.NoError(t, err)
+		defer r.Close()
+
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("should read the blocks from both storages", func(t *testing.T) {
+		for _, coldBlocks := range [][]ulid.ULID{nil, {coldID}} {
+			bkt.SetColdBlocks(userID, coldBlocks)
+
+			assert.Equal(t, "hot", readObject(t, hotFile))
+			assert.Equal(t, "cold", readObject(t, coldFile))
+
+			r, err := bkt.GetRange(ctx, coldFile, 1, 2)
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, "ol", string(content))
+
+			exists, err := bkt.Exists(ctx, coldFile)
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			attrs, err := bkt.Attributes(ctx, coldFile)
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), attrs.Size)
+		}
+	})
+
+	t.Run("should read the other objects only from the hot storage", func(t *testing.T) {
+		assert.Equal(t, "index", readObject(t, userID+"/bucket-index.json.gz"))
+
+		_, err := bkt.Get(ctx, userID+"/missing.json")
+		assert.True(t, bkt.IsObjNotFoundErr(err))
+	})
+
+	t.Run("should list the objects from both storages", func(t *testing.T) {
+		var names []string
+		require.NoError(t, bkt.Iter(ctx, userID+"/", func(name string) error {
+			names = append(names, name)
+			return nil
+		}))
+
+		assert.Equal(t, []string{userID + "/" + hotID.String() + "/", userID + "/" + coldID.String() + "/", userID + "/bucket-index.json.gz"}, names)
+	})
+
+	t.Run("should upload to the hot storage and delete from both storages", func(t *testing.T) {
+		markFile := userID + "/" + coldID.String() + "/deletion-mark.json"
+		require.NoError(t, bkt.Upload(ctx, markFile, bytes.NewReader([]byte("mark"))))
+
+		exists, err := hot.Exists(ctx, markFile)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		require.NoError(t, bkt.Delete(ctx, markFile))
+		require.NoError(t, bkt.Delete(ctx, coldFile))
+
+		exists, err = bkt.Exists(ctx, markFile)
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		exists, err = bkt.Exists(ctx, coldFile)
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		assert.True(t, bkt.IsObjNotFoundErr(bkt.Delete(ctx, coldFile)))
+	})
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+
+	"github.com/grafana/dskit/gate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	util_math "github.com/grafana/mimir/pkg/util/math"
+)
+
+const (
+	// adaptiveGateSoftLimitRatio is the ratio of the memory threshold above which the
+	// effective max concurrency starts to be reduced.
+	adaptiveGateSoftLimitRatio = 0.8
+
+	// adaptiveGateMaxIncreaseRatio is the max ratio of the configured max concurrency by which the
+	// effective max concurrency is increased at each update, so that it's restored gradually once the
+	// memory pressure decreases, instead of admitting a burst of queries causing another memory spike.
+	adaptiveGateMaxIncreaseRatio = 0.1
+
+	heapObjectsBytesMetric = "/memory/classes/heap/objects:bytes"
+)
+
+// adaptiveGate is a gate.Gate whose max concurrency is dynamically reduced when the heap memory
+// in-use gets close to the configured threshold, so that new requests are not admitted while the
+// store-gateway is under memory pressure. The max concurrency is never reduced below 1, to guarantee
+// that requests can progress.
+//
+// The memory threshold is either static, or a ratio of the Go runtime soft memory limit.
+// The memory-mapped index-headers are not accounted, because they're not allocated in the Go heap and
+// their pages are loaded and evicted by the kernel on demand.
+type adaptiveGate struct {
+	maxConcurrent    int
+	memoryThreshold  uint64
+	memoryLimitRatio float64
+
+	// Reads the heap memory in-use and the Go runtime soft memory limit. Configurable for testing.
+	heapInuse   func() uint64
+	memoryLimit func() int64
+
+	mtx      sync.Mutex
+	inflight int
+	limit    int
+	// Closed and replaced whenever a slot may have become available.
+	wake chan struct{}
+
+	effectiveMaxConcurrent prometheus.Gauge
+}
+
+func newAdaptiveGate(maxConcurrent int, memoryThreshold uint64, memoryLimitRatio float64, reg prometheus.Registerer) *adaptiveGate {
+	g := &adaptiveGate{
+		maxConcurrent:    maxConcurrent,
+		memoryThreshold:  memoryThreshold,
+		memoryLimitRatio: memoryLimitRatio,
+		heapInuse:        readHeapInuse,
+		memoryLimit:      readMemoryLimit,
+		limit:            maxConcurrent,
+		wake:             make(chan struct{}),
+		effectiveMaxConcurrent: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "gate_queries_concurrent_effective_max",
+			Help: "Number of maximum concurrent queries currently allowed, reduced under memory pressure.",
+		}),
+	}
+
+	g.effectiveMaxConcurrent.Set(float64(maxConcurrent))
+	return g
+}
+
+// Start implements gate.Gate.
+func (g *adaptiveGate) Start(ctx context.Context) error {
+	for {
+		g.mtx.Lock()
+		if g.inflight < g.limit {
+			g.inflight++
+			g.mtx.Unlock()
+			return nil
+		}
+		wake := g.wake
+		g.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Done implements gate.Gate.
+func (g *adaptiveGate) Done() {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.inflight <= 0 {
+		panic("gate.Done: more operations done than started")
+	}
+	g.inflight--
+	g.wakeUpLocked()
+}
+
+// updateLimit recomputes the effective max concurrency based on the current heap memory in-use.
+// The effective max concurrency is reduced immediately, but increased gradually.
+func (g *adaptiveGate) updateLimit() {
+	limit := g.computeLimit(g.heapInuse())
+
+	g.mtx.Lock()
+	maxIncrease := util_math.Max(1, int(float64(g.maxConcurrent)*adaptiveGateMaxIncreaseRatio))
+	if limit > g.limit+maxIncrease {
+		limit = g.limit + maxIncrease
+	}
+	increased := limit > g.limit
+	g.limit = limit
+	if increased {
+		g.wakeUpLocked()
+	}
+	g.mtx.Unlock()
+
+	g.effectiveMaxConcurrent.Set(float64(limit))
+}
+
+// computeLimit returns the max concurrency for the given heap memory in-use. The max concurrency is
+// linearly reduced from the configured one to 1 while the heap goes from the soft limit to the threshold.
+func (g *adaptiveGate) computeLimit(heapInuse uint64) int {
+	threshold := g.threshold()
+	softLimit := uint64(float64(threshold) * adaptiveGateSoftLimitRatio)
+
+	switch {
+	case threshold == 0 || heapInuse <= softLimit:
+		return g.maxConcurrent
+	case heapInuse >= threshold:
+		return 1
+	}
+
+	ratio := float64(threshold-heapInuse) / float64(threshold-softLimit)
+	return util_math.Max(1, int(float64(g.maxConcurrent)*ratio))
+}
+
+// threshold returns the heap memory in-use at which only 1 concurrent query is admitted. The threshold
+// computed from the Go runtime soft memory limit, if enabled and set, takes precedence over the static one.
+func (g *adaptiveGate) threshold() uint64 {
+	if g.memoryLimitRatio > 0 {
+		if limit := g.memoryLimit(); limit > 0 && limit < math.MaxInt64 {
+			return uint64(float64(limit) * g.memoryLimitRatio)
+		}
+	}
+	return g.memoryThreshold
+}
+
+func (g *adaptiveGate) wakeUpLocked() {
+	close(g.wake)
+	g.wake = make(chan struct{})
+}
+
+func readHeapInuse() uint64 {
+	samples := []metrics.Sample{{Name: heapObjectsBytesMetric}}
+	metrics.Read(samples)
+
+	if samples[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return samples[0].Value.Uint64()
+}
+
+// readMemoryLimit returns the Go runtime soft memory limit, which is math.MaxInt64 if not set.
+func readMemoryLimit() int64 {
+	// A negative input doesn't change the memory limit, and returns the current one.
+	return debug.SetMemoryLimit(-1)
+}
+
+var _ gate.Gate = &adaptiveGate{}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveGate_ComputeLimit(t *testing.T) {
+	g := newAdaptiveGate(100, 1000, 0, nil)
+
+	tests := map[string]struct {
+		heapInuse uint64
+		expected  int
+	}{
+		"below the soft limit": {
+			heapInuse: 500,
+			expected:  100,
+		},
+		"at the soft limit": {
+			heapInuse: 800,
+			expected:  100,
+		},
+		"between the soft limit and the threshold": {
+			heapInuse: 900,
+			expected:  50,
+		},
+		"close to the threshold": {
+			heapInuse: 999,
+			expected:  1,
+		},
+		"at the threshold": {
+			heapInuse: 1000,
+			expected:  1,
+		},
+		"above the threshold": {
+			heapInuse: 2000,
+			expected:  1,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, g.computeLimit(testData.heapInuse))
+		})
+	}
+}
+
+func TestAdaptiveGate_ShouldReduceAdmissionUnderMemoryPressure(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	g := newAdaptiveGate(2, 1000, 0, reg)
+
+	heapInuse := uint64(0)
+	g.heapInuse = func() uint64 { return heapInuse }
+
+	// Simulate the memory pressure.
+	heapInuse = 1000
+	g.updateLimit()
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP gate_queries_concurrent_effective_max Number of maximum concurrent queries currently allowed, reduced under memory pressure.
+		# TYPE gate_queries_concurrent_effective_max gauge
+		gate_queries_concurrent_effective_max 1
+	`)))
+
+	// The first request is admitted, while the second one has to wait.
+	require.NoError(t, g.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, g.Start(ctx), context.DeadlineExceeded)
+
+	// Once the memory pressure decreases, the waiting request is admitted.
+	admitted := make(chan error)
+	go func() {
+		admitted <- g.Start(context.Background())
+	}()
+
+	heapInuse = 0
+	g.updateLimit()
+
+	select {
+	case err := <-admitted:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the waiting request has not been admitted")
+	}
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP gate_queries_concurrent_effective_max Number of maximum concurrent queries currently allowed, reduced under memory pressure.
+		# TYPE gate_queries_concurrent_effective_max gauge
+		gate_queries_concurrent_effective_max 2
+	`)))
+
+	g.Done()
+	g.Done()
+	assert.Panics(t, g.Done)
+}
+
+func TestAdaptiveGate_ShouldAdmitRequestWhenAnotherOneIsDone(t *testing.T) {
+	g := newAdaptiveGate(1, 1000, 0, nil)
+	require.NoError(t, g.Start(context.Background()))
+
+	admitted := make(chan error)
+	go func() {
+		admitted <- g.Start(context.Background())
+	}()
+
+	g.Done()
+
+	select {
+	case err := <-admitted:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "the waiting request has not been admitted")
+	}
+}
+
+func TestAdaptiveGate_ComputeLimitFromMemoryLimit(t *testing.T) {
+	g := newAdaptiveGate(100, 1000, 0.5, nil)
+
+	// The static threshold is used when the memory limit is not set.
+	g.memoryLimit = func() int64 { return math.MaxInt64 }
+	assert.Equal(t, uint64(1000), g.threshold())
+	assert.Equal(t, 50, g.computeLimit(900))
+
+	// The threshold follows the memory limit.
+	memoryLimit := int64(4000)
+	g.memoryLimit = func() int64 { return memoryLimit }
+	assert.Equal(t, uint64(2000), g.threshold())
+	assert.Equal(t, 100, g.computeLimit(900))
+	assert.Equal(t, 50, g.computeLimit(1800))
+
+	memoryLimit = 2000
+	assert.Equal(t, 1, g.computeLimit(1800))
+
+	// The max concurrency is not reduced if no threshold is available.
+	g = newAdaptiveGate(100, 0, 0.5, nil)
+	g.memoryLimit = func() int64 { return math.MaxInt64 }
+	assert.Equal(t, 100, g.computeLimit(math.MaxUint64))
+}
+
+func TestAdaptiveGate_ShouldIncreaseTheLimitGradually(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	g := newAdaptiveGate(20, 1000, 0, reg)
+
+	heapInuse := uint64(1000)
+	g.heapInuse = func() uint64 { return heapInuse }
+
+	// The limit is reduced immediately.
+	g.updateLimit()
+	assert.Equal(t, 1, g.limit)
+
+	// The limit is increased by 10% of the max concurrency at each update.
+	heapInuse = 0
+	for _, expected := range []int{3, 5, 7, 9, 11, 13, 15, 17, 19, 20, 20} {
+		g.updateLimit()
+		assert.Equal(t, expected, g.limit)
+	}
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP gate_queries_concurrent_effective_max Number of maximum concurrent queries currently allowed, reduced under memory pressure.
+		# TYPE gate_queries_concurrent_effective_max gauge
+		gate_queries_concurrent_effective_max 20
+	`)))
+}
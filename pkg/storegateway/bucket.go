@@ -118,6 +118,13 @@ type BucketStore struct {
 	// or rely on the transparent caching bucket.
 	fineGrainedChunksCachingEnabled bool
 
+	// strictChunksTimeRangePruning controls whether chunks fully outside of the queried time range
+	// are never fetched, even if that means not caching the whole ranges of chunks.
+	strictChunksTimeRangePruning bool
+
+	// queryCostLimits are the tenant's limits used to reject a Series() call based on its estimated cost.
+	queryCostLimits queryCostLimits
+
 	// Query gate which limits the maximum amount of concurrent queries.
 	queryGate gate.Gate
 
@@ -128,11 +135,27 @@ type BucketStore struct {
 	seriesLimiterFactory SeriesLimiterFactory
 	partitioners         blockPartitioners
 
+	// Max number of GET operations and fetched bytes against the object storage for each Series() call.
+	// The budget is enforced only if the bucket client has been wrapped by the budgetedBucket. 0 to disable.
+	maxBucketGetOperations int
+	maxBucketFetchedBytes  uint64
+
+	// Max uncompressed size of the exemplars file of each block read by an Exemplars() call, 0 for no limit.
+	maxExemplarsFileSizeBytes int64
+
+	// chunksCacheTTL returns the TTL of the tenant's chunks stored in the chunks cache, 0 to use the configured one.
+	// chunksCacheBypass returns whether the tenant's chunks should bypass the chunks cache.
+	chunksCacheTTL    func() time.Duration
+	chunksCacheBypass func() bool
+
 	// Every how many posting offset entry we pool in heap memory. Default in Prometheus is 32.
 	postingOffsetsInMemSampling int
 
 	// Additional configuration for experimental indexheader.BinaryReader behaviour.
 	indexHeaderCfg indexheader.Config
+
+	// indexHeaderOpenFilesBudget limits the index-header files open across the stores sharing it. Optional.
+	indexHeaderOpenFilesBudget *indexheader.OpenFilesBudget
 }
 
 type noopCache struct{}
@@ -210,12 +233,62 @@ func WithChunkPool(chunkPool pool.Bytes) BucketStoreOption {
 	}
 }
 
+// WithBucketBudget sets the max number of GET operations and fetched bytes against the object storage
+// for each Series() call.
+func WithBucketBudget(maxGetOperations int, maxFetchedBytes uint64) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.maxBucketGetOperations = maxGetOperations
+		s.maxBucketFetchedBytes = maxFetchedBytes
+	}
+}
+
+// WithExemplarsMaxFileSize sets the max uncompressed size of the exemplars file of each block
+// read by an Exemplars() call.
+func WithExemplarsMaxFileSize(maxSizeBytes int64) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.maxExemplarsFileSizeBytes = maxSizeBytes
+	}
+}
+
+// WithChunksCacheOverrides sets the functions returning the tenant's chunks cache TTL and
+// whether the tenant's chunks should bypass the chunks cache.
+func WithChunksCacheOverrides(ttl func() time.Duration, bypass func() bool) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.chunksCacheTTL = ttl
+		s.chunksCacheBypass = bypass
+	}
+}
+
 func WithFineGrainedChunksCaching(enabled bool) BucketStoreOption {
 	return func(s *BucketStore) {
 		s.fineGrainedChunksCachingEnabled = enabled
 	}
 }
 
+func WithStrictChunksTimeRangePruning(enabled bool) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.strictChunksTimeRangePruning = enabled
+	}
+}
+
+// WithQueryCostLimits sets the functions returning the tenant's max number of blocks and max estimated
+// postings size, in bytes, a Series() call can touch before being rejected.
+func WithQueryCostLimits(maxBlocks func() int, maxEstimatedPostingsSize func() int) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.queryCostLimits = queryCostLimits{
+			maxBlocks:                maxBlocks,
+			maxEstimatedPostingsSize: maxEstimatedPostingsSize,
+		}
+	}
+}
+
+// WithIndexHeaderOpenFilesBudget sets the budget of the index-header files open, shared across stores.
+func WithIndexHeaderOpenFilesBudget(budget *indexheader.OpenFilesBudget) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.indexHeaderOpenFilesBudget = budget
+	}
+}
+
 // NewBucketStore creates a new bucket backed store that implements the store API against
 // an object store bucket. It is optimized to work against high latency backends.
 func NewBucketStore(
@@ -266,7 +339,7 @@ func NewBucketStore(
 	}
 
 	// Depend on the options
-	s.indexReaderPool = indexheader.NewReaderPool(s.logger, lazyIndexReaderEnabled, lazyIndexReaderIdleTimeout, metrics.indexHeaderReaderMetrics)
+	s.indexReaderPool = indexheader.NewReaderPool(s.logger, lazyIndexReaderEnabled, lazyIndexReaderIdleTimeout, metrics.indexHeaderReaderMetrics, s.indexHeaderOpenFilesBudget)
 
 	if err := os.MkdirAll(dir, 0750); err != nil {
 		return nil, errors.Wrap(err, "create dir")
@@ -384,6 +457,32 @@ func (s *BucketStore) InitialSync(ctx context.Context) error {
 	return nil
 }
 
+// WarmUpIndexHeader builds the index-header of a block which hasn't been loaded yet, so that it's already
+// available on the local disk once the block gets loaded by a subsequent sync. Returns whether the
+// index-header has been built.
+func (s *BucketStore) WarmUpIndexHeader(ctx context.Context, id ulid.ULID) (bool, error) {
+	if b := s.getBlock(id); b != nil {
+		return false, nil
+	}
+
+	indexHeaderPath := filepath.Join(s.dir, id.String(), block.IndexHeaderFilename)
+	if _, err := os.Stat(indexHeaderPath); err == nil {
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, errors.Wrap(err, "read index header")
+	}
+
+	// The block could be concurrently loaded by a sync, but the index-header is written to a temporary
+	// file which is atomically renamed, so a partially written index-header is never exposed. When enabled,
+	// the index-header prebuilt by the compactor is downloaded instead of being built.
+	if err := indexheader.WriteOrDownloadBinary(ctx, s.logger, s.bkt, id, indexHeaderPath, s.indexHeaderCfg); err != nil {
+		return false, errors.Wrap(err, "write index header")
+	}
+
+	level.Info(s.logger).Log("msg", "built index-header of block not loaded yet", "id", id)
+	return true, nil
+}
+
 func (s *BucketStore) getBlock(id ulid.ULID) *bucketBlock {
 	s.blocksMx.RLock()
 	defer s.blocksMx.RUnlock()
@@ -593,6 +692,29 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 	)
 	defer s.recordSeriesCallResult(stats)
 
+	if s.maxBucketGetOperations > 0 || s.maxBucketFetchedBytes > 0 {
+		budget := newBucketBudget(s.maxBucketGetOperations, s.maxBucketFetchedBytes, s.metrics.queriesDropped)
+		ctx = withBucketBudget(ctx, budget)
+
+		// The error returned by the object storage operations may be wrapped before getting
+		// here, so we return the budget error as is, to make sure the client gets its status.
+		defer func() {
+			if err != nil && budget.Err() != nil {
+				err = budget.Err()
+			}
+		}()
+	}
+
+	// The chunks are read through the caching bucket with the context of the chunk readers,
+	// so the tenant's overrides must be applied before opening them.
+	if !req.SkipChunks {
+		if s.isChunksCacheBypassed() {
+			ctx = bucketcache.WithSubrangeCachingDisabled(ctx)
+		} else if s.chunksCacheTTL != nil {
+			ctx = bucketcache.WithSubrangeTTL(ctx, s.chunksCacheTTL())
+		}
+	}
+
 	if req.Hints != nil {
 		reqHints := &hintspb.SeriesRequestHints{}
 		if err := types.UnmarshalAny(req.Hints, reqHints); err != nil {
@@ -618,6 +740,10 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storepb.Store_Serie
 
 	span.Finish()
 
+	if err := s.admitQuery(blocks, matchers); err != nil {
+		return err
+	}
+
 	var readers *bucketChunkReaders
 	if !req.SkipChunks {
 		readers = newChunkReaders(chunkReaders)
@@ -815,16 +941,20 @@ func (s *BucketStore) streamingSeriesSetForBlocks(
 	var set storepb.SeriesSet
 	if !req.SkipChunks {
 		var cache chunkscache.Cache
-		if s.fineGrainedChunksCachingEnabled {
+		if s.fineGrainedChunksCachingEnabled && !s.isChunksCacheBypassed() {
 			cache = s.chunksCache
 		}
-		set = newSeriesSetWithChunks(ctx, s.logger, s.userID, cache, *chunkReaders, mergedIterator, s.maxSeriesPerBatch, stats, req.MinTime, req.MaxTime)
+		set = newSeriesSetWithChunks(ctx, s.logger, s.userID, cache, *chunkReaders, mergedIterator, s.maxSeriesPerBatch, stats, req.MinTime, req.MaxTime, s.strictChunksTimeRangePruning)
 	} else {
 		set = newSeriesSetWithoutChunks(ctx, mergedIterator, stats)
 	}
 	return set, resHints, nil
 }
 
+func (s *BucketStore) isChunksCacheBypassed() bool {
+	return s.chunksCacheBypass != nil && s.chunksCacheBypass()
+}
+
 func (s *BucketStore) recordSeriesCallResult(safeStats *safeQueryStats) {
 	stats := safeStats.export()
 	s.metrics.seriesDataTouched.WithLabelValues("postings", "").Observe(float64(stats.postingsTouched))
@@ -857,6 +987,7 @@ func (s *BucketStore) recordSeriesCallResult(safeStats *safeQueryStats) {
 		s.metrics.seriesDataSizeTouched.WithLabelValues("chunks", "returned").Observe(float64(stats.chunksTouchedSizeSum))
 	}
 
+	s.metrics.seriesChunksPruned.Add(float64(stats.chunksPruned))
 	s.metrics.resultSeriesCount.Observe(float64(stats.mergedSeriesCount))
 	s.metrics.cachedPostingsCompressions.WithLabelValues(labelEncode).Add(float64(stats.cachedPostingsCompressions))
 	s.metrics.cachedPostingsCompressions.WithLabelValues(labelDecode).Add(float64(stats.cachedPostingsDecompressions))
@@ -910,6 +1041,32 @@ func (s *BucketStore) openBlocksForReading(ctx context.Context, skipChunks bool,
 
 // LabelNames implements the storepb.StoreServer interface.
 func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
+	var (
+		mtx  sync.Mutex
+		sets [][]string
+	)
+
+	anyHints, err := s.labelNames(ctx, req, func(names []string) error {
+		mtx.Lock()
+		sets = append(sets, names)
+		mtx.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &storepb.LabelNamesResponse{
+		Names: util.MergeSlices(sets...),
+		Hints: anyHints,
+	}, nil
+}
+
+// labelNames runs the input request against the blocks of the store, and returns the response hints.
+// The sorted label names of each block are passed to onBlockNames as soon as they're read, without
+// deduplicating them across blocks. onBlockNames is called concurrently, and the request fails if it
+// returns an error.
+func (s *BucketStore) labelNames(ctx context.Context, req *storepb.LabelNamesRequest, onBlockNames func([]string) error) (*types.Any, error) {
 	reqSeriesMatchers, err := storepb.MatchersToPromMatchers(req.Matchers...)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "translate request labels matchers").Error())
@@ -935,8 +1092,6 @@ func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesReq
 
 	s.blocksMx.RLock()
 
-	var mtx sync.Mutex
-	var sets [][]string
 	seriesLimiter := s.seriesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("series"))
 
 	for _, b := range s.blocks {
@@ -961,9 +1116,7 @@ func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesReq
 			}
 
 			if len(result) > 0 {
-				mtx.Lock()
-				sets = append(sets, result)
-				mtx.Unlock()
+				return onBlockNames(result)
 			}
 
 			return nil
@@ -985,10 +1138,7 @@ func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesReq
 		return nil, status.Error(codes.Unknown, errors.Wrap(err, "marshal label names response hints").Error())
 	}
 
-	return &storepb.LabelNamesResponse{
-		Names: util.MergeSlices(sets...),
-		Hints: anyHints,
-	}, nil
+	return anyHints, nil
 }
 
 func blockLabelNames(ctx context.Context, indexr *bucketIndexReader, matchers []*labels.Matcher, seriesLimiter SeriesLimiter, seriesPerBatch int, logger log.Logger) ([]string, error) {
@@ -1092,6 +1242,32 @@ func storeCachedLabelNames(ctx context.Context, indexCache indexcache.IndexCache
 
 // LabelValues implements the storepb.StoreServer interface.
 func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
+	var (
+		mtx  sync.Mutex
+		sets [][]string
+	)
+
+	anyHints, err := s.labelValues(ctx, req, func(values []string) error {
+		mtx.Lock()
+		sets = append(sets, values)
+		mtx.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &storepb.LabelValuesResponse{
+		Values: util.MergeSlices(sets...),
+		Hints:  anyHints,
+	}, nil
+}
+
+// labelValues runs the input request against the blocks of the store, and returns the response hints.
+// The sorted label values of each block are passed to onBlockValues as soon as they're read, without
+// deduplicating them across blocks. onBlockValues is called concurrently, and the request fails if it
+// returns an error.
+func (s *BucketStore) labelValues(ctx context.Context, req *storepb.LabelValuesRequest, onBlockValues func([]string) error) (*types.Any, error) {
 	reqSeriesMatchers, err := storepb.MatchersToPromMatchers(req.Matchers...)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "translate request labels matchers").Error())
@@ -1117,8 +1293,6 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 
 	s.blocksMx.RLock()
 
-	var mtx sync.Mutex
-	var sets [][]string
 	for _, b := range s.blocks {
 		b := b
 
@@ -1142,9 +1316,7 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 			}
 
 			if len(result) > 0 {
-				mtx.Lock()
-				sets = append(sets, result)
-				mtx.Unlock()
+				return onBlockValues(result)
 			}
 
 			return nil
@@ -1166,10 +1338,7 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 		return nil, status.Error(codes.Unknown, errors.Wrap(err, "marshal label values response hints").Error())
 	}
 
-	return &storepb.LabelValuesResponse{
-		Values: util.MergeSlices(sets...),
-		Hints:  anyHints,
-	}, nil
+	return anyHints, nil
 }
 
 // blockLabelValues provides the values of the label with requested name,
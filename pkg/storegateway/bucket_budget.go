@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/httpgrpc"
+)
+
+const (
+	ErrBucketGetOperationsLimitMessage = "exceeded bucket GET operations limit"
+	ErrBucketFetchedBytesLimitMessage  = "exceeded bucket fetched bytes limit"
+)
+
+type bucketBudgetContextKey int
+
+const bucketBudgetKey bucketBudgetContextKey = 0
+
+// bucketBudget tracks the GET operations run and the bytes fetched from the object storage
+// by a single request, failing the operations once the configured limits are exceeded.
+type bucketBudget struct {
+	getOperations *Limiter
+	fetchedBytes  *Limiter
+
+	// The error of the first limit exceeded, if any.
+	errMx sync.Mutex
+	err   error
+}
+
+func newBucketBudget(maxGetOperations int, maxFetchedBytes uint64, failedCounter *prometheus.CounterVec) *bucketBudget {
+	var maxOps uint64
+	if maxGetOperations > 0 {
+		maxOps = uint64(maxGetOperations)
+	}
+
+	return &bucketBudget{
+		getOperations: NewLimiter(maxOps, failedCounter.WithLabelValues("bucket-get-operations")),
+		fetchedBytes:  NewLimiter(maxFetchedBytes, failedCounter.WithLabelValues("bucket-fetched-bytes")),
+	}
+}
+
+func (b *bucketBudget) reserveGetOperation() error {
+	if err := b.getOperations.Reserve(1); err != nil {
+		return b.exceeded(ErrBucketGetOperationsLimitMessage, b.getOperations.limit)
+	}
+	return nil
+}
+
+func (b *bucketBudget) reserveFetchedBytes(num int) error {
+	if err := b.fetchedBytes.Reserve(uint64(num)); err != nil {
+		return b.exceeded(ErrBucketFetchedBytesLimitMessage, b.fetchedBytes.limit)
+	}
+	return nil
+}
+
+func (b *bucketBudget) exceeded(msg string, limit uint64) error {
+	err := httpgrpc.Errorf(http.StatusUnprocessableEntity, "%s (limit: %d)", msg, limit)
+
+	b.errMx.Lock()
+	defer b.errMx.Unlock()
+
+	if b.err == nil {
+		b.err = err
+	}
+	return err
+}
+
+// Err returns the error of the first limit exceeded, or nil if the budget has not been exceeded.
+// The error is a gRPC status error, so it can be returned as is to the client, regardless of how
+// it has been wrapped by the code running the operation.
+func (b *bucketBudget) Err() error {
+	b.errMx.Lock()
+	defer b.errMx.Unlock()
+
+	return b.err
+}
+
+// withBucketBudget returns a context carrying the input budget, which is honored by the
+// budgetedBucket for all the object storage operations run with the returned context.
+func withBucketBudget(ctx context.Context, budget *bucketBudget) context.Context {
+	return context.WithValue(ctx, bucketBudgetKey, budget)
+}
+
+func bucketBudgetFromContext(ctx context.Context) *bucketBudget {
+	budget, _ := ctx.Value(bucketBudgetKey).(*bucketBudget)
+	return budget
+}
+
+// budgetedBucket is an objstore.Bucket enforcing the bucketBudget carried by the context of the
+// GET operations. Operations whose context has no budget are not limited. The budgetedBucket
+// is expected to wrap the object storage client below the caching layer, so that only the
+// operations actually hitting the object storage are accounted.
+type budgetedBucket struct {
+	objstore.Bucket
+}
+
+func newBudgetedBucket(bkt objstore.Bucket) *budgetedBucket {
+	return &budgetedBucket{Bucket: bkt}
+}
+
+// Get implements objstore.Bucket.
+func (b *budgetedBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	budget := bucketBudgetFromContext(ctx)
+	if budget == nil {
+		return b.Bucket.Get(ctx, name)
+	}
+
+	if err := budget.reserveGetOperation(); err != nil {
+		return nil, err
+	}
+
+	r, err := b.Bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetedReader{ReadCloser: r, budget: budget}, nil
+}
+
+// GetRange implements objstore.Bucket.
+func (b *budgetedBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	budget := bucketBudgetFromContext(ctx)
+	if budget == nil {
+		return b.Bucket.GetRange(ctx, name, off, length)
+	}
+
+	if err := budget.reserveGetOperation(); err != nil {
+		return nil, err
+	}
+
+	r, err := b.Bucket.GetRange(ctx, name, off, length)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetedReader{ReadCloser: r, budget: budget}, nil
+}
+
+// ReaderWithExpectedErrs implements objstore.InstrumentedBucket.
+func (b *budgetedBucket) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+// WithExpectedErrs implements objstore.InstrumentedBucket.
+func (b *budgetedBucket) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	if ib, ok := b.Bucket.(objstore.InstrumentedBucket); ok {
+		return newBudgetedBucket(ib.WithExpectedErrs(fn))
+	}
+	return b
+}
+
+// budgetedReader accounts the bytes read from the object storage to the budget.
+type budgetedReader struct {
+	io.ReadCloser
+	budget *bucketBudget
+}
+
+func (r *budgetedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if budgetErr := r.budget.reserveFetchedBytes(n); budgetErr != nil {
+			return n, budgetErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestBudgetedBucket(t *testing.T) {
+	ctx := context.Background()
+
+	bkt := newBudgetedBucket(objstore.NewInMemBucket())
+	require.NoError(t, bkt.Upload(ctx, "object", bytes.NewReader([]byte("0123456789"))))
+
+	readObject := func(ctx context.Context) error {
+		r, err := bkt.GetRange(ctx, "object", 0, 10)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		_, err = io.ReadAll(r)
+		return err
+	}
+
+	t.Run("should not limit the operations without a budget", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			require.NoError(t, readObject(ctx))
+		}
+	})
+
+	t.Run("should fail once the max GET operations limit is exceeded", func(t *testing.T) {
+		failed := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "queries_dropped_total"}, []string{"reason"})
+
+		budget := newBucketBudget(2, 0, failed)
+		budgetCtx := withBucketBudget(ctx, budget)
+
+		require.NoError(t, readObject(budgetCtx))
+		require.NoError(t, readObject(budgetCtx))
+		assert.NoError(t, budget.Err())
+
+		err := readObject(budgetCtx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrBucketGetOperationsLimitMessage)
+		assert.Equal(t, err, budget.Err())
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(failed.WithLabelValues("bucket-get-operations")))
+	})
+
+	t.Run("should fail once the max fetched bytes limit is exceeded", func(t *testing.T) {
+		failed := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "queries_dropped_total"}, []string{"reason"})
+
+		budget := newBucketBudget(0, 15, failed)
+		budgetCtx := withBucketBudget(ctx, budget)
+
+		require.NoError(t, readObject(budgetCtx))
+
+		err := readObject(budgetCtx)
+		require.Error(t, err)
+		assert.True(t, strings.Contains(err.Error(), ErrBucketFetchedBytesLimitMessage))
+		assert.Equal(t, err, budget.Err())
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(failed.WithLabelValues("bucket-fetched-bytes")))
+	})
+}
@@ -632,6 +632,64 @@ func TestBucketStore_Series_ChunksLimiter_e2e(t *testing.T) {
 	}
 }
 
+func TestBucketStore_Series_BucketBudget_e2e(t *testing.T) {
+	cases := map[string]struct {
+		maxGetOperations int
+		maxFetchedBytes  uint64
+		expectedErr      string
+	}{
+		"should succeed if the budget is disabled": {},
+		"should succeed if the budget is not exceeded": {
+			maxGetOperations: 1000,
+			maxFetchedBytes:  10 * 1024 * 1024,
+		},
+		"should fail if the max GET operations limit is exceeded - 422": {
+			maxGetOperations: 1,
+			expectedErr:      ErrBucketGetOperationsLimitMessage,
+		},
+		"should fail if the max fetched bytes limit is exceeded - 422": {
+			maxFetchedBytes: 1,
+			expectedErr:     ErrBucketFetchedBytesLimitMessage,
+		},
+	}
+
+	for testName, testData := range cases {
+		t.Run(testName, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			bkt := newBudgetedBucket(objstore.NewInMemBucket())
+
+			s := prepareStoreWithTestBlocks(t, bkt, defaultPrepareStoreConfig(t))
+			assert.NoError(t, s.store.SyncBlocks(ctx))
+
+			s.store.maxBucketGetOperations = testData.maxGetOperations
+			s.store.maxBucketFetchedBytes = testData.maxFetchedBytes
+
+			req := &storepb.SeriesRequest{
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_EQ, Name: "a", Value: "1"},
+				},
+				MinTime: timestamp.FromTime(minTime),
+				MaxTime: timestamp.FromTime(maxTime),
+			}
+
+			srv := newBucketStoreTestServer(t, s.store)
+			seriesSet, _, _, err := srv.Series(context.Background(), req)
+
+			if testData.expectedErr == "" {
+				require.NoError(t, err)
+				assert.Len(t, seriesSet, 4)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testData.expectedErr)
+				status, ok := status.FromError(err)
+				assert.Equal(t, true, ok)
+				assert.Equal(t, codes.Code(http.StatusUnprocessableEntity), status.Code())
+			}
+		})
+	}
+}
+
 func TestBucketStore_LabelNames_e2e(t *testing.T) {
 	foreachStore(t, func(t *testing.T, newSuite suiteFactory) {
 		ctx, cancel := context.WithCancel(context.Background())
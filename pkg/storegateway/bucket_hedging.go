@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	hedgedOpGet      = "get"
+	hedgedOpGetRange = "get_range"
+)
+
+type hedgedBucketMetrics struct {
+	hedgedRequests  *prometheus.CounterVec
+	hedgedWins      *prometheus.CounterVec
+	budgetExhausted *prometheus.CounterVec
+}
+
+func newHedgedBucketMetrics(reg prometheus.Registerer) *hedgedBucketMetrics {
+	return &hedgedBucketMetrics{
+		hedgedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_bucket_store_hedged_requests_total",
+			Help: "Total number of hedged GET requests sent to the object storage.",
+		}, []string{"operation"}),
+		hedgedWins: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_bucket_store_hedged_requests_won_total",
+			Help: "Total number of hedged GET requests to the object storage which returned before the original request.",
+		}, []string{"operation"}),
+		budgetExhausted: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_bucket_store_hedging_budget_exhausted_total",
+			Help: "Total number of GET requests to the object storage which have not been hedged because the hedging budget was exhausted.",
+		}, []string{"operation"}),
+	}
+}
+
+// hedgedBucket is an objstore.Bucket hedging the GET operations: if a GET hasn't returned within
+// the configured delay, a second identical GET is sent, and the first one to successfully return
+// is used. The number of hedged requests is limited by the hedging budget.
+type hedgedBucket struct {
+	objstore.Bucket
+
+	delay   time.Duration
+	budget  *util.HedgingBudget
+	metrics *hedgedBucketMetrics
+}
+
+func newHedgedBucket(bkt objstore.Bucket, delay time.Duration, budget float64, reg prometheus.Registerer) *hedgedBucket {
+	return &hedgedBucket{
+		Bucket:  bkt,
+		delay:   delay,
+		budget:  util.NewHedgingBudget(budget),
+		metrics: newHedgedBucketMetrics(reg),
+	}
+}
+
+// Get implements objstore.Bucket.
+func (b *hedgedBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.hedge(ctx, hedgedOpGet, func(ctx context.Context) (io.ReadCloser, error) {
+		return b.Bucket.Get(ctx, name)
+	})
+}
+
+// GetRange implements objstore.Bucket.
+func (b *hedgedBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	return b.hedge(ctx, hedgedOpGetRange, func(ctx context.Context) (io.ReadCloser, error) {
+		return b.Bucket.GetRange(ctx, name, off, length)
+	})
+}
+
+// ReaderWithExpectedErrs implements objstore.InstrumentedBucket.
+func (b *hedgedBucket) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+// WithExpectedErrs implements objstore.InstrumentedBucket.
+func (b *hedgedBucket) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	if ib, ok := b.Bucket.(objstore.InstrumentedBucket); ok {
+		return &hedgedBucket{
+			Bucket:  ib.WithExpectedErrs(fn),
+			delay:   b.delay,
+			budget:  b.budget,
+			metrics: b.metrics,
+		}
+	}
+	return b
+}
+
+type hedgedResult struct {
+	reader io.ReadCloser
+	err    error
+	idx    int
+}
+
+func (b *hedgedBucket) hedge(ctx context.Context, op string, get func(context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	b.budget.AddRequest()
+
+	// Each request runs with its own context, so that the one which loses the race can be canceled
+	// without affecting the reader returned by the other one.
+	results := make(chan hedgedResult, 2)
+	var cancels []context.CancelFunc
+	start := func() {
+		reqCtx, cancel := context.WithCancel(ctx)
+		idx := len(cancels)
+		cancels = append(cancels, cancel)
+
+		go func() {
+			r, err := get(reqCtx)
+			results <- hedgedResult{reader: r, err: err, idx: idx}
+		}()
+	}
+
+	start()
+	pending := 1
+
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if b.budget.TryHedge() {
+				b.metrics.hedgedRequests.WithLabelValues(op).Inc()
+				start()
+				pending++
+			} else {
+				b.metrics.budgetExhausted.WithLabelValues(op).Inc()
+			}
+
+		case res := <-results:
+			pending--
+
+			if res.err != nil {
+				cancels[res.idx]()
+				if pending > 0 {
+					// Wait for the other request.
+					continue
+				}
+				return nil, res.err
+			}
+
+			if res.idx > 0 {
+				b.metrics.hedgedWins.WithLabelValues(op).Inc()
+			}
+
+			// Cancel the other request, if any, and release its reader once it returns.
+			if pending > 0 {
+				for idx, cancel := range cancels {
+					if idx != res.idx {
+						cancel()
+					}
+				}
+				go func(pending int) {
+					for i := 0; i < pending; i++ {
+						if other := <-results; other.err == nil {
+							_ = other.reader.Close()
+						}
+					}
+				}(pending)
+			}
+
+			return &cancelOnCloseReader{ReadCloser: res.reader, cancel: cancels[res.idx]}, nil
+		}
+	}
+}
+
+// cancelOnCloseReader cancels the context of the request which returned the reader once it's closed.
+type cancelOnCloseReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnCloseReader) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+)
+
+// slowFirstGetBucket blocks the first GET operation until its context is canceled.
+type slowFirstGetBucket struct {
+	objstore.Bucket
+
+	calls    atomic.Int64
+	canceled chan struct{}
+	err      error
+}
+
+func (b *slowFirstGetBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if b.calls.Inc() == 1 {
+		<-ctx.Done()
+		close(b.canceled)
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func TestHedgedBucket(t *testing.T) {
+	const hedgingDelay = 50 * time.Millisecond
+
+	ctx := context.Background()
+	inmem := objstore.NewInMemBucket()
+	require.NoError(t, inmem.Upload(ctx, "object", bytes.NewReader([]byte("0123456789"))))
+
+	t.Run("should send a hedged request if the original one hasn't returned within the delay", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		slow := &slowFirstGetBucket{Bucket: inmem, canceled: make(chan struct{})}
+		bkt := newHedgedBucket(slow, hedgingDelay, 1, reg)
+
+		r, err := bkt.GetRange(ctx, "object", 0, 4)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, "0123", string(data))
+
+		// The original request should have been canceled.
+		select {
+		case <-slow.canceled:
+		case <-time.After(time.Second):
+			t.Fatal("the original request has not been canceled")
+		}
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_bucket_store_hedged_requests_total Total number of hedged GET requests sent to the object storage.
+			# TYPE cortex_bucket_store_hedged_requests_total counter
+			cortex_bucket_store_hedged_requests_total{operation="get_range"} 1
+
+			# HELP cortex_bucket_store_hedged_requests_won_total Total number of hedged GET requests to the object storage which returned before the original request.
+			# TYPE cortex_bucket_store_hedged_requests_won_total counter
+			cortex_bucket_store_hedged_requests_won_total{operation="get_range"} 1
+		`), "cortex_bucket_store_hedged_requests_total", "cortex_bucket_store_hedged_requests_won_total", "cortex_bucket_store_hedging_budget_exhausted_total"))
+	})
+
+	t.Run("should not send a hedged request if the budget is exhausted", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		slow := &slowFirstGetBucket{Bucket: inmem, canceled: make(chan struct{})}
+		bkt := newHedgedBucket(slow, hedgingDelay, 0, reg)
+
+		reqCtx, cancel := context.WithTimeout(ctx, 4*hedgingDelay)
+		defer cancel()
+
+		_, err := bkt.GetRange(reqCtx, "object", 0, 4)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int64(1), slow.calls.Load())
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_bucket_store_hedging_budget_exhausted_total Total number of GET requests to the object storage which have not been hedged because the hedging budget was exhausted.
+			# TYPE cortex_bucket_store_hedging_budget_exhausted_total counter
+			cortex_bucket_store_hedging_budget_exhausted_total{operation="get_range"} 1
+		`), "cortex_bucket_store_hedged_requests_total", "cortex_bucket_store_hedged_requests_won_total", "cortex_bucket_store_hedging_budget_exhausted_total"))
+	})
+
+	t.Run("should return the error if all requests fail", func(t *testing.T) {
+		expectedErr := errors.New("mocked error")
+		slow := &slowFirstGetBucket{Bucket: inmem, canceled: make(chan struct{}), err: expectedErr}
+		bkt := newHedgedBucket(slow, hedgingDelay, 1, nil)
+
+		reqCtx, cancel := context.WithTimeout(ctx, 4*hedgingDelay)
+		defer cancel()
+
+		// The hedged request fails, so the original one is waited for until the context expires.
+		_, err := bkt.GetRange(reqCtx, "object", 0, 4)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int64(2), slow.calls.Load())
+	})
+
+	t.Run("should not hedge requests returning within the delay", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		bkt := newHedgedBucket(inmem, time.Minute, 1, reg)
+
+		r, err := bkt.Get(ctx, "object")
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, "0123456789", string(data))
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_bucket_store_hedged_requests_total", "cortex_bucket_store_hedging_budget_exhausted_total"))
+	})
+}
@@ -17,7 +17,9 @@ import (
 	"github.com/thanos-io/objstore"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
 	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 )
@@ -35,6 +37,13 @@ type BucketIndexMetadataFetcher struct {
 	logger      log.Logger
 	filters     []block.MetadataFilter
 	metrics     *block.FetcherMetrics
+
+	// If set, the blocks tier read from the bucket index is propagated to the tiered bucket,
+	// so that the blocks are read from the storage they're stored in.
+	tieredBucket *mimir_tsdb.TieredBucket
+
+	// If set, the cache keys of the bucket index are versioned with the tenant cache epoch.
+	cacheEpoch *cacheEpoch
 }
 
 func NewBucketIndexMetadataFetcher(
@@ -69,6 +78,9 @@ func (f *BucketIndexMetadataFetcher) Fetch(ctx context.Context) (metas map[ulid.
 	f.metrics.Syncs.Inc()
 
 	// Fetch the bucket index.
+	if f.cacheEpoch != nil {
+		ctx = bucketcache.WithCacheKeyEpoch(ctx, f.cacheEpoch.tenantKeyEpoch())
+	}
 	idx, err := bucketindex.ReadIndex(ctx, f.bkt, f.userID, f.cfgProvider, f.logger)
 	if errors.Is(err, bucketindex.ErrIndexNotFound) {
 		// This is a legit case happening when the first blocks of a tenant have recently been uploaded by ingesters
@@ -97,8 +109,17 @@ func (f *BucketIndexMetadataFetcher) Fetch(ctx context.Context) (metas map[ulid.
 
 	// Build block metas out of the index.
 	metas = make(map[ulid.ULID]*metadata.Meta, len(idx.Blocks))
+	var coldBlocks []ulid.ULID
 	for _, b := range idx.Blocks {
 		metas[b.ID] = b.ThanosMeta()
+
+		if b.Tier == bucketindex.BlockTierCold {
+			coldBlocks = append(coldBlocks, b.ID)
+		}
+	}
+
+	if f.tieredBucket != nil {
+		f.tieredBucket.SetColdBlocks(f.userID, coldBlocks)
 	}
 
 	for _, filter := range f.filters {
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// bucketIndexWriter periodically updates the bucket index of the tenants owned by the store-gateway.
+// It's meant for deployments running without the compactor, which is otherwise responsible for
+// keeping the bucket index updated. Unlike the compactor, it doesn't apply the retention, and
+// doesn't delete any block.
+type bucketIndexWriter struct {
+	services.Service
+
+	bkt         objstore.Bucket
+	cfgProvider bucket.TenantConfigProvider
+	isOwned     func(userID string) (bool, error)
+	concurrency int
+	logger      log.Logger
+
+	runsStarted       prometheus.Counter
+	runsCompleted     prometheus.Counter
+	runsFailed        prometheus.Counter
+	tenantLastUpdated *prometheus.GaugeVec
+}
+
+func newBucketIndexWriter(interval time.Duration, concurrency int, bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider, isOwned func(userID string) (bool, error), logger log.Logger, reg prometheus.Registerer) *bucketIndexWriter {
+	w := &bucketIndexWriter{
+		bkt:         bkt,
+		cfgProvider: cfgProvider,
+		isOwned:     isOwned,
+		concurrency: concurrency,
+		logger:      logger,
+		runsStarted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_storegateway_bucket_index_writer_runs_started_total",
+			Help: "Total number of bucket index update runs started.",
+		}),
+		runsCompleted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_storegateway_bucket_index_writer_runs_completed_total",
+			Help: "Total number of bucket index update runs successfully completed.",
+		}),
+		runsFailed: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_storegateway_bucket_index_writer_runs_failed_total",
+			Help: "Total number of bucket index update runs failed.",
+		}),
+		tenantLastUpdated: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_storegateway_bucket_index_last_successful_update_timestamp_seconds",
+			Help: "Timestamp of the last successful update of a tenant's bucket index by the store-gateway.",
+		}, []string{"user"}),
+	}
+
+	w.Service = services.NewTimerService(interval, nil, w.iteration, nil)
+	return w
+}
+
+func (w *bucketIndexWriter) iteration(ctx context.Context) error {
+	w.runsStarted.Inc()
+
+	if err := w.updateIndexes(ctx); err != nil {
+		w.runsFailed.Inc()
+		level.Warn(w.logger).Log("msg", "failed to update the bucket index", "err", err)
+		// Don't return the error, otherwise the service would stop.
+		return nil
+	}
+
+	w.runsCompleted.Inc()
+	return nil
+}
+
+func (w *bucketIndexWriter) updateIndexes(ctx context.Context) error {
+	userIDs, err := mimir_tsdb.ListUsers(ctx, w.bkt)
+	if err != nil {
+		return errors.Wrap(err, "list tenants")
+	}
+
+	owned := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		ok, err := w.isOwned(userID)
+		if err != nil {
+			level.Warn(w.logger).Log("msg", "failed to check if the bucket index of the tenant is owned by the store-gateway", "user", userID, "err", err)
+		}
+		if ok {
+			owned = append(owned, userID)
+		} else {
+			// Remove the metrics of the tenants not owned anymore.
+			w.tenantLastUpdated.DeleteLabelValues(userID)
+		}
+	}
+
+	return concurrency.ForEachUser(ctx, owned, w.concurrency, func(ctx context.Context, userID string) error {
+		if err := w.updateIndex(ctx, userID); err != nil {
+			return errors.Wrapf(err, "update bucket index for tenant %s", userID)
+		}
+		return nil
+	})
+}
+
+func (w *bucketIndexWriter) updateIndex(ctx context.Context, userID string) error {
+	userLogger := util_log.WithUserID(userID, w.logger)
+
+	idx, err := bucketindex.ReadIndex(ctx, w.bkt, userID, w.cfgProvider, userLogger)
+	if errors.Is(err, bucketindex.ErrIndexCorrupted) {
+		level.Warn(userLogger).Log("msg", "found a corrupted bucket index, recreating it")
+	} else if err != nil && !errors.Is(err, bucketindex.ErrIndexNotFound) {
+		return err
+	}
+
+	idx, _, err = bucketindex.NewUpdater(w.bkt, userID, w.cfgProvider, userLogger).UpdateIndex(ctx, idx)
+	if err != nil {
+		return err
+	}
+
+	if err := bucketindex.WriteIndex(ctx, w.bkt, userID, w.cfgProvider, idx); err != nil {
+		return err
+	}
+
+	w.tenantLastUpdated.WithLabelValues(userID).SetToCurrentTime()
+	level.Debug(userLogger).Log("msg", "updated bucket index", "blocks", len(idx.Blocks), "deletion_marks", len(idx.BlockDeletionMarks))
+	return nil
+}
+
+// bucketIndexOwner returns a function checking whether the bucket index of a tenant should be updated
+// by the input store-gateway instance. Each tenant's bucket index is updated by only one store-gateway,
+// chosen among the store-gateways of the tenant's shard.
+func bucketIndexOwner(r *ring.Ring, instanceAddr string, limits ShardingLimits) func(userID string) (bool, error) {
+	return func(userID string) (bool, error) {
+		hasher := fnv.New32a()
+		_, _ = hasher.Write([]byte(userID))
+
+		rs, err := GetShuffleShardingSubring(r, userID, limits).Get(hasher.Sum32(), BlocksOwnerSync, nil, nil, nil)
+		if err != nil {
+			return false, err
+		}
+		if len(rs.Instances) == 0 {
+			return false, nil
+		}
+		return rs.Instances[0].Addr == instanceAddr, nil
+	}
+}
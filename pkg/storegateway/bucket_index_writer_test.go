@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+)
+
+func TestBucketIndexWriter(t *testing.T) {
+	ctx := context.Background()
+	bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	bkt = bucketindex.BucketWithGlobalMarkers(bkt)
+
+	user1Block1 := mimir_testutil.MockStorageBlock(t, bkt, "user-1", 10, 20)
+	user1Block2 := mimir_testutil.MockStorageBlock(t, bkt, "user-1", 20, 30)
+	mimir_testutil.MockStorageDeletionMark(t, bkt, "user-1", user1Block2)
+	mimir_testutil.MockStorageBlock(t, bkt, "user-2", 10, 20)
+
+	reg := prometheus.NewPedanticRegistry()
+	isOwned := func(userID string) (bool, error) { return userID == "user-1", nil }
+	w := newBucketIndexWriter(time.Hour, 1, bkt, nil, isOwned, log.NewNopLogger(), reg)
+
+	require.NoError(t, w.iteration(ctx))
+
+	// The bucket index of the owned tenant has been written.
+	idx, err := bucketindex.ReadIndex(ctx, bkt, "user-1", nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ulid.ULID{user1Block1.ULID, user1Block2.ULID}, idx.Blocks.GetULIDs())
+	assert.Equal(t, []ulid.ULID{user1Block2.ULID}, idx.BlockDeletionMarks.GetULIDs())
+
+	// The bucket index of the tenant not owned has not been written.
+	_, err = bucketindex.ReadIndex(ctx, bkt, "user-2", nil, log.NewNopLogger())
+	assert.ErrorIs(t, err, bucketindex.ErrIndexNotFound)
+
+	// New blocks are added to the existing bucket index.
+	user1Block3 := mimir_testutil.MockStorageBlock(t, bkt, "user-1", 30, 40)
+	require.NoError(t, w.iteration(ctx))
+
+	idx, err = bucketindex.ReadIndex(ctx, bkt, "user-1", nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ulid.ULID{user1Block1.ULID, user1Block2.ULID, user1Block3.ULID}, idx.Blocks.GetULIDs())
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_storegateway_bucket_index_writer_runs_started_total Total number of bucket index update runs started.
+		# TYPE cortex_storegateway_bucket_index_writer_runs_started_total counter
+		cortex_storegateway_bucket_index_writer_runs_started_total 2
+
+		# HELP cortex_storegateway_bucket_index_writer_runs_completed_total Total number of bucket index update runs successfully completed.
+		# TYPE cortex_storegateway_bucket_index_writer_runs_completed_total counter
+		cortex_storegateway_bucket_index_writer_runs_completed_total 2
+
+		# HELP cortex_storegateway_bucket_index_writer_runs_failed_total Total number of bucket index update runs failed.
+		# TYPE cortex_storegateway_bucket_index_writer_runs_failed_total counter
+		cortex_storegateway_bucket_index_writer_runs_failed_total 0
+	`), "cortex_storegateway_bucket_index_writer_runs_started_total", "cortex_storegateway_bucket_index_writer_runs_completed_total", "cortex_storegateway_bucket_index_writer_runs_failed_total"))
+}
+
+func TestBucketIndexOwner(t *testing.T) {
+	ctx := context.Background()
+	registeredAt := time.Now()
+
+	store, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	require.NoError(t, store.CAS(ctx, "test", func(in interface{}) (interface{}, bool, error) {
+		d := ring.NewDesc()
+		d.AddIngester("instance-1", "127.0.0.1", "", []uint32{1 << 30, 3 << 30}, ring.ACTIVE, registeredAt)
+		d.AddIngester("instance-2", "127.0.0.2", "", []uint32{2 << 30}, ring.ACTIVE, registeredAt)
+		d.AddIngester("instance-3", "127.0.0.3", "", []uint32{(3 << 30) + (1 << 29)}, ring.ACTIVE, registeredAt)
+		return d, true, nil
+	}))
+
+	cfg := ring.Config{
+		ReplicationFactor:    3,
+		HeartbeatTimeout:     time.Minute,
+		SubringCacheDisabled: true,
+	}
+	r, err := ring.NewWithStoreClientAndStrategy(cfg, "test", "test", store, ring.NewIgnoreUnhealthyInstancesReplicationStrategy(), nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, r))
+	t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, r)) })
+	require.NoError(t, ring.WaitInstanceState(ctx, r, "instance-1", ring.ACTIVE))
+
+	for _, shardSize := range []int{0, 2} {
+		limits := &shardingLimitsMock{storeGatewayTenantShardSize: shardSize}
+
+		// Each tenant's bucket index must be owned by exactly one store-gateway, even if the
+		// tenant's blocks are replicated across multiple store-gateways.
+		for _, userID := range []string{"user-1", "user-2", "user-3", "user-4"} {
+			owners := 0
+			for _, addr := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"} {
+				owned, err := bucketIndexOwner(r, addr, limits)(userID)
+				require.NoError(t, err)
+				if owned {
+					owners++
+				}
+			}
+			assert.Equal(t, 1, owners, "shard size: %d, user: %s", shardSize, userID)
+		}
+	}
+}
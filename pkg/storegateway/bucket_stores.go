@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/cache"
 	"github.com/grafana/dskit/gate"
+	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -31,6 +32,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/storegateway/chunkscache"
 	"github.com/grafana/mimir/pkg/storegateway/indexcache"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
 	"github.com/grafana/mimir/pkg/storegateway/storepb"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/pool"
@@ -48,6 +50,7 @@ type BucketStores struct {
 	cfg                tsdb.BlocksStorageConfig
 	limits             *validation.Overrides
 	bucket             objstore.Bucket
+	tieredBucket       *tsdb.TieredBucket // Set only if the cold storage is enabled.
 	bucketStoreMetrics *BucketStoreMetrics
 	metaFetcherMetrics *MetadataFetcherMetrics
 	shardingStrategy   ShardingStrategy
@@ -70,9 +73,16 @@ type BucketStores struct {
 	// Gate used to limit query concurrency across all tenants.
 	queryGate gate.Gate
 
-	// Keeps a bucket store for each tenant.
-	storesMu sync.RWMutex
-	stores   map[string]*BucketStore
+	// Adaptive gate reducing the query concurrency under memory pressure. Nil if disabled.
+	adaptiveQueryGate *adaptiveGate
+
+	// Budget of the index-header files open across all tenants. Nil if disabled.
+	indexHeaderOpenFilesBudget *indexheader.OpenFilesBudget
+
+	// Keeps a bucket store and the cache epoch for each tenant.
+	storesMu    sync.RWMutex
+	stores      map[string]*BucketStore
+	cacheEpochs map[string]*cacheEpoch
 
 	// Metrics.
 	syncTimes         prometheus.Histogram
@@ -80,16 +90,36 @@ type BucketStores struct {
 	tenantsDiscovered prometheus.Gauge
 	tenantsSynced     prometheus.Gauge
 	blocksLoaded      prometheus.GaugeFunc
+
+	indexHeaderWarmups prometheus.Counter
 }
 
 // NewBucketStores makes a new BucketStores.
 func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStrategy, bucketClient objstore.Bucket, limits *validation.Overrides, logger log.Logger, reg prometheus.Registerer) (*BucketStores, error) {
+	if err := validateMetadataFilters(cfg.BucketStore.MetadataFilters); err != nil {
+		return nil, err
+	}
+
 	chunksCacheClient, err := cache.CreateClient("chunks-cache", cfg.BucketStore.ChunksCache.BackendConfig, logger, prometheus.WrapRegistererWithPrefix("thanos_", reg))
 	if err != nil {
 		return nil, errors.Wrapf(err, "chunks-cache")
 	}
 
-	cachingBucket, err := tsdb.CreateCachingBucket(chunksCacheClient, cfg.BucketStore.ChunksCache, cfg.BucketStore.MetadataCache, bucketClient, logger, reg)
+	// The GET operations are hedged right above the object storage client, so that the hedged
+	// requests are not accounted by the per-request bucket budget.
+	hedgedBucketClient := bucketClient
+	if cfg.BucketStore.HedgedRequestsDelay > 0 {
+		hedgedBucketClient = newHedgedBucket(bucketClient, cfg.BucketStore.HedgedRequestsDelay, cfg.BucketStore.HedgedRequestsBudget, reg)
+	}
+
+	// The per-request bucket budget is enforced below the caching layer, so that only
+	// the operations actually hitting the object storage are accounted.
+	budgetedBucketClient := hedgedBucketClient
+	if cfg.BucketStore.SeriesMaxBucketGetOperations > 0 || cfg.BucketStore.SeriesMaxBucketFetchedBytes > 0 {
+		budgetedBucketClient = newBudgetedBucket(hedgedBucketClient)
+	}
+
+	cachingBucket, err := tsdb.CreateCachingBucket(chunksCacheClient, cfg.BucketStore.ChunksCache, cfg.BucketStore.MetadataCache, budgetedBucketClient, logger, reg)
 	if err != nil {
 		return nil, errors.Wrapf(err, "create caching bucket")
 	}
@@ -97,18 +127,30 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 	// The number of concurrent queries against the tenants BucketStores are limited.
 	queryGateReg := prometheus.WrapRegistererWithPrefix("cortex_bucket_stores_", reg)
 	queryGate := gate.NewBlocking(cfg.BucketStore.MaxConcurrent)
+
+	// When enabled, the number of concurrent queries is also reduced under memory pressure.
+	var adaptiveQueryGate *adaptiveGate
+	if cfg.BucketStore.AdaptiveMaxConcurrentEnabled() {
+		adaptiveQueryGate = newAdaptiveGate(cfg.BucketStore.MaxConcurrent, cfg.BucketStore.MaxConcurrentMemoryThreshold, cfg.BucketStore.MaxConcurrentMemoryLimitRatio, queryGateReg)
+		queryGate = adaptiveQueryGate
+	}
 	queryGate = gate.NewInstrumented(queryGateReg, cfg.BucketStore.MaxConcurrent, queryGate)
 
+	tieredBucket, _ := bucketClient.(*tsdb.TieredBucket)
+
 	u := &BucketStores{
 		logger:             logger,
 		cfg:                cfg,
 		limits:             limits,
 		bucket:             cachingBucket,
+		tieredBucket:       tieredBucket,
 		shardingStrategy:   shardingStrategy,
 		stores:             map[string]*BucketStore{},
+		cacheEpochs:        map[string]*cacheEpoch{},
 		bucketStoreMetrics: NewBucketStoreMetrics(reg),
 		metaFetcherMetrics: NewMetadataFetcherMetrics(),
 		queryGate:          queryGate,
+		adaptiveQueryGate:  adaptiveQueryGate,
 		partitioners:       newGapBasedPartitioners(cfg.BucketStore.PartitionerMaxGapBytes, reg),
 		seriesHashCache:    hashcache.NewSeriesHashCache(cfg.BucketStore.SeriesHashCacheMaxBytes),
 		syncBackoffConfig: backoff.Config{
@@ -140,13 +182,23 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 		Name: "cortex_bucket_store_blocks_loaded",
 		Help: "Number of currently loaded blocks.",
 	}, u.getBlocksLoadedMetric)
+	u.indexHeaderWarmups = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_bucket_stores_index_header_warmups_total",
+		Help: "Total number of index-headers built in advance for blocks having a replacement mark.",
+	})
+
+	// When enabled, the index-header files open across all tenants are limited unloading the least recently used.
+	if cfg.BucketStore.IndexHeaderLazyLoadingEnabled && cfg.BucketStore.IndexHeader.MaxOpenFiles > 0 {
+		u.indexHeaderOpenFilesBudget = indexheader.NewOpenFilesBudget(cfg.BucketStore.IndexHeader.MaxOpenFiles, u.bucketStoreMetrics.indexHeaderReaderMetrics, logger, prometheus.WrapRegistererWithPrefix("cortex_bucket_store_", reg))
+	}
 
 	// Init the index cache.
 	if u.indexCache, err = tsdb.NewIndexCache(cfg.BucketStore.IndexCache, logger, reg); err != nil {
 		return nil, errors.Wrap(err, "create index cache")
 	}
 
-	chunksCache, err := chunkscache.NewChunksCache(logger, chunksCacheClient, reg)
+	// The chunks are cached for a tenant ID including the tenant cache epoch, if any.
+	chunksCache, err := chunkscache.NewChunksCache(logger, chunksCacheClient, cacheKeyUserIDLimits{limits}, reg)
 	if err != nil {
 		return nil, errors.Wrap(err, "create chunks cache")
 	}
@@ -254,6 +306,12 @@ func (u *BucketStores) syncUsersBlocks(ctx context.Context, f func(context.Conte
 			defer wg.Done()
 
 			for job := range jobs {
+				if err := u.syncCacheEpoch(ctx, job.userID); err != nil {
+					errsMx.Lock()
+					errs.Add(errors.Wrapf(err, "failed to synchronize cache epoch for user %s", job.userID))
+					errsMx.Unlock()
+				}
+
 				if err := f(ctx, job.store); err != nil {
 					errsMx.Lock()
 					errs.Add(errors.Wrapf(err, "failed to synchronize TSDB blocks for user %s", job.userID))
@@ -298,6 +356,45 @@ func (u *BucketStores) syncUsersBlocks(ctx context.Context, f func(context.Conte
 	return errs.Err()
 }
 
+// syncCacheEpoch reads the cache epoch of the given user from the bucket, so that the entries cached
+// before the last cache invalidation are no longer used.
+func (u *BucketStores) syncCacheEpoch(ctx context.Context, userID string) error {
+	u.storesMu.RLock()
+	epoch := u.cacheEpochs[userID]
+	u.storesMu.RUnlock()
+
+	if epoch == nil {
+		return nil
+	}
+
+	cacheEpoch, err := tsdb.ReadCacheEpoch(ctx, u.bucket, userID)
+	if err != nil {
+		return err
+	}
+
+	epoch.set(cacheEpoch)
+	return nil
+}
+
+// InvalidateCaches invalidates the entries cached for the given user's block, or for all the user's
+// blocks if blockID is nil, bumping the user cache epoch in the bucket. The other store-gateways
+// stop using the invalidated entries after their next blocks sync.
+func (u *BucketStores) InvalidateCaches(ctx context.Context, userID string, blockID *ulid.ULID) error {
+	cacheEpoch, err := tsdb.BumpCacheEpoch(ctx, u.bucket, userID, u.limits, blockID)
+	if err != nil {
+		return err
+	}
+
+	u.storesMu.RLock()
+	epoch := u.cacheEpochs[userID]
+	u.storesMu.RUnlock()
+
+	if epoch != nil {
+		epoch.set(cacheEpoch)
+	}
+	return nil
+}
+
 // Series implements the storepb.StoreServer interface, making a series request to the underlying user bucket store.
 func (u *BucketStores) Series(req *storepb.SeriesRequest, srv storepb.Store_SeriesServer) error {
 	spanLog, spanCtx := spanlogger.NewWithLogger(srv.Context(), u.logger, "BucketStores.Series")
@@ -355,6 +452,14 @@ func (u *BucketStores) LabelValues(ctx context.Context, req *storepb.LabelValues
 	return store.LabelValues(ctx, req)
 }
 
+// UpdateQueryConcurrency updates the max number of concurrent queries based on the current memory
+// pressure. It's a no-op if the adaptive query concurrency is disabled.
+func (u *BucketStores) UpdateQueryConcurrency() {
+	if u.adaptiveQueryGate != nil {
+		u.adaptiveQueryGate.updateLimit()
+	}
+}
+
 // scanUsers in the bucket and return the list of found users. If an error occurs while
 // iterating the bucket, it may return both an error and a subset of the users in the bucket.
 func (u *BucketStores) scanUsers(ctx context.Context) ([]string, error) {
@@ -390,6 +495,7 @@ func (u *BucketStores) closeBucketStore(userID string) error {
 	}
 
 	delete(u.stores, userID)
+	delete(u.cacheEpochs, userID)
 	unlockInDefer = false
 	u.storesMu.Unlock()
 
@@ -421,26 +527,26 @@ func (u *BucketStores) getOrCreateStore(userID string) (*BucketStore, error) {
 
 	level.Info(userLogger).Log("msg", "creating user bucket store")
 
-	userBkt := bucket.NewUserBucketClient(userID, u.bucket, u.limits)
+	// The keys of the entries cached for the tenant are versioned with the tenant cache epoch.
+	epoch := newCacheEpoch()
+	userBkt := newCacheEpochBucket(bucket.NewUserBucketClient(userID, u.bucket, u.limits), epoch)
 	fetcherReg := prometheus.NewRegistry()
 
-	// The sharding strategy filter MUST be before the ones we create here (order matters).
-	filters := []block.MetadataFilter{
-		NewShardingMetadataFilterAdapter(userID, u.shardingStrategy),
-		block.NewConsistencyDelayMetaFilter(userLogger, u.cfg.BucketStore.DeprecatedConsistencyDelay, fetcherReg),
-		newMinTimeMetaFilter(u.cfg.BucketStore.IgnoreBlocksWithin),
-		// Use our own custom implementation.
-		NewIgnoreDeletionMarkFilter(userLogger, userBkt, u.cfg.BucketStore.IgnoreDeletionMarksDelay, u.cfg.BucketStore.MetaSyncConcurrency),
-		// The duplicate filter has been intentionally omitted because it could cause troubles with
-		// the consistency check done on the querier. The duplicate filter removes redundant blocks
-		// but if the store-gateway removes redundant blocks before the querier discovers them, the
-		// consistency check on the querier will fail.
+	// The sharding strategy filter MUST be before the configured ones (order matters).
+	// The duplicate filter has been intentionally omitted because it could cause troubles with
+	// the consistency check done on the querier. The duplicate filter removes redundant blocks
+	// but if the store-gateway removes redundant blocks before the querier discovers them, the
+	// consistency check on the querier will fail.
+	configuredFilters, err := createMetadataFilters(u.cfg.BucketStore, userID, userBkt, userLogger, fetcherReg)
+	if err != nil {
+		return nil, err
 	}
+	filters := append([]block.MetadataFilter{NewShardingMetadataFilterAdapter(userID, u.shardingStrategy)}, configuredFilters...)
 
 	// Instantiate a different blocks metadata fetcher based on whether bucket index is enabled or not.
 	var fetcher block.MetadataFetcher
 	if u.cfg.BucketStore.BucketIndex.Enabled {
-		fetcher = NewBucketIndexMetadataFetcher(
+		indexFetcher := NewBucketIndexMetadataFetcher(
 			userID,
 			u.bucket,
 			u.limits,
@@ -448,8 +554,10 @@ func (u *BucketStores) getOrCreateStore(userID string) (*BucketStore, error) {
 			fetcherReg,
 			filters,
 		)
+		indexFetcher.tieredBucket = u.tieredBucket
+		indexFetcher.cacheEpoch = epoch
+		fetcher = indexFetcher
 	} else {
-		var err error
 		fetcher, err = block.NewMetaFetcher(
 			userLogger,
 			u.cfg.BucketStore.MetaSyncConcurrency,
@@ -465,14 +573,26 @@ func (u *BucketStores) getOrCreateStore(userID string) (*BucketStore, error) {
 
 	bucketStoreOpts := []BucketStoreOption{
 		WithLogger(userLogger),
-		WithIndexCache(u.indexCache),
-		WithChunksCache(u.chunksCache),
+		WithIndexCache(newCacheEpochIndexCache(u.indexCache, epoch)),
+		WithChunksCache(newCacheEpochChunksCache(u.chunksCache, epoch)),
 		WithQueryGate(u.queryGate),
 		WithChunkPool(u.chunksPool),
 		WithFineGrainedChunksCaching(u.cfg.BucketStore.ChunksCache.FineGrainedChunksCachingEnabled),
-	}
-
-	bs, err := NewBucketStore(
+		WithStrictChunksTimeRangePruning(u.cfg.BucketStore.StrictChunksTimeRangePruningEnabled),
+		WithBucketBudget(u.cfg.BucketStore.SeriesMaxBucketGetOperations, u.cfg.BucketStore.SeriesMaxBucketFetchedBytes),
+		WithExemplarsMaxFileSize(u.cfg.BucketStore.ExemplarsMaxFileSizeBytes),
+		WithChunksCacheOverrides(
+			func() time.Duration { return u.limits.StoreGatewayChunksCacheTTL(userID) },
+			func() bool { return u.limits.StoreGatewayChunksCacheBypass(userID) },
+		),
+		WithQueryCostLimits(
+			func() int { return u.limits.StoreGatewayMaxBlocksPerQuery(userID) },
+			func() int { return u.limits.StoreGatewayMaxEstimatedPostingsBytes(userID) },
+		),
+		WithIndexHeaderOpenFilesBudget(u.indexHeaderOpenFilesBudget),
+	}
+
+	bs, err = NewBucketStore(
 		userID,
 		userBkt,
 		fetcher,
@@ -500,6 +620,7 @@ func (u *BucketStores) getOrCreateStore(userID string) (*BucketStore, error) {
 	}
 
 	u.stores[userID] = bs
+	u.cacheEpochs[userID] = epoch
 	u.metaFetcherMetrics.AddUserRegistry(userID, fetcherReg)
 
 	return bs, nil
@@ -12,6 +12,7 @@ import (
 	"io"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -296,6 +297,9 @@ func TestBucketStores_syncUsersBlocks(t *testing.T) {
 
 			bucketClient := &bucket.ClientMock{}
 			bucketClient.MockIter("", allUsers, nil)
+			for _, userID := range allUsers {
+				bucketClient.MockIter(path.Join(userID, mimir_tsdb.CacheEpochsDir), []string{}, nil)
+			}
 
 			stores, err := NewBucketStores(cfg, testData.shardingStrategy, bucketClient, defaultLimitsOverrides(t), log.NewNopLogger(), nil)
 			require.NoError(t, err)
@@ -308,7 +312,8 @@ func TestBucketStores_syncUsersBlocks(t *testing.T) {
 			})
 
 			assert.NoError(t, err)
-			bucketClient.AssertNumberOfCalls(t, "Iter", 1)
+			// The tenants are listed once, and the cache epoch of each synced tenant is listed once.
+			bucketClient.AssertNumberOfCalls(t, "Iter", 1+int(testData.expectedStores))
 			assert.Equal(t, storesCount.Load(), testData.expectedStores)
 		})
 	}
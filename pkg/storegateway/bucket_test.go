@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -64,6 +65,7 @@ import (
 	"github.com/grafana/mimir/pkg/storegateway/indexheader"
 	"github.com/grafana/mimir/pkg/storegateway/storepb"
 	"github.com/grafana/mimir/pkg/storegateway/testhelper"
+	"github.com/grafana/mimir/pkg/util/globalerror"
 	"github.com/grafana/mimir/pkg/util/pool"
 	"github.com/grafana/mimir/pkg/util/test"
 )
@@ -1570,7 +1572,7 @@ func TestBucketStore_Series_OneBlock_InMemIndexCacheSegfault(t *testing.T) {
 		logger:          logger,
 		indexCache:      indexCache,
 		chunksCache:     chunkscache.NoopCache{},
-		indexReaderPool: indexheader.NewReaderPool(log.NewNopLogger(), false, 0, indexheader.NewReaderPoolMetrics(nil)),
+		indexReaderPool: indexheader.NewReaderPool(log.NewNopLogger(), false, 0, indexheader.NewReaderPoolMetrics(nil), nil),
 		metrics:         NewBucketStoreMetrics(nil),
 		blockSet:        &bucketBlockSet{blocks: []*bucketBlock{b1, b2}},
 		blocks: map[ulid.ULID]*bucketBlock{
@@ -2408,6 +2410,101 @@ func TestLabelNamesAndValuesHints(t *testing.T) {
 	}
 }
 
+func TestBucketStore_Series_ChunksCacheBypass(t *testing.T) {
+	for _, bypass := range []bool{false, true} {
+		t.Run(fmt.Sprintf("bypass=%t", bypass), func(t *testing.T) {
+			chunksCache := newInMemoryChunksCache()
+			_, store, _, _, _, _, close := setupStoreForHintsTest(t, 5000,
+				WithChunksCache(chunksCache),
+				WithFineGrainedChunksCaching(true),
+				WithChunksCacheOverrides(func() time.Duration { return 0 }, func() bool { return bypass }),
+			)
+			defer close()
+
+			req := &storepb.SeriesRequest{
+				MinTime: 0,
+				MaxTime: 3,
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: ".*"},
+				},
+			}
+
+			srv := newBucketStoreTestServer(t, store)
+			seriesSet, _, _, err := srv.Series(context.Background(), req)
+			require.NoError(t, err)
+			require.NotEmpty(t, seriesSet)
+
+			cached := chunksCache.(*inMemoryChunksCache).cached["tenant"]
+			if bypass {
+				assert.Empty(t, cached)
+			} else {
+				assert.NotEmpty(t, cached)
+			}
+		})
+	}
+}
+
+func TestBucketStore_Series_QueryCostLimits(t *testing.T) {
+	tests := map[string]struct {
+		maxBlocks                int
+		maxEstimatedPostingsSize int
+		expectedErr              string
+	}{
+		"no limits": {},
+		"number of blocks within the limit": {
+			maxBlocks: 2,
+		},
+		"number of blocks exceeding the limit": {
+			maxBlocks:   1,
+			expectedErr: "touches too many blocks (blocks: 2, limit: 1)",
+		},
+		"estimated postings size within the limit": {
+			maxEstimatedPostingsSize: 1024 * 1024,
+		},
+		"estimated postings size exceeding the limit": {
+			maxEstimatedPostingsSize: 1,
+			expectedErr:              "the estimated size of the postings to fetch exceeds the limit",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, store, _, _, _, _, close := setupStoreForHintsTest(t, 5000,
+				WithQueryCostLimits(
+					func() int { return testData.maxBlocks },
+					func() int { return testData.maxEstimatedPostingsSize },
+				),
+			)
+			defer close()
+
+			req := &storepb.SeriesRequest{
+				MinTime: 0,
+				MaxTime: 3,
+				Matchers: []storepb.LabelMatcher{
+					{Type: storepb.LabelMatcher_RE, Name: "__name__", Value: ".*"},
+				},
+			}
+
+			srv := newBucketStoreTestServer(t, store)
+			seriesSet, _, _, err := srv.Series(context.Background(), req)
+
+			if testData.expectedErr == "" {
+				require.NoError(t, err)
+				require.NotEmpty(t, seriesSet)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testData.expectedErr)
+			assert.Contains(t, err.Error(), string(globalerror.QueryTooExpensive))
+
+			s, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, codes.Code(http.StatusUnprocessableEntity), s.Code())
+		})
+	}
+}
+
 func TestLabelNames_Cancelled(t *testing.T) {
 	_, store, _, _, _, _, close := setupStoreForHintsTest(t, 5000)
 	defer close()
@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/storage/sharding"
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketcache"
+	"github.com/grafana/mimir/pkg/storegateway/chunkscache"
+	"github.com/grafana/mimir/pkg/storegateway/indexcache"
+	"github.com/grafana/mimir/pkg/util/pool"
+)
+
+// cacheKeyEpochSeparator separates the tenant ID from the cache key epoch in the tenant ID used in the
+// cache keys. It's not a valid character in tenant IDs, so the cache keys of different tenants can't clash.
+const cacheKeyEpochSeparator = "@"
+
+// cacheEpoch holds the cache epoch of a tenant, which versions the keys of the entries cached for the tenant.
+type cacheEpoch struct {
+	epoch *atomic.Pointer[tsdb.CacheEpoch]
+}
+
+func newCacheEpoch() *cacheEpoch {
+	return &cacheEpoch{epoch: atomic.NewPointer(&tsdb.CacheEpoch{})}
+}
+
+func (e *cacheEpoch) set(epoch *tsdb.CacheEpoch) {
+	e.epoch.Store(epoch)
+}
+
+// blockKeyEpoch returns the epoch of the cache keys of the given block.
+func (e *cacheEpoch) blockKeyEpoch(blockID ulid.ULID) string {
+	return e.epoch.Load().BlockKeyEpoch(blockID)
+}
+
+// tenantKeyEpoch returns the epoch of the cache keys of the tenant objects not belonging to a block.
+func (e *cacheEpoch) tenantKeyEpoch() string {
+	return e.epoch.Load().TenantKeyEpoch()
+}
+
+// objectKeyEpoch returns the epoch of the cache keys of the object with the given name, relative to the
+// tenant location in the bucket.
+func (e *cacheEpoch) objectKeyEpoch(name string) string {
+	return e.epoch.Load().ObjectKeyEpoch(name)
+}
+
+// cacheKeyUserID returns the tenant ID to use in the keys of the entries cached for the given block.
+func (e *cacheEpoch) cacheKeyUserID(userID string, blockID ulid.ULID) string {
+	if epoch := e.blockKeyEpoch(blockID); epoch != "" {
+		return userID + cacheKeyEpochSeparator + epoch
+	}
+	return userID
+}
+
+// cacheKeyUserIDLimits wraps the per-tenant limits of the chunks cache, to look up the limits of the
+// tenant IDs including a cache key epoch.
+type cacheKeyUserIDLimits struct {
+	chunkscache.Limits
+}
+
+func (l cacheKeyUserIDLimits) StoreGatewayChunksCacheTTL(userID string) time.Duration {
+	userID, _, _ = strings.Cut(userID, cacheKeyEpochSeparator)
+	return l.Limits.StoreGatewayChunksCacheTTL(userID)
+}
+
+// cacheEpochBucket is a bucket client which versions the cache keys of the objects with the tenant cache epoch.
+// It expects the object names to be relative to the tenant location in the bucket.
+type cacheEpochBucket struct {
+	objstore.Bucket
+
+	epoch *cacheEpoch
+}
+
+func newCacheEpochBucket(bkt objstore.Bucket, epoch *cacheEpoch) objstore.InstrumentedBucket {
+	return &cacheEpochBucket{Bucket: bkt, epoch: epoch}
+}
+
+func (b *cacheEpochBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	return b.Bucket.Iter(bucketcache.WithCacheKeyEpoch(ctx, b.epoch.objectKeyEpoch(dir)), dir, f, options...)
+}
+
+func (b *cacheEpochBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.Bucket.Get(bucketcache.WithCacheKeyEpoch(ctx, b.epoch.objectKeyEpoch(name)), name)
+}
+
+func (b *cacheEpochBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	return b.Bucket.GetRange(bucketcache.WithCacheKeyEpoch(ctx, b.epoch.objectKeyEpoch(name)), name, off, length)
+}
+
+func (b *cacheEpochBucket) Exists(ctx context.Context, name string) (bool, error) {
+	return b.Bucket.Exists(bucketcache.WithCacheKeyEpoch(ctx, b.epoch.objectKeyEpoch(name)), name)
+}
+
+func (b *cacheEpochBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	return b.Bucket.Attributes(bucketcache.WithCacheKeyEpoch(ctx, b.epoch.objectKeyEpoch(name)), name)
+}
+
+func (b *cacheEpochBucket) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+func (b *cacheEpochBucket) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	if ib, ok := b.Bucket.(objstore.InstrumentedBucket); ok {
+		return &cacheEpochBucket{Bucket: ib.WithExpectedErrs(fn), epoch: b.epoch}
+	}
+	return b
+}
+
+// cacheEpochIndexCache is an index cache which versions the cache keys with the tenant cache epoch.
+type cacheEpochIndexCache struct {
+	c     indexcache.IndexCache
+	epoch *cacheEpoch
+}
+
+func newCacheEpochIndexCache(c indexcache.IndexCache, epoch *cacheEpoch) indexcache.IndexCache {
+	return &cacheEpochIndexCache{c: c, epoch: epoch}
+}
+
+func (c *cacheEpochIndexCache) StorePostings(userID string, blockID ulid.ULID, l labels.Label, v []byte) {
+	c.c.StorePostings(c.epoch.cacheKeyUserID(userID, blockID), blockID, l, v)
+}
+
+func (c *cacheEpochIndexCache) FetchMultiPostings(ctx context.Context, userID string, blockID ulid.ULID, keys []labels.Label) (hits map[labels.Label][]byte, misses []labels.Label) {
+	return c.c.FetchMultiPostings(ctx, c.epoch.cacheKeyUserID(userID, blockID), blockID, keys)
+}
+
+func (c *cacheEpochIndexCache) StoreSeriesForRef(userID string, blockID ulid.ULID, id storage.SeriesRef, v []byte) {
+	c.c.StoreSeriesForRef(c.epoch.cacheKeyUserID(userID, blockID), blockID, id, v)
+}
+
+func (c *cacheEpochIndexCache) FetchMultiSeriesForRefs(ctx context.Context, userID string, blockID ulid.ULID, ids []storage.SeriesRef) (hits map[storage.SeriesRef][]byte, misses []storage.SeriesRef) {
+	return c.c.FetchMultiSeriesForRefs(ctx, c.epoch.cacheKeyUserID(userID, blockID), blockID, ids)
+}
+
+func (c *cacheEpochIndexCache) StoreExpandedPostings(userID string, blockID ulid.ULID, key indexcache.LabelMatchersKey, v []byte) {
+	c.c.StoreExpandedPostings(c.epoch.cacheKeyUserID(userID, blockID), blockID, key, v)
+}
+
+func (c *cacheEpochIndexCache) FetchExpandedPostings(ctx context.Context, userID string, blockID ulid.ULID, key indexcache.LabelMatchersKey) ([]byte, bool) {
+	return c.c.FetchExpandedPostings(ctx, c.epoch.cacheKeyUserID(userID, blockID), blockID, key)
+}
+
+func (c *cacheEpochIndexCache) StoreSeriesForPostings(userID string, blockID ulid.ULID, shard *sharding.ShardSelector, postingsKey indexcache.PostingsKey, v []byte) {
+	c.c.StoreSeriesForPostings(c.epoch.cacheKeyUserID(userID, blockID), blockID, shard, postingsKey, v)
+}
+
+func (c *cacheEpochIndexCache) FetchSeriesForPostings(ctx context.Context, userID string, blockID ulid.ULID, shard *sharding.ShardSelector, postingsKey indexcache.PostingsKey) ([]byte, bool) {
+	return c.c.FetchSeriesForPostings(ctx, c.epoch.cacheKeyUserID(userID, blockID), blockID, shard, postingsKey)
+}
+
+func (c *cacheEpochIndexCache) StoreLabelNames(userID string, blockID ulid.ULID, matchersKey indexcache.LabelMatchersKey, v []byte) {
+	c.c.StoreLabelNames(c.epoch.cacheKeyUserID(userID, blockID), blockID, matchersKey, v)
+}
+
+func (c *cacheEpochIndexCache) FetchLabelNames(ctx context.Context, userID string, blockID ulid.ULID, matchersKey indexcache.LabelMatchersKey) ([]byte, bool) {
+	return c.c.FetchLabelNames(ctx, c.epoch.cacheKeyUserID(userID, blockID), blockID, matchersKey)
+}
+
+func (c *cacheEpochIndexCache) StoreLabelValues(userID string, blockID ulid.ULID, labelName string, matchersKey indexcache.LabelMatchersKey, v []byte) {
+	c.c.StoreLabelValues(c.epoch.cacheKeyUserID(userID, blockID), blockID, labelName, matchersKey, v)
+}
+
+func (c *cacheEpochIndexCache) FetchLabelValues(ctx context.Context, userID string, blockID ulid.ULID, labelName string, matchersKey indexcache.LabelMatchersKey) ([]byte, bool) {
+	return c.c.FetchLabelValues(ctx, c.epoch.cacheKeyUserID(userID, blockID), blockID, labelName, matchersKey)
+}
+
+// cacheEpochChunksCache is a chunks cache which versions the cache keys with the tenant cache epoch.
+type cacheEpochChunksCache struct {
+	c     chunkscache.Cache
+	epoch *cacheEpoch
+}
+
+func newCacheEpochChunksCache(c chunkscache.Cache, epoch *cacheEpoch) chunkscache.Cache {
+	return &cacheEpochChunksCache{c: c, epoch: epoch}
+}
+
+func (c *cacheEpochChunksCache) FetchMultiChunks(ctx context.Context, userID string, ranges []chunkscache.Range, chunksPool *pool.SafeSlabPool[byte]) map[chunkscache.Range][]byte {
+	// The ranges of different blocks may have different cache key epochs.
+	rangesByUserID := map[string][]chunkscache.Range{}
+	for _, r := range ranges {
+		keyUserID := c.epoch.cacheKeyUserID(userID, r.BlockID)
+		rangesByUserID[keyUserID] = append(rangesByUserID[keyUserID], r)
+	}
+	if len(rangesByUserID) == 1 {
+		for keyUserID := range rangesByUserID {
+			return c.c.FetchMultiChunks(ctx, keyUserID, ranges, chunksPool)
+		}
+	}
+
+	var hits map[chunkscache.Range][]byte
+	for keyUserID, userRanges := range rangesByUserID {
+		for r, b := range c.c.FetchMultiChunks(ctx, keyUserID, userRanges, chunksPool) {
+			if hits == nil {
+				hits = make(map[chunkscache.Range][]byte, len(ranges))
+			}
+			hits[r] = b
+		}
+	}
+	return hits
+}
+
+func (c *cacheEpochChunksCache) StoreChunks(userID string, ranges map[chunkscache.Range][]byte) {
+	rangesByUserID := map[string]map[chunkscache.Range][]byte{}
+	for r, b := range ranges {
+		keyUserID := c.epoch.cacheKeyUserID(userID, r.BlockID)
+		if rangesByUserID[keyUserID] == nil {
+			rangesByUserID[keyUserID] = map[chunkscache.Range][]byte{}
+		}
+		rangesByUserID[keyUserID][r] = b
+	}
+
+	for keyUserID, userRanges := range rangesByUserID {
+		c.c.StoreChunks(keyUserID, userRanges)
+	}
+}
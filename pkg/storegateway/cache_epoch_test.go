@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storegateway/chunkscache"
+	"github.com/grafana/mimir/pkg/storegateway/indexcache"
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestCacheEpoch_objectKeyEpoch(t *testing.T) {
+	block1 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0")
+	block2 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E1")
+
+	epoch := newCacheEpoch()
+	assert.Equal(t, "", epoch.objectKeyEpoch(block1.String()+"/meta.json"))
+	assert.Equal(t, "", epoch.objectKeyEpoch("bucket-index.json.gz"))
+
+	epoch.set(&tsdb.CacheEpoch{Tenant: 2, Blocks: map[ulid.ULID]uint64{block1: 1}})
+	assert.Equal(t, "2.1", epoch.objectKeyEpoch(block1.String()+"/chunks/000001"))
+	assert.Equal(t, "2.1", epoch.objectKeyEpoch(block1.String()))
+	assert.Equal(t, "2.0", epoch.objectKeyEpoch(block2.String()+"/index"))
+	assert.Equal(t, "2", epoch.objectKeyEpoch("bucket-index.json.gz"))
+	assert.Equal(t, "2", epoch.objectKeyEpoch(""))
+}
+
+func TestCacheEpochIndexCache(t *testing.T) {
+	const userID = "user-1"
+	blockID := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0")
+	lbl := labels.Label{Name: "a", Value: "1"}
+
+	inmemory, err := indexcache.NewInMemoryIndexCacheWithConfig(log.NewNopLogger(), nil, indexcache.DefaultInMemoryIndexCacheConfig)
+	require.NoError(t, err)
+
+	epoch := newCacheEpoch()
+	c := newCacheEpochIndexCache(inmemory, epoch)
+
+	c.StorePostings(userID, blockID, lbl, []byte("postings"))
+	hits, _ := c.FetchMultiPostings(context.Background(), userID, blockID, []labels.Label{lbl})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("postings")}, hits)
+
+	// The entries cached before the cache epoch has been bumped are no longer used.
+	epoch.set(&tsdb.CacheEpoch{Blocks: map[ulid.ULID]uint64{blockID: 1}})
+	hits, misses := c.FetchMultiPostings(context.Background(), userID, blockID, []labels.Label{lbl})
+	assert.Empty(t, hits)
+	assert.Equal(t, []labels.Label{lbl}, misses)
+
+	// The entries are stored with the new cache epoch.
+	c.StorePostings(userID, blockID, lbl, []byte("new-postings"))
+	hits, _ = c.FetchMultiPostings(context.Background(), userID, blockID, []labels.Label{lbl})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("new-postings")}, hits)
+}
+
+func TestCacheEpochChunksCache(t *testing.T) {
+	const userID = "user-1"
+	block1 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E0")
+	block2 := ulid.MustParse("01EQK4QKFHVSZYVJ908Y7HH9E1")
+	range1 := chunkscache.Range{BlockID: block1, Start: 10, NumChunks: 1}
+	range2 := chunkscache.Range{BlockID: block2, Start: 10, NumChunks: 1}
+
+	inmemory := newInMemoryChunksCache().(*inMemoryChunksCache)
+	epoch := newCacheEpoch()
+	c := newCacheEpochChunksCache(inmemory, epoch)
+
+	c.StoreChunks(userID, map[chunkscache.Range][]byte{range1: []byte("chunk-1"), range2: []byte("chunk-2")})
+	assert.Equal(t, map[chunkscache.Range][]byte{range1: []byte("chunk-1"), range2: []byte("chunk-2")},
+		c.FetchMultiChunks(context.Background(), userID, []chunkscache.Range{range1, range2}, nil))
+
+	// Only the entries of the block whose cache epoch has been bumped are no longer used.
+	epoch.set(&tsdb.CacheEpoch{Blocks: map[ulid.ULID]uint64{block1: 1}})
+	assert.Equal(t, map[chunkscache.Range][]byte{range2: []byte("chunk-2")},
+		c.FetchMultiChunks(context.Background(), userID, []chunkscache.Range{range1, range2}, nil))
+
+	c.StoreChunks(userID, map[chunkscache.Range][]byte{range1: []byte("new-chunk-1")})
+	assert.Equal(t, map[chunkscache.Range][]byte{range1: []byte("new-chunk-1"), range2: []byte("chunk-2")},
+		c.FetchMultiChunks(context.Background(), userID, []chunkscache.Range{range1, range2}, nil))
+	assert.Contains(t, inmemory.cached, userID+"@0.1")
+}
+
+func TestBucketStores_InvalidateCaches(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	const userID = "user-1"
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+
+	storageDir := t.TempDir()
+	generateStorageBlock(t, storageDir, userID, "series_1", 10, 100, 15)
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	// Two store-gateways loading the blocks of the same tenant.
+	stores1, err := NewBucketStores(cfg, newNoShardingStrategy(), bkt, defaultLimitsOverrides(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, stores1.InitialSync(ctx))
+
+	stores2, err := NewBucketStores(cfg, newNoShardingStrategy(), bkt, defaultLimitsOverrides(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, stores2.InitialSync(ctx))
+
+	assert.Equal(t, "", stores1.cacheEpochs[userID].tenantKeyEpoch())
+
+	require.NoError(t, stores1.InvalidateCaches(ctx, userID, nil))
+
+	// The store-gateway invalidating the caches uses the new cache epoch right away,
+	// while the other store-gateways use it after the next blocks sync.
+	epoch := stores1.cacheEpochs[userID].tenantKeyEpoch()
+	assert.NotEqual(t, "", epoch)
+	assert.Equal(t, "", stores2.cacheEpochs[userID].tenantKeyEpoch())
+
+	require.NoError(t, stores2.SyncBlocks(ctx))
+	assert.Equal(t, epoch, stores2.cacheEpochs[userID].tenantKeyEpoch())
+
+	// Series are still queried successfully.
+	seriesSet, warnings, err := querySeries(t, stores2, userID, "series_1", 20, 40)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Len(t, seriesSet, 1)
+}
@@ -69,9 +69,17 @@ func (c TracingCache) StoreChunks(userID string, ranges map[Range][]byte) {
 	c.c.StoreChunks(userID, ranges)
 }
 
+// Limits is the per-tenant configuration used by the ChunksCache.
+type Limits interface {
+	// StoreGatewayChunksCacheTTL returns the TTL of the tenant's chunks stored in the cache.
+	// 0 means the default TTL is used.
+	StoreGatewayChunksCacheTTL(userID string) time.Duration
+}
+
 type ChunksCache struct {
 	logger log.Logger
 	cache  cache.Cache
+	limits Limits
 
 	// TODO these two will soon be tracked by the dskit, we can remove them once https://github.com/grafana/mimir/pull/4078 is merged
 	requests prometheus.Counter
@@ -87,10 +95,11 @@ func (NoopCache) FetchMultiChunks(_ context.Context, _ string, _ []Range, _ *poo
 func (NoopCache) StoreChunks(_ string, _ map[Range][]byte) {
 }
 
-func NewChunksCache(logger log.Logger, client cache.Cache, reg prometheus.Registerer) (*ChunksCache, error) {
+func NewChunksCache(logger log.Logger, client cache.Cache, limits Limits, reg prometheus.Registerer) (*ChunksCache, error) {
 	c := &ChunksCache{
 		logger: logger,
 		cache:  client,
+		limits: limits,
 	}
 
 	c.requests = promauto.With(reg).NewCounter(prometheus.CounterOpts{
@@ -143,5 +152,14 @@ func (c *ChunksCache) StoreChunks(userID string, ranges map[Range][]byte) {
 	for r, v := range ranges {
 		rangesWithTenant[chunksKey(userID, r)] = v
 	}
-	c.cache.StoreAsync(rangesWithTenant, defaultTTL)
+	c.cache.StoreAsync(rangesWithTenant, c.ttl(userID))
+}
+
+func (c *ChunksCache) ttl(userID string) time.Duration {
+	if c.limits != nil {
+		if ttl := c.limits.StoreGatewayChunksCacheTTL(userID); ttl > 0 {
+			return ttl
+		}
+	}
+	return defaultTTL
 }
@@ -82,7 +82,7 @@ func TestDskitChunksCache_FetchMultiChunks(t *testing.T) {
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
 			cacheClient := newMockedCacheClient(testData.mockedErr)
-			c, err := NewChunksCache(log.NewNopLogger(), cacheClient, nil)
+			c, err := NewChunksCache(log.NewNopLogger(), cacheClient, nil, nil)
 			assert.NoError(t, err)
 
 			// Store the postings expected before running the test.
@@ -110,6 +110,44 @@ func TestDskitChunksCache_FetchMultiChunks(t *testing.T) {
 	}
 }
 
+func TestDskitChunksCache_StoreChunks(t *testing.T) {
+	rng := Range{BlockID: ulid.MustNew(1, nil), Start: chunks.ChunkRef(100), NumChunks: 10}
+	limits := mockedLimits{chunksCacheTTL: map[string]time.Duration{"tenant-with-ttl": time.Hour}}
+
+	tests := map[string]struct {
+		userID      string
+		expectedTTL time.Duration
+	}{
+		"should store the chunks with the default TTL": {
+			userID:      "tenant",
+			expectedTTL: defaultTTL,
+		},
+		"should store the chunks with the TTL overridden for the tenant": {
+			userID:      "tenant-with-ttl",
+			expectedTTL: time.Hour,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cacheClient := newMockedCacheClient(nil)
+			c, err := NewChunksCache(log.NewNopLogger(), cacheClient, limits, nil)
+			assert.NoError(t, err)
+
+			c.StoreChunks(testData.userID, map[Range][]byte{rng: {1}})
+			assert.Equal(t, map[string]time.Duration{chunksKey(testData.userID, rng): testData.expectedTTL}, cacheClient.ttls)
+		})
+	}
+}
+
+type mockedLimits struct {
+	chunksCacheTTL map[string]time.Duration
+}
+
+func (m mockedLimits) StoreGatewayChunksCacheTTL(userID string) time.Duration {
+	return m.chunksCacheTTL[userID]
+}
+
 func BenchmarkStringCacheKeys(b *testing.B) {
 	userID := "tenant"
 	rng := Range{BlockID: ulid.MustNew(1, nil), Start: chunks.ChunkRef(200), NumChunks: 20}
@@ -130,12 +168,14 @@ type mockedChunks struct {
 
 type mockedCacheClient struct {
 	cache             map[string][]byte
+	ttls              map[string]time.Duration
 	mockedGetMultiErr error
 }
 
 func newMockedCacheClient(mockedGetMultiErr error) *mockedCacheClient {
 	return &mockedCacheClient{
 		cache:             map[string][]byte{},
+		ttls:              map[string]time.Duration{},
 		mockedGetMultiErr: mockedGetMultiErr,
 	}
 }
@@ -156,9 +196,10 @@ func (c *mockedCacheClient) Fetch(_ context.Context, keys []string, _ ...cache.O
 	return hits
 }
 
-func (c *mockedCacheClient) StoreAsync(data map[string][]byte, _ time.Duration) {
+func (c *mockedCacheClient) StoreAsync(data map[string][]byte, ttl time.Duration) {
 	for key, value := range data {
 		c.cache[key] = value
+		c.ttls[key] = ttl
 	}
 }
 
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/grafana/dskit/runutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/weaveworks/common/httpgrpc"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+// Exemplars returns the exemplars of the tenant in the request context.
+func (u *BucketStores) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	spanLog, spanCtx := spanlogger.NewWithLogger(ctx, u.logger, "BucketStores.Exemplars")
+	defer spanLog.Span.Finish()
+
+	userID := getUserIDFromGRPCContext(spanCtx)
+	if userID == "" {
+		return nil, fmt.Errorf("no userID")
+	}
+
+	store := u.getStore(userID)
+	if store == nil {
+		return &storegatewaypb.ExemplarsResponse{}, nil
+	}
+
+	return store.Exemplars(ctx, req)
+}
+
+// Exemplars returns the exemplars stored in the requested blocks, matching the request time range
+// and any of the request matchers sets. The blocks with no exemplars file are reported as queried too.
+func (s *BucketStore) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	matcherSets := make([][]*labels.Matcher, 0, len(req.Matchers))
+	for _, m := range req.Matchers {
+		matchers, err := storepb.MatchersToPromMatchers(m.Matchers...)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "translate request labels matchers").Error())
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+
+	blockIDs := make(map[string]struct{}, len(req.BlockIds))
+	for _, id := range req.BlockIds {
+		blockIDs[id] = struct{}{}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	s.blocksMx.RLock()
+
+	var (
+		mtx           sync.Mutex
+		sets          [][]mimirpb.TimeSeries
+		queriedBlocks []string
+	)
+
+	for _, b := range s.blocks {
+		b := b
+		if !b.overlapsClosedInterval(req.MinTime, req.MaxTime) {
+			continue
+		}
+		if _, ok := blockIDs[b.meta.ULID.String()]; len(blockIDs) > 0 && !ok {
+			continue
+		}
+
+		queriedBlocks = append(queriedBlocks, b.meta.ULID.String())
+
+		if !block.HasExemplars(b.meta) {
+			continue
+		}
+
+		g.Go(func() error {
+			result, err := blockExemplars(gctx, b, req.MinTime, req.MaxTime, matcherSets, s.maxExemplarsFileSizeBytes)
+			if err != nil {
+				return errors.Wrapf(err, "block %s", b.meta.ULID)
+			}
+
+			if len(result) > 0 {
+				mtx.Lock()
+				sets = append(sets, result)
+				mtx.Unlock()
+			}
+
+			return nil
+		})
+	}
+
+	s.blocksMx.RUnlock()
+
+	if err := g.Wait(); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, status.Error(codes.Canceled, err.Error())
+		}
+		if errors.Is(err, block.ErrExemplarsTooLarge) {
+			return nil, httpgrpc.Errorf(http.StatusUnprocessableEntity, "%s (limit: %d bytes)", err.Error(), s.maxExemplarsFileSizeBytes)
+		}
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &storegatewaypb.ExemplarsResponse{
+		Timeseries:    block.MergeExemplars(sets...),
+		QueriedBlocks: queriedBlocks,
+	}, nil
+}
+
+// blockExemplars reads the exemplars file of the block, and returns the exemplars in the closed interval
+// [mint, maxt] of the series matching any of the matcher sets. The exemplars file is read through the
+// block bucket, which caches it, and it fails with block.ErrExemplarsTooLarge if its uncompressed size
+// exceeds maxSizeBytes (0 for no limit).
+func blockExemplars(ctx context.Context, b *bucketBlock, mint, maxt int64, matcherSets [][]*labels.Matcher, maxSizeBytes int64) ([]mimirpb.TimeSeries, error) {
+	r, err := b.bkt.Get(ctx, path.Join(b.meta.ULID.String(), block.ExemplarsFilename))
+	if err != nil {
+		return nil, errors.Wrap(err, "get exemplars file")
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "close exemplars file")
+
+	series, err := block.ReadExemplars(r, maxSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := series[:0]
+	for _, s := range series {
+		if !matchesAnySet(mimirpb.FromLabelAdaptersToLabels(s.Labels), matcherSets) {
+			continue
+		}
+
+		exemplars := s.Exemplars[:0]
+		for _, e := range s.Exemplars {
+			if e.TimestampMs >= mint && e.TimestampMs <= maxt {
+				exemplars = append(exemplars, e)
+			}
+		}
+		if len(exemplars) == 0 {
+			continue
+		}
+
+		s.Exemplars = exemplars
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// matchesAnySet returns whether the series labels match all the matchers of at least one of the sets.
+// An empty list of sets matches any series.
+func matchesAnySet(lbls labels.Labels, matcherSets [][]*labels.Matcher) bool {
+	if len(matcherSets) == 0 {
+		return true
+	}
+
+	for _, matchers := range matcherSets {
+		matches := true
+		for _, m := range matchers {
+			if !m.Matches(lbls.Get(m.Name)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+)
+
+func TestBucketStore_Exemplars(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+
+	series1 := labels.FromStrings(labels.MetricName, "series_1", "job", "a")
+	series2 := labels.FromStrings(labels.MetricName, "series_2", "job", "b")
+
+	// Block 1 and 2 have exemplars, block 3 has no exemplars, block 4 is out of the queried time range.
+	block1 := createBlockWithExemplars(t, bkt, 1, 0, 100, exemplarsSeries(series1, 10, 50), exemplarsSeries(series2, 20))
+	block2 := createBlockWithExemplars(t, bkt, 2, 100, 200, exemplarsSeries(series1, 150, 180))
+	block3 := createBlockWithExemplars(t, bkt, 3, 100, 200)
+	block4 := createBlockWithExemplars(t, bkt, 4, 300, 400, exemplarsSeries(series1, 350))
+
+	store := &BucketStore{blocks: map[ulid.ULID]*bucketBlock{}}
+	for _, b := range []*metadata.Meta{block1, block2, block3, block4} {
+		store.blocks[b.ULID] = &bucketBlock{bkt: bkt, meta: b, logger: log.NewNopLogger()}
+	}
+
+	tests := map[string]struct {
+		req                   *storegatewaypb.ExemplarsRequest
+		expectedSeries        []mimirpb.TimeSeries
+		expectedQueriedBlocks []string
+	}{
+		"should return the exemplars of all series within the time range": {
+			req: &storegatewaypb.ExemplarsRequest{MinTime: 20, MaxTime: 160},
+			expectedSeries: []mimirpb.TimeSeries{
+				exemplarsSeries(series1, 50, 150),
+				exemplarsSeries(series2, 20),
+			},
+			expectedQueriedBlocks: []string{block1.ULID.String(), block2.ULID.String(), block3.ULID.String()},
+		},
+		"should return the exemplars of the series matching any of the matchers sets": {
+			req: &storegatewaypb.ExemplarsRequest{MinTime: 0, MaxTime: 200, Matchers: []storegatewaypb.ExemplarsMatchers{
+				{Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "a"}}},
+			}},
+			expectedSeries: []mimirpb.TimeSeries{
+				exemplarsSeries(series1, 10, 50, 150, 180),
+			},
+			expectedQueriedBlocks: []string{block1.ULID.String(), block2.ULID.String(), block3.ULID.String()},
+		},
+		"should query only the requested blocks": {
+			req: &storegatewaypb.ExemplarsRequest{MinTime: 0, MaxTime: 200, BlockIds: []string{block2.ULID.String()}},
+			expectedSeries: []mimirpb.TimeSeries{
+				exemplarsSeries(series1, 150, 180),
+			},
+			expectedQueriedBlocks: []string{block2.ULID.String()},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			res, err := store.Exemplars(ctx, testData.req)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedSeries, res.Timeseries)
+			assert.ElementsMatch(t, testData.expectedQueriedBlocks, res.QueriedBlocks)
+		})
+	}
+}
+
+func TestBucketStore_Exemplars_ShouldFailIfTheExemplarsFileExceedsTheMaxSize(t *testing.T) {
+	bkt := objstore.NewInMemBucket()
+	series1 := labels.FromStrings(labels.MetricName, "series_1")
+	block1 := createBlockWithExemplars(t, bkt, 1, 0, 100, exemplarsSeries(series1, 10, 20, 30))
+
+	store := &BucketStore{blocks: map[ulid.ULID]*bucketBlock{}, maxExemplarsFileSizeBytes: 10}
+	store.blocks[block1.ULID] = &bucketBlock{bkt: bkt, meta: block1, logger: log.NewNopLogger()}
+
+	_, err := store.Exemplars(context.Background(), &storegatewaypb.ExemplarsRequest{MinTime: 0, MaxTime: 100})
+	require.Error(t, err)
+
+	res, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusUnprocessableEntity), res.Code)
+	assert.Contains(t, string(res.Body), block.ErrExemplarsTooLarge.Error())
+}
+
+func createBlockWithExemplars(t *testing.T, bkt objstore.Bucket, id uint64, minT, maxT int64, series ...mimirpb.TimeSeries) *metadata.Meta {
+	dir := t.TempDir()
+	meta := &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(id, nil), MinTime: minT, MaxTime: maxT}}
+
+	require.NoError(t, block.WriteExemplarsFile(dir, series))
+	if len(series) == 0 {
+		return meta
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, block.ExemplarsFilename))
+	require.NoError(t, err)
+	require.NoError(t, bkt.Upload(context.Background(), path.Join(meta.ULID.String(), block.ExemplarsFilename), bytes.NewReader(data)))
+
+	meta.Thanos.Files = []metadata.File{{RelPath: block.ExemplarsFilename, SizeBytes: int64(len(data))}}
+	return meta
+}
+
+func exemplarsSeries(lbls labels.Labels, timestamps ...int64) mimirpb.TimeSeries {
+	ts := mimirpb.TimeSeries{Labels: mimirpb.FromLabelsToLabelAdapters(lbls)}
+	for _, t := range timestamps {
+		ts.Exemplars = append(ts.Exemplars, mimirpb.Exemplar{
+			Labels:      []mimirpb.LabelAdapter{{Name: "trace_id", Value: "abc"}},
+			Value:       float64(t),
+			TimestampMs: t,
+		})
+	}
+	return ts
+}
@@ -22,12 +22,12 @@ import (
 	"github.com/thanos-io/objstore"
 	"github.com/weaveworks/common/tracing"
 
-	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
 	"github.com/grafana/mimir/pkg/storegateway/storepb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
+	"github.com/grafana/mimir/pkg/util/conditions"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -39,21 +39,31 @@ const (
 	// ringAutoForgetUnhealthyPeriods is how many consecutive timeout periods an unhealthy instance
 	// in the ring will be automatically removed.
 	ringAutoForgetUnhealthyPeriods = 10
+
+	// queryConcurrencyUpdateInterval is how frequently the query concurrency is adapted to the memory pressure.
+	queryConcurrencyUpdateInterval = time.Second
 )
 
 var (
 	// Validation errors.
-	errInvalidTenantShardSize = errors.New("invalid tenant shard size, the value must be greater or equal to 0")
+	errInvalidTenantShardSize           = errors.New("invalid tenant shard size, the value must be greater or equal to 0")
+	errInvalidBucketIndexWriterInterval = errors.New("invalid bucket index writer interval, the value must be greater than 0")
 )
 
 // Config holds the store gateway config.
 type Config struct {
 	ShardingRing RingConfig `yaml:"sharding_ring" doc:"description=The hash ring configuration."`
+
+	BucketIndexWriterEnabled  bool          `yaml:"bucket_index_writer_enabled" category:"experimental"`
+	BucketIndexWriterInterval time.Duration `yaml:"bucket_index_writer_interval" category:"experimental"`
 }
 
 // RegisterFlags registers the Config flags.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.ShardingRing.RegisterFlags(f, logger)
+
+	f.BoolVar(&cfg.BucketIndexWriterEnabled, "store-gateway.bucket-index-writer-enabled", false, "True to periodically update the bucket index of the tenants from the store-gateway. Enable it only when running Mimir without the compactor, which otherwise updates the bucket index. The bucket index of each tenant is updated by one store-gateway of the tenant's shard. The store-gateway doesn't apply the blocks retention, and doesn't delete any block.")
+	f.DurationVar(&cfg.BucketIndexWriterInterval, "store-gateway.bucket-index-writer-interval", 15*time.Minute, "How frequently the store-gateway updates the bucket index, when the bucket index writer is enabled.")
 }
 
 // Validate the Config.
@@ -61,6 +71,9 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 	if limits.StoreGatewayTenantShardSize < 0 {
 		return errInvalidTenantShardSize
 	}
+	if cfg.BucketIndexWriterEnabled && cfg.BucketIndexWriterInterval <= 0 {
+		return errInvalidBucketIndexWriterInterval
+	}
 
 	return nil
 }
@@ -81,11 +94,17 @@ type StoreGateway struct {
 	ringLifecycler *ring.BasicLifecycler
 	ring           *ring.Ring
 
+	// Optional writer of the bucket index, used when running without the compactor.
+	bucketIndexWriter *bucketIndexWriter
+
 	// Subservices manager (ring, lifecycler)
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
 
 	bucketSync *prometheus.CounterVec
+
+	// Status conditions exposed by the ready detail endpoint.
+	conditions *conditions.Set
 }
 
 func NewStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfig, limits *validation.Overrides, logger log.Logger, reg prometheus.Registerer, tracker *activitytracker.ActivityTracker) (*StoreGateway, error) {
@@ -121,6 +140,7 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 			Name: "cortex_storegateway_bucket_sync_total",
 			Help: "Total number of times the bucket sync operation triggered.",
 		}, []string{"reason"}),
+		conditions: conditions.NewSet(conditionRingHealthy, conditionSynced),
 	}
 
 	// Init metrics.
@@ -161,6 +181,11 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 		return nil, errors.Wrap(err, "create bucket stores")
 	}
 
+	if gatewayCfg.BucketIndexWriterEnabled {
+		isOwned := bucketIndexOwner(g.ring, lifecyclerCfg.Addr, limits)
+		g.bucketIndexWriter = newBucketIndexWriter(gatewayCfg.BucketIndexWriterInterval, storageCfg.BucketStore.TenantSyncConcurrency, bucketClient, limits, isOwned, logger, reg)
+	}
+
 	g.Service = services.NewBasicService(g.starting, g.running, g.stopping)
 
 	return g, nil
@@ -182,7 +207,11 @@ func (g *StoreGateway) starting(ctx context.Context) (err error) {
 
 	// First of all we register the instance in the ring and wait
 	// until the lifecycler successfully started.
-	if g.subservices, err = services.NewManager(g.ringLifecycler, g.ring); err != nil {
+	subservices := []services.Service{g.ringLifecycler, g.ring}
+	if g.bucketIndexWriter != nil {
+		subservices = append(subservices, g.bucketIndexWriter)
+	}
+	if g.subservices, err = services.NewManager(subservices...); err != nil {
 		return errors.Wrap(err, "unable to start store-gateway dependencies")
 	}
 
@@ -221,9 +250,12 @@ func (g *StoreGateway) starting(ctx context.Context) (err error) {
 	// At this point, if sharding is enabled, the instance is registered with some tokens
 	// and we can run the initial synchronization.
 	g.bucketSync.WithLabelValues(syncReasonInitial).Inc()
+	g.conditions.Set(conditionSynced, conditions.StatusFalse, "InitialSyncInProgress", "")
 	if err = g.stores.InitialSync(ctx); err != nil {
+		g.conditions.Set(conditionSynced, conditions.StatusFalse, "InitialSyncFailed", err.Error())
 		return errors.Wrap(err, "initial blocks synchronization")
 	}
+	g.conditions.Set(conditionSynced, conditions.StatusTrue, "InitialSyncSucceeded", "")
 
 	// Now that the initial sync is done, we should have loaded all blocks
 	// assigned to our shard, so we can switch to ACTIVE and start serving
@@ -250,6 +282,30 @@ func (g *StoreGateway) running(ctx context.Context) error {
 	syncTicker := time.NewTicker(util.DurationWithJitter(g.storageCfg.BucketStore.SyncInterval, 0.2))
 	defer syncTicker.Stop()
 
+	// The index-header warm up is disabled by default, in which case the channel is nil and never selected.
+	var warmupC <-chan time.Time
+	if interval := g.storageCfg.BucketStore.IndexHeaderWarmupInterval; interval > 0 {
+		warmupTicker := time.NewTicker(util.DurationWithJitter(interval, 0.2))
+		defer warmupTicker.Stop()
+		warmupC = warmupTicker.C
+	}
+
+	// The query concurrency is adapted to the memory pressure only if enabled. It's done in a dedicated
+	// goroutine, so that it's not delayed by the blocks synchronization, which may take a long time
+	// and allocate a lot of memory.
+	if g.storageCfg.BucketStore.AdaptiveMaxConcurrentEnabled() {
+		queryConcurrencyCtx, cancelQueryConcurrency := context.WithCancel(ctx)
+		queryConcurrencyDone := make(chan struct{})
+		go func() {
+			defer close(queryConcurrencyDone)
+			g.updateQueryConcurrency(queryConcurrencyCtx)
+		}()
+		defer func() {
+			cancelQueryConcurrency()
+			<-queryConcurrencyDone
+		}()
+	}
+
 	ringLastState, _ := g.ring.GetAllHealthy(BlocksOwnerSync) // nolint:errcheck
 	ringTicker := time.NewTicker(util.DurationWithJitter(g.gatewayCfg.ShardingRing.RingCheckPeriod, 0.2))
 	defer ringTicker.Stop()
@@ -258,6 +314,10 @@ func (g *StoreGateway) running(ctx context.Context) error {
 		select {
 		case <-syncTicker.C:
 			g.syncStores(ctx, syncReasonPeriodic)
+		case <-warmupC:
+			if err := g.stores.WarmUpIndexHeaders(ctx); err != nil {
+				level.Warn(g.logger).Log("msg", "failed to warm up index-headers", "err", err)
+			}
 		case <-ringTicker.C:
 			// We ignore the error because in case of error it will return an empty
 			// replication set which we use to compare with the previous state.
@@ -275,6 +335,22 @@ func (g *StoreGateway) running(ctx context.Context) error {
 	}
 }
 
+// updateQueryConcurrency periodically adapts the query concurrency to the memory pressure, until the
+// input context is canceled.
+func (g *StoreGateway) updateQueryConcurrency(ctx context.Context) {
+	ticker := time.NewTicker(queryConcurrencyUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			g.stores.UpdateQueryConcurrency()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (g *StoreGateway) stopping(_ error) error {
 	if g.subservices != nil {
 		return services.StopManagerAndAwaitStopped(context.Background(), g.subservices)
@@ -288,8 +364,10 @@ func (g *StoreGateway) syncStores(ctx context.Context, reason string) {
 
 	if err := g.stores.SyncBlocks(ctx); err != nil {
 		level.Warn(g.logger).Log("msg", "failed to synchronize TSDB blocks", "reason", reason, "err", err)
+		g.conditions.Set(conditionSynced, conditions.StatusFalse, "SyncFailed", err.Error())
 	} else {
 		level.Info(g.logger).Log("msg", "successfully synchronized TSDB blocks for all users", "reason", reason)
+		g.conditions.Set(conditionSynced, conditions.StatusTrue, "SyncSucceeded", "")
 	}
 }
 
@@ -310,7 +388,24 @@ func (g *StoreGateway) LabelNames(ctx context.Context, req *storepb.LabelNamesRe
 	})
 	defer g.tracker.Delete(ix)
 
-	return g.stores.LabelNames(ctx, req)
+	resp, err := g.stores.LabelNames(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.stores.checkLabelNamesAndValuesSize(ctx, resp.Names); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LabelNamesStream implements the storegatewaypb.StoreGatewayServer interface.
+func (g *StoreGateway) LabelNamesStream(req *storepb.LabelNamesRequest, srv storegatewaypb.StoreGateway_LabelNamesStreamServer) error {
+	ix := g.tracker.Insert(func() string {
+		return requestActivity(srv.Context(), "StoreGateway/LabelNamesStream", req)
+	})
+	defer g.tracker.Delete(ix)
+
+	return g.stores.StreamLabelNames(req, srv)
 }
 
 // LabelValues implements the storegatewaypb.StoreGatewayServer interface.
@@ -320,7 +415,34 @@ func (g *StoreGateway) LabelValues(ctx context.Context, req *storepb.LabelValues
 	})
 	defer g.tracker.Delete(ix)
 
-	return g.stores.LabelValues(ctx, req)
+	resp, err := g.stores.LabelValues(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.stores.checkLabelNamesAndValuesSize(ctx, resp.Values); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LabelValuesStream implements the storegatewaypb.StoreGatewayServer interface.
+func (g *StoreGateway) LabelValuesStream(req *storepb.LabelValuesRequest, srv storegatewaypb.StoreGateway_LabelValuesStreamServer) error {
+	ix := g.tracker.Insert(func() string {
+		return requestActivity(srv.Context(), "StoreGateway/LabelValuesStream", req)
+	})
+	defer g.tracker.Delete(ix)
+
+	return g.stores.StreamLabelValues(req, srv)
+}
+
+// Exemplars implements the storegatewaypb.StoreGatewayServer interface.
+func (g *StoreGateway) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	ix := g.tracker.Insert(func() string {
+		return requestActivity(ctx, "StoreGateway/Exemplars", req)
+	})
+	defer g.tracker.Delete(ix)
+
+	return g.stores.Exemplars(ctx, req)
 }
 
 func requestActivity(ctx context.Context, name string, req interface{}) string {
@@ -330,7 +452,7 @@ func requestActivity(ctx context.Context, name string, req interface{}) string {
 }
 
 func createBucketClient(cfg mimir_tsdb.BlocksStorageConfig, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
-	bucketClient, err := bucket.NewClient(context.Background(), cfg.Bucket, "store-gateway", logger, reg)
+	bucketClient, err := mimir_tsdb.NewBucketClient(context.Background(), cfg, "store-gateway", logger, reg)
 	if err != nil {
 		return nil, errors.Wrap(err, "create bucket client")
 	}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/oklog/ulid"
+)
+
+// InvalidateCachesHandler invalidates the chunks, index and metadata cache entries of the tenant, or of
+// a single tenant's block if the "block" parameter is set. The cache entries are invalidated bumping the
+// tenant cache epoch in the bucket, which is included in the cache keys.
+func (s *StoreGateway) InvalidateCachesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("can't parse form: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var blockID *ulid.ULID
+	if b := r.Form.Get("block"); b != "" {
+		id, err := ulid.Parse(b)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid block ID: %s", err), http.StatusBadRequest)
+			return
+		}
+		blockID = &id
+	}
+
+	if err := s.stores.InvalidateCaches(ctx, userID, blockID); err != nil {
+		level.Error(s.logger).Log("msg", "failed to invalidate caches", "user", userID, "block", r.Form.Get("block"), "err", err)
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(s.logger).Log("msg", "caches invalidated", "user", userID, "block", r.Form.Get("block"))
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"net/http"
+
+	"github.com/grafana/dskit/services"
+
+	"github.com/grafana/mimir/pkg/util/conditions"
+)
+
+const (
+	conditionRingHealthy = "RingHealthy"
+	conditionSynced      = "Synced"
+)
+
+// ReadyDetailHandler returns the status conditions of the store-gateway, providing more detail than the
+// binary readiness: whether the instance is ACTIVE in the ring and whether the last blocks synchronization succeeded.
+func (g *StoreGateway) ReadyDetailHandler(w http.ResponseWriter, _ *http.Request) {
+	// The ring can't be read before the store-gateway is in the Running state.
+	if g.State() == services.Running {
+		g.conditions.UpdateRingHealthy(conditionRingHealthy, g.ring, g.ringLifecycler.GetInstanceID())
+	}
+
+	conditions.WriteResponse(w, g.conditions.List())
+}
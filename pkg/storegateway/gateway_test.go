@@ -79,6 +79,13 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expected: nil,
 		},
+		"should fail if the bucket index writer is enabled with an invalid interval": {
+			setup: func(cfg *Config, limits *validation.Limits) {
+				cfg.BucketIndexWriterEnabled = true
+				cfg.BucketIndexWriterInterval = 0
+			},
+			expected: errInvalidBucketIndexWriterInterval,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -160,6 +167,8 @@ func TestStoreGateway_InitialSyncWithDefaultShardingEnabled(t *testing.T) {
 			})
 			bucketClient.MockIter("user-1/", []string{}, nil)
 			bucketClient.MockIter("user-2/", []string{}, nil)
+			bucketClient.MockIter(path.Join("user-1", mimir_tsdb.CacheEpochsDir), []string{}, nil)
+			bucketClient.MockIter(path.Join("user-2", mimir_tsdb.CacheEpochsDir), []string{}, nil)
 
 			// Once successfully started, the instance should be ACTIVE in the ring.
 			require.NoError(t, services.StartAndAwaitRunning(ctx, g))
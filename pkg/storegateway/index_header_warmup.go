@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/tsdb"
+	tsdb_errors "github.com/prometheus/prometheus/tsdb/errors"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// WarmUpIndexHeaders looks for the block replacement marks uploaded by the compactor and builds the
+// index-header of the new blocks which are owned by this store-gateway but haven't been loaded yet.
+// This way, the index-header is already on the local disk when the blocks get loaded by the
+// periodic sync, before the replaced blocks are deleted.
+func (u *BucketStores) WarmUpIndexHeaders(ctx context.Context) error {
+	u.storesMu.RLock()
+	stores := make(map[string]*BucketStore, len(u.stores))
+	for userID, store := range u.stores {
+		stores[userID] = store
+	}
+	u.storesMu.RUnlock()
+
+	errs := tsdb_errors.NewMulti()
+	for userID, store := range stores {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := u.warmUpUserIndexHeaders(ctx, userID, store); err != nil {
+			errs.Add(errors.Wrapf(err, "failed to warm up index-headers for user %s", userID))
+		}
+	}
+
+	return errs.Err()
+}
+
+func (u *BucketStores) warmUpUserIndexHeaders(ctx context.Context, userID string, store *BucketStore) error {
+	userBkt := bucket.NewUserBucketClient(userID, u.bucket, u.limits)
+
+	ids, err := bucketindex.ListBlockReplacementMarks(ctx, userBkt)
+	if err != nil {
+		return err
+	}
+
+	metas := make(map[ulid.ULID]*metadata.Meta, len(ids))
+	for _, id := range ids {
+		if store.getBlock(id) == nil {
+			metas[id] = &metadata.Meta{BlockMeta: tsdb.BlockMeta{ULID: id}}
+		}
+	}
+	if len(metas) == 0 {
+		return nil
+	}
+
+	// Blocks are sharded by ID, so the sharding strategy doesn't need the full meta.
+	synced := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "synced"}, []string{"state"})
+	if err := u.shardingStrategy.FilterBlocks(ctx, userID, metas, map[ulid.ULID]struct{}{}, synced); err != nil {
+		return err
+	}
+
+	userLogger := util_log.WithUserID(userID, u.logger)
+	for id := range metas {
+		built, err := store.WarmUpIndexHeader(ctx, id)
+		if err != nil {
+			// The block could be partially uploaded yet, so we just retry at the next warm up.
+			level.Warn(userLogger).Log("msg", "failed to build index-header of replacement block", "block", id, "err", err)
+			continue
+		}
+		if built {
+			u.indexHeaderWarmups.Inc()
+		}
+	}
+
+	return nil
+}
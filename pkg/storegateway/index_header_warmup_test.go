@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestBucketStores_WarmUpIndexHeaders(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	const (
+		userID     = "user-1"
+		metricName = "series_1"
+	)
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+	storageDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bkt, defaultLimitsOverrides(t), log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	generateStorageBlock(t, storageDir, userID, metricName, 10, 100, 15)
+	require.NoError(t, stores.InitialSync(ctx))
+	require.Equal(t, float64(1), stores.getBlocksLoadedMetric())
+
+	// Generate a new block, simulating the compactor uploading it along with its replacement mark.
+	generateStorageBlock(t, storageDir, userID, metricName, 100, 200, 15)
+	newBlockID := findBlockNotLoaded(t, stores, storageDir, userID)
+	require.NoError(t, bucketindex.WriteBlockReplacementMark(ctx, bucket.NewUserBucketClient(userID, bkt, nil), newBlockID, nil))
+
+	require.NoError(t, stores.WarmUpIndexHeaders(ctx))
+
+	// The index-header has been built, but the block hasn't been loaded yet.
+	_, err = os.Stat(filepath.Join(cfg.BucketStore.SyncDir, userID, newBlockID.String(), block.IndexHeaderFilename))
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), stores.getBlocksLoadedMetric())
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.indexHeaderWarmups))
+
+	// No temporary file has been left behind.
+	tmpFiles, err := filepath.Glob(filepath.Join(cfg.BucketStore.SyncDir, userID, newBlockID.String(), "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, tmpFiles)
+
+	// A subsequent warm up doesn't build the index-header again.
+	require.NoError(t, stores.WarmUpIndexHeaders(ctx))
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.indexHeaderWarmups))
+
+	// The next sync loads the block using the already built index-header.
+	require.NoError(t, stores.SyncBlocks(ctx))
+	assert.Equal(t, float64(2), stores.getBlocksLoadedMetric())
+
+	seriesSet, _, err := querySeries(t, stores, userID, metricName, 150, 180)
+	require.NoError(t, err)
+	assert.Len(t, seriesSet, 1)
+}
+
+func TestBucketStores_WarmUpIndexHeaders_ShouldDownloadPrebuiltIndexHeader(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	const (
+		userID     = "user-1"
+		metricName = "series_1"
+	)
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+	cfg.BucketStore.IndexHeader.PrebuiltDownloadEnabled = true
+	storageDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bkt, defaultLimitsOverrides(t), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+
+	generateStorageBlock(t, storageDir, userID, metricName, 10, 100, 15)
+	require.NoError(t, stores.InitialSync(ctx))
+
+	generateStorageBlock(t, storageDir, userID, metricName, 100, 200, 15)
+	newBlockID := findBlockNotLoaded(t, stores, storageDir, userID)
+	userBkt := bucket.NewUserBucketClient(userID, bkt, nil)
+	require.NoError(t, bucketindex.WriteBlockReplacementMark(ctx, userBkt, newBlockID, nil))
+
+	// Upload the index-header prebuilt by the compactor, and remove the block index, so that the
+	// index-header can only be downloaded.
+	prebuiltPath := filepath.Join(t.TempDir(), block.IndexHeaderFilename)
+	require.NoError(t, indexheader.WriteBinary(ctx, userBkt, newBlockID, prebuiltPath))
+	prebuilt, err := os.ReadFile(prebuiltPath)
+	require.NoError(t, err)
+	require.NoError(t, userBkt.Upload(ctx, path.Join(newBlockID.String(), block.IndexHeaderFilename), bytes.NewReader(prebuilt)))
+	require.NoError(t, userBkt.Delete(ctx, path.Join(newBlockID.String(), block.IndexFilename)))
+
+	require.NoError(t, stores.WarmUpIndexHeaders(ctx))
+
+	actual, err := os.ReadFile(filepath.Join(cfg.BucketStore.SyncDir, userID, newBlockID.String(), block.IndexHeaderFilename))
+	require.NoError(t, err)
+	assert.Equal(t, prebuilt, actual)
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.indexHeaderWarmups))
+}
+
+func findBlockNotLoaded(t *testing.T, stores *BucketStores, storageDir, userID string) ulid.ULID {
+	entries, err := os.ReadDir(filepath.Join(storageDir, userID))
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		id, ok := block.IsBlockDir(entry.Name())
+		if ok && stores.getStore(userID).getBlock(id) == nil {
+			return id
+		}
+	}
+
+	require.FailNow(t, "no block found which hasn't been loaded")
+	return ulid.ULID{}
+}
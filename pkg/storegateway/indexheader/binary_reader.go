@@ -9,13 +9,18 @@ import (
 	"bufio"
 	"context"
 	"encoding/binary"
+	"fmt"
 	"hash"
 	"hash/crc32"
 	"io"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"time"
 
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/runutil"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
@@ -116,6 +121,111 @@ func WriteBinary(ctx context.Context, bkt objstore.BucketReader, id ulid.ULID, f
 	return os.Rename(tmpFilename, filename)
 }
 
+// DownloadBinary downloads the index-header of the block prebuilt by the compactor to filename, after verifying
+// its format is supported. It returns false if the block has no prebuilt index-header in the bucket.
+func DownloadBinary(ctx context.Context, bkt objstore.BucketReader, id ulid.ULID, filename string) (_ bool, err error) {
+	rc, err := bkt.Get(ctx, path.Join(id.String(), block.IndexHeaderFilename))
+	if bkt.IsObjNotFoundErr(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "get prebuilt index-header")
+	}
+	defer runutil.CloseWithErrCapture(&err, rc, "close prebuilt index-header reader")
+
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return false, err
+	}
+
+	tmpFilename := filename + ".tmp"
+	if err := writeFileAndSync(tmpFilename, rc); err != nil {
+		_ = os.Remove(tmpFilename)
+		return false, errors.Wrap(err, "download prebuilt index-header")
+	}
+
+	if err := verifyBinary(tmpFilename); err != nil {
+		_ = os.Remove(tmpFilename)
+		return false, errors.Wrap(err, "verify prebuilt index-header")
+	}
+
+	return true, os.Rename(tmpFilename, filename)
+}
+
+func writeFileAndSync(filename string, r io.Reader) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer runutil.CloseWithErrCapture(&err, f, "close %s", filename)
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// verifyBinary checks the magic number, the format version and the table of contents checksum of the index-header file.
+func verifyBinary(filename string) (err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer runutil.CloseWithErrCapture(&err, f, "close %s", filename)
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < headerLen+binaryTOCLen {
+		return fmt.Errorf("index-header size %d is too small", info.Size())
+	}
+
+	header := make([]byte, headerLen)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return err
+	}
+	if magic := binary.BigEndian.Uint32(header); magic != MagicIndex {
+		return fmt.Errorf("invalid magic number %x", magic)
+	}
+	if version := header[4]; version != BinaryFormatV1 {
+		return fmt.Errorf("unknown index-header file version %d", version)
+	}
+
+	toc := make([]byte, binaryTOCLen)
+	if _, err := f.ReadAt(toc, info.Size()-binaryTOCLen); err != nil {
+		return err
+	}
+	if crc32.Checksum(toc[:binaryTOCLen-crc32.Size], castagnoliTable) != binary.BigEndian.Uint32(toc[binaryTOCLen-crc32.Size:]) {
+		return errors.New("index-header table of contents checksum mismatch")
+	}
+	return nil
+}
+
+// WriteOrDownloadBinary writes the index-header of the block to filename. When enabled, the index-header prebuilt
+// by the compactor is downloaded, otherwise, or if the block has no valid prebuilt index-header, the index-header
+// is built from the pieces of index in object storage.
+func WriteOrDownloadBinary(ctx context.Context, logger log.Logger, bkt objstore.BucketReader, id ulid.ULID, filename string, cfg Config) error {
+	start := time.Now()
+
+	if cfg.PrebuiltDownloadEnabled {
+		found, err := DownloadBinary(ctx, bkt, id, filename)
+		if err == nil && found {
+			level.Debug(logger).Log("msg", "downloaded prebuilt index-header file", "path", filename, "elapsed", time.Since(start))
+			return nil
+		}
+		if err != nil {
+			level.Warn(logger).Log("msg", "failed to download prebuilt index-header; building it", "path", filename, "err", err)
+		}
+	}
+
+	if err := WriteBinary(ctx, bkt, id, filename); err != nil {
+		return err
+	}
+
+	level.Debug(logger).Log("msg", "built index-header file", "path", filename, "elapsed", time.Since(start))
+	return nil
+}
+
 type chunkedIndexReader struct {
 	ctx  context.Context
 	path string
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexheader
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storegateway/testhelper"
+)
+
+func TestWriteOrDownloadBinary(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	require.NoError(t, err)
+
+	series := []labels.Labels{
+		labels.FromStrings("a", "1"),
+		labels.FromStrings("a", "2"),
+		labels.FromStrings("a", "3"),
+		labels.FromStrings("a", "4"),
+	}
+	id, err := testhelper.CreateBlock(ctx, tmpDir, series, 100, 0, 1000, labels.FromStrings("ext1", "1"))
+	require.NoError(t, err)
+	require.NoError(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, id.String()), nil))
+
+	// Build the index-header the compactor would upload.
+	expected := filepath.Join(tmpDir, "expected-index-header")
+	require.NoError(t, WriteBinary(ctx, bkt, id, expected))
+	expectedContent, err := os.ReadFile(expected)
+	require.NoError(t, err)
+
+	unsupportedVersion := append([]byte{}, expectedContent...)
+	unsupportedVersion[4] = BinaryFormatV1 + 1
+
+	corrupted := append([]byte{}, expectedContent...)
+	corrupted[len(corrupted)-binaryTOCLen] ^= 0xff
+
+	tests := map[string]struct {
+		prebuilt                []byte
+		prebuiltDownloadEnabled bool
+		expectedDownloaded      bool
+	}{
+		"should build the index-header if the download is disabled": {
+			prebuilt:                expectedContent,
+			prebuiltDownloadEnabled: false,
+			expectedDownloaded:      false,
+		},
+		"should build the index-header if the block has no prebuilt index-header": {
+			prebuiltDownloadEnabled: true,
+			expectedDownloaded:      false,
+		},
+		"should download the prebuilt index-header": {
+			prebuilt:                expectedContent,
+			prebuiltDownloadEnabled: true,
+			expectedDownloaded:      true,
+		},
+		"should build the index-header if the prebuilt index-header version is not supported": {
+			prebuilt:                unsupportedVersion,
+			prebuiltDownloadEnabled: true,
+			expectedDownloaded:      false,
+		},
+		"should build the index-header if the prebuilt index-header is corrupted": {
+			prebuilt:                corrupted,
+			prebuiltDownloadEnabled: true,
+			expectedDownloaded:      false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			objName := path.Join(id.String(), block.IndexHeaderFilename)
+			if tc.prebuilt != nil {
+				require.NoError(t, bkt.Upload(ctx, objName, bytes.NewReader(tc.prebuilt)))
+			} else {
+				require.NoError(t, bkt.Delete(ctx, objName))
+			}
+
+			// Keep track of the reads of the block index, to check whether the index-header has been built.
+			countingBkt := &bucketWithIndexGets{Bucket: bkt}
+
+			filename := filepath.Join(t.TempDir(), id.String(), block.IndexHeaderFilename)
+			require.NoError(t, WriteOrDownloadBinary(ctx, log.NewNopLogger(), countingBkt, id, filename, Config{PrebuiltDownloadEnabled: tc.prebuiltDownloadEnabled}))
+
+			actual, err := os.ReadFile(filename)
+			require.NoError(t, err)
+			assert.Equal(t, expectedContent, actual)
+			assert.Equal(t, tc.expectedDownloaded, countingBkt.indexGets == 0)
+			assert.NoFileExists(t, filename+".tmp")
+		})
+	}
+}
+
+type bucketWithIndexGets struct {
+	*filesystem.Bucket
+
+	indexGets int
+}
+
+func (b *bucketWithIndexGets) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	if path.Base(name) == block.IndexFilename {
+		b.indexGets++
+	}
+	return b.Bucket.Attributes(ctx, name)
+}
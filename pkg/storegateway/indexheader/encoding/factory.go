@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
 )
 
 var ErrPoolStopped = errors.New("file handle pool is stopped")
@@ -22,10 +23,22 @@ type DecbufFactoryMetrics struct {
 	pooledOpenCount  prometheus.Counter
 	closeCount       prometheus.Counter
 	pooledCloseCount prometheus.Counter
+
+	// Number of index-header file handles currently open, across all the factories sharing the metrics.
+	openFiles *atomic.Int64
 }
 
 func NewDecbufFactoryMetrics(reg prometheus.Registerer) *DecbufFactoryMetrics {
+	openFiles := atomic.NewInt64(0)
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "indexheader_stream_open_files",
+		Help: "Number of index-header file handles currently open.",
+	}, func() float64 {
+		return float64(openFiles.Load())
+	})
+
 	return &DecbufFactoryMetrics{
+		openFiles: openFiles,
 		openCount: promauto.With(reg).NewCounter(prometheus.CounterOpts{
 			Name: "indexheader_stream_unpooled_open_total",
 			Help: "Total number of times index-header file has been opened instead of using a pooled handle.",
@@ -45,6 +58,12 @@ func NewDecbufFactoryMetrics(reg prometheus.Registerer) *DecbufFactoryMetrics {
 	}
 }
 
+// OpenFiles returns the number of index-header file handles currently open, across all the
+// factories sharing the metrics.
+func (m *DecbufFactoryMetrics) OpenFiles() int64 {
+	return m.openFiles.Load()
+}
+
 // DecbufFactory creates new file-backed decoding buffer instances for a specific index-header file.
 type DecbufFactory struct {
 	files *filePool
@@ -60,6 +79,7 @@ func NewDecbufFactory(path string, maxIdleFileHandles uint, logger log.Logger, m
 			metrics.pooledOpenCount,
 			metrics.closeCount,
 			metrics.pooledCloseCount,
+			metrics.openFiles,
 		),
 	}
 }
@@ -183,11 +203,12 @@ type filePool struct {
 	pooledOpens  prometheus.Counter
 	closes       prometheus.Counter
 	pooledCloses prometheus.Counter
+	openFiles    *atomic.Int64
 }
 
 // newFilePool creates a new file pool for path with cap capacity. If cap is 0,
 // get always opens new file handles and put always closes them immediately.
-func newFilePool(path string, cap uint, logger log.Logger, opens prometheus.Counter, pooledOpens prometheus.Counter, closes prometheus.Counter, pooledCloses prometheus.Counter) *filePool {
+func newFilePool(path string, cap uint, logger log.Logger, opens prometheus.Counter, pooledOpens prometheus.Counter, closes prometheus.Counter, pooledCloses prometheus.Counter, openFiles *atomic.Int64) *filePool {
 	return &filePool{
 		path:   path,
 		logger: logger,
@@ -200,6 +221,7 @@ func newFilePool(path string, cap uint, logger log.Logger, opens prometheus.Coun
 		pooledOpens:  pooledOpens,
 		closes:       closes,
 		pooledCloses: pooledCloses,
+		openFiles:    openFiles,
 	}
 }
 
@@ -220,7 +242,12 @@ func (p *filePool) get() (*os.File, error) {
 		return f, nil
 	default:
 		p.opens.Inc()
-		return os.Open(p.path)
+		f, err := os.Open(p.path)
+		if err != nil {
+			return nil, err
+		}
+		p.openFiles.Inc()
+		return f, nil
 	}
 }
 
@@ -232,7 +259,7 @@ func (p *filePool) put(f *os.File) error {
 	defer p.mtx.RUnlock()
 
 	if p.stopped {
-		return f.Close()
+		return p.closeFile(f)
 	}
 
 	select {
@@ -241,7 +268,7 @@ func (p *filePool) put(f *os.File) error {
 		return nil
 	default:
 		p.closes.Inc()
-		return f.Close()
+		return p.closeFile(f)
 	}
 }
 
@@ -256,7 +283,7 @@ func (p *filePool) stop() {
 	for {
 		select {
 		case f := <-p.handles:
-			if err := f.Close(); err != nil {
+			if err := p.closeFile(f); err != nil {
 				level.Warn(p.logger).Log("msg", "closing index-header file during pool stop", "path", p.path, "err", err)
 			}
 		default:
@@ -264,3 +291,9 @@ func (p *filePool) stop() {
 		}
 	}
 }
+
+// closeFile closes a file handle opened by this pool.
+func (p *filePool) closeFile(f *os.File) error {
+	p.openFiles.Dec()
+	return f.Close()
+}
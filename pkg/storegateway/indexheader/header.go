@@ -45,9 +45,13 @@ type Reader interface {
 }
 
 type Config struct {
-	MaxIdleFileHandles uint `yaml:"max_idle_file_handles" category:"advanced"`
+	MaxIdleFileHandles      uint `yaml:"max_idle_file_handles" category:"advanced"`
+	PrebuiltDownloadEnabled bool `yaml:"prebuilt_download_enabled" category:"experimental"`
+	MaxOpenFiles            uint `yaml:"max_open_files" category:"experimental"`
 }
 
 func (cfg *Config) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
 	f.UintVar(&cfg.MaxIdleFileHandles, prefix+"max-idle-file-handles", 1, "Maximum number of idle file handles the store-gateway keeps open for each index-header file.")
+	f.BoolVar(&cfg.PrebuiltDownloadEnabled, prefix+"prebuilt-download-enabled", false, "If enabled, the store-gateway downloads the index-header of a block prebuilt by the compactor, if any, instead of building it from the block index. The store-gateway falls back to building the index-header if the block has no prebuilt index-header or its format is not supported. Requires -compactor.upload-index-headers.")
+	f.UintVar(&cfg.MaxOpenFiles, prefix+"max-open-files", 0, "Maximum number of index-header file handles the store-gateway keeps open across all tenants. When the limit is exceeded, the least recently used index-headers are unloaded, closing their file handles. Requires index-header lazy loading. 0 to disable.")
 }
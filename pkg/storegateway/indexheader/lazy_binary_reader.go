@@ -85,7 +85,8 @@ type LazyBinaryReader struct {
 
 // NewLazyBinaryReader makes a new LazyBinaryReader. If the index-header does not exist
 // on the local disk at dir location, this function will build it downloading required
-// sections from the full index stored in the bucket. However, this function doesn't load
+// sections from the full index stored in the bucket, or download the index-header prebuilt
+// by the compactor if enabled. However, this function doesn't load
 // (mmap or streaming read) the index-header; it will be loaded at first Reader function call.
 func NewLazyBinaryReader(
 	ctx context.Context,
@@ -96,6 +97,7 @@ func NewLazyBinaryReader(
 	id ulid.ULID,
 	metrics *LazyBinaryReaderMetrics,
 	onClosed func(*LazyBinaryReader),
+	cfg Config,
 ) (*LazyBinaryReader, error) {
 	path := filepath.Join(dir, id.String(), block.IndexHeaderFilename)
 
@@ -107,12 +109,9 @@ func NewLazyBinaryReader(
 
 		level.Debug(logger).Log("msg", "the index-header doesn't exist on disk; recreating", "path", path)
 
-		start := time.Now()
-		if err := WriteBinary(ctx, bkt, id, path); err != nil {
+		if err := WriteOrDownloadBinary(ctx, logger, bkt, id, path, cfg); err != nil {
 			return nil, errors.Wrap(err, "write index header")
 		}
-
-		level.Debug(logger).Log("msg", "built index-header file", "path", path, "elapsed", time.Since(start))
 	}
 
 	return &LazyBinaryReader{
@@ -271,6 +270,29 @@ func (r *LazyBinaryReader) unloadIfIdleSince(ts int64) error {
 		return errNotIdle
 	}
 
+	return r.unload()
+}
+
+// unloadIfNotInUse closes underlying BinaryReader if the reader is loaded and not in use by any
+// caller. Returns whether the reader has been unloaded. This function never blocks.
+func (r *LazyBinaryReader) unloadIfNotInUse() (bool, error) {
+	if !r.readerMx.TryLock() {
+		return false, nil
+	}
+	defer r.readerMx.Unlock()
+
+	if r.reader == nil {
+		return false, nil
+	}
+
+	if err := r.unload(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// unload closes underlying BinaryReader. This function MUST be called with the write lock already acquired.
+func (r *LazyBinaryReader) unload() error {
 	r.metrics.unloadCount.Inc()
 	if err := r.reader.Close(); err != nil {
 		r.metrics.unloadFailedCount.Inc()
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexheader
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// OpenFilesBudget limits the number of index-header file handles open across all the reader pools
+// sharing it. When the limit is exceeded, the least recently used lazy readers are unloaded, closing
+// their file handles, until the number of open file handles is back within the limit.
+type OpenFilesBudget struct {
+	maxOpenFiles int64
+	openFiles    func() int64
+	logger       log.Logger
+
+	// Keep track of all lazy readers managed by the reader pools sharing the budget.
+	readersMx sync.Mutex
+	readers   map[*LazyBinaryReader]struct{}
+
+	unloads prometheus.Counter
+}
+
+// NewOpenFilesBudget makes a new OpenFilesBudget allowing up to maxOpenFiles index-header file handles
+// open, as tracked by the provided metrics.
+func NewOpenFilesBudget(maxOpenFiles uint, metrics *ReaderPoolMetrics, logger log.Logger, reg prometheus.Registerer) *OpenFilesBudget {
+	b := &OpenFilesBudget{
+		maxOpenFiles: int64(maxOpenFiles),
+		openFiles:    metrics.streamReader.decbufFactory.OpenFiles,
+		logger:       logger,
+		readers:      map[*LazyBinaryReader]struct{}{},
+		unloads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "indexheader_open_files_budget_unloads_total",
+			Help: "Total number of index-headers unloaded because the limit on the open index-header file handles has been exceeded.",
+		}),
+	}
+
+	promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+		Name: "indexheader_max_open_files",
+		Help: "Maximum number of index-header file handles kept open.",
+	}).Set(float64(maxOpenFiles))
+
+	return b
+}
+
+func (b *OpenFilesBudget) track(r *LazyBinaryReader) {
+	b.readersMx.Lock()
+	defer b.readersMx.Unlock()
+
+	b.readers[r] = struct{}{}
+}
+
+func (b *OpenFilesBudget) untrack(r *LazyBinaryReader) {
+	b.readersMx.Lock()
+	defer b.readersMx.Unlock()
+
+	delete(b.readers, r)
+}
+
+// enforce unloads the least recently used lazy readers not in use until the number of open file
+// handles is within the limit.
+func (b *OpenFilesBudget) enforce() {
+	if b.openFiles() <= b.maxOpenFiles {
+		return
+	}
+
+	b.readersMx.Lock()
+	readers := make([]*LazyBinaryReader, 0, len(b.readers))
+	for r := range b.readers {
+		readers = append(readers, r)
+	}
+	b.readersMx.Unlock()
+
+	sort.Slice(readers, func(i, j int) bool {
+		return readers[i].usedAt.Load() < readers[j].usedAt.Load()
+	})
+
+	for _, r := range readers {
+		if b.openFiles() <= b.maxOpenFiles {
+			return
+		}
+
+		unloaded, err := r.unloadIfNotInUse()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to close index-header reader exceeding the open files limit", "path", r.filepath, "err", err)
+			continue
+		}
+		if unloaded {
+			b.unloads.Inc()
+		}
+	}
+}
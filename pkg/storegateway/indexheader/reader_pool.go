@@ -43,6 +43,9 @@ type ReaderPool struct {
 	logger                log.Logger
 	metrics               *ReaderPoolMetrics
 
+	// Optional budget of the index-header files open, shared across pools.
+	openFilesBudget *OpenFilesBudget
+
 	// Channel used to signal once the pool is closing.
 	close chan struct{}
 
@@ -52,8 +55,9 @@ type ReaderPool struct {
 }
 
 // NewReaderPool makes a new ReaderPool and starts a background task for unloading idle Readers if enabled.
-func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, metrics *ReaderPoolMetrics) *ReaderPool {
-	p := newReaderPool(logger, lazyReaderEnabled, lazyReaderIdleTimeout, metrics)
+// The openFilesBudget is optional and only enforced when the lazy reader is enabled.
+func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, metrics *ReaderPoolMetrics, openFilesBudget *OpenFilesBudget) *ReaderPool {
+	p := newReaderPool(logger, lazyReaderEnabled, lazyReaderIdleTimeout, metrics, openFilesBudget)
 
 	// Start a goroutine to close idle readers (only if required).
 	if p.lazyReaderEnabled && p.lazyReaderIdleTimeout > 0 {
@@ -75,10 +79,15 @@ func NewReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTime
 }
 
 // newReaderPool makes a new ReaderPool.
-func newReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, metrics *ReaderPoolMetrics) *ReaderPool {
+func newReaderPool(logger log.Logger, lazyReaderEnabled bool, lazyReaderIdleTimeout time.Duration, metrics *ReaderPoolMetrics, openFilesBudget *OpenFilesBudget) *ReaderPool {
+	if !lazyReaderEnabled {
+		openFilesBudget = nil
+	}
+
 	return &ReaderPool{
 		logger:                logger,
 		metrics:               metrics,
+		openFilesBudget:       openFilesBudget,
 		lazyReaderEnabled:     lazyReaderEnabled,
 		lazyReaderIdleTimeout: lazyReaderIdleTimeout,
 		lazyReaders:           make(map[*LazyBinaryReader]struct{}),
@@ -95,11 +104,16 @@ func (p *ReaderPool) NewBinaryReader(ctx context.Context, logger log.Logger, bkt
 	var err error
 
 	readerFactory = func() (Reader, error) {
-		return NewStreamBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling, p.metrics.streamReader, cfg)
+		r, err := NewStreamBinaryReader(ctx, logger, bkt, dir, id, postingOffsetsInMemSampling, p.metrics.streamReader, cfg)
+		if err == nil && p.openFilesBudget != nil {
+			// Loading the reader may have exceeded the open files limit.
+			p.openFilesBudget.enforce()
+		}
+		return r, err
 	}
 
 	if p.lazyReaderEnabled {
-		reader, err = NewLazyBinaryReader(ctx, readerFactory, logger, bkt, dir, id, p.metrics.lazyReader, p.onLazyReaderClosed)
+		reader, err = NewLazyBinaryReader(ctx, readerFactory, logger, bkt, dir, id, p.metrics.lazyReader, p.onLazyReaderClosed, cfg)
 	} else {
 		reader, err = readerFactory()
 	}
@@ -114,6 +128,9 @@ func (p *ReaderPool) NewBinaryReader(ctx context.Context, logger log.Logger, bkt
 		p.lazyReaders[reader.(*LazyBinaryReader)] = struct{}{}
 		p.lazyReadersMx.Unlock()
 	}
+	if p.openFilesBudget != nil {
+		p.openFilesBudget.track(reader.(*LazyBinaryReader))
+	}
 
 	return reader, err
 }
@@ -157,6 +174,10 @@ func (p *ReaderPool) isTracking(r *LazyBinaryReader) bool {
 }
 
 func (p *ReaderPool) onLazyReaderClosed(r *LazyBinaryReader) {
+	if p.openFilesBudget != nil {
+		p.openFilesBudget.untrack(r)
+	}
+
 	p.lazyReadersMx.Lock()
 	defer p.lazyReadersMx.Unlock()
 
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
 	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/require"
@@ -62,7 +63,7 @@ func TestReaderPool_NewBinaryReader(t *testing.T) {
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			pool := NewReaderPool(log.NewNopLogger(), testData.lazyReaderEnabled, testData.lazyReaderIdleTimeout, NewReaderPoolMetrics(nil))
+			pool := NewReaderPool(log.NewNopLogger(), testData.lazyReaderEnabled, testData.lazyReaderIdleTimeout, NewReaderPoolMetrics(nil), nil)
 			defer pool.Close()
 
 			r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, blockID, 3, Config{})
@@ -102,7 +103,7 @@ func TestReaderPool_ShouldCloseIdleLazyReaders(t *testing.T) {
 	metrics := NewReaderPoolMetrics(nil)
 	// Note that we are creating a ReaderPool that doesn't run a background cleanup task for idle
 	// Reader instances. We'll manually invoke the cleanup task when we need it as part of this test.
-	pool := newReaderPool(log.NewNopLogger(), true, idleTimeout, metrics)
+	pool := newReaderPool(log.NewNopLogger(), true, idleTimeout, metrics, nil)
 	defer pool.Close()
 
 	r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, blockID, 3, Config{})
@@ -139,3 +140,58 @@ func TestReaderPool_ShouldCloseIdleLazyReaders(t *testing.T) {
 	require.Equal(t, float64(2), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
 	require.Equal(t, float64(2), promtestutil.ToFloat64(metrics.lazyReader.unloadCount))
 }
+
+func TestReaderPool_ShouldUnloadLeastRecentlyUsedLazyReadersExceedingOpenFilesBudget(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, bkt.Close()) }()
+
+	// Create blocks.
+	var blockIDs []ulid.ULID
+	for i := 0; i < 3; i++ {
+		blockID, err := testhelper.CreateBlock(ctx, tmpDir, []labels.Labels{
+			labels.FromStrings("a", "1"),
+			labels.FromStrings("a", "2"),
+			labels.FromStrings("a", "3"),
+		}, 100, 0, 1000, labels.FromStrings("ext1", "1"))
+		require.NoError(t, err)
+		require.NoError(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), nil))
+		blockIDs = append(blockIDs, blockID)
+	}
+
+	metrics := NewReaderPoolMetrics(nil)
+	budget := NewOpenFilesBudget(1, metrics, log.NewNopLogger(), nil)
+	pool := newReaderPool(log.NewNopLogger(), true, 0, metrics, budget)
+	defer pool.Close()
+
+	var readers []*LazyBinaryReader
+	for _, blockID := range blockIDs {
+		r, err := pool.NewBinaryReader(ctx, log.NewNopLogger(), bkt, tmpDir, blockID, 3, Config{MaxIdleFileHandles: 1})
+		require.NoError(t, err)
+		defer func() { require.NoError(t, r.Close()) }()
+
+		// Ensure it can read data.
+		labelNames, err := r.LabelNames()
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, labelNames)
+
+		readers = append(readers, r.(*LazyBinaryReader))
+	}
+
+	// Loading the readers has unloaded the least recently used ones to stay within the budget.
+	require.LessOrEqual(t, metrics.streamReader.decbufFactory.OpenFiles(), int64(1))
+	require.Equal(t, float64(3), promtestutil.ToFloat64(metrics.lazyReader.loadCount))
+	require.Equal(t, float64(2), promtestutil.ToFloat64(budget.unloads))
+	require.Nil(t, readers[0].reader)
+	require.Nil(t, readers[1].reader)
+	require.NotNil(t, readers[2].reader)
+
+	// Closed readers are no longer tracked by the budget.
+	require.NoError(t, readers[0].Close())
+	budget.readersMx.Lock()
+	require.Len(t, budget.readers, 2)
+	budget.readersMx.Unlock()
+}
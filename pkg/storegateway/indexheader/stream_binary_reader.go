@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"path/filepath"
 	"sync"
-	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -69,12 +68,10 @@ func NewStreamBinaryReader(ctx context.Context, logger log.Logger, bkt objstore.
 
 	level.Debug(logger).Log("msg", "failed to read index-header from disk; recreating", "path", binfn, "err", err)
 
-	start := time.Now()
-	if err := WriteBinary(ctx, bkt, id, binfn); err != nil {
+	if err := WriteOrDownloadBinary(ctx, logger, bkt, id, binfn, cfg); err != nil {
 		return nil, fmt.Errorf("cannot write index header: %w", err)
 	}
 
-	level.Debug(logger).Log("msg", "built index-header file", "path", binfn, "elapsed", time.Since(start))
 	return newFileStreamBinaryReader(binfn, postingOffsetsInMemSampling, logger, metrics, cfg)
 }
 
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// labelNamesAndValuesMessageSizeThresholdBytes is the target size of each message streamed
+// in response to LabelNamesStream and LabelValuesStream requests.
+const labelNamesAndValuesMessageSizeThresholdBytes = 1 * 1024 * 1024
+
+// StreamLabelNames streams the label names of the tenant in the request context, as soon as they're read
+// from each block. Hints are sent in the last message.
+func (u *BucketStores) StreamLabelNames(req *storepb.LabelNamesRequest, srv storegatewaypb.StoreGateway_LabelNamesStreamServer) error {
+	spanLog, spanCtx := spanlogger.NewWithLogger(srv.Context(), u.logger, "BucketStores.StreamLabelNames")
+	defer spanLog.Span.Finish()
+
+	userID := getUserIDFromGRPCContext(spanCtx)
+	if userID == "" {
+		return fmt.Errorf("no userID")
+	}
+
+	store := u.getStore(userID)
+	if store == nil {
+		return nil
+	}
+
+	streamer := newLabelNamesAndValuesStreamer(u.limits.StoreGatewayLabelNamesAndValuesMaxSizeBytes(userID), func(names []string) error {
+		return srv.Send(&storepb.LabelNamesResponse{Names: names})
+	})
+
+	anyHints, err := store.labelNames(spanCtx, req, streamer.send)
+	if streamer.err != nil {
+		return streamer.err
+	}
+	if err != nil {
+		return err
+	}
+
+	return srv.Send(&storepb.LabelNamesResponse{Hints: anyHints})
+}
+
+// StreamLabelValues streams the label values of the tenant in the request context, as soon as they're read
+// from each block. Hints are sent in the last message.
+func (u *BucketStores) StreamLabelValues(req *storepb.LabelValuesRequest, srv storegatewaypb.StoreGateway_LabelValuesStreamServer) error {
+	spanLog, spanCtx := spanlogger.NewWithLogger(srv.Context(), u.logger, "BucketStores.StreamLabelValues")
+	defer spanLog.Span.Finish()
+
+	userID := getUserIDFromGRPCContext(spanCtx)
+	if userID == "" {
+		return fmt.Errorf("no userID")
+	}
+
+	store := u.getStore(userID)
+	if store == nil {
+		return nil
+	}
+
+	streamer := newLabelNamesAndValuesStreamer(u.limits.StoreGatewayLabelNamesAndValuesMaxSizeBytes(userID), func(values []string) error {
+		return srv.Send(&storepb.LabelValuesResponse{Values: values})
+	})
+
+	anyHints, err := store.labelValues(spanCtx, req, streamer.send)
+	if streamer.err != nil {
+		return streamer.err
+	}
+	if err != nil {
+		return err
+	}
+
+	return srv.Send(&storepb.LabelValuesResponse{Hints: anyHints})
+}
+
+// labelNamesAndValuesStreamer sends the label names or values read from each block, skipping the ones
+// which have already been sent, and enforces the max size of the response.
+type labelNamesAndValuesStreamer struct {
+	maxSizeBytes int
+	sendFn       func([]string) error
+
+	mtx       sync.Mutex
+	sent      map[string]struct{}
+	sizeBytes int
+
+	// err is the first error returned by send. It's safe to read it once no more send calls are in progress.
+	err error
+}
+
+func newLabelNamesAndValuesStreamer(maxSizeBytes int, sendFn func([]string) error) *labelNamesAndValuesStreamer {
+	return &labelNamesAndValuesStreamer{
+		maxSizeBytes: maxSizeBytes,
+		sendFn:       sendFn,
+		sent:         map[string]struct{}{},
+	}
+}
+
+// send sends the input sorted label names or values of a block, split into multiple messages. It's
+// safe to call it concurrently.
+func (s *labelNamesAndValuesStreamer) send(values []string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	unsent := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := s.sent[v]; ok {
+			continue
+		}
+
+		s.sent[v] = struct{}{}
+		s.sizeBytes += len(v)
+		if s.maxSizeBytes > 0 && s.sizeBytes > s.maxSizeBytes {
+			s.err = httpgrpc.Errorf(http.StatusUnprocessableEntity, validation.NewStoreGatewayLabelNamesAndValuesMaxSizeBytesError(s.maxSizeBytes).Error())
+			return s.err
+		}
+
+		unsent = append(unsent, v)
+	}
+
+	if len(unsent) == 0 {
+		return nil
+	}
+
+	for _, batch := range splitStringsBySize(unsent, labelNamesAndValuesMessageSizeThresholdBytes) {
+		if err := s.sendFn(batch); err != nil {
+			s.err = err
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkLabelNamesAndValuesSize returns an error if the size of the input label names or values
+// exceeds the limit of the tenant in the request context.
+func (u *BucketStores) checkLabelNamesAndValuesSize(ctx context.Context, values []string) error {
+	userID := getUserIDFromGRPCContext(ctx)
+	maxSizeBytes := u.limits.StoreGatewayLabelNamesAndValuesMaxSizeBytes(userID)
+	if maxSizeBytes <= 0 {
+		return nil
+	}
+
+	sizeBytes := 0
+	for _, v := range values {
+		sizeBytes += len(v)
+		if sizeBytes > maxSizeBytes {
+			return httpgrpc.Errorf(http.StatusUnprocessableEntity, validation.NewStoreGatewayLabelNamesAndValuesMaxSizeBytesError(maxSizeBytes).Error())
+		}
+	}
+
+	return nil
+}
+
+// splitStringsBySize splits the input strings into batches, each one with a size in bytes close to
+// the target size. A string is never split, so a batch can exceed the target size by the size of its
+// last string. It always returns at least one batch, which is empty if there are no input strings.
+func splitStringsBySize(values []string, targetSizeBytes int) [][]string {
+	batches := [][]string{}
+	start, sizeBytes := 0, 0
+
+	for idx, v := range values {
+		sizeBytes += len(v)
+		if sizeBytes >= targetSizeBytes {
+			batches = append(batches, values[start:idx+1])
+			start, sizeBytes = idx+1, 0
+		}
+	}
+
+	if start < len(values) || len(batches) == 0 {
+		batches = append(batches, values[start:])
+	}
+
+	return batches
+}
@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/test"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestSplitStringsBySize(t *testing.T) {
+	tests := map[string]struct {
+		values          []string
+		targetSizeBytes int
+		expected        [][]string
+	}{
+		"no values": {
+			values:          nil,
+			targetSizeBytes: 10,
+			expected:        [][]string{nil},
+		},
+		"values smaller than the target size": {
+			values:          []string{"a", "b", "c"},
+			targetSizeBytes: 10,
+			expected:        [][]string{{"a", "b", "c"}},
+		},
+		"values exactly matching the target size": {
+			values:          []string{"aa", "bb", "cc", "dd"},
+			targetSizeBytes: 4,
+			expected:        [][]string{{"aa", "bb"}, {"cc", "dd"}},
+		},
+		"values exceeding the target size": {
+			values:          []string{"aaa", "bbb", "ccc", "ddd", "eee"},
+			targetSizeBytes: 4,
+			expected:        [][]string{{"aaa", "bbb"}, {"ccc", "ddd"}, {"eee"}},
+		},
+		"a single value larger than the target size": {
+			values:          []string{"a", "bbbbbbbbbb", "c"},
+			targetSizeBytes: 4,
+			expected:        [][]string{{"a", "bbbbbbbbbb"}, {"c"}},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, splitStringsBySize(testData.values, testData.targetSizeBytes))
+		})
+	}
+}
+
+func TestStoreGateway_LabelNamesAndValues_ShouldStreamResponsesAndEnforceMaxSize(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	var (
+		ctx    = context.Background()
+		userID = "user-1"
+		series = []labels.Labels{
+			labels.FromStrings(labels.MetricName, "series_1", "zone", "a"),
+			labels.FromStrings(labels.MetricName, "series_2", "zone", "b"),
+			labels.FromStrings(labels.MetricName, "series_3", "zone", "c"),
+		}
+	)
+
+	// Prepare the storage dir.
+	bucketClient, storageDir := mimir_testutil.PrepareFilesystemBucket(t)
+
+	// Generate a TSDB block in the storage dir, containing the fixture series.
+	mockTSDBWithGenerator(t, path.Join(storageDir, userID), func() func() (bool, labels.Labels, int64, float64) {
+		nextID := 0
+		return func() (bool, labels.Labels, int64, float64) {
+			if nextID >= len(series) {
+				return false, labels.Labels{}, 0, 0
+			}
+
+			nextSeries := series[nextID]
+			nextID++
+
+			return true, nextSeries, util.TimeToMillis(time.Now().Add(-time.Duration(nextID) * time.Second)), float64(nextID)
+		}
+	}())
+
+	createBucketIndex(t, bucketClient, userID)
+
+	tests := map[string]struct {
+		maxSizeBytes   int
+		expectedNames  []string
+		expectedValues []string
+		expectedErr    bool
+	}{
+		"should return label names and values if the limit is disabled": {
+			maxSizeBytes:   0,
+			expectedNames:  []string{labels.MetricName, "zone"},
+			expectedValues: []string{"series_1", "series_2", "series_3"},
+		},
+		"should return label names and values if the limit is not exceeded": {
+			maxSizeBytes:   100,
+			expectedNames:  []string{labels.MetricName, "zone"},
+			expectedValues: []string{"series_1", "series_2", "series_3"},
+		},
+		"should fail if the limit is exceeded": {
+			maxSizeBytes: 5,
+			expectedErr:  true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := defaultLimitsConfig()
+			limits.StoreGatewayLabelNamesAndValuesMaxSizeBytes = testData.maxSizeBytes
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			// Create a store-gateway.
+			gatewayCfg := mockGatewayConfig()
+			storageCfg := mockStorageConfig(t)
+			storageCfg.BucketStore.BucketIndex.Enabled = true
+
+			ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+			t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+			g, err := newStoreGateway(gatewayCfg, storageCfg, bucketClient, ringStore, overrides, log.NewNopLogger(), nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+			t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, g)) })
+
+			srv := newStoreGatewayTestServer(t, g)
+
+			conn, err := grpc.Dial(srv.serverListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			t.Cleanup(func() { assert.NoError(t, conn.Close()) })
+
+			client := storegatewaypb.NewStoreGatewayClient(conn)
+			reqCtx := setUserIDToGRPCContext(ctx, userID)
+
+			namesReq := &storepb.LabelNamesRequest{Start: math.MinInt64, End: math.MaxInt64}
+			valuesReq := &storepb.LabelValuesRequest{Label: labels.MetricName, Start: math.MinInt64, End: math.MaxInt64}
+
+			namesStream, err := client.LabelNamesStream(reqCtx, namesReq)
+			require.NoError(t, err)
+			names, err := receiveLabelNames(namesStream)
+			if testData.expectedErr {
+				assertLabelNamesAndValuesMaxSizeError(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, testData.expectedNames, names)
+			}
+
+			namesRes, err := client.LabelNames(reqCtx, namesReq)
+			if testData.expectedErr {
+				assertLabelNamesAndValuesMaxSizeError(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, testData.expectedNames, namesRes.Names)
+			}
+
+			valuesStream, err := client.LabelValuesStream(reqCtx, valuesReq)
+			require.NoError(t, err)
+			values, err := receiveLabelValues(valuesStream)
+			if testData.expectedErr {
+				assertLabelNamesAndValuesMaxSizeError(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, testData.expectedValues, values)
+			}
+
+			valuesRes, err := client.LabelValues(reqCtx, valuesReq)
+			if testData.expectedErr {
+				assertLabelNamesAndValuesMaxSizeError(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, testData.expectedValues, valuesRes.Values)
+			}
+		})
+	}
+}
+
+func TestLabelNamesAndValuesStreamer(t *testing.T) {
+	t.Run("should send the values which have not been sent yet", func(t *testing.T) {
+		var sent [][]string
+		streamer := newLabelNamesAndValuesStreamer(0, func(values []string) error {
+			sent = append(sent, values)
+			return nil
+		})
+
+		require.NoError(t, streamer.send([]string{"a", "b", "c"}))
+		require.NoError(t, streamer.send([]string{"b", "c"}))
+		require.NoError(t, streamer.send([]string{"a", "d"}))
+		assert.Equal(t, [][]string{{"a", "b", "c"}, {"d"}}, sent)
+	})
+
+	t.Run("should fail once the max size of the sent values is exceeded", func(t *testing.T) {
+		var sent [][]string
+		streamer := newLabelNamesAndValuesStreamer(4, func(values []string) error {
+			sent = append(sent, values)
+			return nil
+		})
+
+		require.NoError(t, streamer.send([]string{"aa", "bb"}))
+		require.NoError(t, streamer.send([]string{"aa"}))
+		assertLabelNamesAndValuesMaxSizeError(t, streamer.send([]string{"aa", "c"}))
+		assertLabelNamesAndValuesMaxSizeError(t, streamer.err)
+		assert.Equal(t, [][]string{{"aa", "bb"}}, sent)
+	})
+
+	t.Run("should stop sending after the first send error", func(t *testing.T) {
+		calls := 0
+		streamer := newLabelNamesAndValuesStreamer(0, func([]string) error {
+			calls++
+			return errors.New("send failed")
+		})
+
+		require.EqualError(t, streamer.send([]string{"a"}), "send failed")
+		require.EqualError(t, streamer.send([]string{"b"}), "send failed")
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func receiveLabelNames(stream storegatewaypb.StoreGateway_LabelNamesStreamClient) ([]string, error) {
+	var names []string
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, res.Names...)
+	}
+}
+
+func receiveLabelValues(stream storegatewaypb.StoreGateway_LabelValuesStreamClient) ([]string, error) {
+	var values []string
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, res.Values...)
+	}
+}
+
+func assertLabelNamesAndValuesMaxSizeError(t *testing.T, err error) {
+	require.Error(t, err)
+
+	res, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusUnprocessableEntity), res.Code)
+	assert.True(t, strings.Contains(string(res.Body), "label-names-and-values-too-large"))
+}
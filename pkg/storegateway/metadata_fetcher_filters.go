@@ -110,3 +110,24 @@ func (f *minTimeMetaFilter) Filter(_ context.Context, metas map[ulid.ULID]*metad
 	}
 	return nil
 }
+
+// BlockPredicateMetaFilter filters out blocks which don't match the predicate.
+type BlockPredicateMetaFilter struct {
+	predicate BlockPredicate
+}
+
+func NewBlockPredicateMetaFilter(predicate BlockPredicate) *BlockPredicateMetaFilter {
+	return &BlockPredicateMetaFilter{predicate: predicate}
+}
+
+func (f *BlockPredicateMetaFilter) Filter(_ context.Context, metas map[ulid.ULID]*metadata.Meta, synced block.GaugeVec, _ block.GaugeVec) error {
+	for id, m := range metas {
+		if f.predicate(m) {
+			continue
+		}
+
+		synced.WithLabelValues(block.LabelExcludedMeta).Inc()
+		delete(metas, id)
+	}
+	return nil
+}
@@ -148,3 +148,26 @@ func TestTimeMetaFilter(t *testing.T) {
 	assert.Equal(t, expectedMetas, inputMetas)
 	assert.Equal(t, 2.0, promtest.ToFloat64(synced.WithLabelValues(minTimeExcludedMeta)))
 }
+
+func TestBlockPredicateMetaFilter_ExternalLabels(t *testing.T) {
+	ulid1 := ulid.MustNew(1, nil)
+	ulid2 := ulid.MustNew(2, nil)
+	ulid3 := ulid.MustNew(3, nil)
+
+	inputMetas := map[ulid.ULID]*metadata.Meta{
+		ulid1: {Thanos: metadata.Thanos{Labels: map[string]string{"region": "eu", "env": "prod"}}}, // All labels match, keep.
+		ulid2: {Thanos: metadata.Thanos{Labels: map[string]string{"region": "us", "env": "prod"}}}, // Label value doesn't match, remove.
+		ulid3: {},                                                                                  // No labels, remove.
+	}
+
+	expectedMetas := map[ulid.ULID]*metadata.Meta{}
+	expectedMetas[ulid1] = inputMetas[ulid1]
+
+	synced := extprom.NewTxGaugeVec(nil, prometheus.GaugeOpts{Name: "synced"}, []string{"state"})
+
+	f := NewBlockPredicateMetaFilter(externalLabelsPredicate(map[string]string{"region": "eu", "env": "prod"}))
+	require.NoError(t, f.Filter(context.Background(), inputMetas, synced, nil))
+
+	assert.Equal(t, expectedMetas, inputMetas)
+	assert.Equal(t, 2.0, promtest.ToFloat64(synced.WithLabelValues(block.LabelExcludedMeta)))
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+const (
+	ConsistencyDelayMetadataFilter    = "consistency-delay"
+	IgnoreBlocksWithinMetadataFilter  = "ignore-blocks-within"
+	IgnoreDeletionMarksMetadataFilter = "ignore-deletion-marks"
+	ExternalLabelsMetadataFilter      = "external-labels"
+)
+
+// metadataFilterFactory creates a built-in blocks metadata filter for a tenant.
+type metadataFilterFactory func(cfg tsdb.BucketStoreConfig, userID string, userBkt objstore.InstrumentedBucketReader, logger log.Logger, reg prometheus.Registerer) (block.MetadataFilter, error)
+
+// BlockPredicate returns whether a block, described by its metadata, must be loaded and queried.
+type BlockPredicate func(meta *metadata.Meta) bool
+
+// BlockPredicateFactory creates a BlockPredicate from the configuration.
+type BlockPredicateFactory func(cfg tsdb.BucketStoreConfig) (BlockPredicate, error)
+
+var (
+	// The built-in filters only exclude blocks which the querier doesn't expect the store-gateways to have loaded:
+	// recently uploaded blocks, blocks within -blocks-storage.bucket-store.ignore-blocks-within, which are queried
+	// from ingesters, and blocks marked for deletion.
+	builtinMetadataFilters = map[string]metadataFilterFactory{
+		ConsistencyDelayMetadataFilter: func(cfg tsdb.BucketStoreConfig, _ string, _ objstore.InstrumentedBucketReader, logger log.Logger, reg prometheus.Registerer) (block.MetadataFilter, error) {
+			return block.NewConsistencyDelayMetaFilter(logger, cfg.DeprecatedConsistencyDelay, reg), nil
+		},
+		IgnoreBlocksWithinMetadataFilter: func(cfg tsdb.BucketStoreConfig, _ string, _ objstore.InstrumentedBucketReader, _ log.Logger, _ prometheus.Registerer) (block.MetadataFilter, error) {
+			return newMinTimeMetaFilter(cfg.IgnoreBlocksWithin), nil
+		},
+		IgnoreDeletionMarksMetadataFilter: func(cfg tsdb.BucketStoreConfig, _ string, userBkt objstore.InstrumentedBucketReader, logger log.Logger, _ prometheus.Registerer) (block.MetadataFilter, error) {
+			// Use our own custom implementation.
+			return NewIgnoreDeletionMarkFilter(logger, userBkt, cfg.IgnoreDeletionMarksDelay, cfg.MetaSyncConcurrency), nil
+		},
+	}
+
+	// The filters excluding blocks by their metadata are block predicates, which are applied by the querier
+	// too, so that the querier doesn't expect the excluded blocks to be loaded by any store-gateway.
+	blockPredicatesMx sync.RWMutex
+	blockPredicates   = map[string]BlockPredicateFactory{
+		ExternalLabelsMetadataFilter: func(cfg tsdb.BucketStoreConfig) (BlockPredicate, error) {
+			labels, err := cfg.ParseExternalLabelsFilter()
+			if err != nil {
+				return nil, err
+			}
+			return externalLabelsPredicate(labels), nil
+		},
+	}
+)
+
+// RegisterMetadataFilter registers a custom blocks metadata filter, which can then be enabled by name
+// in the store-gateway configured metadata filters. The filter is a predicate, because the querier must
+// apply it too to find the blocks to query. It must be called before the store-gateway and the querier
+// are created.
+func RegisterMetadataFilter(name string, factory BlockPredicateFactory) {
+	blockPredicatesMx.Lock()
+	defer blockPredicatesMx.Unlock()
+
+	if _, ok := builtinMetadataFilters[name]; ok {
+		panic(fmt.Sprintf("metadata filter %q already registered", name))
+	}
+	if _, ok := blockPredicates[name]; ok {
+		panic(fmt.Sprintf("metadata filter %q already registered", name))
+	}
+	blockPredicates[name] = factory
+}
+
+// validateMetadataFilters returns an error if any of the input filters has not been registered.
+func validateMetadataFilters(names []string) error {
+	blockPredicatesMx.RLock()
+	defer blockPredicatesMx.RUnlock()
+
+	for _, name := range names {
+		if _, ok := builtinMetadataFilters[name]; ok {
+			continue
+		}
+		if _, ok := blockPredicates[name]; !ok {
+			return fmt.Errorf("unknown metadata filter %q", name)
+		}
+	}
+	return nil
+}
+
+// createMetadataFilters creates the configured blocks metadata filters for a tenant, in order.
+func createMetadataFilters(cfg tsdb.BucketStoreConfig, userID string, userBkt objstore.InstrumentedBucketReader, logger log.Logger, reg prometheus.Registerer) ([]block.MetadataFilter, error) {
+	blockPredicatesMx.RLock()
+	defer blockPredicatesMx.RUnlock()
+
+	filters := make([]block.MetadataFilter, 0, len(cfg.MetadataFilters))
+	for _, name := range cfg.MetadataFilters {
+		if factory, ok := builtinMetadataFilters[name]; ok {
+			filter, err := factory(cfg, userID, userBkt, logger, reg)
+			if err != nil {
+				return nil, fmt.Errorf("create metadata filter %q: %w", name, err)
+			}
+			filters = append(filters, filter)
+			continue
+		}
+
+		factory, ok := blockPredicates[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metadata filter %q", name)
+		}
+
+		predicate, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("create metadata filter %q: %w", name, err)
+		}
+		filters = append(filters, NewBlockPredicateMetaFilter(predicate))
+	}
+	return filters, nil
+}
+
+// CreateBlockPredicates creates the block predicates of the configured blocks metadata filters. The querier
+// applies them to the blocks to query, in order to only expect the blocks loaded by the store-gateways.
+func CreateBlockPredicates(cfg tsdb.BucketStoreConfig) ([]BlockPredicate, error) {
+	if err := validateMetadataFilters(cfg.MetadataFilters); err != nil {
+		return nil, err
+	}
+
+	blockPredicatesMx.RLock()
+	defer blockPredicatesMx.RUnlock()
+
+	var predicates []BlockPredicate
+	for _, name := range cfg.MetadataFilters {
+		factory, ok := blockPredicates[name]
+		if !ok {
+			continue
+		}
+
+		predicate, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("create metadata filter %q: %w", name, err)
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// MatchesBlockPredicates returns whether the block matches all the input predicates.
+func MatchesBlockPredicates(predicates []BlockPredicate, meta *metadata.Meta) bool {
+	for _, predicate := range predicates {
+		if !predicate(meta) {
+			return false
+		}
+	}
+	return true
+}
+
+// externalLabelsPredicate returns a predicate matching the blocks having all the input external labels.
+func externalLabelsPredicate(labels map[string]string) BlockPredicate {
+	return func(meta *metadata.Meta) bool {
+		for name, value := range labels {
+			if actual, ok := meta.Thanos.Labels[name]; !ok || actual != value {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+func TestCreateMetadataFilters(t *testing.T) {
+	bkt := objstore.WithNoopInstr(objstore.NewInMemBucket())
+
+	t.Run("should create the configured filters in order", func(t *testing.T) {
+		cfg := tsdb.BucketStoreConfig{
+			MetadataFilters:      flagext.StringSliceCSV{ExternalLabelsMetadataFilter, IgnoreBlocksWithinMetadataFilter, IgnoreDeletionMarksMetadataFilter},
+			ExternalLabelsFilter: flagext.StringSliceCSV{"region=eu"},
+			IgnoreBlocksWithin:   time.Hour,
+		}
+
+		filters, err := createMetadataFilters(cfg, "user-1", bkt, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		require.NoError(t, err)
+		require.Len(t, filters, 3)
+		assert.IsType(t, &BlockPredicateMetaFilter{}, filters[0])
+		assert.Equal(t, newMinTimeMetaFilter(time.Hour), filters[1])
+		assert.IsType(t, &IgnoreDeletionMarkFilter{}, filters[2])
+	})
+
+	t.Run("should fail on unknown filter", func(t *testing.T) {
+		cfg := tsdb.BucketStoreConfig{MetadataFilters: flagext.StringSliceCSV{"unknown"}}
+
+		_, err := createMetadataFilters(cfg, "user-1", bkt, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		require.Error(t, err)
+		require.Error(t, validateMetadataFilters(cfg.MetadataFilters))
+	})
+
+	t.Run("should create a registered custom filter", func(t *testing.T) {
+		registerTestBlockPredicate(t)
+
+		cfg := tsdb.BucketStoreConfig{MetadataFilters: flagext.StringSliceCSV{"test-custom"}}
+		require.NoError(t, validateMetadataFilters(cfg.MetadataFilters))
+
+		filters, err := createMetadataFilters(cfg, "user-1", bkt, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		require.NoError(t, err)
+		require.Len(t, filters, 1)
+		assert.IsType(t, &BlockPredicateMetaFilter{}, filters[0])
+	})
+}
+
+func TestCreateBlockPredicates(t *testing.T) {
+	registerTestBlockPredicate(t)
+
+	t.Run("should create the predicates of the configured filters only", func(t *testing.T) {
+		cfg := tsdb.BucketStoreConfig{
+			MetadataFilters:      flagext.StringSliceCSV{IgnoreDeletionMarksMetadataFilter, ExternalLabelsMetadataFilter, "test-custom"},
+			ExternalLabelsFilter: flagext.StringSliceCSV{"region=eu"},
+		}
+
+		predicates, err := CreateBlockPredicates(cfg)
+		require.NoError(t, err)
+		require.Len(t, predicates, 2)
+
+		for _, testData := range []struct {
+			labels   map[string]string
+			expected bool
+		}{
+			{labels: map[string]string{"region": "eu"}, expected: true},
+			{labels: map[string]string{"region": "eu", "custom": "excluded"}, expected: false},
+			{labels: map[string]string{"region": "us"}, expected: false},
+			{labels: nil, expected: false},
+		} {
+			meta := &metadata.Meta{Thanos: metadata.Thanos{Labels: testData.labels}}
+			assert.Equal(t, testData.expected, MatchesBlockPredicates(predicates, meta), testData.labels)
+		}
+	})
+
+	t.Run("should return no predicates with the default filters", func(t *testing.T) {
+		predicates, err := CreateBlockPredicates(tsdb.BucketStoreConfig{MetadataFilters: tsdb.DefaultMetadataFilters})
+		require.NoError(t, err)
+		assert.Empty(t, predicates)
+	})
+
+	t.Run("should fail on unknown filter", func(t *testing.T) {
+		_, err := CreateBlockPredicates(tsdb.BucketStoreConfig{MetadataFilters: flagext.StringSliceCSV{"unknown"}})
+		require.Error(t, err)
+	})
+}
+
+// registerTestBlockPredicate registers the "test-custom" filter, excluding the blocks with the custom=excluded label.
+func registerTestBlockPredicate(t *testing.T) {
+	RegisterMetadataFilter("test-custom", func(tsdb.BucketStoreConfig) (BlockPredicate, error) {
+		return func(meta *metadata.Meta) bool {
+			return meta.Thanos.Labels["custom"] != "excluded"
+		}, nil
+	})
+	t.Cleanup(func() {
+		blockPredicatesMx.Lock()
+		delete(blockPredicates, "test-custom")
+		blockPredicatesMx.Unlock()
+	})
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// queryCostLimits holds the functions returning the tenant's limits used to admit a Series() call
+// based on its estimated cost. A value of 0 disables the limit.
+type queryCostLimits struct {
+	maxBlocks                func() int
+	maxEstimatedPostingsSize func() int
+}
+
+// admitQuery estimates the cost of a Series() call touching the input blocks and returns
+// a gRPC status error if the estimate exceeds the tenant's limits. The estimate is computed
+// only from data already available locally (the index-header), so that a rejected request
+// doesn't fetch anything from the object storage.
+func (s *BucketStore) admitQuery(blocks []*bucketBlock, matchers []*labels.Matcher) error {
+	if maxBlocks := s.queryCostLimits.maxBlocks; maxBlocks != nil {
+		if limit := maxBlocks(); limit > 0 && len(blocks) > limit {
+			s.metrics.queriesDropped.WithLabelValues("blocks").Inc()
+			return httpgrpc.Errorf(http.StatusUnprocessableEntity, validation.NewStoreGatewayMaxBlocksPerQueryError(len(blocks), limit).Error())
+		}
+	}
+
+	if maxSize := s.queryCostLimits.maxEstimatedPostingsSize; maxSize != nil {
+		limit := maxSize()
+		if limit <= 0 {
+			return nil
+		}
+
+		size, err := estimatePostingsSize(blocks, matchers)
+		if err != nil {
+			return errors.Wrap(err, "estimate postings size")
+		}
+
+		if size > limit {
+			s.metrics.queriesDropped.WithLabelValues("estimated-postings-size").Inc()
+			return httpgrpc.Errorf(http.StatusUnprocessableEntity, validation.NewStoreGatewayMaxEstimatedPostingsBytesError(size, limit).Error())
+		}
+	}
+
+	return nil
+}
+
+// estimatePostingsSize returns the total size, in bytes, of the postings lists which would be fetched
+// to expand the postings of the input matchers in all blocks. The size of each postings list is read
+// from the index-header, so the estimate doesn't take into account the postings found in the index cache.
+func estimatePostingsSize(blocks []*bucketBlock, matchers []*labels.Matcher) (int, error) {
+	size := 0
+	for _, b := range blocks {
+		_, keys, err := toPostingGroups(matchers, b.indexHeaderReader)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, key := range keys {
+			r, err := b.indexHeaderReader.PostingsOffset(key.Name, key.Value)
+			if errors.Is(err, indexheader.NotFoundRangeErr) {
+				continue
+			}
+			if err != nil {
+				return 0, errors.Wrap(err, "index header PostingsOffset")
+			}
+
+			size += int(r.End - r.Start)
+		}
+	}
+	return size, nil
+}
@@ -187,9 +187,10 @@ func newSeriesSetWithChunks(
 	refsIteratorBatchSize int,
 	stats *safeQueryStats,
 	minT, maxT int64,
+	strictTimeRangePruning bool,
 ) storepb.SeriesSet {
 	var iterator seriesChunksSetIterator
-	iterator = newLoadingSeriesChunksSetIterator(ctx, logger, userID, cache, chunkReaders, refsIterator, refsIteratorBatchSize, stats, minT, maxT)
+	iterator = newLoadingSeriesChunksSetIterator(ctx, logger, userID, cache, chunkReaders, refsIterator, refsIteratorBatchSize, stats, minT, maxT, strictTimeRangePruning)
 	iterator = newPreloadingAndStatsTrackingSetIterator[seriesChunksSet](ctx, 1, iterator, stats)
 	return newSeriesChunksSeriesSet(iterator)
 }
@@ -336,6 +337,10 @@ type loadingSeriesChunksSetIterator struct {
 	current          seriesChunksSet
 	err              error
 	minTime, maxTime int64
+
+	// strictTimeRangePruning is true to never fetch chunks outside minTime/maxTime, even when
+	// that means not storing the whole chunks ranges in the cache.
+	strictTimeRangePruning bool
 }
 
 func newLoadingSeriesChunksSetIterator(
@@ -349,18 +354,20 @@ func newLoadingSeriesChunksSetIterator(
 	stats *safeQueryStats,
 	minT int64,
 	maxT int64,
+	strictTimeRangePruning bool,
 ) *loadingSeriesChunksSetIterator {
 	return &loadingSeriesChunksSetIterator{
-		ctx:           ctx,
-		logger:        logger,
-		userID:        userID,
-		cache:         cache,
-		chunkReaders:  chunkReaders,
-		from:          from,
-		fromBatchSize: fromBatchSize,
-		stats:         stats,
-		minTime:       minT,
-		maxTime:       maxT,
+		ctx:                    ctx,
+		logger:                 logger,
+		userID:                 userID,
+		cache:                  cache,
+		chunkReaders:           chunkReaders,
+		from:                   from,
+		fromBatchSize:          fromBatchSize,
+		stats:                  stats,
+		minTime:                minT,
+		maxTime:                maxT,
+		strictTimeRangePruning: strictTimeRangePruning,
 	}
 }
 
@@ -423,9 +430,10 @@ func (c *loadingSeriesChunksSetIterator) Next() (retHasNext bool) {
 			}
 
 			for _, chunk := range chunksRange.refs {
-				if c.cache == nil && (chunk.minTime > c.maxTime || chunk.maxTime < c.minTime) {
+				if (c.cache == nil || c.strictTimeRangePruning) && (chunk.minTime > c.maxTime || chunk.maxTime < c.minTime) {
 					// If the cache is not set, then we don't need to overfetch chunks that we know are outside minT/maxT.
 					// If the cache is set, then we need to do that, so we can cache the complete chunks ranges; they will be filtered out after fetching.
+					// With strict time range pruning we never overfetch, and the incomplete chunks ranges are not cached.
 					seriesChunkIdx++
 					continue
 				}
@@ -452,10 +460,16 @@ func (c *loadingSeriesChunksSetIterator) Next() (retHasNext bool) {
 	// We might have over-fetched some chunks that were outside minT/maxT because we fetch a whole
 	// range of chunks. After storing the chunks in the cache, we should throw away the chunks that are outside
 	// the requested time range.
+	prunedChunks := 0
 	for sIdx := range nextSet.series {
+		numChunks := len(nextSet.series[sIdx].chks)
 		nextSet.series[sIdx].chks = removeChunksOutsideRange(nextSet.series[sIdx].chks, c.minTime, c.maxTime)
+		prunedChunks += numChunks - len(nextSet.series[sIdx].chks)
 	}
 	c.recordReturnedChunks(nextSet.series)
+	c.stats.update(func(stats *queryStats) {
+		stats.chunksPruned += prunedChunks
+	})
 
 	nextSet.chunksReleaser = chunksPool
 	c.current = nextSet
@@ -603,6 +617,11 @@ func (c *loadingSeriesChunksSetIterator) storeRangesInCache(seriesRefs []seriesC
 				seriesChunkIdx += len(chunksRange.refs)
 				continue
 			}
+			if c.strictTimeRangePruning && !chunksRangeWithinTime(chunksRange, c.minTime, c.maxTime) {
+				// The chunks outside minT/maxT haven't been fetched, so the range is incomplete.
+				seriesChunkIdx += len(chunksRange.refs)
+				continue
+			}
 			rangeChunks := seriesChunks[sIdx].chks[seriesChunkIdx : seriesChunkIdx+len(chunksRange.refs)]
 			toStore[cacheKey] = encodeChunksForCache(rangeChunks)
 
@@ -612,6 +631,16 @@ func (c *loadingSeriesChunksSetIterator) storeRangesInCache(seriesRefs []seriesC
 	c.cache.StoreChunks(c.userID, toStore)
 }
 
+// chunksRangeWithinTime returns true if all the chunks of the range overlap minT/maxT.
+func chunksRangeWithinTime(chunksRange seriesChunkRefsRange, minT, maxT int64) bool {
+	for _, chunk := range chunksRange.refs {
+		if chunk.minTime > maxT || chunk.maxTime < minT {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *loadingSeriesChunksSetIterator) recordReturnedChunks(series []seriesEntry) {
 	returnedChunks, returnedChunksBytes := chunkStats(series)
 
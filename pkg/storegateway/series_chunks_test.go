@@ -943,7 +943,7 @@ func TestLoadingSeriesChunksSetIterator(t *testing.T) {
 					}
 
 					// Run test
-					set := newLoadingSeriesChunksSetIterator(context.Background(), log.NewNopLogger(), "tenant", chunksCache, *readers, newSliceSeriesChunkRefsSetIterator(nil, testCase.setsToLoad...), 100, newSafeQueryStats(), minT, maxT)
+					set := newLoadingSeriesChunksSetIterator(context.Background(), log.NewNopLogger(), "tenant", chunksCache, *readers, newSliceSeriesChunkRefsSetIterator(nil, testCase.setsToLoad...), 100, newSafeQueryStats(), minT, maxT, false)
 					loadedSets := readAllSeriesChunksSets(set)
 
 					// Assertions
@@ -980,6 +980,60 @@ func TestLoadingSeriesChunksSetIterator(t *testing.T) {
 	}
 }
 
+func TestLoadingSeriesChunksSetIterator_StrictTimeRangePruning(t *testing.T) {
+	block := testBlock{
+		ulid:   ulid.MustNew(1, nil),
+		series: generateSeriesEntries(t, 1),
+	}
+
+	// The chunks of the series cover time 0 to 500.
+	const minT, maxT = 150, 250
+	const numRanges = 5
+	refs := block.toSeriesChunkRefsWithNRanges(0, numRanges)
+	expected := block.toSeriesChunksOverlapping(0, minT, maxT)
+	numChunks := len(block.series[0].chks)
+	require.Greater(t, numChunks, len(expected.chks))
+
+	numRangesWithinTime := 0
+	for _, r := range refs.chunksRanges {
+		if chunksRangeWithinTime(r, minT, maxT) {
+			numRangesWithinTime++
+		}
+	}
+	require.Less(t, numRangesWithinTime, numRanges)
+
+	for _, strictPruning := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict pruning enabled: %t", strictPruning), func(t *testing.T) {
+			reader := newChunkReaderMockWithSeries(block.series, nil, nil)
+			readers := newChunkReaders(map[ulid.ULID]chunkReader{block.ulid: reader})
+			chunksCache := newInMemoryChunksCache()
+			stats := newSafeQueryStats()
+
+			set := newLoadingSeriesChunksSetIterator(context.Background(), log.NewNopLogger(), "tenant", chunksCache, *readers, newSliceSeriesChunkRefsSetIterator(nil, seriesChunkRefsSet{series: []seriesChunkRefs{refs}}), 100, stats, minT, maxT, strictPruning)
+			loadedSets := readAllSeriesChunksSets(set)
+			require.NoError(t, set.Err())
+			require.Len(t, loadedSets, 1)
+			require.Len(t, loadedSets[0].series, 1)
+			assert.ElementsMatch(t, expected.chks, loadedSets[0].series[0].chks)
+			assert.Equal(t, numChunks-len(expected.chks), stats.export().chunksPruned)
+
+			// The chunks loaded by the last batch are still tracked by the reader.
+			cached := chunksCache.(*inMemoryChunksCache).cached["tenant"]
+			if strictPruning {
+				assert.Len(t, reader.toLoad, len(expected.chks))
+				assert.Len(t, cached, numRangesWithinTime)
+			} else {
+				assert.Len(t, reader.toLoad, numChunks)
+				assert.Len(t, cached, numRanges)
+			}
+
+			for _, s := range loadedSets {
+				s.release()
+			}
+		})
+	}
+}
+
 func BenchmarkLoadingSeriesChunksSetIterator(b *testing.B) {
 	for batchSize := 10; batchSize <= 1000; batchSize *= 10 {
 		b.Run(fmt.Sprintf("batch size: %d", batchSize), func(b *testing.B) {
@@ -1024,7 +1078,7 @@ func BenchmarkLoadingSeriesChunksSetIterator(b *testing.B) {
 
 			for n := 0; n < b.N; n++ {
 				batchSize := numSeriesPerSet
-				it := newLoadingSeriesChunksSetIterator(context.Background(), log.NewNopLogger(), "tenant", newInMemoryChunksCache(), *chunkReaders, newSliceSeriesChunkRefsSetIterator(nil, sets...), batchSize, stats, 0, 10000)
+				it := newLoadingSeriesChunksSetIterator(context.Background(), log.NewNopLogger(), "tenant", newInMemoryChunksCache(), *chunkReaders, newSliceSeriesChunkRefsSetIterator(nil, sets...), batchSize, stats, 0, 10000, false)
 
 				actualSeries := 0
 				actualChunks := 0
@@ -51,6 +51,7 @@ type queryStats struct {
 	chunksProcessedSizeSum int
 	chunksReturned         int
 	chunksReturnedSizeSum  int
+	chunksPruned           int
 
 	mergedSeriesCount int
 	mergedChunksCount int
@@ -116,6 +117,7 @@ func (s queryStats) merge(o *queryStats) *queryStats {
 	s.chunksProcessedSizeSum += o.chunksProcessedSizeSum
 	s.chunksReturned += o.chunksReturned
 	s.chunksReturnedSizeSum += o.chunksReturnedSizeSum
+	s.chunksPruned += o.chunksPruned
 
 	s.mergedSeriesCount += o.mergedSeriesCount
 	s.mergedChunksCount += o.mergedChunksCount
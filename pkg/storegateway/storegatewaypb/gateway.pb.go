@@ -6,12 +6,18 @@ package storegatewaypb
 import (
 	context "context"
 	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
 	proto "github.com/gogo/protobuf/proto"
+	mimirpb "github.com/grafana/mimir/pkg/mimirpb"
 	storepb "github.com/grafana/mimir/pkg/storegateway/storepb"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	io "io"
 	math "math"
+	math_bits "math/bits"
+	reflect "reflect"
+	strings "strings"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -25,27 +31,383 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
+type ExemplarsRequest struct {
+	MinTime int64 `protobuf:"varint,1,opt,name=min_time,json=minTime,proto3" json:"min_time,omitempty"`
+	MaxTime int64 `protobuf:"varint,2,opt,name=max_time,json=maxTime,proto3" json:"max_time,omitempty"`
+	// Sets of matchers selecting the series whose exemplars are returned. A series is selected if it
+	// matches all the matchers of at least one set.
+	Matchers []ExemplarsMatchers `protobuf:"bytes,3,rep,name=matchers,proto3" json:"matchers"`
+	// IDs of the blocks to query.
+	BlockIds []string `protobuf:"bytes,4,rep,name=block_ids,json=blockIds,proto3" json:"block_ids,omitempty"`
+}
+
+func (m *ExemplarsRequest) Reset()      { *m = ExemplarsRequest{} }
+func (*ExemplarsRequest) ProtoMessage() {}
+func (*ExemplarsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1a937782ebbded5, []int{0}
+}
+func (m *ExemplarsRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ExemplarsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ExemplarsRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ExemplarsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExemplarsRequest.Merge(m, src)
+}
+func (m *ExemplarsRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *ExemplarsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExemplarsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExemplarsRequest proto.InternalMessageInfo
+
+func (m *ExemplarsRequest) GetMinTime() int64 {
+	if m != nil {
+		return m.MinTime
+	}
+	return 0
+}
+
+func (m *ExemplarsRequest) GetMaxTime() int64 {
+	if m != nil {
+		return m.MaxTime
+	}
+	return 0
+}
+
+func (m *ExemplarsRequest) GetMatchers() []ExemplarsMatchers {
+	if m != nil {
+		return m.Matchers
+	}
+	return nil
+}
+
+func (m *ExemplarsRequest) GetBlockIds() []string {
+	if m != nil {
+		return m.BlockIds
+	}
+	return nil
+}
+
+type ExemplarsMatchers struct {
+	Matchers []storepb.LabelMatcher `protobuf:"bytes,1,rep,name=matchers,proto3" json:"matchers"`
+}
+
+func (m *ExemplarsMatchers) Reset()      { *m = ExemplarsMatchers{} }
+func (*ExemplarsMatchers) ProtoMessage() {}
+func (*ExemplarsMatchers) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1a937782ebbded5, []int{1}
+}
+func (m *ExemplarsMatchers) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ExemplarsMatchers) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ExemplarsMatchers.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ExemplarsMatchers) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExemplarsMatchers.Merge(m, src)
+}
+func (m *ExemplarsMatchers) XXX_Size() int {
+	return m.Size()
+}
+func (m *ExemplarsMatchers) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExemplarsMatchers.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExemplarsMatchers proto.InternalMessageInfo
+
+func (m *ExemplarsMatchers) GetMatchers() []storepb.LabelMatcher {
+	if m != nil {
+		return m.Matchers
+	}
+	return nil
+}
+
+type ExemplarsResponse struct {
+	Timeseries []mimirpb.TimeSeries `protobuf:"bytes,1,rep,name=timeseries,proto3" json:"timeseries"`
+	// IDs of the blocks queried.
+	QueriedBlocks []string `protobuf:"bytes,2,rep,name=queried_blocks,json=queriedBlocks,proto3" json:"queried_blocks,omitempty"`
+}
+
+func (m *ExemplarsResponse) Reset()      { *m = ExemplarsResponse{} }
+func (*ExemplarsResponse) ProtoMessage() {}
+func (*ExemplarsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f1a937782ebbded5, []int{2}
+}
+func (m *ExemplarsResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ExemplarsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ExemplarsResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ExemplarsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExemplarsResponse.Merge(m, src)
+}
+func (m *ExemplarsResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *ExemplarsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExemplarsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExemplarsResponse proto.InternalMessageInfo
+
+func (m *ExemplarsResponse) GetTimeseries() []mimirpb.TimeSeries {
+	if m != nil {
+		return m.Timeseries
+	}
+	return nil
+}
+
+func (m *ExemplarsResponse) GetQueriedBlocks() []string {
+	if m != nil {
+		return m.QueriedBlocks
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*ExemplarsRequest)(nil), "gatewaypb.ExemplarsRequest")
+	proto.RegisterType((*ExemplarsMatchers)(nil), "gatewaypb.ExemplarsMatchers")
+	proto.RegisterType((*ExemplarsResponse)(nil), "gatewaypb.ExemplarsResponse")
+}
+
 func init() { proto.RegisterFile("gateway.proto", fileDescriptor_f1a937782ebbded5) }
 
 var fileDescriptor_f1a937782ebbded5 = []byte{
-	// 263 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x90, 0xbd, 0x4a, 0x04, 0x31,
-	0x14, 0x46, 0x93, 0x66, 0xc1, 0xf8, 0x53, 0x04, 0x14, 0x5c, 0xe1, 0x3e, 0xc2, 0x44, 0xb4, 0x12,
-	0x3b, 0x15, 0x6d, 0xc4, 0xc2, 0x05, 0x0b, 0xbb, 0x9b, 0xe5, 0x3a, 0x3b, 0xb8, 0x33, 0x89, 0x49,
-	0x06, 0xb1, 0xf3, 0x11, 0x7c, 0x0c, 0x1f, 0xc5, 0x72, 0x2a, 0xd9, 0xd2, 0xc9, 0x34, 0x96, 0xfb,
-	0x08, 0xe2, 0x66, 0x07, 0x7f, 0xd8, 0xf2, 0x3b, 0xf7, 0x70, 0x8a, 0x2b, 0x36, 0x73, 0x0c, 0xf4,
-	0x88, 0x4f, 0x99, 0x75, 0x26, 0x18, 0xb9, 0xb6, 0x9c, 0x56, 0x0f, 0x8f, 0xf3, 0x22, 0x4c, 0x6a,
-	0x9d, 0x8d, 0x4d, 0xa9, 0x72, 0x87, 0x77, 0x58, 0xa1, 0x2a, 0x8b, 0xb2, 0x70, 0xca, 0xde, 0xe7,
-	0xca, 0x07, 0xe3, 0x68, 0x29, 0xa7, 0x61, 0xb5, 0x72, 0x76, 0x9c, 0x3a, 0x07, 0xef, 0x5c, 0x6c,
-	0x8c, 0xbe, 0xe9, 0x45, 0x52, 0xe4, 0x91, 0x18, 0x8c, 0xc8, 0x15, 0xe4, 0xe5, 0x76, 0x16, 0x26,
-	0x58, 0x19, 0x9f, 0xa5, 0x7d, 0x4d, 0x0f, 0x35, 0xf9, 0x30, 0xdc, 0xf9, 0x8f, 0xbd, 0x35, 0x95,
-	0xa7, 0x7d, 0x2e, 0x4f, 0x85, 0xb8, 0x44, 0x4d, 0xd3, 0x2b, 0x2c, 0xc9, 0xcb, 0xdd, 0xde, 0xfb,
-	0x61, 0x7d, 0x62, 0xb8, 0xea, 0x94, 0x32, 0xf2, 0x5c, 0xac, 0x2f, 0xe8, 0x0d, 0x4e, 0x6b, 0xf2,
-	0xf2, 0xaf, 0x9a, 0x60, 0x9f, 0xd9, 0x5b, 0x79, 0x4b, 0x9d, 0x93, 0xb3, 0xa6, 0x05, 0x36, 0x6b,
-	0x81, 0xcd, 0x5b, 0xe0, 0xcf, 0x11, 0xf8, 0x6b, 0x04, 0xfe, 0x16, 0x81, 0x37, 0x11, 0xf8, 0x47,
-	0x04, 0xfe, 0x19, 0x81, 0xcd, 0x23, 0xf0, 0x97, 0x0e, 0x58, 0xd3, 0x01, 0x9b, 0x75, 0xc0, 0x6e,
-	0xb7, 0x7e, 0xbf, 0xcb, 0x6a, 0x3d, 0x58, 0x7c, 0xe9, 0xf0, 0x2b, 0x00, 0x00, 0xff, 0xff, 0xb9,
-	0xbe, 0x15, 0x37, 0x7e, 0x01, 0x00, 0x00,
+	// 502 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x52, 0xcf, 0x6e, 0xd3, 0x30,
+	0x18, 0x8f, 0xdb, 0x69, 0xac, 0x1e, 0x9b, 0xc0, 0x02, 0xd4, 0xb5, 0x93, 0xa9, 0x2a, 0x21, 0xf5,
+	0x42, 0x32, 0x0d, 0x09, 0x09, 0x10, 0x1c, 0x2a, 0xc6, 0x1f, 0xf1, 0xe7, 0x90, 0x21, 0x0e, 0x5c,
+	0x2a, 0x3b, 0xf5, 0xd2, 0x68, 0x4d, 0xec, 0xc5, 0x0e, 0x74, 0x37, 0x1e, 0x81, 0x67, 0x40, 0x42,
+	0xe2, 0x51, 0x76, 0xec, 0x71, 0x27, 0x44, 0xd3, 0x0b, 0xc7, 0x3d, 0x02, 0x8a, 0xed, 0x86, 0xac,
+	0x54, 0x48, 0x9c, 0xe2, 0xef, 0xf7, 0xef, 0xfb, 0x3e, 0x3b, 0x70, 0x2b, 0x24, 0x8a, 0x7d, 0x22,
+	0xa7, 0xae, 0x48, 0xb9, 0xe2, 0xa8, 0x61, 0x4b, 0x41, 0x5b, 0x77, 0xc3, 0x48, 0x8d, 0x32, 0xea,
+	0x06, 0x3c, 0xf6, 0x42, 0x1e, 0x72, 0x4f, 0x2b, 0x68, 0x76, 0xa4, 0x2b, 0x5d, 0xe8, 0x93, 0x71,
+	0xb6, 0xf6, 0xaa, 0xf2, 0x94, 0x1c, 0x91, 0x84, 0x78, 0x71, 0x14, 0x47, 0xa9, 0x27, 0x8e, 0x43,
+	0x73, 0x12, 0xd4, 0x7c, 0xad, 0xe3, 0xd1, 0x3f, 0x1d, 0x52, 0xf1, 0x94, 0xd9, 0x69, 0x4c, 0x21,
+	0xa8, 0x97, 0x8a, 0xc0, 0x9a, 0x1f, 0xff, 0xbf, 0x59, 0x9d, 0x0a, 0x26, 0x8d, 0xbd, 0xfb, 0x0d,
+	0xc0, 0x6b, 0x07, 0x13, 0x16, 0x8b, 0x31, 0x49, 0xa5, 0xcf, 0x4e, 0x32, 0x26, 0x15, 0xda, 0x81,
+	0x1b, 0x71, 0x94, 0x0c, 0x54, 0x14, 0xb3, 0x26, 0xe8, 0x80, 0x5e, 0xdd, 0xbf, 0x12, 0x47, 0xc9,
+	0xbb, 0x28, 0x66, 0x9a, 0x22, 0x13, 0x43, 0xd5, 0x2c, 0x45, 0x26, 0x9a, 0x7a, 0x52, 0x50, 0x2a,
+	0x18, 0xb1, 0x54, 0x36, 0xeb, 0x9d, 0x7a, 0x6f, 0x73, 0x7f, 0xd7, 0x2d, 0x6f, 0xd1, 0x2d, 0x9b,
+	0xbc, 0xb1, 0x9a, 0xfe, 0xda, 0xd9, 0x8f, 0xdb, 0x8e, 0x5f, 0x7a, 0x50, 0x1b, 0x36, 0xe8, 0x98,
+	0x07, 0xc7, 0x83, 0x68, 0x28, 0x9b, 0x6b, 0x9d, 0x7a, 0xaf, 0xe1, 0x6f, 0x68, 0xe0, 0xe5, 0x50,
+	0x76, 0x5f, 0xc1, 0xeb, 0x7f, 0x25, 0xa0, 0xfb, 0x95, 0x8e, 0x40, 0x77, 0xbc, 0xe1, 0xaa, 0x11,
+	0x49, 0xb8, 0x74, 0x5f, 0x13, 0xca, 0xc6, 0x56, 0xb8, 0xdc, 0xa9, 0xfb, 0xb1, 0x12, 0xe6, 0x33,
+	0x29, 0x78, 0x22, 0x19, 0x7a, 0x08, 0x61, 0xb1, 0x95, 0x64, 0x69, 0xc4, 0xfe, 0xc4, 0x05, 0x3c,
+	0x55, 0x6c, 0x22, 0xa8, 0x5b, 0xac, 0x78, 0xa8, 0x39, 0x1b, 0x57, 0x51, 0xa3, 0x3b, 0x70, 0xfb,
+	0x24, 0x2b, 0x8e, 0xc3, 0x81, 0x9e, 0x58, 0x36, 0x6b, 0x7a, 0xfe, 0x2d, 0x8b, 0xf6, 0x35, 0xb8,
+	0xff, 0xb5, 0x06, 0xaf, 0x1e, 0x16, 0x8f, 0xf0, 0xdc, 0x5c, 0x0b, 0x7a, 0x00, 0xd7, 0x4d, 0x26,
+	0xba, 0xb9, 0x18, 0xdc, 0xd4, 0xf6, 0x25, 0x5a, 0xb7, 0x96, 0x61, 0x33, 0xec, 0x1e, 0x40, 0x07,
+	0x10, 0xea, 0x1d, 0xdf, 0x92, 0x98, 0x49, 0xb4, 0x73, 0x69, 0x6f, 0x8d, 0x2d, 0x22, 0x5a, 0xab,
+	0xa8, 0x32, 0xe6, 0x05, 0xdc, 0xd4, 0xf8, 0x7b, 0x32, 0xce, 0x98, 0x44, 0x97, 0xc5, 0x06, 0x5c,
+	0x04, 0xb5, 0x57, 0x72, 0x65, 0xd2, 0x33, 0xd8, 0x28, 0x2f, 0x15, 0xb5, 0x57, 0xbd, 0xfc, 0x22,
+	0x68, 0x77, 0x35, 0x69, 0x92, 0xfa, 0x4f, 0xa7, 0x33, 0xec, 0x9c, 0xcf, 0xb0, 0x73, 0x31, 0xc3,
+	0xe0, 0x73, 0x8e, 0xc1, 0xf7, 0x1c, 0x83, 0xb3, 0x1c, 0x83, 0x69, 0x8e, 0xc1, 0xcf, 0x1c, 0x83,
+	0x5f, 0x39, 0x76, 0x2e, 0x72, 0x0c, 0xbe, 0xcc, 0xb1, 0x33, 0x9d, 0x63, 0xe7, 0x7c, 0x8e, 0x9d,
+	0x0f, 0xdb, 0xd5, 0x3f, 0x5d, 0x50, 0xba, 0xae, 0x7f, 0xef, 0x7b, 0xbf, 0x07, 0x00, 0x12, 0x05,
+	0x01, 0xd8, 0xd7, 0x03, 0x00, 0x00,
+}
+
+func (this *ExemplarsRequest) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*ExemplarsRequest)
+	if !ok {
+		that2, ok := that.(ExemplarsRequest)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if this.MinTime != that1.MinTime {
+		return false
+	}
+	if this.MaxTime != that1.MaxTime {
+		return false
+	}
+	if len(this.Matchers) != len(that1.Matchers) {
+		return false
+	}
+	for i := range this.Matchers {
+		if !this.Matchers[i].Equal(&that1.Matchers[i]) {
+			return false
+		}
+	}
+	if len(this.BlockIds) != len(that1.BlockIds) {
+		return false
+	}
+	for i := range this.BlockIds {
+		if this.BlockIds[i] != that1.BlockIds[i] {
+			return false
+		}
+	}
+	return true
+}
+func (this *ExemplarsMatchers) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*ExemplarsMatchers)
+	if !ok {
+		that2, ok := that.(ExemplarsMatchers)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Matchers) != len(that1.Matchers) {
+		return false
+	}
+	for i := range this.Matchers {
+		if !this.Matchers[i].Equal(&that1.Matchers[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (this *ExemplarsResponse) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*ExemplarsResponse)
+	if !ok {
+		that2, ok := that.(ExemplarsResponse)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Timeseries) != len(that1.Timeseries) {
+		return false
+	}
+	for i := range this.Timeseries {
+		if !this.Timeseries[i].Equal(&that1.Timeseries[i]) {
+			return false
+		}
+	}
+	if len(this.QueriedBlocks) != len(that1.QueriedBlocks) {
+		return false
+	}
+	for i := range this.QueriedBlocks {
+		if this.QueriedBlocks[i] != that1.QueriedBlocks[i] {
+			return false
+		}
+	}
+	return true
+}
+func (this *ExemplarsRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 8)
+	s = append(s, "&storegatewaypb.ExemplarsRequest{")
+	s = append(s, "MinTime: "+fmt.Sprintf("%#v", this.MinTime)+",\n")
+	s = append(s, "MaxTime: "+fmt.Sprintf("%#v", this.MaxTime)+",\n")
+	if this.Matchers != nil {
+		vs := make([]ExemplarsMatchers, len(this.Matchers))
+		for i := range vs {
+			vs[i] = this.Matchers[i]
+		}
+		s = append(s, "Matchers: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	s = append(s, "BlockIds: "+fmt.Sprintf("%#v", this.BlockIds)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ExemplarsMatchers) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&storegatewaypb.ExemplarsMatchers{")
+	if this.Matchers != nil {
+		vs := make([]storepb.LabelMatcher, len(this.Matchers))
+		for i := range vs {
+			vs[i] = this.Matchers[i]
+		}
+		s = append(s, "Matchers: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ExemplarsResponse) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&storegatewaypb.ExemplarsResponse{")
+	if this.Timeseries != nil {
+		vs := make([]mimirpb.TimeSeries, len(this.Timeseries))
+		for i := range vs {
+			vs[i] = this.Timeseries[i]
+		}
+		s = append(s, "Timeseries: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	s = append(s, "QueriedBlocks: "+fmt.Sprintf("%#v", this.QueriedBlocks)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func valueToGoStringGateway(v interface{}, typ string) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("func(v %v) *%v { return &v } ( %#v )", typ, typ, pv)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -70,8 +432,22 @@ type StoreGatewayClient interface {
 	Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (StoreGateway_SeriesClient, error)
 	// LabelNames returns all label names that is available.
 	LabelNames(ctx context.Context, in *storepb.LabelNamesRequest, opts ...grpc.CallOption) (*storepb.LabelNamesResponse, error)
+	// LabelNamesStream streams all label names that are available.
+	//
+	// Label names are streamed as soon as they're read from each block, so they can be split across multiple
+	// frames. The label names of each frame are sorted and not repeated in other frames. Hints are sent in the
+	// last frame.
+	LabelNamesStream(ctx context.Context, in *storepb.LabelNamesRequest, opts ...grpc.CallOption) (StoreGateway_LabelNamesStreamClient, error)
 	// LabelValues returns all label values for given label name.
 	LabelValues(ctx context.Context, in *storepb.LabelValuesRequest, opts ...grpc.CallOption) (*storepb.LabelValuesResponse, error)
+	// LabelValuesStream streams all label values for given label name.
+	//
+	// Label values are streamed as soon as they're read from each block, so they can be split across multiple
+	// frames. The label values of each frame are sorted and not repeated in other frames. Hints are sent in the
+	// last frame.
+	LabelValuesStream(ctx context.Context, in *storepb.LabelValuesRequest, opts ...grpc.CallOption) (StoreGateway_LabelValuesStreamClient, error)
+	// Exemplars returns the exemplars stored in the blocks for given label matchers and time range.
+	Exemplars(ctx context.Context, in *ExemplarsRequest, opts ...grpc.CallOption) (*ExemplarsResponse, error)
 }
 
 type storeGatewayClient struct {
@@ -132,6 +508,79 @@ func (c *storeGatewayClient) LabelValues(ctx context.Context, in *storepb.LabelV
 	return out, nil
 }
 
+func (c *storeGatewayClient) LabelNamesStream(ctx context.Context, in *storepb.LabelNamesRequest, opts ...grpc.CallOption) (StoreGateway_LabelNamesStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_StoreGateway_serviceDesc.Streams[1], "/gatewaypb.StoreGateway/LabelNamesStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeGatewayLabelNamesStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StoreGateway_LabelNamesStreamClient interface {
+	Recv() (*storepb.LabelNamesResponse, error)
+	grpc.ClientStream
+}
+
+type storeGatewayLabelNamesStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeGatewayLabelNamesStreamClient) Recv() (*storepb.LabelNamesResponse, error) {
+	m := new(storepb.LabelNamesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeGatewayClient) LabelValuesStream(ctx context.Context, in *storepb.LabelValuesRequest, opts ...grpc.CallOption) (StoreGateway_LabelValuesStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_StoreGateway_serviceDesc.Streams[2], "/gatewaypb.StoreGateway/LabelValuesStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeGatewayLabelValuesStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StoreGateway_LabelValuesStreamClient interface {
+	Recv() (*storepb.LabelValuesResponse, error)
+	grpc.ClientStream
+}
+
+type storeGatewayLabelValuesStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeGatewayLabelValuesStreamClient) Recv() (*storepb.LabelValuesResponse, error) {
+	m := new(storepb.LabelValuesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeGatewayClient) Exemplars(ctx context.Context, in *ExemplarsRequest, opts ...grpc.CallOption) (*ExemplarsResponse, error) {
+	out := new(ExemplarsResponse)
+	err := c.cc.Invoke(ctx, "/gatewaypb.StoreGateway/Exemplars", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StoreGatewayServer is the server API for StoreGateway service.
 type StoreGatewayServer interface {
 	// Series streams each Series for given label matchers and time range.
@@ -144,8 +593,22 @@ type StoreGatewayServer interface {
 	Series(*storepb.SeriesRequest, StoreGateway_SeriesServer) error
 	// LabelNames returns all label names that is available.
 	LabelNames(context.Context, *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error)
+	// LabelNamesStream streams all label names that are available.
+	//
+	// Label names are streamed as soon as they're read from each block, so they can be split across multiple
+	// frames. The label names of each frame are sorted and not repeated in other frames. Hints are sent in the
+	// last frame.
+	LabelNamesStream(*storepb.LabelNamesRequest, StoreGateway_LabelNamesStreamServer) error
 	// LabelValues returns all label values for given label name.
 	LabelValues(context.Context, *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error)
+	// LabelValuesStream streams all label values for given label name.
+	//
+	// Label values are streamed as soon as they're read from each block, so they can be split across multiple
+	// frames. The label values of each frame are sorted and not repeated in other frames. Hints are sent in the
+	// last frame.
+	LabelValuesStream(*storepb.LabelValuesRequest, StoreGateway_LabelValuesStreamServer) error
+	// Exemplars returns the exemplars stored in the blocks for given label matchers and time range.
+	Exemplars(context.Context, *ExemplarsRequest) (*ExemplarsResponse, error)
 }
 
 // UnimplementedStoreGatewayServer can be embedded to have forward compatible implementations.
@@ -161,6 +624,15 @@ func (*UnimplementedStoreGatewayServer) LabelNames(ctx context.Context, req *sto
 func (*UnimplementedStoreGatewayServer) LabelValues(ctx context.Context, req *storepb.LabelValuesRequest) (*storepb.LabelValuesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LabelValues not implemented")
 }
+func (*UnimplementedStoreGatewayServer) LabelNamesStream(req *storepb.LabelNamesRequest, srv StoreGateway_LabelNamesStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method LabelNamesStream not implemented")
+}
+func (*UnimplementedStoreGatewayServer) LabelValuesStream(req *storepb.LabelValuesRequest, srv StoreGateway_LabelValuesStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method LabelValuesStream not implemented")
+}
+func (*UnimplementedStoreGatewayServer) Exemplars(ctx context.Context, req *ExemplarsRequest) (*ExemplarsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exemplars not implemented")
+}
 
 func RegisterStoreGatewayServer(s *grpc.Server, srv StoreGatewayServer) {
 	s.RegisterService(&_StoreGateway_serviceDesc, srv)
@@ -223,6 +695,66 @@ func _StoreGateway_LabelValues_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StoreGateway_LabelNamesStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(storepb.LabelNamesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreGatewayServer).LabelNamesStream(m, &storeGatewayLabelNamesStreamServer{stream})
+}
+
+type StoreGateway_LabelNamesStreamServer interface {
+	Send(*storepb.LabelNamesResponse) error
+	grpc.ServerStream
+}
+
+type storeGatewayLabelNamesStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeGatewayLabelNamesStreamServer) Send(m *storepb.LabelNamesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _StoreGateway_LabelValuesStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(storepb.LabelValuesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreGatewayServer).LabelValuesStream(m, &storeGatewayLabelValuesStreamServer{stream})
+}
+
+type StoreGateway_LabelValuesStreamServer interface {
+	Send(*storepb.LabelValuesResponse) error
+	grpc.ServerStream
+}
+
+type storeGatewayLabelValuesStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeGatewayLabelValuesStreamServer) Send(m *storepb.LabelValuesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _StoreGateway_Exemplars_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExemplarsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServer).Exemplars(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gatewaypb.StoreGateway/Exemplars",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServer).Exemplars(ctx, req.(*ExemplarsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _StoreGateway_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "gatewaypb.StoreGateway",
 	HandlerType: (*StoreGatewayServer)(nil),
@@ -235,6 +767,10 @@ var _StoreGateway_serviceDesc = grpc.ServiceDesc{
 			MethodName: "LabelValues",
 			Handler:    _StoreGateway_LabelValues_Handler,
 		},
+		{
+			MethodName: "Exemplars",
+			Handler:    _StoreGateway_Exemplars_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -242,6 +778,731 @@ var _StoreGateway_serviceDesc = grpc.ServiceDesc{
 			Handler:       _StoreGateway_Series_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "LabelNamesStream",
+			Handler:       _StoreGateway_LabelNamesStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "LabelValuesStream",
+			Handler:       _StoreGateway_LabelValuesStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "gateway.proto",
 }
+
+func (m *ExemplarsRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ExemplarsRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExemplarsRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.BlockIds) > 0 {
+		for iNdEx := len(m.BlockIds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.BlockIds[iNdEx])
+			copy(dAtA[i:], m.BlockIds[iNdEx])
+			i = encodeVarintGateway(dAtA, i, uint64(len(m.BlockIds[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Matchers) > 0 {
+		for iNdEx := len(m.Matchers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Matchers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintGateway(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.MaxTime != 0 {
+		i = encodeVarintGateway(dAtA, i, uint64(m.MaxTime))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.MinTime != 0 {
+		i = encodeVarintGateway(dAtA, i, uint64(m.MinTime))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ExemplarsMatchers) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ExemplarsMatchers) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExemplarsMatchers) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Matchers) > 0 {
+		for iNdEx := len(m.Matchers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Matchers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintGateway(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ExemplarsResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ExemplarsResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExemplarsResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.QueriedBlocks) > 0 {
+		for iNdEx := len(m.QueriedBlocks) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.QueriedBlocks[iNdEx])
+			copy(dAtA[i:], m.QueriedBlocks[iNdEx])
+			i = encodeVarintGateway(dAtA, i, uint64(len(m.QueriedBlocks[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Timeseries) > 0 {
+		for iNdEx := len(m.Timeseries) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Timeseries[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintGateway(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintGateway(dAtA []byte, offset int, v uint64) int {
+	offset -= sovGateway(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *ExemplarsRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.MinTime != 0 {
+		n += 1 + sovGateway(uint64(m.MinTime))
+	}
+	if m.MaxTime != 0 {
+		n += 1 + sovGateway(uint64(m.MaxTime))
+	}
+	if len(m.Matchers) > 0 {
+		for _, e := range m.Matchers {
+			l = e.Size()
+			n += 1 + l + sovGateway(uint64(l))
+		}
+	}
+	if len(m.BlockIds) > 0 {
+		for _, s := range m.BlockIds {
+			l = len(s)
+			n += 1 + l + sovGateway(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *ExemplarsMatchers) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Matchers) > 0 {
+		for _, e := range m.Matchers {
+			l = e.Size()
+			n += 1 + l + sovGateway(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *ExemplarsResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Timeseries) > 0 {
+		for _, e := range m.Timeseries {
+			l = e.Size()
+			n += 1 + l + sovGateway(uint64(l))
+		}
+	}
+	if len(m.QueriedBlocks) > 0 {
+		for _, s := range m.QueriedBlocks {
+			l = len(s)
+			n += 1 + l + sovGateway(uint64(l))
+		}
+	}
+	return n
+}
+
+func sovGateway(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozGateway(x uint64) (n int) {
+	return sovGateway(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (this *ExemplarsRequest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForMatchers := "[]ExemplarsMatchers{"
+	for _, f := range this.Matchers {
+		repeatedStringForMatchers += strings.Replace(strings.Replace(f.String(), "ExemplarsMatchers", "ExemplarsMatchers", 1), `&`, ``, 1) + ","
+	}
+	repeatedStringForMatchers += "}"
+	s := strings.Join([]string{`&ExemplarsRequest{`,
+		`MinTime:` + fmt.Sprintf("%v", this.MinTime) + `,`,
+		`MaxTime:` + fmt.Sprintf("%v", this.MaxTime) + `,`,
+		`Matchers:` + repeatedStringForMatchers + `,`,
+		`BlockIds:` + fmt.Sprintf("%v", this.BlockIds) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ExemplarsMatchers) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForMatchers := "[]LabelMatcher{"
+	for _, f := range this.Matchers {
+		repeatedStringForMatchers += fmt.Sprintf("%v", f) + ","
+	}
+	repeatedStringForMatchers += "}"
+	s := strings.Join([]string{`&ExemplarsMatchers{`,
+		`Matchers:` + repeatedStringForMatchers + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ExemplarsResponse) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForTimeseries := "[]TimeSeries{"
+	for _, f := range this.Timeseries {
+		repeatedStringForTimeseries += fmt.Sprintf("%v", f) + ","
+	}
+	repeatedStringForTimeseries += "}"
+	s := strings.Join([]string{`&ExemplarsResponse{`,
+		`Timeseries:` + repeatedStringForTimeseries + `,`,
+		`QueriedBlocks:` + fmt.Sprintf("%v", this.QueriedBlocks) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func valueToStringGateway(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("*%v", pv)
+}
+func (m *ExemplarsRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowGateway
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExemplarsRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExemplarsRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinTime", wireType)
+			}
+			m.MinTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MinTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTime", wireType)
+			}
+			m.MaxTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Matchers = append(m.Matchers, ExemplarsMatchers{})
+			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockIds", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BlockIds = append(m.BlockIds, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipGateway(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ExemplarsMatchers) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowGateway
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExemplarsMatchers: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExemplarsMatchers: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Matchers = append(m.Matchers, storepb.LabelMatcher{})
+			if err := m.Matchers[len(m.Matchers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipGateway(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ExemplarsResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowGateway
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExemplarsResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExemplarsResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timeseries", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Timeseries = append(m.Timeseries, mimirpb.TimeSeries{})
+			if err := m.Timeseries[len(m.Timeseries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueriedBlocks", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGateway
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.QueriedBlocks = append(m.QueriedBlocks, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipGateway(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthGateway
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipGateway(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowGateway
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowGateway
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthGateway
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupGateway
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthGateway
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthGateway        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowGateway          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupGateway = fmt.Errorf("proto: unexpected end of group")
+)
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cachemigration
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/multierror"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	backendTarget = "target"
+	backendSource = "source"
+)
+
+var supportedBackends = []string{cache.BackendMemcached, cache.BackendRedis}
+
+// Config configures the cache backend a cache is migrated from. While the migration source is configured,
+// the cache is written to both the target and source backends, and read from the target backend falling
+// back to the source backend on miss, so that the target backend can be warmed up without impacting the
+// cache hit ratio.
+type Config struct {
+	Backend   string                      `yaml:"backend" category:"experimental"`
+	Memcached cache.MemcachedClientConfig `yaml:"memcached"`
+	Redis     cache.RedisClientConfig     `yaml:"redis"`
+}
+
+// RegisterFlagsWithPrefix registers the flags of the migration source of the cache whose target backend
+// is configured by the input targetBackendFlag.
+func (cfg *Config) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix, targetBackendFlag string) {
+	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend the cache is migrated from, if not empty. When set, the cache entries are written to both the backend configured by -%s and this backend, and read from the former falling back to the latter on miss. Supported values: %s.", targetBackendFlag, strings.Join(supportedBackends, ", ")))
+
+	cfg.Memcached.RegisterFlagsWithPrefix(prefix+"memcached.", f)
+	cfg.Redis.RegisterFlagsWithPrefix(prefix+"redis.", f)
+}
+
+// Validate the config.
+func (cfg *Config) Validate() error {
+	backendCfg := cfg.backendConfig()
+	if err := backendCfg.Validate(); err != nil {
+		return errors.Wrap(err, "cache migration source")
+	}
+	return nil
+}
+
+func (cfg *Config) backendConfig() cache.BackendConfig {
+	return cache.BackendConfig{
+		Backend:   cfg.Backend,
+		Memcached: cfg.Memcached,
+		Redis:     cfg.Redis,
+	}
+}
+
+// WrapWithMigrationSource returns a cache.Cache writing to both the input target cache and the configured
+// migration source, and reading from the target cache falling back to the migration source on miss.
+// The target cache is returned as is if the migration source is not configured.
+func WrapWithMigrationSource(cacheName string, target cache.Cache, cfg Config, logger log.Logger, reg prometheus.Registerer) (cache.Cache, error) {
+	if target == nil || cfg.Backend == "" {
+		return target, nil
+	}
+
+	source, err := cache.CreateClient(cacheName+"-migration-source", cfg.backendConfig(), logger, prometheus.WrapRegistererWithPrefix("thanos_", reg))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s migration source", cacheName)
+	}
+
+	return newDualWriteCache(target, source, prometheus.WrapRegistererWith(prometheus.Labels{"name": cacheName}, reg)), nil
+}
+
+// dualWriteCache is a cache.Cache writing to both a target and a source cache, and reading from the target
+// cache falling back to the source cache on miss.
+type dualWriteCache struct {
+	target cache.Cache
+	source cache.Cache
+
+	requestedKeys prometheus.Counter
+	hits          *prometheus.CounterVec
+}
+
+func newDualWriteCache(target, source cache.Cache, reg prometheus.Registerer) *dualWriteCache {
+	return &dualWriteCache{
+		target: target,
+		source: source,
+		requestedKeys: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_cache_migration_requested_keys_total",
+			Help: "Total number of keys requested to a cache being migrated to a new backend.",
+		}),
+		hits: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_cache_migration_hits_total",
+			Help: "Total number of keys found in a cache being migrated to a new backend, by the backend the key has been found in. Once the keys are no longer found in the source backend, the migration source can be removed.",
+		}, []string{"backend"}),
+	}
+}
+
+// StoreAsync implements cache.Cache.
+func (c *dualWriteCache) StoreAsync(data map[string][]byte, ttl time.Duration) {
+	c.target.StoreAsync(data, ttl)
+	c.source.StoreAsync(data, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (c *dualWriteCache) Fetch(ctx context.Context, keys []string, opts ...cache.Option) map[string][]byte {
+	c.requestedKeys.Add(float64(len(keys)))
+
+	found := c.target.Fetch(ctx, keys, opts...)
+	c.hits.WithLabelValues(backendTarget).Add(float64(len(found)))
+	if len(found) == len(keys) {
+		return found
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	fromSource := c.source.Fetch(ctx, missing, opts...)
+	c.hits.WithLabelValues(backendSource).Add(float64(len(fromSource)))
+	if len(found) == 0 {
+		return fromSource
+	}
+
+	for key, value := range fromSource {
+		found[key] = value
+	}
+	return found
+}
+
+// Delete implements cache.Cache.
+func (c *dualWriteCache) Delete(ctx context.Context, key string) error {
+	errs := multierror.New()
+	errs.Add(c.target.Delete(ctx, key))
+	errs.Add(c.source.Delete(ctx, key))
+	return errs.Err()
+}
+
+// Name implements cache.Cache.
+func (c *dualWriteCache) Name() string {
+	return c.target.Name()
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package cachemigration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualWriteCache(t *testing.T) {
+	ctx := context.Background()
+	target := cache.NewMockCache()
+	source := cache.NewMockCache()
+
+	reg := prometheus.NewPedanticRegistry()
+	c := newDualWriteCache(target, source, reg)
+
+	// Entries stored before the migration are only in the source cache.
+	source.StoreAsync(map[string][]byte{"old": []byte("old-value")}, time.Hour)
+
+	// New entries are written to both caches.
+	c.StoreAsync(map[string][]byte{"new": []byte("new-value")}, time.Hour)
+	assert.Equal(t, map[string][]byte{"new": []byte("new-value")}, target.Fetch(ctx, []string{"new"}))
+	assert.Equal(t, map[string][]byte{"new": []byte("new-value")}, source.Fetch(ctx, []string{"new"}))
+
+	// Keys missing in the target cache are read from the source cache.
+	assert.Equal(t, map[string][]byte{
+		"new": []byte("new-value"),
+		"old": []byte("old-value"),
+	}, c.Fetch(ctx, []string{"new", "old", "missing"}))
+
+	assert.Equal(t, map[string][]byte{"new": []byte("new-value")}, c.Fetch(ctx, []string{"new"}))
+
+	// Keys are deleted from both caches.
+	require.NoError(t, c.Delete(ctx, "new"))
+	assert.Empty(t, target.Fetch(ctx, []string{"new"}))
+	assert.Empty(t, source.Fetch(ctx, []string{"new"}))
+
+	assert.Equal(t, target.Name(), c.Name())
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_cache_migration_hits_total Total number of keys found in a cache being migrated to a new backend, by the backend the key has been found in. Once the keys are no longer found in the source backend, the migration source can be removed.
+		# TYPE cortex_cache_migration_hits_total counter
+		cortex_cache_migration_hits_total{backend="source"} 1
+		cortex_cache_migration_hits_total{backend="target"} 2
+
+		# HELP cortex_cache_migration_requested_keys_total Total number of keys requested to a cache being migrated to a new backend.
+		# TYPE cortex_cache_migration_requested_keys_total counter
+		cortex_cache_migration_requested_keys_total 4
+	`)))
+}
+
+func TestWrapWithMigrationSource(t *testing.T) {
+	target := cache.NewMockCache()
+
+	t.Run("migration source not configured", func(t *testing.T) {
+		c, err := WrapWithMigrationSource("test", target, Config{}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		require.NoError(t, err)
+		assert.Same(t, target, c)
+	})
+
+	t.Run("target cache not configured", func(t *testing.T) {
+		c, err := WrapWithMigrationSource("test", nil, Config{Backend: cache.BackendMemcached}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Backend: "unknown"}).Validate())
+	assert.Error(t, (&Config{Backend: cache.BackendMemcached}).Validate())
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package conditions tracks the status of a component as a set of conditions, modelled after
+// the Kubernetes status conditions, to expose more detail than the binary readiness.
+package conditions
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type Status string
+
+const (
+	StatusTrue    Status = "True"
+	StatusFalse   Status = "False"
+	StatusUnknown Status = "Unknown"
+)
+
+// Condition is the status of a single aspect of a component.
+type Condition struct {
+	Type    string `json:"type"`
+	Status  Status `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the last time the status of the condition changed.
+	LastTransitionTime time.Time `json:"last_transition_time"`
+
+	// LastUpdateTime is the last time the condition has been set.
+	LastUpdateTime time.Time `json:"last_update_time"`
+}
+
+// Set holds the conditions of a component. It's safe for concurrent use.
+type Set struct {
+	mu         sync.Mutex
+	conditions []Condition
+}
+
+// NewSet returns a Set with the input condition types, all in the Unknown status.
+func NewSet(conditionTypes ...string) *Set {
+	now := time.Now()
+	s := &Set{conditions: make([]Condition, 0, len(conditionTypes))}
+
+	for _, conditionType := range conditionTypes {
+		s.conditions = append(s.conditions, Condition{
+			Type:               conditionType,
+			Status:             StatusUnknown,
+			Reason:             "NotObserved",
+			LastTransitionTime: now,
+			LastUpdateTime:     now,
+		})
+	}
+
+	return s
+}
+
+// Set updates the condition of the input type. The last transition time is updated only if the status changed.
+// Conditions types not passed to NewSet are ignored.
+func (s *Set) Set(conditionType string, status Status, reason, message string) {
+	s.set(conditionType, status, reason, message, time.Now())
+}
+
+func (s *Set) set(conditionType string, status Status, reason, message string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.conditions {
+		c := &s.conditions[i]
+		if c.Type != conditionType {
+			continue
+		}
+
+		if c.Status != status {
+			c.LastTransitionTime = now
+		}
+		c.Status = status
+		c.Reason = reason
+		c.Message = message
+		c.LastUpdateTime = now
+		return
+	}
+}
+
+// Get returns the condition of the input type, and whether it exists.
+func (s *Set) Get(conditionType string) (Condition, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.conditions {
+		if c.Type == conditionType {
+			return c, true
+		}
+	}
+
+	return Condition{}, false
+}
+
+// List returns a copy of the conditions, in the order their types were passed to NewSet.
+func (s *Set) List() []Condition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Condition(nil), s.conditions...)
+}
+
+type response struct {
+	Conditions []Condition `json:"conditions"`
+}
+
+// WriteResponse writes the input conditions as a JSON response.
+func WriteResponse(w http.ResponseWriter, conditions []Condition) {
+	util.WriteJSONResponse(w, response{Conditions: conditions})
+}
+
+// UpdateRingHealthy sets the condition of the input type based on the state of the instance in the ring:
+// the condition is true only if the instance is ACTIVE.
+func (s *Set) UpdateRingHealthy(conditionType string, r ring.ReadRing, instanceID string) {
+	state, err := r.GetInstanceState(instanceID)
+	switch {
+	case err != nil:
+		s.Set(conditionType, StatusFalse, "InstanceNotInRing", err.Error())
+	case state != ring.ACTIVE:
+		s.Set(conditionType, StatusFalse, "InstanceNotActive", fmt.Sprintf("the instance is %s in the ring", state))
+	default:
+		s.Set(conditionType, StatusTrue, "InstanceActive", "")
+	}
+}
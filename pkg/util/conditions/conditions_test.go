@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package conditions
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	s := NewSet("Synced", "RingHealthy")
+
+	for _, c := range s.List() {
+		assert.Equal(t, StatusUnknown, c.Status)
+		assert.Equal(t, "NotObserved", c.Reason)
+	}
+
+	t0 := time.Unix(1000, 0)
+	s.set("Synced", StatusFalse, "InitialSyncInProgress", "", t0)
+
+	c, ok := s.Get("Synced")
+	require.True(t, ok)
+	assert.Equal(t, Condition{Type: "Synced", Status: StatusFalse, Reason: "InitialSyncInProgress", LastTransitionTime: t0, LastUpdateTime: t0}, c)
+
+	// Setting the same status updates the reason, but not the last transition time.
+	t1 := t0.Add(time.Minute)
+	s.set("Synced", StatusFalse, "SyncFailed", "bucket unavailable", t1)
+
+	c, _ = s.Get("Synced")
+	assert.Equal(t, Condition{Type: "Synced", Status: StatusFalse, Reason: "SyncFailed", Message: "bucket unavailable", LastTransitionTime: t0, LastUpdateTime: t1}, c)
+
+	// Changing the status updates the last transition time.
+	t2 := t1.Add(time.Minute)
+	s.set("Synced", StatusTrue, "SyncSucceeded", "", t2)
+
+	c, _ = s.Get("Synced")
+	assert.Equal(t, Condition{Type: "Synced", Status: StatusTrue, Reason: "SyncSucceeded", LastTransitionTime: t2, LastUpdateTime: t2}, c)
+
+	// Unknown condition types are ignored.
+	s.Set("Unknown", StatusTrue, "", "")
+	_, ok = s.Get("Unknown")
+	assert.False(t, ok)
+
+	list := s.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "Synced", list[0].Type)
+	assert.Equal(t, "RingHealthy", list[1].Type)
+}
+
+func TestWriteResponse(t *testing.T) {
+	ts := time.Unix(1000, 0).UTC()
+
+	w := httptest.NewRecorder()
+	WriteResponse(w, []Condition{{Type: "Synced", Status: StatusTrue, Reason: "SyncSucceeded", LastTransitionTime: ts, LastUpdateTime: ts}})
+
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"conditions":[{"type":"Synced","status":"True","reason":"SyncSucceeded","last_transition_time":"1970-01-01T00:16:40Z","last_update_time":"1970-01-01T00:16:40Z"}]}`, w.Body.String())
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package faultinjection provides gRPC client interceptors injecting latency and failures into the
+// requests, to test how Mimir behaves when the remote services are slow or unavailable. It's meant
+// to be used for chaos testing in staging environments only.
+package faultinjection
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errInvalidDelayPercentage = errors.New("the fault injection delay percentage must be between 0 and 100")
+	errInvalidErrorPercentage = errors.New("the fault injection error percentage must be between 0 and 100")
+	errNegativeDelay          = errors.New("the fault injection delay must not be negative")
+)
+
+// Config configures the faults injected into the requests of a gRPC client.
+type Config struct {
+	Tenants         flagext.StringSliceCSV `yaml:"tenants" category:"experimental"`
+	Delay           time.Duration          `yaml:"delay" category:"experimental"`
+	DelayPercentage float64                `yaml:"delay_percentage" category:"experimental"`
+	ErrorPercentage float64                `yaml:"error_percentage" category:"experimental"`
+}
+
+func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Var(&cfg.Tenants, prefix+".tenants", "Comma-separated list of tenants whose requests are subject to fault injection. If empty, the requests of all tenants are subject to fault injection.")
+	f.DurationVar(&cfg.Delay, prefix+".delay", 0, "Delay injected into the requests selected by -"+prefix+".delay-percentage.")
+	f.Float64Var(&cfg.DelayPercentage, prefix+".delay-percentage", 0, "Percentage of the requests, between 0 and 100, to delay by -"+prefix+".delay. 0 to disable.")
+	f.Float64Var(&cfg.ErrorPercentage, prefix+".error-percentage", 0, "Percentage of the requests, between 0 and 100, to fail with an Unavailable error without sending them. 0 to disable.")
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.Delay < 0 {
+		return errNegativeDelay
+	}
+	if cfg.DelayPercentage < 0 || cfg.DelayPercentage > 100 {
+		return errInvalidDelayPercentage
+	}
+	if cfg.ErrorPercentage < 0 || cfg.ErrorPercentage > 100 {
+		return errInvalidErrorPercentage
+	}
+	return nil
+}
+
+// Enabled returns whether any fault is injected.
+func (cfg *Config) Enabled() bool {
+	return (cfg.Delay > 0 && cfg.DelayPercentage > 0) || cfg.ErrorPercentage > 0
+}
+
+// Interceptors appends the fault injection interceptors to the input ones, if fault injection is enabled.
+// The fault injection interceptors run last, so that the injected delays and errors are tracked by
+// the instrumentation interceptors.
+func (cfg Config) Interceptors(unary []grpc.UnaryClientInterceptor, stream []grpc.StreamClientInterceptor) ([]grpc.UnaryClientInterceptor, []grpc.StreamClientInterceptor) {
+	if !cfg.Enabled() {
+		return unary, stream
+	}
+
+	i := newInjector(cfg, rand.Float64)
+	return append(unary, i.unaryClientInterceptor), append(stream, i.streamClientInterceptor)
+}
+
+type injector struct {
+	cfg     Config
+	tenants map[string]struct{}
+
+	// random returns a pseudo-random number in [0.0,1.0).
+	random func() float64
+}
+
+func newInjector(cfg Config, random func() float64) *injector {
+	i := &injector{cfg: cfg, random: random}
+	if len(cfg.Tenants) > 0 {
+		i.tenants = make(map[string]struct{}, len(cfg.Tenants))
+		for _, t := range cfg.Tenants {
+			i.tenants[t] = struct{}{}
+		}
+	}
+	return i
+}
+
+func (i *injector) unaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := i.inject(ctx, method); err != nil {
+		return err
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (i *injector) streamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := i.inject(ctx, method); err != nil {
+		return nil, err
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// inject delays the request and returns an error to fail it, according to the configured percentages.
+func (i *injector) inject(ctx context.Context, method string) error {
+	if !i.selected(ctx) {
+		return nil
+	}
+
+	if i.cfg.Delay > 0 && i.random()*100 < i.cfg.DelayPercentage {
+		select {
+		case <-time.After(i.cfg.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if i.random()*100 < i.cfg.ErrorPercentage {
+		return status.Errorf(codes.Unavailable, "fault injected into request %s", method)
+	}
+	return nil
+}
+
+// selected returns whether the request is subject to fault injection, based on its tenants.
+func (i *injector) selected(ctx context.Context) bool {
+	if i.tenants == nil {
+		return true
+	}
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return false
+	}
+	for _, id := range tenantIDs {
+		if _, ok := i.tenants[id]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package faultinjection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg         Config
+		expectedErr error
+	}{
+		"should pass on default config": {
+			cfg: Config{},
+		},
+		"should pass on valid config": {
+			cfg: Config{Delay: time.Second, DelayPercentage: 100, ErrorPercentage: 0.5},
+		},
+		"should fail on negative delay": {
+			cfg:         Config{Delay: -time.Second},
+			expectedErr: errNegativeDelay,
+		},
+		"should fail on delay percentage greater than 100": {
+			cfg:         Config{DelayPercentage: 101},
+			expectedErr: errInvalidDelayPercentage,
+		},
+		"should fail on negative error percentage": {
+			cfg:         Config{ErrorPercentage: -1},
+			expectedErr: errInvalidErrorPercentage,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, tc.cfg.Validate())
+		})
+	}
+}
+
+func TestConfig_Interceptors(t *testing.T) {
+	unary, stream := (Config{}).Interceptors(nil, nil)
+	assert.Empty(t, unary)
+	assert.Empty(t, stream)
+
+	unary, stream = (Config{ErrorPercentage: 10}).Interceptors(nil, nil)
+	assert.Len(t, unary, 1)
+	assert.Len(t, stream, 1)
+}
+
+func TestInjector(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	tests := map[string]struct {
+		cfg           Config
+		random        float64
+		tenantID      string
+		expectedDelay bool
+		expectedError bool
+	}{
+		"should inject delay and error to all tenants if no tenant is configured": {
+			cfg:           Config{Delay: delay, DelayPercentage: 50, ErrorPercentage: 50},
+			random:        0.1,
+			tenantID:      "user-1",
+			expectedDelay: true,
+			expectedError: true,
+		},
+		"should not inject faults if the request is not selected by the percentages": {
+			cfg:      Config{Delay: delay, DelayPercentage: 50, ErrorPercentage: 50},
+			random:   0.9,
+			tenantID: "user-1",
+		},
+		"should inject only the error if the delay percentage is 0": {
+			cfg:           Config{Delay: delay, ErrorPercentage: 100},
+			random:        0.1,
+			tenantID:      "user-1",
+			expectedError: true,
+		},
+		"should inject faults to the configured tenants": {
+			cfg:           Config{Tenants: flagext.StringSliceCSV{"user-1"}, Delay: delay, DelayPercentage: 100, ErrorPercentage: 100},
+			random:        0.1,
+			tenantID:      "user-1",
+			expectedDelay: true,
+			expectedError: true,
+		},
+		"should not inject faults to other tenants": {
+			cfg:      Config{Tenants: flagext.StringSliceCSV{"user-1"}, Delay: delay, DelayPercentage: 100, ErrorPercentage: 100},
+			random:   0.1,
+			tenantID: "user-2",
+		},
+		"should not inject faults to requests without tenant if tenants are configured": {
+			cfg:    Config{Tenants: flagext.StringSliceCSV{"user-1"}, ErrorPercentage: 100},
+			random: 0.1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			i := newInjector(tc.cfg, func() float64 { return tc.random })
+
+			ctx := context.Background()
+			if tc.tenantID != "" {
+				ctx = user.InjectOrgID(ctx, tc.tenantID)
+			}
+
+			invoked := false
+			invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+				invoked = true
+				return nil
+			}
+
+			start := time.Now()
+			err := i.unaryClientInterceptor(ctx, "/test", nil, nil, nil, invoker)
+			elapsed := time.Since(start)
+
+			if tc.expectedError {
+				require.Error(t, err)
+				assert.Equal(t, codes.Unavailable, status.Code(err))
+				assert.False(t, invoked)
+			} else {
+				require.NoError(t, err)
+				assert.True(t, invoked)
+			}
+
+			if tc.expectedDelay {
+				assert.GreaterOrEqual(t, elapsed, delay)
+			} else {
+				assert.Less(t, elapsed, delay)
+			}
+		})
+	}
+}
+
+func TestInjector_ShouldStopDelayOnContextCancellation(t *testing.T) {
+	i := newInjector(Config{Delay: time.Minute, DelayPercentage: 100}, func() float64 { return 0 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := i.streamClientInterceptor(ctx, &grpc.StreamDesc{}, nil, "/test", func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		t.Fatal("the request should not be sent")
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -68,6 +68,51 @@ var overrides = map[string]Category{
 	"server.path-prefix":                                Advanced,
 	"server.register-instrumentation":                   Advanced,
 	"server.log-request-at-info-level-enabled":          Advanced,
+
+	// grafana/dskit/cache in the query-frontend results cache migration source
+	"query-frontend.results-cache.migration-source.memcached.addresses":                                Experimental,
+	"query-frontend.results-cache.migration-source.memcached.connect-timeout":                          Experimental,
+	"query-frontend.results-cache.migration-source.memcached.max-async-buffer-size":                    Experimental,
+	"query-frontend.results-cache.migration-source.memcached.max-async-concurrency":                    Experimental,
+	"query-frontend.results-cache.migration-source.memcached.max-get-multi-batch-size":                 Experimental,
+	"query-frontend.results-cache.migration-source.memcached.max-get-multi-concurrency":                Experimental,
+	"query-frontend.results-cache.migration-source.memcached.max-idle-connections":                     Experimental,
+	"query-frontend.results-cache.migration-source.memcached.max-item-size":                            Experimental,
+	"query-frontend.results-cache.migration-source.memcached.min-idle-connections-headroom-percentage": Experimental,
+	"query-frontend.results-cache.migration-source.memcached.timeout":                                  Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-ca-path":                              Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-cert-path":                            Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-cipher-suites":                        Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-enabled":                              Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-insecure-skip-verify":                 Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-key-path":                             Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-min-version":                          Experimental,
+	"query-frontend.results-cache.migration-source.memcached.tls-server-name":                          Experimental,
+	"query-frontend.results-cache.migration-source.redis.connection-pool-size":                         Experimental,
+	"query-frontend.results-cache.migration-source.redis.db":                                           Experimental,
+	"query-frontend.results-cache.migration-source.redis.dial-timeout":                                 Experimental,
+	"query-frontend.results-cache.migration-source.redis.endpoint":                                     Experimental,
+	"query-frontend.results-cache.migration-source.redis.idle-timeout":                                 Experimental,
+	"query-frontend.results-cache.migration-source.redis.master-name":                                  Experimental,
+	"query-frontend.results-cache.migration-source.redis.max-async-buffer-size":                        Experimental,
+	"query-frontend.results-cache.migration-source.redis.max-async-concurrency":                        Experimental,
+	"query-frontend.results-cache.migration-source.redis.max-connection-age":                           Experimental,
+	"query-frontend.results-cache.migration-source.redis.max-get-multi-batch-size":                     Experimental,
+	"query-frontend.results-cache.migration-source.redis.max-get-multi-concurrency":                    Experimental,
+	"query-frontend.results-cache.migration-source.redis.max-item-size":                                Experimental,
+	"query-frontend.results-cache.migration-source.redis.min-idle-connections":                         Experimental,
+	"query-frontend.results-cache.migration-source.redis.password":                                     Experimental,
+	"query-frontend.results-cache.migration-source.redis.read-timeout":                                 Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-ca-path":                                  Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-cert-path":                                Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-cipher-suites":                            Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-enabled":                                  Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-insecure-skip-verify":                     Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-key-path":                                 Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-min-version":                              Experimental,
+	"query-frontend.results-cache.migration-source.redis.tls-server-name":                              Experimental,
+	"query-frontend.results-cache.migration-source.redis.username":                                     Experimental,
+	"query-frontend.results-cache.migration-source.redis.write-timeout":                                Experimental,
 }
 
 func AddOverrides(o map[string]Category) {
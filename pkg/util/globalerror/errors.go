@@ -20,9 +20,11 @@ const (
 	SeriesInvalidLabel            ID = "label-invalid"
 	SeriesLabelNameTooLong        ID = "label-name-too-long"
 	SeriesLabelValueTooLong       ID = "label-value-too-long"
+	SeriesLabelsTooLarge          ID = "labels-size-too-large"
 	SeriesWithDuplicateLabelNames ID = "duplicate-label-names"
 	SeriesLabelsNotSorted         ID = "labels-not-sorted"
 	SampleTooFarInFuture          ID = "too-far-in-future"
+	SeriesHistogramFloatConflict  ID = "histogram-float-conflict"
 	MaxSeriesPerMetric            ID = "max-series-per-metric"
 	MaxMetadataPerMetric          ID = "max-metadata-per-metric"
 	MaxSeriesPerUser              ID = "max-series-per-user"
@@ -30,10 +32,13 @@ const (
 	MaxChunksPerQuery             ID = "max-chunks-per-query"
 	MaxSeriesPerQuery             ID = "max-series-per-query"
 	MaxChunkBytesPerQuery         ID = "max-chunks-bytes-per-query"
+	MaxEstimatedMemoryPerQuery    ID = "max-estimated-memory-per-query"
 
 	DistributorMaxIngestionRate             ID = "distributor-max-ingestion-rate"
 	DistributorMaxInflightPushRequests      ID = "distributor-max-inflight-push-requests"
 	DistributorMaxInflightPushRequestsBytes ID = "distributor-max-inflight-push-requests-bytes"
+	DistributorIngestersPressure            ID = "distributor-ingesters-pressure"
+	DistributorIngesterCircuitBreakerOpen   ID = "distributor-ingester-circuit-breaker-open"
 
 	IngesterMaxIngestionRate        ID = "ingester-max-ingestion-rate"
 	IngesterMaxTenants              ID = "ingester-max-tenants"
@@ -48,23 +53,34 @@ const (
 	MetricMetadataMetricNameTooLong ID = "metric-name-too-long"
 	MetricMetadataHelpTooLong       ID = "help-too-long" // unused, left here to prevent reuse for different purpose
 	MetricMetadataUnitTooLong       ID = "unit-too-long"
+	MetricMetadataTooLarge          ID = "metadata-too-large"
 
 	MaxQueryLength              ID = "max-query-length"
 	MaxTotalQueryLength         ID = "max-total-query-length"
 	MaxQueryExpressionSizeBytes ID = "max-query-expression-size-bytes"
+	MaxInstantQueryResultSeries ID = "max-instant-query-result-series"
+	MaxRangeQueryResultSamples  ID = "max-range-query-result-samples"
+	QueryBlocked                ID = "query-blocked"
 	RequestRateLimited          ID = "tenant-max-request-rate"
+	SourceRequestRateLimited    ID = "source-max-request-rate"
 	IngestionRateLimited        ID = "tenant-max-ingestion-rate"
 	TooManyHAClusters           ID = "tenant-too-many-ha-clusters"
 
 	SampleTimestampTooOld    ID = "sample-timestamp-too-old"
 	SampleOutOfOrder         ID = "sample-out-of-order"
 	SampleDuplicateTimestamp ID = "sample-duplicate-timestamp"
+	SampleTooFrequent        ID = "sample-too-frequent"
 	ExemplarSeriesMissing    ID = "exemplar-series-missing"
 
 	StoreConsistencyCheckFailed ID = "store-consistency-check-failed"
+	LabelNamesAndValuesTooLarge ID = "label-names-and-values-too-large"
+	QueryTooExpensive           ID = "query-too-expensive"
 	BucketIndexTooOld           ID = "bucket-index-too-old"
 
 	DistributorMaxWriteMessageSize ID = "distributor-max-write-message-size"
+
+	SandboxTenantExpired    ID = "sandbox-tenant-expired"
+	TenantMarkedForDeletion ID = "tenant-marked-for-deletion"
 )
 
 // Message returns the provided msg, appending the error id.
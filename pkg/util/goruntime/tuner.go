@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package goruntime
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+var errInvalidMemoryLimit = errors.New("the memory limit must not be negative")
+
+// Config holds the Go runtime garbage collector settings.
+type Config struct {
+	GOGC             int   `yaml:"gogc" category:"experimental"`
+	MemoryLimitBytes int64 `yaml:"memory_limit_bytes" category:"experimental"`
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.IntVar(&cfg.GOGC, "go-runtime.gogc", 0, "Garbage collection target percentage, like the GOGC environment variable. A negative value disables the garbage collection. 0 to keep the value set by the GOGC environment variable, or the Go default.")
+	f.Int64Var(&cfg.MemoryLimitBytes, "go-runtime.memory-limit-bytes", 0, "Soft memory limit of the Go runtime, in bytes, like the GOMEMLIMIT environment variable. 0 to keep the value set by the GOMEMLIMIT environment variable, or no limit.")
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.MemoryLimitBytes < 0 {
+		return errInvalidMemoryLimit
+	}
+	return nil
+}
+
+// Status is the Go runtime garbage collector settings and statistics.
+type Status struct {
+	GOGC             int   `json:"gogc"`
+	MemoryLimitBytes int64 `json:"memory_limit_bytes"`
+
+	NumGC             uint32    `json:"num_gc"`
+	LastGC            time.Time `json:"last_gc"`
+	PauseTotalSeconds float64   `json:"pause_total_seconds"`
+	GCCPUFraction     float64   `json:"gc_cpu_fraction"`
+	HeapAllocBytes    uint64    `json:"heap_alloc_bytes"`
+	HeapInuseBytes    uint64    `json:"heap_inuse_bytes"`
+	NextGCBytes       uint64    `json:"next_gc_bytes"`
+}
+
+// Tuner applies the Go runtime garbage collector settings, and allows to change them at runtime.
+type Tuner struct {
+	logger log.Logger
+
+	mtx  sync.Mutex
+	gogc int
+}
+
+// NewTuner applies the configured settings, and returns a Tuner to change them at runtime.
+func NewTuner(cfg Config, logger log.Logger) *Tuner {
+	t := &Tuner{logger: logger}
+
+	// There's no way to read the GC percentage without setting it, so we read it
+	// once at startup and then keep track of it.
+	t.gogc = debug.SetGCPercent(100)
+	debug.SetGCPercent(t.gogc)
+
+	if cfg.GOGC != 0 {
+		t.setGOGC(cfg.GOGC)
+	}
+	if cfg.MemoryLimitBytes > 0 {
+		t.setMemoryLimit(cfg.MemoryLimitBytes)
+	}
+	return t
+}
+
+func (t *Tuner) setGOGC(gogc int) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	debug.SetGCPercent(gogc)
+	t.gogc = gogc
+	level.Info(t.logger).Log("msg", "set Go runtime GOGC", "gogc", gogc)
+}
+
+func (t *Tuner) setMemoryLimit(limit int64) {
+	debug.SetMemoryLimit(limit)
+	level.Info(t.logger).Log("msg", "set Go runtime memory limit", "bytes", limit)
+}
+
+// Status returns the current garbage collector settings and statistics.
+func (t *Tuner) Status() Status {
+	t.mtx.Lock()
+	gogc := t.gogc
+	t.mtx.Unlock()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	s := Status{
+		GOGC: gogc,
+		// A negative input doesn't change the memory limit, and returns the current one.
+		MemoryLimitBytes:  debug.SetMemoryLimit(-1),
+		NumGC:             stats.NumGC,
+		PauseTotalSeconds: time.Duration(stats.PauseTotalNs).Seconds(),
+		GCCPUFraction:     stats.GCCPUFraction,
+		HeapAllocBytes:    stats.HeapAlloc,
+		HeapInuseBytes:    stats.HeapInuse,
+		NextGCBytes:       stats.NextGC,
+	}
+	if stats.LastGC > 0 {
+		s.LastGC = time.Unix(0, int64(stats.LastGC)).UTC()
+	}
+	return s
+}
+
+// ServeHTTP returns the garbage collector settings and statistics. On POST, it first applies the settings
+// passed in the gogc and memory_limit_bytes form values. A memory_limit_bytes of 0 removes the limit.
+func (t *Tuner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if err := t.update(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	util.WriteJSONResponse(w, t.Status())
+}
+
+func (t *Tuner) update(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	var (
+		gogc     int
+		limit    int64
+		setGOGC  = r.Form.Has("gogc")
+		setLimit = r.Form.Has("memory_limit_bytes")
+		err      error
+	)
+	if !setGOGC && !setLimit {
+		return errors.New("at least one of gogc and memory_limit_bytes must be set")
+	}
+
+	if setGOGC {
+		if gogc, err = strconv.Atoi(r.Form.Get("gogc")); err != nil {
+			return fmt.Errorf("invalid gogc: %w", err)
+		}
+	}
+	if setLimit {
+		if limit, err = strconv.ParseInt(r.Form.Get("memory_limit_bytes"), 10, 64); err != nil {
+			return fmt.Errorf("invalid memory_limit_bytes: %w", err)
+		}
+		if limit < 0 {
+			return errInvalidMemoryLimit
+		}
+		if limit == 0 {
+			limit = math.MaxInt64
+		}
+	}
+
+	if setGOGC {
+		t.setGOGC(gogc)
+	}
+	if setLimit {
+		t.setMemoryLimit(limit)
+	}
+	return nil
+}
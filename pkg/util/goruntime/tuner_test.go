@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package goruntime
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTuner(t *testing.T) {
+	// Restore the original settings once done.
+	origGOGC := debug.SetGCPercent(100)
+	debug.SetGCPercent(origGOGC)
+	origLimit := debug.SetMemoryLimit(-1)
+	t.Cleanup(func() {
+		debug.SetGCPercent(origGOGC)
+		debug.SetMemoryLimit(origLimit)
+	})
+
+	tuner := NewTuner(Config{GOGC: 50, MemoryLimitBytes: 1 << 30}, log.NewNopLogger())
+
+	request := func(t *testing.T, method string, form url.Values) (int, Status) {
+		req := httptest.NewRequest(method, "/debug/gc", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		tuner.ServeHTTP(rec, req)
+
+		var status Status
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		}
+		return rec.Code, status
+	}
+
+	t.Run("should apply the configured settings", func(t *testing.T) {
+		code, status := request(t, http.MethodGet, nil)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 50, status.GOGC)
+		assert.Equal(t, int64(1<<30), status.MemoryLimitBytes)
+	})
+
+	t.Run("should change the settings on POST", func(t *testing.T) {
+		code, status := request(t, http.MethodPost, url.Values{"gogc": []string{"200"}, "memory_limit_bytes": []string{"0"}})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 200, status.GOGC)
+		assert.Equal(t, int64(math.MaxInt64), status.MemoryLimitBytes)
+
+		code, status = request(t, http.MethodPost, url.Values{"memory_limit_bytes": []string{"1024"}})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 200, status.GOGC)
+		assert.Equal(t, int64(1024), status.MemoryLimitBytes)
+	})
+
+	t.Run("should fail on invalid settings", func(t *testing.T) {
+		for _, form := range []url.Values{
+			{},
+			{"gogc": []string{"foo"}},
+			{"memory_limit_bytes": []string{"-1"}},
+		} {
+			code, _ := request(t, http.MethodPost, form)
+			assert.Equal(t, http.StatusBadRequest, code, form.Encode())
+		}
+	})
+}
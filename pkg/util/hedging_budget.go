@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"sync"
+)
+
+// hedgingBudgetMaxTokens is the max number of hedged requests which can be accumulated in the
+// hedging budget, and then sent in a burst.
+const hedgingBudgetMaxTokens = 10
+
+// HedgingBudget limits the number of hedged requests to a ratio of the requests. Each request
+// adds the ratio to the budget, and each hedged request consumes 1 from the budget.
+type HedgingBudget struct {
+	ratio float64
+
+	mtx    sync.Mutex
+	tokens float64
+}
+
+func NewHedgingBudget(ratio float64) *HedgingBudget {
+	return &HedgingBudget{ratio: ratio}
+}
+
+// AddRequest adds a request to the budget.
+func (b *HedgingBudget) AddRequest() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > hedgingBudgetMaxTokens {
+		b.tokens = hedgingBudgetMaxTokens
+	}
+}
+
+// TryHedge returns whether a hedged request is allowed by the budget, and consumes it if so.
+func (b *HedgingBudget) TryHedge() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgingBudget(t *testing.T) {
+	b := NewHedgingBudget(0.5)
+
+	// The budget is initially empty.
+	assert.False(t, b.TryHedge())
+
+	b.AddRequest()
+	assert.False(t, b.TryHedge())
+
+	b.AddRequest()
+	assert.True(t, b.TryHedge())
+	assert.False(t, b.TryHedge())
+
+	// The budget is capped.
+	for i := 0; i < 100; i++ {
+		b.AddRequest()
+	}
+	for i := 0; i < hedgingBudgetMaxTokens; i++ {
+		assert.True(t, b.TryHedge())
+	}
+	assert.False(t, b.TryHedge())
+}
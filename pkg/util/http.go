@@ -26,6 +26,10 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// ConfigAuthorHeader is the HTTP header used to set the author of a configuration change
+// (e.g. a rule group or an Alertmanager configuration), which is recorded in the configuration history.
+const ConfigAuthorHeader = "X-Mimir-Config-Author"
+
 // IsRequestBodyTooLarge returns true if the error is "http: request body too large".
 func IsRequestBodyTooLarge(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "http: request body too large")
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingestaggregation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// DefaultInterval is the interval at which the aggregated series are computed when not set in the rule.
+const DefaultInterval = time.Minute
+
+// Rule is a validation.IngestAggregationRule ready to be applied to the ingested series.
+type Rule struct {
+	key       string
+	matchers  []*labels.Matcher
+	without   map[string]struct{}
+	interval  time.Duration
+	dropInput bool
+}
+
+// Compile parses the input rules. The returned rules are in the same order as the input ones.
+func Compile(cfgs []validation.IngestAggregationRule) ([]*Rule, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	rules := make([]*Rule, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		matchers, err := parser.ParseMetricSelector(cfg.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingest aggregation rule selector %q: %w", cfg.Selector, err)
+		}
+
+		without := make(map[string]struct{}, len(cfg.Without))
+		for _, name := range cfg.Without {
+			without[name] = struct{}{}
+		}
+
+		interval := time.Duration(cfg.Interval)
+		if interval <= 0 {
+			interval = DefaultInterval
+		}
+
+		rules = append(rules, &Rule{
+			key:       fmt.Sprintf("%s without(%s) every %s", cfg.Selector, strings.Join(cfg.Without, ","), interval),
+			matchers:  matchers,
+			without:   without,
+			interval:  interval,
+			dropInput: cfg.DropInput,
+		})
+	}
+
+	return rules, nil
+}
+
+// RulesCache caches the rules compiled from the tenants' configs, so that the rules of a tenant are
+// compiled again only when its config changes.
+type RulesCache struct {
+	mtx     sync.RWMutex
+	tenants map[string]compiledRules
+}
+
+type compiledRules struct {
+	cfgs  []validation.IngestAggregationRule
+	rules []*Rule
+	err   error
+}
+
+// NewRulesCache returns an empty RulesCache.
+func NewRulesCache() *RulesCache {
+	return &RulesCache{tenants: map[string]compiledRules{}}
+}
+
+// Get returns the rules compiled from the input config of the tenant, compiling them if the config
+// changed since the previous call.
+func (c *RulesCache) Get(userID string, cfgs []validation.IngestAggregationRule) ([]*Rule, error) {
+	c.mtx.RLock()
+	cached, ok := c.tenants[userID]
+	c.mtx.RUnlock()
+
+	if ok && reflect.DeepEqual(cached.cfgs, cfgs) {
+		return cached.rules, cached.err
+	}
+	if !ok && len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(cfgs) == 0 {
+		delete(c.tenants, userID)
+		return nil, nil
+	}
+
+	rules, err := Compile(cfgs)
+	c.tenants[userID] = compiledRules{cfgs: cfgs, rules: rules, err: err}
+	return rules, err
+}
+
+// Match returns the first rule aggregating the input series, or nil if the series is not aggregated.
+// A series is aggregated by a rule if it matches the rule's selector and has at least one of the
+// labels dropped by the rule.
+func Match(rules []*Rule, series []mimirpb.LabelAdapter) *Rule {
+	for _, r := range rules {
+		if r.matches(series) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(series []mimirpb.LabelAdapter) bool {
+	hasDroppedLabel := false
+	for _, l := range series {
+		if _, ok := r.without[l.Name]; ok {
+			hasDroppedLabel = true
+			break
+		}
+	}
+	if !hasDroppedLabel {
+		return false
+	}
+
+	for _, m := range r.matchers {
+		if !m.Matches(labelValue(series, m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// OutputLabels returns the labels of the aggregated series the input series is rolled up into.
+// The returned labels share the strings with the input ones.
+func (r *Rule) OutputLabels(series []mimirpb.LabelAdapter) []mimirpb.LabelAdapter {
+	out := make([]mimirpb.LabelAdapter, 0, len(series))
+	for _, l := range series {
+		if _, ok := r.without[l.Name]; !ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Key uniquely identifies the rule.
+func (r *Rule) Key() string {
+	return r.key
+}
+
+// Interval returns the interval at which the aggregated series are computed.
+func (r *Rule) Interval() time.Duration {
+	return r.interval
+}
+
+// DropInput returns whether the series rolled up by the rule should not be ingested.
+func (r *Rule) DropInput() bool {
+	return r.dropInput
+}
+
+func labelValue(series []mimirpb.LabelAdapter, name string) string {
+	for _, l := range series {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
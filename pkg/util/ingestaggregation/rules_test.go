@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingestaggregation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestCompile(t *testing.T) {
+	rules, err := Compile([]validation.IngestAggregationRule{
+		{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod"}},
+		{Selector: `{job="api"}`, Without: []string{"pod", "instance"}, Interval: model.Duration(30 * time.Second), DropInput: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	assert.Equal(t, DefaultInterval, rules[0].Interval())
+	assert.False(t, rules[0].DropInput())
+	assert.Equal(t, 30*time.Second, rules[1].Interval())
+	assert.True(t, rules[1].DropInput())
+	assert.NotEqual(t, rules[0].Key(), rules[1].Key())
+
+	_, err = Compile([]validation.IngestAggregationRule{{Selector: `{job=`, Without: []string{"pod"}}})
+	require.Error(t, err)
+}
+
+func TestRulesCache(t *testing.T) {
+	c := NewRulesCache()
+
+	rules, err := c.Get("user-1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	cfgs := []validation.IngestAggregationRule{{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod"}}}
+	rules, err = c.Get("user-1", cfgs)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	// The rules are not compiled again as long as the config doesn't change.
+	cached, err := c.Get("user-1", []validation.IngestAggregationRule{{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod"}}})
+	require.NoError(t, err)
+	assert.Same(t, rules[0], cached[0])
+
+	// The rules are compiled again once the config changes.
+	changed, err := c.Get("user-1", []validation.IngestAggregationRule{{Selector: `{__name__="http_requests_total"}`, Without: []string{"instance"}}})
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.NotSame(t, rules[0], changed[0])
+
+	// Invalid rules are cached along with their error.
+	_, err = c.Get("user-2", []validation.IngestAggregationRule{{Selector: `{job=`, Without: []string{"pod"}}})
+	require.Error(t, err)
+	_, err = c.Get("user-2", []validation.IngestAggregationRule{{Selector: `{job=`, Without: []string{"pod"}}})
+	require.Error(t, err)
+
+	// The tenants without rules are not tracked anymore.
+	rules, err = c.Get("user-1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+	assert.NotContains(t, c.tenants, "user-1")
+}
+
+func TestMatch(t *testing.T) {
+	rules, err := Compile([]validation.IngestAggregationRule{
+		{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod"}},
+		{Selector: `{job="api"}`, Without: []string{"instance"}},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		series   []mimirpb.LabelAdapter
+		expected *Rule
+	}{
+		"matching the first rule": {
+			series:   []mimirpb.LabelAdapter{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}, {Name: "pod", Value: "api-1"}},
+			expected: rules[0],
+		},
+		"matching the selector of the first rule but without the dropped labels": {
+			series:   []mimirpb.LabelAdapter{{Name: "__name__", Value: "http_requests_total"}, {Name: "instance", Value: "1"}, {Name: "job", Value: "api"}},
+			expected: rules[1],
+		},
+		"matching the selector of no rule": {
+			series: []mimirpb.LabelAdapter{{Name: "__name__", Value: "up"}, {Name: "job", Value: "db"}, {Name: "pod", Value: "db-1"}},
+		},
+		"matching the selector of all rules but without any dropped label": {
+			series: []mimirpb.LabelAdapter{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Same(t, testData.expected, Match(rules, testData.series))
+		})
+	}
+}
+
+func TestRule_OutputLabels(t *testing.T) {
+	rules, err := Compile([]validation.IngestAggregationRule{
+		{Selector: `{job="api"}`, Without: []string{"pod", "instance"}},
+	})
+	require.NoError(t, err)
+
+	input := []mimirpb.LabelAdapter{
+		{Name: "__name__", Value: "http_requests_total"},
+		{Name: "instance", Value: "1"},
+		{Name: "job", Value: "api"},
+		{Name: "pod", Value: "api-1"},
+		{Name: "status", Value: "200"},
+	}
+
+	assert.Equal(t, []mimirpb.LabelAdapter{
+		{Name: "__name__", Value: "http_requests_total"},
+		{Name: "job", Value: "api"},
+		{Name: "status", Value: "200"},
+	}, rules[0].OutputLabels(input))
+}
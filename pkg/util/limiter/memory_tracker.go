@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+type memoryTrackerCtxKey struct{}
+
+var (
+	memoryTrackerKey                    = &memoryTrackerCtxKey{}
+	MaxEstimatedMemoryPerQueryMsgFormat = globalerror.MaxEstimatedMemoryPerQuery.MessageWithPerTenantLimitConfig(
+		"the query exceeded the maximum estimated memory of the fetched series and chunks (limit: %d bytes)",
+		validation.MaxEstimatedMemoryPerQueryFlag,
+	)
+
+	sampleSize = int(unsafe.Sizeof(mimirpb.Sample{}))
+)
+
+// MemoryTracker accounts the estimated memory taken by the series and chunks fetched by a query
+// from ingesters and store-gateways, and fails the query once the configured limit is exceeded.
+type MemoryTracker struct {
+	bytes    atomic.Int64
+	maxBytes int
+}
+
+// NewMemoryTracker makes a new per-query memory tracker. The maxBytes limit is disabled if 0.
+func NewMemoryTracker(maxBytes int) *MemoryTracker {
+	return &MemoryTracker{maxBytes: maxBytes}
+}
+
+func AddMemoryTrackerToContext(ctx context.Context, tracker *MemoryTracker) context.Context {
+	return context.WithValue(ctx, memoryTrackerKey, tracker)
+}
+
+// MemoryTrackerFromContextWithFallback returns a MemoryTracker from the current context.
+// If there is not a MemoryTracker on the context it will return a new unlimited tracker.
+func MemoryTrackerFromContextWithFallback(ctx context.Context) *MemoryTracker {
+	t, ok := ctx.Value(memoryTrackerKey).(*MemoryTracker)
+	if !ok {
+		t = NewMemoryTracker(0)
+	}
+	return t
+}
+
+// Add accounts the input bytes and returns an error if the limit is exceeded.
+func (t *MemoryTracker) Add(bytes int) error {
+	total := t.bytes.Add(int64(bytes))
+	if t.maxBytes > 0 && total > int64(t.maxBytes) {
+		return fmt.Errorf(MaxEstimatedMemoryPerQueryMsgFormat, t.maxBytes)
+	}
+	return nil
+}
+
+// Bytes returns the estimated memory accounted so far.
+func (t *MemoryTracker) Bytes() int64 {
+	return t.bytes.Load()
+}
+
+// AddSeries accounts the estimated memory of the labels of the input series.
+func (t *MemoryTracker) AddSeries(seriesLabels []mimirpb.LabelAdapter) error {
+	size := 0
+	for _, l := range seriesLabels {
+		size += len(l.Name) + len(l.Value)
+	}
+	return t.Add(size)
+}
+
+// AddSamples accounts the estimated memory of the input number of decoded float samples.
+func (t *MemoryTracker) AddSamples(count int) error {
+	return t.Add(count * sampleSize)
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestMemoryTracker(t *testing.T) {
+	tracker := NewMemoryTracker(100)
+
+	require.NoError(t, tracker.AddSeries([]mimirpb.LabelAdapter{{Name: "__name__", Value: "metric"}}))
+	assert.Equal(t, int64(14), tracker.Bytes())
+
+	require.NoError(t, tracker.AddSamples(2))
+	assert.Equal(t, int64(14+2*sampleSize), tracker.Bytes())
+
+	require.NoError(t, tracker.Add(int(100-tracker.Bytes())))
+	assert.EqualError(t, tracker.Add(1), fmt.Sprintf(MaxEstimatedMemoryPerQueryMsgFormat, 100))
+}
+
+func TestMemoryTracker_Unlimited(t *testing.T) {
+	tracker := MemoryTrackerFromContextWithFallback(context.Background())
+
+	require.NoError(t, tracker.Add(1e9))
+	assert.Equal(t, int64(1e9), tracker.Bytes())
+
+	// The tracker is read back from the context.
+	ctx := AddMemoryTrackerToContext(context.Background(), tracker)
+	assert.Same(t, tracker, MemoryTrackerFromContextWithFallback(ctx))
+}
@@ -8,6 +8,7 @@ package util
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/go-kit/log/level"
@@ -16,7 +17,8 @@ import (
 )
 
 // GetFirstAddressOf returns the first IPv4 address of the supplied interface names, omitting any 169.254.x.x automatic private IPs if possible.
-func GetFirstAddressOf(names []string) (string, error) {
+// If enableInet6 is true, IPv6 global unicast addresses are considered too, but IPv4 addresses are still preferred.
+func GetFirstAddressOf(names []string, enableInet6 bool) (string, error) {
 	var ipAddr string
 	for _, name := range names {
 		inf, err := net.InterfaceByName(name)
@@ -33,7 +35,7 @@ func GetFirstAddressOf(names []string) (string, error) {
 			level.Warn(util_log.Logger).Log("msg", "no addresses found for interface", "inf", name, "err", err)
 			continue
 		}
-		if ip := filterIPs(addrs); ip != "" {
+		if ip := filterIPs(addrs, enableInet6); ip != "" {
 			ipAddr = ip
 		}
 		if strings.HasPrefix(ipAddr, `169.254.`) || ipAddr == "" {
@@ -50,9 +52,24 @@ func GetFirstAddressOf(names []string) (string, error) {
 	return ipAddr, nil
 }
 
+// GetInstanceAddr returns the address to use to register the instance in the ring. It's the configured
+// address if any, otherwise the first address of the supplied interface names.
+func GetInstanceAddr(configAddr string, names []string, enableInet6 bool) (string, error) {
+	if configAddr != "" {
+		return configAddr, nil
+	}
+	return GetFirstAddressOf(names, enableInet6)
+}
+
+// JoinHostPort combines the input address and port into a network address, enclosing IPv6 addresses in square brackets.
+func JoinHostPort(addr string, port int) string {
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
 // filterIPs attempts to return the first non automatic private IP (APIPA / 169.254.x.x) if possible, only returning APIPA if available and no other valid IP is found.
-func filterIPs(addrs []net.Addr) string {
-	var ipAddr string
+// If enableInet6 is true, the first IPv6 global unicast address is returned when no valid IPv4 address is found. Link-local IPv6 addresses are never returned.
+func filterIPs(addrs []net.Addr, enableInet6 bool) string {
+	var ipAddr, ipv6Addr string
 	for _, addr := range addrs {
 		if v, ok := addr.(*net.IPNet); ok {
 			if ip := v.IP.To4(); ip != nil {
@@ -60,8 +77,13 @@ func filterIPs(addrs []net.Addr) string {
 				if !strings.HasPrefix(ipAddr, `169.254.`) {
 					return ipAddr
 				}
+			} else if enableInet6 && ipv6Addr == "" && v.IP.IsGlobalUnicast() {
+				ipv6Addr = v.IP.String()
 			}
 		}
 	}
+	if ipv6Addr != "" {
+		return ipv6Addr
+	}
 	return ipAddr
 }
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterIPs(t *testing.T) {
+	parseAddrs := func(cidrs ...string) []net.Addr {
+		addrs := make([]net.Addr, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			ip, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ipNet.IP = ip
+			addrs = append(addrs, ipNet)
+		}
+		return addrs
+	}
+
+	tests := map[string]struct {
+		addrs       []net.Addr
+		enableInet6 bool
+		expected    string
+	}{
+		"IPv4 address": {
+			addrs:    parseAddrs("10.0.0.1/8"),
+			expected: "10.0.0.1",
+		},
+		"IPv4 address preferred over automatic private IP": {
+			addrs:    parseAddrs("169.254.1.1/16", "10.0.0.1/8"),
+			expected: "10.0.0.1",
+		},
+		"automatic private IP if no other IPv4 address": {
+			addrs:    parseAddrs("169.254.1.1/16", "2001:db8::1/64"),
+			expected: "169.254.1.1",
+		},
+		"IPv6 address ignored if IPv6 is disabled": {
+			addrs:    parseAddrs("2001:db8::1/64"),
+			expected: "",
+		},
+		"IPv6 address if IPv6 is enabled": {
+			addrs:       parseAddrs("fe80::1/64", "2001:db8::1/64"),
+			enableInet6: true,
+			expected:    "2001:db8::1",
+		},
+		"IPv4 address preferred over IPv6 address": {
+			addrs:       parseAddrs("2001:db8::1/64", "10.0.0.1/8"),
+			enableInet6: true,
+			expected:    "10.0.0.1",
+		},
+		"IPv6 address preferred over automatic private IP": {
+			addrs:       parseAddrs("169.254.1.1/16", "2001:db8::1/64"),
+			enableInet6: true,
+			expected:    "2001:db8::1",
+		},
+		"link-local IPv6 address never used": {
+			addrs:       parseAddrs("fe80::1/64"),
+			enableInet6: true,
+			expected:    "",
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, filterIPs(testData.addrs, testData.enableInet6))
+		})
+	}
+}
+
+func TestJoinHostPort(t *testing.T) {
+	assert.Equal(t, "1.2.3.4:9095", JoinHostPort("1.2.3.4", 9095))
+	assert.Equal(t, "[2001:db8::1]:9095", JoinHostPort("2001:db8::1", 9095))
+}
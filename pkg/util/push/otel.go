@@ -5,23 +5,30 @@ package push
 import (
 	"compress/gzip"
 	"context"
+	"encoding/hex"
 	"errors"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/tenant"
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheusremotewrite"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	conventions "go.opentelemetry.io/collector/semconv/v1.6.1"
 	"go.uber.org/multierr"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
@@ -36,12 +43,27 @@ const (
 
 	otelParseError = "otlp_parse_error"
 	maxErrMsgLen   = 1024
+
+	traceIDLabel = "trace_id"
+	spanIDLabel  = "span_id"
+
+	// nativeHistogramMaxSchema is the maximum schema supported by native histograms.
+	nativeHistogramMaxSchema = 8
 )
 
+// OTLPHandlerLimits are the per-tenant limits used by the OTLP handler.
+type OTLPHandlerLimits interface {
+	OTelExponentialHistogramsDownscalingEnabled(userID string) bool
+	OTelMinMaxSeriesEnabled(userID string) bool
+	OTelTranslationStrategy(userID string) string
+	CreatedTimestampsIngestionEnabled(userID string) bool
+}
+
 func OTLPHandler(
 	maxRecvMsgSize int,
 	sourceIPs *middleware.SourceIPExtractor,
 	allowSkipLabelNameValidation bool,
+	limits OTLPHandlerLimits,
 	reg prometheus.Registerer,
 	push Func,
 ) http.Handler {
@@ -114,17 +136,36 @@ func OTLPHandler(
 			return body, err
 		}
 
-		metrics, err := otelMetricsToTimeseries(ctx, discardedDueToOtelParseError, logger, otlpReq.Metrics())
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return body, err
+		}
+		// The metrics are renamed first, so that the series added from them are named after the translated names.
+		translateMetricNames(otlpReq.Metrics(), limits.OTelTranslationStrategy(userID))
+		if limits.OTelExponentialHistogramsDownscalingEnabled(userID) {
+			downscaleExponentialHistograms(otlpReq.Metrics())
+		}
+		if limits.OTelMinMaxSeriesEnabled(userID) {
+			addMinMaxGauges(otlpReq.Metrics())
+		}
+
+		// Metadata is extracted before converting the series, because metrics without data points
+		// are removed from the request: they only carry metadata and aren't dropped series.
+		metadata := otelMetricsToMetadata(otlpReq.Metrics())
+		removeMetricsWithoutDataPoints(otlpReq.Metrics())
+
+		metrics, err := otelMetricsToTimeseries(ctx, discardedDueToOtelParseError, logger, otlpReq.Metrics(), limits.CreatedTimestampsIngestionEnabled(userID))
 		if err != nil {
 			return body, err
 		}
 
 		req.Timeseries = metrics
+		req.Metadata = metadata
 		return body, nil
 	})
 }
 
-func otelMetricsToTimeseries(ctx context.Context, discardedDueToOtelParseError *prometheus.CounterVec, logger kitlog.Logger, md pmetric.Metrics) ([]mimirpb.PreallocTimeseries, error) {
+func otelMetricsToTimeseries(ctx context.Context, discardedDueToOtelParseError *prometheus.CounterVec, logger kitlog.Logger, md pmetric.Metrics, createdTimestampsEnabled bool) ([]mimirpb.PreallocTimeseries, error) {
 	tsMap, errs := prometheusremotewrite.FromMetrics(md, prometheusremotewrite.Settings{})
 
 	if errs != nil {
@@ -148,14 +189,485 @@ func otelMetricsToTimeseries(ctx context.Context, discardedDueToOtelParseError *
 		level.Warn(logger).Log("msg", "OTLP parse error", "err", parseErrs)
 	}
 
+	addGaugeExemplars(md, tsMap)
+
+	var createdTimestamps map[string]int64
+	if createdTimestampsEnabled {
+		createdTimestamps = otelCreatedTimestamps(md)
+	}
+
 	mimirTs := mimirpb.PreallocTimeseriesSliceFromPool()
-	for _, promTs := range tsMap {
-		mimirTs = append(mimirTs, promToMimirTimeseries(promTs))
+	for sig, promTs := range tsMap {
+		ts := promToMimirTimeseries(promTs)
+		ts.CreatedTimestampMs = createdTimestamps[sig]
+		mimirTs = append(mimirTs, ts)
 	}
 
 	return mimirTs, nil
 }
 
+// otelMetricsToMetadata returns the metadata of the input metrics, including the ones without data points.
+func otelMetricsToMetadata(md pmetric.Metrics) []*mimirpb.MetricMetadata {
+	var metadata []*mimirpb.MetricMetadata
+
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				if metric.Type() == pmetric.MetricTypeEmpty {
+					continue
+				}
+
+				metadata = append(metadata, &mimirpb.MetricMetadata{
+					Type:             otelMetricTypeToMimirMetricType(metric),
+					MetricFamilyName: prometheustranslator.BuildPromCompliantName(metric, ""),
+					Help:             metric.Description(),
+					Unit:             metric.Unit(),
+				})
+			}
+		}
+	}
+
+	return metadata
+}
+
+func otelMetricTypeToMimirMetricType(metric pmetric.Metric) mimirpb.MetricMetadata_MetricType {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		return mimirpb.GAUGE
+	case pmetric.MetricTypeSum:
+		if metric.Sum().IsMonotonic() {
+			return mimirpb.COUNTER
+		}
+		return mimirpb.GAUGE
+	case pmetric.MetricTypeHistogram, pmetric.MetricTypeExponentialHistogram:
+		return mimirpb.HISTOGRAM
+	case pmetric.MetricTypeSummary:
+		return mimirpb.SUMMARY
+	}
+	return mimirpb.UNKNOWN
+}
+
+// removeMetricsWithoutDataPoints removes from the input metrics the ones which only carry metadata,
+// so that they're not reported as parse errors by the OTLP translator.
+func removeMetricsWithoutDataPoints(md pmetric.Metrics) {
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			scopeMetricsSlice.At(j).Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					return metric.Gauge().DataPoints().Len() == 0
+				case pmetric.MetricTypeSum:
+					return metric.Sum().DataPoints().Len() == 0
+				case pmetric.MetricTypeHistogram:
+					return metric.Histogram().DataPoints().Len() == 0
+				case pmetric.MetricTypeExponentialHistogram:
+					return metric.ExponentialHistogram().DataPoints().Len() == 0
+				case pmetric.MetricTypeSummary:
+					return metric.Summary().DataPoints().Len() == 0
+				}
+				return false
+			})
+		}
+	}
+}
+
+// minMaxPoint is the min and max of the values observed by a histogram data point.
+type minMaxPoint struct {
+	attributes     pcommon.Map
+	startTimestamp pcommon.Timestamp
+	timestamp      pcommon.Timestamp
+	flags          pmetric.DataPointFlags
+	min, max       float64
+	hasMin, hasMax bool
+}
+
+// addMinMaxGauges adds the <name>_min and <name>_max gauges for each histogram and exponential histogram
+// whose data points carry the min and max of the observed values. The histograms are not downsampled,
+// while the downsampled blocks keep the min and max of the float series, so the companion gauges allow
+// long-range queries to show the peaks of the histograms.
+func addMinMaxGauges(md pmetric.Metrics) {
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+
+			// The gauges are appended to the metrics being iterated, so only the original metrics are iterated.
+			for k, numMetrics := 0, metricSlice.Len(); k < numMetrics; k++ {
+				metric := metricSlice.At(k)
+
+				var points []minMaxPoint
+				switch metric.Type() {
+				case pmetric.MetricTypeHistogram:
+					dataPoints := metric.Histogram().DataPoints()
+					for x := 0; x < dataPoints.Len(); x++ {
+						pt := dataPoints.At(x)
+						points = append(points, minMaxPoint{
+							attributes:     pt.Attributes(),
+							startTimestamp: pt.StartTimestamp(),
+							timestamp:      pt.Timestamp(),
+							flags:          pt.Flags(),
+							min:            pt.Min(),
+							max:            pt.Max(),
+							hasMin:         pt.HasMin(),
+							hasMax:         pt.HasMax(),
+						})
+					}
+				case pmetric.MetricTypeExponentialHistogram:
+					dataPoints := metric.ExponentialHistogram().DataPoints()
+					for x := 0; x < dataPoints.Len(); x++ {
+						pt := dataPoints.At(x)
+						points = append(points, minMaxPoint{
+							attributes:     pt.Attributes(),
+							startTimestamp: pt.StartTimestamp(),
+							timestamp:      pt.Timestamp(),
+							flags:          pt.Flags(),
+							min:            pt.Min(),
+							max:            pt.Max(),
+							hasMin:         pt.HasMin(),
+							hasMax:         pt.HasMax(),
+						})
+					}
+				default:
+					continue
+				}
+
+				appendMinMaxGauge(metricSlice, metric, "_min", points, func(p minMaxPoint) (float64, bool) { return p.min, p.hasMin })
+				appendMinMaxGauge(metricSlice, metric, "_max", points, func(p minMaxPoint) (float64, bool) { return p.max, p.hasMax })
+			}
+		}
+	}
+}
+
+// appendMinMaxGauge appends to metrics the gauge named after the source metric with the input suffix, holding
+// the value returned by get for each point. The gauge is not appended if no point has a value.
+//
+// The gauge is named after the Prometheus name of the source metric, so that the unit suffix, if any, comes
+// before the min and max suffixes (e.g. <name>_seconds_min rather than <name>_min_seconds): the unit suffix
+// isn't appended again when the gauge name is normalized, because the name already contains it.
+func appendMinMaxGauge(metrics pmetric.MetricSlice, source pmetric.Metric, suffix string, points []minMaxPoint, get func(minMaxPoint) (float64, bool)) {
+	var dataPoints pmetric.NumberDataPointSlice
+	created := false
+
+	for _, p := range points {
+		v, ok := get(p)
+		if !ok {
+			continue
+		}
+
+		if !created {
+			gauge := metrics.AppendEmpty()
+			gauge.SetName(prometheustranslator.BuildPromCompliantName(source, "") + suffix)
+			gauge.SetDescription(source.Description())
+			gauge.SetUnit(source.Unit())
+			dataPoints = gauge.SetEmptyGauge().DataPoints()
+			created = true
+		}
+
+		pt := dataPoints.AppendEmpty()
+		p.attributes.CopyTo(pt.Attributes())
+		pt.SetStartTimestamp(p.startTimestamp)
+		pt.SetTimestamp(p.timestamp)
+		pt.SetFlags(p.flags)
+		pt.SetDoubleValue(v)
+	}
+}
+
+// downscaleExponentialHistograms reduces the scale of the exponential histogram data points having a scale greater
+// than the maximum schema supported by native histograms, merging their buckets. The zero bucket is not affected.
+func downscaleExponentialHistograms(md pmetric.Metrics) {
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				if metric.Type() != pmetric.MetricTypeExponentialHistogram {
+					continue
+				}
+
+				dataPoints := metric.ExponentialHistogram().DataPoints()
+				for x := 0; x < dataPoints.Len(); x++ {
+					pt := dataPoints.At(x)
+					if pt.Scale() <= nativeHistogramMaxSchema {
+						continue
+					}
+
+					scaleDown := pt.Scale() - nativeHistogramMaxSchema
+					downscaleExponentialHistogramBuckets(pt.Positive(), scaleDown)
+					downscaleExponentialHistogramBuckets(pt.Negative(), scaleDown)
+					pt.SetScale(nativeHistogramMaxSchema)
+				}
+			}
+		}
+	}
+}
+
+// downscaleExponentialHistogramBuckets merges the input buckets so that they match a scale reduced by scaleDown.
+// Reducing the scale by 1 merges each pair of adjacent buckets, because the bucket with index i at the new scale
+// covers the buckets with index 2i and 2i+1 at the old scale.
+func downscaleExponentialHistogramBuckets(buckets pmetric.ExponentialHistogramDataPointBuckets, scaleDown int32) {
+	counts := buckets.BucketCounts()
+	offset := buckets.Offset()
+	newOffset := offset >> scaleDown
+
+	if counts.Len() == 0 {
+		buckets.SetOffset(newOffset)
+		return
+	}
+
+	lastIdx := offset + int32(counts.Len()) - 1
+	merged := make([]uint64, (lastIdx>>scaleDown)-newOffset+1)
+	for i := 0; i < counts.Len(); i++ {
+		merged[((offset+int32(i))>>scaleDown)-newOffset] += counts.At(i)
+	}
+
+	buckets.SetOffset(newOffset)
+	counts.FromRaw(merged)
+}
+
+// addGaugeExemplars attaches the exemplars of gauge data points to the converted series, because the
+// OTLP translator only does it for sums and histograms. The series of each data point is found by its
+// signature in tsMap, built the same way as the translator does.
+func addGaugeExemplars(md pmetric.Metrics, tsMap map[string]*prompb.TimeSeries) {
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		resource := resourceMetricsSlice.At(i).Resource()
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				if metric.Type() != pmetric.MetricTypeGauge {
+					continue
+				}
+
+				name := prometheustranslator.BuildPromCompliantName(metric, "")
+				dataPoints := metric.Gauge().DataPoints()
+				for x := 0; x < dataPoints.Len(); x++ {
+					pt := dataPoints.At(x)
+					if pt.Exemplars().Len() == 0 {
+						continue
+					}
+
+					if ts, ok := tsMap[otelSeriesSignature(pmetric.MetricTypeGauge, resource, pt.Attributes(), name)]; ok {
+						ts.Exemplars = append(ts.Exemplars, otelExemplarsToProm(pt.Exemplars())...)
+					}
+				}
+			}
+		}
+	}
+}
+
+// otelSeriesSignature returns the signature of the series named name converted from a data point with the input
+// attributes, as computed by the translator with the default settings to key the converted series.
+func otelSeriesSignature(metricType pmetric.MetricType, resource pcommon.Resource, attributes pcommon.Map, name string) string {
+	lbls := map[string]string{}
+
+	// The attributes are merged in order, so that the values of the attributes whose names collide once
+	// normalized are joined the same way as the translator does.
+	names := make([]string, 0, attributes.Len())
+	attributes.Range(func(key string, _ pcommon.Value) bool {
+		names = append(names, key)
+		return true
+	})
+	sort.Strings(names)
+	for _, key := range names {
+		value, _ := attributes.Get(key)
+		normalized := prometheustranslator.NormalizeLabel(key)
+		if existing, ok := lbls[normalized]; ok {
+			lbls[normalized] = existing + ";" + value.AsString()
+		} else {
+			lbls[normalized] = value.AsString()
+		}
+	}
+
+	if serviceName, ok := resource.Attributes().Get(conventions.AttributeServiceName); ok {
+		job := serviceName.AsString()
+		if serviceNamespace, ok := resource.Attributes().Get(conventions.AttributeServiceNamespace); ok {
+			job = serviceNamespace.AsString() + "/" + job
+		}
+		lbls[model.JobLabel] = job
+	}
+	if instance, ok := resource.Attributes().Get(conventions.AttributeServiceInstanceID); ok {
+		lbls[model.InstanceLabel] = instance.AsString()
+	}
+	lbls[model.MetricNameLabel] = name
+
+	names = names[:0]
+	for n := range lbls {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	sig := strings.Builder{}
+	sig.WriteString(metricType.String())
+	for _, n := range names {
+		sig.WriteString("-")
+		sig.WriteString(n)
+		sig.WriteString("-")
+		sig.WriteString(lbls[n])
+	}
+	return sig.String()
+}
+
+// otelCreatedTimestamps returns the created timestamps of the converted series, by series signature, taken from
+// the start timestamps of the cumulative data points of monotonic sums, histograms and exponential histograms.
+// The series of each data point are found by converting the data point alone, because a data point is converted
+// to several series for histograms.
+func otelCreatedTimestamps(md pmetric.Metrics) map[string]int64 {
+	createdTimestamps := map[string]int64{}
+	add := func(converted map[string]*prompb.TimeSeries, start pcommon.Timestamp) {
+		createdTimestamp := timestamp.FromTime(start.AsTime())
+		for sig := range converted {
+			// Keep the earliest created timestamp if the series has multiple data points.
+			if existing, ok := createdTimestamps[sig]; !ok || createdTimestamp < existing {
+				createdTimestamps[sig] = createdTimestamp
+			}
+		}
+	}
+
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		resource := resourceMetricsSlice.At(i).Resource()
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metricSlice := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+
+				switch metric.Type() {
+				case pmetric.MetricTypeSum:
+					sum := metric.Sum()
+					if !sum.IsMonotonic() || sum.AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+						continue
+					}
+					dataPoints := sum.DataPoints()
+					for x := 0; x < dataPoints.Len(); x++ {
+						pt := dataPoints.At(x)
+						if pt.StartTimestamp() == 0 {
+							continue
+						}
+						add(convertSingleDataPoint(resource, metric, func(single pmetric.Metric) {
+							singleSum := single.SetEmptySum()
+							singleSum.SetIsMonotonic(true)
+							singleSum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+							pt.CopyTo(singleSum.DataPoints().AppendEmpty())
+						}), pt.StartTimestamp())
+					}
+
+				case pmetric.MetricTypeHistogram:
+					if metric.Histogram().AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+						continue
+					}
+					dataPoints := metric.Histogram().DataPoints()
+					for x := 0; x < dataPoints.Len(); x++ {
+						pt := dataPoints.At(x)
+						if pt.StartTimestamp() == 0 {
+							continue
+						}
+						add(convertSingleDataPoint(resource, metric, func(single pmetric.Metric) {
+							singleHistogram := single.SetEmptyHistogram()
+							singleHistogram.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+							pt.CopyTo(singleHistogram.DataPoints().AppendEmpty())
+						}), pt.StartTimestamp())
+					}
+
+				case pmetric.MetricTypeExponentialHistogram:
+					if metric.ExponentialHistogram().AggregationTemporality() != pmetric.AggregationTemporalityCumulative {
+						continue
+					}
+					dataPoints := metric.ExponentialHistogram().DataPoints()
+					for x := 0; x < dataPoints.Len(); x++ {
+						pt := dataPoints.At(x)
+						if pt.StartTimestamp() == 0 {
+							continue
+						}
+						add(convertSingleDataPoint(resource, metric, func(single pmetric.Metric) {
+							singleHistogram := single.SetEmptyExponentialHistogram()
+							singleHistogram.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+							pt.CopyTo(singleHistogram.DataPoints().AppendEmpty())
+						}), pt.StartTimestamp())
+					}
+				}
+			}
+		}
+	}
+
+	return createdTimestamps
+}
+
+// convertSingleDataPoint converts the input metric holding only the data point set by setDataPoint, and returns
+// the converted series. The labels of the converted series are built exactly the same way as the translator does
+// when converting the whole request, so the series signatures can be used to find the series of the data point.
+func convertSingleDataPoint(resource pcommon.Resource, metric pmetric.Metric, setDataPoint func(single pmetric.Metric)) map[string]*prompb.TimeSeries {
+	single := pmetric.NewMetrics()
+	singleResourceMetrics := single.ResourceMetrics().AppendEmpty()
+	resource.CopyTo(singleResourceMetrics.Resource())
+	singleMetric := singleResourceMetrics.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	singleMetric.SetName(metric.Name())
+	singleMetric.SetUnit(metric.Unit())
+	setDataPoint(singleMetric)
+
+	converted, _ := prometheusremotewrite.FromMetrics(single, prometheusremotewrite.Settings{DisableTargetInfo: true})
+	return converted
+}
+
+// otelExemplarsToProm converts OTLP exemplars to Prometheus exemplars, storing the trace and span IDs
+// as exemplar labels. Filtered attributes are added as labels too, unless the exemplar labels
+// would exceed the maximum length allowed.
+func otelExemplarsToProm(exemplars pmetric.ExemplarSlice) []prompb.Exemplar {
+	promExemplars := make([]prompb.Exemplar, 0, exemplars.Len())
+
+	for i := 0; i < exemplars.Len(); i++ {
+		exemplar := exemplars.At(i)
+		labelsLen := 0
+
+		promExemplar := prompb.Exemplar{
+			Timestamp: timestamp.FromTime(exemplar.Timestamp().AsTime()),
+		}
+		switch exemplar.ValueType() {
+		case pmetric.ExemplarValueTypeInt:
+			promExemplar.Value = float64(exemplar.IntValue())
+		case pmetric.ExemplarValueTypeDouble:
+			promExemplar.Value = exemplar.DoubleValue()
+		}
+
+		if traceID := exemplar.TraceID(); !traceID.IsEmpty() {
+			value := hex.EncodeToString(traceID[:])
+			labelsLen += utf8.RuneCountInString(traceIDLabel) + utf8.RuneCountInString(value)
+			promExemplar.Labels = append(promExemplar.Labels, prompb.Label{Name: traceIDLabel, Value: value})
+		}
+		if spanID := exemplar.SpanID(); !spanID.IsEmpty() {
+			value := hex.EncodeToString(spanID[:])
+			labelsLen += utf8.RuneCountInString(spanIDLabel) + utf8.RuneCountInString(value)
+			promExemplar.Labels = append(promExemplar.Labels, prompb.Label{Name: spanIDLabel, Value: value})
+		}
+
+		var attributeLabels []prompb.Label
+		exemplar.FilteredAttributes().Range(func(key string, value pcommon.Value) bool {
+			val := value.AsString()
+			labelsLen += utf8.RuneCountInString(key) + utf8.RuneCountInString(val)
+			attributeLabels = append(attributeLabels, prompb.Label{Name: key, Value: val})
+			return true
+		})
+		if labelsLen <= validation.ExemplarMaxLabelSetLength {
+			promExemplar.Labels = append(promExemplar.Labels, attributeLabels...)
+		}
+
+		promExemplars = append(promExemplars, promExemplar)
+	}
+
+	return promExemplars
+}
+
 func promToMimirTimeseries(promTs *prompb.TimeSeries) mimirpb.PreallocTimeseries {
 	labels := make([]mimirpb.LabelAdapter, 0, len(promTs.Labels))
 	for _, label := range promTs.Labels {
@@ -205,7 +717,7 @@ func promToMimirTimeseries(promTs *prompb.TimeSeries) mimirpb.PreallocTimeseries
 }
 
 func promToMimirHistogram(h *prompb.Histogram) mimirpb.Histogram {
-	pSpans := make([]mimirpb.BucketSpan, len(h.PositiveSpans))
+	pSpans := make([]mimirpb.BucketSpan, 0, len(h.PositiveSpans))
 	for _, span := range h.PositiveSpans {
 		pSpans = append(
 			pSpans, mimirpb.BucketSpan{
@@ -214,7 +726,7 @@ func promToMimirHistogram(h *prompb.Histogram) mimirpb.Histogram {
 			},
 		)
 	}
-	nSpans := make([]mimirpb.BucketSpan, len(h.NegativeSpans))
+	nSpans := make([]mimirpb.BucketSpan, 0, len(h.NegativeSpans))
 	for _, span := range h.NegativeSpans {
 		nSpans = append(
 			nSpans, mimirpb.BucketSpan{
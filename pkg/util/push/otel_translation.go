@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/open-telemetry/opentelemetry-collector-contrib/blob/v0.73.0/pkg/translator/prometheus/normalize_name.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The OpenTelemetry Authors.
+
+package push
+
+import (
+	"strings"
+	"unicode"
+
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// The map to translate OTLP units to Prometheus units.
+var otelUnitToPromUnit = map[string]string{
+	// Time
+	"d":   "days",
+	"h":   "hours",
+	"min": "minutes",
+	"s":   "seconds",
+	"ms":  "milliseconds",
+	"us":  "microseconds",
+	"ns":  "nanoseconds",
+
+	// Bytes
+	"By":   "bytes",
+	"KiBy": "kibibytes",
+	"MiBy": "mebibytes",
+	"GiBy": "gibibytes",
+	"TiBy": "tibibytes",
+	"KBy":  "kilobytes",
+	"MBy":  "megabytes",
+	"GBy":  "gigabytes",
+	"TBy":  "terabytes",
+	"B":    "bytes",
+	"KB":   "kilobytes",
+	"MB":   "megabytes",
+	"GB":   "gigabytes",
+	"TB":   "terabytes",
+
+	// SI
+	"m": "meters",
+	"V": "volts",
+	"A": "amperes",
+	"J": "joules",
+	"W": "watts",
+	"g": "grams",
+
+	// Misc
+	"Cel": "celsius",
+	"Hz":  "hertz",
+	"1":   "",
+	"%":   "percent",
+	"$":   "dollars",
+}
+
+// The map to translate the OTLP "per" units to Prometheus units.
+var otelPerUnitToPromPerUnit = map[string]string{
+	"s":  "second",
+	"m":  "minute",
+	"h":  "hour",
+	"d":  "day",
+	"w":  "week",
+	"mo": "month",
+	"y":  "year",
+}
+
+// translateMetricNames renames the input metrics according to the input translation strategy, before they're
+// converted to Prometheus series. The metric names are left unchanged by the underscore escaping without suffixes
+// strategy, because the conversion already replaces the characters not supported by Prometheus.
+func translateMetricNames(md pmetric.Metrics, strategy string) {
+	if strategy != validation.OTelTranslationStrategyUnderscoreEscapingWithSuffixes {
+		return
+	}
+
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			metrics := scopeMetricsSlice.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				metric.SetName(metricNameWithSuffixes(metric))
+			}
+		}
+	}
+}
+
+// metricNameWithSuffixes returns the name of the input metric following the Prometheus naming conventions:
+// the characters not supported by Prometheus are replaced with underscores, the unit is appended to the name,
+// and the _total suffix is appended to the name of counters.
+func metricNameWithSuffixes(metric pmetric.Metric) string {
+	// Split the metric name in tokens, removing all the non-alphanumeric characters.
+	nameTokens := strings.FieldsFunc(metric.Name(), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+
+	// Split the unit at the '/', if any. The main and "per" units are appended if not blank, not containing
+	// '{}' and not present in the metric name already.
+	unitTokens := strings.SplitN(metric.Unit(), "/", 2)
+
+	mainUnit := strings.TrimSpace(unitTokens[0])
+	if mainUnit != "" && !strings.ContainsAny(mainUnit, "{}") {
+		promUnit := prometheustranslator.CleanUpString(getOrDefault(otelUnitToPromUnit, mainUnit))
+		if promUnit != "" && !containsToken(nameTokens, promUnit) {
+			nameTokens = append(nameTokens, promUnit)
+		}
+	}
+
+	if len(unitTokens) > 1 {
+		perUnit := strings.TrimSpace(unitTokens[1])
+		if perUnit != "" && !strings.ContainsAny(perUnit, "{}") {
+			promPerUnit := prometheustranslator.CleanUpString(getOrDefault(otelPerUnitToPromPerUnit, perUnit))
+			if promPerUnit != "" && !containsToken(nameTokens, promPerUnit) {
+				nameTokens = append(nameTokens, "per", promPerUnit)
+			}
+		}
+	}
+
+	if metric.Type() == pmetric.MetricTypeSum && metric.Sum().IsMonotonic() {
+		nameTokens = append(removeToken(nameTokens, "total"), "total")
+	}
+
+	// Some OTLP receivers use the unit "1" for counters of objects, so the _ratio suffix is only appended to gauges.
+	if metric.Unit() == "1" && metric.Type() == pmetric.MetricTypeGauge {
+		nameTokens = append(removeToken(nameTokens, "ratio"), "ratio")
+	}
+
+	name := strings.Join(nameTokens, "_")
+
+	// The metric name can't start with a digit.
+	if name != "" && unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+
+	return name
+}
+
+func getOrDefault(m map[string]string, key string) string {
+	if value, ok := m[key]; ok {
+		return value
+	}
+	return key
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func removeToken(tokens []string, token string) []string {
+	result := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t != token {
+			result = append(result, t)
+		}
+	}
+	return result
+}
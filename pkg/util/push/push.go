@@ -9,10 +9,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 
 	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
 
@@ -48,7 +50,17 @@ func Handler(
 	push Func,
 ) http.Handler {
 	return handler(maxRecvMsgSize, sourceIPs, allowSkipLabelNameValidation, push, func(ctx context.Context, r *http.Request, maxRecvMsgSize int, dst []byte, req *mimirpb.PreallocWriteRequest) ([]byte, error) {
-		res, err := util.ParseProtoReader(ctx, r.Body, int(r.ContentLength), maxRecvMsgSize, dst, req, util.RawSnappy)
+		v2, err := isRemoteWriteV2(r.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, httpgrpc.Errorf(http.StatusUnsupportedMediaType, err.Error())
+		}
+
+		var msg proto.Message = req
+		if v2 {
+			msg = &writeRequestV2{dst: req}
+		}
+
+		res, err := util.ParseProtoReader(ctx, r.Body, int(r.ContentLength), maxRecvMsgSize, dst, msg, util.RawSnappy)
 		if errors.Is(err, util.MsgSizeTooLargeErr{}) {
 			err = distributorMaxWriteMessageSizeErr{actual: int(r.ContentLength), limit: maxRecvMsgSize}
 		}
@@ -77,6 +89,9 @@ func handler(maxRecvMsgSize int,
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		logger := log.WithContext(ctx, log.Logger)
+		// The source address is the address of the peer, and not the one from the forwarding headers,
+		// which can be spoofed by the clients.
+		sourceAddress, _, _ := net.SplitHostPort(r.RemoteAddr)
 		if sourceIPs != nil {
 			source := sourceIPs.Get(r)
 			if source != "" {
@@ -115,7 +130,13 @@ func handler(maxRecvMsgSize int,
 			return &req.WriteRequest, cleanup, nil
 		}
 		req := newRequest(supplier)
-		if _, err := push(ctx, req); err != nil {
+		req.header = r.Header
+		req.sourceAddress = sourceAddress
+		_, err := push(ctx, req)
+		if v2, _ := isRemoteWriteV2(r.Header.Get("Content-Type")); v2 {
+			setRemoteWriteV2WrittenHeaders(w.Header(), req)
+		}
+		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				http.Error(w, err.Error(), statusClientClosedRequest)
 				level.Warn(logger).Log("msg", "push request canceled", "err", err)
@@ -13,24 +13,29 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/snappy"
 	"github.com/grafana/dskit/tenant"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
 	"github.com/prometheus/prometheus/storage/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
+	"go.opentelemetry.io/collector/featuregate"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/util/test"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 func TestHandler_remoteWrite(t *testing.T) {
@@ -159,7 +164,7 @@ func TestHandlerOTLPPush(t *testing.T) {
 				req.Header.Set("Content-Encoding", tt.encoding)
 			}
 
-			handler := OTLPHandler(tt.maxMsgSize, nil, false, nil, tt.verifyFunc)
+			handler := OTLPHandler(tt.maxMsgSize, nil, false, otlpLimitsMock{}, nil, tt.verifyFunc)
 
 			resp := httptest.NewRecorder()
 			handler.ServeHTTP(resp, req)
@@ -214,7 +219,7 @@ func TestHandler_otlpDroppedMetricsPanic(t *testing.T) {
 
 	req := createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
 	resp := httptest.NewRecorder()
-	handler := OTLPHandler(100000, nil, false, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+	handler := OTLPHandler(100000, nil, false, otlpLimitsMock{}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
 		request, err := pushReq.WriteRequest()
 		assert.NoError(t, err)
 		assert.Len(t, request.Timeseries, 3)
@@ -254,7 +259,7 @@ func TestHandler_otlpDroppedMetricsPanic2(t *testing.T) {
 
 	req := createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
 	resp := httptest.NewRecorder()
-	handler := OTLPHandler(100000, nil, false, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+	handler := OTLPHandler(100000, nil, false, otlpLimitsMock{}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
 		request, err := pushReq.WriteRequest()
 		assert.NoError(t, err)
 		assert.Len(t, request.Timeseries, 2)
@@ -280,7 +285,7 @@ func TestHandler_otlpDroppedMetricsPanic2(t *testing.T) {
 
 	req = createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
 	resp = httptest.NewRecorder()
-	handler = OTLPHandler(100000, nil, false, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+	handler = OTLPHandler(100000, nil, false, otlpLimitsMock{}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
 		request, err := pushReq.WriteRequest()
 		assert.NoError(t, err)
 		assert.Len(t, request.Timeseries, 10) // 6 buckets (including +Inf) + 2 sum/count + 2 from the first case
@@ -292,6 +297,165 @@ func TestHandler_otlpDroppedMetricsPanic2(t *testing.T) {
 	assert.Equal(t, 200, resp.Code)
 }
 
+func TestHandler_otlpExemplars(t *testing.T) {
+	traceID := pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	spanID := pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8}
+	now := time.Now()
+
+	addExemplar := func(exemplars pmetric.ExemplarSlice, value float64) {
+		exemplar := exemplars.AppendEmpty()
+		exemplar.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		exemplar.SetDoubleValue(value)
+		exemplar.SetTraceID(traceID)
+		exemplar.SetSpanID(spanID)
+	}
+
+	md := pmetric.NewMetrics()
+	resourceMetrics := md.ResourceMetrics().AppendEmpty()
+	resourceMetrics.Resource().Attributes().PutStr("service.name", "service")
+	resourceMetrics.Resource().Attributes().PutStr("service.namespace", "namespace")
+	resourceMetrics.Resource().Attributes().PutStr("service.instance.id", "instance")
+	metrics := resourceMetrics.ScopeMetrics().AppendEmpty().Metrics()
+
+	gauge := metrics.AppendEmpty()
+	gauge.SetName("gauge")
+	gaugePoints := gauge.SetEmptyGauge().DataPoints()
+	for _, route := range []string{"/a", "/b"} {
+		gaugePoint := gaugePoints.AppendEmpty()
+		gaugePoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		gaugePoint.SetDoubleValue(1)
+		// The attribute names collide once normalized, so their values are joined.
+		gaugePoint.Attributes().PutStr("http.route", route)
+		gaugePoint.Attributes().PutStr("http_route", "/c")
+		addExemplar(gaugePoint.Exemplars(), 1)
+	}
+
+	sum := metrics.AppendEmpty()
+	sum.SetName("sum")
+	sum.SetEmptySum().SetIsMonotonic(true)
+	sum.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	sumPoint := sum.Sum().DataPoints().AppendEmpty()
+	sumPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+	sumPoint.SetDoubleValue(2)
+	addExemplar(sumPoint.Exemplars(), 2)
+
+	histogram := metrics.AppendEmpty()
+	histogram.SetName("exponential_histogram")
+	histogram.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	histogramPoint := histogram.ExponentialHistogram().DataPoints().AppendEmpty()
+	histogramPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+	histogramPoint.SetCount(2)
+	histogramPoint.SetSum(6)
+	histogramPoint.Positive().BucketCounts().FromRaw([]uint64{1, 1})
+	addExemplar(histogramPoint.Exemplars(), 3)
+
+	expectedLabels := []mimirpb.LabelAdapter{
+		{Name: "trace_id", Value: "0102030405060708090a0b0c0d0e0f10"},
+		{Name: "span_id", Value: "0102030405060708"},
+	}
+
+	req := createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	resp := httptest.NewRecorder()
+	handler := OTLPHandler(100000, nil, false, otlpLimitsMock{}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+		request, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+
+		exemplarValues := map[string]float64{}
+		for _, series := range request.Timeseries {
+			lbls := mimirpb.FromLabelAdaptersToLabels(series.Labels)
+			name := lbls.Get(model.MetricNameLabel)
+			if name == "target_info" {
+				continue
+			}
+			if name == "gauge" {
+				name += lbls.Get("http_route")
+			}
+
+			require.Len(t, series.Exemplars, 1, name)
+			assert.Equal(t, expectedLabels, series.Exemplars[0].Labels)
+			assert.Equal(t, now.UnixMilli(), series.Exemplars[0].TimestampMs)
+			exemplarValues[name] = series.Exemplars[0].Value
+		}
+		assert.Equal(t, map[string]float64{"gauge/a;/c": 1, "gauge/b;/c": 1, "sum": 2, "exponential_histogram": 3}, exemplarValues)
+
+		pushReq.CleanUp()
+		return &mimirpb.WriteResponse{}, nil
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestHandler_otlpSummaries(t *testing.T) {
+	now := time.Now()
+
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+	summary := metrics.AppendEmpty()
+	summary.SetName("latency")
+	summary.SetDescription("Requests latency.")
+	summary.SetUnit("s")
+	summaryPoints := summary.SetEmptySummary().DataPoints()
+
+	addPoint := func(route string, noRecordedValue bool) {
+		point := summaryPoints.AppendEmpty()
+		point.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		point.Attributes().PutStr("route", route)
+		point.SetCount(10)
+		point.SetSum(5)
+		point.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(noRecordedValue))
+
+		quantile := point.QuantileValues().AppendEmpty()
+		quantile.SetQuantile(0.5)
+		quantile.SetValue(0.4)
+		quantile = point.QuantileValues().AppendEmpty()
+		quantile.SetQuantile(0.99)
+		quantile.SetValue(0.9)
+	}
+	addPoint("/api", false)
+	addPoint("/stale", true)
+
+	req := createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	resp := httptest.NewRecorder()
+	handler := OTLPHandler(100000, nil, false, otlpLimitsMock{}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+		request, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+
+		values := map[string]float64{}
+		for _, series := range request.Timeseries {
+			lbls := mimirpb.FromLabelAdaptersToLabels(series.Labels)
+			if lbls.Get(model.MetricNameLabel) == "target_info" {
+				continue
+			}
+
+			require.Len(t, series.Samples, 1, lbls.String())
+			assert.Equal(t, now.UnixMilli(), series.Samples[0].TimestampMs)
+
+			if lbls.Get("route") == "/stale" {
+				assert.True(t, value.IsStaleNaN(series.Samples[0].Value), lbls.String())
+				continue
+			}
+			values[lbls.String()] = series.Samples[0].Value
+		}
+
+		assert.Equal(t, map[string]float64{
+			`{__name__="latency", quantile="0.5", route="/api"}`:  0.4,
+			`{__name__="latency", quantile="0.99", route="/api"}`: 0.9,
+			`{__name__="latency_sum", route="/api"}`:              5,
+			`{__name__="latency_count", route="/api"}`:            10,
+		}, values)
+
+		assert.Equal(t, []*mimirpb.MetricMetadata{
+			{Type: mimirpb.SUMMARY, MetricFamilyName: "latency", Help: "Requests latency.", Unit: "s"},
+		}, request.Metadata)
+
+		pushReq.CleanUp()
+		return &mimirpb.WriteResponse{}, nil
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
 func TestHandler_otlpWriteRequestTooBigWithCompression(t *testing.T) {
 
 	// createOTLPRequest will create a request which is BIGGER with compression (37 vs 58 bytes).
@@ -309,7 +473,7 @@ func TestHandler_otlpWriteRequestTooBigWithCompression(t *testing.T) {
 
 	resp := httptest.NewRecorder()
 
-	handler := OTLPHandler(140, nil, false, nil, readBodyPushFunc(t))
+	handler := OTLPHandler(140, nil, false, otlpLimitsMock{}, nil, readBodyPushFunc(t))
 	handler.ServeHTTP(resp, req)
 	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
 	body, err := io.ReadAll(resp.Body)
@@ -326,6 +490,45 @@ func TestHandler_mimirWriteRequest(t *testing.T) {
 	assert.Equal(t, 200, resp.Code)
 }
 
+func TestHandler_RequestHeaderAndSourceAddress(t *testing.T) {
+	tests := map[string]struct {
+		sourceIPs             *middleware.SourceIPExtractor
+		expectedSourceAddress string
+	}{
+		"should use the remote address if the source IPs extraction is disabled": {
+			expectedSourceAddress: "10.0.0.1",
+		},
+		"should use the remote address if the source IPs extraction is enabled, since the forwarding headers can be spoofed": {
+			sourceIPs: func() *middleware.SourceIPExtractor {
+				sourceIPs, err := middleware.NewSourceIPs("", "")
+				require.NoError(t, err)
+				return sourceIPs
+			}(),
+			expectedSourceAddress: "10.0.0.1",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := createRequest(t, createPrometheusRemoteWriteProtobuf(t))
+			req.RemoteAddr = "10.0.0.1:12345"
+			req.Header.Set("X-Forwarded-For", "192.168.0.1")
+			req.Header.Set("X-Agent-ID", "agent-1")
+
+			resp := httptest.NewRecorder()
+			handler := Handler(100000, tc.sourceIPs, false, func(_ context.Context, pushReq *Request) (*mimirpb.WriteResponse, error) {
+				defer pushReq.CleanUp()
+				assert.Equal(t, "agent-1", pushReq.Header("X-Agent-ID"))
+				assert.Equal(t, "", pushReq.Header("X-Missing"))
+				assert.Equal(t, tc.expectedSourceAddress, pushReq.SourceAddress())
+				return &mimirpb.WriteResponse{}, nil
+			})
+			handler.ServeHTTP(resp, req)
+			assert.Equal(t, 200, resp.Code)
+		})
+	}
+}
+
 func TestHandler_contextCanceledRequest(t *testing.T) {
 	req := createRequest(t, createMimirWriteRequestProtobuf(t, false))
 	resp := httptest.NewRecorder()
@@ -472,6 +675,29 @@ func readBodyPushFunc(t *testing.T) Func {
 	}
 }
 
+type otlpLimitsMock struct {
+	exponentialHistogramsDownscalingEnabled bool
+	minMaxSeriesEnabled                     bool
+	translationStrategy                     string
+	createdTimestampsIngestionEnabled       bool
+}
+
+func (o otlpLimitsMock) OTelExponentialHistogramsDownscalingEnabled(string) bool {
+	return o.exponentialHistogramsDownscalingEnabled
+}
+
+func (o otlpLimitsMock) OTelMinMaxSeriesEnabled(string) bool {
+	return o.minMaxSeriesEnabled
+}
+
+func (o otlpLimitsMock) OTelTranslationStrategy(string) string {
+	return o.translationStrategy
+}
+
+func (o otlpLimitsMock) CreatedTimestampsIngestionEnabled(string) bool {
+	return o.createdTimestampsIngestionEnabled
+}
+
 func createRequest(t testing.TB, protobuf []byte) *http.Request {
 	t.Helper()
 	inoutBytes := snappy.Encode(nil, protobuf)
@@ -656,3 +882,444 @@ func TestHandler_ErrorTranslation(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_otlpMetadataOnly(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+	counter := metrics.AppendEmpty()
+	counter.SetName("requests")
+	counter.SetDescription("Total number of requests.")
+	counter.SetUnit("1")
+	counter.SetEmptySum().SetIsMonotonic(true)
+	counter.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+	histogram := metrics.AppendEmpty()
+	histogram.SetName("latency")
+	histogram.SetDescription("Requests latency.")
+	histogram.SetUnit("s")
+	histogram.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+	req := createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	resp := httptest.NewRecorder()
+	handler := OTLPHandler(100000, nil, false, otlpLimitsMock{}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+		request, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+
+		assert.Empty(t, request.Timeseries)
+		assert.Equal(t, []*mimirpb.MetricMetadata{
+			{Type: mimirpb.COUNTER, MetricFamilyName: "requests", Help: "Total number of requests.", Unit: "1"},
+			{Type: mimirpb.HISTOGRAM, MetricFamilyName: "latency", Help: "Requests latency.", Unit: "s"},
+		}, request.Metadata)
+
+		pushReq.CleanUp()
+		return &mimirpb.WriteResponse{}, nil
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestHandler_otlpExponentialHistogramsDownscaling(t *testing.T) {
+	now := time.Now()
+
+	createRequest := func() *http.Request {
+		md := pmetric.NewMetrics()
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+		gauge := metrics.AppendEmpty()
+		gauge.SetName("gauge")
+		gaugePoint := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+		gaugePoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		gaugePoint.SetDoubleValue(1)
+
+		histogram := metrics.AppendEmpty()
+		histogram.SetName("histogram")
+		histogram.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		point := histogram.ExponentialHistogram().DataPoints().AppendEmpty()
+		point.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		point.SetScale(10)
+		point.SetCount(18)
+		point.SetSum(30)
+		point.SetZeroCount(3)
+		point.Positive().SetOffset(-3)
+		point.Positive().BucketCounts().FromRaw([]uint64{1, 2, 3, 4, 5})
+
+		return createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	}
+
+	tests := map[string]struct {
+		downscalingEnabled bool
+		expectedHistograms []mimirpb.Histogram
+	}{
+		"exponential histograms with an unsupported scale are dropped when downscaling is disabled": {
+			downscalingEnabled: false,
+		},
+		"exponential histograms with an unsupported scale are downscaled when downscaling is enabled": {
+			downscalingEnabled: true,
+			expectedHistograms: []mimirpb.Histogram{{
+				Count:          &mimirpb.Histogram_CountInt{CountInt: 18},
+				Sum:            30,
+				Schema:         8,
+				ZeroThreshold:  1e-128,
+				ZeroCount:      &mimirpb.Histogram_ZeroCountInt{ZeroCountInt: 3},
+				NegativeSpans:  []mimirpb.BucketSpan{},
+				PositiveSpans:  []mimirpb.BucketSpan{{Offset: 0, Length: 2}},
+				PositiveDeltas: []int64{6, 3},
+				Timestamp:      now.UnixMilli(),
+			}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			handler := OTLPHandler(100000, nil, false, otlpLimitsMock{exponentialHistogramsDownscalingEnabled: tc.downscalingEnabled}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+				request, err := pushReq.WriteRequest()
+				require.NoError(t, err)
+
+				var histograms []mimirpb.Histogram
+				for _, series := range request.Timeseries {
+					histograms = append(histograms, series.Histograms...)
+				}
+				assert.Equal(t, tc.expectedHistograms, histograms)
+
+				pushReq.CleanUp()
+				return &mimirpb.WriteResponse{}, nil
+			})
+			handler.ServeHTTP(resp, createRequest())
+			assert.Equal(t, http.StatusOK, resp.Code)
+		})
+	}
+}
+
+func TestHandler_otlpMinMaxSeries(t *testing.T) {
+	const normalizeNameFeatureGate = "pkg.translator.prometheus.NormalizeName"
+	now := time.Now()
+
+	createRequest := func() *http.Request {
+		md := pmetric.NewMetrics()
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+		histogram := metrics.AppendEmpty()
+		histogram.SetName("histogram")
+		histogram.SetUnit("s")
+		histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		point := histogram.Histogram().DataPoints().AppendEmpty()
+		point.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		point.Attributes().PutStr("attr", "value")
+		point.SetCount(2)
+		point.SetSum(5)
+		point.SetMin(1)
+		point.SetMax(4)
+
+		expHistogram := metrics.AppendEmpty()
+		expHistogram.SetName("exp_histogram")
+		expHistogram.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		expPoint := expHistogram.ExponentialHistogram().DataPoints().AppendEmpty()
+		expPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		expPoint.SetCount(1)
+		expPoint.SetSum(3)
+		expPoint.SetMax(3)
+
+		return createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	}
+
+	tests := map[string]struct {
+		minMaxSeriesEnabled bool
+		translationStrategy string
+		normalizeNames      bool
+		expectedGauges      map[string]float64
+	}{
+		"min and max are not ingested when disabled": {
+			minMaxSeriesEnabled: false,
+			expectedGauges:      map[string]float64{},
+		},
+		"min and max are ingested as gauges when enabled": {
+			minMaxSeriesEnabled: true,
+			expectedGauges: map[string]float64{
+				`{__name__="histogram_min", attr="value"}`: 1,
+				`{__name__="histogram_max", attr="value"}`: 4,
+				`{__name__="exp_histogram_max"}`:           3,
+			},
+		},
+		"unit suffix is appended before the min and max suffixes": {
+			minMaxSeriesEnabled: true,
+			translationStrategy: validation.OTelTranslationStrategyUnderscoreEscapingWithSuffixes,
+			expectedGauges: map[string]float64{
+				`{__name__="histogram_seconds_min", attr="value"}`: 1,
+				`{__name__="histogram_seconds_max", attr="value"}`: 4,
+				`{__name__="exp_histogram_max"}`:                   3,
+			},
+		},
+
+		"unit suffix is appended before the min and max suffixes when the translator normalizes the names": {
+			minMaxSeriesEnabled: true,
+			normalizeNames:      true,
+			expectedGauges: map[string]float64{
+				`{__name__="histogram_seconds_min", attr="value"}`: 1,
+				`{__name__="histogram_seconds_max", attr="value"}`: 4,
+				`{__name__="exp_histogram_max"}`:                   3,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.normalizeNames {
+				require.NoError(t, featuregate.GlobalRegistry().Set(normalizeNameFeatureGate, true))
+				t.Cleanup(func() { require.NoError(t, featuregate.GlobalRegistry().Set(normalizeNameFeatureGate, false)) })
+			}
+
+			resp := httptest.NewRecorder()
+			handler := OTLPHandler(100000, nil, false, otlpLimitsMock{minMaxSeriesEnabled: tc.minMaxSeriesEnabled, translationStrategy: tc.translationStrategy}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+				request, err := pushReq.WriteRequest()
+				require.NoError(t, err)
+
+				gauges := map[string]float64{}
+				for _, series := range request.Timeseries {
+					metricName := mimirpb.FromLabelAdaptersToLabels(series.Labels).Get(labels.MetricName)
+					if !strings.HasSuffix(metricName, "_min") && !strings.HasSuffix(metricName, "_max") {
+						continue
+					}
+					require.Len(t, series.Samples, 1)
+					assert.Equal(t, now.UnixMilli(), series.Samples[0].TimestampMs)
+					gauges[mimirpb.FromLabelAdaptersToLabels(series.Labels).String()] = series.Samples[0].Value
+				}
+				assert.Equal(t, tc.expectedGauges, gauges)
+
+				for _, metadata := range request.Metadata {
+					if strings.HasSuffix(metadata.MetricFamilyName, "_min") || strings.HasSuffix(metadata.MetricFamilyName, "_max") {
+						assert.Equal(t, mimirpb.GAUGE, metadata.Type)
+					}
+				}
+
+				pushReq.CleanUp()
+				return &mimirpb.WriteResponse{}, nil
+			})
+			handler.ServeHTTP(resp, createRequest())
+			assert.Equal(t, http.StatusOK, resp.Code)
+		})
+	}
+}
+
+func TestHandler_otlpTranslationStrategy(t *testing.T) {
+	now := time.Now()
+
+	createRequest := func() *http.Request {
+		md := pmetric.NewMetrics()
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+		counter := metrics.AppendEmpty()
+		counter.SetName("http.server.requests")
+		counter.SetUnit("{request}")
+		counter.SetEmptySum().SetIsMonotonic(true)
+		counter.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		counterPoint := counter.Sum().DataPoints().AppendEmpty()
+		counterPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		counterPoint.SetDoubleValue(10)
+
+		gauge := metrics.AppendEmpty()
+		gauge.SetName("system.cpu.utilization")
+		gauge.SetUnit("1")
+		gaugePoint := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+		gaugePoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		gaugePoint.SetDoubleValue(0.5)
+
+		throughput := metrics.AppendEmpty()
+		throughput.SetName("network.io")
+		throughput.SetUnit("By/s")
+		throughputPoint := throughput.SetEmptyGauge().DataPoints().AppendEmpty()
+		throughputPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		throughputPoint.SetDoubleValue(100)
+
+		histogram := metrics.AppendEmpty()
+		histogram.SetName("http.server.duration")
+		histogram.SetUnit("ms")
+		histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		histogramPoint := histogram.Histogram().DataPoints().AppendEmpty()
+		histogramPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		histogramPoint.SetCount(1)
+		histogramPoint.SetSum(5)
+
+		return createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	}
+
+	tests := map[string]struct {
+		strategy         string
+		expectedNames    []string
+		expectedMetadata []string
+	}{
+		"default strategy": {
+			strategy:         "",
+			expectedNames:    []string{"http_server_duration_bucket", "http_server_duration_count", "http_server_duration_sum", "http_server_requests", "network_io", "system_cpu_utilization"},
+			expectedMetadata: []string{"http_server_duration", "http_server_requests", "network_io", "system_cpu_utilization"},
+		},
+		"underscore escaping without suffixes": {
+			strategy:         validation.OTelTranslationStrategyUnderscoreEscapingWithoutSuffixes,
+			expectedNames:    []string{"http_server_duration_bucket", "http_server_duration_count", "http_server_duration_sum", "http_server_requests", "network_io", "system_cpu_utilization"},
+			expectedMetadata: []string{"http_server_duration", "http_server_requests", "network_io", "system_cpu_utilization"},
+		},
+		"underscore escaping with suffixes": {
+			strategy:         validation.OTelTranslationStrategyUnderscoreEscapingWithSuffixes,
+			expectedNames:    []string{"http_server_duration_milliseconds_bucket", "http_server_duration_milliseconds_count", "http_server_duration_milliseconds_sum", "http_server_requests_total", "network_io_bytes_per_second", "system_cpu_utilization_ratio"},
+			expectedMetadata: []string{"http_server_duration_milliseconds", "http_server_requests_total", "network_io_bytes_per_second", "system_cpu_utilization_ratio"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			handler := OTLPHandler(100000, nil, false, otlpLimitsMock{translationStrategy: tc.strategy}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+				request, err := pushReq.WriteRequest()
+				require.NoError(t, err)
+
+				var names []string
+				for _, series := range request.Timeseries {
+					names = append(names, mimirpb.FromLabelAdaptersToLabels(series.Labels).Get(labels.MetricName))
+				}
+				var metadataNames []string
+				for _, metadata := range request.Metadata {
+					metadataNames = append(metadataNames, metadata.MetricFamilyName)
+				}
+
+				assert.ElementsMatch(t, tc.expectedNames, names)
+				assert.ElementsMatch(t, tc.expectedMetadata, metadataNames)
+
+				pushReq.CleanUp()
+				return &mimirpb.WriteResponse{}, nil
+			})
+			handler.ServeHTTP(resp, createRequest())
+			assert.Equal(t, http.StatusOK, resp.Code)
+		})
+	}
+}
+
+func TestHandler_otlpCreatedTimestamps(t *testing.T) {
+	now := time.Now()
+	start := now.Add(-time.Minute)
+
+	createRequest := func() *http.Request {
+		md := pmetric.NewMetrics()
+		metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+
+		counter := metrics.AppendEmpty()
+		counter.SetName("counter")
+		counter.SetEmptySum().SetIsMonotonic(true)
+		counter.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		counterPoint := counter.Sum().DataPoints().AppendEmpty()
+		counterPoint.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		counterPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		counterPoint.SetDoubleValue(10)
+
+		histogram := metrics.AppendEmpty()
+		histogram.SetName("histogram")
+		histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		histogramPoint := histogram.Histogram().DataPoints().AppendEmpty()
+		histogramPoint.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		histogramPoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		histogramPoint.SetCount(1)
+		histogramPoint.SetSum(3)
+
+		gauge := metrics.AppendEmpty()
+		gauge.SetName("gauge")
+		gaugePoint := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+		gaugePoint.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		gaugePoint.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		gaugePoint.SetDoubleValue(1)
+
+		return createOTLPRequest(t, pmetricotlp.NewExportRequestFromMetrics(md), false)
+	}
+
+	tests := map[string]struct {
+		createdTimestampsIngestionEnabled bool
+		expectedCreatedTimestamps         map[string]int64
+	}{
+		"created timestamps are not set when disabled": {
+			createdTimestampsIngestionEnabled: false,
+			expectedCreatedTimestamps: map[string]int64{
+				"counter":         0,
+				"histogram_count": 0,
+				"histogram_sum":   0,
+				"gauge":           0,
+			},
+		},
+		"created timestamps of counters and histograms are set when enabled": {
+			createdTimestampsIngestionEnabled: true,
+			expectedCreatedTimestamps: map[string]int64{
+				"counter":         start.UnixMilli(),
+				"histogram_count": start.UnixMilli(),
+				"histogram_sum":   start.UnixMilli(),
+				"gauge":           0,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			handler := OTLPHandler(100000, nil, false, otlpLimitsMock{createdTimestampsIngestionEnabled: tc.createdTimestampsIngestionEnabled}, nil, func(ctx context.Context, pushReq *Request) (response *mimirpb.WriteResponse, err error) {
+				request, err := pushReq.WriteRequest()
+				require.NoError(t, err)
+
+				createdTimestamps := map[string]int64{}
+				for _, series := range request.Timeseries {
+					metricName := mimirpb.FromLabelAdaptersToLabels(series.Labels).Get(labels.MetricName)
+					if metricName == "histogram_bucket" {
+						assert.Equal(t, tc.expectedCreatedTimestamps["histogram_count"], series.CreatedTimestampMs)
+						continue
+					}
+					createdTimestamps[metricName] = series.CreatedTimestampMs
+				}
+				assert.Equal(t, tc.expectedCreatedTimestamps, createdTimestamps)
+
+				pushReq.CleanUp()
+				return &mimirpb.WriteResponse{}, nil
+			})
+			handler.ServeHTTP(resp, createRequest())
+			assert.Equal(t, http.StatusOK, resp.Code)
+		})
+	}
+}
+
+func TestDownscaleExponentialHistogramBuckets(t *testing.T) {
+	tests := map[string]struct {
+		offset         int32
+		counts         []uint64
+		scaleDown      int32
+		expectedOffset int32
+		expectedCounts []uint64
+	}{
+		"no buckets": {
+			offset:         5,
+			scaleDown:      1,
+			expectedOffset: 2,
+		},
+		"positive offset": {
+			offset:         1,
+			counts:         []uint64{1, 2, 3, 4},
+			scaleDown:      1,
+			expectedOffset: 0,
+			expectedCounts: []uint64{1, 5, 4},
+		},
+		"negative offset": {
+			offset:         -3,
+			counts:         []uint64{1, 2, 3, 4, 5},
+			scaleDown:      2,
+			expectedOffset: -1,
+			expectedCounts: []uint64{6, 9},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			buckets := pmetric.NewExponentialHistogramDataPointBuckets()
+			buckets.SetOffset(tc.offset)
+			buckets.BucketCounts().FromRaw(tc.counts)
+
+			downscaleExponentialHistogramBuckets(buckets, tc.scaleDown)
+
+			assert.Equal(t, tc.expectedOffset, buckets.Offset())
+			assert.Equal(t, tc.expectedCounts, buckets.BucketCounts().AsRaw())
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// remoteWriteV1ProtoMessage and remoteWriteV2ProtoMessage are the values of the "proto" parameter
+	// of the Content-Type header used by Prometheus to negotiate the remote write protocol version.
+	remoteWriteV1ProtoMessage = "prometheus.WriteRequest"
+	remoteWriteV2ProtoMessage = "io.prometheus.write.v2.Request"
+
+	// The response headers returning to the remote write 2.0 clients the number of samples, histograms and
+	// exemplars written.
+	remoteWriteSamplesWrittenHeader    = "X-Prometheus-Remote-Write-Samples-Written"
+	remoteWriteHistogramsWrittenHeader = "X-Prometheus-Remote-Write-Histograms-Written"
+	remoteWriteExemplarsWrittenHeader  = "X-Prometheus-Remote-Write-Exemplars-Written"
+)
+
+// isRemoteWriteV2 returns whether the Content-Type header of a push request selects the remote write 2.0 protocol.
+// Requests without a "proto" parameter are considered remote write 1.0 requests, for backward compatibility.
+func isRemoteWriteV2(contentType string) (bool, error) {
+	if contentType == "" {
+		return false, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false, fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+	if mediaType != pbContentType {
+		// Preserve the remote write 1.0 behaviour, which never validated the media type.
+		return false, nil
+	}
+
+	switch params["proto"] {
+	case "", remoteWriteV1ProtoMessage:
+		return false, nil
+	case remoteWriteV2ProtoMessage:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported remote write protobuf message %q, supported: [%s, %s]", params["proto"], remoteWriteV1ProtoMessage, remoteWriteV2ProtoMessage)
+	}
+}
+
+// setRemoteWriteV2WrittenHeaders sets the response headers with the number of samples, histograms and exemplars
+// of the request which have been written. The headers are set on errors too, because the requests may be partially
+// written.
+func setRemoteWriteV2WrittenHeaders(h http.Header, req *Request) {
+	h.Set(remoteWriteSamplesWrittenHeader, strconv.Itoa(req.writtenSamples))
+	h.Set(remoteWriteHistogramsWrittenHeader, strconv.Itoa(req.writtenHistograms))
+	h.Set(remoteWriteExemplarsWrittenHeader, strconv.Itoa(req.writtenExemplars))
+}
+
+// writeRequestV2 decodes a remote write 2.0 request (io.prometheus.write.v2.Request) into a Mimir WriteRequest.
+// It implements proto.Message so that it can be used with util.ParseProtoReader.
+//
+// Series labels, exemplar labels and metadata are resolved against the request's symbols table. Each symbol
+// is copied once, so the decoded request doesn't reference the request body.
+// The created timestamp of each series is decoded into TimeSeries.CreatedTimestampMs, and it's ingested only if
+// enabled for the tenant with -distributor.created-timestamps-ingestion-enabled.
+type writeRequestV2 struct {
+	dst *mimirpb.PreallocWriteRequest
+}
+
+func (w *writeRequestV2) Reset() {
+	w.dst.WriteRequest = mimirpb.WriteRequest{}
+}
+
+func (w *writeRequestV2) String() string { return "writeRequestV2" }
+
+func (w *writeRequestV2) ProtoMessage() {}
+
+// Unmarshal implements proto.Unmarshaler.
+func (w *writeRequestV2) Unmarshal(buf []byte) error {
+	var (
+		symbols []string
+		series  [][]byte
+	)
+
+	// Symbols and timeseries can be encoded in any order, so collect both before resolving references.
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 4 && typ == protowire.BytesType:
+			symbols = append(symbols, string(value))
+		case num == 5 && typ == protowire.BytesType:
+			series = append(series, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.dst.Timeseries = mimirpb.PreallocTimeseriesSliceFromPool()
+	for _, s := range series {
+		if err := w.unmarshalTimeSeries(s, symbols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writeRequestV2) unmarshalTimeSeries(buf []byte, symbols []string) error {
+	var (
+		labelRefs []uint32
+		metadata  []byte
+	)
+
+	ts := mimirpb.TimeseriesFromPool()
+	w.dst.Timeseries = append(w.dst.Timeseries, mimirpb.PreallocTimeseries{TimeSeries: ts})
+
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 1:
+			var err error
+			if labelRefs, err = appendUint32s(labelRefs, typ, value); err != nil {
+				return err
+			}
+		case 2:
+			s, err := unmarshalSampleV2(value)
+			if err != nil {
+				return err
+			}
+			ts.Samples = append(ts.Samples, s)
+		case 3:
+			// The remote write 2.0 Histogram message is wire compatible with the Mimir one.
+			var h mimirpb.Histogram
+			if err := h.Unmarshal(value); err != nil {
+				return err
+			}
+			ts.Histograms = append(ts.Histograms, h)
+		case 4:
+			e, err := unmarshalExemplarV2(value, symbols)
+			if err != nil {
+				return err
+			}
+			ts.Exemplars = append(ts.Exemplars, e)
+		case 5:
+			metadata = value
+		case 6:
+			if typ == protowire.VarintType {
+				v, _ := protowire.ConsumeVarint(value)
+				ts.CreatedTimestampMs = int64(v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if ts.Labels, err = resolveLabelRefs(ts.Labels, labelRefs, symbols); err != nil {
+		return err
+	}
+
+	if metadata != nil {
+		m, err := unmarshalMetadataV2(metadata, symbols)
+		if err != nil {
+			return err
+		}
+		if m.Type != mimirpb.UNKNOWN || m.Help != "" || m.Unit != "" {
+			for _, l := range ts.Labels {
+				if l.Name == model.MetricNameLabel {
+					m.MetricFamilyName = l.Value
+					break
+				}
+			}
+			w.dst.Metadata = append(w.dst.Metadata, m)
+		}
+	}
+
+	// A series may only carry metadata, in which case there's nothing to ingest for the series itself.
+	if len(ts.Samples) == 0 && len(ts.Histograms) == 0 && len(ts.Exemplars) == 0 {
+		last := len(w.dst.Timeseries) - 1
+		mimirpb.ReusePreallocTimeseries(&w.dst.Timeseries[last])
+		w.dst.Timeseries = w.dst.Timeseries[:last]
+	}
+
+	return nil
+}
+
+func unmarshalSampleV2(buf []byte) (mimirpb.Sample, error) {
+	var s mimirpb.Sample
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(value)
+			s.Value = math.Float64frombits(v)
+		case num == 2 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			s.TimestampMs = int64(v)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func unmarshalExemplarV2(buf []byte, symbols []string) (mimirpb.Exemplar, error) {
+	var (
+		e         mimirpb.Exemplar
+		labelRefs []uint32
+	)
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		var err error
+		switch {
+		case num == 1:
+			labelRefs, err = appendUint32s(labelRefs, typ, value)
+		case num == 2 && typ == protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(value)
+			e.Value = math.Float64frombits(v)
+		case num == 3 && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			e.TimestampMs = int64(v)
+		}
+		return err
+	})
+	if err != nil {
+		return e, err
+	}
+
+	e.Labels, err = resolveLabelRefs(nil, labelRefs, symbols)
+	return e, err
+}
+
+func unmarshalMetadataV2(buf []byte, symbols []string) (*mimirpb.MetricMetadata, error) {
+	m := &mimirpb.MetricMetadata{}
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.VarintType {
+			return nil
+		}
+		v, _ := protowire.ConsumeVarint(value)
+
+		switch num {
+		case 1:
+			// The remote write 2.0 metric types have the same values as the Mimir ones.
+			m.Type = mimirpb.MetricMetadata_MetricType(v)
+		case 3, 4:
+			if v >= uint64(len(symbols)) {
+				return fmt.Errorf("metadata references symbol %d, but the symbols table has only %d entries", v, len(symbols))
+			}
+			if num == 3 {
+				m.Help = symbols[v]
+			} else {
+				m.Unit = symbols[v]
+			}
+		}
+		return nil
+	})
+	return m, err
+}
+
+func resolveLabelRefs(dst []mimirpb.LabelAdapter, refs []uint32, symbols []string) ([]mimirpb.LabelAdapter, error) {
+	if len(refs)%2 != 0 {
+		return nil, fmt.Errorf("odd number of label references: %d", len(refs))
+	}
+	for i := 0; i < len(refs); i += 2 {
+		nameRef, valueRef := refs[i], refs[i+1]
+		if int(nameRef) >= len(symbols) || int(valueRef) >= len(symbols) {
+			return nil, fmt.Errorf("label references symbols %d and %d, but the symbols table has only %d entries", nameRef, valueRef, len(symbols))
+		}
+		dst = append(dst, mimirpb.LabelAdapter{Name: symbols[nameRef], Value: symbols[valueRef]})
+	}
+	return dst, nil
+}
+
+// appendUint32s decodes a repeated uint32 field, which can be either packed or not.
+func appendUint32s(dst []uint32, typ protowire.Type, value []byte) ([]uint32, error) {
+	switch typ {
+	case protowire.VarintType:
+		v, _ := protowire.ConsumeVarint(value)
+		return append(dst, uint32(v)), nil
+	case protowire.BytesType:
+		for len(value) > 0 {
+			v, n := protowire.ConsumeVarint(value)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			dst = append(dst, uint32(v))
+			value = value[n:]
+		}
+		return dst, nil
+	default:
+		return nil, fmt.Errorf("unexpected wire type %d for repeated uint32 field", typ)
+	}
+}
+
+// forEachField calls f for each field of the protobuf message encoded in buf. For length-delimited fields,
+// value is the field payload; for all other fields it's the raw encoded value.
+func forEachField(buf []byte, f func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return errors.Wrap(protowire.ParseError(n), "decode remote write 2.0 request")
+		}
+		buf = buf[n:]
+
+		var value []byte
+		if typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(buf)
+			if m < 0 {
+				return errors.Wrap(protowire.ParseError(m), "decode remote write 2.0 request")
+			}
+			value, n = v, m
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+			if n < 0 {
+				return errors.Wrap(protowire.ParseError(n), "decode remote write 2.0 request")
+			}
+			value = buf[:n]
+		}
+		buf = buf[n:]
+
+		if err := f(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
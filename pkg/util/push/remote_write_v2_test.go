@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package push
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestIsRemoteWriteV2(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		expected    bool
+		expectedErr bool
+	}{
+		"empty":                 {contentType: "", expected: false},
+		"protobuf without type": {contentType: "application/x-protobuf", expected: false},
+		"explicit 1.0":          {contentType: "application/x-protobuf;proto=prometheus.WriteRequest", expected: false},
+		"explicit 2.0":          {contentType: "application/x-protobuf;proto=io.prometheus.write.v2.Request", expected: true},
+		"unrelated media type":  {contentType: "text/plain", expected: false},
+		"unknown message":       {contentType: "application/x-protobuf;proto=foo.Bar", expectedErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := isRemoteWriteV2(tc.contentType)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestHandler_remoteWriteV2(t *testing.T) {
+	body := createRemoteWriteV2Protobuf()
+
+	req, err := http.NewRequest("POST", "http://localhost/", bytes.NewReader(snappy.Encode(nil, body)))
+	require.NoError(t, err)
+	req.Header.Add("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+
+	resp := httptest.NewRecorder()
+	handler := Handler(100000, nil, false, func(ctx context.Context, pushReq *Request) (*mimirpb.WriteResponse, error) {
+		request, err := pushReq.WriteRequest()
+		require.NoError(t, err)
+
+		require.Len(t, request.Timeseries, 1)
+		series := request.Timeseries[0]
+		assert.Equal(t, []mimirpb.LabelAdapter{{Name: "__name__", Value: "foo"}, {Name: "job", Value: "bar"}}, series.Labels)
+		assert.Equal(t, []mimirpb.Sample{{TimestampMs: 1000, Value: 1.5}}, series.Samples)
+		assert.Equal(t, int64(500), series.CreatedTimestampMs)
+		require.Len(t, series.Exemplars, 1)
+		assert.Equal(t, []mimirpb.LabelAdapter{{Name: "trace_id", Value: "abc"}}, series.Exemplars[0].Labels)
+		assert.Equal(t, int64(1000), series.Exemplars[0].TimestampMs)
+		assert.Equal(t, 2.0, series.Exemplars[0].Value)
+
+		assert.Equal(t, []*mimirpb.MetricMetadata{{
+			Type:             mimirpb.COUNTER,
+			MetricFamilyName: "foo",
+			Help:             "Some help.",
+		}}, request.Metadata)
+
+		pushReq.SetWritten(1, 0, 1)
+		pushReq.CleanUp()
+		return &mimirpb.WriteResponse{}, nil
+	})
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("X-Prometheus-Remote-Write-Samples-Written"))
+	assert.Equal(t, "0", resp.Header().Get("X-Prometheus-Remote-Write-Histograms-Written"))
+	assert.Equal(t, "1", resp.Header().Get("X-Prometheus-Remote-Write-Exemplars-Written"))
+}
+
+func TestHandler_remoteWriteV2WrittenHeaders(t *testing.T) {
+	tests := map[string]struct {
+		contentType     string
+		pushErr         error
+		expectedCode    int
+		expectedHeaders bool
+	}{
+		"remote write 2.0": {
+			contentType:     "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+			expectedCode:    http.StatusOK,
+			expectedHeaders: true,
+		},
+		"remote write 2.0 partially written": {
+			contentType:     "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+			pushErr:         httpgrpc.Errorf(http.StatusBadRequest, "invalid series"),
+			expectedCode:    http.StatusBadRequest,
+			expectedHeaders: true,
+		},
+		"remote write 1.0": {
+			contentType:  "application/x-protobuf",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "http://localhost/", bytes.NewReader(snappy.Encode(nil, createRemoteWriteV2Protobuf())))
+			require.NoError(t, err)
+			req.Header.Add("Content-Encoding", "snappy")
+			req.Header.Set("Content-Type", tc.contentType)
+
+			resp := httptest.NewRecorder()
+			handler := Handler(100000, nil, false, func(ctx context.Context, pushReq *Request) (*mimirpb.WriteResponse, error) {
+				_, err := pushReq.WriteRequest()
+				require.NoError(t, err)
+
+				pushReq.SetWritten(3, 2, 1)
+				pushReq.CleanUp()
+				return &mimirpb.WriteResponse{}, tc.pushErr
+			})
+			handler.ServeHTTP(resp, req)
+			assert.Equal(t, tc.expectedCode, resp.Code)
+
+			if tc.expectedHeaders {
+				assert.Equal(t, "3", resp.Header().Get("X-Prometheus-Remote-Write-Samples-Written"))
+				assert.Equal(t, "2", resp.Header().Get("X-Prometheus-Remote-Write-Histograms-Written"))
+				assert.Equal(t, "1", resp.Header().Get("X-Prometheus-Remote-Write-Exemplars-Written"))
+			} else {
+				assert.Empty(t, resp.Header().Get("X-Prometheus-Remote-Write-Samples-Written"))
+				assert.Empty(t, resp.Header().Get("X-Prometheus-Remote-Write-Histograms-Written"))
+				assert.Empty(t, resp.Header().Get("X-Prometheus-Remote-Write-Exemplars-Written"))
+			}
+		})
+	}
+}
+
+func TestHandler_remoteWriteV2UnsupportedProtoMessage(t *testing.T) {
+	req := createRequest(t, createPrometheusRemoteWriteProtobuf(t))
+	req.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v3.Request")
+
+	resp := httptest.NewRecorder()
+	handler := Handler(100000, nil, false, readBodyPushFunc(t))
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+}
+
+func TestWriteRequestV2_Unmarshal_MetadataOnlySeries(t *testing.T) {
+	var metadata []byte
+	metadata = protowire.AppendTag(metadata, 1, protowire.VarintType)
+	metadata = protowire.AppendVarint(metadata, uint64(mimirpb.GAUGE))
+	metadata = protowire.AppendTag(metadata, 4, protowire.VarintType)
+	metadata = protowire.AppendVarint(metadata, 3)
+
+	var series []byte
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	series = protowire.AppendBytes(series, appendPackedUint32s(nil, 1, 2))
+	series = protowire.AppendTag(series, 5, protowire.BytesType)
+	series = protowire.AppendBytes(series, metadata)
+
+	var body []byte
+	for _, s := range []string{"", "__name__", "temperature", "celsius"} {
+		body = protowire.AppendTag(body, 4, protowire.BytesType)
+		body = protowire.AppendString(body, s)
+	}
+	body = protowire.AppendTag(body, 5, protowire.BytesType)
+	body = protowire.AppendBytes(body, series)
+
+	dst := &mimirpb.PreallocWriteRequest{}
+	req := &writeRequestV2{dst: dst}
+	require.NoError(t, req.Unmarshal(body))
+
+	assert.Empty(t, dst.Timeseries)
+	assert.Equal(t, []*mimirpb.MetricMetadata{{
+		Type:             mimirpb.GAUGE,
+		MetricFamilyName: "temperature",
+		Unit:             "celsius",
+	}}, dst.Metadata)
+}
+
+func TestWriteRequestV2_Unmarshal_InvalidSymbolReference(t *testing.T) {
+	var series []byte
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	series = protowire.AppendBytes(series, protowire.AppendVarint(protowire.AppendVarint(nil, 0), 5))
+
+	var body []byte
+	body = protowire.AppendTag(body, 4, protowire.BytesType)
+	body = protowire.AppendString(body, "__name__")
+	body = protowire.AppendTag(body, 5, protowire.BytesType)
+	body = protowire.AppendBytes(body, series)
+
+	req := &writeRequestV2{dst: &mimirpb.PreallocWriteRequest{}}
+	require.Error(t, req.Unmarshal(body))
+}
+
+// createRemoteWriteV2Protobuf builds a remote write 2.0 request with a single counter series.
+func createRemoteWriteV2Protobuf() []byte {
+	symbols := []string{"", "__name__", "foo", "job", "bar", "trace_id", "abc", "Some help."}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(1.5))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, 1000)
+
+	var exemplar []byte
+	exemplar = protowire.AppendTag(exemplar, 1, protowire.BytesType)
+	exemplar = protowire.AppendBytes(exemplar, appendPackedUint32s(nil, 5, 6))
+	exemplar = protowire.AppendTag(exemplar, 2, protowire.Fixed64Type)
+	exemplar = protowire.AppendFixed64(exemplar, math.Float64bits(2))
+	exemplar = protowire.AppendTag(exemplar, 3, protowire.VarintType)
+	exemplar = protowire.AppendVarint(exemplar, 1000)
+
+	var metadata []byte
+	metadata = protowire.AppendTag(metadata, 1, protowire.VarintType)
+	metadata = protowire.AppendVarint(metadata, uint64(mimirpb.COUNTER))
+	metadata = protowire.AppendTag(metadata, 3, protowire.VarintType)
+	metadata = protowire.AppendVarint(metadata, 7)
+
+	var series []byte
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	series = protowire.AppendBytes(series, appendPackedUint32s(nil, 1, 2, 3, 4))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+	series = protowire.AppendTag(series, 4, protowire.BytesType)
+	series = protowire.AppendBytes(series, exemplar)
+	series = protowire.AppendTag(series, 5, protowire.BytesType)
+	series = protowire.AppendBytes(series, metadata)
+	series = protowire.AppendTag(series, 6, protowire.VarintType)
+	series = protowire.AppendVarint(series, 500)
+
+	var body []byte
+	for _, s := range symbols {
+		body = protowire.AppendTag(body, 4, protowire.BytesType)
+		body = protowire.AppendString(body, s)
+	}
+	body = protowire.AppendTag(body, 5, protowire.BytesType)
+	body = protowire.AppendBytes(body, series)
+	return body
+}
+
+func appendPackedUint32s(dst []byte, values ...uint32) []byte {
+	for _, v := range values {
+		dst = protowire.AppendVarint(dst, uint64(v))
+	}
+	return dst
+}
@@ -4,6 +4,7 @@ package push
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 )
@@ -22,6 +23,13 @@ type Request struct {
 
 	request *mimirpb.WriteRequest
 	err     error
+
+	// Set only when the request has been received over HTTP.
+	header        http.Header
+	sourceAddress string
+
+	// The number of samples, histograms and exemplars written, set by the push function.
+	writtenSamples, writtenHistograms, writtenExemplars int
 }
 
 func newRequest(p supplierFunc) *Request {
@@ -52,6 +60,24 @@ func (r *Request) WriteRequest() (*mimirpb.WriteRequest, error) {
 	return r.request, r.err
 }
 
+// Header returns the value of the HTTP header with the given name, or an empty string if the
+// header is missing or the request hasn't been received over HTTP.
+func (r *Request) Header(name string) string {
+	return r.header.Get(name)
+}
+
+// SourceAddress returns the address of the peer which sent the request, or an empty string if the
+// request hasn't been received over HTTP. The forwarding headers of the request are not trusted.
+func (r *Request) SourceAddress() string {
+	return r.sourceAddress
+}
+
+// SetWritten records the number of float samples, histogram samples and exemplars of the request which have been
+// written. They're returned to the remote write 2.0 clients in the response headers.
+func (r *Request) SetWritten(samples, histograms, exemplars int) {
+	r.writtenSamples, r.writtenHistograms, r.writtenExemplars = samples, histograms, exemplars
+}
+
 // AddCleanup adds a function that will be called once CleanUp is called. If f is nil, it will not be invoked.
 func (r *Request) AddCleanup(f func()) {
 	if f == nil {
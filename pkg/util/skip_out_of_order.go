@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// SkipOutOfOrderHeader is the HTTP header which, when set to true, excludes the samples ingested
+// out-of-order from the query results.
+const SkipOutOfOrderHeader = "X-Mimir-Skip-Out-Of-Order"
+
+// skipOutOfOrderKey is the key for the GRPC metadata where the skip out-of-order option is stored.
+const skipOutOfOrderKey = "skip-out-of-order"
+
+// SkipOutOfOrderFromRequest returns whether the request asks to exclude the samples ingested out-of-order.
+func SkipOutOfOrderFromRequest(r *http.Request) bool {
+	skip, err := strconv.ParseBool(r.Header.Get(SkipOutOfOrderHeader))
+	return err == nil && skip
+}
+
+// AddSkipOutOfOrderToOutgoingContext adds the skip out-of-order option to the GRPC context.
+func AddSkipOutOfOrderToOutgoingContext(ctx context.Context) context.Context {
+	if SkipOutOfOrderFromOutgoingContext(ctx) {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, skipOutOfOrderKey, "true")
+}
+
+// SkipOutOfOrderFromOutgoingContext returns whether the skip out-of-order option is set in the outgoing GRPC context.
+func SkipOutOfOrderFromOutgoingContext(ctx context.Context) bool {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	return ok && len(md.Get(skipOutOfOrderKey)) > 0
+}
+
+// SkipOutOfOrderFromIncomingContext returns whether the skip out-of-order option is set in the incoming GRPC context.
+func SkipOutOfOrderFromIncomingContext(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(skipOutOfOrderKey)) > 0
+}
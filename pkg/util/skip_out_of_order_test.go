@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestSkipOutOfOrderFromRequest(t *testing.T) {
+	for value, expected := range map[string]bool{
+		"":      false,
+		"false": false,
+		"foo":   false,
+		"true":  true,
+		"1":     true,
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(SkipOutOfOrderHeader, value)
+		assert.Equal(t, expected, SkipOutOfOrderFromRequest(r), value)
+	}
+}
+
+func TestSkipOutOfOrderContext(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, SkipOutOfOrderFromOutgoingContext(ctx))
+
+	ctx = AddSkipOutOfOrderToOutgoingContext(ctx)
+	assert.True(t, SkipOutOfOrderFromOutgoingContext(ctx))
+
+	// Adding the option twice doesn't duplicate the metadata.
+	ctx = AddSkipOutOfOrderToOutgoingContext(ctx)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.Len(t, md.Get(skipOutOfOrderKey), 1)
+
+	// The option is received in the incoming context.
+	assert.False(t, SkipOutOfOrderFromIncomingContext(context.Background()))
+	assert.True(t, SkipOutOfOrderFromIncomingContext(metadata.NewIncomingContext(context.Background(), md)))
+}
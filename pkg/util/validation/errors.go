@@ -105,6 +105,26 @@ func (e tooManyLabelsError) Error() string {
 		maxLabelNamesPerSeriesFlag)
 }
 
+type labelsTooLargeError struct {
+	series []mimirpb.LabelAdapter
+	size   int
+	limit  int
+}
+
+func newLabelsTooLargeError(series []mimirpb.LabelAdapter, size, limit int) ValidationError {
+	return labelsTooLargeError{
+		series: series,
+		size:   size,
+		limit:  limit,
+	}
+}
+
+func (e labelsTooLargeError) Error() string {
+	return globalerror.SeriesLabelsTooLarge.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("received a series whose combined size of label names and values exceeds the limit (actual: %d bytes, limit: %d bytes) series: '%.200s'", e.size, e.limit, formatLabelSet(e.series)),
+		maxLabelsSizeBytesFlag)
+}
+
 type noMetricNameError struct{}
 
 func newNoMetricNameError() ValidationError {
@@ -152,6 +172,24 @@ func newSampleTimestampTooNewError(metricName string, timestamp int64) Validatio
 	}
 }
 
+// histogramFloatConflictError is a ValidationError returned when a series is rejected because it has
+// both float and native histogram samples.
+type histogramFloatConflictError struct {
+	metricName string
+}
+
+func newHistogramFloatConflictError(metricName string) ValidationError {
+	return histogramFloatConflictError{
+		metricName: metricName,
+	}
+}
+
+func (e histogramFloatConflictError) Error() string {
+	return globalerror.SeriesHistogramFloatConflict.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("received a series with both float and native histogram samples, series: '%.200s'", e.metricName),
+		histogramFloatConflictPolicyFlag)
+}
+
 // exemplarValidationError is a ValidationError implementation suitable for exemplar validation errors.
 type exemplarValidationError struct {
 	message        string
@@ -246,6 +284,26 @@ func newMetadataUnitTooLongError(metadata *mimirpb.MetricMetadata) ValidationErr
 	}
 }
 
+type metadataTooLargeError struct {
+	metricName string
+	size       int
+	limit      int
+}
+
+func newMetadataTooLargeError(metadata *mimirpb.MetricMetadata, size, limit int) ValidationError {
+	return metadataTooLargeError{
+		metricName: metadata.GetMetricFamilyName(),
+		size:       size,
+		limit:      limit,
+	}
+}
+
+func (e metadataTooLargeError) Error() string {
+	return globalerror.MetricMetadataTooLarge.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("received a metric metadata whose combined size of metric name, help and unit exceeds the limit (actual: %d bytes, limit: %d bytes) metric name: '%.200s'", e.size, e.limit, e.metricName),
+		maxMetadataSizeBytesFlag)
+}
+
 func NewMaxQueryLengthError(actualQueryLen, maxQueryLength time.Duration) LimitError {
 	return LimitError(globalerror.MaxQueryLength.MessageWithPerTenantLimitConfig(
 		fmt.Sprintf("the query time range exceeds the limit (query length: %s, limit: %s)", actualQueryLen, maxQueryLength),
@@ -264,12 +322,54 @@ func NewMaxQueryExpressionSizeBytesError(actualSizeBytes, maxQuerySizeBytes int)
 		maxQueryExpressionSizeBytesFlag))
 }
 
+func NewMaxInstantQueryResultSeriesError(actualSeries, maxSeries int) LimitError {
+	return LimitError(globalerror.MaxInstantQueryResultSeries.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the number of series in the instant query result exceeds the limit (series: %d, limit: %d)", actualSeries, maxSeries),
+		maxInstantQueryResultSeriesFlag))
+}
+
+func NewMaxRangeQueryResultSamplesError(actualSamples, maxSamples int) LimitError {
+	return LimitError(globalerror.MaxRangeQueryResultSamples.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the number of samples in the range query result exceeds the limit (samples: %d, limit: %d)", actualSamples, maxSamples),
+		maxRangeQueryResultSamplesFlag))
+}
+
+func NewMaxEstimatedMemoryPerQueryError(estimatedBytes, maxBytes int) LimitError {
+	return LimitError(globalerror.MaxEstimatedMemoryPerQuery.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the query has been rejected before execution because its estimated memory exceeds the limit (estimated: %d bytes, limit: %d bytes)", estimatedBytes, maxBytes),
+		MaxEstimatedMemoryPerQueryFlag))
+}
+
+func NewStoreGatewayLabelNamesAndValuesMaxSizeBytesError(maxSizeBytes int) LimitError {
+	return LimitError(globalerror.LabelNamesAndValuesTooLarge.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the size of the label names or values fetched from a store-gateway exceeds the limit (limit: %d bytes)", maxSizeBytes),
+		storeGatewayLabelsMaxSizeFlag))
+}
+
+func NewStoreGatewayMaxBlocksPerQueryError(actualBlocks, maxBlocks int) LimitError {
+	return LimitError(globalerror.QueryTooExpensive.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the query has been rejected by the store-gateway because it touches too many blocks (blocks: %d, limit: %d)", actualBlocks, maxBlocks),
+		storeGatewayMaxBlocksPerQueryFlag))
+}
+
+func NewStoreGatewayMaxEstimatedPostingsBytesError(estimatedBytes, maxBytes int) LimitError {
+	return LimitError(globalerror.QueryTooExpensive.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the query has been rejected by the store-gateway because the estimated size of the postings to fetch exceeds the limit (estimated: %d bytes, limit: %d bytes)", estimatedBytes, maxBytes),
+		storeGatewayMaxPostingsBytesFlag))
+}
+
 func NewRequestRateLimitedError(limit float64, burst int) LimitError {
 	return LimitError(globalerror.RequestRateLimited.MessageWithPerTenantLimitConfig(
 		fmt.Sprintf("the request has been rejected because the tenant exceeded the request rate limit, set to %v requests/s across all distributors with a maximum allowed burst of %d", limit, burst),
 		requestRateFlag, requestBurstSizeFlag))
 }
 
+func NewSourceRequestRateLimitedError(limit float64, burst int) LimitError {
+	return LimitError(globalerror.SourceRequestRateLimited.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the request has been rejected because its source exceeded the per-source request rate limit, set to %v requests/s across all distributors with a maximum allowed burst of %d", limit, burst),
+		requestRatePerSourceFlag, requestBurstSizePerSourceFlag))
+}
+
 func NewIngestionRateLimitedError(limit float64, burst int) LimitError {
 	return LimitError(globalerror.IngestionRateLimited.MessageWithPerTenantLimitConfig(
 		fmt.Sprintf("the request has been rejected because the tenant exceeded the ingestion rate limit, set to %v items/s with a maximum allowed burst of %d. This limit is applied on the total number of samples, exemplars and metadata received across all distributors", limit, burst),
@@ -12,16 +12,20 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/promql/parser"
+	"golang.org/x/exp/slices"
 	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 )
 
@@ -33,17 +37,29 @@ const (
 	MaxChunksPerQueryFlag                  = "querier.max-fetched-chunks-per-query"
 	MaxChunkBytesPerQueryFlag              = "querier.max-fetched-chunk-bytes-per-query"
 	MaxSeriesPerQueryFlag                  = "querier.max-fetched-series-per-query"
+	MaxEstimatedMemoryPerQueryFlag         = "querier.max-estimated-memory-per-query"
 	maxLabelNamesPerSeriesFlag             = "validation.max-label-names-per-series"
 	maxLabelNameLengthFlag                 = "validation.max-length-label-name"
 	maxLabelValueLengthFlag                = "validation.max-length-label-value"
 	maxMetadataLengthFlag                  = "validation.max-metadata-length"
+	maxLabelsSizeBytesFlag                 = "validation.max-labels-size-bytes"
+	maxMetadataSizeBytesFlag               = "validation.max-metadata-size-bytes"
 	creationGracePeriodFlag                = "validation.create-grace-period"
+	histogramFloatConflictPolicyFlag       = "validation.histogram-float-conflict-policy"
 	maxQueryLengthFlag                     = "store.max-query-length"
 	maxPartialQueryLengthFlag              = "querier.max-partial-query-length"
 	maxTotalQueryLengthFlag                = "query-frontend.max-total-query-length"
 	maxQueryExpressionSizeBytesFlag        = "query-frontend.max-query-expression-size-bytes"
+	maxInstantQueryResultSeriesFlag        = "query-frontend.max-instant-query-result-series"
+	maxRangeQueryResultSamplesFlag         = "query-frontend.max-range-query-result-samples"
+	storeGatewayLabelsMaxSizeFlag          = "store-gateway.label-names-and-values-max-size-bytes"
+	storeGatewayMaxBlocksPerQueryFlag      = "store-gateway.max-blocks-per-query"
+	storeGatewayMaxPostingsBytesFlag       = "store-gateway.max-estimated-postings-bytes-per-query"
 	requestRateFlag                        = "distributor.request-rate-limit"
 	requestBurstSizeFlag                   = "distributor.request-burst-size"
+	requestRatePerSourceFlag               = "distributor.request-rate-limit-per-source"
+	requestBurstSizePerSourceFlag          = "distributor.request-burst-size-per-source"
+	requestRateSourceHeaderFlag            = "distributor.request-rate-limit-source-header"
 	ingestionRateFlag                      = "distributor.ingestion-rate-limit"
 	ingestionBurstSizeFlag                 = "distributor.ingestion-burst-size"
 	HATrackerMaxClustersFlag               = "distributor.ha-tracker.max-clusters"
@@ -52,8 +68,34 @@ const (
 
 	// MinCompactorPartialBlockDeletionDelay is the minimum partial blocks deletion delay that can be configured in Mimir.
 	MinCompactorPartialBlockDeletionDelay = 4 * time.Hour
+
+	// Policies applied to the series having both float and native histogram samples.
+	HistogramFloatConflictPolicyReject          = "reject"
+	HistogramFloatConflictPolicyPreferHistogram = "prefer-histogram"
+	HistogramFloatConflictPolicyPreferFloat     = "prefer-float"
+	HistogramFloatConflictPolicySplitSeries     = "split-series-with-suffix"
+
+	// HistogramFloatConflictSeriesSuffix is appended to the metric name of the series the native histogram
+	// samples are moved to by the split-series-with-suffix policy.
+	HistogramFloatConflictSeriesSuffix = "_histogram"
+
+	// Strategies translating the OTLP metric names to Prometheus metric names.
+	OTelTranslationStrategyUnderscoreEscapingWithoutSuffixes = "underscore-escaping-without-suffixes"
+	OTelTranslationStrategyUnderscoreEscapingWithSuffixes    = "underscore-escaping-with-suffixes"
 )
 
+var histogramFloatConflictPolicies = []string{
+	HistogramFloatConflictPolicyReject,
+	HistogramFloatConflictPolicyPreferHistogram,
+	HistogramFloatConflictPolicyPreferFloat,
+	HistogramFloatConflictPolicySplitSeries,
+}
+
+var otelTranslationStrategies = []string{
+	OTelTranslationStrategyUnderscoreEscapingWithoutSuffixes,
+	OTelTranslationStrategyUnderscoreEscapingWithSuffixes,
+}
+
 // LimitError are errors that do not comply with the limits specified.
 type LimitError string
 
@@ -69,27 +111,102 @@ type ForwardingRule struct {
 // ForwardingRules are keyed by metric names, excluding labels.
 type ForwardingRules map[string]ForwardingRule
 
+// BlockedQuery defines a query rejected by the query-frontend. A query is blocked either if it matches
+// the pattern, or if any of its vector selectors contains all the label matchers of the selector.
+type BlockedQuery struct {
+	// Pattern is compared to the whole query, or matched as a regular expression if Regex is true.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Regex   bool   `yaml:"regex,omitempty" json:"regex,omitempty"`
+
+	// Selector is a series selector, like {__name__="expensive_metric", job="dashboards"}.
+	Selector string `yaml:"selector,omitempty" json:"selector,omitempty"`
+}
+
+// Validate returns an error if the blocked query is invalid.
+func (q BlockedQuery) Validate() error {
+	if (q.Pattern == "") == (q.Selector == "") {
+		return fmt.Errorf("exactly one of the pattern and the selector of a blocked query must be set")
+	}
+	if q.Regex {
+		if _, err := regexp.Compile(q.Pattern); err != nil {
+			return fmt.Errorf("invalid blocked query regex %q: %w", q.Pattern, err)
+		}
+	}
+	if q.Selector != "" {
+		if _, err := parser.ParseMetricSelector(q.Selector); err != nil {
+			return fmt.Errorf("invalid blocked query selector %q: %w", q.Selector, err)
+		}
+	}
+	return nil
+}
+
+// IngestAggregationRule rolls up, at ingestion time, the series matching the selector into aggregated
+// series without the labels listed in Without. The rolled up series are expected to be counters: the value
+// of each aggregated series is the sum of their increases, computed every Interval.
+type IngestAggregationRule struct {
+	// Selector is a series selector, like {__name__="http_requests_total"}.
+	Selector string   `yaml:"selector" json:"selector"`
+	Without  []string `yaml:"without" json:"without"`
+
+	// Interval is the interval at which the aggregated series are computed. 0 to use the default interval.
+	Interval model.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+
+	// DropInput defines whether the series rolled up are not ingested.
+	DropInput bool `yaml:"drop_input,omitempty" json:"drop_input,omitempty"`
+}
+
+// Validate returns an error if the ingest aggregation rule is invalid.
+func (r IngestAggregationRule) Validate() error {
+	if _, err := parser.ParseMetricSelector(r.Selector); err != nil {
+		return fmt.Errorf("invalid ingest aggregation rule selector %q: %w", r.Selector, err)
+	}
+	if len(r.Without) == 0 {
+		return fmt.Errorf("the ingest aggregation rule with selector %q must drop at least one label", r.Selector)
+	}
+	for _, name := range r.Without {
+		if name == model.MetricNameLabel {
+			return fmt.Errorf("the ingest aggregation rule with selector %q can't drop the metric name", r.Selector)
+		}
+	}
+	return nil
+}
+
 // Limits describe all the limits for users; can be used to describe global default
 // limits via flags, or per-user limits via yaml config.
 type Limits struct {
 	// Distributor enforced limits.
-	RequestRate               float64             `yaml:"request_rate" json:"request_rate" category:"experimental"`
-	RequestBurstSize          int                 `yaml:"request_burst_size" json:"request_burst_size" category:"experimental"`
-	IngestionRate             float64             `yaml:"ingestion_rate" json:"ingestion_rate"`
-	IngestionBurstSize        int                 `yaml:"ingestion_burst_size" json:"ingestion_burst_size"`
-	AcceptHASamples           bool                `yaml:"accept_ha_samples" json:"accept_ha_samples"`
-	HAClusterLabel            string              `yaml:"ha_cluster_label" json:"ha_cluster_label"`
-	HAReplicaLabel            string              `yaml:"ha_replica_label" json:"ha_replica_label"`
-	HAMaxClusters             int                 `yaml:"ha_max_clusters" json:"ha_max_clusters"`
-	DropLabels                flagext.StringSlice `yaml:"drop_labels" json:"drop_labels" category:"advanced"`
-	MaxLabelNameLength        int                 `yaml:"max_label_name_length" json:"max_label_name_length"`
-	MaxLabelValueLength       int                 `yaml:"max_label_value_length" json:"max_label_value_length"`
-	MaxLabelNamesPerSeries    int                 `yaml:"max_label_names_per_series" json:"max_label_names_per_series"`
-	MaxMetadataLength         int                 `yaml:"max_metadata_length" json:"max_metadata_length"`
-	CreationGracePeriod       model.Duration      `yaml:"creation_grace_period" json:"creation_grace_period" category:"advanced"`
-	EnforceMetadataMetricName bool                `yaml:"enforce_metadata_metric_name" json:"enforce_metadata_metric_name" category:"advanced"`
-	IngestionTenantShardSize  int                 `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
-	MetricRelabelConfigs      []*relabel.Config   `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs." category:"experimental"`
+	RequestRate                 float64                 `yaml:"request_rate" json:"request_rate" category:"experimental"`
+	RequestBurstSize            int                     `yaml:"request_burst_size" json:"request_burst_size" category:"experimental"`
+	RequestRatePerSource        float64                 `yaml:"request_rate_per_source" json:"request_rate_per_source" category:"experimental"`
+	RequestBurstSizePerSource   int                     `yaml:"request_burst_size_per_source" json:"request_burst_size_per_source" category:"experimental"`
+	RequestRateSourceHeader     string                  `yaml:"request_rate_source_header" json:"request_rate_source_header" category:"experimental"`
+	IngestionRate               float64                 `yaml:"ingestion_rate" json:"ingestion_rate"`
+	IngestionBurstSize          int                     `yaml:"ingestion_burst_size" json:"ingestion_burst_size"`
+	AcceptHASamples             bool                    `yaml:"accept_ha_samples" json:"accept_ha_samples"`
+	HAClusterLabel              string                  `yaml:"ha_cluster_label" json:"ha_cluster_label"`
+	HAReplicaLabel              string                  `yaml:"ha_replica_label" json:"ha_replica_label"`
+	HAMaxClusters               int                     `yaml:"ha_max_clusters" json:"ha_max_clusters"`
+	DropLabels                  flagext.StringSlice     `yaml:"drop_labels" json:"drop_labels" category:"advanced"`
+	MaxLabelNameLength          int                     `yaml:"max_label_name_length" json:"max_label_name_length"`
+	MaxLabelValueLength         int                     `yaml:"max_label_value_length" json:"max_label_value_length"`
+	MaxLabelNamesPerSeries      int                     `yaml:"max_label_names_per_series" json:"max_label_names_per_series"`
+	MaxMetadataLength           int                     `yaml:"max_metadata_length" json:"max_metadata_length"`
+	MaxLabelsSizeBytes          int                     `yaml:"max_labels_size_bytes" json:"max_labels_size_bytes" category:"experimental"`
+	MaxMetadataSizeBytes        int                     `yaml:"max_metadata_size_bytes" json:"max_metadata_size_bytes" category:"experimental"`
+	CreationGracePeriod         model.Duration          `yaml:"creation_grace_period" json:"creation_grace_period" category:"advanced"`
+	FutureTimestampsClampWindow model.Duration          `yaml:"future_timestamps_clamp_window" json:"future_timestamps_clamp_window" category:"experimental"`
+	EnforceMetadataMetricName   bool                    `yaml:"enforce_metadata_metric_name" json:"enforce_metadata_metric_name" category:"advanced"`
+	IngestionTenantShardSize    int                     `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
+	IngestionReplicationFactor  int                     `yaml:"ingestion_replication_factor" json:"ingestion_replication_factor" category:"experimental"`
+	MetricRelabelConfigs        []*relabel.Config       `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs." category:"experimental"`
+	IngestAggregationRules      []IngestAggregationRule `yaml:"ingest_aggregation_rules,omitempty" json:"ingest_aggregation_rules,omitempty" doc:"nocli|description=List of rules rolling up series at ingestion time. The series matching the selector of a rule and having at least one of the labels listed in without are aggregated into series without those labels, whose value is the sum of the increases of the aggregated series, computed every interval (1m if not set). The aggregated series are counters, restarting from zero when an ingester restarts. If drop_input is true, the aggregated series are not ingested. The first matching rule applies." category:"experimental"`
+	// OTLP
+	OTelExponentialHistogramsDownscalingEnabled bool   `yaml:"otel_exponential_histograms_downscaling_enabled" json:"otel_exponential_histograms_downscaling_enabled" category:"experimental"`
+	OTelMinMaxSeriesEnabled                     bool   `yaml:"otel_min_max_series_enabled" json:"otel_min_max_series_enabled" category:"experimental"`
+	OTelTranslationStrategy                     string `yaml:"otel_translation_strategy" json:"otel_translation_strategy" category:"experimental"`
+	CreatedTimestampsIngestionEnabled           bool   `yaml:"created_timestamps_ingestion_enabled" json:"created_timestamps_ingestion_enabled" category:"experimental"`
+	// Native histograms
+	HistogramFloatConflictPolicy string `yaml:"histogram_float_conflict_policy" json:"histogram_float_conflict_policy" category:"experimental"`
 
 	// Ingester enforced limits.
 	// Series
@@ -107,6 +224,8 @@ type Limits struct {
 	// Max allowed time window for out-of-order samples.
 	OutOfOrderTimeWindow                 model.Duration `yaml:"out_of_order_time_window" json:"out_of_order_time_window" category:"experimental"`
 	OutOfOrderBlocksExternalLabelEnabled bool           `yaml:"out_of_order_blocks_external_label_enabled" json:"out_of_order_blocks_external_label_enabled" category:"experimental"`
+	// Min allowed interval between two samples of the same series.
+	MinSampleInterval model.Duration `yaml:"min_sample_interval" json:"min_sample_interval" category:"experimental"`
 
 	// User defined label to give the option of subdividing specific metrics by another label
 	SeparateMetricsGroupLabel string `yaml:"separate_metrics_group_label" json:"separate_metrics_group_label" category:"experimental"`
@@ -115,6 +234,7 @@ type Limits struct {
 	MaxChunksPerQuery              int            `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
 	MaxFetchedSeriesPerQuery       int            `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
 	MaxFetchedChunkBytesPerQuery   int            `yaml:"max_fetched_chunk_bytes_per_query" json:"max_fetched_chunk_bytes_per_query"`
+	MaxEstimatedMemoryPerQuery     int            `yaml:"max_estimated_memory_per_query" json:"max_estimated_memory_per_query" category:"experimental"`
 	MaxQueryLookback               model.Duration `yaml:"max_query_lookback" json:"max_query_lookback"`
 	MaxQueryLength                 model.Duration `yaml:"max_query_length" json:"max_query_length" doc:"hidden"` // TODO: deprecated, remove in 2.8
 	MaxPartialQueryLength          model.Duration `yaml:"max_partial_query_length" json:"max_partial_query_length"`
@@ -127,10 +247,17 @@ type Limits struct {
 	SplitInstantQueriesByInterval  model.Duration `yaml:"split_instant_queries_by_interval" json:"split_instant_queries_by_interval" category:"experimental"`
 
 	// Query-frontend limits.
-	MaxTotalQueryLength                    model.Duration `yaml:"max_total_query_length" json:"max_total_query_length"`
-	ResultsCacheTTL                        model.Duration `yaml:"results_cache_ttl" json:"results_cache_ttl" category:"experimental"`
-	ResultsCacheTTLForOutOfOrderTimeWindow model.Duration `yaml:"results_cache_ttl_for_out_of_order_time_window" json:"results_cache_ttl_for_out_of_order_time_window" category:"experimental"`
-	MaxQueryExpressionSizeBytes            int            `yaml:"max_query_expression_size_bytes" json:"max_query_expression_size_bytes" category:"experimental"`
+	MaxTotalQueryLength                       model.Duration `yaml:"max_total_query_length" json:"max_total_query_length"`
+	ResultsCacheTTL                           model.Duration `yaml:"results_cache_ttl" json:"results_cache_ttl" category:"experimental"`
+	ResultsCacheTTLForOutOfOrderTimeWindow    model.Duration `yaml:"results_cache_ttl_for_out_of_order_time_window" json:"results_cache_ttl_for_out_of_order_time_window" category:"experimental"`
+	ResultsCacheMaxEntrySizeBytes             int            `yaml:"results_cache_max_entry_size_bytes" json:"results_cache_max_entry_size_bytes" category:"experimental"`
+	ResultsCacheTTLForCardinalityQuery        model.Duration `yaml:"results_cache_ttl_for_cardinality_query" json:"results_cache_ttl_for_cardinality_query" category:"experimental"`
+	MaxQueryExpressionSizeBytes               int            `yaml:"max_query_expression_size_bytes" json:"max_query_expression_size_bytes" category:"experimental"`
+	MaxInstantQueryResultSeries               int            `yaml:"max_instant_query_result_series" json:"max_instant_query_result_series" category:"experimental"`
+	InstantQueryResultSeriesTruncationEnabled bool           `yaml:"instant_query_result_series_truncation_enabled" json:"instant_query_result_series_truncation_enabled" category:"experimental"`
+	MaxRangeQueryResultSamples                int            `yaml:"max_range_query_result_samples" json:"max_range_query_result_samples" category:"experimental"`
+	RangeQueryResultStepIncreaseEnabled       bool           `yaml:"range_query_result_step_increase_enabled" json:"range_query_result_step_increase_enabled" category:"experimental"`
+	BlockedQueries                            []BlockedQuery `yaml:"blocked_queries,omitempty" json:"blocked_queries,omitempty" doc:"nocli|description=List of queries to block. Each entry sets either a pattern, compared to the whole query or matched as a regular expression if regex is true, or a series selector blocking the queries with a vector selector containing all its label matchers." category:"experimental"`
 
 	// Cardinality
 	CardinalityAnalysisEnabled                    bool `yaml:"cardinality_analysis_enabled" json:"cardinality_analysis_enabled"`
@@ -144,19 +271,28 @@ type Limits struct {
 	RulerMaxRuleGroupsPerTenant          int            `yaml:"ruler_max_rule_groups_per_tenant" json:"ruler_max_rule_groups_per_tenant"`
 	RulerRecordingRulesEvaluationEnabled bool           `yaml:"ruler_recording_rules_evaluation_enabled" json:"ruler_recording_rules_evaluation_enabled" category:"experimental"`
 	RulerAlertingRulesEvaluationEnabled  bool           `yaml:"ruler_alerting_rules_evaluation_enabled" json:"ruler_alerting_rules_evaluation_enabled" category:"experimental"`
+	RulerRemoteEvaluationEnabled         bool           `yaml:"ruler_remote_evaluation_enabled" json:"ruler_remote_evaluation_enabled" category:"experimental"`
+	RulerMaxIndependentRuleConcurrency   int            `yaml:"ruler_max_independent_rule_concurrency" json:"ruler_max_independent_rule_concurrency" category:"experimental"`
+	RulerForStateRestoreLookback         model.Duration `yaml:"ruler_for_state_restore_lookback" json:"ruler_for_state_restore_lookback" category:"experimental"`
 
 	// Store-gateway.
-	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayTenantShardSize                 int            `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayLabelNamesAndValuesMaxSizeBytes int            `yaml:"store_gateway_label_names_and_values_max_size_bytes" json:"store_gateway_label_names_and_values_max_size_bytes" category:"experimental"`
+	StoreGatewayChunksCacheTTL                  model.Duration `yaml:"store_gateway_chunks_cache_ttl" json:"store_gateway_chunks_cache_ttl" category:"experimental"`
+	StoreGatewayChunksCacheBypass               bool           `yaml:"store_gateway_chunks_cache_bypass" json:"store_gateway_chunks_cache_bypass" category:"experimental"`
+	StoreGatewayMaxBlocksPerQuery               int            `yaml:"store_gateway_max_blocks_per_query" json:"store_gateway_max_blocks_per_query" category:"experimental"`
+	StoreGatewayMaxEstimatedPostingsBytes       int            `yaml:"store_gateway_max_estimated_postings_bytes_per_query" json:"store_gateway_max_estimated_postings_bytes_per_query" category:"experimental"`
 
 	// Compactor.
-	CompactorBlocksRetentionPeriod        model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
-	CompactorSplitAndMergeShards          int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
-	CompactorSplitGroups                  int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
-	CompactorTenantShardSize              int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
-	CompactorPartialBlockDeletionDelay    model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
-	CompactorBlockUploadEnabled           bool           `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
-	CompactorBlockUploadValidationEnabled bool           `yaml:"compactor_block_upload_validation_enabled" json:"compactor_block_upload_validation_enabled"`
-	CompactorBlockUploadVerifyChunks      bool           `yaml:"compactor_block_upload_verify_chunks" json:"compactor_block_upload_verify_chunks"`
+	CompactorBlocksRetentionPeriod        model.Duration          `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
+	CompactorSplitAndMergeShards          int                     `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
+	CompactorSplitGroups                  int                     `yaml:"compactor_split_groups" json:"compactor_split_groups"`
+	CompactorTenantShardSize              int                     `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
+	CompactorPartialBlockDeletionDelay    model.Duration          `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
+	CompactorBlockUploadEnabled           bool                    `yaml:"compactor_block_upload_enabled" json:"compactor_block_upload_enabled"`
+	CompactorBlockUploadValidationEnabled bool                    `yaml:"compactor_block_upload_validation_enabled" json:"compactor_block_upload_validation_enabled"`
+	CompactorBlockUploadVerifyChunks      bool                    `yaml:"compactor_block_upload_verify_chunks" json:"compactor_block_upload_verify_chunks"`
+	CompactorBlockRanges                  mimir_tsdb.DurationList `yaml:"compactor_block_ranges,omitempty" json:"compactor_block_ranges,omitempty" doc:"nocli|description=List of compaction time ranges for the tenant. Each range should be divisible by the previous one, and the first one should be a multiple of the TSDB block range. If empty, the compaction time ranges configured with -compactor.block-ranges are used." category:"experimental"`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -188,8 +324,12 @@ type Limits struct {
 // RegisterFlags adds the flags required to config this to the given FlagSet
 func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.IngestionTenantShardSize, "distributor.ingestion-tenant-shard-size", 0, "The tenant's shard size used by shuffle-sharding. Must be set both on ingesters and distributors. 0 disables shuffle sharding.")
+	f.IntVar(&l.IngestionReplicationFactor, "distributor.ingestion-replication-factor", 0, "The tenant's replication factor, used to write series to and read series from ingesters. It can only lower the ingesters ring replication factor, which is used when 0 or greater than the ingesters ring replication factor.")
 	f.Float64Var(&l.RequestRate, requestRateFlag, 0, "Per-tenant request rate limit in requests per second. 0 to disable.")
 	f.IntVar(&l.RequestBurstSize, requestBurstSizeFlag, 0, "Per-tenant allowed request burst size. 0 to disable.")
+	f.Float64Var(&l.RequestRatePerSource, requestRatePerSourceFlag, 0, "Per-source request rate limit in requests per second, applied to each source of write requests within the tenant in addition to the per-tenant request rate limit. The source is identified by -"+requestRateSourceHeaderFlag+". 0 to disable.")
+	f.IntVar(&l.RequestBurstSizePerSource, requestBurstSizePerSourceFlag, 0, "Per-source allowed request burst size. 0 to disable.")
+	f.StringVar(&l.RequestRateSourceHeader, requestRateSourceHeaderFlag, "", "Name of the HTTP header identifying the source of write requests for the per-source request rate limit, like an API key or an agent ID. Requests without the header are not subject to the per-source limit. The header value is combined with the address of the peer which sent the request, so that a client can't consume the limit of the other clients by sending their header value. If empty, the address of the peer which sent the request is used, ignoring the forwarding headers.")
 	f.Float64Var(&l.IngestionRate, ingestionRateFlag, 10000, "Per-tenant ingestion rate limit in samples per second.")
 	f.IntVar(&l.IngestionBurstSize, ingestionBurstSizeFlag, 200000, "Per-tenant allowed ingestion burst size (in number of samples).")
 	f.BoolVar(&l.AcceptHASamples, "distributor.ha-tracker.enable-for-all-users", false, "Flag to enable, for all tenants, handling of samples with external labels identifying replicas in an HA Prometheus setup.")
@@ -201,9 +341,17 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.MaxLabelValueLength, maxLabelValueLengthFlag, 2048, "Maximum length accepted for label value. This setting also applies to the metric name")
 	f.IntVar(&l.MaxLabelNamesPerSeries, maxLabelNamesPerSeriesFlag, 30, "Maximum number of label names per series.")
 	f.IntVar(&l.MaxMetadataLength, maxMetadataLengthFlag, 1024, "Maximum length accepted for metric metadata. Metadata refers to Metric Name, HELP and UNIT. Longer metadata is dropped except for HELP which is truncated.")
+	f.IntVar(&l.MaxLabelsSizeBytes, maxLabelsSizeBytesFlag, 0, "Maximum combined size in bytes of all label names and values of a series, including the metric name. Series exceeding the limit are discarded. 0 to disable.")
+	f.IntVar(&l.MaxMetadataSizeBytes, maxMetadataSizeBytesFlag, 0, "Maximum combined size in bytes of the metric name, HELP and UNIT of a metric metadata, after HELP has been truncated to -"+maxMetadataLengthFlag+". Metadata exceeding the limit is discarded. 0 to disable.")
 	_ = l.CreationGracePeriod.Set("10m")
 	f.Var(&l.CreationGracePeriod, creationGracePeriodFlag, "Controls how far into the future incoming samples are accepted compared to the wall clock. Any sample with timestamp `t` will be rejected if `t > (now + validation.create-grace-period)`. Also used by query-frontend to avoid querying too far into the future. 0 to disable.")
+	f.Var(&l.FutureTimestampsClampWindow, "validation.future-timestamps-clamp-window", "Samples with a timestamp beyond -"+creationGracePeriodFlag+" but no more than this duration into the future compared to the wall clock have their timestamp clamped to the current time instead of being rejected. Useful for clients with a slight clock skew. Samples further into the future are rejected. Clamping is disabled if -validation.create-grace-period is 0. 0 to disable.")
 	f.BoolVar(&l.EnforceMetadataMetricName, "validation.enforce-metadata-metric-name", true, "Enforce every metadata has a metric name.")
+	f.BoolVar(&l.OTelExponentialHistogramsDownscalingEnabled, "distributor.otel-exponential-histograms-downscaling-enabled", false, "Whether to downscale OTLP exponential histograms with a scale greater than the maximum schema supported by native histograms, merging their buckets, so that they can be converted to native histograms. If false, such exponential histograms are dropped.")
+	f.BoolVar(&l.OTelMinMaxSeriesEnabled, "distributor.otel-min-max-series-enabled", false, "Whether to ingest the min and max of the values observed by OTLP histograms and exponential histograms, when their data points carry them, as the <name>_min and <name>_max gauges. Unlike histograms, the gauges keep their min and max in downsampled blocks, so long-range queries can show the peaks.")
+	f.StringVar(&l.OTelTranslationStrategy, "distributor.otel-translation-strategy", OTelTranslationStrategyUnderscoreEscapingWithoutSuffixes, fmt.Sprintf("Strategy translating the OTLP metric names to Prometheus metric names. Supported values: %s. The %s strategy replaces the characters not supported by Prometheus with underscores. The %s strategy also follows the Prometheus naming conventions, appending the unit and the _total suffix of counters to the metric names. Changing the strategy changes the names of the ingested metrics, so it can be rolled out per tenant.", strings.Join(otelTranslationStrategies, ", "), OTelTranslationStrategyUnderscoreEscapingWithoutSuffixes, OTelTranslationStrategyUnderscoreEscapingWithSuffixes))
+	f.StringVar(&l.HistogramFloatConflictPolicy, histogramFloatConflictPolicyFlag, "", fmt.Sprintf("Policy applied to the series received with both float and native histogram samples in the same write request, which may conflict in the ingesters. Supported values: %s. The %s policy moves the native histogram samples to a separate series, whose metric name has the %s suffix. If empty, the series are ingested as they are.", strings.Join(histogramFloatConflictPolicies, ", "), HistogramFloatConflictPolicySplitSeries, HistogramFloatConflictSeriesSuffix))
+	f.BoolVar(&l.CreatedTimestampsIngestionEnabled, "distributor.created-timestamps-ingestion-enabled", false, "Whether to ingest the created timestamps of counters and histograms, like the start timestamps of OTLP cumulative data points, as zero samples preceding the series samples. This allows rate() and increase() to account for the increase since a counter has been created or reset, for example after a restart. If false, the created timestamps are dropped.")
 
 	f.IntVar(&l.MaxGlobalSeriesPerUser, MaxSeriesPerUserFlag, 150000, "The maximum number of in-memory series per tenant, across the cluster before replication. 0 to disable.")
 	f.IntVar(&l.MaxGlobalSeriesPerMetric, MaxSeriesPerMetricFlag, 0, "The maximum number of in-memory series per metric name, across the cluster before replication. 0 to disable.")
@@ -214,6 +362,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.ActiveSeriesCustomTrackersConfig, "ingester.active-series-custom-trackers", "Additional active series metrics, matching the provided matchers. Matchers should be in form <name>:<matcher>, like 'foobar:{foo=\"bar\"}'. Multiple matchers can be provided either providing the flag multiple times or providing multiple semicolon-separated values to a single flag.")
 	f.Var(&l.OutOfOrderTimeWindow, "ingester.out-of-order-time-window", fmt.Sprintf("Non-zero value enables out-of-order support for most recent samples that are within the time window in relation to the TSDB's maximum time, i.e., within [db.maxTime-timeWindow, db.maxTime]). The ingester will need more memory as a factor of rate of out-of-order samples being ingested and the number of series that are getting out-of-order samples. If query falls into this window, cached results will use value from -%s option to specify TTL for resulting cache entry.", resultsCacheTTLForOutOfOrderWindowFlag))
 	f.BoolVar(&l.NativeHistogramsIngestionEnabled, "ingester.native-histograms-ingestion-enabled", false, "Enable ingestion of native histogram samples. If false, native histogram samples are ignored without an error. To query native histograms with query-sharding enabled make sure to set -query-frontend.query-result-response-format to 'protobuf'.")
+	f.Var(&l.MinSampleInterval, "ingester.min-sample-interval", "The minimum interval between two samples of the same series. Samples received more frequently than this interval are discarded. 0 to disable.")
 	f.BoolVar(&l.OutOfOrderBlocksExternalLabelEnabled, "ingester.out-of-order-blocks-external-label-enabled", false, "Whether the shipper should label out-of-order blocks with an external label before uploading them. Setting this label will compact out-of-order blocks separately from non-out-of-order blocks")
 
 	f.StringVar(&l.SeparateMetricsGroupLabel, "validation.separate-metrics-group-label", "", "Label used to define the group label for metrics separation. For each write request, the group is obtained from the first non-empty group label from the first timeseries in the incoming list of timeseries. Specific distributor and ingester metrics will be further separated adding a 'group' label with group label's value. Currently applies to the following metrics: cortex_discarded_samples_total")
@@ -221,6 +370,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.MaxChunksPerQuery, MaxChunksPerQueryFlag, 2e6, "Maximum number of chunks that can be fetched in a single query from ingesters and long-term storage. This limit is enforced in the querier, ruler and store-gateway. 0 to disable.")
 	f.IntVar(&l.MaxFetchedSeriesPerQuery, MaxSeriesPerQueryFlag, 0, "The maximum number of unique series for which a query can fetch samples from each ingesters and storage. This limit is enforced in the querier, ruler and store-gateway. 0 to disable")
 	f.IntVar(&l.MaxFetchedChunkBytesPerQuery, MaxChunkBytesPerQueryFlag, 0, "The maximum size of all chunks in bytes that a query can fetch from each ingester and storage. This limit is enforced in the querier and ruler. 0 to disable.")
+	f.IntVar(&l.MaxEstimatedMemoryPerQuery, MaxEstimatedMemoryPerQueryFlag, 0, "The maximum estimated memory, in bytes, taken by the series labels, chunks and samples a query fetches from ingesters and storage. Queries exceeding the limit are failed instead of running the querier out of memory. This limit is enforced in the querier and ruler. When the query-frontend cardinality-based query sharding is enabled, the query-frontend also rejects before execution the queries whose memory, estimated from previous executions of the same query, exceeds the limit. 0 to disable.")
 	// TODO: Deprecated in Mimir 2.6, remove in Mimir 2.8
 	f.Var(&l.MaxQueryLength, maxQueryLengthFlag, fmt.Sprintf("Deprecated: Limit the query time range (end - start time). This limit is enforced in the querier (on the query possibly split by the query-frontend) and ruler. 0 to disable. This option is deprecated, use -%s or -%s instead.", maxPartialQueryLengthFlag, maxTotalQueryLengthFlag))
 	f.Var(&l.MaxPartialQueryLength, maxPartialQueryLengthFlag, fmt.Sprintf("Limit the time range for partial queries at the querier level. Defaults to the value of -%s if set to 0.", maxQueryLengthFlag))
@@ -245,6 +395,9 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.RulerMaxRuleGroupsPerTenant, "ruler.max-rule-groups-per-tenant", 70, "Maximum number of rule groups per-tenant. 0 to disable.")
 	f.BoolVar(&l.RulerRecordingRulesEvaluationEnabled, "ruler.recording-rules-evaluation-enabled", true, "Controls whether recording rules evaluation is enabled. This configuration option can be used to forcefully disable recording rules evaluation on a per-tenant basis.")
 	f.BoolVar(&l.RulerAlertingRulesEvaluationEnabled, "ruler.alerting-rules-evaluation-enabled", true, "Controls whether alerting rules evaluation is enabled. This configuration option can be used to forcefully disable alerting rules evaluation on a per-tenant basis.")
+	f.BoolVar(&l.RulerRemoteEvaluationEnabled, "ruler.remote-evaluation-enabled", true, "Controls whether the rule expressions are evaluated against the query-frontend configured via -ruler.query-frontend.address. If false, or if the query-frontend address is not configured, the rule expressions are evaluated by the querier embedded in the ruler.")
+	f.IntVar(&l.RulerMaxIndependentRuleConcurrency, "ruler.max-independent-rule-concurrency", 0, "Maximum number of queries of independent rules evaluated concurrently, across all the rule groups of the tenant. A rule is independent if its query doesn't read the series written by the rules of its rule group. At each evaluation of a rule group, the queries of its independent rules are evaluated concurrently, while the results of all the rules are still written in the order of the rule group. 0 to evaluate all rules sequentially.")
+	f.Var(&l.RulerForStateRestoreLookback, "ruler.for-state-restore-lookback", "How far back the ALERTS_FOR_STATE series are queried to restore the \"for\" state of the alerts when the rule groups are loaded. When greater than -ruler.for-outage-tolerance, the state of the alerts is restored after ruler outages up to this lookback, and the ALERTS_FOR_STATE series older than the ingesters retention are queried from the store-gateways. 0 to use -ruler.for-outage-tolerance.")
 
 	f.Var(&l.CompactorBlocksRetentionPeriod, "compactor.blocks-retention-period", "Delete blocks containing samples older than the specified retention period. Also used by query-frontend to avoid querying beyond the retention period. 0 to disable.")
 	f.IntVar(&l.CompactorSplitAndMergeShards, "compactor.split-and-merge-shards", 0, "The number of shards to use when splitting blocks. 0 to disable splitting.")
@@ -261,10 +414,21 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.ResultsCacheTTL, resultsCacheTTLFlag, fmt.Sprintf("Time to live duration for cached query results. If query falls into out-of-order time window, -%s is used instead.", resultsCacheTTLForOutOfOrderWindowFlag))
 	_ = l.ResultsCacheTTLForOutOfOrderTimeWindow.Set("10m")
 	f.Var(&l.ResultsCacheTTLForOutOfOrderTimeWindow, resultsCacheTTLForOutOfOrderWindowFlag, fmt.Sprintf("Time to live duration for cached query results if query falls into out-of-order time window. This is lower than -%s so that incoming out-of-order samples are returned in the query results sooner.", resultsCacheTTLFlag))
+	f.IntVar(&l.ResultsCacheMaxEntrySizeBytes, "query-frontend.results-cache-max-entry-size-bytes", 0, "Maximum size, in bytes, of a query results cache entry, before compression. Query results larger than this limit are not cached, so that a tenant running queries with large results doesn't evict the cached results of other tenants. 0 to disable.")
+	f.Var(&l.ResultsCacheTTLForCardinalityQuery, "query-frontend.results-cache-ttl-for-cardinality-query", "Time to live duration for cached cardinality query results. The cardinality analysis API responses are cached only when the query results cache is enabled. 0 to disable.")
 	f.IntVar(&l.MaxQueryExpressionSizeBytes, maxQueryExpressionSizeBytesFlag, 0, "Max size of the raw query, in bytes. 0 to not apply a limit to the size of the query.")
+	f.IntVar(&l.MaxInstantQueryResultSeries, maxInstantQueryResultSeriesFlag, 0, "Maximum number of series returned in the result of an instant query. If the limit is exceeded, the query fails, unless -query-frontend.instant-query-result-series-truncation-enabled is true. 0 to disable.")
+	f.BoolVar(&l.InstantQueryResultSeriesTruncationEnabled, "query-frontend.instant-query-result-series-truncation-enabled", false, fmt.Sprintf("Whether to truncate the result of an instant query exceeding -%s to the series with the highest values, adding a warning to the response, instead of failing the query.", maxInstantQueryResultSeriesFlag))
+	f.IntVar(&l.MaxRangeQueryResultSamples, maxRangeQueryResultSamplesFlag, 0, "Maximum number of samples returned in the result of a range query, summed across all series. If the limit is exceeded, the query fails, unless -query-frontend.range-query-result-step-increase-enabled is true. 0 to disable.")
+	f.BoolVar(&l.RangeQueryResultStepIncreaseEnabled, "query-frontend.range-query-result-step-increase-enabled", false, fmt.Sprintf("Whether to increase the step of a range query whose result exceeds -%s to the smallest multiple of the requested step keeping the result within the limit, decimating the result and adding a warning to the response, instead of failing the query.", maxRangeQueryResultSamplesFlag))
 
 	// Store-gateway.
 	f.IntVar(&l.StoreGatewayTenantShardSize, "store-gateway.tenant-shard-size", 0, "The tenant's shard size, used when store-gateway sharding is enabled. Value of 0 disables shuffle sharding for the tenant, that is all tenant blocks are sharded across all store-gateway replicas.")
+	f.IntVar(&l.StoreGatewayLabelNamesAndValuesMaxSizeBytes, storeGatewayLabelsMaxSizeFlag, 0, "Maximum size, in bytes, of the label names or label values returned by a store-gateway for a single request. If the limit is exceeded, the request fails. 0 to disable.")
+	f.Var(&l.StoreGatewayChunksCacheTTL, "store-gateway.chunks-cache-ttl", "TTL of the tenant's chunks stored in the chunks cache by the store-gateway. 0 to use the TTL configured for the chunks cache.")
+	f.BoolVar(&l.StoreGatewayChunksCacheBypass, "store-gateway.chunks-cache-bypass", false, "If enabled, the store-gateway doesn't read or store the tenant's chunks from or to the chunks cache.")
+	f.IntVar(&l.StoreGatewayMaxBlocksPerQuery, storeGatewayMaxBlocksPerQueryFlag, 0, "Maximum number of blocks a single series request to a store-gateway can touch. The request is rejected before fetching any series or chunk if the limit is exceeded. 0 to disable.")
+	f.IntVar(&l.StoreGatewayMaxEstimatedPostingsBytes, storeGatewayMaxPostingsBytesFlag, 0, "Maximum size, in bytes, of the postings a single series request to a store-gateway is estimated to fetch. The estimate is computed from the index-header of the queried blocks, and the request is rejected before fetching any series or chunk if the limit is exceeded. 0 to disable.")
 
 	// Alertmanager.
 	f.Var(&l.AlertmanagerReceiversBlockCIDRNetworks, "alertmanager.receivers-firewall-block-cidr-networks", "Comma-separated list of network CIDRs to block in Alertmanager receiver integrations.")
@@ -328,6 +492,48 @@ func (l *Limits) validate() error {
 		}
 	}
 
+	if l.HistogramFloatConflictPolicy != "" && !slices.Contains(histogramFloatConflictPolicies, l.HistogramFloatConflictPolicy) {
+		return fmt.Errorf("invalid histogram_float_conflict_policy: supported values are %s", strings.Join(histogramFloatConflictPolicies, ", "))
+	}
+
+	if l.OTelTranslationStrategy != "" && !slices.Contains(otelTranslationStrategies, l.OTelTranslationStrategy) {
+		return fmt.Errorf("invalid otel_translation_strategy: supported values are %s", strings.Join(otelTranslationStrategies, ", "))
+	}
+
+	if l.IngestionReplicationFactor < 0 {
+		return fmt.Errorf("invalid ingestion_replication_factor: must be greater than or equal to 0")
+	}
+
+	for _, q := range l.BlockedQueries {
+		if err := q.Validate(); err != nil {
+			return fmt.Errorf("invalid blocked_queries: %w", err)
+		}
+	}
+
+	for _, r := range l.IngestAggregationRules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("invalid ingest_aggregation_rules: %w", err)
+		}
+	}
+
+	for i, r := range l.CompactorBlockRanges {
+		if r <= 0 {
+			return fmt.Errorf("invalid compactor_block_ranges: %s is not a positive duration", r)
+		}
+		if i > 0 && r%l.CompactorBlockRanges[i-1] != 0 {
+			return fmt.Errorf("invalid compactor_block_ranges: %s is not divisible by %s", r, l.CompactorBlockRanges[i-1])
+		}
+	}
+
+	return nil
+}
+
+// ValidateCompactorBlockRanges returns an error if the first of the compactor_block_ranges isn't a multiple of
+// the TSDB block range, because the blocks shipped by the ingesters couldn't be compacted into it.
+func (l *Limits) ValidateCompactorBlockRanges(tsdbBlockRange time.Duration) error {
+	if len(l.CompactorBlockRanges) > 0 && tsdbBlockRange > 0 && l.CompactorBlockRanges[0]%tsdbBlockRange != 0 {
+		return fmt.Errorf("invalid compactor_block_ranges: %s is not a multiple of the TSDB block range %s", l.CompactorBlockRanges[0], tsdbBlockRange)
+	}
 	return nil
 }
 
@@ -385,6 +591,21 @@ func (o *Overrides) RequestBurstSize(userID string) int {
 	return o.getOverridesForUser(userID).RequestBurstSize
 }
 
+// RequestRatePerSource returns the limit on request rate (requests per second) of each source of write requests.
+func (o *Overrides) RequestRatePerSource(userID string) float64 {
+	return o.getOverridesForUser(userID).RequestRatePerSource
+}
+
+// RequestBurstSizePerSource returns the burst size for request rate of each source of write requests.
+func (o *Overrides) RequestBurstSizePerSource(userID string) int {
+	return o.getOverridesForUser(userID).RequestBurstSizePerSource
+}
+
+// RequestRateSourceHeader returns the name of the HTTP header identifying the source of write requests.
+func (o *Overrides) RequestRateSourceHeader(userID string) string {
+	return o.getOverridesForUser(userID).RequestRateSourceHeader
+}
+
 // IngestionRate returns the limit on ingester rate (samples per second).
 func (o *Overrides) IngestionRate(userID string) float64 {
 	return o.getOverridesForUser(userID).IngestionRate
@@ -451,12 +672,33 @@ func (o *Overrides) MaxMetadataLength(userID string) int {
 	return o.getOverridesForUser(userID).MaxMetadataLength
 }
 
+// MaxLabelsSizeBytes returns the maximum combined size in bytes of the label names and values of a series.
+func (o *Overrides) MaxLabelsSizeBytes(userID string) int {
+	return o.getOverridesForUser(userID).MaxLabelsSizeBytes
+}
+
+// MaxMetadataSizeBytes returns the maximum combined size in bytes of the metric name, HELP and UNIT of a metric metadata.
+func (o *Overrides) MaxMetadataSizeBytes(userID string) int {
+	return o.getOverridesForUser(userID).MaxMetadataSizeBytes
+}
+
 // CreationGracePeriod is misnamed, and actually returns how far into the future
 // we should accept samples.
 func (o *Overrides) CreationGracePeriod(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).CreationGracePeriod)
 }
 
+// FutureTimestampsClampWindow returns how far into the future samples have their timestamp clamped
+// to the current time, instead of being rejected.
+func (o *Overrides) FutureTimestampsClampWindow(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).FutureTimestampsClampWindow)
+}
+
+// HistogramFloatConflictPolicy returns the policy applied to the series having both float and native histogram samples.
+func (o *Overrides) HistogramFloatConflictPolicy(userID string) string {
+	return o.getOverridesForUser(userID).HistogramFloatConflictPolicy
+}
+
 // MaxGlobalSeriesPerUser returns the maximum number of series a user is allowed to store across the cluster.
 func (o *Overrides) MaxGlobalSeriesPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerUser
@@ -483,6 +725,12 @@ func (o *Overrides) MaxFetchedChunkBytesPerQuery(userID string) int {
 	return o.getOverridesForUser(userID).MaxFetchedChunkBytesPerQuery
 }
 
+// MaxEstimatedMemoryPerQuery returns the maximum estimated memory, in bytes, taken by the series and chunks
+// fetched by a query from ingesters and blocks storage.
+func (o *Overrides) MaxEstimatedMemoryPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).MaxEstimatedMemoryPerQuery
+}
+
 // MaxQueryLookback returns the max lookback period of queries.
 func (o *Overrides) MaxQueryLookback(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxQueryLookback)
@@ -516,6 +764,33 @@ func (o *Overrides) MaxQueryExpressionSizeBytes(userID string) int {
 	return o.getOverridesForUser(userID).MaxQueryExpressionSizeBytes
 }
 
+// MaxInstantQueryResultSeries returns the limit of the number of series returned in the result of an instant query.
+func (o *Overrides) MaxInstantQueryResultSeries(userID string) int {
+	return o.getOverridesForUser(userID).MaxInstantQueryResultSeries
+}
+
+// InstantQueryResultSeriesTruncationEnabled returns whether the result of an instant query exceeding the max number
+// of series should be truncated instead of failing the query.
+func (o *Overrides) InstantQueryResultSeriesTruncationEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).InstantQueryResultSeriesTruncationEnabled
+}
+
+// MaxRangeQueryResultSamples returns the limit of the number of samples returned in the result of a range query.
+func (o *Overrides) MaxRangeQueryResultSamples(userID string) int {
+	return o.getOverridesForUser(userID).MaxRangeQueryResultSamples
+}
+
+// RangeQueryResultStepIncreaseEnabled returns whether the step of a range query exceeding the max number of
+// result samples should be increased instead of failing the query.
+func (o *Overrides) RangeQueryResultStepIncreaseEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).RangeQueryResultStepIncreaseEnabled
+}
+
+// BlockedQueries returns the queries blocked by the query-frontend for the tenant.
+func (o *Overrides) BlockedQueries(userID string) []BlockedQuery {
+	return o.getOverridesForUser(userID).BlockedQueries
+}
+
 // MaxLabelsQueryLength returns the limit of the length (in time) of a label names or values request.
 func (o *Overrides) MaxLabelsQueryLength(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxLabelsQueryLength)
@@ -585,6 +860,11 @@ func (o *Overrides) OutOfOrderTimeWindow(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).OutOfOrderTimeWindow)
 }
 
+// MinSampleInterval returns the minimum interval between two samples of the same series for the user.
+func (o *Overrides) MinSampleInterval(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).MinSampleInterval)
+}
+
 // OutOfOrderBlocksExternalLabelEnabled returns if the shipper is flagging out-of-order blocks with an external label.
 func (o *Overrides) OutOfOrderBlocksExternalLabelEnabled(userID string) bool {
 	return o.getOverridesForUser(userID).OutOfOrderBlocksExternalLabelEnabled
@@ -600,6 +880,12 @@ func (o *Overrides) IngestionTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).IngestionTenantShardSize
 }
 
+// IngestionReplicationFactor returns the replication factor of the series ingested for a given user.
+// 0 means the ingesters ring replication factor is used.
+func (o *Overrides) IngestionReplicationFactor(userID string) int {
+	return o.getOverridesForUser(userID).IngestionReplicationFactor
+}
+
 // CompactorTenantShardSize returns number of compactors that this user can use. 0 = all compactors.
 func (o *Overrides) CompactorTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).CompactorTenantShardSize
@@ -620,6 +906,12 @@ func (o *Overrides) CompactorSplitAndMergeShards(userID string) int {
 	return o.getOverridesForUser(userID).CompactorSplitAndMergeShards
 }
 
+// CompactorBlockRanges returns the compaction time ranges for a given user. An empty list means
+// the compactor's default ranges should be used.
+func (o *Overrides) CompactorBlockRanges(userID string) mimir_tsdb.DurationList {
+	return o.getOverridesForUser(userID).CompactorBlockRanges
+}
+
 // CompactorSplitGroups returns the number of groups that blocks for splitting should be grouped into.
 func (o *Overrides) CompactorSplitGroups(userID string) int {
 	return o.getOverridesForUser(userID).CompactorSplitGroups
@@ -659,6 +951,33 @@ func (o *Overrides) MetricRelabelConfigs(userID string) []*relabel.Config {
 	return o.getOverridesForUser(userID).MetricRelabelConfigs
 }
 
+// IngestAggregationRules returns the rules rolling up the tenant's series at ingestion time.
+func (o *Overrides) IngestAggregationRules(userID string) []IngestAggregationRule {
+	return o.getOverridesForUser(userID).IngestAggregationRules
+}
+
+// OTelExponentialHistogramsDownscalingEnabled returns whether OTLP exponential histograms with a scale
+// not supported by native histograms should be downscaled instead of dropped.
+func (o *Overrides) OTelExponentialHistogramsDownscalingEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).OTelExponentialHistogramsDownscalingEnabled
+}
+
+// OTelMinMaxSeriesEnabled returns whether the min and max of OTLP histograms should be ingested as gauges.
+func (o *Overrides) OTelMinMaxSeriesEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).OTelMinMaxSeriesEnabled
+}
+
+// OTelTranslationStrategy returns the strategy translating the OTLP metric names to Prometheus metric names.
+func (o *Overrides) OTelTranslationStrategy(userID string) string {
+	return o.getOverridesForUser(userID).OTelTranslationStrategy
+}
+
+// CreatedTimestampsIngestionEnabled returns whether the created timestamps of counters and histograms
+// should be ingested as zero samples.
+func (o *Overrides) CreatedTimestampsIngestionEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).CreatedTimestampsIngestionEnabled
+}
+
 // NativeHistogramsIngestionEnabled returns whether to ingest native histograms in the ingester
 func (o *Overrides) NativeHistogramsIngestionEnabled(userID string) bool {
 	return o.getOverridesForUser(userID).NativeHistogramsIngestionEnabled
@@ -689,11 +1008,55 @@ func (o *Overrides) RulerAlertingRulesEvaluationEnabled(userID string) bool {
 	return o.getOverridesForUser(userID).RulerAlertingRulesEvaluationEnabled
 }
 
+// RulerRemoteEvaluationEnabled returns whether the rule expressions of a given user are evaluated against the query-frontend.
+func (o *Overrides) RulerRemoteEvaluationEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).RulerRemoteEvaluationEnabled
+}
+
+// RulerMaxIndependentRuleConcurrency returns the maximum number of queries of independent rules of a given user evaluated concurrently.
+func (o *Overrides) RulerMaxIndependentRuleConcurrency(userID string) int {
+	return o.getOverridesForUser(userID).RulerMaxIndependentRuleConcurrency
+}
+
+// RulerForStateRestoreLookback returns how far back the ALERTS_FOR_STATE series of a given user are queried to restore the "for" state of the alerts.
+func (o *Overrides) RulerForStateRestoreLookback(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerForStateRestoreLookback)
+}
+
 // StoreGatewayTenantShardSize returns the store-gateway shard size for a given user.
 func (o *Overrides) StoreGatewayTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).StoreGatewayTenantShardSize
 }
 
+// StoreGatewayChunksCacheTTL returns the TTL of the tenant's chunks stored in the chunks cache.
+// 0 means the TTL configured for the chunks cache is used.
+func (o *Overrides) StoreGatewayChunksCacheTTL(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).StoreGatewayChunksCacheTTL)
+}
+
+// StoreGatewayChunksCacheBypass returns whether the tenant's chunks should bypass the chunks cache.
+func (o *Overrides) StoreGatewayChunksCacheBypass(userID string) bool {
+	return o.getOverridesForUser(userID).StoreGatewayChunksCacheBypass
+}
+
+// StoreGatewayMaxBlocksPerQuery returns the maximum number of blocks a single series request
+// to a store-gateway can touch.
+func (o *Overrides) StoreGatewayMaxBlocksPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayMaxBlocksPerQuery
+}
+
+// StoreGatewayMaxEstimatedPostingsBytes returns the maximum size, in bytes, of the postings
+// a single series request to a store-gateway is estimated to fetch.
+func (o *Overrides) StoreGatewayMaxEstimatedPostingsBytes(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayMaxEstimatedPostingsBytes
+}
+
+// StoreGatewayLabelNamesAndValuesMaxSizeBytes returns the maximum size, in bytes, of the label names or
+// label values returned by a store-gateway for a single request.
+func (o *Overrides) StoreGatewayLabelNamesAndValuesMaxSizeBytes(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayLabelNamesAndValuesMaxSizeBytes
+}
+
 // MaxHAClusters returns maximum number of clusters that HA tracker will track for a user.
 func (o *Overrides) MaxHAClusters(user string) int {
 	return o.getOverridesForUser(user).HAMaxClusters
@@ -818,6 +1181,19 @@ func (o *Overrides) ResultsCacheTTLForOutOfOrderTimeWindow(user string) time.Dur
 	return time.Duration(o.getOverridesForUser(user).ResultsCacheTTLForOutOfOrderTimeWindow)
 }
 
+func (o *Overrides) ResultsCacheMaxEntrySizeBytes(user string) int {
+	return o.getOverridesForUser(user).ResultsCacheMaxEntrySizeBytes
+}
+
+func (o *Overrides) ResultsCacheTTLForCardinalityQuery(user string) time.Duration {
+	return time.Duration(o.getOverridesForUser(user).ResultsCacheTTLForCardinalityQuery)
+}
+
+// HasTenantOverrides returns whether the tenant has per-tenant limits overriding the default ones.
+func (o *Overrides) HasTenantOverrides(userID string) bool {
+	return o.tenantLimits != nil && o.tenantLimits.ByUserID(userID) != nil
+}
+
 func (o *Overrides) getOverridesForUser(userID string) *Limits {
 	if o.tenantLimits != nil {
 		l := o.tenantLimits.ByUserID(userID)
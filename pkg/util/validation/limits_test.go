@@ -7,6 +7,7 @@ package validation
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -20,6 +21,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 )
 
 func TestOverridesManager_GetOverrides(t *testing.T) {
@@ -647,6 +649,133 @@ metric_relabel_configs:
 	})
 }
 
+func TestUnmarshalBlockedQueries(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+blocked_queries:
+  - pattern: sum(rate(http_requests_total[30d]))
+  - pattern: rate\(.*\[\d+d\]\)
+    regex: true
+  - selector: '{job="dashboards"}'
+`
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &limits))
+		assert.Equal(t, []BlockedQuery{
+			{Pattern: "sum(rate(http_requests_total[30d]))"},
+			{Pattern: `rate\(.*\[\d+d\]\)`, Regex: true},
+			{Selector: `{job="dashboards"}`},
+		}, limits.BlockedQueries)
+	})
+
+	for name, cfg := range map[string]string{
+		"neither pattern nor selector": `{"blocked_queries": [{"regex": true}]}`,
+		"both pattern and selector":    `{"blocked_queries": [{"pattern": "up", "selector": "{job=\"dashboards\"}"}]}`,
+		"invalid regex":                `{"blocked_queries": [{"pattern": "(", "regex": true}]}`,
+		"invalid selector":             `{"blocked_queries": [{"selector": "{job="}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := Limits{}
+			require.ErrorContains(t, json.Unmarshal([]byte(cfg), &limits), "invalid blocked_queries")
+		})
+	}
+}
+
+func TestUnmarshalIngestAggregationRules(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		limits := Limits{}
+		cfg := `
+ingest_aggregation_rules:
+  - selector: '{__name__="http_requests_total"}'
+    without: [pod, instance]
+    interval: 30s
+    drop_input: true
+`
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &limits))
+		assert.Equal(t, []IngestAggregationRule{
+			{Selector: `{__name__="http_requests_total"}`, Without: []string{"pod", "instance"}, Interval: model.Duration(30 * time.Second), DropInput: true},
+		}, limits.IngestAggregationRules)
+	})
+
+	for name, cfg := range map[string]string{
+		"invalid selector":     `{"ingest_aggregation_rules": [{"selector": "{job=", "without": ["pod"]}]}`,
+		"no label to drop":     `{"ingest_aggregation_rules": [{"selector": "{job=\"api\"}"}]}`,
+		"drop the metric name": `{"ingest_aggregation_rules": [{"selector": "{job=\"api\"}", "without": ["__name__"]}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := Limits{}
+			require.ErrorContains(t, json.Unmarshal([]byte(cfg), &limits), "invalid ingest_aggregation_rules")
+		})
+	}
+}
+
+func TestUnmarshalHistogramFloatConflictPolicy(t *testing.T) {
+	for _, policy := range append([]string{""}, histogramFloatConflictPolicies...) {
+		t.Run(fmt.Sprintf("valid policy %q", policy), func(t *testing.T) {
+			limits := Limits{}
+			require.NoError(t, yaml.Unmarshal([]byte(fmt.Sprintf("histogram_float_conflict_policy: %q", policy)), &limits))
+			assert.Equal(t, policy, limits.HistogramFloatConflictPolicy)
+		})
+	}
+
+	t.Run("invalid policy", func(t *testing.T) {
+		limits := Limits{}
+		require.ErrorContains(t, yaml.Unmarshal([]byte(`histogram_float_conflict_policy: unknown`), &limits), "invalid histogram_float_conflict_policy")
+	})
+}
+
+func TestUnmarshalCompactorBlockRanges(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		limits := Limits{}
+		require.NoError(t, yaml.Unmarshal([]byte(`compactor_block_ranges: [2h, 24h, 168h]`), &limits))
+		assert.Equal(t, mimir_tsdb.DurationList{2 * time.Hour, 24 * time.Hour, 168 * time.Hour}, limits.CompactorBlockRanges)
+	})
+
+	for name, cfg := range map[string]string{
+		"not divisible":     `compactor_block_ranges: [2h, 5h]`,
+		"negative duration": `compactor_block_ranges: [-2h]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := Limits{}
+			require.ErrorContains(t, yaml.Unmarshal([]byte(cfg), &limits), "invalid compactor_block_ranges")
+		})
+	}
+}
+
+func TestLimits_ValidateCompactorBlockRanges(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ranges      mimir_tsdb.DurationList
+		expectedErr string
+	}{
+		"no ranges": {
+			ranges: nil,
+		},
+		"first range is the TSDB block range": {
+			ranges: mimir_tsdb.DurationList{2 * time.Hour, 24 * time.Hour},
+		},
+		"first range is a multiple of the TSDB block range": {
+			ranges: mimir_tsdb.DurationList{4 * time.Hour, 24 * time.Hour},
+		},
+		"first range is not a multiple of the TSDB block range": {
+			ranges:      mimir_tsdb.DurationList{3 * time.Hour, 24 * time.Hour},
+			expectedErr: "invalid compactor_block_ranges: 3h0m0s is not a multiple of the TSDB block range 2h0m0s",
+		},
+		"first range is shorter than the TSDB block range": {
+			ranges:      mimir_tsdb.DurationList{time.Hour, 24 * time.Hour},
+			expectedErr: "invalid compactor_block_ranges: 1h0m0s is not a multiple of the TSDB block range 2h0m0s",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := Limits{CompactorBlockRanges: tc.ranges}
+			err := limits.ValidateCompactorBlockRanges(2 * time.Hour)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
 type structExtension struct {
 	Foo int `yaml:"foo"`
 }
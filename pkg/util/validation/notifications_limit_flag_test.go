@@ -28,6 +28,24 @@ func TestNotificationLimitsMap(t *testing.T) {
 			},
 		},
 
+		"all integrations": {
+			args: []string{"-map-flag", "{\"webhook\": 1, \"email\": 2, \"pagerduty\": 3, \"opsgenie\": 4, \"wechat\": 5, \"slack\": 6, \"victorops\": 7, \"pushover\": 8, \"sns\": 9, \"telegram\": 10, \"discord\": 11, \"webex\": 12}"},
+			expected: NotificationRateLimitMap{
+				"webhook":   1,
+				"email":     2,
+				"pagerduty": 3,
+				"opsgenie":  4,
+				"wechat":    5,
+				"slack":     6,
+				"victorops": 7,
+				"pushover":  8,
+				"sns":       9,
+				"telegram":  10,
+				"discord":   11,
+				"webex":     12,
+			},
+		},
+
 		"unknown integration": {
 			args:  []string{"-map-flag", "{\"unknown\": 200 }"},
 			error: "invalid value \"{\\\"unknown\\\": 200 }\" for flag -map-flag: unknown integration name: unknown",
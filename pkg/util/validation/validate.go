@@ -35,8 +35,10 @@ var (
 	reasonInvalidLabel           = metricReasonFromErrorID(globalerror.SeriesInvalidLabel)
 	reasonLabelNameTooLong       = metricReasonFromErrorID(globalerror.SeriesLabelNameTooLong)
 	reasonLabelValueTooLong      = metricReasonFromErrorID(globalerror.SeriesLabelValueTooLong)
+	reasonLabelsTooLarge         = metricReasonFromErrorID(globalerror.SeriesLabelsTooLarge)
 	reasonDuplicateLabelNames    = metricReasonFromErrorID(globalerror.SeriesWithDuplicateLabelNames)
 	reasonTooFarInFuture         = metricReasonFromErrorID(globalerror.SampleTooFarInFuture)
+	reasonHistogramFloatConflict = metricReasonFromErrorID(globalerror.SeriesHistogramFloatConflict)
 
 	// Discarded exemplars reasons.
 	reasonExemplarLabelsMissing    = metricReasonFromErrorID(globalerror.ExemplarLabelsMissing)
@@ -48,11 +50,15 @@ var (
 	// Discarded metadata reasons.
 	reasonMetadataMetricNameTooLong = metricReasonFromErrorID(globalerror.MetricMetadataMetricNameTooLong)
 	reasonMetadataUnitTooLong       = metricReasonFromErrorID(globalerror.MetricMetadataUnitTooLong)
+	reasonMetadataTooLarge          = metricReasonFromErrorID(globalerror.MetricMetadataTooLarge)
 
 	// ReasonRateLimited is one of the values for the reason to discard samples.
 	// Declared here to avoid duplication in ingester and distributor.
 	ReasonRateLimited = "rate_limited" // same for request and ingestion which are separate errors, so not using metricReasonFromErrorID with global error
 
+	// ReasonSourceRateLimited is the reason to discard requests exceeding the per-source request rate limit.
+	ReasonSourceRateLimited = "source_rate_limited"
+
 	// ReasonTooManyHAClusters is one of the reasons for discarding samples.
 	ReasonTooManyHAClusters = "too_many_ha_clusters"
 )
@@ -109,6 +115,8 @@ func DiscardedMetadataCounter(reg prometheus.Registerer, reason string) *prometh
 // SampleValidationConfig helps with getting required config to validate sample.
 type SampleValidationConfig interface {
 	CreationGracePeriod(userID string) time.Duration
+	FutureTimestampsClampWindow(userID string) time.Duration
+	HistogramFloatConflictPolicy(userID string) string
 }
 
 // SampleValidationMetrics is a collection of metrics used during sample validation.
@@ -119,8 +127,13 @@ type SampleValidationMetrics struct {
 	invalidLabel           *prometheus.CounterVec
 	labelNameTooLong       *prometheus.CounterVec
 	labelValueTooLong      *prometheus.CounterVec
+	labelsTooLarge         *prometheus.CounterVec
 	duplicateLabelNames    *prometheus.CounterVec
 	tooFarInFuture         *prometheus.CounterVec
+	histogramFloatConflict *prometheus.CounterVec
+
+	clampedFutureTimestamps         *prometheus.CounterVec
+	histogramFloatConflictingSeries *prometheus.CounterVec
 }
 
 func (m *SampleValidationMetrics) DeleteUserMetrics(userID string) {
@@ -131,8 +144,12 @@ func (m *SampleValidationMetrics) DeleteUserMetrics(userID string) {
 	m.invalidLabel.DeletePartialMatch(filter)
 	m.labelNameTooLong.DeletePartialMatch(filter)
 	m.labelValueTooLong.DeletePartialMatch(filter)
+	m.labelsTooLarge.DeletePartialMatch(filter)
 	m.duplicateLabelNames.DeletePartialMatch(filter)
 	m.tooFarInFuture.DeletePartialMatch(filter)
+	m.histogramFloatConflict.DeletePartialMatch(filter)
+	m.clampedFutureTimestamps.DeletePartialMatch(filter)
+	m.histogramFloatConflictingSeries.DeletePartialMatch(filter)
 }
 
 func (m *SampleValidationMetrics) DeleteUserMetricsForGroup(userID, group string) {
@@ -142,8 +159,12 @@ func (m *SampleValidationMetrics) DeleteUserMetricsForGroup(userID, group string
 	m.invalidLabel.DeleteLabelValues(userID, group)
 	m.labelNameTooLong.DeleteLabelValues(userID, group)
 	m.labelValueTooLong.DeleteLabelValues(userID, group)
+	m.labelsTooLarge.DeleteLabelValues(userID, group)
 	m.duplicateLabelNames.DeleteLabelValues(userID, group)
 	m.tooFarInFuture.DeleteLabelValues(userID, group)
+	m.histogramFloatConflict.DeleteLabelValues(userID, group)
+	m.clampedFutureTimestamps.DeleteLabelValues(userID, group)
+	m.histogramFloatConflictingSeries.DeleteLabelValues(userID, group)
 }
 
 func NewSampleValidationMetrics(r prometheus.Registerer) *SampleValidationMetrics {
@@ -154,8 +175,18 @@ func NewSampleValidationMetrics(r prometheus.Registerer) *SampleValidationMetric
 		invalidLabel:           DiscardedSamplesCounter(r, reasonInvalidLabel),
 		labelNameTooLong:       DiscardedSamplesCounter(r, reasonLabelNameTooLong),
 		labelValueTooLong:      DiscardedSamplesCounter(r, reasonLabelValueTooLong),
+		labelsTooLarge:         DiscardedSamplesCounter(r, reasonLabelsTooLarge),
 		duplicateLabelNames:    DiscardedSamplesCounter(r, reasonDuplicateLabelNames),
 		tooFarInFuture:         DiscardedSamplesCounter(r, reasonTooFarInFuture),
+		histogramFloatConflict: DiscardedSamplesCounter(r, reasonHistogramFloatConflict),
+		clampedFutureTimestamps: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_samples_clamped_total",
+			Help: "The total number of samples whose timestamp too far in the future has been clamped to the current time.",
+		}, []string{"user", "group"}),
+		histogramFloatConflictingSeries: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_histogram_float_conflicting_series_total",
+			Help: "The total number of series received with both float and native histogram samples, handled according to the per-tenant policy.",
+		}, []string{"user", "group"}),
 	}
 }
 
@@ -186,6 +217,58 @@ func NewExemplarValidationMetrics(r prometheus.Registerer) *ExemplarValidationMe
 	}
 }
 
+// ClampSampleTimestamp returns the input timestamp clamped to now if it's beyond the creation grace period
+// but within the per-tenant future timestamps clamp window, otherwise it returns the input timestamp unchanged.
+// Clamping is disabled if the creation grace period is disabled, because no sample is too far in the future then.
+func ClampSampleTimestamp(m *SampleValidationMetrics, now model.Time, cfg SampleValidationConfig, userID, group string, ts int64) int64 {
+	window := cfg.FutureTimestampsClampWindow(userID)
+	gracePeriod := cfg.CreationGracePeriod(userID)
+	if window <= 0 || gracePeriod <= 0 {
+		return ts
+	}
+
+	if t := model.Time(ts); t > now.Add(gracePeriod) && t <= now.Add(window) {
+		m.clampedFutureTimestamps.WithLabelValues(userID, group).Inc()
+		return int64(now)
+	}
+	return ts
+}
+
+// ResolveHistogramFloatConflict applies the per-tenant policy to a series having both float and native histogram
+// samples, which would conflict in the ingesters. The float or the native histogram samples may be dropped from
+// the series. The returned split is true if the native histogram samples have to be moved to a separate series,
+// whose metric name is suffixed with HistogramFloatConflictSeriesSuffix. An error is returned if the series is
+// rejected. The returned error may retain the provided series labels.
+func ResolveHistogramFloatConflict(m *SampleValidationMetrics, cfg SampleValidationConfig, userID, group string, ts *mimirpb.TimeSeries) (split bool, err ValidationError) {
+	if len(ts.Samples) == 0 || len(ts.Histograms) == 0 {
+		return false, nil
+	}
+
+	switch cfg.HistogramFloatConflictPolicy(userID) {
+	case HistogramFloatConflictPolicyReject:
+		m.histogramFloatConflictingSeries.WithLabelValues(userID, group).Inc()
+		m.histogramFloatConflict.WithLabelValues(userID, group).Add(float64(len(ts.Samples) + len(ts.Histograms)))
+		unsafeMetricName, _ := extract.UnsafeMetricNameFromLabelAdapters(ts.Labels)
+		return false, newHistogramFloatConflictError(unsafeMetricName)
+
+	case HistogramFloatConflictPolicyPreferHistogram:
+		m.histogramFloatConflictingSeries.WithLabelValues(userID, group).Inc()
+		m.histogramFloatConflict.WithLabelValues(userID, group).Add(float64(len(ts.Samples)))
+		ts.Samples = ts.Samples[:0]
+
+	case HistogramFloatConflictPolicyPreferFloat:
+		m.histogramFloatConflictingSeries.WithLabelValues(userID, group).Inc()
+		m.histogramFloatConflict.WithLabelValues(userID, group).Add(float64(len(ts.Histograms)))
+		ts.Histograms = ts.Histograms[:0]
+
+	case HistogramFloatConflictPolicySplitSeries:
+		m.histogramFloatConflictingSeries.WithLabelValues(userID, group).Inc()
+		return true, nil
+	}
+
+	return false, nil
+}
+
 // ValidateSample returns an err if the sample is invalid.
 // The returned error may retain the provided series labels.
 // It uses the passed 'now' time to measure the relative time of the sample.
@@ -278,6 +361,7 @@ type LabelValidationConfig interface {
 	MaxLabelNamesPerSeries(userID string) int
 	MaxLabelNameLength(userID string) int
 	MaxLabelValueLength(userID string) int
+	MaxLabelsSizeBytes(userID string) int
 }
 
 // ValidateLabels returns an err if the labels are invalid.
@@ -303,6 +387,7 @@ func ValidateLabels(m *SampleValidationMetrics, cfg LabelValidationConfig, userI
 	maxLabelNameLength := cfg.MaxLabelNameLength(userID)
 	maxLabelValueLength := cfg.MaxLabelValueLength(userID)
 	lastLabelName := ""
+	labelsSize := 0
 	for _, l := range ls {
 		if !skipLabelNameValidation && !model.LabelName(l.Name).IsValid() {
 			m.invalidLabel.WithLabelValues(userID, group).Inc()
@@ -319,6 +404,12 @@ func ValidateLabels(m *SampleValidationMetrics, cfg LabelValidationConfig, userI
 		}
 
 		lastLabelName = l.Name
+		labelsSize += len(l.Name) + len(l.Value)
+	}
+
+	if maxLabelsSize := cfg.MaxLabelsSizeBytes(userID); maxLabelsSize > 0 && labelsSize > maxLabelsSize {
+		m.labelsTooLarge.WithLabelValues(userID, group).Inc()
+		return newLabelsTooLargeError(ls, labelsSize, maxLabelsSize)
 	}
 	return nil
 }
@@ -328,12 +419,14 @@ type MetadataValidationMetrics struct {
 	missingMetricName *prometheus.CounterVec
 	metricNameTooLong *prometheus.CounterVec
 	unitTooLong       *prometheus.CounterVec
+	metadataTooLarge  *prometheus.CounterVec
 }
 
 func (m *MetadataValidationMetrics) DeleteUserMetrics(userID string) {
 	m.missingMetricName.DeleteLabelValues(userID)
 	m.metricNameTooLong.DeleteLabelValues(userID)
 	m.unitTooLong.DeleteLabelValues(userID)
+	m.metadataTooLarge.DeleteLabelValues(userID)
 }
 
 func NewMetadataValidationMetrics(r prometheus.Registerer) *MetadataValidationMetrics {
@@ -341,6 +434,7 @@ func NewMetadataValidationMetrics(r prometheus.Registerer) *MetadataValidationMe
 		missingMetricName: DiscardedMetadataCounter(r, reasonMissingMetricName),
 		metricNameTooLong: DiscardedMetadataCounter(r, reasonMetadataMetricNameTooLong),
 		unitTooLong:       DiscardedMetadataCounter(r, reasonMetadataUnitTooLong),
+		metadataTooLarge:  DiscardedMetadataCounter(r, reasonMetadataTooLarge),
 	}
 }
 
@@ -348,6 +442,7 @@ func NewMetadataValidationMetrics(r prometheus.Registerer) *MetadataValidationMe
 type MetadataValidationConfig interface {
 	EnforceMetadataMetricName(userID string) bool
 	MaxMetadataLength(userID string) int
+	MaxMetadataSizeBytes(userID string) int
 }
 
 // CleanAndValidateMetadata returns an err if a metric metadata is invalid.
@@ -377,6 +472,11 @@ func CleanAndValidateMetadata(m *MetadataValidationMetrics, cfg MetadataValidati
 	} else if len(metadata.Unit) > maxMetadataValueLength {
 		m.unitTooLong.WithLabelValues(userID).Inc()
 		err = newMetadataUnitTooLongError(metadata)
+	} else if maxMetadataSize := cfg.MaxMetadataSizeBytes(userID); maxMetadataSize > 0 {
+		if size := len(metadata.GetMetricFamilyName()) + len(metadata.Help) + len(metadata.Unit); size > maxMetadataSize {
+			m.metadataTooLarge.WithLabelValues(userID).Inc()
+			err = newMetadataTooLargeError(metadata, size, maxMetadataSize)
+		}
 	}
 
 	return err
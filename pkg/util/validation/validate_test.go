@@ -8,20 +8,24 @@ package validation
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/globalerror"
 )
 
 type validateLabelsCfg struct {
 	maxLabelNamesPerSeries int
 	maxLabelNameLength     int
 	maxLabelValueLength    int
+	maxLabelsSizeBytes     int
 }
 
 func (v validateLabelsCfg) MaxLabelNamesPerSeries(userID string) int {
@@ -36,9 +40,14 @@ func (v validateLabelsCfg) MaxLabelValueLength(userID string) int {
 	return v.maxLabelValueLength
 }
 
+func (v validateLabelsCfg) MaxLabelsSizeBytes(userID string) int {
+	return v.maxLabelsSizeBytes
+}
+
 type validateMetadataCfg struct {
 	enforceMetadataMetricName bool
 	maxMetadataLength         int
+	maxMetadataSizeBytes      int
 }
 
 func (vm validateMetadataCfg) EnforceMetadataMetricName(userID string) bool {
@@ -49,6 +58,28 @@ func (vm validateMetadataCfg) MaxMetadataLength(userID string) int {
 	return vm.maxMetadataLength
 }
 
+func (vm validateMetadataCfg) MaxMetadataSizeBytes(userID string) int {
+	return vm.maxMetadataSizeBytes
+}
+
+type sampleValidationCfg struct {
+	creationGracePeriod          time.Duration
+	futureTimestampsClampWindow  time.Duration
+	histogramFloatConflictPolicy string
+}
+
+func (c sampleValidationCfg) CreationGracePeriod(string) time.Duration {
+	return c.creationGracePeriod
+}
+
+func (c sampleValidationCfg) FutureTimestampsClampWindow(string) time.Duration {
+	return c.futureTimestampsClampWindow
+}
+
+func (c sampleValidationCfg) HistogramFloatConflictPolicy(string) string {
+	return c.histogramFloatConflictPolicy
+}
+
 func TestValidateLabels(t *testing.T) {
 	reg := prometheus.NewPedanticRegistry()
 	s := NewSampleValidationMetrics(reg)
@@ -59,6 +90,7 @@ func TestValidateLabels(t *testing.T) {
 	cfg.maxLabelValueLength = 25
 	cfg.maxLabelNameLength = 25
 	cfg.maxLabelNamesPerSeries = 2
+	cfg.maxLabelsSizeBytes = 48
 
 	for _, c := range []struct {
 		metric                  model.Metric
@@ -118,6 +150,14 @@ func TestValidateLabels(t *testing.T) {
 			true,
 			nil,
 		},
+		{
+			map[model.LabelName]model.LabelValue{model.MetricNameLabel: "too_large_labels", "label_one": "value_one_is_long"},
+			false,
+			newLabelsTooLargeError([]mimirpb.LabelAdapter{
+				{Name: model.MetricNameLabel, Value: "too_large_labels"},
+				{Name: "label_one", Value: "value_one_is_long"},
+			}, 50, 48),
+		},
 	} {
 		err := ValidateLabels(s, cfg, userID, "custom label", mimirpb.FromMetricsToLabelAdapters(c.metric), c.skipLabelNameValidation)
 		assert.Equal(t, c.err, err, "wrong error")
@@ -132,6 +172,7 @@ func TestValidateLabels(t *testing.T) {
 			cortex_discarded_samples_total{group="custom label",reason="label_invalid",user="testUser"} 1
 			cortex_discarded_samples_total{group="custom label",reason="label_name_too_long",user="testUser"} 1
 			cortex_discarded_samples_total{group="custom label",reason="label_value_too_long",user="testUser"} 1
+			cortex_discarded_samples_total{group="custom label",reason="labels_size_too_large",user="testUser"} 1
 			cortex_discarded_samples_total{group="custom label",reason="max_label_names_per_series",user="testUser"} 1
 			cortex_discarded_samples_total{group="custom label",reason="metric_name_invalid",user="testUser"} 1
 			cortex_discarded_samples_total{group="custom label",reason="missing_metric_name",user="testUser"} 1
@@ -223,6 +264,133 @@ func TestValidateExemplars(t *testing.T) {
 	`), "cortex_discarded_exemplars_total"))
 }
 
+func TestClampSampleTimestamp(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	m := NewSampleValidationMetrics(reg)
+
+	userID := "testUser"
+	now := model.Now()
+	cfg := sampleValidationCfg{creationGracePeriod: time.Minute, futureTimestampsClampWindow: time.Hour}
+
+	tests := map[string]struct {
+		cfg      sampleValidationCfg
+		ts       int64
+		expected int64
+	}{
+		"in the past": {
+			cfg:      cfg,
+			ts:       int64(now.Add(-time.Hour)),
+			expected: int64(now.Add(-time.Hour)),
+		},
+		"within the creation grace period": {
+			cfg:      cfg,
+			ts:       int64(now.Add(30 * time.Second)),
+			expected: int64(now.Add(30 * time.Second)),
+		},
+		"beyond the creation grace period, within the clamp window": {
+			cfg:      cfg,
+			ts:       int64(now.Add(30 * time.Minute)),
+			expected: int64(now),
+		},
+		"beyond the clamp window": {
+			cfg:      cfg,
+			ts:       int64(now.Add(2 * time.Hour)),
+			expected: int64(now.Add(2 * time.Hour)),
+		},
+		"clamping disabled": {
+			cfg:      sampleValidationCfg{creationGracePeriod: time.Minute},
+			ts:       int64(now.Add(30 * time.Minute)),
+			expected: int64(now.Add(30 * time.Minute)),
+		},
+		"creation grace period disabled": {
+			cfg:      sampleValidationCfg{futureTimestampsClampWindow: time.Hour},
+			ts:       int64(now.Add(30 * time.Minute)),
+			expected: int64(now.Add(30 * time.Minute)),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, ClampSampleTimestamp(m, now, testData.cfg, userID, "group", testData.ts))
+		})
+	}
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_distributor_samples_clamped_total The total number of samples whose timestamp too far in the future has been clamped to the current time.
+			# TYPE cortex_distributor_samples_clamped_total counter
+			cortex_distributor_samples_clamped_total{group="group",user="testUser"} 1
+		`), "cortex_distributor_samples_clamped_total"))
+
+	m.DeleteUserMetrics(userID)
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_distributor_samples_clamped_total"))
+}
+
+func TestResolveHistogramFloatConflict(t *testing.T) {
+	userID := "testUser"
+	h := mimirpb.FromHistogramToHistogramProto(0, &histogram.Histogram{Count: 1, Sum: 1, Schema: 0})
+
+	tests := map[string]struct {
+		policy             string
+		series             mimirpb.TimeSeries
+		expectedSplit      bool
+		expectedErr        bool
+		expectedSamples    int
+		expectedHistograms int
+	}{
+		"no conflict": {
+			policy:          HistogramFloatConflictPolicyReject,
+			series:          mimirpb.TimeSeries{Samples: []mimirpb.Sample{{Value: 1}}},
+			expectedSamples: 1,
+		},
+		"no policy": {
+			series:             mimirpb.TimeSeries{Samples: []mimirpb.Sample{{Value: 1}}, Histograms: []mimirpb.Histogram{h}},
+			expectedSamples:    1,
+			expectedHistograms: 1,
+		},
+		"reject": {
+			policy:             HistogramFloatConflictPolicyReject,
+			series:             mimirpb.TimeSeries{Samples: []mimirpb.Sample{{Value: 1}}, Histograms: []mimirpb.Histogram{h}},
+			expectedErr:        true,
+			expectedSamples:    1,
+			expectedHistograms: 1,
+		},
+		"prefer histogram": {
+			policy:             HistogramFloatConflictPolicyPreferHistogram,
+			series:             mimirpb.TimeSeries{Samples: []mimirpb.Sample{{Value: 1}}, Histograms: []mimirpb.Histogram{h}},
+			expectedHistograms: 1,
+		},
+		"prefer float": {
+			policy:          HistogramFloatConflictPolicyPreferFloat,
+			series:          mimirpb.TimeSeries{Samples: []mimirpb.Sample{{Value: 1}}, Histograms: []mimirpb.Histogram{h}},
+			expectedSamples: 1,
+		},
+		"split series": {
+			policy:             HistogramFloatConflictPolicySplitSeries,
+			series:             mimirpb.TimeSeries{Samples: []mimirpb.Sample{{Value: 1}}, Histograms: []mimirpb.Histogram{h}},
+			expectedSplit:      true,
+			expectedSamples:    1,
+			expectedHistograms: 1,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			m := NewSampleValidationMetrics(prometheus.NewPedanticRegistry())
+			cfg := sampleValidationCfg{histogramFloatConflictPolicy: testData.policy}
+
+			split, err := ResolveHistogramFloatConflict(m, cfg, userID, "group", &testData.series)
+			if testData.expectedErr {
+				assert.ErrorContains(t, err, string(globalerror.SeriesHistogramFloatConflict))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testData.expectedSplit, split)
+			assert.Len(t, testData.series.Samples, testData.expectedSamples)
+			assert.Len(t, testData.series.Histograms, testData.expectedHistograms)
+		})
+	}
+}
+
 func TestValidateMetadata(t *testing.T) {
 	reg := prometheus.NewPedanticRegistry()
 	m := NewMetadataValidationMetrics(reg)
@@ -231,6 +399,7 @@ func TestValidateMetadata(t *testing.T) {
 	var cfg validateMetadataCfg
 	cfg.enforceMetadataMetricName = true
 	cfg.maxMetadataLength = 22
+	cfg.maxMetadataSizeBytes = 40
 
 	for _, c := range []struct {
 		desc        string
@@ -280,6 +449,12 @@ func TestValidateMetadata(t *testing.T) {
 			newMetadataUnitTooLongError(&mimirpb.MetricMetadata{MetricFamilyName: "go_goroutines", Unit: "a_made_up_unit_that_is_really_long"}),
 			nil,
 		},
+		{
+			"with too large metadata",
+			&mimirpb.MetricMetadata{MetricFamilyName: "go_goroutines", Type: mimirpb.COUNTER, Help: "Number of goroutines.", Unit: "goroutines_total"},
+			newMetadataTooLargeError(&mimirpb.MetricMetadata{MetricFamilyName: "go_goroutines"}, 50, 40),
+			nil,
+		},
 	} {
 		t.Run(c.desc, func(t *testing.T) {
 			err := CleanAndValidateMetadata(m, cfg, userID, c.metadata)
@@ -295,6 +470,7 @@ func TestValidateMetadata(t *testing.T) {
 	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
 			# HELP cortex_discarded_metadata_total The total number of metadata that were discarded.
 			# TYPE cortex_discarded_metadata_total counter
+			cortex_discarded_metadata_total{reason="metadata_too_large",user="testUser"} 1
 			cortex_discarded_metadata_total{reason="metric_name_too_long",user="testUser"} 1
 			cortex_discarded_metadata_total{reason="missing_metric_name",user="testUser"} 1
 			cortex_discarded_metadata_total{reason="unit_too_long",user="testUser"} 1
@@ -22,6 +22,7 @@ import (
 	"github.com/prometheus/prometheus/model/relabel"
 	"github.com/weaveworks/common/logging"
 
+	"github.com/grafana/mimir/pkg/frontend/querymiddleware"
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
 	"github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/util/fieldcategory"
@@ -346,6 +347,12 @@ func getFieldCustomType(t reflect.Type) (string, bool) {
 		return "string", true
 	case reflect.TypeOf([]*relabel.Config{}).String():
 		return "relabel_config...", true
+	case reflect.TypeOf([]validation.BlockedQuery{}).String():
+		return "list of blocked queries", true
+	case reflect.TypeOf([]validation.IngestAggregationRule{}).String():
+		return "list of ingest aggregation rules", true
+	case reflect.TypeOf([]querymiddleware.RefreshedQuery{}).String():
+		return "list of refreshed queries", true
 	case reflect.TypeOf(activeseries.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
 	default:
@@ -426,6 +433,12 @@ func getCustomFieldType(t reflect.Type) (string, bool) {
 		return "string", true
 	case reflect.TypeOf([]*relabel.Config{}).String():
 		return "relabel_config...", true
+	case reflect.TypeOf([]validation.BlockedQuery{}).String():
+		return "list of blocked queries", true
+	case reflect.TypeOf([]validation.IngestAggregationRule{}).String():
+		return "list of ingest aggregation rules", true
+	case reflect.TypeOf([]querymiddleware.RefreshedQuery{}).String():
+		return "list of refreshed queries", true
 	case reflect.TypeOf(activeseries.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
 	default:
@@ -457,6 +470,12 @@ func ReflectType(typ string) reflect.Type {
 		return reflect.TypeOf(map[string]string{})
 	case "relabel_config...":
 		return reflect.TypeOf([]*relabel.Config{})
+	case "list of blocked queries":
+		return reflect.TypeOf([]validation.BlockedQuery{})
+	case "list of ingest aggregation rules":
+		return reflect.TypeOf([]validation.IngestAggregationRule{})
+	case "list of refreshed queries":
+		return reflect.TypeOf([]querymiddleware.RefreshedQuery{})
 	case "map of string to float64":
 		return reflect.TypeOf(map[string]float64{})
 	case "list of durations":